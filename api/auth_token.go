@@ -371,4 +371,5 @@ type TokenCreateRequest struct {
 	Renewable       *bool             `json:"renewable,omitempty"`
 	Type            string            `json:"type"`
 	EntityAlias     string            `json:"entity_alias"`
+	InlinePolicy    string            `json:"inline_policy,omitempty"`
 }
@@ -75,3 +75,63 @@ func (c *Sys) CapabilitiesWithContext(ctx context.Context, token, path string) (
 
 	return res, nil
 }
+
+// PathCapabilities holds the capabilities of a token on each of the queried
+// paths and, when requested, the mounts each path applies to.
+type PathCapabilities struct {
+	Capabilities   map[string][]string
+	ExpandedMounts map[string][]string
+}
+
+func (c *Sys) CapabilitiesSelfPaths(paths []string, expandMounts bool) (*PathCapabilities, error) {
+	return c.CapabilitiesSelfPathsWithContext(context.Background(), paths, expandMounts)
+}
+
+// CapabilitiesSelfPathsWithContext fetches the capabilities of the client
+// token on all of the given paths in a single request.
+func (c *Sys) CapabilitiesSelfPathsWithContext(ctx context.Context, paths []string, expandMounts bool) (*PathCapabilities, error) {
+	ctx, cancelFunc := c.c.withConfiguredTimeout(ctx)
+	defer cancelFunc()
+
+	body := map[string]interface{}{
+		"paths":         paths,
+		"expand_mounts": expandMounts,
+	}
+
+	r := c.c.NewRequest(http.MethodPost, "/v1/sys/capabilities-self")
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.c.rawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	res := &PathCapabilities{
+		Capabilities: make(map[string][]string, len(paths)),
+	}
+	for _, path := range paths {
+		var capabilities []string
+		if err := mapstructure.Decode(secret.Data[path], &capabilities); err != nil {
+			return nil, err
+		}
+		res.Capabilities[path] = capabilities
+	}
+	if expandMounts {
+		if err := mapstructure.Decode(secret.Data["expanded_mounts"], &res.ExpandedMounts); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
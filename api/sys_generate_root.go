@@ -58,19 +58,29 @@ func (c *Sys) GenerateRecoveryOperationTokenInit(otp, pgpKey string) (*GenerateR
 	return c.GenerateRecoveryOperationTokenInitWithContext(context.Background(), otp, pgpKey)
 }
 
+// GenerateReadOnlyRecoveryTokenInit starts the generation of a read-only
+// recovery token valid for ttl, on a server that is not in recovery mode.
+func (c *Sys) GenerateReadOnlyRecoveryTokenInit(otp, pgpKey, ttl string) (*GenerateRootStatusResponse, error) {
+	return c.GenerateReadOnlyRecoveryTokenInitWithContext(context.Background(), otp, pgpKey, ttl)
+}
+
 func (c *Sys) GenerateRootInitWithContext(ctx context.Context, otp, pgpKey string) (*GenerateRootStatusResponse, error) {
-	return c.generateRootInitCommonWithContext(ctx, "/v1/sys/generate-root/attempt", otp, pgpKey)
+	return c.generateRootInitCommonWithContext(ctx, "/v1/sys/generate-root/attempt", otp, pgpKey, "")
 }
 
 func (c *Sys) GenerateDROperationTokenInitWithContext(ctx context.Context, otp, pgpKey string) (*GenerateRootStatusResponse, error) {
-	return c.generateRootInitCommonWithContext(ctx, "/v1/sys/replication/dr/secondary/generate-operation-token/attempt", otp, pgpKey)
+	return c.generateRootInitCommonWithContext(ctx, "/v1/sys/replication/dr/secondary/generate-operation-token/attempt", otp, pgpKey, "")
 }
 
 func (c *Sys) GenerateRecoveryOperationTokenInitWithContext(ctx context.Context, otp, pgpKey string) (*GenerateRootStatusResponse, error) {
-	return c.generateRootInitCommonWithContext(ctx, "/v1/sys/generate-recovery-token/attempt", otp, pgpKey)
+	return c.generateRootInitCommonWithContext(ctx, "/v1/sys/generate-recovery-token/attempt", otp, pgpKey, "")
 }
 
-func (c *Sys) generateRootInitCommonWithContext(ctx context.Context, path, otp, pgpKey string) (*GenerateRootStatusResponse, error) {
+func (c *Sys) GenerateReadOnlyRecoveryTokenInitWithContext(ctx context.Context, otp, pgpKey, ttl string) (*GenerateRootStatusResponse, error) {
+	return c.generateRootInitCommonWithContext(ctx, "/v1/sys/generate-recovery-token/attempt", otp, pgpKey, ttl)
+}
+
+func (c *Sys) generateRootInitCommonWithContext(ctx context.Context, path, otp, pgpKey, ttl string) (*GenerateRootStatusResponse, error) {
 	ctx, cancelFunc := c.c.withConfiguredTimeout(ctx)
 	defer cancelFunc()
 
@@ -78,6 +88,9 @@ func (c *Sys) generateRootInitCommonWithContext(ctx context.Context, path, otp,
 		"otp":     otp,
 		"pgp_key": pgpKey,
 	}
+	if ttl != "" {
+		body["ttl"] = ttl
+	}
 
 	r := c.c.NewRequest(http.MethodPut, path)
 	if err := r.SetJSONBody(body); err != nil {
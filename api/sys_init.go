@@ -59,6 +59,8 @@ type InitRequest struct {
 	RecoveryThreshold int      `json:"recovery_threshold"`
 	RecoveryPGPKeys   []string `json:"recovery_pgp_keys"`
 	RootTokenPGPKey   string   `json:"root_token_pgp_key"`
+
+	TOTPProtectedShares bool `json:"totp_protected_shares,omitempty"`
 }
 
 type InitStatusResponse struct {
@@ -71,4 +73,5 @@ type InitResponse struct {
 	RecoveryKeys    []string `json:"recovery_keys"`
 	RecoveryKeysB64 []string `json:"recovery_keys_base64"`
 	RootToken       string   `json:"root_token"`
+	KeysTOTP        []string `json:"keys_totp"`
 }
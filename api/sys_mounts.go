@@ -82,6 +82,93 @@ func (c *Sys) UnmountWithContext(ctx context.Context, path string) error {
 	return err
 }
 
+// UnmountWithRetention wraps UnmountWithRetentionWithContext using context.Background.
+func (c *Sys) UnmountWithRetention(path, retention string) error {
+	return c.UnmountWithRetentionWithContext(context.Background(), path, retention)
+}
+
+// UnmountWithRetentionWithContext disables the mount at path, keeping its
+// data in the trash for the given retention period, during which the mount
+// can be restored with RestoreMountWithContext.
+func (c *Sys) UnmountWithRetentionWithContext(ctx context.Context, path, retention string) error {
+	ctx, cancelFunc := c.c.withConfiguredTimeout(ctx)
+	defer cancelFunc()
+
+	r := c.c.NewRequest(http.MethodDelete, fmt.Sprintf("/v1/sys/mounts/%s", path))
+	r.Params.Set("retention", retention)
+
+	resp, err := c.c.rawRequestWithContext(ctx, r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// ListMountTrash wraps ListMountTrashWithContext using context.Background.
+func (c *Sys) ListMountTrash() (map[string]*MountTrashOutput, error) {
+	return c.ListMountTrashWithContext(context.Background())
+}
+
+// ListMountTrashWithContext returns the mounts disabled with a retention
+// period, by ID.
+func (c *Sys) ListMountTrashWithContext(ctx context.Context) (map[string]*MountTrashOutput, error) {
+	ctx, cancelFunc := c.c.withConfiguredTimeout(ctx)
+	defer cancelFunc()
+
+	r := c.c.NewRequest("LIST", "/v1/sys/mounts/trash")
+
+	resp, err := c.c.rawRequestWithContext(ctx, r)
+	if resp != nil && resp.StatusCode == 404 {
+		return map[string]*MountTrashOutput{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var result struct {
+		KeyInfo map[string]*MountTrashOutput `mapstructure:"key_info"`
+	}
+	if err := mapstructure.Decode(secret.Data, &result); err != nil {
+		return nil, err
+	}
+	if result.KeyInfo == nil {
+		result.KeyInfo = map[string]*MountTrashOutput{}
+	}
+	return result.KeyInfo, nil
+}
+
+// RestoreMount wraps RestoreMountWithContext using context.Background.
+func (c *Sys) RestoreMount(id, path string) error {
+	return c.RestoreMountWithContext(context.Background(), id, path)
+}
+
+// RestoreMountWithContext enables the mount of the trash with the given ID
+// again, with its data, at path or at its original path if path is empty.
+func (c *Sys) RestoreMountWithContext(ctx context.Context, id, path string) error {
+	ctx, cancelFunc := c.c.withConfiguredTimeout(ctx)
+	defer cancelFunc()
+
+	r := c.c.NewRequest(http.MethodPost, fmt.Sprintf("/v1/sys/mounts/trash/%s/restore", id))
+	if err := r.SetJSONBody(map[string]string{"path": path}); err != nil {
+		return err
+	}
+
+	resp, err := c.c.rawRequestWithContext(ctx, r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
 // Remount wraps RemountWithContext using context.Background.
 func (c *Sys) Remount(from, to string) error {
 	return c.RemountWithContext(context.Background(), from, to)
@@ -268,6 +355,10 @@ type MountConfigInput struct {
 	AllowedManagedKeys        []string                `json:"allowed_managed_keys,omitempty" mapstructure:"allowed_managed_keys"`
 	PluginVersion             string                  `json:"plugin_version,omitempty"`
 	UserLockoutConfig         *UserLockoutConfigInput `json:"user_lockout_config,omitempty"`
+	IdempotencyKeyTTL         string                  `json:"idempotency_key_ttl,omitempty" mapstructure:"idempotency_key_ttl"`
+	MaxRequestSize            int64                   `json:"max_request_size,omitempty" mapstructure:"max_request_size"`
+	MaxResponseSize           int64                   `json:"max_response_size,omitempty" mapstructure:"max_response_size"`
+	RequestTimeout            string                  `json:"request_timeout,omitempty" mapstructure:"request_timeout"`
 	// Deprecated: This field will always be blank for newer server responses.
 	PluginName string `json:"plugin_name,omitempty" mapstructure:"plugin_name"`
 }
@@ -288,6 +379,16 @@ type MountOutput struct {
 	DeprecationStatus     string            `json:"deprecation_status" mapstructure:"deprecation_status"`
 }
 
+type MountTrashOutput struct {
+	Path         string `json:"path"`
+	Type         string `json:"type"`
+	Description  string `json:"description"`
+	Accessor     string `json:"accessor"`
+	Local        bool   `json:"local"`
+	DeletionTime string `json:"deletion_time" mapstructure:"deletion_time"`
+	PurgeTime    string `json:"purge_time" mapstructure:"purge_time"`
+}
+
 type MountConfigOutput struct {
 	DefaultLeaseTTL           int                      `json:"default_lease_ttl" mapstructure:"default_lease_ttl"`
 	MaxLeaseTTL               int                      `json:"max_lease_ttl" mapstructure:"max_lease_ttl"`
@@ -300,6 +401,10 @@ type MountConfigOutput struct {
 	TokenType                 string                   `json:"token_type,omitempty" mapstructure:"token_type"`
 	AllowedManagedKeys        []string                 `json:"allowed_managed_keys,omitempty" mapstructure:"allowed_managed_keys"`
 	UserLockoutConfig         *UserLockoutConfigOutput `json:"user_lockout_config,omitempty"`
+	IdempotencyKeyTTL         int                      `json:"idempotency_key_ttl,omitempty" mapstructure:"idempotency_key_ttl"`
+	MaxRequestSize            int64                    `json:"max_request_size,omitempty" mapstructure:"max_request_size"`
+	MaxResponseSize           int64                    `json:"max_response_size,omitempty" mapstructure:"max_response_size"`
+	RequestTimeout            int                      `json:"request_timeout,omitempty" mapstructure:"request_timeout"`
 	// Deprecated: This field will always be blank for newer server responses.
 	PluginName string `json:"plugin_name,omitempty" mapstructure:"plugin_name"`
 }
@@ -268,6 +268,55 @@ func (c *Sys) RaftSnapshotRestoreWithContext(ctx context.Context, snapReader io.
 	return nil
 }
 
+// RaftSnapshotPreview wraps RaftSnapshotPreviewWithContext using context.Background.
+func (c *Sys) RaftSnapshotPreview(snapReader io.Reader) (*Secret, error) {
+	return c.RaftSnapshotPreviewWithContext(context.Background(), snapReader)
+}
+
+// RaftSnapshotPreviewWithContext reads the snapshot from the io.Reader and
+// returns the differences between it and the current state of the cluster,
+// without installing it.
+func (c *Sys) RaftSnapshotPreviewWithContext(ctx context.Context, snapReader io.Reader) (*Secret, error) {
+	r := c.c.NewRequest(http.MethodPost, "/v1/sys/storage/raft/snapshot-preview")
+	r.Body = snapReader
+
+	resp, err := c.c.httpRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ParseSecret(resp.Body)
+}
+
+// RaftSnapshotRestoreMounts wraps RaftSnapshotRestoreMountsWithContext using context.Background.
+func (c *Sys) RaftSnapshotRestoreMounts(snapReader io.Reader, mounts []string, dryRun bool) (*Secret, error) {
+	return c.RaftSnapshotRestoreMountsWithContext(context.Background(), snapReader, mounts, dryRun)
+}
+
+// RaftSnapshotRestoreMountsWithContext reads the snapshot from the io.Reader
+// and restores the data of the given mounts from it, leaving the rest of the
+// cluster untouched. With dryRun set, the changes are only reported.
+func (c *Sys) RaftSnapshotRestoreMountsWithContext(ctx context.Context, snapReader io.Reader, mounts []string, dryRun bool) (*Secret, error) {
+	r := c.c.NewRequest(http.MethodPost, "/v1/sys/storage/raft/snapshot-selective")
+	for _, mount := range mounts {
+		r.Params.Add("mount", mount)
+	}
+	if dryRun {
+		r.Params.Set("dry_run", "true")
+	}
+	r.URL.RawQuery = r.Params.Encode()
+	r.Body = snapReader
+
+	resp, err := c.c.httpRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ParseSecret(resp.Body)
+}
+
 // RaftAutopilotState wraps RaftAutopilotStateWithContext using context.Background.
 func (c *Sys) RaftAutopilotState() (*AutopilotState, error) {
 	return c.RaftAutopilotStateWithContext(context.Background())
@@ -433,6 +433,7 @@ type RekeyInitRequest struct {
 	PGPKeys             []string `json:"pgp_keys"`
 	Backup              bool
 	RequireVerification bool `json:"require_verification"`
+	TOTPProtectedShares bool `json:"totp_protected_shares,omitempty"`
 }
 
 type RekeyStatusResponse struct {
@@ -457,6 +458,7 @@ type RekeyUpdateResponse struct {
 	Backup               bool     `json:"backup"`
 	VerificationRequired bool     `json:"verification_required"`
 	VerificationNonce    string   `json:"verification_nonce,omitempty"`
+	KeysTOTP             []string `json:"keys_totp,omitempty"`
 }
 
 type RekeyRetrieveResponse struct {
@@ -106,6 +106,7 @@ type SealStatusResponse struct {
 	ClusterName       string   `json:"cluster_name,omitempty"`
 	ClusterID         string   `json:"cluster_id,omitempty"`
 	RecoverySeal      bool     `json:"recovery_seal"`
+	TOTPProtected     bool     `json:"totp_protected"`
 	StorageType       string   `json:"storage_type,omitempty"`
 	HCPLinkStatus     string   `json:"hcp_link_status,omitempty"`
 	HCPLinkResourceID string   `json:"hcp_link_resource_ID,omitempty"`
@@ -116,4 +117,5 @@ type UnsealOpts struct {
 	Key     string `json:"key"`
 	Reset   bool   `json:"reset"`
 	Migrate bool   `json:"migrate"`
+	TOTP    string `json:"totp,omitempty"`
 }
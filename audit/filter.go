@@ -0,0 +1,345 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Fields of audit entries which filter expressions can compare.
+const (
+	FilterFieldMountPoint  = "mount_point"
+	FilterFieldMountType   = "mount_type"
+	FilterFieldNamespace   = "namespace"
+	FilterFieldOperation   = "operation"
+	FilterFieldPath        = "path"
+	FilterFieldDisplayName = "display_name"
+)
+
+var filterFields = map[string]struct{}{
+	FilterFieldMountPoint:  {},
+	FilterFieldMountType:   {},
+	FilterFieldNamespace:   {},
+	FilterFieldOperation:   {},
+	FilterFieldPath:        {},
+	FilterFieldDisplayName: {},
+}
+
+// Filter is a parsed filter expression selecting the entries an audit
+// device logs. Expressions compare fields of the entry with double-quoted
+// strings, and combine comparisons with "and", "or", "not" and
+// parentheses:
+//
+//	operation == "update" and path matches "^auth/[^/]+/login"
+//	not (namespace == "ns1/" or display_name != "root")
+//
+// "==" and "!=" compare strings exactly, "matches" and "not matches" with a
+// regular expression.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// NewFilter parses expr into a filter.
+func NewFilter(expr string) (*Filter, error) {
+	p := &filterParser{input: expr}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("invalid filter expression: %w", err)
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("invalid filter expression: empty expression")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression: %w", err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid filter expression: unexpected %q", p.tokens[p.pos].value)
+	}
+
+	return &Filter{
+		expr: expr,
+		root: root,
+	}, nil
+}
+
+// String returns the expression the filter was parsed from.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Evaluate reports whether the audit device of the filter logs in.
+func (f *Filter) Evaluate(ctx context.Context, in *logical.LogInput) bool {
+	return f.root.eval(FilterFieldsFromLogInput(ctx, in))
+}
+
+// FilterFieldsFromLogInput returns the fields filter expressions compare for
+// the given entry.
+func FilterFieldsFromLogInput(ctx context.Context, in *logical.LogInput) map[string]string {
+	fields := make(map[string]string, len(filterFields))
+	if ns, err := namespace.FromContext(ctx); err == nil {
+		fields[FilterFieldNamespace] = ns.Path
+	}
+	if in.Request != nil {
+		fields[FilterFieldMountPoint] = in.Request.MountPoint
+		fields[FilterFieldMountType] = in.Request.MountType
+		fields[FilterFieldOperation] = string(in.Request.Operation)
+		fields[FilterFieldPath] = in.Request.Path
+	}
+	if in.Auth != nil {
+		fields[FilterFieldDisplayName] = in.Auth.DisplayName
+	}
+	return fields
+}
+
+type filterNode interface {
+	eval(fields map[string]string) bool
+}
+
+type filterAnd struct{ left, right filterNode }
+
+func (n filterAnd) eval(fields map[string]string) bool {
+	return n.left.eval(fields) && n.right.eval(fields)
+}
+
+type filterOr struct{ left, right filterNode }
+
+func (n filterOr) eval(fields map[string]string) bool {
+	return n.left.eval(fields) || n.right.eval(fields)
+}
+
+type filterNot struct{ node filterNode }
+
+func (n filterNot) eval(fields map[string]string) bool {
+	return !n.node.eval(fields)
+}
+
+type filterEqual struct {
+	field string
+	value string
+}
+
+func (n filterEqual) eval(fields map[string]string) bool {
+	return fields[n.field] == n.value
+}
+
+type filterMatch struct {
+	field string
+	re    *regexp.Regexp
+}
+
+func (n filterMatch) eval(fields map[string]string) bool {
+	return n.re.MatchString(fields[n.field])
+}
+
+type filterTokenKind int
+
+const (
+	filterTokenWord filterTokenKind = iota
+	filterTokenString
+	filterTokenOperator
+	filterTokenLParen
+	filterTokenRParen
+)
+
+type filterToken struct {
+	kind  filterTokenKind
+	value string
+}
+
+type filterParser struct {
+	input  string
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) tokenize() error {
+	input := p.input
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenLParen, value: "("})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenRParen, value: ")"})
+			i++
+		case strings.HasPrefix(input[i:], "=="), strings.HasPrefix(input[i:], "!="):
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenOperator, value: input[i : i+2]})
+			i += 2
+		case c == '"':
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return fmt.Errorf("unterminated string starting at offset %d", i)
+			}
+			value, err := strconv.Unquote(input[i : end+1])
+			if err != nil {
+				return fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenString, value: value})
+			i = end + 1
+		case isFilterWordByte(c) && (c < '0' || c > '9'):
+			end := i
+			for end < len(input) && isFilterWordByte(input[end]) {
+				end++
+			}
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenWord, value: input[i:end]})
+			i = end
+		default:
+			return fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return nil
+}
+
+func isFilterWordByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (p *filterParser) peek() *filterToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *filterParser) peekWord(word string) bool {
+	t := p.peek()
+	return t != nil && t.kind == filterTokenWord && t.value == word
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	t := p.peek()
+	if t == nil {
+		return filterToken{}, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	return *t, nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekWord("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekWord("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.peekWord("not") {
+		p.pos++
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node: node}, nil
+	}
+
+	if t := p.peek(); t != nil && t.kind == filterTokenLParen {
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if t.kind != filterTokenRParen {
+			return nil, fmt.Errorf("expected \")\" but found %q", t.value)
+		}
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if field.kind != filterTokenWord {
+		return nil, fmt.Errorf("expected a field but found %q", field.value)
+	}
+	if _, ok := filterFields[field.value]; !ok {
+		return nil, fmt.Errorf("unknown field %q", field.value)
+	}
+
+	negate := false
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if op.kind == filterTokenWord && op.value == "not" && p.peekWord("matches") {
+		negate = true
+		op, _ = p.next()
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if value.kind != filterTokenString {
+		return nil, fmt.Errorf("expected a double-quoted string after %q but found %q", op.value, value.value)
+	}
+
+	var node filterNode
+	switch {
+	case op.kind == filterTokenOperator && op.value == "==":
+		node = filterEqual{field: field.value, value: value.value}
+	case op.kind == filterTokenOperator && op.value == "!=":
+		node = filterNot{node: filterEqual{field: field.value, value: value.value}}
+	case op.kind == filterTokenWord && op.value == "matches":
+		re, err := regexp.Compile(value.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value.value, err)
+		}
+		node = filterMatch{field: field.value, re: re}
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op.value)
+	}
+
+	if negate {
+		node = filterNot{node: node}
+	}
+	return node, nil
+}
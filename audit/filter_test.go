@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestFilter(t *testing.T) {
+	ns := &namespace.Namespace{ID: "abc", Path: "ns1/"}
+	ctx := namespace.ContextWithNamespace(context.Background(), ns)
+	in := &logical.LogInput{
+		Auth: &logical.Auth{
+			DisplayName: "userpass-bob",
+		},
+		Request: &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "auth/userpass/login/bob",
+			MountPoint: "auth/userpass/",
+			MountType:  "userpass",
+		},
+	}
+
+	for expr, expected := range map[string]bool{
+		`operation == "update"`:                                     true,
+		`operation != "update"`:                                     false,
+		`path matches "^auth/[^/]+/login"`:                          true,
+		`path not matches "^auth/[^/]+/login"`:                      false,
+		`mount_point == "auth/userpass/" and mount_type == "ldap"`:  false,
+		`mount_type == "ldap" or mount_type == "userpass"`:          true,
+		`namespace == "ns1/" and display_name == "userpass-bob"`:    true,
+		`not namespace == "ns1/"`:                                   false,
+		`not (namespace == "" or operation == "read")`:              true,
+		`operation == "read" and (path == "x" or path == "y")`:      false,
+		`operation == "read" and path == "x" or path matches "bob"`: true,
+		`display_name == "with \"quotes\""`:                         false,
+	} {
+		filter, err := NewFilter(expr)
+		if err != nil {
+			t.Fatalf("error parsing %s: %v", expr, err)
+		}
+		if filter.String() != expr {
+			t.Fatalf("expected expression %s, got %s", expr, filter.String())
+		}
+		if actual := filter.Evaluate(ctx, in); actual != expected {
+			t.Fatalf("expected %s to evaluate to %t", expr, expected)
+		}
+	}
+
+	for _, expr := range []string{
+		``,
+		`operation`,
+		`operation ==`,
+		`operation == update`,
+		`unknown == "x"`,
+		`operation === "x"`,
+		`operation == "x" and`,
+		`(operation == "x"`,
+		`operation == "x")`,
+		`path matches "["`,
+		`path == "unterminated`,
+		`operation in "x"`,
+	} {
+		if _, err := NewFilter(expr); err == nil {
+			t.Fatalf("expected an error parsing %q", expr)
+		}
+	}
+}
@@ -125,6 +125,8 @@ func (f *AuditFormatter) FormatRequest(ctx context.Context, w io.Writer, config
 			PolicyOverride:                req.PolicyOverride,
 			RemoteAddr:                    getRemoteAddr(req),
 			RemotePort:                    getRemotePort(req),
+			RemoteCountry:                 getRemoteCountry(req),
+			RemoteASN:                     getRemoteASN(req),
 			ReplicationCluster:            req.ReplicationCluster,
 			Headers:                       req.Headers,
 			ClientCertificateSerialNumber: getClientCertificateSerialNumber(connState),
@@ -305,6 +307,8 @@ func (f *AuditFormatter) FormatResponse(ctx context.Context, w io.Writer, config
 			PolicyOverride:                req.PolicyOverride,
 			RemoteAddr:                    getRemoteAddr(req),
 			RemotePort:                    getRemotePort(req),
+			RemoteCountry:                 getRemoteCountry(req),
+			RemoteASN:                     getRemoteASN(req),
 			ClientCertificateSerialNumber: getClientCertificateSerialNumber(connState),
 			ReplicationCluster:            req.ReplicationCluster,
 			Headers:                       req.Headers,
@@ -385,6 +389,8 @@ type AuditRequest struct {
 	PolicyOverride                bool                   `json:"policy_override,omitempty"`
 	RemoteAddr                    string                 `json:"remote_address,omitempty"`
 	RemotePort                    int                    `json:"remote_port,omitempty"`
+	RemoteCountry                 string                 `json:"remote_country,omitempty"`
+	RemoteASN                     int                    `json:"remote_asn,omitempty"`
 	WrapTTL                       int                    `json:"wrap_ttl,omitempty"`
 	Headers                       map[string][]string    `json:"headers,omitempty"`
 	ClientCertificateSerialNumber string                 `json:"client_certificate_serial_number,omitempty"`
@@ -467,6 +473,22 @@ func getRemotePort(req *logical.Request) int {
 	return 0
 }
 
+// getRemoteCountry safely gets the remote country avoiding a nil pointer
+func getRemoteCountry(req *logical.Request) string {
+	if req != nil && req.Connection != nil {
+		return req.Connection.RemoteCountry
+	}
+	return ""
+}
+
+// getRemoteASN safely gets the remote ASN avoiding a nil pointer
+func getRemoteASN(req *logical.Request) int {
+	if req != nil && req.Connection != nil {
+		return req.Connection.RemoteASN
+	}
+	return 0
+}
+
 func getClientCertificateSerialNumber(connState *tls.ConnectionState) string {
 	if connState == nil || len(connState.VerifiedChains) == 0 || len(connState.VerifiedChains[0]) == 0 {
 		return ""
@@ -508,6 +530,10 @@ func NewTemporaryFormatter(format, prefix string) *AuditFormatter {
 			Prefix:   prefix,
 			SaltFunc: temporarySalt,
 		}
+	case "cbor":
+		ret.AuditFormatWriter = &CBORFormatWriter{
+			SaltFunc: temporarySalt,
+		}
 	default:
 		ret.AuditFormatWriter = &JSONFormatWriter{
 			Prefix:   prefix,
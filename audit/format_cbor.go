@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+)
+
+// cborLengthSize is the size of the length prefix of CBOR records
+const cborLengthSize = 4
+
+var (
+	cborHandle = func() *codec.CborHandle {
+		h := &codec.CborHandle{}
+		// Decode maps as they are represented in the JSON format
+		h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+		return h
+	}()
+
+	cborBufferPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+)
+
+// CBORFormatWriter is an AuditFormatWriter implementation that structures data
+// into CBOR records, each preceded by its length as a big-endian uint32 so
+// that they can be read back from a stream without delimiters.
+type CBORFormatWriter struct {
+	SaltFunc func(context.Context) (*salt.Salt, error)
+}
+
+func (f *CBORFormatWriter) WriteRequest(w io.Writer, req *AuditRequestEntry) error {
+	if req == nil {
+		return fmt.Errorf("request entry was nil, cannot encode")
+	}
+	return writeCBORRecord(w, req)
+}
+
+func (f *CBORFormatWriter) WriteResponse(w io.Writer, resp *AuditResponseEntry) error {
+	if resp == nil {
+		return fmt.Errorf("response entry was nil, cannot encode")
+	}
+	return writeCBORRecord(w, resp)
+}
+
+func (f *CBORFormatWriter) Salt(ctx context.Context) (*salt.Salt, error) {
+	return f.SaltFunc(ctx)
+}
+
+// writeCBORRecord writes the record of entry with a single call to w, so that
+// concurrent records are not interleaved.
+func writeCBORRecord(w io.Writer, entry interface{}) error {
+	buf := cborBufferPool.Get().(*bytes.Buffer)
+	defer cborBufferPool.Put(buf)
+	buf.Reset()
+
+	var length [cborLengthSize]byte
+	buf.Write(length[:])
+	if err := codec.NewEncoder(buf, cborHandle).Encode(entry); err != nil {
+		return err
+	}
+
+	record := buf.Bytes()
+	binary.BigEndian.PutUint32(record, uint32(len(record)-cborLengthSize))
+	_, err := w.Write(record)
+	return err
+}
+
+// ReadCBORRecord reads the next record written by a CBORFormatWriter from r,
+// and decodes it into out. It returns io.EOF once r has no more records.
+func ReadCBORRecord(r io.Reader, out interface{}) error {
+	var length [cborLengthSize]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return err
+	}
+
+	record := make([]byte, binary.BigEndian.Uint32(length[:]))
+	if _, err := io.ReadFull(r, record); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return codec.NewDecoderBytes(record, cborHandle).Decode(out)
+}
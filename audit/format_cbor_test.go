@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func testCBORLogInput() *logical.LogInput {
+	return &logical.LogInput{
+		Auth: &logical.Auth{
+			ClientToken: "foo",
+			Accessor:    "bar",
+			DisplayName: "testtoken",
+			EntityID:    "foobarentity",
+			Policies:    []string{"root"},
+			TokenType:   logical.TokenTypeService,
+			LeaseOptions: logical.LeaseOptions{
+				TTL: time.Hour * 4,
+			},
+		},
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "/foo",
+			Connection: &logical.Connection{
+				RemoteAddr: "127.0.0.1",
+			},
+			Data: map[string]interface{}{
+				"password": "secret",
+				"nested": map[string]interface{}{
+					"list": []interface{}{"a", "b"},
+				},
+			},
+			Headers: map[string][]string{
+				"foo": {"bar"},
+			},
+		},
+	}
+}
+
+func TestFormatCBOR_formatRequest(t *testing.T) {
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saltFunc := func(context.Context) (*salt.Salt, error) {
+		return salter, nil
+	}
+	config := FormatterConfig{OmitTime: true}
+	ctx := namespace.RootContext(nil)
+
+	var jsonBuf bytes.Buffer
+	jsonFormatter := AuditFormatter{AuditFormatWriter: &JSONFormatWriter{SaltFunc: saltFunc}}
+	if err := jsonFormatter.FormatRequest(ctx, &jsonBuf, config, testCBORLogInput()); err != nil {
+		t.Fatal(err)
+	}
+	expected := new(AuditRequestEntry)
+	if err := json.Unmarshal(jsonBuf.Bytes(), expected); err != nil {
+		t.Fatal(err)
+	}
+	expectedBytes, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write several records to check that they can be read back in sequence
+	var buf bytes.Buffer
+	formatter := AuditFormatter{AuditFormatWriter: &CBORFormatWriter{SaltFunc: saltFunc}}
+	for i := 0; i < 2; i++ {
+		if err := formatter.FormatRequest(ctx, &buf, config, testCBORLogInput()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		actual := new(AuditRequestEntry)
+		if err := ReadCBORRecord(&buf, actual); err != nil {
+			t.Fatal(err)
+		}
+		actualBytes, err := json.Marshal(actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(actualBytes, expectedBytes) {
+			t.Fatalf("bad record %d:\nexpected: %s\nactual:   %s", i, expectedBytes, actualBytes)
+		}
+	}
+	if err := ReadCBORRecord(&buf, new(AuditRequestEntry)); err != io.EOF {
+		t.Fatalf("expected io.EOF after the last record, got %v", err)
+	}
+}
+
+func BenchmarkFormatRequest(b *testing.B) {
+	salter := salt.NewNonpersistentSalt()
+	saltFunc := func(context.Context) (*salt.Salt, error) {
+		return salter, nil
+	}
+	ctx := namespace.RootContext(nil)
+	in := testCBORLogInput()
+
+	writers := map[string]AuditFormatWriter{
+		"json": &JSONFormatWriter{SaltFunc: saltFunc},
+		"cbor": &CBORFormatWriter{SaltFunc: saltFunc},
+	}
+	for name, w := range writers {
+		b.Run(name, func(b *testing.B) {
+			formatter := AuditFormatter{AuditFormatWriter: w}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := formatter.FormatRequest(ctx, io.Discard, FormatterConfig{}, in); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
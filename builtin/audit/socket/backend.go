@@ -49,6 +49,11 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 	}
 	switch format {
 	case "json", "jsonx":
+	case "cbor":
+		// Records are length-prefixed, so a prefix would corrupt them
+		if conf.Config["prefix"] != "" {
+			return nil, fmt.Errorf("prefix is not supported with the cbor format")
+		}
 	default:
 		return nil, fmt.Errorf("unknown format type %q", format)
 	}
@@ -97,6 +102,10 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 			Prefix:   conf.Config["prefix"],
 			SaltFunc: b.Salt,
 		}
+	case "cbor":
+		b.formatter.AuditFormatWriter = &audit.CBORFormatWriter{
+			SaltFunc: b.Salt,
+		}
 	}
 
 	return b, nil
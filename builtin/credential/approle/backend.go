@@ -3,6 +3,7 @@ package approle
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -16,6 +17,10 @@ const (
 	secretIDLocalPrefix         = "secret_id_local/"
 	secretIDAccessorPrefix      = "accessor/"
 	secretIDAccessorLocalPrefix = "accessor_local/"
+
+	// defaultSecretIDRotationWrapTTL is the TTL of the wrapping token of the
+	// SecretIDs issued on use, when the role doesn't set one.
+	defaultSecretIDRotationWrapTTL = 5 * time.Minute
 )
 
 // ReportedVersion is used to report a specific version to Vault.
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -124,6 +125,7 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 
 	metadata := make(map[string]string)
 	var entry *secretIDStorageEntry
+	var rotatedEntryIndex, rotatedSecretIDHMAC string
 	if role.BindSecretID {
 		secretID := strings.TrimSpace(data.Get("secret_id").(string))
 		if secretID == "" {
@@ -192,7 +194,7 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 		}
 
 		switch {
-		case entry.SecretIDNumUses == 0:
+		case entry.SecretIDNumUses == 0 && !role.SecretIDRotateOnUse:
 			//
 			// SecretIDNumUses will be zero only if the usage limit was not set at all,
 			// in which case, the SecretID will remain to be valid as long as it is not
@@ -245,10 +247,14 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 				return logical.ErrorResponse(fmt.Sprintf("invalid secret_id %q", secretID)), nil
 			}
 
-			// If there exists a single use left, delete the SecretID entry from
-			// the storage but do not fail the validation request. Subsequent
+			// If the SecretID rotates on use, it is only deleted once its
+			// successor is issued, while the lock is still held. If there
+			// exists a single use left, delete the SecretID entry from the
+			// storage but do not fail the validation request. Subsequent
 			// requests to use the same SecretID will fail.
-			if entry.SecretIDNumUses == 1 {
+			if role.SecretIDRotateOnUse {
+				rotatedEntryIndex, rotatedSecretIDHMAC = entryIndex, secretIDHMAC
+			} else if entry.SecretIDNumUses == 1 {
 				// Delete the secret IDs accessor first
 				err = b.deleteSecretIDAccessorEntry(ctx, req.Storage, entry.SecretIDAccessor, role.SecretIDPrefix)
 				if err != nil {
@@ -327,6 +333,15 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 		}
 	}
 
+	// Issue the successor of the SecretID once the login is known to succeed
+	var rotatedSecretID *wrapping.ResponseWrapInfo
+	if rotatedEntryIndex != "" {
+		rotatedSecretID, err = b.rotateSecretID(ctx, req.Storage, role, entry, rotatedEntryIndex, rotatedSecretIDHMAC)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// For some reason, if metadata was set to nil while processing secret ID
 	// binding, ensure that it is initialized again to avoid a panic.
 	if metadata == nil {
@@ -351,9 +366,17 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 	// Allow for overridden token bound CIDRs
 	auth.BoundCIDRs = tokenBoundCIDRs
 
-	return &logical.Response{
+	resp := &logical.Response{
 		Auth: auth,
-	}, nil
+	}
+	if rotatedSecretID != nil {
+		resp.Data = map[string]interface{}{
+			"secret_id_wrapping_token":    rotatedSecretID.Token,
+			"secret_id_wrapping_accessor": rotatedSecretID.Accessor,
+			"secret_id_wrapping_ttl":      int64(rotatedSecretID.TTL.Seconds()),
+		}
+	}
+	return resp, nil
 }
 
 // Invoked when the token issued by this backend is attempting a renewal.
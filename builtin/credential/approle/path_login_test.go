@@ -2,10 +2,12 @@ package approle
 
 import (
 	"context"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -390,3 +392,165 @@ func TestAppRole_RoleDoesNotExist(t *testing.T) {
 		t.Fatalf("Error was not due to invalid role ID. Error: %s", errString)
 	}
 }
+
+// wrappingSystemView records the data it response wraps, returning its
+// index as the wrapping token.
+type wrappingSystemView struct {
+	logical.StaticSystemView
+	wrapped []map[string]interface{}
+}
+
+func (s *wrappingSystemView) ResponseWrapData(_ context.Context, data map[string]interface{}, ttl time.Duration, _ bool) (*wrapping.ResponseWrapInfo, error) {
+	s.wrapped = append(s.wrapped, data)
+	return &wrapping.ResponseWrapInfo{
+		Token: strconv.Itoa(len(s.wrapped) - 1),
+		TTL:   ttl,
+	}, nil
+}
+
+func TestAppRole_SecretIDRotateOnUse(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	sysView := &wrappingSystemView{StaticSystemView: *config.System.(*logical.StaticSystemView)}
+	config.System = sysView
+
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	s := config.StorageView
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"bind_secret_id":              false,
+			"secret_id_bound_cidrs":       []string{"127.0.0.1/32"},
+			"secret_id_rotate_on_use":     true,
+			"secret_id_rotation_wrap_ttl": "1m",
+		},
+		Storage: s,
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error as secret_id_rotate_on_use requires bind_secret_id, got err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"secret_id_bound_cidrs":       []string{"127.0.0.1/32"},
+			"secret_id_rotate_on_use":     true,
+			"secret_id_rotation_wrap_ttl": "1m",
+		},
+		Storage: s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole/role-id",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	roleID := resp.Data["role_id"]
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole/secret-id",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"cidr_list": []string{"127.0.0.1/32"},
+			"metadata":  `{"host": "daemon"}`,
+		},
+		Storage: s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	secretID := resp.Data["secret_id"].(string)
+
+	login := func(secretID, remoteAddr string) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "login",
+			Operation: logical.UpdateOperation,
+			Data: map[string]interface{}{
+				"role_id":   roleID,
+				"secret_id": secretID,
+			},
+			Storage:    s,
+			Connection: &logical.Connection{RemoteAddr: remoteAddr},
+		})
+		if err != nil && resp == nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Logins from outside of the CIDR blocks are rejected, without
+	// consuming the secret ID
+	if resp := login(secretID, "10.0.0.1"); resp.Auth != nil {
+		t.Fatalf("expected login to fail: %#v", resp)
+	}
+	if len(sysView.wrapped) != 0 {
+		t.Fatalf("expected no secret ID to be issued, got %d", len(sysView.wrapped))
+	}
+
+	for i := 0; i < 3; i++ {
+		resp := login(secretID, "127.0.0.1")
+		if resp.IsError() || resp.Auth == nil {
+			t.Fatalf("expected login to succeed: %#v", resp)
+		}
+		if resp.Auth.Metadata["host"] != "daemon" {
+			t.Fatalf("bad: metadata: %#v", resp.Auth.Metadata)
+		}
+		if resp.Data["secret_id_wrapping_ttl"] != int64(60) {
+			t.Fatalf("bad: wrapping TTL: %#v", resp.Data)
+		}
+
+		// The used secret ID can't be used again
+		if resp := login(secretID, "127.0.0.1"); resp.Auth != nil {
+			t.Fatalf("expected login with a used secret ID to fail: %#v", resp)
+		}
+
+		index, err := strconv.Atoi(resp.Data["secret_id_wrapping_token"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		secretID = sysView.wrapped[index]["secret_id"].(string)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole/secret-id",
+		Operation: logical.ListOperation,
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 {
+		t.Fatalf("expected a single secret ID to remain, got %v", keys)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole/secret-id/lookup",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"secret_id": secretID,
+		},
+		Storage: s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if cidrs := resp.Data["cidr_list"].([]string); len(cidrs) != 1 || cidrs[0] != "127.0.0.1/32" {
+		t.Fatalf("bad: cidr_list: %#v", resp.Data)
+	}
+}
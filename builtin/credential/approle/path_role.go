@@ -77,6 +77,15 @@ type roleStorageEntry struct {
 	// SecretIDPrefix is the storage prefix for persisting secret IDs. This
 	// differs based on whether the secret IDs are cluster local or not.
 	SecretIDPrefix string `json:"secret_id_prefix" mapstructure:"secret_id_prefix"`
+
+	// SecretIDRotateOnUse, if set, invalidates a SecretID each time it is
+	// used to login, and issues a successor with the same constraints,
+	// response wrapped in the login response.
+	SecretIDRotateOnUse bool `json:"secret_id_rotate_on_use" mapstructure:"secret_id_rotate_on_use"`
+
+	// SecretIDRotationWrapTTL is the TTL of the wrapping token of the
+	// successors of the SecretIDs. Zero uses defaultSecretIDRotationWrapTTL.
+	SecretIDRotationWrapTTL time.Duration `json:"secret_id_rotation_wrap_ttl" mapstructure:"secret_id_rotation_wrap_ttl"`
 }
 
 // roleIDStorageEntry represents the reverse mapping from RoleID to Role
@@ -167,6 +176,19 @@ to 0, meaning no expiration.`,
 				Description: `If set, the secret IDs generated using this role will be cluster local. This
 can only be set during role creation and once set, it can't be reset later.`,
 			},
+
+			"secret_id_rotate_on_use": {
+				Type: framework.TypeBool,
+				Description: `If set, a secret ID is invalidated each time it is used to login, and a
+successor bound to the same CIDR blocks and metadata is returned response
+wrapped in the login response. Requires 'bind_secret_id'.`,
+			},
+
+			"secret_id_rotation_wrap_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `Duration in seconds of the wrapping token of the secret IDs issued on use
+when 'secret_id_rotate_on_use' is set. Defaults to 0, meaning 5 minutes.`,
+			},
 		},
 		ExistenceCheck: b.pathRoleExistenceCheck,
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -992,6 +1014,20 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		role.SecretIDTTL = time.Second * time.Duration(data.Get("secret_id_ttl").(int))
 	}
 
+	if rotateOnUseRaw, ok := data.GetOk("secret_id_rotate_on_use"); ok {
+		role.SecretIDRotateOnUse = rotateOnUseRaw.(bool)
+	}
+	if role.SecretIDRotateOnUse && !role.BindSecretID {
+		return logical.ErrorResponse("secret_id_rotate_on_use requires bind_secret_id to be set"), nil
+	}
+
+	if rotationWrapTTLRaw, ok := data.GetOk("secret_id_rotation_wrap_ttl"); ok {
+		role.SecretIDRotationWrapTTL = time.Second * time.Duration(rotationWrapTTLRaw.(int))
+	}
+	if role.SecretIDRotationWrapTTL < 0 {
+		return logical.ErrorResponse("secret_id_rotation_wrap_ttl cannot be negative"), nil
+	}
+
 	// handle upgrade cases
 	{
 		if err := tokenutil.UpgradeValue(data, "policies", "token_policies", &role.Policies, &role.TokenPolicies); err != nil {
@@ -1041,11 +1077,13 @@ func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *
 	}
 
 	respData := map[string]interface{}{
-		"bind_secret_id":        role.BindSecretID,
-		"secret_id_bound_cidrs": role.SecretIDBoundCIDRs,
-		"secret_id_num_uses":    role.SecretIDNumUses,
-		"secret_id_ttl":         role.SecretIDTTL / time.Second,
-		"local_secret_ids":      false,
+		"bind_secret_id":              role.BindSecretID,
+		"secret_id_bound_cidrs":       role.SecretIDBoundCIDRs,
+		"secret_id_num_uses":          role.SecretIDNumUses,
+		"secret_id_ttl":               role.SecretIDTTL / time.Second,
+		"local_secret_ids":            false,
+		"secret_id_rotate_on_use":     role.SecretIDRotateOnUse,
+		"secret_id_rotation_wrap_ttl": role.SecretIDRotationWrapTTL / time.Second,
 	}
 	role.PopulateTokenData(respData)
 
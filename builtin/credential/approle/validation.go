@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/vault/helper/parseip"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -290,6 +291,97 @@ func (b *backend) registerSecretIDEntry(ctx context.Context, s logical.Storage,
 	return secretEntry, nil
 }
 
+// rotateSecretID issues the successor of a SecretID used to login with a role
+// having secret_id_rotate_on_use set, and deletes the used SecretID stored at
+// entryIndex. The successor has the same constraints and metadata, and is
+// returned response wrapped. The caller must hold the write lock of the used
+// SecretID.
+func (b *backend) rotateSecretID(ctx context.Context, s logical.Storage, role *roleStorageEntry, entry *secretIDStorageEntry, entryIndex, secretIDHMAC string) (*wrapping.ResponseWrapInfo, error) {
+	var secretID string
+	for {
+		var err error
+		secretID, err = uuid.GenerateUUID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate secret_id: %w", err)
+		}
+		successorHMAC, err := createHMAC(role.HMACKey, secretID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HMAC of secret ID: %w", err)
+		}
+		// The lock of the used SecretID is held, so the successor must not
+		// share it
+		if b.secretIDLock(successorHMAC) != b.secretIDLock(secretIDHMAC) {
+			break
+		}
+	}
+
+	successor := &secretIDStorageEntry{
+		SecretIDNumUses: entry.SecretIDNumUses,
+		SecretIDTTL:     entry.SecretIDTTL,
+		Metadata:        make(map[string]string, len(entry.Metadata)),
+		CIDRList:        entry.CIDRList,
+		TokenBoundCIDRs: entry.TokenBoundCIDRs,
+	}
+	for k, v := range entry.Metadata {
+		successor.Metadata[k] = v
+	}
+	successor, err := b.registerSecretIDEntry(ctx, s, role.name, secretID, role.HMACKey, role.SecretIDPrefix, successor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store secret_id: %w", err)
+	}
+
+	wrapTTL := role.SecretIDRotationWrapTTL
+	if wrapTTL == 0 {
+		wrapTTL = defaultSecretIDRotationWrapTTL
+	}
+	wrapInfo, err := b.System().ResponseWrapData(ctx, map[string]interface{}{
+		"secret_id":          secretID,
+		"secret_id_accessor": successor.SecretIDAccessor,
+		"secret_id_ttl":      int64(b.deriveSecretIDTTL(successor.SecretIDTTL).Seconds()),
+		"secret_id_num_uses": successor.SecretIDNumUses,
+	}, wrapTTL, false)
+	if err != nil {
+		// Keep the used SecretID valid, as its successor can't be handed out
+		if destroyErr := b.destroySecretID(ctx, s, role, secretID); destroyErr != nil {
+			b.Logger().Error("failed to destroy undelivered secret_id", "accessor", successor.SecretIDAccessor, "error", destroyErr)
+		}
+		return nil, fmt.Errorf("failed to wrap secret_id: %w", err)
+	}
+
+	if err := b.deleteSecretIDAccessorEntry(ctx, s, entry.SecretIDAccessor, role.SecretIDPrefix); err != nil {
+		return nil, err
+	}
+	if err := s.Delete(ctx, entryIndex); err != nil {
+		return nil, fmt.Errorf("failed to delete secret ID: %w", err)
+	}
+	return wrapInfo, nil
+}
+
+// destroySecretID deletes a SecretID of the role along with its accessor.
+func (b *backend) destroySecretID(ctx context.Context, s logical.Storage, role *roleStorageEntry, secretID string) error {
+	secretIDHMAC, err := createHMAC(role.HMACKey, secretID)
+	if err != nil {
+		return fmt.Errorf("failed to create HMAC of secret ID: %w", err)
+	}
+	roleNameHMAC, err := createHMAC(role.HMACKey, role.name)
+	if err != nil {
+		return fmt.Errorf("failed to create HMAC of role_name: %w", err)
+	}
+
+	lock := b.secretIDLock(secretIDHMAC)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := b.nonLockedSecretIDStorageEntry(ctx, s, role.SecretIDPrefix, roleNameHMAC, secretIDHMAC)
+	if err != nil || entry == nil {
+		return err
+	}
+	if err := b.deleteSecretIDAccessorEntry(ctx, s, entry.SecretIDAccessor, role.SecretIDPrefix); err != nil {
+		return err
+	}
+	return s.Delete(ctx, fmt.Sprintf("%s%s/%s", role.SecretIDPrefix, roleNameHMAC, secretIDHMAC))
+}
+
 // deriveSecretIDTTL determines the secret ID TTL to use based on the system's
 // max lease TTL.
 //
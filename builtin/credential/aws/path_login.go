@@ -631,6 +631,18 @@ func (b *backend) verifyInstanceMeetsRoleRequirements(ctx context.Context,
 		}
 	}
 
+	// Verify that the instance only serves its metadata, including its
+	// identity document, to IMDSv2 sessions, which SSRF requests through
+	// workloads running on the instance can't open
+	if roleEntry.RequireIMDSv2 {
+		if instance.MetadataOptions == nil || instance.MetadataOptions.HttpTokens == nil {
+			return nil, fmt.Errorf("metadata options in the instance description are nil")
+		}
+		if *instance.MetadataOptions.HttpTokens != ec2.HttpTokensStateRequired {
+			return fmt.Errorf("instance %q does not require IMDSv2 as required by role %q", *instance.InstanceId, roleName), nil
+		}
+	}
+
 	// Validate the SubnetID if corresponding bound was set on the role
 	if len(roleEntry.BoundSubnetIDs) > 0 {
 		if instance.SubnetId == nil {
@@ -149,6 +149,14 @@ is only allowed if auth_type is ec2.`,
 				Description: tokenutil.DeprecationText("token_policies"),
 				Deprecated:  true,
 			},
+			"require_imdsv2": {
+				Type:    framework.TypeBool,
+				Default: false,
+				Description: `If set, only EC2 instances which require IMDSv2 sessions to
+access their metadata, and so their identity document, can log in. This is
+only applicable when auth_type is ec2 or inferred_entity_type is
+ec2_instance.`,
+			},
 			"allow_instance_migration": {
 				Type:    framework.TypeBool,
 				Default: false,
@@ -829,6 +837,13 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		return logical.ErrorResponse("at least one bound parameter should be specified on the role"), nil
 	}
 
+	if requireIMDSv2Raw, ok := data.GetOk("require_imdsv2"); ok {
+		roleEntry.RequireIMDSv2 = requireIMDSv2Raw.(bool)
+	}
+	if roleEntry.RequireIMDSv2 && !allowEc2Binds {
+		return logical.ErrorResponse(fmt.Sprintf("specified require_imdsv2 but not specifying ec2 auth_type or inferring %s", ec2EntityType)), nil
+	}
+
 	disallowReauthenticationBool, ok := data.GetOk("disallow_reauthentication")
 	if ok {
 		if roleEntry.AuthType != ec2AuthType {
@@ -954,6 +969,7 @@ type awsRoleEntry struct {
 	InferredAWSRegion           string   `json:"inferred_aws_region"`
 	ResolveAWSUniqueIDs         bool     `json:"resolve_aws_unique_ids"`
 	RoleTag                     string   `json:"role_tag"`
+	RequireIMDSv2               bool     `json:"require_imdsv2"`
 	AllowInstanceMigration      bool     `json:"allow_instance_migration"`
 	DisallowReauthentication    bool     `json:"disallow_reauthentication"`
 	HMACKey                     string   `json:"hmac_key"`
@@ -995,6 +1011,7 @@ func (r *awsRoleEntry) ToResponseData() map[string]interface{} {
 		"resolve_aws_unique_ids":         r.ResolveAWSUniqueIDs,
 		"role_id":                        r.RoleID,
 		"role_tag":                       r.RoleTag,
+		"require_imdsv2":                 r.RequireIMDSv2,
 		"allow_instance_migration":       r.AllowInstanceMigration,
 		"disallow_reauthentication":      r.DisallowReauthentication,
 	}
@@ -615,6 +615,7 @@ func TestAwsEc2_RoleCrud(t *testing.T) {
 		"inferred_aws_region":            "",
 		"resolve_aws_unique_ids":         false,
 		"role_tag":                       "testtag",
+		"require_imdsv2":                 false,
 		"allow_instance_migration":       true,
 		"ttl":                            int64(600),
 		"token_ttl":                      int64(600),
@@ -72,6 +72,11 @@ type backend struct {
 	ocspClientMutex sync.RWMutex
 	ocspClient      *ocsp.Client
 	configUpdated   atomic.Bool
+
+	// pkiIssuers caches the issuers of the PKI mounts certificate entries
+	// trust, by mount path
+	pkiIssuers     map[string]*pkiIssuers
+	pkiIssuersLock sync.RWMutex
 }
 
 func (b *backend) invalidate(_ context.Context, key string) {
@@ -48,6 +48,12 @@ Must be x509 PEM encoded.`,
 					EditType: "file",
 				},
 			},
+			"pki_mount": {
+				Type: framework.TypeString,
+				Description: `The path of a PKI mount of the same namespace, whose
+issuers should be trusted in addition to the certificate. The issuers are
+fetched again as they rotate.`,
+			},
 			"ocsp_enabled": {
 				Type:        framework.TypeBool,
 				Description: `Whether to attempt OCSP verification of certificates at login`,
@@ -269,6 +275,7 @@ func (b *backend) pathCertRead(ctx context.Context, req *logical.Request, d *fra
 
 	data := map[string]interface{}{
 		"certificate":                  cert.Certificate,
+		"pki_mount":                    cert.PKIMount,
 		"display_name":                 cert.DisplayName,
 		"allowed_names":                cert.AllowedNames,
 		"allowed_common_names":         cert.AllowedCommonNames,
@@ -320,6 +327,12 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 	if certificateRaw, ok := d.GetOk("certificate"); ok {
 		cert.Certificate = certificateRaw.(string)
 	}
+	if pkiMountRaw, ok := d.GetOk("pki_mount"); ok {
+		cert.PKIMount = pkiMountRaw.(string)
+		if cert.PKIMount != "" {
+			cert.PKIMount = normalizePKIMount(cert.PKIMount)
+		}
+	}
 	if ocspCertificatesRaw, ok := d.GetOk("ocsp_ca_certificates"); ok {
 		cert.OcspCaCertificates = ocspCertificatesRaw.(string)
 	}
@@ -425,13 +438,23 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 		cert.DisplayName = name
 	}
 
+	if cert.PKIMount != "" {
+		if _, err := b.fetchPKIIssuers(ctx, cert.PKIMount); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to load the issuers of pki_mount: %s", err)), nil
+		}
+	}
+
 	parsed := parsePEM([]byte(cert.Certificate))
-	if len(parsed) == 0 {
+	switch {
+	case len(parsed) == 0 && cert.PKIMount == "":
 		return logical.ErrorResponse("failed to parse certificate"), nil
+	case len(parsed) == 0:
+	case !parsed[0].IsCA && cert.PKIMount != "":
+		return logical.ErrorResponse("pki_mount cannot be used with a non-CA certificate"), nil
 	}
 
 	// If the certificate is not a CA cert, then ensure that x509.ExtKeyUsageClientAuth is set
-	if !parsed[0].IsCA && parsed[0].ExtKeyUsage != nil {
+	if len(parsed) > 0 && !parsed[0].IsCA && parsed[0].ExtKeyUsage != nil {
 		var clientAuth bool
 		for _, usage := range parsed[0].ExtKeyUsage {
 			if usage == x509.ExtKeyUsageClientAuth || usage == x509.ExtKeyUsageAny {
@@ -465,6 +488,7 @@ type CertEntry struct {
 
 	Name                       string
 	Certificate                string
+	PKIMount                   string
 	DisplayName                string
 	Policies                   []string
 	TTL                        time.Duration
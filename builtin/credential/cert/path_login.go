@@ -558,6 +558,13 @@ func (b *backend) loadTrustedCerts(ctx context.Context, storage logical.Storage,
 		}
 
 		parsed := parsePEM([]byte(entry.Certificate))
+		if entry.PKIMount != "" {
+			issuers, err := b.pkiMountIssuers(ctx, entry.PKIMount)
+			if err != nil {
+				b.Logger().Error("failed to load PKI issuers", "name", name, "mount", entry.PKIMount, "error", err)
+			}
+			parsed = append(parsed, issuers...)
+		}
 		if len(parsed) == 0 {
 			b.Logger().Error("failed to parse certificate", "name", name)
 			continue
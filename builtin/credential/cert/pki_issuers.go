@@ -0,0 +1,95 @@
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// pkiIssuersRefreshInterval is how long the issuers fetched from a PKI
+	// mount are trusted before being fetched again, so that rotated issuers
+	// are picked up without updating the certificate entries.
+	pkiIssuersRefreshInterval = 30 * time.Second
+
+	// pkiIssuersMaxStaleness is how long the issuers last fetched from a PKI
+	// mount are still trusted while they cannot be fetched again. Past it,
+	// none of the issuers of the mount are trusted until they are fetched.
+	pkiIssuersMaxStaleness = 5 * time.Minute
+)
+
+// pkiIssuers are the certificates of the issuers of a PKI mount, as fetched
+// at a given time.
+type pkiIssuers struct {
+	certs   []*x509.Certificate
+	fetched time.Time
+}
+
+// normalizePKIMount returns the path of a PKI mount with a single trailing
+// slash.
+func normalizePKIMount(mount string) string {
+	return strings.Trim(mount, "/") + "/"
+}
+
+// pkiMountIssuers returns the certificates of the issuers of the PKI mount at
+// mount. The issuers are cached for pkiIssuersRefreshInterval, and the cached
+// ones are returned if they cannot be fetched again, up to
+// pkiIssuersMaxStaleness after they were fetched.
+func (b *backend) pkiMountIssuers(ctx context.Context, mount string) ([]*x509.Certificate, error) {
+	mount = normalizePKIMount(mount)
+
+	b.pkiIssuersLock.RLock()
+	cached, ok := b.pkiIssuers[mount]
+	b.pkiIssuersLock.RUnlock()
+	if ok && time.Since(cached.fetched) < pkiIssuersRefreshInterval {
+		return cached.certs, nil
+	}
+
+	certs, err := b.fetchPKIIssuers(ctx, mount)
+	if err != nil {
+		if ok && time.Since(cached.fetched) < pkiIssuersMaxStaleness {
+			b.Logger().Warn("failed to refresh PKI issuers, trusting the last ones fetched", "mount", mount, "error", err)
+			return cached.certs, nil
+		}
+		return nil, err
+	}
+
+	b.pkiIssuersLock.Lock()
+	defer b.pkiIssuersLock.Unlock()
+	if b.pkiIssuers == nil {
+		b.pkiIssuers = make(map[string]*pkiIssuers)
+	}
+	b.pkiIssuers[mount] = &pkiIssuers{
+		certs:   certs,
+		fetched: time.Now(),
+	}
+	return certs, nil
+}
+
+// fetchPKIIssuers reads the certificates of the issuers of the PKI mount at
+// mount.
+func (b *backend) fetchPKIIssuers(ctx context.Context, mount string) ([]*x509.Certificate, error) {
+	reader, ok := b.System().(logical.PKIIssuersReader)
+	if !ok {
+		return nil, errors.New("reading the issuers of PKI mounts is not supported by this backend")
+	}
+
+	pems, err := reader.PKIIssuers(ctx, mount)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for _, pem := range pems {
+		certs = append(certs, parsePEM([]byte(pem))...)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no issuers found in %q", mount)
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,222 @@
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	mathrand "math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// testPKIMountSystemView serves the issuers of a PKI mount at "pki/", or err
+// if it is set
+type testPKIMountSystemView struct {
+	logical.StaticSystemView
+	issuers map[string]string
+	err     error
+}
+
+func (s *testPKIMountSystemView) PKIIssuers(_ context.Context, mount string) ([]string, error) {
+	if mount != "pki/" {
+		return nil, fmt.Errorf("no PKI secrets engine is mounted at %q", mount)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	var ids []string
+	for id := range s.issuers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var certificates []string
+	for _, id := range ids {
+		certificates = append(certificates, s.issuers[id])
+	}
+	return certificates, nil
+}
+
+func TestCert_PKIMount(t *testing.T) {
+	certTemplate := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: "example.com",
+		},
+		DNSNames:    []string{"example.com"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement,
+		SerialNumber: big.NewInt(mathrand.Int63()),
+		NotBefore:    time.Now().Add(-30 * time.Second),
+		NotAfter:     time.Now().Add(262980 * time.Hour),
+	}
+	tempDir, connState, err := generateTestCertAndConnState(t, certTemplate)
+	if tempDir != "" {
+		defer os.RemoveAll(tempDir)
+	}
+	if err != nil {
+		t.Fatalf("error testing connection state: %v", err)
+	}
+	ca, err := ioutil.ReadFile(filepath.Join(tempDir, "ca_cert.pem"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	otherCA, err := ioutil.ReadFile("test-fixtures/root/rootcacert.pem")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	sysView := &testPKIMountSystemView{
+		StaticSystemView: logical.StaticSystemView{
+			DefaultLeaseTTLVal: 1000 * time.Second,
+			MaxLeaseTTLVal:     1800 * time.Second,
+		},
+		issuers: map[string]string{
+			"issuer-1": string(ca),
+		},
+	}
+	lb, err := Factory(ctx, &logical.BackendConfig{
+		System:      sysView,
+		StorageView: storage,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	b := lb.(*backend)
+
+	write := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "certs/web",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+	login := func() (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "login",
+			Storage:    storage,
+			Connection: &logical.Connection{ConnState: &connState},
+			Data: map[string]interface{}{
+				"name": "web",
+			},
+		})
+	}
+
+	// The mount must have issuers
+	resp := write(map[string]interface{}{
+		"pki_mount":        "missing",
+		"allowed_dns_sans": "example.com",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for a mount without issuers, got: %#v", resp)
+	}
+
+	// No certificate is needed with a PKI mount
+	if resp := write(map[string]interface{}{
+		"pki_mount":        "/pki/",
+		"allowed_dns_sans": "example.com",
+		"policies":         "foo",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("err: %v", resp.Error())
+	}
+	entry, err := b.Cert(ctx, storage, "web")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry.PKIMount != "pki/" {
+		t.Fatalf("bad pki_mount: %q", entry.PKIMount)
+	}
+
+	resp, err = login()
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("expected a successful login, got: %#v, %v", resp, err)
+	}
+
+	// Logins are verified against the issuers of the mount as they rotate
+	sysView.issuers = map[string]string{
+		"issuer-2": string(otherCA),
+	}
+	b.pkiIssuersLock.Lock()
+	b.pkiIssuers["pki/"].fetched = time.Now().Add(-pkiIssuersRefreshInterval)
+	b.pkiIssuersLock.Unlock()
+
+	resp, err = login()
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected the login to fail after the issuer rotated, got: %#v", resp)
+	}
+
+	sysView.issuers["issuer-3"] = string(ca)
+	b.pkiIssuersLock.Lock()
+	b.pkiIssuers["pki/"].fetched = time.Now().Add(-pkiIssuersRefreshInterval)
+	b.pkiIssuersLock.Unlock()
+
+	resp, err = login()
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("expected a successful login, got: %#v, %v", resp, err)
+	}
+
+	// The last issuers fetched are trusted while the mount cannot be read,
+	// but only for a while
+	sysView.err = errors.New("unavailable")
+	b.pkiIssuersLock.Lock()
+	b.pkiIssuers["pki/"].fetched = time.Now().Add(-pkiIssuersRefreshInterval)
+	b.pkiIssuersLock.Unlock()
+
+	resp, err = login()
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("expected a successful login, got: %#v, %v", resp, err)
+	}
+
+	b.pkiIssuersLock.Lock()
+	b.pkiIssuers["pki/"].fetched = time.Now().Add(-pkiIssuersMaxStaleness)
+	b.pkiIssuersLock.Unlock()
+
+	resp, err = login()
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected the login to fail with stale issuers, got: %#v", resp)
+	}
+}
+
+func TestCert_PKIMount_Unsupported(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	b := testFactory(t)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "certs/web",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"pki_mount": "pki",
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error without support for reading local mounts, got: %#v", resp)
+	}
+}
@@ -0,0 +1,173 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// defaultActionsIssuer is the issuer of the OIDC tokens of GitHub
+	// Actions jobs on github.com.
+	defaultActionsIssuer = "https://token.actions.githubusercontent.com"
+
+	// actionsKeysMaxAge is how long the signing keys of the issuer are
+	// cached for.
+	actionsKeysMaxAge = 10 * time.Minute
+
+	// actionsKeysMinRefresh is how long after fetching the signing keys they
+	// can be fetched again for a token signed by an unknown key.
+	actionsKeysMinRefresh = time.Minute
+)
+
+// actionsClaims are the claims of the OIDC tokens of GitHub Actions jobs used
+// to bind them to roles.
+type actionsClaims struct {
+	jwt.Claims
+
+	Repository        string `json:"repository"`
+	RepositoryOwner   string `json:"repository_owner"`
+	RepositoryOwnerID string `json:"repository_owner_id"`
+	Ref               string `json:"ref"`
+	SHA               string `json:"sha"`
+	Workflow          string `json:"workflow"`
+	Environment       string `json:"environment"`
+	Actor             string `json:"actor"`
+	RunID             string `json:"run_id"`
+}
+
+// actionsKeySet caches the signing keys of the issuer of the OIDC tokens of
+// GitHub Actions jobs.
+type actionsKeySet struct {
+	l         sync.Mutex
+	jwksURL   string
+	keys      *jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// key returns the signing key with the given ID, fetching the keys of the
+// issuer when they are stale or don't include it.
+func (s *actionsKeySet) key(ctx context.Context, jwksURL, kid string) (*jose.JSONWebKey, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	now := time.Now()
+	if s.keys == nil || s.jwksURL != jwksURL || now.Sub(s.fetchedAt) >= actionsKeysMaxAge ||
+		(len(s.keys.Key(kid)) == 0 && now.Sub(s.fetchedAt) >= actionsKeysMinRefresh) {
+		keys, err := fetchActionsKeys(ctx, jwksURL)
+		if err != nil {
+			return nil, err
+		}
+		s.jwksURL = jwksURL
+		s.keys = keys
+		s.fetchedAt = now
+	}
+
+	keys := s.keys.Key(kid)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signing key with ID %q found at %s", kid, jwksURL)
+	}
+	return &keys[0], nil
+}
+
+func fetchActionsKeys(ctx context.Context, jwksURL string) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the signing keys of GitHub Actions: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response code %d fetching the signing keys of GitHub Actions from %s", resp.StatusCode, jwksURL)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return nil, fmt.Errorf("failed to decode the signing keys of GitHub Actions: %w", err)
+	}
+	return &keys, nil
+}
+
+// actionsIssuer returns the issuer of the OIDC tokens of GitHub Actions jobs
+// for the configuration.
+func (c *config) actionsIssuer() string {
+	if c.ActionsIssuer != "" {
+		return strings.TrimSuffix(c.ActionsIssuer, "/")
+	}
+	return defaultActionsIssuer
+}
+
+// verifyActionsToken verifies the OIDC token of a GitHub Actions job and
+// returns its claims if the job runs for a repository of the organization
+// which is allowed to log in with the role.
+func (b *backend) verifyActionsToken(ctx context.Context, config *config, role *roleEntry, token string) (*actionsClaims, error) {
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the GitHub Actions token: %w", err)
+	}
+	if len(parsed.Headers) != 1 {
+		return nil, errors.New("the GitHub Actions token must have a single signature")
+	}
+	header := parsed.Headers[0]
+	if header.Algorithm != string(jose.RS256) {
+		return nil, fmt.Errorf("unexpected signing algorithm %q for a GitHub Actions token", header.Algorithm)
+	}
+
+	issuer := config.actionsIssuer()
+	key, err := b.actionsKeys.key(ctx, issuer+"/.well-known/jwks", header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims actionsClaims
+	if err := parsed.Claims(key, &claims); err != nil {
+		return nil, fmt.Errorf("failed to verify the GitHub Actions token: %w", err)
+	}
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer: issuer,
+		Time:   time.Now(),
+	}, jwt.DefaultLeeway); err != nil {
+		return nil, fmt.Errorf("invalid GitHub Actions token: %w", err)
+	}
+
+	audiences := role.BoundAudiences
+	if len(audiences) == 0 {
+		audiences = []string{"https://github.com/" + config.Organization}
+	}
+	var audienceMatched bool
+	for _, aud := range audiences {
+		if claims.Audience.Contains(aud) {
+			audienceMatched = true
+			break
+		}
+	}
+	if !audienceMatched {
+		return nil, errors.New("the GitHub Actions token was not issued for an audience of the role")
+	}
+
+	if claims.RepositoryOwnerID != strconv.FormatInt(config.OrganizationID, 10) {
+		return nil, errors.New("the GitHub Actions token was not issued to a repository of the required org")
+	}
+	if !strutil.StrListContainsGlob(role.BoundRepositories, claims.Repository) {
+		return nil, fmt.Errorf("repository %q is not allowed to log in with the role", claims.Repository)
+	}
+	if len(role.BoundRefs) > 0 && !strutil.StrListContainsGlob(role.BoundRefs, claims.Ref) {
+		return nil, fmt.Errorf("ref %q is not allowed to log in with the role", claims.Ref)
+	}
+
+	return &claims, nil
+}
@@ -45,7 +45,7 @@ func Backend() *backend {
 			},
 		},
 
-		Paths:       append([]*framework.Path{pathConfig(&b), pathLogin(&b)}, allPaths...),
+		Paths:       append([]*framework.Path{pathConfig(&b), pathLogin(&b), pathRoleList(&b), pathRole(&b)}, allPaths...),
 		AuthRenew:   b.pathLoginRenew,
 		BackendType: logical.TypeCredential,
 	}
@@ -59,6 +59,8 @@ type backend struct {
 	TeamMap *framework.PolicyMap
 
 	UserMap *framework.PolicyMap
+
+	actionsKeys actionsKeySet
 }
 
 // Client returns the GitHub client to communicate to GitHub via the
@@ -97,6 +99,9 @@ provider verifies they're part of the correct organization and then
 maps the user to a set of Vault policies according to the teams they're
 part of.
 
+GitHub Actions jobs and GitHub Apps log in against a role instead, with
+the OIDC token of the job or the installation access token of the app.
+
 After enabling the credential provider, use the "config" route to
 configure it.
 `
@@ -36,6 +36,17 @@ API-compatible authentication server.`,
 					Group: "GitHub Options",
 				},
 			},
+			"actions_issuer": {
+				Type: framework.TypeString,
+				Description: `The issuer of the OIDC tokens of GitHub
+Actions jobs logging in with roles. Defaults to
+"https://token.actions.githubusercontent.com";
+set it when running GitHub Enterprise Server.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "Actions Issuer",
+					Group: "GitHub Options",
+				},
+			},
 			"ttl": {
 				Type:        framework.TypeDurationSecond,
 				Description: tokenutil.DeprecationText("token_ttl"),
@@ -93,6 +104,15 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 		c.BaseURL = baseURL
 	}
 
+	if actionsIssuerRaw, ok := data.GetOk("actions_issuer"); ok {
+		c.ActionsIssuer = actionsIssuerRaw.(string)
+		if c.ActionsIssuer != "" {
+			if _, err := url.Parse(c.ActionsIssuer); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("error parsing given actions_issuer: %s", err)), nil
+			}
+		}
+	}
+
 	if c.OrganizationID == 0 {
 		client, err := b.Client("")
 		if err != nil {
@@ -157,6 +177,7 @@ func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, data
 		"organization_id": config.OrganizationID,
 		"organization":    config.Organization,
 		"base_url":        config.BaseURL,
+		"actions_issuer":  config.ActionsIssuer,
 	}
 	config.PopulateTokenData(d)
 
@@ -205,6 +226,7 @@ type config struct {
 	OrganizationID int64         `json:"organization_id" structs:"organization_id" mapstructure:"organization_id"`
 	Organization   string        `json:"organization" structs:"organization" mapstructure:"organization"`
 	BaseURL        string        `json:"base_url" structs:"base_url" mapstructure:"base_url"`
+	ActionsIssuer  string        `json:"actions_issuer" structs:"actions_issuer" mapstructure:"actions_issuer"`
 	TTL            time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
 	MaxTTL         time.Duration `json:"max_ttl" structs:"max_ttl" mapstructure:"max_ttl"`
 }
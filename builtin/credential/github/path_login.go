@@ -19,7 +19,11 @@ func pathLogin(b *backend) *framework.Path {
 		Fields: map[string]*framework.FieldSchema{
 			"token": {
 				Type:        framework.TypeString,
-				Description: "GitHub personal API token",
+				Description: "GitHub personal API token, or when logging in with a role, the OIDC token of a GitHub Actions job or the installation access token of a GitHub App",
+			},
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role to log in with, for GitHub Actions jobs and GitHub Apps",
 			},
 		},
 
@@ -33,6 +37,20 @@ func pathLogin(b *backend) *framework.Path {
 func (b *backend) pathLoginAliasLookahead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	token := data.Get("token").(string)
 
+	if roleName := data.Get("role").(string); roleName != "" {
+		verifyResp, err := b.verifyRoleCredentials(ctx, req, roleName, token)
+		if err != nil {
+			return nil, err
+		}
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: &logical.Alias{
+					Name: verifyResp.Alias,
+				},
+			},
+		}, nil
+	}
+
 	verifyResp, err := b.verifyCredentials(ctx, req, token)
 	if err != nil {
 		return nil, err
@@ -51,6 +69,10 @@ func (b *backend) pathLoginAliasLookahead(ctx context.Context, req *logical.Requ
 func (b *backend) pathLogin(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	token := data.Get("token").(string)
 
+	if roleName := data.Get("role").(string); roleName != "" {
+		return b.pathLoginRole(ctx, req, roleName, token)
+	}
+
 	verifyResp, err := b.verifyCredentials(ctx, req, token)
 	if err != nil {
 		return nil, err
@@ -93,11 +115,64 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, data *fra
 	return resp, nil
 }
 
+// pathLoginRole logs in a GitHub Actions job or a GitHub App against a
+// role. The role alone determines the token settings.
+func (b *backend) pathLoginRole(ctx context.Context, req *logical.Request, roleName, token string) (*logical.Response, error) {
+	verifyResp, err := b.verifyRoleCredentials(ctx, req, roleName, token)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &logical.Auth{
+		InternalData: map[string]interface{}{
+			"role": verifyResp.Metadata["role"],
+		},
+		Metadata:    verifyResp.Metadata,
+		DisplayName: verifyResp.Alias,
+		Alias: &logical.Alias{
+			Name:     verifyResp.Alias,
+			Metadata: verifyResp.Metadata,
+		},
+	}
+	verifyResp.Role.PopulateTokenAuth(auth)
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+// pathLoginRoleRenew renews tokens created by logging in against a role. The
+// credentials used to log in are short-lived, so renewal only checks that
+// the role still grants the same policies.
+func (b *backend) pathLoginRoleRenew(ctx context.Context, req *logical.Request, roleName string) (*logical.Response, error) {
+	role, err := b.Role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %q no longer exists", roleName)
+	}
+
+	if !policyutil.EquivalentPolicies(role.TokenPolicies, req.Auth.TokenPolicies) {
+		return nil, fmt.Errorf("policies do not match")
+	}
+
+	resp := &logical.Response{Auth: req.Auth}
+	resp.Auth.Period = role.TokenPeriod
+	resp.Auth.TTL = role.TokenTTL
+	resp.Auth.MaxTTL = role.TokenMaxTTL
+	return resp, nil
+}
+
 func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	if req.Auth == nil {
 		return nil, fmt.Errorf("request auth was nil")
 	}
 
+	if roleRaw, ok := req.Auth.InternalData["role"]; ok {
+		return b.pathLoginRoleRenew(ctx, req, roleRaw.(string))
+	}
+
 	tokenRaw, ok := req.Auth.InternalData["token"]
 	if !ok {
 		return nil, fmt.Errorf("token created in previous version of Vault cannot be validated properly at renewal time")
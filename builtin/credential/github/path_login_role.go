@@ -0,0 +1,149 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// verifyRoleResp is the outcome of verifying the credentials of a login
+// against a role.
+type verifyRoleResp struct {
+	Role     *roleEntry
+	Alias    string
+	Metadata map[string]string
+}
+
+// verifyRoleCredentials verifies the OIDC token of a GitHub Actions job or
+// the installation access token of a GitHub App against the role.
+func (b *backend) verifyRoleCredentials(ctx context.Context, req *logical.Request, roleName, token string) (*verifyRoleResp, error) {
+	config, err := b.Config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, errors.New("configuration has not been set")
+	}
+	if config.OrganizationID == 0 {
+		return nil, errors.New("organization_id must be set in the configuration to log in with a role")
+	}
+
+	role, err := b.Role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("invalid role %q", roleName)
+	}
+
+	if len(role.TokenBoundCIDRs) > 0 {
+		if req.Connection == nil {
+			b.Logger().Error("token bound CIDRs found but no connection information available for validation")
+			return nil, logical.ErrPermissionDenied
+		}
+		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, role.TokenBoundCIDRs) {
+			return nil, logical.ErrPermissionDenied
+		}
+	}
+
+	switch role.RoleType {
+	case roleTypeActions:
+		claims, err := b.verifyActionsToken(ctx, config, role, token)
+		if err != nil {
+			return nil, err
+		}
+		return &verifyRoleResp{
+			Role:  role,
+			Alias: claims.Repository,
+			Metadata: map[string]string{
+				"role":        strings.ToLower(roleName),
+				"org":         claims.RepositoryOwner,
+				"repository":  claims.Repository,
+				"ref":         claims.Ref,
+				"sha":         claims.SHA,
+				"workflow":    claims.Workflow,
+				"environment": claims.Environment,
+				"actor":       claims.Actor,
+				"run_id":      claims.RunID,
+			},
+		}, nil
+
+	case roleTypeAppInstallation:
+		repositories, err := b.verifyAppInstallationToken(ctx, config, role, token)
+		if err != nil {
+			return nil, err
+		}
+		return &verifyRoleResp{
+			Role:  role,
+			Alias: strings.ToLower(roleName),
+			Metadata: map[string]string{
+				"role":         strings.ToLower(roleName),
+				"org":          config.Organization,
+				"repositories": strings.Join(repositories, ","),
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("role %q has an invalid role_type %q", roleName, role.RoleType)
+	}
+}
+
+// verifyAppInstallationToken verifies that the installation access token of
+// a GitHub App only gives access to repositories of the organization which
+// are allowed to log in with the role, and returns them.
+func (b *backend) verifyAppInstallationToken(ctx context.Context, config *config, role *roleEntry, token string) ([]string, error) {
+	client, err := b.Client(token)
+	if err != nil {
+		return nil, err
+	}
+	if config.BaseURL != "" {
+		parsedURL, err := url.Parse(config.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("successfully parsed base_url when set but failing to parse now: %w", err)
+		}
+		client.BaseURL = parsedURL
+	}
+
+	// Only installation access tokens can list the repositories of their
+	// installation
+	opt := &github.ListOptions{
+		PerPage: 100,
+	}
+	var allRepos []*github.Repository
+	for {
+		repos, resp, err := client.Apps.ListRepos(ctx, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the repositories of the GitHub App installation: %w", err)
+		}
+		allRepos = append(allRepos, repos...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	if len(allRepos) == 0 {
+		return nil, errors.New("the GitHub App installation token gives access to no repository")
+	}
+
+	repositories := make([]string, 0, len(allRepos))
+	for _, repo := range allRepos {
+		if repo.GetOwner().GetID() != config.OrganizationID {
+			return nil, errors.New("the GitHub App installation token gives access to repositories outside of the required org")
+		}
+		if len(role.BoundRepositories) > 0 && !strutil.StrListContainsGlob(role.BoundRepositories, repo.GetFullName()) {
+			return nil, fmt.Errorf("the GitHub App installation token gives access to repository %q, which is not allowed to log in with the role", repo.GetFullName())
+		}
+		repositories = append(repositories, repo.GetFullName())
+	}
+	sort.Strings(repositories)
+
+	return repositories, nil
+}
@@ -0,0 +1,187 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// setupRoleTestServer returns a test server acting as the issuer of the OIDC
+// tokens of GitHub Actions, signing with key, and as the GitHub API listing
+// the repositories of an App installation.
+func setupRoleTestServer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/.well-known/jwks":
+			json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+				Keys: []jose.JSONWebKey{
+					{Key: &key.PublicKey, KeyID: "key-1", Algorithm: string(jose.RS256), Use: "sig"},
+				},
+			})
+		case strings.Contains(r.URL.Path, "/installation/repositories"):
+			fmt.Fprintln(w, `{"total_count": 2, "repositories": [
+				{"full_name": "foo-org/app", "owner": {"id": 12345}},
+				{"full_name": "foo-org/lib", "owner": {"id": 12345}}
+			]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func signActionsToken(t *testing.T, key *rsa.PrivateKey, issuer string, claims map[string]interface{}) string {
+	t.Helper()
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "key-1"),
+	)
+	require.NoError(t, err)
+
+	now := time.Now()
+	std := jwt.Claims{
+		Issuer:    issuer,
+		Audience:  jwt.Audience{"https://github.com/foo-org"},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Expiry:    jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	}
+	token, err := jwt.Signed(signer).Claims(std).Claims(claims).CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func TestGitHub_LoginRole(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ts := setupRoleTestServer(t, key)
+	defer ts.Close()
+
+	handle := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      path,
+			Data:      data,
+			Storage:   s,
+		})
+	}
+
+	resp, err := handle(logical.UpdateOperation, "config", map[string]interface{}{
+		"organization":    "foo-org",
+		"organization_id": 12345,
+		"base_url":        ts.URL,
+		"actions_issuer":  ts.URL,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	// Roles are validated for their type
+	resp, err = handle(logical.CreateOperation, "role/deploy", map[string]interface{}{
+		"role_type": roleTypeActions,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.IsError())
+	resp, err = handle(logical.CreateOperation, "role/app", map[string]interface{}{
+		"role_type":  roleTypeAppInstallation,
+		"bound_refs": "refs/heads/main",
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.IsError())
+
+	resp, err = handle(logical.CreateOperation, "role/deploy", map[string]interface{}{
+		"role_type":          roleTypeActions,
+		"bound_repositories": "foo-org/app",
+		"bound_refs":         "refs/heads/main,refs/tags/v*",
+		"token_policies":     "deploy",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	claims := map[string]interface{}{
+		"repository":          "foo-org/app",
+		"repository_owner":    "foo-org",
+		"repository_owner_id": "12345",
+		"ref":                 "refs/tags/v1.2.0",
+		"workflow":            "release",
+		"actor":               "user-foo",
+	}
+	resp, err = handle(logical.UpdateOperation, "login", map[string]interface{}{
+		"role":  "deploy",
+		"token": signActionsToken(t, key, ts.URL, claims),
+	})
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	assert.Equal(t, "foo-org/app", resp.Auth.Alias.Name)
+	assert.Equal(t, []string{"deploy"}, resp.Auth.Policies)
+	assert.Equal(t, "refs/tags/v1.2.0", resp.Auth.Metadata["ref"])
+
+	// Tokens of other refs, repositories, orgs or issuers are rejected
+	for name, override := range map[string]map[string]interface{}{
+		"ref":        {"ref": "refs/heads/feature"},
+		"repository": {"repository": "foo-org/other"},
+		"org":        {"repository_owner_id": "98765"},
+	} {
+		bad := make(map[string]interface{})
+		for k, v := range claims {
+			bad[k] = v
+		}
+		for k, v := range override {
+			bad[k] = v
+		}
+		_, err = handle(logical.UpdateOperation, "login", map[string]interface{}{
+			"role":  "deploy",
+			"token": signActionsToken(t, key, ts.URL, bad),
+		})
+		assert.Error(t, err, name)
+	}
+	_, err = handle(logical.UpdateOperation, "login", map[string]interface{}{
+		"role":  "deploy",
+		"token": signActionsToken(t, key, "https://token.actions.example.com", claims),
+	})
+	assert.Error(t, err)
+
+	// App installation tokens must only give access to bound repositories
+	resp, err = handle(logical.CreateOperation, "role/app", map[string]interface{}{
+		"role_type":          roleTypeAppInstallation,
+		"bound_repositories": "foo-org/app",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+	_, err = handle(logical.UpdateOperation, "login", map[string]interface{}{
+		"role":  "app",
+		"token": "ghs_installation",
+	})
+	assert.Error(t, err)
+
+	resp, err = handle(logical.UpdateOperation, "role/app", map[string]interface{}{
+		"bound_repositories": "foo-org/*",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+	resp, err = handle(logical.UpdateOperation, "login", map[string]interface{}{
+		"role":  "app",
+		"token": "ghs_installation",
+	})
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	assert.Equal(t, "app", resp.Auth.Alias.Name)
+	assert.Equal(t, "foo-org/app,foo-org/lib", resp.Auth.Metadata["repositories"])
+}
@@ -0,0 +1,215 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/tokenutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// roleTypeActions roles log in GitHub Actions workflows with the OIDC
+	// tokens GitHub issues to their jobs.
+	roleTypeActions = "actions"
+
+	// roleTypeAppInstallation roles log in with the installation access
+	// tokens of GitHub Apps installed on the organization.
+	roleTypeAppInstallation = "app_installation"
+)
+
+func pathRoleList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	p := &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"role_type": {
+				Type:        framework.TypeString,
+				Description: `Type of the credentials the role logs in: "actions" for the OIDC tokens of GitHub Actions jobs, or "app_installation" for the installation access tokens of GitHub Apps.`,
+			},
+			"bound_repositories": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Repositories, as "owner/name", allowed to log in with the role. Required for "actions" roles; for "app_installation" roles, every repository the token can access must match. Supports globs at the start or end.`,
+			},
+			"bound_refs": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Git refs, such as "refs/heads/main", the workflows logging in with an "actions" role must run for. Supports globs at the start or end. Defaults to any ref.`,
+			},
+			"bound_audiences": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Audiences the OIDC tokens of an "actions" role must be issued for, one of which must match. Defaults to the default audience of GitHub, the URL of the organization.`,
+			},
+		},
+
+		ExistenceCheck: b.pathRoleExistenceCheck,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+
+	tokenutil.AddTokenFields(p.Fields)
+	return p
+}
+
+type roleEntry struct {
+	tokenutil.TokenParams
+
+	RoleType          string   `json:"role_type"`
+	BoundRepositories []string `json:"bound_repositories"`
+	BoundRefs         []string `json:"bound_refs"`
+	BoundAudiences    []string `json:"bound_audiences"`
+}
+
+// Role returns the role with the given name, or nil if there is none.
+func (b *backend) Role(ctx context.Context, s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get(ctx, "role/"+strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role roleEntry
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, fmt.Errorf("error reading role: %w", err)
+	}
+	return &role, nil
+}
+
+func (b *backend) pathRoleExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	role, err := b.Role(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := b.Role(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	d := map[string]interface{}{
+		"role_type":          role.RoleType,
+		"bound_repositories": role.BoundRepositories,
+		"bound_refs":         role.BoundRefs,
+		"bound_audiences":    role.BoundAudiences,
+	}
+	role.PopulateTokenData(d)
+
+	return &logical.Response{
+		Data: d,
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(data.Get("name").(string))
+	role, err := b.Role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{}
+	}
+
+	if roleTypeRaw, ok := data.GetOk("role_type"); ok {
+		role.RoleType = roleTypeRaw.(string)
+	}
+	if boundRepositoriesRaw, ok := data.GetOk("bound_repositories"); ok {
+		role.BoundRepositories = boundRepositoriesRaw.([]string)
+	}
+	if boundRefsRaw, ok := data.GetOk("bound_refs"); ok {
+		role.BoundRefs = boundRefsRaw.([]string)
+	}
+	if boundAudiencesRaw, ok := data.GetOk("bound_audiences"); ok {
+		role.BoundAudiences = boundAudiencesRaw.([]string)
+	}
+
+	switch role.RoleType {
+	case roleTypeActions:
+		if len(role.BoundRepositories) == 0 {
+			return logical.ErrorResponse(`"actions" roles must set bound_repositories`), nil
+		}
+	case roleTypeAppInstallation:
+		if len(role.BoundRefs) > 0 || len(role.BoundAudiences) > 0 {
+			return logical.ErrorResponse(`bound_refs and bound_audiences only apply to "actions" roles`), nil
+		}
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("role_type must be %q or %q", roleTypeActions, roleTypeAppInstallation)), nil
+	}
+
+	if err := role.ParseTokenFields(req, data); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, "role/"+strings.ToLower(data.Get("name").(string))); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+const pathRoleHelpSyn = `
+Manage the roles logging in GitHub Actions workflows and GitHub Apps.
+`
+
+const pathRoleHelpDesc = `
+Roles let machine credentials log in, instead of the personal access tokens
+of organization members. "actions" roles accept the OIDC tokens GitHub issues
+to the jobs of Actions workflows, bound to repositories and refs through the
+claims of the tokens. "app_installation" roles accept the installation access
+tokens of GitHub Apps installed on the organization, bound to the
+repositories the tokens can access.
+
+Logins against a role get the token settings of the role, rather than the
+policies mapped to teams and users.
+`
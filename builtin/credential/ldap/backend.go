@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/vault/sdk/framework"
@@ -43,17 +44,23 @@ func Backend() *backend {
 			pathUsers(&b),
 			pathUsersList(&b),
 			pathLogin(&b),
+			pathGroupCache(&b),
 		},
 
-		AuthRenew:   b.pathLoginRenew,
-		BackendType: logical.TypeCredential,
+		AuthRenew:    b.pathLoginRenew,
+		Invalidate:   b.invalidate,
+		PeriodicFunc: b.refreshGroupCache,
+		BackendType:  logical.TypeCredential,
 	}
 
+	b.groupCache = newGroupCache()
 	return &b
 }
 
 type backend struct {
 	*framework.Backend
+
+	groupCache *groupCache
 }
 
 func (b *backend) Login(ctx context.Context, req *logical.Request, username string, password string, usernameAsAlias bool) (string, []string, *logical.Response, []string, error) {
@@ -129,20 +136,32 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		return "", nil, logical.ErrorResponse(err.Error()), nil, nil
 	}
 
-	if cfg.AnonymousGroupSearch {
-		c, err = ldapClient.DialLDAP(cfg.ConfigEntry)
-		if err != nil {
-			return "", nil, logical.ErrorResponse("ldap operation failed: failed to connect to LDAP server"), nil, nil
-		}
-		defer c.Close() // Defer closing of this connection as the deferal above closes the other defined connection
+	canonicalUsername := username
+	cs := *cfg.CaseSensitiveNames
+	if !cs {
+		canonicalUsername = strings.ToLower(username)
 	}
 
-	ldapGroups, err := ldapClient.GetLdapGroups(cfg.ConfigEntry, c, userDN, username)
-	if err != nil {
-		return "", nil, logical.ErrorResponse(err.Error()), nil, nil
+	ldapGroups, cached := b.groupCache.get(canonicalUsername, userDN, cfg.GroupCacheTTL, time.Now())
+	if !cached {
+		if cfg.AnonymousGroupSearch {
+			c, err = ldapClient.DialLDAP(cfg.ConfigEntry)
+			if err != nil {
+				return "", nil, logical.ErrorResponse("ldap operation failed: failed to connect to LDAP server"), nil, nil
+			}
+			defer c.Close() // Defer closing of this connection as the deferal above closes the other defined connection
+		}
+
+		ldapGroups, err = ldapClient.GetLdapGroups(cfg.ConfigEntry, c, userDN, username)
+		if err != nil {
+			return "", nil, logical.ErrorResponse(err.Error()), nil, nil
+		}
+		if cfg.GroupCacheTTL > 0 {
+			b.groupCache.put(canonicalUsername, username, userDN, ldapGroups, time.Now())
+		}
 	}
 	if b.Logger().IsDebug() {
-		b.Logger().Debug("groups fetched from server", "num_server_groups", len(ldapGroups), "server_groups", ldapGroups)
+		b.Logger().Debug("groups fetched from server", "num_server_groups", len(ldapGroups), "server_groups", ldapGroups, "cached", cached)
 	}
 
 	ldapResponse := &logical.Response{
@@ -156,11 +175,6 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 	}
 
 	var allGroups []string
-	canonicalUsername := username
-	cs := *cfg.CaseSensitiveNames
-	if !cs {
-		canonicalUsername = strings.ToLower(username)
-	}
 	// Import the custom added groups from ldap backend
 	user, err := b.User(ctx, req.Storage, canonicalUsername)
 	if err == nil && user != nil && user.Groups != nil {
@@ -0,0 +1,177 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/helper/ldaputil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// groupCacheFlushPrefix is the storage prefix recording the last flush of
+// the cached groups of a user, so that the flush reaches every node through
+// invalidation.
+const groupCacheFlushPrefix = "group-cache-flush/"
+
+// groupCacheEntry holds the LDAP groups resolved for a user at login.
+type groupCacheEntry struct {
+	username  string
+	userDN    string
+	groups    []string
+	fetchedAt time.Time
+
+	// used is whether a login used the groups since they were fetched, in
+	// which case they are refreshed in the background before they expire.
+	used bool
+}
+
+// groupCache caches the LDAP groups of users by their canonical username,
+// sparing logins the group search, which can take seconds when it resolves
+// nested groups of large directories.
+type groupCache struct {
+	l       sync.Mutex
+	entries map[string]*groupCacheEntry
+}
+
+func newGroupCache() *groupCache {
+	return &groupCache{
+		entries: make(map[string]*groupCacheEntry),
+	}
+}
+
+// get returns the cached groups of the user, if they were resolved for the
+// same DN less than ttl ago.
+func (c *groupCache) get(key, userDN string, ttl time.Duration, now time.Time) ([]string, bool) {
+	if ttl <= 0 {
+		return nil, false
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.userDN != userDN || now.Sub(entry.fetchedAt) >= ttl {
+		return nil, false
+	}
+	entry.used = true
+	return entry.groups, true
+}
+
+func (c *groupCache) put(key, username, userDN string, groups []string, now time.Time) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.entries[key] = &groupCacheEntry{
+		username:  username,
+		userDN:    userDN,
+		groups:    groups,
+		fetchedAt: now,
+	}
+}
+
+// refreshed replaces the groups of a refreshed entry, unless the entry was
+// invalidated or replaced by a login during the refresh.
+func (c *groupCache) refreshed(key string, stale *groupCacheEntry, groups []string, now time.Time) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !entry.fetchedAt.Equal(stale.fetchedAt) {
+		return
+	}
+	c.entries[key] = &groupCacheEntry{
+		username:  stale.username,
+		userDN:    stale.userDN,
+		groups:    groups,
+		fetchedAt: now,
+	}
+}
+
+// stale drops the expired entries and returns copies of the ones used
+// since they were fetched which are past half their TTL.
+func (c *groupCache) stale(ttl time.Duration, now time.Time) map[string]groupCacheEntry {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	stale := make(map[string]groupCacheEntry)
+	for key, entry := range c.entries {
+		age := now.Sub(entry.fetchedAt)
+		switch {
+		case age >= ttl:
+			delete(c.entries, key)
+		case entry.used && age >= ttl/2:
+			stale[key] = *entry
+		}
+	}
+	return stale
+}
+
+func (c *groupCache) invalidate(key string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	delete(c.entries, key)
+}
+
+func (c *groupCache) flush() {
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.entries = make(map[string]*groupCacheEntry)
+}
+
+// refreshGroupCache resolves again the groups of the users who logged in
+// since their groups were cached, before they expire. Refreshing searches
+// as the bind DN, so without one the cached groups just expire.
+func (b *backend) refreshGroupCache(ctx context.Context, req *logical.Request) error {
+	cfg, err := b.Config(ctx, req)
+	if err != nil {
+		return err
+	}
+	if cfg == nil || cfg.GroupCacheTTL <= 0 {
+		b.groupCache.flush()
+		return nil
+	}
+
+	now := time.Now()
+	stale := b.groupCache.stale(cfg.GroupCacheTTL, now)
+	if len(stale) == 0 {
+		return nil
+	}
+	if !cfg.AnonymousGroupSearch && (cfg.BindDN == "" || cfg.BindPassword == "") {
+		return nil
+	}
+
+	ldapClient := ldaputil.Client{
+		Logger: b.Logger(),
+		LDAP:   ldaputil.NewLDAP(),
+	}
+	c, err := ldapClient.DialLDAP(cfg.ConfigEntry)
+	if err != nil {
+		return fmt.Errorf("failed to connect to LDAP server to refresh cached groups: %w", err)
+	}
+	if c == nil {
+		return fmt.Errorf("invalid connection returned from LDAP dial")
+	}
+	defer c.Close()
+
+	if !cfg.AnonymousGroupSearch {
+		if err := c.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return fmt.Errorf("failed to bind with the BindDN user to refresh cached groups: %w", err)
+		}
+	}
+
+	var errs *multierror.Error
+	for key, entry := range stale {
+		groups, err := ldapClient.GetLdapGroups(cfg.ConfigEntry, c, entry.userDN, entry.username)
+		if err != nil {
+			// Logins resolve the groups themselves until the next refresh
+			b.groupCache.invalidate(key)
+			errs = multierror.Append(errs, fmt.Errorf("failed to refresh the cached groups of %q: %w", entry.username, err))
+			continue
+		}
+		b.groupCache.refreshed(key, &entry, groups, time.Now())
+	}
+	return errs.ErrorOrNil()
+}
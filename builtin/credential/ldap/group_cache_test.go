@@ -0,0 +1,70 @@
+package ldap
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGroupCache(t *testing.T) {
+	c := newGroupCache()
+	now := time.Now()
+	ttl := time.Hour
+
+	if _, ok := c.get("alice", "cn=alice", ttl, now); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+
+	c.put("alice", "Alice", "cn=alice", []string{"dev"}, now)
+	c.put("bob", "bob", "cn=bob", []string{"ops"}, now)
+
+	// Caching is disabled by a zero TTL, and only applies to the same DN
+	if _, ok := c.get("alice", "cn=alice", 0, now); ok {
+		t.Fatal("expected a miss with caching disabled")
+	}
+	if _, ok := c.get("alice", "cn=alice,ou=moved", ttl, now); ok {
+		t.Fatal("expected a miss for another DN")
+	}
+	groups, ok := c.get("alice", "cn=alice", ttl, now.Add(10*time.Minute))
+	if !ok || !reflect.DeepEqual(groups, []string{"dev"}) {
+		t.Fatalf("bad groups: %v, %t", groups, ok)
+	}
+	if _, ok := c.get("alice", "cn=alice", ttl, now.Add(ttl)); ok {
+		t.Fatal("expected a miss on an expired entry")
+	}
+
+	// Only the entries used since they were fetched are refreshed, and the
+	// expired ones are dropped
+	if stale := c.stale(ttl, now.Add(10*time.Minute)); len(stale) != 0 {
+		t.Fatalf("expected no stale entries before half the TTL, got %v", stale)
+	}
+	stale := c.stale(ttl, now.Add(40*time.Minute))
+	if len(stale) != 1 || stale["alice"].username != "Alice" {
+		t.Fatalf("bad stale entries: %v", stale)
+	}
+	entry := stale["alice"]
+	c.refreshed("alice", &entry, []string{"dev", "admins"}, now.Add(41*time.Minute))
+	if stale := c.stale(ttl, now.Add(61*time.Minute)); len(stale) != 0 {
+		t.Fatalf("expected no stale entries after the refresh, got %v", stale)
+	}
+	if _, ok := c.get("bob", "cn=bob", 2*ttl, now); ok {
+		t.Fatal("expected the expired entry to be dropped")
+	}
+	groups, ok = c.get("alice", "cn=alice", ttl, now.Add(90*time.Minute))
+	if !ok || !reflect.DeepEqual(groups, []string{"dev", "admins"}) {
+		t.Fatalf("bad groups: %v, %t", groups, ok)
+	}
+
+	// A refresh racing with an invalidation doesn't bring the entry back
+	c.invalidate("alice")
+	c.refreshed("alice", &entry, []string{"dev"}, now.Add(91*time.Minute))
+	if _, ok := c.get("alice", "cn=alice", ttl, now.Add(91*time.Minute)); ok {
+		t.Fatal("expected a miss after invalidation")
+	}
+
+	c.put("alice", "Alice", "cn=alice", []string{"dev"}, now)
+	c.flush()
+	if _, ok := c.get("alice", "cn=alice", ttl, now); ok {
+		t.Fatal("expected a miss after a flush")
+	}
+}
@@ -3,6 +3,7 @@ package ldap
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -30,6 +31,14 @@ func pathConfig(b *backend) *framework.Path {
 		},
 	}
 
+	p.Fields["group_cache_ttl"] = &framework.FieldSchema{
+		Type:        framework.TypeDurationSecond,
+		Description: "How long the LDAP groups of a user are cached for after they log in. Groups of users logging in again are refreshed in the background before they expire. Defaults to 0, which disables caching.",
+		DisplayAttrs: &framework.DisplayAttributes{
+			Name: "Group Cache TTL",
+		},
+	}
+
 	tokenutil.AddTokenFields(p.Fields)
 	p.Fields["token_policies"].Description += ". This will apply to all tokens generated by this auth method, in addition to any configured for specific users/groups."
 	return p
@@ -112,6 +121,7 @@ func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *f
 
 	data := cfg.PasswordlessMap()
 	cfg.PopulateTokenData(data)
+	data["group_cache_ttl"] = int64(cfg.GroupCacheTTL.Seconds())
 
 	resp := &logical.Response{
 		Data: data,
@@ -178,6 +188,13 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
+	if groupCacheTTLRaw, ok := d.GetOk("group_cache_ttl"); ok {
+		cfg.GroupCacheTTL = time.Duration(groupCacheTTLRaw.(int)) * time.Second
+		if cfg.GroupCacheTTL < 0 {
+			return logical.ErrorResponse("group_cache_ttl cannot be negative"), logical.ErrInvalidRequest
+		}
+	}
+
 	entry, err := logical.StorageEntryJSON("config", cfg)
 	if err != nil {
 		return nil, err
@@ -186,6 +203,9 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		return nil, err
 	}
 
+	// The groups may resolve differently with the new configuration
+	b.groupCache.flush()
+
 	if warnings := b.checkConfigUserFilter(cfg); len(warnings) > 0 {
 		return &logical.Response{
 			Warnings: warnings,
@@ -218,6 +238,8 @@ func (b *backend) getConfigFieldData() (*framework.FieldData, error) {
 type ldapConfigEntry struct {
 	tokenutil.TokenParams
 	*ldaputil.ConfigEntry
+
+	GroupCacheTTL time.Duration `json:"group_cache_ttl"`
 }
 
 const pathConfigHelpSyn = `
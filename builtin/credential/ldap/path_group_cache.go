@@ -0,0 +1,73 @@
+package ldap
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathGroupCache(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `group-cache/(?P<username>.+)`,
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Name of the LDAP user whose cached groups to flush.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.DeleteOperation: b.pathGroupCacheDelete,
+		},
+
+		HelpSynopsis:    pathGroupCacheHelpSyn,
+		HelpDescription: pathGroupCacheHelpDesc,
+	}
+}
+
+func (b *backend) pathGroupCacheDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	cfg, err := b.Config(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := d.Get("username").(string)
+	if cfg != nil && !*cfg.CaseSensitiveNames {
+		key = strings.ToLower(key)
+	}
+
+	// Other nodes drop the cached groups when the flush is invalidated
+	entry, err := logical.StorageEntryJSON(groupCacheFlushPrefix+key, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	b.groupCache.invalidate(key)
+
+	return nil, nil
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	switch {
+	case key == "config":
+		b.groupCache.flush()
+	case strings.HasPrefix(key, groupCacheFlushPrefix):
+		b.groupCache.invalidate(strings.TrimPrefix(key, groupCacheFlushPrefix))
+	}
+}
+
+const pathGroupCacheHelpSyn = `
+Flush the cached LDAP groups of a user.
+`
+
+const pathGroupCacheHelpDesc = `
+When "group_cache_ttl" is configured, the LDAP groups of users are cached
+for that long after they log in. Deleting "group-cache/<username>" drops the
+cached groups of the user on every node, so that their next login resolves
+them from the LDAP server, for instance after removing them from a group.
+`
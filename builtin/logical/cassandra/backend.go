@@ -22,7 +22,9 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 
 // Backend contains the base information for the backend's functionality
 func Backend() *backend {
-	var b backend
+	b := backend{
+		status: newCredsStatus(),
+	}
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(backendHelp),
 
@@ -36,6 +38,7 @@ func Backend() *backend {
 			pathConfigConnection(&b),
 			pathRoles(&b),
 			pathCredsCreate(&b),
+			pathStatus(&b),
 		},
 
 		Secrets: []*framework.Secret{
@@ -61,6 +64,9 @@ type backend struct {
 	// can close it and use a new connection; hence the lock
 	session *gocql.Session
 	lock    sync.Mutex
+
+	// Failures of the credential lifecycle, surfaced by the status path
+	status *credsStatus
 }
 
 type sessionConfig struct {
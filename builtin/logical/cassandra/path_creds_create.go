@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/gocql/gocql"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	uuid "github.com/hashicorp/go-uuid"
@@ -56,9 +57,12 @@ func (b *backend) pathCredsCreateRead(ctx context.Context, req *logical.Request,
 		return nil, err
 	}
 
+	defer metrics.MeasureSinceWithLabels([]string{"secrets", "cassandra", "creds", "create"}, time.Now(), credsMetricsLabels(ctx, name))
+
 	// Get our connection
 	session, err := b.DB(ctx, req.Storage)
 	if err != nil {
+		b.status.recordFailure(ctx, name, credsOperationCreate, statementConnection, err)
 		return nil, err
 	}
 
@@ -73,7 +77,7 @@ func (b *backend) pathCredsCreateRead(ctx context.Context, req *logical.Request,
 	}
 
 	// Execute each query
-	for _, query := range strutil.ParseArbitraryStringSlice(role.CreationCQL, ";") {
+	for i, query := range strutil.ParseArbitraryStringSlice(role.CreationCQL, ";") {
 		query = strings.TrimSpace(query)
 		if len(query) == 0 {
 			continue
@@ -84,6 +88,7 @@ func (b *backend) pathCredsCreateRead(ctx context.Context, req *logical.Request,
 			"password": password,
 		})).Exec()
 		if err != nil {
+			b.status.recordFailure(ctx, name, credsOperationCreate, creationStatement(i), err)
 			for _, query := range strutil.ParseArbitraryStringSlice(role.RollbackCQL, ";") {
 				query = strings.TrimSpace(query)
 				if len(query) == 0 {
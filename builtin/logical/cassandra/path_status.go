@@ -0,0 +1,168 @@
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	credsOperationCreate = "create"
+	credsOperationRevoke = "revoke"
+
+	// Statement label of failures which happen before any CQL is run, such
+	// as failing to connect.
+	statementConnection = "connection"
+
+	// Statement label of the statement dropping revoked users.
+	statementDropUser = "drop_user"
+)
+
+// credsError is the last failure to create or revoke credentials of a role.
+type credsError struct {
+	Time      time.Time
+	Operation string
+	Statement string
+	Error     string
+}
+
+// credsStatus tracks the failures of the credential lifecycle since this
+// node started, per role.
+type credsStatus struct {
+	lock       sync.Mutex
+	lastErrors map[string]*credsError
+	// Usernames whose revocation failed and will be retried, per role.
+	pendingRevocations map[string]map[string]struct{}
+}
+
+func newCredsStatus() *credsStatus {
+	return &credsStatus{
+		lastErrors:         make(map[string]*credsError),
+		pendingRevocations: make(map[string]map[string]struct{}),
+	}
+}
+
+func credsMetricsLabels(ctx context.Context, role string) []metrics.Label {
+	labels := []metrics.Label{{"role", role}}
+	if ns, err := namespace.FromContext(ctx); err == nil {
+		labels = append(labels, metricsutil.NamespaceLabel(ns))
+	}
+	return labels
+}
+
+// recordFailure counts a failure of operation on the given statement and
+// keeps it as the last error of the role.
+func (s *credsStatus) recordFailure(ctx context.Context, role, operation, statement string, err error) {
+	labels := append(credsMetricsLabels(ctx, role), metrics.Label{"statement", statement})
+	metrics.IncrCounterWithLabels([]string{"secrets", "cassandra", "creds", operation, "failure"}, 1, labels)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastErrors[role] = &credsError{
+		Time:      time.Now(),
+		Operation: operation,
+		Statement: statement,
+		Error:     err.Error(),
+	}
+}
+
+// recordRevocation updates the revocation backlog of the role with the
+// outcome of revoking username.
+func (s *credsStatus) recordRevocation(ctx context.Context, role, username string, failed bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pending := s.pendingRevocations[role]
+	if failed {
+		if pending == nil {
+			pending = make(map[string]struct{})
+			s.pendingRevocations[role] = pending
+		}
+		pending[username] = struct{}{}
+	} else {
+		delete(pending, username)
+		if len(pending) == 0 {
+			delete(s.pendingRevocations, role)
+		}
+	}
+
+	metrics.SetGaugeWithLabels([]string{"secrets", "cassandra", "creds", "revocation_backlog"}, float32(len(pending)), credsMetricsLabels(ctx, role))
+}
+
+func (s *credsStatus) responseData() map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	roleNames := make(map[string]struct{})
+	for role := range s.lastErrors {
+		roleNames[role] = struct{}{}
+	}
+	for role := range s.pendingRevocations {
+		roleNames[role] = struct{}{}
+	}
+
+	roles := make(map[string]interface{}, len(roleNames))
+	backlog := 0
+	for role := range roleNames {
+		roleData := map[string]interface{}{
+			"revocation_backlog": len(s.pendingRevocations[role]),
+		}
+		backlog += len(s.pendingRevocations[role])
+		if lastError, ok := s.lastErrors[role]; ok {
+			roleData["last_error"] = map[string]interface{}{
+				"time":      lastError.Time.Format(time.RFC3339Nano),
+				"operation": lastError.Operation,
+				"statement": lastError.Statement,
+				"error":     lastError.Error,
+			}
+		}
+		roles[role] = roleData
+	}
+
+	return map[string]interface{}{
+		"roles":              roles,
+		"revocation_backlog": backlog,
+	}
+}
+
+// creationStatement labels the i-th statement of the creation CQL.
+func creationStatement(i int) string {
+	return fmt.Sprintf("creation_cql.%d", i)
+}
+
+func pathStatus(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "status",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathStatusRead,
+		},
+
+		HelpSynopsis:    pathStatusHelpSyn,
+		HelpDescription: pathStatusHelpDesc,
+	}
+}
+
+func (b *backend) pathStatusRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: b.status.responseData(),
+	}, nil
+}
+
+const pathStatusHelpSyn = `
+Read the last errors and revocation backlog of each role.
+`
+
+const pathStatusHelpDesc = `
+This path returns, for each role, the last failure to create or revoke
+credentials with the CQL statement which failed, and the number of
+credentials whose revocation failed and is being retried. The status is
+kept in memory by the node serving the request since it started.
+`
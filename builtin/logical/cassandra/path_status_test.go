@@ -0,0 +1,97 @@
+package cassandra
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestBackend_status(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	readStatus := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "status",
+			Storage:   config.StorageView,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+		}
+		return resp.Data
+	}
+
+	data := readStatus()
+	if len(data["roles"].(map[string]interface{})) != 0 || data["revocation_backlog"] != 0 {
+		t.Fatalf("expected empty status, got: %#v", data)
+	}
+
+	_, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"creation_cql": testRole,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a connection, creating credentials fails before any statement
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/test",
+		Storage:   config.StorageView,
+	})
+	if err == nil {
+		t.Fatal("expected an error creating credentials without a connection")
+	}
+
+	// ...and so does revoking them
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   config.StorageView,
+		Secret: &logical.Secret{
+			InternalData: map[string]interface{}{
+				"secret_type": SecretCredsType,
+				"username":    "vault_test_user",
+				"role":        "test",
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error revoking credentials without a connection")
+	}
+
+	data = readStatus()
+	if data["revocation_backlog"] != 1 {
+		t.Fatalf("expected a revocation backlog of 1, got: %#v", data["revocation_backlog"])
+	}
+	role, ok := data["roles"].(map[string]interface{})["test"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("missing status of role test: %#v", data)
+	}
+	if role["revocation_backlog"] != 1 {
+		t.Fatalf("expected a revocation backlog of 1 for role test, got: %#v", role["revocation_backlog"])
+	}
+	lastError := role["last_error"].(map[string]interface{})
+	if lastError["operation"] != credsOperationRevoke || lastError["statement"] != statementConnection || lastError["error"] == "" {
+		t.Fatalf("bad last error: %#v", lastError)
+	}
+
+	// A successful revocation clears the backlog
+	b.status.recordRevocation(context.Background(), "test", "vault_test_user", false)
+	data = readStatus()
+	role = data["roles"].(map[string]interface{})["test"].(map[string]interface{})
+	if data["revocation_backlog"] != 0 || role["revocation_backlog"] != 0 {
+		t.Fatalf("expected the revocation backlog to be cleared, got: %#v", data)
+	}
+}
@@ -3,7 +3,9 @@ package cassandra
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -63,15 +65,25 @@ func (b *backend) secretCredsRevoke(ctx context.Context, req *logical.Request, d
 		return nil, fmt.Errorf("error converting username internal data to string")
 	}
 
+	// Secrets issued before the role was recorded have no role to tag
+	// their metrics with
+	roleName, _ := req.Secret.InternalData["role"].(string)
+	defer metrics.MeasureSinceWithLabels([]string{"secrets", "cassandra", "creds", "revoke"}, time.Now(), credsMetricsLabels(ctx, roleName))
+
 	session, err := b.DB(ctx, req.Storage)
 	if err != nil {
+		b.status.recordFailure(ctx, roleName, credsOperationRevoke, statementConnection, err)
+		b.status.recordRevocation(ctx, roleName, username, true)
 		return nil, fmt.Errorf("error getting session")
 	}
 
 	err = session.Query(fmt.Sprintf("DROP USER '%s'", username)).Exec()
 	if err != nil {
+		b.status.recordFailure(ctx, roleName, credsOperationRevoke, statementDropUser, err)
+		b.status.recordRevocation(ctx, roleName, username, true)
 		return nil, fmt.Errorf("error removing user %q", username)
 	}
 
+	b.status.recordRevocation(ctx, roleName, username, false)
 	return nil, nil
 }
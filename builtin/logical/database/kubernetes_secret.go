@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+
+	log "github.com/hashicorp/go-hclog"
+	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/vault/secretsync"
+)
+
+// Keys of the internal data of leases whose credentials were pushed to a
+// Kubernetes Secret
+const (
+	kubernetesSecretNameInternalKey      = "kubernetes_secret_name"
+	kubernetesSecretNamespaceInternalKey = "kubernetes_secret_namespace"
+)
+
+// kubernetesSecret is the Kubernetes Secret that the credentials of a role
+// are pushed to, so that applications which can only read Kubernetes Secrets
+// can consume them. The credentials of each lease of a role are pushed to
+// their own Secret, named after the one of the role and the database user of
+// the lease.
+type kubernetesSecret struct {
+	Name              string `json:"name"`
+	Namespace         string `json:"namespace"`
+	Host              string `json:"host,omitempty"`
+	CACert            string `json:"ca_cert,omitempty"`
+	ServiceAccountJWT string `json:"service_account_jwt,omitempty"`
+}
+
+// kubernetesSecretFields returns the fields configuring the Kubernetes
+// Secret of a role.
+func kubernetesSecretFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"kubernetes_secret_name": {
+			Type: framework.TypeString,
+			Description: `Name of the Kubernetes Secret that the credentials
+	of the role are pushed to. The credentials of each lease are pushed to a
+	Secret named after it and the database user. Set to an empty string to
+	stop pushing credentials.`,
+		},
+		"kubernetes_secret_namespace": {
+			Type:        framework.TypeString,
+			Description: `Kubernetes namespace of the Secret. Defaults to "default".`,
+		},
+		"kubernetes_host": {
+			Type: framework.TypeString,
+			Description: `URL of the Kubernetes API server. Defaults to the
+	API server of the cluster Vault is running in.`,
+		},
+		"kubernetes_ca_cert": {
+			Type: framework.TypeString,
+			Description: `PEM encoded CA certificate of the Kubernetes API
+	server. Defaults to the CA certificate of the service account of Vault
+	when running in a cluster.`,
+		},
+		"kubernetes_service_account_jwt": {
+			Type: framework.TypeString,
+			Description: `JWT of a service account allowed to create,
+	update and delete the Secrets. Defaults to the token of the service account of Vault
+	when running in a cluster.`,
+			DisplayAttrs: &framework.DisplayAttributes{
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+// setKubernetesSecret updates the Kubernetes Secret of the role from the
+// request data.
+func (r *roleEntry) setKubernetesSecret(data *framework.FieldData) error {
+	if raw, ok := data.GetOk("kubernetes_secret_name"); ok {
+		name := raw.(string)
+		if name == "" {
+			r.KubernetesSecret = nil
+			return nil
+		}
+		if r.KubernetesSecret == nil {
+			r.KubernetesSecret = &kubernetesSecret{}
+		}
+		r.KubernetesSecret.Name = name
+	}
+
+	if r.KubernetesSecret == nil {
+		for _, field := range []string{"kubernetes_secret_namespace", "kubernetes_host", "kubernetes_ca_cert", "kubernetes_service_account_jwt"} {
+			if _, ok := data.GetOk(field); ok {
+				return fmt.Errorf("%q requires kubernetes_secret_name to be set", field)
+			}
+		}
+		return nil
+	}
+
+	if raw, ok := data.GetOk("kubernetes_secret_namespace"); ok {
+		r.KubernetesSecret.Namespace = raw.(string)
+	}
+	if r.KubernetesSecret.Namespace == "" {
+		r.KubernetesSecret.Namespace = "default"
+	}
+	if raw, ok := data.GetOk("kubernetes_host"); ok {
+		r.KubernetesSecret.Host = raw.(string)
+	}
+	if raw, ok := data.GetOk("kubernetes_ca_cert"); ok {
+		r.KubernetesSecret.CACert = raw.(string)
+	}
+	if raw, ok := data.GetOk("kubernetes_service_account_jwt"); ok {
+		r.KubernetesSecret.ServiceAccountJWT = raw.(string)
+	}
+
+	if r.KubernetesSecret.Host != "" {
+		u, err := url.Parse(r.KubernetesSecret.Host)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return errors.New("kubernetes_host must be an http or https URL")
+		}
+	}
+	if r.KubernetesSecret.CACert != "" {
+		if ok := x509.NewCertPool().AppendCertsFromPEM([]byte(r.KubernetesSecret.CACert)); !ok {
+			return errors.New("kubernetes_ca_cert does not contain a PEM encoded certificate")
+		}
+	}
+	return nil
+}
+
+// kubernetesSecretResponseData adds the Kubernetes Secret of the role to the
+// data of a role read response.
+func (r *roleEntry) kubernetesSecretResponseData(data map[string]interface{}) {
+	if r.KubernetesSecret == nil {
+		return
+	}
+	data["kubernetes_secret_name"] = r.KubernetesSecret.Name
+	data["kubernetes_secret_namespace"] = r.KubernetesSecret.Namespace
+	if r.KubernetesSecret.Host != "" {
+		data["kubernetes_host"] = r.KubernetesSecret.Host
+	}
+	if r.KubernetesSecret.CACert != "" {
+		data["kubernetes_ca_cert"] = r.KubernetesSecret.CACert
+	}
+}
+
+// staticAccountSecretData returns the data of the Kubernetes Secret of a
+// static role.
+func staticAccountSecretData(role *roleEntry) map[string]string {
+	data := map[string]string{
+		"username": role.StaticAccount.Username,
+	}
+	switch role.CredentialType {
+	case v5.CredentialTypePassword:
+		data["password"] = role.StaticAccount.Password
+	case v5.CredentialTypeRSAPrivateKey:
+		data["rsa_private_key"] = string(role.StaticAccount.PrivateKey)
+	}
+	return data
+}
+
+// pushStaticAccountSecret pushes the current credential of a static role to
+// its Kubernetes Secret. Failures are logged since the credential has already
+// been rotated; the Secret is updated again on the next rotation.
+func (b *databaseBackend) pushStaticAccountSecret(ctx context.Context, name string, role *roleEntry) {
+	if role.KubernetesSecret == nil || role.StaticAccount == nil {
+		return
+	}
+	err := role.KubernetesSecret.put(ctx, b.Logger(), role.KubernetesSecret.Name, staticAccountSecretData(role))
+	if err != nil {
+		b.Logger().Error("failed to push credentials to Kubernetes secret", "role", name,
+			"secret", role.KubernetesSecret.Namespace+"/"+role.KubernetesSecret.Name, "error", err)
+	}
+}
+
+// pushLeaseSecret pushes the credentials of a lease, created for the database
+// user username, to their own Kubernetes Secret and returns its name.
+func (k *kubernetesSecret) pushLeaseSecret(ctx context.Context, logger log.Logger, username string, data map[string]string) (string, error) {
+	dest, err := k.destination(ctx, logger)
+	if err != nil {
+		return "", err
+	}
+	name := dest.NormalizeName(k.Name + "-" + username)
+	if err := dest.Put(ctx, name, data); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// deleteLeaseSecret deletes the Kubernetes Secret the credentials of a lease
+// were pushed to, if any, given the internal data of the lease. The Secret
+// is reached with the current settings of the role, and cannot be deleted if
+// the role no longer pushes credentials.
+func (b *databaseBackend) deleteLeaseSecret(ctx context.Context, role *roleEntry, internal map[string]interface{}) error {
+	name, _ := internal[kubernetesSecretNameInternalKey].(string)
+	if name == "" {
+		return nil
+	}
+	if role == nil || role.KubernetesSecret == nil {
+		b.Logger().Warn("cannot delete the Kubernetes secret of a revoked lease as its role no longer pushes credentials", "secret", name)
+		return nil
+	}
+
+	k := *role.KubernetesSecret
+	if namespace, _ := internal[kubernetesSecretNamespaceInternalKey].(string); namespace != "" {
+		k.Namespace = namespace
+	}
+	dest, err := k.destination(ctx, b.Logger())
+	if err != nil {
+		return err
+	}
+	if err := dest.Delete(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete Kubernetes secret %s/%s: %w", k.Namespace, name, err)
+	}
+	return nil
+}
+
+// put creates or replaces the named Kubernetes Secret with the given data.
+func (k *kubernetesSecret) put(ctx context.Context, logger log.Logger, name string, data map[string]string) error {
+	dest, err := k.destination(ctx, logger)
+	if err != nil {
+		return err
+	}
+	return dest.Put(ctx, name, data)
+}
+
+// destination returns the secrets sync destination writing the Kubernetes
+// Secrets of the role. The service account token of Vault is read again for
+// each destination, since the kubelet rotates it.
+func (k *kubernetesSecret) destination(ctx context.Context, logger log.Logger) (secretsync.Destination, error) {
+	return secretsync.NewKubernetes(ctx, map[string]string{
+		"host":      k.Host,
+		"ca_cert":   k.CACert,
+		"token":     k.ServiceAccountJWT,
+		"namespace": k.Namespace,
+	}, logger)
+}
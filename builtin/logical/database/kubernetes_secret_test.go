@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestKubernetesSecret_LeaseSecrets(t *testing.T) {
+	var mu sync.Mutex
+	secrets := make(map[string]map[string]string)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-jwt" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		const prefix = "/api/v1/namespaces/apps/secrets"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+		var secret struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Data map[string][]byte `json:"data"`
+		}
+		if r.Method == http.MethodPut || r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&secret); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		data := make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		_, exists := secrets[name]
+		switch {
+		case r.Method == http.MethodPut && exists:
+			secrets[name] = data
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && name == "":
+			secrets[secret.Metadata.Name] = data
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodDelete && exists:
+			delete(secrets, name)
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPut, r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	role := &roleEntry{
+		KubernetesSecret: &kubernetesSecret{
+			Name:              "db-creds",
+			Namespace:         "apps",
+			Host:              server.URL,
+			CACert:            string(caCert),
+			ServiceAccountJWT: "test-jwt",
+		},
+	}
+	b := Backend(&logical.BackendConfig{})
+	ctx := context.Background()
+
+	// The credentials of each lease are pushed to their own Secret
+	internals := make(map[string]map[string]interface{})
+	for _, username := range []string{"v-token-my-role-first", "v-token-my-role-second"} {
+		data := map[string]string{
+			"username": username,
+			"password": "password",
+		}
+		name, err := role.KubernetesSecret.pushLeaseSecret(ctx, b.Logger(), username, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "db-creds-"+username {
+			t.Fatalf("bad secret name: %q", name)
+		}
+		mu.Lock()
+		actual := secrets[name]
+		mu.Unlock()
+		if !reflect.DeepEqual(actual, data) {
+			t.Fatalf("expected %v, got %v", data, actual)
+		}
+		internals[username] = map[string]interface{}{
+			kubernetesSecretNameInternalKey:      name,
+			kubernetesSecretNamespaceInternalKey: "apps",
+		}
+	}
+
+	// Revoking a lease deletes its Secret only
+	if err := b.deleteLeaseSecret(ctx, role, internals["v-token-my-role-first"]); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	_, firstExists := secrets["db-creds-v-token-my-role-first"]
+	_, secondExists := secrets["db-creds-v-token-my-role-second"]
+	mu.Unlock()
+	if firstExists || !secondExists {
+		t.Fatalf("bad secrets after revocation: %v", secrets)
+	}
+
+	// Deleting a Secret that no longer exists succeeds, so that
+	// revocations can be retried
+	if err := b.deleteLeaseSecret(ctx, role, internals["v-token-my-role-first"]); err != nil {
+		t.Fatal(err)
+	}
+
+	role.KubernetesSecret.ServiceAccountJWT = "wrong-jwt"
+	_, err := role.KubernetesSecret.pushLeaseSecret(ctx, b.Logger(), "v-token-my-role-third", map[string]string{"username": "vault-user"})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected unauthorized error, got: %v", err)
+	}
+	if err := b.deleteLeaseSecret(ctx, role, internals["v-token-my-role-second"]); err == nil {
+		t.Fatal("expected an error deleting the secret")
+	}
+}
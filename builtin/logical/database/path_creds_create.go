@@ -11,6 +11,13 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+const (
+	// leaseTagRole and leaseTagDBName are the tags of the leases of
+	// credentials holding the role and database names.
+	leaseTagRole   = "role"
+	leaseTagDBName = "db_name"
+)
+
 func pathCredsCreate(b *databaseBackend) []*framework.Path {
 	return []*framework.Path{
 		{
@@ -107,6 +114,9 @@ func (b *databaseBackend) pathCredsCreateRead() framework.OperationFunc {
 			RollbackStatements: v5.Statements{
 				Commands: role.Statements.Rollback,
 			},
+			RevocationStatements: v5.Statements{
+				Commands: role.Statements.Revocation,
+			},
 			Expiration: expiration,
 		}
 
@@ -180,10 +190,42 @@ func (b *databaseBackend) pathCredsCreateRead() framework.OperationFunc {
 		resp := b.Secret(SecretCredsType).Response(respData, internal)
 		resp.Secret.TTL = role.DefaultTTL
 		resp.Secret.MaxTTL = role.MaxTTL
+		resp.Secret.Tags = leaseTags(name, role)
+
+		// Push the credential to the Kubernetes Secret of the lease, which
+		// is deleted when the lease is revoked. The credential is still
+		// returned if that fails, since it was created.
+		if role.KubernetesSecret != nil {
+			secretData := make(map[string]string, len(respData))
+			for k, v := range respData {
+				secretData[k] = v.(string)
+			}
+			secretName, err := role.KubernetesSecret.pushLeaseSecret(ctx, b.Logger(), newUserResp.Username, secretData)
+			if err != nil {
+				b.Logger().Error("failed to push credentials to Kubernetes secret", "role", name, "error", err)
+				resp.AddWarning(fmt.Sprintf("failed to push credentials to a Kubernetes secret in namespace %s: %s", role.KubernetesSecret.Namespace, err))
+			} else {
+				resp.Secret.InternalData[kubernetesSecretNameInternalKey] = secretName
+				resp.Secret.InternalData[kubernetesSecretNamespaceInternalKey] = role.KubernetesSecret.Namespace
+				resp.Data["kubernetes_secret_name"] = secretName
+			}
+		}
 		return resp, nil
 	}
 }
 
+// leaseTags returns the tags of the lease of credentials issued with the
+// role: its own tags, and the role and database names.
+func leaseTags(name string, role *roleEntry) map[string]string {
+	tags := make(map[string]string, len(role.LeaseTags)+2)
+	for k, v := range role.LeaseTags {
+		tags[k] = v
+	}
+	tags[leaseTagRole] = name
+	tags[leaseTagDBName] = role.DBName
+	return tags
+}
+
 func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		name := data.Get("name").(string)
@@ -114,6 +114,9 @@ func fieldsForType(roleType string) map[string]*framework.FieldSchema {
 	for k, v := range typeFields {
 		fields[k] = v
 	}
+	for k, v := range kubernetesSecretFields() {
+		fields[k] = v
+	}
 
 	return fields
 }
@@ -156,6 +159,12 @@ func dynamicFields() map[string]*framework.FieldSchema {
 	type will support this functionality. See the plugin's API page for
 	more information on support and formatting for this parameter.`,
 		},
+		"lease_tags": {
+			Type: framework.TypeKVPairs,
+			Description: `Tags attached to the leases of the credentials, such as
+	the application or environment they are for. Leases are always tagged
+	with the role and database names.`,
+		},
 	}
 	return fields
 }
@@ -279,6 +288,7 @@ func (b *databaseBackend) pathStaticRoleRead(ctx context.Context, req *logical.R
 	if len(role.CredentialConfig) > 0 {
 		data["credential_config"] = role.CredentialConfig
 	}
+	role.kubernetesSecretResponseData(data)
 	if len(role.Statements.Rotation) == 0 {
 		data["rotation_statements"] = []string{}
 	}
@@ -310,6 +320,10 @@ func (b *databaseBackend) pathRoleRead(ctx context.Context, req *logical.Request
 	if len(role.CredentialConfig) > 0 {
 		data["credential_config"] = role.CredentialConfig
 	}
+	if len(role.LeaseTags) > 0 {
+		data["lease_tags"] = role.LeaseTags
+	}
+	role.kubernetesSecretResponseData(data)
 	if len(role.Statements.Creation) == 0 {
 		data["creation_statements"] = []string{}
 	}
@@ -392,6 +406,19 @@ func (b *databaseBackend) pathRoleCreateUpdate(ctx context.Context, req *logical
 		if err := role.setCredentialConfig(credentialConfig); err != nil {
 			return logical.ErrorResponse("credential_config validation failed: %s", err), nil
 		}
+
+		if err := role.setKubernetesSecret(data); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		if leaseTagsRaw, ok := data.GetOk("lease_tags"); ok {
+			role.LeaseTags = leaseTagsRaw.(map[string]string)
+		}
+		for key := range role.LeaseTags {
+			if key == leaseTagRole || key == leaseTagDBName {
+				return logical.ErrorResponse("lease_tags must not set the reserved %q tag", key), nil
+			}
+		}
 	}
 
 	// Statements
@@ -552,6 +579,14 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 		return logical.ErrorResponse("credential_config validation failed: %s", err), nil
 	}
 
+	var pushSecret bool
+	if _, ok := data.GetOk("kubernetes_secret_name"); ok {
+		pushSecret = true
+	}
+	if err := role.setKubernetesSecret(data); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	// lvr represents the roles' LastVaultRotation
 	lvr := role.StaticAccount.LastVaultRotation
 
@@ -598,6 +633,12 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 		if err != nil {
 			return nil, err
 		}
+
+		// Push the current credential when the Kubernetes Secret changes,
+		// rather than waiting for the next rotation
+		if pushSecret {
+			b.pushStaticAccountSecret(ctx, name, role)
+		}
 	}
 
 	item.Priority = lvr.Add(role.StaticAccount.RotationPeriod).Unix()
@@ -618,6 +659,8 @@ type roleEntry struct {
 	CredentialType   v5.CredentialType      `json:"credential_type"`
 	CredentialConfig map[string]interface{} `json:"credential_config"`
 	StaticAccount    *staticAccount         `json:"static_account" mapstructure:"static_account"`
+	KubernetesSecret *kubernetesSecret      `json:"kubernetes_secret,omitempty" mapstructure:"kubernetes_secret"`
+	LeaseTags        map[string]string      `json:"lease_tags,omitempty"`
 }
 
 // setCredentialType sets the credential type for the role given its string form.
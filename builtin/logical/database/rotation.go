@@ -500,6 +500,8 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	}
 	b.Logger().Debug("deleted WAL", "WAL ID", output.WALID)
 
+	b.pushStaticAccountSecret(ctx, input.RoleName, input.Role)
+
 	// The WAL has been deleted, return new setStaticAccountOutput without it
 	return &setStaticAccountOutput{RotationTime: lvr}, nil
 }
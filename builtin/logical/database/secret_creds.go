@@ -139,6 +139,12 @@ func (b *databaseBackend) secretCredsRevoke() framework.OperationFunc {
 			}
 		}
 
+		// Delete the Kubernetes Secret of the lease first, so that the
+		// revocation is retried if that fails
+		if err := b.deleteLeaseSecret(ctx, role, req.Secret.InternalData); err != nil {
+			return nil, err
+		}
+
 		// Get our connection
 		dbi, err := b.GetConnection(ctx, req.Storage, dbName)
 		if err != nil {
@@ -17,6 +17,7 @@ import (
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/ldaputil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -77,6 +78,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 				"cert/*",
 				"ca/pem",
 				"ca_chain",
+				"ca_chain/p7b",
 				"ca",
 				"crl/delta",
 				"crl/delta/pem",
@@ -102,6 +104,10 @@ func Backend(conf *logical.BackendConfig) *backend {
 				legacyCRLPath,
 				"crls/",
 				"certs/",
+				pendingPossessionPrefix,
+				revocationChallengePrefix,
+				serialNumberCounterPath,
+				issuanceCountsPrefix,
 			},
 
 			Root: []string{
@@ -112,6 +118,9 @@ func Backend(conf *logical.BackendConfig) *backend {
 			SealWrapStorage: []string{
 				legacyCertBundlePath,
 				keyPrefix,
+				ldapPublishConfigPath,
+				ocspDelegatePrefix,
+				truststoreConfigPath,
 			},
 		},
 
@@ -127,18 +136,29 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathConfigCA(&b),
 			pathConfigCRL(&b),
 			pathConfigURLs(&b),
+			pathConfigSSHBridge(&b),
+			pathConfigLDAPPublish(&b),
+			pathConfigTruststore(&b),
+			pathConfigBlockedNames(&b),
 			pathSignVerbatim(&b),
 			pathSign(&b),
 			pathIssue(&b),
+			pathIssueBatch(&b),
+			pathIssueSSH(&b),
+			pathRenew(&b),
+			pathActivate(&b),
 			pathRotateCRL(&b),
 			pathRotateDeltaCRL(&b),
+			pathLDAPPublish(&b),
 			pathRevoke(&b),
 			pathRevokeWithKey(&b),
+			pathRevokeWithKeyChallenge(&b),
 			pathListCertsRevoked(&b),
 			pathTidy(&b),
 			pathTidyCancel(&b),
 			pathTidyStatus(&b),
 			pathConfigAutoTidy(&b),
+			pathIssuanceReport(&b),
 
 			// Issuer APIs
 			pathListIssuers(&b),
@@ -146,6 +166,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathGetIssuerCRL(&b),
 			pathImportIssuer(&b),
 			pathIssuerIssue(&b),
+			pathIssuerIssueBatch(&b),
 			pathIssuerSign(&b),
 			pathIssuerSignIntermediate(&b),
 			pathIssuerSignSelfIssued(&b),
@@ -164,10 +185,13 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathGenerateKey(&b),
 			pathImportKey(&b),
 			pathConfigKeys(&b),
+			pathEscrowKey(&b),
 
 			// Fetch APIs have been lowered to favor the newer issuer API endpoints
 			pathFetchCA(&b),
 			pathFetchCAChain(&b),
+			pathFetchCAChainPKCS7(&b),
+			pathFetchCAChainTruststore(&b),
 			pathFetchCRL(&b),
 			pathFetchCRLViaCertPath(&b),
 			pathFetchValidRaw(&b),
@@ -180,6 +204,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 
 			// CRL Signing
 			pathResignCrls(&b),
+			pathOcspDelegate(&b),
 		},
 
 		Secrets: []*framework.Secret{
@@ -206,6 +231,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 	cannotRebuildCRLs := conf.System.ReplicationState().HasState(consts.ReplicationPerformanceStandby) ||
 		conf.System.ReplicationState().HasState(consts.ReplicationDRSecondary)
 	b.crlBuilder = newCRLBuilder(!cannotRebuildCRLs)
+	b.issuerCache = newIssuerCache()
 
 	// Delay the first tidy until after we've started up.
 	b.lastTidy = time.Now()
@@ -226,6 +252,7 @@ type backend struct {
 	backendUUID       string
 	storage           logical.Storage
 	revokeStorageLock sync.RWMutex
+	serialCounterLock sync.Mutex
 	tidyCASGuard      *uint32
 	tidyCancelCAS     *uint32
 
@@ -244,6 +271,16 @@ type backend struct {
 
 	// Write lock around issuers and keys.
 	issuersLock sync.RWMutex
+
+	// Lock around the per-entity issuance counts.
+	issuanceCountsLock sync.Mutex
+
+	// Parsed issuers, invalidated on any issuer or key change.
+	issuerCache *issuerCache
+
+	// Client used to publish to LDAP; tests replace it to fake the
+	// directory.
+	ldapPublishClient *ldaputil.Client
 }
 
 type (
@@ -436,7 +473,11 @@ func (b *backend) invalidate(ctx context.Context, key string) {
 			b.updatePkiStorageVersion(ctx, true)
 			b.crlBuilder.requestRebuildIfActiveNode(b)
 		}()
+	case strings.HasPrefix(key, keyPrefix):
+		b.issuerCache.Invalidate()
 	case strings.HasPrefix(key, issuerPrefix):
+		b.issuerCache.Invalidate()
+
 		if !b.useLegacyBundleCaStorage() {
 			// See note in updateDefaultIssuerId about why this is necessary.
 			// We do this ahead of CRL rebuilding just so we know that things
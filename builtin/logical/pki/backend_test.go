@@ -3622,6 +3622,12 @@ func TestReadWriteDeleteRoles(t *testing.T) {
 		"code_signing_flag":                  false,
 		"issuer_ref":                         "default",
 		"cn_validations":                     []interface{}{"email", "hostname"},
+		"allow_ssh_bridge":                   false,
+		"ssh_principal_template":             "",
+		"require_proof_of_possession":        false,
+		"proof_of_possession_window":         json.Number("300"),
+		"max_certs_per_entity":               json.Number("0"),
+		"max_certs_per_entity_period":        json.Number("86400"),
 	}
 
 	if diff := deep.Equal(expectedData, resp.Data); len(diff) > 0 {
@@ -38,6 +38,14 @@ type inputBundle struct {
 	role    *roleEntry
 	req     *logical.Request
 	apiData *framework.FieldData
+
+	// serialNumber, when set, is the serial number of the certificate
+	// instead of a random one.
+	serialNumber *big.Int
+
+	// blockedNames, when set, is the list of names blocked on the mount,
+	// checked before the allowed names of the role.
+	blockedNames *blockedNamesConfig
 }
 
 var (
@@ -107,7 +115,7 @@ func (sc *storageContext) fetchCAInfo(issuerRef string, usage issuerUsage) (*cer
 // fetchCAInfoByIssuerId will fetch the CA info, will return an error if no ca info exists for the given issuerId.
 // This does support the loading using the legacyBundleShimID
 func (sc *storageContext) fetchCAInfoByIssuerId(issuerId issuerID, usage issuerUsage) (*certutil.CAInfoBundle, error) {
-	entry, bundle, err := sc.fetchCertBundleByIssuerId(issuerId, true)
+	entry, parsedBundle, err := sc.fetchParsedIssuer(issuerId)
 	if err != nil {
 		switch err.(type) {
 		case errutil.UserError:
@@ -123,11 +131,6 @@ func (sc *storageContext) fetchCAInfoByIssuerId(issuerId issuerID, usage issuerU
 		return nil, errutil.InternalError{Err: fmt.Sprintf("error while attempting to use issuer %v: %v", issuerId, err)}
 	}
 
-	parsedBundle, err := parseCABundle(sc.Context, sc.Backend, bundle)
-	if err != nil {
-		return nil, errutil.InternalError{Err: err.Error()}
-	}
-
 	if parsedBundle.Certificate == nil {
 		return nil, errutil.InternalError{Err: "stored CA information not able to be parsed"}
 	}
@@ -1130,6 +1133,19 @@ func generateCreationBundle(b *backend, data *inputBundle, caSign *certutil.CAIn
 			}
 		}
 
+		// Names blocked on the mount are rejected whatever the role allows.
+		if cn != "" {
+			if err := data.blockedNames.validateBlockedNames("common name", []string{cn}); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := data.blockedNames.validateBlockedNames("subject alternate name", dnsNames); err != nil {
+			return nil, nil, err
+		}
+		if err := data.blockedNames.validateBlockedNames("email address", emailAddresses); err != nil {
+			return nil, nil, err
+		}
+
 		// Check the CN. This ensures that the CN is checked even if it's
 		// excluded from SANs.
 		if cn != "" {
@@ -1406,6 +1422,7 @@ func generateCreationBundle(b *backend, data *inputBundle, caSign *certutil.CAIn
 			NotBeforeDuration:             data.role.NotBeforeDuration,
 			ForceAppendCaChain:            caSign != nil,
 			SKID:                          skid,
+			SerialNumber:                  data.serialNumber,
 		},
 		SigningBundle: caSign,
 		CSR:           csr,
@@ -1051,6 +1051,10 @@ func buildAnyCRLs(sc *storageContext, forceNew bool, isDelta bool) error {
 		}
 	}
 
+	if !wasLegacy {
+		sc.Backend.publishToLDAPIfEnabled(sc)
+	}
+
 	// All good :-)
 	return nil
 }
@@ -88,6 +88,33 @@ comma-delimited list.`,
 	return fields
 }
 
+// addPKCS12Fields adds the fields returning the issued certificate and its
+// private key as a PKCS#12 file, which only apply when the key is generated
+// by Vault.
+func addPKCS12Fields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["format"].Description = `Format for returned data. Can be "pem", "der",
+"pem_bundle" or "pkcs12". If "pem_bundle", any private
+key and issuing cert will be appended to the
+certificate pem. If "der", the value will be
+base64 encoded. If "pkcs12", the certificate, its chain
+and the private key are returned as a base64-encoded
+PKCS#12 file protected with pkcs12_password. Defaults
+to "pem".`
+	fields["format"].AllowedValues = []interface{}{"pem", "der", "pem_bundle", "pkcs12"}
+
+	fields["pkcs12_password"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Password protecting the PKCS#12 file returned
+with the "pkcs12" format. If omitted, a password is
+generated and returned as pkcs12_password.`,
+		DisplayAttrs: &framework.DisplayAttributes{
+			Sensitive: true,
+		},
+	}
+
+	return fields
+}
+
 // addNonCACommonFields adds fields with help text specific to non-CA
 // certificate issuing and signing
 func addNonCACommonFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
@@ -0,0 +1,111 @@
+package pki
+
+import (
+	"sync"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+)
+
+// issuerCache holds parsed issuer bundles (certificate, chain and private
+// key) so that issuance does not need to re-read and re-parse them from
+// storage on every request.
+//
+// Validity is tracked with a generation counter: every local write to an
+// issuer or key, and every replicated invalidation of one, bumps the
+// generation and drops all entries. A reader records the generation before
+// it goes to storage and only populates the cache if no write happened in
+// the meantime, so a racing write can never be masked by a stale entry.
+type issuerCache struct {
+	lock       sync.RWMutex
+	generation uint64
+	entries    map[issuerID]*cachedIssuer
+}
+
+type cachedIssuer struct {
+	entry  *issuerEntry
+	bundle *certutil.ParsedCertBundle
+}
+
+func newIssuerCache() *issuerCache {
+	return &issuerCache{
+		entries: make(map[issuerID]*cachedIssuer),
+	}
+}
+
+// Get returns the cached issuer, if any, along with the current generation.
+// The generation should be passed to Put when populating the cache after a
+// miss.
+func (c *issuerCache) Get(id issuerID) (*cachedIssuer, uint64) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.entries[id], c.generation
+}
+
+// Put stores the parsed issuer, unless the cache has been invalidated since
+// generation was obtained.
+func (c *issuerCache) Put(id issuerID, generation uint64, entry *issuerEntry, bundle *certutil.ParsedCertBundle) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	c.entries[id] = &cachedIssuer{
+		entry:  entry,
+		bundle: bundle,
+	}
+}
+
+// Invalidate drops all cached issuers and advances the generation.
+func (c *issuerCache) Invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+	c.entries = make(map[issuerID]*cachedIssuer)
+}
+
+func (b *backend) issuerCacheMetricsKey(result string) []string {
+	return []string{"secrets", "pki", b.backendUUID, "issuer_cache", result}
+}
+
+// fetchParsedIssuer returns the issuer entry and its parsed bundle,
+// consulting the issuer cache first. Legacy bundles and managed keys are
+// never cached: the former are only used until migration completes, and
+// the latter hold a handle to an external key whose availability may change
+// independently of storage.
+func (sc *storageContext) fetchParsedIssuer(issuerId issuerID) (*issuerEntry, *certutil.ParsedCertBundle, error) {
+	cache := sc.Backend.issuerCache
+	cacheable := issuerId != legacyBundleShimID
+
+	var generation uint64
+	if cacheable {
+		var cached *cachedIssuer
+		cached, generation = cache.Get(issuerId)
+		if cached != nil {
+			metrics.IncrCounter(sc.Backend.issuerCacheMetricsKey("hit"), 1)
+			return cached.entry, cached.bundle, nil
+		}
+		metrics.IncrCounter(sc.Backend.issuerCacheMetricsKey("miss"), 1)
+	}
+
+	entry, bundle, err := sc.fetchCertBundleByIssuerId(issuerId, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsedBundle, err := parseCABundle(sc.Context, sc.Backend, bundle)
+	if err != nil {
+		return nil, nil, errutil.InternalError{Err: err.Error()}
+	}
+
+	if cacheable && bundle.PrivateKeyType != certutil.ManagedPrivateKey {
+		cache.Put(issuerId, generation, entry, parsedBundle)
+	}
+
+	return entry, parsedBundle, nil
+}
@@ -0,0 +1,60 @@
+package pki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPKI_IssuerCache_StaleGenerationNotStored(t *testing.T) {
+	t.Parallel()
+	cache := newIssuerCache()
+
+	cached, generation := cache.Get("issuer")
+	require.Nil(t, cached)
+
+	// A write landing between the miss and the population must win.
+	cache.Invalidate()
+	cache.Put("issuer", generation, &issuerEntry{ID: "issuer"}, nil)
+
+	cached, _ = cache.Get("issuer")
+	require.Nil(t, cached, "entry read before invalidation must not be cached")
+
+	_, generation = cache.Get("issuer")
+	cache.Put("issuer", generation, &issuerEntry{ID: "issuer"}, nil)
+	cached, _ = cache.Get("issuer")
+	require.NotNil(t, cached)
+}
+
+func TestPKI_IssuerCache_InvalidatedOnIssuerUpdate(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root example.com",
+		"issuer_name": "root",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+	issuerId := resp.Data["issuer_id"].(issuerID)
+
+	sc := b.makeStorageContext(context.Background(), s)
+	_, err = sc.fetchCAInfoByIssuerId(issuerId, IssuanceUsage)
+	require.NoError(t, err)
+
+	cached, _ := b.issuerCache.Get(issuerId)
+	require.NotNil(t, cached, "issuer should be cached after first use")
+
+	// Removing the issuance usage must take effect immediately.
+	resp, err = CBPatch(b, s, "issuer/root", map[string]interface{}{
+		"usage": "read-only,crl-signing",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed updating issuer")
+
+	cached, _ = b.issuerCache.Get(issuerId)
+	require.Nil(t, cached, "issuer cache should be invalidated by issuer write")
+
+	_, err = sc.fetchCAInfoByIssuerId(issuerId, IssuanceUsage)
+	require.Error(t, err, "issuer without issuing usage should not be usable for issuance")
+}
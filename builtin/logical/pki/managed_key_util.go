@@ -5,38 +5,179 @@ package pki
 import (
 	"context"
 	"crypto"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
 )
 
-var errEntOnly = errors.New("managed keys are supported within enterprise edition only")
+// managedKeyBytesPrefix prefixes the UUID of a managed key in the private
+// key bytes stored for it. The key material itself never leaves the key
+// management system.
+const managedKeyBytesPrefix = "managed_key_uuid:"
+
+var errManagedKeysUnsupported = errors.New("managed keys are not supported by this Vault server")
+
+// managedKeyBytes returns the private key bytes stored for a managed key.
+func managedKeyBytes(keyId UUIDKey) []byte {
+	return []byte(managedKeyBytesPrefix + string(keyId))
+}
+
+func withManagedSigningKey(ctx context.Context, b *backend, keyId managedKeyId, f logical.ManagedSigningKeyConsumer) error {
+	sysView, ok := b.System().(logical.ManagedKeySystemView)
+	if !ok {
+		return errManagedKeysUnsupported
+	}
+
+	consumer := func(ctx context.Context, key logical.ManagedSigningKey) error {
+		if !key.AllowsAll([]logical.KeyUsage{logical.KeyUsageSign}) {
+			return errutil.UserError{Err: fmt.Sprintf("managed key %q does not allow signing", key.Name())}
+		}
+		return f(ctx, key)
+	}
+
+	switch id := keyId.(type) {
+	case NameKey:
+		return sysView.WithManagedSigningKeyByName(ctx, string(id), b.backendUUID, consumer)
+	case UUIDKey:
+		return sysView.WithManagedSigningKeyByUUID(ctx, string(id), b.backendUUID, consumer)
+	default:
+		return errutil.InternalError{Err: fmt.Sprintf("unknown managed key id type %T", keyId)}
+	}
+}
+
+// managedKeyGenerator returns a key generator setting the managed key on the
+// created bundle in place of generating a key.
+func managedKeyGenerator(key logical.ManagedSigningKey, signer crypto.Signer) certutil.KeyGenerator {
+	return func(_ string, _ int, container certutil.ParsedPrivateKeyContainer, _ io.Reader) error {
+		container.SetParsedPrivateKey(signer, certutil.ManagedPrivateKey, managedKeyBytes(UUIDKey(key.UUID())))
+		return nil
+	}
+}
 
 func generateManagedKeyCABundle(ctx context.Context, b *backend, keyId managedKeyId, data *certutil.CreationBundle, randomSource io.Reader) (bundle *certutil.ParsedCertBundle, err error) {
-	return nil, errEntOnly
+	err = withManagedSigningKey(ctx, b, keyId, func(ctx context.Context, key logical.ManagedSigningKey) error {
+		signer, err := key.GetSigner(ctx)
+		if err != nil {
+			return err
+		}
+		bundle, err = certutil.CreateCertificateWithKeyGenerator(data, randomSource, managedKeyGenerator(key, signer))
+		return err
+	})
+	return bundle, err
 }
 
 func generateManagedKeyCSRBundle(ctx context.Context, b *backend, keyId managedKeyId, data *certutil.CreationBundle, addBasicConstraints bool, randomSource io.Reader) (bundle *certutil.ParsedCSRBundle, err error) {
-	return nil, errEntOnly
+	err = withManagedSigningKey(ctx, b, keyId, func(ctx context.Context, key logical.ManagedSigningKey) error {
+		signer, err := key.GetSigner(ctx)
+		if err != nil {
+			return err
+		}
+		bundle, err = certutil.CreateCSRWithKeyGenerator(data, addBasicConstraints, randomSource, managedKeyGenerator(key, signer))
+		return err
+	})
+	return bundle, err
 }
 
 func getManagedKeyPublicKey(ctx context.Context, b *backend, keyId managedKeyId) (crypto.PublicKey, error) {
-	return nil, errEntOnly
+	info, err := getManagedKeyInfo(ctx, b, keyId)
+	if err != nil {
+		return nil, err
+	}
+	return info.publicKey, nil
+}
+
+// managedKeySigner signs with a managed key, looking the key up on every
+// signature so that it is only used within the scope of a registry call.
+type managedKeySigner struct {
+	ctx    context.Context
+	b      *backend
+	keyId  UUIDKey
+	public crypto.PublicKey
+}
+
+func (s *managedKeySigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *managedKeySigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) (sig []byte, err error) {
+	err = withManagedSigningKey(s.ctx, s.b, s.keyId, func(ctx context.Context, key logical.ManagedSigningKey) error {
+		sig, err = key.Sign(ctx, digest, rand, opts)
+		return err
+	})
+	return sig, err
 }
 
 func parseManagedKeyCABundle(ctx context.Context, b *backend, bundle *certutil.CertBundle) (*certutil.ParsedCertBundle, error) {
-	return nil, errEntOnly
+	return bundle.ToParsedCertBundleWithExtractor(func(c *certutil.CertBundle, parsedBundle *certutil.ParsedCertBundle) error {
+		keyId, err := extractManagedKeyId([]byte(c.PrivateKey))
+		if err != nil {
+			return err
+		}
+		public, err := getManagedKeyPublicKey(ctx, b, keyId)
+		if err != nil {
+			return err
+		}
+
+		parsedBundle.PrivateKeyFormat = certutil.PKCS8Block
+		parsedBundle.SetParsedPrivateKey(&managedKeySigner{
+			ctx:    ctx,
+			b:      b,
+			keyId:  keyId,
+			public: public,
+		}, certutil.ManagedPrivateKey, managedKeyBytes(keyId))
+		return nil
+	})
 }
 
 func extractManagedKeyId(privateKeyBytes []byte) (UUIDKey, error) {
-	return "", errEntOnly
+	block, _ := pem.Decode(privateKeyBytes)
+	if block == nil {
+		return "", errutil.InternalError{Err: "no data found in managed key PEM block"}
+	}
+	keyId := string(block.Bytes)
+	if !strings.HasPrefix(keyId, managedKeyBytesPrefix) {
+		return "", errutil.InternalError{Err: "private key is not a reference to a managed key"}
+	}
+	return UUIDKey(strings.TrimPrefix(keyId, managedKeyBytesPrefix)), nil
 }
 
 func createKmsKeyBundle(ctx context.Context, b *backend, keyId managedKeyId) (certutil.KeyBundle, certutil.PrivateKeyType, error) {
-	return certutil.KeyBundle{}, certutil.UnknownPrivateKey, errEntOnly
+	info, err := getManagedKeyInfo(ctx, b, keyId)
+	if err != nil {
+		return certutil.KeyBundle{}, certutil.UnknownPrivateKey, err
+	}
+
+	return certutil.KeyBundle{
+		PrivateKeyType:  certutil.ManagedPrivateKey,
+		PrivateKeyBytes: managedKeyBytes(info.uuid),
+	}, info.keyType, nil
 }
 
 func getManagedKeyInfo(ctx context.Context, b *backend, keyId managedKeyId) (*managedKeyInfo, error) {
-	return nil, errEntOnly
+	var info *managedKeyInfo
+	err := withManagedSigningKey(ctx, b, keyId, func(ctx context.Context, key logical.ManagedSigningKey) error {
+		public, err := key.GetPublicKey(ctx)
+		if err != nil {
+			return err
+		}
+		keyType, _, err := getKeyTypeAndBitsFromPublicKeyForRole(public)
+		if err != nil {
+			return err
+		}
+
+		info = &managedKeyInfo{
+			publicKey: public,
+			keyType:   keyType,
+			name:      NameKey(key.Name()),
+			uuid:      UUIDKey(key.UUID()),
+		}
+		return nil
+	})
+	return info, err
 }
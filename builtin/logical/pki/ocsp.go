@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/sha1"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/vault/sdk/helper/errutil"
@@ -37,6 +41,14 @@ type ocspRespInfo struct {
 	issuerID          issuerID
 }
 
+// ocspSignedResponse is a signed OCSP response along with the validity
+// window it was signed with, from which the HTTP caching headers are derived.
+type ocspSignedResponse struct {
+	der        []byte
+	thisUpdate time.Time
+	nextUpdate time.Time
+}
+
 // These response variables should not be mutated, instead treat them as constants
 var (
 	OcspUnauthorizedResponse = &logical.Response{
@@ -128,7 +140,7 @@ func (b *backend) ocspHandler(ctx context.Context, request *logical.Request, dat
 			// Since we were not able to find a matching issuer for the incoming request
 			// generate an Unknown OCSP response. This might turn into an Unauthorized if
 			// we find out that we don't have a default issuer or it's missing the proper Usage flags
-			return generateUnknownResponse(cfg, sc, ocspReq), nil
+			return generateUnknownResponse(cfg, sc, request, ocspReq), nil
 		}
 		if errors.Is(err, ErrMissingOcspUsage) {
 			// If we did find a matching issuer but aren't allowed to sign, the spec says
@@ -140,21 +152,46 @@ func (b *backend) ocspHandler(ctx context.Context, request *logical.Request, dat
 		return logAndReturnInternalError(b, err), nil
 	}
 
-	byteResp, err := genResponse(cfg, caBundle, ocspStatus, ocspReq.HashAlgorithm, issuer.RevocationSigAlg)
+	signedResp, err := genResponse(cfg, sc, caBundle, issuer, ocspStatus, ocspReq.HashAlgorithm)
 	if err != nil {
 		return logAndReturnInternalError(b, err), nil
 	}
 
-	return &logical.Response{
+	return ocspSuccessfulResponse(request, signedResp), nil
+}
+
+// ocspSuccessfulResponse returns the HTTP response carrying signedResp. The
+// responses to GET requests can be cached by HTTP caches and CDNs until their
+// nextUpdate, so they carry the headers described in RFC 5019 section 6.2.
+// POST requests are not cacheable, and responses without a validity window
+// (an ocsp_expiry of 0) keep the default Cache-Control of no-store.
+func ocspSuccessfulResponse(request *logical.Request, signedResp *ocspSignedResponse) *logical.Response {
+	resp := &logical.Response{
 		Data: map[string]interface{}{
 			logical.HTTPContentType: ocspResponseContentType,
 			logical.HTTPStatusCode:  http.StatusOK,
-			logical.HTTPRawBody:     byteResp,
+			logical.HTTPRawBody:     signedResp.der,
 		},
-	}, nil
+	}
+
+	if request.Operation != logical.ReadOperation {
+		return resp
+	}
+
+	maxAge := int64(time.Until(signedResp.nextUpdate) / time.Second)
+	if maxAge <= 0 {
+		return resp
+	}
+
+	etag := sha1.Sum(signedResp.der)
+	resp.Data[logical.HTTPCacheControlHeader] = fmt.Sprintf("max-age=%d, public, no-transform, must-revalidate", maxAge)
+	resp.Data[logical.HTTPETagHeader] = `"` + hex.EncodeToString(etag[:]) + `"`
+	resp.Data[logical.HTTPExpiresHeader] = signedResp.nextUpdate.UTC().Format(http.TimeFormat)
+	resp.Data[logical.HTTPLastModifiedHeader] = signedResp.thisUpdate.UTC().Format(http.TimeFormat)
+	return resp
 }
 
-func generateUnknownResponse(cfg *crlConfig, sc *storageContext, ocspReq *ocsp.Request) *logical.Response {
+func generateUnknownResponse(cfg *crlConfig, sc *storageContext, request *logical.Request, ocspReq *ocsp.Request) *logical.Response {
 	// Generate an Unknown OCSP response, signing with the default issuer from the mount as we did
 	// not match the request's issuer. If no default issuer can be used, return with Unauthorized as there
 	// isn't much else we can do at this point.
@@ -188,18 +225,12 @@ func generateUnknownResponse(cfg *crlConfig, sc *storageContext, ocspReq *ocsp.R
 		ocspStatus:   ocsp.Unknown,
 	}
 
-	byteResp, err := genResponse(cfg, caBundle, info, ocspReq.HashAlgorithm, issuer.RevocationSigAlg)
+	signedResp, err := genResponse(cfg, sc, caBundle, issuer, info, ocspReq.HashAlgorithm)
 	if err != nil {
 		return logAndReturnInternalError(sc.Backend, err)
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			logical.HTTPContentType: ocspResponseContentType,
-			logical.HTTPStatusCode:  http.StatusOK,
-			logical.HTTPRawBody:     byteResp,
-		},
-	}
+	return ocspSuccessfulResponse(request, signedResp)
 }
 
 func fetchDerEncodedRequest(request *logical.Request, data *framework.FieldData) ([]byte, error) {
@@ -215,7 +246,7 @@ func fetchDerEncodedRequest(request *logical.Request, data *framework.FieldData)
 			return nil, errors.New("request is too large")
 		}
 
-		return base64.StdEncoding.DecodeString(base64Req)
+		return decodeOcspGetRequest(base64Req)
 	case logical.UpdateOperation:
 		// POST bodies should contain the binary form of the DER request.
 		rawBody := request.HTTPRequest.Body
@@ -235,6 +266,34 @@ func fetchDerEncodedRequest(request *logical.Request, data *framework.FieldData)
 	}
 }
 
+// decodeOcspGetRequest decodes the DER request from the path of a GET request.
+// RFC 6960 Appendix A.1 calls for the url-encoding of the base64 encoded
+// request, but some clients use the URL-safe alphabet or omit the padding
+// instead, so all of these are accepted.
+func decodeOcspGetRequest(base64Req string) ([]byte, error) {
+	if strings.Contains(base64Req, "%") {
+		unescaped, err := url.PathUnescape(base64Req)
+		if err != nil {
+			return nil, err
+		}
+		base64Req = unescaped
+	}
+
+	encodings := []*base64.Encoding{
+		base64.StdEncoding,
+		base64.URLEncoding,
+		base64.RawStdEncoding,
+		base64.RawURLEncoding,
+	}
+	for _, encoding := range encodings {
+		if derReq, err := encoding.DecodeString(base64Req); err == nil {
+			return derReq, nil
+		}
+	}
+
+	return nil, errors.New("ocsp request is not base64 encoded")
+}
+
 func logAndReturnInternalError(b *backend, err error) *logical.Response {
 	// Since OCSP might be a high traffic endpoint, we will log at debug level only
 	// any internal errors we do get. There is no way for us to return to the end-user
@@ -384,13 +443,46 @@ func doesRequestMatchIssuer(parsedBundle *certutil.ParsedCertBundle, req *ocsp.R
 	return bytes.Equal(req.IssuerKeyHash, issuerKeyHash) && bytes.Equal(req.IssuerNameHash, issuerNameHash), nil
 }
 
-func genResponse(cfg *crlConfig, caBundle *certutil.ParsedCertBundle, info *ocspRespInfo, reqHash crypto.Hash, revSigAlg x509.SignatureAlgorithm) ([]byte, error) {
+// getOcspResponder returns the certificate and key to sign the OCSP responses
+// of issuer with: its delegated responder while valid, otherwise the issuer
+// itself.
+func getOcspResponder(sc *storageContext, caBundle *certutil.ParsedCertBundle, issuer *issuerEntry) (*x509.Certificate, crypto.Signer, x509.SignatureAlgorithm, error) {
+	delegate, err := sc.fetchOcspDelegate(issuer.ID)
+	if err != nil {
+		return nil, nil, x509.UnknownSignatureAlgorithm, err
+	}
+
+	if delegate != nil {
+		responderCert, responderKey, err := delegate.GetSigner()
+		if err != nil {
+			return nil, nil, x509.UnknownSignatureAlgorithm, err
+		}
+
+		now := time.Now()
+		if now.After(responderCert.NotBefore) && now.Before(responderCert.NotAfter) {
+			// The signature algorithm of the issuer does not apply to the
+			// responder key, so let it be picked from the key type.
+			return responderCert, responderKey, x509.UnknownSignatureAlgorithm, nil
+		}
+
+		sc.Backend.Logger().Debug("OCSP delegate certificate is not valid, signing with the issuer", "issuer_id", issuer.ID)
+	}
+
+	return caBundle.Certificate, caBundle.PrivateKey, issuer.RevocationSigAlg, nil
+}
+
+func genResponse(cfg *crlConfig, sc *storageContext, caBundle *certutil.ParsedCertBundle, issuer *issuerEntry, info *ocspRespInfo, reqHash crypto.Hash) (*ocspSignedResponse, error) {
 	curTime := time.Now()
 	duration, err := time.ParseDuration(cfg.OcspExpiry)
 	if err != nil {
 		return nil, err
 	}
 
+	responderCert, responderKey, revSigAlg, err := getOcspResponder(sc, caBundle, issuer)
+	if err != nil {
+		return nil, err
+	}
+
 	// x/crypto/ocsp lives outside of the standard library's crypto/x509 and includes
 	// ripped-off variants of many internal structures and functions. These
 	// lack support for PSS signatures altogether, so if we have revSigAlg
@@ -415,7 +507,7 @@ func genResponse(cfg *crlConfig, caBundle *certutil.ParsedCertBundle, info *ocsp
 		SerialNumber:       info.serialNumber,
 		ThisUpdate:         curTime,
 		NextUpdate:         curTime.Add(duration),
-		Certificate:        caBundle.Certificate,
+		Certificate:        responderCert,
 		ExtraExtensions:    []pkix.Extension{},
 		SignatureAlgorithm: revSigAlg,
 	}
@@ -425,7 +517,16 @@ func genResponse(cfg *crlConfig, caBundle *certutil.ParsedCertBundle, info *ocsp
 		template.RevocationReason = ocsp.Unspecified
 	}
 
-	return ocsp.CreateResponse(caBundle.Certificate, caBundle.Certificate, template, caBundle.PrivateKey)
+	der, err := ocsp.CreateResponse(caBundle.Certificate, responderCert, template, responderKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ocspSignedResponse{
+		der:        der,
+		thisUpdate: template.ThisUpdate,
+		nextUpdate: template.NextUpdate,
+	}, nil
 }
 
 const pathOcspHelpSyn = `
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/sha1"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
@@ -406,6 +409,85 @@ func TestOcsp_ValidRequests(t *testing.T) {
 	}
 }
 
+// Validate that GET requests are accepted whether the base64 request in the path is url-encoded,
+// uses the URL-safe alphabet or has no padding, as clients differ in the encoding they use.
+func TestOcsp_GetRequestEncodings(t *testing.T) {
+	t.Parallel()
+	b, s, testEnv := setupOcspEnv(t, "rsa")
+	ocspRequest := generateRequest(t, crypto.SHA1, testEnv.leafCertIssuer1, testEnv.issuer1)
+
+	encodings := map[string]string{
+		"std":         base64.StdEncoding.EncodeToString(ocspRequest),
+		"url-encoded": url.PathEscape(base64.StdEncoding.EncodeToString(ocspRequest)),
+		"url":         base64.URLEncoding.EncodeToString(ocspRequest),
+		"raw-std":     base64.RawStdEncoding.EncodeToString(ocspRequest),
+		"raw-url":     base64.RawURLEncoding.EncodeToString(ocspRequest),
+	}
+	for name, encoded := range encodings {
+		resp, err := CBRead(b, s, "ocsp/"+encoded)
+		require.NoError(t, err, name)
+		requireFieldsSetInResp(t, resp, "http_content_type", "http_status_code", "http_raw_body")
+		require.Equal(t, 200, resp.Data["http_status_code"], name)
+
+		ocspResp, err := ocsp.ParseResponse(resp.Data["http_raw_body"].([]byte), testEnv.issuer1)
+		require.NoError(t, err, name)
+		require.Equal(t, ocsp.Good, ocspResp.Status, name)
+	}
+}
+
+// Validate that responses to GET requests carry the caching headers of RFC 5019 section 6.2, aligned
+// to the nextUpdate of the response, while POST responses and responses without a validity window
+// are not cacheable.
+func TestOcsp_CachingHeaders(t *testing.T) {
+	t.Parallel()
+	b, s, testEnv := setupOcspEnv(t, "ec")
+
+	resp, err := SendOcspRequest(t, b, s, "get", testEnv.leafCertIssuer1, testEnv.issuer1, crypto.SHA256)
+	require.NoError(t, err)
+	requireFieldsSetInResp(t, resp, "http_raw_cache_control", "http_raw_etag", "http_raw_expires", "http_raw_last_modified")
+
+	respDer := resp.Data["http_raw_body"].([]byte)
+	ocspResp, err := ocsp.ParseResponse(respDer, testEnv.issuer1)
+	require.NoError(t, err)
+
+	cacheControl := resp.Data["http_raw_cache_control"].(string)
+	require.Contains(t, cacheControl, "public")
+	var maxAge int64
+	_, err = fmt.Sscanf(cacheControl, "max-age=%d,", &maxAge)
+	require.NoError(t, err, "failed parsing max-age from %s", cacheControl)
+	require.Greater(t, maxAge, int64(0))
+	require.LessOrEqual(t, maxAge, int64(time.Until(ocspResp.NextUpdate)/time.Second)+1)
+
+	expires, err := http.ParseTime(resp.Data["http_raw_expires"].(string))
+	require.NoError(t, err)
+	require.True(t, expires.Equal(ocspResp.NextUpdate), "expires %s does not match nextUpdate %s", expires, ocspResp.NextUpdate)
+
+	lastModified, err := http.ParseTime(resp.Data["http_raw_last_modified"].(string))
+	require.NoError(t, err)
+	require.True(t, lastModified.Equal(ocspResp.ThisUpdate), "last-modified %s does not match thisUpdate %s", lastModified, ocspResp.ThisUpdate)
+
+	etag := sha1.Sum(respDer)
+	require.Equal(t, `"`+hex.EncodeToString(etag[:])+`"`, resp.Data["http_raw_etag"])
+
+	// POST requests aren't cacheable
+	resp, err = SendOcspRequest(t, b, s, "post", testEnv.leafCertIssuer1, testEnv.issuer1, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.Data["http_status_code"])
+	require.NotContains(t, resp.Data, "http_raw_cache_control")
+	require.NotContains(t, resp.Data, "http_raw_etag")
+
+	// Neither are responses without a validity window
+	resp, err = CBWrite(b, s, "config/crl", map[string]interface{}{
+		"ocsp_expiry": "0",
+	})
+	requireSuccessNilResponse(t, resp, err)
+	resp, err = SendOcspRequest(t, b, s, "get", testEnv.leafCertIssuer1, testEnv.issuer1, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.Data["http_status_code"])
+	require.NotContains(t, resp.Data, "http_raw_cache_control")
+	require.NotContains(t, resp.Data, "http_raw_expires")
+}
+
 func runOcspRequestTest(t *testing.T, requestType string, caKeyType string, caKeyBits int, caKeySigBits int, requestHash crypto.Hash) {
 	b, s, testEnv := setupOcspEnvWithCaKeyConfig(t, caKeyType, caKeyBits, caKeySigBits)
 
@@ -0,0 +1,169 @@
+package pki
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/ryanuber/go-glob"
+)
+
+const blockedNamesConfigPath = "config/blocked-names"
+
+// blockedNamesConfig is the mount-wide list of names no certificate can be
+// issued for, whatever the configuration of the role.
+type blockedNamesConfig struct {
+	// BlockedNames maps exact or glob patterns, matched case-insensitively
+	// against the common name and the DNS and email SANs, to the reason
+	// they are blocked.
+	BlockedNames map[string]string `json:"blocked_names"`
+}
+
+func pathConfigBlockedNames(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/blocked-names",
+		Fields: map[string]*framework.FieldSchema{
+			"blocked_names": {
+				Type: framework.TypeKVPairs,
+				Description: `Map of the names no certificate can be issued
+for, to the reason they are blocked. Names are exact names or glob patterns
+such as "*.example.com" or "*@example.com", matched case-insensitively
+against the common name and the DNS and email subject alternative names.
+Replaces the existing list.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathWriteBlockedNamesConfig,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathReadBlockedNamesConfig,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathDeleteBlockedNamesConfig,
+			},
+		},
+
+		HelpSynopsis:    pathConfigBlockedNamesHelpSyn,
+		HelpDescription: pathConfigBlockedNamesHelpDesc,
+	}
+}
+
+func getBlockedNamesConfig(ctx context.Context, storage logical.Storage) (*blockedNamesConfig, error) {
+	entry, err := storage.Get(ctx, blockedNamesConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config blockedNamesConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (b *backend) pathReadBlockedNamesConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	config, err := getBlockedNamesConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"blocked_names": config.BlockedNames,
+		},
+	}, nil
+}
+
+func (b *backend) pathWriteBlockedNamesConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config := &blockedNamesConfig{
+		BlockedNames: map[string]string{},
+	}
+	for name, reason := range data.Get("blocked_names").(map[string]string) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return logical.ErrorResponse("blocked names cannot be empty"), nil
+		}
+		config.BlockedNames[name] = reason
+	}
+
+	entry, err := logical.StorageEntryJSON(blockedNamesConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+func (b *backend) pathDeleteBlockedNamesConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, blockedNamesConfigPath)
+}
+
+// blockedBy returns the blocked name matching name and the reason it is
+// blocked for, or an empty string if name is not blocked. A wildcard name is
+// also blocked when it covers an exact blocked name.
+func (c *blockedNamesConfig) blockedBy(name string) (string, string) {
+	if c == nil || len(c.BlockedNames) == 0 {
+		return "", ""
+	}
+	name = strings.ToLower(name)
+
+	// Sort the blocked names so that the same one is always reported.
+	blocked := make([]string, 0, len(c.BlockedNames))
+	for pattern := range c.BlockedNames {
+		blocked = append(blocked, pattern)
+	}
+	sort.Strings(blocked)
+
+	for _, pattern := range blocked {
+		if pattern == name || glob.Glob(pattern, name) {
+			return pattern, c.BlockedNames[pattern]
+		}
+		if strings.HasPrefix(name, "*.") && !strings.Contains(pattern, "*") {
+			if label, parent, found := strings.Cut(pattern, "."); found && label != "" && parent == name[2:] {
+				return pattern, c.BlockedNames[pattern]
+			}
+		}
+	}
+	return "", ""
+}
+
+// validateBlockedNames returns an error naming the first of names blocked by
+// the configuration of the mount, along with the reason it is blocked.
+func (c *blockedNamesConfig) validateBlockedNames(kind string, names []string) error {
+	for _, name := range names {
+		pattern, reason := c.blockedBy(name)
+		if pattern == "" {
+			continue
+		}
+		msg := fmt.Sprintf("%s %s is blocked on this mount by %q", kind, name, pattern)
+		if reason != "" {
+			msg += ": " + reason
+		}
+		return errutil.UserError{Err: msg}
+	}
+	return nil
+}
+
+const pathConfigBlockedNamesHelpSyn = `
+Configure the names no certificate can be issued for on this mount.
+`
+
+const pathConfigBlockedNamesHelpDesc = `
+This path configures a mount-wide list of blocked names, such as compromised
+or reserved names. Issuance and signing requests whose common name or DNS or
+email subject alternative names match a blocked name are rejected before the
+allowed names of the role are checked, so no role can issue certificates for
+them. A wildcard name covering a blocked name, such as "*.example.com" for
+"admin.example.com", is rejected as well.
+`
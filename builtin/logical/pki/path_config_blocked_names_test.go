@@ -0,0 +1,88 @@
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_BlockedNames(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "48h",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	// A role allowing any name cannot issue blocked names
+	resp, err = CBWrite(b, s, "roles/any", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "ec",
+	})
+	require.NoError(t, err)
+
+	resp, err = CBWrite(b, s, "config/blocked-names", map[string]interface{}{
+		"blocked_names": map[string]interface{}{
+			"Login.Example.com":  "compromised",
+			"*.corp.example.com": "reserved",
+			"*@example.org":      "",
+		},
+	})
+	requireSuccessNilResponse(t, resp, err)
+
+	resp, err = CBRead(b, s, "config/blocked-names")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, map[string]string{
+		"login.example.com":  "compromised",
+		"*.corp.example.com": "reserved",
+		"*@example.org":      "",
+	}, resp.Data["blocked_names"])
+
+	for _, tc := range []struct {
+		name string
+		data map[string]interface{}
+		err  string
+	}{
+		{"exact common name", map[string]interface{}{"common_name": "LOGIN.example.com"}, `common name LOGIN.example.com is blocked on this mount by "login.example.com": compromised`},
+		{"excluded common name", map[string]interface{}{"common_name": "login.example.com", "exclude_cn_from_sans": true}, "common name login.example.com is blocked"},
+		{"glob alt name", map[string]interface{}{"common_name": "www.example.com", "alt_names": "vpn.corp.example.com"}, "subject alternate name vpn.corp.example.com is blocked"},
+		{"covering wildcard", map[string]interface{}{"common_name": "*.example.com"}, `blocked on this mount by "login.example.com"`},
+		{"email", map[string]interface{}{"common_name": "www.example.com", "alt_names": "admin@example.org"}, "email address admin@example.org is blocked"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CBWrite(b, s, "issue/any", tc.data)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+
+	// Other names are still issued
+	resp, err = CBWrite(b, s, "issue/any", map[string]interface{}{
+		"common_name": "www.example.com",
+		"alt_names":   "corp.example.com,*.www.example.com",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	// Signed CSRs are checked too
+	_, _, csrPem := generateCSR(t, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "login.example.com"},
+	}, "ec", 256)
+	resp, err = CBWrite(b, s, "sign-verbatim", map[string]interface{}{
+		"csr": csrPem,
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is blocked on this mount")
+
+	// Deleting the list unblocks the names
+	_, err = CBDelete(b, s, "config/blocked-names")
+	require.NoError(t, err)
+	resp, err = CBWrite(b, s, "issue/any", map[string]interface{}{
+		"common_name": "login.example.com",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+}
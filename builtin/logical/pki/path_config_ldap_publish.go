@@ -0,0 +1,511 @@
+package pki
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/ldaputil"
+	"github.com/hashicorp/vault/sdk/helper/tlsutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	ldapPublishConfigPath = "config/ldap-publish"
+	ldapPublishStatusPath = "ldap-publish-status"
+
+	defaultLDAPPublishRequestTimeout = 30
+
+	// Attributes of the certificationAuthority and cRLDistributionPoint
+	// object classes, which Active Directory uses for AIA and CDP entries.
+	ldapCACertificateAttr    = "cACertificate;binary"
+	ldapCRLAttr              = "certificateRevocationList;binary"
+	ldapDeltaCRLAttr         = "deltaRevocationList;binary"
+	ldapDNTemplateIssuerID   = "{{issuer_id}}"
+	ldapDNTemplateIssuerName = "{{issuer_name}}"
+	ldapDNTemplateCommonName = "{{common_name}}"
+)
+
+// ldapPublishConfig configures the publication of the CA certificates and
+// CRLs of the mount to an LDAP directory.
+type ldapPublishConfig struct {
+	Enabled        bool   `json:"enabled"`
+	URL            string `json:"url"`
+	BindDN         string `json:"binddn"`
+	BindPassword   string `json:"bindpass"`
+	Certificate    string `json:"certificate"`
+	InsecureTLS    bool   `json:"insecure_tls"`
+	StartTLS       bool   `json:"starttls"`
+	TLSMinVersion  string `json:"tls_min_version"`
+	RequestTimeout int    `json:"request_timeout"`
+
+	// CADNTemplate and CRLDNTemplate are the DNs of the entries holding the
+	// CA certificate and the CRLs of each issuer.
+	CADNTemplate  string `json:"ca_dn_template"`
+	CRLDNTemplate string `json:"crl_dn_template"`
+}
+
+func (c *ldapPublishConfig) ldapConfig() *ldaputil.ConfigEntry {
+	return &ldaputil.ConfigEntry{
+		Url:            c.URL,
+		BindDN:         c.BindDN,
+		BindPassword:   c.BindPassword,
+		Certificate:    c.Certificate,
+		InsecureTLS:    c.InsecureTLS,
+		StartTLS:       c.StartTLS,
+		TLSMinVersion:  c.TLSMinVersion,
+		RequestTimeout: c.RequestTimeout,
+	}
+}
+
+// ldapPublishStatus records the outcome of the last publication.
+type ldapPublishStatus struct {
+	LastPublishTime time.Time `json:"last_publish_time"`
+	LastError       string    `json:"last_error"`
+}
+
+func pathConfigLDAPPublish(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/ldap-publish",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type: framework.TypeBool,
+				Description: `Whether to publish the CA certificates and CRLs
+every time the CRLs are rebuilt.`,
+			},
+			"url": {
+				Type:        framework.TypeString,
+				Description: `Comma-separated list of LDAP URLs to try, in order.`,
+			},
+			"binddn": {
+				Type:        framework.TypeString,
+				Description: `DN of the account used to update the directory.`,
+			},
+			"bindpass": {
+				Type:        framework.TypeString,
+				Description: `Password of the bind DN.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+			"certificate": {
+				Type:        framework.TypeString,
+				Description: `PEM-encoded CA certificate used to verify the LDAP server.`,
+			},
+			"insecure_tls": {
+				Type:        framework.TypeBool,
+				Description: `Skip the verification of the LDAP server certificate.`,
+			},
+			"starttls": {
+				Type:        framework.TypeBool,
+				Description: `Issue a StartTLS command after connecting with an ldap:// URL.`,
+			},
+			"tls_min_version": {
+				Type:        framework.TypeString,
+				Description: `Minimum TLS version to use. Accepted values are 'tls10', 'tls11', 'tls12' or 'tls13'.`,
+			},
+			"request_timeout": {
+				Type:        framework.TypeDurationSecond,
+				Description: `Timeout, in seconds, of the requests to the LDAP server. Defaults to 30 seconds.`,
+			},
+			"ca_dn_template": {
+				Type: framework.TypeString,
+				Description: `DN of the entry holding the cACertificate of an
+issuer. {{issuer_id}}, {{issuer_name}} and {{common_name}} are replaced
+with the ID, name and subject common name of the issuer.`,
+			},
+			"crl_dn_template": {
+				Type: framework.TypeString,
+				Description: `DN of the entry holding the
+certificateRevocationList and deltaRevocationList of an issuer. Accepts
+the same placeholders as ca_dn_template.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathWriteLDAPPublishConfig,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathReadLDAPPublishConfig,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathDeleteLDAPPublishConfig,
+			},
+		},
+
+		HelpSynopsis:    pathConfigLDAPPublishHelpSyn,
+		HelpDescription: pathConfigLDAPPublishHelpDesc,
+	}
+}
+
+func pathLDAPPublish(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ldap-publish",
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathLDAPPublish,
+			},
+		},
+
+		HelpSynopsis:    pathLDAPPublishHelpSyn,
+		HelpDescription: pathLDAPPublishHelpDesc,
+	}
+}
+
+func getLDAPPublishConfig(ctx context.Context, storage logical.Storage) (*ldapPublishConfig, error) {
+	entry, err := storage.Get(ctx, ldapPublishConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config ldapPublishConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func getLDAPPublishStatus(ctx context.Context, storage logical.Storage) (*ldapPublishStatus, error) {
+	entry, err := storage.Get(ctx, ldapPublishStatusPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var status ldapPublishStatus
+	if entry != nil {
+		if err := entry.DecodeJSON(&status); err != nil {
+			return nil, err
+		}
+	}
+	return &status, nil
+}
+
+func (b *backend) pathReadLDAPPublishConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	config, err := getLDAPPublishConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+	status, err := getLDAPPublishStatus(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":         config.Enabled,
+			"url":             config.URL,
+			"binddn":          config.BindDN,
+			"certificate":     config.Certificate,
+			"insecure_tls":    config.InsecureTLS,
+			"starttls":        config.StartTLS,
+			"tls_min_version": config.TLSMinVersion,
+			"request_timeout": config.RequestTimeout,
+			"ca_dn_template":  config.CADNTemplate,
+			"crl_dn_template": config.CRLDNTemplate,
+			"last_error":      status.LastError,
+		},
+	}
+	if !status.LastPublishTime.IsZero() {
+		resp.Data["last_publish_time"] = status.LastPublishTime.Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+func (b *backend) pathWriteLDAPPublishConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getLDAPPublishConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &ldapPublishConfig{
+			RequestTimeout: defaultLDAPPublishRequestTimeout,
+		}
+	}
+
+	if v, ok := data.GetOk("enabled"); ok {
+		config.Enabled = v.(bool)
+	}
+	if v, ok := data.GetOk("url"); ok {
+		config.URL = strings.ToLower(strings.TrimSpace(v.(string)))
+	}
+	if v, ok := data.GetOk("binddn"); ok {
+		config.BindDN = v.(string)
+	}
+	if v, ok := data.GetOk("bindpass"); ok {
+		config.BindPassword = v.(string)
+	}
+	if v, ok := data.GetOk("certificate"); ok {
+		config.Certificate = v.(string)
+	}
+	if v, ok := data.GetOk("insecure_tls"); ok {
+		config.InsecureTLS = v.(bool)
+	}
+	if v, ok := data.GetOk("starttls"); ok {
+		config.StartTLS = v.(bool)
+	}
+	if v, ok := data.GetOk("tls_min_version"); ok {
+		config.TLSMinVersion = v.(string)
+	}
+	if v, ok := data.GetOk("request_timeout"); ok {
+		config.RequestTimeout = v.(int)
+	}
+	if v, ok := data.GetOk("ca_dn_template"); ok {
+		config.CADNTemplate = v.(string)
+	}
+	if v, ok := data.GetOk("crl_dn_template"); ok {
+		config.CRLDNTemplate = v.(string)
+	}
+
+	if config.URL == "" {
+		return logical.ErrorResponse("url is required"), nil
+	}
+	for _, u := range strings.Split(config.URL, ",") {
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "ldap" && parsed.Scheme != "ldaps") {
+			return logical.ErrorResponse(fmt.Sprintf("invalid LDAP URL %q", u)), nil
+		}
+	}
+	if config.CADNTemplate == "" && config.CRLDNTemplate == "" {
+		return logical.ErrorResponse("at least one of ca_dn_template and crl_dn_template is required"), nil
+	}
+	for _, template := range []string{config.CADNTemplate, config.CRLDNTemplate} {
+		if template == "" {
+			continue
+		}
+		if _, err := ldap.ParseDN(renderLDAPDN(template, &issuerEntry{ID: "id", Name: "name"}, "cn")); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid DN template %q: %v", template, err)), nil
+		}
+	}
+	if config.TLSMinVersion != "" {
+		if _, ok := tlsutil.TLSLookup[config.TLSMinVersion]; !ok {
+			return logical.ErrorResponse("invalid tls_min_version"), nil
+		}
+	}
+	if config.RequestTimeout < 0 {
+		return logical.ErrorResponse("request_timeout cannot be negative"), nil
+	}
+	if config.Certificate != "" {
+		if block, _ := pem.Decode([]byte(config.Certificate)); block == nil {
+			return logical.ErrorResponse("could not parse certificate"), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(ldapPublishConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+func (b *backend) pathDeleteLDAPPublishConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, ldapPublishStatusPath); err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Delete(ctx, ldapPublishConfigPath)
+}
+
+func (b *backend) pathLDAPPublish(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	config, err := getLDAPPublishConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("LDAP publishing is not configured"), nil
+	}
+
+	sc := b.makeStorageContext(ctx, req.Storage)
+	published, publishErr := b.publishToLDAP(sc, config)
+	if publishErr != nil {
+		return logical.ErrorResponse(publishErr.Error()), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"published": published,
+		},
+	}, nil
+}
+
+// publishToLDAPIfEnabled publishes the CA certificates and CRLs after the
+// CRLs were rebuilt. Failures are logged and recorded in the publication
+// status, but do not fail the rebuild.
+func (b *backend) publishToLDAPIfEnabled(sc *storageContext) {
+	config, err := getLDAPPublishConfig(sc.Context, sc.Storage)
+	if err != nil {
+		b.Logger().Error("failed to read LDAP publishing configuration", "error", err)
+		return
+	}
+	if config == nil || !config.Enabled {
+		return
+	}
+
+	if _, err := b.publishToLDAP(sc, config); err != nil {
+		b.Logger().Error("failed to publish to LDAP", "error", err)
+	}
+}
+
+// publishToLDAP replaces the CA certificate and CRL attributes of the
+// entries of every issuer, returning the DNs that were updated. The entries
+// must already exist in the directory.
+func (b *backend) publishToLDAP(sc *storageContext, config *ldapPublishConfig) ([]string, error) {
+	published, err := b.doPublishToLDAP(sc, config)
+
+	status := &ldapPublishStatus{
+		LastPublishTime: time.Now().UTC(),
+	}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	entry, putErr := logical.StorageEntryJSON(ldapPublishStatusPath, status)
+	if putErr == nil {
+		putErr = sc.Storage.Put(sc.Context, entry)
+	}
+	if putErr != nil {
+		b.Logger().Warn("failed to persist LDAP publishing status", "error", putErr)
+	}
+
+	return published, err
+}
+
+func (b *backend) doPublishToLDAP(sc *storageContext, config *ldapPublishConfig) ([]string, error) {
+	if b.useLegacyBundleCaStorage() {
+		return nil, errors.New("LDAP publishing requires the issuer storage migration to have completed")
+	}
+
+	issuers, err := sc.listIssuers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issuers: %w", err)
+	}
+	if len(issuers) == 0 {
+		return nil, nil
+	}
+
+	client := b.ldapPublishClient
+	if client == nil {
+		client = &ldaputil.Client{
+			LDAP:   ldaputil.NewLDAP(),
+			Logger: b.Logger(),
+		}
+	}
+	ldapConfig := config.ldapConfig()
+	conn, err := client.DialLDAP(ldapConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP: %w", err)
+	}
+	defer conn.Close()
+
+	if ldapConfig.BindPassword != "" {
+		err = conn.Bind(ldapConfig.BindDN, ldapConfig.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind(ldapConfig.BindDN)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind to LDAP: %w", err)
+	}
+
+	var published []string
+	for _, issuerId := range issuers {
+		issuer, err := sc.fetchIssuerById(issuerId)
+		if err != nil {
+			return published, err
+		}
+		cert, err := parseCertificateFromBytes([]byte(issuer.Certificate))
+		if err != nil {
+			return published, fmt.Errorf("failed to parse certificate of issuer %v: %w", issuerId, err)
+		}
+
+		if config.CADNTemplate != "" {
+			dn := renderLDAPDN(config.CADNTemplate, issuer, cert.Subject.CommonName)
+			modify := ldap.NewModifyRequest(dn, nil)
+			modify.Replace(ldapCACertificateAttr, []string{string(cert.Raw)})
+			if err := conn.Modify(modify); err != nil {
+				return published, fmt.Errorf("failed to publish CA certificate of issuer %v to %q: %w", issuerId, dn, err)
+			}
+			published = append(published, dn)
+		}
+
+		if config.CRLDNTemplate == "" {
+			continue
+		}
+		crlPath, err := sc.resolveIssuerCRLPath(string(issuerId))
+		if err != nil {
+			// The issuer may not be allowed to sign CRLs
+			continue
+		}
+		crl, err := sc.Storage.Get(sc.Context, crlPath)
+		if err != nil {
+			return published, err
+		}
+		if crl == nil || len(crl.Value) == 0 {
+			continue
+		}
+
+		dn := renderLDAPDN(config.CRLDNTemplate, issuer, cert.Subject.CommonName)
+		modify := ldap.NewModifyRequest(dn, nil)
+		modify.Replace(ldapCRLAttr, []string{string(crl.Value)})
+
+		delta, err := sc.Storage.Get(sc.Context, crlPath+deltaCRLPathSuffix)
+		if err != nil {
+			return published, err
+		}
+		if delta != nil && len(delta.Value) > 0 {
+			modify.Replace(ldapDeltaCRLAttr, []string{string(delta.Value)})
+		}
+
+		if err := conn.Modify(modify); err != nil {
+			return published, fmt.Errorf("failed to publish CRL of issuer %v to %q: %w", issuerId, dn, err)
+		}
+		published = append(published, dn)
+	}
+
+	return published, nil
+}
+
+func renderLDAPDN(template string, issuer *issuerEntry, commonName string) string {
+	return strings.NewReplacer(
+		ldapDNTemplateIssuerID, ldaputil.EscapeLDAPValue(string(issuer.ID)),
+		ldapDNTemplateIssuerName, ldaputil.EscapeLDAPValue(issuer.Name),
+		ldapDNTemplateCommonName, ldaputil.EscapeLDAPValue(commonName),
+	).Replace(template)
+}
+
+const pathConfigLDAPPublishHelpSyn = `
+Configure the publication of CA certificates and CRLs to an LDAP directory.
+`
+
+const pathConfigLDAPPublishHelpDesc = `
+This path configures the LDAP directory to which the CA certificates and
+CRLs of the issuers of this mount are published, such as the AIA and CDP
+containers of Active Directory referenced by ldap:// URLs in the
+issuing_certificates and crl_distribution_points of config/urls.
+
+The cACertificate;binary attribute of the entry named by ca_dn_template
+and the certificateRevocationList;binary and deltaRevocationList;binary
+attributes of the entry named by crl_dn_template are replaced; the entries
+must already exist. When enabled, publication happens every time the CRLs
+are rebuilt.
+`
+
+const pathLDAPPublishHelpSyn = `
+Publish the CA certificates and CRLs to the configured LDAP directory.
+`
+
+const pathLDAPPublishHelpDesc = `
+This path publishes the CA certificates and CRLs of all issuers to the
+directory configured on config/ldap-publish, even if automatic publication
+is disabled, and returns the DNs of the updated entries.
+`
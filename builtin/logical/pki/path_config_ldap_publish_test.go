@@ -0,0 +1,146 @@
+package pki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/vault/sdk/helper/ldaputil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLDAPDirectory records the attributes replaced on its entries.
+type fakeLDAPDirectory struct {
+	l       sync.Mutex
+	bindDN  string
+	entries map[string]map[string][]string
+}
+
+func (d *fakeLDAPDirectory) Dial(string, string) (ldaputil.Connection, error) {
+	return &fakeLDAPConnection{directory: d}, nil
+}
+
+func (d *fakeLDAPDirectory) DialTLS(string, string, *tls.Config) (ldaputil.Connection, error) {
+	return &fakeLDAPConnection{directory: d}, nil
+}
+
+type fakeLDAPConnection struct {
+	ldaputil.Connection
+	directory *fakeLDAPDirectory
+}
+
+func (c *fakeLDAPConnection) Bind(username, _ string) error {
+	c.directory.l.Lock()
+	defer c.directory.l.Unlock()
+	c.directory.bindDN = username
+	return nil
+}
+
+func (c *fakeLDAPConnection) Modify(req *ldap.ModifyRequest) error {
+	c.directory.l.Lock()
+	defer c.directory.l.Unlock()
+
+	entry, ok := c.directory.entries[req.DN]
+	if !ok {
+		return ldap.NewError(ldap.LDAPResultNoSuchObject, nil)
+	}
+	for _, change := range req.Changes {
+		entry[change.Modification.Type] = change.Modification.Vals
+	}
+	return nil
+}
+
+func (c *fakeLDAPConnection) SetTimeout(time.Duration) {}
+
+func (c *fakeLDAPConnection) Close() {}
+
+func TestBackend_LDAPPublish(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	directory := &fakeLDAPDirectory{
+		entries: map[string]map[string][]string{
+			"cn=Root CA,cn=AIA,cn=Public Key Services,dc=example,dc=com": {},
+			"cn=Root CA,cn=CDP,cn=Public Key Services,dc=example,dc=com": {},
+		},
+	}
+	b.ldapPublishClient = &ldaputil.Client{
+		LDAP:   directory,
+		Logger: b.Logger(),
+	}
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	caCert := parseCert(t, resp.Data["certificate"].(string))
+
+	// Publishing requires a configuration
+	_, err = CBWrite(b, s, "ldap-publish", nil)
+	require.Error(t, err)
+
+	_, err = CBWrite(b, s, "config/ldap-publish", map[string]interface{}{
+		"url":            "ftp://dc.example.com",
+		"ca_dn_template": "cn={{common_name}},dc=example,dc=com",
+	})
+	require.Error(t, err, "expected invalid URL to be rejected")
+
+	resp, err = CBWrite(b, s, "config/ldap-publish", map[string]interface{}{
+		"url":             "ldap://dc.example.com",
+		"binddn":          "cn=vault,dc=example,dc=com",
+		"bindpass":        "secret",
+		"ca_dn_template":  "cn={{common_name}},cn=AIA,cn=Public Key Services,dc=example,dc=com",
+		"crl_dn_template": "cn={{common_name}},cn=CDP,cn=Public Key Services,dc=example,dc=com",
+	})
+	requireSuccessNilResponse(t, resp, err)
+
+	resp, err = CBRead(b, s, "config/ldap-publish")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.NotContains(t, resp.Data, "bindpass")
+	require.Equal(t, false, resp.Data["enabled"])
+
+	resp, err = CBWrite(b, s, "ldap-publish", nil)
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Len(t, resp.Data["published"], 2)
+	require.Equal(t, "cn=vault,dc=example,dc=com", directory.bindDN)
+
+	aia := directory.entries["cn=Root CA,cn=AIA,cn=Public Key Services,dc=example,dc=com"]
+	require.Equal(t, []string{string(caCert.Raw)}, aia[ldapCACertificateAttr])
+
+	cdp := directory.entries["cn=Root CA,cn=CDP,cn=Public Key Services,dc=example,dc=com"]
+	require.Len(t, cdp[ldapCRLAttr], 1)
+	crl, err := x509.ParseRevocationList([]byte(cdp[ldapCRLAttr][0]))
+	require.NoError(t, err)
+	require.NoError(t, crl.CheckSignatureFrom(caCert))
+
+	// Once enabled, rebuilding the CRL publishes it
+	resp, err = CBWrite(b, s, "config/ldap-publish", map[string]interface{}{
+		"enabled": true,
+	})
+	requireSuccessNilResponse(t, resp, err)
+
+	resp, err = CBRead(b, s, "crl/rotate")
+	requireSuccessNonNilResponse(t, resp, err)
+
+	crl2, err := x509.ParseRevocationList([]byte(cdp[ldapCRLAttr][0]))
+	require.NoError(t, err)
+	require.Equal(t, 1, crl2.Number.Cmp(crl.Number), "expected the rebuilt CRL to be published")
+
+	resp, err = CBRead(b, s, "config/ldap-publish")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, "", resp.Data["last_error"])
+	require.NotEmpty(t, resp.Data["last_publish_time"])
+
+	// Missing entries are reported
+	delete(directory.entries, "cn=Root CA,cn=CDP,cn=Public Key Services,dc=example,dc=com")
+	_, err = CBWrite(b, s, "ldap-publish", nil)
+	require.Error(t, err)
+
+	resp, err = CBRead(b, s, "config/ldap-publish")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.NotEmpty(t, resp.Data["last_error"])
+}
@@ -0,0 +1,275 @@
+package pki
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/pgpkeys"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/shamir"
+)
+
+const (
+	keyEscrowPrefix = "config/key-escrow/"
+
+	// Upper bound on the number of custodians; matches the limit the
+	// shamir package places on the number of parts.
+	maxEscrowShares = 255
+)
+
+// keyEscrowEntry is the persisted audit record of a single split-knowledge
+// export of a key. The shares themselves are never stored; only enough
+// information to establish who received them and when.
+type keyEscrowEntry struct {
+	ID                   string    `json:"id"`
+	KeyID                keyID     `json:"key_id"`
+	SecretShares         int       `json:"secret_shares"`
+	SecretThreshold      int       `json:"secret_threshold"`
+	RecipientKeyIds      []string  `json:"recipient_key_ids"`
+	RequestorEntityID    string    `json:"requestor_entity_id"`
+	RequestorDisplayName string    `json:"requestor_display_name"`
+	Created              time.Time `json:"created"`
+}
+
+func pathEscrowKey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "key/" + framework.GenericNameRegex(keyRefParam) + "/escrow",
+
+		Fields: map[string]*framework.FieldSchema{
+			keyRefParam: {
+				Type:        framework.TypeString,
+				Description: `Reference to key; either "default" for the configured default key, an identifier of a key, or the name assigned to the key.`,
+				Default:     defaultRef,
+			},
+			"pgp_keys": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `List of base64-encoded PGP public keys, one per
+custodian. The key is split into one share per entry and each share is
+encrypted to the matching public key.`,
+			},
+			"secret_threshold": {
+				Type: framework.TypeInt,
+				Description: `Number of shares required to reconstruct the
+private key. Must be at least 2 and no greater than the number of pgp_keys.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:                    b.pathReadKeyEscrowHandler,
+				ForwardPerformanceStandby:   false,
+				ForwardPerformanceSecondary: false,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                    b.pathEscrowKeyHandler,
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathEscrowKeyHelpSyn,
+		HelpDescription: pathEscrowKeyHelpDesc,
+	}
+}
+
+const (
+	pathEscrowKeyHelpSyn  = `Export a key as PGP-encrypted Shamir shares for escrow under dual control.`
+	pathEscrowKeyHelpDesc = `Writing to /key/:ref/escrow splits the private key into
+one Shamir share per entry in pgp_keys, encrypting each share to its
+recipient. Any secret_threshold of the recipients can reconstruct the key
+offline; no single recipient learns anything about it.
+
+Each export is recorded, without the shares, and reading /key/:ref/escrow
+returns the history of exports of the key.
+
+Managed keys cannot be escrowed as their material is not held by Vault.
+`
+)
+
+func (b *backend) pathEscrowKeyHandler(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if b.useLegacyBundleCaStorage() {
+		return logical.ErrorResponse("Can not escrow keys until migration has completed"), nil
+	}
+
+	keyRef := data.Get(keyRefParam).(string)
+	if len(keyRef) == 0 {
+		return logical.ErrorResponse("missing key reference"), nil
+	}
+
+	pgpKeys := data.Get("pgp_keys").([]string)
+	threshold := data.Get("secret_threshold").(int)
+	switch {
+	case len(pgpKeys) < 2:
+		return logical.ErrorResponse("at least two pgp_keys are required for split-knowledge export"), nil
+	case len(pgpKeys) > maxEscrowShares:
+		return logical.ErrorResponse(fmt.Sprintf("at most %d pgp_keys may be specified", maxEscrowShares)), nil
+	case threshold < 2:
+		return logical.ErrorResponse("secret_threshold must be at least 2"), nil
+	case threshold > len(pgpKeys):
+		return logical.ErrorResponse("secret_threshold cannot exceed the number of pgp_keys"), nil
+	}
+
+	// Validate the recipients before touching key material so that a bad
+	// public key does not leave a half-finished export behind.
+	if _, err := pgpkeys.GetEntities(pgpKeys); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to parse pgp_keys: %v", err)), nil
+	}
+
+	// Hold the read lock while reading the key and writing the escrow
+	// record so the key cannot be deleted underneath us.
+	b.issuersLock.RLock()
+	defer b.issuersLock.RUnlock()
+
+	sc := b.makeStorageContext(ctx, req.Storage)
+	keyId, err := sc.resolveKeyReference(keyRef)
+	if err != nil {
+		return nil, err
+	}
+	if keyId == "" {
+		return logical.ErrorResponse("unable to resolve key id for reference" + keyRef), nil
+	}
+
+	key, err := sc.fetchKeyById(keyId)
+	if err != nil {
+		return nil, err
+	}
+	if key.isManagedPrivateKey() {
+		return logical.ErrorResponse("cannot escrow managed key %v: key material is not held by Vault", keyId), nil
+	}
+
+	shares, err := shamir.Split([]byte(key.PrivateKey), len(pgpKeys), threshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split key: %w", err)
+	}
+
+	recipientKeyIds, encryptedShares, err := pgpkeys.EncryptShares(shares, pgpKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key shares: %w", err)
+	}
+
+	escrowId, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &keyEscrowEntry{
+		ID:              escrowId,
+		KeyID:           keyId,
+		SecretShares:    len(pgpKeys),
+		SecretThreshold: threshold,
+		RecipientKeyIds: recipientKeyIds,
+		Created:         time.Now().UTC(),
+
+		RequestorEntityID:    req.EntityID,
+		RequestorDisplayName: req.DisplayName,
+	}
+
+	if err := sc.writeKeyEscrowEntry(entry); err != nil {
+		return nil, err
+	}
+
+	b.Logger().Info("exported key as escrow shares", "key_id", keyId, "escrow_id", escrowId,
+		"shares", len(pgpKeys), "threshold", threshold, "recipients", recipientKeyIds)
+
+	encodedShares := make([]string, 0, len(encryptedShares))
+	for _, share := range encryptedShares {
+		encodedShares = append(encodedShares, base64.StdEncoding.EncodeToString(share))
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			keyIdParam:          key.ID,
+			keyNameParam:        key.Name,
+			keyTypeParam:        string(key.PrivateKeyType),
+			"escrow_id":         escrowId,
+			"secret_threshold":  threshold,
+			"shares":            encodedShares,
+			"recipient_key_ids": recipientKeyIds,
+		},
+	}, nil
+}
+
+func (b *backend) pathReadKeyEscrowHandler(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if b.useLegacyBundleCaStorage() {
+		return logical.ErrorResponse("Can not read key escrow history until migration has completed"), nil
+	}
+
+	keyRef := data.Get(keyRefParam).(string)
+	if len(keyRef) == 0 {
+		return logical.ErrorResponse("missing key reference"), nil
+	}
+
+	sc := b.makeStorageContext(ctx, req.Storage)
+	keyId, err := sc.resolveKeyReference(keyRef)
+	if err != nil {
+		return nil, err
+	}
+	if keyId == "" {
+		return logical.ErrorResponse("unable to resolve key id for reference" + keyRef), nil
+	}
+
+	entries, err := sc.listKeyEscrowEntries(keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		history = append(history, map[string]interface{}{
+			"escrow_id":              entry.ID,
+			"secret_shares":          entry.SecretShares,
+			"secret_threshold":       entry.SecretThreshold,
+			"recipient_key_ids":      entry.RecipientKeyIds,
+			"requestor_entity_id":    entry.RequestorEntityID,
+			"requestor_display_name": entry.RequestorDisplayName,
+			"created":                entry.Created.Format(time.RFC3339),
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			keyIdParam: keyId,
+			"escrows":  history,
+		},
+	}, nil
+}
+
+func (sc *storageContext) writeKeyEscrowEntry(entry *keyEscrowEntry) error {
+	json, err := logical.StorageEntryJSON(keyEscrowPrefix+entry.KeyID.String()+"/"+entry.ID, entry)
+	if err != nil {
+		return err
+	}
+
+	return sc.Storage.Put(sc.Context, json)
+}
+
+func (sc *storageContext) listKeyEscrowEntries(id keyID) ([]*keyEscrowEntry, error) {
+	prefix := keyEscrowPrefix + id.String() + "/"
+	ids, err := sc.Storage.List(sc.Context, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*keyEscrowEntry, 0, len(ids))
+	for _, escrowId := range ids {
+		raw, err := sc.Storage.Get(sc.Context, prefix+escrowId)
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			continue
+		}
+
+		var entry keyEscrowEntry
+		if err := raw.DecodeJSON(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
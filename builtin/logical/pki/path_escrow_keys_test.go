@@ -0,0 +1,69 @@
+package pki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/pgpkeys"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/shamir"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPKI_EscrowKey(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "keys/generate/internal", map[string]interface{}{
+		"key_name": "escrowed",
+		"key_type": "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating key")
+
+	// Threshold above the number of recipients must be rejected.
+	_, err = CBWrite(b, s, "key/escrowed/escrow", map[string]interface{}{
+		"pgp_keys":         []string{pgpkeys.TestPubKey1, pgpkeys.TestPubKey2},
+		"secret_threshold": 3,
+	})
+	require.Error(t, err, "expected error with threshold exceeding shares")
+
+	resp, err = CBWrite(b, s, "key/escrowed/escrow", map[string]interface{}{
+		"pgp_keys":         []string{pgpkeys.TestPubKey1, pgpkeys.TestPubKey2, pgpkeys.TestPubKey3},
+		"secret_threshold": 2,
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed escrowing key")
+	shares := resp.Data["shares"].([]string)
+	require.Len(t, shares, 3)
+	require.Len(t, resp.Data["recipient_key_ids"], 3)
+
+	// Any two custodians together must recover the original key.
+	var plaintextShares [][]byte
+	for index, privKey := range []string{pgpkeys.TestPrivKey1, pgpkeys.TestPrivKey3} {
+		shareIndex := index * 2
+		ptBuf, err := pgpkeys.DecryptBytes(shares[shareIndex], privKey)
+		require.NoError(t, err)
+		plaintextShares = append(plaintextShares, ptBuf.Bytes())
+	}
+	recovered, err := shamir.Combine(plaintextShares)
+	require.NoError(t, err)
+
+	sc := b.makeStorageContext(context.Background(), s)
+	keyId, err := sc.resolveKeyReference("escrowed")
+	require.NoError(t, err)
+	key, err := sc.fetchKeyById(keyId)
+	require.NoError(t, err)
+	require.Equal(t, key.PrivateKey, string(recovered))
+
+	// The export must be recorded without the shares.
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation:  logical.ReadOperation,
+		Path:       "key/escrowed/escrow",
+		Storage:    s,
+		MountPoint: "pki/",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed reading escrow history")
+	history := resp.Data["escrows"].([]map[string]interface{})
+	require.Len(t, history, 1)
+	require.Equal(t, 2, history[0]["secret_threshold"])
+	require.NotContains(t, history[0], "shares")
+}
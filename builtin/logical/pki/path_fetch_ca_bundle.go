@@ -0,0 +1,299 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/keystore"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	truststoreConfigPath = "config/truststore"
+
+	truststoreFormatPKCS12 = "pkcs12"
+	truststoreFormatJKS    = "jks"
+)
+
+// truststoreConfig configures the truststore the CA chain can be downloaded
+// as by Java consumers.
+type truststoreConfig struct {
+	Format   string `json:"format"`
+	Password string `json:"password"`
+}
+
+func pathFetchCAChainPKCS7(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `ca_chain/p7b`,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathFetchCAChainPKCS7,
+			},
+		},
+
+		HelpSynopsis:    pathFetchCAChainPKCS7HelpSyn,
+		HelpDescription: pathFetchCAChainPKCS7HelpDesc,
+	}
+}
+
+func pathFetchCAChainTruststore(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `ca_chain/truststore`,
+
+		Fields: map[string]*framework.FieldSchema{
+			"format": {
+				Type: framework.TypeString,
+				Description: `Format of the truststore; "pkcs12" or "jks".
+Defaults to the format of the truststore configuration.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathFetchCAChainTruststore,
+			},
+		},
+
+		HelpSynopsis:    pathFetchCAChainTruststoreHelpSyn,
+		HelpDescription: pathFetchCAChainTruststoreHelpDesc,
+	}
+}
+
+func pathConfigTruststore(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/truststore",
+		Fields: map[string]*framework.FieldSchema{
+			"format": {
+				Type:        framework.TypeString,
+				Default:     truststoreFormatPKCS12,
+				Description: `Default format of the truststore; "pkcs12" or "jks". Defaults to "pkcs12".`,
+			},
+			"password": {
+				Type:        framework.TypeString,
+				Description: `Password protecting the integrity of the truststore.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathWriteTruststoreConfig,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathReadTruststoreConfig,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathDeleteTruststoreConfig,
+			},
+		},
+
+		HelpSynopsis:    pathConfigTruststoreHelpSyn,
+		HelpDescription: pathConfigTruststoreHelpDesc,
+	}
+}
+
+func getTruststoreConfig(ctx context.Context, storage logical.Storage) (*truststoreConfig, error) {
+	entry, err := storage.Get(ctx, truststoreConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config truststoreConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func validateTruststoreFormat(format string) error {
+	switch format {
+	case truststoreFormatPKCS12, truststoreFormatJKS:
+		return nil
+	default:
+		return fmt.Errorf(`invalid truststore format %q; must be "pkcs12" or "jks"`, format)
+	}
+}
+
+func (b *backend) pathReadTruststoreConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	config, err := getTruststoreConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"format":       config.Format,
+			"password_set": config.Password != "",
+		},
+	}, nil
+}
+
+func (b *backend) pathWriteTruststoreConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getTruststoreConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &truststoreConfig{
+			Format: truststoreFormatPKCS12,
+		}
+	}
+
+	if v, ok := data.GetOk("format"); ok {
+		config.Format = v.(string)
+	}
+	if v, ok := data.GetOk("password"); ok {
+		config.Password = v.(string)
+	}
+
+	if err := validateTruststoreFormat(config.Format); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if config.Password == "" {
+		return logical.ErrorResponse("password is required"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(truststoreConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathDeleteTruststoreConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, truststoreConfigPath); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// fetchCAChainCerts returns the full chain of the default issuer.
+func (b *backend) fetchCAChainCerts(ctx context.Context, req *logical.Request) ([]*x509.Certificate, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	caInfo, err := sc.fetchCAInfo(defaultRef, ReadOnlyUsage)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for _, block := range caInfo.GetFullChain() {
+		certs = append(certs, block.Certificate)
+	}
+	return certs, nil
+}
+
+func (b *backend) pathFetchCAChainPKCS7(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	certs, err := b.fetchCAChainCerts(ctx, req)
+	if err != nil {
+		if _, ok := err.(errutil.UserError); ok {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		return nil, err
+	}
+
+	p7b, err := keystore.EncodePKCS7Certs(certs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/x-pkcs7-certificates",
+			logical.HTTPRawBody:     p7b,
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+func (b *backend) pathFetchCAChainTruststore(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getTruststoreConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("the truststore has not been configured"), nil
+	}
+
+	format := config.Format
+	if v, ok := data.GetOk("format"); ok {
+		format = v.(string)
+	}
+	if err := validateTruststoreFormat(format); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	certs, err := b.fetchCAChainCerts(ctx, req)
+	if err != nil {
+		if _, ok := err.(errutil.UserError); ok {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		return nil, err
+	}
+
+	var truststore []byte
+	var contentType string
+	switch format {
+	case truststoreFormatJKS:
+		truststore, err = keystore.EncodeJKSTruststore(certs, config.Password)
+		contentType = "application/x-java-keystore"
+	default:
+		truststore, err = keystore.EncodePKCS12Truststore(certs, config.Password)
+		contentType = "application/x-pkcs12"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     truststore,
+			logical.HTTPStatusCode:  200,
+		},
+	}, nil
+}
+
+const pathFetchCAChainPKCS7HelpSyn = `
+Fetch the CA chain as PKCS#7.
+`
+
+const pathFetchCAChainPKCS7HelpDesc = `
+This endpoint returns the CA chain of the default issuer as a DER-encoded,
+certificates-only PKCS#7 structure, as found in .p7b files.
+`
+
+const pathFetchCAChainTruststoreHelpSyn = `
+Fetch the CA chain as a Java truststore.
+`
+
+const pathFetchCAChainTruststoreHelpDesc = `
+This endpoint returns the CA chain of the default issuer as a truststore
+holding a trusted certificate entry per certificate, protected with the
+password of the truststore configuration. The truststore is a PKCS#12 file
+or a Java KeyStore (JKS).
+`
+
+const pathConfigTruststoreHelpSyn = `
+Configure the truststore the CA chain can be fetched as.
+`
+
+const pathConfigTruststoreHelpDesc = `
+This endpoint configures the password and the default format of the
+truststore returned by the ca_chain/truststore endpoint. The password is
+never returned.
+`
@@ -0,0 +1,71 @@
+package pki
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/helper/keystore"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_CAChainBundles(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	// PKCS#7
+	resp, err = CBRead(b, s, "ca_chain/p7b")
+	require.NoError(t, err)
+	require.Equal(t, "application/x-pkcs7-certificates", resp.Data[logical.HTTPContentType])
+
+	certs, err := keystore.DecodePKCS7Certs(resp.Data[logical.HTTPRawBody].([]byte))
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	require.Equal(t, "Root CA", certs[0].Subject.CommonName)
+
+	// Truststores require a configured password
+	_, err = CBRead(b, s, "ca_chain/truststore")
+	require.Error(t, err)
+	_, err = CBWrite(b, s, "config/truststore", map[string]interface{}{
+		"format": "p12",
+	})
+	require.Error(t, err)
+	_, err = CBWrite(b, s, "config/truststore", map[string]interface{}{
+		"password": "changeit",
+	})
+	require.NoError(t, err)
+
+	resp, err = CBRead(b, s, "config/truststore")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, truststoreFormatPKCS12, resp.Data["format"])
+	require.Equal(t, true, resp.Data["password_set"])
+	require.NotContains(t, resp.Data, "password")
+
+	// PKCS#12
+	resp, err = CBRead(b, s, "ca_chain/truststore")
+	require.NoError(t, err)
+	require.Equal(t, "application/x-pkcs12", resp.Data[logical.HTTPContentType])
+
+	truststoreCerts, key, err := keystore.DecodePKCS12(resp.Data[logical.HTTPRawBody].([]byte), "changeit")
+	require.NoError(t, err)
+	require.Nil(t, key)
+	require.Equal(t, certs, truststoreCerts)
+
+	// JKS
+	resp, err = CBReq(b, s, logical.ReadOperation, "ca_chain/truststore", map[string]interface{}{
+		"format": truststoreFormatJKS,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "application/x-java-keystore", resp.Data[logical.HTTPContentType])
+
+	entries, err := keystore.DecodeJKS(resp.Data[logical.HTTPRawBody].([]byte), "changeit")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "0-root ca", entries[0].Alias)
+	require.Equal(t, certs, entries[0].Certificates)
+}
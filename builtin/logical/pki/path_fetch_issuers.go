@@ -119,6 +119,21 @@ which may not be known at modification time (such as with PKCS#11 managed
 RSA keys).`,
 		Default: "",
 	}
+	fields["serial_number_strategy"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `How serial numbers of certificates issued by this
+issuer are generated: "random" for random serial numbers of
+serial_number_bits bits; "time-prefixed" for the issuance time in
+nanoseconds followed by 64 random bits, so serial numbers sort in issuance
+order; or "sequential" for a counter shared by the sequential issuers of
+this mount, only suitable for closed ecosystems.`,
+		Default: serialNumberStrategyRandom,
+	}
+	fields["serial_number_bits"] = &framework.FieldSchema{
+		Type: framework.TypeInt,
+		Description: `Number of random bits of serial numbers with the
+"random" strategy, between 64 and 159. Defaults to 159.`,
+	}
 	fields["issuing_certificates"] = &framework.FieldSchema{
 		Type: framework.TypeCommaStringSlice,
 		Description: `Comma-separated list of URLs to be used
@@ -226,11 +241,21 @@ func respondReadIssuer(issuer *issuerEntry) (*logical.Response, error) {
 		"usage":                          issuer.Usage.Names(),
 		"revocation_signature_algorithm": revSigAlgStr,
 		"revoked":                        issuer.Revoked,
+		"serial_number_strategy":         serialNumberStrategyRandom,
+		"serial_number_bits":             defaultSerialNumberBits,
 		"issuing_certificates":           []string{},
 		"crl_distribution_points":        []string{},
 		"ocsp_servers":                   []string{},
 	}
 
+	if issuer.SerialNumberStrategy != "" {
+		// Bits only apply to random serial numbers.
+		data["serial_number_strategy"] = issuer.SerialNumberStrategy
+		data["serial_number_bits"] = 0
+	} else if issuer.SerialNumberBits != 0 {
+		data["serial_number_bits"] = issuer.SerialNumberBits
+	}
+
 	if issuer.Revoked {
 		data["revocation_time"] = issuer.RevocationTime
 		data["revocation_time_rfc3339"] = issuer.RevocationTimeUTC.Format(time.RFC3339Nano)
@@ -335,6 +360,16 @@ func (b *backend) pathUpdateIssuer(ctx context.Context, req *logical.Request, da
 		return nil, err
 	}
 
+	// Serial number strategy changes
+	serialNumberStrategy := data.Get("serial_number_strategy").(string)
+	serialNumberBits := data.Get("serial_number_bits").(int)
+	if err := validateSerialNumberStrategy(serialNumberStrategy, serialNumberBits); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if serialNumberStrategy == serialNumberStrategyRandom {
+		serialNumberStrategy = ""
+	}
+
 	// AIA access changes
 	issuerCertificates := data.Get("issuing_certificates").([]string)
 	if badURL := validateURLs(issuerCertificates); badURL != "" {
@@ -392,6 +427,12 @@ func (b *backend) pathUpdateIssuer(ctx context.Context, req *logical.Request, da
 		modified = true
 	}
 
+	if serialNumberStrategy != issuer.SerialNumberStrategy || serialNumberBits != issuer.SerialNumberBits {
+		issuer.SerialNumberStrategy = serialNumberStrategy
+		issuer.SerialNumberBits = serialNumberBits
+		modified = true
+	}
+
 	if issuer.AIAURIs == nil && (len(issuerCertificates) > 0 || len(crlDistributionPoints) > 0 || len(ocspServers) > 0) {
 		issuer.AIAURIs = &certutil.URLEntries{}
 	}
@@ -627,6 +668,35 @@ func (b *backend) pathPatchIssuer(ctx context.Context, req *logical.Request, dat
 		}
 	}
 
+	// Serial number strategy changes
+	rawSerialNumberStrategy, strategyOk := data.GetOk("serial_number_strategy")
+	rawSerialNumberBits, bitsOk := data.GetOk("serial_number_bits")
+	if strategyOk || bitsOk {
+		serialNumberStrategy := issuer.SerialNumberStrategy
+		if strategyOk {
+			serialNumberStrategy = rawSerialNumberStrategy.(string)
+			if serialNumberStrategy == serialNumberStrategyRandom {
+				serialNumberStrategy = ""
+			}
+		}
+		serialNumberBits := issuer.SerialNumberBits
+		if bitsOk {
+			serialNumberBits = rawSerialNumberBits.(int)
+		} else if serialNumberStrategy != "" {
+			// Bits only apply to random serial numbers.
+			serialNumberBits = 0
+		}
+		if err := validateSerialNumberStrategy(serialNumberStrategy, serialNumberBits); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		if serialNumberStrategy != issuer.SerialNumberStrategy || serialNumberBits != issuer.SerialNumberBits {
+			issuer.SerialNumberStrategy = serialNumberStrategy
+			issuer.SerialNumberBits = serialNumberBits
+			modified = true
+		}
+	}
+
 	// AIA access changes.
 	if issuer.AIAURIs == nil {
 		issuer.AIAURIs = &certutil.URLEntries{}
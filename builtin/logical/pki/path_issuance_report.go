@@ -0,0 +1,267 @@
+package pki
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	issuanceCountsPrefix = "issuance-counts/"
+
+	defaultMaxCertsPerEntityPeriod = 24 * time.Hour
+)
+
+// issuanceCount is the number of certificates issued with a role to an
+// entity, in total and over the current period of the role's cap.
+type issuanceCount struct {
+	DisplayName string    `json:"display_name"`
+	Total       uint64    `json:"total"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodCount uint64    `json:"period_count"`
+	LastIssued  time.Time `json:"last_issued"`
+}
+
+func maxCertsPerEntityPeriod(role *roleEntry) time.Duration {
+	if role.MaxCertsPerEntityPeriod == 0 {
+		return defaultMaxCertsPerEntityPeriod
+	}
+	return role.MaxCertsPerEntityPeriod
+}
+
+// tracksIssuance returns whether issuance with the role is counted. Roles
+// which don't store certificates don't write to storage on issuance, so they
+// are only counted when they cap issuance.
+func tracksIssuance(role *roleEntry) bool {
+	return !role.NoStore || role.MaxCertsPerEntity > 0
+}
+
+// issuanceEntity returns the key issuance by the request is counted under:
+// the entity of the request, or the accessor of its token for tokens without
+// an entity.
+func issuanceEntity(req *logical.Request) string {
+	switch {
+	case req.EntityID != "":
+		return req.EntityID
+	case req.ClientTokenAccessor != "":
+		return "accessor:" + req.ClientTokenAccessor
+	default:
+		return ""
+	}
+}
+
+func issuanceCountKey(roleName, entity string) string {
+	return issuanceCountsPrefix + roleName + "/" + entity
+}
+
+func pathIssuanceReport(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuance-report$",
+
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: `The role to report on. Defaults to all roles.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:                  b.pathIssuanceReportRead,
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathIssuanceReportHelpSyn,
+		HelpDescription: pathIssuanceReportHelpDesc,
+	}
+}
+
+func (b *backend) pathIssuanceReportRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var roleNames []string
+	if roleName := data.Get("role").(string); roleName != "" {
+		roleNames = []string{roleName}
+	} else {
+		keys, err := req.Storage.List(ctx, issuanceCountsPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list issuance counts: %w", err)
+		}
+		for _, key := range keys {
+			roleNames = append(roleNames, strings.TrimSuffix(key, "/"))
+		}
+	}
+
+	roles := make(map[string]interface{}, len(roleNames))
+	for _, roleName := range roleNames {
+		entities, err := req.Storage.List(ctx, issuanceCountsPrefix+roleName+"/")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list issuance counts: %w", err)
+		}
+
+		var maxCerts int
+		role, err := b.getRole(ctx, req.Storage, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			maxCerts = role.MaxCertsPerEntity
+		}
+
+		counts := make(map[string]interface{}, len(entities))
+		for _, entity := range entities {
+			count, err := b.loadIssuanceCount(ctx, req.Storage, roleName, entity)
+			if err != nil {
+				return nil, err
+			}
+			if count == nil {
+				continue
+			}
+
+			countData := map[string]interface{}{
+				"display_name": count.DisplayName,
+				"total":        count.Total,
+				"period_count": count.PeriodCount,
+				"period_start": count.PeriodStart.Format(time.RFC3339),
+				"last_issued":  count.LastIssued.Format(time.RFC3339),
+			}
+			if role != nil && role.MaxCertsPerEntity > 0 && time.Since(count.PeriodStart) >= maxCertsPerEntityPeriod(role) {
+				countData["period_count"] = 0
+			}
+			counts[entity] = countData
+		}
+
+		roles[roleName] = map[string]interface{}{
+			"max_certs_per_entity": maxCerts,
+			"entities":             counts,
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"roles": roles,
+		},
+	}, nil
+}
+
+func (b *backend) loadIssuanceCount(ctx context.Context, s logical.Storage, roleName, entity string) (*issuanceCount, error) {
+	entry, err := s.Get(ctx, issuanceCountKey(roleName, entity))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuance count: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var count issuanceCount
+	if err := entry.DecodeJSON(&count); err != nil {
+		return nil, fmt.Errorf("failed to decode issuance count: %w", err)
+	}
+	return &count, nil
+}
+
+func (b *backend) storeIssuanceCount(ctx context.Context, s logical.Storage, roleName, entity string, count *issuanceCount) error {
+	entry, err := logical.StorageEntryJSON(issuanceCountKey(roleName, entity), count)
+	if err != nil {
+		return fmt.Errorf("failed to encode issuance count: %w", err)
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to store issuance count: %w", err)
+	}
+	return nil
+}
+
+// reserveIssuance counts the issuance of a certificate with the role to the
+// entity of the request, failing with a user error if the entity reached the
+// cap of the role. The returned function releases the reservation, for when
+// the certificate ends up not being issued.
+func (b *backend) reserveIssuance(ctx context.Context, req *logical.Request, roleName string, role *roleEntry) (func(), error) {
+	entity := issuanceEntity(req)
+	if roleName == "" || entity == "" || !tracksIssuance(role) {
+		return func() {}, nil
+	}
+
+	b.issuanceCountsLock.Lock()
+	defer b.issuanceCountsLock.Unlock()
+
+	count, err := b.loadIssuanceCount(ctx, req.Storage, roleName, entity)
+	if err != nil {
+		return nil, err
+	}
+	if count == nil {
+		count = &issuanceCount{}
+	}
+
+	now := time.Now()
+	if now.Sub(count.PeriodStart) >= maxCertsPerEntityPeriod(role) {
+		count.PeriodStart = now
+		count.PeriodCount = 0
+	}
+	if role.MaxCertsPerEntity > 0 && count.PeriodCount >= uint64(role.MaxCertsPerEntity) {
+		return nil, errutil.UserError{Err: fmt.Sprintf(
+			"the limit of %d certificates per %s issued with role %q has been reached; retry after %s",
+			role.MaxCertsPerEntity, maxCertsPerEntityPeriod(role), roleName,
+			count.PeriodStart.Add(maxCertsPerEntityPeriod(role)).Format(time.RFC3339))}
+	}
+
+	periodStart := count.PeriodStart
+	count.DisplayName = req.DisplayName
+	count.Total++
+	count.PeriodCount++
+	count.LastIssued = now
+	if err := b.storeIssuanceCount(ctx, req.Storage, roleName, entity, count); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		b.issuanceCountsLock.Lock()
+		defer b.issuanceCountsLock.Unlock()
+
+		count, err := b.loadIssuanceCount(ctx, req.Storage, roleName, entity)
+		if err != nil || count == nil {
+			return
+		}
+		if count.Total > 0 {
+			count.Total--
+		}
+		if count.PeriodStart.Equal(periodStart) && count.PeriodCount > 0 {
+			count.PeriodCount--
+		}
+		if err := b.storeIssuanceCount(ctx, req.Storage, roleName, entity, count); err != nil {
+			b.Logger().Warn("failed to release issuance count", "role", roleName, "error", err)
+		}
+	}, nil
+}
+
+// deleteIssuanceCounts removes the issuance counts of a deleted role.
+func (b *backend) deleteIssuanceCounts(ctx context.Context, s logical.Storage, roleName string) error {
+	b.issuanceCountsLock.Lock()
+	defer b.issuanceCountsLock.Unlock()
+
+	entities, err := s.List(ctx, issuanceCountsPrefix+roleName+"/")
+	if err != nil {
+		return fmt.Errorf("failed to list issuance counts: %w", err)
+	}
+	for _, entity := range entities {
+		if err := s.Delete(ctx, issuanceCountKey(roleName, entity)); err != nil {
+			return fmt.Errorf("failed to delete issuance count: %w", err)
+		}
+	}
+	return nil
+}
+
+const pathIssuanceReportHelpSyn = `
+Report the number of certificates issued per entity.
+`
+
+const pathIssuanceReportHelpDesc = `
+This endpoint reports, for each role or for the given role, the number of
+certificates issued to each entity, in total and over the current period of
+the role's max_certs_per_entity cap. Issuance by tokens without an entity is
+reported by token accessor. Roles with no_store set are only counted when they
+cap issuance.
+`
@@ -0,0 +1,90 @@
+package pki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_IssuanceReport(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "48h",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	_, err = CBWrite(b, s, "roles/capped", map[string]interface{}{
+		"allowed_domains":      "example.com",
+		"allow_subdomains":     true,
+		"key_type":             "ec",
+		"max_certs_per_entity": 2,
+	})
+	require.NoError(t, err)
+
+	resp, err = CBRead(b, s, "roles/capped")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, 2, resp.Data["max_certs_per_entity"])
+	require.Equal(t, int64(86400), resp.Data["max_certs_per_entity_period"])
+
+	issue := func(entityID string) (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "issue/capped",
+			Storage:     s,
+			MountPoint:  "pki/",
+			EntityID:    entityID,
+			DisplayName: "approle-" + entityID,
+			Data: map[string]interface{}{
+				"common_name": "www.example.com",
+			},
+		})
+	}
+
+	// Each entity is capped separately
+	for i := 0; i < 2; i++ {
+		resp, err = issue("entity-a")
+		requireSuccessNonNilResponse(t, resp, err)
+	}
+	resp, err = issue("entity-a")
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), "limit of 2 certificates")
+
+	resp, err = issue("entity-b")
+	requireSuccessNonNilResponse(t, resp, err)
+
+	resp, err = CBRead(b, s, "issuance-report")
+	requireSuccessNonNilResponse(t, resp, err)
+	roles := resp.Data["roles"].(map[string]interface{})
+	capped := roles["capped"].(map[string]interface{})
+	require.Equal(t, 2, capped["max_certs_per_entity"])
+	entities := capped["entities"].(map[string]interface{})
+	require.Len(t, entities, 2)
+	entityA := entities["entity-a"].(map[string]interface{})
+	require.Equal(t, uint64(2), entityA["total"])
+	require.Equal(t, uint64(2), entityA["period_count"])
+	require.Equal(t, "approle-entity-a", entityA["display_name"])
+	require.Equal(t, uint64(1), entities["entity-b"].(map[string]interface{})["total"])
+
+	// Lifting the cap lets the entity issue again
+	_, err = CBPatch(b, s, "roles/capped", map[string]interface{}{
+		"max_certs_per_entity": 0,
+	})
+	require.NoError(t, err)
+	resp, err = issue("entity-a")
+	requireSuccessNonNilResponse(t, resp, err)
+
+	// Counts are removed with their role
+	_, err = CBDelete(b, s, "roles/capped")
+	require.NoError(t, err)
+	resp, err = CBRead(b, s, "issuance-report")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Empty(t, resp.Data["roles"])
+}
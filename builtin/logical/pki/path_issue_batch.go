@@ -0,0 +1,203 @@
+package pki
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+// maxBatchIssueItems bounds the number of certificates a single
+// issue-batch call may request, so that one caller cannot monopolize the
+// signing workers or produce an unbounded response.
+const maxBatchIssueItems = 250
+
+// issueBatchResponseItem represents the result of a single item of an
+// issue-batch request.
+type issueBatchResponseItem struct {
+	// Data holds the same fields returned by the issue endpoint.
+	Data map[string]interface{} `json:"data,omitempty" structs:"data" mapstructure:"data"`
+
+	// Warnings, if set, are the warnings generated while issuing this item.
+	Warnings []string `json:"warnings,omitempty" structs:"warnings" mapstructure:"warnings"`
+
+	// Error, if set represents a failure encountered while issuing the
+	// corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
+func pathIssueBatch(b *backend) *framework.Path {
+	pattern := "issue-batch/" + framework.GenericNameRegex("role")
+	return buildPathIssueBatch(b, pattern)
+}
+
+func pathIssuerIssueBatch(b *backend) *framework.Path {
+	pattern := "issuer/" + framework.GenericNameRegex(issuerRefParam) + "/issue-batch/" + framework.GenericNameRegex("role")
+	return buildPathIssueBatch(b, pattern)
+}
+
+func buildPathIssueBatch(b *backend, pattern string) *framework.Path {
+	ret := &framework.Path{
+		Pattern: pattern,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.metricsWrap("issue-batch", roleRequired, b.pathIssueBatch),
+			},
+		},
+
+		HelpSynopsis:    pathIssueBatchHelpSyn,
+		HelpDescription: pathIssueBatchHelpDesc,
+	}
+
+	ret.Fields = addIssuerRefField(map[string]*framework.FieldSchema{})
+	ret.Fields["role"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The desired role with configuration for this request`,
+	}
+	ret.Fields["batch_input"] = &framework.FieldSchema{
+		Type: framework.TypeSlice,
+		Description: fmt.Sprintf(`List of issuance requests. Each item
+accepts the same parameters as the issue endpoint. At most %d items may
+be submitted in one call.`, maxBatchIssueItems),
+	}
+
+	return ret
+}
+
+// pathIssueBatch issues a certificate and private key for each of the
+// submitted items, subject to role restrictions. Items are signed in
+// parallel; a failure of one item does not affect the others.
+func (b *backend) pathIssueBatch(ctx context.Context, req *logical.Request, data *framework.FieldData, role *roleEntry) (*logical.Response, error) {
+	if role.KeyType == "any" {
+		return logical.ErrorResponse("role key type \"any\" not allowed for issuing certificates, only signing"), nil
+	}
+
+	// Leases are tied to a single secret per response, so batch issuance
+	// can only be used with roles which do not generate leases.
+	if role.GenerateLease != nil && *role.GenerateLease {
+		return logical.ErrorResponse("batch issuance is not supported with roles that have generate_lease enabled"), nil
+	}
+
+	if !role.NoStore && b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+		return nil, logical.ErrReadOnly
+	}
+
+	batchInputRaw, ok := data.Raw["batch_input"]
+	if !ok || batchInputRaw == nil {
+		return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+	}
+
+	var batchInputItems []map[string]interface{}
+	if err := mapstructure.Decode(batchInputRaw, &batchInputItems); err != nil {
+		return nil, fmt.Errorf("failed to parse batch input: %w", err)
+	}
+	if len(batchInputItems) == 0 {
+		return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+	}
+	if len(batchInputItems) > maxBatchIssueItems {
+		return logical.ErrorResponse(fmt.Sprintf("batch input contains %d items; at most %d may be submitted", len(batchInputItems), maxBatchIssueItems)), logical.ErrInvalidRequest
+	}
+
+	// Each item is handled as though it was sent to the corresponding
+	// single-item issue endpoint, which determines how the issuer is
+	// selected.
+	roleName := data.Get("role").(string)
+	itemPath := "issue/" + roleName
+	issuerRef := ""
+	if _, ok := data.Raw[issuerRefParam]; ok {
+		issuerRef = getIssuerRef(data)
+		itemPath = "issuer/" + issuerRef + "/" + itemPath
+	}
+
+	itemSchema := addPKCS12Fields(addNonCACommonFields(map[string]*framework.FieldSchema{}))
+	batchResponseItems := make([]issueBatchResponseItem, len(batchInputItems))
+
+	workers := runtime.NumCPU()
+	if workers > len(batchInputItems) {
+		workers = len(batchInputItems)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				batchResponseItems[index] = b.issueBatchItem(ctx, req, itemPath, role, roleName, issuerRef, itemSchema, batchInputItems[index])
+			}
+		}()
+	}
+
+	for index := range batchInputItems {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"batch_results": batchResponseItems,
+		},
+	}, nil
+}
+
+func (b *backend) issueBatchItem(ctx context.Context, req *logical.Request, itemPath string, role *roleEntry, roleName string, issuerRef string, schema map[string]*framework.FieldSchema, raw map[string]interface{}) issueBatchResponseItem {
+	// The role and issuer are fixed by the batch request's path and
+	// cannot be overridden per item.
+	itemRaw := make(map[string]interface{}, len(raw)+2)
+	for k, v := range raw {
+		itemRaw[k] = v
+	}
+	itemRaw["role"] = roleName
+	if issuerRef != "" {
+		itemRaw[issuerRefParam] = issuerRef
+	}
+
+	itemData := &framework.FieldData{
+		Raw:    itemRaw,
+		Schema: schema,
+	}
+	if err := itemData.Validate(); err != nil {
+		return issueBatchResponseItem{Error: err.Error()}
+	}
+
+	itemReq := *req
+	itemReq.Path = itemPath
+	itemReq.Data = itemRaw
+
+	resp, err := b.pathIssueSignCert(ctx, &itemReq, itemData, role, false, false)
+	switch {
+	case err != nil:
+		return issueBatchResponseItem{Error: err.Error()}
+	case resp == nil:
+		return issueBatchResponseItem{Error: "no response generated for item"}
+	case resp.IsError():
+		return issueBatchResponseItem{Error: resp.Error().Error()}
+	}
+
+	return issueBatchResponseItem{
+		Data:     resp.Data,
+		Warnings: resp.Warnings,
+	}
+}
+
+const pathIssueBatchHelpSyn = `
+Request multiple certificates using a certain role with the provided details.
+`
+
+const pathIssueBatchHelpDesc = `
+This path allows requesting many certificates to be issued according to the
+policy of the given role in a single call. Each item of batch_input accepts
+the same parameters as the issue endpoint; its result, or the error that
+prevented issuance, is returned at the same position of batch_results.
+
+Items are issued in parallel. Batch issuance is not available for roles
+which generate leases.
+`
@@ -0,0 +1,61 @@
+package pki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPKI_IssueBatch(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root example.com",
+		"ttl":         "48h",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+
+	resp, err = CBWrite(b, s, "roles/example", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"key_type":         "ec",
+		"generate_lease":   false,
+	})
+	requireSuccessNilResponse(t, resp, err, "failed writing role")
+
+	resp, err = CBWrite(b, s, "issue-batch/example", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"common_name": "one.example.com"},
+			map[string]interface{}{"common_name": "two.example.com", "format": "der"},
+			map[string]interface{}{"common_name": "not-allowed.com"},
+		},
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed batch issuance")
+
+	results := resp.Data["batch_results"].([]issueBatchResponseItem)
+	require.Len(t, results, 3)
+	require.Empty(t, results[0].Error)
+	require.NotEmpty(t, results[0].Data["certificate"])
+	require.NotEmpty(t, results[0].Data["private_key"])
+	require.Empty(t, results[1].Error)
+	require.NotEmpty(t, results[1].Data["serial_number"])
+	require.NotEqual(t, results[0].Data["serial_number"], results[1].Data["serial_number"])
+	require.Contains(t, results[2].Error, "not-allowed.com")
+
+	// Roles generating leases cannot be used with batch issuance.
+	resp, err = CBWrite(b, s, "roles/leased", map[string]interface{}{
+		"allow_any_name": true,
+		"key_type":       "ec",
+		"generate_lease": true,
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed writing role")
+
+	_, err = CBWrite(b, s, "issue-batch/leased", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"common_name": "leased"},
+		},
+	})
+	require.Error(t, err, "expected error issuing batch with leased role")
+}
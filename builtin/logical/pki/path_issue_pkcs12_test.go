@@ -0,0 +1,65 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/keystore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_IssuePKCS12(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "48h",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	_, err = CBWrite(b, s, "roles/pfx", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"key_type":         "ec",
+	})
+	require.NoError(t, err)
+
+	// A password is generated unless one is supplied
+	for _, password := range []string{"", "s3cret"} {
+		resp, err = CBWrite(b, s, "issue/pfx", map[string]interface{}{
+			"common_name":     "www.example.com",
+			"format":          "pkcs12",
+			"pkcs12_password": password,
+		})
+		requireSuccessNonNilResponse(t, resp, err)
+		require.NotContains(t, resp.Data, "private_key")
+		require.NotContains(t, resp.Data, "certificate")
+		if password == "" {
+			require.NotEmpty(t, resp.Data["pkcs12_password"])
+			password = resp.Data["pkcs12_password"].(string)
+		} else {
+			require.NotContains(t, resp.Data, "pkcs12_password")
+		}
+
+		pfx, err := base64.StdEncoding.DecodeString(resp.Data["pkcs12"].(string))
+		require.NoError(t, err)
+		certs, key, err := keystore.DecodePKCS12(pfx, password)
+		require.NoError(t, err)
+		require.Len(t, certs, 2)
+		require.Equal(t, "www.example.com", certs[0].Subject.CommonName)
+		require.Equal(t, "Root CA", certs[1].Subject.CommonName)
+		require.NotNil(t, key)
+		require.True(t, key.(*ecdsa.PrivateKey).PublicKey.Equal(certs[0].PublicKey))
+	}
+
+	// PKCS#12 requires the key to be generated by Vault
+	_, err = CBWrite(b, s, "sign/pfx", map[string]interface{}{
+		"common_name": "www.example.com",
+		"format":      "pkcs12",
+		"csr":         "-----BEGIN CERTIFICATE REQUEST-----",
+	})
+	require.Error(t, err)
+}
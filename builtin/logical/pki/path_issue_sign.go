@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/hashicorp/vault/helper/keystore"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/certutil"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -41,7 +44,7 @@ func buildPathIssue(b *backend, pattern string) *framework.Path {
 		HelpDescription: pathIssueHelpDesc,
 	}
 
-	ret.Fields = addNonCACommonFields(map[string]*framework.FieldSchema{})
+	ret.Fields = addPKCS12Fields(addNonCACommonFields(map[string]*framework.FieldSchema{}))
 	return ret
 }
 
@@ -251,9 +254,10 @@ func (b *backend) pathSignVerbatim(ctx context.Context, req *logical.Request, da
 }
 
 func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, data *framework.FieldData, role *roleEntry, useCSR, useCSRValues bool) (*logical.Response, error) {
-	// If storing the certificate and on a performance standby, forward this request on to the primary
-	// Allow performance secondaries to generate and store certificates locally to them.
-	if !role.NoStore && b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+	// If storing the certificate or counting its issuance and on a performance standby, forward this
+	// request on to the primary. Allow performance secondaries to generate and store certificates
+	// locally to them.
+	if tracksIssuance(role) && b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
 		return nil, logical.ErrReadOnly
 	}
 
@@ -262,13 +266,14 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	// 1. On the legacy sign-verbatim paths, as we always provision an issuer
 	//    in both the role and role-less cases, and
 	// 2. On the legacy sign/:role or issue/:role paths, as the issuer was
-	//    set on the role directly (either via upgrade or not). Note that
+	//    set on the role directly (either via upgrade or not). The same
+	//    goes for renew/:serial, which is validated against a role. Note that
 	//    the updated issuer/:ref/{sign,issue}/:role path is not affected,
 	//    and we instead pull the issuer out of the path instead (which
 	//    allows users with access to those paths to manually choose their
 	//    issuer in desired scenarios).
 	var issuerName string
-	if strings.HasPrefix(req.Path, "sign-verbatim/") || strings.HasPrefix(req.Path, "sign/") || strings.HasPrefix(req.Path, "issue/") {
+	if strings.HasPrefix(req.Path, "sign-verbatim/") || strings.HasPrefix(req.Path, "sign/") || strings.HasPrefix(req.Path, "issue/") || strings.HasPrefix(req.Path, "renew/") {
 		issuerName = role.Issuer
 		if len(issuerName) == 0 {
 			issuerName = defaultRef
@@ -283,9 +288,21 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	}
 
 	format := getFormat(data)
+	if data.Get("format").(string) == "pkcs12" {
+		if useCSR {
+			return logical.ErrorResponse(
+				`the "pkcs12" format can only be used when the private key is generated by Vault`), nil
+		}
+		format = "pkcs12"
+	}
 	if format == "" {
 		return logical.ErrorResponse(
-			`the "format" path parameter must be "pem", "der", or "pem_bundle"`), nil
+			`the "format" path parameter must be "pem", "der", "pem_bundle" or "pkcs12"`), nil
+	}
+	holdForPossession := !useCSR && role.RequireProofOfPossession
+	if holdForPossession && (format == "pem_bundle" || format == "pkcs12") {
+		return logical.ErrorResponse(fmt.Sprintf(
+			`the %q format cannot be used with roles requiring proof of possession`, format)), nil
 	}
 
 	var caErr error
@@ -302,13 +319,42 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 		}
 	}
 
+	var roleName string
+	if r, ok, err := data.GetOkErr("role"); err == nil && ok {
+		roleName = r.(string)
+	}
+	release, err := b.reserveIssuance(ctx, req, roleName, role)
+	if err != nil {
+		if _, ok := err.(errutil.UserError); ok {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		return nil, err
+	}
+	issued := false
+	defer func() {
+		if !issued {
+			release()
+		}
+	}()
+
+	serialNumber, err := sc.generateSerialNumber(issuerName)
+	if err != nil {
+		return nil, err
+	}
+
+	blockedNames, err := getBlockedNamesConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
 	input := &inputBundle{
-		req:     req,
-		apiData: data,
-		role:    role,
+		req:          req,
+		apiData:      data,
+		role:         role,
+		serialNumber: serialNumber,
+		blockedNames: blockedNames,
 	}
 	var parsedBundle *certutil.ParsedCertBundle
-	var err error
 	var warnings []string
 	if useCSR {
 		parsedBundle, warnings, err = signCert(b, input, signingBundle, false, useCSRValues)
@@ -378,6 +424,27 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 			respData["private_key"] = base64.StdEncoding.EncodeToString(parsedBundle.PrivateKeyBytes)
 			respData["private_key_type"] = cb.PrivateKeyType
 		}
+
+	case "pkcs12":
+		password, generated, err := getPKCS12Password(data)
+		if err != nil {
+			return nil, err
+		}
+
+		var chain []*x509.Certificate
+		for _, block := range caChainGen.chain {
+			chain = append(chain, block.Certificate)
+		}
+		pfx, err := keystore.EncodePKCS12KeyStore(parsedBundle.PrivateKey, parsedBundle.Certificate, chain, parsedBundle.Certificate.Subject.CommonName, password)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding PKCS#12 file: %w", err)
+		}
+
+		respData["pkcs12"] = base64.StdEncoding.EncodeToString(pfx)
+		respData["private_key_type"] = cb.PrivateKeyType
+		if generated {
+			respData["pkcs12_password"] = password
+		}
 	default:
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
@@ -408,6 +475,15 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 		}
 	}
 
+	if holdForPossession {
+		resp, err = b.holdForProofOfPossession(ctx, req, role, parsedBundle, resp)
+		if err != nil {
+			return nil, err
+		}
+		issued = true
+		return addWarnings(resp, warnings), nil
+	}
+
 	if !role.NoStore {
 		key := "certs/" + normalizeSerial(cb.SerialNumber)
 		certsCounted := b.certsCounted.Load()
@@ -431,10 +507,25 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	}
 
 	resp = addWarnings(resp, warnings)
+	issued = true
 
 	return resp, nil
 }
 
+// getPKCS12Password returns the password protecting a PKCS#12 response, and
+// whether it was generated because none was supplied.
+func getPKCS12Password(data *framework.FieldData) (string, bool, error) {
+	if password, ok, _ := data.GetOkErr("pkcs12_password"); ok && password.(string) != "" {
+		return password.(string), false, nil
+	}
+
+	password, err := base62.Random(24)
+	if err != nil {
+		return "", false, fmt.Errorf("error generating PKCS#12 password: %w", err)
+	}
+	return password, true, nil
+}
+
 type caChainOutput struct {
 	chain []*certutil.CertBlock
 }
@@ -0,0 +1,355 @@
+package pki
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshBridgeConfigPath = "config/ssh-bridge"
+
+	// sshPrincipalPlaceholder is replaced by each principal of the SSH
+	// certificate in the ssh_principal_template of the role.
+	sshPrincipalPlaceholder = "{{principal}}"
+)
+
+// sshBridgeConfig holds the SSH certificate authorities whose certificates
+// can be exchanged for X.509 certificates.
+type sshBridgeConfig struct {
+	TrustedCAKeys []string `json:"trusted_ca_keys"`
+}
+
+func pathConfigSSHBridge(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/ssh-bridge",
+		Fields: map[string]*framework.FieldSchema{
+			"trusted_ca_keys": {
+				Type: framework.TypeStringSlice,
+				Description: `Public keys, in OpenSSH authorized_keys format, of the
+SSH certificate authorities whose user certificates can be exchanged on the
+issue-ssh endpoint, such as the public_key of an SSH secrets engine mount.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathWriteSSHBridgeConfig,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathReadSSHBridgeConfig,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathDeleteSSHBridgeConfig,
+			},
+		},
+
+		HelpSynopsis:    pathConfigSSHBridgeHelpSyn,
+		HelpDescription: pathConfigSSHBridgeHelpDesc,
+	}
+}
+
+func pathIssueSSH(b *backend) *framework.Path {
+	ret := &framework.Path{
+		Pattern: "issue-ssh/" + framework.GenericNameRegex("role"),
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.metricsWrap("issue-ssh", roleRequired, b.pathIssueSSH),
+			},
+		},
+
+		HelpSynopsis:    pathIssueSSHHelpSyn,
+		HelpDescription: pathIssueSSHHelpDesc,
+	}
+
+	ret.Fields = addNonCACommonFields(map[string]*framework.FieldSchema{})
+
+	ret.Fields["csr"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Default:     "",
+		Description: `PEM-format CSR to be signed.`,
+	}
+	ret.Fields["ssh_certificate"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `SSH user certificate, in OpenSSH format, signed by one of
+the trusted SSH certificate authorities.`,
+	}
+	ret.Fields["ssh_signature"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Base64-encoded SSH signature of the DER bytes of the CSR,
+made with the private key of the SSH certificate. It proves possession of the
+key, so that a certificate which leaked can't be exchanged.`,
+	}
+
+	return ret
+}
+
+func getSSHBridgeConfig(ctx context.Context, storage logical.Storage) (*sshBridgeConfig, error) {
+	entry, err := storage.Get(ctx, sshBridgeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &sshBridgeConfig{
+		TrustedCAKeys: []string{},
+	}
+	if entry == nil {
+		return config, nil
+	}
+
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (b *backend) pathReadSSHBridgeConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	config, err := getSSHBridgeConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"trusted_ca_keys": config.TrustedCAKeys,
+		},
+	}, nil
+}
+
+func (b *backend) pathWriteSSHBridgeConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getSSHBridgeConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if keysRaw, ok := data.GetOk("trusted_ca_keys"); ok {
+		config.TrustedCAKeys = config.TrustedCAKeys[:0]
+		for _, key := range keysRaw.([]string) {
+			key = strings.TrimSpace(key)
+			if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key)); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid SSH public key in trusted_ca_keys: %v", err)), nil
+			}
+			config.TrustedCAKeys = append(config.TrustedCAKeys, key)
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(sshBridgeConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+func (b *backend) pathDeleteSSHBridgeConfig(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, sshBridgeConfigPath)
+}
+
+// pathIssueSSH signs a CSR for the holder of a valid SSH user certificate.
+// The subject of the certificate is derived from the principals of the SSH
+// certificate, and its validity is capped at the one of the SSH certificate.
+func (b *backend) pathIssueSSH(ctx context.Context, req *logical.Request, data *framework.FieldData, role *roleEntry) (*logical.Response, error) {
+	if !role.AllowSSHBridge {
+		return logical.ErrorResponse("role does not allow exchanging SSH certificates"), nil
+	}
+
+	for _, field := range []string{"common_name", "alt_names"} {
+		if _, ok := data.Raw[field]; ok {
+			return logical.ErrorResponse(fmt.Sprintf("%s can't be set, it is derived from the principals of the SSH certificate", field)), nil
+		}
+	}
+
+	config, err := getSSHBridgeConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.TrustedCAKeys) == 0 {
+		return logical.ErrorResponse("no trusted SSH certificate authority is configured"), nil
+	}
+
+	sshCert, err := parseSSHUserCertificate(data.Get("ssh_certificate").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := verifySSHUserCertificate(sshCert, config.TrustedCAKeys, time.Now()); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	csrPEM := data.Get("csr").(string)
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil {
+		return logical.ErrorResponse("csr is not a PEM-encoded certificate signing request"), nil
+	}
+	if err := verifySSHSignature(sshCert.Key, block.Bytes, data.Get("ssh_signature").(string)); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	names := mapSSHPrincipals(sshCert.ValidPrincipals, role.SSHPrincipalTemplate)
+
+	// Never let the certificate outlive the SSH certificate it was exchanged
+	// for.
+	ttl := role.TTL
+	if ttlRaw, ok := data.GetOk("ttl"); ok {
+		ttl = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if sshCert.ValidBefore != ssh.CertTimeInfinity {
+		remaining := time.Until(time.Unix(int64(sshCert.ValidBefore), 0))
+		if ttl == 0 || ttl > remaining {
+			ttl = remaining
+		}
+	}
+
+	raw := make(map[string]interface{}, len(data.Raw)+3)
+	for k, v := range data.Raw {
+		raw[k] = v
+	}
+	raw["common_name"] = names[0]
+	raw["alt_names"] = strings.Join(names[1:], ",")
+	if ttl > 0 {
+		raw["ttl"] = int64(ttl.Seconds())
+	}
+	signData := &framework.FieldData{
+		Raw:    raw,
+		Schema: data.Schema,
+	}
+
+	// The subject comes from the SSH certificate, never from the CSR.
+	signRole := *role
+	signRole.UseCSRCommonName = false
+	signRole.UseCSRSANs = false
+
+	// Sign as though the request was sent to the sign endpoint of the role,
+	// which selects the issuer of the role.
+	signReq := *req
+	signReq.Path = "sign/" + data.Get("role").(string)
+
+	resp, err := b.pathIssueSignCert(ctx, &signReq, signData, &signRole, true, false)
+	if err != nil || resp == nil || resp.IsError() {
+		return resp, err
+	}
+
+	resp.Data["ssh_key_id"] = sshCert.KeyId
+	resp.Data["ssh_serial_number"] = sshCert.Serial
+	return resp, nil
+}
+
+func parseSSHUserCertificate(raw string) (*ssh.Certificate, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("missing ssh_certificate")
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh_certificate: %w", err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("ssh_certificate is a public key, not a certificate")
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("ssh_certificate is not a user certificate")
+	}
+	if len(cert.ValidPrincipals) == 0 {
+		return nil, fmt.Errorf("ssh_certificate has no principals")
+	}
+	return cert, nil
+}
+
+// verifySSHUserCertificate checks that the certificate is currently valid and
+// signed by one of the trusted authorities. Certificates with critical
+// options, such as source-address, are rejected as their restrictions can't
+// be carried over to the X.509 certificate.
+func verifySSHUserCertificate(cert *ssh.Certificate, trustedCAKeys []string, now time.Time) error {
+	trusted := make(map[string]bool, len(trustedCAKeys))
+	for _, key := range trustedCAKeys {
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return fmt.Errorf("invalid trusted SSH certificate authority: %w", err)
+		}
+		trusted[string(pub.Marshal())] = true
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return trusted[string(auth.Marshal())]
+		},
+		Clock: func() time.Time {
+			return now
+		},
+	}
+	if !checker.IsUserAuthority(cert.SignatureKey) {
+		return fmt.Errorf("ssh_certificate is not signed by a trusted SSH certificate authority")
+	}
+	if err := checker.CheckCert(cert.ValidPrincipals[0], cert); err != nil {
+		return fmt.Errorf("invalid ssh_certificate: %w", err)
+	}
+	return nil
+}
+
+func verifySSHSignature(key ssh.PublicKey, message []byte, signatureB64 string) error {
+	if signatureB64 == "" {
+		return fmt.Errorf("missing ssh_signature")
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(signatureB64)
+	if err != nil {
+		return fmt.Errorf("failed to decode ssh_signature: %w", err)
+	}
+
+	var signature ssh.Signature
+	if err := ssh.Unmarshal(signatureBytes, &signature); err != nil {
+		return fmt.Errorf("failed to parse ssh_signature: %w", err)
+	}
+	if err := key.Verify(message, &signature); err != nil {
+		return fmt.Errorf("ssh_signature does not match the key of the SSH certificate")
+	}
+	return nil
+}
+
+// mapSSHPrincipals applies the principal template of the role to each
+// principal; the first one is the common name of the certificate.
+func mapSSHPrincipals(principals []string, template string) []string {
+	if template == "" {
+		template = sshPrincipalPlaceholder
+	}
+
+	names := make([]string, 0, len(principals))
+	for _, principal := range principals {
+		names = append(names, strings.ReplaceAll(template, sshPrincipalPlaceholder, principal))
+	}
+	return names
+}
+
+const pathConfigSSHBridgeHelpSyn = `
+Configure the SSH certificate authorities trusted by the issue-ssh endpoint.
+`
+
+const pathConfigSSHBridgeHelpDesc = `
+This path configures the public keys of the SSH certificate authorities whose
+user certificates can be exchanged for X.509 certificates on the issue-ssh
+endpoint. The public key of an SSH secrets engine mount can be read from its
+public_key endpoint.
+`
+
+const pathIssueSSHHelpSyn = `
+Exchange an SSH user certificate for an X.509 certificate.
+`
+
+const pathIssueSSHHelpDesc = `
+This path signs a CSR for the holder of an SSH user certificate signed by a
+trusted SSH certificate authority, bridging SSH identities into mTLS. The
+ssh_signature must be the SSH signature of the DER bytes of the CSR made with
+the key of the SSH certificate.
+
+The common name and alternative names of the certificate are the principals
+of the SSH certificate, mapped through the ssh_principal_template of the role,
+and are subject to the same restrictions as any other name issued by the
+role. The certificate never outlives the SSH certificate. The role must be
+created with allow_ssh_bridge.
+`
@@ -0,0 +1,138 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPKI_IssueSSH(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "root example.com",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+
+	_, caPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	caSigner, err := ssh.NewSignerFromKey(caPriv)
+	require.NoError(t, err)
+
+	_, userPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	userSigner, err := ssh.NewSignerFromKey(userPriv)
+	require.NoError(t, err)
+
+	sshCert := &ssh.Certificate{
+		Key:             userSigner.PublicKey(),
+		Serial:          42,
+		CertType:        ssh.UserCert,
+		KeyId:           "alice-key",
+		ValidPrincipals: []string{"alice"},
+		ValidAfter:      uint64(time.Now().Add(-time.Minute).Unix()),
+		ValidBefore:     uint64(time.Now().Add(10 * time.Minute).Unix()),
+	}
+	require.NoError(t, sshCert.SignCert(rand.Reader, caSigner))
+	sshCertRaw := string(ssh.MarshalAuthorizedKey(sshCert))
+
+	csrKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "ignored.example.com"},
+	}, csrKey)
+	require.NoError(t, err)
+	csrPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))
+
+	signature, err := userSigner.Sign(rand.Reader, csrDER)
+	require.NoError(t, err)
+	signatureB64 := base64.StdEncoding.EncodeToString(ssh.Marshal(signature))
+
+	_, err = CBWrite(b, s, "roles/ssh", map[string]interface{}{
+		"allowed_domains":        "users.example.com",
+		"allow_subdomains":       true,
+		"allow_ssh_bridge":       true,
+		"ssh_principal_template": "{{principal}}.users.example.com",
+		"client_flag":            true,
+		"server_flag":            false,
+		"key_type":               "ec",
+		"ttl":                    "1h",
+	})
+	require.NoError(t, err, "failed writing role")
+
+	issue := map[string]interface{}{
+		"csr":             csrPEM,
+		"ssh_certificate": sshCertRaw,
+		"ssh_signature":   signatureB64,
+	}
+
+	// No trusted authority yet.
+	_, err = CBWrite(b, s, "issue-ssh/ssh", issue)
+	require.Error(t, err, "expected error without trusted authority")
+
+	_, err = CBWrite(b, s, "config/ssh-bridge", map[string]interface{}{
+		"trusted_ca_keys": []string{"not a key"},
+	})
+	require.Error(t, err, "expected error with invalid key")
+
+	_, err = CBWrite(b, s, "config/ssh-bridge", map[string]interface{}{
+		"trusted_ca_keys": []string{string(ssh.MarshalAuthorizedKey(caSigner.PublicKey()))},
+	})
+	require.NoError(t, err)
+
+	resp, err = CBWrite(b, s, "issue-ssh/ssh", issue)
+	requireSuccessNonNilResponse(t, resp, err, "failed exchanging SSH certificate")
+	require.Equal(t, "alice-key", resp.Data["ssh_key_id"])
+	require.Equal(t, uint64(42), resp.Data["ssh_serial_number"])
+
+	cert := parseCert(t, resp.Data["certificate"].(string))
+	require.Equal(t, "alice.users.example.com", cert.Subject.CommonName)
+	require.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, cert.ExtKeyUsage)
+	require.False(t, cert.NotAfter.After(time.Unix(int64(sshCert.ValidBefore), 0)),
+		"certificate outlives the SSH certificate")
+
+	// The names come from the SSH certificate only.
+	issue["common_name"] = "bob.users.example.com"
+	_, err = CBWrite(b, s, "issue-ssh/ssh", issue)
+	require.Error(t, err, "expected error when setting common_name")
+	delete(issue, "common_name")
+
+	// The signature must be made by the key of the SSH certificate.
+	otherSignature, err := caSigner.Sign(rand.Reader, csrDER)
+	require.NoError(t, err)
+	issue["ssh_signature"] = base64.StdEncoding.EncodeToString(ssh.Marshal(otherSignature))
+	_, err = CBWrite(b, s, "issue-ssh/ssh", issue)
+	require.Error(t, err, "expected error with signature of another key")
+	issue["ssh_signature"] = signatureB64
+
+	// Certificates of other authorities are rejected.
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherSigner, err := ssh.NewSignerFromKey(otherPriv)
+	require.NoError(t, err)
+	require.NoError(t, sshCert.SignCert(rand.Reader, otherSigner))
+	issue["ssh_certificate"] = string(ssh.MarshalAuthorizedKey(sshCert))
+	_, err = CBWrite(b, s, "issue-ssh/ssh", issue)
+	require.Error(t, err, "expected error with untrusted authority")
+	issue["ssh_certificate"] = sshCertRaw
+
+	// Roles must opt in.
+	_, err = CBPatch(b, s, "roles/ssh", map[string]interface{}{
+		"allow_ssh_bridge": false,
+	})
+	require.NoError(t, err)
+	_, err = CBWrite(b, s, "issue-ssh/ssh", issue)
+	require.Error(t, err, "expected error when the role does not allow the bridge")
+}
@@ -0,0 +1,332 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	ocspDelegatePrefix = "ocsp-delegate/"
+
+	defaultOcspDelegateTTL = 30 * 24 * time.Hour
+)
+
+// id-pkix-ocsp-nocheck from RFC 6960 Section 4.2.2.2.1: clients need not
+// check the revocation status of the delegated responder certificate.
+var ocspNoCheckOID = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+
+// ocspDelegateEntry is a responder certificate, issued by an issuer with the
+// id-kp-OCSPSigning extended key usage, whose key signs the OCSP responses
+// of that issuer instead of the issuer's own key.
+type ocspDelegateEntry struct {
+	IssuerID       issuerID                `json:"issuer_id"`
+	Certificate    string                  `json:"certificate"`
+	PrivateKey     string                  `json:"private_key"`
+	PrivateKeyType certutil.PrivateKeyType `json:"private_key_type"`
+	SerialNumber   string                  `json:"serial_number"`
+}
+
+func pathOcspDelegate(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex(issuerRefParam) + "/ocsp-delegate",
+		Fields: map[string]*framework.FieldSchema{
+			issuerRefParam: {
+				Type: framework.TypeString,
+				Description: `Reference to a existing issuer; either "default"
+for the configured default issuer, an identifier or the name assigned
+to the issuer.`,
+				Default: defaultRef,
+			},
+			"key_type": {
+				Type:        framework.TypeString,
+				Default:     "ec",
+				Description: `The type of key to generate for the responder; "rsa", "ec" or "ed25519".`,
+			},
+			"key_bits": {
+				Type:        framework.TypeInt,
+				Default:     0,
+				Description: `The number of bits to use for the responder key. Defaults to the default of the key type.`,
+			},
+			"ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `The validity period of the responder certificate; defaults
+to 30 days. It is capped to the validity period of the issuer.`,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathOcspDelegateRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathOcspDelegateWrite,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathOcspDelegateDelete,
+			},
+		},
+
+		HelpSynopsis:    pathOcspDelegateHelpSyn,
+		HelpDescription: pathOcspDelegateHelpDesc,
+	}
+}
+
+func (b *backend) pathOcspDelegateRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	issuerId, resp := b.resolveOcspDelegateIssuer(sc, data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	delegate, err := sc.fetchOcspDelegate(issuerId)
+	if err != nil {
+		return nil, err
+	}
+	if delegate == nil {
+		return nil, nil
+	}
+
+	return respondOcspDelegate(delegate)
+}
+
+func (b *backend) pathOcspDelegateWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	issuerId, resp := b.resolveOcspDelegateIssuer(sc, data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	keyType := data.Get("key_type").(string)
+	if keyType == "any" {
+		return logical.ErrorResponse("key_type must be set to rsa, ec or ed25519"), nil
+	}
+	keyBits, err := certutil.DefaultOrValueKeyBits(keyType, data.Get("key_bits").(int))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := certutil.ValidateKeyTypeLength(keyType, keyBits); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	ttl := defaultOcspDelegateTTL
+	if rawTTL, ok := data.GetOk("ttl"); ok {
+		ttl = time.Duration(rawTTL.(int)) * time.Second
+	}
+	if ttl <= 0 {
+		return logical.ErrorResponse("ttl must be greater than 0"), nil
+	}
+
+	caInfo, err := sc.fetchCAInfoByIssuerId(issuerId, OCSPSigningUsage)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+
+	serialNumber, err := sc.generateSerialNumber(issuerId.String())
+	if err != nil {
+		return nil, err
+	}
+
+	delegate, certBytes, err := createOcspDelegate(&caInfo.ParsedCertBundle, issuerId, serialNumber, keyType, keyBits, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the responder certificate like any other issued certificate, so
+	// it can be listed and revoked.
+	key := "certs/" + normalizeSerial(delegate.SerialNumber)
+	certsCounted := b.certsCounted.Load()
+	if err := req.Storage.Put(ctx, &logical.StorageEntry{
+		Key:   key,
+		Value: certBytes,
+	}); err != nil {
+		return nil, fmt.Errorf("unable to store certificate locally: %w", err)
+	}
+	b.incrementTotalCertificatesCount(certsCounted, key)
+
+	entry, err := logical.StorageEntryJSON(ocspDelegatePrefix+issuerId.String(), delegate)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return respondOcspDelegate(delegate)
+}
+
+func (b *backend) pathOcspDelegateDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	issuerId, resp := b.resolveOcspDelegateIssuer(sc, data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	if err := req.Storage.Delete(ctx, ocspDelegatePrefix+issuerId.String()); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) resolveOcspDelegateIssuer(sc *storageContext, data *framework.FieldData) (issuerID, *logical.Response) {
+	if b.useLegacyBundleCaStorage() {
+		return "", logical.ErrorResponse("Can not manage OCSP delegates until migration has completed")
+	}
+
+	issuerRef := getIssuerRef(data)
+	if issuerRef == "" {
+		return "", logical.ErrorResponse("%s parameter cannot be blank", issuerRefParam)
+	}
+
+	issuerId, err := sc.resolveIssuerReference(issuerRef)
+	if err != nil {
+		return "", logical.ErrorResponse(err.Error())
+	}
+	return issuerId, nil
+}
+
+func respondOcspDelegate(delegate *ocspDelegateEntry) (*logical.Response, error) {
+	cert, err := delegate.GetCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":     delegate.IssuerID,
+			"certificate":   delegate.Certificate,
+			"serial_number": delegate.SerialNumber,
+			"expiration":    cert.NotAfter.Unix(),
+		},
+	}, nil
+}
+
+// createOcspDelegate generates a new key and signs a responder certificate
+// for it with the issuer of caBundle. The certificate carries the
+// id-kp-OCSPSigning extended key usage and the id-pkix-ocsp-nocheck
+// extension, as required of delegated responders by RFC 6960.
+func createOcspDelegate(caBundle *certutil.ParsedCertBundle, issuerId issuerID, serialNumber *big.Int, keyType string, keyBits int, ttl time.Duration) (*ocspDelegateEntry, []byte, error) {
+	keyBundle := &certutil.ParsedCertBundle{}
+	if err := certutil.GeneratePrivateKey(keyType, keyBits, keyBundle); err != nil {
+		return nil, nil, fmt.Errorf("error generating responder key: %w", err)
+	}
+
+	subjKeyID, err := certutil.GetSubjKeyID(keyBundle.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.Add(ttl)
+	if notAfter.After(caBundle.Certificate.NotAfter) {
+		notAfter = caBundle.Certificate.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName: fmt.Sprintf("%s OCSP Responder", caBundle.Certificate.Subject.CommonName),
+		},
+		NotBefore:             now.Add(-30 * time.Second),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+		BasicConstraintsValid: true,
+		SubjectKeyId:          subjKeyID,
+		ExtraExtensions: []pkix.Extension{
+			{Id: ocspNoCheckOID, Value: asn1.NullBytes},
+		},
+	}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, caBundle.Certificate, keyBundle.PrivateKey.Public(), caBundle.PrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error signing responder certificate: %w", err)
+	}
+
+	keyBundle.CertificateBytes = certBytes
+	keyBundle.Certificate, err = x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	certBundle, err := keyBundle.ToCertBundle()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &ocspDelegateEntry{
+		IssuerID:       issuerId,
+		Certificate:    certBundle.Certificate,
+		PrivateKey:     certBundle.PrivateKey,
+		PrivateKeyType: certBundle.PrivateKeyType,
+		SerialNumber:   serialFromCert(keyBundle.Certificate),
+	}, certBytes, nil
+}
+
+func (d *ocspDelegateEntry) GetCertificate() (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(d.Certificate))
+	if block == nil {
+		return nil, fmt.Errorf("unable to parse OCSP delegate certificate: no PEM data")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// GetSigner returns the responder certificate and its key.
+func (d *ocspDelegateEntry) GetSigner() (*x509.Certificate, crypto.Signer, error) {
+	bundle := &certutil.CertBundle{
+		Certificate:    d.Certificate,
+		PrivateKey:     d.PrivateKey,
+		PrivateKeyType: d.PrivateKeyType,
+	}
+	parsed, err := bundle.ToParsedCertBundle()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse OCSP delegate: %w", err)
+	}
+	return parsed.Certificate, parsed.PrivateKey, nil
+}
+
+func (sc *storageContext) fetchOcspDelegate(issuerId issuerID) (*ocspDelegateEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, ocspDelegatePrefix+issuerId.String())
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var delegate ocspDelegateEntry
+	if err := entry.DecodeJSON(&delegate); err != nil {
+		return nil, err
+	}
+	return &delegate, nil
+}
+
+const pathOcspDelegateHelpSyn = `
+Manage the delegated OCSP responder certificate of an issuer.
+`
+
+const pathOcspDelegateHelpDesc = `
+Writing to this endpoint generates a new key and a responder certificate
+signed by the issuer, with the id-kp-OCSPSigning extended key usage and the
+id-pkix-ocsp-nocheck extension. While it is valid, OCSP responses for
+certificates of the issuer are signed with this key instead of the issuer's
+key, which is then only used when rotating the responder certificate.
+
+Reading returns the current responder certificate and deleting it reverts
+to signing OCSP responses with the issuer's key.
+`
@@ -0,0 +1,78 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestOcsp_DelegatedResponder(t *testing.T) {
+	t.Parallel()
+
+	b, s, testEnv := setupOcspEnv(t, "rsa")
+
+	resp, err := CBRead(b, s, "issuer/"+testEnv.issuerId1.String()+"/ocsp-delegate")
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	_, err = CBWrite(b, s, "issuer/"+testEnv.issuerId1.String()+"/ocsp-delegate", map[string]interface{}{
+		"key_type": "any",
+	})
+	require.Error(t, err)
+
+	resp, err = CBWrite(b, s, "issuer/"+testEnv.issuerId1.String()+"/ocsp-delegate", map[string]interface{}{
+		"key_type": "ec",
+		"ttl":      "1000h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "ocsp-delegate")
+	requireFieldsSetInResp(t, resp, "certificate", "serial_number", "expiration", "issuer_id")
+	delegateCert := parseCert(t, resp.Data["certificate"].(string))
+
+	require.NoError(t, delegateCert.CheckSignatureFrom(testEnv.issuer1))
+	require.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, delegateCert.ExtKeyUsage)
+	require.False(t, delegateCert.IsCA)
+	require.Equal(t, testEnv.issuer1.NotAfter, delegateCert.NotAfter, "responder validity should be capped to the issuer's")
+	hasNoCheck := false
+	for _, ext := range delegateCert.Extensions {
+		if ext.Id.Equal(ocspNoCheckOID) {
+			hasNoCheck = true
+		}
+	}
+	require.True(t, hasNoCheck, "missing id-pkix-ocsp-nocheck extension")
+
+	// The responder certificate can be revoked like other certificates
+	resp, err = CBRead(b, s, "cert/"+resp.Data["serial_number"].(string))
+	requireSuccessNonNilResponse(t, resp, err)
+
+	// Responses of the first issuer are signed by the delegate...
+	resp, err = SendOcspRequest(t, b, s, "get", testEnv.leafCertIssuer1, testEnv.issuer1, crypto.SHA256)
+	requireSuccessNonNilResponse(t, resp, err, "ocsp get request")
+	require.Equal(t, 200, resp.Data["http_status_code"])
+	ocspResp, err := ocsp.ParseResponse(resp.Data["http_raw_body"].([]byte), testEnv.issuer1)
+	require.NoError(t, err, "parsing ocsp get response")
+	require.Equal(t, ocsp.Good, ocspResp.Status)
+	require.NotNil(t, ocspResp.Certificate)
+	require.Equal(t, delegateCert.Raw, ocspResp.Certificate.Raw)
+	requireOcspResponseSignedBy(t, ocspResp, delegateCert)
+	require.Error(t, ocspResp.CheckSignatureFrom(testEnv.issuer1))
+
+	// ...but not those of the second issuer
+	resp, err = SendOcspRequest(t, b, s, "get", testEnv.leafCertIssuer2, testEnv.issuer2, crypto.SHA256)
+	requireSuccessNonNilResponse(t, resp, err, "ocsp get request")
+	ocspResp, err = ocsp.ParseResponse(resp.Data["http_raw_body"].([]byte), testEnv.issuer2)
+	require.NoError(t, err, "parsing ocsp get response")
+	requireOcspResponseSignedBy(t, ocspResp, testEnv.issuer2)
+
+	// Once deleted, the issuer signs its responses again
+	_, err = CBDelete(b, s, "issuer/"+testEnv.issuerId1.String()+"/ocsp-delegate")
+	require.NoError(t, err)
+
+	resp, err = SendOcspRequest(t, b, s, "get", testEnv.leafCertIssuer1, testEnv.issuer1, crypto.SHA256)
+	requireSuccessNonNilResponse(t, resp, err, "ocsp get request")
+	ocspResp, err = ocsp.ParseResponse(resp.Data["http_raw_body"].([]byte), testEnv.issuer1)
+	require.NoError(t, err, "parsing ocsp get response")
+	requireOcspResponseSignedBy(t, ocspResp, testEnv.issuer1)
+}
@@ -0,0 +1,230 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	pendingPossessionPrefix = "pop-pending/"
+
+	defaultProofOfPossessionWindow = 5 * time.Minute
+)
+
+// pendingPossessionCert is a certificate issued with a server-generated key
+// that is withheld until the client proves it holds the key, by signing
+// Nonce before ExpireTime.
+type pendingPossessionCert struct {
+	SerialNumber string                 `json:"serial_number"`
+	Nonce        string                 `json:"nonce"`
+	ExpireTime   time.Time              `json:"expire_time"`
+	Certificate  []byte                 `json:"certificate"`
+	Response     map[string]interface{} `json:"response"`
+}
+
+func proofOfPossessionWindow(role *roleEntry) time.Duration {
+	if role.ProofOfPossessionWindow == 0 {
+		return defaultProofOfPossessionWindow
+	}
+	return role.ProofOfPossessionWindow
+}
+
+func pathActivate(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `activate/(?P<serial>[0-9A-Fa-f-:]+)`,
+
+		Fields: map[string]*framework.FieldSchema{
+			"serial": {
+				Type: framework.TypeString,
+				Description: `Serial number of the certificate to activate, as
+returned by the issue endpoint.`,
+			},
+			"signature": {
+				Type: framework.TypeString,
+				Description: `Base64-encoded signature of the
+proof_of_possession_nonce made with the private key returned by the issue
+endpoint.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.metricsWrap("activate", noRole, b.pathActivateWrite),
+			},
+		},
+
+		HelpSynopsis:    pathActivateHelpSyn,
+		HelpDescription: pathActivateHelpDesc,
+	}
+}
+
+// holdForProofOfPossession stores the certificate of resp as pending instead
+// of publishing it, and strips it from the response in favor of a nonce the
+// client must sign with the returned private key.
+func (b *backend) holdForProofOfPossession(ctx context.Context, req *logical.Request, role *roleEntry, parsedBundle *certutil.ParsedCertBundle, resp *logical.Response) (*logical.Response, error) {
+	nonce, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+
+	serial := resp.Data["serial_number"].(string)
+	pending := &pendingPossessionCert{
+		SerialNumber: serial,
+		Nonce:        base64.StdEncoding.EncodeToString(nonce),
+		ExpireTime:   time.Now().Add(proofOfPossessionWindow(role)),
+		Certificate:  parsedBundle.CertificateBytes,
+		Response:     map[string]interface{}{},
+	}
+	for _, field := range []string{"certificate", "issuing_ca", "ca_chain"} {
+		if value, ok := resp.Data[field]; ok {
+			pending.Response[field] = value
+			delete(resp.Data, field)
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(pendingPossessionPrefix+normalizeSerial(serial), pending)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, fmt.Errorf("unable to store pending certificate: %w", err)
+	}
+
+	resp.Data["proof_of_possession_nonce"] = pending.Nonce
+	resp.Data["proof_of_possession_expiration"] = pending.ExpireTime.Unix()
+	return resp, nil
+}
+
+func (b *backend) pathActivateWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, _ *roleEntry) (*logical.Response, error) {
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+		return nil, logical.ErrReadOnly
+	}
+
+	serial := normalizeSerial(data.Get("serial").(string))
+	if serial == "" {
+		return logical.ErrorResponse("the serial number must be provided"), nil
+	}
+	signature, err := base64.StdEncoding.DecodeString(data.Get("signature").(string))
+	if err != nil || len(signature) == 0 {
+		return logical.ErrorResponse("the signature must be provided in base64"), nil
+	}
+
+	raw, err := req.Storage.Get(ctx, pendingPossessionPrefix+serial)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return logical.ErrorResponse(fmt.Sprintf("no certificate with serial %s is waiting for activation", serial)), nil
+	}
+	var pending pendingPossessionCert
+	if err := raw.DecodeJSON(&pending); err != nil {
+		return nil, err
+	}
+	if time.Now().After(pending.ExpireTime) {
+		if err := req.Storage.Delete(ctx, pendingPossessionPrefix+serial); err != nil {
+			return nil, err
+		}
+		return logical.ErrorResponse(fmt.Sprintf("the activation window of the certificate with serial %s has passed", serial)), nil
+	}
+
+	cert, err := x509.ParseCertificate(pending.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse pending certificate: %w", err)
+	}
+	if err := verifyProofOfPossession(cert, pending.Nonce, signature); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid proof of possession: %s", err)), nil
+	}
+
+	key := "certs/" + serial
+	certsCounted := b.certsCounted.Load()
+	if err := req.Storage.Put(ctx, &logical.StorageEntry{
+		Key:   key,
+		Value: pending.Certificate,
+	}); err != nil {
+		return nil, fmt.Errorf("unable to store certificate locally: %w", err)
+	}
+	b.incrementTotalCertificatesCount(certsCounted, key)
+
+	if err := req.Storage.Delete(ctx, pendingPossessionPrefix+serial); err != nil {
+		return nil, err
+	}
+
+	respData := pending.Response
+	respData["serial_number"] = pending.SerialNumber
+	respData["expiration"] = cert.NotAfter.Unix()
+	return &logical.Response{
+		Data: respData,
+	}, nil
+}
+
+// verifyProofOfPossession checks the signature of nonce by the key of cert:
+// PKCS#1 v1.5 over SHA-256 for RSA, ASN.1 ECDSA over SHA-256, or Ed25519
+// over the nonce itself.
+func verifyProofOfPossession(cert *x509.Certificate, nonce string, signature []byte) error {
+	var algo x509.SignatureAlgorithm
+	switch cert.PublicKeyAlgorithm {
+	case x509.RSA:
+		algo = x509.SHA256WithRSA
+	case x509.ECDSA:
+		algo = x509.ECDSAWithSHA256
+	case x509.Ed25519:
+		algo = x509.PureEd25519
+	default:
+		return fmt.Errorf("unsupported key type %s", cert.PublicKeyAlgorithm)
+	}
+	return cert.CheckSignature(algo, []byte(nonce), signature)
+}
+
+// tidyPendingPossessionCerts removes the certificates whose activation window
+// has passed.
+func (b *backend) tidyPendingPossessionCerts(ctx context.Context, s logical.Storage) error {
+	serials, err := s.List(ctx, pendingPossessionPrefix)
+	if err != nil {
+		return fmt.Errorf("error fetching list of pending certificates: %w", err)
+	}
+
+	for _, serial := range serials {
+		raw, err := s.Get(ctx, pendingPossessionPrefix+serial)
+		if err != nil {
+			return fmt.Errorf("error fetching pending certificate %q: %w", serial, err)
+		}
+		if raw != nil {
+			var pending pendingPossessionCert
+			if err := raw.DecodeJSON(&pending); err != nil {
+				return fmt.Errorf("error decoding pending certificate %q: %w", serial, err)
+			}
+			if time.Now().Before(pending.ExpireTime) {
+				continue
+			}
+		}
+		if err := s.Delete(ctx, pendingPossessionPrefix+serial); err != nil {
+			return fmt.Errorf("error deleting pending certificate %q: %w", serial, err)
+		}
+	}
+	return nil
+}
+
+const pathActivateHelpSyn = `
+Activate a certificate by proving possession of its private key.
+`
+
+const pathActivateHelpDesc = `
+When a role sets require_proof_of_possession, certificates issued with a
+server-generated key are not returned nor stored by the issue endpoint.
+Instead, it returns the private key along with a nonce, which must be signed
+with that key and sent to this endpoint within the proof_of_possession_window
+of the role. The certificate is then stored and returned.
+
+RSA keys sign with PKCS#1 v1.5 and ECDSA keys with ASN.1 signatures, both
+over the SHA-256 digest of the nonce string; Ed25519 keys sign the nonce
+string itself.
+`
@@ -0,0 +1,101 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_ProofOfPossession(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "48h",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	_, err = CBWrite(b, s, "roles/pop", map[string]interface{}{
+		"allowed_domains":             "example.com",
+		"allow_subdomains":            true,
+		"key_type":                    "ec",
+		"require_proof_of_possession": true,
+		"generate_lease":              true,
+	})
+	require.Error(t, err, "expected generate_lease to be rejected")
+
+	_, err = CBWrite(b, s, "roles/pop", map[string]interface{}{
+		"allowed_domains":             "example.com",
+		"allow_subdomains":            true,
+		"key_type":                    "ec",
+		"require_proof_of_possession": true,
+	})
+	require.NoError(t, err)
+
+	resp, err = CBRead(b, s, "roles/pop")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, true, resp.Data["require_proof_of_possession"])
+	require.Equal(t, int64(300), resp.Data["proof_of_possession_window"])
+
+	_, err = CBWrite(b, s, "issue/pop", map[string]interface{}{
+		"common_name": "www.example.com",
+		"format":      "pem_bundle",
+	})
+	require.Error(t, err)
+
+	resp, err = CBWrite(b, s, "issue/pop", map[string]interface{}{
+		"common_name": "www.example.com",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	require.NotContains(t, resp.Data, "certificate")
+	require.NotEmpty(t, resp.Data["proof_of_possession_expiration"])
+	serial := resp.Data["serial_number"].(string)
+	nonce := resp.Data["proof_of_possession_nonce"].(string)
+
+	block, _ := pem.Decode([]byte(resp.Data["private_key"].(string)))
+	require.NotNil(t, block)
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	require.NoError(t, err)
+
+	// The certificate is not published until activated
+	resp, err = CBRead(b, s, "cert/"+serial)
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	// A signature of something else is rejected
+	digest := sha256.Sum256([]byte("not the nonce"))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	_, err = CBWrite(b, s, "activate/"+serial, map[string]interface{}{
+		"signature": base64.StdEncoding.EncodeToString(sig),
+	})
+	require.Error(t, err)
+
+	digest = sha256.Sum256([]byte(nonce))
+	sig, err = ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	resp, err = CBWrite(b, s, "activate/"+serial, map[string]interface{}{
+		"signature": base64.StdEncoding.EncodeToString(sig),
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	cert := parseCert(t, resp.Data["certificate"].(string))
+	require.Equal(t, "www.example.com", cert.Subject.CommonName)
+	require.True(t, key.PublicKey.Equal(cert.PublicKey))
+
+	resp, err = CBRead(b, s, "cert/"+serial)
+	requireSuccessNonNilResponse(t, resp, err)
+
+	// Activation happens once
+	_, err = CBWrite(b, s, "activate/"+serial, map[string]interface{}{
+		"signature": base64.StdEncoding.EncodeToString(sig),
+	})
+	require.Error(t, err)
+}
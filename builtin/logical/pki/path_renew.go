@@ -0,0 +1,205 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRenew(b *backend) *framework.Path {
+	ret := &framework.Path{
+		Pattern: `renew/(?P<serial>[0-9A-Fa-f-:]+)`,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.metricsWrap("renew", roleRequired, b.pathRenew),
+			},
+		},
+
+		HelpSynopsis:    pathRenewHelpSyn,
+		HelpDescription: pathRenewHelpDesc,
+	}
+
+	ret.Fields = renewFields(map[string]*framework.FieldSchema{})
+	return ret
+}
+
+func renewFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields = addPKCS12Fields(addNonCACommonFields(fields))
+
+	fields["serial"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Serial number of the certificate to renew, in
+hyphen-separated or colon-separated hexadecimal.`,
+	}
+	fields["csr"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `PEM-format CSR holding the key of the renewed
+certificate. If omitted, a new key is generated.`,
+	}
+
+	return fields
+}
+
+// pathRenew issues a new certificate with the subject, alternative names
+// and extended key usages of a stored certificate, validated against the
+// current state of the role.
+func (b *backend) pathRenew(ctx context.Context, req *logical.Request, data *framework.FieldData, role *roleEntry) (*logical.Response, error) {
+	serial := data.Get("serial").(string)
+	if serial == "" || serial == legacyCRLPath || serial == deltaCRLPath {
+		return logical.ErrorResponse("the serial number of a certificate must be provided"), nil
+	}
+
+	certEntry, err := fetchCertBySerial(ctx, b, req, "certs/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil {
+		return logical.ErrorResponse(fmt.Sprintf("certificate with serial %s not found", serial)), nil
+	}
+	cert, err := x509.ParseCertificate(certEntry.Value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse stored certificate: %w", err)
+	}
+	if cert.IsCA {
+		return logical.ErrorResponse("CA certificates cannot be renewed"), nil
+	}
+
+	revokedEntry, err := fetchCertBySerial(ctx, b, req, "revoked/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if revokedEntry != nil {
+		return logical.ErrorResponse(fmt.Sprintf("certificate with serial %s is revoked", serial)), nil
+	}
+
+	useCSR := data.Get("csr").(string) != ""
+	if !useCSR && role.KeyType == "any" {
+		return logical.ErrorResponse("role key type \"any\" not allowed for issuing certificates, only signing; provide a csr"), nil
+	}
+
+	if err := checkRenewalExtKeyUsages(cert, role); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	// Replay the original request; only the output options and lifetime
+	// may be overridden by the caller.
+	raw := map[string]interface{}{
+		"role":                 data.Get("role"),
+		"csr":                  data.Get("csr"),
+		"common_name":          cert.Subject.CommonName,
+		"alt_names":            strings.Join(append(append([]string{}, cert.DNSNames...), cert.EmailAddresses...), ","),
+		"exclude_cn_from_sans": true,
+		"format":               data.Get("format"),
+		"private_key_format":   data.Get("private_key_format"),
+		"pkcs12_password":      data.Get("pkcs12_password"),
+	}
+	if v, ok := data.GetOk("remove_roots_from_chain"); ok {
+		raw["remove_roots_from_chain"] = v
+	}
+	if cert.Subject.SerialNumber != "" {
+		raw["serial_number"] = cert.Subject.SerialNumber
+	}
+
+	var ipSANs []string
+	for _, ip := range cert.IPAddresses {
+		ipSANs = append(ipSANs, ip.String())
+	}
+	raw["ip_sans"] = ipSANs
+
+	var uriSANs []string
+	for _, uri := range cert.URIs {
+		uriSANs = append(uriSANs, uri.String())
+	}
+	raw["uri_sans"] = uriSANs
+
+	otherSANs, err := getOtherSANsFromX509Extensions(cert.Extensions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse other SANs of stored certificate: %w", err)
+	}
+	var otherSANStrings []string
+	for _, otherSAN := range otherSANs {
+		otherSANStrings = append(otherSANStrings, otherSAN.oid+";UTF8:"+otherSAN.value)
+	}
+	raw["other_sans"] = otherSANStrings
+
+	switch {
+	case data.Get("not_after").(string) != "":
+		raw["not_after"] = data.Get("not_after")
+	case data.Get("ttl").(int) != 0:
+		raw["ttl"] = data.Get("ttl")
+	default:
+		// Keep the lifetime of the original certificate, without the
+		// backdating of its NotBefore
+		lifetime := cert.NotAfter.Sub(cert.NotBefore) - role.NotBeforeDuration
+		raw["ttl"] = strconv.FormatInt(int64(lifetime.Seconds()), 10) + "s"
+	}
+
+	renewData := &framework.FieldData{
+		Raw:    raw,
+		Schema: renewFields(map[string]*framework.FieldSchema{}),
+	}
+
+	resp, err := b.pathIssueSignCert(ctx, req, renewData, role, useCSR, false)
+	if err != nil || resp.IsError() {
+		return resp, err
+	}
+	resp.Data["renewed_serial_number"] = serialFromCert(cert)
+	return resp, nil
+}
+
+// checkRenewalExtKeyUsages ensures the role still issues the extended key
+// usages of the certificate being renewed.
+func checkRenewalExtKeyUsages(cert *x509.Certificate, role *roleEntry) error {
+	template := &x509.Certificate{}
+	certutil.AddKeyUsages(&certutil.CreationBundle{
+		Params: &certutil.CreationParameters{
+			ExtKeyUsage: parseExtKeyUsages(role),
+		},
+	}, template)
+
+	have := make([]string, 0, len(cert.ExtKeyUsage)+len(cert.UnknownExtKeyUsage))
+	for _, usage := range cert.ExtKeyUsage {
+		have = append(have, strconv.Itoa(int(usage)))
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		have = append(have, oid.String())
+	}
+
+	want := make([]string, 0, len(template.ExtKeyUsage)+len(role.ExtKeyUsageOIDs))
+	for _, usage := range template.ExtKeyUsage {
+		want = append(want, strconv.Itoa(int(usage)))
+	}
+	want = append(want, role.ExtKeyUsageOIDs...)
+
+	sort.Strings(have)
+	sort.Strings(want)
+	if strings.Join(have, ",") != strings.Join(want, ",") {
+		return fmt.Errorf("the extended key usages of the role no longer match the certificate; issue a new certificate instead")
+	}
+	return nil
+}
+
+const pathRenewHelpSyn = `
+Renew a certificate, reusing the parameters it was issued with.
+`
+
+const pathRenewHelpDesc = `
+This path issues a new certificate with the common name, alternative names,
+subject serial number and extended key usages of a stored certificate, so
+that rotation does not require the original request. The request is
+validated against the role as if it was made on the issue or sign path, and
+fails if the role no longer allows the names or no longer issues the same
+extended key usages. Revoked certificates cannot be renewed.
+
+A new key is generated unless a CSR is provided. The renewed certificate
+keeps the lifetime of the original one unless ttl or not_after are given.
+`
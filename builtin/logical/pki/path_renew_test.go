@@ -0,0 +1,117 @@
+package pki
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_Renew(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	resp, err = CBWrite(b, s, "roles/web", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"allow_ip_sans":    true,
+		"key_type":         "ec",
+		"ttl":              "2h",
+	})
+	require.NoError(t, err)
+
+	resp, err = CBWrite(b, s, "issue/web", map[string]interface{}{
+		"common_name": "www.example.com",
+		"alt_names":   "api.example.com",
+		"ip_sans":     "10.0.0.1",
+		"ttl":         "1h",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	serial := resp.Data["serial_number"].(string)
+	orig := parseCert(t, resp.Data["certificate"].(string))
+
+	// Renewal is validated against a role
+	resp, err = CBWrite(b, s, "renew/"+serial, nil)
+	require.Error(t, err)
+
+	resp, err = CBWrite(b, s, "renew/"+serial, map[string]interface{}{
+		"role": "web",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, serial, resp.Data["renewed_serial_number"])
+	require.NotEqual(t, serial, resp.Data["serial_number"])
+	require.NotEmpty(t, resp.Data["private_key"])
+
+	renewed := parseCert(t, resp.Data["certificate"].(string))
+	require.Equal(t, orig.Subject.CommonName, renewed.Subject.CommonName)
+	require.ElementsMatch(t, orig.DNSNames, renewed.DNSNames)
+	require.Len(t, renewed.IPAddresses, 1)
+	require.Equal(t, "10.0.0.1", renewed.IPAddresses[0].String())
+	require.Equal(t, orig.ExtKeyUsage, renewed.ExtKeyUsage)
+	require.Equal(t, orig.NotAfter.Sub(orig.NotBefore), renewed.NotAfter.Sub(renewed.NotBefore))
+
+	// The lifetime can be overridden
+	resp, err = CBWrite(b, s, "renew/"+serial, map[string]interface{}{
+		"role": "web",
+		"ttl":  "30m",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	renewed = parseCert(t, resp.Data["certificate"].(string))
+	require.Less(t, renewed.NotAfter.Sub(renewed.NotBefore), orig.NotAfter.Sub(orig.NotBefore))
+
+	// Names no longer allowed by the role are rejected
+	resp, err = CBWrite(b, s, "roles/api", map[string]interface{}{
+		"allowed_domains":    "api.example.com",
+		"allow_bare_domains": true,
+		"allow_ip_sans":      true,
+		"key_type":           "ec",
+	})
+	require.NoError(t, err)
+	resp, err = CBWrite(b, s, "renew/"+serial, map[string]interface{}{
+		"role": "api",
+	})
+	require.Error(t, err)
+
+	// So are changes to the extended key usages
+	resp, err = CBWrite(b, s, "roles/web", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"allow_ip_sans":    true,
+		"key_type":         "ec",
+		"client_flag":      false,
+	})
+	require.NoError(t, err)
+	resp, err = CBWrite(b, s, "renew/"+serial, map[string]interface{}{
+		"role": "web",
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "extended key usages")
+
+	// Revoked certificates cannot be renewed
+	resp, err = CBWrite(b, s, "roles/web", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"allow_ip_sans":    true,
+		"key_type":         "ec",
+	})
+	require.NoError(t, err)
+	resp, err = CBWrite(b, s, "revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	resp, err = CBWrite(b, s, "renew/"+serial, map[string]interface{}{
+		"role": "web",
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "revoked")
+
+	resp, err = CBWrite(b, s, "renew/00-11-22", map[string]interface{}{
+		"role": "web",
+	})
+	require.Error(t, err)
+}
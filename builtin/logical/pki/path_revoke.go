@@ -7,6 +7,7 @@ import (
 	"crypto/rsa"
 	"crypto/subtle"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"fmt"
 	"strings"
@@ -83,6 +84,18 @@ signed by an issuer in this mount.`,
 				Description: `Key to use to verify revocation permission; must
 be in PEM format.`,
 			},
+			"signature": {
+				Type: framework.TypeString,
+				Description: `Base64-encoded signature of the challenge returned
+by the revoke-with-key/challenge path, made with the certificate's key.
+Alternative to private_key.`,
+			},
+			"jws": {
+				Type: framework.TypeString,
+				Description: `JWS whose payload is the challenge returned by the
+revoke-with-key/challenge path, signed with the certificate's key.
+Alternative to private_key.`,
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -333,18 +346,43 @@ func (b *backend) pathRevokeWrite(ctx context.Context, req *logical.Request, dat
 		return logical.ErrorResponse("Must provide either the certificate or the serial to revoke; not both."), nil
 	}
 
-	var keyPem string
+	var keyPem, jws string
+	var signature []byte
 	if req.Path == "revoke-with-key" {
 		rawKey, haveKey := data.GetOk("private_key")
-		if !haveKey {
-			return logical.ErrorResponse("Must have private key to revoke via the /revoke-with-key path."), nil
+		rawSignature, haveSignature := data.GetOk("signature")
+		rawJWS, haveJWS := data.GetOk("jws")
+
+		switch {
+		case haveKey && !haveSignature && !haveJWS:
+			keyPem = rawKey.(string)
+			if len(keyPem) < 64 {
+				// See note in pathImportKeyHandler...
+				return logical.ErrorResponse("Provided data for private_key was too short; perhaps a path was passed to the API rather than the contents of a PEM file?"), nil
+			}
+		case haveSignature && !haveKey && !haveJWS:
+			var err error
+			signature, err = base64.StdEncoding.DecodeString(rawSignature.(string))
+			if err != nil || len(signature) == 0 {
+				return logical.ErrorResponse("The signature must be provided in base64."), nil
+			}
+		case haveJWS && !haveKey && !haveSignature:
+			jws = rawJWS.(string)
+			if len(jws) == 0 {
+				return logical.ErrorResponse("The JWS must be provided."), nil
+			}
+		default:
+			return logical.ErrorResponse("Must have exactly one of private key, signature or JWS to revoke via the /revoke-with-key path."), nil
 		}
+	}
 
-		keyPem = rawKey.(string)
-		if len(keyPem) < 64 {
-			// See note in pathImportKeyHandler...
-			return logical.ErrorResponse("Provided data for private_key was too short; perhaps a path was passed to the API rather than the contents of a PEM file?"), nil
+	// Proves the requester holds the key of the certificate when revoking
+	// via the /revoke-with-key path.
+	handleProof := func(cert []byte) error {
+		if signature != nil || jws != "" {
+			return b.pathRevokeWriteHandleSignature(ctx, req, cert, signature, jws)
 		}
+		return b.pathRevokeWriteHandleKey(ctx, req, cert, keyPem)
 	}
 
 	var serial string
@@ -369,9 +407,9 @@ func (b *backend) pathRevokeWrite(ctx context.Context, req *logical.Request, dat
 			return logical.ErrorResponse(fmt.Sprintf("certificate with serial %s not found or was already revoked", serial)), nil
 		}
 
-		// Now, if the user provided a key, we'll have to make sure the key
-		// and stored certificate match.
-		if err := b.pathRevokeWriteHandleKey(ctx, req, certEntry.Value, keyPem); err != nil {
+		// Now, if the user provided a key or signature, we'll have to make
+		// sure it matches the stored certificate.
+		if err := handleProof(certEntry.Value); err != nil {
 			return nil, err
 		}
 	} else {
@@ -390,7 +428,7 @@ func (b *backend) pathRevokeWrite(ctx context.Context, req *logical.Request, dat
 		// Before we write the certificate, we've gotta verify the request in
 		// the event of a PoP-based revocation scheme; we don't want to litter
 		// storage with issued-but-not-revoked certificates.
-		if err := b.pathRevokeWriteHandleKey(ctx, req, certBytes, keyPem); err != nil {
+		if err := handleProof(certBytes); err != nil {
 			return nil, err
 		}
 
@@ -501,7 +539,9 @@ const pathRevokeHelpSyn = `
 Revoke a certificate by serial number or with explicit certificate.
 
 When calling /revoke-with-key, the private key corresponding to the
-certificate must be provided to authenticate the request.
+certificate must be provided to authenticate the request. Alternatively,
+a challenge from /revoke-with-key/challenge signed with that key may be
+provided instead.
 `
 
 const pathRevokeHelpDesc = `
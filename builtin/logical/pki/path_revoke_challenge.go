@@ -0,0 +1,238 @@
+package pki
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	revocationChallengePrefix = "revoke-challenge/"
+
+	revocationChallengeWindow = 5 * time.Minute
+)
+
+// revocationChallenge is a nonce the holder of a certificate signs with the
+// certificate's key to revoke it without sending the key itself.
+type revocationChallenge struct {
+	SerialNumber string    `json:"serial_number"`
+	Challenge    string    `json:"challenge"`
+	ExpireTime   time.Time `json:"expire_time"`
+}
+
+func pathRevokeWithKeyChallenge(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `revoke-with-key/challenge`,
+		Fields: map[string]*framework.FieldSchema{
+			"serial_number": {
+				Type: framework.TypeString,
+				Description: `Certificate serial number, in colon- or
+hyphen-separated octal`,
+			},
+			"certificate": {
+				Type: framework.TypeString,
+				Description: `Certificate to revoke in PEM format; must be
+signed by an issuer in this mount.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.metricsWrap("revoke-challenge", noRole, b.pathRevokeChallengeWrite),
+			},
+		},
+
+		HelpSynopsis:    pathRevokeChallengeHelpSyn,
+		HelpDescription: pathRevokeChallengeHelpDesc,
+	}
+}
+
+func (b *backend) pathRevokeChallengeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData, _ *roleEntry) (*logical.Response, error) {
+	rawSerial, haveSerial := data.GetOk("serial_number")
+	rawCertificate, haveCert := data.GetOk("certificate")
+
+	if !haveSerial && !haveCert {
+		return logical.ErrorResponse("The serial number or certificate to revoke must be provided."), nil
+	} else if haveSerial && haveCert {
+		return logical.ErrorResponse("Must provide either the certificate or the serial to revoke; not both."), nil
+	}
+
+	var serial string
+	if haveSerial {
+		serial = rawSerial.(string)
+		if len(serial) == 0 {
+			return logical.ErrorResponse("The serial number must be provided"), nil
+		}
+
+		certEntry, err := fetchCertBySerial(ctx, b, req, req.Path, serial)
+		if err != nil {
+			switch err.(type) {
+			case errutil.UserError:
+				return logical.ErrorResponse(err.Error()), nil
+			default:
+				return nil, err
+			}
+		}
+		if certEntry == nil {
+			return logical.ErrorResponse(fmt.Sprintf("certificate with serial %s not found or was already revoked", serial)), nil
+		}
+	} else {
+		// The certificate is only verified against the issuers of this mount
+		// when it is revoked, as for the challenge it only names the serial.
+		pemBlock, _ := pem.Decode([]byte(rawCertificate.(string)))
+		if pemBlock == nil {
+			return logical.ErrorResponse("certificate contains no PEM data"), nil
+		}
+		cert, err := x509.ParseCertificate(pemBlock.Bytes)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("certificate could not be parsed: %v", err)), nil
+		}
+		serial = serialFromCert(cert)
+		if len(serial) == 0 {
+			return logical.ErrorResponse("invalid serial number on presented certificate"), nil
+		}
+	}
+
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+		return nil, logical.ErrReadOnly
+	}
+
+	nonce, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate challenge: %w", err)
+	}
+
+	challenge := &revocationChallenge{
+		SerialNumber: denormalizeSerial(serial),
+		Challenge:    base64.StdEncoding.EncodeToString(nonce),
+		ExpireTime:   time.Now().Add(revocationChallengeWindow),
+	}
+	entry, err := logical.StorageEntryJSON(revocationChallengePrefix+normalizeSerial(serial), challenge)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, fmt.Errorf("unable to store revocation challenge: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"serial_number":        challenge.SerialNumber,
+			"challenge":            challenge.Challenge,
+			"challenge_expiration": challenge.ExpireTime.Unix(),
+		},
+	}, nil
+}
+
+// pathRevokeWriteHandleSignature verifies that the holder of cert signed the
+// pending revocation challenge for its serial, either with a raw signature
+// or as the payload of a JWS. The challenge can only be used once.
+func (b *backend) pathRevokeWriteHandleSignature(ctx context.Context, req *logical.Request, cert []byte, signature []byte, jws string) error {
+	certReference, err := x509.ParseCertificate(cert)
+	if err != nil {
+		return errutil.UserError{Err: fmt.Sprintf("certificate could not be parsed: %v", err)}
+	}
+
+	serial := normalizeSerial(serialFromCert(certReference))
+	raw, err := req.Storage.Get(ctx, revocationChallengePrefix+serial)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return errutil.UserError{Err: fmt.Sprintf("no revocation challenge is pending for the certificate with serial %s; request one from the /revoke-with-key/challenge path", denormalizeSerial(serial))}
+	}
+	var challenge revocationChallenge
+	if err := raw.DecodeJSON(&challenge); err != nil {
+		return err
+	}
+	if time.Now().After(challenge.ExpireTime) {
+		if err := req.Storage.Delete(ctx, revocationChallengePrefix+serial); err != nil {
+			return err
+		}
+		return errutil.UserError{Err: fmt.Sprintf("the revocation challenge for the certificate with serial %s has expired", denormalizeSerial(serial))}
+	}
+
+	if jws != "" {
+		err = verifyRevocationJWS(certReference, challenge.Challenge, jws)
+	} else {
+		err = verifyProofOfPossession(certReference, challenge.Challenge, signature)
+	}
+	if err != nil {
+		return errutil.UserError{Err: fmt.Sprintf("invalid signature of the revocation challenge: %s", err)}
+	}
+
+	return req.Storage.Delete(ctx, revocationChallengePrefix+serial)
+}
+
+// verifyRevocationJWS checks that jws, in compact or JSON serialization, is
+// signed by the key of cert and that its payload is the challenge.
+func verifyRevocationJWS(cert *x509.Certificate, challenge string, jws string) error {
+	object, err := jose.ParseSigned(jws)
+	if err != nil {
+		return fmt.Errorf("unable to parse JWS: %w", err)
+	}
+	payload, err := object.Verify(cert.PublicKey)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(payload, []byte(challenge)) != 1 {
+		return fmt.Errorf("JWS payload does not match the challenge")
+	}
+	return nil
+}
+
+// tidyRevocationChallenges removes the revocation challenges which have
+// expired.
+func (b *backend) tidyRevocationChallenges(ctx context.Context, s logical.Storage) error {
+	serials, err := s.List(ctx, revocationChallengePrefix)
+	if err != nil {
+		return fmt.Errorf("error fetching list of revocation challenges: %w", err)
+	}
+
+	for _, serial := range serials {
+		raw, err := s.Get(ctx, revocationChallengePrefix+serial)
+		if err != nil {
+			return fmt.Errorf("error fetching revocation challenge %q: %w", serial, err)
+		}
+		if raw != nil {
+			var challenge revocationChallenge
+			if err := raw.DecodeJSON(&challenge); err != nil {
+				return fmt.Errorf("error decoding revocation challenge %q: %w", serial, err)
+			}
+			if time.Now().Before(challenge.ExpireTime) {
+				continue
+			}
+		}
+		if err := s.Delete(ctx, revocationChallengePrefix+serial); err != nil {
+			return fmt.Errorf("error deleting revocation challenge %q: %w", serial, err)
+		}
+	}
+	return nil
+}
+
+const pathRevokeChallengeHelpSyn = `
+Request a challenge to revoke a certificate by signing it with its key.
+`
+
+const pathRevokeChallengeHelpDesc = `
+This returns a challenge for the given certificate, valid for five minutes.
+Instead of sending the private key of the certificate to /revoke-with-key,
+its holder may sign this challenge with the key and send either the
+signature or a JWS whose payload is the challenge. Each challenge can only
+be used once.
+
+RSA keys sign with PKCS#1 v1.5 and ECDSA keys with ASN.1 signatures, both
+over the SHA-256 digest of the challenge string; Ed25519 keys sign the
+challenge string itself.
+`
@@ -0,0 +1,146 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestBackend_RevokeWithSignedChallenge(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "48h",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	_, err = CBWrite(b, s, "roles/testing", map[string]interface{}{
+		"allow_any_name":    true,
+		"enforce_hostnames": false,
+		"key_type":          "ec",
+	})
+	require.NoError(t, err)
+
+	issue := func(t *testing.T) (string, string, *ecdsa.PrivateKey) {
+		t.Helper()
+		resp, err := CBWrite(b, s, "issue/testing", map[string]interface{}{
+			"common_name": "testing",
+		})
+		requireSuccessNonNilResponse(t, resp, err)
+		block, _ := pem.Decode([]byte(resp.Data["private_key"].(string)))
+		require.NotNil(t, block)
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		require.NoError(t, err)
+		return resp.Data["serial_number"].(string), resp.Data["certificate"].(string), key
+	}
+	sign := func(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+		t.Helper()
+		digest := sha256.Sum256([]byte(message))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+	requireRevoked := func(t *testing.T, serial string, revoked bool) {
+		t.Helper()
+		resp, err := CBRead(b, s, "cert/"+serial)
+		requireSuccessNonNilResponse(t, resp, err)
+		require.Equal(t, revoked, resp.Data["revocation_time"].(int64) != 0)
+	}
+
+	// A signature without a pending challenge is rejected
+	serial, _, key := issue(t)
+	_, err = CBWrite(b, s, "revoke-with-key", map[string]interface{}{
+		"serial_number": serial,
+		"signature":     sign(t, key, "no challenge"),
+	})
+	require.Error(t, err)
+
+	resp, err = CBWrite(b, s, "revoke-with-key/challenge", map[string]interface{}{
+		"serial_number": serial,
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	require.NotEmpty(t, resp.Data["challenge_expiration"])
+	challenge := resp.Data["challenge"].(string)
+
+	// A signature of something else is rejected
+	_, err = CBWrite(b, s, "revoke-with-key", map[string]interface{}{
+		"serial_number": serial,
+		"signature":     sign(t, key, "not the challenge"),
+	})
+	require.Error(t, err)
+	requireRevoked(t, serial, false)
+
+	// Only one proof can be given
+	_, err = CBWrite(b, s, "revoke-with-key", map[string]interface{}{
+		"serial_number": serial,
+		"signature":     sign(t, key, challenge),
+		"jws":           "a.b.c",
+	})
+	require.Error(t, err)
+
+	_, err = CBWrite(b, s, "revoke-with-key", map[string]interface{}{
+		"serial_number": serial,
+		"signature":     sign(t, key, challenge),
+	})
+	require.NoError(t, err)
+	requireRevoked(t, serial, true)
+
+	// The certificate holder can also sign the challenge as a JWS
+	serial, cert, key := issue(t)
+	resp, err = CBWrite(b, s, "revoke-with-key/challenge", map[string]interface{}{
+		"certificate": cert,
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	challenge = resp.Data["challenge"].(string)
+
+	_, otherCert, otherKey := issue(t)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: otherKey}, nil)
+	require.NoError(t, err)
+	object, err := signer.Sign([]byte(challenge))
+	require.NoError(t, err)
+	jws, err := object.CompactSerialize()
+	require.NoError(t, err)
+
+	// The JWS of another key is rejected
+	_, err = CBWrite(b, s, "revoke-with-key", map[string]interface{}{
+		"certificate": cert,
+		"jws":         jws,
+	})
+	require.Error(t, err)
+	_, err = CBWrite(b, s, "revoke-with-key", map[string]interface{}{
+		"certificate": otherCert,
+		"jws":         jws,
+	})
+	require.Error(t, err)
+
+	signer, err = jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+	require.NoError(t, err)
+	object, err = signer.Sign([]byte(challenge))
+	require.NoError(t, err)
+	jws, err = object.CompactSerialize()
+	require.NoError(t, err)
+
+	_, err = CBWrite(b, s, "revoke-with-key", map[string]interface{}{
+		"certificate": cert,
+		"jws":         jws,
+	})
+	require.NoError(t, err)
+	requireRevoked(t, serial, true)
+
+	// The challenge can only be used once
+	_, err = CBWrite(b, s, "revoke-with-key", map[string]interface{}{
+		"certificate": cert,
+		"jws":         jws,
+	})
+	require.Error(t, err)
+}
@@ -440,6 +440,55 @@ The value format should be given in UTC format YYYY-MM-ddTHH:MM:SSZ.`,
 serviced by this role.`,
 				Default: defaultRef,
 			},
+
+			"allow_ssh_bridge": {
+				Type: framework.TypeBool,
+				Description: `If set, SSH user certificates signed by a trusted
+SSH certificate authority can be exchanged for certificates of this role
+on the issue-ssh endpoint. Defaults to false.`,
+			},
+
+			"ssh_principal_template": {
+				Type: framework.TypeString,
+				Description: `Template mapping each principal of an exchanged
+SSH certificate to a name of the certificate; {{principal}} is replaced
+by the principal. Defaults to the principal itself.`,
+			},
+
+			"require_proof_of_possession": {
+				Type: framework.TypeBool,
+				Description: `If set, certificates issued with a key generated
+by Vault are withheld until the client signs a nonce with the returned
+private key on the activate endpoint. Requires storing certificates and
+is incompatible with generate_lease. Defaults to false.`,
+			},
+
+			"proof_of_possession_window": {
+				Type:    framework.TypeDurationSecond,
+				Default: 300,
+				Description: `The time the client has to prove possession of
+the private key before the certificate is discarded. Defaults to 5 minutes.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Value: 300,
+				},
+			},
+
+			"max_certs_per_entity": {
+				Type: framework.TypeInt,
+				Description: `The maximum number of certificates an entity can
+be issued with this role per max_certs_per_entity_period. Defaults to 0, which
+does not limit issuance.`,
+			},
+
+			"max_certs_per_entity_period": {
+				Type:    framework.TypeDurationSecond,
+				Default: 86400,
+				Description: `The period over which max_certs_per_entity is
+enforced. Defaults to 24 hours.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Value: 86400,
+				},
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -617,11 +666,16 @@ func (b *backend) getRole(ctx context.Context, s logical.Storage, n string) (*ro
 }
 
 func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete(ctx, "role/"+data.Get("name").(string))
+	name := data.Get("name").(string)
+	err := req.Storage.Delete(ctx, "role/"+name)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := b.deleteIssuanceCounts(ctx, req.Storage, name); err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -698,6 +752,12 @@ func (b *backend) pathRoleCreate(ctx context.Context, req *logical.Request, data
 		RequireCN:                     data.Get("require_cn").(bool),
 		CNValidations:                 data.Get("cn_validations").([]string),
 		AllowedSerialNumbers:          data.Get("allowed_serial_numbers").([]string),
+		AllowSSHBridge:                data.Get("allow_ssh_bridge").(bool),
+		SSHPrincipalTemplate:          data.Get("ssh_principal_template").(string),
+		RequireProofOfPossession:      data.Get("require_proof_of_possession").(bool),
+		ProofOfPossessionWindow:       time.Duration(data.Get("proof_of_possession_window").(int)) * time.Second,
+		MaxCertsPerEntity:             data.Get("max_certs_per_entity").(int),
+		MaxCertsPerEntityPeriod:       time.Duration(data.Get("max_certs_per_entity_period").(int)) * time.Second,
 		PolicyIdentifiers:             getPolicyIdentifier(data, nil),
 		BasicConstraintsValidForNonCA: data.Get("basic_constraints_valid_for_non_ca").(bool),
 		NotBeforeDuration:             time.Duration(data.Get("not_before_duration").(int)) * time.Second,
@@ -776,6 +836,18 @@ func validateRole(b *backend, entry *roleEntry, ctx context.Context, s logical.S
 		), nil
 	}
 
+	if entry.RequireProofOfPossession && (entry.NoStore || *entry.GenerateLease) {
+		return logical.ErrorResponse(
+			`"require_proof_of_possession" cannot be used with "no_store" or "generate_lease"`,
+		), nil
+	}
+
+	if entry.MaxCertsPerEntity < 0 || entry.MaxCertsPerEntityPeriod < 0 {
+		return logical.ErrorResponse(
+			`"max_certs_per_entity" and "max_certs_per_entity_period" cannot be negative`,
+		), nil
+	}
+
 	if entry.KeyBits, entry.SignatureBits, err = certutil.ValidateDefaultOrValueKeyTypeSignatureLength(entry.KeyType, entry.KeyBits, entry.SignatureBits); err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
@@ -898,6 +970,12 @@ func (b *backend) pathRolePatch(ctx context.Context, req *logical.Request, data
 		RequireCN:                     getWithExplicitDefault(data, "require_cn", oldEntry.RequireCN).(bool),
 		CNValidations:                 getWithExplicitDefault(data, "cn_validations", oldEntry.CNValidations).([]string),
 		AllowedSerialNumbers:          getWithExplicitDefault(data, "allowed_serial_numbers", oldEntry.AllowedSerialNumbers).([]string),
+		AllowSSHBridge:                getWithExplicitDefault(data, "allow_ssh_bridge", oldEntry.AllowSSHBridge).(bool),
+		SSHPrincipalTemplate:          getWithExplicitDefault(data, "ssh_principal_template", oldEntry.SSHPrincipalTemplate).(string),
+		RequireProofOfPossession:      getWithExplicitDefault(data, "require_proof_of_possession", oldEntry.RequireProofOfPossession).(bool),
+		ProofOfPossessionWindow:       getTimeWithExplicitDefault(data, "proof_of_possession_window", oldEntry.ProofOfPossessionWindow),
+		MaxCertsPerEntity:             getWithExplicitDefault(data, "max_certs_per_entity", oldEntry.MaxCertsPerEntity).(int),
+		MaxCertsPerEntityPeriod:       getTimeWithExplicitDefault(data, "max_certs_per_entity_period", oldEntry.MaxCertsPerEntityPeriod),
 		PolicyIdentifiers:             getPolicyIdentifier(data, &oldEntry.PolicyIdentifiers),
 		BasicConstraintsValidForNonCA: getWithExplicitDefault(data, "basic_constraints_valid_for_non_ca", oldEntry.BasicConstraintsValidForNonCA).(bool),
 		NotBeforeDuration:             getTimeWithExplicitDefault(data, "not_before_duration", oldEntry.NotBeforeDuration),
@@ -1108,6 +1186,12 @@ type roleEntry struct {
 	NotBeforeDuration             time.Duration `json:"not_before_duration"`
 	NotAfter                      string        `json:"not_after"`
 	Issuer                        string        `json:"issuer"`
+	AllowSSHBridge                bool          `json:"allow_ssh_bridge"`
+	SSHPrincipalTemplate          string        `json:"ssh_principal_template"`
+	RequireProofOfPossession      bool          `json:"require_proof_of_possession"`
+	ProofOfPossessionWindow       time.Duration `json:"proof_of_possession_window"`
+	MaxCertsPerEntity             int           `json:"max_certs_per_entity"`
+	MaxCertsPerEntityPeriod       time.Duration `json:"max_certs_per_entity_period"`
 }
 
 func (r *roleEntry) ToResponseData() map[string]interface{} {
@@ -1157,6 +1241,12 @@ func (r *roleEntry) ToResponseData() map[string]interface{} {
 		"not_before_duration":                int64(r.NotBeforeDuration.Seconds()),
 		"not_after":                          r.NotAfter,
 		"issuer_ref":                         r.Issuer,
+		"allow_ssh_bridge":                   r.AllowSSHBridge,
+		"ssh_principal_template":             r.SSHPrincipalTemplate,
+		"require_proof_of_possession":        r.RequireProofOfPossession,
+		"proof_of_possession_window":         int64(r.ProofOfPossessionWindow.Seconds()),
+		"max_certs_per_entity":               r.MaxCertsPerEntity,
+		"max_certs_per_entity_period":        int64(r.MaxCertsPerEntityPeriod.Seconds()),
 	}
 	if r.MaxPathLength != nil {
 		responseData["max_path_length"] = r.MaxPathLength
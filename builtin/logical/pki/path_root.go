@@ -366,10 +366,22 @@ func (b *backend) pathIssuerSignIntermediate(ctx context.Context, req *logical.R
 		role.MaxPathLength = &maxPathLength
 	}
 
+	serialNumber, err := sc.generateSerialNumber(issuerName)
+	if err != nil {
+		return nil, err
+	}
+
+	blockedNames, err := getBlockedNamesConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
 	input := &inputBundle{
-		req:     req,
-		apiData: data,
-		role:    role,
+		req:          req,
+		apiData:      data,
+		role:         role,
+		serialNumber: serialNumber,
+		blockedNames: blockedNames,
 	}
 	parsedBundle, warnings, err := signCert(b, input, signingBundle, true, useCSRValues)
 	if err != nil {
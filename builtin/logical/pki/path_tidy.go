@@ -306,6 +306,14 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 		}
 	}
 
+	if err := b.tidyPendingPossessionCerts(ctx, req.Storage); err != nil {
+		return err
+	}
+
+	if err := b.tidyRevocationChallenges(ctx, req.Storage); err != nil {
+		return err
+	}
+
 	b.tidyStatusLock.RLock()
 	metrics.SetGauge([]string{"secrets", "pki", "tidy", "cert_store_total_entries_remaining"}, float32(uint(serialCount)-b.tidyStatus.certStoreDeletedCount))
 	b.tidyStatusLock.RUnlock()
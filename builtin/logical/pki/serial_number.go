@@ -0,0 +1,145 @@
+package pki
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	serialNumberStrategyRandom       = "random"
+	serialNumberStrategyTimePrefixed = "time-prefixed"
+	serialNumberStrategySequential   = "sequential"
+
+	// RFC 5280 Section 4.1.2.2 limits serial numbers to 20 octets, so 159
+	// bits keep them positive; CA/Browser Forum requires 64 bits of entropy.
+	minSerialNumberBits     = 64
+	maxSerialNumberBits     = 159
+	defaultSerialNumberBits = maxSerialNumberBits
+
+	serialNumberCounterPath = "config/serial-counter"
+
+	// Number of serial numbers tried before giving up when they collide with
+	// stored certificates.
+	maxSerialNumberAttempts = 10
+)
+
+func validateSerialNumberStrategy(strategy string, bits int) error {
+	switch strategy {
+	case "", serialNumberStrategyRandom:
+	case serialNumberStrategyTimePrefixed, serialNumberStrategySequential:
+		if bits != 0 {
+			return fmt.Errorf("serial_number_bits can only be set with the %q serial number strategy", serialNumberStrategyRandom)
+		}
+	default:
+		return fmt.Errorf("unknown serial number strategy %q; valid values are %q, %q and %q", strategy, serialNumberStrategyRandom, serialNumberStrategyTimePrefixed, serialNumberStrategySequential)
+	}
+
+	if bits != 0 && (bits < minSerialNumberBits || bits > maxSerialNumberBits) {
+		return fmt.Errorf("serial_number_bits must be between %d and %d", minSerialNumberBits, maxSerialNumberBits)
+	}
+	return nil
+}
+
+// generateSerialNumber returns the serial number of the next certificate
+// issued by the referenced issuer, following its serial number strategy.
+// Serial numbers of certificates already stored in this mount are skipped.
+func (sc *storageContext) generateSerialNumber(issuerRef string) (*big.Int, error) {
+	if sc.Backend.useLegacyBundleCaStorage() {
+		return certutil.GenerateSerialNumber()
+	}
+
+	issuerId, err := sc.resolveIssuerReference(issuerRef)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := sc.fetchIssuerById(issuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < maxSerialNumberAttempts; attempt++ {
+		var serial *big.Int
+		switch issuer.SerialNumberStrategy {
+		case serialNumberStrategyTimePrefixed:
+			serial, err = generateTimePrefixedSerialNumber(time.Now())
+		case serialNumberStrategySequential:
+			serial, err = sc.nextSequentialSerialNumber()
+		default:
+			serial, err = generateRandomSerialNumber(issuer.SerialNumberBits)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if serial.Sign() <= 0 {
+			continue
+		}
+
+		entry, err := sc.Storage.Get(sc.Context, "certs/"+normalizeSerial(serialFromBigInt(serial)))
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return serial, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to generate a serial number not already in use after %d attempts", maxSerialNumberAttempts)
+}
+
+func generateRandomSerialNumber(bits int) (*big.Int, error) {
+	if bits == 0 {
+		bits = defaultSerialNumberBits
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	if err != nil {
+		return nil, fmt.Errorf("error generating serial number: %w", err)
+	}
+	return serial, nil
+}
+
+// generateTimePrefixedSerialNumber returns a serial number made of the
+// nanoseconds since the epoch followed by 64 random bits, so serial numbers
+// sort in issuance order.
+func generateTimePrefixedSerialNumber(now time.Time) (*big.Int, error) {
+	random, err := generateRandomSerialNumber(64)
+	if err != nil {
+		return nil, err
+	}
+	serial := new(big.Int).Lsh(big.NewInt(now.UnixNano()), 64)
+	return serial.Or(serial, random), nil
+}
+
+// nextSequentialSerialNumber increments and returns the counter shared by
+// all issuers using sequential serial numbers in this mount.
+func (sc *storageContext) nextSequentialSerialNumber() (*big.Int, error) {
+	sc.Backend.serialCounterLock.Lock()
+	defer sc.Backend.serialCounterLock.Unlock()
+
+	var counter uint64
+	entry, err := sc.Storage.Get(sc.Context, serialNumberCounterPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		counter, err = strconv.ParseUint(string(entry.Value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse serial number counter: %w", err)
+		}
+	}
+
+	counter++
+	if err := sc.Storage.Put(sc.Context, &logical.StorageEntry{
+		Key:   serialNumberCounterPath,
+		Value: []byte(strconv.FormatUint(counter, 10)),
+	}); err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetUint64(counter), nil
+}
@@ -0,0 +1,112 @@
+package pki
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_SerialNumberStrategy(t *testing.T) {
+	t.Parallel()
+	b, s := CreateBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root CA",
+		"ttl":         "48h",
+		"key_type":    "ec",
+		"issuer_name": "root",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+
+	_, err = CBWrite(b, s, "roles/testing", map[string]interface{}{
+		"allow_any_name":    true,
+		"enforce_hostnames": false,
+		"key_type":          "ec",
+	})
+	require.NoError(t, err)
+
+	issueSerial := func(t *testing.T) *big.Int {
+		t.Helper()
+		resp, err := CBWrite(b, s, "issue/testing", map[string]interface{}{
+			"common_name": "testing",
+		})
+		requireSuccessNonNilResponse(t, resp, err)
+		return parseCert(t, resp.Data["certificate"].(string)).SerialNumber
+	}
+
+	resp, err = CBRead(b, s, "issuer/root")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, serialNumberStrategyRandom, resp.Data["serial_number_strategy"])
+	require.Equal(t, defaultSerialNumberBits, resp.Data["serial_number_bits"])
+
+	for _, data := range []map[string]interface{}{
+		{"serial_number_strategy": "unknown"},
+		{"serial_number_bits": 32},
+		{"serial_number_bits": 160},
+		{"serial_number_strategy": serialNumberStrategySequential, "serial_number_bits": 64},
+	} {
+		_, err = CBPatch(b, s, "issuer/root", data)
+		require.Error(t, err, "expected %v to be rejected", data)
+	}
+
+	_, err = CBPatch(b, s, "issuer/root", map[string]interface{}{
+		"serial_number_bits": 64,
+	})
+	require.NoError(t, err)
+	require.LessOrEqual(t, issueSerial(t).BitLen(), 64)
+
+	// Time-prefixed serial numbers sort in issuance order
+	resp, err = CBPatch(b, s, "issuer/root", map[string]interface{}{
+		"serial_number_strategy": serialNumberStrategyTimePrefixed,
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, serialNumberStrategyTimePrefixed, resp.Data["serial_number_strategy"])
+	first := issueSerial(t)
+	second := issueSerial(t)
+	require.Equal(t, -1, first.Cmp(second))
+	prefix := new(big.Int).Rsh(first, 64).Int64()
+	require.WithinDuration(t, time.Now(), time.Unix(0, prefix), time.Minute)
+
+	// Sequential serial numbers skip those already in use
+	_, err = CBPatch(b, s, "issuer/root", map[string]interface{}{
+		"serial_number_strategy": serialNumberStrategySequential,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), issueSerial(t).Int64())
+	require.NoError(t, s.Put(context.Background(), &logical.StorageEntry{
+		Key:   "certs/" + normalizeSerial(serialFromBigInt(big.NewInt(2))),
+		Value: []byte("in use"),
+	}))
+	require.Equal(t, int64(3), issueSerial(t).Int64())
+
+	// Issuers share the counter of the mount
+	resp, err = CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Other Root CA",
+		"ttl":         "48h",
+		"key_type":    "ec",
+		"issuer_name": "other",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	_, err = CBPatch(b, s, "issuer/other", map[string]interface{}{
+		"serial_number_strategy": serialNumberStrategySequential,
+	})
+	require.NoError(t, err)
+	resp, err = CBWrite(b, s, "issuer/other/issue/testing", map[string]interface{}{
+		"common_name": "testing",
+	})
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, int64(4), parseCert(t, resp.Data["certificate"].(string)).SerialNumber.Int64())
+
+	_, err = CBPatch(b, s, "issuer/root", map[string]interface{}{
+		"serial_number_strategy": serialNumberStrategyRandom,
+	})
+	require.NoError(t, err)
+	resp, err = CBRead(b, s, "issuer/root")
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Equal(t, serialNumberStrategyRandom, resp.Data["serial_number_strategy"])
+	require.Equal(t, defaultSerialNumberBits, resp.Data["serial_number_bits"])
+}
@@ -169,6 +169,8 @@ type issuerEntry struct {
 	RevocationTime       int64                     `json:"revocation_time"`
 	RevocationTimeUTC    time.Time                 `json:"revocation_time_utc"`
 	AIAURIs              *certutil.URLEntries      `json:"aia_uris,omitempty"`
+	SerialNumberStrategy string                    `json:"serial_number_strategy,omitempty"`
+	SerialNumberBits     int                       `json:"serial_number_bits,omitempty"`
 	LastModified         time.Time                 `json:"last_modified"`
 	Version              uint                      `json:"version"`
 }
@@ -252,6 +254,7 @@ func (sc *storageContext) writeKey(key keyEntry) error {
 		return err
 	}
 
+	defer sc.Backend.issuerCache.Invalidate()
 	return sc.Storage.Put(sc.Context, json)
 }
 
@@ -270,6 +273,7 @@ func (sc *storageContext) deleteKey(id keyID) (bool, error) {
 		}
 	}
 
+	defer sc.Backend.issuerCache.Invalidate()
 	return wasDefault, sc.Storage.Delete(sc.Context, keyPrefix+id.String())
 }
 
@@ -651,6 +655,7 @@ func (sc *storageContext) writeIssuer(issuer *issuerEntry) error {
 		return err
 	}
 
+	defer sc.Backend.issuerCache.Invalidate()
 	return sc.Storage.Put(sc.Context, json)
 }
 
@@ -672,6 +677,11 @@ func (sc *storageContext) deleteIssuer(id issuerID) (bool, error) {
 		}
 	}
 
+	if err := sc.Storage.Delete(sc.Context, ocspDelegatePrefix+id.String()); err != nil {
+		return wasDefault, err
+	}
+
+	defer sc.Backend.issuerCache.Invalidate()
 	return wasDefault, sc.Storage.Delete(sc.Context, issuerPrefix+id.String())
 }
 
@@ -1861,6 +1861,113 @@ func TestBackend_DefExtTemplatingDisabled(t *testing.T) {
 	}
 }
 
+func TestBackend_PrincipalPolicies(t *testing.T) {
+	cluster, userpassToken := getSshCaTestCluster(t, testUserName)
+	defer cluster.Cleanup()
+	client := cluster.Cores[0].Client
+
+	tokenLookupResponse, err := client.Logical().Write("/auth/token/lookup", map[string]interface{}{
+		"token": userpassToken,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entityID := tokenLookupResponse.Data["entity_id"].(string)
+	_, err = client.Logical().Write("/identity/entity/id/"+entityID, map[string]interface{}{
+		"metadata": map[string]string{
+			"team":          "dev",
+			"admin_max_ttl": "1h",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid policies are rejected
+	_, err = client.Logical().Write("ssh/roles/test", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allowed_users":           "*",
+		"principal_policies": map[string]interface{}{
+			"*": map[string]interface{}{"ttl": "2h", "max_ttl": "1h"},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error writing a principal policy with ttl above max_ttl")
+	}
+
+	_, err = client.Logical().Write("ssh/roles/test", map[string]interface{}{
+		"key_type":                 "ca",
+		"allow_user_certificates":  true,
+		"allowed_users":            "*",
+		"allowed_principals_regex": "{{identity.entity.metadata.team}}-[a-z]+",
+		"principal_policies": map[string]interface{}{
+			"*": map[string]interface{}{
+				"ttl": "2h",
+			},
+			"{{identity.entity.metadata.team}}-admin": map[string]interface{}{
+				"max_ttl":    "{{identity.entity.metadata.admin_max_ttl}}",
+				"extensions": map[string]interface{}{"permit-pty": ""},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshKeyID := "vault-userpass-" + testUserName + "-9bd0f01b7dfc50a13aa5e5cd11aea19276968755c8f1f9c98965d04147f30ed0"
+	client.SetToken(userpassToken)
+	sign := func(principal string, ttl string) (*ssh.Certificate, error) {
+		data := map[string]interface{}{
+			"public_key":       publicKey4096,
+			"valid_principals": principal,
+		}
+		if ttl != "" {
+			data["ttl"] = ttl
+		}
+		resp, err := client.Logical().Write("ssh/sign/test", data)
+		if err != nil {
+			return nil, err
+		}
+		signedKey := resp.Data["signed_key"].(string)
+		key, _ := base64.StdEncoding.DecodeString(strings.Split(signedKey, " ")[1])
+		parsedKey, err := ssh.ParsePublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsedKey.(*ssh.Certificate), nil
+	}
+
+	// The ttl of the catch-all policy replaces the mount default
+	cert, err := sign("dev-app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = validateSSHCertificate(cert, sshKeyID, ssh.UserCert, []string{"dev-app"}, map[string]string{}, map[string]string{}, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The admin principal of the team is capped by its entity metadata and
+	// granted its extensions
+	cert, err = sign("dev-admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = validateSSHCertificate(cert, sshKeyID, ssh.UserCert, []string{"dev-admin"}, map[string]string{}, map[string]string{"permit-pty": ""}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sign("dev-admin", "3h"); err == nil {
+		t.Fatal("expected an error requesting a ttl above the max_ttl of the principal policy")
+	}
+
+	// Principals of other teams don't match the regex
+	if _, err := sign("ops-app", ""); err == nil {
+		t.Fatal("expected an error signing a principal not matching allowed_principals_regex")
+	}
+}
+
 func TestSSHBackend_ValidateNotBeforeDuration(t *testing.T) {
 	config := logical.TestBackendConfig()
 
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -83,7 +84,16 @@ func (b *backend) pathSignIssueCertificateHelper(ctx context.Context, req *logic
 		}
 	}
 
-	ttl, err := b.calculateTTL(data, role)
+	if err := b.validatePrincipalsMatchRegex(req, role, parsedPrincipals); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	policy, err := b.calculatePrincipalPolicy(req, role, parsedPrincipals)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	ttl, err := b.calculateTTL(data, role, policy)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
@@ -93,7 +103,7 @@ func (b *backend) pathSignIssueCertificateHelper(ctx context.Context, req *logic
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	extensions, addExtTemplatingWarning, err := b.calculateExtensions(data, req, role)
+	extensions, addExtTemplatingWarning, err := b.calculateExtensions(data, req, role, policy)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
@@ -209,6 +219,134 @@ func (b *backend) calculateValidPrincipals(data *framework.FieldData, req *logic
 	}
 }
 
+// renderTemplates renders the identity templates within the value, passing
+// each rendered value through escape.
+func (b *backend) renderTemplates(value string, req *logical.Request, escape func(string) string) (string, error) {
+	if !containsTemplateRegex.MatchString(value) {
+		return value, nil
+	}
+	if req.EntityID == "" {
+		return "", fmt.Errorf("template '%s' requires an identity entity", value)
+	}
+
+	var renderErr error
+	rendered := containsTemplateRegex.ReplaceAllStringFunc(value, func(tpl string) string {
+		out, err := framework.PopulateIdentityTemplate(tpl, req.EntityID, b.System())
+		if err != nil && renderErr == nil {
+			renderErr = fmt.Errorf("template '%s' could not be rendered -> %s", tpl, err)
+		}
+		return escape(out)
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+	return rendered, nil
+}
+
+func (b *backend) validatePrincipalsMatchRegex(req *logical.Request, role *sshRole, principals []string) error {
+	if role.AllowedPrincipalsRegex == "" {
+		return nil
+	}
+
+	expr, err := b.renderTemplates(role.AllowedPrincipalsRegex, req, regexp.QuoteMeta)
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return fmt.Errorf("allowed_principals_regex is invalid: %w", err)
+	}
+
+	for _, principal := range principals {
+		if !re.MatchString(principal) {
+			return fmt.Errorf("%v does not match the allowed principals of the role", principal)
+		}
+	}
+	return nil
+}
+
+// principalPolicy is the combination of the principal policies of a role
+// matching the principals of a certificate.
+type principalPolicy struct {
+	ttl        time.Duration
+	maxTTL     time.Duration
+	extensions map[string]string
+}
+
+func (b *backend) calculatePrincipalPolicy(req *logical.Request, role *sshRole, principals []string) (*principalPolicy, error) {
+	result := &principalPolicy{
+		extensions: make(map[string]string),
+	}
+	if len(role.PrincipalPolicies) == 0 || len(principals) == 0 {
+		return result, nil
+	}
+
+	identity := func(s string) string { return s }
+	minDuration := func(current time.Duration, value string) (time.Duration, error) {
+		if value == "" {
+			return current, nil
+		}
+		rendered, err := b.renderTemplates(value, req, identity)
+		if err != nil {
+			return 0, err
+		}
+		d, err := parseutil.ParseDurationSecond(rendered)
+		if err != nil {
+			return 0, fmt.Errorf("invalid principal policy duration %q: %w", rendered, err)
+		}
+		if current == 0 || (d != 0 && d < current) {
+			return d, nil
+		}
+		return current, nil
+	}
+
+	// Apply policies in a stable order so that conflicting extensions
+	// resolve the same way on every request.
+	patterns := make([]string, 0, len(role.PrincipalPolicies))
+	for pattern := range role.PrincipalPolicies {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		policy := role.PrincipalPolicies[pattern]
+		if containsTemplateRegex.MatchString(pattern) && req.EntityID == "" {
+			// Templated patterns can't match requests without an entity
+			continue
+		}
+		rendered, err := b.renderTemplates(pattern, req, identity)
+		if err != nil {
+			return nil, err
+		}
+
+		// GlobbedStringsMatch compares a lone "*" literally
+		matched := rendered == "*"
+		for _, principal := range principals {
+			if strutil.GlobbedStringsMatch(rendered, principal) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		if result.ttl, err = minDuration(result.ttl, policy.TTL); err != nil {
+			return nil, err
+		}
+		if result.maxTTL, err = minDuration(result.maxTTL, policy.MaxTTL); err != nil {
+			return nil, err
+		}
+		for extensionKey, extensionValue := range policy.Extensions {
+			if result.extensions[extensionKey], err = b.renderTemplates(extensionValue, req, identity); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
 func validateValidPrincipalForHosts(role *sshRole) func([]string, string) bool {
 	return func(allowedPrincipals []string, validPrincipal string) bool {
 		for _, allowedPrincipal := range allowedPrincipals {
@@ -300,7 +438,7 @@ func (b *backend) calculateCriticalOptions(data *framework.FieldData, role *sshR
 	return criticalOptions, nil
 }
 
-func (b *backend) calculateExtensions(data *framework.FieldData, req *logical.Request, role *sshRole) (map[string]string, bool, error) {
+func (b *backend) calculateExtensions(data *framework.FieldData, req *logical.Request, role *sshRole, policy *principalPolicy) (map[string]string, bool, error) {
 	unparsedExtensions := data.Get("extensions").(map[string]interface{})
 	extensions := make(map[string]string)
 
@@ -350,19 +488,29 @@ func (b *backend) calculateExtensions(data *framework.FieldData, req *logical.Re
 			}
 		}
 	} else {
-		extensions = role.DefaultExtensions
+		for extensionKey, extensionValue := range role.DefaultExtensions {
+			extensions[extensionKey] = extensionValue
+		}
+	}
+
+	// Extensions granted by the principal policies of the role take
+	// precedence over the default ones.
+	for extensionKey, extensionValue := range policy.extensions {
+		extensions[extensionKey] = extensionValue
 	}
 
 	return extensions, haveMissingEntityInfoWithTemplatedExt, nil
 }
 
-func (b *backend) calculateTTL(data *framework.FieldData, role *sshRole) (time.Duration, error) {
+func (b *backend) calculateTTL(data *framework.FieldData, role *sshRole, policy *principalPolicy) (time.Duration, error) {
 	var ttl, maxTTL time.Duration
 	var err error
 
 	ttlRaw, specifiedTTL := data.GetOk("ttl")
 	if specifiedTTL {
 		ttl = time.Duration(ttlRaw.(int)) * time.Second
+	} else if policy.ttl != 0 {
+		ttl = policy.ttl
 	} else {
 		ttl, err = parseutil.ParseDurationSecond(role.TTL)
 		if err != nil {
@@ -380,6 +528,9 @@ func (b *backend) calculateTTL(data *framework.FieldData, role *sshRole) (time.D
 	if maxTTL == 0 {
 		maxTTL = b.System().MaxLeaseTTL()
 	}
+	if policy.maxTTL != 0 && policy.maxTTL < maxTTL {
+		maxTTL = policy.maxTTL
+	}
 
 	if ttl > maxTTL {
 		// Don't error if they were using system defaults, only error if
@@ -3,6 +3,7 @@ package ssh
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -68,6 +70,19 @@ type sshRole struct {
 	AlgorithmSigner            string            `mapstructure:"algorithm_signer" json:"algorithm_signer"`
 	Version                    int               `mapstructure:"role_version" json:"role_version"`
 	NotBeforeDuration          time.Duration     `mapstructure:"not_before_duration" json:"not_before_duration"`
+
+	// Principal validation and per-principal policies of CA roles; both may
+	// contain identity templates.
+	AllowedPrincipalsRegex string                         `mapstructure:"allowed_principals_regex" json:"allowed_principals_regex"`
+	PrincipalPolicies      map[string]*sshPrincipalPolicy `mapstructure:"principal_policies" json:"principal_policies"`
+}
+
+// sshPrincipalPolicy holds the TTLs and extensions of certificates issued for
+// the principals matching the policy. Each value can be an identity template.
+type sshPrincipalPolicy struct {
+	TTL        string            `mapstructure:"ttl" json:"ttl"`
+	MaxTTL     string            `mapstructure:"max_ttl" json:"max_ttl"`
+	Extensions map[string]string `mapstructure:"extensions" json:"extensions"`
 }
 
 func pathListRoles(b *backend) *framework.Path {
@@ -395,6 +410,29 @@ func pathRoles(b *backend) *framework.Path {
 					Value: 30,
 				},
 			},
+			"allowed_principals_regex": {
+				Type: framework.TypeString,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				A regular expression every valid principal of signed certificates must match, in
+				addition to being allowed by "allowed_users" or "allowed_domains". Identity templates
+				within the expression are rendered, with the rendered values matched literally.
+				`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Allowed principals regex",
+				},
+			},
+			"principal_policies": {
+				Type: framework.TypeMap,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				Policies applying to certificates signed for principals matching a glob pattern, as
+				a map of patterns to objects with optional "ttl", "max_ttl" and "extensions" keys.
+				The max_ttl of a policy caps the TTL of certificates and its ttl replaces the default
+				TTL of the role; its extensions are added to the default extensions. When several
+				policies match, the shortest TTLs apply. Patterns and values can be identity templates.
+				`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -590,6 +628,7 @@ func (b *backend) createCARole(allowedUsers, defaultUser, signer string, data *f
 		AlgorithmSigner:           signer,
 		Version:                   roleEntryVersion,
 		NotBeforeDuration:         time.Duration(data.Get("not_before_duration").(int)) * time.Second,
+		AllowedPrincipalsRegex:    data.Get("allowed_principals_regex").(string),
 	}
 
 	if !role.AllowUserCertificates && !role.AllowHostCertificates {
@@ -608,6 +647,16 @@ func (b *backend) createCARole(allowedUsers, defaultUser, signer string, data *f
 			`"ttl" value must be less than "max_ttl" when both are specified`)
 	}
 
+	if err := validatePrincipalsRegex(role.AllowedPrincipalsRegex); err != nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("error processing allowed_principals_regex: %s", err.Error()))
+	}
+
+	principalPolicies, err := parsePrincipalPolicies(data.Get("principal_policies").(map[string]interface{}))
+	if err != nil {
+		return nil, logical.ErrorResponse(fmt.Sprintf("error processing principal_policies: %s", err.Error()))
+	}
+	role.PrincipalPolicies = principalPolicies
+
 	// Persist TTLs
 	role.TTL = ttl.String()
 	role.MaxTTL = maxTTL.String()
@@ -618,6 +667,63 @@ func (b *backend) createCARole(allowedUsers, defaultUser, signer string, data *f
 	return role, nil
 }
 
+// validatePrincipalsRegex checks that the allowed principals regex compiles,
+// with its identity templates standing in for literal values.
+func validatePrincipalsRegex(expr string) error {
+	if expr == "" {
+		return nil
+	}
+	expr = containsTemplateRegex.ReplaceAllString(expr, "x")
+	_, err := regexp.Compile(expr)
+	return err
+}
+
+// parsePrincipalPolicies decodes the principal_policies field of a role,
+// validating the TTLs which aren't identity templates.
+func parsePrincipalPolicies(raw map[string]interface{}) (map[string]*sshPrincipalPolicy, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	policies := make(map[string]*sshPrincipalPolicy, len(raw))
+	for pattern, rawPolicy := range raw {
+		if pattern == "" {
+			return nil, fmt.Errorf("principal patterns must not be empty")
+		}
+
+		policy := &sshPrincipalPolicy{}
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			WeaklyTypedInput: true,
+			ErrorUnused:      true,
+			Result:           policy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := decoder.Decode(rawPolicy); err != nil {
+			return nil, fmt.Errorf("invalid policy for %q: %w", pattern, err)
+		}
+
+		var ttl, maxTTL time.Duration
+		if policy.TTL != "" && !containsTemplateRegex.MatchString(policy.TTL) {
+			if ttl, err = parseutil.ParseDurationSecond(policy.TTL); err != nil {
+				return nil, fmt.Errorf("invalid ttl for %q: %w", pattern, err)
+			}
+		}
+		if policy.MaxTTL != "" && !containsTemplateRegex.MatchString(policy.MaxTTL) {
+			if maxTTL, err = parseutil.ParseDurationSecond(policy.MaxTTL); err != nil {
+				return nil, fmt.Errorf("invalid max_ttl for %q: %w", pattern, err)
+			}
+		}
+		if ttl != 0 && maxTTL != 0 && ttl > maxTTL {
+			return nil, fmt.Errorf(`"ttl" value must be less than "max_ttl" for %q`, pattern)
+		}
+
+		policies[pattern] = policy
+	}
+	return policies, nil
+}
+
 func (b *backend) getRole(ctx context.Context, s logical.Storage, n string) (*sshRole, error) {
 	entry, err := s.Get(ctx, "roles/"+n)
 	if err != nil {
@@ -783,6 +889,8 @@ func (b *backend) parseRole(role *sshRole) (map[string]interface{}, error) {
 			"allowed_user_key_lengths":    role.AllowedUserKeyTypesLengths,
 			"algorithm_signer":            role.AlgorithmSigner,
 			"not_before_duration":         int64(role.NotBeforeDuration.Seconds()),
+			"allowed_principals_regex":    role.AllowedPrincipalsRegex,
+			"principal_policies":          role.PrincipalPolicies,
 		}
 	case KeyTypeDynamic:
 		result = map[string]interface{}{
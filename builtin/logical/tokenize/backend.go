@@ -0,0 +1,147 @@
+package tokenize
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// How often expired tokens are removed from storage.
+const tidyInterval = 1 * time.Hour
+
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b, err := Backend(conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend(conf *logical.BackendConfig) (*backend, error) {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				"archive/",
+				"policy/",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathEncode(&b),
+			pathDecode(&b),
+			pathLookup(&b),
+			pathMetadata(&b),
+			pathExport(&b),
+		},
+
+		Secrets:      []*framework.Secret{},
+		Invalidate:   b.invalidate,
+		BackendType:  logical.TypeLogical,
+		PeriodicFunc: b.periodicFunc,
+	}
+
+	var err error
+	b.lm, err = keysutil.NewLockManager(!conf.System.CachingDisabled(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+type backend struct {
+	*framework.Backend
+	lm *keysutil.LockManager
+
+	tidyLock sync.Mutex
+	lastTidy time.Time
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	if b.Logger().IsDebug() {
+		b.Logger().Debug("invalidating key", "key", key)
+	}
+	switch {
+	case strings.HasPrefix(key, "policy/"):
+		name := strings.TrimPrefix(key, "policy/")
+		b.lm.InvalidatePolicy(name)
+	}
+}
+
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	// Tokens are replicated, so only the primary may delete them.
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary | consts.ReplicationPerformanceStandby | consts.ReplicationDRSecondary) {
+		return nil
+	}
+
+	b.tidyLock.Lock()
+	defer b.tidyLock.Unlock()
+
+	if time.Since(b.lastTidy) < tidyInterval {
+		return nil
+	}
+	b.lastTidy = time.Now()
+
+	return b.tidyExpiredTokens(ctx, req.Storage)
+}
+
+func (b *backend) tidyExpiredTokens(ctx context.Context, s logical.Storage) error {
+	roles, err := s.List(ctx, rolePrefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, role := range roles {
+		prefix := tokenPrefix + role + "/"
+		keys, err := s.List(ctx, prefix)
+		if err != nil {
+			return err
+		}
+
+		var deleted int
+		for _, key := range keys {
+			entry, err := getTokenEntry(ctx, s, prefix+key)
+			if err != nil {
+				return err
+			}
+			if entry == nil || !entry.expired(now) {
+				continue
+			}
+
+			if err := s.Delete(ctx, prefix+key); err != nil {
+				return err
+			}
+			deleted++
+		}
+
+		if deleted > 0 {
+			b.Logger().Debug("removed expired tokens", "role", role, "count", deleted)
+		}
+	}
+
+	return nil
+}
+
+const backendHelp = `
+The tokenize backend replaces sensitive values with tokens.
+
+Values are encrypted with a per-role key and stored in Vault; the token
+returned in their place can later be exchanged for the original value by
+authorized clients. Roles may be convergent, in which case the same value
+always maps to the same token and tokens can be looked up by value.
+`
@@ -0,0 +1,224 @@
+package tokenize
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func createBackendWithStorage(t testing.TB) (*backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+func doRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("%s %s: err: %v resp: %#v", op, path, err, resp)
+	}
+	return resp
+}
+
+func TestTokenize_EncodeDecode(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	doRequest(t, b, s, logical.UpdateOperation, "roles/cards", nil)
+
+	resp := doRequest(t, b, s, logical.UpdateOperation, "encode/cards", map[string]interface{}{
+		"value":    "4111111111111111",
+		"metadata": map[string]interface{}{"owner": "alice"},
+	})
+	token := resp.Data["token"].(string)
+	if token == "" || token == "4111111111111111" {
+		t.Fatalf("bad token: %q", token)
+	}
+
+	// Non-convergent roles issue a new token every time.
+	resp = doRequest(t, b, s, logical.UpdateOperation, "encode/cards", map[string]interface{}{
+		"value": "4111111111111111",
+	})
+	if resp.Data["token"].(string) == token {
+		t.Fatal("expected distinct tokens for non-convergent role")
+	}
+
+	resp = doRequest(t, b, s, logical.UpdateOperation, "decode/cards", map[string]interface{}{
+		"token": token,
+	})
+	if resp.Data["value"] != "4111111111111111" {
+		t.Fatalf("bad value: %v", resp.Data["value"])
+	}
+
+	resp = doRequest(t, b, s, logical.UpdateOperation, "metadata/cards", map[string]interface{}{
+		"token": token,
+	})
+	if resp.Data["metadata"].(map[string]string)["owner"] != "alice" {
+		t.Fatalf("bad metadata: %v", resp.Data["metadata"])
+	}
+
+	// Lookup by value is only available for convergent roles.
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "lookup/cards",
+		Storage:   s,
+		Data:      map[string]interface{}{"value": "4111111111111111"},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected lookup error, got err: %v resp: %#v", err, resp)
+	}
+
+	// Tokens from one role cannot be decoded through another.
+	doRequest(t, b, s, logical.UpdateOperation, "roles/other", nil)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "decode/other",
+		Storage:   s,
+		Data:      map[string]interface{}{"token": token},
+	})
+	if err == nil {
+		t.Fatal("expected error decoding token through another role")
+	}
+}
+
+func TestTokenize_Convergent(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	doRequest(t, b, s, logical.UpdateOperation, "roles/ssn", map[string]interface{}{
+		"convergent": true,
+	})
+
+	first := doRequest(t, b, s, logical.UpdateOperation, "encode/ssn", map[string]interface{}{
+		"value": "078-05-1120",
+	}).Data["token"].(string)
+	second := doRequest(t, b, s, logical.UpdateOperation, "encode/ssn", map[string]interface{}{
+		"value": "078-05-1120",
+	}).Data["token"].(string)
+	if first != second {
+		t.Fatalf("convergent role returned different tokens: %q %q", first, second)
+	}
+
+	resp := doRequest(t, b, s, logical.UpdateOperation, "lookup/ssn", map[string]interface{}{
+		"value": "078-05-1120",
+	})
+	if resp.Data["token"] != first {
+		t.Fatalf("bad lookup result: %v", resp.Data["token"])
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/ssn",
+		Storage:   s,
+		Data:      map[string]interface{}{"convergent": false},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error changing convergent, got err: %v resp: %#v", err, resp)
+	}
+}
+
+func TestTokenize_Expiration(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	doRequest(t, b, s, logical.UpdateOperation, "roles/short", map[string]interface{}{
+		"max_ttl": "1h",
+	})
+
+	resp := doRequest(t, b, s, logical.UpdateOperation, "encode/short", map[string]interface{}{
+		"value": "secret",
+		"ttl":   "24h",
+	})
+	expiration, err := time.Parse(time.RFC3339, resp.Data["expiration_time"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expiration.After(time.Now().Add(time.Hour + time.Minute)) {
+		t.Fatalf("ttl was not capped by max_ttl: %v", expiration)
+	}
+
+	keys, err := s.List(context.Background(), tokenPrefix+"short/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("expected one stored token, got %d", len(keys))
+	}
+
+	// Force the entry into the past and ensure the tidy removes it.
+	key := tokenPrefix + "short/" + keys[0]
+	entry, err := getTokenEntry(context.Background(), s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.ExpirationTime = time.Now().Add(-time.Minute)
+	storageEntry, err := logical.StorageEntryJSON(key, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(context.Background(), storageEntry); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "decode/short",
+		Storage:   s,
+		Data:      map[string]interface{}{"token": resp.Data["token"]},
+	})
+	if err == nil {
+		t.Fatal("expected expired token to be rejected")
+	}
+
+	if err := b.tidyExpiredTokens(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = s.List(context.Background(), tokenPrefix+"short/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("expected expired token to be removed, got %d", len(keys))
+	}
+}
+
+func TestTokenize_Export(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	doRequest(t, b, s, logical.UpdateOperation, "roles/plain", nil)
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "export/plain",
+		Storage:   s,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error exporting non-exportable role, got err: %v resp: %#v", err, resp)
+	}
+
+	doRequest(t, b, s, logical.UpdateOperation, "roles/plain", map[string]interface{}{
+		"exportable": true,
+	})
+	resp = doRequest(t, b, s, logical.ReadOperation, "export/plain", nil)
+	if len(resp.Data["encryption_keys"].(map[string]string)) != 1 {
+		t.Fatalf("bad export: %#v", resp.Data)
+	}
+
+	doRequest(t, b, s, logical.DeleteOperation, "roles/plain", nil)
+	resp = doRequest(t, b, s, logical.ReadOperation, "roles/plain", nil)
+	if resp != nil {
+		t.Fatalf("expected role to be deleted, got %#v", resp)
+	}
+}
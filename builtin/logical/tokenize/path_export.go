@@ -0,0 +1,73 @@
+package tokenize
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathExport(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "export/" + framework.GenericNameRegex("role"),
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathExportRead,
+		},
+
+		HelpSynopsis:    pathExportHelpSyn,
+		HelpDescription: pathExportHelpDesc,
+	}
+}
+
+func (b *backend) pathExportRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+	if !role.Exportable {
+		return logical.ErrorResponse("role is not exportable"), nil
+	}
+
+	p, err := b.rolePolicy(ctx, req, roleName)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Unlock()
+
+	encryptionKeys := make(map[string]string, len(p.Keys))
+	hmacKeys := make(map[string]string, len(p.Keys))
+	for k, v := range p.Keys {
+		encryptionKeys[k] = base64.StdEncoding.EncodeToString(v.Key)
+		hmacKeys[k] = base64.StdEncoding.EncodeToString(v.HMACKey)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":            roleName,
+			"type":            p.Type.String(),
+			"latest_version":  strconv.Itoa(p.LatestVersion),
+			"encryption_keys": encryptionKeys,
+			"hmac_keys":       hmacKeys,
+		},
+	}, nil
+}
+
+const pathExportHelpSyn = `Export the key material of a role.`
+
+const pathExportHelpDesc = `
+Returns the encryption and HMAC keys of every version of the role's key.
+Only available for roles created with exportable set.
+`
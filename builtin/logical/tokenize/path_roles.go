@@ -0,0 +1,285 @@
+package tokenize
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	rolePrefix  = "role/"
+	tokenPrefix = "token/"
+)
+
+type roleEntry struct {
+	Convergent bool          `json:"convergent"`
+	Exportable bool          `json:"exportable"`
+	TTL        time.Duration `json:"ttl"`
+	MaxTTL     time.Duration `json:"max_ttl"`
+}
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"convergent": {
+				Type: framework.TypeBool,
+				Description: `If true, encoding the same value always returns the
+same token, and tokens can be looked up by value. Cannot be changed after
+the role is created.`,
+			},
+			"exportable": {
+				Type: framework.TypeBool,
+				Description: `If true, the role's key material can be exported.
+Once set this cannot be disabled.`,
+			},
+			"ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `Default lifetime of tokens. Zero means tokens do not
+expire unless a TTL is given when encoding.`,
+			},
+			"max_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lifetime of tokens. Zero means no maximum.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		ExistenceCheck: b.pathRoleExistenceCheck,
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func (b *backend) role(ctx context.Context, s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get(ctx, rolePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// rolePolicy returns the role's key, locked for reading. The caller must
+// call Unlock on the returned policy.
+func (b *backend) rolePolicy(ctx context.Context, req *logical.Request, name string) (*keysutil.Policy, error) {
+	p, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("key for role %q not found", name)
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+
+	return p, nil
+}
+
+func (b *backend) pathRoleExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	role, err := b.role(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, rolePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := b.role(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"convergent": role.Convergent,
+			"exportable": role.Exportable,
+			"ttl":        int64(role.TTL.Seconds()),
+			"max_ttl":    int64(role.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	role, err := b.role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	create := role == nil
+	if create {
+		role = &roleEntry{}
+	}
+
+	if convergentRaw, ok := data.GetOk("convergent"); ok {
+		if !create && convergentRaw.(bool) != role.Convergent {
+			return logical.ErrorResponse("convergent cannot be changed after the role is created"), nil
+		}
+		role.Convergent = convergentRaw.(bool)
+	}
+
+	if exportableRaw, ok := data.GetOk("exportable"); ok {
+		if role.Exportable && !exportableRaw.(bool) {
+			return logical.ErrorResponse("exportable cannot be disabled once enabled"), nil
+		}
+		role.Exportable = exportableRaw.(bool)
+	}
+
+	if ttlRaw, ok := data.GetOk("ttl"); ok {
+		role.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := data.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if role.MaxTTL > 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+
+	// The key is created along with the role; exportability can only ever
+	// be turned on, matching the underlying key.
+	p, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Upsert:     true,
+		Storage:    req.Storage,
+		Name:       name,
+		KeyType:    keysutil.KeyType_AES256_GCM96,
+		Exportable: role.Exportable,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("error generating key for role %q", name)
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(true)
+	}
+	if role.Exportable && !p.Exportable {
+		p.Exportable = true
+		err = p.Persist(ctx, req.Storage)
+	}
+	p.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := logical.StorageEntryJSON(rolePrefix+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	// Remove the tokens first; without the key they could never be decoded.
+	prefix := tokenPrefix + name + "/"
+	keys, err := req.Storage.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if err := req.Storage.Delete(ctx, prefix+key); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keys are created non-deletable; allow removal along with the role.
+	p, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		if !b.System().CachingDisabled() {
+			p.Lock(true)
+		}
+		p.DeletionAllowed = true
+		err = p.Persist(ctx, req.Storage)
+		p.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := b.lm.DeletePolicy(ctx, req.Storage, name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := req.Storage.Delete(ctx, rolePrefix+name); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathRoleHelpSyn = `
+Manage the roles used to tokenize values.
+`
+
+const pathRoleHelpDesc = `
+Each role has its own encryption key, created along with the role. Tokens
+issued under one role can only be decoded through the same role.
+
+Deleting a role permanently deletes its key and every token issued under it.
+`
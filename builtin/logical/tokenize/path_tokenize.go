@@ -0,0 +1,431 @@
+package tokenize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Length of randomly generated (non-convergent) tokens.
+const randomTokenLength = 32
+
+type tokenEntry struct {
+	Ciphertext     string            `json:"ciphertext"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	CreationTime   time.Time         `json:"creation_time"`
+	ExpirationTime time.Time         `json:"expiration_time,omitempty"`
+}
+
+func (t *tokenEntry) expired(now time.Time) bool {
+	return !t.ExpirationTime.IsZero() && now.After(t.ExpirationTime)
+}
+
+func pathEncode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "encode/" + framework.GenericNameRegex("role"),
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"value": {
+				Type:        framework.TypeString,
+				Description: "The value to tokenize.",
+			},
+			"metadata": {
+				Type:        framework.TypeKVPairs,
+				Description: "Arbitrary key/value metadata stored along with the token.",
+			},
+			"ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `Lifetime of the token. Defaults to the role's ttl and is
+capped at the role's max_ttl.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathEncodeWrite,
+		},
+
+		HelpSynopsis:    pathEncodeHelpSyn,
+		HelpDescription: pathEncodeHelpDesc,
+	}
+}
+
+func pathDecode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "decode/" + framework.GenericNameRegex("role"),
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"token": {
+				Type:        framework.TypeString,
+				Description: "The token to decode.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDecodeWrite,
+		},
+
+		HelpSynopsis:    pathDecodeHelpSyn,
+		HelpDescription: pathDecodeHelpDesc,
+	}
+}
+
+func pathLookup(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "lookup/" + framework.GenericNameRegex("role"),
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"value": {
+				Type:        framework.TypeString,
+				Description: "The value to find the token of.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathLookupWrite,
+		},
+
+		HelpSynopsis:    pathLookupHelpSyn,
+		HelpDescription: pathLookupHelpDesc,
+	}
+}
+
+func pathMetadata(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "metadata/" + framework.GenericNameRegex("role"),
+		Fields: map[string]*framework.FieldSchema{
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"token": {
+				Type:        framework.TypeString,
+				Description: "The token to return the metadata of.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathMetadataWrite,
+		},
+
+		HelpSynopsis:    pathMetadataHelpSyn,
+		HelpDescription: pathMetadataHelpDesc,
+	}
+}
+
+// tokenStorageKey returns the storage key of a token. Tokens are stored
+// under their HMAC so that storage does not reveal them.
+func tokenStorageKey(p *keysutil.Policy, role, token string) (string, error) {
+	key, err := p.HMACKey(1)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("token:" + token))
+	return tokenPrefix + role + "/" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// convergentToken deterministically derives the token of a value. The
+// first key version is always used so that tokens remain stable if the key
+// is ever rotated.
+func convergentToken(p *keysutil.Policy, value string) (string, error) {
+	key, err := p.HMACKey(1)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("value:" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func getTokenEntry(ctx context.Context, s logical.Storage, key string) (*tokenEntry, error) {
+	raw, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry tokenEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// lookupToken returns the live entry of the token, or nil if it does not
+// exist or has expired.
+func (b *backend) lookupToken(ctx context.Context, req *logical.Request, p *keysutil.Policy, role, token string) (*tokenEntry, error) {
+	key, err := tokenStorageKey(p, role, token)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := getTokenEntry(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.expired(time.Now()) {
+		return nil, nil
+	}
+
+	return entry, nil
+}
+
+func (b *backend) pathEncodeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("unknown role: %s", roleName), nil
+	}
+
+	value := data.Get("value").(string)
+	if value == "" {
+		return logical.ErrorResponse("missing value to tokenize"), nil
+	}
+
+	ttl := role.TTL
+	if ttlRaw, ok := data.GetOk("ttl"); ok {
+		ttl = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if role.MaxTTL > 0 && (ttl == 0 || ttl > role.MaxTTL) {
+		ttl = role.MaxTTL
+	}
+
+	p, err := b.rolePolicy(ctx, req, roleName)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Unlock()
+
+	var token string
+	if role.Convergent {
+		token, err = convergentToken(p, value)
+	} else {
+		token, err = base62.Random(randomTokenLength)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := p.Encrypt(p.LatestVersion, nil, nil, base64.StdEncoding.EncodeToString([]byte(value)))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	entry := &tokenEntry{
+		Ciphertext:   ciphertext,
+		Metadata:     data.Get("metadata").(map[string]string),
+		CreationTime: now,
+	}
+	if ttl > 0 {
+		entry.ExpirationTime = now.Add(ttl)
+	}
+
+	// For convergent roles, encoding a value again replaces the metadata
+	// and expiration of its existing token.
+	key, err := tokenStorageKey(p, roleName, token)
+	if err != nil {
+		return nil, err
+	}
+	storageEntry, err := logical.StorageEntryJSON(key, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, storageEntry); err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"token": token,
+		},
+	}
+	if !entry.ExpirationTime.IsZero() {
+		resp.Data["expiration_time"] = entry.ExpirationTime.Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+func (b *backend) pathDecodeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("unknown role: %s", roleName), nil
+	}
+
+	token := data.Get("token").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing token to decode"), nil
+	}
+
+	p, err := b.rolePolicy(ctx, req, roleName)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Unlock()
+
+	entry, err := b.lookupToken(ctx, req, p, roleName, token)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("token not found or expired"), logical.ErrInvalidRequest
+	}
+
+	plaintext, err := p.Decrypt(nil, nil, entry.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	value, err := base64.StdEncoding.DecodeString(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"value": string(value),
+		},
+	}, nil
+}
+
+func (b *backend) pathLookupWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("unknown role: %s", roleName), nil
+	}
+	if !role.Convergent {
+		return logical.ErrorResponse("lookup by value is only supported on convergent roles"), nil
+	}
+
+	value := data.Get("value").(string)
+	if value == "" {
+		return logical.ErrorResponse("missing value to look up"), nil
+	}
+
+	p, err := b.rolePolicy(ctx, req, roleName)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Unlock()
+
+	token, err := convergentToken(p, value)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := b.lookupToken(ctx, req, p, roleName, token)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("no token exists for value"), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token": token,
+		},
+	}, nil
+}
+
+func (b *backend) pathMetadataWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleName := data.Get("role").(string)
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("unknown role: %s", roleName), nil
+	}
+
+	token := data.Get("token").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing token"), nil
+	}
+
+	p, err := b.rolePolicy(ctx, req, roleName)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Unlock()
+
+	entry, err := b.lookupToken(ctx, req, p, roleName, token)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return logical.ErrorResponse("token not found or expired"), logical.ErrInvalidRequest
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"metadata":      entry.Metadata,
+			"creation_time": entry.CreationTime.Format(time.RFC3339),
+		},
+	}
+	if !entry.ExpirationTime.IsZero() {
+		resp.Data["expiration_time"] = entry.ExpirationTime.Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+const pathEncodeHelpSyn = `Replace a value with a token.`
+
+const pathEncodeHelpDesc = `
+Encrypts the value with the role's key, stores it and returns a token which
+can later be exchanged for the value at the decode endpoint. For convergent
+roles the same value always yields the same token; encoding it again
+replaces the token's metadata and expiration.
+`
+
+const pathDecodeHelpSyn = `Exchange a token for its original value.`
+
+const pathDecodeHelpDesc = `
+Returns the value a token was issued for. Expired tokens cannot be decoded.
+`
+
+const pathLookupHelpSyn = `Find the token of a value.`
+
+const pathLookupHelpDesc = `
+Returns the token previously issued for the value, without issuing a new one.
+Only available on convergent roles.
+`
+
+const pathMetadataHelpSyn = `Read the metadata of a token.`
+
+const pathMetadataHelpDesc = `
+Returns the metadata stored with a token along with its creation and
+expiration times, without revealing the tokenized value.
+`
@@ -44,6 +44,7 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			// Rotate/Config needs to come before Keys
 			// as the handler is greedy
 			b.pathConfig(),
+			b.pathUsageLog(),
 			b.pathRotate(),
 			b.pathRewrap(),
 			b.pathWrappingKey(),
@@ -54,7 +55,10 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			b.pathExportKeys(),
 			b.pathEncrypt(),
 			b.pathDecrypt(),
+			b.pathHPKESeal(),
+			b.pathHPKEOpen(),
 			b.pathDatakey(),
+			b.pathDerive(),
 			b.pathRandom(),
 			b.pathHash(),
 			b.pathHMAC(),
@@ -64,13 +68,19 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			b.pathRestore(),
 			b.pathTrim(),
 			b.pathCacheConfig(),
+			b.pathImportAttestationConfig(),
 		},
 
 		Secrets:      []*framework.Secret{},
 		Invalidate:   b.invalidate,
 		BackendType:  logical.TypeLogical,
 		PeriodicFunc: b.periodicFunc,
+		Clean:        b.cleanup,
 	}
+	b.Backend.Paths = append(b.Backend.Paths, b.pathSigningApprovals()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.pathRotationHooks()...)
+
+	b.rotationHookCtx, b.rotationHookCancel = context.WithCancel(context.Background())
 
 	// determine cacheSize to use. Defaults to 0 which means unlimited
 	cacheSize := 0
@@ -94,6 +104,8 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 		return nil, err
 	}
 
+	b.backendUUID = conf.BackendUUID
+
 	return &b, nil
 }
 
@@ -105,6 +117,18 @@ type backend struct {
 	cacheSizeChanged     bool
 	checkAutoRotateAfter time.Time
 	autoRotateOnce       sync.Once
+	backendUUID          string
+	// Lock serializing writes to the usage logs of keys
+	usageLogLock sync.Mutex
+	// Lock serializing the approvals of signatures
+	signingApprovalLock sync.Mutex
+	// Context of the deliveries of rotation events, canceled on unload
+	rotationHookCtx    context.Context
+	rotationHookCancel context.CancelFunc
+}
+
+func (b *backend) cleanup(ctx context.Context) {
+	b.rotationHookCancel()
 }
 
 func GetCacheSizeFromStorage(ctx context.Context, s logical.Storage) (int, error) {
@@ -255,6 +279,19 @@ func (b *backend) rotateIfRequired(ctx context.Context, req *logical.Request, ke
 		return nil
 	}
 
+	// Rotate the key once its latest version can no longer encrypt, so that
+	// the key stays usable when its versions expire.
+	if expiry := p.VersionEncryptionExpiry(p.LatestVersion); !expiry.IsZero() && !time.Now().Before(expiry) && p.Type != keysutil.KeyType_MANAGED_KEY {
+		if b.Logger().IsDebug() {
+			b.Logger().Debug("rotating key whose latest version expired", "key", key)
+		}
+		if err := p.Rotate(ctx, req.Storage, b.GetRandomReader()); err != nil {
+			return err
+		}
+		b.notifyRotation(ctx, req.Storage, p)
+		return nil
+	}
+
 	// If the policy's automatic rotation period is 0, it should not
 	// automatically rotate.
 	if p.AutoRotatePeriod == 0 {
@@ -267,8 +304,10 @@ func (b *backend) rotateIfRequired(ctx context.Context, req *logical.Request, ke
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("automatically rotating key", "key", key)
 		}
-		return p.Rotate(ctx, req.Storage, b.GetRandomReader())
-
+		if err := p.Rotate(ctx, req.Storage, b.GetRandomReader()); err != nil {
+			return err
+		}
+		b.notifyRotation(ctx, req.Storage, p)
 	}
 	return nil
 }
@@ -1857,4 +1857,34 @@ func testTransit_AEAD(t *testing.T, keyType string) {
 	if err == nil || (resp != nil && !resp.IsError()) {
 		t.Fatalf("bad expected error: err: %v\nresp: %#v", err, resp)
 	}
+
+	// Rewrapping requires the associated data, and binds it to the new
+	// ciphertext.
+	rewrapReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rewrap/aead",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"ciphertext": ciphertext2,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), rewrapReq)
+	if err == nil || (resp != nil && !resp.IsError()) {
+		t.Fatalf("bad expected error: err: %v\nresp: %#v", err, resp)
+	}
+
+	rewrapReq.Data["associated_data"] = associated
+	resp, err = b.HandleRequest(context.Background(), rewrapReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+
+	decryptReq.Data["ciphertext"] = resp.Data["ciphertext"].(string)
+	resp, err = b.HandleRequest(context.Background(), decryptReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if plaintext != resp.Data["plaintext"] {
+		t.Fatalf("bad: plaintext; expected: %q\nactual: %q", plaintext, resp.Data["plaintext"])
+	}
 }
@@ -0,0 +1,79 @@
+package transit
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+var errManagedKeysUnsupported = errors.New("managed keys are not supported by this Vault server")
+
+func (b *backend) withManagedSigningKey(ctx context.Context, keyName string, usage logical.KeyUsage, f logical.ManagedSigningKeyConsumer) error {
+	sysView, ok := b.System().(logical.ManagedKeySystemView)
+	if !ok {
+		return errManagedKeysUnsupported
+	}
+
+	return sysView.WithManagedSigningKeyByName(ctx, keyName, b.backendUUID, func(ctx context.Context, key logical.ManagedSigningKey) error {
+		if !key.AllowsAll([]logical.KeyUsage{usage}) {
+			return errutil.UserError{Err: fmt.Sprintf("managed key %q does not allow this operation", keyName)}
+		}
+		return f(ctx, key)
+	})
+}
+
+// getManagedKeyPublicKey returns the public key of a managed key, checking
+// that the mount may verify signatures with it.
+func (b *backend) getManagedKeyPublicKey(ctx context.Context, keyName string) (public crypto.PublicKey, err error) {
+	err = b.withManagedSigningKey(ctx, keyName, logical.KeyUsageVerify, func(ctx context.Context, key logical.ManagedSigningKey) error {
+		public, err = key.GetPublicKey(ctx)
+		return err
+	})
+	return public, err
+}
+
+// getManagedKeySigner returns a signer for a managed key, checking that the
+// mount may sign with it.
+func (b *backend) getManagedKeySigner(ctx context.Context, keyName string) (crypto.Signer, error) {
+	var public crypto.PublicKey
+	err := b.withManagedSigningKey(ctx, keyName, logical.KeyUsageSign, func(ctx context.Context, key logical.ManagedSigningKey) (err error) {
+		public, err = key.GetPublicKey(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &managedKeySigner{
+		ctx:     ctx,
+		b:       b,
+		keyName: keyName,
+		public:  public,
+	}, nil
+}
+
+// managedKeySigner signs with a managed key, looking the key up on every
+// signature so that it is only used within the scope of a registry call.
+type managedKeySigner struct {
+	ctx     context.Context
+	b       *backend
+	keyName string
+	public  crypto.PublicKey
+}
+
+func (s *managedKeySigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *managedKeySigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) (sig []byte, err error) {
+	err = s.b.withManagedSigningKey(s.ctx, s.keyName, logical.KeyUsageSign, func(ctx context.Context, key logical.ManagedSigningKey) error {
+		sig, err = key.Sign(ctx, digest, rand, opts)
+		return err
+	})
+	return sig, err
+}
@@ -0,0 +1,198 @@
+package transit
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+type testManagedKey struct {
+	name   string
+	key    *ecdsa.PrivateKey
+	usages []logical.KeyUsage
+}
+
+func (k *testManagedKey) Name() string                                     { return k.name }
+func (k *testManagedKey) UUID() string                                     { return k.name + "-uuid" }
+func (k *testManagedKey) Present(context.Context) (bool, error)            { return true, nil }
+func (k *testManagedKey) GetSigner(context.Context) (crypto.Signer, error) { return k.key, nil }
+
+func (k *testManagedKey) AllowsAll(usages []logical.KeyUsage) bool {
+	for _, usage := range usages {
+		found := false
+		for _, allowed := range k.usages {
+			found = found || allowed == usage
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (k *testManagedKey) GetPublicKey(context.Context) (crypto.PublicKey, error) {
+	return k.key.Public(), nil
+}
+
+func (k *testManagedKey) Sign(_ context.Context, value []byte, randomSource io.Reader, opts crypto.SignerOpts) ([]byte, error) {
+	return k.key.Sign(randomSource, value, opts)
+}
+
+func (k *testManagedKey) Verify(_ context.Context, signature, value []byte, _ crypto.SignerOpts) (bool, error) {
+	return ecdsa.VerifyASN1(&k.key.PublicKey, value, signature), nil
+}
+
+type testManagedKeySystemView struct {
+	*logical.StaticSystemView
+	keys map[string]*testManagedKey
+}
+
+func (s *testManagedKeySystemView) WithManagedKeyByName(ctx context.Context, keyName, _ string, f logical.ManagedKeyConsumer) error {
+	return s.WithManagedSigningKeyByName(ctx, keyName, "", func(ctx context.Context, key logical.ManagedSigningKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (s *testManagedKeySystemView) WithManagedKeyByUUID(context.Context, string, string, logical.ManagedKeyConsumer) error {
+	return errors.New("not implemented")
+}
+
+func (s *testManagedKeySystemView) WithManagedSigningKeyByName(ctx context.Context, keyName, _ string, f logical.ManagedSigningKeyConsumer) error {
+	key, ok := s.keys[keyName]
+	if !ok {
+		return errors.New("no such managed key")
+	}
+	return f(ctx, key)
+}
+
+func (s *testManagedKeySystemView) WithManagedSigningKeyByUUID(context.Context, string, string, logical.ManagedSigningKeyConsumer) error {
+	return errors.New("not implemented")
+}
+
+func (s *testManagedKeySystemView) WithManagedEncryptingKeyByName(context.Context, string, string, logical.ManagedEncryptingKeyConsumer) error {
+	return errors.New("not implemented")
+}
+
+func (s *testManagedKeySystemView) WithManagedEncryptingKeyByUUID(context.Context, string, string, logical.ManagedEncryptingKeyConsumer) error {
+	return errors.New("not implemented")
+}
+
+func TestTransit_ManagedKey(t *testing.T) {
+	newKey := func(name string, usages ...logical.KeyUsage) *testManagedKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &testManagedKey{name: name, key: key, usages: usages}
+	}
+
+	storage := &logical.InmemStorage{}
+	conf := &logical.BackendConfig{
+		StorageView: storage,
+		System: &testManagedKeySystemView{
+			StaticSystemView: logical.TestSystemView(),
+			keys: map[string]*testManagedKey{
+				"hsm":         newKey("hsm", logical.KeyUsageSign, logical.KeyUsageVerify),
+				"verify-only": newKey("verify-only", logical.KeyUsageVerify),
+			},
+		},
+	}
+	b, err := Backend(context.Background(), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Setup(context.Background(), conf); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+	}
+
+	// The managed key name is required and must exist
+	for _, data := range []map[string]interface{}{
+		{"type": "managed_key"},
+		{"type": "managed_key", "managed_key_name": "missing"},
+		{"type": "managed_key", "managed_key_name": "hsm", "exportable": true},
+		{"type": "managed_key", "managed_key_name": "hsm", "auto_rotate_period": "24h"},
+		{"type": "aes256-gcm96", "managed_key_name": "hsm"},
+	} {
+		resp, err := request(logical.UpdateOperation, "keys/invalid", data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error creating key with %v", data)
+		}
+	}
+
+	resp, err := request(logical.UpdateOperation, "keys/managed", map[string]interface{}{
+		"type":             "managed_key",
+		"managed_key_name": "hsm",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	resp, err = request(logical.ReadOperation, "keys/managed", nil)
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	if resp.Data["type"] != "managed_key" || resp.Data["managed_key_name"] != "hsm" || resp.Data["supports_signing"] != true {
+		t.Fatalf("unexpected key data: %#v", resp.Data)
+	}
+
+	input := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	for _, marshaling := range []string{"asn1", "jws"} {
+		resp, err = request(logical.UpdateOperation, "sign/managed", map[string]interface{}{
+			"input":                input,
+			"marshaling_algorithm": marshaling,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+		signature := resp.Data["signature"].(string)
+
+		resp, err = request(logical.UpdateOperation, "verify/managed", map[string]interface{}{
+			"input":                input,
+			"signature":            signature,
+			"marshaling_algorithm": marshaling,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+		if resp.Data["valid"] != true {
+			t.Fatalf("%s signature did not verify", marshaling)
+		}
+	}
+
+	// Rotation happens within the key management system
+	resp, err = request(logical.UpdateOperation, "keys/managed/rotate", nil)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatal("expected managed key rotation to fail")
+	}
+
+	// Usages of the managed key are enforced
+	resp, err = request(logical.UpdateOperation, "keys/verify-only", map[string]interface{}{
+		"type":             "managed_key",
+		"managed_key_name": "verify-only",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+	resp, err = request(logical.UpdateOperation, "sign/verify-only", map[string]interface{}{
+		"input": input,
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatal("expected signing with a verify-only managed key to fail")
+	}
+}
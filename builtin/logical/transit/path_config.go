@@ -55,6 +55,65 @@ the latest version of the key is allowed.`,
 being automatically rotated. A value of 0
 disables automatic rotation for the key.`,
 			},
+
+			"usage_log": {
+				Type: framework.TypeBool,
+				Description: `Enables the usage log of the key, a
+hash-chained record of every signing operation
+performed with it. Only valid for keys that
+support signing.`,
+			},
+
+			"signing_approvals_required": {
+				Type: framework.TypeInt,
+				Description: `Number of distinct entities that must
+request the same signature before it is
+produced. A value of 0 or 1 disables
+threshold signing. Only valid for keys that
+support signing.`,
+			},
+
+			"signing_approval_window": {
+				Type: framework.TypeDurationSecond,
+				Description: `Amount of time the approvals of a
+signature are kept while waiting for the
+remaining approvers. Defaults to one hour.`,
+			},
+
+			"allow_subkey_derivation": {
+				Type: framework.TypeBool,
+				Description: `Enables deriving subkeys from the key
+with the derive endpoint, which returns key
+material to the caller.`,
+			},
+
+			"max_subkey_length": {
+				Type: framework.TypeInt,
+				Description: fmt.Sprintf(`Maximum length in bytes of the subkeys
+derived from the key, up to %d. Defaults to %d.`, keysutil.MaxSubkeyLength, keysutil.DefaultMaxSubkeyLength),
+			},
+
+			"subkey_wrap_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `If set, derived subkeys are always
+response-wrapped, with at most this TTL.`,
+			},
+
+			"version_encryption_period": {
+				Type: framework.TypeDurationSecond,
+				Description: `Amount of time after its creation each
+version of the key can be used to encrypt and
+sign. A value of 0 disables the limit.`,
+			},
+
+			"version_decryption_period": {
+				Type: framework.TypeDurationSecond,
+				Description: `Amount of time after its creation each
+version of the key can be used to decrypt and
+verify, after which the version is disabled.
+Must not be shorter than the encryption period.
+A value of 0 disables the limit.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -92,6 +151,14 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 	originalDeletionAllowed := p.DeletionAllowed
 	originalExportable := p.Exportable
 	originalAllowPlaintextBackup := p.AllowPlaintextBackup
+	originalUsageLog := p.UsageLog
+	originalSigningApprovalsRequired := p.SigningApprovalsRequired
+	originalSigningApprovalWindow := p.SigningApprovalWindow
+	originalSubkeyDerivationAllowed := p.SubkeyDerivationAllowed
+	originalMaxSubkeyLength := p.MaxSubkeyLength
+	originalSubkeyWrapTTL := p.SubkeyWrapTTL
+	originalVersionEncryptionPeriod := p.VersionEncryptionPeriod
+	originalVersionDecryptionPeriod := p.VersionDecryptionPeriod
 
 	defer func() {
 		if retErr != nil || (resp != nil && resp.IsError()) {
@@ -100,6 +167,14 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 			p.DeletionAllowed = originalDeletionAllowed
 			p.Exportable = originalExportable
 			p.AllowPlaintextBackup = originalAllowPlaintextBackup
+			p.UsageLog = originalUsageLog
+			p.SigningApprovalsRequired = originalSigningApprovalsRequired
+			p.SigningApprovalWindow = originalSigningApprovalWindow
+			p.SubkeyDerivationAllowed = originalSubkeyDerivationAllowed
+			p.MaxSubkeyLength = originalMaxSubkeyLength
+			p.SubkeyWrapTTL = originalSubkeyWrapTTL
+			p.VersionEncryptionPeriod = originalVersionEncryptionPeriod
+			p.VersionDecryptionPeriod = originalVersionDecryptionPeriod
 		}
 	}()
 
@@ -178,6 +253,9 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		exportable := exportableRaw.(bool)
 		// Don't unset the already set value
 		if exportable && !p.Exportable {
+			if p.Type == keysutil.KeyType_MANAGED_KEY {
+				return logical.ErrorResponse("managed keys cannot be exported"), nil
+			}
 			p.Exportable = exportable
 			persistNeeded = true
 		}
@@ -204,12 +282,135 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 			return logical.ErrorResponse("auto rotate period must be 0 to disable or at least an hour"), nil
 		}
 
+		if autoRotatePeriod != 0 && p.Type == keysutil.KeyType_MANAGED_KEY {
+			return logical.ErrorResponse("managed keys cannot be automatically rotated"), nil
+		}
+
 		if autoRotatePeriod != p.AutoRotatePeriod {
 			p.AutoRotatePeriod = autoRotatePeriod
 			persistNeeded = true
 		}
 	}
 
+	usageLogRaw, ok := d.GetOk("usage_log")
+	if ok {
+		usageLog := usageLogRaw.(bool)
+		if usageLog && !p.Type.SigningSupported() {
+			return logical.ErrorResponse(fmt.Sprintf("key type %v does not support signing, the usage log cannot be enabled", p.Type)), nil
+		}
+		if usageLog != p.UsageLog {
+			p.UsageLog = usageLog
+			persistNeeded = true
+		}
+	}
+
+	signingApprovalsRequiredRaw, ok := d.GetOk("signing_approvals_required")
+	if ok {
+		signingApprovalsRequired := signingApprovalsRequiredRaw.(int)
+		if signingApprovalsRequired < 0 {
+			return logical.ErrorResponse("signing approvals required cannot be negative"), nil
+		}
+		if signingApprovalsRequired > 1 && !p.Type.SigningSupported() {
+			return logical.ErrorResponse(fmt.Sprintf("key type %v does not support signing, signing approvals cannot be required", p.Type)), nil
+		}
+		if signingApprovalsRequired != p.SigningApprovalsRequired {
+			p.SigningApprovalsRequired = signingApprovalsRequired
+			persistNeeded = true
+		}
+	}
+
+	signingApprovalWindowRaw, ok, err := d.GetOkErr("signing_approval_window")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		signingApprovalWindow := time.Second * time.Duration(signingApprovalWindowRaw.(int))
+		if signingApprovalWindow < 0 {
+			return logical.ErrorResponse("signing approval window cannot be negative"), nil
+		}
+		if signingApprovalWindow != p.SigningApprovalWindow {
+			p.SigningApprovalWindow = signingApprovalWindow
+			persistNeeded = true
+		}
+	}
+
+	allowSubkeyDerivationRaw, ok := d.GetOk("allow_subkey_derivation")
+	if ok {
+		allowSubkeyDerivation := allowSubkeyDerivationRaw.(bool)
+		if allowSubkeyDerivation && !p.Type.SubkeyDerivationSupported() {
+			return logical.ErrorResponse(fmt.Sprintf("subkey derivation not supported for key type %v", p.Type)), nil
+		}
+		if allowSubkeyDerivation != p.SubkeyDerivationAllowed {
+			p.SubkeyDerivationAllowed = allowSubkeyDerivation
+			persistNeeded = true
+		}
+	}
+
+	maxSubkeyLengthRaw, ok := d.GetOk("max_subkey_length")
+	if ok {
+		maxSubkeyLength := maxSubkeyLengthRaw.(int)
+		if maxSubkeyLength < 0 || maxSubkeyLength > keysutil.MaxSubkeyLength {
+			return logical.ErrorResponse(fmt.Sprintf("max subkey length must be between 0 and %d", keysutil.MaxSubkeyLength)), nil
+		}
+		if maxSubkeyLength != p.MaxSubkeyLength {
+			p.MaxSubkeyLength = maxSubkeyLength
+			persistNeeded = true
+		}
+	}
+
+	subkeyWrapTTLRaw, ok, err := d.GetOkErr("subkey_wrap_ttl")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		subkeyWrapTTL := time.Second * time.Duration(subkeyWrapTTLRaw.(int))
+		if subkeyWrapTTL < 0 {
+			return logical.ErrorResponse("subkey wrap TTL cannot be negative"), nil
+		}
+		if subkeyWrapTTL != p.SubkeyWrapTTL {
+			p.SubkeyWrapTTL = subkeyWrapTTL
+			persistNeeded = true
+		}
+	}
+
+	versionEncryptionPeriodRaw, ok, err := d.GetOkErr("version_encryption_period")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		versionEncryptionPeriod := time.Second * time.Duration(versionEncryptionPeriodRaw.(int))
+		if versionEncryptionPeriod < 0 {
+			return logical.ErrorResponse("version encryption period cannot be negative"), nil
+		}
+		if versionEncryptionPeriod != p.VersionEncryptionPeriod {
+			p.VersionEncryptionPeriod = versionEncryptionPeriod
+			persistNeeded = true
+		}
+	}
+
+	versionDecryptionPeriodRaw, ok, err := d.GetOkErr("version_decryption_period")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		versionDecryptionPeriod := time.Second * time.Duration(versionDecryptionPeriodRaw.(int))
+		if versionDecryptionPeriod < 0 {
+			return logical.ErrorResponse("version decryption period cannot be negative"), nil
+		}
+		if versionDecryptionPeriod != p.VersionDecryptionPeriod {
+			p.VersionDecryptionPeriod = versionDecryptionPeriod
+			persistNeeded = true
+		}
+	}
+
+	if p.VersionDecryptionPeriod > 0 && p.VersionDecryptionPeriod < p.VersionEncryptionPeriod {
+		return logical.ErrorResponse(
+			fmt.Sprintf("version decryption period of %s cannot be shorter than the version encryption period of %s", p.VersionDecryptionPeriod, p.VersionEncryptionPeriod)), nil
+	}
+	if p.VersionDecryptionPeriod > 0 && p.VersionEncryptionPeriod == 0 {
+		return logical.ErrorResponse("a version decryption period requires a version encryption period"), nil
+	}
+
 	if !persistNeeded {
 		return nil, nil
 	}
@@ -13,6 +13,7 @@ import (
 	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/api"
 	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault"
 )
@@ -403,3 +404,83 @@ func TestTransit_UpdateKeyConfigWithAutorotation(t *testing.T) {
 		})
 	}
 }
+
+func TestTransit_ConfigVersionPeriods(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/periods",
+	}
+	if _, err := b.HandleRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfig := func(data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Storage:   s,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/periods/config",
+			Data:      data,
+		})
+	}
+
+	resp, err := writeConfig(map[string]interface{}{
+		"version_encryption_period": "2h",
+		"version_decryption_period": "1h",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error with a decryption period shorter than the encryption period, got: resp: %#v, err: %v", resp, err)
+	}
+	resp, err = writeConfig(map[string]interface{}{
+		"version_decryption_period": "1h",
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error with a decryption period alone, got: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = writeConfig(map[string]interface{}{
+		"version_encryption_period": "1h",
+		"version_decryption_period": "24h",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("failed to configure version periods: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.ReadOperation,
+		Path:      "keys/periods",
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("failed to read key: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["version_encryption_period"] != int64(3600) || resp.Data["version_decryption_period"] != int64(86400) {
+		t.Fatalf("unexpected version periods: %v, %v", resp.Data["version_encryption_period"], resp.Data["version_decryption_period"])
+	}
+	expirations := resp.Data["version_expirations"].(map[string]interface{})
+	if _, ok := expirations["1"]; !ok {
+		t.Fatalf("expected the expiration of version 1, got: %#v", expirations)
+	}
+
+	// Once the latest version can no longer encrypt, the key is rotated
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: s,
+		Name:    "periods",
+	}, b.GetRandomReader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := p.Keys["1"]
+	entry.CreationTime = time.Now().Add(-2 * time.Hour)
+	p.Keys["1"] = entry
+
+	if err := b.rotateIfRequired(ctx, &logical.Request{Storage: s}, "periods", p); err != nil {
+		t.Fatal(err)
+	}
+	if p.LatestVersion != 2 {
+		t.Fatalf("expected the key to be rotated, latest version is %d", p.LatestVersion)
+	}
+}
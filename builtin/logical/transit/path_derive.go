@@ -0,0 +1,137 @@
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func (b *backend) pathDerive() *framework.Path {
+	return &framework.Path{
+		Pattern: "derive/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key to derive the subkey from",
+			},
+
+			"algorithm": {
+				Type:    framework.TypeString,
+				Default: keysutil.SubkeyAlgorithmHKDFSHA256,
+				Description: `The derivation algorithm. Options are
+"hkdf-sha256" (default) and "kbkdf-hmac-sha256".`,
+			},
+
+			"info": {
+				Type: framework.TypeString,
+				Description: `Base64 encoded application-specific
+information the subkey is bound to, such as its
+purpose. The context of the counter mode KDF for
+"kbkdf-hmac-sha256".`,
+			},
+
+			"salt": {
+				Type:        framework.TypeString,
+				Description: `Base64 encoded salt. Only supported by "hkdf-sha256".`,
+			},
+
+			"length": {
+				Type:    framework.TypeInt,
+				Default: 32,
+				Description: `Length of the subkey in bytes, up to the
+maximum configured on the key.`,
+			},
+
+			"key_version": {
+				Type: framework.TypeInt,
+				Description: `The version of the key to derive the
+subkey from. Defaults to the latest version.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDeriveWrite,
+		},
+
+		HelpSynopsis:    pathDeriveHelpSyn,
+		HelpDescription: pathDeriveHelpDesc,
+	}
+}
+
+func (b *backend) pathDeriveWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	info, err := base64.StdEncoding.DecodeString(d.Get("info").(string))
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode info"), logical.ErrInvalidRequest
+	}
+	salt, err := base64.StdEncoding.DecodeString(d.Get("salt").(string))
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode salt"), logical.ErrInvalidRequest
+	}
+	algorithm := d.Get("algorithm").(string)
+
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    d.Get("name").(string),
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	keyVersion := d.Get("key_version").(int)
+	if keyVersion == 0 {
+		keyVersion = p.LatestVersion
+	}
+
+	subkey, err := p.DeriveSubkey(keyVersion, algorithm, salt, info, d.Get("length").(int))
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"subkey":      base64.StdEncoding.EncodeToString(subkey),
+			"algorithm":   algorithm,
+			"key_version": keyVersion,
+		},
+	}
+
+	// The core wraps the response, with the TTL of the request if it is
+	// lower
+	if p.SubkeyWrapTTL > 0 {
+		resp.WrapInfo = &wrapping.ResponseWrapInfo{
+			TTL: p.SubkeyWrapTTL,
+		}
+	}
+
+	return resp, nil
+}
+
+const pathDeriveHelpSyn = `Derive a subkey from a named key`
+
+const pathDeriveHelpDesc = `
+This path derives an application subkey from the named key, with HKDF or the
+counter mode KDF of NIST SP 800-108, and returns it to the caller. The same
+parameters always derive the same subkey, so that applications sharing a
+transit key can derive the same subkeys without sharing a master secret.
+
+Subkey derivation must be allowed with the allow_subkey_derivation parameter
+of the key configuration, which also controls the maximum subkey length and
+whether subkeys are always response-wrapped.
+`
@@ -0,0 +1,105 @@
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_Derive(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	handle := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+
+	resp, err := handle("keys/master", nil)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+
+	deriveData := map[string]interface{}{
+		"info": base64.StdEncoding.EncodeToString([]byte("tenant-1/db")),
+		"salt": base64.StdEncoding.EncodeToString([]byte("salt")),
+	}
+
+	// Derivation must be allowed first
+	resp, err = handle("derive/master", deriveData)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error deriving from a key not allowing it: %#v", resp)
+	}
+
+	resp, err = handle("keys/master/config", map[string]interface{}{
+		"allow_subkey_derivation": true,
+		"max_subkey_length":       48,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+
+	resp, err = handle("derive/master", deriveData)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	subkey, err := base64.StdEncoding.DecodeString(resp.Data["subkey"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subkey) != 32 || resp.Data["key_version"] != 1 || resp.Data["algorithm"] != "hkdf-sha256" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.WrapInfo != nil {
+		t.Fatalf("expected no wrapping: %#v", resp.WrapInfo)
+	}
+
+	// The configured maximum length applies
+	deriveData["length"] = 48
+	resp, err = handle("derive/master", deriveData)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	deriveData["length"] = 49
+	resp, err = handle("derive/master", deriveData)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error above the maximum length: %#v", resp)
+	}
+
+	// Responses are wrapped when the key requires it
+	resp, err = handle("keys/master/config", map[string]interface{}{
+		"subkey_wrap_ttl": "5m",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	deriveData["length"] = 32
+	resp, err = handle("derive/master", deriveData)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != 5*time.Minute {
+		t.Fatalf("expected the response to be wrapped: %#v", resp.WrapInfo)
+	}
+
+	// Asymmetric keys are not supported
+	resp, err = handle("keys/signing", map[string]interface{}{
+		"type": "ed25519",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	resp, err = handle("keys/signing/config", map[string]interface{}{
+		"allow_subkey_derivation": true,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error allowing subkey derivation for an ed25519 key: %#v", resp)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/hmac"
 	"encoding/base64"
 	"fmt"
+	"hash"
 	"strconv"
 	"strings"
 
@@ -12,6 +13,9 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/keysutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/mitchellh/mapstructure"
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
 )
 
 // BatchRequestHMACItem represents a request item for batch processing.
@@ -64,6 +68,12 @@ func (b *backend) pathHMAC() *framework.Path {
 * sha3-256
 * sha3-384
 * sha3-512
+* blake2b-256
+* blake2b-512
+* blake2s-256
+* blake3
+
+The BLAKE2 and BLAKE3 algorithms are keyed hashes used in place of HMAC.
 
 Defaults to "sha2-256".`,
 			},
@@ -90,6 +100,37 @@ to the min_encryption_version configured on the key.`,
 	}
 }
 
+// keyedHashes are the keyed hash functions which can be used in place of HMAC,
+// taking the HMAC key directly as their key.
+var keyedHashes = map[string]func(key []byte) (hash.Hash, error){
+	"blake2b-256": blake2b.New256,
+	"blake2b-512": blake2b.New512,
+	"blake2s-256": blake2s.New256,
+	"blake3": func(key []byte) (hash.Hash, error) {
+		return blake3.NewKeyed(key)
+	},
+}
+
+// macFunc returns the constructor of the MAC for the algorithm: either a
+// keyed hash, or HMAC with the hash function of the algorithm.
+func macFunc(algorithm string) (func(key []byte) (hash.Hash, error), bool) {
+	if f, ok := keyedHashes[algorithm]; ok {
+		return f, true
+	}
+
+	hashAlgorithm, ok := keysutil.HashTypeMap[algorithm]
+	if !ok {
+		return nil, false
+	}
+	hashAlg := keysutil.HashFuncMap[hashAlgorithm]
+	if hashAlg == nil {
+		return nil, false
+	}
+	return func(key []byte) (hash.Hash, error) {
+		return hmac.New(hashAlg, key), nil
+	}, true
+}
+
 func (b *backend) pathHMACWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 	ver := d.Get("key_version").(int)
@@ -136,14 +177,12 @@ func (b *backend) pathHMACWrite(ctx context.Context, req *logical.Request, d *fr
 		return nil, fmt.Errorf("HMAC key value could not be computed")
 	}
 
-	hashAlgorithm, ok := keysutil.HashTypeMap[algorithm]
+	newMAC, ok := macFunc(algorithm)
 	if !ok {
 		p.Unlock()
-		return logical.ErrorResponse("unsupported algorithm %q", hashAlgorithm), nil
+		return logical.ErrorResponse("unsupported algorithm %q", algorithm), nil
 	}
 
-	hashAlg := keysutil.HashFuncMap[hashAlgorithm]
-
 	batchInputRaw := d.Raw["batch_input"]
 	var batchInputItems []batchRequestHMACItem
 	if batchInputRaw != nil {
@@ -187,7 +226,12 @@ func (b *backend) pathHMACWrite(ctx context.Context, req *logical.Request, d *fr
 			continue
 		}
 
-		hf := hmac.New(hashAlg, key)
+		hf, err := newMAC(key)
+		if err != nil {
+			response[i].Error = fmt.Sprintf("unable to use key with %s: %s", algorithm, err)
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
 		hf.Write(input)
 		retBytes := hf.Sum(nil)
 
@@ -242,14 +286,12 @@ func (b *backend) pathHMACVerify(ctx context.Context, req *logical.Request, d *f
 		p.Lock(false)
 	}
 
-	hashAlgorithm, ok := keysutil.HashTypeMap[algorithm]
+	newMAC, ok := macFunc(algorithm)
 	if !ok {
 		p.Unlock()
-		return logical.ErrorResponse("unsupported algorithm %q", hashAlgorithm), nil
+		return logical.ErrorResponse("unsupported algorithm %q", algorithm), nil
 	}
 
-	hashAlg := keysutil.HashFuncMap[hashAlgorithm]
-
 	batchInputRaw := d.Raw["batch_input"]
 	var batchInputItems []batchRequestHMACItem
 	if batchInputRaw != nil {
@@ -351,7 +393,12 @@ func (b *backend) pathHMACVerify(ctx context.Context, req *logical.Request, d *f
 			continue
 		}
 
-		hf := hmac.New(hashAlg, key)
+		hf, err := newMAC(key)
+		if err != nil {
+			response[i].Error = fmt.Sprintf("unable to use key with %s: %s", algorithm, err)
+			response[i].err = logical.ErrInvalidRequest
+			continue
+		}
 		hf.Write(input)
 		retBytes := hf.Sum(nil)
 		response[i].Valid = hmac.Equal(retBytes, verBytes)
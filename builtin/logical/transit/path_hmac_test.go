@@ -147,6 +147,22 @@ func TestTransit_HMAC(t *testing.T) {
 		req.Data["format"] = "base64"
 		doRequest(req, false, "vault:v1:GrNA8sU88naMPEQ7UZGj9EJl7YJhl03AFHfxcEURFrtvnobdea9ZlZHePpxAx/oCaC7R2HkrAO+Tu3uXPIl3lg==")
 
+		// Test keyed BLAKE2 and BLAKE3
+		req.Data["algorithm"] = "blake2b-256"
+		doRequest(req, false, "vault:v1:es2P+f375OyQ1wA5jITVsrRAVUVuKLaOeg36Tw0UNeE=")
+
+		req.Data["algorithm"] = "blake2b-512"
+		doRequest(req, false, "vault:v1:aFZg3Z+5/5udg+GKMx8J2rhITWzV+FNf8R5SL2ciW5tkLjjh0Z6pKifCpnv2KQ2Ss6/bjj35LOAyoxt6ueZLrQ==")
+
+		req.Data["algorithm"] = "blake2s-256"
+		doRequest(req, false, "vault:v1:XQrN2PwFsrY9wn46K5T+FZ/qW+kjvjOje569aa11kcA=")
+
+		req.Data["algorithm"] = "blake3"
+		doRequest(req, false, "vault:v1:IsdLj1NU6QqOFJ+vOJGgjr/o3z365nMMXUpXjSI//rQ=")
+
+		req.Data["algorithm"] = "none"
+		doRequest(req, true, "")
+
 		req.Data["algorithm"] = "foobar"
 		doRequest(req, true, "")
 
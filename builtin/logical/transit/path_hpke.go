@@ -0,0 +1,303 @@
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func hpkeFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"name": {
+			Type:        framework.TypeString,
+			Description: "Name of the x25519 key",
+		},
+
+		"info": {
+			Type:        framework.TypeString,
+			Description: "Base64 encoded application-supplied information binding the messages to their context.",
+		},
+
+		"associated_data": {
+			Type:        framework.TypeString,
+			Description: "Base64 encoded associated data authenticated along with each message.",
+		},
+
+		"aead": {
+			Type:    framework.TypeString,
+			Default: "aes256-gcm",
+			Description: `The AEAD of the HPKE suite. Options are "aes128-gcm",
+"aes256-gcm" (default) and "chacha20-poly1305".`,
+		},
+
+		"key_version": {
+			Type: framework.TypeInt,
+			Description: `The version of the key to use. Defaults to the latest
+version.`,
+		},
+	}
+}
+
+func (b *backend) pathHPKESeal() *framework.Path {
+	fields := hpkeFields()
+	fields["plaintext"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Base64 encoded plaintext to seal",
+	}
+	fields["plaintexts"] = &framework.FieldSchema{
+		Type:        framework.TypeStringSlice,
+		Description: "Base64 encoded plaintexts to seal in order, as a stream of messages sharing an encapsulated key",
+	}
+
+	return &framework.Path{
+		Pattern: "hpke/seal/" + framework.GenericNameRegex("name"),
+		Fields:  fields,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathHPKESealWrite,
+		},
+
+		HelpSynopsis:    pathHPKESealHelpSyn,
+		HelpDescription: pathHPKESealHelpDesc,
+	}
+}
+
+func (b *backend) pathHPKEOpen() *framework.Path {
+	fields := hpkeFields()
+	fields["enc"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Base64 encoded encapsulated key returned when sealing",
+	}
+	fields["ciphertext"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Base64 encoded ciphertext to open",
+	}
+	fields["ciphertexts"] = &framework.FieldSchema{
+		Type:        framework.TypeStringSlice,
+		Description: "Base64 encoded ciphertexts of a stream of messages to open, in the order they were sealed",
+	}
+
+	return &framework.Path{
+		Pattern: "hpke/open/" + framework.GenericNameRegex("name"),
+		Fields:  fields,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathHPKEOpenWrite,
+		},
+
+		HelpSynopsis:    pathHPKEOpenHelpSyn,
+		HelpDescription: pathHPKEOpenHelpDesc,
+	}
+}
+
+// hpkeParams holds the decoded parameters common to sealing and opening.
+type hpkeParams struct {
+	info           []byte
+	associatedData []byte
+	aead           keysutil.HPKEAEAD
+	messages       [][]byte
+	stream         bool
+}
+
+func parseHPKEParams(d *framework.FieldData, singleField, streamField string) (*hpkeParams, error) {
+	params := &hpkeParams{}
+	var err error
+
+	params.aead, err = keysutil.ParseHPKEAEAD(d.Get("aead").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	if params.info, err = base64.StdEncoding.DecodeString(d.Get("info").(string)); err != nil {
+		return nil, fmt.Errorf("failed to base64-decode info")
+	}
+	if params.associatedData, err = base64.StdEncoding.DecodeString(d.Get("associated_data").(string)); err != nil {
+		return nil, fmt.Errorf("failed to base64-decode associated_data")
+	}
+
+	single := d.Get(singleField).(string)
+	stream := d.Get(streamField).([]string)
+	switch {
+	case single != "" && len(stream) > 0:
+		return nil, fmt.Errorf("only one of %q and %q may be set", singleField, streamField)
+	case single != "":
+		stream = []string{single}
+	case len(stream) > 0:
+		params.stream = true
+	default:
+		return nil, fmt.Errorf("missing %s", singleField)
+	}
+
+	for i, encoded := range stream {
+		message, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			if params.stream {
+				return nil, fmt.Errorf("failed to base64-decode %s %d", singleField, i)
+			}
+			return nil, fmt.Errorf("failed to base64-decode %s", singleField)
+		}
+		params.messages = append(params.messages, message)
+	}
+
+	return params, nil
+}
+
+func (b *backend) getHPKEPolicy(ctx context.Context, req *logical.Request, name string) (*keysutil.Policy, *logical.Response, error) {
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return nil, logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+	return p, nil, nil
+}
+
+func (b *backend) pathHPKESealWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	params, err := parseHPKEParams(d, "plaintext", "plaintexts")
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	p, resp, err := b.getHPKEPolicy(ctx, req, d.Get("name").(string))
+	if p == nil {
+		return resp, err
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	if !p.Type.HPKESupported() {
+		return logical.ErrorResponse(fmt.Sprintf("HPKE not supported for key type %v", p.Type)), logical.ErrInvalidRequest
+	}
+
+	keyVersion := d.Get("key_version").(int)
+	if keyVersion == 0 {
+		keyVersion = p.LatestVersion
+	}
+	if p.MinEncryptionVersion > 0 && keyVersion < p.MinEncryptionVersion {
+		return logical.ErrorResponse("cannot seal with a key version lower than the policy's minimum encryption version"), logical.ErrInvalidRequest
+	}
+
+	publicKey, err := p.HPKEPublicKey(keyVersion)
+	if err != nil {
+		if _, ok := err.(errutil.UserError); ok {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		return nil, err
+	}
+
+	enc, hpkeCtx, err := keysutil.HPKESetupSender(b.GetRandomReader(), publicKey, params.aead, params.info)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertexts := make([]string, 0, len(params.messages))
+	for _, plaintext := range params.messages {
+		ciphertext, err := hpkeCtx.Seal(plaintext, params.associatedData)
+		if err != nil {
+			return nil, err
+		}
+		ciphertexts = append(ciphertexts, base64.StdEncoding.EncodeToString(ciphertext))
+	}
+
+	resp = &logical.Response{
+		Data: map[string]interface{}{
+			"enc":         base64.StdEncoding.EncodeToString(enc),
+			"aead":        params.aead.String(),
+			"key_version": keyVersion,
+		},
+	}
+	if params.stream {
+		resp.Data["ciphertexts"] = ciphertexts
+	} else {
+		resp.Data["ciphertext"] = ciphertexts[0]
+	}
+	return resp, nil
+}
+
+func (b *backend) pathHPKEOpenWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	params, err := parseHPKEParams(d, "ciphertext", "ciphertexts")
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	enc, err := base64.StdEncoding.DecodeString(d.Get("enc").(string))
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode enc"), logical.ErrInvalidRequest
+	}
+	if len(enc) == 0 {
+		return logical.ErrorResponse("missing enc"), logical.ErrInvalidRequest
+	}
+
+	p, resp, err := b.getHPKEPolicy(ctx, req, d.Get("name").(string))
+	if p == nil {
+		return resp, err
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	if !p.Type.HPKESupported() {
+		return logical.ErrorResponse(fmt.Sprintf("HPKE not supported for key type %v", p.Type)), logical.ErrInvalidRequest
+	}
+
+	plaintexts := make([]string, 0, len(params.messages))
+	hpkeCtx, err := p.HPKESetupReceiver(d.Get("key_version").(int), enc, params.aead, params.info)
+	if err == nil {
+		for _, ciphertext := range params.messages {
+			var plaintext []byte
+			plaintext, err = hpkeCtx.Open(ciphertext, params.associatedData)
+			if err != nil {
+				break
+			}
+			plaintexts = append(plaintexts, base64.StdEncoding.EncodeToString(plaintext))
+		}
+	}
+	if err != nil {
+		if _, ok := err.(errutil.UserError); ok {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		return nil, err
+	}
+
+	resp = &logical.Response{
+		Data: map[string]interface{}{},
+	}
+	if params.stream {
+		resp.Data["plaintexts"] = plaintexts
+	} else {
+		resp.Data["plaintext"] = plaintexts[0]
+	}
+	return resp, nil
+}
+
+const pathHPKESealHelpSyn = `Seal messages with HPKE for an x25519 key`
+
+const pathHPKESealHelpDesc = `
+This path seals messages for the named x25519 key with HPKE (RFC 9180) in
+base mode, with the DHKEM(X25519, HKDF-SHA256) KEM and the HKDF-SHA256 KDF.
+As sealing only requires the public key of the key, clients can seal messages
+themselves with any RFC 9180 implementation instead of calling this path.
+
+The messages given in "plaintexts" are sealed in order in a single HPKE
+context, and must be opened in the same order.
+`
+
+const pathHPKEOpenHelpSyn = `Open messages sealed with HPKE for an x25519 key`
+
+const pathHPKEOpenHelpDesc = `
+This path opens the messages sealed with HPKE (RFC 9180) for the named x25519
+key, given the encapsulated key returned when sealing them. The messages of a
+stream, given in "ciphertexts", must be in the order they were sealed.
+`
@@ -0,0 +1,138 @@
+package transit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_HPKE(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	handle := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+
+	resp, err := handle("keys/hpke", map[string]interface{}{
+		"type": "x25519",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+
+	// Seal offline with the public key of the key
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/hpke",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	keys := resp.Data["keys"].(map[string]map[string]interface{})
+	if keys["1"]["name"] != "x25519" {
+		t.Fatalf("bad: key name: %v", keys["1"]["name"])
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(keys["1"]["public_key"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := []byte("ingest")
+	aad := []byte("record-42")
+	enc, hpkeCtx, err := keysutil.HPKESetupSender(rand.Reader, publicKey, keysutil.HPKEAEAD_ChaCha20_Poly1305, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := hpkeCtx.Seal([]byte("offline secret"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openData := map[string]interface{}{
+		"enc":             base64.StdEncoding.EncodeToString(enc),
+		"ciphertext":      base64.StdEncoding.EncodeToString(ciphertext),
+		"info":            base64.StdEncoding.EncodeToString(info),
+		"associated_data": base64.StdEncoding.EncodeToString(aad),
+		"aead":            "chacha20-poly1305",
+	}
+	resp, err = handle("hpke/open/hpke", openData)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if resp.Data["plaintext"] != base64.StdEncoding.EncodeToString([]byte("offline secret")) {
+		t.Fatalf("bad: plaintext: %v", resp.Data["plaintext"])
+	}
+
+	// The associated data is authenticated
+	openData["associated_data"] = base64.StdEncoding.EncodeToString([]byte("record-43"))
+	resp, err = handle("hpke/open/hpke", openData)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error opening with other associated data: %#v", resp)
+	}
+
+	// Streams of messages are sealed and opened in order
+	plaintexts := []string{
+		base64.StdEncoding.EncodeToString([]byte("chunk 1")),
+		base64.StdEncoding.EncodeToString([]byte("chunk 2")),
+		base64.StdEncoding.EncodeToString([]byte("chunk 3")),
+	}
+	resp, err = handle("hpke/seal/hpke", map[string]interface{}{
+		"plaintexts": plaintexts,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	if resp.Data["key_version"] != 1 || resp.Data["aead"] != "aes256-gcm" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	sealed := resp.Data["ciphertexts"].([]string)
+	if len(sealed) != 3 {
+		t.Fatalf("expected 3 ciphertexts, got %d", len(sealed))
+	}
+
+	resp, err = handle("hpke/open/hpke", map[string]interface{}{
+		"enc":         resp.Data["enc"],
+		"ciphertexts": sealed,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	opened := resp.Data["plaintexts"].([]string)
+	for i := range plaintexts {
+		if opened[i] != plaintexts[i] {
+			t.Fatalf("bad: plaintext %d: expected %s, got %s", i, plaintexts[i], opened[i])
+		}
+	}
+
+	// Keys of other types are rejected
+	resp, err = handle("keys/aes", nil)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	resp, err = handle("hpke/seal/aes", map[string]interface{}{
+		"plaintext": plaintexts[0],
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error sealing with an aes key: %#v", resp)
+	}
+
+	// Derivation is not supported
+	resp, err = handle("keys/derived", map[string]interface{}{
+		"type":    "x25519",
+		"derived": true,
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatal("expected an error creating a derived x25519 key")
+	}
+}
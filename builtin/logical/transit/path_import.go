@@ -89,6 +89,9 @@ being automatically rotated. A value of 0
 (default) disables automatic rotation for the
 key.`,
 			},
+			"attestation":              importAttestationFields["attestation"],
+			"attestation_signature":    importAttestationFields["attestation_signature"],
+			"attestation_certificates": importAttestationFields["attestation_certificates"],
 		},
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.UpdateOperation: b.pathImportWrite,
@@ -117,6 +120,9 @@ with the wrapping key and then concatenated with the import key, wrapped by the
 				Description: `The hash function used as a random oracle in the OAEP wrapping of the user-generated,
 ephemeral AES key. Can be one of "SHA1", "SHA224", "SHA256" (default), "SHA384", or "SHA512"`,
 			},
+			"attestation":              importAttestationFields["attestation"],
+			"attestation_signature":    importAttestationFields["attestation_signature"],
+			"attestation_certificates": importAttestationFields["attestation_certificates"],
 		},
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.UpdateOperation: b.pathImportVersionWrite,
@@ -210,6 +216,11 @@ func (b *backend) pathImportWrite(ctx context.Context, req *logical.Request, d *
 		return nil, err
 	}
 
+	polReq.ImportAttestation, err = b.verifyImportAttestation(ctx, req.Storage, d, polReq.KeyType, key)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	err = b.lm.ImportPolicy(ctx, polReq, key, b.GetRandomReader())
 	if err != nil {
 		return nil, err
@@ -261,10 +272,15 @@ func (b *backend) pathImportVersionWrite(ctx context.Context, req *logical.Reque
 	if err != nil {
 		return nil, err
 	}
-	err = p.Import(ctx, req.Storage, importKey, b.GetRandomReader())
+	attestation, err := b.verifyImportAttestation(ctx, req.Storage, d, p.Type, importKey)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	err = p.ImportWithAttestation(ctx, req.Storage, importKey, attestation, b.GetRandomReader())
 	if err != nil {
 		return nil, err
 	}
+	b.notifyRotation(ctx, req.Storage, p)
 
 	return nil, nil
 }
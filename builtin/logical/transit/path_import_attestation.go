@@ -0,0 +1,450 @@
+package transit
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	importAttestationConfigPath = "config/import-attestation"
+
+	// importAttestationClockSkew is how far in the future the issue time of
+	// an attestation statement may be.
+	importAttestationClockSkew = 5 * time.Minute
+)
+
+// importAttestationConfig configures the verification of the attestations
+// of imported key material.
+type importAttestationConfig struct {
+	// TrustedCertificates is the PEM bundle of the CA certificates the
+	// signers of attestations must chain to
+	TrustedCertificates string `json:"trusted_certificates"`
+
+	// RequireAttestation rejects the import of key material without an
+	// attestation
+	RequireAttestation bool `json:"require_attestation"`
+
+	// MaxAttestationAge rejects attestations issued longer ago; zero
+	// accepts attestations of any age
+	MaxAttestationAge time.Duration `json:"max_attestation_age"`
+}
+
+// importAttestationStatement is the signed statement of an attestation.
+type importAttestationStatement struct {
+	// Origin is the origin of the key material, such as "aws-cloudhsm"
+	Origin string `json:"origin"`
+
+	// KeyDigest is the hex-encoded SHA-256 digest of the key material for
+	// symmetric keys, or of the DER-encoded public key for asymmetric keys
+	KeyDigest string `json:"key_digest"`
+
+	// IssuedAt is when the statement was produced
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+var importAttestationFields = map[string]*framework.FieldSchema{
+	"attestation": {
+		Type: framework.TypeString,
+		Description: `The base64-encoded attestation statement of the origin of
+the key material. The statement is a JSON object with the "origin" of the
+key, the hex-encoded SHA-256 "key_digest" of the key material (of the
+DER-encoded public key for asymmetric keys) and its "issued_at" time.`,
+	},
+	"attestation_signature": {
+		Type:        framework.TypeString,
+		Description: `The base64-encoded signature of the attestation statement.`,
+	},
+	"attestation_certificates": {
+		Type: framework.TypeString,
+		Description: `The PEM-encoded certificate chain of the signer of the
+attestation statement, leaf first. It must chain to one of the trusted
+certificates of config/import-attestation.`,
+	},
+}
+
+func (b *backend) pathImportAttestationConfig() *framework.Path {
+	return &framework.Path{
+		Pattern: "config/import-attestation",
+		Fields: map[string]*framework.FieldSchema{
+			"trusted_certificates": {
+				Type: framework.TypeString,
+				Description: `PEM bundle of the CA certificates the signers of
+import attestations must chain to.`,
+			},
+			"require_attestation": {
+				Type: framework.TypeBool,
+				Description: `If set, key material can only be imported along
+with a valid attestation.`,
+			},
+			"max_attestation_age": {
+				Type: framework.TypeDurationSecond,
+				Description: `Maximum age of the attestations accepted on import.
+A value of 0 (default) accepts attestations of any age.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathImportAttestationConfigRead,
+				Summary:  "Returns the configuration of import attestations",
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathImportAttestationConfigWrite,
+				Summary:  "Configures the verification of import attestations",
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathImportAttestationConfigDelete,
+				Summary:  "Removes the configuration of import attestations",
+			},
+		},
+
+		HelpSynopsis:    pathImportAttestationConfigHelpSyn,
+		HelpDescription: pathImportAttestationConfigHelpDesc,
+	}
+}
+
+func getImportAttestationConfig(ctx context.Context, s logical.Storage) (*importAttestationConfig, error) {
+	entry, err := s.Get(ctx, importAttestationConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config importAttestationConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (b *backend) pathImportAttestationConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getImportAttestationConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"trusted_certificates": config.TrustedCertificates,
+			"require_attestation":  config.RequireAttestation,
+			"max_attestation_age":  int64(config.MaxAttestationAge.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathImportAttestationConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := getImportAttestationConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &importAttestationConfig{}
+	}
+
+	if raw, ok := d.GetOk("trusted_certificates"); ok {
+		config.TrustedCertificates = raw.(string)
+	}
+	if raw, ok := d.GetOk("require_attestation"); ok {
+		config.RequireAttestation = raw.(bool)
+	}
+	if raw, ok := d.GetOk("max_attestation_age"); ok {
+		config.MaxAttestationAge = time.Duration(raw.(int)) * time.Second
+	}
+
+	if config.MaxAttestationAge < 0 {
+		return logical.ErrorResponse("max_attestation_age cannot be negative"), logical.ErrInvalidRequest
+	}
+	if config.TrustedCertificates == "" {
+		return logical.ErrorResponse("trusted_certificates is required"), logical.ErrInvalidRequest
+	}
+	if _, err := parseTrustedAttestationCertificates(config.TrustedCertificates); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	entry, err := logical.StorageEntryJSON(importAttestationConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathImportAttestationConfigDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, importAttestationConfigPath)
+}
+
+func parseTrustedAttestationCertificates(bundle string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	rest := []byte(bundle)
+	found := false
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trusted certificate: %w", err)
+		}
+		if !cert.IsCA {
+			return nil, fmt.Errorf("trusted certificate %q is not a CA certificate", cert.Subject.String())
+		}
+		pool.AddCert(cert)
+		found = true
+	}
+	if !found {
+		return nil, errors.New("no certificates found in trusted_certificates")
+	}
+	return pool, nil
+}
+
+// verifyImportAttestation verifies the attestation provided along with key
+// material being imported, and returns the attestation to record with the
+// key version. It returns nil when no attestation was provided and none is
+// required.
+func (b *backend) verifyImportAttestation(ctx context.Context, s logical.Storage, d *framework.FieldData, keyType keysutil.KeyType, key []byte) (*keysutil.ImportAttestation, error) {
+	statementB64 := d.Get("attestation").(string)
+	signatureB64 := d.Get("attestation_signature").(string)
+	certificates := d.Get("attestation_certificates").(string)
+
+	config, err := getImportAttestationConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if statementB64 == "" {
+		if signatureB64 != "" || certificates != "" {
+			return nil, errors.New("attestation_signature and attestation_certificates require attestation")
+		}
+		if config != nil && config.RequireAttestation {
+			return nil, errors.New("an attestation is required to import key material")
+		}
+		return nil, nil
+	}
+	if config == nil {
+		return nil, errors.New("attestations cannot be verified before config/import-attestation is configured")
+	}
+	if signatureB64 == "" || certificates == "" {
+		return nil, errors.New("attestation_signature and attestation_certificates are required with attestation")
+	}
+
+	statementBytes, err := base64.StdEncoding.DecodeString(statementB64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64-decode attestation: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(signatureB64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64-decode attestation_signature: %w", err)
+	}
+
+	// Verify the signer chains to a trusted certificate before trusting
+	// anything it signed
+	roots, err := parseTrustedAttestationCertificates(config.TrustedCertificates)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := parseAttestationCertificates(certificates)
+	if err != nil {
+		return nil, err
+	}
+	signer := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to verify the attestation certificates: %w", err)
+	}
+
+	if err := verifyAttestationSignature(signer, statementBytes, signature); err != nil {
+		return nil, fmt.Errorf("failed to verify the attestation signature: %w", err)
+	}
+
+	var statement importAttestationStatement
+	if err := json.Unmarshal(statementBytes, &statement); err != nil {
+		return nil, fmt.Errorf("failed to parse attestation: %w", err)
+	}
+	if statement.Origin == "" {
+		return nil, errors.New("attestation is missing the origin of the key material")
+	}
+	if statement.IssuedAt.IsZero() {
+		return nil, errors.New("attestation is missing its issue time")
+	}
+
+	now := time.Now()
+	if statement.IssuedAt.After(now.Add(importAttestationClockSkew)) {
+		return nil, errors.New("attestation was issued in the future")
+	}
+	if config.MaxAttestationAge > 0 && now.Sub(statement.IssuedAt) > config.MaxAttestationAge {
+		return nil, fmt.Errorf("attestation is older than the maximum age of %s", config.MaxAttestationAge)
+	}
+
+	// Bind the attestation to the key material being imported
+	digest, err := importedKeyDigest(keyType, key)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := hex.DecodeString(statement.KeyDigest)
+	if err != nil || subtle.ConstantTimeCompare(expected, digest) != 1 {
+		return nil, errors.New("attestation does not match the imported key material")
+	}
+
+	return &keysutil.ImportAttestation{
+		Origin:        statement.Origin,
+		Statement:     statementBytes,
+		Signature:     signature,
+		Certificates:  certificates,
+		SignerSubject: signer.Subject.String(),
+		VerifiedTime:  now,
+	}, nil
+}
+
+func parseAttestationCertificates(bundle string) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse attestation certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificates found in attestation_certificates")
+	}
+	return chain, nil
+}
+
+// verifyAttestationSignature verifies the signature of an attestation
+// statement with the public key of its signer. RSA signers use PKCS #1 v1.5
+// with SHA-256, and ECDSA signers an ASN.1 signature with the hash matching
+// the size of their curve.
+func verifyAttestationSignature(signer *x509.Certificate, statement, signature []byte) error {
+	var algorithm x509.SignatureAlgorithm
+	switch pub := signer.PublicKey.(type) {
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			algorithm = x509.ECDSAWithSHA256
+		case elliptic.P384():
+			algorithm = x509.ECDSAWithSHA384
+		case elliptic.P521():
+			algorithm = x509.ECDSAWithSHA512
+		default:
+			return fmt.Errorf("unsupported curve %s", pub.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		algorithm = x509.PureEd25519
+	default:
+		return fmt.Errorf("unsupported signer key type %T", signer.PublicKey)
+	}
+
+	return signer.CheckSignature(algorithm, statement, signature)
+}
+
+// importedKeyDigest returns the SHA-256 digest attestations of key material
+// refer to: the digest of the key itself for symmetric keys, and of its
+// DER-encoded public key for asymmetric keys, since HSMs attest the public
+// part of the keys they export.
+func importedKeyDigest(keyType keysutil.KeyType, key []byte) ([]byte, error) {
+	switch keyType {
+	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_HMAC:
+		sum := sha256.Sum256(key)
+		return sum[:], nil
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(key)
+	if err != nil {
+		if !strings.Contains(err.Error(), "unknown elliptic curve") {
+			return nil, fmt.Errorf("error parsing asymmetric key: %w", err)
+		}
+		privateKey, err = keysutil.ParsePKCS8Ed25519PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing asymmetric key: %w", err)
+		}
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return sum[:], nil
+}
+
+// importAttestationsResponse returns the attestations recorded for the
+// versions of an imported key, by version.
+func importAttestationsResponse(p *keysutil.Policy) map[string]interface{} {
+	attestations := map[string]interface{}{}
+	for version, entry := range p.Keys {
+		a := entry.ImportAttestation
+		if a == nil {
+			continue
+		}
+		attestations[version] = map[string]interface{}{
+			"origin":         a.Origin,
+			"statement":      base64.StdEncoding.EncodeToString(a.Statement),
+			"signature":      base64.StdEncoding.EncodeToString(a.Signature),
+			"certificates":   a.Certificates,
+			"signer_subject": a.SignerSubject,
+			"verified_time":  a.VerifiedTime,
+		}
+	}
+	return attestations
+}
+
+const pathImportAttestationConfigHelpSyn = `Configure the verification of import attestations`
+
+const pathImportAttestationConfigHelpDesc = `
+This path configures the CA certificates the signers of the attestations of
+imported key material must chain to, such as the manufacturer certificate of
+an HSM, and whether imports require an attestation.
+`
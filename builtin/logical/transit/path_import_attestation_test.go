@@ -0,0 +1,274 @@
+package transit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+type testAttestationCA struct {
+	cert    *x509.Certificate
+	certPEM string
+	key     *ecdsa.PrivateKey
+}
+
+func newTestAttestationCA(t *testing.T) *testAttestationCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "HSM Manufacturer Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testAttestationCA{
+		cert:    cert,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		key:     key,
+	}
+}
+
+// issueSigner issues a certificate for a new attestation signing key.
+func (ca *testAttestationCA) issueSigner(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "hsm-1234"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// attest returns the import request fields attesting digest.
+func attest(t *testing.T, signer *ecdsa.PrivateKey, signerPEM string, digest []byte, issuedAt time.Time) map[string]interface{} {
+	t.Helper()
+
+	statement, err := json.Marshal(&importAttestationStatement{
+		Origin:    "aws-cloudhsm",
+		KeyDigest: hex.EncodeToString(digest),
+		IssuedAt:  issuedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(statement)
+	signature, err := ecdsa.SignASN1(rand.Reader, signer, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]interface{}{
+		"attestation":              base64.StdEncoding.EncodeToString(statement),
+		"attestation_signature":    base64.StdEncoding.EncodeToString(signature),
+		"attestation_certificates": signerPEM,
+	}
+}
+
+func TestTransit_ImportAttestation(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	ca := newTestAttestationCA(t)
+	signer, signerPEM := ca.issueSigner(t)
+
+	wrappingKey, err := b.getWrappingKey(ctx, s)
+	if err != nil || wrappingKey == nil {
+		t.Fatalf("failed to retrieve public wrapping key: %s", err)
+	}
+	pubWrappingKey := &wrappingKey.Keys[strconv.Itoa(wrappingKey.LatestVersion)].RSAKey.PublicKey
+
+	targetKey, err := generateKey("aes256-gcm96")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(targetKey.([]byte))
+
+	// importKey imports the target key, returning the error of the response
+	// if there is one
+	importKey := func(t *testing.T, path string, fields map[string]interface{}) error {
+		t.Helper()
+		data := map[string]interface{}{
+			"ciphertext": wrapTargetKeyForImport(t, pubWrappingKey, targetKey, "aes256-gcm96", "SHA256"),
+		}
+		for k, v := range fields {
+			data[k] = v
+		}
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   s,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if resp != nil && resp.IsError() {
+			return resp.Error()
+		}
+		return err
+	}
+
+	// Attestations cannot be verified without trusted certificates
+	err = importKey(t, "keys/unconfigured/import", attest(t, signer, signerPEM, digest[:], time.Now()))
+	if err == nil || !strings.Contains(err.Error(), "config/import-attestation") {
+		t.Fatalf("expected an error about the missing configuration, got: %v", err)
+	}
+
+	// Only CA certificates can be trusted
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "config/import-attestation",
+		Data: map[string]interface{}{
+			"trusted_certificates": signerPEM,
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected an error trusting a leaf certificate, got: %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "config/import-attestation",
+		Data: map[string]interface{}{
+			"trusted_certificates": ca.certPEM,
+			"require_attestation":  true,
+			"max_attestation_age":  "1h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("failed to configure import attestations: resp: %#v, err: %v", resp, err)
+	}
+
+	t.Run("missing attestation", func(t *testing.T) {
+		err := importKey(t, "keys/missing/import", nil)
+		if err == nil || !strings.Contains(err.Error(), "attestation is required") {
+			t.Fatalf("expected an error about the missing attestation, got: %v", err)
+		}
+	})
+
+	t.Run("untrusted signer", func(t *testing.T) {
+		other := newTestAttestationCA(t)
+		otherSigner, otherSignerPEM := other.issueSigner(t)
+		err := importKey(t, "keys/untrusted/import", attest(t, otherSigner, otherSignerPEM, digest[:], time.Now()))
+		if err == nil || !strings.Contains(err.Error(), "certificates") {
+			t.Fatalf("expected an error verifying the certificates, got: %v", err)
+		}
+	})
+
+	t.Run("other key material", func(t *testing.T) {
+		otherDigest := sha256.Sum256([]byte("other key"))
+		err := importKey(t, "keys/other/import", attest(t, signer, signerPEM, otherDigest[:], time.Now()))
+		if err == nil || !strings.Contains(err.Error(), "does not match") {
+			t.Fatalf("expected an error matching the key material, got: %v", err)
+		}
+	})
+
+	t.Run("tampered statement", func(t *testing.T) {
+		fields := attest(t, signer, signerPEM, digest[:], time.Now())
+		fields["attestation_signature"] = attest(t, signer, signerPEM, digest[:], time.Now().Add(-time.Minute))["attestation_signature"]
+		err := importKey(t, "keys/tampered/import", fields)
+		if err == nil || !strings.Contains(err.Error(), "signature") {
+			t.Fatalf("expected an error verifying the signature, got: %v", err)
+		}
+	})
+
+	t.Run("expired attestation", func(t *testing.T) {
+		err := importKey(t, "keys/expired/import", attest(t, signer, signerPEM, digest[:], time.Now().Add(-2*time.Hour)))
+		if err == nil || !strings.Contains(err.Error(), "maximum age") {
+			t.Fatalf("expected an error about the age of the attestation, got: %v", err)
+		}
+	})
+
+	t.Run("valid attestation", func(t *testing.T) {
+		if err := importKey(t, "keys/attested/import", attest(t, signer, signerPEM, digest[:], time.Now())); err != nil {
+			t.Fatalf("failed to import key: %v", err)
+		}
+		if err := importKey(t, "keys/attested/import_version", attest(t, signer, signerPEM, digest[:], time.Now())); err != nil {
+			t.Fatalf("failed to import key version: %v", err)
+		}
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   s,
+			Operation: logical.ReadOperation,
+			Path:      "keys/attested",
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("failed to read key: resp: %#v, err: %v", resp, err)
+		}
+		attestations, ok := resp.Data["import_attestations"].(map[string]interface{})
+		if !ok || len(attestations) != 2 {
+			t.Fatalf("expected the attestations of both versions, got: %#v", resp.Data["import_attestations"])
+		}
+		attestation := attestations["1"].(map[string]interface{})
+		if attestation["origin"] != "aws-cloudhsm" {
+			t.Fatalf("unexpected origin: %v", attestation["origin"])
+		}
+		if attestation["signer_subject"] != "CN=hsm-1234" {
+			t.Fatalf("unexpected signer subject: %v", attestation["signer_subject"])
+		}
+	})
+}
+
+func TestTransit_ImportedKeyDigest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := importedKeyDigest(keysutil.KeyType_ECDSA_P256, pkcs8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := sha256.Sum256(spki)
+	if hex.EncodeToString(digest) != hex.EncodeToString(expected[:]) {
+		t.Fatal("expected the digest of the public key of asymmetric keys")
+	}
+}
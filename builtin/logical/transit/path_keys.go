@@ -46,10 +46,16 @@ func (b *backend) pathKeys() *framework.Path {
 				Description: `
 The type of key to create. Currently, "aes128-gcm96" (symmetric), "aes256-gcm96" (symmetric), "ecdsa-p256"
 (asymmetric), "ecdsa-p384" (asymmetric), "ecdsa-p521" (asymmetric), "ed25519" (asymmetric), "rsa-2048" (asymmetric), "rsa-3072"
-(asymmetric), "rsa-4096" (asymmetric) are supported.  Defaults to "aes256-gcm96".
+(asymmetric), "rsa-4096" (asymmetric), "x25519" (asymmetric, HPKE only), "managed_key" (asymmetric, signing only) are supported.  Defaults to "aes256-gcm96".
 `,
 			},
 
+			"managed_key_name": {
+				Type: framework.TypeString,
+				Description: `The name of the managed key to use
+when the key type is "managed_key".`,
+			},
+
 			"derived": {
 				Type: framework.TypeBool,
 				Description: `Enables key derivation mode. This
@@ -139,6 +145,7 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 	exportable := d.Get("exportable").(bool)
 	allowPlaintextBackup := d.Get("allow_plaintext_backup").(bool)
 	autoRotatePeriod := time.Second * time.Duration(d.Get("auto_rotate_period").(int))
+	managedKeyName := d.Get("managed_key_name").(string)
 
 	if autoRotatePeriod != 0 && autoRotatePeriod < time.Hour {
 		return logical.ErrorResponse("auto rotate period must be 0 to disable or at least an hour"), nil
@@ -182,6 +189,10 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 		polReq.KeyType = keysutil.KeyType_RSA4096
 	case "hmac":
 		polReq.KeyType = keysutil.KeyType_HMAC
+	case "x25519":
+		polReq.KeyType = keysutil.KeyType_X25519
+	case "managed_key":
+		polReq.KeyType = keysutil.KeyType_MANAGED_KEY
 	default:
 		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
 	}
@@ -195,6 +206,21 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 		polReq.KeySize = keySize
 	}
 
+	if polReq.KeyType == keysutil.KeyType_MANAGED_KEY {
+		if managedKeyName == "" {
+			return logical.ErrorResponse("managed_key_name is required for keys of type managed_key"), logical.ErrInvalidRequest
+		}
+		if autoRotatePeriod != 0 {
+			return logical.ErrorResponse("managed keys cannot be automatically rotated"), logical.ErrInvalidRequest
+		}
+		if _, err := b.getManagedKeyPublicKey(ctx, managedKeyName); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("unable to use managed key %q: %s", managedKeyName, err)), logical.ErrInvalidRequest
+		}
+		polReq.ManagedKeyName = managedKeyName
+	} else if managedKeyName != "" {
+		return logical.ErrorResponse(fmt.Sprintf("managed_key_name is not valid for algorithm %v", polReq.KeyType)), logical.ErrInvalidRequest
+	}
+
 	p, upserted, err := b.GetPolicy(ctx, polReq, b.GetRandomReader())
 	if err != nil {
 		return nil, err
@@ -258,6 +284,7 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 			"supports_derivation":    p.Type.DerivationSupported(),
 			"auto_rotate_period":     int64(p.AutoRotatePeriod.Seconds()),
 			"imported_key":           p.Imported,
+			"usage_log":              p.UsageLog,
 		},
 	}
 	if p.KeySize != 0 {
@@ -266,6 +293,45 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 
 	if p.Imported {
 		resp.Data["imported_key_allow_rotation"] = p.AllowImportedKeyRotation
+		if attestations := importAttestationsResponse(p); len(attestations) > 0 {
+			resp.Data["import_attestations"] = attestations
+		}
+	}
+
+	if p.Type == keysutil.KeyType_MANAGED_KEY {
+		resp.Data["managed_key_name"] = p.ManagedKeyName
+	}
+
+	if p.SubkeyDerivationAllowed {
+		resp.Data["allow_subkey_derivation"] = true
+		resp.Data["max_subkey_length"] = p.MaxSubkeyLengthOrDefault()
+		resp.Data["subkey_wrap_ttl"] = int64(p.SubkeyWrapTTL.Seconds())
+	}
+
+	if p.SigningApprovalsRequired > 1 {
+		resp.Data["signing_approvals_required"] = p.SigningApprovalsRequired
+		resp.Data["signing_approval_window"] = int64(signingApprovalWindow(p).Seconds())
+	}
+
+	if p.VersionEncryptionPeriod > 0 {
+		resp.Data["version_encryption_period"] = int64(p.VersionEncryptionPeriod.Seconds())
+		resp.Data["version_decryption_period"] = int64(p.VersionDecryptionPeriod.Seconds())
+
+		expirations := map[string]interface{}{}
+		for k := range p.Keys {
+			ver, err := strconv.Atoi(k)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version %q: %w", k, err)
+			}
+			expiration := map[string]interface{}{
+				"encryption_expiration_time": p.VersionEncryptionExpiry(ver),
+			}
+			if decryptionExpiry := p.VersionDecryptionExpiry(ver); !decryptionExpiry.IsZero() {
+				expiration["decryption_expiration_time"] = decryptionExpiry
+			}
+			expirations[k] = expiration
+		}
+		resp.Data["version_expirations"] = expirations
 	}
 
 	if p.BackupInfo != nil {
@@ -312,7 +378,7 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 		}
 		resp.Data["keys"] = retKeys
 
-	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521, keysutil.KeyType_ED25519, keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521, keysutil.KeyType_ED25519, keysutil.KeyType_X25519, keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
 		retKeys := map[string]map[string]interface{}{}
 		for k, v := range p.Keys {
 			key := asymKey{
@@ -348,6 +414,8 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 					}
 				}
 				key.Name = "ed25519"
+			case keysutil.KeyType_X25519:
+				key.Name = "x25519"
 			case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
 				key.Name = "rsa-2048"
 				if p.Type == keysutil.KeyType_RSA3072 {
@@ -392,6 +460,18 @@ func (b *backend) pathPolicyDelete(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse(fmt.Sprintf("error deleting policy %s: %s", name, err)), err
 	}
 
+	// The usage log would otherwise be continued by a new key of the same
+	// name
+	if err := b.deleteUsageLog(ctx, req.Storage, name); err != nil {
+		return nil, err
+	}
+	if err := b.deleteSigningApprovals(ctx, req.Storage, name); err != nil {
+		return nil, err
+	}
+	if err := b.deleteRotationHooks(ctx, req.Storage, name); err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
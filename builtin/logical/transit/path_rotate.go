@@ -45,8 +45,16 @@ func (b *backend) pathRotateWrite(ctx context.Context, req *logical.Request, d *
 		p.Lock(true)
 	}
 
+	if p.Type == keysutil.KeyType_MANAGED_KEY {
+		p.Unlock()
+		return logical.ErrorResponse("managed keys must be rotated within their key management system"), logical.ErrInvalidRequest
+	}
+
 	// Rotate the policy
 	err = p.Rotate(ctx, req.Storage, b.GetRandomReader())
+	if err == nil {
+		b.notifyRotation(ctx, req.Storage, p)
+	}
 
 	p.Unlock()
 	return nil, err
@@ -0,0 +1,299 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	rotationHooksPrefix = "rotation-hooks/"
+
+	rotationHookTimeout = 10 * time.Second
+
+	// rotationHookSignatureHeader carries the HMAC-SHA256 of the body, keyed
+	// with the secret of the hook, when the hook has one.
+	rotationHookSignatureHeader = "X-Vault-Signature"
+
+	rotationEventKeyRotated = "key-rotated"
+)
+
+// rotationHook is a URL notified whenever a new version of a key is created,
+// so that its consumers can rewrap the data encrypted with older versions.
+type rotationHook struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// rotationEvent is the body of the requests sent to rotation hooks.
+type rotationEvent struct {
+	Type                 string    `json:"type"`
+	Hook                 string    `json:"hook"`
+	Key                  string    `json:"key"`
+	LatestVersion        int       `json:"latest_version"`
+	MinDecryptionVersion int       `json:"min_decryption_version"`
+	MinEncryptionVersion int       `json:"min_encryption_version"`
+	Timestamp            time.Time `json:"timestamp"`
+}
+
+func rotationHooksPath(name string) string {
+	return rotationHooksPrefix + name + "/"
+}
+
+func (b *backend) pathRotationHooks() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "keys/" + framework.GenericNameRegex("name") + "/rotation-hooks/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the key",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.pathRotationHooksList,
+			},
+
+			HelpSynopsis:    pathRotationHooksHelpSyn,
+			HelpDescription: pathRotationHooksHelpDesc,
+		},
+		{
+			Pattern: "keys/" + framework.GenericNameRegex("name") + "/rotation-hooks/" + framework.GenericNameRegex("hook"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the key",
+				},
+				"hook": {
+					Type:        framework.TypeString,
+					Description: "Name of the rotation hook",
+				},
+				"url": {
+					Type: framework.TypeString,
+					Description: `URL the rotation events of the key are
+posted to.`,
+				},
+				"secret": {
+					Type: framework.TypeString,
+					Description: `If set, the body of the events is signed
+with HMAC-SHA256 keyed with this secret, in the
+X-Vault-Signature header.`,
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.pathRotationHookRead,
+				logical.UpdateOperation: b.pathRotationHookWrite,
+				logical.DeleteOperation: b.pathRotationHookDelete,
+			},
+
+			HelpSynopsis:    pathRotationHooksHelpSyn,
+			HelpDescription: pathRotationHooksHelpDesc,
+		},
+	}
+}
+
+func (b *backend) pathRotationHooksList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	hooks, err := req.Storage.List(ctx, rotationHooksPath(d.Get("name").(string)))
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(hooks), nil
+}
+
+func (b *backend) pathRotationHookRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	hook, err := getRotationHook(ctx, req.Storage, d.Get("name").(string), d.Get("hook").(string))
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":       hook.Name,
+			"url":        hook.URL,
+			"has_secret": hook.Secret != "",
+		},
+	}, nil
+}
+
+func (b *backend) pathRotationHookWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse(fmt.Sprintf("no existing key named %s could be found", name)), logical.ErrInvalidRequest
+	}
+	if b.System().CachingDisabled() {
+		p.Unlock()
+	}
+
+	hook, err := getRotationHook(ctx, req.Storage, name, d.Get("hook").(string))
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		hook = &rotationHook{
+			Name: d.Get("hook").(string),
+		}
+	}
+
+	if urlRaw, ok := d.GetOk("url"); ok {
+		hook.URL = urlRaw.(string)
+	}
+	if hook.URL == "" {
+		return logical.ErrorResponse("url is required"), nil
+	}
+	parsed, err := url.Parse(hook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return logical.ErrorResponse("url must be an absolute http or https URL"), nil
+	}
+	if secretRaw, ok := d.GetOk("secret"); ok {
+		hook.Secret = secretRaw.(string)
+	}
+
+	entry, err := logical.StorageEntryJSON(rotationHooksPath(name)+hook.Name, hook)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+func (b *backend) pathRotationHookDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, rotationHooksPath(d.Get("name").(string))+d.Get("hook").(string))
+}
+
+func getRotationHook(ctx context.Context, s logical.Storage, name, hook string) (*rotationHook, error) {
+	raw, err := s.Get(ctx, rotationHooksPath(name)+hook)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry rotationHook
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (b *backend) deleteRotationHooks(ctx context.Context, s logical.Storage, name string) error {
+	hooks, err := s.List(ctx, rotationHooksPath(name))
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := s.Delete(ctx, rotationHooksPath(name)+hook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyRotation posts a rotation event to the hooks of the key in the
+// background. Failing to notify the hooks does not fail the rotation. The
+// policy must be locked by the caller.
+func (b *backend) notifyRotation(ctx context.Context, s logical.Storage, p *keysutil.Policy) {
+	names, err := s.List(ctx, rotationHooksPath(p.Name))
+	if err != nil {
+		b.Logger().Error("failed to list rotation hooks", "key", p.Name, "error", err)
+		return
+	}
+
+	var hooks []*rotationHook
+	for _, name := range names {
+		hook, err := getRotationHook(ctx, s, p.Name, name)
+		if err != nil {
+			b.Logger().Error("failed to read rotation hook", "key", p.Name, "hook", name, "error", err)
+			continue
+		}
+		if hook != nil {
+			hooks = append(hooks, hook)
+		}
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	event := rotationEvent{
+		Type:                 rotationEventKeyRotated,
+		Key:                  p.Name,
+		LatestVersion:        p.LatestVersion,
+		MinDecryptionVersion: p.MinDecryptionVersion,
+		MinEncryptionVersion: p.MinEncryptionVersion,
+		Timestamp:            time.Now(),
+	}
+
+	go func() {
+		for _, hook := range hooks {
+			event.Hook = hook.Name
+			if err := b.sendRotationEvent(b.rotationHookCtx, hook, &event); err != nil {
+				b.Logger().Error("failed to notify rotation hook", "key", event.Key, "hook", hook.Name, "error", err)
+			}
+		}
+	}()
+}
+
+func (b *backend) sendRotationEvent(ctx context.Context, hook *rotationHook, event *rotationEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(body)
+		req.Header.Set(rotationHookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = rotationHookTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+const pathRotationHooksHelpSyn = `Manage the URLs notified when a key rotates`
+
+const pathRotationHooksHelpDesc = `
+Rotation hooks are URLs a "key-rotated" event is posted to whenever a new
+version of the key is created, by the rotate endpoint, by automatic rotation
+or by importing a new version. Consumers of the key can use the events to
+rewrap the data they encrypted with older versions.
+`
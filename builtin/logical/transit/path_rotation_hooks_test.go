@@ -0,0 +1,148 @@
+package transit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_RotationHooks(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	ctx := context.Background()
+
+	type delivery struct {
+		body      []byte
+		signature string
+	}
+	deliveries := make(chan delivery, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{body: body, signature: r.Header.Get(rotationHookSignatureHeader)}
+	}))
+	defer server.Close()
+
+	// Hooks can only be registered for existing keys
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/missing/rotation-hooks/rewrapper",
+		Data: map[string]interface{}{
+			"url": server.URL,
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected an error registering a hook for a missing key, got: %#v", resp)
+	}
+
+	if _, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/hooked",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/hooked/rotation-hooks/rewrapper",
+		Data: map[string]interface{}{
+			"url": "not a url",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error with an invalid url, got: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/hooked/rotation-hooks/rewrapper",
+		Data: map[string]interface{}{
+			"url":    server.URL,
+			"secret": "hmac-secret",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("failed to register hook: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.ReadOperation,
+		Path:      "keys/hooked/rotation-hooks/rewrapper",
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("failed to read hook: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["url"] != server.URL || resp.Data["has_secret"] != true {
+		t.Fatalf("unexpected hook: %#v", resp.Data)
+	}
+
+	if _, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/hooked/rotate",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var d delivery
+	select {
+	case d = <-deliveries:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the rotation event")
+	}
+
+	mac := hmac.New(sha256.New, []byte("hmac-secret"))
+	mac.Write(d.body)
+	if d.signature != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Fatalf("unexpected signature: %q", d.signature)
+	}
+	var event rotationEvent
+	if err := json.Unmarshal(d.body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != rotationEventKeyRotated || event.Key != "hooked" || event.Hook != "rewrapper" || event.LatestVersion != 2 {
+		t.Fatalf("unexpected event: %#v", event)
+	}
+
+	// Deleting the key deletes its hooks
+	if _, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/hooked/config",
+		Data: map[string]interface{}{
+			"deletion_allowed": true,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.DeleteOperation,
+		Path:      "keys/hooked",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Storage:   s,
+		Operation: logical.ListOperation,
+		Path:      "keys/hooked/rotation-hooks/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := resp.Data["keys"].([]string); len(keys) != 0 {
+		t.Fatalf("expected the hooks to be deleted, got: %v", keys)
+	}
+}
@@ -2,6 +2,7 @@ package transit
 
 import (
 	"context"
+	"crypto"
 	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
@@ -357,7 +358,33 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *fr
 		}
 	}
 
+	if p.SigningApprovalsRequired > 1 {
+		if batchInputRaw != nil {
+			p.Unlock()
+			return logical.ErrorResponse("batch_input is not supported for keys requiring signing approvals"), logical.ErrInvalidRequest
+		}
+
+		// Approvers must agree on everything that goes into the signature
+		approvalID := signingApprovalID(p.Name, strconv.Itoa(ver), batchInputItems[0]["input"], batchInputItems[0]["context"],
+			hashAlgorithmStr, strconv.FormatBool(prehashed), sigAlgorithm, marshalingStr, strconv.Itoa(saltLength))
+		resp, approved, err := b.approveSigning(ctx, req, p, approvalID)
+		if !approved || err != nil {
+			p.Unlock()
+			return resp, err
+		}
+	}
+
+	var managedKeySigner crypto.Signer
+	if p.Type == keysutil.KeyType_MANAGED_KEY {
+		managedKeySigner, err = b.getManagedKeySigner(ctx, p.ManagedKeyName)
+		if err != nil {
+			p.Unlock()
+			return logical.ErrorResponse(fmt.Sprintf("unable to use managed key %q: %s", p.ManagedKeyName, err)), logical.ErrInvalidRequest
+		}
+	}
+
 	response := make([]batchResponseSignItem, len(batchInputItems))
+	inputs := make([][]byte, len(batchInputItems))
 
 	for i, item := range batchInputItems {
 
@@ -393,10 +420,11 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *fr
 		}
 
 		sig, err := p.SignWithOptions(ver, context, input, &keysutil.SigningOptions{
-			HashAlgorithm: hashAlgorithm,
-			Marshaling:    marshaling,
-			SaltLength:    saltLength,
-			SigAlgorithm:  sigAlgorithm,
+			HashAlgorithm:    hashAlgorithm,
+			Marshaling:       marshaling,
+			SaltLength:       saltLength,
+			SigAlgorithm:     sigAlgorithm,
+			ManagedKeySigner: managedKeySigner,
 		})
 		if err != nil {
 			if batchInputRaw != nil {
@@ -414,6 +442,23 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *fr
 			response[i].Signature = sig.Signature
 			response[i].PublicKey = sig.PublicKey
 			response[i].KeyVersion = keyVersion
+			inputs[i] = input
+		}
+	}
+
+	if p.UsageLog {
+		var keyVersions []int
+		var signedInputs [][]byte
+		for i := range response {
+			if response[i].err == nil && response[i].Error == "" {
+				keyVersions = append(keyVersions, response[i].KeyVersion)
+				signedInputs = append(signedInputs, inputs[i])
+			}
+		}
+		// Signatures are not returned unless they have been recorded
+		if err := b.appendUsageLog(ctx, req, p, "sign", keyVersions, signedInputs, managedKeySigner); err != nil {
+			p.Unlock()
+			return nil, err
 		}
 	}
 
@@ -569,6 +614,15 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *
 		return logical.ErrorResponse(fmt.Sprintf("key type %v does not support verification", p.Type)), logical.ErrInvalidRequest
 	}
 
+	var managedKeyPublicKey crypto.PublicKey
+	if p.Type == keysutil.KeyType_MANAGED_KEY {
+		managedKeyPublicKey, err = b.getManagedKeyPublicKey(ctx, p.ManagedKeyName)
+		if err != nil {
+			p.Unlock()
+			return logical.ErrorResponse(fmt.Sprintf("unable to use managed key %q: %s", p.ManagedKeyName, err)), logical.ErrInvalidRequest
+		}
+	}
+
 	response := make([]batchResponseVerifyItem, len(batchInputItems))
 
 	for i, item := range batchInputItems {
@@ -612,10 +666,11 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *
 		}
 
 		valid, err := p.VerifySignatureWithOptions(context, input, sig, &keysutil.SigningOptions{
-			HashAlgorithm: hashAlgorithm,
-			Marshaling:    marshaling,
-			SaltLength:    saltLength,
-			SigAlgorithm:  sigAlgorithm,
+			HashAlgorithm:       hashAlgorithm,
+			Marshaling:          marshaling,
+			SaltLength:          saltLength,
+			SigAlgorithm:        sigAlgorithm,
+			ManagedKeyPublicKey: managedKeyPublicKey,
 		})
 		if err != nil {
 			switch err.(type) {
@@ -0,0 +1,281 @@
+package transit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	signingApprovalsPrefix = "signing-approvals/"
+
+	defaultSigningApprovalWindow = time.Hour
+)
+
+// signingApproval is a signature waiting for the approval of more callers.
+// It is identified by the hash of the signing request so that all approvers
+// request exactly the same signature.
+type signingApproval struct {
+	ID          string    `json:"id"`
+	Approvers   []string  `json:"approvers"`
+	CreatedTime time.Time `json:"created_time"`
+	ExpireTime  time.Time `json:"expire_time"`
+}
+
+func signingApprovalWindow(p *keysutil.Policy) time.Duration {
+	if p.SigningApprovalWindow == 0 {
+		return defaultSigningApprovalWindow
+	}
+	return p.SigningApprovalWindow
+}
+
+func signingApprovalsPath(name string) string {
+	return signingApprovalsPrefix + name + "/"
+}
+
+// signingApprovalID identifies a signing request by everything that affects
+// the produced signature.
+func signingApprovalID(parts ...string) string {
+	for i, part := range parts {
+		// Length prefixes keep the parts from running into each other
+		parts[i] = strconv.Itoa(len(part)) + ":" + part
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (b *backend) pathSigningApprovals() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "keys/" + framework.GenericNameRegex("name") + "/approvals/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the key",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.pathSigningApprovalsList,
+			},
+
+			HelpSynopsis:    pathSigningApprovalsHelpSyn,
+			HelpDescription: pathSigningApprovalsHelpDesc,
+		},
+		{
+			Pattern: "keys/" + framework.GenericNameRegex("name") + "/approvals/" + framework.GenericNameRegex("id"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the key",
+				},
+				"id": {
+					Type:        framework.TypeString,
+					Description: "Identifier of the pending signature",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.pathSigningApprovalRead,
+				logical.DeleteOperation: b.pathSigningApprovalDelete,
+			},
+
+			HelpSynopsis:    pathSigningApprovalsHelpSyn,
+			HelpDescription: pathSigningApprovalsHelpDesc,
+		},
+	}
+}
+
+func (b *backend) pathSigningApprovalsList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	b.signingApprovalLock.Lock()
+	defer b.signingApprovalLock.Unlock()
+
+	ids, err := req.Storage.List(ctx, signingApprovalsPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, id := range ids {
+		approval, err := b.getSigningApproval(ctx, req.Storage, name, id)
+		if err != nil {
+			return nil, err
+		}
+		if approval != nil {
+			pending = append(pending, id)
+		}
+	}
+
+	return logical.ListResponse(pending), nil
+}
+
+func (b *backend) pathSigningApprovalRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	id := d.Get("id").(string)
+
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, nil
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	b.signingApprovalLock.Lock()
+	defer b.signingApprovalLock.Unlock()
+
+	approval, err := b.getSigningApproval(ctx, req.Storage, name, id)
+	if err != nil {
+		return nil, err
+	}
+	if approval == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: signingApprovalResponseData(p, approval),
+	}, nil
+}
+
+func (b *backend) pathSigningApprovalDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	id := d.Get("id").(string)
+
+	b.signingApprovalLock.Lock()
+	defer b.signingApprovalLock.Unlock()
+
+	return nil, req.Storage.Delete(ctx, signingApprovalsPath(name)+id)
+}
+
+// getSigningApproval returns a pending signature, or nil if it does not exist
+// or has expired. Expired approvals are deleted.
+func (b *backend) getSigningApproval(ctx context.Context, s logical.Storage, name, id string) (*signingApproval, error) {
+	raw, err := s.Get(ctx, signingApprovalsPath(name)+id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var approval signingApproval
+	if err := raw.DecodeJSON(&approval); err != nil {
+		return nil, err
+	}
+	if time.Now().After(approval.ExpireTime) {
+		if err := s.Delete(ctx, signingApprovalsPath(name)+id); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return &approval, nil
+}
+
+// approveSigning records the approval of the caller for a signature. It
+// returns true once enough distinct entities approved the signature, in
+// which case the approvals are consumed and the caller may sign. Otherwise
+// the returned response reports the state of the approvals. The policy must
+// be locked by the caller.
+func (b *backend) approveSigning(ctx context.Context, req *logical.Request, p *keysutil.Policy, id string) (*logical.Response, bool, error) {
+	if req.EntityID == "" {
+		return logical.ErrorResponse("key %q requires signing approvals from distinct entities; the token has no entity", p.Name), false, logical.ErrPermissionDenied
+	}
+
+	b.signingApprovalLock.Lock()
+	defer b.signingApprovalLock.Unlock()
+
+	approval, err := b.getSigningApproval(ctx, req.Storage, p.Name, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if approval == nil {
+		now := time.Now().UTC()
+		approval = &signingApproval{
+			ID:          id,
+			CreatedTime: now,
+			ExpireTime:  now.Add(signingApprovalWindow(p)),
+		}
+	}
+
+	resp := &logical.Response{}
+	if strutil.StrListContains(approval.Approvers, req.EntityID) {
+		resp.AddWarning("the signature was already approved by this entity")
+	} else {
+		approval.Approvers = append(approval.Approvers, req.EntityID)
+	}
+
+	if len(approval.Approvers) >= p.SigningApprovalsRequired {
+		if err := req.Storage.Delete(ctx, signingApprovalsPath(p.Name)+id); err != nil {
+			return nil, false, err
+		}
+		return nil, true, nil
+	}
+
+	raw, err := logical.StorageEntryJSON(signingApprovalsPath(p.Name)+id, approval)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := req.Storage.Put(ctx, raw); err != nil {
+		return nil, false, err
+	}
+
+	resp.Data = signingApprovalResponseData(p, approval)
+	return resp, false, nil
+}
+
+func signingApprovalResponseData(p *keysutil.Policy, approval *signingApproval) map[string]interface{} {
+	return map[string]interface{}{
+		"approval_id":        approval.ID,
+		"approvers":          approval.Approvers,
+		"approvals":          len(approval.Approvers),
+		"approvals_required": p.SigningApprovalsRequired,
+		"created_time":       approval.CreatedTime,
+		"expire_time":        approval.ExpireTime,
+	}
+}
+
+func (b *backend) deleteSigningApprovals(ctx context.Context, s logical.Storage, name string) error {
+	b.signingApprovalLock.Lock()
+	defer b.signingApprovalLock.Unlock()
+
+	ids, err := s.List(ctx, signingApprovalsPath(name))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Delete(ctx, signingApprovalsPath(name)+id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const pathSigningApprovalsHelpSyn = `List, read and cancel signatures waiting for approval`
+
+const pathSigningApprovalsHelpDesc = `
+When signing_approvals_required is set on a key, a signature is only
+produced once that many distinct entities requested it with identical
+parameters within the signing_approval_window. Until then the sign endpoint
+returns the state of the approvals instead of a signature; the call that
+brings the last approval returns the signature.
+
+These paths list the pending signatures of a key, read the entities that
+approved one of them, and cancel a pending signature.
+`
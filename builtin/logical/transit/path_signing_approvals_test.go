@@ -0,0 +1,120 @@
+package transit
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_SigningApprovals(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+
+	request := func(op logical.Operation, path, entityID string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+			EntityID:  entityID,
+		})
+	}
+	doReq := func(t *testing.T, op logical.Operation, path, entityID string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(op, path, entityID, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("got err:\n%#v\nresp:\n%#v\n", err, resp)
+		}
+		return resp
+	}
+	doErrReq := func(t *testing.T, op logical.Operation, path, entityID string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := request(op, path, entityID, data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected error; resp:\n%#v\n", resp)
+		}
+	}
+
+	doReq(t, logical.UpdateOperation, "keys/aes", "", nil)
+	doErrReq(t, logical.UpdateOperation, "keys/aes/config", "", map[string]interface{}{"signing_approvals_required": 2})
+
+	doReq(t, logical.UpdateOperation, "keys/release", "", map[string]interface{}{"type": "ed25519"})
+	doReq(t, logical.UpdateOperation, "keys/release/config", "", map[string]interface{}{"signing_approvals_required": 3})
+
+	resp := doReq(t, logical.ReadOperation, "keys/release", "", nil)
+	if resp.Data["signing_approvals_required"] != 3 || resp.Data["signing_approval_window"] != int64(3600) {
+		t.Fatalf("bad key: %#v", resp.Data)
+	}
+
+	input := map[string]interface{}{"input": base64.StdEncoding.EncodeToString([]byte("release-1.0"))}
+
+	// Approvals need distinct entities and do not apply to batches
+	doErrReq(t, logical.UpdateOperation, "sign/release", "", input)
+	doErrReq(t, logical.UpdateOperation, "sign/release", "entity-1", map[string]interface{}{
+		"batch_input": []interface{}{input},
+	})
+
+	resp = doReq(t, logical.UpdateOperation, "sign/release", "entity-1", input)
+	if resp.Data["signature"] != nil || resp.Data["approvals"] != 1 {
+		t.Fatalf("expected a pending signature, got %#v", resp.Data)
+	}
+	id := resp.Data["approval_id"].(string)
+
+	// Approving twice does not count
+	resp = doReq(t, logical.UpdateOperation, "sign/release", "entity-1", input)
+	if resp.Data["approvals"] != 1 || len(resp.Warnings) != 1 {
+		t.Fatalf("expected the approval to be counted once, got %#v", resp)
+	}
+
+	// A different input is a different signature
+	resp = doReq(t, logical.UpdateOperation, "sign/release", "entity-2", map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString([]byte("release-2.0")),
+	})
+	if resp.Data["approval_id"] == id || resp.Data["approvals"] != 1 {
+		t.Fatalf("bad approval: %#v", resp.Data)
+	}
+
+	resp = doReq(t, logical.UpdateOperation, "sign/release", "entity-2", input)
+	if resp.Data["approvals"] != 2 {
+		t.Fatalf("bad approvals: %#v", resp.Data)
+	}
+
+	resp = doReq(t, logical.ListOperation, "keys/release/approvals/", "", nil)
+	if len(resp.Data["keys"].([]string)) != 2 {
+		t.Fatalf("expected 2 pending signatures, got %#v", resp.Data)
+	}
+	resp = doReq(t, logical.ReadOperation, "keys/release/approvals/"+id, "", nil)
+	if len(resp.Data["approvers"].([]string)) != 2 {
+		t.Fatalf("bad approvers: %#v", resp.Data)
+	}
+
+	resp = doReq(t, logical.UpdateOperation, "sign/release", "entity-3", input)
+	if resp.Data["signature"] == nil {
+		t.Fatalf("expected a signature, got %#v", resp.Data)
+	}
+	resp = doReq(t, logical.UpdateOperation, "verify/release", "", map[string]interface{}{
+		"input":     input["input"],
+		"signature": resp.Data["signature"],
+	})
+	if !resp.Data["valid"].(bool) {
+		t.Fatal("expected the signature to verify")
+	}
+
+	// The approvals are consumed by the signature
+	resp = doReq(t, logical.ReadOperation, "keys/release/approvals/"+id, "", nil)
+	if resp != nil {
+		t.Fatalf("expected the approvals to be consumed, got %#v", resp.Data)
+	}
+	resp = doReq(t, logical.UpdateOperation, "sign/release", "entity-3", input)
+	if resp.Data["signature"] != nil {
+		t.Fatal("expected a new round of approvals")
+	}
+
+	// Pending signatures can be cancelled
+	doReq(t, logical.DeleteOperation, "keys/release/approvals/"+id, "", nil)
+	resp = doReq(t, logical.ListOperation, "keys/release/approvals/", "", nil)
+	if len(resp.Data["keys"].([]string)) != 1 {
+		t.Fatalf("expected 1 pending signature, got %#v", resp.Data)
+	}
+}
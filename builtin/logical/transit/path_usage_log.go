@@ -0,0 +1,312 @@
+package transit
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	usageLogPrefix = "usage-log/"
+
+	// usageLogHashVersion prefixes the data hashed for every entry so the
+	// format can evolve without breaking the verification of older entries.
+	usageLogHashVersion = "vault-transit-usage-log-v1"
+
+	defaultUsageLogListLimit = 1000
+)
+
+// usageLogHead is the state of the chain of a key: the sequence number and
+// hash of the last entry.
+type usageLogHead struct {
+	Sequence uint64 `json:"sequence"`
+	Hash     string `json:"hash"`
+}
+
+// usageLogEntry records a signing operation performed with a key. Entries
+// are chained by including the hash of the previous entry, and the hash of
+// every entry is signed by the latest version of the key.
+type usageLogEntry struct {
+	Sequence   uint64    `json:"sequence"`
+	Time       time.Time `json:"time"`
+	Operation  string    `json:"operation"`
+	KeyVersion int       `json:"key_version"`
+	EntityID   string    `json:"entity_id"`
+	InputHash  string    `json:"input_hash"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+	Signature  string    `json:"signature"`
+}
+
+// hashInput returns the data hashed to produce the hash of the entry. It is
+// documented in the help of the usage log path so logs can be verified
+// without Vault.
+func (e *usageLogEntry) hashInput() []byte {
+	return []byte(strings.Join([]string{
+		usageLogHashVersion,
+		strconv.FormatUint(e.Sequence, 10),
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.Operation,
+		strconv.Itoa(e.KeyVersion),
+		e.EntityID,
+		e.InputHash,
+		e.PrevHash,
+	}, "\n"))
+}
+
+func usageLogHeadPath(name string) string {
+	return usageLogPrefix + name + "/head"
+}
+
+func usageLogEntriesPath(name string) string {
+	return usageLogPrefix + name + "/entries/"
+}
+
+func usageLogEntryPath(name string, sequence uint64) string {
+	// Zero padding keeps the entries sorted when listed
+	return fmt.Sprintf("%s%020d", usageLogEntriesPath(name), sequence)
+}
+
+func (b *backend) pathUsageLog() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/usage-log",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"after": {
+				Type:        framework.TypeInt,
+				Description: "Only return the entries with a sequence number greater than this value.",
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Default:     defaultUsageLogListLimit,
+				Description: "Maximum number of entries to return.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathUsageLogRead,
+		},
+
+		HelpSynopsis:    pathUsageLogHelpSyn,
+		HelpDescription: pathUsageLogHelpDesc,
+	}
+}
+
+func (b *backend) pathUsageLogRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	after := d.Get("after").(int)
+	limit := d.Get("limit").(int)
+	if after < 0 {
+		return logical.ErrorResponse("after cannot be negative"), logical.ErrInvalidRequest
+	}
+	if limit <= 0 {
+		return logical.ErrorResponse("limit must be positive"), logical.ErrInvalidRequest
+	}
+
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, nil
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	b.usageLogLock.Lock()
+	defer b.usageLogLock.Unlock()
+
+	head, err := b.getUsageLogHead(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := req.Storage.List(ctx, usageLogEntriesPath(name))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	entries := make([]*usageLogEntry, 0)
+	for _, key := range keys {
+		sequence, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || sequence <= uint64(after) {
+			continue
+		}
+		if len(entries) == limit {
+			break
+		}
+
+		raw, err := req.Storage.Get(ctx, usageLogEntriesPath(name)+key)
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			continue
+		}
+		var entry usageLogEntry
+		if err := raw.DecodeJSON(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	// Return the public keys so that the signatures of the entries can be
+	// verified offline
+	publicKeys := make(map[string]string)
+	for version, key := range p.Keys {
+		if key.FormattedPublicKey != "" {
+			publicKeys[version] = key.FormattedPublicKey
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":     p.UsageLog,
+			"sequence":    head.Sequence,
+			"head":        head.Hash,
+			"entries":     entries,
+			"public_keys": publicKeys,
+		},
+	}, nil
+}
+
+func (b *backend) getUsageLogHead(ctx context.Context, s logical.Storage, name string) (*usageLogHead, error) {
+	raw, err := s.Get(ctx, usageLogHeadPath(name))
+	if err != nil {
+		return nil, err
+	}
+	var head usageLogHead
+	if raw != nil {
+		if err := raw.DecodeJSON(&head); err != nil {
+			return nil, err
+		}
+	}
+	return &head, nil
+}
+
+// appendUsageLog records signing operations in the usage log of the key.
+// The policy must be locked by the caller. Inputs are the data that was
+// signed with each key version.
+func (b *backend) appendUsageLog(ctx context.Context, req *logical.Request, p *keysutil.Policy, operation string, keyVersions []int, inputs [][]byte, managedKeySigner crypto.Signer) error {
+	b.usageLogLock.Lock()
+	defer b.usageLogLock.Unlock()
+
+	head, err := b.getUsageLogHead(ctx, req.Storage, p.Name)
+	if err != nil {
+		return err
+	}
+
+	for i, input := range inputs {
+		inputHash := sha256.Sum256(input)
+		entry := &usageLogEntry{
+			Sequence:   head.Sequence + 1,
+			Time:       time.Now().UTC(),
+			Operation:  operation,
+			KeyVersion: keyVersions[i],
+			EntityID:   req.EntityID,
+			InputHash:  hex.EncodeToString(inputHash[:]),
+			PrevHash:   head.Hash,
+		}
+		hash := sha256.Sum256(entry.hashInput())
+		entry.Hash = hex.EncodeToString(hash[:])
+
+		// Keys hashing their input expect the digest of the data to sign,
+		// which is the hash of the entry; other keys sign the hash itself
+		sig, err := p.SignWithOptions(p.LatestVersion, nil, hash[:], &keysutil.SigningOptions{
+			HashAlgorithm:    keysutil.HashTypeSHA2256,
+			Marshaling:       keysutil.MarshalingTypeASN1,
+			SigAlgorithm:     "pss",
+			ManagedKeySigner: managedKeySigner,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to sign usage log entry: %w", err)
+		}
+		entry.Signature = sig.Signature
+
+		raw, err := logical.StorageEntryJSON(usageLogEntryPath(p.Name, entry.Sequence), entry)
+		if err != nil {
+			return err
+		}
+		if err := req.Storage.Put(ctx, raw); err != nil {
+			return err
+		}
+
+		head = &usageLogHead{
+			Sequence: entry.Sequence,
+			Hash:     entry.Hash,
+		}
+		raw, err = logical.StorageEntryJSON(usageLogHeadPath(p.Name), head)
+		if err != nil {
+			return err
+		}
+		if err := req.Storage.Put(ctx, raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *backend) deleteUsageLog(ctx context.Context, s logical.Storage, name string) error {
+	b.usageLogLock.Lock()
+	defer b.usageLogLock.Unlock()
+
+	keys, err := s.List(ctx, usageLogEntriesPath(name))
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.Delete(ctx, usageLogEntriesPath(name)+key); err != nil {
+			return err
+		}
+	}
+	return s.Delete(ctx, usageLogHeadPath(name))
+}
+
+const pathUsageLogHelpSyn = `Read the usage log of a named key`
+
+const pathUsageLogHelpDesc = `
+This path returns the usage log of a signing key, enabled with the
+usage_log parameter of the key configuration. Every signing operation
+performed with the key adds an entry recording the operation, the key
+version, the entity of the caller and the SHA-256 hash of the signed
+input.
+
+Entries are chained: the hash of an entry is the hex encoded SHA-256 of
+the following lines joined with a newline:
+
+    vault-transit-usage-log-v1
+    <sequence>
+    <time, RFC 3339 with nanoseconds, UTC>
+    <operation>
+    <key_version>
+    <entity_id>
+    <input_hash>
+    <prev_hash>
+
+The hash of every entry is signed with the latest version of the key at
+the time of the operation, using PSS for RSA keys and ASN.1 marshaling. For
+ed25519 keys the signed message is the hash itself; for other keys the
+hash is the digest being signed. The public keys of the key versions are
+returned alongside the entries so that the log can be verified offline.
+Use the after and limit parameters to page through long logs.
+`
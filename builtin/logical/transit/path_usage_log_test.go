@@ -0,0 +1,130 @@
+package transit
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_UsageLog(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+
+	doReq := func(t *testing.T, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+			EntityID:  "entity-1",
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("got err:\n%#v\nresp:\n%#v\n", err, resp)
+		}
+		return resp
+	}
+
+	// Symmetric keys cannot sign so they cannot have a usage log
+	doReq(t, logical.UpdateOperation, "keys/aes", nil)
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/aes/config",
+		Storage:   storage,
+		Data:      map[string]interface{}{"usage_log": true},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatal("expected enabling the usage log of an aes key to fail")
+	}
+
+	doReq(t, logical.UpdateOperation, "keys/signer", map[string]interface{}{"type": "ecdsa-p256"})
+
+	// Operations before the log is enabled are not recorded
+	input := base64.StdEncoding.EncodeToString([]byte("release-1.0"))
+	doReq(t, logical.UpdateOperation, "sign/signer", map[string]interface{}{"input": input})
+
+	doReq(t, logical.UpdateOperation, "keys/signer/config", map[string]interface{}{"usage_log": true})
+	doReq(t, logical.UpdateOperation, "sign/signer", map[string]interface{}{"input": input})
+	doReq(t, logical.UpdateOperation, "keys/signer/rotate", nil)
+	doReq(t, logical.UpdateOperation, "sign/signer", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input},
+			map[string]interface{}{"input": "not base64"},
+			map[string]interface{}{"input": base64.StdEncoding.EncodeToString([]byte("release-1.1"))},
+		},
+	})
+
+	resp = doReq(t, logical.ReadOperation, "keys/signer/usage-log", nil)
+	entries := resp.Data["entries"].([]*usageLogEntry)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if resp.Data["head"] != entries[2].Hash || resp.Data["sequence"] != uint64(3) {
+		t.Fatalf("bad head: %#v", resp.Data)
+	}
+
+	publicKeys := resp.Data["public_keys"].(map[string]string)
+	prevHash := ""
+	for i, entry := range entries {
+		if entry.Sequence != uint64(i+1) || entry.EntityID != "entity-1" || entry.Operation != "sign" {
+			t.Fatalf("bad entry %d: %#v", i, entry)
+		}
+		if entry.PrevHash != prevHash {
+			t.Fatalf("entry %d is not chained to the previous entry", i)
+		}
+		prevHash = entry.Hash
+
+		hash := sha256.Sum256(entry.hashInput())
+		if hex.EncodeToString(hash[:]) != entry.Hash {
+			t.Fatalf("bad hash for entry %d", i)
+		}
+
+		// vault:v<version>:<signature>
+		parts := strings.SplitN(entry.Signature, ":", 3)
+		block, _ := pem.Decode([]byte(publicKeys[strings.TrimPrefix(parts[1], "v")]))
+		if block == nil {
+			t.Fatalf("missing public key for entry %d", i)
+		}
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ecdsa.VerifyASN1(public.(*ecdsa.PublicKey), hash[:], sig) {
+			t.Fatalf("bad signature for entry %d", i)
+		}
+	}
+	if entries[0].KeyVersion != 1 || entries[1].KeyVersion != 2 {
+		t.Fatalf("bad key versions: %d, %d", entries[0].KeyVersion, entries[1].KeyVersion)
+	}
+	if entries[1].InputHash != entries[0].InputHash || entries[2].InputHash == entries[1].InputHash {
+		t.Fatal("bad input hashes")
+	}
+
+	resp = doReq(t, logical.ReadOperation, "keys/signer/usage-log", map[string]interface{}{"after": 1, "limit": 1})
+	entries = resp.Data["entries"].([]*usageLogEntry)
+	if len(entries) != 1 || entries[0].Sequence != 2 {
+		t.Fatalf("bad page: %#v", entries)
+	}
+
+	// Deleting the key deletes its log
+	doReq(t, logical.UpdateOperation, "keys/signer/config", map[string]interface{}{"deletion_allowed": true})
+	doReq(t, logical.DeleteOperation, "keys/signer", nil)
+	keys, err := storage.List(namespace.RootContext(nil), usageLogPrefix+"signer/entries/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("expected the usage log to be deleted, got %d entries", len(keys))
+	}
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -36,6 +37,7 @@ import (
 	"github.com/hashicorp/vault/command/agent/cache/cachememdb"
 	"github.com/hashicorp/vault/command/agent/cache/keymanager"
 	agentConfig "github.com/hashicorp/vault/command/agent/config"
+	agentdns "github.com/hashicorp/vault/command/agent/dns"
 	"github.com/hashicorp/vault/command/agent/sink"
 	"github.com/hashicorp/vault/command/agent/sink/file"
 	"github.com/hashicorp/vault/command/agent/sink/inmem"
@@ -46,6 +48,7 @@ import (
 	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/hashicorp/vault/internalshared/listenerutil"
 	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 	"github.com/hashicorp/vault/sdk/helper/useragent"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/version"
@@ -72,10 +75,12 @@ type AgentCommand struct {
 
 	ShutdownCh chan struct{}
 	SighupCh   chan struct{}
+	SigUSR2Ch  chan struct{}
 
 	logWriter io.Writer
 	logGate   *gatedwriter.Writer
 	logger    log.Logger
+	loggers   *logging.SubsystemLoggers
 
 	// Telemetry object
 	metricsHelper *metricsutil.MetricsHelper
@@ -261,6 +266,17 @@ func (c *AgentCommand) Run(args []string) int {
 
 	c.logger = l
 
+	subsystemLevels, err := subsystemLogLevels(config)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.loggers = logging.NewSubsystemLoggers(logLevel, agentConfig.SubsystemAutoAuth, agentConfig.SubsystemCaching, agentConfig.SubsystemTemplating)
+	if err := c.loggers.SetConfiguredLevels(logLevel, subsystemLevels); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	infoKeys := make([]string, 0, 10)
 	info := make(map[string]string)
 	info["log level"] = c.flagLogLevel
@@ -324,6 +340,7 @@ func (c *AgentCommand) Run(args []string) int {
 
 	var method auth.AuthMethod
 	var sinks []*sink.SinkConfig
+	var dnsServer *agentdns.Server
 	var templateNamespace string
 	if config.AutoAuth != nil {
 		if client.Headers().Get(consts.NamespaceHeaderName) == "" && config.AutoAuth.Method.Namespace != "" {
@@ -349,7 +366,7 @@ func (c *AgentCommand) Run(args []string) int {
 			switch sc.Type {
 			case "file":
 				config := &sink.SinkConfig{
-					Logger:    c.logger.Named("sink.file"),
+					Logger:    c.subsystemLogger(agentConfig.SubsystemAutoAuth, "sink.file"),
 					Config:    sc.Config,
 					Client:    sinkClient,
 					WrapTTL:   sc.WrapTTL,
@@ -371,8 +388,33 @@ func (c *AgentCommand) Run(args []string) int {
 			}
 		}
 
+		// The DNS server reads the data of its records with the auto-auth
+		// token, which it receives as a sink
+		if config.DNS != nil {
+			dnsClient, err := client.CloneWithHeaders()
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error cloning client for dns: %v", err))
+				return 1
+			}
+			dnsServer, err = agentdns.NewServer(&agentdns.ServerConfig{
+				Logger: c.logger.Named("dns"),
+				Client: dnsClient,
+				Config: config.DNS,
+			})
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error creating dns server: %v", err))
+				return 1
+			}
+			sinks = append(sinks, &sink.SinkConfig{
+				Logger: c.subsystemLogger(agentConfig.SubsystemAutoAuth, "sink.dns"),
+				Sink:   dnsServer,
+			})
+			info["dns address"] = config.DNS.Address
+			infoKeys = append(infoKeys, "dns address")
+		}
+
 		authConfig := &auth.AuthConfig{
-			Logger:    c.logger.Named(fmt.Sprintf("auth.%s", config.AutoAuth.Method.Type)),
+			Logger:    c.subsystemLogger(agentConfig.SubsystemAutoAuth, fmt.Sprintf("auth.%s", config.AutoAuth.Method.Type)),
 			MountPath: config.AutoAuth.Method.MountPath,
 			Config:    config.AutoAuth.Method.Config,
 		}
@@ -465,7 +507,7 @@ func (c *AgentCommand) Run(args []string) int {
 	var previousToken string
 	// Parse agent listener configurations
 	if config.Cache != nil {
-		cacheLogger := c.logger.Named("cache")
+		cacheLogger := c.subsystemLogger(agentConfig.SubsystemCaching, "cache")
 
 		proxyClient, err := client.CloneWithHeaders()
 		if err != nil {
@@ -484,7 +526,7 @@ func (c *AgentCommand) Run(args []string) int {
 		// Create the API proxier
 		apiProxy, err := cache.NewAPIProxy(&cache.APIProxyConfig{
 			Client:                 proxyClient,
-			Logger:                 cacheLogger.Named("apiproxy"),
+			Logger:                 c.subsystemLogger(agentConfig.SubsystemCaching, "cache.apiproxy"),
 			EnforceConsistency:     enforceConsistency,
 			WhenInconsistentAction: whenInconsistent,
 		})
@@ -499,7 +541,9 @@ func (c *AgentCommand) Run(args []string) int {
 			Client:      proxyClient,
 			BaseContext: ctx,
 			Proxier:     apiProxy,
-			Logger:      cacheLogger.Named("leasecache"),
+			Logger:      c.subsystemLogger(agentConfig.SubsystemCaching, "cache.leasecache"),
+
+			StaticSecretCacheTTL: config.Cache.StaticSecretCacheTTL,
 		})
 		if err != nil {
 			c.UI.Error(fmt.Sprintf("Error creating lease cache: %v", err))
@@ -537,7 +581,7 @@ func (c *AgentCommand) Run(args []string) int {
 				// Open the bolt file, but wait to setup Encryption
 				ps, err := cacheboltdb.NewBoltStorage(&cacheboltdb.BoltStorageConfig{
 					Path:   config.Cache.Persist.Path,
-					Logger: cacheLogger.Named("cacheboltdb"),
+					Logger: c.subsystemLogger(agentConfig.SubsystemCaching, "cache.cacheboltdb"),
 				})
 				if err != nil {
 					c.UI.Error(fmt.Sprintf("Error opening persistent cache: %v", err))
@@ -564,7 +608,7 @@ func (c *AgentCommand) Run(args []string) int {
 				// Open the bolt file with the wrapper provided
 				ps, err = cacheboltdb.NewBoltStorage(&cacheboltdb.BoltStorageConfig{
 					Path:    config.Cache.Persist.Path,
-					Logger:  cacheLogger.Named("cacheboltdb"),
+					Logger:  c.subsystemLogger(agentConfig.SubsystemCaching, "cache.cacheboltdb"),
 					Wrapper: km.Wrapper(),
 					AAD:     aad,
 				})
@@ -626,7 +670,7 @@ func (c *AgentCommand) Run(args []string) int {
 				}
 				ps, err := cacheboltdb.NewBoltStorage(&cacheboltdb.BoltStorageConfig{
 					Path:    config.Cache.Persist.Path,
-					Logger:  cacheLogger.Named("cacheboltdb"),
+					Logger:  c.subsystemLogger(agentConfig.SubsystemCaching, "cache.cacheboltdb"),
 					Wrapper: km.Wrapper(),
 					AAD:     aad,
 				})
@@ -679,7 +723,7 @@ func (c *AgentCommand) Run(args []string) int {
 		if len(config.Templates) > 0 {
 			config.Listeners = append(config.Listeners, &configutil.Listener{Type: listenerutil.BufConnType})
 		}
-		for i, lnConfig := range config.Listeners {
+		for i, lnConfig := range config.APIListeners() {
 			var ln net.Listener
 			var tlsConf *tls.Config
 
@@ -707,10 +751,13 @@ func (c *AgentCommand) Run(args []string) int {
 			// Create a muxer and add paths relevant for the lease cache layer
 			mux := http.NewServeMux()
 			quitEnabled := lnConfig.AgentAPI != nil && lnConfig.AgentAPI.EnableQuit
+			loggersEnabled := lnConfig.AgentAPI != nil && lnConfig.AgentAPI.EnableLoggers
 
 			mux.Handle(consts.AgentPathCacheClear, leaseCache.HandleCacheClear(ctx))
 			mux.Handle(consts.AgentPathQuit, c.handleQuit(quitEnabled))
 			mux.Handle(consts.AgentPathMetrics, c.handleMetrics())
+			mux.Handle(consts.AgentPathLoggers, c.handleLoggers(loggersEnabled))
+			mux.Handle(consts.AgentPathLoggers+"/", c.handleLoggers(loggersEnabled))
 			mux.Handle("/", muxHandler)
 
 			scheme := "https://"
@@ -747,6 +794,44 @@ func (c *AgentCommand) Run(args []string) int {
 		defer c.cleanupGuard.Do(listenerCloseFunc)
 	}
 
+	// Start the listeners only serving metrics, which unlike the others
+	// don't depend on the cache
+	for i, lnConfig := range config.MetricsListeners() {
+		ln, tlsConf, err := cache.StartListener(lnConfig)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error starting metrics listener: %v", err))
+			return 1
+		}
+		defer ln.Close()
+
+		mux := http.NewServeMux()
+		mux.Handle(consts.AgentPathMetrics, c.handleMetrics())
+
+		scheme := "https://"
+		if tlsConf == nil {
+			scheme = "http://"
+		}
+		if ln.Addr().Network() == "unix" {
+			scheme = "unix://"
+		}
+
+		infoKey := fmt.Sprintf("metrics address %d", i+1)
+		info[infoKey] = scheme + ln.Addr().String()
+		infoKeys = append(infoKeys, infoKey)
+
+		server := &http.Server{
+			Addr:              ln.Addr().String(),
+			TLSConfig:         tlsConf,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       5 * time.Minute,
+			ErrorLog:          c.logger.Named("metrics").StandardLogger(nil),
+		}
+
+		go server.Serve(ln)
+	}
+
 	// Inform any tests that the server is ready
 	if c.startedCh != nil {
 		close(c.startedCh)
@@ -772,6 +857,11 @@ func (c *AgentCommand) Run(args []string) int {
 					leaseCache.SetShuttingDown(true)
 				}
 				return nil
+			case <-c.SigUSR2Ch:
+				c.UI.Output("==> Vault agent reloading log levels")
+				if err := c.reloadLogLevels(f); err != nil {
+					c.logger.Error("error reloading log levels", "error", err)
+				}
 			case <-ctx.Done():
 				c.notifySystemd(systemd.SdNotifyStopping)
 				return nil
@@ -802,7 +892,7 @@ func (c *AgentCommand) Run(args []string) int {
 		}
 
 		ah := auth.NewAuthHandler(&auth.AuthHandlerConfig{
-			Logger:                       c.logger.Named("auth.handler"),
+			Logger:                       c.subsystemLogger(agentConfig.SubsystemAutoAuth, "auth.handler"),
 			Client:                       ahClient,
 			WrapTTL:                      config.AutoAuth.Method.WrapTTL,
 			MinBackoff:                   config.AutoAuth.Method.MinBackoff,
@@ -814,14 +904,14 @@ func (c *AgentCommand) Run(args []string) int {
 		})
 
 		ss := sink.NewSinkServer(&sink.SinkServerConfig{
-			Logger:        c.logger.Named("sink.server"),
+			Logger:        c.subsystemLogger(agentConfig.SubsystemAutoAuth, "sink.server"),
 			Client:        ahClient,
 			ExitAfterAuth: config.ExitAfterAuth,
 		})
 
 		ts := template.NewServer(&template.ServerConfig{
-			Logger:        c.logger.Named("template.server"),
-			LogLevel:      logLevel,
+			Logger:        c.subsystemLogger(agentConfig.SubsystemTemplating, "template.server"),
+			LogLevel:      c.loggers.Levels()[agentConfig.SubsystemTemplating],
 			LogWriter:     c.logWriter,
 			AgentConfig:   config,
 			Namespace:     templateNamespace,
@@ -882,6 +972,14 @@ func (c *AgentCommand) Run(args []string) int {
 			ts.Stop()
 		})
 
+		if dnsServer != nil {
+			g.Add(func() error {
+				return dnsServer.Run(ctx)
+			}, func(error) {
+				cancelFunc()
+			})
+		}
+
 	}
 
 	// Server configuration output
@@ -1176,3 +1274,135 @@ func (c *AgentCommand) handleQuit(enabled bool) http.Handler {
 		close(c.ShutdownCh)
 	})
 }
+
+// subsystemLogger returns a named logger whose level follows the level of
+// the given subsystem.
+func (c *AgentCommand) subsystemLogger(subsystem, name string) log.Logger {
+	return c.loggers.Register(subsystem, c.logger.Named(name))
+}
+
+// subsystemLogLevels parses the log_levels of the configuration.
+func subsystemLogLevels(config *agentConfig.Config) (map[string]log.Level, error) {
+	levels := make(map[string]log.Level, len(config.LogLevels))
+	for subsystem, level := range config.LogLevels {
+		parsed, err := logging.ParseLogLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing log_levels.%s: %w", subsystem, err)
+		}
+		levels[subsystem] = parsed
+	}
+	return levels, nil
+}
+
+// reloadLogLevels reads the configuration file again and applies its
+// log_level and log_levels. A log level set with the -log-level flag or the
+// VAULT_LOG_LEVEL environment variable keeps precedence over log_level, and
+// levels set through the loggers endpoint over log_levels.
+func (c *AgentCommand) reloadLogLevels(f *FlagSets) error {
+	config, err := agentConfig.LoadConfig(c.flagConfigs[0])
+	if err != nil {
+		return err
+	}
+
+	c.setStringFlag(f, config.LogLevel, &StringVar{
+		Name:   flagNameLogLevel,
+		EnvVar: EnvVaultLogLevel,
+		Target: &c.flagLogLevel,
+	})
+	logLevel, err := logging.ParseLogLevel(c.flagLogLevel)
+	if err != nil {
+		return err
+	}
+	subsystemLevels, err := subsystemLogLevels(config)
+	if err != nil {
+		return err
+	}
+
+	c.logger.SetLevel(logLevel)
+	if err := c.loggers.SetConfiguredLevels(logLevel, subsystemLevels); err != nil {
+		return err
+	}
+	c.logger.Info("reloaded log levels", "log_level", logLevel.String())
+	return nil
+}
+
+// loggersRequest is the body of requests changing log levels through the
+// loggers endpoint.
+type loggersRequest struct {
+	Level string `json:"level"`
+}
+
+// handleLoggers serves the log level of each subsystem on GET; POST changes
+// the level of the subsystem named in the path, or of all subsystems, and
+// DELETE reverts them to their configured levels.
+func (c *AgentCommand) handleLoggers(enabled bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !enabled {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		subsystems := c.loggers.Subsystems()
+		if subsystem := strings.Trim(strings.TrimPrefix(r.URL.Path, consts.AgentPathLoggers), "/"); subsystem != "" {
+			subsystems = []string{subsystem}
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			levels := make(map[string]string)
+			for subsystem, level := range c.loggers.Levels() {
+				levels[subsystem] = level.String()
+			}
+			body, err := jsonutil.EncodeJSON(map[string]interface{}{
+				"data": levels,
+			})
+			if err != nil {
+				logical.RespondError(w, http.StatusInternalServerError, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(body)
+
+		case http.MethodPost, http.MethodPut:
+			req := new(loggersRequest)
+			if err := jsonutil.DecodeJSONFromReader(r.Body, req); err != nil {
+				if err == io.EOF {
+					err = errors.New("empty JSON provided")
+				}
+				logical.RespondError(w, http.StatusBadRequest, fmt.Errorf("failed to parse JSON input: %w", err))
+				return
+			}
+			if strings.TrimSpace(req.Level) == "" {
+				logical.RespondError(w, http.StatusBadRequest, errors.New("level is required"))
+				return
+			}
+			level, err := logging.ParseLogLevel(req.Level)
+			if err != nil {
+				logical.RespondError(w, http.StatusBadRequest, err)
+				return
+			}
+			for _, subsystem := range subsystems {
+				if err := c.loggers.SetLevel(subsystem, level); err != nil {
+					logical.RespondError(w, http.StatusNotFound, err)
+					return
+				}
+			}
+			c.logger.Info("changed log level", "subsystems", subsystems, "level", level.String())
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodDelete:
+			for _, subsystem := range subsystems {
+				if err := c.loggers.ResetLevel(subsystem); err != nil {
+					logical.RespondError(w, http.StatusNotFound, err)
+					return
+				}
+			}
+			c.logger.Info("reverted log level to configured level", "subsystems", subsystems)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
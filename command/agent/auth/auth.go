@@ -17,6 +17,10 @@ import (
 const (
 	defaultMinBackoff = 1 * time.Second
 	defaultMaxBackoff = 5 * time.Minute
+
+	// tokenTTLReportInterval is how often the remaining TTL of the auto-auth
+	// token is reported
+	tokenTTLReportInterval = 10 * time.Second
 )
 
 // AuthMethod is the interface that auto-auth methods implement for the agent
@@ -369,6 +373,10 @@ func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) error {
 		metrics.IncrCounter([]string{"agent", "auth", "success"}, 1)
 		go watcher.Renew()
 
+		tokenExpiration := authTokenExpiration(secret)
+		reportTokenTTL(tokenExpiration)
+		ttlTicker := time.NewTicker(tokenTTLReportInterval)
+
 	LifetimeWatcherLoop:
 		for {
 			select {
@@ -377,6 +385,9 @@ func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) error {
 				watcher.Stop()
 				break LifetimeWatcherLoop
 
+			case <-ttlTicker.C:
+				reportTokenTTL(tokenExpiration)
+
 			case err := <-watcher.DoneCh():
 				ah.logger.Info("lifetime watcher done channel triggered")
 				if err != nil {
@@ -385,16 +396,43 @@ func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) error {
 				}
 				break LifetimeWatcherLoop
 
-			case <-watcher.RenewCh():
+			case renewal := <-watcher.RenewCh():
 				metrics.IncrCounter([]string{"agent", "auth", "success"}, 1)
 				ah.logger.Info("renewed auth token")
+				if renewal != nil {
+					tokenExpiration = authTokenExpiration(renewal.Secret)
+					reportTokenTTL(tokenExpiration)
+				}
 
 			case <-credCh:
 				ah.logger.Info("auth method found new credentials, re-authenticating")
 				break LifetimeWatcherLoop
 			}
 		}
+		ttlTicker.Stop()
+	}
+}
+
+// authTokenExpiration returns when the token of secret expires, or the zero
+// time if it doesn't.
+func authTokenExpiration(secret *api.Secret) time.Time {
+	if secret == nil || secret.Auth == nil || secret.Auth.LeaseDuration == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(secret.Auth.LeaseDuration) * time.Second)
+}
+
+// reportTokenTTL sets the gauge of the remaining TTL of the auto-auth token,
+// in seconds. Tokens that don't expire are not reported.
+func reportTokenTTL(expiration time.Time) {
+	if expiration.IsZero() {
+		return
+	}
+	ttl := time.Until(expiration)
+	if ttl < 0 {
+		ttl = 0
 	}
+	metrics.SetGauge([]string{"agent", "auth", "token", "ttl"}, float32(ttl.Seconds()))
 }
 
 // agentBackoff tracks exponential backoff state.
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/api"
@@ -21,6 +22,14 @@ type certMethod struct {
 	clientCert string
 	clientKey  string
 
+	// enroll is set when the client certificate is obtained from PKI
+	enroll      *enrollConfig
+	renewerOnce sync.Once
+	doneCh      chan struct{}
+
+	// l protects client and the certificate files during enrollment
+	l sync.Mutex
+
 	// Client is the cached client to use if cert info was provided.
 	client *api.Client
 }
@@ -38,6 +47,7 @@ func NewCertAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
 	c := &certMethod{
 		logger:    conf.Logger,
 		mountPath: conf.MountPath,
+		doneCh:    make(chan struct{}),
 	}
 
 	if conf.Config != nil {
@@ -73,6 +83,14 @@ func NewCertAuthMethod(conf *auth.AuthConfig) (auth.AuthMethod, error) {
 				return nil, errors.New("could not convert 'cert_key' config value to string")
 			}
 		}
+
+		var err error
+		if c.enroll, err = parseEnrollConfig(conf.Config); err != nil {
+			return nil, err
+		}
+		if c.enroll != nil && (c.clientCert == "" || c.clientKey == "") {
+			return nil, errors.New("'client_cert' and 'client_key' must be set to the files holding the enrolled certificate")
+		}
 	}
 
 	return c, nil
@@ -96,14 +114,30 @@ func (c *certMethod) NewCreds() chan struct{} {
 
 func (c *certMethod) CredSuccess() {}
 
-func (c *certMethod) Shutdown() {}
+func (c *certMethod) Shutdown() {
+	close(c.doneCh)
+}
 
 // AuthClient uses the existing client's address and returns a new client with
 // the auto-auth method's certificate information if that's provided in its
-// config map.
+// config map. When enrollment is configured, the certificate is enrolled or
+// renewed first if needed, and then renewed in the background before it
+// expires.
 func (c *certMethod) AuthClient(client *api.Client) (*api.Client, error) {
 	c.logger.Trace("deriving auth client to use")
 
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.enroll != nil {
+		if err := c.ensureCertificate(client); err != nil {
+			return nil, err
+		}
+		c.renewerOnce.Do(func() {
+			go c.runRenewer(client)
+		})
+	}
+
 	clientToAuth := client
 
 	if c.caCert != "" || (c.clientKey != "" && c.clientCert != "") {
@@ -112,31 +146,11 @@ func (c *certMethod) AuthClient(client *api.Client) (*api.Client, error) {
 			return c.client, nil
 		}
 
-		config := api.DefaultConfig()
-		if config.Error != nil {
-			return nil, config.Error
-		}
-		config.Address = client.Address()
-
-		t := &api.TLSConfig{
-			CACert:     c.caCert,
-			ClientCert: c.clientCert,
-			ClientKey:  c.clientKey,
-		}
-
-		// Setup TLS config
-		if err := config.ConfigureTLS(t); err != nil {
-			return nil, err
-		}
-
 		var err error
-		clientToAuth, err = api.NewClient(config)
+		clientToAuth, err = c.newTLSClient(client)
 		if err != nil {
 			return nil, err
 		}
-		if ns := client.Headers().Get(consts.NamespaceHeaderName); ns != "" {
-			clientToAuth.SetNamespace(ns)
-		}
 
 		// Cache the client for future use
 		c.client = clientToAuth
@@ -144,3 +158,33 @@ func (c *certMethod) AuthClient(client *api.Client) (*api.Client, error) {
 
 	return clientToAuth, nil
 }
+
+// newTLSClient returns a client for the address and namespace of client,
+// using the configured certificates.
+func (c *certMethod) newTLSClient(client *api.Client) (*api.Client, error) {
+	config := api.DefaultConfig()
+	if config.Error != nil {
+		return nil, config.Error
+	}
+	config.Address = client.Address()
+
+	t := &api.TLSConfig{
+		CACert:     c.caCert,
+		ClientCert: c.clientCert,
+		ClientKey:  c.clientKey,
+	}
+
+	// Setup TLS config
+	if err := config.ConfigureTLS(t); err != nil {
+		return nil, err
+	}
+
+	tlsClient, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if ns := client.Headers().Get(consts.NamespaceHeaderName); ns != "" {
+		tlsClient.SetNamespace(ns)
+	}
+	return tlsClient, nil
+}
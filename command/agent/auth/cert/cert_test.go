@@ -2,10 +2,23 @@ package cert
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/api"
@@ -130,3 +143,131 @@ func TestCertAuthMethod_AuthClient_withCerts(t *testing.T) {
 		t.Fatal("expected client from AuthClient to return back a cached client")
 	}
 }
+
+func TestCertAuthMethod_Enroll(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "agent.example.com"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+
+	var enrollments int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/pki/issue/agent" || r.Header.Get("X-Vault-Token") != "bootstrap-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		enrollments++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"certificate":   certPEM,
+				"private_key":   keyPEM,
+				"serial_number": "01",
+			},
+		})
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "bootstrap-token")
+	if err := os.WriteFile(tokenFile, []byte("bootstrap-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &auth.AuthConfig{
+		Logger:    hclog.NewNullLogger(),
+		MountPath: "auth/cert",
+		Config: map[string]interface{}{
+			"client_cert":          filepath.Join(dir, "agent.crt"),
+			"client_key":           filepath.Join(dir, "agent.key"),
+			"enroll_path":          "pki/issue/agent",
+			"enroll_common_name":   "agent.example.com",
+			"bootstrap_token_file": tokenFile,
+		},
+	}
+	method, err := NewCertAuthMethod(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer method.Shutdown()
+
+	client, err := api.NewClient(&api.Config{Address: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := method.(auth.AuthMethodWithClient).AuthClient(client); err != nil {
+		t.Fatal(err)
+	}
+	if enrollments != 1 {
+		t.Fatalf("expected one enrollment, got %d", enrollments)
+	}
+	if _, err := tls.LoadX509KeyPair(filepath.Join(dir, "agent.crt"), filepath.Join(dir, "agent.key")); err != nil {
+		t.Fatalf("enrolled pair not written: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "agent.key")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("bad key file: %v %v", info, err)
+	}
+
+	// The certificate is not due for renewal yet
+	if _, err := method.(auth.AuthMethodWithClient).AuthClient(client); err != nil {
+		t.Fatal(err)
+	}
+	if enrollments != 1 {
+		t.Fatalf("expected no new enrollment, got %d", enrollments)
+	}
+
+	cm := method.(*certMethod)
+	cert, err := cm.loadCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewal := cm.enroll.renewalTime(cert); renewal.Before(cert.NotAfter.Add(-21*time.Minute)) || renewal.After(cert.NotAfter.Add(-20*time.Minute)) {
+		t.Fatalf("expected renewal after two thirds of the lifetime, got %s", renewal)
+	}
+}
+
+func TestCertAuthMethod_Enroll_Config(t *testing.T) {
+	for name, conf := range map[string]map[string]interface{}{
+		"no output files": {
+			"enroll_path":        "pki/issue/agent",
+			"enroll_common_name": "agent.example.com",
+		},
+		"no common name": {
+			"client_cert": "agent.crt",
+			"client_key":  "agent.key",
+			"enroll_path": "pki/issue/agent",
+		},
+		"bad renew_before": {
+			"client_cert":         "agent.crt",
+			"client_key":          "agent.key",
+			"enroll_path":         "pki/issue/agent",
+			"enroll_common_name":  "agent.example.com",
+			"enroll_renew_before": "soon",
+		},
+	} {
+		_, err := NewCertAuthMethod(&auth.AuthConfig{
+			Logger:    hclog.NewNullLogger(),
+			MountPath: "auth/cert",
+			Config:    conf,
+		})
+		if err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package cert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	// enrollRetryInterval is how long the renewer waits after a failed
+	// re-enrollment before trying again
+	enrollRetryInterval = time.Minute
+
+	enrollTimeout = 30 * time.Second
+)
+
+// enrollConfig describes how the client certificate is obtained from a PKI
+// issue endpoint: once with a bootstrap token, then with the certificate
+// itself, before it expires.
+type enrollConfig struct {
+	path               string
+	commonName         string
+	altNames           string
+	ttl                string
+	bootstrapTokenFile string
+	renewBefore        time.Duration
+}
+
+func parseEnrollConfig(config map[string]interface{}) (*enrollConfig, error) {
+	if _, ok := config["enroll_path"]; !ok {
+		return nil, nil
+	}
+
+	e := &enrollConfig{}
+	stringFields := map[string]*string{
+		"enroll_path":          &e.path,
+		"enroll_common_name":   &e.commonName,
+		"enroll_alt_names":     &e.altNames,
+		"enroll_ttl":           &e.ttl,
+		"bootstrap_token_file": &e.bootstrapTokenFile,
+	}
+	for key, field := range stringFields {
+		raw, ok := config[key]
+		if !ok {
+			continue
+		}
+		if *field, ok = raw.(string); !ok {
+			return nil, fmt.Errorf("could not convert '%s' config value to string", key)
+		}
+	}
+	if e.path == "" {
+		return nil, errors.New("'enroll_path' value is empty")
+	}
+	e.path = strings.Trim(e.path, "/")
+	if e.commonName == "" {
+		return nil, errors.New("'enroll_common_name' must be set with 'enroll_path'")
+	}
+
+	if raw, ok := config["enroll_renew_before"]; ok {
+		var err error
+		if e.renewBefore, err = parseutil.ParseDurationSecond(raw); err != nil {
+			return nil, fmt.Errorf("error parsing 'enroll_renew_before': %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// renewalTime returns when cert must be renewed: renew_before ahead of its
+// expiration, or once two thirds of its lifetime have passed.
+func (e *enrollConfig) renewalTime(cert *x509.Certificate) time.Time {
+	renewBefore := e.renewBefore
+	if renewBefore == 0 {
+		renewBefore = cert.NotAfter.Sub(cert.NotBefore) / 3
+	}
+	return cert.NotAfter.Add(-renewBefore)
+}
+
+// loadCertificate parses the leaf of the client certificate file, after
+// checking that it matches the client key file.
+func (c *certMethod) loadCertificate() (*x509.Certificate, error) {
+	pair, err := tls.LoadX509KeyPair(c.clientCert, c.clientKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(pair.Certificate[0])
+}
+
+// ensureCertificate enrolls the client certificate when it is missing or
+// expired, using the bootstrap token, and re-enrolls it when it is due for
+// renewal. The lock must be held.
+func (c *certMethod) ensureCertificate(client *api.Client) error {
+	cert, err := c.loadCertificate()
+	switch {
+	case err != nil:
+		c.logger.Info("no usable client certificate, enrolling with the bootstrap token", "error", err)
+		return c.bootstrapEnroll(client)
+	case time.Now().After(cert.NotAfter):
+		c.logger.Info("client certificate expired, enrolling with the bootstrap token")
+		return c.bootstrapEnroll(client)
+	case time.Now().After(c.enroll.renewalTime(cert)):
+		return c.reenroll(client)
+	}
+	return nil
+}
+
+func (c *certMethod) bootstrapEnroll(client *api.Client) error {
+	if c.enroll.bootstrapTokenFile == "" {
+		return errors.New("a client certificate must be enrolled but no 'bootstrap_token_file' is set")
+	}
+	token, err := ioutil.ReadFile(c.enroll.bootstrapTokenFile)
+	if err != nil {
+		return fmt.Errorf("error reading bootstrap token: %w", err)
+	}
+
+	enrollClient, err := client.CloneWithHeaders()
+	if err != nil {
+		return err
+	}
+	enrollClient.SetToken(strings.TrimSpace(string(token)))
+
+	return c.enrollWith(enrollClient)
+}
+
+// reenroll renews the client certificate while it is still valid, with a
+// token obtained by logging in with it. The token is revoked afterwards.
+func (c *certMethod) reenroll(client *api.Client) error {
+	c.logger.Info("renewing client certificate")
+
+	ctx, cancel := context.WithTimeout(context.Background(), enrollTimeout)
+	defer cancel()
+
+	tlsClient, err := c.newTLSClient(client)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{}
+	if c.name != "" {
+		data["name"] = c.name
+	}
+	secret, err := tlsClient.Logical().WriteWithContext(ctx, c.mountPath+"/login", data)
+	if err != nil {
+		return fmt.Errorf("error logging in to renew the client certificate: %w", err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return errors.New("no token returned when logging in to renew the client certificate")
+	}
+	tlsClient.SetToken(secret.Auth.ClientToken)
+	defer func() {
+		if err := tlsClient.Auth().Token().RevokeSelfWithContext(ctx, ""); err != nil {
+			c.logger.Warn("failed to revoke the token used to renew the client certificate", "error", err)
+		}
+	}()
+
+	return c.enrollWith(tlsClient)
+}
+
+// enrollWith issues a certificate from the PKI issue endpoint and replaces
+// the client certificate and key files with it.
+func (c *certMethod) enrollWith(client *api.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), enrollTimeout)
+	defer cancel()
+
+	data := map[string]interface{}{
+		"common_name": c.enroll.commonName,
+		"format":      "pem",
+	}
+	if c.enroll.altNames != "" {
+		data["alt_names"] = c.enroll.altNames
+	}
+	if c.enroll.ttl != "" {
+		data["ttl"] = c.enroll.ttl
+	}
+
+	secret, err := client.Logical().WriteWithContext(ctx, c.enroll.path, data)
+	if err != nil {
+		return fmt.Errorf("error enrolling client certificate: %w", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return errors.New("no certificate returned by the enrollment")
+	}
+
+	certificate, _ := secret.Data["certificate"].(string)
+	privateKey, _ := secret.Data["private_key"].(string)
+	if certificate == "" || privateKey == "" {
+		return errors.New("the enrollment did not return a certificate and private key")
+	}
+	chain := []string{strings.TrimSpace(certificate)}
+	if caChain, ok := secret.Data["ca_chain"].([]interface{}); ok {
+		for _, ca := range caChain {
+			if ca, ok := ca.(string); ok {
+				chain = append(chain, strings.TrimSpace(ca))
+			}
+		}
+	}
+
+	// Each file is replaced atomically; if only the key is replaced, the
+	// pair no longer loads and the next check enrolls a new one
+	if err := writeFileAtomic(c.clientKey, []byte(strings.TrimSpace(privateKey)+"\n"), 0o600); err != nil {
+		return fmt.Errorf("error writing client key: %w", err)
+	}
+	if err := writeFileAtomic(c.clientCert, []byte(strings.Join(chain, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("error writing client certificate: %w", err)
+	}
+
+	// Drop the client using the previous certificate
+	c.client = nil
+
+	c.logger.Info("enrolled client certificate", "serial_number", secret.Data["serial_number"])
+	return nil
+}
+
+// runRenewer re-enrolls the client certificate when it is due, until the
+// method is shut down.
+func (c *certMethod) runRenewer(client *api.Client) {
+	renewed := false
+	for {
+		c.l.Lock()
+		wait := enrollRetryInterval
+		if cert, err := c.loadCertificate(); err == nil {
+			wait = time.Until(c.enroll.renewalTime(cert))
+		}
+		c.l.Unlock()
+
+		if renewed && wait <= 0 {
+			c.logger.Warn("renewed client certificate is already due for renewal, check enroll_renew_before and enroll_ttl")
+			wait = enrollRetryInterval
+		}
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-c.doneCh:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		c.l.Lock()
+		err := c.ensureCertificate(client)
+		c.l.Unlock()
+		renewed = err == nil
+		if err != nil {
+			c.logger.Error("error renewing client certificate", "error", err, "retry", enrollRetryInterval)
+			select {
+			case <-c.doneCh:
+				return
+			case <-time.After(enrollRetryInterval):
+			}
+		}
+	}
+}
+
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -95,6 +95,10 @@ type LeaseCache struct {
 	// shuttingDown is used to determine if cache needs to be evicted or not
 	// when the context is cancelled
 	shuttingDown atomic.Bool
+
+	// staticSecretCacheTTL is the time KV v2 reads are cached for; zero
+	// disables their caching
+	staticSecretCacheTTL time.Duration
 }
 
 // LeaseCacheConfig is the configuration for initializing a new
@@ -105,6 +109,10 @@ type LeaseCacheConfig struct {
 	Proxier     Proxier
 	Logger      hclog.Logger
 	Storage     *cacheboltdb.BoltStorage
+
+	// StaticSecretCacheTTL enables caching of KV v2 reads for the given
+	// duration
+	StaticSecretCacheTTL time.Duration
 }
 
 type inflightRequest struct {
@@ -156,6 +164,8 @@ func NewLeaseCache(conf *LeaseCacheConfig) (*LeaseCache, error) {
 		idLocks:       locksutil.CreateLocks(),
 		inflightCache: gocache.New(gocache.NoExpiration, gocache.NoExpiration),
 		ps:            conf.Storage,
+
+		staticSecretCacheTTL: conf.StaticSecretCacheTTL,
 	}, nil
 }
 
@@ -282,6 +292,18 @@ func (c *LeaseCache) Send(ctx context.Context, req *SendRequest) (*SendResponse,
 		return resp, err
 	}
 
+	// Writes to a KV v2 secret through the agent invalidate its cached reads
+	if c.staticSecretCacheTTL > 0 && resp.Response.StatusCode < 300 {
+		switch req.Request.Method {
+		case http.MethodGet, "LIST":
+		default:
+			if err := c.invalidateStaticSecrets(req); err != nil {
+				c.logger.Error("failed to invalidate static secrets", "error", err)
+				return nil, err
+			}
+		}
+	}
+
 	// If this is a non-2xx or if the returned response does not contain JSON payload,
 	// we skip caching
 	if resp.Response.StatusCode >= 300 || resp.Response.Header.Get("Content-Type") != "application/json" {
@@ -327,6 +349,11 @@ func (c *LeaseCache) Send(ctx context.Context, req *SendRequest) (*SendResponse,
 		return resp, nil
 	}
 
+	// KV v2 reads are cached for a fixed time if enabled
+	if c.staticSecretCacheTTL > 0 && isStaticSecret(req, secret) {
+		return c.cacheStaticSecret(req, resp, index)
+	}
+
 	// Short-circuit if the secret is not renewable
 	tokenRenewable, err := secret.TokenIsRenewable()
 	if err != nil {
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/cache/cachememdb"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+)
+
+// staticSecretType is the index type of cached KV v2 reads. These entries are
+// held in memory only and are never persisted.
+const staticSecretType = "static-secret"
+
+// kvV2WritePaths are the KV v2 API paths of a secret whose writes change the
+// response of a read of its data path.
+var kvV2WritePaths = []string{"/data/", "/metadata/", "/delete/", "/undelete/", "/destroy/"}
+
+// isStaticSecret returns true if the response is a KV v2 read of a secret,
+// which carries neither a lease nor a token that the agent could track.
+func isStaticSecret(req *SendRequest, secret *api.Secret) bool {
+	if req.Request.Method != http.MethodGet || secret.LeaseID != "" || secret.Auth != nil {
+		return false
+	}
+	if _, ok := secret.Data["data"]; !ok {
+		return false
+	}
+	metadata, ok := secret.Data["metadata"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = metadata["version"]
+	return ok
+}
+
+// cacheStaticSecret stores a KV v2 read in the cache until it is invalidated
+// by a write through the agent, by a cache clear, by the revocation of its
+// token if the agent manages it, or at the latest after the static secret
+// cache TTL.
+func (c *LeaseCache) cacheStaticSecret(req *SendRequest, resp *SendResponse, index *cachememdb.Index) (*SendResponse, error) {
+	// Tie the entry to the lifetime of its token, if the agent manages it
+	var parentCtx context.Context
+	entry, err := c.db.Get(cachememdb.IndexNameToken, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		parentCtx = entry.RenewCtxInfo.Ctx
+	}
+
+	// Serialize the response to store it in the cached index
+	var respBytes bytes.Buffer
+	if err := resp.Response.Write(&respBytes); err != nil {
+		c.logger.Error("failed to serialize response", "error", err)
+		return nil, err
+	}
+
+	// Reset the response body for upper layers to read
+	if resp.Response.Body != nil {
+		resp.Response.Body.Close()
+	}
+	resp.Response.Body = ioutil.NopCloser(bytes.NewReader(resp.ResponseBody))
+
+	index.Response = respBytes.Bytes()
+	index.RenewCtxInfo = c.createCtxInfo(parentCtx)
+	index.RequestMethod = req.Request.Method
+	index.RequestToken = req.Token
+	index.RequestHeader = req.Request.Header
+	index.Type = staticSecretType
+
+	c.logger.Debug("storing static secret into the cache", "method", req.Request.Method, "path", req.Request.URL.Path)
+	if err := c.db.Set(index); err != nil {
+		c.logger.Error("failed to cache the static secret", "error", err)
+		return nil, err
+	}
+
+	go c.expireStaticSecret(index)
+
+	return resp, nil
+}
+
+// expireStaticSecret evicts a cached static secret once the static secret
+// cache TTL has passed or its context is canceled.
+func (c *LeaseCache) expireStaticSecret(index *cachememdb.Index) {
+	timer := time.NewTimer(c.staticSecretCacheTTL)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-index.RenewCtxInfo.Ctx.Done():
+		if c.shuttingDown.Load() {
+			c.logger.Trace("not evicting static secret from cache during shutdown", "id", index.ID, "path", index.RequestPath)
+			return
+		}
+	}
+	index.RenewCtxInfo.CancelFunc()
+
+	// The entry may already have been invalidated and replaced by a fresh
+	// response to the same request
+	current, err := c.db.Get(cachememdb.IndexNameID, index.ID)
+	if err != nil {
+		c.logger.Error("failed to look up static secret", "id", index.ID, "error", err)
+		return
+	}
+	if current != index {
+		return
+	}
+
+	c.logger.Debug("evicting static secret from cache", "id", index.ID, "path", index.RequestPath)
+	if err := c.db.Evict(cachememdb.IndexNameID, index.ID); err != nil {
+		c.logger.Error("failed to evict static secret", "id", index.ID, "error", err)
+	}
+}
+
+// invalidateStaticSecrets evicts the cached reads of the KV v2 secret that
+// the request writes to.
+func (c *LeaseCache) invalidateStaticSecrets(req *SendRequest) error {
+	namespace := req.Request.Header.Get(consts.NamespaceHeaderName)
+	if namespace == "" {
+		namespace = "root/"
+	}
+
+	for _, path := range kvV2DataPaths(req.Request.URL.Path) {
+		indexes, err := c.db.GetByPrefix(cachememdb.IndexNameRequestPath, namespace, path)
+		if err != nil {
+			return err
+		}
+		for _, index := range indexes {
+			if index.Type != staticSecretType || index.RequestPath != path {
+				continue
+			}
+			c.logger.Debug("invalidating static secret", "id", index.ID, "path", index.RequestPath)
+			if err := c.db.Evict(cachememdb.IndexNameID, index.ID); err != nil {
+				return err
+			}
+			index.RenewCtxInfo.CancelFunc()
+		}
+	}
+
+	return nil
+}
+
+// kvV2DataPaths returns the KV v2 data paths that the given request path may
+// refer to, e.g. "/v1/secret/data/foo" for "/v1/secret/metadata/foo".
+func kvV2DataPaths(path string) []string {
+	var paths []string
+	for _, writePath := range kvV2WritePaths {
+		for i := 0; i < len(path); {
+			j := strings.Index(path[i:], writePath)
+			if j < 0 {
+				break
+			}
+			j += i
+			paths = append(paths, path[:j]+"/data/"+path[j+len(writePath):])
+			i = j + 1
+		}
+	}
+	return paths
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/command/agent/cache/cachememdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaseCache_StaticSecrets(t *testing.T) {
+	kvRead := func(value string, version int) *SendResponse {
+		return newTestSendResponse(http.StatusOK, fmt.Sprintf(`{"data": {"data": {"value": %q}, "metadata": {"version": %d}}}`, value, version))
+	}
+	responses := []*SendResponse{
+		kvRead("foo", 1),
+		newTestSendResponse(http.StatusOK, `{"data": {"version": 2}}`),
+		kvRead("bar", 2),
+		newTestSendResponse(http.StatusNoContent, ""),
+		kvRead("baz", 3),
+		kvRead("qux", 3),
+	}
+
+	lc := testNewLeaseCache(t, responses)
+	lc.staticSecretCacheTTL = time.Hour
+
+	send := func(method, path string) *SendResponse {
+		t.Helper()
+		resp, err := lc.Send(context.Background(), &SendRequest{
+			Token:   "token",
+			Request: httptest.NewRequest(method, "http://example.com"+path, nil),
+		})
+		require.NoError(t, err)
+		return resp
+	}
+	cached := func(path string) []*cachememdb.Index {
+		t.Helper()
+		indexes, err := lc.db.GetByPrefix(cachememdb.IndexNameRequestPath, "root/", path)
+		require.NoError(t, err)
+		return indexes
+	}
+
+	// Reads are served from the cache once cached
+	resp := send("GET", "/v1/secret/data/foo")
+	require.Nil(t, resp.CacheMeta)
+	resp = send("GET", "/v1/secret/data/foo")
+	require.NotNil(t, resp.CacheMeta)
+	require.True(t, resp.CacheMeta.Hit)
+	require.Contains(t, string(resp.ResponseBody), `"foo"`)
+	require.Len(t, cached("/v1/secret/data/foo"), 1)
+
+	// A write through the agent invalidates the cached read
+	send("PUT", "/v1/secret/data/foo")
+	require.Empty(t, cached("/v1/secret/data/foo"))
+	resp = send("GET", "/v1/secret/data/foo")
+	require.Nil(t, resp.CacheMeta)
+	require.Contains(t, string(resp.ResponseBody), `"bar"`)
+
+	// So does a write to the metadata of the secret
+	send("DELETE", "/v1/secret/metadata/foo")
+	require.Empty(t, cached("/v1/secret/data/foo"))
+	resp = send("GET", "/v1/secret/data/foo")
+	require.Nil(t, resp.CacheMeta)
+	require.Contains(t, string(resp.ResponseBody), `"baz"`)
+
+	// A cache clear of the request path evicts the cached read
+	require.NoError(t, lc.handleCacheClear(context.Background(), &cacheClearInput{
+		Type:        "request_path",
+		RequestPath: "/v1/secret/data/foo",
+	}))
+	require.Eventually(t, func() bool {
+		return len(cached("/v1/secret/data/foo")) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Cached reads expire after the static secret cache TTL
+	lc.staticSecretCacheTTL = 50 * time.Millisecond
+	resp = send("GET", "/v1/secret/data/foo")
+	require.Contains(t, string(resp.ResponseBody), `"qux"`)
+	require.Len(t, cached("/v1/secret/data/foo"), 1)
+	require.Eventually(t, func() bool {
+		return len(cached("/v1/secret/data/foo")) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLeaseCache_StaticSecretsDisabled(t *testing.T) {
+	responses := []*SendResponse{
+		newTestSendResponse(http.StatusOK, `{"data": {"data": {"value": "foo"}, "metadata": {"version": 1}}}`),
+		newTestSendResponse(http.StatusOK, `{"data": {"data": {"value": "foo"}, "metadata": {"version": 1}}}`),
+	}
+	lc := testNewLeaseCache(t, responses)
+
+	for i := 0; i < 2; i++ {
+		resp, err := lc.Send(context.Background(), &SendRequest{
+			Token:   "token",
+			Request: httptest.NewRequest("GET", "http://example.com/v1/secret/data/foo", nil),
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp.CacheMeta)
+	}
+}
+
+func TestKVV2DataPaths(t *testing.T) {
+	require.Equal(t, []string{"/v1/secret/data/foo"}, kvV2DataPaths("/v1/secret/data/foo"))
+	require.Equal(t, []string{"/v1/secret/data/foo"}, kvV2DataPaths("/v1/secret/destroy/foo"))
+	require.Equal(t, []string{
+		"/v1/kv/data/metadata/foo",
+		"/v1/kv/metadata/data/foo",
+	}, kvV2DataPaths("/v1/kv/metadata/metadata/foo"))
+	require.Empty(t, kvV2DataPaths("/v1/sys/health"))
+}
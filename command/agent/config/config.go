@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/logging"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/mitchellh/mapstructure"
@@ -28,6 +30,7 @@ type Config struct {
 	Cache                       *Cache                     `hcl:"cache"`
 	Vault                       *Vault                     `hcl:"vault"`
 	TemplateConfig              *TemplateConfig            `hcl:"template_config"`
+	DNS                         *DNS                       `hcl:"-"`
 	Templates                   []*ctconfig.TemplateConfig `hcl:"templates"`
 	DisableIdleConns            []string                   `hcl:"disable_idle_connections"`
 	DisableIdleConnsCaching     bool                       `hcl:"-"`
@@ -38,13 +41,54 @@ type Config struct {
 	DisableKeepAlivesTemplating bool                       `hcl:"-"`
 	DisableKeepAlivesAutoAuth   bool                       `hcl:"-"`
 	LogFile                     string                     `hcl:"log_file"`
+	LogLevels                   map[string]string          `hcl:"log_levels"`
 }
 
+const (
+	// Subsystems of Agent, as named in disable_idle_connections,
+	// disable_keep_alives and log_levels
+	SubsystemAutoAuth   = "auto-auth"
+	SubsystemCaching    = "caching"
+	SubsystemTemplating = "templating"
+)
+
 const (
 	DisableIdleConnsEnv  = "VAULT_AGENT_DISABLE_IDLE_CONNECTIONS"
 	DisableKeepAlivesEnv = "VAULT_AGENT_DISABLE_KEEP_ALIVES"
 )
 
+const (
+	// ListenerRoleDefault listeners serve the whole Agent API
+	ListenerRoleDefault = "default"
+
+	// ListenerRoleMetricsOnly listeners only serve the metrics endpoint, and
+	// don't require the cache to be enabled
+	ListenerRoleMetricsOnly = "metrics_only"
+)
+
+// APIListeners returns the listeners serving the Agent API, as opposed to
+// the ones only serving metrics.
+func (c *Config) APIListeners() []*configutil.Listener {
+	var listeners []*configutil.Listener
+	for _, l := range c.Listeners {
+		if l.Role != ListenerRoleMetricsOnly {
+			listeners = append(listeners, l)
+		}
+	}
+	return listeners
+}
+
+// MetricsListeners returns the listeners only serving metrics.
+func (c *Config) MetricsListeners() []*configutil.Listener {
+	var listeners []*configutil.Listener
+	for _, l := range c.Listeners {
+		if l.Role == ListenerRoleMetricsOnly {
+			listeners = append(listeners, l)
+		}
+	}
+	return listeners
+}
+
 func (c *Config) Prune() {
 	for _, l := range c.Listeners {
 		l.RawConfig = nil
@@ -98,6 +142,11 @@ type Cache struct {
 	WhenInconsistent    string          `hcl:"when_inconsistent"`
 	Persist             *Persist        `hcl:"persist"`
 	InProcDialer        transportDialer `hcl:"-"`
+
+	// StaticSecretCacheTTL enables caching of KV v2 reads for the given
+	// duration; zero disables it.
+	StaticSecretCacheTTLRaw interface{}   `hcl:"static_secret_cache_ttl"`
+	StaticSecretCacheTTL    time.Duration `hcl:"-"`
 }
 
 // Persist contains configuration needed for persistent caching
@@ -147,6 +196,35 @@ type Sink struct {
 	Config     map[string]interface{}
 }
 
+// DNS contains the configuration of the DNS listener, which serves records
+// derived from Vault data
+type DNS struct {
+	Address string        `hcl:"address"`
+	Zone    string        `hcl:"zone"`
+	TTLRaw  interface{}   `hcl:"ttl"`
+	TTL     time.Duration `hcl:"-"`
+	Records []*DNSRecord  `hcl:"-"`
+}
+
+// DNSRecord is a record of the DNS listener, built from the response of a
+// Vault read
+type DNSRecord struct {
+	Name               string
+	Type               string        `hcl:"type"`
+	Path               string        `hcl:"path"`
+	RefreshIntervalRaw interface{}   `hcl:"refresh_interval"`
+	RefreshInterval    time.Duration `hcl:"-"`
+
+	// Fields are the fields of the response published in TXT records
+	Fields []string `hcl:"fields"`
+
+	// TargetField, PortField, Priority and Weight build SRV records
+	TargetField string `hcl:"target_field"`
+	PortField   string `hcl:"port_field"`
+	Priority    int    `hcl:"priority"`
+	Weight      int    `hcl:"weight"`
+}
+
 // TemplateConfig defines global behaviors around template
 type TemplateConfig struct {
 	ExitOnRetryFailure       bool          `hcl:"exit_on_retry_failure"`
@@ -204,8 +282,15 @@ func LoadConfig(path string) (*Config, error) {
 	}
 
 	// Pruning custom headers for Agent for now
-	for _, ln := range sharedConfig.Listeners {
+	for i, ln := range sharedConfig.Listeners {
 		ln.CustomResponseHeaders = nil
+
+		ln.Role = strings.ToLower(ln.Role)
+		switch ln.Role {
+		case "", ListenerRoleDefault, ListenerRoleMetricsOnly:
+		default:
+			return nil, fmt.Errorf("listeners.%d: unsupported listener role %q", i, ln.Role)
+		}
 	}
 
 	result.SharedConfig = sharedConfig
@@ -231,8 +316,12 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("error parsing 'template': %w", err)
 	}
 
+	if err := parseDNS(result, list); err != nil {
+		return nil, fmt.Errorf("error parsing 'dns': %w", err)
+	}
+
 	if result.Cache != nil {
-		if len(result.Listeners) < 1 && len(result.Templates) < 1 {
+		if len(result.APIListeners()) < 1 && len(result.Templates) < 1 {
 			return nil, fmt.Errorf("enabling the cache requires at least 1 template or 1 listener to be defined")
 		}
 
@@ -249,9 +338,17 @@ func LoadConfig(path string) (*Config, error) {
 	if result.AutoAuth != nil {
 		if len(result.AutoAuth.Sinks) == 0 &&
 			(result.Cache == nil || !result.Cache.UseAutoAuthToken) &&
-			len(result.Templates) == 0 {
-			return nil, fmt.Errorf("auto_auth requires at least one sink or at least one template or cache.use_auto_auth_token=true")
+			len(result.Templates) == 0 &&
+			result.DNS == nil {
+			return nil, fmt.Errorf("auto_auth requires at least one sink or at least one template or a dns block or cache.use_auto_auth_token=true")
 		}
+		if result.DNS != nil && result.AutoAuth.Method.WrapTTL > 0 {
+			return nil, fmt.Errorf("dns is configured and auto_auth uses wrapping")
+		}
+	}
+
+	if result.DNS != nil && result.AutoAuth == nil {
+		return nil, fmt.Errorf("dns is configured but auto_auth is not configured")
 	}
 
 	err = parseVault(result, list)
@@ -274,6 +371,17 @@ func LoadConfig(path string) (*Config, error) {
 		result.Vault.Retry.NumRetries = 0
 	}
 
+	for subsystem, level := range result.LogLevels {
+		switch subsystem {
+		case SubsystemAutoAuth, SubsystemCaching, SubsystemTemplating:
+		default:
+			return nil, fmt.Errorf("unknown log_levels subsystem: %s", subsystem)
+		}
+		if _, err := logging.ParseLogLevel(level); err != nil {
+			return nil, fmt.Errorf("error parsing log_levels.%s: %w", subsystem, err)
+		}
+	}
+
 	if disableIdleConnsEnv := os.Getenv(DisableIdleConnsEnv); disableIdleConnsEnv != "" {
 		result.DisableIdleConns, err = parseutil.ParseCommaStringSlice(strings.ToLower(disableIdleConnsEnv))
 		if err != nil {
@@ -426,6 +534,12 @@ func parseCache(result *Config, list *ast.ObjectList) error {
 			}
 		}
 	}
+	if c.StaticSecretCacheTTLRaw != nil {
+		if c.StaticSecretCacheTTL, err = parseutil.ParseDurationSecond(c.StaticSecretCacheTTLRaw); err != nil {
+			return fmt.Errorf("error parsing static_secret_cache_ttl: %w", err)
+		}
+		c.StaticSecretCacheTTLRaw = nil
+	}
 	result.Cache = &c
 
 	subs, ok := item.Val.(*ast.ObjectType)
@@ -653,6 +767,116 @@ func parseSinks(result *Config, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseDNS(result *Config, list *ast.ObjectList) error {
+	name := "dns"
+
+	dnsList := list.Filter(name)
+	if len(dnsList.Items) == 0 {
+		return nil
+	}
+	if len(dnsList.Items) > 1 {
+		return fmt.Errorf("at most one %q block is allowed", name)
+	}
+
+	item := dnsList.Items[0]
+
+	var d DNS
+	if err := hcl.DecodeObject(&d, item.Val); err != nil {
+		return err
+	}
+
+	if d.Address == "" {
+		return errors.New("dns address must be specified")
+	}
+	if d.Zone == "" {
+		return errors.New("dns zone must be specified")
+	}
+	// Zones are handled as fully qualified names
+	d.Zone = strings.ToLower(strings.TrimSuffix(d.Zone, ".") + ".")
+
+	d.TTL = 30 * time.Second
+	if d.TTLRaw != nil {
+		var err error
+		if d.TTL, err = parseutil.ParseDurationSecond(d.TTLRaw); err != nil {
+			return err
+		}
+		d.TTLRaw = nil
+	}
+
+	subs, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("could not parse %q as an object", name)
+	}
+	if err := parseDNSRecords(&d, subs.List); err != nil {
+		return fmt.Errorf("error parsing 'record' stanzas: %w", err)
+	}
+	if len(d.Records) == 0 {
+		return errors.New("at least one record must be specified")
+	}
+
+	result.DNS = &d
+	return nil
+}
+
+func parseDNSRecords(d *DNS, list *ast.ObjectList) error {
+	name := "record"
+
+	names := make(map[string]bool)
+	for _, item := range list.Filter(name).Items {
+		var r DNSRecord
+		if err := hcl.DecodeObject(&r, item.Val); err != nil {
+			return err
+		}
+
+		if len(item.Keys) == 1 {
+			r.Name = strings.ToLower(item.Keys[0].Token.Value().(string))
+		}
+		if r.Name == "" {
+			return errors.New("record name must be specified")
+		}
+		prefix := fmt.Sprintf("record.%s", r.Name)
+
+		r.Type = strings.ToUpper(r.Type)
+		if names[r.Name+"/"+r.Type] {
+			return multierror.Prefix(errors.New("record defined more than once"), prefix)
+		}
+		names[r.Name+"/"+r.Type] = true
+
+		if r.Path == "" {
+			return multierror.Prefix(errors.New("path must be specified"), prefix)
+		}
+
+		switch r.Type {
+		case "TXT":
+		case "SRV":
+			if r.TargetField == "" || r.PortField == "" {
+				return multierror.Prefix(errors.New("'target_field' and 'port_field' must be specified for SRV records"), prefix)
+			}
+			if r.Priority < 0 || r.Priority > math.MaxUint16 || r.Weight < 0 || r.Weight > math.MaxUint16 {
+				return multierror.Prefix(errors.New("'priority' and 'weight' must be between 0 and 65535"), prefix)
+			}
+		default:
+			return multierror.Prefix(fmt.Errorf("unsupported record type %q", r.Type), prefix)
+		}
+
+		r.RefreshInterval = time.Minute
+		if r.RefreshIntervalRaw != nil {
+			var err error
+			if r.RefreshInterval, err = parseutil.ParseDurationSecond(r.RefreshIntervalRaw); err != nil {
+				return multierror.Prefix(err, prefix)
+			}
+			r.RefreshIntervalRaw = nil
+		}
+		if r.RefreshInterval <= 0 {
+			return multierror.Prefix(errors.New("refresh_interval must be positive"), prefix)
+		}
+
+		d.Records = append(d.Records, &r)
+	}
+
+	return nil
+}
+
 func parseTemplateConfig(result *Config, list *ast.ObjectList) error {
 	name := "template_config"
 
@@ -411,6 +411,39 @@ func TestLoadConfigFile_AgentCache_NoAutoAuth(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFile_AgentCache_StaticSecrets(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-cache-static-secrets.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		Cache: &Cache{
+			StaticSecretCacheTTL: 5 * time.Minute,
+		},
+		SharedConfig: &configutil.SharedConfig{
+			PidFile: "./pidfile",
+			Listeners: []*configutil.Listener{
+				{
+					Type:       "tcp",
+					Address:    "127.0.0.1:8300",
+					TLSDisable: true,
+				},
+			},
+		},
+		Vault: &Vault{
+			Retry: &Retry{
+				NumRetries: 12,
+			},
+		},
+	}
+
+	config.Prune()
+	if diff := deep.Equal(config, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
 func TestLoadConfigFile_Bad_AgentCache_InconsisentAutoAuth(t *testing.T) {
 	_, err := LoadConfig("./test-fixtures/bad-config-cache-inconsistent-auto_auth.hcl")
 	if err == nil {
@@ -432,6 +465,105 @@ func TestLoadConfigFile_Bad_AgentCache_NoListeners(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFile_Bad_AgentCache_MetricsOnlyListener(t *testing.T) {
+	_, err := LoadConfig("./test-fixtures/bad-config-cache-metrics-only-listener.hcl")
+	if err == nil {
+		t.Fatal("LoadConfig should return an error when cache section present and only metrics listeners present and no templates defined")
+	}
+}
+
+func TestLoadConfigFile_MetricsListener(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-metrics-listener.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(config.APIListeners()) != 0 {
+		t.Fatalf("expected no API listener, got %#v", config.APIListeners())
+	}
+	listeners := config.MetricsListeners()
+	if len(listeners) != 1 || listeners[0].Address != "127.0.0.1:8300" {
+		t.Fatalf("expected one metrics listener, got %#v", listeners)
+	}
+}
+
+func TestLoadConfigFile_DNS(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-dns.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &DNS{
+		Address: "127.0.0.1:8600",
+		Zone:    "vault.internal.",
+		TTL:     10 * time.Second,
+		Records: []*DNSRecord{
+			{
+				Name:            "db",
+				Type:            "TXT",
+				Path:            "secret/data/db",
+				Fields:          []string{"data.username"},
+				RefreshInterval: 5 * time.Minute,
+			},
+			{
+				Name:            "_postgres._tcp",
+				Type:            "SRV",
+				Path:            "secret/data/service",
+				TargetField:     "data.host",
+				PortField:       "data.port",
+				Priority:        10,
+				RefreshInterval: time.Minute,
+			},
+		},
+	}
+	if diff := deep.Equal(config.DNS, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestLoadConfigFile_Bad_DNS(t *testing.T) {
+	for _, fixture := range []string{
+		"./test-fixtures/bad-config-dns-srv-no-port.hcl",
+		"./test-fixtures/bad-config-dns-no-auto_auth.hcl",
+	} {
+		if _, err := LoadConfig(fixture); err == nil {
+			t.Fatalf("LoadConfig should return an error for %s", fixture)
+		}
+	}
+}
+
+func TestLoadConfigFile_LogLevels(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-log-levels.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if config.LogLevel != "warn" {
+		t.Fatalf("expected log_level warn, got %q", config.LogLevel)
+	}
+	expected := map[string]string{
+		SubsystemAutoAuth:   "debug",
+		SubsystemTemplating: "error",
+	}
+	if diff := deep.Equal(config.LogLevels, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestLoadConfigFile_Bad_LogLevels(t *testing.T) {
+	_, err := LoadConfig("./test-fixtures/bad-config-log-levels.hcl")
+	if err == nil {
+		t.Fatal("LoadConfig should return an error when log_levels has an unknown subsystem")
+	}
+}
+
+func TestLoadConfigFile_Bad_ListenerRole(t *testing.T) {
+	_, err := LoadConfig("./test-fixtures/bad-config-listener-role.hcl")
+	if err == nil {
+		t.Fatal("LoadConfig should return an error when a listener has an unknown role")
+	}
+}
+
 func TestLoadConfigFile_Bad_AutoAuth_Wrapped_Multiple_Sinks(t *testing.T) {
 	_, err := LoadConfig("./test-fixtures/bad-config-auto_auth-wrapped-multiple-sinks.hcl")
 	if err == nil {
@@ -0,0 +1,11 @@
+pid_file = "./pidfile"
+
+dns {
+	address = "127.0.0.1:8600"
+	zone = "vault.internal"
+
+	record "db" {
+		type = "TXT"
+		path = "secret/data/db"
+	}
+}
@@ -0,0 +1,21 @@
+pid_file = "./pidfile"
+
+auto_auth {
+	method {
+		type = "aws"
+		config = {
+			role = "foobar"
+		}
+	}
+}
+
+dns {
+	address = "127.0.0.1:8600"
+	zone = "vault.internal"
+
+	record "_postgres._tcp" {
+		type = "SRV"
+		path = "secret/data/service"
+		target_field = "data.host"
+	}
+}
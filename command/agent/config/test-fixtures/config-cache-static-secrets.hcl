@@ -0,0 +1,10 @@
+pid_file = "./pidfile"
+
+cache {
+    static_secret_cache_ttl = "5m"
+}
+
+listener "tcp" {
+    address = "127.0.0.1:8300"
+    tls_disable = true
+}
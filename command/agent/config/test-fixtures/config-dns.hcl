@@ -0,0 +1,31 @@
+pid_file = "./pidfile"
+
+auto_auth {
+	method {
+		type = "aws"
+		config = {
+			role = "foobar"
+		}
+	}
+}
+
+dns {
+	address = "127.0.0.1:8600"
+	zone = "Vault.Internal"
+	ttl = "10s"
+
+	record "db" {
+		type = "txt"
+		path = "secret/data/db"
+		fields = ["data.username"]
+		refresh_interval = "5m"
+	}
+
+	record "_postgres._tcp" {
+		type = "SRV"
+		path = "secret/data/service"
+		target_field = "data.host"
+		port_field = "data.port"
+		priority = 10
+	}
+}
@@ -0,0 +1,24 @@
+pid_file = "./pidfile"
+
+auto_auth {
+	method {
+		type = "aws"
+		config = {
+			role = "foobar"
+		}
+	}
+
+	sink {
+		type = "file"
+		config = {
+			path = "/tmp/file-foo"
+		}
+	}
+}
+
+log_level = "warn"
+
+log_levels {
+	auto-auth  = "debug"
+	templating = "error"
+}
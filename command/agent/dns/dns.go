@@ -0,0 +1,338 @@
+// Package dns serves DNS records derived from Vault data, for workloads that
+// can only consume their configuration through DNS. Each record is built from
+// a periodic read made with the auto-auth token, which the Server receives as
+// a sink.
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/miekg/dns"
+	"go.uber.org/atomic"
+)
+
+// ServerConfig is the configuration of a Server
+type ServerConfig struct {
+	Logger hclog.Logger
+	Client *api.Client
+	Config *config.DNS
+}
+
+// Server answers DNS queries for the records of its zone
+type Server struct {
+	logger hclog.Logger
+	client *api.Client
+	config *config.DNS
+
+	token   *atomic.String
+	tokenCh chan struct{}
+
+	l       sync.RWMutex
+	records map[string][]*record
+}
+
+// record is the state of a configured record. Serial is bumped every time
+// the data read from Vault changes, so that consumers can detect rotations
+// without the data being published.
+type record struct {
+	config *config.DNSRecord
+	fqdn   string
+	loaded bool
+	hash   [sha256.Size]byte
+	serial uint32
+	rrs    []dns.RR
+}
+
+// NewServer returns a Server for the given configuration
+func NewServer(conf *ServerConfig) (*Server, error) {
+	if conf.Logger == nil {
+		return nil, errors.New("nil logger provided")
+	}
+	if conf.Client == nil {
+		return nil, errors.New("nil client provided")
+	}
+	if conf.Config == nil {
+		return nil, errors.New("nil dns configuration provided")
+	}
+
+	s := &Server{
+		logger:  conf.Logger,
+		client:  conf.Client,
+		config:  conf.Config,
+		token:   atomic.NewString(""),
+		tokenCh: make(chan struct{}, 1),
+		records: make(map[string][]*record),
+	}
+	for _, rc := range conf.Config.Records {
+		fqdn := strings.ToLower(dns.Fqdn(rc.Name + "." + conf.Config.Zone))
+		s.records[fqdn] = append(s.records[fqdn], &record{
+			config: rc,
+			fqdn:   fqdn,
+		})
+	}
+	return s, nil
+}
+
+// WriteToken implements sink.Sink, to receive the auto-auth token
+func (s *Server) WriteToken(token string) error {
+	s.token.Store(token)
+	select {
+	case s.tokenCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run serves DNS over UDP and TCP, and refreshes the records, until ctx is
+// done.
+func (s *Server) Run(ctx context.Context) error {
+	pc, err := net.ListenPacket("udp", s.config.Address)
+	if err != nil {
+		return fmt.Errorf("dns: failed to listen on udp: %w", err)
+	}
+	ln, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		pc.Close()
+		return fmt.Errorf("dns: failed to listen on tcp: %w", err)
+	}
+
+	servers := []*dns.Server{
+		{PacketConn: pc, Handler: s},
+		{Listener: ln, Handler: s},
+	}
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			if err := server.ActivateAndServe(); err != nil {
+				s.logger.Error("dns server stopped", "error", err)
+			}
+		}(server)
+	}
+	s.logger.Info("serving dns", "address", s.config.Address, "zone", s.config.Zone)
+
+	s.refreshLoop(ctx)
+
+	for _, server := range servers {
+		server.Shutdown()
+	}
+	return nil
+}
+
+// refreshLoop reads the records from Vault once a token is available, then
+// at their refresh interval and every time the token changes.
+func (s *Server) refreshLoop(ctx context.Context) {
+	next := make(map[*record]time.Time)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		timer.Stop()
+		var wait time.Duration
+		if s.token.Load() == "" {
+			wait = time.Hour
+		} else {
+			now := time.Now()
+			wait = time.Hour
+			for _, records := range s.records {
+				for _, r := range records {
+					if now.After(next[r]) {
+						if err := s.refresh(ctx, r); err != nil {
+							s.logger.Error("failed to refresh dns record", "name", r.fqdn, "type", r.config.Type, "error", err)
+							metrics.IncrCounter([]string{"agent", "dns", "refresh", "failure"}, 1)
+						}
+						next[r] = now.Add(r.config.RefreshInterval)
+					}
+					if until := next[r].Sub(now); until < wait {
+						wait = until
+					}
+				}
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.tokenCh:
+			next = make(map[*record]time.Time)
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh reads the data of r from Vault and rebuilds its resource records
+func (s *Server) refresh(ctx context.Context, r *record) error {
+	client, err := s.client.CloneWithHeaders()
+	if err != nil {
+		return err
+	}
+	client.SetToken(s.token.Load())
+
+	secret, err := client.Logical().ReadWithContext(ctx, r.config.Path)
+	if err != nil {
+		return err
+	}
+	if secret == nil || secret.Data == nil {
+		return fmt.Errorf("no data found at %q", r.config.Path)
+	}
+
+	encoded, err := json.Marshal(secret.Data)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(encoded)
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	serial := r.serial
+	if !r.loaded || hash != r.hash {
+		serial++
+	}
+	rrs, err := s.buildRRs(r, secret.Data, serial)
+	if err != nil {
+		return err
+	}
+
+	r.loaded = true
+	r.hash = hash
+	r.serial = serial
+	r.rrs = rrs
+	return nil
+}
+
+func (s *Server) buildRRs(r *record, data map[string]interface{}, serial uint32) ([]dns.RR, error) {
+	hdr := dns.RR_Header{
+		Name:  r.fqdn,
+		Class: dns.ClassINET,
+		Ttl:   uint32(s.config.TTL.Seconds()),
+	}
+
+	switch r.config.Type {
+	case "TXT":
+		hdr.Rrtype = dns.TypeTXT
+		txt := []string{"serial=" + strconv.FormatUint(uint64(serial), 10)}
+		for _, field := range r.config.Fields {
+			value, err := lookupString(data, field)
+			if err != nil {
+				return nil, err
+			}
+			entry := field + "=" + value
+			if len(entry) > 255 {
+				return nil, fmt.Errorf("field %q is too long for a TXT record", field)
+			}
+			txt = append(txt, entry)
+		}
+		return []dns.RR{&dns.TXT{Hdr: hdr, Txt: txt}}, nil
+
+	case "SRV":
+		hdr.Rrtype = dns.TypeSRV
+		target, err := lookupString(data, r.config.TargetField)
+		if err != nil {
+			return nil, err
+		}
+		portRaw, err := lookupString(data, r.config.PortField)
+		if err != nil {
+			return nil, err
+		}
+		port, err := strconv.ParseUint(portRaw, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("field %q is not a port: %w", r.config.PortField, err)
+		}
+		return []dns.RR{&dns.SRV{
+			Hdr:      hdr,
+			Priority: uint16(r.config.Priority),
+			Weight:   uint16(r.config.Weight),
+			Port:     uint16(port),
+			Target:   dns.Fqdn(target),
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported record type %q", r.config.Type)
+}
+
+// lookupString returns the value of a field of data as a string. Nested
+// fields, such as the data of KV version 2 secrets, are separated by dots.
+func lookupString(data map[string]interface{}, field string) (string, error) {
+	var value interface{} = data
+	for _, part := range strings.Split(field, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("field %q not found", field)
+		}
+		if value, ok = m[part]; !ok {
+			return "", fmt.Errorf("field %q not found", field)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool, float64, int, int64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("field %q is not a scalar", field)
+	}
+}
+
+// ServeDNS implements dns.Handler
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.Authoritative = true
+
+	if len(req.Question) != 1 {
+		m.Rcode = dns.RcodeFormatError
+		w.WriteMsg(m)
+		return
+	}
+	q := req.Question[0]
+	name := strings.ToLower(q.Name)
+
+	if !dns.IsSubDomain(s.config.Zone, name) {
+		m.Authoritative = false
+		m.Rcode = dns.RcodeRefused
+		w.WriteMsg(m)
+		return
+	}
+
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	records, ok := s.records[name]
+	if !ok {
+		m.Rcode = dns.RcodeNameError
+		w.WriteMsg(m)
+		return
+	}
+
+	for _, r := range records {
+		if q.Qtype != dns.TypeANY && q.Qtype != dns.StringToType[r.config.Type] {
+			continue
+		}
+		if !r.loaded {
+			// Don't answer with partial data
+			m.Answer = nil
+			m.Rcode = dns.RcodeServerFailure
+			break
+		}
+		m.Answer = append(m.Answer, r.rrs...)
+	}
+
+	metrics.IncrCounter([]string{"agent", "dns", "query"}, 1)
+	w.WriteMsg(m)
+}
@@ -0,0 +1,163 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/miekg/dns"
+)
+
+// recorder is a dns.ResponseWriter keeping the written message
+type recorder struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (r *recorder) WriteMsg(m *dns.Msg) error {
+	r.msg = m
+	return nil
+}
+
+func (r *recorder) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53}
+}
+
+func query(t *testing.T, s *Server, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+	w := &recorder{}
+	s.ServeDNS(w, new(dns.Msg).SetQuestion(name, qtype))
+	if w.msg == nil {
+		t.Fatal("no response written")
+	}
+	return w.msg
+}
+
+func TestServer(t *testing.T) {
+	var password atomic.Value
+	password.Store("hunter2")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/db":
+			fmt.Fprintf(w, `{"data": {"data": {"username": "app", "password": %q}, "metadata": {"version": 3}}}`, password.Load())
+		case "/v1/secret/data/service":
+			fmt.Fprint(w, `{"data": {"data": {"host": "db.example.com", "port": 5432}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := api.NewClient(&api.Config{Address: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(&ServerConfig{
+		Logger: hclog.NewNullLogger(),
+		Client: client,
+		Config: &config.DNS{
+			Address: "127.0.0.1:0",
+			Zone:    "vault.internal.",
+			TTL:     30 * time.Second,
+			Records: []*config.DNSRecord{
+				{
+					Name:            "db",
+					Type:            "TXT",
+					Path:            "secret/data/db",
+					Fields:          []string{"data.username"},
+					RefreshInterval: time.Minute,
+				},
+				{
+					Name:            "_postgres._tcp",
+					Type:            "SRV",
+					Path:            "secret/data/service",
+					TargetField:     "data.host",
+					PortField:       "data.port",
+					Priority:        10,
+					Weight:          5,
+					RefreshInterval: time.Minute,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Records are not served before they are loaded
+	if resp := query(t, s, "db.vault.internal.", dns.TypeTXT); resp.Rcode != dns.RcodeServerFailure {
+		t.Fatalf("expected SERVFAIL, got %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	if err := s.WriteToken("test-token"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, records := range s.records {
+		for _, r := range records {
+			if err := s.refresh(ctx, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	resp := query(t, s, "DB.vault.internal.", dns.TypeTXT)
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 1 {
+		t.Fatalf("bad response: %s", resp)
+	}
+	txt := resp.Answer[0].(*dns.TXT).Txt
+	if len(txt) != 2 || txt[0] != "serial=1" || txt[1] != "data.username=app" {
+		t.Fatalf("bad TXT record: %v", txt)
+	}
+
+	resp = query(t, s, "_postgres._tcp.vault.internal.", dns.TypeSRV)
+	if len(resp.Answer) != 1 {
+		t.Fatalf("bad response: %s", resp)
+	}
+	srv := resp.Answer[0].(*dns.SRV)
+	if srv.Target != "db.example.com." || srv.Port != 5432 || srv.Priority != 10 || srv.Weight != 5 || srv.Hdr.Ttl != 30 {
+		t.Fatalf("bad SRV record: %s", srv)
+	}
+
+	// The serial only changes with the data
+	refreshAll := func() {
+		t.Helper()
+		for _, r := range s.records["db.vault.internal."] {
+			if err := s.refresh(ctx, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	refreshAll()
+	if txt := query(t, s, "db.vault.internal.", dns.TypeTXT).Answer[0].(*dns.TXT).Txt; txt[0] != "serial=1" {
+		t.Fatalf("expected the serial to be kept, got %v", txt)
+	}
+	password.Store("correct horse")
+	refreshAll()
+	if txt := query(t, s, "db.vault.internal.", dns.TypeTXT).Answer[0].(*dns.TXT).Txt; txt[0] != "serial=2" {
+		t.Fatalf("expected the serial to be bumped, got %v", txt)
+	}
+
+	if resp := query(t, s, "db.vault.internal.", dns.TypeSRV); resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 0 {
+		t.Fatalf("expected an empty answer, got %s", resp)
+	}
+	if resp := query(t, s, "nope.vault.internal.", dns.TypeTXT); resp.Rcode != dns.RcodeNameError {
+		t.Fatalf("expected NXDOMAIN, got %s", dns.RcodeToString[resp.Rcode])
+	}
+	if resp := query(t, s, "example.com.", dns.TypeTXT); resp.Rcode != dns.RcodeRefused {
+		t.Fatalf("expected REFUSED, got %s", dns.RcodeToString[resp.Rcode])
+	}
+}
@@ -0,0 +1,277 @@
+package template
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	ctconfig "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/vault/helper/keystore"
+)
+
+// privateKeyFilePerms are the permissions of rendered files holding private
+// keys, unless configured otherwise.
+const privateKeyFilePerms os.FileMode = 0o600
+
+// pkiFuncMap holds the template functions assembling the results of PKI
+// issue requests into bundles. They take the .Data of the secrets returned by
+// either the secret or the pkiCert template function.
+var pkiFuncMap = map[string]interface{}{
+	"pkiCertChainPEM": pkiCertChainPEM,
+	"pkiBundlePEM":    pkiBundlePEM,
+	"pkiPKCS12":       pkiPKCS12,
+	"pkiJKS":          pkiJKS,
+}
+
+// pkiPrivateKeyFuncs are the template functions rendering private keys.
+var pkiPrivateKeyFuncs = []string{"pkiBundlePEM", "pkiPKCS12", "pkiJKS"}
+
+// pkiIssueResult holds the parsed result of a PKI issue request.
+type pkiIssueResult struct {
+	key    crypto.Signer
+	keyPEM string
+	cert   *x509.Certificate
+	chain  []*x509.Certificate
+}
+
+// pkiIssueData returns the data of a PKI issue result as returned by the
+// API. The pkiCert template function returns it as a struct instead, whose
+// fields are mapped to their API names.
+func pkiIssueData(raw interface{}) (map[string]interface{}, error) {
+	if data, ok := raw.(map[string]interface{}); ok {
+		return data, nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(raw))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported PKI secret data of type %T", raw)
+	}
+	data := make(map[string]interface{})
+	for field, key := range map[string]string{
+		"Cert":    "certificate",
+		"Key":     "private_key",
+		"CA":      "issuing_ca",
+		"CACerts": "ca_chain",
+	} {
+		if f := v.FieldByName(field); f.IsValid() && f.CanInterface() {
+			data[key] = f.Interface()
+		}
+	}
+	return data, nil
+}
+
+// parsePKIIssueResult parses the certificate, the CA chain and, if
+// requireKey is set, the private key of the data of a PKI issue secret. The
+// chain is ordered from the issuer of the certificate up to the root, and
+// holds the issuing CA if the result has no CA chain.
+func parsePKIIssueResult(raw interface{}, requireKey bool) (*pkiIssueResult, error) {
+	var result pkiIssueResult
+
+	data, err := pkiIssueData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM, _ := data["certificate"].(string)
+	if certPEM == "" {
+		return nil, errors.New("no certificate in secret; PKI results must be in the \"pem\" format")
+	}
+	certs, err := parsePEMCertificates(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	result.cert = certs[0]
+
+	var chainPEMs []string
+	switch chain := data["ca_chain"].(type) {
+	case []interface{}:
+		for _, raw := range chain {
+			if s, ok := raw.(string); ok {
+				chainPEMs = append(chainPEMs, s)
+			}
+		}
+	case []string:
+		chainPEMs = chain
+	}
+	if len(chainPEMs) == 0 {
+		if issuingCA, ok := data["issuing_ca"].(string); ok && issuingCA != "" {
+			chainPEMs = []string{issuingCA}
+		}
+	}
+	for _, chainPEM := range chainPEMs {
+		chainCerts, err := parsePEMCertificates(chainPEM)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range chainCerts {
+			if cert.Equal(result.cert) {
+				continue
+			}
+			result.chain = append(result.chain, cert)
+		}
+	}
+
+	if !requireKey {
+		return &result, nil
+	}
+
+	result.keyPEM, _ = data["private_key"].(string)
+	if result.keyPEM == "" {
+		return nil, errors.New("no private key in secret; only results of issue requests hold one")
+	}
+	block, _ := pem.Decode([]byte(result.keyPEM))
+	if block == nil {
+		return nil, errors.New("failed to decode private key PEM")
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	result.key = signer
+
+	return &result, nil
+}
+
+func parsePEMCertificates(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found in PEM data")
+	}
+	return certs, nil
+}
+
+func encodePEMCertificates(buf *bytes.Buffer, certs ...*x509.Certificate) {
+	for _, cert := range certs {
+		pem.Encode(buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+}
+
+// pkiCertChainPEM returns the certificate of a PKI issue or sign result
+// followed by its CA chain, up to the root, as PEM.
+func pkiCertChainPEM(data interface{}) (string, error) {
+	result, err := parsePKIIssueResult(data, false)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	encodePEMCertificates(&buf, result.cert)
+	encodePEMCertificates(&buf, result.chain...)
+	return buf.String(), nil
+}
+
+// pkiBundlePEM returns the private key of a PKI issue result, followed by
+// its certificate and CA chain, as PEM.
+func pkiBundlePEM(data interface{}) (string, error) {
+	result, err := parsePKIIssueResult(data, true)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(strings.TrimSpace(result.keyPEM))
+	buf.WriteString("\n")
+	encodePEMCertificates(&buf, result.cert)
+	encodePEMCertificates(&buf, result.chain...)
+	return buf.String(), nil
+}
+
+// pkiPKCS12 returns the private key, certificate and CA chain of a PKI issue
+// result as a PKCS#12 file protected with password.
+func pkiPKCS12(data interface{}, password string) (string, error) {
+	result, err := parsePKIIssueResult(data, true)
+	if err != nil {
+		return "", err
+	}
+
+	pfx, err := keystore.EncodePKCS12KeyStore(result.key, result.cert, result.chain, result.cert.Subject.CommonName, password)
+	if err != nil {
+		return "", err
+	}
+	return string(pfx), nil
+}
+
+// pkiJKS returns the private key, certificate and CA chain of a PKI issue
+// result as a private key entry of a Java KeyStore, protected with
+// password. The alias of the entry defaults to the common name of the
+// certificate.
+func pkiJKS(data interface{}, password string, alias ...string) (string, error) {
+	result, err := parsePKIIssueResult(data, true)
+	if err != nil {
+		return "", err
+	}
+
+	entryAlias := result.cert.Subject.CommonName
+	if len(alias) > 0 && alias[0] != "" {
+		entryAlias = alias[0]
+	}
+	jks, err := keystore.EncodeJKSKeyStore(result.key, result.cert, result.chain, entryAlias, password)
+	if err != nil {
+		return "", err
+	}
+	return string(jks), nil
+}
+
+// setPKIFuncs makes the PKI template functions available to the template,
+// and restricts the permissions of its destination to the owner if it
+// renders private keys and has no configured permissions.
+func setPKIFuncs(tmpl *ctconfig.TemplateConfig) {
+	tmpl.ExtFuncMap = pkiFuncMap
+
+	if tmpl.Perms != nil {
+		return
+	}
+	contents := ""
+	switch {
+	case tmpl.Contents != nil && *tmpl.Contents != "":
+		contents = *tmpl.Contents
+	case tmpl.Source != nil && *tmpl.Source != "":
+		// Errors reading the source are reported by the runner
+		raw, err := os.ReadFile(*tmpl.Source)
+		if err != nil {
+			return
+		}
+		contents = string(raw)
+	}
+	for _, name := range pkiPrivateKeyFuncs {
+		if strings.Contains(contents, name) {
+			perms := privateKeyFilePerms
+			tmpl.Perms = &perms
+			return
+		}
+	}
+}
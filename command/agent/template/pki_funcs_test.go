@@ -0,0 +1,175 @@
+package template
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ctconfig "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/vault/helper/keystore"
+	"github.com/hashicorp/vault/sdk/helper/pointerutil"
+	"github.com/stretchr/testify/require"
+)
+
+// testPKIIssueData returns the data of a PKI issue secret for a leaf
+// certificate issued by an intermediate CA, and its expected chain.
+func testPKIIssueData(t *testing.T) (map[string]interface{}, []*x509.Certificate) {
+	t.Helper()
+
+	issue := func(cn string, isCA bool, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(time.Now().UnixNano()),
+			Subject:               pkix.Name{CommonName: cn},
+			NotBefore:             time.Now().Add(-time.Minute),
+			NotAfter:              time.Now().Add(time.Hour),
+			BasicConstraintsValid: true,
+			IsCA:                  isCA,
+		}
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return cert, key
+	}
+	certPEM := func(cert *x509.Certificate) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+
+	root, rootKey := issue("Root CA", true, nil, nil)
+	intermediate, intKey := issue("Intermediate CA", true, root, rootKey)
+	leaf, leafKey := issue("www.example.com", false, intermediate, intKey)
+	keyDER, err := x509.MarshalECPrivateKey(leafKey)
+	require.NoError(t, err)
+
+	return map[string]interface{}{
+		"certificate": certPEM(leaf),
+		"issuing_ca":  certPEM(intermediate),
+		"ca_chain":    []interface{}{certPEM(intermediate), certPEM(root)},
+		"private_key": string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}, []*x509.Certificate{leaf, intermediate, root}
+}
+
+func TestPKIFuncs(t *testing.T) {
+	data, chain := testPKIIssueData(t)
+
+	// PEM bundles are ordered from the leaf up to the root
+	chainPEM, err := pkiCertChainPEM(data)
+	require.NoError(t, err)
+	certs, err := parsePEMCertificates(chainPEM)
+	require.NoError(t, err)
+	require.Equal(t, chain, certs)
+
+	bundlePEM, err := pkiBundlePEM(data)
+	require.NoError(t, err)
+	block, rest := pem.Decode([]byte(bundlePEM))
+	require.Equal(t, "EC PRIVATE KEY", block.Type)
+	certs, err = parsePEMCertificates(string(rest))
+	require.NoError(t, err)
+	require.Equal(t, chain, certs)
+
+	// Without a CA chain, the issuing CA is used
+	noChain := map[string]interface{}{
+		"certificate": data["certificate"],
+		"issuing_ca":  data["issuing_ca"],
+	}
+	chainPEM, err = pkiCertChainPEM(noChain)
+	require.NoError(t, err)
+	certs, err = parsePEMCertificates(chainPEM)
+	require.NoError(t, err)
+	require.Equal(t, chain[:2], certs)
+
+	// Keystores
+	pfx, err := pkiPKCS12(data, "changeit")
+	require.NoError(t, err)
+	certs, key, err := keystore.DecodePKCS12([]byte(pfx), "changeit")
+	require.NoError(t, err)
+	require.Equal(t, chain, certs)
+	require.True(t, key.(*ecdsa.PrivateKey).PublicKey.Equal(chain[0].PublicKey))
+
+	jks, err := pkiJKS(data, "changeit", "server")
+	require.NoError(t, err)
+	entries, err := keystore.DecodeJKS([]byte(jks), "changeit")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "server", entries[0].Alias)
+	require.Equal(t, chain, entries[0].Certificates)
+
+	jks, err = pkiJKS(data, "changeit")
+	require.NoError(t, err)
+	entries, err = keystore.DecodeJKS([]byte(jks), "changeit")
+	require.NoError(t, err)
+	require.Equal(t, "www.example.com", entries[0].Alias)
+
+	// The data of pkiCert secrets is a struct
+	pemEncoded := struct {
+		Key, Cert, CA string
+		CACerts       []string
+	}{
+		Key:     data["private_key"].(string),
+		Cert:    data["certificate"].(string),
+		CA:      data["issuing_ca"].(string),
+		CACerts: []string{data["ca_chain"].([]interface{})[0].(string), data["ca_chain"].([]interface{})[1].(string)},
+	}
+	bundlePEM, err = pkiBundlePEM(&pemEncoded)
+	require.NoError(t, err)
+	block, rest = pem.Decode([]byte(bundlePEM))
+	require.Equal(t, "EC PRIVATE KEY", block.Type)
+	certs, err = parsePEMCertificates(string(rest))
+	require.NoError(t, err)
+	require.Equal(t, chain, certs)
+
+	// Keystores require a private key
+	delete(data, "private_key")
+	_, err = pkiPKCS12(data, "changeit")
+	require.Error(t, err)
+	_, err = pkiCertChainPEM(data)
+	require.NoError(t, err)
+}
+
+func TestSetPKIFuncs(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "keystore.ctmpl")
+	require.NoError(t, os.WriteFile(source, []byte(`{{ with secret "pki/issue/app" "common_name=app.example.com" }}{{ pkiJKS .Data "changeit" }}{{ end }}`), 0o600))
+
+	perms := os.FileMode(0o640)
+	cases := map[string]struct {
+		tmpl     *ctconfig.TemplateConfig
+		expected *os.FileMode
+	}{
+		"private key contents": {
+			tmpl:     &ctconfig.TemplateConfig{Contents: pointerutil.StringPtr(`{{ with secret "pki/issue/app" }}{{ pkiBundlePEM .Data }}{{ end }}`)},
+			expected: func() *os.FileMode { p := privateKeyFilePerms; return &p }(),
+		},
+		"private key source": {
+			tmpl:     &ctconfig.TemplateConfig{Source: pointerutil.StringPtr(source)},
+			expected: func() *os.FileMode { p := privateKeyFilePerms; return &p }(),
+		},
+		"certificates only": {
+			tmpl: &ctconfig.TemplateConfig{Contents: pointerutil.StringPtr(`{{ with secret "pki/issue/app" }}{{ pkiCertChainPEM .Data }}{{ end }}`)},
+		},
+		"configured perms": {
+			tmpl:     &ctconfig.TemplateConfig{Contents: pointerutil.StringPtr(`{{ with secret "pki/issue/app" }}{{ pkiBundlePEM .Data }}{{ end }}`), Perms: &perms},
+			expected: &perms,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			setPKIFuncs(tc.tmpl)
+			require.Contains(t, tc.tmpl.ExtFuncMap, "pkiJKS")
+			require.Equal(t, tc.expected, tc.tmpl.Perms)
+		})
+	}
+}
@@ -11,9 +11,11 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"go.uber.org/atomic"
 
+	"github.com/armon/go-metrics"
 	ctconfig "github.com/hashicorp/consul-template/config"
 	ctlogging "github.com/hashicorp/consul-template/logging"
 	"github.com/hashicorp/consul-template/manager"
@@ -65,8 +67,16 @@ type Server struct {
 
 	logger        hclog.Logger
 	exitAfterAuth bool
+
+	// lastRendered holds when each destination was last rendered, to report
+	// the age of the renders
+	lastRendered map[string]time.Time
 }
 
+// renderAgeReportInterval is how often the age of the last render of each
+// template is reported
+const renderAgeReportInterval = 10 * time.Second
+
 // NewServer returns a new configured server
 func NewServer(conf *ServerConfig) *Server {
 	ts := Server{
@@ -77,6 +87,7 @@ func NewServer(conf *ServerConfig) *Server {
 		logger:        conf.Logger,
 		config:        conf,
 		exitAfterAuth: conf.ExitAfterAuth,
+		lastRendered:  make(map[string]time.Time),
 	}
 	return &ts
 }
@@ -135,12 +146,18 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 	}
 	ts.lookupMap = lookupMap
 
+	renderAgeTicker := time.NewTicker(renderAgeReportInterval)
+	defer renderAgeTicker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			ts.runner.Stop()
 			return nil
 
+		case <-renderAgeTicker.C:
+			ts.reportRenderAges()
+
 		case token := <-incoming:
 			if token != *latestToken {
 				ts.logger.Info("template server received new token")
@@ -174,6 +191,7 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 
 		case err := <-ts.runner.ErrCh:
 			ts.logger.Error("template server error", "error", err.Error())
+			metrics.IncrCounter([]string{"agent", "template", "error"}, 1)
 			ts.runner.StopImmediately()
 
 			// Return after stopping the runner if exit on retry failure was
@@ -191,6 +209,7 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 		case <-ts.runner.TemplateRenderedCh():
 			// A template has been rendered, figure out what to do
 			events := ts.runner.RenderEvents()
+			ts.reportRenderEvents(events)
 
 			// events are keyed by template ID, and can be matched up to the id's from
 			// the lookupMap
@@ -219,6 +238,35 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 	}
 }
 
+// reportRenderEvents sets the render status of each template, labeled by its
+// destination: whether it was rendered at least once, and how long ago it was
+// last written.
+func (ts *Server) reportRenderEvents(events map[string]*manager.RenderEvent) {
+	for _, event := range events {
+		rendered := float32(0)
+		if !event.LastWouldRender.IsZero() {
+			rendered = 1
+		}
+		for _, tmpl := range event.TemplateConfigs {
+			labels := []metrics.Label{{Name: "destination", Value: ctconfig.StringVal(tmpl.Destination)}}
+			metrics.SetGaugeWithLabels([]string{"agent", "template", "rendered"}, rendered, labels)
+			if !event.LastDidRender.IsZero() {
+				ts.lastRendered[ctconfig.StringVal(tmpl.Destination)] = event.LastDidRender
+			}
+		}
+	}
+	ts.reportRenderAges()
+}
+
+// reportRenderAges sets the time elapsed since each template was last
+// written, in seconds.
+func (ts *Server) reportRenderAges() {
+	for destination, lastRendered := range ts.lastRendered {
+		labels := []metrics.Label{{Name: "destination", Value: destination}}
+		metrics.SetGaugeWithLabels([]string{"agent", "template", "render_age"}, float32(time.Since(lastRendered).Seconds()), labels)
+	}
+}
+
 func (ts *Server) Stop() {
 	if ts.stopped.CAS(false, true) {
 		close(ts.DoneCh)
@@ -230,6 +278,9 @@ func (ts *Server) Stop() {
 func newRunnerConfig(sc *ServerConfig, templates ctconfig.TemplateConfigs) (*ctconfig.Config, error) {
 	conf := ctconfig.DefaultConfig()
 	conf.Templates = templates.Copy()
+	for _, tmpl := range *conf.Templates {
+		setPKIFuncs(tmpl)
+	}
 
 	// Setup the Vault config
 	// Always set these to ensure nothing is picked up from the environment
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -21,6 +22,7 @@ import (
 	credAppRole "github.com/hashicorp/vault/builtin/credential/approle"
 	"github.com/hashicorp/vault/command/agent"
 	agentConfig "github.com/hashicorp/vault/command/agent/config"
+	vaultlogging "github.com/hashicorp/vault/helper/logging"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/logging"
@@ -2363,3 +2365,72 @@ func generateListenerAddress(t *testing.T) string {
 	ln1.Close()
 	return listenAddr
 }
+
+func TestAgent_Loggers(t *testing.T) {
+	logger, err := vaultlogging.Setup(vaultlogging.NewLogConfig("agent", hclog.Info, vaultlogging.StandardFormat, ""), ioutil.Discard)
+	require.NoError(t, err)
+
+	_, cmd := testAgentCommand(t, logger)
+	cmd.loggers = vaultlogging.NewSubsystemLoggers(hclog.Info, agentConfig.SubsystemAutoAuth, agentConfig.SubsystemCaching, agentConfig.SubsystemTemplating)
+	cacheLogger := cmd.subsystemLogger(agentConfig.SubsystemCaching, "cache")
+	templateLogger := cmd.subsystemLogger(agentConfig.SubsystemTemplating, "template.server")
+
+	request := func(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	// The endpoint is disabled unless enable_loggers is set
+	rr := request(cmd.handleLoggers(false), http.MethodGet, consts.AgentPathLoggers, "")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	handler := cmd.handleLoggers(true)
+	rr = request(handler, http.MethodPost, consts.AgentPathLoggers+"/"+agentConfig.SubsystemCaching, `{"level": "debug"}`)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.True(t, cacheLogger.IsDebug())
+	require.False(t, templateLogger.IsDebug())
+
+	rr = request(handler, http.MethodGet, consts.AgentPathLoggers, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, map[string]string{
+		agentConfig.SubsystemAutoAuth:   "info",
+		agentConfig.SubsystemCaching:    "debug",
+		agentConfig.SubsystemTemplating: "info",
+	}, resp.Data)
+
+	for _, tc := range []struct {
+		path string
+		body string
+		code int
+	}{
+		{consts.AgentPathLoggers, `{"level": "verbose"}`, http.StatusBadRequest},
+		{consts.AgentPathLoggers, `{}`, http.StatusBadRequest},
+		{consts.AgentPathLoggers, ``, http.StatusBadRequest},
+		{consts.AgentPathLoggers + "/dns", `{"level": "debug"}`, http.StatusNotFound},
+	} {
+		rr = request(handler, http.MethodPost, tc.path, tc.body)
+		require.Equal(t, tc.code, rr.Code, "%s %s", tc.path, tc.body)
+	}
+
+	// Without a subsystem, all subsystems are changed
+	rr = request(handler, http.MethodPost, consts.AgentPathLoggers, `{"level": "error"}`)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.False(t, cacheLogger.IsWarn())
+	require.False(t, templateLogger.IsWarn())
+
+	rr = request(handler, http.MethodDelete, consts.AgentPathLoggers+"/"+agentConfig.SubsystemTemplating, "")
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.True(t, templateLogger.IsInfo())
+	require.False(t, cacheLogger.IsWarn())
+
+	rr = request(handler, http.MethodDelete, consts.AgentPathLoggers, "")
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.True(t, cacheLogger.IsInfo())
+	require.False(t, cacheLogger.IsDebug())
+}
@@ -395,6 +395,7 @@ func TestPredict_Plugins(t *testing.T) {
 				"snowflake-database-plugin",
 				"ssh",
 				"terraform",
+				"tokenize",
 				"totp",
 				"transform",
 				"transit",
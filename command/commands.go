@@ -249,6 +249,7 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 					UI: serverCmdUi,
 				},
 				ShutdownCh: MakeShutdownCh(),
+				SigUSR2Ch:  MakeSigUSR2Ch(),
 			}, nil
 		},
 		"audit": func() (cli.Command, error) {
@@ -721,6 +722,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"kv diff": func() (cli.Command, error) {
+			return &KVDiffCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"kv rollback": func() (cli.Command, error) {
 			return &KVRollbackCommand{
 				BaseCommand: getBaseCommand(),
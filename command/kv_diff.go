@@ -0,0 +1,232 @@
+package command
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*KVDiffCommand)(nil)
+	_ cli.CommandAutocomplete = (*KVDiffCommand)(nil)
+)
+
+type KVDiffCommand struct {
+	*BaseCommand
+
+	flagFromVersion int
+	flagToVersion   int
+	flagMaskValues  bool
+	flagMount       string
+}
+
+func (c *KVDiffCommand) Synopsis() string {
+	return "Compares versions of data"
+}
+
+func (c *KVDiffCommand) Help() string {
+	helpText := `
+Usage: vault kv diff [options] KEY [OTHER_KEY]
+
+  *NOTE*: This is only supported for KV v2 engine mounts.
+
+  Compares two versions of the data at the given path, or the data at two
+  paths, which may be in different mounts. Only the keys that were added,
+  removed or modified are shown. The comparison is made by the server, and
+  requires permission to read both versions.
+
+  Compare version 2 of the "foo" secret to its latest version:
+
+      $ vault kv diff -mount=secret -from-version=2 foo
+
+  Compare the "foo" secret in two mounts, without showing values:
+
+      $ vault kv diff -mask-values staging/foo production/foo
+
+  Additional flags and more advanced use cases are detailed below.
+
+` + c.Flags().Help()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVDiffCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	// Common Options
+	f := set.NewFlagSet("Common Options")
+
+	f.IntVar(&IntVar{
+		Name:   "from-version",
+		Target: &c.flagFromVersion,
+		Usage:  `Specifies the version to compare from. Defaults to the latest version.`,
+	})
+
+	f.IntVar(&IntVar{
+		Name:   "to-version",
+		Target: &c.flagToVersion,
+		Usage:  `Specifies the version to compare to. Defaults to the latest version.`,
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "mask-values",
+		Target:  &c.flagMaskValues,
+		Default: false,
+		Usage:   `Only show the changed keys, without their values.`,
+	})
+
+	f.StringVar(&StringVar{
+		Name:    "mount",
+		Target:  &c.flagMount,
+		Default: "", // no default, because the handling of the next arg is determined by whether this flag has a value
+		Usage: `Specifies the path where the KV backend is mounted. If specified,
+		the next arguments will be interpreted as secret paths within the mount.
+		If this flag is not specified, the next arguments will be interpreted as
+		combined mount paths and secret paths.`,
+	})
+
+	return set
+}
+
+func (c *KVDiffCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *KVDiffCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *KVDiffCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	switch {
+	case len(args) < 1:
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1-2, got %d)", len(args)))
+		return 1
+	case len(args) > 2:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1-2, got %d)", len(args)))
+		return 1
+	case c.flagFromVersion < 0:
+		c.UI.Error(fmt.Sprintf("Invalid value %d for the from-version flag", c.flagFromVersion))
+		return 1
+	case c.flagToVersion < 0:
+		c.UI.Error(fmt.Sprintf("Invalid value %d for the to-version flag", c.flagToVersion))
+		return 1
+	case len(args) == 1 && c.flagFromVersion == c.flagToVersion:
+		c.UI.Error("Comparing a path to itself requires different from-version and to-version flags")
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	fromPath := c.secretPath(args[0])
+	toPath := fromPath
+	if len(args) == 2 {
+		toPath = c.secretPath(args[1])
+	}
+
+	secret, err := client.Logical().Write("sys/kv-diff", map[string]interface{}{
+		"from_path":    fromPath,
+		"from_version": c.flagFromVersion,
+		"to_path":      toPath,
+		"to_version":   c.flagToVersion,
+		"mask_values":  c.flagMaskValues,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error comparing %s to %s: %s", fromPath, toPath, err))
+		return 2
+	}
+	if secret == nil || secret.Data == nil {
+		c.UI.Error(fmt.Sprintf("No comparison returned for %s and %s", fromPath, toPath))
+		return 2
+	}
+
+	if Format(c.UI) != "table" {
+		return OutputSecret(c.UI, secret)
+	}
+
+	c.UI.Info(fmt.Sprintf("From: %s", kvDiffSideHeader(secret.Data["from"])))
+	c.UI.Info(fmt.Sprintf("To:   %s", kvDiffSideHeader(secret.Data["to"])))
+	c.UI.Info("")
+
+	out := kvDiffRows(secret, c.flagMaskValues)
+	if len(out) == 1 {
+		c.UI.Info("No changes")
+		return 0
+	}
+	c.UI.Output(tableOutput(out, nil))
+	return 0
+}
+
+// secretPath returns the path of a secret argument, including its mount.
+func (c *KVDiffCommand) secretPath(arg string) string {
+	if c.flagMount == "" {
+		return sanitizePath(arg)
+	}
+	return path.Join(sanitizePath(c.flagMount), sanitizePath(arg))
+}
+
+// kvDiffSideHeader describes a version compared by a diff.
+func kvDiffSideHeader(raw interface{}) string {
+	side, _ := raw.(map[string]interface{})
+	if exists, _ := side["exists"].(bool); !exists {
+		return fmt.Sprintf("%v (no data)", side["path"])
+	}
+	return fmt.Sprintf("%v (version %v)", side["path"], side["version"])
+}
+
+// kvDiffRows returns the rows of the table of the changes of a diff, sorted
+// by key.
+func kvDiffRows(secret *api.Secret, maskValues bool) []string {
+	header := "Key | Change | From | To"
+	if maskValues {
+		header = "Key | Change"
+	}
+	out := []string{header}
+
+	changes, _ := secret.Data["changes"].(map[string]interface{})
+	type row struct {
+		key, change string
+	}
+	var rows []row
+	for _, change := range []string{"added", "removed", "modified"} {
+		keys, _ := secret.Data[change].([]interface{})
+		for _, key := range keys {
+			rows = append(rows, row{key: fmt.Sprint(key), change: change})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].key < rows[j].key
+	})
+
+	for _, r := range rows {
+		if maskValues {
+			out = append(out, fmt.Sprintf("%s | %s", r.key, r.change))
+			continue
+		}
+		values, _ := changes[r.key].(map[string]interface{})
+		from, to := "n/a", "n/a"
+		if v, ok := values["from"]; ok {
+			from = fmt.Sprint(v)
+		}
+		if v, ok := values["to"]; ok {
+			to = fmt.Sprint(v)
+		}
+		out = append(out, fmt.Sprintf("%s | %s | %s | %s", r.key, r.change, from, to))
+	}
+	return out
+}
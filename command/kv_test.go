@@ -1416,3 +1416,120 @@ func createTokenForPolicy(t *testing.T, client *api.Client, policy string) (*api
 
 	return secret.Auth, err
 }
+
+func testKVDiffCommand(tb testing.TB) (*cli.MockUi, *KVDiffCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &KVDiffCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestKVDiffCommand(t *testing.T) {
+	t.Parallel()
+
+	client, closer := testVaultServer(t)
+	defer closer()
+
+	for _, mount := range []string{"kv/", "kv-other/"} {
+		if err := client.Sys().Mount(mount, &api.MountInput{
+			Type: "kv-v2",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"kv/foo", "user=app", "password=old", "host=db.example.com"},
+		{"kv/foo", "user=app", "password=new", "port=5432"},
+		{"kv-other/foo", "user=app", "password=old"},
+	} {
+		if code, combined := kvPutWithRetry(t, client, args); code != 0 {
+			t.Fatalf("expected 0 to be %d: %s", code, combined)
+		}
+	}
+
+	cases := []struct {
+		name       string
+		args       []string
+		outStrings []string
+		code       int
+	}{
+		{
+			"not_enough_args",
+			[]string{},
+			[]string{"Not enough arguments"},
+			1,
+		},
+		{
+			"same_version",
+			[]string{"kv/foo"},
+			[]string{"requires different from-version and to-version"},
+			1,
+		},
+		{
+			"versions",
+			[]string{"-from-version=1", "kv/foo"},
+			[]string{
+				"kv/foo (version 1)", "kv/foo (version 2)",
+				"host", "removed", "db.example.com",
+				"password", "modified", "new",
+				"port", "added", "5432",
+			},
+			0,
+		},
+		{
+			"mount_flag_syntax",
+			[]string{"-mount=kv", "-from-version=1", "-to-version=2", "foo"},
+			[]string{"kv/foo (version 1)", "kv/foo (version 2)", "password"},
+			0,
+		},
+		{
+			"mask_values",
+			[]string{"-mask-values", "-from-version=1", "kv/foo"},
+			[]string{"password", "modified"},
+			0,
+		},
+		{
+			"mounts",
+			[]string{"-from-version=1", "kv/foo", "kv-other/foo"},
+			[]string{"kv-other/foo (version 1)", "host", "removed"},
+			0,
+		},
+		{
+			"no_changes",
+			[]string{"-to-version=1", "kv-other/foo", "kv-other/foo"},
+			[]string{"No changes"},
+			0,
+		},
+		{
+			"v1_mount",
+			[]string{"secret/foo", "secret/bar"},
+			[]string{"is not a KV version 2 secrets engine"},
+			2,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			ui, cmd := testKVDiffCommand(t)
+			cmd.client = client
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			for _, str := range tc.outStrings {
+				if !strings.Contains(combined, str) {
+					t.Errorf("expected %q to contain %q", combined, str)
+				}
+			}
+		})
+	}
+}
@@ -656,7 +656,7 @@ SEALFAIL:
 		// Make sure we close all listeners from this point on
 		listenerCloseFunc := func() {
 			for _, ln := range lns {
-				ln.Listener.Close()
+				ln.Close()
 			}
 		}
 
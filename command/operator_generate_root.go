@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-secure-stdlib/password"
 	"github.com/hashicorp/vault/api"
@@ -41,6 +42,7 @@ type OperatorGenerateRootCommand struct {
 	flagGenerateOTP   bool
 	flagDRToken       bool
 	flagRecoveryToken bool
+	flagTTL           time.Duration
 
 	testStdin io.Reader // for tests
 }
@@ -81,6 +83,10 @@ Usage: vault operator generate-root [options] [KEY]
 
       $ vault operator generate-root -otp="..."
 
+  Start the generation of a read-only recovery token valid for 4 hours:
+
+      $ vault operator generate-root -recovery-token -init -otp="..." -ttl=4h
+
 ` + c.Flags().Help()
 	return strings.TrimSpace(helpText)
 }
@@ -157,7 +163,20 @@ func (c *OperatorGenerateRootCommand) Flags() *FlagSets {
 		EnvVar:     "",
 		Completion: complete.PredictNothing,
 		Usage: "Set this flag to do generate root operations on Recovery Operational " +
-			"tokens.",
+			"tokens. On a server that is not in recovery mode, this generates a " +
+			"read-only recovery token, which can be used when the token store is " +
+			"unavailable.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:       "ttl",
+		Target:     &c.flagTTL,
+		Default:    0,
+		EnvVar:     "",
+		Completion: complete.PredictAnything,
+		Usage: "Time the read-only recovery token is valid for, with the " +
+			"\"-recovery-token\" and \"-init\" flags. Defaults to 1h, and can be " +
+			"at most 24h.",
 	})
 
 	f.StringVar(&StringVar{
@@ -222,6 +241,11 @@ func (c *OperatorGenerateRootCommand) Run(args []string) int {
 		return 1
 	}
 
+	if c.flagTTL != 0 && !c.flagRecoveryToken {
+		c.UI.Error("The -ttl flag requires the -recovery-token flag")
+		return 1
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		c.UI.Error(err.Error())
@@ -375,6 +399,11 @@ func (c *OperatorGenerateRootCommand) init(client *api.Client, otp, pgpKey strin
 		f = client.Sys().GenerateDROperationTokenInit
 	case generateRootRecovery:
 		f = client.Sys().GenerateRecoveryOperationTokenInit
+		if c.flagTTL != 0 {
+			f = func(otp, pgpKey string) (*api.GenerateRootStatusResponse, error) {
+				return client.Sys().GenerateReadOnlyRecoveryTokenInit(otp, pgpKey, c.flagTTL.String())
+			}
+		}
 	}
 	status, err := f(otp, pgpKey)
 	if err != nil {
@@ -27,6 +27,7 @@ type OperatorInitCommand struct {
 	flagKeyThreshold    int
 	flagPGPKeys         []string
 	flagRootTokenPGPKey string
+	flagTOTPProtected   bool
 
 	// Auto Unseal
 	flagRecoveryShares    int
@@ -142,6 +143,16 @@ func (c *OperatorInitCommand) Flags() *FlagSets {
 			"key.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:    "totp-protected-shares",
+		Target:  &c.flagTOTPProtected,
+		Default: false,
+		Usage: "Bind each unseal key to a TOTP secret, printed along with it. " +
+			"Unsealing then requires the current code of the secret bound to " +
+			"each key. When -pgp-keys are supplied, the TOTP secrets are " +
+			"encrypted with the same keys as the unseal keys.",
+	})
+
 	f.IntVar(&IntVar{
 		Name:    "stored-shares",
 		Target:  &c.flagStoredShares,
@@ -280,6 +291,8 @@ func (c *OperatorInitCommand) Run(args []string) int {
 		PGPKeys:         c.flagPGPKeys,
 		RootTokenPGPKey: c.flagRootTokenPGPKey,
 
+		TOTPProtectedShares: c.flagTOTPProtected,
+
 		RecoveryShares:    c.flagRecoveryShares,
 		RecoveryThreshold: c.flagRecoveryThreshold,
 		RecoveryPGPKeys:   c.flagRecoveryPGPKeys,
@@ -456,6 +469,9 @@ func (c *OperatorInitCommand) init(client *api.Client, req *api.InitRequest) int
 			c.UI.Output(fmt.Sprintf("Unseal Key %d: %s", i+1, key))
 		}
 	}
+	for i, key := range resp.KeysTOTP {
+		c.UI.Output(fmt.Sprintf("Unseal Key %d TOTP: %s", i+1, key))
+	}
 	for i, key := range resp.RecoveryKeys {
 		if resp.RecoveryKeysB64 != nil && len(resp.RecoveryKeysB64) == len(resp.RecoveryKeys) {
 			c.UI.Output(fmt.Sprintf("Recovery Key %d: %s", i+1, resp.RecoveryKeysB64[i]))
@@ -541,6 +557,7 @@ func (c *OperatorInitCommand) status(client *api.Client) int {
 type machineInit struct {
 	UnsealKeysB64     []string `json:"unseal_keys_b64"`
 	UnsealKeysHex     []string `json:"unseal_keys_hex"`
+	UnsealKeysTOTP    []string `json:"unseal_keys_totp,omitempty"`
 	UnsealShares      int      `json:"unseal_shares"`
 	UnsealThreshold   int      `json:"unseal_threshold"`
 	RecoveryKeysB64   []string `json:"recovery_keys_b64"`
@@ -563,6 +580,8 @@ func newMachineInit(req *api.InitRequest, resp *api.InitResponse) *machineInit {
 		init.UnsealKeysB64[i] = v
 	}
 
+	init.UnsealKeysTOTP = resp.KeysTOTP
+
 	// If we don't get a set of keys back, it means that we are storing the keys,
 	// so the key shares and threshold has been set to 1.
 	if len(resp.Keys) == 0 {
@@ -15,7 +15,9 @@ var (
 )
 
 type OperatorRaftSnapshotRestoreCommand struct {
-	flagForce bool
+	flagForce  bool
+	flagDryRun bool
+	flagMounts []string
 	*BaseCommand
 }
 
@@ -31,6 +33,15 @@ Usage: vault operator raft snapshot restore <snapshot_file>
 
 	  $ vault operator raft snapshot restore raft.snap
 
+  Report the mounts the snapshot would add or remove and the number of keys
+  it holds under each storage prefix, without installing it:
+
+	  $ vault operator raft snapshot restore -dry-run raft.snap
+
+  Restore only the data of the "secret/" mount from the snapshot:
+
+	  $ vault operator raft snapshot restore -mount=secret/ raft.snap
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
@@ -48,6 +59,23 @@ func (c *OperatorRaftSnapshotRestoreCommand) Flags() *FlagSets {
 		Usage:   "This bypasses checks ensuring the Autounseal or shamir keys are consistent with the snapshot data.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:    "dry-run",
+		Target:  &c.flagDryRun,
+		Default: false,
+		Usage: "Report the changes restoring the snapshot would make, without " +
+			"making them.",
+	})
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:   "mount",
+		Target: &c.flagMounts,
+		Usage: "Path of a secrets engine or auth method, such as \"auth/userpass/\", " +
+			"whose data is restored from the snapshot, leaving the rest of the " +
+			"cluster untouched. The mount must still exist. To specify multiple " +
+			"values, specify this flag multiple times.",
+	})
+
 	return set
 }
 
@@ -83,6 +111,11 @@ func (c *OperatorRaftSnapshotRestoreCommand) Run(args []string) int {
 		return 1
 	}
 
+	if c.flagForce && (c.flagDryRun || len(c.flagMounts) > 0) {
+		c.UI.Error("The -force flag cannot be used with -dry-run or -mount")
+		return 1
+	}
+
 	snapReader, err := os.Open(snapFile)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error opening policy file: %s", err))
@@ -96,6 +129,23 @@ func (c *OperatorRaftSnapshotRestoreCommand) Run(args []string) int {
 		return 2
 	}
 
+	switch {
+	case len(c.flagMounts) > 0:
+		secret, err := client.Sys().RaftSnapshotRestoreMounts(snapReader, c.flagMounts, c.flagDryRun)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error restoring mounts from the snapshot: %s", err))
+			return 2
+		}
+		return OutputSecret(c.UI, secret)
+	case c.flagDryRun:
+		secret, err := client.Sys().RaftSnapshotPreview(snapReader)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error previewing the snapshot: %s", err))
+			return 2
+		}
+		return OutputSecret(c.UI, secret)
+	}
+
 	err = client.Sys().RaftSnapshotRestore(snapReader, c.flagForce)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error installing the snapshot: %s", err))
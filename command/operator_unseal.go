@@ -22,6 +22,7 @@ type OperatorUnsealCommand struct {
 
 	flagReset   bool
 	flagMigrate bool
+	flagTOTP    string
 
 	testOutput io.Writer // for tests
 }
@@ -48,6 +49,11 @@ Usage: vault operator unseal [options] [KEY]
       $ vault operator unseal
       Key (will be hidden): IXyR0OJnSFobekZMMCKCoVEpT7wI6l+USMzE3IcyDyo=
 
+  If the unseal keys are TOTP protected, provide the current code of the TOTP
+  secret bound to the key as well:
+
+      $ vault operator unseal -totp=123456
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
@@ -78,6 +84,13 @@ func (c *OperatorUnsealCommand) Flags() *FlagSets {
 		Usage:      "Indicate that this share is provided with the intent that it is part of a seal migration process.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "totp",
+		Target:     &c.flagTOTP,
+		Completion: complete.PredictNothing,
+		Usage:      "Current TOTP code of the secret bound to the unseal key, when the unseal keys are TOTP protected.",
+	})
+
 	return set
 }
 
@@ -152,6 +165,7 @@ func (c *OperatorUnsealCommand) Run(args []string) int {
 	status, err := client.Sys().UnsealWithOptions(&api.UnsealOpts{
 		Key:     unsealKey,
 		Migrate: c.flagMigrate,
+		TOTP:    c.flagTOTP,
 	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error unsealing: %s", err))
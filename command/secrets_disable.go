@@ -3,6 +3,7 @@ package command
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/cli"
 	"github.com/posener/complete"
@@ -15,6 +16,8 @@ var (
 
 type SecretsDisableCommand struct {
 	*BaseCommand
+
+	flagRetention time.Duration
 }
 
 func (c *SecretsDisableCommand) Synopsis() string {
@@ -27,19 +30,38 @@ Usage: vault secrets disable [options] PATH
 
   Disables a secrets engine at the given PATH. The argument corresponds to
   the enabled PATH of the engine, not the TYPE! All secrets created by this
-  engine are revoked and its Vault data is removed.
+  engine are revoked and its Vault data is removed, unless a retention period
+  is given.
 
   Disable the secrets engine enabled at aws/:
 
       $ vault secrets disable aws/
 
+  Disable the secrets engine enabled at kv/, keeping its data for a week
+  during which it can be restored from sys/mounts/trash:
+
+      $ vault secrets disable -retention=168h kv/
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
 }
 
 func (c *SecretsDisableCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.DurationVar(&DurationVar{
+		Name:       "retention",
+		Target:     &c.flagRetention,
+		Completion: complete.PredictAnything,
+		Usage: "How long to keep the data of the secrets engine after it is " +
+			"disabled. Until then, the secrets engine can be restored from " +
+			"sys/mounts/trash. If unspecified, the data is removed immediately.",
+	})
+
+	return set
 }
 
 func (c *SecretsDisableCommand) AutocompleteArgs() complete.Predictor {
@@ -76,6 +98,17 @@ func (c *SecretsDisableCommand) Run(args []string) int {
 
 	path := ensureTrailingSlash(sanitizePath(args[0]))
 
+	if c.flagRetention > 0 {
+		if err := client.Sys().UnmountWithRetention(path, c.flagRetention.String()); err != nil {
+			c.UI.Error(fmt.Sprintf("Error disabling secrets engine at %s: %s", path, err))
+			return 2
+		}
+
+		c.UI.Output(fmt.Sprintf("Success! Disabled the secrets engine (if it existed) at: %s, "+
+			"its data is kept for %s", path, c.flagRetention))
+		return 0
+	}
+
 	if err := client.Sys().Unmount(path); err != nil {
 		c.UI.Error(fmt.Sprintf("Error disabling secrets engine at %s: %s", path, err))
 		return 2
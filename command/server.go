@@ -35,6 +35,8 @@ import (
 	"github.com/hashicorp/vault/command/server"
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/helper/constants"
+	"github.com/hashicorp/vault/helper/entropy"
+	vaultlogging "github.com/hashicorp/vault/helper/logging"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	vaulthttp "github.com/hashicorp/vault/http"
@@ -140,6 +142,7 @@ type ServerCommand struct {
 	flagTestServerConfig   bool
 	flagDevConsul          bool
 	flagExitOnCoreShutdown bool
+	flagCheckConfig        bool
 }
 
 func (c *ServerCommand) Synopsis() string {
@@ -164,6 +167,10 @@ Usage: vault server [options]
 
       $ vault server -dev -dev-root-token-id="root"
 
+  Validate a configuration before restarting a server with it:
+
+      $ vault server -config=/etc/vault/config.hcl -check-config
+
   For a full list of examples, please see the documentation.
 
 ` + c.Flags().Help()
@@ -222,6 +229,17 @@ func (c *ServerCommand) Flags() *FlagSets {
 			"Using a recovery operation token, \"sys/raw\" API can be used to manipulate the storage.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:   "check-config",
+		Target: &c.flagCheckConfig,
+		Usage: "Validate the configuration and exit without starting the server. " +
+			"This connects to the storage backend, verifies the seal configuration " +
+			"stored in it matches the configured seals, and validates the listener " +
+			"TLS material and telemetry sinks. The findings are output as a table, " +
+			"or as JSON if VAULT_FORMAT is \"json\", and the exit code is 1 if any " +
+			"check failed.",
+	})
+
 	f = set.NewFlagSet("Dev Options")
 
 	f.BoolVar(&BoolVar{
@@ -636,15 +654,12 @@ func (c *ServerCommand) runRecoveryMode() int {
 			return 1
 		}
 
-		lns = append(lns, listenerutil.Listener{
-			Listener: ln,
-			Config:   lnConfig,
-		})
+		lns = append(lns, *ln)
 	}
 
 	listenerCloseFunc := func() {
 		for _, ln := range lns {
-			ln.Listener.Close()
+			ln.Close()
 		}
 	}
 
@@ -687,6 +702,7 @@ func (c *ServerCommand) runRecoveryMode() int {
 
 	c.UI.Output("")
 
+	clientCertHeaders := listenerClientCertHeaders(lns)
 	for _, ln := range lns {
 		handler := vaulthttp.Handler(&vault.HandlerProperties{
 			Core:                  core,
@@ -694,6 +710,7 @@ func (c *ServerCommand) runRecoveryMode() int {
 			DisablePrintableCheck: config.DisablePrintableCheck,
 			RecoveryMode:          c.flagRecovery,
 			RecoveryToken:         atomic.NewString(""),
+			ClientCertHeaders:     clientCertHeaders,
 		})
 
 		server := &http.Server{
@@ -833,11 +850,23 @@ func (c *ServerCommand) processLogLevelAndFormat(config *server.Config) (hclog.L
 		return level, logLevelString, logLevelWasNotSet, logFormat, fmt.Errorf("unknown log level: %s", c.flagLogLevel)
 	}
 
+	logFormat, err := c.logFormat(config)
+	if err != nil {
+		return level, logLevelString, logLevelWasNotSet, logFormat, err
+	}
+
+	return level, logLevelString, logLevelWasNotSet, logFormat, nil
+}
+
+// logFormat returns the log format set by the -log-format flag, the
+// environment or else config, in that order.
+func (c *ServerCommand) logFormat(config *server.Config) (logging.LogFormat, error) {
+	logFormat := logging.UnspecifiedFormat
 	if c.flagLogFormat != notSetValue {
 		var err error
 		logFormat, err = logging.ParseLogFormat(c.flagLogFormat)
 		if err != nil {
-			return level, logLevelString, logLevelWasNotSet, logFormat, err
+			return logFormat, err
 		}
 	}
 	if logFormat == logging.UnspecifiedFormat {
@@ -847,11 +876,10 @@ func (c *ServerCommand) processLogLevelAndFormat(config *server.Config) (hclog.L
 		var err error
 		logFormat, err = logging.ParseLogFormat(config.LogFormat)
 		if err != nil {
-			return level, logLevelString, logLevelWasNotSet, logFormat, err
+			return logFormat, err
 		}
 	}
-
-	return level, logLevelString, logLevelWasNotSet, logFormat, nil
+	return logFormat, nil
 }
 
 type quiescenceSink struct {
@@ -992,10 +1020,7 @@ func (c *ServerCommand) InitListeners(config *server.Config, disableClustering b
 		}
 		props["max_request_duration"] = lnConfig.MaxRequestDuration.String()
 
-		lns = append(lns, listenerutil.Listener{
-			Listener: ln,
-			Config:   lnConfig,
-		})
+		lns = append(lns, *ln)
 
 		// Store the listener props for output later
 		key := fmt.Sprintf("listener %d", i+1)
@@ -1163,14 +1188,33 @@ func (c *ServerCommand) Run(args []string) int {
 			IndependentLevels: true,
 		})
 	} else {
-		c.logger = hclog.NewInterceptLogger(&hclog.LoggerOptions{
+		opts := &hclog.LoggerOptions{
 			Output:            c.gatedWriter,
 			Level:             level,
 			IndependentLevels: true,
 			// Note that if logFormat is either unspecified or standard, then
 			// the resulting logger's format will be standard.
 			JSONFormat: logFormat == logging.JSONFormat,
-		})
+		}
+
+		// Collapse floods of identical messages, such as storage errors
+		// during an outage, into periodic summaries
+		var sampler *vaultlogging.LogSampler
+		if config.LogSamplingInterval > 0 {
+			sampler = vaultlogging.NewLogSampler(config.LogSamplingBurst, config.LogSamplingInterval)
+			opts.Exclude = sampler.Exclude
+		}
+
+		c.logger = hclog.NewInterceptLogger(opts)
+
+		if sampler != nil {
+			sampler.SetLogger(c.logger)
+			go sampler.Run(c.ShutdownCh)
+		}
+	}
+
+	if c.flagCheckConfig {
+		return c.runConfigCheck(config, configErrors)
 	}
 
 	// reporting Errors found in the config
@@ -1332,6 +1376,13 @@ func (c *ServerCommand) Run(args []string) int {
 		c.UI.Error(err.Error())
 		return 1
 	}
+	if reader, ok := secureRandomReader.(*entropy.AugmentedReader); ok {
+		reader.SetLogger(c.logger.Named("entropy"))
+		reader.Check()
+		if healthy, err := reader.Healthy(); !healthy {
+			c.UI.Warn(fmt.Sprintf("WARNING: The external entropy source is unhealthy: %v", err))
+		}
+	}
 
 	coreConfig := createCoreConfig(c, config, backend, configSR, barrierSeal, unwrapSeal, metricsHelper, metricSink, secureRandomReader)
 	if c.flagDevThreeNode {
@@ -1460,7 +1511,7 @@ func (c *ServerCommand) Run(args []string) int {
 	// Make sure we close all listeners from this point on
 	listenerCloseFunc := func() {
 		for _, ln := range lns {
-			ln.Listener.Close()
+			ln.Close()
 		}
 	}
 
@@ -1642,10 +1693,15 @@ func (c *ServerCommand) Run(args []string) int {
 			var config *server.Config
 			var level hclog.Level
 			var configErrors []configutil.ConfigError
+			reloadStatus := &vault.ConfigReloadStatus{
+				Time: time.Now(),
+			}
+			oldConfig := core.GetCoreConfigInternal()
 			for _, path := range c.flagConfigs {
 				current, err := server.LoadConfig(path)
 				if err != nil {
 					c.logger.Error("could not reload config", "path", path, "error", err)
+					reloadStatus.Errors = append(reloadStatus.Errors, fmt.Sprintf("could not reload config %q: %s", path, err))
 					goto RUNRELOADFUNCS
 				}
 
@@ -1661,6 +1717,7 @@ func (c *ServerCommand) Run(args []string) int {
 			// Ensure at least one config was found.
 			if config == nil {
 				c.logger.Error("no config found at reload time")
+				reloadStatus.Errors = append(reloadStatus.Errors, "no config found at reload time")
 				goto RUNRELOADFUNCS
 			}
 
@@ -1669,6 +1726,15 @@ func (c *ServerCommand) Run(args []string) int {
 				c.logger.Warn(cErr.String())
 			}
 
+			// Resolve the log format as on startup, so that it only shows as
+			// changed if the format in use would change
+			if logFormat, err := c.logFormat(config); err == nil {
+				config.LogFormat = logFormat.String()
+			}
+			if oldConfig != nil {
+				reloadStatus.Changes = server.DiffConfig(oldConfig, config)
+			}
+
 			core.SetConfig(config)
 
 			// reloading custom response headers to make sure we have
@@ -1680,12 +1746,19 @@ func (c *ServerCommand) Run(args []string) int {
 			// Setting log request with the new value in the config after reload
 			core.ReloadLogRequestsLevel()
 
+			// Reopen the GeoIP databases, which may have been updated
+			if err := core.ReloadGeoIP(); err != nil {
+				c.logger.Error(err.Error())
+			}
+
 			// reloading HCP link
 			hcpLink, err = c.reloadHCPLink(hcpLink, config, core, hcpLogger)
 			if err != nil {
 				c.logger.Error(err.Error())
 			}
 
+			c.reloadTelemetry(config, reloadStatus.Changes)
+
 			if config.LogLevel != "" {
 				configLogLevel := strings.ToLower(strings.TrimSpace(config.LogLevel))
 				switch configLogLevel {
@@ -1701,6 +1774,11 @@ func (c *ServerCommand) Run(args []string) int {
 					level = hclog.Error
 				default:
 					c.logger.Error("unknown log level found on reload", "level", config.LogLevel)
+					for _, change := range reloadStatus.Changes {
+						if change.Setting == "log_level" {
+							change.Error = fmt.Sprintf("unknown log level %q", config.LogLevel)
+						}
+					}
 					goto RUNRELOADFUNCS
 				}
 				core.SetLogLevel(level)
@@ -1709,7 +1787,9 @@ func (c *ServerCommand) Run(args []string) int {
 		RUNRELOADFUNCS:
 			if err := c.Reload(c.reloadFuncsLock, c.reloadFuncs, c.flagConfigs); err != nil {
 				c.UI.Error(fmt.Sprintf("Error(s) were encountered during reload: %s", err))
+				reloadStatus.Errors = append(reloadStatus.Errors, err.Error())
 			}
+			c.recordConfigReload(core, reloadStatus)
 
 			// Reload license file
 			if err = vault.LicenseReload(core); err != nil {
@@ -1792,6 +1872,59 @@ func (c *ServerCommand) Run(args []string) int {
 	return retCode
 }
 
+// reloadTelemetry applies the telemetry settings of config among changes
+// that can be reloaded, if any.
+func (c *ServerCommand) reloadTelemetry(config *server.Config, changes []*server.ConfigChange) {
+	var reloaded []*server.ConfigChange
+	for _, change := range changes {
+		if change.Reloadable && strings.HasPrefix(change.Setting, "telemetry.") {
+			reloaded = append(reloaded, change)
+		}
+	}
+	if len(reloaded) == 0 {
+		return
+	}
+
+	err := configutil.ReloadTelemetry(&configutil.SetupTelemetryOpts{
+		Config:      config.Telemetry,
+		Ui:          c.UI,
+		ServiceName: "vault",
+		DisplayName: "Vault",
+		UserAgent:   useragent.String(),
+		ClusterName: config.ClusterName,
+	})
+	if err != nil {
+		c.logger.Error("could not reload telemetry", "error", err)
+		for _, change := range reloaded {
+			change.Error = err.Error()
+		}
+	}
+}
+
+// recordConfigReload marks the reloadable changes of status that did not fail
+// as applied, logs them and makes them available through
+// sys/config/reload-status.
+func (c *ServerCommand) recordConfigReload(core *vault.Core, status *vault.ConfigReloadStatus) {
+	var applied, restart []string
+	for _, change := range status.Changes {
+		switch {
+		case !change.Reloadable:
+			restart = append(restart, change.Setting)
+		case change.Error == "":
+			change.Applied = true
+			applied = append(applied, change.Setting)
+		}
+	}
+	if len(applied) > 0 {
+		c.logger.Info("applied configuration changes", "settings", applied)
+	}
+	if len(restart) > 0 {
+		c.logger.Warn("configuration changes require a restart to be applied", "settings", restart)
+	}
+
+	core.SetConfigReloadStatus(status)
+}
+
 func (c *ServerCommand) reloadHCPLink(hcpLinkVault *hcp_link.WrappedHCPLinkVault, conf *server.Config, core *vault.Core, hcpLogger hclog.Logger) (*hcp_link.WrappedHCPLinkVault, error) {
 	// trigger a shutdown
 	if hcpLinkVault != nil {
@@ -2696,9 +2829,13 @@ func createCoreConfig(c *ServerCommand, config *server.Config, backend physical.
 		DefaultLeaseTTL:                config.DefaultLeaseTTL,
 		ClusterName:                    config.ClusterName,
 		CacheSize:                      config.CacheSize,
+		CacheSizeBytes:                 config.CacheSizeBytes,
+		CachePinnedPrefixes:            config.CachePinnedPrefixes,
 		PluginDirectory:                config.PluginDirectory,
 		PluginFileUid:                  config.PluginFileUid,
 		PluginFilePermissions:          config.PluginFilePermissions,
+		GeoIPCountryDatabase:           config.GeoIPCountryDatabase,
+		GeoIPASNDatabase:               config.GeoIPASNDatabase,
 		EnableUI:                       config.EnableUI,
 		EnableRaw:                      config.EnableRawEndpoint,
 		DisableSealWrap:                config.DisableSealWrap,
@@ -2712,6 +2849,8 @@ func createCoreConfig(c *ServerCommand, config *server.Config, backend physical.
 		SecureRandomReader:             secureRandomReader,
 		EnableResponseHeaderHostname:   config.EnableResponseHeaderHostname,
 		EnableResponseHeaderRaftNodeID: config.EnableResponseHeaderRaftNodeID,
+		TokenAccessorWriteBehind:       config.TokenAccessorWriteBehind,
+		LazyLeaseRestore:               config.LazyLeaseRestore,
 		License:                        config.License,
 		LicensePath:                    config.LicensePath,
 		DisableSSCTokens:               config.DisableSSCTokens,
@@ -2879,8 +3018,21 @@ func initDevCore(c *ServerCommand, coreConfig *vault.CoreConfig, config *server.
 	return nil
 }
 
+// listenerClientCertHeaders returns the client certificate headers set by any
+// of the listeners.
+func listenerClientCertHeaders(lns []listenerutil.Listener) []string {
+	var headers []string
+	for _, ln := range lns {
+		if ln.Config != nil && ln.Config.TLSClientCertHeader != "" {
+			headers = append(headers, ln.Config.TLSClientCertHeader)
+		}
+	}
+	return headers
+}
+
 // Initialize the HTTP servers
 func startHttpServers(c *ServerCommand, core *vault.Core, config *server.Config, lns []listenerutil.Listener) error {
+	clientCertHeaders := listenerClientCertHeaders(lns)
 	for _, ln := range lns {
 		if ln.Config == nil {
 			return fmt.Errorf("Found nil listener config after parsing")
@@ -2895,6 +3047,7 @@ func startHttpServers(c *ServerCommand, core *vault.Core, config *server.Config,
 			ListenerConfig:        ln.Config,
 			DisablePrintableCheck: config.DisablePrintableCheck,
 			RecoveryMode:          c.flagRecovery,
+			ClientCertHeaders:     clientCertHeaders,
 		})
 
 		if len(ln.Config.XForwardedForAuthorizedAddrs) > 0 {
@@ -2902,7 +3055,7 @@ func startHttpServers(c *ServerCommand, core *vault.Core, config *server.Config,
 		}
 
 		// server defaults
-		server := &http.Server{
+		srv := &http.Server{
 			Handler:           handler,
 			ReadHeaderTimeout: 10 * time.Second,
 			ReadTimeout:       30 * time.Second,
@@ -2912,16 +3065,16 @@ func startHttpServers(c *ServerCommand, core *vault.Core, config *server.Config,
 
 		// override server defaults with config values for read/write/idle timeouts if configured
 		if ln.Config.HTTPReadHeaderTimeout > 0 {
-			server.ReadHeaderTimeout = ln.Config.HTTPReadHeaderTimeout
+			srv.ReadHeaderTimeout = ln.Config.HTTPReadHeaderTimeout
 		}
 		if ln.Config.HTTPReadTimeout > 0 {
-			server.ReadTimeout = ln.Config.HTTPReadTimeout
+			srv.ReadTimeout = ln.Config.HTTPReadTimeout
 		}
 		if ln.Config.HTTPWriteTimeout > 0 {
-			server.WriteTimeout = ln.Config.HTTPWriteTimeout
+			srv.WriteTimeout = ln.Config.HTTPWriteTimeout
 		}
 		if ln.Config.HTTPIdleTimeout > 0 {
-			server.IdleTimeout = ln.Config.HTTPIdleTimeout
+			srv.IdleTimeout = ln.Config.HTTPIdleTimeout
 		}
 
 		// server config tests can exit now
@@ -2929,7 +3082,13 @@ func startHttpServers(c *ServerCommand, core *vault.Core, config *server.Config,
 			continue
 		}
 
-		go server.Serve(ln.Listener)
+		if ln.HTTP3 != nil {
+			h3Server := server.NewHTTP3Server(handler, ln.HTTP3, ln.Config)
+			srv.Handler = server.WrapAltSvcHandler(srv.Handler, ln.HTTP3, ln.Config)
+			go h3Server.Serve(ln.HTTP3.PacketConn)
+		}
+
+		go srv.Serve(ln.Listener)
 	}
 	return nil
 }
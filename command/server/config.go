@@ -45,7 +45,12 @@ type Config struct {
 
 	ServiceRegistration *ServiceRegistration `hcl:"-"`
 
+	KMSLibraries []*KMSLibrary `hcl:"-"`
+
 	CacheSize                int         `hcl:"cache_size"`
+	CacheSizeBytes           int64       `hcl:"-"`
+	CacheSizeBytesRaw        interface{} `hcl:"cache_size_bytes"`
+	CachePinnedPrefixes      []string    `hcl:"cache_pinned_prefixes"`
 	DisableCache             bool        `hcl:"-"`
 	DisableCacheRaw          interface{} `hcl:"disable_cache"`
 	DisablePrintableCheck    bool        `hcl:"-"`
@@ -68,6 +73,9 @@ type Config struct {
 	PluginFilePermissions    int         `hcl:"-"`
 	PluginFilePermissionsRaw interface{} `hcl:"plugin_file_permissions,alias:PluginFilePermissions"`
 
+	GeoIPCountryDatabase string `hcl:"geoip_country_database"`
+	GeoIPASNDatabase     string `hcl:"geoip_asn_database"`
+
 	EnableRawEndpoint    bool        `hcl:"-"`
 	EnableRawEndpointRaw interface{} `hcl:"raw_storage_endpoint,alias:EnableRawEndpoint"`
 
@@ -94,9 +102,19 @@ type Config struct {
 	LogRequestsLevel    string      `hcl:"-"`
 	LogRequestsLevelRaw interface{} `hcl:"log_requests_level"`
 
+	LogSamplingInterval    time.Duration `hcl:"-"`
+	LogSamplingIntervalRaw interface{}   `hcl:"log_sampling_interval"`
+	LogSamplingBurst       int           `hcl:"log_sampling_burst"`
+
 	EnableResponseHeaderRaftNodeID    bool        `hcl:"-"`
 	EnableResponseHeaderRaftNodeIDRaw interface{} `hcl:"enable_response_header_raft_node_id"`
 
+	TokenAccessorWriteBehind    bool        `hcl:"-"`
+	TokenAccessorWriteBehindRaw interface{} `hcl:"token_accessor_write_behind"`
+
+	LazyLeaseRestore    bool        `hcl:"-"`
+	LazyLeaseRestoreRaw interface{} `hcl:"lazy_lease_restore"`
+
 	License          string `hcl:"-"`
 	LicensePath      string `hcl:"license_path"`
 	DisableSSCTokens bool   `hcl:"-"`
@@ -241,6 +259,19 @@ func (b *ServiceRegistration) GoString() string {
 	return fmt.Sprintf("*%#v", *b)
 }
 
+// KMSLibrary is a PKCS#11 library that managed keys can be backed by. Keys
+// refer to libraries by name so the library paths are only set by the
+// operator of the server.
+type KMSLibrary struct {
+	Type    string
+	Name    string
+	Library string
+}
+
+func (k *KMSLibrary) GoString() string {
+	return fmt.Sprintf("*%#v", *k)
+}
+
 func NewConfig() *Config {
 	return &Config{
 		SharedConfig: new(configutil.SharedConfig),
@@ -275,11 +306,26 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.ServiceRegistration = c2.ServiceRegistration
 	}
 
+	result.KMSLibraries = c.KMSLibraries
+	if len(c2.KMSLibraries) > 0 {
+		result.KMSLibraries = c2.KMSLibraries
+	}
+
 	result.CacheSize = c.CacheSize
 	if c2.CacheSize != 0 {
 		result.CacheSize = c2.CacheSize
 	}
 
+	result.CacheSizeBytes = c.CacheSizeBytes
+	if c2.CacheSizeBytes != 0 {
+		result.CacheSizeBytes = c2.CacheSizeBytes
+	}
+
+	result.CachePinnedPrefixes = c.CachePinnedPrefixes
+	if c2.CachePinnedPrefixes != nil {
+		result.CachePinnedPrefixes = c2.CachePinnedPrefixes
+	}
+
 	// merging these booleans via an OR operation
 	result.DisableCache = c.DisableCache
 	if c2.DisableCache {
@@ -345,6 +391,16 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.PluginDirectory = c2.PluginDirectory
 	}
 
+	result.GeoIPCountryDatabase = c.GeoIPCountryDatabase
+	if c2.GeoIPCountryDatabase != "" {
+		result.GeoIPCountryDatabase = c2.GeoIPCountryDatabase
+	}
+
+	result.GeoIPASNDatabase = c.GeoIPASNDatabase
+	if c2.GeoIPASNDatabase != "" {
+		result.GeoIPASNDatabase = c2.GeoIPASNDatabase
+	}
+
 	result.PluginFileUid = c.PluginFileUid
 	if c2.PluginFileUid != 0 {
 		result.PluginFileUid = c2.PluginFileUid
@@ -381,11 +437,31 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.LogRequestsLevel = c2.LogRequestsLevel
 	}
 
+	result.LogSamplingInterval = c.LogSamplingInterval
+	if c2.LogSamplingInterval > 0 {
+		result.LogSamplingInterval = c2.LogSamplingInterval
+	}
+
+	result.LogSamplingBurst = c.LogSamplingBurst
+	if c2.LogSamplingBurst > 0 {
+		result.LogSamplingBurst = c2.LogSamplingBurst
+	}
+
 	result.EnableResponseHeaderRaftNodeID = c.EnableResponseHeaderRaftNodeID
 	if c2.EnableResponseHeaderRaftNodeID {
 		result.EnableResponseHeaderRaftNodeID = c2.EnableResponseHeaderRaftNodeID
 	}
 
+	result.TokenAccessorWriteBehind = c.TokenAccessorWriteBehind
+	if c2.TokenAccessorWriteBehind {
+		result.TokenAccessorWriteBehind = c2.TokenAccessorWriteBehind
+	}
+
+	result.LazyLeaseRestore = c.LazyLeaseRestore
+	if c2.LazyLeaseRestore {
+		result.LazyLeaseRestore = c2.LazyLeaseRestore
+	}
+
 	result.LicensePath = c.LicensePath
 	if c2.LicensePath != "" {
 		result.LicensePath = c2.LicensePath
@@ -561,6 +637,14 @@ func ParseConfig(d, source string) (*Config, error) {
 		}
 	}
 
+	if result.CacheSizeBytesRaw != nil {
+		cacheSizeBytes, err := parseutil.ParseCapacityString(result.CacheSizeBytesRaw)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing cache_size_bytes: %w", err)
+		}
+		result.CacheSizeBytes = int64(cacheSizeBytes)
+	}
+
 	if result.DisablePrintableCheckRaw != nil {
 		if result.DisablePrintableCheck, err = parseutil.ParseBool(result.DisablePrintableCheckRaw); err != nil {
 			return nil, err
@@ -629,12 +713,37 @@ func ParseConfig(d, source string) (*Config, error) {
 		result.LogRequestsLevelRaw = ""
 	}
 
+	if result.LogSamplingIntervalRaw != nil {
+		if result.LogSamplingInterval, err = parseutil.ParseDurationSecond(result.LogSamplingIntervalRaw); err != nil {
+			return nil, err
+		}
+		result.LogSamplingIntervalRaw = nil
+	}
+	if result.LogSamplingInterval < 0 {
+		return nil, fmt.Errorf("log_sampling_interval must not be negative")
+	}
+	if result.LogSamplingBurst < 0 {
+		return nil, fmt.Errorf("log_sampling_burst must not be negative")
+	}
+
 	if result.EnableResponseHeaderRaftNodeIDRaw != nil {
 		if result.EnableResponseHeaderRaftNodeID, err = parseutil.ParseBool(result.EnableResponseHeaderRaftNodeIDRaw); err != nil {
 			return nil, err
 		}
 	}
 
+	if result.TokenAccessorWriteBehindRaw != nil {
+		if result.TokenAccessorWriteBehind, err = parseutil.ParseBool(result.TokenAccessorWriteBehindRaw); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.LazyLeaseRestoreRaw != nil {
+		if result.LazyLeaseRestore, err = parseutil.ParseBool(result.LazyLeaseRestoreRaw); err != nil {
+			return nil, err
+		}
+	}
+
 	list, ok := obj.Node.(*ast.ObjectList)
 	if !ok {
 		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
@@ -678,6 +787,13 @@ func ParseConfig(d, source string) (*Config, error) {
 		}
 	}
 
+	if o := list.Filter("kms_library"); len(o.Items) > 0 {
+		delete(result.UnusedKeys, "kms_library")
+		if err := parseKMSLibraries(result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'kms_library': %w", err)
+		}
+	}
+
 	if err := result.parseConfig(list); err != nil {
 		return nil, fmt.Errorf("error parsing enterprise config: %w", err)
 	}
@@ -957,6 +1073,41 @@ func parseServiceRegistration(result *Config, list *ast.ObjectList, name string)
 	return nil
 }
 
+func parseKMSLibraries(result *Config, list *ast.ObjectList) error {
+	names := make(map[string]bool)
+	for _, item := range list.Items {
+		if len(item.Keys) == 0 {
+			return errors.New("kms_library blocks must be labeled with the library type")
+		}
+		typ := strings.ToLower(item.Keys[0].Token.Value().(string))
+		if typ != "pkcs11" {
+			return fmt.Errorf("unsupported kms_library type %q", typ)
+		}
+
+		var m struct {
+			Name    string `hcl:"name"`
+			Library string `hcl:"library"`
+		}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("kms_library.%s:", typ))
+		}
+		if m.Name == "" || m.Library == "" {
+			return fmt.Errorf("kms_library.%s: name and library are required", typ)
+		}
+		if names[m.Name] {
+			return fmt.Errorf("kms_library.%s: duplicate library name %q", typ, m.Name)
+		}
+		names[m.Name] = true
+
+		result.KMSLibraries = append(result.KMSLibraries, &KMSLibrary{
+			Type:    typ,
+			Name:    m.Name,
+			Library: m.Library,
+		})
+	}
+	return nil
+}
+
 // Sanitized returns a copy of the config with all values that are considered
 // sensitive stripped. It also strips all `*Raw` values that are mainly
 // used for parsing.
@@ -975,6 +1126,8 @@ func (c *Config) Sanitized() map[string]interface{} {
 	sharedResult := c.SharedConfig.Sanitized()
 	result := map[string]interface{}{
 		"cache_size":              c.CacheSize,
+		"cache_size_bytes":        c.CacheSizeBytes,
+		"cache_pinned_prefixes":   c.CachePinnedPrefixes,
 		"disable_sentinel_trace":  c.DisableSentinelTrace,
 		"disable_cache":           c.DisableCache,
 		"disable_printable_check": c.DisablePrintableCheck,
@@ -992,6 +1145,9 @@ func (c *Config) Sanitized() map[string]interface{} {
 
 		"plugin_file_permissions": c.PluginFilePermissions,
 
+		"geoip_country_database": c.GeoIPCountryDatabase,
+		"geoip_asn_database":     c.GeoIPASNDatabase,
+
 		"raw_storage_endpoint": c.EnableRawEndpoint,
 
 		"api_addr":           c.APIAddr,
@@ -1008,7 +1164,15 @@ func (c *Config) Sanitized() map[string]interface{} {
 
 		"enable_response_header_raft_node_id": c.EnableResponseHeaderRaftNodeID,
 
+		"token_accessor_write_behind": c.TokenAccessorWriteBehind,
+
+		"lazy_lease_restore": c.LazyLeaseRestore,
+
 		"log_requests_level": c.LogRequestsLevel,
+
+		"log_sampling_interval": c.LogSamplingInterval / time.Second,
+
+		"log_sampling_burst": c.LogSamplingBurst,
 	}
 	for k, v := range sharedResult {
 		result[k] = v
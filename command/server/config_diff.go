@@ -0,0 +1,156 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/vault/internalshared/configutil"
+)
+
+// ConfigChange is a setting whose value differs between the configuration a
+// server runs with and the one it reloads.
+type ConfigChange struct {
+	// Setting is the name of the setting, prefixed with the name of its
+	// stanza, such as "telemetry.statsd_address" or
+	// "listener[127.0.0.1:8200].max_request_size".
+	Setting string `json:"setting"`
+
+	// Reloadable is whether the setting is applied on reload, rather than on
+	// the next restart.
+	Reloadable bool `json:"reloadable"`
+
+	// Applied is whether the new value was applied, and Error why it could
+	// not be.
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// reloadableTelemetrySettings are the telemetry settings applied on reload:
+// the ones configuring the sinks metrics are pushed to, and the filters of
+// the metrics. The Prometheus sink and the settings of the metrics
+// themselves only change on restart.
+var reloadableTelemetrySettings = []string{
+	"statsite_address",
+	"statsd_address",
+	"dogstatsd_addr",
+	"dogstatsd_tags",
+	"circonus_",
+	"stackdriver_",
+	"prefix_filter",
+}
+
+// DiffConfig returns the settings that changed from old to new, for the
+// sections of the configuration that are reloaded on SIGHUP: logging,
+// telemetry and the settings of listeners whose address did not change.
+func DiffConfig(old, new *Config) []*ConfigChange {
+	var changes []*ConfigChange
+	add := func(setting string, reloadable bool) {
+		changes = append(changes, &ConfigChange{
+			Setting:    setting,
+			Reloadable: reloadable,
+		})
+	}
+
+	if !strings.EqualFold(old.LogLevel, new.LogLevel) {
+		add("log_level", true)
+	}
+	// Loggers are created with their format, which cannot be changed after
+	if old.LogFormat != new.LogFormat {
+		add("log_format", false)
+	}
+	if old.LogRequestsLevel != new.LogRequestsLevel {
+		add("log_requests_level", true)
+	}
+
+	oldTelemetry, newTelemetry := old.Telemetry, new.Telemetry
+	if oldTelemetry == nil {
+		oldTelemetry = &configutil.Telemetry{}
+	}
+	if newTelemetry == nil {
+		newTelemetry = &configutil.Telemetry{}
+	}
+	for _, setting := range diffTelemetry(oldTelemetry, newTelemetry) {
+		reloadable := false
+		for _, prefix := range reloadableTelemetrySettings {
+			if strings.HasPrefix(setting, prefix) {
+				reloadable = true
+				break
+			}
+		}
+		add("telemetry."+setting, reloadable)
+	}
+
+	oldListeners := make(map[string]*configutil.Listener, len(old.Listeners))
+	for _, l := range old.Listeners {
+		oldListeners[l.Address] = l
+	}
+	newListeners := make(map[string]*configutil.Listener, len(new.Listeners))
+	for _, l := range new.Listeners {
+		newListeners[l.Address] = l
+	}
+	for _, l := range new.Listeners {
+		prefix := fmt.Sprintf("listener[%s]", l.Address)
+		oldListener, ok := oldListeners[l.Address]
+		if !ok {
+			add(prefix, false)
+			continue
+		}
+		if oldListener.MaxRequestSize != l.MaxRequestSize {
+			add(prefix+".max_request_size", true)
+		}
+		if oldListener.MaxRequestDuration != l.MaxRequestDuration {
+			add(prefix+".max_request_duration", true)
+		}
+		if oldListener.Profiling.UnauthenticatedPProfAccess != l.Profiling.UnauthenticatedPProfAccess {
+			add(prefix+".profiling.unauthenticated_pprof_access", true)
+		}
+	}
+	for _, l := range old.Listeners {
+		if _, ok := newListeners[l.Address]; !ok {
+			add(fmt.Sprintf("listener[%s]", l.Address), false)
+		}
+	}
+
+	return changes
+}
+
+// diffTelemetry returns the names of the telemetry settings that differ. The
+// parsed value of settings is compared rather than their raw value when they
+// have both.
+func diffTelemetry(old, new *configutil.Telemetry) []string {
+	var settings []string
+
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	t := oldValue.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		switch {
+		case field.Name == "FoundKeys" || field.Name == "UnusedKeys":
+			continue
+		case strings.HasSuffix(field.Name, "Raw"):
+			if _, ok := t.FieldByName(strings.TrimSuffix(field.Name, "Raw")); ok {
+				continue
+			}
+		}
+
+		tag := field.Tag.Get("hcl")
+		if tag == "" || tag == "-" {
+			raw, ok := t.FieldByName(field.Name + "Raw")
+			if !ok {
+				continue
+			}
+			tag = raw.Tag.Get("hcl")
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			settings = append(settings, name)
+		}
+	}
+
+	return settings
+}
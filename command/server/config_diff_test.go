@@ -0,0 +1,106 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/internalshared/configutil"
+)
+
+func TestDiffConfig(t *testing.T) {
+	config := func(modify func(*Config)) *Config {
+		c := &Config{
+			SharedConfig: &configutil.SharedConfig{
+				LogLevel:  "info",
+				LogFormat: "standard",
+				Telemetry: &configutil.Telemetry{
+					StatsdAddr:              "127.0.0.1:8125",
+					PrometheusRetentionTime: time.Minute,
+					PrefixFilter:            []string{"-vault.core"},
+				},
+				Listeners: []*configutil.Listener{
+					{
+						Type:           "tcp",
+						Address:        "127.0.0.1:8200",
+						MaxRequestSize: 1024,
+					},
+				},
+			},
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	cases := map[string]struct {
+		modify   func(*Config)
+		expected []*ConfigChange
+	}{
+		"no changes": {
+			modify: func(c *Config) {
+				c.LogLevel = "INFO"
+			},
+		},
+		"logging": {
+			modify: func(c *Config) {
+				c.LogLevel = "debug"
+				c.LogFormat = "json"
+			},
+			expected: []*ConfigChange{
+				{Setting: "log_level", Reloadable: true},
+				{Setting: "log_format", Reloadable: false},
+			},
+		},
+		"telemetry": {
+			modify: func(c *Config) {
+				c.Telemetry.StatsdAddr = "127.0.0.1:9125"
+				c.Telemetry.PrometheusRetentionTime = time.Hour
+				c.Telemetry.PrefixFilter = nil
+			},
+			expected: []*ConfigChange{
+				{Setting: "telemetry.statsd_address", Reloadable: true},
+				{Setting: "telemetry.prometheus_retention_time", Reloadable: false},
+				{Setting: "telemetry.prefix_filter", Reloadable: true},
+			},
+		},
+		"listeners": {
+			modify: func(c *Config) {
+				c.Listeners[0].MaxRequestSize = 2048
+				c.Listeners[0].MaxRequestDuration = time.Minute
+				c.Listeners[0].Profiling.UnauthenticatedPProfAccess = true
+				c.Listeners = append(c.Listeners, &configutil.Listener{
+					Type:    "tcp",
+					Address: "127.0.0.1:8300",
+				})
+			},
+			expected: []*ConfigChange{
+				{Setting: "listener[127.0.0.1:8200].max_request_size", Reloadable: true},
+				{Setting: "listener[127.0.0.1:8200].max_request_duration", Reloadable: true},
+				{Setting: "listener[127.0.0.1:8200].profiling.unauthenticated_pprof_access", Reloadable: true},
+				{Setting: "listener[127.0.0.1:8300]", Reloadable: false},
+			},
+		},
+		"removed listener": {
+			modify: func(c *Config) {
+				c.Listeners = nil
+			},
+			expected: []*ConfigChange{
+				{Setting: "listener[127.0.0.1:8200]", Reloadable: false},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			changes := DiffConfig(config(nil), config(tc.modify))
+			if !reflect.DeepEqual(changes, tc.expected) {
+				for _, c := range changes {
+					t.Logf("change: %+v", *c)
+				}
+				t.Fatalf("bad: got %d changes, expected %d", len(changes), len(tc.expected))
+			}
+		})
+	}
+}
@@ -4,16 +4,18 @@ package server
 
 import (
 	"testing"
+
+	"github.com/hashicorp/vault/internalshared/configutil"
 )
 
 func TestLoadConfigFile_topLevel(t *testing.T) {
-	testLoadConfigFile_topLevel(t, nil)
+	testLoadConfigFile_topLevel(t, &configutil.Entropy{Mode: configutil.EntropyAugmentation, Type: configutil.EntropyTypeSeal})
 }
 
 func TestLoadConfigFile_json2(t *testing.T) {
-	testLoadConfigFile_json2(t, nil)
+	testLoadConfigFile_json2(t, &configutil.Entropy{Mode: configutil.EntropyAugmentation, Type: configutil.EntropyTypeSeal})
 }
 
 func TestParseEntropy(t *testing.T) {
-	testParseEntropy(t, true)
+	testParseEntropy(t)
 }
@@ -263,7 +263,7 @@ func testLoadConfigFile_json2(t *testing.T, entropy *configutil.Entropy) {
 	}
 }
 
-func testParseEntropy(t *testing.T, oss bool) {
+func testParseEntropy(t *testing.T) {
 	tests := []struct {
 		inConfig   string
 		outErr     error
@@ -274,7 +274,34 @@ func testParseEntropy(t *testing.T, oss bool) {
 				mode = "augmentation"
 				}`,
 			outErr:     nil,
-			outEntropy: configutil.Entropy{Mode: configutil.EntropyAugmentation},
+			outEntropy: configutil.Entropy{Mode: configutil.EntropyAugmentation, Type: configutil.EntropyTypeSeal},
+		},
+		{
+			inConfig: `entropy "esv" {
+				mode = "augmentation"
+				address = "https://esv.example.com/random"
+				fallback = "platform"
+				}`,
+			outErr: nil,
+			outEntropy: configutil.Entropy{
+				Mode:     configutil.EntropyAugmentation,
+				Type:     configutil.EntropyTypeESV,
+				Fallback: "platform",
+				Address:  "https://esv.example.com/random",
+			},
+		},
+		{
+			inConfig: `entropy "esv" {
+				mode = "augmentation"
+				}`,
+			outErr: fmt.Errorf("entropy.esv: address is required"),
+		},
+		{
+			inConfig: `entropy "seal" {
+				mode = "augmentation"
+				fallback = "sometimes"
+				}`,
+			outErr: fmt.Errorf("the specified entropy fallback %q is not supported", "sometimes"),
 		},
 		{
 			inConfig: `entropy "seal" {
@@ -286,7 +313,7 @@ func testParseEntropy(t *testing.T, oss bool) {
 			inConfig: `entropy "device_that_is_not_supported" {
 				mode = "augmentation"
 				}`,
-			outErr: fmt.Errorf("only the %q type of external entropy is supported", "seal"),
+			outErr: fmt.Errorf("only the %q and %q types of external entropy are supported", "seal", "esv"),
 		},
 		{
 			inConfig: `entropy "seal" {
@@ -310,10 +337,6 @@ func testParseEntropy(t *testing.T, oss bool) {
 		err := configutil.ParseEntropy(config.SharedConfig, objList, "entropy")
 		// validate the error, both should be nil or have the same Error()
 		switch {
-		case oss:
-			if config.Entropy != nil {
-				t.Fatalf("parsing Entropy should not be possible in oss but got a non-nil config.Entropy: %#v", config.Entropy)
-			}
 		case err != nil && test.outErr != nil:
 			if err.Error() != test.outErr.Error() {
 				t.Fatalf("error mismatch: expected %#v got %#v", err, test.outErr)
@@ -725,6 +748,8 @@ func testConfig_Sanitized(t *testing.T) {
 	expected := map[string]interface{}{
 		"api_addr":                            "top_level_api_addr",
 		"cache_size":                          0,
+		"cache_size_bytes":                    int64(0),
+		"cache_pinned_prefixes":               []string(nil),
 		"cluster_addr":                        "top_level_cluster_addr",
 		"cluster_cipher_suites":               "",
 		"cluster_name":                        "testcluster",
@@ -745,6 +770,10 @@ func testConfig_Sanitized(t *testing.T) {
 		"enable_response_header_hostname":     false,
 		"enable_response_header_raft_node_id": false,
 		"log_requests_level":                  "basic",
+		"log_sampling_interval":               0 * time.Second,
+		"log_sampling_burst":                  0,
+		"token_accessor_write_behind":         false,
+		"lazy_lease_restore":                  false,
 		"ha_storage": map[string]interface{}{
 			"cluster_addr":       "top_level_cluster_addr",
 			"disable_clustering": true,
@@ -759,11 +788,13 @@ func testConfig_Sanitized(t *testing.T) {
 				"type": "tcp",
 			},
 		},
-		"log_format":       "",
-		"log_level":        "",
-		"max_lease_ttl":    (30 * 24 * time.Hour) / time.Second,
-		"pid_file":         "./pidfile",
-		"plugin_directory": "",
+		"geoip_asn_database":     "",
+		"geoip_country_database": "",
+		"log_format":             "",
+		"log_level":              "",
+		"max_lease_ttl":          (30 * 24 * time.Hour) / time.Second,
+		"pid_file":               "./pidfile",
+		"plugin_directory":       "",
 		"seals": []interface{}{
 			map[string]interface{}{
 				"disabled": false,
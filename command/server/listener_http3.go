@@ -0,0 +1,55 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/internalshared/configutil"
+	"github.com/hashicorp/vault/internalshared/listenerutil"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// defaultAltSvcMaxAge is how long clients remember that HTTP/3 is available
+// unless configured otherwise.
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+// NewHTTP3Server returns the HTTP/3 server for a listener. It is started by
+// calling Serve with the listener's PacketConn.
+func NewHTTP3Server(handler http.Handler, ln *listenerutil.HTTP3Listener, l *configutil.Listener) *http3.Server {
+	quicConfig := &quic.Config{
+		MaxIdleTimeout:             l.HTTP3.MaxIdleTimeout,
+		MaxIncomingStreams:         l.HTTP3.MaxIncomingStreams,
+		MaxStreamReceiveWindow:     uint64(l.HTTP3.MaxStreamReceiveWindow),
+		MaxConnectionReceiveWindow: uint64(l.HTTP3.MaxConnectionReceiveWindow),
+		DisablePathMTUDiscovery:    l.HTTP3.DisablePathMTUDiscovery,
+	}
+
+	return &http3.Server{
+		Handler:    handler,
+		TLSConfig:  ln.TLSConfig,
+		QuicConfig: quicConfig,
+	}
+}
+
+// WrapAltSvcHandler advertises HTTP/3 in the Alt-Svc header of the
+// responses served over TCP, so that clients switch to it.
+func WrapAltSvcHandler(h http.Handler, ln *listenerutil.HTTP3Listener, l *configutil.Listener) http.Handler {
+	maxAge := l.HTTP3.AltSvcMaxAge
+	if maxAge == 0 {
+		maxAge = defaultAltSvcMaxAge
+	}
+
+	var port int
+	if addr, ok := ln.PacketConn.LocalAddr().(*net.UDPAddr); ok {
+		port = addr.Port
+	}
+	altSvc := fmt.Sprintf(`h3=":%d"; ma=%d`, port, int64(maxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", altSvc)
+		h.ServeHTTP(w, r)
+	})
+}
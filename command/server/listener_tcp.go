@@ -15,7 +15,7 @@ import (
 	"github.com/mitchellh/cli"
 )
 
-func tcpListenerFactory(l *configutil.Listener, _ io.Writer, ui cli.Ui) (net.Listener, map[string]string, reloadutil.ReloadFunc, error) {
+func tcpListenerFactory(l *configutil.Listener, _ io.Writer, ui cli.Ui) (*listenerutil.Listener, map[string]string, reloadutil.ReloadFunc, error) {
 	addr := l.Address
 	if addr == "" {
 		addr = "127.0.0.1:8200"
@@ -71,7 +71,31 @@ func tcpListenerFactory(l *configutil.Listener, _ io.Writer, ui cli.Ui) (net.Lis
 		ln = tls.NewListener(ln, tlsConfig)
 	}
 
-	return ln, props, reloadFunc, nil
+	listener := &listenerutil.Listener{
+		Listener: ln,
+		Config:   l,
+	}
+
+	if l.HTTP3.Enabled {
+		// Configuration parsing ensures TLS is enabled.
+		udpProto := "udp"
+		if bindProto == "tcp4" {
+			udpProto = "udp4"
+		}
+		pc, err := net.ListenPacket(udpProto, addr)
+		if err != nil {
+			ln.Close()
+			return nil, nil, nil, fmt.Errorf("failed to listen for http3: %w", err)
+		}
+
+		props["http3"] = "enabled"
+		listener.HTTP3 = &listenerutil.HTTP3Listener{
+			PacketConn: pc,
+			TLSConfig:  tlsConfig,
+		}
+	}
+
+	return listener, props, reloadFunc, nil
 }
 
 // TCPKeepAliveListener sets TCP keep-alive timeouts on accepted
@@ -0,0 +1,341 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/internalshared/configutil"
+	"github.com/hashicorp/vault/sdk/helper/tlsutil"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/hashicorp/vault/vault/diagnose"
+)
+
+const (
+	configCheckOK      = "ok"
+	configCheckWarning = "warning"
+	configCheckError   = "error"
+	configCheckSkipped = "skipped"
+
+	// configCheckTimeout bounds each of the checks reaching out to storage,
+	// seals or telemetry sinks.
+	configCheckTimeout = 30 * time.Second
+
+	// sealConfigStoragePath is where the barrier seal configuration is
+	// stored, outside of the barrier.
+	sealConfigStoragePath = "core/seal-config"
+)
+
+// configCheckFinding is the outcome of a single check of -check-config.
+type configCheckFinding struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// configCheckFindings collects the findings of -check-config.
+type configCheckFindings []*configCheckFinding
+
+func (f *configCheckFindings) add(check, status, format string, args ...interface{}) {
+	*f = append(*f, &configCheckFinding{
+		Check:   check,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// status returns the most severe status of the findings.
+func (f configCheckFindings) status() string {
+	status := configCheckOK
+	for _, finding := range f {
+		switch finding.Status {
+		case configCheckError:
+			return configCheckError
+		case configCheckWarning:
+			status = configCheckWarning
+		}
+	}
+	return status
+}
+
+// runConfigCheck validates the server configuration against the resources it
+// refers to, without starting the server: it connects to the storage backend,
+// verifies the seal configuration stored in it matches the configured seals,
+// and validates the listener TLS material and telemetry sinks. It outputs the
+// findings and returns 1 if any check failed.
+func (c *ServerCommand) runConfigCheck(config *server.Config, configErrors []configutil.ConfigError) int {
+	var findings configCheckFindings
+
+	if len(configErrors) == 0 {
+		findings.add("config", configCheckOK, "Configuration parsed successfully.")
+	}
+	for _, cErr := range configErrors {
+		findings.add("config", configCheckWarning, "%s", cErr.String())
+	}
+
+	storedSealConfig := c.checkConfigStorage(&findings, config)
+	c.checkConfigSeals(&findings, config, storedSealConfig)
+	checkConfigListeners(&findings, config.Listeners)
+	checkConfigTelemetry(&findings, config.Telemetry)
+
+	status := findings.status()
+	switch Format(c.UI) {
+	case "json":
+		out, err := json.MarshalIndent(map[string]interface{}{
+			"status":   status,
+			"findings": findings,
+		}, "", "  ")
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error encoding findings: %s", err))
+			return 1
+		}
+		c.UI.Output(string(out))
+	default:
+		rows := []string{"Check | Status | Message"}
+		for _, finding := range findings {
+			rows = append(rows, fmt.Sprintf("%s | %s | %s", finding.Check, finding.Status, finding.Message))
+		}
+		c.UI.Output(tableOutput(rows, nil))
+		c.UI.Output("")
+		c.UI.Output(fmt.Sprintf("Configuration check result: %s", status))
+	}
+
+	if status == configCheckError {
+		return 1
+	}
+	return 0
+}
+
+// checkConfigStorage connects to the storage backend and returns the barrier
+// seal configuration stored in it, if any.
+func (c *ServerCommand) checkConfigStorage(findings *configCheckFindings, config *server.Config) *vault.SealConfig {
+	backend, err := c.setupStorage(config)
+	if err != nil {
+		findings.add("storage", configCheckError, "%s", err)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), configCheckTimeout)
+	defer cancel()
+
+	entry, err := backend.Get(ctx, sealConfigStoragePath)
+	if err != nil {
+		findings.add("storage", configCheckError, "Could not read from %s storage: %s", config.Storage.Type, err)
+		return nil
+	}
+	findings.add("storage", configCheckOK, "Connected to %s storage.", config.Storage.Type)
+
+	if entry == nil {
+		findings.add("seal-config", configCheckWarning, "No seal configuration found in storage; Vault is not initialized.")
+		return nil
+	}
+	return decodeStoredSealConfig(findings, entry)
+}
+
+func decodeStoredSealConfig(findings *configCheckFindings, entry *physical.Entry) *vault.SealConfig {
+	var sealConfig vault.SealConfig
+	if err := json.Unmarshal(entry.Value, &sealConfig); err != nil {
+		findings.add("seal-config", configCheckError, "Could not decode the seal configuration found in storage: %s", err)
+		return nil
+	}
+	if sealConfig.Type == "" {
+		sealConfig.Type = wrapping.WrapperTypeShamir.String()
+	}
+	if err := sealConfig.Validate(); err != nil {
+		findings.add("seal-config", configCheckError, "Invalid seal configuration found in storage: %s", err)
+		return nil
+	}
+	return &sealConfig
+}
+
+// checkConfigSeals creates the configured seals, verifies the auto seal can
+// encrypt and decrypt, and that the seal configuration found in storage is
+// the one of the configured seals.
+func (c *ServerCommand) checkConfigSeals(findings *configCheckFindings, config *server.Config, stored *vault.SealConfig) {
+	barrierSeal, barrierWrapper, unwrapSeal, seals, sealConfigError, err := setSeal(c, config, nil, make(map[string]string))
+	defer func() {
+		for _, seal := range seals {
+			if seal != nil {
+				seal.Finalize(context.Background())
+			}
+		}
+	}()
+	switch {
+	case err != nil:
+		findings.add("seal", configCheckError, "%s", err)
+		return
+	case sealConfigError != nil:
+		findings.add("seal", configCheckError, "Seal could not be configured: %s", sealConfigError)
+		return
+	case barrierSeal == nil:
+		findings.add("seal", configCheckError, "No barrier seal could be created from the seal configuration.")
+		return
+	}
+
+	sealType := barrierSeal.BarrierType().String()
+	if barrierSeal.BarrierType() == wrapping.WrapperTypeShamir {
+		findings.add("seal", configCheckOK, "Using a %s seal.", sealType)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), configCheckTimeout)
+		defer cancel()
+
+		if err := checkConfigSealWrapper(ctx, barrierWrapper); err != nil {
+			findings.add("seal", configCheckError, "The %s seal could not be used: %s", sealType, err)
+		} else {
+			findings.add("seal", configCheckOK, "The %s seal can encrypt and decrypt.", sealType)
+		}
+	}
+
+	if stored == nil {
+		return
+	}
+	switch {
+	case stored.Type == sealType:
+		findings.add("seal-config", configCheckOK, "The seal configuration in storage matches the %s seal.", sealType)
+	case unwrapSeal != nil && stored.Type == unwrapSeal.BarrierType().String():
+		findings.add("seal-config", configCheckWarning, "The seal configuration in storage is for the disabled %s seal; a seal migration to %s is required.", stored.Type, sealType)
+	default:
+		findings.add("seal-config", configCheckError, "The seal configuration in storage is for a %s seal, but a %s seal is configured.", stored.Type, sealType)
+	}
+}
+
+func checkConfigSealWrapper(ctx context.Context, wrapper wrapping.Wrapper) error {
+	if wrapper == nil {
+		return fmt.Errorf("no wrapper was created")
+	}
+	const value = "vault-check-config"
+	ciphertext, err := wrapper.Encrypt(ctx, []byte(value), nil)
+	if err != nil {
+		return fmt.Errorf("error encrypting: %w", err)
+	}
+	plaintext, err := wrapper.Decrypt(ctx, ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("error decrypting: %w", err)
+	}
+	if string(plaintext) != value {
+		return fmt.Errorf("decryption returned an incorrect value")
+	}
+	return nil
+}
+
+// checkConfigListeners validates the TLS material of the listeners.
+func checkConfigListeners(findings *configCheckFindings, listeners []*configutil.Listener) {
+	for _, l := range listeners {
+		check := "listener " + l.Address
+		if l.Type != "tcp" {
+			findings.add(check, configCheckSkipped, "TLS is not supported by %s listeners.", l.Type)
+			continue
+		}
+		if l.TLSDisable {
+			findings.add(check, configCheckWarning, "TLS is disabled.")
+			continue
+		}
+
+		failed := false
+		for _, version := range []string{l.TLSMinVersion, l.TLSMaxVersion} {
+			if _, ok := tlsutil.TLSLookup[version]; version != "" && !ok {
+				findings.add(check, configCheckError, "TLS version %q is not supported.", version)
+				failed = true
+			}
+		}
+
+		warnings, err := diagnose.TLSFileChecks(l.TLSCertFile, l.TLSKeyFile)
+		for _, warning := range warnings {
+			findings.add(check, configCheckWarning, "%s", warning)
+		}
+		if err != nil {
+			findings.add(check, configCheckError, "Invalid TLS certificate or key: %s", err)
+			failed = true
+		}
+
+		warnings, err = diagnose.TLSClientCAFileCheck(l)
+		for _, warning := range warnings {
+			findings.add(check, configCheckWarning, "%s", warning)
+		}
+		if err != nil {
+			findings.add(check, configCheckError, "Invalid TLS client CA: %s", err)
+			failed = true
+		}
+
+		if !failed {
+			findings.add(check, configCheckOK, "TLS certificate and key are valid.")
+		}
+	}
+}
+
+// checkConfigTelemetry validates the telemetry sinks, resolving the address
+// of the statsd sinks and connecting to the statsite one.
+func checkConfigTelemetry(findings *configCheckFindings, t *configutil.Telemetry) {
+	if t == nil {
+		findings.add("telemetry", configCheckSkipped, "No telemetry configured.")
+		return
+	}
+
+	sinks := 0
+	checkUDP := func(name, addr string) {
+		if addr == "" {
+			return
+		}
+		sinks++
+		if _, err := net.ResolveUDPAddr("udp", addr); err != nil {
+			findings.add("telemetry "+name, configCheckError, "Invalid address %q: %s", addr, err)
+			return
+		}
+		findings.add("telemetry "+name, configCheckOK, "Address %q resolved.", addr)
+	}
+	checkUDP("statsd", t.StatsdAddr)
+	checkUDP("dogstatsd", t.DogStatsDAddr)
+
+	if t.StatsiteAddr != "" {
+		sinks++
+		conn, err := net.DialTimeout("tcp", t.StatsiteAddr, configCheckTimeout)
+		if err != nil {
+			findings.add("telemetry statsite", configCheckError, "Could not connect to %q: %s", t.StatsiteAddr, err)
+		} else {
+			conn.Close()
+			findings.add("telemetry statsite", configCheckOK, "Connected to %q.", t.StatsiteAddr)
+		}
+	}
+
+	if len(t.DogStatsDTags) > 0 && t.DogStatsDAddr == "" {
+		findings.add("telemetry dogstatsd", configCheckError, "dogstatsd_tags are set without dogstatsd_addr.")
+	}
+
+	if t.CirconusAPIURL != "" || t.CirconusAPIToken != "" || t.CirconusCheckSubmissionURL != "" || t.CirconusCheckID != "" {
+		sinks++
+		if t.CirconusAPIToken == "" && t.CirconusCheckSubmissionURL == "" {
+			findings.add("telemetry circonus", configCheckError, "Either circonus_api_token or circonus_submission_url is required.")
+		} else {
+			findings.add("telemetry circonus", configCheckOK, "Circonus sink configured.")
+		}
+	}
+
+	if t.StackdriverProjectID != "" || t.StackdriverLocation != "" || t.StackdriverNamespace != "" {
+		sinks++
+		var missing []string
+		if t.StackdriverProjectID == "" {
+			missing = append(missing, "stackdriver_project_id")
+		}
+		if t.StackdriverLocation == "" {
+			missing = append(missing, "stackdriver_location")
+		}
+		if t.StackdriverNamespace == "" {
+			missing = append(missing, "stackdriver_namespace")
+		}
+		if len(missing) > 0 {
+			findings.add("telemetry stackdriver", configCheckError, "Incomplete configuration, missing %s.", strings.Join(missing, ", "))
+		} else {
+			findings.add("telemetry stackdriver", configCheckOK, "Stackdriver sink configured.")
+		}
+	}
+
+	if sinks == 0 {
+		findings.add("telemetry", configCheckOK, "No external sinks configured; metrics are only available through the API.")
+	}
+}
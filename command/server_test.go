@@ -261,6 +261,20 @@ func TestServer(t *testing.T) {
 			1,
 			"-test-server-config",
 		},
+		{
+			"check_config",
+			testBaseHCL(t, "") + inmemHCL,
+			"Configuration check result: warning",
+			0,
+			"-check-config",
+		},
+		{
+			"check_config_bad_telemetry",
+			testBaseHCL(t, "") + inmemHCL + "telemetry {\n  dogstatsd_tags = [\"env:test\"]\n}\n",
+			"dogstatsd_tags are set without dogstatsd_addr",
+			1,
+			"-check-config",
+		},
 	}
 
 	for _, tc := range cases {
@@ -1,6 +1,8 @@
 package command
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -8,8 +10,10 @@ import (
 	"os"
 	"os/exec"
 	"os/user"
+	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/builtin/logical/ssh"
@@ -17,6 +21,8 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/posener/complete"
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 var (
@@ -42,6 +48,9 @@ type SSHCommand struct {
 	flagHostKeyMountPoint string
 	flagHostKeyHostnames  string
 	flagValidPrincipals   string
+	flagCacheCert         bool
+	flagCertCacheDir      string
+	flagSSHAgent          bool
 }
 
 func (c *SSHCommand) Synopsis() string {
@@ -66,6 +75,16 @@ Usage: vault ssh [options] username@ip [ssh options]
 
       $ vault ssh -mode=ca -role=my-role user@1.2.3.4
 
+  SSH using the CA mode, reusing the signed certificate across connections
+  until it is close to expiring:
+
+      $ vault ssh -mode=ca -role=my-role -cache-cert user@1.2.3.4
+
+  SSH using the CA mode through a jump host, loading the signed certificate
+  into the running ssh-agent:
+
+      $ vault ssh -mode=ca -role=my-role -ssh-agent -J jump@bastion user@1.2.3.4
+
   SSH using CA mode with host key verification:
 
       $ vault ssh \
@@ -205,6 +224,39 @@ func (c *SSHCommand) Flags() *FlagSets {
 			"user certificate. This is specified as a comma-separated list of values.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:       "cache-cert",
+		Target:     &c.flagCacheCert,
+		Default:    false,
+		EnvVar:     "VAULT_SSH_CACHE_CERT",
+		Completion: complete.PredictNothing,
+		Usage: "Cache the signed certificate in -cert-cache-dir, per Vault " +
+			"cluster, mount point, role, principals and public key, and reuse it " +
+			"until it is close to expiring instead of signing the public key on " +
+			"every connection.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "cert-cache-dir",
+		Target:     &c.flagCertCacheDir,
+		Default:    "~/.vault-ssh",
+		EnvVar:     "VAULT_SSH_CERT_CACHE_DIR",
+		Completion: complete.PredictDirs("*"),
+		Usage:      "Directory signed certificates are cached in with -cache-cert.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:       "ssh-agent",
+		Target:     &c.flagSSHAgent,
+		Default:    false,
+		EnvVar:     "VAULT_SSH_AGENT",
+		Completion: complete.PredictNothing,
+		Usage: "Load the private key and the signed certificate into the " +
+			"running ssh-agent, found with SSH_AUTH_SOCK, until the certificate " +
+			"expires, instead of passing them to the SSH executable. The agent " +
+			"offers them to jump hosts as well.",
+	})
+
 	f.StringVar(&StringVar{
 		Name:       "ssh-executable",
 		Target:     &c.flagSSHExecutable,
@@ -247,6 +299,7 @@ func (c *SSHCommand) Run(args []string) int {
 	c.flagUserKnownHostsFile = expandPath(c.flagUserKnownHostsFile)
 	c.flagPublicKeyPath = expandPath(c.flagPublicKeyPath)
 	c.flagPrivateKeyPath = expandPath(c.flagPrivateKeyPath)
+	c.flagCertCacheDir = expandPath(c.flagCertCacheDir)
 
 	args = f.Args()
 	if len(args) < 1 {
@@ -370,54 +423,64 @@ func (c *SSHCommand) handleTypeCA(username, ip, port string, sshArgs []string) i
 		return 1
 	}
 
-	sshClient := c.client.SSHWithMountPoint(c.flagMountPoint)
+	jumpHosts, sshConfigFile, sshArgs := c.parseSSHJumpOptions(sshArgs)
 
+	// Jump hosts accept the certificate as well, if it is valid for the
+	// users they are logged into with
 	principals := username
 	if c.flagValidPrincipals != "" {
 		principals = c.flagValidPrincipals
-	}
-
-	// Attempt to sign the public key
-	secret, err := sshClient.SignKey(c.flagRole, map[string]interface{}{
-		// WARNING: publicKey is []byte, which is b64 encoded on JSON upload. We
-		// have to convert it to a string. SV lost many hours to this...
-		"public_key":       string(publicKey),
-		"valid_principals": principals,
-		"cert_type":        "user",
-
-		// TODO: let the user configure these. In the interim, if users want to
-		// customize these values, they can produce the key themselves.
-		"extensions": map[string]string{
-			"permit-X11-forwarding":   "",
-			"permit-agent-forwarding": "",
-			"permit-port-forwarding":  "",
-			"permit-pty":              "",
-			"permit-user-rc":          "",
-		},
-	})
-	if err != nil {
-		c.UI.Error(fmt.Sprintf("failed to sign public key %s: %s",
-			c.flagPublicKeyPath, err))
-		return 2
-	}
-	if secret == nil || secret.Data == nil {
-		c.UI.Error("missing signed key")
-		return 2
+	} else {
+		seen := map[string]bool{username: true}
+		for _, jumpHost := range jumpHosts {
+			if jumpUser, _ := splitSSHJumpHost(jumpHost); jumpUser != "" && !seen[jumpUser] {
+				seen[jumpUser] = true
+				principals += "," + jumpUser
+			}
+		}
 	}
 
 	// Handle no-exec
 	if c.flagNoExec {
+		secret, err := c.signPublicKey(publicKey, principals)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 2
+		}
 		if c.flagField != "" {
 			return PrintRawField(c.UI, secret, c.flagField)
 		}
 		return OutputSecret(c.UI, secret)
 	}
 
-	// Extract public key
-	key, ok := secret.Data["signed_key"].(string)
-	if !ok || key == "" {
-		c.UI.Error("signed key is empty")
-		return 2
+	// Reuse the cached certificate unless it is close to expiring
+	var key string
+	var cachePath string
+	if c.flagCacheCert {
+		cachePath = c.sshCertCachePath(publicKey, principals)
+		key, _ = cachedSSHCert(cachePath, publicKey, time.Now())
+	}
+	if key == "" {
+		secret, err := c.signPublicKey(publicKey, principals)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 2
+		}
+
+		// Extract public key
+		signedKey, ok := secret.Data["signed_key"].(string)
+		if !ok || signedKey == "" {
+			c.UI.Error("signed key is empty")
+			return 2
+		}
+		key = signedKey
+
+		if cachePath != "" {
+			if err := writeSSHCertCache(cachePath, []byte(key)); err != nil {
+				c.UI.Warn(fmt.Sprintf("failed to cache signed public key: %s", err))
+				cachePath = ""
+			}
+		}
 	}
 
 	// Capture the current value - this could be overwritten later if the user
@@ -459,18 +522,7 @@ func (c *SSHCommand) handleTypeCA(username, ip, port string, sshArgs []string) i
 		strictHostKeyChecking = "yes"
 	}
 
-	// Write the signed public key to disk
-	name := fmt.Sprintf("vault_ssh_ca_%s_%s", username, ip)
-	signedPublicKeyPath, err, closer := c.writeTemporaryKey(name, []byte(key))
-	defer closer()
-	if err != nil {
-		c.UI.Error(fmt.Sprintf("failed to write signed public key: %s", err))
-		return 2
-	}
-
 	args := []string{
-		"-i", c.flagPrivateKeyPath,
-		"-i", signedPublicKeyPath,
 		"-o StrictHostKeyChecking=" + strictHostKeyChecking,
 	}
 
@@ -480,6 +532,53 @@ func (c *SSHCommand) handleTypeCA(username, ip, port string, sshArgs []string) i
 		)
 	}
 
+	if c.flagSSHAgent {
+		// The agent offers the certificate to the destination and all jump
+		// hosts alike
+		if err := c.addSSHCertToAgent(c.flagPrivateKeyPath, key); err != nil {
+			c.UI.Error(fmt.Sprintf("failed to add signed public key to the ssh-agent: %s", err))
+			return 2
+		}
+		if sshConfigFile != "" {
+			args = append(args, "-F", sshConfigFile)
+		}
+	} else {
+		// Write the signed public key to disk, unless it is cached
+		signedPublicKeyPath := cachePath
+		if signedPublicKeyPath == "" {
+			name := fmt.Sprintf("vault_ssh_ca_%s_%s", username, ip)
+			path, err, closer := c.writeTemporaryKey(name, []byte(key))
+			defer closer()
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("failed to write signed public key: %s", err))
+				return 2
+			}
+			signedPublicKeyPath = path
+		}
+
+		args = append(args,
+			"-i", c.flagPrivateKeyPath,
+			"-i", signedPublicKeyPath,
+		)
+
+		// Options given on the command line only apply to the destination,
+		// while ssh passes the configuration file given with -F on to jump
+		// hosts. Hand them the identity through a configuration file.
+		if len(jumpHosts) > 0 {
+			data := sshJumpHostConfig(jumpHosts, sshConfigFile, c.flagPrivateKeyPath, signedPublicKeyPath, userKnownHostsFile, strictHostKeyChecking)
+			name := fmt.Sprintf("vault_ssh_ca_config_%s_%s", username, ip)
+			path, err, closer := c.writeTemporaryFile(name, []byte(data), 0o600)
+			defer closer()
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("failed to write ssh configuration for jump hosts: %s", err))
+				return 1
+			}
+			args = append(args, "-F", path)
+		} else if sshConfigFile != "" {
+			args = append(args, "-F", sshConfigFile)
+		}
+	}
+
 	// Add extra user defined ssh arguments
 	args = append(args, sshArgs...)
 
@@ -508,6 +607,37 @@ func (c *SSHCommand) handleTypeCA(username, ip, port string, sshArgs []string) i
 	return 0
 }
 
+// signPublicKey signs the public key for the given principals with the role.
+func (c *SSHCommand) signPublicKey(publicKey []byte, principals string) (*api.Secret, error) {
+	sshClient := c.client.SSHWithMountPoint(c.flagMountPoint)
+
+	// Attempt to sign the public key
+	secret, err := sshClient.SignKey(c.flagRole, map[string]interface{}{
+		// WARNING: publicKey is []byte, which is b64 encoded on JSON upload. We
+		// have to convert it to a string. SV lost many hours to this...
+		"public_key":       string(publicKey),
+		"valid_principals": principals,
+		"cert_type":        "user",
+
+		// TODO: let the user configure these. In the interim, if users want to
+		// customize these values, they can produce the key themselves.
+		"extensions": map[string]string{
+			"permit-X11-forwarding":   "",
+			"permit-agent-forwarding": "",
+			"permit-port-forwarding":  "",
+			"permit-pty":              "",
+			"permit-user-rc":          "",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign public key %s: %w", c.flagPublicKeyPath, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("missing signed key")
+	}
+	return secret, nil
+}
+
 // handleTypeOTP is used to handle SSH logins using the "otp" key type.
 func (c *SSHCommand) handleTypeOTP(username, ip, port string, sshArgs []string) int {
 	secret, cred, err := c.generateCredential(username, ip)
@@ -888,3 +1018,214 @@ func (c *SSHCommand) resolveHostname(hostname string) (ip string, err error) {
 	ip = ipAddr.String()
 	return ip, nil
 }
+
+// parseSSHJumpOptions returns the jump hosts given with -J or
+// "-o ProxyJump=...", and the configuration file given with -F, from the
+// options of an ssh command. The configuration file is removed from the
+// returned arguments.
+func (c *SSHCommand) parseSSHJumpOptions(args []string) (jumpHosts []string, configFile string, rest []string) {
+	var proxyJump string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		// Options end at the destination
+		if !strings.HasPrefix(arg, "-") || len(arg) < 2 {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		// Options without a value, or with their value attached
+		if c.isSingleSSHArg(arg) || len(arg) > 2 || i+1 == len(args) {
+			rest = append(rest, arg)
+			continue
+		}
+
+		value := args[i+1]
+		i++
+		switch arg {
+		case "-F":
+			configFile = value
+			continue
+		case "-J":
+			proxyJump = value
+		case "-o":
+			split := strings.SplitN(value, "=", 2)
+			if len(split) == 2 && strings.EqualFold(strings.TrimSpace(split[0]), "ProxyJump") {
+				proxyJump = strings.TrimSpace(split[1])
+			}
+		}
+		rest = append(rest, arg, value)
+	}
+
+	if proxyJump != "" && !strings.EqualFold(proxyJump, "none") {
+		jumpHosts = strings.Split(proxyJump, ",")
+	}
+	return jumpHosts, configFile, rest
+}
+
+// splitSSHJumpHost returns the user and the host of a jump host given as
+// [user@]host[:port] or ssh://[user@]host[:port].
+func splitSSHJumpHost(jumpHost string) (user, host string) {
+	host = strings.TrimPrefix(jumpHost, "ssh://")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		user, host = host[:i], host[i+1:]
+	}
+	if strings.HasPrefix(host, "[") {
+		if i := strings.Index(host, "]"); i >= 0 {
+			return user, host[1:i]
+		}
+	}
+	if i := strings.LastIndex(host, ":"); i >= 0 && strings.Count(host, ":") == 1 {
+		host = host[:i]
+	}
+	return user, host
+}
+
+// sshJumpHostConfig returns an ssh configuration file handing the private key
+// and the signed certificate to the given jump hosts, along with the host key
+// checking options, before including the configuration ssh would otherwise
+// read.
+func sshJumpHostConfig(jumpHosts []string, configFile, privateKeyPath, certPath, userKnownHostsFile, strictHostKeyChecking string) string {
+	var hosts []string
+	for _, jumpHost := range jumpHosts {
+		_, host := splitSSHJumpHost(jumpHost)
+		hosts = append(hosts, host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Host %s\n", strings.Join(hosts, " "))
+	fmt.Fprintf(&b, "  IdentityFile %q\n", privateKeyPath)
+	fmt.Fprintf(&b, "  CertificateFile %q\n", certPath)
+	fmt.Fprintf(&b, "  StrictHostKeyChecking %s\n", strictHostKeyChecking)
+	if userKnownHostsFile != "" {
+		fmt.Fprintf(&b, "  UserKnownHostsFile %q\n", userKnownHostsFile)
+	}
+
+	// Configuration given with -F replaces both the user and the system
+	// configuration
+	b.WriteString("Host *\n")
+	if configFile != "" {
+		fmt.Fprintf(&b, "Include %q\n", configFile)
+	} else {
+		b.WriteString("Include \"~/.ssh/config\"\n")
+		b.WriteString("Include \"/etc/ssh/ssh_config\"\n")
+	}
+	return b.String()
+}
+
+// sshCertCachePath returns the path signed certificates of the public key
+// for the given principals are cached at, per Vault cluster, namespace,
+// mount point and role.
+func (c *SSHCommand) sshCertCachePath(publicKey []byte, principals string) string {
+	h := sha256.New()
+	for _, v := range []string{c.client.Address(), c.client.Namespace(), c.flagMountPoint, c.flagRole, principals, string(bytes.TrimSpace(publicKey))} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return filepath.Join(c.flagCertCacheDir, fmt.Sprintf("%s-%x-cert.pub", c.flagRole, h.Sum(nil)[:12]))
+}
+
+// cachedSSHCert returns the certificate cached at path, if it certifies the
+// public key and does not need to be renewed yet.
+func cachedSSHCert(path string, publicKey []byte, now time.Time) (string, bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	parsed, _, _, _, err := cryptossh.ParseAuthorizedKey(data)
+	if err != nil {
+		return "", false
+	}
+	cert, ok := parsed.(*cryptossh.Certificate)
+	if !ok {
+		return "", false
+	}
+	key, _, _, _, err := cryptossh.ParseAuthorizedKey(publicKey)
+	if err != nil || !bytes.Equal(cert.Key.Marshal(), key.Marshal()) {
+		return "", false
+	}
+	if sshCertNeedsRenewal(cert, now) {
+		return "", false
+	}
+	return string(data), true
+}
+
+// sshCertNeedsRenewal returns true if less than a tenth of the validity of
+// the certificate, and at least a minute, remains.
+func sshCertNeedsRenewal(cert *cryptossh.Certificate, now time.Time) bool {
+	if cert.ValidBefore == cryptossh.CertTimeInfinity {
+		return false
+	}
+	validAfter := time.Unix(int64(cert.ValidAfter), 0)
+	validBefore := time.Unix(int64(cert.ValidBefore), 0)
+	renewBefore := validBefore.Sub(validAfter) / 10
+	if renewBefore < time.Minute {
+		renewBefore = time.Minute
+	}
+	return now.Before(validAfter) || now.Add(renewBefore).After(validBefore)
+}
+
+// writeSSHCertCache caches the signed certificate at path.
+func writeSSHCertCache(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0o600)
+}
+
+// addSSHCertToAgent loads the private key and its signed certificate into the
+// running ssh-agent until the certificate expires.
+func (c *SSHCommand) addSSHCertToAgent(privateKeyPath, signedKey string) error {
+	parsed, _, _, _, err := cryptossh.ParseAuthorizedKey([]byte(signedKey))
+	if err != nil {
+		return errors.Wrap(err, "parsing signed public key")
+	}
+	cert, ok := parsed.(*cryptossh.Certificate)
+	if !ok {
+		return errors.New("signed public key is not a certificate")
+	}
+
+	pemBytes, err := ioutil.ReadFile(privateKeyPath)
+	if err != nil {
+		return errors.Wrap(err, "reading private key")
+	}
+	privateKey, err := cryptossh.ParseRawPrivateKey(pemBytes)
+	if _, ok := err.(*cryptossh.PassphraseMissingError); ok {
+		passphrase, err := c.UI.AskSecret(fmt.Sprintf("Enter passphrase for %s:", privateKeyPath))
+		if err != nil {
+			return errors.Wrap(err, "reading passphrase")
+		}
+		privateKey, err = cryptossh.ParseRawPrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+		if err != nil {
+			return errors.Wrap(err, "parsing private key")
+		}
+	} else if err != nil {
+		return errors.Wrap(err, "parsing private key")
+	}
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return errors.New("SSH_AUTH_SOCK is not set; is an ssh-agent running?")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return errors.Wrap(err, "connecting to the ssh-agent")
+	}
+	defer conn.Close()
+
+	var lifetime uint32
+	if cert.ValidBefore != cryptossh.CertTimeInfinity {
+		remaining := time.Until(time.Unix(int64(cert.ValidBefore), 0))
+		if remaining <= 0 {
+			return errors.New("signed public key has expired")
+		}
+		lifetime = uint32(remaining.Seconds())
+	}
+
+	return agent.NewClient(conn).Add(agent.AddedKey{
+		PrivateKey:   privateKey,
+		Certificate:  cert,
+		Comment:      fmt.Sprintf("vault ssh %s/%s", strings.TrimSuffix(c.flagMountPoint, "/"), c.flagRole),
+		LifetimeSecs: lifetime,
+	})
+}
@@ -1,9 +1,16 @@
 package command
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mitchellh/cli"
+	cryptossh "golang.org/x/crypto/ssh"
 )
 
 func testSSHCommand(tb testing.TB) (*cli.MockUi, *SSHCommand) {
@@ -214,3 +221,150 @@ func TestIsSingleSSHArg(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSSHJumpOptions(t *testing.T) {
+	t.Parallel()
+
+	_, cmd := testSSHCommand(t)
+	tests := []struct {
+		name       string
+		args       []string
+		jumpHosts  []string
+		configFile string
+		rest       []string
+	}{
+		{
+			"No jump hosts",
+			[]string{"-v", "-p", "2222", "user@host", "-J", "ls"},
+			nil,
+			"",
+			[]string{"-v", "-p", "2222", "user@host", "-J", "ls"},
+		},
+		{
+			"Jump hosts given with -J",
+			[]string{"-J", "jump@bastion:2222,inner", "user@host"},
+			[]string{"jump@bastion:2222", "inner"},
+			"",
+			[]string{"-J", "jump@bastion:2222,inner", "user@host"},
+		},
+		{
+			"Jump hosts given with -o ProxyJump",
+			[]string{"-o", "ProxyJump=bastion", "-F", "/tmp/config", "user@host"},
+			[]string{"bastion"},
+			"/tmp/config",
+			[]string{"-o", "ProxyJump=bastion", "user@host"},
+		},
+		{
+			"ProxyJump none",
+			[]string{"-o", "proxyjump=none", "host"},
+			nil,
+			"",
+			[]string{"-o", "proxyjump=none", "host"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jumpHosts, configFile, rest := cmd.parseSSHJumpOptions(test.args)
+			if !reflect.DeepEqual(jumpHosts, test.jumpHosts) {
+				t.Errorf("got jump hosts: %q want %q", jumpHosts, test.jumpHosts)
+			}
+			if configFile != test.configFile {
+				t.Errorf("got config file: %q want %q", configFile, test.configFile)
+			}
+			if !reflect.DeepEqual(rest, test.rest) {
+				t.Errorf("got args: %q want %q", rest, test.rest)
+			}
+		})
+	}
+}
+
+func TestSplitSSHJumpHost(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][2]string{
+		"bastion":                    {"", "bastion"},
+		"jump@bastion:2222":          {"jump", "bastion"},
+		"ssh://jump@bastion:2222":    {"jump", "bastion"},
+		"jump@[2001:db8::1]:22":      {"jump", "2001:db8::1"},
+		"2001:db8::1":                {"", "2001:db8::1"},
+		"first.last@corp@bastion.io": {"first.last@corp", "bastion.io"},
+	}
+	for jumpHost, want := range tests {
+		user, host := splitSSHJumpHost(jumpHost)
+		if user != want[0] || host != want[1] {
+			t.Errorf("%q: got %q, %q want %q, %q", jumpHost, user, host, want[0], want[1])
+		}
+	}
+}
+
+func TestSSHJumpHostConfig(t *testing.T) {
+	t.Parallel()
+
+	config := sshJumpHostConfig([]string{"jump@bastion:2222", "inner"}, "", "/keys/id_rsa", "/keys/cert.pub", "", "yes")
+	want := `Host bastion inner
+  IdentityFile "/keys/id_rsa"
+  CertificateFile "/keys/cert.pub"
+  StrictHostKeyChecking yes
+Host *
+Include "~/.ssh/config"
+Include "/etc/ssh/ssh_config"
+`
+	if config != want {
+		t.Errorf("got config:\n%s\nwant:\n%s", config, want)
+	}
+
+	config = sshJumpHostConfig([]string{"bastion"}, "/tmp/config", "/keys/id_rsa", "/keys/cert.pub", "/tmp/known_hosts", "ask")
+	if !strings.Contains(config, "UserKnownHostsFile \"/tmp/known_hosts\"\n") || !strings.HasSuffix(config, "Host *\nInclude \"/tmp/config\"\n") {
+		t.Errorf("unexpected config:\n%s", config)
+	}
+}
+
+func TestCachedSSHCert(t *testing.T) {
+	t.Parallel()
+
+	newKey := func() cryptossh.Signer {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := cryptossh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signer
+	}
+	caKey, userKey := newKey(), newKey()
+
+	now := time.Now()
+	cert := &cryptossh.Certificate{
+		Key:         userKey.PublicKey(),
+		CertType:    cryptossh.UserCert,
+		ValidAfter:  uint64(now.Add(-time.Hour).Unix()),
+		ValidBefore: uint64(now.Add(9 * time.Hour).Unix()),
+	}
+	if err := cert.SignCert(rand.Reader, caKey); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "certs", "role-cert.pub")
+	publicKey := cryptossh.MarshalAuthorizedKey(userKey.PublicKey())
+	if _, ok := cachedSSHCert(path, publicKey, now); ok {
+		t.Fatal("expected no cached certificate")
+	}
+	if err := writeSSHCertCache(path, cryptossh.MarshalAuthorizedKey(cert)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cachedSSHCert(path, publicKey, now); !ok {
+		t.Fatal("expected the cached certificate")
+	}
+	// The certificate is renewed once a tenth of its validity remains
+	if _, ok := cachedSSHCert(path, publicKey, now.Add(8*time.Hour+time.Minute)); ok {
+		t.Fatal("expected the cached certificate to need renewal")
+	}
+	// The certificate must certify the public key
+	if _, ok := cachedSSHCert(path, cryptossh.MarshalAuthorizedKey(newKey().PublicKey()), now); ok {
+		t.Fatal("expected the cached certificate of another key to be ignored")
+	}
+}
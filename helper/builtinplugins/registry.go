@@ -46,6 +46,7 @@ import (
 	logicalPostgres "github.com/hashicorp/vault/builtin/logical/postgresql"
 	logicalRabbit "github.com/hashicorp/vault/builtin/logical/rabbitmq"
 	logicalSsh "github.com/hashicorp/vault/builtin/logical/ssh"
+	logicalTokenize "github.com/hashicorp/vault/builtin/logical/tokenize"
 	logicalTotp "github.com/hashicorp/vault/builtin/logical/totp"
 	logicalTransit "github.com/hashicorp/vault/builtin/logical/transit"
 	dbCass "github.com/hashicorp/vault/plugins/database/cassandra"
@@ -176,6 +177,7 @@ func newRegistry() *registry {
 			"rabbitmq":  {Factory: logicalRabbit.Factory},
 			"ssh":       {Factory: logicalSsh.Factory},
 			"terraform": {Factory: logicalTerraform.Factory},
+			"tokenize":  {Factory: logicalTokenize.Factory},
 			"totp":      {Factory: logicalTotp.Factory},
 			"transit":   {Factory: logicalTransit.Factory},
 		},
@@ -0,0 +1,199 @@
+// Package entropy mixes entropy sampled from an external source, such as the
+// RNG of an HSM or an entropy source validation server, into the randomness
+// Vault uses for critical security parameters.
+package entropy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+)
+
+const (
+	// FallbackFail fails the operation requesting randomness when the
+	// external source is unavailable or fails its health tests.
+	FallbackFail = "fail"
+
+	// FallbackPlatform uses the platform source alone when the external
+	// source is unavailable or fails its health tests.
+	FallbackPlatform = "platform"
+)
+
+// retryInterval is how long the external source is not sampled after a
+// failure when falling back to the platform source, so that an unavailable
+// source doesn't slow every read down.
+var retryInterval = 10 * time.Second
+
+// errNotSampled is reported by Healthy until the external source was sampled
+// successfully once.
+var errNotSampled = errors.New("external entropy source has not been sampled yet")
+
+// minHealthTestSize is the smallest sample the health tests apply to; shorter
+// samples repeat by chance too often to be flagged.
+const minHealthTestSize = 8
+
+// Source is an external source of entropy. Seal wrappers able to provide
+// entropy implement it.
+type Source interface {
+	GetRandom(bytes int) ([]byte, error)
+}
+
+// AugmentedReader is an io.Reader which returns the XOR of the platform
+// source and of the external source, so that its output is never weaker than
+// either of them.
+type AugmentedReader struct {
+	source   Source
+	platform io.Reader
+	fallback string
+
+	l          sync.Mutex
+	logger     log.Logger
+	previous   []byte
+	sampled    bool
+	lastErr    error
+	retryAfter time.Time
+}
+
+// NewAugmentedReader returns a reader mixing the output of the source into
+// the platform source. fallback is FallbackFail or FallbackPlatform.
+func NewAugmentedReader(source Source, fallback string) (*AugmentedReader, error) {
+	if source == nil {
+		return nil, errors.New("no external entropy source")
+	}
+	switch fallback {
+	case "":
+		fallback = FallbackFail
+	case FallbackFail, FallbackPlatform:
+	default:
+		return nil, fmt.Errorf("unsupported entropy fallback %q; must be %q or %q", fallback, FallbackFail, FallbackPlatform)
+	}
+
+	return &AugmentedReader{
+		source:   source,
+		platform: rand.Reader,
+		fallback: fallback,
+		logger:   log.NewNullLogger(),
+	}, nil
+}
+
+// SetLogger sets the logger reporting failures of the external source.
+func (r *AugmentedReader) SetLogger(logger log.Logger) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.logger = logger
+}
+
+// Healthy returns whether the last sample of the external source succeeded,
+// and the error of the last sample otherwise. The reader is unhealthy until
+// the source was sampled successfully once.
+func (r *AugmentedReader) Healthy() (bool, error) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if r.lastErr != nil {
+		return false, r.lastErr
+	}
+	if !r.sampled {
+		return false, errNotSampled
+	}
+	return true, nil
+}
+
+// Check samples the external source once, so that Healthy reports its state
+// before the reader is first used.
+func (r *AugmentedReader) Check() error {
+	_, err := r.sample(minHealthTestSize)
+	return err
+}
+
+// Read fills p with the platform source XORed with the external source.
+func (r *AugmentedReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if _, err := io.ReadFull(r.platform, p); err != nil {
+		return 0, fmt.Errorf("failed to read platform entropy: %w", err)
+	}
+
+	external, err := r.sample(len(p))
+	if err != nil {
+		if r.fallback == FallbackPlatform {
+			metrics.IncrCounter([]string{"entropy", "external", "fallback"}, 1)
+			return len(p), nil
+		}
+		return 0, err
+	}
+
+	for i := range p {
+		p[i] ^= external[i]
+	}
+	return len(p), nil
+}
+
+// sample reads n bytes from the external source and runs the health tests on
+// them.
+func (r *AugmentedReader) sample(n int) ([]byte, error) {
+	r.l.Lock()
+	if r.fallback == FallbackPlatform && r.lastErr != nil && time.Now().Before(r.retryAfter) {
+		err := r.lastErr
+		r.l.Unlock()
+		return nil, err
+	}
+	r.l.Unlock()
+
+	// The source may be a remote server, so it is read without holding the
+	// lock, which would otherwise serialize all readers behind it.
+	external, err := r.source.GetRandom(n)
+	if err == nil && len(external) != n {
+		err = fmt.Errorf("requested %d bytes of entropy but got %d", n, len(external))
+	}
+
+	r.l.Lock()
+	defer r.l.Unlock()
+	if err == nil {
+		err = r.healthTest(external)
+	}
+
+	if err != nil {
+		metrics.IncrCounter([]string{"entropy", "external", "failure"}, 1)
+		if r.lastErr == nil {
+			r.logger.Error("external entropy source failed", "fallback", r.fallback, "error", err)
+		}
+		r.lastErr = fmt.Errorf("external entropy source failed: %w", err)
+		r.retryAfter = time.Now().Add(retryInterval)
+		return nil, r.lastErr
+	}
+
+	if r.lastErr != nil {
+		r.logger.Info("external entropy source recovered")
+		r.lastErr = nil
+	}
+	r.sampled = true
+	return external, nil
+}
+
+// healthTest is a continuous test of the external source: a sample made of a
+// single repeated byte, or identical to the previous sample, reveals a stuck
+// source. It must be called with the lock held.
+func (r *AugmentedReader) healthTest(sample []byte) error {
+	if len(sample) < minHealthTestSize {
+		return nil
+	}
+
+	if bytes.Count(sample, sample[:1]) == len(sample) {
+		return errors.New("health test failed: sample is a single repeated byte")
+	}
+	if bytes.Equal(sample, r.previous) {
+		return errors.New("health test failed: sample repeats the previous sample")
+	}
+
+	r.previous = append(r.previous[:0], sample...)
+	return nil
+}
@@ -0,0 +1,207 @@
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type testSource struct {
+	data []byte
+	err  error
+}
+
+func (s *testSource) GetRandom(n int) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.data[:n], nil
+}
+
+func TestAugmentedReader(t *testing.T) {
+	source := &testSource{data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}}
+	r, err := NewAugmentedReader(source, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	platform := bytes.Repeat([]byte{0xff}, 64)
+	r.platform = bytes.NewReader(platform)
+
+	// The health of the source is unknown until it was sampled
+	if healthy, err := r.Healthy(); healthy || err != errNotSampled {
+		t.Fatalf("expected the reader not to be healthy before sampling, got: %t, %v", healthy, err)
+	}
+
+	out := make([]byte, 10)
+	if _, err := r.Read(out); err != nil {
+		t.Fatal(err)
+	}
+	for i := range out {
+		if out[i] != platform[i]^source.data[i] {
+			t.Fatalf("output is not mixed with the external source: %v", out)
+		}
+	}
+
+	if healthy, err := r.Healthy(); !healthy {
+		t.Fatalf("expected the reader to be healthy, got: %v", err)
+	}
+
+	// The source is stuck and returns the same sample again.
+	if _, err := io.ReadFull(r, out); err == nil {
+		t.Fatal("expected the health test to fail")
+	}
+	if healthy, _ := r.Healthy(); healthy {
+		t.Fatal("expected the reader to be unhealthy")
+	}
+
+	// A single repeated byte fails too.
+	source.data = bytes.Repeat([]byte{7}, 16)
+	r.previous = nil
+	if _, err := io.ReadFull(r, out); err == nil {
+		t.Fatal("expected the health test to fail")
+	}
+}
+
+func TestAugmentedReader_Fallback(t *testing.T) {
+	source := &testSource{err: errors.New("hsm unavailable")}
+
+	r, err := NewAugmentedReader(source, FallbackFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(r, make([]byte, 16)); err == nil {
+		t.Fatal("expected an error with the fail fallback")
+	}
+
+	r, err = NewAugmentedReader(source, FallbackPlatform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, 16)
+	if _, err := io.ReadFull(r, out); err != nil {
+		t.Fatal(err)
+	}
+	if healthy, _ := r.Healthy(); healthy {
+		t.Fatal("expected the reader to be unhealthy")
+	}
+
+	// The source is not sampled again before the retry interval elapsed.
+	source.err = nil
+	source.data = make([]byte, 16)
+	for i := range source.data {
+		source.data[i] = byte(i)
+	}
+	if _, err := io.ReadFull(r, out); err != nil {
+		t.Fatal(err)
+	}
+	if healthy, _ := r.Healthy(); healthy {
+		t.Fatal("expected the source not to be sampled before the retry interval")
+	}
+
+	r.retryAfter = r.retryAfter.Add(-2 * retryInterval)
+	if _, err := io.ReadFull(r, out); err != nil {
+		t.Fatal(err)
+	}
+	if healthy, err := r.Healthy(); !healthy {
+		t.Fatalf("expected the reader to recover, got: %v", err)
+	}
+
+	if _, err := NewAugmentedReader(source, "sometimes"); err == nil {
+		t.Fatal("expected an error for an unsupported fallback")
+	}
+}
+
+type blockingSource struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSource) GetRandom(n int) ([]byte, error) {
+	close(s.started)
+	<-s.release
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(i)
+	}
+	return out, nil
+}
+
+func TestAugmentedReader_Check(t *testing.T) {
+	source := &blockingSource{started: make(chan struct{}), release: make(chan struct{})}
+	r, err := NewAugmentedReader(source, FallbackFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checked := make(chan error)
+	go func() {
+		checked <- r.Check()
+	}()
+	<-source.started
+
+	// The reader isn't locked while the source is read
+	if healthy, _ := r.Healthy(); healthy {
+		t.Fatal("expected the reader not to be healthy before the first sample")
+	}
+
+	close(source.release)
+	if err := <-checked; err != nil {
+		t.Fatal(err)
+	}
+	if healthy, err := r.Healthy(); !healthy {
+		t.Fatalf("expected the reader to be healthy, got: %v", err)
+	}
+}
+
+func TestESVSource(t *testing.T) {
+	var requested []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(r.URL.Query().Get("bytes"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requested = append(requested, n)
+		out := make([]byte, n)
+		for i := range out {
+			out[i] = byte(len(requested))
+		}
+		w.Write(out)
+	}))
+	defer srv.Close()
+
+	s, err := NewESVSource(&ESVConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Small reads are served from a single request.
+	for i := 0; i < 4; i++ {
+		out, err := s.GetRandom(32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 32 || out[0] != 1 {
+			t.Fatalf("unexpected output: %v", out)
+		}
+	}
+	if len(requested) != 1 || requested[0] != esvMinFetchSize {
+		t.Fatalf("unexpected requests: %v", requested)
+	}
+
+	out, err := s.GetRandom(2 * esvMinFetchSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2*esvMinFetchSize || out[len(out)-1] != 2 {
+		t.Fatalf("unexpected output length %d", len(out))
+	}
+
+	if _, err := NewESVSource(&ESVConfig{Address: "ftp://example.com"}); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
@@ -0,0 +1,169 @@
+package entropy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+const (
+	esvTimeout = 10 * time.Second
+
+	// esvMinFetchSize is the smallest number of bytes requested from the
+	// server. Key generation reads entropy in many small chunks, which are
+	// served from the buffer instead of a request each.
+	esvMinFetchSize = 1024
+
+	// esvMaxFetchSize bounds the size of a single request to the server.
+	esvMaxFetchSize = 64 * 1024
+)
+
+// ESVConfig configures the connection to an entropy source validation
+// server.
+type ESVConfig struct {
+	// Address is the URL entropy is requested from. The number of bytes is
+	// passed as the bytes query parameter, and the server returns them as
+	// the raw body of the response.
+	Address string
+
+	TLSCACert     string
+	TLSClientCert string
+	TLSClientKey  string
+}
+
+// ESVSource samples entropy from an entropy source validation server over
+// HTTPS.
+type ESVSource struct {
+	address *url.URL
+	client  *http.Client
+
+	l      sync.Mutex
+	buffer []byte
+}
+
+// NewESVSource returns a source fetching entropy from the configured server.
+func NewESVSource(config *ESVConfig) (*ESVSource, error) {
+	if config.Address == "" {
+		return nil, errors.New("address is required")
+	}
+	address, err := url.Parse(config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	if address.Scheme != "https" && address.Scheme != "http" {
+		return nil, fmt.Errorf("unsupported address scheme %q", address.Scheme)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if config.TLSCACert != "" {
+		pem, err := os.ReadFile(config.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no CA certificate found in tls_ca_cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.TLSClientCert != "" || config.TLSClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSClientCert, config.TLSClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	client := cleanhttp.DefaultPooledClient()
+	client.Timeout = esvTimeout
+	client.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+
+	return &ESVSource{
+		address: address,
+		client:  client,
+	}, nil
+}
+
+// GetRandom implements Source.
+func (s *ESVSource) GetRandom(n int) ([]byte, error) {
+	for {
+		s.l.Lock()
+		if len(s.buffer) >= n {
+			// Copy the bytes out and wipe them from the buffer so that they
+			// are never handed out twice.
+			out := make([]byte, n)
+			copy(out, s.buffer)
+			for i := range s.buffer[:n] {
+				s.buffer[i] = 0
+			}
+			s.buffer = s.buffer[n:]
+			s.l.Unlock()
+			return out, nil
+		}
+
+		size := n - len(s.buffer)
+		if size < esvMinFetchSize {
+			size = esvMinFetchSize
+		}
+		if size > esvMaxFetchSize {
+			size = esvMaxFetchSize
+		}
+		s.l.Unlock()
+
+		// Fetch without holding the lock, so that a slow server doesn't
+		// block the reads the buffer can already serve.
+		fetched, err := s.fetch(size)
+		if err != nil {
+			return nil, err
+		}
+
+		s.l.Lock()
+		s.buffer = append(s.buffer, fetched...)
+		s.l.Unlock()
+	}
+}
+
+func (s *ESVSource) fetch(n int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), esvTimeout)
+	defer cancel()
+
+	u := *s.address
+	query := u.Query()
+	query.Set("bytes", strconv.Itoa(n))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from entropy server", resp.StatusCode)
+	}
+
+	out := make([]byte, n)
+	if _, err := io.ReadFull(resp.Body, out); err != nil {
+		return nil, fmt.Errorf("failed to read entropy from server: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,183 @@
+// Package geoip resolves the country and the autonomous system of IP
+// addresses from local MaxMind DB files, such as the GeoLite2-Country and
+// GeoLite2-ASN databases.
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Result is the information resolved for an address. Fields are empty when
+// the databases have no record for the address.
+type Result struct {
+	// Country is the ISO 3166-1 alpha-2 code of the country of the address.
+	Country string
+
+	// ASN is the number of the autonomous system of the address.
+	ASN uint
+
+	// ASOrganization is the organization owning the autonomous system.
+	ASOrganization string
+}
+
+// Resolver looks addresses up in country and ASN databases; either may be
+// omitted. A Resolver is safe for concurrent use, including with Reload.
+type Resolver struct {
+	// l guards the readers, which are swapped on reload
+	l       sync.RWMutex
+	country *maxminddb.Reader
+	asn     *maxminddb.Reader
+}
+
+// countryRecord is the part of GeoIP2 and GeoLite2 country records used,
+// the registered country being the fallback for addresses without a
+// location, like anycast networks.
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// asnRecord is the part of GeoIP2 and GeoLite2 ASN records used
+type asnRecord struct {
+	ASN            uint   `maxminddb:"autonomous_system_number"`
+	ASOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// NewResolver opens the databases at the given paths. Either path may be
+// empty.
+func NewResolver(countryPath, asnPath string) (*Resolver, error) {
+	r := &Resolver{}
+	if err := r.Reload(countryPath, asnPath); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload opens the databases at the given paths, either of which may be
+// empty, in place of the ones in use, so that updated databases are used
+// without restarting. The databases in use are kept if either fails to open.
+func (r *Resolver) Reload(countryPath, asnPath string) error {
+	var country, asn *maxminddb.Reader
+	var err error
+	if countryPath != "" {
+		if country, err = maxminddb.Open(countryPath); err != nil {
+			return fmt.Errorf("failed to open GeoIP country database: %w", err)
+		}
+	}
+	if asnPath != "" {
+		if asn, err = maxminddb.Open(asnPath); err != nil {
+			if country != nil {
+				country.Close()
+			}
+			return fmt.Errorf("failed to open GeoIP ASN database: %w", err)
+		}
+	}
+
+	r.l.Lock()
+	oldCountry, oldASN := r.country, r.asn
+	r.country, r.asn = country, asn
+	r.l.Unlock()
+
+	// Lookups hold the read lock, so none uses the old databases anymore
+	if oldCountry != nil {
+		oldCountry.Close()
+	}
+	if oldASN != nil {
+		oldASN.Close()
+	}
+	return nil
+}
+
+// Close closes the databases.
+func (r *Resolver) Close() error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	var err error
+	for _, reader := range []*maxminddb.Reader{r.country, r.asn} {
+		if reader != nil {
+			if closeErr := reader.Close(); closeErr != nil {
+				err = closeErr
+			}
+		}
+	}
+	r.country, r.asn = nil, nil
+	return err
+}
+
+// HasCountry returns whether a country database is loaded.
+func (r *Resolver) HasCountry() bool {
+	if r == nil {
+		return false
+	}
+	r.l.RLock()
+	defer r.l.RUnlock()
+	return r.country != nil
+}
+
+// HasASN returns whether an ASN database is loaded.
+func (r *Resolver) HasASN() bool {
+	if r == nil {
+		return false
+	}
+	r.l.RLock()
+	defer r.l.RUnlock()
+	return r.asn != nil
+}
+
+// Lookup resolves the address, which may include a port.
+func (r *Resolver) Lookup(addr string) (*Result, error) {
+	ip := parseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", addr)
+	}
+
+	r.l.RLock()
+	defer r.l.RUnlock()
+
+	result := &Result{}
+	if r.country != nil && hasVersion(r.country, ip) {
+		var record countryRecord
+		if err := r.country.Lookup(ip, &record); err != nil {
+			return nil, err
+		}
+		result.Country = record.Country.ISOCode
+		if result.Country == "" {
+			result.Country = record.RegisteredCountry.ISOCode
+		}
+	}
+	if r.asn != nil && hasVersion(r.asn, ip) {
+		var record asnRecord
+		if err := r.asn.Lookup(ip, &record); err != nil {
+			return nil, err
+		}
+		result.ASN = record.ASN
+		result.ASOrganization = record.ASOrganization
+	}
+	return result, nil
+}
+
+// hasVersion returns whether the database may hold a record for the
+// address: IPv4 databases have none for IPv6 addresses.
+func hasVersion(reader *maxminddb.Reader, ip net.IP) bool {
+	return reader.Metadata.IPVersion == 6 || ip.To4() != nil
+}
+
+func parseIP(addr string) net.IP {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.Trim(addr, "[]"))
+}
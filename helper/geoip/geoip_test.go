@@ -0,0 +1,269 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// testDBWriter builds minimal IPv4 MaxMind DB files with 32 bit records.
+type testDBWriter struct {
+	nodes  [][2]int64
+	data   bytes.Buffer
+	dbType string
+}
+
+const (
+	emptyRecord = -1
+	dataFlag    = int64(1) << 40
+
+	// dataSectionSeparatorSize is the size of the zeroed separator between
+	// the search tree and the data section.
+	dataSectionSeparatorSize = 16
+)
+
+// metadataMarker precedes the metadata section at the end of MaxMind DB
+// files.
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// Data section field types of the MaxMind DB format used by the tests.
+const (
+	typeString = 2
+	typeUint16 = 5
+	typeUint32 = 6
+	typeMap    = 7
+)
+
+func newTestDBWriter(dbType string) *testDBWriter {
+	return &testDBWriter{
+		nodes:  [][2]int64{{emptyRecord, emptyRecord}},
+		dbType: dbType,
+	}
+}
+
+func (w *testDBWriter) insert(t *testing.T, cidr string, record map[string]interface{}) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ones, _ := network.Mask.Size()
+	ip := network.IP.To4()
+
+	offset := int64(w.data.Len())
+	encodeValue(&w.data, record)
+
+	node := 0
+	for i := 0; i < ones; i++ {
+		bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+		if i == ones-1 {
+			w.nodes[node][bit] = dataFlag | offset
+			break
+		}
+		next := w.nodes[node][bit]
+		if next == emptyRecord {
+			w.nodes = append(w.nodes, [2]int64{emptyRecord, emptyRecord})
+			next = int64(len(w.nodes) - 1)
+			w.nodes[node][bit] = next
+		}
+		node = int(next)
+	}
+}
+
+func (w *testDBWriter) bytes() []byte {
+	var out bytes.Buffer
+	nodeCount := int64(len(w.nodes))
+	for _, node := range w.nodes {
+		for _, record := range node {
+			switch {
+			case record == emptyRecord:
+				record = nodeCount
+			case record&dataFlag != 0:
+				record = nodeCount + dataSectionSeparatorSize + record&^dataFlag
+			}
+			binary.Write(&out, binary.BigEndian, uint32(record))
+		}
+	}
+	out.Write(make([]byte, dataSectionSeparatorSize))
+	out.Write(w.data.Bytes())
+	out.Write(metadataMarker)
+	encodeValue(&out, map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(32),
+		"ip_version":    uint16(4),
+		"database_type": w.dbType,
+	})
+	return out.Bytes()
+}
+
+func encodeControl(buf *bytes.Buffer, typeNum, size int) {
+	var extra []byte
+	if size >= 29 {
+		// Sizes up to 284 only, which is plenty for tests.
+		extra = []byte{byte(size - 29)}
+		size = 29
+	}
+	if typeNum > 7 {
+		buf.WriteByte(byte(size))
+		buf.WriteByte(byte(typeNum - 7))
+	} else {
+		buf.WriteByte(byte(typeNum<<5 | size))
+	}
+	buf.Write(extra)
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		encodeControl(buf, typeString, len(v))
+		buf.WriteString(v)
+	case uint16:
+		encodeControl(buf, typeUint16, 2)
+		binary.Write(buf, binary.BigEndian, v)
+	case uint32:
+		encodeControl(buf, typeUint32, 4)
+		binary.Write(buf, binary.BigEndian, v)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		encodeControl(buf, typeMap, len(v))
+		for _, k := range keys {
+			encodeValue(buf, k)
+			encodeValue(buf, v[k])
+		}
+	default:
+		panic("unsupported type")
+	}
+}
+
+func writeTestDB(t *testing.T, w *testDBWriter) string {
+	path := filepath.Join(t.TempDir(), w.dbType+".mmdb")
+	if err := os.WriteFile(path, w.bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolver(t *testing.T) {
+	country := newTestDBWriter("GeoLite2-Country")
+	country.insert(t, "192.0.2.0/24", map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "FR"},
+	})
+	country.insert(t, "198.51.100.0/25", map[string]interface{}{
+		"registered_country": map[string]interface{}{"iso_code": "US"},
+	})
+
+	asn := newTestDBWriter("GeoLite2-ASN")
+	asn.insert(t, "192.0.2.0/23", map[string]interface{}{
+		"autonomous_system_number":       uint32(64500),
+		"autonomous_system_organization": "Example Networks",
+	})
+
+	r, err := NewResolver(writeTestDB(t, country), writeTestDB(t, asn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		addr     string
+		expected Result
+	}{
+		{"192.0.2.10", Result{Country: "FR", ASN: 64500, ASOrganization: "Example Networks"}},
+		{"192.0.3.1:8200", Result{ASN: 64500, ASOrganization: "Example Networks"}},
+		{"198.51.100.1", Result{Country: "US"}},
+		{"198.51.100.200", Result{}},
+		{"203.0.113.1", Result{}},
+		{"2001:db8::1", Result{}},
+	}
+	for _, tc := range cases {
+		result, err := r.Lookup(tc.addr)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.addr, err)
+		}
+		if *result != tc.expected {
+			t.Fatalf("%s: expected %+v, got %+v", tc.addr, tc.expected, *result)
+		}
+	}
+
+	if _, err := r.Lookup("not an address"); err == nil {
+		t.Fatal("expected error for an invalid address")
+	}
+}
+
+func TestResolver_Reload(t *testing.T) {
+	country := newTestDBWriter("GeoLite2-Country")
+	country.insert(t, "192.0.2.0/24", map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "FR"},
+	})
+	path := writeTestDB(t, country)
+
+	r, err := NewResolver(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	lookup := func(expected string) {
+		t.Helper()
+		result, err := r.Lookup("192.0.2.10")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Country != expected {
+			t.Fatalf("expected %q, got %q", expected, result.Country)
+		}
+	}
+	lookup("FR")
+
+	// Databases are replaced by renaming updated files over them, as
+	// geoipupdate does
+	updated := newTestDBWriter("GeoLite2-Country")
+	updated.insert(t, "192.0.2.0/24", map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "DE"},
+	})
+	if err := os.Rename(writeTestDB(t, updated), path); err != nil {
+		t.Fatal(err)
+	}
+	lookup("FR")
+	if err := r.Reload(path, ""); err != nil {
+		t.Fatal(err)
+	}
+	lookup("DE")
+
+	// The databases in use are kept when the updated ones are invalid
+	invalid := filepath.Join(t.TempDir(), "invalid.mmdb")
+	if err := os.WriteFile(invalid, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(invalid, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(path, ""); err == nil {
+		t.Fatal("expected error reloading an invalid database")
+	}
+	lookup("DE")
+}
+
+func TestResolver_InvalidDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.mmdb")
+	if err := os.WriteFile(path, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewResolver(path, ""); err == nil {
+		t.Fatal("expected error for an invalid database")
+	}
+
+	r, err := NewResolver("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.HasCountry() || r.HasASN() {
+		t.Fatal("expected no database to be loaded")
+	}
+}
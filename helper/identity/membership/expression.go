@@ -0,0 +1,433 @@
+// Package membership evaluates the expressions which compute the members of
+// dynamic identity groups from the attributes of entities and their aliases.
+//
+// An expression is made of conditions combined with "and", "or", "not" and
+// parentheses. A condition compares a selector to a quoted string:
+//
+//	entity.metadata.team == "platform" and alias.mount_type == "oidc"
+//	entity.name matches "^svc-" or alias.metadata.department in ["sre", "dev"]
+//
+// The supported operators are "==", "!=", "matches" (regular expression) and
+// "in" (list of strings). A condition on alias attributes is satisfied if any
+// alias of the entity satisfies it. Missing metadata keys select the empty
+// string.
+package membership
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxExpressionLength is the maximum length of an expression.
+const MaxExpressionLength = 4096
+
+// Entity holds the attributes of an entity that expressions are evaluated
+// against.
+type Entity struct {
+	Name     string
+	Metadata map[string]string
+	Aliases  []Alias
+}
+
+// Alias holds the attributes of an entity alias that expressions are
+// evaluated against.
+type Alias struct {
+	Name           string
+	MountAccessor  string
+	MountPath      string
+	MountType      string
+	Metadata       map[string]string
+	CustomMetadata map[string]string
+}
+
+// Expression is a parsed membership expression.
+type Expression struct {
+	raw  string
+	root node
+}
+
+// String returns the expression as it was parsed.
+func (e *Expression) String() string {
+	return e.raw
+}
+
+// Matches returns whether the entity satisfies the expression.
+func (e *Expression) Matches(entity *Entity) bool {
+	return e.root.eval(entity)
+}
+
+// Parse parses an expression.
+func Parse(raw string) (*Expression, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+	if len(raw) > MaxExpressionLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", MaxExpressionLength)
+	}
+
+	tokens, err := tokenize(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", tok, tok.pos)
+	}
+
+	return &Expression{raw: raw, root: root}, nil
+}
+
+type node interface {
+	eval(entity *Entity) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(entity *Entity) bool { return n.left.eval(entity) && n.right.eval(entity) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(entity *Entity) bool { return n.left.eval(entity) || n.right.eval(entity) }
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(entity *Entity) bool { return !n.operand.eval(entity) }
+
+// conditionNode compares the value of a selector using match.
+type conditionNode struct {
+	selector selector
+	match    func(value string) bool
+}
+
+func (n conditionNode) eval(entity *Entity) bool {
+	if n.selector.alias == nil {
+		return n.match(n.selector.entity(entity))
+	}
+	for i := range entity.Aliases {
+		if n.match(n.selector.alias(&entity.Aliases[i])) {
+			return true
+		}
+	}
+	return false
+}
+
+// selector returns the value of an entity attribute, or of an alias attribute.
+type selector struct {
+	entity func(*Entity) string
+	alias  func(*Alias) string
+}
+
+func parseSelector(path string) (selector, error) {
+	parts := strings.SplitN(path, ".", 3)
+	if len(parts) < 2 {
+		return selector{}, fmt.Errorf("invalid selector %q", path)
+	}
+
+	switch parts[0] {
+	case "entity":
+		switch {
+		case len(parts) == 2 && parts[1] == "name":
+			return selector{entity: func(e *Entity) string { return e.Name }}, nil
+		case len(parts) == 3 && parts[1] == "metadata":
+			key := parts[2]
+			return selector{entity: func(e *Entity) string { return e.Metadata[key] }}, nil
+		}
+	case "alias":
+		if len(parts) == 2 {
+			switch parts[1] {
+			case "name":
+				return selector{alias: func(a *Alias) string { return a.Name }}, nil
+			case "mount_accessor":
+				return selector{alias: func(a *Alias) string { return a.MountAccessor }}, nil
+			case "mount_path":
+				return selector{alias: func(a *Alias) string { return a.MountPath }}, nil
+			case "mount_type":
+				return selector{alias: func(a *Alias) string { return a.MountType }}, nil
+			}
+		}
+		if len(parts) == 3 {
+			key := parts[2]
+			switch parts[1] {
+			case "metadata":
+				return selector{alias: func(a *Alias) string { return a.Metadata[key] }}, nil
+			case "custom_metadata":
+				return selector{alias: func(a *Alias) string { return a.CustomMetadata[key] }}, nil
+			}
+		}
+	}
+
+	return selector{}, fmt.Errorf("unknown selector %q", path)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, fmt.Errorf("expected %s at offset %d, got %s", kind, tok.pos, tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch tok := p.peek(); {
+	case tok.isKeyword("not"):
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	case tok.kind == tokenLParen:
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		return p.parseCondition()
+	}
+}
+
+func (p *parser) parseCondition() (node, error) {
+	selTok, err := p.expect(tokenWord)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := parseSelector(selTok.value)
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.next()
+	switch {
+	case op.kind == tokenEq || op.kind == tokenNeq:
+		value, err := p.expect(tokenString)
+		if err != nil {
+			return nil, err
+		}
+		if op.kind == tokenEq {
+			return conditionNode{selector: sel, match: func(v string) bool { return v == value.value }}, nil
+		}
+		return conditionNode{selector: sel, match: func(v string) bool { return v != value.value }}, nil
+
+	case op.isKeyword("matches"):
+		value, err := p.expect(tokenString)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(value.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at offset %d: %w", value.pos, err)
+		}
+		return conditionNode{selector: sel, match: re.MatchString}, nil
+
+	case op.isKeyword("in"):
+		if _, err := p.expect(tokenLBracket); err != nil {
+			return nil, err
+		}
+		values := map[string]struct{}{}
+		for {
+			value, err := p.expect(tokenString)
+			if err != nil {
+				return nil, err
+			}
+			values[value.value] = struct{}{}
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(tokenRBracket); err != nil {
+			return nil, err
+		}
+		return conditionNode{selector: sel, match: func(v string) bool {
+			_, ok := values[v]
+			return ok
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("expected an operator at offset %d, got %s", op.pos, op)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenEq
+	tokenNeq
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of expression"
+	case tokenWord:
+		return "selector"
+	case tokenString:
+		return "quoted string"
+	case tokenEq:
+		return `"=="`
+	case tokenNeq:
+		return `"!="`
+	case tokenLParen:
+		return `"("`
+	case tokenRParen:
+		return `")"`
+	case tokenLBracket:
+		return `"["`
+	case tokenRBracket:
+		return `"]"`
+	case tokenComma:
+		return `","`
+	}
+	return "unknown token"
+}
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenWord:
+		return fmt.Sprintf("%q", t.value)
+	case tokenString:
+		return strconv.Quote(t.value)
+	}
+	return t.kind.String()
+}
+
+func (t token) isKeyword(keyword string) bool {
+	return t.kind == tokenWord && t.value == keyword
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '.' || c == '/' || c == '+'
+}
+
+func tokenize(raw string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(raw); {
+		c := raw[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLBracket, pos: i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRBracket, pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, pos: i})
+			i++
+		case strings.HasPrefix(raw[i:], "=="):
+			tokens = append(tokens, token{kind: tokenEq, pos: i})
+			i += 2
+		case strings.HasPrefix(raw[i:], "!="):
+			tokens = append(tokens, token{kind: tokenNeq, pos: i})
+			i += 2
+		case c == '"':
+			end := i + 1
+			for ; end < len(raw) && raw[end] != '"'; end++ {
+				if raw[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(raw) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			value, err := strconv.Unquote(raw[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, pos: i})
+			i = end + 1
+		case isWordChar(c):
+			end := i
+			for end < len(raw) && isWordChar(raw[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: raw[i:end], pos: i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(raw)}), nil
+}
@@ -0,0 +1,97 @@
+package membership
+
+import (
+	"testing"
+)
+
+func TestExpression_Matches(t *testing.T) {
+	entity := &Entity{
+		Name: "svc-billing",
+		Metadata: map[string]string{
+			"team": "platform",
+		},
+		Aliases: []Alias{
+			{
+				Name:      "billing",
+				MountType: "approle",
+				MountPath: "auth/approle/",
+			},
+			{
+				Name:      "jdoe",
+				MountType: "oidc",
+				Metadata: map[string]string{
+					"department": "sre",
+				},
+				CustomMetadata: map[string]string{
+					"cost-center": "42",
+				},
+			},
+		},
+	}
+
+	tests := map[string]bool{
+		`entity.metadata.team == "platform"`:                                    true,
+		`entity.metadata.team != "platform"`:                                    false,
+		`entity.metadata.missing == ""`:                                         true,
+		`entity.name matches "^svc-"`:                                           true,
+		`alias.mount_type == "oidc"`:                                            true,
+		`alias.mount_type == "ldap"`:                                            false,
+		`alias.mount_path == "auth/approle/"`:                                   true,
+		`alias.metadata.department in ["dev", "sre"]`:                           true,
+		`alias.custom_metadata.cost-center in ["7"]`:                            false,
+		`entity.metadata.team == "platform" and alias.mount_type == "oidc"`:     true,
+		`entity.metadata.team == "security" or alias.name == "jdoe"`:            true,
+		`not (entity.metadata.team == "security" or alias.name == "jdoe")`:      false,
+		`not entity.metadata.team == "security" and not alias.name == "nobody"`: true,
+		"entity.name == \"svc-billing\"\n  and alias.name != \"billing\"":       true,
+	}
+
+	for raw, expected := range tests {
+		t.Run(raw, func(t *testing.T) {
+			expr, err := Parse(raw)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if actual := expr.Matches(entity); actual != expected {
+				t.Fatalf("expected %t, got %t", expected, actual)
+			}
+		})
+	}
+}
+
+func TestExpression_NoAliases(t *testing.T) {
+	expr, err := Parse(`alias.name != "jdoe"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.Matches(&Entity{Name: "jdoe"}) {
+		t.Fatal("conditions on aliases should not match entities without aliases")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		``,
+		`entity.metadata.team`,
+		`entity.metadata.team ==`,
+		`entity.metadata.team == platform`,
+		`entity.id == "x"`,
+		`alias.metadata == "x"`,
+		`entity.name == "x" and`,
+		`(entity.name == "x"`,
+		`entity.name == "x")`,
+		`entity.name matches "("`,
+		`entity.name in []`,
+		`entity.name in ["a",]`,
+		`entity.name == "unterminated`,
+		`entity.name == 'x'`,
+	}
+
+	for _, raw := range tests {
+		t.Run(raw, func(t *testing.T) {
+			if _, err := Parse(raw); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
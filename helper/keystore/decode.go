@@ -0,0 +1,291 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// JKSEntry is an entry of a Java KeyStore. PrivateKey is only set for
+// private key entries, whose Certificates hold the certificate of the key
+// followed by its chain.
+type JKSEntry struct {
+	Alias        string
+	PrivateKey   crypto.PrivateKey
+	Certificates []*x509.Certificate
+}
+
+// DecodePKCS7Certs returns the certificates of a certificates-only PKCS#7
+// structure.
+func DecodePKCS7Certs(der []byte) ([]*x509.Certificate, error) {
+	var contentInfo pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(der, &contentInfo); err != nil {
+		return nil, fmt.Errorf("failed to decode PKCS#7 content info: %w", err)
+	}
+	if !contentInfo.ContentType.Equal(oidPKCS7SignedData) {
+		return nil, errors.New("PKCS#7 content is not signed data")
+	}
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("failed to decode PKCS#7 signed data: %w", err)
+	}
+	return x509.ParseCertificates(signedData.Certificates.Bytes)
+}
+
+// DecodePKCS12 verifies the integrity of a PKCS#12 file as encoded by this
+// package with password, and returns its certificates and private key, if
+// any.
+func DecodePKCS12(der []byte, password string) ([]*x509.Certificate, crypto.PrivateKey, error) {
+	var pfx pkcs12PFX
+	if _, err := asn1.Unmarshal(der, &pfx); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode PKCS#12 file: %w", err)
+	}
+	var authenticatedSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authenticatedSafe); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode PKCS#12 authenticated safe: %w", err)
+	}
+
+	macKey := pkcs12KDF(append(bmpString(password), 0, 0), pfx.MacData.MacSalt, pfx.MacData.Iterations, 3, sha256.Size)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(authenticatedSafe)
+	if !hmac.Equal(mac.Sum(nil), pfx.MacData.Mac.Digest) {
+		return nil, nil, errors.New("PKCS#12 integrity check failed")
+	}
+
+	var safes []pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(authenticatedSafe, &safes); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode PKCS#12 safes: %w", err)
+	}
+	var certs []*x509.Certificate
+	var key crypto.PrivateKey
+	for _, safe := range safes {
+		var safeContents []byte
+		if _, err := asn1.Unmarshal(safe.Content.Bytes, &safeContents); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode PKCS#12 safe contents: %w", err)
+		}
+		var bags []pkcs12SafeBag
+		if _, err := asn1.Unmarshal(safeContents, &bags); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode PKCS#12 bags: %w", err)
+		}
+
+		for _, bag := range bags {
+			switch {
+			case bag.ID.Equal(oidPKCS12CertBag):
+				var certBag pkcs12CertBag
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &certBag); err != nil {
+					return nil, nil, fmt.Errorf("failed to decode PKCS#12 certificate bag: %w", err)
+				}
+				cert, err := x509.ParseCertificate(certBag.Data)
+				if err != nil {
+					return nil, nil, err
+				}
+				certs = append(certs, cert)
+
+			case bag.ID.Equal(oidPKCS12ShroudedKeyBag):
+				var err error
+				if key, err = pkcs12DecryptPrivateKey(bag.Value.Bytes, password); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	return certs, key, nil
+}
+
+// pkcs12DecryptPrivateKey decrypts a private key encrypted by
+// pkcs12EncryptPrivateKey.
+func pkcs12DecryptPrivateKey(der []byte, password string) (crypto.PrivateKey, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted private key: %w", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption %s", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("failed to decode PBES2 parameters: %w", err)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("failed to decode PBKDF2 parameters: %w", err)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("failed to decode IV: %w", err)
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(password), kdf.Salt, kdf.Iterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize || len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted private key")
+	}
+	plaintext := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, info.EncryptedData)
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errors.New("invalid private key padding")
+	}
+	return x509.ParsePKCS8PrivateKey(plaintext[:len(plaintext)-padding])
+}
+
+// DecodeJKS verifies the integrity of a Java KeyStore as encoded by this
+// package with password, and returns its entries. Private keys are
+// unprotected with password as well.
+func DecodeJKS(der []byte, password string) ([]JKSEntry, error) {
+	if len(der) < sha1.Size {
+		return nil, errors.New("Java KeyStore too short")
+	}
+	content, digest := der[:len(der)-sha1.Size], der[len(der)-sha1.Size:]
+	if subtle.ConstantTimeCompare(jksDigest(content, password), digest) != 1 {
+		return nil, errors.New("Java KeyStore integrity check failed")
+	}
+
+	r := bytes.NewReader(content)
+	readUint32 := func() (uint32, error) {
+		var v uint32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	}
+	readBytes := func(n int) ([]byte, error) {
+		if n > r.Len() {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	readUTF := func() (string, error) {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return "", err
+		}
+		b, err := readBytes(int(n))
+		return string(b), err
+	}
+	readCert := func() (*x509.Certificate, error) {
+		if _, err := readUTF(); err != nil {
+			return nil, err
+		}
+		n, err := readUint32()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := readBytes(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return x509.ParseCertificate(raw)
+	}
+
+	magic, err := readUint32()
+	if err != nil {
+		return nil, err
+	}
+	version, err := readUint32()
+	if err != nil {
+		return nil, err
+	}
+	if magic != jksMagic || version != jksVersion {
+		return nil, errors.New("not a Java KeyStore")
+	}
+	count, err := readUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []JKSEntry
+	for i := uint32(0); i < count; i++ {
+		tag, err := readUint32()
+		if err != nil {
+			return nil, err
+		}
+		var entry JKSEntry
+		if entry.Alias, err = readUTF(); err != nil {
+			return nil, err
+		}
+		if _, err := readBytes(8); err != nil {
+			return nil, err
+		}
+
+		switch tag {
+		case jksTrustedCertTag:
+			cert, err := readCert()
+			if err != nil {
+				return nil, err
+			}
+			entry.Certificates = []*x509.Certificate{cert}
+
+		case jksPrivateKeyTag:
+			n, err := readUint32()
+			if err != nil {
+				return nil, err
+			}
+			protectedKey, err := readBytes(int(n))
+			if err != nil {
+				return nil, err
+			}
+			if entry.PrivateKey, err = jksUnprotectPrivateKey(protectedKey, password); err != nil {
+				return nil, err
+			}
+			chainLength, err := readUint32()
+			if err != nil {
+				return nil, err
+			}
+			for j := uint32(0); j < chainLength; j++ {
+				cert, err := readCert()
+				if err != nil {
+					return nil, err
+				}
+				entry.Certificates = append(entry.Certificates, cert)
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported Java KeyStore entry type %d", tag)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// jksUnprotectPrivateKey unprotects a private key protected by
+// jksProtectPrivateKey.
+func jksUnprotectPrivateKey(der []byte, password string) (crypto.PrivateKey, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode protected private key: %w", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidJKSKeyProtector) {
+		return nil, fmt.Errorf("unsupported private key protection %s", info.Algorithm.Algorithm)
+	}
+	protected := info.EncryptedData
+	if len(protected) < 2*sha1.Size {
+		return nil, errors.New("invalid protected private key")
+	}
+
+	salt := protected[:sha1.Size]
+	plaintext := jksKeystreamXOR(protected[sha1.Size:len(protected)-sha1.Size], salt, password)
+	check := sha1.New()
+	check.Write(bmpString(password))
+	check.Write(plaintext)
+	if subtle.ConstantTimeCompare(check.Sum(nil), protected[len(protected)-sha1.Size:]) != 1 {
+		return nil, errors.New("private key integrity check failed")
+	}
+	return x509.ParsePKCS8PrivateKey(plaintext)
+}
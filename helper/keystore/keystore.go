@@ -0,0 +1,610 @@
+// Package keystore encodes certificates and private keys in the bundle
+// formats consumed by Java and Windows clients: PKCS#7, PKCS#12 and Java
+// KeyStores.
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	jksMagic          uint32 = 0xFEEDFEED
+	jksVersion        uint32 = 2
+	jksPrivateKeyTag  uint32 = 1
+	jksTrustedCertTag uint32 = 2
+
+	pkcs12MACIterations = 10000
+	pkcs12MACSaltLength = 16
+	pkcs12KeyIterations = 10000
+)
+
+var (
+	oidPKCS7Data       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+	oidPKCS12CertBag        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidPKCS9X509Certificate = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidPKCS9FriendlyName    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidSHA256               = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidAnyExtendedKeyUsage  = asn1.ObjectIdentifier{2, 5, 29, 37, 0}
+	oidPKCS12ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidPKCS9LocalKeyID      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBES2                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA256       = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidJKSKeyProtector      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+
+	// oidJavaTrustedKeyUsage is the attribute marking the certificates of a
+	// PKCS#12 file as trusted certificate entries for Java.
+	oidJavaTrustedKeyUsage = asn1.ObjectIdentifier{2, 16, 840, 1, 113894, 746875, 1, 1}
+)
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      []asn1.RawValue `asn1:"set"`
+}
+
+// EncodePKCS7Certs encodes the given DER certificates as a degenerate,
+// certificates-only PKCS#7 SignedData structure, as found in .p7b files.
+func EncodePKCS7Certs(certs []*x509.Certificate) ([]byte, error) {
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+
+	signedData, err := asn1.Marshal(pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		ContentInfo: pkcs7ContentInfo{
+			ContentType: oidPKCS7Data,
+		},
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      raw,
+		},
+		SignerInfos: []asn1.RawValue{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PKCS#7 signed data: %w", err)
+	}
+
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidPKCS7SignedData,
+		Content:     explicitTag0(signedData),
+	})
+}
+
+// truststoreAlias returns the alias of a certificate in a truststore. Java
+// looks aliases up lowercased.
+func truststoreAlias(cert *x509.Certificate, index int) string {
+	if cert.Subject.CommonName != "" {
+		return strings.ToLower(fmt.Sprintf("%d-%s", index, cert.Subject.CommonName))
+	}
+	return fmt.Sprintf("%d", index)
+}
+
+// jksEntry is an entry of a Java KeyStore: a private key entry holding the
+// protected key and its certificate chain if protectedKey is set, and a
+// trusted certificate entry holding a single certificate otherwise.
+type jksEntry struct {
+	alias        string
+	protectedKey []byte
+	certs        []*x509.Certificate
+}
+
+// EncodeJKSTruststore encodes the given certificates as trusted certificate
+// entries of a Java KeyStore, whose integrity is protected with password.
+func EncodeJKSTruststore(certs []*x509.Certificate, password string) ([]byte, error) {
+	var entries []jksEntry
+	for i, cert := range certs {
+		entries = append(entries, jksEntry{
+			alias: truststoreAlias(cert, i),
+			certs: []*x509.Certificate{cert},
+		})
+	}
+	return encodeJKS(entries, password)
+}
+
+// EncodeJKSKeyStore encodes the given private key, its certificate and the
+// chain of the certificate as the private key entry alias of a Java
+// KeyStore. Both the key and the integrity of the keystore are protected
+// with password.
+func EncodeJKSKeyStore(key crypto.Signer, cert *x509.Certificate, chain []*x509.Certificate, alias, password string) ([]byte, error) {
+	protectedKey, err := jksProtectPrivateKey(key, password)
+	if err != nil {
+		return nil, err
+	}
+	return encodeJKS([]jksEntry{{
+		alias:        strings.ToLower(alias),
+		protectedKey: protectedKey,
+		certs:        append([]*x509.Certificate{cert}, chain...),
+	}}, password)
+}
+
+func encodeJKS(entries []jksEntry, password string) ([]byte, error) {
+	var buf bytes.Buffer
+	writeUint32 := func(v uint32) {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	writeUTF := func(s string) error {
+		if len(s) > 0xFFFF {
+			return errors.New("string too long for a Java KeyStore")
+		}
+		binary.Write(&buf, binary.BigEndian, uint16(len(s)))
+		buf.WriteString(s)
+		return nil
+	}
+	writeCert := func(cert *x509.Certificate) error {
+		if err := writeUTF("X.509"); err != nil {
+			return err
+		}
+		writeUint32(uint32(len(cert.Raw)))
+		buf.Write(cert.Raw)
+		return nil
+	}
+
+	writeUint32(jksMagic)
+	writeUint32(jksVersion)
+	writeUint32(uint32(len(entries)))
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, entry := range entries {
+		tag := jksTrustedCertTag
+		if entry.protectedKey != nil {
+			tag = jksPrivateKeyTag
+		}
+		writeUint32(tag)
+		if err := writeUTF(entry.alias); err != nil {
+			return nil, err
+		}
+		binary.Write(&buf, binary.BigEndian, now)
+
+		if entry.protectedKey == nil {
+			if err := writeCert(entry.certs[0]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		writeUint32(uint32(len(entry.protectedKey)))
+		buf.Write(entry.protectedKey)
+		writeUint32(uint32(len(entry.certs)))
+		for _, cert := range entry.certs {
+			if err := writeCert(cert); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	buf.Write(jksDigest(buf.Bytes(), password))
+
+	return buf.Bytes(), nil
+}
+
+// jksDigest returns the digest a Java KeyStore ends with: a digest of the
+// password, a fixed whitener and the content of the keystore.
+func jksDigest(content []byte, password string) []byte {
+	digest := sha1.New()
+	digest.Write(bmpString(password))
+	digest.Write([]byte("Mighty Aphrodite"))
+	digest.Write(content)
+	return digest.Sum(nil)
+}
+
+// jksProtectPrivateKey returns the DER EncryptedPrivateKeyInfo of the PKCS#8
+// encoding of key, protected with password by the proprietary algorithm of
+// Java KeyStores: the key is XORed with a SHA-1 based keystream seeded with a
+// random salt, and followed by a digest of the password and the key.
+func jksProtectPrivateKey(key crypto.Signer, password string) ([]byte, error) {
+	plaintext, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	salt := make([]byte, sha1.Size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate JKS key salt: %w", err)
+	}
+
+	protected := append([]byte{}, salt...)
+	protected = append(protected, jksKeystreamXOR(plaintext, salt, password)...)
+	check := sha1.New()
+	check.Write(bmpString(password))
+	check.Write(plaintext)
+	protected = check.Sum(protected)
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidJKSKeyProtector,
+			Parameters: asn1.NullRawValue,
+		},
+		EncryptedData: protected,
+	})
+}
+
+// jksKeystreamXOR XORs data with the keystream of the JKS key protector,
+// which chains SHA-1 digests of the password and the previous digest,
+// starting with the salt.
+func jksKeystreamXOR(data, salt []byte, password string) []byte {
+	passwordBytes := bmpString(password)
+	out := make([]byte, len(data))
+	digest := salt
+	for i := 0; i < len(data); i += sha1.Size {
+		h := sha1.New()
+		h.Write(passwordBytes)
+		h.Write(digest)
+		digest = h.Sum(nil)
+		for j := 0; j < sha1.Size && i+j < len(data); j++ {
+			out[i+j] = data[i+j] ^ digest[j]
+		}
+	}
+	return out
+}
+
+type pkcs12PFX struct {
+	Version  int
+	AuthSafe pkcs7ContentInfo
+	MacData  pkcs12MacData
+}
+
+type pkcs12MacData struct {
+	Mac        pkcs12DigestInfo
+	MacSalt    []byte
+	Iterations int
+}
+
+type pkcs12DigestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type pkcs12SafeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID     asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+type pkcs12CertBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	PRF        pkix.AlgorithmIdentifier
+}
+
+// EncodePKCS12Truststore encodes the given certificates as trusted
+// certificate entries of a PKCS#12 file, whose integrity is protected with
+// password. Certificates carry the attribute Java uses to recognize trusted
+// certificate entries.
+func EncodePKCS12Truststore(certs []*x509.Certificate, password string) ([]byte, error) {
+	trustedUsage, err := asn1.Marshal(oidAnyExtendedKeyUsage)
+	if err != nil {
+		return nil, err
+	}
+
+	var bags []pkcs12SafeBag
+	for i, cert := range certs {
+		alias, err := pkcs12FriendlyName(truststoreAlias(cert, i))
+		if err != nil {
+			return nil, err
+		}
+		bag, err := pkcs12CertSafeBag(cert,
+			alias,
+			pkcs12Attribute{ID: oidJavaTrustedKeyUsage, Values: pkcs12AttributeValue(trustedUsage)},
+		)
+		if err != nil {
+			return nil, err
+		}
+		bags = append(bags, bag)
+	}
+
+	return encodePKCS12(bags, password)
+}
+
+// EncodePKCS12KeyStore encodes the given private key, its certificate and
+// the chain of the certificate as a PKCS#12 file, as .pfx files are. The
+// private key is encrypted with password, with PBES2 and AES-256-CBC, and the
+// integrity of the file is protected with password.
+func EncodePKCS12KeyStore(key crypto.Signer, cert *x509.Certificate, chain []*x509.Certificate, friendlyName, password string) ([]byte, error) {
+	keyID := sha1.Sum(cert.Raw)
+	localKeyID, err := asn1.Marshal(keyID[:])
+	if err != nil {
+		return nil, err
+	}
+	keyAttributes := []pkcs12Attribute{
+		{ID: oidPKCS9LocalKeyID, Values: pkcs12AttributeValue(localKeyID)},
+	}
+	if friendlyName != "" {
+		name, err := pkcs12FriendlyName(friendlyName)
+		if err != nil {
+			return nil, err
+		}
+		keyAttributes = append(keyAttributes, name)
+	}
+
+	certBag, err := pkcs12CertSafeBag(cert, keyAttributes...)
+	if err != nil {
+		return nil, err
+	}
+	bags := []pkcs12SafeBag{certBag}
+	for _, chainCert := range chain {
+		bag, err := pkcs12CertSafeBag(chainCert)
+		if err != nil {
+			return nil, err
+		}
+		bags = append(bags, bag)
+	}
+
+	encryptedKey, err := pkcs12EncryptPrivateKey(key, password)
+	if err != nil {
+		return nil, err
+	}
+	bags = append(bags, pkcs12SafeBag{
+		ID:         oidPKCS12ShroudedKeyBag,
+		Value:      explicitTag0(encryptedKey),
+		Attributes: keyAttributes,
+	})
+
+	return encodePKCS12(bags, password)
+}
+
+// pkcs12EncryptPrivateKey returns the DER EncryptedPrivateKeyInfo of the
+// PKCS#8 encoding of key, encrypted with password with PBES2, using PBKDF2
+// with HMAC-SHA256 and AES-256-CBC.
+func pkcs12EncryptPrivateKey(key crypto.Signer, password string) ([]byte, error) {
+	plaintext, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	salt := make([]byte, pkcs12MACSaltLength)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate PKCS#12 key salt: %w", err)
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("failed to generate PKCS#12 key IV: %w", err)
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(password), salt, pkcs12KeyIterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{
+			Algorithm: oidPBKDF2,
+			Parameters: mustMarshalRawValue(pbkdf2Params{
+				Salt:       salt,
+				Iterations: pkcs12KeyIterations,
+				PRF:        pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+			}),
+		},
+		EncryptionScheme: pkix.AlgorithmIdentifier{
+			Algorithm:  oidAES256CBC,
+			Parameters: mustMarshalRawValue(iv),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBES2,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		EncryptedData: ciphertext,
+	})
+}
+
+// mustMarshalRawValue returns the DER encoding of v, which must be
+// encodable, as a raw value.
+func mustMarshalRawValue(v interface{}) asn1.RawValue {
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return asn1.RawValue{FullBytes: der}
+}
+
+// pkcs12FriendlyName returns the friendly name attribute of a bag.
+func pkcs12FriendlyName(name string) (pkcs12Attribute, error) {
+	value, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagBMPString, Bytes: bmpString(name)})
+	if err != nil {
+		return pkcs12Attribute{}, err
+	}
+	return pkcs12Attribute{ID: oidPKCS9FriendlyName, Values: pkcs12AttributeValue(value)}, nil
+}
+
+// pkcs12CertSafeBag returns the bag of the given certificate.
+func pkcs12CertSafeBag(cert *x509.Certificate, attributes ...pkcs12Attribute) (pkcs12SafeBag, error) {
+	certBag, err := asn1.Marshal(pkcs12CertBag{
+		ID:   oidPKCS9X509Certificate,
+		Data: cert.Raw,
+	})
+	if err != nil {
+		return pkcs12SafeBag{}, err
+	}
+	return pkcs12SafeBag{
+		ID:         oidPKCS12CertBag,
+		Value:      explicitTag0(certBag),
+		Attributes: attributes,
+	}, nil
+}
+
+// encodePKCS12 encodes the given bags as a PKCS#12 file, whose integrity is
+// protected with password.
+func encodePKCS12(bags []pkcs12SafeBag, password string) ([]byte, error) {
+	safeContents, err := asn1.Marshal(bags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PKCS#12 safe contents: %w", err)
+	}
+	safe, err := pkcs12DataContentInfo(safeContents)
+	if err != nil {
+		return nil, err
+	}
+	authenticatedSafe, err := asn1.Marshal([]pkcs7ContentInfo{safe})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PKCS#12 authenticated safe: %w", err)
+	}
+	authSafe, err := pkcs12DataContentInfo(authenticatedSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, pkcs12MACSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate PKCS#12 MAC salt: %w", err)
+	}
+	key := pkcs12KDF(append(bmpString(password), 0, 0), salt, pkcs12MACIterations, 3, sha256.Size)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(authenticatedSafe)
+
+	return asn1.Marshal(pkcs12PFX{
+		Version:  3,
+		AuthSafe: authSafe,
+		MacData: pkcs12MacData{
+			Mac: pkcs12DigestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+				Digest:    mac.Sum(nil),
+			},
+			MacSalt:    salt,
+			Iterations: pkcs12MACIterations,
+		},
+	})
+}
+
+// pkcs12DataContentInfo wraps content in a PKCS#7 data ContentInfo.
+func pkcs12DataContentInfo(content []byte) (pkcs7ContentInfo, error) {
+	octets, err := asn1.Marshal(content)
+	if err != nil {
+		return pkcs7ContentInfo{}, err
+	}
+	return pkcs7ContentInfo{
+		ContentType: oidPKCS7Data,
+		Content:     explicitTag0(octets),
+	}, nil
+}
+
+// explicitTag0 returns the given DER value explicitly tagged [0]. Tags in
+// struct fields don't apply to asn1.RawValue fields.
+func explicitTag0(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// pkcs12AttributeValue returns the set of values of an attribute holding
+// the single given DER value.
+func pkcs12AttributeValue(value []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: value}
+}
+
+// bmpString returns the big-endian UTF-16 encoding of s, as BMPStrings and
+// the passwords of Java KeyStores and PKCS#12 files are encoded.
+func bmpString(s string) []byte {
+	ret := make([]byte, 0, 2*len(s))
+	for _, c := range utf16.Encode([]rune(s)) {
+		ret = append(ret, byte(c>>8), byte(c))
+	}
+	return ret
+}
+
+// pkcs12KDF derives size bytes of key material of the given purpose id from
+// the password and salt, as specified by RFC 7292 appendix B.2, with SHA-256.
+func pkcs12KDF(password, salt []byte, iterations int, id byte, size int) []byte {
+	const u, v = sha256.Size, 64
+
+	fill := func(in []byte) []byte {
+		if len(in) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(in)+v-1)/v))
+		for i := range out {
+			out[i] = in[i%len(in)]
+		}
+		return out
+	}
+
+	d := bytes.Repeat([]byte{id}, v)
+	i := append(fill(salt), fill(password)...)
+
+	var out []byte
+	one := big.NewInt(1)
+	for len(out) < size {
+		h := sha256.New()
+		h.Write(d)
+		h.Write(i)
+		a := h.Sum(nil)
+		for r := 1; r < iterations; r++ {
+			sum := sha256.Sum256(a)
+			a = sum[:]
+		}
+		out = append(out, a...)
+
+		// I_j = (I_j + B + 1) mod 2^(8v) for each v-byte block of I
+		b := new(big.Int).SetBytes(fill(a)[:v])
+		b.Add(b, one)
+		for j := 0; j < len(i); j += v {
+			block := new(big.Int).SetBytes(i[j : j+v])
+			block.Add(block, b)
+			sum := block.Bytes()
+			if len(sum) > v {
+				sum = sum[len(sum)-v:]
+			}
+			copy(i[j:j+v], make([]byte, v))
+			copy(i[j+v-len(sum):j+v], sum)
+		}
+	}
+	return out[:size]
+}
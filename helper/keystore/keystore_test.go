@@ -0,0 +1,111 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testChain returns a leaf certificate and its key, issued by an
+// intermediate CA issued by a root CA, and the chain of the leaf.
+func testChain(t *testing.T) (crypto.Signer, *x509.Certificate, []*x509.Certificate) {
+	t.Helper()
+
+	issue := func(cn string, isCA bool, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(time.Now().UnixNano()),
+			Subject:               pkix.Name{CommonName: cn},
+			NotBefore:             time.Now().Add(-time.Minute),
+			NotAfter:              time.Now().Add(time.Hour),
+			BasicConstraintsValid: true,
+			IsCA:                  isCA,
+		}
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return cert
+	}
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	root := issue("Root CA", true, rootKey, nil, nil)
+	intKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	intermediate := issue("Intermediate CA", true, intKey, root, rootKey)
+	leafKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	leaf := issue("www.example.com", false, leafKey, intermediate, intKey)
+
+	return leafKey, leaf, []*x509.Certificate{intermediate, root}
+}
+
+func TestPKCS7Certs(t *testing.T) {
+	_, leaf, chain := testChain(t)
+	certs := append([]*x509.Certificate{leaf}, chain...)
+
+	p7b, err := EncodePKCS7Certs(certs)
+	require.NoError(t, err)
+	decoded, err := DecodePKCS7Certs(p7b)
+	require.NoError(t, err)
+	require.Equal(t, certs, decoded)
+}
+
+func TestPKCS12(t *testing.T) {
+	key, leaf, chain := testChain(t)
+
+	pfx, err := EncodePKCS12KeyStore(key, leaf, chain, "www.example.com", "changeit")
+	require.NoError(t, err)
+	certs, decodedKey, err := DecodePKCS12(pfx, "changeit")
+	require.NoError(t, err)
+	require.Equal(t, append([]*x509.Certificate{leaf}, chain...), certs)
+	require.True(t, key.(*rsa.PrivateKey).Equal(decodedKey))
+
+	_, _, err = DecodePKCS12(pfx, "wrong")
+	require.Error(t, err)
+
+	pfx, err = EncodePKCS12Truststore(chain, "changeit")
+	require.NoError(t, err)
+	certs, decodedKey, err = DecodePKCS12(pfx, "changeit")
+	require.NoError(t, err)
+	require.Equal(t, chain, certs)
+	require.Nil(t, decodedKey)
+}
+
+func TestJKS(t *testing.T) {
+	key, leaf, chain := testChain(t)
+
+	jks, err := EncodeJKSKeyStore(key, leaf, chain, "Server", "changeit")
+	require.NoError(t, err)
+	entries, err := DecodeJKS(jks, "changeit")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "server", entries[0].Alias)
+	require.Equal(t, append([]*x509.Certificate{leaf}, chain...), entries[0].Certificates)
+	require.True(t, key.(*rsa.PrivateKey).Equal(entries[0].PrivateKey))
+
+	_, err = DecodeJKS(jks, "wrong")
+	require.Error(t, err)
+
+	jks, err = EncodeJKSTruststore(chain, "changeit")
+	require.NoError(t, err)
+	entries, err = DecodeJKS(jks, "changeit")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "0-intermediate ca", entries[0].Alias)
+	require.Equal(t, []*x509.Certificate{chain[0]}, entries[0].Certificates)
+	require.Nil(t, entries[0].PrivateKey)
+	require.Equal(t, "1-root ca", entries[1].Alias)
+}
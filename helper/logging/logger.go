@@ -94,6 +94,9 @@ func Setup(config LogConfig, w io.Writer) (log.InterceptLogger, error) {
 		Level:      config.logLevel,
 		Output:     io.MultiWriter(writers...),
 		JSONFormat: config.IsFormatJson(),
+		// Named loggers must not share the level of their parent so the
+		// level of a subsystem can be changed on its own
+		IndependentLevels: true,
 	})
 	return logger, nil
 }
@@ -0,0 +1,166 @@
+package logging
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+const (
+	// DefaultSamplerBurst is the number of identical messages a LogSampler
+	// lets through per interval when no burst is given.
+	DefaultSamplerBurst = 10
+
+	samplerSummaryMsg = "dropped repeated log messages"
+)
+
+// LogSampler collapses identical messages logged at a high frequency, such
+// as the errors of a storage backend retrying during an outage, so they can't
+// flood the logs. Messages are identical when they have the same level and
+// message, whatever their arguments.
+//
+// Within each interval, the first burst occurrences of a message are logged
+// and the following ones are dropped. At the end of the interval, a summary
+// with the number of dropped messages is logged in their place.
+//
+// The sampler is installed with the Exclude option of the logger, which
+// named loggers inherit, and the logger summaries are written to is set
+// with SetLogger once it is created. Run must be running for the summaries
+// to be written.
+type LogSampler struct {
+	burst    int
+	interval time.Duration
+
+	lock    sync.Mutex
+	logger  log.Logger
+	entries map[samplerKey]*samplerEntry
+	pending []samplerSummary
+
+	// Overridden by tests
+	now func() time.Time
+}
+
+type samplerKey struct {
+	level log.Level
+	msg   string
+}
+
+type samplerEntry struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+type samplerSummary struct {
+	samplerKey
+	suppressed int
+	start      time.Time
+}
+
+// NewLogSampler returns a sampler letting burst identical messages through
+// per interval; a burst of 0 uses DefaultSamplerBurst.
+func NewLogSampler(burst int, interval time.Duration) *LogSampler {
+	if burst <= 0 {
+		burst = DefaultSamplerBurst
+	}
+	return &LogSampler{
+		burst:    burst,
+		interval: interval,
+		entries:  make(map[samplerKey]*samplerEntry),
+		now:      time.Now,
+	}
+}
+
+// SetLogger sets the logger the summaries of dropped messages are written
+// to.
+func (s *LogSampler) SetLogger(logger log.Logger) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.logger = logger
+}
+
+// Exclude reports whether the message must be dropped. It has the signature
+// of the Exclude option of hclog.LoggerOptions. As the logger holds its lock
+// while calling it, it must not log.
+func (s *LogSampler) Exclude(level log.Level, msg string, _ ...interface{}) bool {
+	if msg == samplerSummaryMsg {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	key := samplerKey{level: level, msg: msg}
+	entry, ok := s.entries[key]
+	if ok && now.Sub(entry.start) >= s.interval {
+		s.endLocked(key, entry)
+		ok = false
+	}
+	if !ok {
+		entry = &samplerEntry{start: now}
+		s.entries[key] = entry
+	}
+
+	entry.count++
+	if entry.count <= s.burst {
+		return false
+	}
+	entry.suppressed++
+	return true
+}
+
+// Flush writes the summaries of the intervals which have ended.
+func (s *LogSampler) Flush() {
+	s.lock.Lock()
+	now := s.now()
+	for key, entry := range s.entries {
+		if now.Sub(entry.start) >= s.interval {
+			s.endLocked(key, entry)
+		}
+	}
+	pending := s.pending
+	s.pending = nil
+	logger := s.logger
+	s.lock.Unlock()
+
+	if logger == nil {
+		return
+	}
+	for _, summary := range pending {
+		logger.Log(summary.level, samplerSummaryMsg,
+			"message", summary.msg,
+			"dropped", summary.suppressed,
+			"since", summary.start.Format(time.RFC3339))
+	}
+}
+
+// Run flushes the summaries every interval until stopCh is closed.
+func (s *LogSampler) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			s.Flush()
+			return
+		case <-ticker.C:
+			s.Flush()
+		}
+	}
+}
+
+// endLocked ends the interval of the entry, queuing its summary if messages
+// were dropped.
+func (s *LogSampler) endLocked(key samplerKey, entry *samplerEntry) {
+	if entry.suppressed > 0 {
+		s.pending = append(s.pending, samplerSummary{
+			samplerKey: key,
+			suppressed: entry.suppressed,
+			start:      entry.start,
+		})
+	}
+	delete(s.entries, key)
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSampler(t *testing.T) {
+	now := time.Now()
+	sampler := NewLogSampler(2, time.Minute)
+	sampler.now = func() time.Time { return now }
+
+	var buf bytes.Buffer
+	logger := log.New(&log.LoggerOptions{
+		Output:  &buf,
+		Level:   log.Info,
+		Exclude: sampler.Exclude,
+	})
+	sampler.SetLogger(logger)
+	storageLogger := logger.Named("storage")
+
+	for i := 0; i < 5; i++ {
+		storageLogger.Error("error reading from storage", "attempt", i)
+	}
+	logger.Error("unrelated error")
+	logger.Warn("error reading from storage")
+	require.Equal(t, 2, strings.Count(buf.String(), "[ERROR] storage: error reading from storage"))
+	require.Contains(t, buf.String(), "unrelated error")
+	require.Contains(t, buf.String(), "[WARN]  error reading from storage")
+
+	// Nothing is summarized before the end of the interval
+	sampler.Flush()
+	require.NotContains(t, buf.String(), samplerSummaryMsg)
+
+	now = now.Add(time.Minute)
+	buf.Reset()
+	sampler.Flush()
+	require.Equal(t, 1, strings.Count(buf.String(), samplerSummaryMsg))
+	require.Contains(t, buf.String(), "message=\"error reading from storage\" dropped=3")
+
+	// A new interval lets the burst through again
+	buf.Reset()
+	for i := 0; i < 3; i++ {
+		storageLogger.Error("error reading from storage", "attempt", i)
+	}
+	require.Equal(t, 2, strings.Count(buf.String(), "error reading from storage"))
+
+	// Messages of an ended interval are summarized even if the next message
+	// arrives before the flush
+	now = now.Add(2 * time.Minute)
+	buf.Reset()
+	storageLogger.Error("error reading from storage")
+	require.Equal(t, 1, strings.Count(buf.String(), "error reading from storage"))
+	sampler.Flush()
+	require.Contains(t, buf.String(), "dropped=1")
+
+	stopCh := make(chan struct{})
+	close(stopCh)
+	sampler.Run(stopCh)
+}
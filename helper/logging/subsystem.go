@@ -0,0 +1,148 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+// SubsystemLoggers tracks the loggers of each subsystem of a process so the
+// log level of a subsystem can be changed at runtime without affecting the
+// others. The loggers must have independent levels, as the ones created by
+// Setup do.
+//
+// The effective level of a subsystem is, in order of precedence, the level
+// set at runtime with SetLevel, the level configured for the subsystem with
+// SetConfiguredLevels, and the configured default level.
+type SubsystemLoggers struct {
+	lock         sync.RWMutex
+	subsystems   map[string][]log.Logger
+	defaultLevel log.Level
+	configured   map[string]log.Level
+	overrides    map[string]log.Level
+}
+
+// NewSubsystemLoggers returns a registry of the given subsystems, with no
+// loggers yet.
+func NewSubsystemLoggers(defaultLevel log.Level, subsystems ...string) *SubsystemLoggers {
+	s := &SubsystemLoggers{
+		subsystems:   make(map[string][]log.Logger, len(subsystems)),
+		defaultLevel: defaultLevel,
+		configured:   make(map[string]log.Level),
+		overrides:    make(map[string]log.Level),
+	}
+	for _, subsystem := range subsystems {
+		s.subsystems[subsystem] = nil
+	}
+	return s
+}
+
+// Register adds logger to the loggers of subsystem, sets its level to the
+// current level of the subsystem and returns it. Loggers derived from it
+// with Named afterwards only follow later level changes if they are
+// registered too.
+func (s *SubsystemLoggers) Register(subsystem string, logger log.Logger) log.Logger {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.subsystems[subsystem]; !ok {
+		panic(fmt.Sprintf("unknown logging subsystem %q", subsystem))
+	}
+	s.subsystems[subsystem] = append(s.subsystems[subsystem], logger)
+	logger.SetLevel(s.levelLocked(subsystem))
+	return logger
+}
+
+// Subsystems returns the names of the subsystems, sorted.
+func (s *SubsystemLoggers) Subsystems() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	subsystems := make([]string, 0, len(s.subsystems))
+	for subsystem := range s.subsystems {
+		subsystems = append(subsystems, subsystem)
+	}
+	sort.Strings(subsystems)
+	return subsystems
+}
+
+// Levels returns the effective level of each subsystem.
+func (s *SubsystemLoggers) Levels() map[string]log.Level {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	levels := make(map[string]log.Level, len(s.subsystems))
+	for subsystem := range s.subsystems {
+		levels[subsystem] = s.levelLocked(subsystem)
+	}
+	return levels
+}
+
+// SetLevel overrides the level of subsystem until it is reset.
+func (s *SubsystemLoggers) SetLevel(subsystem string, level log.Level) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.subsystems[subsystem]; !ok {
+		return fmt.Errorf("unknown logging subsystem %q", subsystem)
+	}
+	s.overrides[subsystem] = level
+	s.applyLocked(subsystem)
+	return nil
+}
+
+// ResetLevel reverts subsystem to its configured level.
+func (s *SubsystemLoggers) ResetLevel(subsystem string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.subsystems[subsystem]; !ok {
+		return fmt.Errorf("unknown logging subsystem %q", subsystem)
+	}
+	delete(s.overrides, subsystem)
+	s.applyLocked(subsystem)
+	return nil
+}
+
+// SetConfiguredLevels replaces the default level and the levels configured
+// per subsystem, as on a configuration reload. Levels set with SetLevel keep
+// precedence.
+func (s *SubsystemLoggers) SetConfiguredLevels(defaultLevel log.Level, levels map[string]log.Level) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for subsystem := range levels {
+		if _, ok := s.subsystems[subsystem]; !ok {
+			return fmt.Errorf("unknown logging subsystem %q", subsystem)
+		}
+	}
+
+	s.defaultLevel = defaultLevel
+	s.configured = make(map[string]log.Level, len(levels))
+	for subsystem, level := range levels {
+		s.configured[subsystem] = level
+	}
+	for subsystem := range s.subsystems {
+		s.applyLocked(subsystem)
+	}
+	return nil
+}
+
+func (s *SubsystemLoggers) levelLocked(subsystem string) log.Level {
+	if level, ok := s.overrides[subsystem]; ok {
+		return level
+	}
+	if level, ok := s.configured[subsystem]; ok {
+		return level
+	}
+	return s.defaultLevel
+}
+
+func (s *SubsystemLoggers) applyLocked(subsystem string) {
+	level := s.levelLocked(subsystem)
+	for _, logger := range s.subsystems[subsystem] {
+		logger.SetLevel(level)
+	}
+}
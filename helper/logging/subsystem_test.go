@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubsystemLoggers(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := Setup(NewLogConfig("agent", log.Info, StandardFormat, ""), &buf)
+	require.NoError(t, err)
+
+	loggers := NewSubsystemLoggers(log.Info, "caching", "templating")
+	require.Equal(t, []string{"caching", "templating"}, loggers.Subsystems())
+
+	cache := loggers.Register("caching", logger.Named("cache"))
+	leaseCache := loggers.Register("caching", logger.Named("cache.leasecache"))
+	template := loggers.Register("templating", logger.Named("template.server"))
+
+	require.Error(t, loggers.SetLevel("unknown", log.Debug))
+	require.NoError(t, loggers.SetLevel("caching", log.Debug))
+	require.True(t, cache.IsDebug())
+	require.True(t, leaseCache.IsDebug())
+	require.False(t, template.IsDebug())
+	require.False(t, logger.IsDebug(), "the parent logger should keep its level")
+
+	// Configured levels apply below runtime overrides
+	require.Error(t, loggers.SetConfiguredLevels(log.Info, map[string]log.Level{"unknown": log.Trace}))
+	require.NoError(t, loggers.SetConfiguredLevels(log.Warn, map[string]log.Level{"caching": log.Error}))
+	require.Equal(t, map[string]log.Level{"caching": log.Debug, "templating": log.Warn}, loggers.Levels())
+	require.True(t, template.IsWarn())
+	require.False(t, template.IsInfo())
+
+	require.NoError(t, loggers.ResetLevel("caching"))
+	require.Equal(t, map[string]log.Level{"caching": log.Error, "templating": log.Warn}, loggers.Levels())
+	require.False(t, leaseCache.IsWarn())
+
+	// Loggers registered later start at the current level of their subsystem
+	late := loggers.Register("templating", logger.Named("template.runner"))
+	require.True(t, late.IsWarn())
+	require.False(t, late.IsInfo())
+
+	late.Warn("visible warning")
+	late.Info("hidden info")
+	require.Contains(t, buf.String(), "visible warning")
+	require.NotContains(t, buf.String(), "hidden info")
+
+	require.Panics(t, func() {
+		loggers.Register("unknown", logger.Named("unknown"))
+	})
+}
@@ -0,0 +1,260 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+// DefaultSubscriberBufferSize is the number of messages buffered for a
+// subscriber when no buffer size is given.
+const DefaultSubscriberBufferSize = 512
+
+// Multiplexer streams the logs of an InterceptLogger to any number of
+// concurrent subscribers through a single sink, which is only registered
+// while there are subscribers. Each subscriber filters the messages it
+// receives by level and logger name, and chooses their format.
+//
+// Logging never blocks on a subscriber: when the buffer of a slow subscriber
+// is full, its messages are dropped and the number of dropped messages is
+// reported with the next message it receives.
+type Multiplexer struct {
+	logger log.InterceptLogger
+
+	// registerLock serializes the registration of the sink, which must not
+	// happen while holding lock as the logger calls Accept with its own lock
+	// held.
+	registerLock sync.Mutex
+
+	lock        sync.Mutex
+	subscribers map[*Subscriber]struct{}
+}
+
+// SubscriberOptions are the filters and format of the messages streamed to
+// a subscriber.
+type SubscriberOptions struct {
+	// Level is the minimum level of the messages streamed.
+	Level log.Level
+
+	// Loggers are the names of the loggers whose messages are streamed,
+	// along with the messages of their sub-loggers. All messages are streamed
+	// when empty.
+	Loggers []string
+
+	// JSONFormat formats messages as JSON rather than text.
+	JSONFormat bool
+
+	// Framed wraps each message in a Frame.
+	Framed bool
+
+	// BufferSize is the number of messages buffered before messages are
+	// dropped; 0 uses DefaultSubscriberBufferSize.
+	BufferSize int
+}
+
+// Frame is the JSON object messages are wrapped in for subscribers which
+// requested framing. Frames are separated by newlines.
+type Frame struct {
+	// Seq numbers the messages matching the filters of the subscriber,
+	// including the dropped ones, so that gaps reveal dropped messages.
+	Seq uint64 `json:"seq"`
+
+	// Dropped is the number of messages dropped since the previous frame.
+	Dropped uint64 `json:"dropped,omitempty"`
+
+	Level  string `json:"level"`
+	Logger string `json:"logger,omitempty"`
+
+	// Log is the formatted message: a JSON object with the JSON format, a
+	// string otherwise.
+	Log json.RawMessage `json:"log"`
+}
+
+// Subscriber receives the messages of a Multiplexer matching its options
+// until it is stopped.
+type Subscriber struct {
+	mux     *Multiplexer
+	opts    SubscriberOptions
+	logCh   chan []byte
+	stopped bool
+
+	// The following fields are only accessed with the lock of the
+	// multiplexer held.
+	formatter log.SinkAdapter
+	buf       bytes.Buffer
+	seq       uint64
+	dropped   uint64
+}
+
+// NewMultiplexer returns a multiplexer for the messages of logger and its
+// sub-loggers.
+func NewMultiplexer(logger log.InterceptLogger) *Multiplexer {
+	return &Multiplexer{
+		logger:      logger,
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe starts streaming the messages matching opts to a new
+// subscriber.
+func (m *Multiplexer) Subscribe(opts SubscriberOptions) (*Subscriber, error) {
+	if opts.Level == log.NoLevel || opts.Level == log.Off {
+		return nil, fmt.Errorf("invalid level %q", opts.Level.String())
+	}
+	if opts.BufferSize < 0 {
+		return nil, fmt.Errorf("buffer size must not be negative")
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultSubscriberBufferSize
+	}
+
+	s := &Subscriber{
+		mux:   m,
+		opts:  opts,
+		logCh: make(chan []byte, opts.BufferSize),
+	}
+	s.formatter = log.NewSinkAdapter(&log.LoggerOptions{
+		Level:      opts.Level,
+		JSONFormat: opts.JSONFormat,
+		Output:     &s.buf,
+	})
+
+	m.registerLock.Lock()
+	defer m.registerLock.Unlock()
+
+	m.lock.Lock()
+	m.subscribers[s] = struct{}{}
+	first := len(m.subscribers) == 1
+	m.lock.Unlock()
+
+	if first {
+		m.logger.RegisterSink(m)
+	}
+	return s, nil
+}
+
+// Subscribers returns the number of subscribers.
+func (m *Multiplexer) Subscribers() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return len(m.subscribers)
+}
+
+// Accept implements the SinkAdapter interface, dispatching the message to
+// the subscribers. It is called with the lock of the logger held, so it
+// must neither log nor block.
+func (m *Multiplexer) Accept(name string, level log.Level, msg string, args ...interface{}) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for s := range m.subscribers {
+		s.accept(name, level, msg, args...)
+	}
+}
+
+// Logs returns the channel the messages of the subscriber are sent on. It is
+// closed once the subscriber is stopped.
+func (s *Subscriber) Logs() <-chan []byte {
+	return s.logCh
+}
+
+// Stop stops streaming messages to the subscriber and closes its channel.
+func (s *Subscriber) Stop() {
+	m := s.mux
+	m.registerLock.Lock()
+	defer m.registerLock.Unlock()
+
+	m.lock.Lock()
+	if s.stopped {
+		m.lock.Unlock()
+		return
+	}
+	s.stopped = true
+	delete(m.subscribers, s)
+	last := len(m.subscribers) == 0
+	// Messages are only sent with the lock held, so none can be sent once
+	// the subscriber is removed
+	close(s.logCh)
+	m.lock.Unlock()
+
+	if last {
+		m.logger.DeregisterSink(m)
+	}
+}
+
+func (s *Subscriber) matches(name string, level log.Level) bool {
+	if level < s.opts.Level {
+		return false
+	}
+	if len(s.opts.Loggers) == 0 {
+		return true
+	}
+	for _, logger := range s.opts.Loggers {
+		if name == logger || strings.HasPrefix(name, logger+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscriber) accept(name string, level log.Level, msg string, args ...interface{}) {
+	if !s.matches(name, level) {
+		return
+	}
+	s.seq++
+
+	// Without framing, the dropped messages are reported by a message of
+	// their own, which needs room in the buffer as well
+	needed := 1
+	if s.dropped > 0 && !s.opts.Framed {
+		needed = 2
+	}
+	if cap(s.logCh)-len(s.logCh) < needed {
+		s.dropped++
+		return
+	}
+
+	s.buf.Reset()
+	s.formatter.Accept(name, level, msg, args...)
+	formatted := s.buf.Bytes()
+	if len(formatted) == 0 {
+		return
+	}
+
+	var message []byte
+	if s.opts.Framed {
+		frame := &Frame{
+			Seq:     s.seq,
+			Dropped: s.dropped,
+			Level:   level.String(),
+			Logger:  name,
+		}
+		if s.opts.JSONFormat {
+			frame.Log = bytes.TrimSpace(formatted)
+		} else {
+			line, err := json.Marshal(strings.TrimSuffix(string(formatted), "\n"))
+			if err != nil {
+				return
+			}
+			frame.Log = line
+		}
+		encoded, err := json.Marshal(frame)
+		if err != nil {
+			return
+		}
+		message = append(encoded, '\n')
+	} else {
+		if s.dropped > 0 {
+			s.logCh <- []byte(fmt.Sprintf("Monitor dropped %d logs during monitor request\n", s.dropped))
+		}
+		message = make([]byte, len(formatted))
+		copy(message, formatted)
+	}
+
+	s.dropped = 0
+	s.logCh <- message
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, s *Subscriber) string {
+	t.Helper()
+	select {
+	case l := <-s.Logs():
+		return string(l)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected to receive from log channel")
+	}
+	return ""
+}
+
+func TestMultiplexer_Filters(t *testing.T) {
+	t.Parallel()
+
+	logger := log.NewInterceptLogger(&log.LoggerOptions{
+		Level: log.Error,
+	})
+	mux := NewMultiplexer(logger)
+
+	all, err := mux.Subscribe(SubscriberOptions{Level: log.Debug})
+	require.NoError(t, err)
+	defer all.Stop()
+	raft, err := mux.Subscribe(SubscriberOptions{Level: log.Trace, Loggers: []string{"storage.raft"}})
+	require.NoError(t, err)
+	defer raft.Stop()
+	require.Equal(t, 2, mux.Subscribers())
+
+	logger.Named("core").Trace("core trace")
+	logger.Named("storage").Named("raft").Trace("raft trace")
+	logger.Named("storage").Named("raftish").Info("raftish info")
+	logger.Named("storage").Named("raft").Named("fsm").Info("fsm info")
+
+	require.Contains(t, receive(t, all), "[INFO]  storage.raftish: raftish info")
+	require.Contains(t, receive(t, all), "[INFO]  storage.raft.fsm: fsm info")
+	require.Contains(t, receive(t, raft), "[TRACE] storage.raft: raft trace")
+	require.Contains(t, receive(t, raft), "[INFO]  storage.raft.fsm: fsm info")
+
+	_, err = mux.Subscribe(SubscriberOptions{Level: log.NoLevel})
+	require.Error(t, err)
+}
+
+func TestMultiplexer_Framed(t *testing.T) {
+	t.Parallel()
+
+	logger := log.NewInterceptLogger(&log.LoggerOptions{
+		Level: log.Error,
+	})
+	mux := NewMultiplexer(logger)
+
+	text, err := mux.Subscribe(SubscriberOptions{Level: log.Info, Framed: true})
+	require.NoError(t, err)
+	defer text.Stop()
+	jsonSub, err := mux.Subscribe(SubscriberOptions{Level: log.Info, Framed: true, JSONFormat: true})
+	require.NoError(t, err)
+	defer jsonSub.Stop()
+
+	logger.Named("core").Info("first", "key", "value")
+	logger.Named("core").Info("second")
+
+	for i, expected := range []string{"first", "second"} {
+		var frame Frame
+		require.NoError(t, json.Unmarshal([]byte(receive(t, text)), &frame))
+		require.Equal(t, uint64(i+1), frame.Seq)
+		require.Equal(t, "core", frame.Logger)
+		require.Equal(t, "info", frame.Level)
+		var line string
+		require.NoError(t, json.Unmarshal(frame.Log, &line))
+		require.Contains(t, line, "[INFO]  core: "+expected)
+
+		require.NoError(t, json.Unmarshal([]byte(receive(t, jsonSub)), &frame))
+		require.Equal(t, uint64(i+1), frame.Seq)
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(frame.Log, &entry))
+		require.Equal(t, expected, entry["@message"])
+	}
+}
+
+// Ensure slow subscribers drop messages rather than blocking logging, and
+// are told how many were dropped
+func TestMultiplexer_DroppedMessages(t *testing.T) {
+	t.Parallel()
+
+	logger := log.NewInterceptLogger(&log.LoggerOptions{
+		Level: log.Error,
+	})
+	mux := NewMultiplexer(logger)
+
+	text, err := mux.Subscribe(SubscriberOptions{Level: log.Debug, BufferSize: 5})
+	require.NoError(t, err)
+	defer text.Stop()
+	framed, err := mux.Subscribe(SubscriberOptions{Level: log.Debug, BufferSize: 5, Framed: true})
+	require.NoError(t, err)
+	defer framed.Stop()
+
+	for i := 0; i < 100; i++ {
+		logger.Debug(fmt.Sprintf("test message %d", i))
+	}
+	for i := 0; i < 5; i++ {
+		receive(t, text)
+		receive(t, framed)
+	}
+
+	logger.Debug("after drops")
+	require.Equal(t, "Monitor dropped 95 logs during monitor request\n", receive(t, text))
+	require.Contains(t, receive(t, text), "after drops")
+
+	var frame Frame
+	require.NoError(t, json.Unmarshal([]byte(receive(t, framed)), &frame))
+	require.Equal(t, uint64(101), frame.Seq)
+	require.Equal(t, uint64(95), frame.Dropped)
+}
+
+func TestMultiplexer_Stop(t *testing.T) {
+	t.Parallel()
+
+	logger := log.NewInterceptLogger(&log.LoggerOptions{
+		Level: log.Error,
+	})
+	mux := NewMultiplexer(logger)
+
+	first, err := mux.Subscribe(SubscriberOptions{Level: log.Info})
+	require.NoError(t, err)
+	second, err := mux.Subscribe(SubscriberOptions{Level: log.Info})
+	require.NoError(t, err)
+
+	first.Stop()
+	first.Stop()
+	_, ok := <-first.Logs()
+	require.False(t, ok)
+
+	logger.Info("still streaming")
+	require.True(t, strings.Contains(receive(t, second), "still streaming"))
+
+	second.Stop()
+	require.Equal(t, 0, mux.Subscribers())
+	logger.Info("no subscribers")
+}
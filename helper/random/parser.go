@@ -67,8 +67,11 @@ func (p PolicyParser) ParsePolicy(raw string) (sg StringGenerator, err error) {
 	}
 
 	gen = StringGenerator{
-		Length: gen.Length,
-		Rules:  rules,
+		Length:    gen.Length,
+		Mode:      gen.Mode,
+		Words:     gen.Words,
+		Separator: gen.Separator,
+		Rules:     rules,
 	}
 
 	err = gen.validateConfig()
@@ -0,0 +1,79 @@
+package random
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+const (
+	// ModeCharacters generates passwords by picking characters from the charset of the rules. This is the default.
+	ModeCharacters = "characters"
+
+	// ModePassphrase generates diceware-style passphrases made of words from a wordlist.
+	ModePassphrase = "passphrase"
+
+	// ModePronounceable generates passwords made of alternating consonants and vowels.
+	ModePronounceable = "pronounceable"
+)
+
+var (
+	//go:embed wordlist.txt
+	rawWordlist string
+
+	// DefaultWordlist is the list of words passphrases are built from.
+	DefaultWordlist = strings.Fields(rawWordlist)
+
+	pronounceableConsonants = []rune("bcdfghjklmnprstvwxz")
+	pronounceableVowels     = []rune("aeiouy")
+)
+
+// generatePassphrase picks g.Words words from the wordlist and joins them with the separator.
+func (g *StringGenerator) generatePassphrase(rng io.Reader) (candidate []rune, err error) {
+	words := make([]string, 0, g.Words)
+	for len(words) < g.Words {
+		i, err := randomIndex(rng, len(DefaultWordlist))
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, DefaultWordlist[i])
+	}
+	return []rune(strings.Join(words, g.Separator)), nil
+}
+
+// generatePronounceable alternates consonants and vowels, starting with either, until g.Length runes are generated.
+func (g *StringGenerator) generatePronounceable(rng io.Reader) (candidate []rune, err error) {
+	vowel, err := randomIndex(rng, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate = make([]rune, 0, g.Length)
+	for len(candidate) < g.Length {
+		charset := pronounceableConsonants
+		if len(candidate)%2 == vowel {
+			charset = pronounceableVowels
+		}
+		i, err := randomIndex(rng, len(charset))
+		if err != nil {
+			return nil, err
+		}
+		candidate = append(candidate, charset[i])
+	}
+	return candidate, nil
+}
+
+// randomIndex returns a uniformly distributed index in [0, n).
+func randomIndex(rng io.Reader, n int) (int, error) {
+	if rng == nil {
+		rng = rand.Reader
+	}
+	i, err := rand.Int(rng, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("unable to generate random index: %w", err)
+	}
+	return int(i.Int64()), nil
+}
@@ -0,0 +1,112 @@
+package random
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStringGenerator_Generate_passphrase(t *testing.T) {
+	gen, err := ParsePolicy(`
+mode = "passphrase"
+words = 5
+separator = "-"
+`)
+	if err != nil {
+		t.Fatalf("no error expected, got: %s", err)
+	}
+
+	words := map[string]bool{}
+	for _, word := range DefaultWordlist {
+		words[word] = true
+	}
+
+	for i := 0; i < 20; i++ {
+		str, err := gen.Generate(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("no error expected, got: %s", err)
+		}
+		parts := strings.Split(str, "-")
+		if len(parts) != 5 {
+			t.Fatalf("expected 5 words, got %q", str)
+		}
+		for _, part := range parts {
+			if !words[part] {
+				t.Fatalf("word %q of %q is not in the wordlist", part, str)
+			}
+		}
+		if failures := gen.Validate(str); len(failures) > 0 {
+			t.Fatalf("generated passphrase %q failed validation: %v", str, failures)
+		}
+	}
+}
+
+func TestStringGenerator_Generate_pronounceable(t *testing.T) {
+	gen, err := ParsePolicy(`
+mode = "pronounceable"
+length = 12
+`)
+	if err != nil {
+		t.Fatalf("no error expected, got: %s", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		str, err := gen.Generate(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("no error expected, got: %s", err)
+		}
+		if len(str) != 12 {
+			t.Fatalf("expected 12 characters, got %q", str)
+		}
+		for j := 1; j < len(str); j++ {
+			if charIn(rune(str[j]), pronounceableVowels) == charIn(rune(str[j-1]), pronounceableVowels) {
+				t.Fatalf("consonants and vowels do not alternate in %q", str)
+			}
+		}
+	}
+}
+
+func TestStringGenerator_ValidateCandidate(t *testing.T) {
+	type testCase struct {
+		policy       string
+		candidate    string
+		expectedFail int
+	}
+
+	tests := map[string]testCase{
+		"valid": {
+			policy:       "length = 8\nrule \"charset\" {\n  charset = \"abc123\"\n  min-chars = 1\n}\nrule \"charset\" {\n  charset = \"123\"\n  min-chars = 2\n}",
+			candidate:    "abc12abc3",
+			expectedFail: 0,
+		},
+		"too short and missing digits": {
+			policy:       "length = 8\nrule \"charset\" {\n  charset = \"abc123\"\n  min-chars = 1\n}\nrule \"charset\" {\n  charset = \"123\"\n  min-chars = 2\n}",
+			candidate:    "abc",
+			expectedFail: 2,
+		},
+		"character outside of the charset": {
+			policy:       "length = 4\nrule \"charset\" {\n  charset = \"abc\"\n}",
+			candidate:    "abcd",
+			expectedFail: 1,
+		},
+		"passphrase with too few words": {
+			policy:       "mode = \"passphrase\"\nwords = 4\nseparator = \" \"",
+			candidate:    "correct horse battery",
+			expectedFail: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gen, err := ParsePolicy(test.policy)
+			if err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+
+			failures := gen.Validate(test.candidate)
+			if len(failures) != test.expectedFail {
+				t.Fatalf("expected %d failures, got %v", test.expectedFail, failures)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"math"
 	"sort"
+	"strings"
 	"time"
 	"unicode"
 
@@ -69,9 +70,18 @@ func sortCharset(chars string) string {
 // StringGenerator generats random strings from the provided charset & adhering to a set of rules. The set of rules
 // are things like CharsetRule which requires a certain number of characters from a sub-charset.
 type StringGenerator struct {
-	// Length of the string to generate.
+	// Length of the string to generate. Passphrases are not limited in length.
 	Length int `mapstructure:"length" json:"length"`
 
+	// Mode used to generate candidate strings: ModeCharacters (default), ModePassphrase or ModePronounceable.
+	Mode string `mapstructure:"mode" json:"mode,omitempty"`
+
+	// Words is the number of words of a passphrase.
+	Words int `mapstructure:"words" json:"words,omitempty"`
+
+	// Separator placed between the words of a passphrase.
+	Separator string `mapstructure:"separator" json:"separator,omitempty"`
+
 	// Rules the generated strings must adhere to.
 	Rules serializableRules `mapstructure:"-" json:"rule"` // This is "rule" in JSON so it matches the HCL property type
 
@@ -116,7 +126,15 @@ func (g *StringGenerator) generate(rng io.Reader) (str string, err error) {
 	// If performance improvements need to be made, this can be changed to read a batch of
 	// potential strings at once rather than one at a time. This will significantly
 	// improve performance, but at the cost of added complexity.
-	candidate, err := randomRunes(rng, g.charset, g.Length)
+	var candidate []rune
+	switch g.Mode {
+	case ModePassphrase:
+		candidate, err = g.generatePassphrase(rng)
+	case ModePronounceable:
+		candidate, err = g.generatePronounceable(rng)
+	default:
+		candidate, err = randomRunes(rng, g.charset, g.Length)
+	}
 	if err != nil {
 		return "", fmt.Errorf("unable to generate random characters: %w", err)
 	}
@@ -131,6 +149,52 @@ func (g *StringGenerator) generate(rng io.Reader) (str string, err error) {
 	return string(candidate), nil
 }
 
+// Validate a candidate string against the generator's configuration and rules. It returns a description of each
+// requirement the candidate fails to meet, or nil if it meets them all. Unlike generated strings, candidates may be
+// longer than the configured length.
+func (g *StringGenerator) Validate(candidate string) (failures []string) {
+	value := []rune(candidate)
+
+	switch g.Mode {
+	case ModePassphrase:
+		if g.Separator != "" {
+			if words := strings.Split(candidate, g.Separator); len(words) < g.Words {
+				failures = append(failures, fmt.Sprintf("must contain at least %d words separated by %q", g.Words, g.Separator))
+			}
+		}
+	default:
+		if len(value) < g.Length {
+			failures = append(failures, fmt.Sprintf("must be at least %d characters long", g.Length))
+		}
+	}
+
+	if g.Mode == "" || g.Mode == ModeCharacters {
+		if len(g.charset) == 0 {
+			g.charset = getChars(g.Rules)
+		}
+		for _, r := range value {
+			if !charIn(r, g.charset) {
+				failures = append(failures, fmt.Sprintf("contains character %q which is not in the charset", r))
+				break
+			}
+		}
+	}
+
+	for _, rule := range g.Rules {
+		if rule.Pass(value) {
+			continue
+		}
+		switch r := rule.(type) {
+		case CharsetRule:
+			failures = append(failures, fmt.Sprintf("must contain at least %d characters from %q", r.MinChars, string(r.Charset)))
+		default:
+			failures = append(failures, fmt.Sprintf("does not pass the %s rule", rule.Type()))
+		}
+	}
+
+	return failures
+}
+
 const (
 	// maxCharsetLen is the maximum length a charset is allowed to be when generating a candidate string.
 	// This is the total number of numbers available for selecting an index out of the charset slice.
@@ -221,6 +285,22 @@ func randomRunes(rng io.Reader, charset []rune, length int) (candidate []rune, e
 func (g *StringGenerator) validateConfig() (err error) {
 	merr := &multierror.Error{}
 
+	switch g.Mode {
+	case "", ModeCharacters:
+	case ModePassphrase:
+		if g.Words <= 0 {
+			merr = multierror.Append(merr, fmt.Errorf("words must be > 0"))
+		}
+		return merr.ErrorOrNil()
+	case ModePronounceable:
+		if g.Length <= 0 {
+			merr = multierror.Append(merr, fmt.Errorf("length must be > 0"))
+		}
+		return merr.ErrorOrNil()
+	default:
+		return fmt.Errorf("unrecognized mode %q", g.Mode)
+	}
+
 	// Ensure the sum of minimum lengths in the rules doesn't exceed the length specified
 	minLen := getMinLength(g.Rules)
 	if g.Length <= 0 {
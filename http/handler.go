@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -12,7 +13,6 @@ import (
 	"mime"
 	"net"
 	"net/http"
-	"net/http/pprof"
 	"net/textproto"
 	"net/url"
 	"os"
@@ -60,6 +60,13 @@ const (
 	// soft-mandatory Sentinel policies.
 	PolicyOverrideHeaderName = "X-Vault-Policy-Override"
 
+	// IdempotencyKeyHeaderName is the header carrying a client supplied key
+	// that makes retried write requests return the original response.
+	IdempotencyKeyHeaderName = "X-Vault-Idempotency-Key"
+
+	// maxIdempotencyKeyLength bounds the size of idempotency keys.
+	maxIdempotencyKeyLength = 255
+
 	VaultIndexHeaderName        = "X-Vault-Index"
 	VaultInconsistentHeaderName = "X-Vault-Inconsistent"
 	VaultForwardHeaderName      = "X-Vault-Forward"
@@ -114,6 +121,8 @@ func init() {
 	alwaysRedirectPaths.AddPaths([]string{
 		"sys/storage/raft/snapshot",
 		"sys/storage/raft/snapshot-force",
+		"sys/storage/raft/snapshot-preview",
+		"sys/storage/raft/snapshot-selective",
 		"!sys/storage/raft/snapshot-auto/config",
 	})
 }
@@ -150,6 +159,10 @@ func Handler(props *vault.HandlerProperties) http.Handler {
 			handleAuditNonLogical(core, handleSysGenerateRootAttempt(core, vault.GenerateStandardRootTokenStrategy))))
 		mux.Handle("/v1/sys/generate-root/update", handleRequestForwarding(core,
 			handleAuditNonLogical(core, handleSysGenerateRootUpdate(core, vault.GenerateStandardRootTokenStrategy))))
+		mux.Handle("/v1/sys/generate-recovery-token/attempt", handleRequestForwarding(core,
+			handleAuditNonLogical(core, handleSysGenerateRootAttempt(core, vault.GenerateReadOnlyRecoveryTokenStrategy))))
+		mux.Handle("/v1/sys/generate-recovery-token/update", handleRequestForwarding(core,
+			handleAuditNonLogical(core, handleSysGenerateRootUpdate(core, vault.GenerateReadOnlyRecoveryTokenStrategy))))
 		mux.Handle("/v1/sys/rekey/init", handleRequestForwarding(core, handleSysRekeyInit(core, false)))
 		mux.Handle("/v1/sys/rekey/update", handleRequestForwarding(core, handleSysRekeyUpdate(core, false)))
 		mux.Handle("/v1/sys/rekey/verify", handleRequestForwarding(core, handleSysRekeyVerify(core, false)))
@@ -183,18 +196,7 @@ func Handler(props *vault.HandlerProperties) http.Handler {
 			mux.Handle("/v1/sys/metrics", handleLogicalNoForward(core))
 		}
 
-		if props.ListenerConfig != nil && props.ListenerConfig.Profiling.UnauthenticatedPProfAccess {
-			for _, name := range []string{"goroutine", "threadcreate", "heap", "allocs", "block", "mutex"} {
-				mux.Handle("/v1/sys/pprof/"+name, pprof.Handler(name))
-			}
-			mux.Handle("/v1/sys/pprof/", http.HandlerFunc(pprof.Index))
-			mux.Handle("/v1/sys/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
-			mux.Handle("/v1/sys/pprof/profile", http.HandlerFunc(pprof.Profile))
-			mux.Handle("/v1/sys/pprof/symbol", http.HandlerFunc(pprof.Symbol))
-			mux.Handle("/v1/sys/pprof/trace", http.HandlerFunc(pprof.Trace))
-		} else {
-			mux.Handle("/v1/sys/pprof/", handleLogicalNoForward(core))
-		}
+		mux.Handle("/v1/sys/pprof/", handlePProf(core, props))
 
 		if props.ListenerConfig != nil && props.ListenerConfig.InFlightRequestLogging.UnauthenticatedInFlightAccess {
 			mux.Handle("/v1/sys/in-flight-req", handleUnAuthenticatedInFlightRequest(core))
@@ -288,23 +290,22 @@ func handleAuditNonLogical(core *vault.Core, h http.Handler) http.Handler {
 	})
 }
 
+// currentListenerConfig returns the configuration of the listener the handler
+// serves, as of the last reload of the server configuration.
+func currentListenerConfig(core *vault.Core, props *vault.HandlerProperties) *configutil.Listener {
+	if props.ListenerConfig == nil {
+		return nil
+	}
+	if listenerConfig := core.ListenerConfig(props.ListenerConfig.Address); listenerConfig != nil {
+		return listenerConfig
+	}
+	return props.ListenerConfig
+}
+
 // wrapGenericHandler wraps the handler with an extra layer of handler where
 // tasks that should be commonly handled for all the requests and/or responses
 // are performed.
 func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerProperties) http.Handler {
-	var maxRequestDuration time.Duration
-	var maxRequestSize int64
-	if props.ListenerConfig != nil {
-		maxRequestDuration = props.ListenerConfig.MaxRequestDuration
-		maxRequestSize = props.ListenerConfig.MaxRequestSize
-	}
-	if maxRequestDuration == 0 {
-		maxRequestDuration = vault.DefaultMaxRequestDuration
-	}
-	if maxRequestSize == 0 {
-		maxRequestSize = DefaultMaxRequestSize
-	}
-
 	// Swallow this error since we don't want to pollute the logs and we also don't want to
 	// return an HTTP error here. This information is best effort.
 	hostname, _ := os.Hostname()
@@ -331,17 +332,52 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 
 		// Start with the request context
 		ctx := r.Context()
+
+		// The limits of the listener are looked up on each request, so that
+		// they are reloaded on SIGHUP
+		var requestDuration time.Duration
+		var requestSize int64
+		if listenerConfig := currentListenerConfig(core, props); listenerConfig != nil {
+			requestDuration = listenerConfig.MaxRequestDuration
+			requestSize = listenerConfig.MaxRequestSize
+		}
+		if requestDuration == 0 {
+			requestDuration = vault.DefaultMaxRequestDuration
+		}
+		if requestSize == 0 {
+			requestSize = DefaultMaxRequestSize
+		}
+
+		// Mounts can override the limits of the listener for the requests
+		// made to them, and limit the size of their responses
+		var maxResponseSize int64
+		if strings.HasPrefix(r.URL.Path, "/v1/") {
+			limits := core.MountRequestLimits(namespace.RootContext(ctx), strings.TrimPrefix(r.URL.Path, "/v1/"))
+			if limits != nil {
+				if limits.RequestTimeout > 0 {
+					requestDuration = limits.RequestTimeout
+				}
+				if limits.MaxRequestSize > 0 {
+					requestSize = limits.MaxRequestSize
+				}
+				maxResponseSize = limits.MaxResponseSize
+			}
+		}
+
 		var cancelFunc context.CancelFunc
 		// Add our timeout, but not for the monitor endpoint, as it's streaming
 		if strings.HasSuffix(r.URL.Path, "sys/monitor") {
 			ctx, cancelFunc = context.WithCancel(ctx)
 		} else {
-			ctx, cancelFunc = context.WithTimeout(ctx, maxRequestDuration)
+			ctx, cancelFunc = context.WithTimeout(ctx, requestDuration)
 		}
-		// if maxRequestSize < 0, no need to set context value
+		// if requestSize < 0, no need to set context value
 		// Add a size limiter if desired
-		if maxRequestSize > 0 {
-			ctx = context.WithValue(ctx, "max_request_size", maxRequestSize)
+		if requestSize > 0 {
+			ctx = context.WithValue(ctx, "max_request_size", requestSize)
+		}
+		if maxResponseSize > 0 {
+			ctx = context.WithValue(ctx, "max_response_size", maxResponseSize)
 		}
 		ctx = context.WithValue(ctx, "original_request_path", r.URL.Path)
 		r = r.WithContext(ctx)
@@ -369,13 +405,39 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 			}
 			r = newR
 
+			if props.ListenerConfig != nil && !props.ListenerConfig.PathAllowed(strings.TrimPrefix(r.URL.Path, "/v1/")) {
+				respondError(nw, http.StatusNotFound, nil)
+				cancelFunc()
+				return
+			}
+
 		case strings.HasPrefix(r.URL.Path, "/ui"), r.URL.Path == "/robots.txt", r.URL.Path == "/":
+			// The UI is of no use on a listener restricted to some paths.
+			if props.ListenerConfig != nil && len(props.ListenerConfig.AllowedPaths) > 0 {
+				respondError(nw, http.StatusNotFound, nil)
+				cancelFunc()
+				return
+			}
 		default:
 			respondError(nw, http.StatusNotFound, nil)
 			cancelFunc()
 			return
 		}
 
+		// Drop any token on listeners that do not accept authentication, so
+		// that requests are handled as unauthenticated.
+		if props.ListenerConfig != nil && props.ListenerConfig.AuthMode == configutil.ListenerAuthModeNone {
+			r.Header.Del(consts.AuthHeaderName)
+			r.Header.Del("Authorization")
+		}
+
+		// Client certificate headers are only ever set by listeners, so
+		// remove any sent by the client whether or not this listener sets one.
+		removeClientCertHeaders(r, props.ClientCertHeaders)
+		if props.ListenerConfig != nil && props.ListenerConfig.TLSClientCertHeader != "" {
+			setClientCertHeader(r, props.ListenerConfig.TLSClientCertHeader)
+		}
+
 		// The uuid for the request is going to be generated when a logical
 		// request is generated. But, here we generate one to be able to track
 		// in-flight requests, and use that to update the req data with clientID
@@ -518,6 +580,32 @@ func WrapForwardedForHandler(h http.Handler, l *configutil.Listener) http.Handle
 	})
 }
 
+// removeClientCertHeaders removes the well-known client certificate header and
+// the headers of the listeners from the request.
+func removeClientCertHeaders(r *http.Request, headers []string) {
+	r.Header.Del(consts.TLSClientCertHeaderName)
+	for _, header := range headers {
+		r.Header.Del(header)
+	}
+}
+
+// setClientCertHeader sets the header to the URL encoded PEM of the client
+// certificate verified by the listener, removing any value sent by the client
+// so that backends can trust it.
+func setClientCertHeader(r *http.Request, header string) {
+	r.Header.Del(header)
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return
+	}
+
+	block := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: r.TLS.VerifiedChains[0][0].Raw,
+	})
+	// Encode spaces as %20 rather than +, which all URL decoders understand.
+	r.Header.Set(header, strings.ReplaceAll(url.QueryEscape(string(block)), "+", "%20"))
+}
+
 // stripPrefix is a helper to strip a prefix from the path. It will
 // return false from the second return value if it the prefix doesn't exist.
 func stripPrefix(prefix, path string) (string, bool) {
@@ -1064,6 +1152,19 @@ func requestPolicyOverride(r *http.Request, req *logical.Request) error {
 	return nil
 }
 
+func requestIdempotencyKey(r *http.Request, req *logical.Request) error {
+	key := r.Header.Get(IdempotencyKeyHeaderName)
+	if key == "" {
+		return nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("key cannot be longer than %d characters", maxIdempotencyKeyLength)
+	}
+
+	req.IdempotencyKey = key
+	return nil
+}
+
 // requestWrapInfo adds the WrapInfo value to the logical.Request if wrap info exists
 func requestWrapInfo(r *http.Request, req *logical.Request) (*logical.Request, error) {
 	// First try for the header value
@@ -3,7 +3,9 @@ package http
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -18,6 +20,7 @@ import (
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/versions"
+	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault"
@@ -329,6 +332,108 @@ func TestHandler_InFlightRequest(t *testing.T) {
 	}
 }
 
+func TestHandler_ListenerRestrictions(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealedWithConfig(t, &vault.CoreConfig{EnableUI: true})
+	ln, addr := TestListener(t)
+	props := &vault.HandlerProperties{
+		Core: core,
+		ListenerConfig: &configutil.Listener{
+			AllowedPaths: []string{"sys/health", "sys/mounts"},
+			AuthMode:     configutil.ListenerAuthModeNone,
+		},
+	}
+	TestServerWithListenerAndProperties(t, ln, addr, core, props)
+	defer ln.Close()
+
+	client := cleanhttp.DefaultClient()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	for path, expected := range map[string]int{
+		"/v1/sys/health":   200,
+		"/v1/sys/policy":   404,
+		"/v1/sys/mounts":   403,
+		"/v1/secret/foo":   404,
+		"/ui/":             404,
+		"/v1/sys/healthy":  404,
+		"/v1/sys/mounts/x": 403,
+	} {
+		req, err := http.NewRequest("GET", addr+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The token is stripped by the listener.
+		req.Header.Set(consts.AuthHeaderName, token)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Fatalf("%s: expected %d, got %d", path, expected, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandler_ClientCertHeader(t *testing.T) {
+	const header = "X-Client-Cert"
+
+	// A value sent by the client is never passed on.
+	r := httptest.NewRequest("GET", "/v1/sys/health", nil)
+	r.Header.Set(header, "spoofed")
+	r.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Raw: []byte("unverified")}},
+	}
+	setClientCertHeader(r, header)
+	if v := r.Header.Get(header); v != "" {
+		t.Fatalf("expected no header, got %q", v)
+	}
+
+	leaf := &x509.Certificate{Raw: []byte("leaf")}
+	r.TLS.VerifiedChains = [][]*x509.Certificate{{leaf, {Raw: []byte("ca")}}}
+	setClientCertHeader(r, header)
+
+	unescaped, err := url.PathUnescape(r.Header.Get(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(unescaped))
+	if block == nil || block.Type != "CERTIFICATE" || string(block.Bytes) != "leaf" {
+		t.Fatalf("unexpected header value %q", r.Header.Get(header))
+	}
+}
+
+func TestHandler_ForgedClientCertHeader(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+
+	// The listener doesn't set a client certificate header, but another
+	// listener of the server does.
+	props := &vault.HandlerProperties{
+		Core:              core,
+		ListenerConfig:    &configutil.Listener{},
+		ClientCertHeaders: []string{"X-Client-Cert"},
+	}
+	var received http.Header
+	handler := wrapGenericHandler(core, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}), props)
+
+	r := httptest.NewRequest("GET", "/v1/secret/foo", nil)
+	r.Header.Set("X-Client-Cert", "forged")
+	r.Header.Set(consts.TLSClientCertHeaderName, "forged")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if received == nil {
+		t.Fatal("request was not handled")
+	}
+	for _, header := range []string{"X-Client-Cert", consts.TLSClientCertHeaderName} {
+		if v := received.Get(header); v != "" {
+			t.Fatalf("expected the forged %s header to be removed, got %q", header, v)
+		}
+	}
+}
+
 // TestHandler_MissingToken tests the response / error code if a request comes
 // in with a missing client token. See
 // https://github.com/hashicorp/vault/issues/8377
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-secure-stdlib/strutil"
 	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -41,6 +43,14 @@ func (b *bufferedReader) Close() error {
 
 const MergePatchContentTypeHeader = "application/merge-patch+json"
 
+// raftSnapshotUploadPaths are the paths receiving a snapshot as request body.
+var raftSnapshotUploadPaths = []string{
+	"sys/storage/raft/snapshot",
+	"sys/storage/raft/snapshot-force",
+	"sys/storage/raft/snapshot-preview",
+	"sys/storage/raft/snapshot-selective",
+}
+
 func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.Request) (*logical.Request, io.ReadCloser, int, error) {
 	ns, err := namespace.FromContext(r.Context())
 	if err != nil {
@@ -102,11 +112,12 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 		bufferedBody := newBufferedReader(r.Body)
 		r.Body = bufferedBody
 
-		// If we are uploading a snapshot or receiving an ocsp-request (which
-		// is der encoded) we don't want to parse it. Instead, we will simply
-		// add the HTTP request to the logical request object for later consumption.
+		// If we are uploading a snapshot, receiving an ocsp-request (which
+		// is der encoded) or streaming data to hash we don't want to parse it.
+		// Instead, we will simply add the HTTP request to the logical request
+		// object for later consumption.
 		contentType := r.Header.Get("Content-Type")
-		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" || isOcspRequest(contentType) {
+		if strutil.StrListContains(raftSnapshotUploadPaths, path) || isOcspRequest(contentType) || isStreamingHashRequest(path, contentType) {
 			passHTTPReq = true
 			origBody = r.Body
 		} else {
@@ -209,6 +220,19 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 	return req, origBody, 0, nil
 }
 
+// isStreamingHashRequest returns whether the request streams the data to hash
+// as its body rather than sending it base64-encoded in a JSON payload.
+func isStreamingHashRequest(path, contentType string) bool {
+	if path != "sys/tools/hash" && !strings.HasPrefix(path, "sys/tools/hash/") {
+		return false
+	}
+	contentType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return contentType == "application/octet-stream"
+}
+
 func isOcspRequest(contentType string) bool {
 	contentType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
@@ -281,6 +305,11 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to parse %s header: %w", PolicyOverrideHeaderName, err)
 	}
 
+	err = requestIdempotencyKey(r, req)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to parse %s header: %w", IdempotencyKeyHeaderName, err)
+	}
+
 	return req, origBody, 0, nil
 }
 
@@ -385,6 +414,8 @@ func respondLogical(core *vault.Core, w http.ResponseWriter, r *http.Request, re
 		return
 	}
 
+	maxResponseSize := mountMaxResponseSize(r, req)
+
 	if resp != nil {
 		if resp.Redirect != "" {
 			// If we have a redirect, redirect! We use a 307 code
@@ -395,7 +426,7 @@ func respondLogical(core *vault.Core, w http.ResponseWriter, r *http.Request, re
 
 		// Check if this is a raw response
 		if _, ok := resp.Data[logical.HTTPStatusCode]; ok {
-			respondRaw(w, r, resp)
+			respondRaw(w, r, resp, maxResponseSize)
 			return
 		}
 
@@ -427,15 +458,50 @@ func respondLogical(core *vault.Core, w http.ResponseWriter, r *http.Request, re
 
 	adjustResponse(core, w, req)
 
+	// Enforce the maximum response size tuned on the mount, if any, on the
+	// body as it is sent
+	if maxResponseSize > 0 && ret != nil {
+		var body bytes.Buffer
+		if err := json.NewEncoder(&body).Encode(ret); err != nil {
+			respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if int64(body.Len()) > maxResponseSize {
+			respondError(w, http.StatusInternalServerError, errMaxResponseSize(body.Len(), maxResponseSize))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body.Bytes())
+		return
+	}
+
 	// Respond
 	respondOk(w, ret)
 	return
 }
 
+// mountMaxResponseSize returns the maximum response size tuned on the mount
+// of req, or 0 if its response is not limited. Only the responses of reads
+// and lists are limited, as rejecting the response of other operations would
+// not undo their side effects.
+func mountMaxResponseSize(r *http.Request, req *logical.Request) int64 {
+	if req == nil || (req.Operation != logical.ReadOperation && req.Operation != logical.ListOperation) {
+		return 0
+	}
+	maxResponseSize, _ := r.Context().Value("max_response_size").(int64)
+	return maxResponseSize
+}
+
+func errMaxResponseSize(size int, maxResponseSize int64) error {
+	return fmt.Errorf("response size of %d bytes exceeds the maximum response size of the mount of %d bytes", size, maxResponseSize)
+}
+
 // respondRaw is used when the response is using HTTPContentType and HTTPRawBody
 // to change the default response handling. This is only used for specific things like
-// returning the CRL information on the PKI backends.
-func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response) {
+// returning the CRL information on the PKI backends. Bodies larger than
+// maxResponseSize are rejected, unless it is 0.
+func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response, maxResponseSize int64) {
 	retErr := func(w http.ResponseWriter, err string) {
 		w.Header().Set("X-Vault-Raw-Error", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -518,6 +584,11 @@ func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response)
 	}
 
 WRITE_RESPONSE:
+	if maxResponseSize > 0 && int64(len(body)) > maxResponseSize {
+		retErr(w, errMaxResponseSize(len(body), maxResponseSize).Error())
+		return
+	}
+
 	// Write the response
 	if contentType != "" {
 		w.Header().Set("Content-Type", contentType)
@@ -531,6 +602,18 @@ WRITE_RESPONSE:
 		w.Header().Set("Pragma", pragma)
 	}
 
+	if etag, ok := resp.Data[logical.HTTPETagHeader].(string); ok {
+		w.Header().Set("ETag", etag)
+	}
+
+	if expires, ok := resp.Data[logical.HTTPExpiresHeader].(string); ok {
+		w.Header().Set("Expires", expires)
+	}
+
+	if lastModified, ok := resp.Data[logical.HTTPLastModifiedHeader].(string); ok {
+		w.Header().Set("Last-Modified", lastModified)
+	}
+
 	if wwwAuthn, ok := resp.Data[logical.HTTPWWWAuthenticateHeader].(string); ok {
 		w.Header().Set("WWW-Authenticate", wwwAuthn)
 	}
@@ -309,6 +309,83 @@ func TestLogical_RequestSizeDisableLimit(t *testing.T) {
 	testResponseStatus(t, resp, http.StatusNoContent)
 }
 
+func TestLogical_MountRequestLimits(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestListener(t)
+	props := &vault.HandlerProperties{
+		Core: core,
+		ListenerConfig: &configutil.Listener{
+			MaxRequestSize: 1024,
+			Address:        "127.0.0.1",
+			TLSDisable:     true,
+		},
+	}
+	TestServerWithListenerAndProperties(t, ln, addr, core, props)
+
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPost(t, token, addr+"/v1/sys/mounts/secret/tune", map[string]interface{}{
+		"max_request_size":  4096,
+		"max_response_size": 100,
+	})
+	testResponseStatus(t, resp, http.StatusNoContent)
+
+	// The limit of the mount overrides the one of the listener
+	resp = testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": make([]byte, 2048),
+	})
+	testResponseStatus(t, resp, http.StatusNoContent)
+	resp = testHttpPut(t, token, addr+"/v1/cubbyhole/foo", map[string]interface{}{
+		"data": make([]byte, 2048),
+	})
+	testResponseStatus(t, resp, http.StatusRequestEntityTooLarge)
+
+	// Responses larger than the limit of the mount are rejected
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, http.StatusInternalServerError)
+
+	resp = testHttpPost(t, token, addr+"/v1/sys/mounts/secret/tune", map[string]interface{}{
+		"max_response_size": 0,
+	})
+	testResponseStatus(t, resp, http.StatusNoContent)
+	resp = testHttpGet(t, token, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, http.StatusOK)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/mounts/secret/tune")
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, http.StatusOK)
+	testResponseBody(t, resp, &actual)
+	if actual["max_request_size"] != json.Number("4096") {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if _, ok := actual["max_response_size"]; ok {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestLogical_RespondRawMaxResponseSize(t *testing.T) {
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPRawBody:     []byte(strings.Repeat("a", 200)),
+		},
+	}
+
+	w := httptest.NewRecorder()
+	respondRaw(w, nil, resp, 100)
+	if w.Code != http.StatusInternalServerError || w.Body.Len() != 0 {
+		t.Fatalf("expected the raw response to be rejected, got: %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	respondRaw(w, nil, resp, 200)
+	if w.Code != http.StatusOK || w.Body.Len() != 200 {
+		t.Fatalf("bad: %d %q", w.Code, w.Body.String())
+	}
+}
+
 func TestLogical_ListSuffix(t *testing.T) {
 	core, _, rootToken := vault.TestCoreUnsealed(t)
 	req, _ := http.NewRequest("GET", "http://127.0.0.1:8200/v1/secret/foo", nil)
@@ -26,6 +26,8 @@ func TestSysConfigState_Sanitized(t *testing.T) {
 	configResp := map[string]interface{}{
 		"api_addr":                            "",
 		"cache_size":                          json.Number("0"),
+		"cache_size_bytes":                    json.Number("0"),
+		"cache_pinned_prefixes":               nil,
 		"cluster_addr":                        "",
 		"cluster_cipher_suites":               "",
 		"cluster_name":                        "",
@@ -51,6 +53,12 @@ func TestSysConfigState_Sanitized(t *testing.T) {
 		"enable_response_header_hostname":     false,
 		"enable_response_header_raft_node_id": false,
 		"log_requests_level":                  "",
+		"log_sampling_interval":               json.Number("0"),
+		"log_sampling_burst":                  json.Number("0"),
+		"token_accessor_write_behind":         false,
+		"lazy_lease_restore":                  false,
+		"geoip_country_database":              "",
+		"geoip_asn_database":                  "",
 	}
 
 	expected = map[string]interface{}{
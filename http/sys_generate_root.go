@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/vault/vault"
 )
 
@@ -116,7 +118,20 @@ func handleSysGenerateRootAttemptPut(core *vault.Core, w http.ResponseWriter, r
 	}
 
 	// Attemptialize the generation
-	if err := core.GenerateRootInit(req.OTP, req.PGPKey, generateStrategy); err != nil {
+	if generateStrategy == vault.GenerateReadOnlyRecoveryTokenStrategy {
+		var ttl time.Duration
+		if req.TTL != "" {
+			ttl, err = parseutil.ParseDurationSecond(req.TTL)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, fmt.Errorf("error parsing ttl: %w", err))
+				return
+			}
+		}
+		err = core.GenerateReadOnlyRecoveryTokenInit(req.OTP, req.PGPKey, ttl)
+	} else {
+		err = core.GenerateRootInit(req.OTP, req.PGPKey, generateStrategy)
+	}
+	if err != nil {
 		respondError(w, http.StatusBadRequest, err)
 		return
 	}
@@ -172,6 +187,12 @@ func handleSysGenerateRootUpdate(core *vault.Core, generateStrategy vault.Genera
 		ctx, cancel := core.GetContext()
 		defer cancel()
 
+		// Verify the TOTP code submitted with the key
+		if err := core.CheckUnsealKeyTOTP(ctx, key, req.TOTP); err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
 		// Use the key to make progress on root generation
 		result, err := core.GenerateRootUpdate(ctx, key, req.Nonce, generateStrategy)
 		if err != nil {
@@ -200,6 +221,7 @@ func handleSysGenerateRootUpdate(core *vault.Core, generateStrategy vault.Genera
 type GenerateRootInitRequest struct {
 	OTP    string `json:"otp"`
 	PGPKey string `json:"pgp_key"`
+	TTL    string `json:"ttl"`
 }
 
 type GenerateRootStatusResponse struct {
@@ -218,4 +240,5 @@ type GenerateRootStatusResponse struct {
 type GenerateRootUpdateRequest struct {
 	Nonce string
 	Key   string
+	TOTP  string
 }
@@ -468,3 +468,76 @@ func TestSysGenerateRoot_Update_PGP(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestSysGenerateRecoveryToken_ReadOnly(t *testing.T) {
+	var records *[][]byte
+	ln, addr, token, keys := testServerWithAudit(t, &records)
+	defer ln.Close()
+
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"bar": "baz",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/generate-recovery-token/attempt", map[string]interface{}{
+		"ttl": "48h",
+	})
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/generate-recovery-token/attempt", map[string]interface{}{
+		"ttl": "2h",
+	})
+	var status map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &status)
+	otp := status["otp"].(string)
+
+	var actual map[string]interface{}
+	for _, key := range keys {
+		resp = testHttpPut(t, token, addr+"/v1/sys/generate-recovery-token/update", map[string]interface{}{
+			"nonce": status["nonce"].(string),
+			"key":   hex.EncodeToString(key),
+		})
+		actual = map[string]interface{}{}
+		testResponseStatus(t, resp, 200)
+		testResponseBody(t, resp, &actual)
+	}
+	if actual["complete"] != true || actual["encoded_root_token"] != "" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	tokenBytes, err := base64.RawStdEncoding.DecodeString(actual["encoded_token"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenBytes, err = xor.XORBytes(tokenBytes, []byte(otp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveryToken := string(tokenBytes)
+
+	resp = testHttpGet(t, recoveryToken, addr+"/v1/sys/mounts")
+	testResponseStatus(t, resp, 200)
+	resp = testHttpGet(t, recoveryToken, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 403)
+	resp = testHttpPut(t, recoveryToken, addr+"/v1/sys/mounts/foo", map[string]interface{}{
+		"type": "kv",
+	})
+	testResponseStatus(t, resp, 403)
+
+	// The requests made with the token are audited
+	var audited bool
+	for _, r := range *records {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(r, &entry); err != nil {
+			t.Fatal(err)
+		}
+		auth, _ := entry["auth"].(map[string]interface{})
+		if auth["display_name"] == "recovery-read-only" {
+			audited = true
+		}
+	}
+	if !audited {
+		t.Fatal("expected audit entries for the recovery token")
+	}
+}
@@ -58,6 +58,7 @@ func handleSysInitPut(core *vault.Core, w http.ResponseWriter, r *http.Request)
 		SecretThreshold: req.SecretThreshold,
 		StoredShares:    req.StoredShares,
 		PGPKeys:         req.PGPKeys,
+		TOTPProtected:   req.TOTPProtectedShares,
 	}
 
 	recoveryConfig := &vault.SealConfig{
@@ -97,6 +98,10 @@ func handleSysInitPut(core *vault.Core, w http.ResponseWriter, r *http.Request)
 		RootToken: result.RootToken,
 	}
 
+	for _, k := range result.SecretSharesTOTP {
+		resp.KeysTOTP = append(resp.KeysTOTP, string(k))
+	}
+
 	if len(result.RecoveryShares) > 0 {
 		resp.RecoveryKeys = make([]string, 0, len(result.RecoveryShares))
 		resp.RecoveryKeysB64 = make([]string, 0, len(result.RecoveryShares))
@@ -123,6 +128,8 @@ type InitRequest struct {
 	RecoveryThreshold int      `json:"recovery_threshold"`
 	RecoveryPGPKeys   []string `json:"recovery_pgp_keys"`
 	RootTokenPGPKey   string   `json:"root_token_pgp_key"`
+
+	TOTPProtectedShares bool `json:"totp_protected_shares"`
 }
 
 type InitResponse struct {
@@ -131,6 +138,7 @@ type InitResponse struct {
 	RecoveryKeys    []string `json:"recovery_keys,omitempty"`
 	RecoveryKeysB64 []string `json:"recovery_keys_base64,omitempty"`
 	RootToken       string   `json:"root_token"`
+	KeysTOTP        []string `json:"keys_totp,omitempty"`
 }
 
 type InitStatusResponse struct {
@@ -151,6 +159,9 @@ func validateInitParameters(core *vault.Core, req InitRequest) error {
 	if len(req.PGPKeys) != 0 {
 		barrierFlags = append(barrierFlags, "pgp_keys")
 	}
+	if req.TOTPProtectedShares {
+		barrierFlags = append(barrierFlags, "totp_protected_shares")
+	}
 	if req.RecoveryShares != 0 {
 		recoveryFlags = append(recoveryFlags, "recovery_shares")
 	}
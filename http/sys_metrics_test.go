@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/command/server"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/hashicorp/vault/vault"
@@ -94,3 +95,38 @@ func TestSysPProfUnauthenticated(t *testing.T) {
 	resp = testHttpGet(t, token, addr+"/v1/sys/pprof/cmdline")
 	testResponseStatus(t, resp, 200)
 }
+
+func TestSysPProfUnauthenticated_Reload(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestListener(t)
+	props := &vault.HandlerProperties{
+		Core: core,
+		ListenerConfig: &configutil.Listener{
+			Address: "pprof-listener",
+		},
+	}
+	TestServerWithListenerAndProperties(t, ln, addr, core, props)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpGet(t, "", addr+"/v1/sys/pprof/cmdline")
+	testResponseStatus(t, resp, 403)
+
+	// Unauthenticated access is picked up from the reloaded configuration
+	core.SetConfig(&server.Config{
+		SharedConfig: &configutil.SharedConfig{
+			Listeners: []*configutil.Listener{
+				{
+					Address: "pprof-listener",
+					Profiling: configutil.ListenerProfiling{
+						UnauthenticatedPProfAccess: true,
+					},
+				},
+			},
+		},
+	})
+	resp = testHttpGet(t, "", addr+"/v1/sys/pprof/cmdline")
+	testResponseStatus(t, resp, 200)
+	resp = testHttpGet(t, "", addr+"/v1/sys/pprof/goroutine")
+	testResponseStatus(t, resp, 200)
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"strings"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/monitor"
 	"github.com/hashicorp/vault/helper/testhelpers"
 	"github.com/hashicorp/vault/sdk/helper/logging"
 	"github.com/hashicorp/vault/vault"
@@ -121,3 +123,59 @@ func TestSysMonitorStreamingLogs(t *testing.T) {
 
 	<-stopCh
 }
+
+func TestSysMonitorFramedSubscribers(t *testing.T) {
+	logger := log.NewInterceptLogger(&log.LoggerOptions{
+		Output: log.DefaultOutput,
+		Level:  log.Debug,
+	})
+
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{HandlerFunc: Handler, Logger: logger})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	stopCh := testhelpers.GenerateDebugLogs(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	// Two concurrent subscribers with different filters
+	var scanners []*bufio.Scanner
+	for _, loggerName := range []string{"core0.core", "core0.core.secrets.deletion"} {
+		request := client.NewRequest("GET", "/v1/sys/monitor")
+		request.Params.Add("log_level", "debug")
+		request.Params.Add("logger_name", loggerName)
+		request.Params.Add("framed", "true")
+		resp, err := client.RawRequestWithContext(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		scanners = append(scanners, bufio.NewScanner(resp.Body))
+	}
+
+	for i, loggerName := range []string{"core0.core", "core0.core.secrets.deletion"} {
+		var lastSeq uint64
+		for frames := 0; frames < 3; frames++ {
+			if !scanners[i].Scan() {
+				t.Fatalf("expected a frame for %s: %v", loggerName, scanners[i].Err())
+			}
+
+			var frame monitor.Frame
+			if err := json.Unmarshal(scanners[i].Bytes(), &frame); err != nil {
+				t.Fatalf("expected a JSON frame, got %q: %v", scanners[i].Text(), err)
+			}
+			if frame.Logger != loggerName && !strings.HasPrefix(frame.Logger, loggerName+".") {
+				t.Fatalf("expected a log of %s, got one of %s", loggerName, frame.Logger)
+			}
+			if frame.Seq <= lastSeq {
+				t.Fatalf("expected increasing sequence numbers, got %d after %d", frame.Seq, lastSeq)
+			}
+			lastSeq = frame.Seq
+		}
+	}
+
+	stopCh <- struct{}{}
+	<-stopCh
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/hashicorp/vault/vault"
+)
+
+// handlePProf serves the pprof endpoints without authentication if the
+// listener allows it, and through the system backend otherwise. Whether it
+// is allowed is looked up on each request, so that it is reloaded on SIGHUP.
+func handlePProf(core *vault.Core, props *vault.HandlerProperties) http.Handler {
+	authenticated := handleLogicalNoForward(core)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listenerConfig := currentListenerConfig(core, props)
+		if listenerConfig == nil || !listenerConfig.Profiling.UnauthenticatedPProfAccess {
+			authenticated.ServeHTTP(w, r)
+			return
+		}
+
+		switch name := strings.TrimPrefix(r.URL.Path, "/v1/sys/pprof/"); name {
+		case "goroutine", "threadcreate", "heap", "allocs", "block", "mutex":
+			pprof.Handler(name).ServeHTTP(w, r)
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		default:
+			pprof.Index(w, r)
+		}
+	})
+}
@@ -131,6 +131,7 @@ func handleSysRekeyInitPut(ctx context.Context, core *vault.Core, recovery bool,
 		PGPKeys:              req.PGPKeys,
 		Backup:               req.Backup,
 		VerificationRequired: req.RequireVerification,
+		TOTPProtected:        req.TOTPProtectedShares,
 	}, recovery)
 	if err != nil {
 		respondError(w, err.Code(), err)
@@ -188,6 +189,14 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 		ctx, cancel := core.GetContext()
 		defer cancel()
 
+		// Verify the TOTP code submitted with the key
+		if !recovery {
+			if err := core.CheckUnsealKeyTOTP(ctx, key, req.TOTP); err != nil {
+				respondError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
 		// Use the key to make progress on rekey
 		result, rekeyErr := core.RekeyUpdate(ctx, key, req.Nonce, recovery)
 		if rekeyErr != nil {
@@ -214,6 +223,9 @@ func handleSysRekeyUpdate(core *vault.Core, recovery bool) http.Handler {
 			}
 			resp.Keys = keys
 			resp.KeysB64 = keysB64
+			for _, k := range result.TOTPKeys {
+				resp.KeysTOTP = append(resp.KeysTOTP, string(k))
+			}
 			respondOk(w, resp)
 		} else {
 			handleSysRekeyInitGet(ctx, core, recovery, w, r)
@@ -361,6 +373,7 @@ type RekeyRequest struct {
 	PGPKeys             []string `json:"pgp_keys"`
 	Backup              bool     `json:"backup"`
 	RequireVerification bool     `json:"require_verification"`
+	TOTPProtectedShares bool     `json:"totp_protected_shares"`
 }
 
 type RekeyStatusResponse struct {
@@ -379,6 +392,7 @@ type RekeyStatusResponse struct {
 type RekeyUpdateRequest struct {
 	Nonce string
 	Key   string
+	TOTP  string
 }
 
 type RekeyUpdateResponse struct {
@@ -390,6 +404,7 @@ type RekeyUpdateResponse struct {
 	Backup               bool     `json:"backup"`
 	VerificationRequired bool     `json:"verification_required"`
 	VerificationNonce    string   `json:"verification_nonce,omitempty"`
+	KeysTOTP             []string `json:"keys_totp,omitempty"`
 }
 
 type RekeyVerificationUpdateRequest struct {
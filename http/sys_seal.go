@@ -122,11 +122,14 @@ func handleSysUnseal(core *vault.Core) http.Handler {
 			}
 		}
 
-		// Attempt the unseal.  If migrate was specified, the key should correspond
-		// to the old seal.
-		if req.Migrate {
+		// Verify the TOTP code submitted with the key, then attempt the unseal.
+		// If migrate was specified, the key should correspond to the old seal.
+		err = core.CheckUnsealKeyTOTP(r.Context(), key, req.TOTP)
+		switch {
+		case err != nil:
+		case req.Migrate:
 			_, err = core.UnsealMigrate(key)
-		} else {
+		default:
 			_, err = core.Unseal(key)
 		}
 		if err != nil {
@@ -178,4 +181,5 @@ type UnsealRequest struct {
 	Key     string
 	Reset   bool
 	Migrate bool
+	TOTP    string
 }
@@ -3,7 +3,14 @@
 package configutil
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/entropy"
 )
 
 type EntSharedConfig struct{}
@@ -13,5 +20,67 @@ func (ec *EntSharedConfig) ParseConfig(list *ast.ObjectList) error {
 }
 
 func ParseEntropy(result *SharedConfig, list *ast.ObjectList, blockName string) error {
+	if len(list.Items) != 1 {
+		return fmt.Errorf("only one %q block is permitted", blockName)
+	}
+
+	// Get our item
+	item := list.Items[0]
+
+	var key string
+	if len(item.Keys) > 0 {
+		key = item.Keys[0].Token.Value().(string)
+	}
+	key = strings.ToLower(key)
+	if key != EntropyTypeSeal && key != EntropyTypeESV {
+		return fmt.Errorf("only the %q and %q types of external entropy are supported", EntropyTypeSeal, EntropyTypeESV)
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+	}
+
+	config := &Entropy{
+		Type: key,
+	}
+	for k, v := range m {
+		s, err := parseutil.ParseString(v)
+		if err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+		}
+
+		switch k {
+		case "mode":
+			if s != "augmentation" {
+				return fmt.Errorf("the specified entropy mode %q is not supported", s)
+			}
+			config.Mode = EntropyAugmentation
+		case "fallback":
+			if s != entropy.FallbackFail && s != entropy.FallbackPlatform {
+				return fmt.Errorf("the specified entropy fallback %q is not supported", s)
+			}
+			config.Fallback = s
+		case "address":
+			config.Address = s
+		case "tls_ca_cert":
+			config.TLSCACert = s
+		case "tls_client_cert":
+			config.TLSClientCert = s
+		case "tls_client_key":
+			config.TLSClientKey = s
+		default:
+			return fmt.Errorf("%s.%s: unknown parameter %q", blockName, key, k)
+		}
+	}
+
+	if config.Mode != EntropyAugmentation {
+		return fmt.Errorf("%s.%s: mode is required", blockName, key)
+	}
+	if config.Type == EntropyTypeESV && config.Address == "" {
+		return fmt.Errorf("%s.%s: address is required", blockName, key)
+	}
+
+	result.Entropy = config
 	return nil
 }
@@ -3,6 +3,7 @@ package configutil
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -21,6 +22,7 @@ import (
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/entropy"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -37,8 +39,30 @@ const (
 	EntropyAugmentation
 )
 
+const (
+	// EntropyTypeSeal samples entropy from the seal, e.g. the RNG of a
+	// PKCS#11 HSM.
+	EntropyTypeSeal = "seal"
+
+	// EntropyTypeESV samples entropy from an entropy source validation
+	// server over HTTPS.
+	EntropyTypeESV = "esv"
+)
+
 type Entropy struct {
 	Mode EntropyMode
+	Type string
+
+	// Fallback is what happens when the external source fails: "fail"
+	// (default) fails the operation, "platform" uses the platform source
+	// alone.
+	Fallback string
+
+	// The fields below configure the esv type.
+	Address       string
+	TLSCACert     string
+	TLSClientCert string
+	TLSClientKey  string
 }
 
 // KMS contains KMS configuration for the server
@@ -345,5 +369,44 @@ var GetTransitKMSFunc = func(kms *KMS, opts ...wrapping.Option) (wrapping.Wrappe
 }
 
 func createSecureRandomReader(conf *SharedConfig, wrapper wrapping.Wrapper) (io.Reader, error) {
-	return rand.Reader, nil
+	if conf == nil || conf.Entropy == nil || conf.Entropy.Mode != EntropyAugmentation {
+		return rand.Reader, nil
+	}
+
+	var source entropy.Source
+	switch conf.Entropy.Type {
+	case EntropyTypeSeal:
+		sealSource, ok := wrapper.(entropy.Source)
+		if !ok {
+			return nil, errors.New("entropy augmentation is configured with the seal, but the seal does not provide entropy")
+		}
+		source = sealSource
+	case EntropyTypeESV:
+		esvSource, err := entropy.NewESVSource(&entropy.ESVConfig{
+			Address:       conf.Entropy.Address,
+			TLSCACert:     conf.Entropy.TLSCACert,
+			TLSClientCert: conf.Entropy.TLSClientCert,
+			TLSClientKey:  conf.Entropy.TLSClientKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error configuring the esv entropy source: %w", err)
+		}
+		source = esvSource
+	default:
+		return nil, fmt.Errorf("unsupported external entropy type %q", conf.Entropy.Type)
+	}
+
+	reader, err := entropy.NewAugmentedReader(source, conf.Entropy.Fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sample the source once so that a misconfiguration is reported at
+	// startup rather than on the first key generation. With the platform
+	// fallback, the read succeeds and the reader reports it as unhealthy.
+	if _, err := io.ReadFull(reader, make([]byte, 32)); err != nil {
+		return nil, fmt.Errorf("error sampling the external entropy source: %w", err)
+	}
+
+	return reader, nil
 }
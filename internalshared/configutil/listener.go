@@ -36,6 +36,45 @@ type ListenerInFlightRequestLogging struct {
 	UnauthenticatedInFlightAccessRaw interface{}  `hcl:"unauthenticated_in_flight_requests_access,alias:unauthenticatedInFlightAccessRaw"`
 }
 
+const (
+	// ListenerAuthModeToken lets requests authenticate with a Vault token,
+	// as usual. It is the default.
+	ListenerAuthModeToken = "token"
+
+	// ListenerAuthModeNone strips Vault tokens from incoming requests, so
+	// that only unauthenticated endpoints can be used.
+	ListenerAuthModeNone = "none"
+)
+
+// ListenerHTTP3 configures serving the API over HTTP/3 (QUIC), on the UDP
+// port matching the address of a tcp listener.
+type ListenerHTTP3 struct {
+	UnusedKeys UnusedKeyMap `hcl:",unusedKeyPositions"`
+	Enabled    bool         `hcl:"-"`
+	EnabledRaw interface{}  `hcl:"enabled"`
+
+	// AltSvcMaxAge is how long clients may remember that HTTP/3 is
+	// available, as advertised in the Alt-Svc header of TCP responses.
+	AltSvcMaxAge    time.Duration `hcl:"-"`
+	AltSvcMaxAgeRaw interface{}   `hcl:"alt_svc_max_age"`
+
+	MaxIdleTimeout    time.Duration `hcl:"-"`
+	MaxIdleTimeoutRaw interface{}   `hcl:"max_idle_timeout"`
+
+	MaxIncomingStreams    int64       `hcl:"-"`
+	MaxIncomingStreamsRaw interface{} `hcl:"max_incoming_streams"`
+
+	// The receive windows bound how much data a peer may send before being
+	// acknowledged, and with it the throughput over high latency links.
+	MaxStreamReceiveWindow        int64       `hcl:"-"`
+	MaxStreamReceiveWindowRaw     interface{} `hcl:"max_stream_receive_window"`
+	MaxConnectionReceiveWindow    int64       `hcl:"-"`
+	MaxConnectionReceiveWindowRaw interface{} `hcl:"max_connection_receive_window"`
+
+	DisablePathMTUDiscovery    bool        `hcl:"-"`
+	DisablePathMTUDiscoveryRaw interface{} `hcl:"disable_path_mtu_discovery"`
+}
+
 // Listener is the listener configuration for the server.
 type Listener struct {
 	UnusedKeys UnusedKeyMap `hcl:",unusedKeyPositions"`
@@ -54,6 +93,12 @@ type Listener struct {
 	RequireRequestHeader    bool          `hcl:"-"`
 	RequireRequestHeaderRaw interface{}   `hcl:"require_request_header"`
 
+	// AllowedPaths restricts the API paths served by the listener; when
+	// set, the UI and every other path respond with a 404.
+	AllowedPaths    []string    `hcl:"-"`
+	AllowedPathsRaw interface{} `hcl:"allowed_paths"`
+	AuthMode        string      `hcl:"auth_mode"`
+
 	TLSDisable                       bool        `hcl:"-"`
 	TLSDisableRaw                    interface{} `hcl:"tls_disable"`
 	TLSCertFile                      string      `hcl:"tls_cert_file"`
@@ -68,6 +113,11 @@ type Listener struct {
 	TLSDisableClientCerts            bool        `hcl:"-"`
 	TLSDisableClientCertsRaw         interface{} `hcl:"tls_disable_client_certs"`
 
+	// TLSClientCertHeader is the request header in which the client
+	// certificate verified by the listener is passed on to backends. Any
+	// value sent by the client is removed.
+	TLSClientCertHeader string `hcl:"tls_client_cert_header"`
+
 	HTTPReadTimeout          time.Duration `hcl:"-"`
 	HTTPReadTimeoutRaw       interface{}   `hcl:"http_read_timeout"`
 	HTTPReadHeaderTimeout    time.Duration `hcl:"-"`
@@ -96,9 +146,13 @@ type Listener struct {
 
 	AgentAPI *AgentAPI `hcl:"agent_api"`
 
+	// Role is used by Agent to restrict a listener to a subset of its API
+	Role string `hcl:"role"`
+
 	Telemetry              ListenerTelemetry              `hcl:"telemetry"`
 	Profiling              ListenerProfiling              `hcl:"profiling"`
 	InFlightRequestLogging ListenerInFlightRequestLogging `hcl:"inflight_requests_logging"`
+	HTTP3                  ListenerHTTP3                  `hcl:"http3"`
 
 	// RandomPort is used only for some testing purposes
 	RandomPort bool `hcl:"-"`
@@ -116,7 +170,8 @@ type Listener struct {
 
 // AgentAPI allows users to select which parts of the Agent API they want enabled.
 type AgentAPI struct {
-	EnableQuit bool `hcl:"enable_quit"`
+	EnableQuit    bool `hcl:"enable_quit"`
+	EnableLoggers bool `hcl:"enable_loggers"`
 }
 
 func (l *Listener) GoString() string {
@@ -125,9 +180,34 @@ func (l *Listener) GoString() string {
 
 func (l *Listener) Validate(path string) []ConfigError {
 	results := append(ValidateUnusedFields(l.UnusedKeys, path), ValidateUnusedFields(l.Telemetry.UnusedKeys, path)...)
+	results = append(results, ValidateUnusedFields(l.HTTP3.UnusedKeys, path)...)
 	return append(results, ValidateUnusedFields(l.Profiling.UnusedKeys, path)...)
 }
 
+// PathAllowed reports whether the listener serves the API path p, given
+// relative to /v1/. An allowed path matches itself and the paths below it;
+// a trailing "*" matches any path starting with the rest of the entry.
+func (l *Listener) PathAllowed(p string) bool {
+	if len(l.AllowedPaths) == 0 {
+		return true
+	}
+
+	for _, allowed := range l.AllowedPaths {
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(p, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+			continue
+		}
+
+		allowed = strings.TrimSuffix(allowed, "/")
+		if p == allowed || strings.HasPrefix(p, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 func ParseListeners(result *SharedConfig, list *ast.ObjectList) error {
 	var err error
 	result.Listeners = make([]*Listener, 0, len(list.Items))
@@ -214,6 +294,31 @@ func ParseListeners(result *SharedConfig, list *ast.ObjectList) error {
 			}
 		}
 
+		// Path restrictions
+		{
+			if l.AllowedPathsRaw != nil {
+				if l.AllowedPaths, err = parseutil.ParseCommaStringSlice(l.AllowedPathsRaw); err != nil {
+					return multierror.Prefix(fmt.Errorf("invalid value for allowed_paths: %w", err), fmt.Sprintf("listeners.%d", i))
+				}
+				for j, p := range l.AllowedPaths {
+					p = strings.TrimPrefix(strings.TrimPrefix(p, "/"), "v1/")
+					if p == "" {
+						return multierror.Prefix(errors.New("allowed_paths cannot contain an empty path"), fmt.Sprintf("listeners.%d", i))
+					}
+					l.AllowedPaths[j] = p
+				}
+
+				l.AllowedPathsRaw = nil
+			}
+
+			l.AuthMode = strings.ToLower(l.AuthMode)
+			switch l.AuthMode {
+			case "", ListenerAuthModeToken, ListenerAuthModeNone:
+			default:
+				return multierror.Prefix(fmt.Errorf("invalid value for auth_mode %q, must be %q or %q", l.AuthMode, ListenerAuthModeToken, ListenerAuthModeNone), fmt.Sprintf("listeners.%d", i))
+			}
+		}
+
 		// TLS Parameters
 		{
 			if l.TLSDisableRaw != nil {
@@ -245,6 +350,17 @@ func ParseListeners(result *SharedConfig, list *ast.ObjectList) error {
 
 				l.TLSDisableClientCertsRaw = nil
 			}
+
+			if l.TLSClientCertHeader != "" {
+				switch {
+				case l.TLSDisable:
+					return multierror.Prefix(errors.New("tls_client_cert_header requires TLS to be enabled"), fmt.Sprintf("listeners.%d", i))
+				case l.TLSDisableClientCerts:
+					return multierror.Prefix(errors.New("tls_client_cert_header and tls_disable_client_certs are mutually exclusive"), fmt.Sprintf("listeners.%d", i))
+				case l.TLSClientCAFile == "":
+					return multierror.Prefix(errors.New("tls_client_cert_header requires tls_client_ca_file to be set"), fmt.Sprintf("listeners.%d", i))
+				}
+			}
 		}
 
 		// HTTP timeouts
@@ -372,6 +488,90 @@ func ParseListeners(result *SharedConfig, list *ast.ObjectList) error {
 			}
 		}
 
+		// HTTP/3
+		{
+			h := &l.HTTP3
+			if h.EnabledRaw != nil {
+				if h.Enabled, err = parseutil.ParseBool(h.EnabledRaw); err != nil {
+					return multierror.Prefix(fmt.Errorf("invalid value for http3.enabled: %w", err), fmt.Sprintf("listeners.%d", i))
+				}
+
+				h.EnabledRaw = nil
+			}
+
+			if h.AltSvcMaxAgeRaw != nil {
+				if h.AltSvcMaxAge, err = parseutil.ParseDurationSecond(h.AltSvcMaxAgeRaw); err != nil {
+					return multierror.Prefix(fmt.Errorf("error parsing http3.alt_svc_max_age: %w", err), fmt.Sprintf("listeners.%d", i))
+				}
+				if h.AltSvcMaxAge < 0 {
+					return multierror.Prefix(errors.New("http3.alt_svc_max_age cannot be negative"), fmt.Sprintf("listeners.%d", i))
+				}
+
+				h.AltSvcMaxAgeRaw = nil
+			}
+
+			if h.MaxIdleTimeoutRaw != nil {
+				if h.MaxIdleTimeout, err = parseutil.ParseDurationSecond(h.MaxIdleTimeoutRaw); err != nil {
+					return multierror.Prefix(fmt.Errorf("error parsing http3.max_idle_timeout: %w", err), fmt.Sprintf("listeners.%d", i))
+				}
+				if h.MaxIdleTimeout < 0 {
+					return multierror.Prefix(errors.New("http3.max_idle_timeout cannot be negative"), fmt.Sprintf("listeners.%d", i))
+				}
+
+				h.MaxIdleTimeoutRaw = nil
+			}
+
+			if h.MaxIncomingStreamsRaw != nil {
+				if h.MaxIncomingStreams, err = parseutil.ParseInt(h.MaxIncomingStreamsRaw); err != nil {
+					return multierror.Prefix(fmt.Errorf("error parsing http3.max_incoming_streams: %w", err), fmt.Sprintf("listeners.%d", i))
+				}
+				if h.MaxIncomingStreams < 0 {
+					return multierror.Prefix(errors.New("http3.max_incoming_streams cannot be negative"), fmt.Sprintf("listeners.%d", i))
+				}
+
+				h.MaxIncomingStreamsRaw = nil
+			}
+
+			if h.MaxStreamReceiveWindowRaw != nil {
+				if h.MaxStreamReceiveWindow, err = parseutil.ParseInt(h.MaxStreamReceiveWindowRaw); err != nil {
+					return multierror.Prefix(fmt.Errorf("error parsing http3.max_stream_receive_window: %w", err), fmt.Sprintf("listeners.%d", i))
+				}
+				if h.MaxStreamReceiveWindow < 0 {
+					return multierror.Prefix(errors.New("http3.max_stream_receive_window cannot be negative"), fmt.Sprintf("listeners.%d", i))
+				}
+
+				h.MaxStreamReceiveWindowRaw = nil
+			}
+
+			if h.MaxConnectionReceiveWindowRaw != nil {
+				if h.MaxConnectionReceiveWindow, err = parseutil.ParseInt(h.MaxConnectionReceiveWindowRaw); err != nil {
+					return multierror.Prefix(fmt.Errorf("error parsing http3.max_connection_receive_window: %w", err), fmt.Sprintf("listeners.%d", i))
+				}
+				if h.MaxConnectionReceiveWindow < 0 {
+					return multierror.Prefix(errors.New("http3.max_connection_receive_window cannot be negative"), fmt.Sprintf("listeners.%d", i))
+				}
+
+				h.MaxConnectionReceiveWindowRaw = nil
+			}
+
+			if h.DisablePathMTUDiscoveryRaw != nil {
+				if h.DisablePathMTUDiscovery, err = parseutil.ParseBool(h.DisablePathMTUDiscoveryRaw); err != nil {
+					return multierror.Prefix(fmt.Errorf("invalid value for http3.disable_path_mtu_discovery: %w", err), fmt.Sprintf("listeners.%d", i))
+				}
+
+				h.DisablePathMTUDiscoveryRaw = nil
+			}
+
+			if h.Enabled {
+				switch {
+				case l.Type != "tcp":
+					return multierror.Prefix(errors.New("http3 is only supported on tcp listeners"), fmt.Sprintf("listeners.%d", i))
+				case l.TLSDisable:
+					return multierror.Prefix(errors.New("http3 requires TLS to be enabled"), fmt.Sprintf("listeners.%d", i))
+				}
+			}
+		}
+
 		// CORS
 		{
 			if l.CorsEnabledRaw != nil {
@@ -3,7 +3,10 @@ package configutil
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -47,3 +50,102 @@ func TestParseSingleIPTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestListener_AllowedPaths(t *testing.T) {
+	obj, err := hcl.Parse(`
+listener "tcp" {
+  address       = "0.0.0.0:8210"
+  allowed_paths = ["/v1/sys/health", "pki/crl", "pki/ocsp", "pki/acme/*"]
+  auth_mode     = "None"
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := obj.Node.(*ast.ObjectList)
+
+	config := new(SharedConfig)
+	if err := ParseListeners(config, list.Filter("listener")); err != nil {
+		t.Fatal(err)
+	}
+	l := config.Listeners[0]
+	assert.Equal(t, []string{"sys/health", "pki/crl", "pki/ocsp", "pki/acme/*"}, l.AllowedPaths)
+	assert.Equal(t, ListenerAuthModeNone, l.AuthMode)
+
+	for p, allowed := range map[string]bool{
+		"sys/health":         true,
+		"sys/healthy":        false,
+		"pki/crl":            true,
+		"pki/crl/delta/pem":  true,
+		"pki/crlx":           false,
+		"pki/acme/new-nonce": true,
+		"pki/issue/web":      false,
+		"sys/mounts":         false,
+	} {
+		assert.Equal(t, allowed, l.PathAllowed(p), p)
+	}
+
+	// Listeners without allowed_paths serve everything.
+	assert.True(t, (&Listener{}).PathAllowed("sys/mounts"))
+
+	obj, err = hcl.Parse(`
+listener "tcp" {
+  auth_mode = "cert"
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ = obj.Node.(*ast.ObjectList)
+	if err := ParseListeners(new(SharedConfig), list.Filter("listener")); err == nil {
+		t.Fatal("expected error for invalid auth_mode")
+	}
+}
+
+func TestListener_HTTP3(t *testing.T) {
+	obj, err := hcl.Parse(`
+listener "tcp" {
+  address = "0.0.0.0:8200"
+  http3 {
+    enabled                   = true
+    alt_svc_max_age           = "1h"
+    max_idle_timeout          = "45s"
+    max_stream_receive_window = 16777216
+  }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := obj.Node.(*ast.ObjectList)
+
+	config := new(SharedConfig)
+	if err := ParseListeners(config, list.Filter("listener")); err != nil {
+		t.Fatal(err)
+	}
+	h := config.Listeners[0].HTTP3
+	assert.True(t, h.Enabled)
+	assert.Equal(t, time.Hour, h.AltSvcMaxAge)
+	assert.Equal(t, 45*time.Second, h.MaxIdleTimeout)
+	assert.Equal(t, int64(16777216), h.MaxStreamReceiveWindow)
+	assert.Zero(t, h.MaxConnectionReceiveWindow)
+
+	for _, bad := range []string{
+		`listener "tcp" {
+  tls_disable = true
+  http3 { enabled = true }
+}`,
+		`listener "unix" {
+  http3 { enabled = true }
+}`,
+		`listener "tcp" {
+  http3 { max_incoming_streams = -1 }
+}`,
+	} {
+		obj, err := hcl.Parse(bad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, _ := obj.Node.(*ast.ObjectList)
+		if err := ParseListeners(new(SharedConfig), list.Filter("listener")); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
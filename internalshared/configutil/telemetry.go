@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
@@ -272,7 +273,6 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 		metricsConf.FilterDefault = *opts.Config.FilterDefault
 	}
 
-	// Configure the statsite sink
 	var fanout metrics.FanoutSink
 	var prometheusEnabled bool
 
@@ -290,10 +290,62 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 		fanout = append(fanout, sink)
 	}
 
+	// Configure the sinks metrics are pushed to. Unlike the Prometheus sink,
+	// which registers its collector once, they can be reloaded.
+	sinks, err := newTelemetrySinks(opts, metricsConf.HostName)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	telemetrySinks.swap(sinks)
+
+	// Initialize the global sink
+	if len(fanout)+len(sinks) > 1 {
+		// Hostname enabled will create poor quality metrics name for prometheus
+		if !opts.Config.DisableHostname {
+			opts.Ui.Warn("telemetry.disable_hostname has been set to false. Recommended setting is true for Prometheus to avoid poorly named metrics.")
+		}
+	} else {
+		metricsConf.EnableHostname = false
+	}
+	fanout = append(fanout, telemetrySinks, inm)
+	globalMetrics, err := metrics.NewGlobal(metricsConf, fanout)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	// Intialize a wrapper around the global sink; this will be passed to Core
+	// and to any backend.
+	wrapper := metricsutil.NewClusterMetricSink(opts.ClusterName, globalMetrics)
+	wrapper.MaxGaugeCardinality = opts.Config.MaximumGaugeCardinality
+	wrapper.GaugeInterval = opts.Config.UsageGaugePeriod
+	wrapper.TelemetryConsts.LeaseMetricsEpsilon = opts.Config.LeaseMetricsEpsilon
+	wrapper.TelemetryConsts.LeaseMetricsNameSpaceLabels = opts.Config.LeaseMetricsNameSpaceLabels
+	wrapper.TelemetryConsts.NumLeaseMetricsTimeBuckets = opts.Config.NumLeaseMetricsTimeBuckets
+
+	// Parse the metric filters
+	telemetryAllowedPrefixes, telemetryBlockedPrefixes, err := parsePrefixFilter(opts.Config.PrefixFilter)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	metrics.UpdateFilter(telemetryAllowedPrefixes, telemetryBlockedPrefixes)
+	return inm, wrapper, prometheusEnabled, nil
+}
+
+// newTelemetrySinks returns the sinks metrics are pushed to, as configured
+// by the telemetry configuration of opts.
+func newTelemetrySinks(opts *SetupTelemetryOpts, hostName string) (fanout metrics.FanoutSink, err error) {
+	// Stop the sinks already started if a later one fails
+	defer func() {
+		if err != nil {
+			fanout.Shutdown()
+		}
+	}()
+
 	if opts.Config.StatsiteAddr != "" {
 		sink, err := metrics.NewStatsiteSink(opts.Config.StatsiteAddr)
 		if err != nil {
-			return nil, nil, false, err
+			return fanout, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -302,7 +354,7 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 	if opts.Config.StatsdAddr != "" {
 		sink, err := metrics.NewStatsdSink(opts.Config.StatsdAddr)
 		if err != nil {
-			return nil, nil, false, err
+			return fanout, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -338,7 +390,7 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 
 		sink, err := circonus.NewCirconusSink(cfg)
 		if err != nil {
-			return nil, nil, false, err
+			return fanout, err
 		}
 		sink.Start()
 		fanout = append(fanout, sink)
@@ -351,9 +403,9 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 			tags = opts.Config.DogStatsDTags
 		}
 
-		sink, err := datadog.NewDogStatsdSink(opts.Config.DogStatsDAddr, metricsConf.HostName)
+		sink, err := datadog.NewDogStatsdSink(opts.Config.DogStatsDAddr, hostName)
 		if err != nil {
-			return nil, nil, false, fmt.Errorf("failed to start DogStatsD sink: %w", err)
+			return fanout, fmt.Errorf("failed to start DogStatsD sink: %w", err)
 		}
 		sink.SetTags(tags)
 		fanout = append(fanout, sink)
@@ -363,7 +415,7 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 	if opts.Config.StackdriverProjectID != "" {
 		client, err := monitoring.NewMetricClient(context.Background(), option.WithUserAgent(opts.UserAgent))
 		if err != nil {
-			return nil, nil, false, fmt.Errorf("Failed to create stackdriver client: %v", err)
+			return fanout, fmt.Errorf("Failed to create stackdriver client: %v", err)
 		}
 		sink := stackdriver.NewSink(client, &stackdriver.Config{
 			LabelExtractor: stackdrivervault.Extractor,
@@ -376,38 +428,92 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 		fanout = append(fanout, sink)
 	}
 
-	// Initialize the global sink
-	if len(fanout) > 1 {
-		// Hostname enabled will create poor quality metrics name for prometheus
-		if !opts.Config.DisableHostname {
-			opts.Ui.Warn("telemetry.disable_hostname has been set to false. Recommended setting is true for Prometheus to avoid poorly named metrics.")
-		}
-	} else {
-		metricsConf.EnableHostname = false
+	return fanout, nil
+}
+
+// reloadableSink forwards metrics to the sinks of the telemetry
+// configuration the server was last set up or reloaded with.
+type reloadableSink struct {
+	sinks atomic.Value
+}
+
+// telemetrySinks holds the sinks metrics are pushed to. Like the global
+// metrics it feeds, there is one per process.
+var telemetrySinks = &reloadableSink{}
+
+var _ metrics.ShutdownSink = (*reloadableSink)(nil)
+
+func (r *reloadableSink) load() metrics.FanoutSink {
+	sinks, _ := r.sinks.Load().(metrics.FanoutSink)
+	return sinks
+}
+
+// swap replaces the sinks metrics are forwarded to, and returns the
+// previous ones.
+func (r *reloadableSink) swap(sinks metrics.FanoutSink) metrics.FanoutSink {
+	old, _ := r.sinks.Swap(sinks).(metrics.FanoutSink)
+	return old
+}
+
+func (r *reloadableSink) SetGauge(key []string, val float32) {
+	r.load().SetGauge(key, val)
+}
+
+func (r *reloadableSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	r.load().SetGaugeWithLabels(key, val, labels)
+}
+
+func (r *reloadableSink) EmitKey(key []string, val float32) {
+	r.load().EmitKey(key, val)
+}
+
+func (r *reloadableSink) IncrCounter(key []string, val float32) {
+	r.load().IncrCounter(key, val)
+}
+
+func (r *reloadableSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	r.load().IncrCounterWithLabels(key, val, labels)
+}
+
+func (r *reloadableSink) AddSample(key []string, val float32) {
+	r.load().AddSample(key, val)
+}
+
+func (r *reloadableSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	r.load().AddSampleWithLabels(key, val, labels)
+}
+
+func (r *reloadableSink) Shutdown() {
+	r.load().Shutdown()
+}
+
+// ReloadTelemetry replaces the sinks metrics are pushed to and the metric
+// filters with the ones of the telemetry configuration of opts, after
+// SetupTelemetry. The previous sinks are shut down, flushing their metrics.
+// The other telemetry settings are only applied on restart.
+func ReloadTelemetry(opts *SetupTelemetryOpts) error {
+	if opts == nil {
+		return errors.New("nil opts passed into ReloadTelemetry")
 	}
-	fanout = append(fanout, inm)
-	globalMetrics, err := metrics.NewGlobal(metricsConf, fanout)
-	if err != nil {
-		return nil, nil, false, err
+	if opts.Config == nil {
+		opts.Config = &Telemetry{}
+	}
+	if opts.Config.MetricsPrefix != "" {
+		opts.ServiceName = opts.Config.MetricsPrefix
 	}
 
-	// Intialize a wrapper around the global sink; this will be passed to Core
-	// and to any backend.
-	wrapper := metricsutil.NewClusterMetricSink(opts.ClusterName, globalMetrics)
-	wrapper.MaxGaugeCardinality = opts.Config.MaximumGaugeCardinality
-	wrapper.GaugeInterval = opts.Config.UsageGaugePeriod
-	wrapper.TelemetryConsts.LeaseMetricsEpsilon = opts.Config.LeaseMetricsEpsilon
-	wrapper.TelemetryConsts.LeaseMetricsNameSpaceLabels = opts.Config.LeaseMetricsNameSpaceLabels
-	wrapper.TelemetryConsts.NumLeaseMetricsTimeBuckets = opts.Config.NumLeaseMetricsTimeBuckets
-
-	// Parse the metric filters
 	telemetryAllowedPrefixes, telemetryBlockedPrefixes, err := parsePrefixFilter(opts.Config.PrefixFilter)
 	if err != nil {
-		return nil, nil, false, err
+		return err
+	}
+	sinks, err := newTelemetrySinks(opts, metrics.DefaultConfig(opts.ServiceName).HostName)
+	if err != nil {
+		return err
 	}
 
+	telemetrySinks.swap(sinks).Shutdown()
 	metrics.UpdateFilter(telemetryAllowedPrefixes, telemetryBlockedPrefixes)
-	return inm, wrapper, prometheusEnabled, nil
+	return nil
 }
 
 func parsePrefixFilter(prefixFilters []string) ([]string, []string, error) {
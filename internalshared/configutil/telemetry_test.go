@@ -2,7 +2,9 @@ package configutil
 
 import (
 	"testing"
+	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -51,3 +53,30 @@ func TestParsePrefixFilters(t *testing.T) {
 		}
 	})
 }
+
+func TestReloadableSink(t *testing.T) {
+	t.Parallel()
+	sink := &reloadableSink{}
+
+	// Metrics are dropped until sinks are set up
+	sink.IncrCounter([]string{"dropped"}, 1)
+
+	first := metrics.NewInmemSink(time.Minute, time.Minute)
+	assert.Nil(t, sink.swap(metrics.FanoutSink{first}))
+	sink.IncrCounter([]string{"counter"}, 1)
+
+	second := metrics.NewInmemSink(time.Minute, time.Minute)
+	old := sink.swap(metrics.FanoutSink{second})
+	assert.Equal(t, metrics.FanoutSink{first}, old)
+	sink.IncrCounter([]string{"counter"}, 2)
+
+	counter := func(inm *metrics.InmemSink) float64 {
+		data := inm.Data()
+		if len(data) == 0 {
+			return 0
+		}
+		return data[0].Counters["counter"].Sum
+	}
+	assert.Equal(t, float64(1), counter(first))
+	assert.Equal(t, float64(2), counter(second))
+}
@@ -21,6 +21,27 @@ import (
 type Listener struct {
 	net.Listener
 	Config *configutil.Listener
+
+	// HTTP3 is set when the listener also serves HTTP/3
+	HTTP3 *HTTP3Listener
+}
+
+// Close closes the listener and its HTTP/3 socket, if any.
+func (l Listener) Close() error {
+	err := l.Listener.Close()
+	if l.HTTP3 != nil {
+		if pcErr := l.HTTP3.PacketConn.Close(); err == nil {
+			err = pcErr
+		}
+	}
+	return err
+}
+
+// HTTP3Listener holds the UDP socket and TLS configuration used to serve
+// HTTP/3 on the address of a TCP listener.
+type HTTP3Listener struct {
+	PacketConn net.PacketConn
+	TLSConfig  *tls.Config
 }
 
 type UnixSocketsConfig struct {
@@ -161,8 +182,16 @@ Please see https://tools.ietf.org/html/rfc7540#appendix-A for further informatio
 		tlsConf.CipherSuites = l.TLSCipherSuites
 	}
 
-	if l.TLSRequireAndVerifyClientCert {
+	switch {
+	case l.TLSRequireAndVerifyClientCert:
 		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	case l.TLSClientCertHeader != "":
+		// Client certificates passed on in a header must have been verified,
+		// but are only required if configured so.
+		tlsConf.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	if tlsConf.ClientAuth != tls.RequestClientCert {
 		if l.TLSClientCAFile != "" {
 			caPool := x509.NewCertPool()
 			data, err := ioutil.ReadFile(l.TLSClientCAFile)
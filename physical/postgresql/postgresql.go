@@ -12,6 +12,7 @@ import (
 
 	"github.com/armon/go-metrics"
 	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
 	"github.com/hashicorp/vault/sdk/physical"
@@ -31,6 +32,13 @@ const (
 	// PostgreSQLLockRetryInterval is the amount of time to wait
 	// if a lock fails before trying again.
 	PostgreSQLLockRetryInterval = time.Second
+
+	// HA lock modes: locks held in the HA table until their TTL expires, or
+	// session advisory locks released as soon as the holder disconnects
+	haLockModeTable    = "table"
+	haLockModeAdvisory = "advisory"
+
+	defaultInvalidationChannel = "vault_kv_invalidations"
 )
 
 // Verify PostgreSQLBackend satisfies the correct interfaces
@@ -59,6 +67,18 @@ type PostgreSQLBackend struct {
 	haUpsertLockIdentityExec string
 	haDeleteLockExec         string
 
+	haLockMode                 string
+	haAdvisoryLockValueQuery   string
+	haAdvisoryUpsertValueExec  string
+	haAdvisoryLockKeyNamespace string
+
+	// invalidationChannel is the channel writes are notified on, it is
+	// empty if cache invalidation is disabled
+	invalidationChannel string
+	connURL             string
+	identity            string
+	invalidationOnce    sync.Once
+
 	haEnabled  bool
 	logger     log.Logger
 	permitPool *physical.PermitPool
@@ -125,16 +145,64 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 		}
 	}
 
+	var maxConnLifetime, maxConnIdleTime time.Duration
+	if raw, ok := conf["max_connection_lifetime"]; ok {
+		maxConnLifetime, err = parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing max_connection_lifetime parameter: %w", err)
+		}
+	}
+	if raw, ok := conf["max_connection_idle_time"]; ok {
+		maxConnIdleTime, err = parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing max_connection_idle_time parameter: %w", err)
+		}
+	}
+
+	haLockMode := conf["ha_lock_mode"]
+	switch haLockMode {
+	case "":
+		haLockMode = haLockModeTable
+	case haLockModeTable, haLockModeAdvisory:
+	default:
+		return nil, fmt.Errorf("invalid ha_lock_mode %q, must be %q or %q", haLockMode, haLockModeTable, haLockModeAdvisory)
+	}
+
+	var invalidationChannel string
+	if raw, ok := conf["cache_invalidation"]; ok {
+		enabled, err := parseutil.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing cache_invalidation parameter: %w", err)
+		}
+		if enabled {
+			invalidationChannel = conf["cache_invalidation_channel"]
+			if invalidationChannel == "" {
+				invalidationChannel = defaultInvalidationChannel
+			}
+		}
+	}
+
 	// Create PostgreSQL handle for the database.
 	db, err := sql.Open("pgx", connURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
 	}
-	db.SetMaxOpenConns(maxParInt)
+	maxOpenConns := maxParInt
+	if haLockMode == haLockModeAdvisory {
+		// An advisory lock holds a connection for as long as it is held
+		maxOpenConns++
+	}
+	db.SetMaxOpenConns(maxOpenConns)
 
 	if maxIdleConnsIsSet {
 		db.SetMaxIdleConns(maxIdleConns)
 	}
+	if maxConnLifetime > 0 {
+		db.SetConnMaxLifetime(maxConnLifetime)
+	}
+	if maxConnIdleTime > 0 {
+		db.SetConnMaxIdleTime(maxConnIdleTime)
+	}
 
 	// Determine if we should use a function to work around lack of upsert (versions < 9.5)
 	var upsertAvailable bool
@@ -164,6 +232,11 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 	}
 	quoted_ha_table := dbutil.QuoteIdentifier(unquoted_ha_table)
 
+	identity, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
 	// Setup the backend.
 	m := &PostgreSQLBackend{
 		table:        quoted_table,
@@ -188,9 +261,28 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 		haDeleteLockExec:
 		// $1=ha_identity $2=ha_key
 		" DELETE FROM " + quoted_ha_table + " WHERE ha_identity=$1 AND ha_key=$2 ",
-		logger:     logger,
-		permitPool: physical.NewPermitPool(maxParInt),
-		haEnabled:  conf["ha_enabled"] == "true",
+		haLockMode: haLockMode,
+		haAdvisoryLockValueQuery:
+		// $1=ha_key $2,$3=the high and low halves of the advisory lock key
+		// only read the value if the advisory lock is held by a session
+		" SELECT ha_value FROM " + quoted_ha_table + " WHERE ha_key = $1 AND EXISTS (" +
+			" SELECT 1 FROM pg_locks WHERE locktype = 'advisory' AND granted" +
+			" AND database = (SELECT oid FROM pg_database WHERE datname = current_database())" +
+			" AND classid = $2::bigint::oid AND objid = $3::bigint::oid AND objsubid = 1) ",
+		haAdvisoryUpsertValueExec:
+		// $1=identity $2=ha_key $3=ha_value, the advisory lock is held
+		// valid_until is only used by table locks, which never see this one
+		// as valid
+		" INSERT INTO " + quoted_ha_table + " (ha_identity, ha_key, ha_value, valid_until) VALUES ($1, $2, $3, NOW()) " +
+			" ON CONFLICT (ha_key) DO " +
+			" UPDATE SET (ha_identity, ha_value, valid_until) = ($1, $3, NOW()) ",
+		haAdvisoryLockKeyNamespace: unquoted_ha_table,
+		invalidationChannel:        invalidationChannel,
+		connURL:                    connURL,
+		identity:                   identity,
+		logger:                     logger,
+		permitPool:                 physical.NewPermitPool(maxParInt),
+		haEnabled:                  conf["ha_enabled"] == "true",
 	}
 
 	return m, nil
@@ -246,7 +338,7 @@ func (m *PostgreSQLBackend) Put(ctx context.Context, entry *physical.Entry) erro
 	if err != nil {
 		return err
 	}
-	return nil
+	return m.notifyInvalidation(ctx, entry.Key)
 }
 
 // Get is used to fetch and entry.
@@ -287,7 +379,7 @@ func (m *PostgreSQLBackend) Delete(ctx context.Context, fullPath string) error {
 	if err != nil {
 		return err
 	}
-	return nil
+	return m.notifyInvalidation(ctx, fullPath)
 }
 
 // List is used to list all the keys under a given
@@ -324,6 +416,17 @@ func (p *PostgreSQLBackend) LockWith(key, value string) (physical.Lock, error) {
 	if err != nil {
 		return nil, err
 	}
+	if p.haLockMode == haLockModeAdvisory {
+		return &PostgreSQLAdvisoryLock{
+			backend:       p,
+			key:           key,
+			value:         value,
+			identity:      identity,
+			lockID:        advisoryLockID(p.haAdvisoryLockKeyNamespace, key),
+			checkInterval: PostgreSQLLockRenewInterval,
+			retryInterval: PostgreSQLLockRetryInterval,
+		}, nil
+	}
 	return &PostgreSQLLock{
 		backend:       p,
 		key:           key,
@@ -0,0 +1,193 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+var _ physical.Lock = (*PostgreSQLAdvisoryLock)(nil)
+
+// PostgreSQLAdvisoryLock implements a lock with a session advisory lock. The
+// lock is held by a dedicated connection, so that it is released by the
+// database as soon as the holder disconnects, without waiting for a TTL. Its
+// value is kept in the HA table.
+type PostgreSQLAdvisoryLock struct {
+	backend    *PostgreSQLBackend
+	value, key string
+	identity   string
+	lockID     int64
+	lock       sync.Mutex
+
+	conn   *sql.Conn
+	stopCh chan struct{}
+
+	// checkInterval is how much time to wait between checks of the
+	// connection holding the lock
+	checkInterval time.Duration
+
+	// retryInterval is how much time to wait between attempts to grab the lock
+	retryInterval time.Duration
+}
+
+// advisoryLockID derives the 64-bit advisory lock key of key, scoped to the
+// HA table so that several Vault clusters can share a database.
+func advisoryLockID(namespace, key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// Lock tries to acquire the advisory lock every retryInterval, until either
+// the stop channel is closed or the lock is acquired. The returned channel is
+// closed once the connection holding the lock is lost.
+func (l *PostgreSQLAdvisoryLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	ticker := time.NewTicker(l.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return nil, nil
+		case <-ticker.C:
+		}
+
+		conn, err := l.tryToLock()
+		if err != nil {
+			return nil, err
+		}
+		if conn == nil {
+			continue
+		}
+
+		l.conn = conn
+		l.stopCh = make(chan struct{})
+		leader := make(chan struct{})
+		go l.watch(conn, l.stopCh, leader)
+		return leader, nil
+	}
+}
+
+// tryToLock returns the connection holding the lock if it was acquired, or
+// nil if it is held by another session.
+func (l *PostgreSQLAdvisoryLock) tryToLock() (*sql.Conn, error) {
+	pg := l.backend
+	pg.permitPool.Acquire()
+	defer pg.permitPool.Release()
+
+	ctx := context.Background()
+	conn, err := pg.client.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.lockID).Scan(&locked); err != nil {
+		discardConn(conn)
+		return nil, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, pg.haAdvisoryUpsertValueExec, l.identity, l.key, l.value); err != nil {
+		discardConn(conn)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// watch closes leader when the connection holding the lock fails, in which
+// case the database has released the lock or is about to.
+func (l *PostgreSQLAdvisoryLock) watch(conn *sql.Conn, stopCh, leader chan struct{}) {
+	ticker := time.NewTicker(l.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.checkInterval)
+		err := conn.PingContext(ctx)
+		cancel()
+		if err != nil {
+			l.backend.logger.Error("lost the connection holding the advisory lock", "error", err)
+			close(leader)
+			return
+		}
+	}
+}
+
+// Unlock releases the advisory lock, and deletes the lock value from the HA
+// table.
+func (l *PostgreSQLAdvisoryLock) Unlock() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+	close(l.stopCh)
+	conn := l.conn
+	l.conn = nil
+
+	pg := l.backend
+	pg.permitPool.Acquire()
+	defer pg.permitPool.Release()
+
+	ctx := context.Background()
+	if _, err := conn.ExecContext(ctx, pg.haDeleteLockExec, l.identity, l.key); err != nil {
+		// Closing the session releases the lock regardless
+		discardConn(conn)
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.lockID); err != nil {
+		discardConn(conn)
+		return err
+	}
+	return conn.Close()
+}
+
+// Value checks whether or not the advisory lock is held by any session, and
+// returns the current value.
+func (l *PostgreSQLAdvisoryLock) Value() (bool, string, error) {
+	pg := l.backend
+	pg.permitPool.Acquire()
+	defer pg.permitPool.Release()
+
+	// pg_locks exposes bigint advisory lock keys as two 32-bit halves
+	lockID := uint64(l.lockID)
+	var result string
+	err := pg.client.QueryRow(pg.haAdvisoryLockValueQuery, l.key, int64(lockID>>32), int64(lockID&0xffffffff)).Scan(&result)
+	switch err {
+	case nil:
+		return true, result, nil
+	case sql.ErrNoRows:
+		return false, "", nil
+	default:
+		return false, "", err
+	}
+}
+
+// discardConn closes the session of conn instead of returning it to the pool,
+// so that no advisory lock it may hold outlives it.
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	conn.Close()
+}
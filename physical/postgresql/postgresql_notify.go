@@ -0,0 +1,90 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/jackc/pgx/v4"
+)
+
+// invalidationRetryInterval is how long to wait before listening again after
+// the listening connection fails
+const invalidationRetryInterval = 5 * time.Second
+
+var _ physical.Invalidator = (*PostgreSQLBackend)(nil)
+
+// notifyInvalidation notifies the other nodes that key was changed. The
+// payload is prefixed with the identity of the backend, so that it ignores
+// its own notifications.
+func (m *PostgreSQLBackend) notifyInvalidation(ctx context.Context, key string) error {
+	if m.invalidationChannel == "" {
+		return nil
+	}
+	_, err := m.client.ExecContext(ctx, "SELECT pg_notify($1, $2)", m.invalidationChannel, m.identity+":"+key)
+	if err != nil {
+		return fmt.Errorf("failed to notify invalidation: %w", err)
+	}
+	return nil
+}
+
+// SetInvalidationHandler implements physical.Invalidator. When cache
+// invalidation is enabled, it listens for the changes made by the other nodes
+// and calls handler with their keys.
+func (m *PostgreSQLBackend) SetInvalidationHandler(handler func(key string)) {
+	if m.invalidationChannel == "" {
+		return
+	}
+	m.invalidationOnce.Do(func() {
+		go m.listenInvalidations(handler)
+	})
+}
+
+func (m *PostgreSQLBackend) listenInvalidations(handler func(key string)) {
+	ctx := context.Background()
+	prefix := m.identity + ":"
+	connected := false
+
+	for {
+		if connected {
+			time.Sleep(invalidationRetryInterval)
+		}
+
+		conn, err := pgx.Connect(ctx, m.connURL)
+		if err != nil {
+			m.logger.Error("failed to connect to listen for invalidations", "error", err)
+			time.Sleep(invalidationRetryInterval)
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+dbutil.QuoteIdentifier(m.invalidationChannel)); err != nil {
+			m.logger.Error("failed to listen for invalidations", "error", err)
+			conn.Close(ctx)
+			time.Sleep(invalidationRetryInterval)
+			continue
+		}
+
+		// Changes may have been missed while not listening
+		if connected {
+			handler("")
+		}
+		connected = true
+
+		for {
+			notification, err := conn.WaitForNotification(ctx)
+			if err != nil {
+				m.logger.Error("stopped listening for invalidations", "error", err)
+				break
+			}
+			if strings.HasPrefix(notification.Payload, prefix) {
+				continue
+			}
+			if idx := strings.IndexByte(notification.Payload, ':'); idx != -1 {
+				handler(notification.Payload[idx+1:])
+			}
+		}
+		conn.Close(ctx)
+	}
+}
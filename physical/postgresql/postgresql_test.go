@@ -1,6 +1,7 @@
 package postgresql
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -110,6 +111,79 @@ func TestPostgreSQLBackendMaxIdleConnectionsParameter(t *testing.T) {
 	}
 }
 
+func TestPostgreSQLBackendHALockModeParameter(t *testing.T) {
+	_, err := NewPostgreSQLBackend(map[string]string{
+		"connection_url": "some connection url",
+		"ha_lock_mode":   "bad mode",
+	}, logging.NewVaultLogger(log.Debug))
+	if err == nil {
+		t.Error("Expected invalid ha_lock_mode param to return error")
+	}
+}
+
+func TestAdvisoryLockID(t *testing.T) {
+	if advisoryLockID("vault_ha_locks", "core/lock") == advisoryLockID("other_ha_locks", "core/lock") {
+		t.Fatal("expected advisory lock ids to be scoped to the HA table")
+	}
+	if advisoryLockID("vault_ha_locks", "core/lock") != advisoryLockID("vault_ha_locks", "core/lock") {
+		t.Fatal("expected advisory lock ids to be stable")
+	}
+}
+
+func TestPostgreSQLBackend_AdvisoryLockAndInvalidation(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	connURL := os.Getenv("PGURL")
+	if connURL == "" {
+		cleanup, u := postgresql.PrepareTestContainer(t, "11.1")
+		defer cleanup()
+		connURL = u
+	}
+
+	conf := map[string]string{
+		"connection_url":          connURL,
+		"ha_enabled":              "true",
+		"ha_lock_mode":            "advisory",
+		"cache_invalidation":      "true",
+		"max_connection_lifetime": "1h",
+	}
+	b1, err := NewPostgreSQLBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+	b2, err := NewPostgreSQLBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+	setupDatabaseObjects(t, logger, b1.(*PostgreSQLBackend))
+
+	invalidated := make(chan string, 10)
+	b2.(physical.Invalidator).SetInvalidationHandler(func(key string) {
+		invalidated <- key
+	})
+	b1.(physical.Invalidator).SetInvalidationHandler(func(key string) {
+		t.Errorf("unexpected invalidation of the writing backend: %q", key)
+	})
+
+	// Wait for the listener to be set up
+	deadline := time.Now().Add(10 * time.Second)
+	var got string
+	for got == "" && time.Now().Before(deadline) {
+		if err := b1.Put(context.Background(), &physical.Entry{Key: "foo/bar", Value: []byte("baz")}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got = <-invalidated:
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	if got != "foo/bar" {
+		t.Fatalf("expected foo/bar to be invalidated, got %q", got)
+	}
+
+	physical.ExerciseHABackend(t, b1.(physical.HABackend), b2.(physical.HABackend))
+}
+
 func TestConnectionURL(t *testing.T) {
 	type input struct {
 		envar string
@@ -104,6 +104,13 @@ type FSM struct {
 
 	localID         string
 	desiredSuffrage string
+
+	// compaction tracks the progress of database compactions
+	compaction compactionTracker
+
+	// compactionCallback is called in tests between the copy of a
+	// compaction and the swap of the databases
+	compactionCallback func()
 }
 
 // NewFSM constructs a FSM using the given directory
@@ -241,6 +248,8 @@ func (f *FSM) Close() error {
 	f.l.RLock()
 	defer f.l.RUnlock()
 
+	f.compaction.abort()
+
 	return f.db.Close()
 }
 
@@ -398,6 +407,7 @@ func (f *FSM) persistDesiredSuffrage(lnconfig *LocalNodeConfigValue) error {
 		return err
 	}
 
+	f.compaction.markDirty(configBucketName, localNodeConfigKey)
 	return f.db.Update(func(tx *bolt.Tx) error {
 		return tx.Bucket(configBucketName).Put(localNodeConfigKey, dsBytes)
 	})
@@ -407,6 +417,8 @@ func (f *FSM) witnessSnapshot(metadata *raft.SnapshotMeta) error {
 	f.l.RLock()
 	defer f.l.RUnlock()
 
+	f.compaction.markDirty(configBucketName, latestConfigKey)
+	f.compaction.markDirty(configBucketName, latestIndexKey)
 	err := writeSnapshotMetaToDB(metadata, f.db)
 	if err != nil {
 		return err
@@ -435,6 +447,7 @@ func (f *FSM) Delete(ctx context.Context, path string) error {
 	f.l.RLock()
 	defer f.l.RUnlock()
 
+	f.compaction.markDirty(dataBucketName, []byte(path))
 	return f.db.Update(func(tx *bolt.Tx) error {
 		return tx.Bucket(dataBucketName).Delete([]byte(path))
 	})
@@ -453,6 +466,7 @@ func (f *FSM) DeletePrefix(ctx context.Context, prefix string) error {
 
 		prefixBytes := []byte(prefix)
 		for k, _ := c.Seek(prefixBytes); k != nil && bytes.HasPrefix(k, prefixBytes); k, _ = c.Next() {
+			f.compaction.markDirty(dataBucketName, k)
 			if err := c.Delete(); err != nil {
 				return err
 			}
@@ -507,6 +521,7 @@ func (f *FSM) Put(ctx context.Context, entry *physical.Entry) error {
 	defer f.l.RUnlock()
 
 	// Start a write transaction.
+	f.compaction.markDirty(dataBucketName, []byte(entry.Key))
 	return f.db.Update(func(tx *bolt.Tx) error {
 		return tx.Bucket(dataBucketName).Put([]byte(entry.Key), entry.Value)
 	})
@@ -558,6 +573,8 @@ func (f *FSM) Transaction(ctx context.Context, txns []*physical.TxnEntry) error
 	err := f.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(dataBucketName)
 		for _, txn := range txns {
+			f.compaction.markDirty(dataBucketName, []byte(txn.Entry.Key))
+
 			var err error
 			switch txn.Operation {
 			case physical.PutOperation:
@@ -652,8 +669,10 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 					var err error
 					switch op.OpType {
 					case putOp:
+						f.compaction.markDirty(dataBucketName, []byte(op.Key))
 						err = b.Put([]byte(op.Key), op.Value)
 					case deleteOp:
+						f.compaction.markDirty(dataBucketName, []byte(op.Key))
 						err = b.Delete([]byte(op.Key))
 					case getOp:
 						fsmEntry := &FSMEntry{
@@ -685,6 +704,7 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 				if err != nil {
 					return err
 				}
+				f.compaction.markDirty(configBucketName, latestConfigKey)
 				if err := b.Put(latestConfigKey, configBytes); err != nil {
 					return err
 				}
@@ -695,6 +715,7 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 
 		if len(logIndex) > 0 {
 			b := tx.Bucket(configBucketName)
+			f.compaction.markDirty(configBucketName, latestIndexKey)
 			err = b.Put(latestIndexKey, logIndex)
 			if err != nil {
 				return err
@@ -833,6 +854,9 @@ func (f *FSM) Restore(r io.ReadCloser) error {
 	f.l.Lock()
 	defer f.l.Unlock()
 
+	// A compaction of the database being replaced must not swap it back in
+	f.compaction.abort()
+
 	// Cache the local node config before closing the db file
 	lnConfig, err := f.localNodeConfig()
 	if err != nil {
@@ -966,6 +990,7 @@ func (f *FSMChunkStorage) StoreChunk(chunk *raftchunking.ChunkInfo) (bool, error
 
 	// Start a write transaction.
 	done := new(bool)
+	f.f.compaction.markDirty(dataBucketName, []byte(entry.Key))
 	if err := f.f.db.Update(func(tx *bolt.Tx) error {
 		if err := tx.Bucket(dataBucketName).Put([]byte(entry.Key), entry.Value); err != nil {
 			return fmt.Errorf("error storing chunk info: %w", err)
@@ -0,0 +1,492 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	CompactionStateIdle      = "idle"
+	CompactionStateRunning   = "running"
+	CompactionStateCompleted = "completed"
+	CompactionStateFailed    = "failed"
+
+	// compactionTxMaxKeys bounds the number of keys copied in a single write
+	// transaction of the compacted database, to bound memory use.
+	compactionTxMaxKeys = 10000
+)
+
+// ErrCompactionInProgress is returned when a compaction is requested while
+// another one is running.
+var ErrCompactionInProgress = errors.New("compaction already in progress")
+
+// PrefixUsage is the storage used by the keys under a prefix.
+type PrefixUsage struct {
+	Prefix string `json:"prefix" mapstructure:"prefix"`
+	Keys   int64  `json:"keys" mapstructure:"keys"`
+	Bytes  int64  `json:"bytes" mapstructure:"bytes"`
+}
+
+// StorageUsage describes the content of the FSM database.
+type StorageUsage struct {
+	// DBFileSize is the size of the database file on disk.
+	DBFileSize int64 `json:"db_file_size" mapstructure:"db_file_size"`
+
+	// DBDataSize is the size of the database as seen by bolt; the part of
+	// the file holding the free pages counted in FreeBytes is reclaimed by
+	// compaction.
+	DBDataSize int64 `json:"db_data_size" mapstructure:"db_data_size"`
+	FreeBytes  int64 `json:"free_bytes" mapstructure:"free_bytes"`
+	FreePages  int   `json:"free_pages" mapstructure:"free_pages"`
+
+	TotalKeys  int64 `json:"total_keys" mapstructure:"total_keys"`
+	TotalBytes int64 `json:"total_bytes" mapstructure:"total_bytes"`
+
+	// Prefixes lists usage by key prefix, largest first.
+	Prefixes []*PrefixUsage `json:"prefixes" mapstructure:"prefixes"`
+}
+
+// CompactionStatus reports the progress of the current or last compaction.
+type CompactionStatus struct {
+	State      string    `json:"state" mapstructure:"state"`
+	StartTime  time.Time `json:"start_time" mapstructure:"start_time"`
+	EndTime    time.Time `json:"end_time" mapstructure:"end_time"`
+	TotalKeys  int64     `json:"total_keys" mapstructure:"total_keys"`
+	KeysCopied int64     `json:"keys_copied" mapstructure:"keys_copied"`
+	SizeBefore int64     `json:"size_before" mapstructure:"size_before"`
+	SizeAfter  int64     `json:"size_after" mapstructure:"size_after"`
+	Error      string    `json:"error" mapstructure:"error"`
+}
+
+// compactionTracker holds the state of the current or last compaction, and
+// journals the keys written while a compaction copies the database.
+type compactionTracker struct {
+	l      sync.Mutex
+	status CompactionStatus
+
+	// dirty holds, by bucket, the keys written since the running compaction
+	// took its snapshot of the database. It is nil when no compaction is
+	// copying the database or when the copy was aborted.
+	dirty map[string]map[string]struct{}
+
+	// cancel stops the copy of the running compaction
+	cancel context.CancelFunc
+}
+
+func (c *compactionTracker) get() CompactionStatus {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	status := c.status
+	if status.State == "" {
+		status.State = CompactionStateIdle
+	}
+	return status
+}
+
+func (c *compactionTracker) startJournal(cancel context.CancelFunc) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.dirty = make(map[string]map[string]struct{})
+	c.cancel = cancel
+}
+
+// markDirty records that key was written in bucket, so that a running
+// compaction brings it up to date before swapping the databases. Callers
+// must hold the FSM read lock.
+func (c *compactionTracker) markDirty(bucket []byte, key []byte) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.dirty == nil {
+		return
+	}
+	keys, ok := c.dirty[string(bucket)]
+	if !ok {
+		keys = make(map[string]struct{})
+		c.dirty[string(bucket)] = keys
+	}
+	keys[string(key)] = struct{}{}
+}
+
+// stopJournal returns the journaled keys and stops journaling. It returns
+// nil if the compaction was aborted.
+func (c *compactionTracker) stopJournal() map[string]map[string]struct{} {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	dirty := c.dirty
+	c.dirty = nil
+	c.cancel = nil
+	return dirty
+}
+
+// abort stops the copy of the running compaction, if any, and prevents it
+// from swapping the databases. It is called before the database is closed
+// or replaced.
+func (c *compactionTracker) abort() {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.dirty = nil
+	c.cancel = nil
+}
+
+// usagePrefix returns the first depth path segments of key, including the
+// trailing slash. Keys with fewer segments are attributed to their parent
+// directory.
+func usagePrefix(key string, depth int) string {
+	idx := 0
+	for i := 0; i < depth; i++ {
+		next := strings.IndexByte(key[idx:], '/')
+		if next < 0 {
+			return key[:strings.LastIndexByte(key, '/')+1]
+		}
+		idx += next + 1
+	}
+	return key[:idx]
+}
+
+// StorageUsage walks the data stored in the FSM and aggregates its size by
+// key prefix, up to depth path segments deep. The walk runs in a single read
+// transaction and does not block writes.
+func (f *FSM) StorageUsage(ctx context.Context, depth int) (*StorageUsage, error) {
+	defer metrics.MeasureSince([]string{"raft_storage", "fsm", "storage_usage"}, time.Now())
+
+	if depth < 1 {
+		return nil, fmt.Errorf("depth must be at least 1")
+	}
+
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	usage := &StorageUsage{}
+	if info, err := os.Stat(f.db.Path()); err == nil {
+		usage.DBFileSize = info.Size()
+	}
+	stats := f.db.Stats()
+	usage.FreeBytes = int64(stats.FreeAlloc)
+	usage.FreePages = stats.FreePageN
+
+	prefixes := make(map[string]*PrefixUsage)
+	err := f.db.View(func(tx *bolt.Tx) error {
+		usage.DBDataSize = tx.Size()
+
+		c := tx.Bucket(dataBucketName).Cursor()
+		var n int
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			// Checking the context on every key would dominate the walk.
+			n++
+			if n%10000 == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+
+			size := int64(len(k) + len(v))
+			prefix := usagePrefix(string(k), depth)
+			p, ok := prefixes[prefix]
+			if !ok {
+				p = &PrefixUsage{Prefix: prefix}
+				prefixes[prefix] = p
+			}
+			p.Keys++
+			p.Bytes += size
+
+			usage.TotalKeys++
+			usage.TotalBytes += size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Prefixes = sortedPrefixUsage(prefixes)
+	return usage, nil
+}
+
+// sortedPrefixUsage returns the usage of the prefixes, largest first.
+func sortedPrefixUsage(prefixes map[string]*PrefixUsage) []*PrefixUsage {
+	sorted := make([]*PrefixUsage, 0, len(prefixes))
+	for _, p := range prefixes {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Bytes != sorted[j].Bytes {
+			return sorted[i].Bytes > sorted[j].Bytes
+		}
+		return sorted[i].Prefix < sorted[j].Prefix
+	})
+	return sorted
+}
+
+// CompactionStatus returns the progress of the current or last compaction.
+func (f *FSM) CompactionStatus() CompactionStatus {
+	return f.compaction.get()
+}
+
+// Compact rewrites the FSM database into a new file, releasing the space
+// held by free pages back to the filesystem, and swaps it in place of the
+// current file. The database is copied from a read transaction while reads
+// and writes go on; the keys written in the meantime are then copied again
+// while the FSM is locked, just before the files are swapped. A snapshot
+// restore aborts a running compaction.
+func (f *FSM) Compact(ctx context.Context) error {
+	f.compaction.l.Lock()
+	if f.compaction.status.State == CompactionStateRunning {
+		f.compaction.l.Unlock()
+		return ErrCompactionInProgress
+	}
+	f.compaction.status = CompactionStatus{
+		State:     CompactionStateRunning,
+		StartTime: time.Now().UTC(),
+	}
+	f.compaction.l.Unlock()
+
+	err := f.compact(ctx)
+
+	f.compaction.l.Lock()
+	defer f.compaction.l.Unlock()
+	f.compaction.status.EndTime = time.Now().UTC()
+	if err != nil {
+		f.compaction.status.State = CompactionStateFailed
+		f.compaction.status.Error = err.Error()
+		f.logger.Error("compaction failed", "error", err)
+		return err
+	}
+	f.compaction.status.State = CompactionStateCompleted
+	f.logger.Info("compaction completed", "size_before", f.compaction.status.SizeBefore, "size_after", f.compaction.status.SizeAfter)
+	return nil
+}
+
+func (f *FSM) compact(ctx context.Context) error {
+	defer metrics.MeasureSince([]string{"raft_storage", "fsm", "compact"}, time.Now())
+
+	dbPath := filepath.Join(f.path, databaseFilename)
+	compactPath := dbPath + ".compact"
+	if err := os.Remove(compactPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale compaction file: %w", err)
+	}
+
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return err
+	}
+	f.compaction.l.Lock()
+	f.compaction.status.SizeBefore = info.Size()
+	f.compaction.l.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Begin the read transaction the database is copied from while no write
+	// is in progress, so that every write it does not see is journaled.
+	f.l.Lock()
+	srcTx, err := f.db.Begin(false)
+	if err == nil {
+		f.compaction.startJournal(cancel)
+	}
+	f.l.Unlock()
+	if err != nil {
+		return err
+	}
+	defer f.compaction.stopJournal()
+
+	dst, err := bolt.Open(compactPath, 0o600, boltOptions(compactPath))
+	if err != nil {
+		_ = srcTx.Rollback()
+		return fmt.Errorf("failed to create compacted database: %w", err)
+	}
+	err = f.copyDB(ctx, srcTx, dst)
+	_ = srcTx.Rollback()
+	if err == nil && f.compactionCallback != nil {
+		f.compactionCallback()
+	}
+	if err != nil {
+		_ = dst.Close()
+		_ = os.Remove(compactPath)
+		return err
+	}
+
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	dirty := f.compaction.stopJournal()
+	if dirty == nil {
+		err = errors.New("compaction aborted")
+	} else {
+		err = f.copyDirtyKeys(dst, dirty)
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(compactPath)
+		return err
+	}
+
+	if err := f.db.Close(); err != nil {
+		_ = os.Remove(compactPath)
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+
+	// Reopen whichever file ends up in place, even if the rename failed.
+	renameErr := os.Rename(compactPath, dbPath)
+	if err := f.openDBFile(dbPath); err != nil {
+		return fmt.Errorf("failed to reopen database: %w", err)
+	}
+	if renameErr != nil {
+		_ = os.Remove(compactPath)
+		return fmt.Errorf("failed to install compacted database: %w", renameErr)
+	}
+
+	if info, err := os.Stat(dbPath); err == nil {
+		f.compaction.l.Lock()
+		f.compaction.status.SizeAfter = info.Size()
+		f.compaction.l.Unlock()
+	}
+	return nil
+}
+
+// copyDB copies every bucket seen by srcTx into dst, committing every
+// compactionTxMaxKeys keys.
+func (f *FSM) copyDB(ctx context.Context, srcTx *bolt.Tx, dst *bolt.DB) error {
+	var totalKeys int64
+	err := srcTx.ForEach(func(_ []byte, b *bolt.Bucket) error {
+		totalKeys += int64(b.Stats().KeyN)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	f.compaction.l.Lock()
+	f.compaction.status.TotalKeys = totalKeys
+	f.compaction.l.Unlock()
+
+	f.logger.Info("compacting database", "size", srcTx.Size(), "keys", totalKeys)
+
+	var copied int64
+	progress := func() {
+		f.compaction.l.Lock()
+		f.compaction.status.KeysCopied = copied
+		f.compaction.l.Unlock()
+	}
+	defer progress()
+
+	return srcTx.ForEach(func(name []byte, srcBucket *bolt.Bucket) error {
+		dstTx, err := dst.Begin(true)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dstTx.Rollback() }()
+
+		dstBucket, err := dstTx.CreateBucketIfNotExists(name)
+		if err != nil {
+			return err
+		}
+		dstBucket.FillPercent = 1.0
+
+		var inTx int
+		c := srcBucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				return fmt.Errorf("unexpected nested bucket %q in bucket %q", k, name)
+			}
+
+			if inTx == compactionTxMaxKeys {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := dstTx.Commit(); err != nil {
+					return err
+				}
+				progress()
+				dstTx, err = dst.Begin(true)
+				if err != nil {
+					return err
+				}
+				dstBucket = dstTx.Bucket(name)
+				dstBucket.FillPercent = 1.0
+				inTx = 0
+			}
+
+			if err := dstBucket.Put(k, v); err != nil {
+				return err
+			}
+			inTx++
+			copied++
+		}
+
+		return dstTx.Commit()
+	})
+}
+
+// copyDirtyKeys brings the keys written since the copy began up to date in
+// dst. The caller must hold the FSM lock.
+func (f *FSM) copyDirtyKeys(dst *bolt.DB, dirty map[string]map[string]struct{}) error {
+	return f.db.View(func(srcTx *bolt.Tx) error {
+		return dst.Update(func(dstTx *bolt.Tx) error {
+			for name, keys := range dirty {
+				dstBucket, err := dstTx.CreateBucketIfNotExists([]byte(name))
+				if err != nil {
+					return err
+				}
+
+				srcBucket := srcTx.Bucket([]byte(name))
+				for key := range keys {
+					var v []byte
+					if srcBucket != nil {
+						v = srcBucket.Get([]byte(key))
+					}
+					if v == nil {
+						err = dstBucket.Delete([]byte(key))
+					} else {
+						err = dstBucket.Put([]byte(key), v)
+					}
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// StorageUsage returns the storage used by the FSM, aggregated by key prefix.
+func (b *RaftBackend) StorageUsage(ctx context.Context, depth int) (*StorageUsage, error) {
+	return b.fsm.StorageUsage(ctx, depth)
+}
+
+// StartCompaction compacts the FSM database in the background. Use
+// CompactionStatus to follow its progress.
+func (b *RaftBackend) StartCompaction() error {
+	if b.fsm.CompactionStatus().State == CompactionStateRunning {
+		return ErrCompactionInProgress
+	}
+
+	// Failures are logged and recorded in the compaction status.
+	go b.fsm.Compact(context.Background())
+	return nil
+}
+
+// CompactionStatus returns the progress of the current or last compaction.
+func (b *RaftBackend) CompactionStatus() CompactionStatus {
+	return b.fsm.CompactionStatus()
+}
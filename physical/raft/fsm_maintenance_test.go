@@ -0,0 +1,160 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+func TestUsagePrefix(t *testing.T) {
+	cases := []struct {
+		key      string
+		depth    int
+		expected string
+	}{
+		{"logical/abc/foo/bar", 2, "logical/abc/"},
+		{"logical/abc/foo/bar", 1, "logical/"},
+		{"core/mounts", 2, "core/"},
+		{"sys/expire/id/auth/token/x", 2, "sys/expire/"},
+		{"toplevel", 2, ""},
+	}
+
+	for _, tc := range cases {
+		if got := usagePrefix(tc.key, tc.depth); got != tc.expected {
+			t.Fatalf("usagePrefix(%q, %d): expected %q, got %q", tc.key, tc.depth, tc.expected, got)
+		}
+	}
+}
+
+func TestFSM_StorageUsageAndCompact(t *testing.T) {
+	fsm, dir := getFSM(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	ctx := context.Background()
+
+	value := make([]byte, 4096)
+	for i := 0; i < 1000; i++ {
+		prefix := "logical/kv/"
+		if i%4 == 0 {
+			prefix = "sys/expire/"
+		}
+		if err := fsm.Put(ctx, &physical.Entry{Key: fmt.Sprintf("%sentry-%d", prefix, i), Value: value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := fsm.StorageUsage(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage.Prefixes) != 2 || usage.Prefixes[0].Prefix != "logical/kv/" || usage.Prefixes[0].Keys != 750 {
+		t.Fatalf("unexpected usage: %#v", usage.Prefixes)
+	}
+	if usage.Prefixes[1].Prefix != "sys/expire/" || usage.Prefixes[1].Keys != 250 {
+		t.Fatalf("unexpected usage: %#v", usage.Prefixes[1])
+	}
+
+	// Free up most of the database.
+	if err := fsm.DeletePrefix(ctx, "logical/kv/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := fsm.CompactionStatus(); status.State != CompactionStateIdle {
+		t.Fatalf("expected idle compaction state, got %q", status.State)
+	}
+	if err := fsm.Compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	status := fsm.CompactionStatus()
+	if status.State != CompactionStateCompleted {
+		t.Fatalf("expected completed compaction, got %#v", status)
+	}
+	if status.SizeAfter >= status.SizeBefore {
+		t.Fatalf("database did not shrink: %d -> %d", status.SizeBefore, status.SizeAfter)
+	}
+	if status.KeysCopied != status.TotalKeys {
+		t.Fatalf("copied %d of %d keys", status.KeysCopied, status.TotalKeys)
+	}
+
+	// Data and FSM state survive compaction.
+	entry, err := fsm.Get(ctx, "sys/expire/entry-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || len(entry.Value) != len(value) {
+		t.Fatalf("entry lost during compaction: %#v", entry)
+	}
+	usage, err = fsm.StorageUsage(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.TotalKeys != 250 {
+		t.Fatalf("expected 250 keys after compaction, got %d", usage.TotalKeys)
+	}
+	if err := fsm.Put(ctx, &physical.Entry{Key: "core/after", Value: []byte("ok")}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSM_CompactConcurrentWrites(t *testing.T) {
+	fsm, dir := getFSM(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		if err := fsm.Put(ctx, &physical.Entry{Key: fmt.Sprintf("logical/kv/entry-%d", i), Value: []byte("before")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Writes made after the copy must not block and must not be lost.
+	fsm.compactionCallback = func() {
+		if err := fsm.Put(ctx, &physical.Entry{Key: "logical/kv/entry-0", Value: []byte("after")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsm.Put(ctx, &physical.Entry{Key: "logical/kv/new", Value: []byte("after")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsm.Delete(ctx, "logical/kv/entry-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsm.DeletePrefix(ctx, "logical/kv/entry-2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fsm.Compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"logical/kv/entry-0":  "after",
+		"logical/kv/new":      "after",
+		"logical/kv/entry-1":  "",
+		"logical/kv/entry-2":  "",
+		"logical/kv/entry-25": "",
+		"logical/kv/entry-3":  "before",
+	}
+	for key, value := range expected {
+		entry, err := fsm.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case value == "" && entry != nil:
+			t.Fatalf("expected %q to be deleted, got %q", key, entry.Value)
+		case value != "" && (entry == nil || string(entry.Value) != value):
+			t.Fatalf("expected %q to be %q, got %#v", key, value, entry)
+		}
+	}
+
+	keys, err := fsm.List(ctx, "logical/kv/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 100-12+1 {
+		t.Fatalf("expected %d keys, got %d", 100-12+1, len(keys))
+	}
+}
@@ -0,0 +1,74 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/hashicorp/vault/sdk/plugin/pb"
+)
+
+// ForEachSnapshotEntry reads the snapshot data written to a temporary file by
+// WriteSnapshotToTemp and calls fn with every storage entry it holds, in key
+// order. It stops at the first error returned by fn. The reader is not
+// closed, so that the file can be read again or restored.
+func ForEachSnapshotEntry(ctx context.Context, snap io.Reader, fn func(key string, value []byte) error) error {
+	protoReader := NewDelimitedReader(snap, math.MaxInt32)
+
+	entry := new(pb.StorageEntry)
+	for n := 1; ; n++ {
+		// Checking the context on every entry would dominate the walk.
+		if n%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		err := protoReader.ReadMsg(entry)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot entry: %w", err)
+		}
+
+		if err := fn(entry.Key, entry.Value); err != nil {
+			return err
+		}
+	}
+}
+
+// SnapshotUsage aggregates the entries of the snapshot data written by
+// WriteSnapshotToTemp by key prefix, up to depth path segments deep, the way
+// StorageUsage does for the FSM database. Only the key and byte totals and
+// the prefixes are set.
+func SnapshotUsage(ctx context.Context, snap io.Reader, depth int) (*StorageUsage, error) {
+	if depth < 1 {
+		return nil, fmt.Errorf("depth must be at least 1")
+	}
+
+	usage := &StorageUsage{}
+	prefixes := make(map[string]*PrefixUsage)
+	err := ForEachSnapshotEntry(ctx, snap, func(key string, value []byte) error {
+		size := int64(len(key) + len(value))
+		prefix := usagePrefix(key, depth)
+		p, ok := prefixes[prefix]
+		if !ok {
+			p = &PrefixUsage{Prefix: prefix}
+			prefixes[prefix] = p
+		}
+		p.Keys++
+		p.Bytes += size
+
+		usage.TotalKeys++
+		usage.TotalBytes += size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Prefixes = sortedPrefixUsage(prefixes)
+	return usage, nil
+}
@@ -0,0 +1,42 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/plugin/pb"
+)
+
+func TestSnapshotUsage(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewDelimitedWriter(&buf)
+	for _, key := range []string{"core/mounts", "logical/abc/foo", "logical/abc/bar", "sys/policy/default"} {
+		if err := w.WriteMsg(&pb.StorageEntry{Key: key, Value: []byte("value")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var keys []string
+	err := ForEachSnapshotEntry(context.Background(), bytes.NewReader(buf.Bytes()), func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 4 || keys[1] != "logical/abc/foo" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	usage, err := SnapshotUsage(context.Background(), bytes.NewReader(buf.Bytes()), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.TotalKeys != 4 {
+		t.Fatalf("expected 4 keys, got %d", usage.TotalKeys)
+	}
+	if p := usage.Prefixes[0]; p.Prefix != "logical/abc/" || p.Keys != 2 {
+		t.Fatalf("unexpected largest prefix: %#v", p)
+	}
+}
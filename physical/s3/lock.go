@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/physical/dynamodb"
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+// lockConfigPrefix prefixes the parameters of the backend passed to the lock
+// provider, with the prefix removed.
+const lockConfigPrefix = "lock_"
+
+// LockProvider provides the HA locks of the S3 backend, since object stores
+// lack the conditional writes needed to implement them.
+type LockProvider interface {
+	LockWith(key, value string) (physical.Lock, error)
+}
+
+// LockProviderFactory creates a LockProvider from its configuration
+type LockProviderFactory func(conf map[string]string, logger log.Logger) (LockProvider, error)
+
+var (
+	lockProvidersLock sync.RWMutex
+	lockProviders     = map[string]LockProviderFactory{
+		"dynamodb": newDynamoDBLockProvider,
+	}
+)
+
+// RegisterLockProvider makes a lock provider available to the S3 backend
+// under the given name, for use with the lock_provider parameter.
+func RegisterLockProvider(name string, factory LockProviderFactory) {
+	lockProvidersLock.Lock()
+	defer lockProvidersLock.Unlock()
+	lockProviders[name] = factory
+}
+
+// newDynamoDBLockProvider locks with a DynamoDB-compatible table, in the same
+// way as the DynamoDB backend.
+func newDynamoDBLockProvider(conf map[string]string, logger log.Logger) (LockProvider, error) {
+	b, err := dynamodb.NewDynamoDBBackend(conf, logger)
+	if err != nil {
+		return nil, err
+	}
+	return b.(*dynamodb.DynamoDBBackend), nil
+}
+
+// newLockProvider returns the lock provider configured by conf, or nil if HA
+// is not enabled.
+func newLockProvider(conf map[string]string, logger log.Logger) (LockProvider, error) {
+	haEnabledStr, ok := conf["ha_enabled"]
+	if !ok {
+		return nil, nil
+	}
+	haEnabled, err := parseutil.ParseBool(haEnabledStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid boolean set for ha_enabled: %q", haEnabledStr)
+	}
+	if !haEnabled {
+		return nil, nil
+	}
+
+	name := conf["lock_provider"]
+	if name == "" {
+		name = "dynamodb"
+	}
+	lockProvidersLock.RLock()
+	factory, ok := lockProviders[name]
+	lockProvidersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown lock_provider %q", name)
+	}
+
+	lockConf := make(map[string]string)
+	for k, v := range conf {
+		if k == "lock_provider" || !strings.HasPrefix(k, lockConfigPrefix) {
+			continue
+		}
+		lockConf[strings.TrimPrefix(k, lockConfigPrefix)] = v
+	}
+
+	provider, err := factory(lockConf, logger.Named(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up %s lock provider: %w", name, err)
+	}
+	return provider, nil
+}
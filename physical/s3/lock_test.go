@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/sdk/physical/inmem"
+)
+
+func TestS3Backend_LockProvider(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	var gotConf map[string]string
+	RegisterLockProvider("test-inmem", func(conf map[string]string, logger log.Logger) (LockProvider, error) {
+		gotConf = conf
+		b, err := inmem.NewInmemHA(nil, logger)
+		if err != nil {
+			return nil, err
+		}
+		return b.(physical.HABackend), nil
+	})
+
+	provider, err := newLockProvider(map[string]string{"bucket": "vault"}, logger)
+	if err != nil || provider != nil {
+		t.Fatalf("expected no lock provider without ha_enabled, got %v, %v", provider, err)
+	}
+
+	if _, err := newLockProvider(map[string]string{"ha_enabled": "true", "lock_provider": "nope"}, logger); err == nil {
+		t.Fatal("expected an unknown lock provider to be rejected")
+	}
+
+	provider, err = newLockProvider(map[string]string{
+		"bucket":        "vault",
+		"ha_enabled":    "true",
+		"lock_provider": "test-inmem",
+		"lock_table":    "vault-locks",
+		"lock_endpoint": "http://127.0.0.1:8000",
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotConf) != 2 || gotConf["table"] != "vault-locks" || gotConf["endpoint"] != "http://127.0.0.1:8000" {
+		t.Fatalf("bad lock provider config: %v", gotConf)
+	}
+
+	s := &S3Backend{bucket: "vault", path: "prod", logger: logger, lockProvider: provider}
+	if !s.HAEnabled() {
+		t.Fatal("expected HA to be enabled")
+	}
+	lock, err := s.LockWith("core/lock", "node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lock.Lock(nil); err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock()
+
+	// The lock is held for the bucket and path only
+	other, err := provider.LockWith("vault/prod/core/lock", "node-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	held, value, err := other.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !held || value != "node-1" {
+		t.Fatalf("expected the lock to be held by node-1, got %v, %q", held, value)
+	}
+}
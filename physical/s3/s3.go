@@ -27,7 +27,10 @@ import (
 )
 
 // Verify S3Backend satisfies the correct interfaces
-var _ physical.Backend = (*S3Backend)(nil)
+var (
+	_ physical.Backend   = (*S3Backend)(nil)
+	_ physical.HABackend = (*S3Backend)(nil)
+)
 
 // S3Backend is a physical backend that stores data
 // within an S3 bucket.
@@ -38,6 +41,9 @@ type S3Backend struct {
 	client     *s3.S3
 	logger     log.Logger
 	permitPool *physical.PermitPool
+
+	// lockProvider provides the HA locks, it is nil if HA is not enabled
+	lockProvider LockProvider
 }
 
 // NewS3Backend constructs a S3 backend using a pre-existing
@@ -148,17 +154,38 @@ func NewS3Backend(conf map[string]string, logger log.Logger) (physical.Backend,
 		kmsKeyId = ""
 	}
 
+	lockProvider, err := newLockProvider(conf, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &S3Backend{
-		client:     s3conn,
-		bucket:     bucket,
-		path:       path,
-		kmsKeyId:   kmsKeyId,
-		logger:     logger,
-		permitPool: physical.NewPermitPool(maxParInt),
+		client:       s3conn,
+		bucket:       bucket,
+		path:         path,
+		kmsKeyId:     kmsKeyId,
+		logger:       logger,
+		permitPool:   physical.NewPermitPool(maxParInt),
+		lockProvider: lockProvider,
 	}
 	return s, nil
 }
 
+// LockWith is used for mutual exclusion based on the given key. The key is
+// scoped to the bucket and path, so that a lock table can be shared by
+// several backends.
+func (s *S3Backend) LockWith(key, value string) (physical.Lock, error) {
+	if s.lockProvider == nil {
+		return nil, fmt.Errorf("HA is not enabled on the s3 backend")
+	}
+	return s.lockProvider.LockWith(path.Join(s.bucket, s.path, key), value)
+}
+
+// HAEnabled indicates whether the HA functionality should be exposed
+func (s *S3Backend) HAEnabled() bool {
+	return s.lockProvider != nil
+}
+
 // Put is used to insert or update an entry
 func (s *S3Backend) Put(ctx context.Context, entry *physical.Entry) error {
 	defer metrics.MeasureSince([]string{"s3", "put"}, time.Now())
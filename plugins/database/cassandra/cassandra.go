@@ -100,6 +100,11 @@ func (c *Cassandra) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (d
 	c.Lock()
 	defer c.Unlock()
 
+	// Refuse to create a user the revocation statements would fail to revoke
+	if err := validateRevocationStatements(req.RevocationStatements.Commands); err != nil {
+		return dbplugin.NewUserResponse{}, err
+	}
+
 	session, err := c.getConnection(ctx)
 	if err != nil {
 		return dbplugin.NewUserResponse{}, err
@@ -226,35 +231,142 @@ func (c *Cassandra) DeleteUser(ctx context.Context, req dbplugin.DeleteUserReque
 	c.Lock()
 	defer c.Unlock()
 
+	revocationCQL := req.Statements.Commands
+	if len(revocationCQL) == 0 {
+		revocationCQL = []string{defaultUserDeletionCQL}
+	}
+
+	m := map[string]string{
+		"name":     req.Username,
+		"username": req.Username,
+	}
+
+	if err := validateRevocationStatements(revocationCQL); err != nil {
+		return dbplugin.DeleteUserResponse{}, err
+	}
+
+	var queries []string
+	for _, stmt := range revocationCQL {
+		for _, query := range strutil.ParseArbitraryStringSlice(stmt, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+			queries = append(queries, dbutil.QueryHelper(query, m))
+		}
+	}
+
 	session, err := c.getConnection(ctx)
 	if err != nil {
 		return dbplugin.DeleteUserResponse{}, err
 	}
 
-	revocationCQL := req.Statements.Commands
-	if len(revocationCQL) == 0 {
-		revocationCQL = []string{defaultUserDeletionCQL}
+	if !c.BatchRevocationStatements {
+		var result *multierror.Error
+		for i, query := range queries {
+			err := session.Query(query).WithContext(ctx).Exec()
+			if err != nil {
+				result = multierror.Append(result, fmt.Errorf("revocation statement %d failed: %w", i+1, err))
+			}
+		}
+		return dbplugin.DeleteUserResponse{}, result.ErrorOrNil()
 	}
 
+	return dbplugin.DeleteUserResponse{}, executeBatched(ctx, session, queries)
+}
+
+// executeBatched runs consecutive data manipulation statements as logged
+// batches, so that they are applied atomically. Schema and permission
+// statements (DROP ROLE, REVOKE, ...) cannot be batched by Cassandra and
+// are executed individually, in order, between batches.
+func executeBatched(ctx context.Context, session *gocql.Session, queries []string) error {
 	var result *multierror.Error
-	for _, stmt := range revocationCQL {
+
+	var batch *gocql.Batch
+	var batchStart int
+	flush := func(end int) {
+		if batch == nil {
+			return
+		}
+		if err := session.ExecuteBatch(batch); err != nil {
+			result = multierror.Append(result, fmt.Errorf("revocation statements %d-%d failed as a batch: %w", batchStart+1, end, err))
+		}
+		batch = nil
+	}
+
+	for i, query := range queries {
+		if !isBatchableQuery(query) {
+			flush(i)
+			if err := session.Query(query).WithContext(ctx).Exec(); err != nil {
+				result = multierror.Append(result, fmt.Errorf("revocation statement %d failed: %w", i+1, err))
+			}
+			continue
+		}
+
+		if batch == nil {
+			batch = session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+			batchStart = i
+		}
+		batch.Query(query)
+	}
+	flush(len(queries))
+
+	return result.ErrorOrNil()
+}
+
+// isBatchableQuery reports whether Cassandra permits query inside a batch;
+// only INSERT, UPDATE and DELETE statements are allowed.
+func isBatchableQuery(query string) bool {
+	switch firstKeyword(query) {
+	case "INSERT", "UPDATE", "DELETE":
+		return true
+	default:
+		return false
+	}
+}
+
+// validateRevocationStatements checks each query of the revocation
+// statements with validateRevocationQuery.
+func validateRevocationStatements(statements []string) error {
+	for _, stmt := range statements {
 		for _, query := range strutil.ParseArbitraryStringSlice(stmt, ";") {
 			query = strings.TrimSpace(query)
 			if len(query) == 0 {
 				continue
 			}
 
-			m := map[string]string{
-				"username": req.Username,
+			if err := validateRevocationQuery(query); err != nil {
+				return err
 			}
-			err := session.
-				Query(dbutil.QueryHelper(query, m)).
-				WithContext(ctx).
-				Exec()
+		}
+	}
+	return nil
+}
 
-			result = multierror.Append(result, err)
+// validateRevocationQuery rejects UPDATE and DELETE statements without a
+// WHERE clause. Revocation statements run once per lease, so an unbounded
+// statement would wipe the whole table the first time any credential is
+// revoked.
+func validateRevocationQuery(query string) error {
+	switch firstKeyword(query) {
+	case "UPDATE", "DELETE":
+	default:
+		return nil
+	}
+
+	for _, field := range strings.Fields(strings.ToUpper(query)) {
+		if field == "WHERE" {
+			return nil
 		}
 	}
 
-	return dbplugin.DeleteUserResponse{}, result.ErrorOrNil()
+	return fmt.Errorf("revocation statement %q has no WHERE clause", query)
+}
+
+func firstKeyword(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
 }
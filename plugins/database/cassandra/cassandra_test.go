@@ -303,3 +303,78 @@ func assertNoCreds(t testing.TB, address string, port int, username, password st
 
 const createUserStatements = `CREATE USER '{{username}}' WITH PASSWORD '{{password}}' NOSUPERUSER;
 GRANT ALL PERMISSIONS ON ALL KEYSPACES TO '{{username}}';`
+
+func TestValidateRevocationQuery(t *testing.T) {
+	type testCase struct {
+		query     string
+		expectErr bool
+		batchable bool
+	}
+
+	tests := map[string]testCase{
+		"drop user": {
+			query: `DROP USER '{{username}}'`,
+		},
+		"drop role": {
+			query: `DROP ROLE IF EXISTS '{{name}}'`,
+		},
+		"delete with where": {
+			query:     `DELETE FROM app.grants WHERE username = '{{username}}'`,
+			batchable: true,
+		},
+		"lowercase delete with where": {
+			query:     `delete from app.grants where username = '{{username}}'`,
+			batchable: true,
+		},
+		"delete without where": {
+			query:     `DELETE FROM app.grants`,
+			expectErr: true,
+			batchable: true,
+		},
+		"update without where": {
+			query:     `UPDATE app.users SET active = false`,
+			expectErr: true,
+			batchable: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateRevocationQuery(test.query)
+			if test.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.batchable, isBatchableQuery(test.query))
+		})
+	}
+}
+
+func TestValidateRevocationStatements(t *testing.T) {
+	require.NoError(t, validateRevocationStatements(nil))
+	require.NoError(t, validateRevocationStatements([]string{
+		`DELETE FROM app.grants WHERE username = '{{username}}'; DROP USER '{{username}}';`,
+	}))
+	require.Error(t, validateRevocationStatements([]string{
+		`DROP USER '{{username}}'`,
+		`DROP USER '{{username}}'; DELETE FROM app.grants;`,
+	}))
+}
+
+func TestNewUser_RejectsUnboundedRevocationStatements(t *testing.T) {
+	// The statements are checked before connecting, so no user is created
+	// with statements that would fail to revoke it
+	db := new()
+	_, err := db.NewUser(context.Background(), dbplugin.NewUserRequest{
+		Statements: dbplugin.Statements{
+			Commands: []string{createUserStatements},
+		},
+		RevocationStatements: dbplugin.Statements{
+			Commands: []string{`DROP USER '{{username}}'; DELETE FROM app.grants;`},
+		},
+		Password: "gh8eruajASDFAsgy89svn",
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "has no WHERE clause")
+}
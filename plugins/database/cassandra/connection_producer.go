@@ -20,22 +20,27 @@ import (
 // cassandraConnectionProducer implements ConnectionProducer and provides an
 // interface for cassandra databases to make connections.
 type cassandraConnectionProducer struct {
-	Hosts              string      `json:"hosts" structs:"hosts" mapstructure:"hosts"`
-	Port               int         `json:"port" structs:"port" mapstructure:"port"`
-	Username           string      `json:"username" structs:"username" mapstructure:"username"`
-	Password           string      `json:"password" structs:"password" mapstructure:"password"`
-	TLS                bool        `json:"tls" structs:"tls" mapstructure:"tls"`
-	InsecureTLS        bool        `json:"insecure_tls" structs:"insecure_tls" mapstructure:"insecure_tls"`
-	TLSServerName      string      `json:"tls_server_name" structs:"tls_server_name" mapstructure:"tls_server_name"`
-	ProtocolVersion    int         `json:"protocol_version" structs:"protocol_version" mapstructure:"protocol_version"`
-	ConnectTimeoutRaw  interface{} `json:"connect_timeout" structs:"connect_timeout" mapstructure:"connect_timeout"`
-	SocketKeepAliveRaw interface{} `json:"socket_keep_alive" structs:"socket_keep_alive" mapstructure:"socket_keep_alive"`
-	TLSMinVersion      string      `json:"tls_min_version" structs:"tls_min_version" mapstructure:"tls_min_version"`
-	Consistency        string      `json:"consistency" structs:"consistency" mapstructure:"consistency"`
-	LocalDatacenter    string      `json:"local_datacenter" structs:"local_datacenter" mapstructure:"local_datacenter"`
-	PemBundle          string      `json:"pem_bundle" structs:"pem_bundle" mapstructure:"pem_bundle"`
-	PemJSON            string      `json:"pem_json" structs:"pem_json" mapstructure:"pem_json"`
-	SkipVerification   bool        `json:"skip_verification" structs:"skip_verification" mapstructure:"skip_verification"`
+	Hosts                     string      `json:"hosts" structs:"hosts" mapstructure:"hosts"`
+	Port                      int         `json:"port" structs:"port" mapstructure:"port"`
+	Username                  string      `json:"username" structs:"username" mapstructure:"username"`
+	Password                  string      `json:"password" structs:"password" mapstructure:"password"`
+	TLS                       bool        `json:"tls" structs:"tls" mapstructure:"tls"`
+	InsecureTLS               bool        `json:"insecure_tls" structs:"insecure_tls" mapstructure:"insecure_tls"`
+	TLSServerName             string      `json:"tls_server_name" structs:"tls_server_name" mapstructure:"tls_server_name"`
+	ProtocolVersion           int         `json:"protocol_version" structs:"protocol_version" mapstructure:"protocol_version"`
+	ConnectTimeoutRaw         interface{} `json:"connect_timeout" structs:"connect_timeout" mapstructure:"connect_timeout"`
+	SocketKeepAliveRaw        interface{} `json:"socket_keep_alive" structs:"socket_keep_alive" mapstructure:"socket_keep_alive"`
+	TLSMinVersion             string      `json:"tls_min_version" structs:"tls_min_version" mapstructure:"tls_min_version"`
+	Consistency               string      `json:"consistency" structs:"consistency" mapstructure:"consistency"`
+	LocalDatacenter           string      `json:"local_datacenter" structs:"local_datacenter" mapstructure:"local_datacenter"`
+	LocalDatacenterOnly       bool        `json:"local_datacenter_only" structs:"local_datacenter_only" mapstructure:"local_datacenter_only"`
+	TokenAwareRouting         bool        `json:"token_aware_routing" structs:"token_aware_routing" mapstructure:"token_aware_routing"`
+	ShuffleReplicas           bool        `json:"shuffle_replicas" structs:"shuffle_replicas" mapstructure:"shuffle_replicas"`
+	NonLocalReplicasFallback  bool        `json:"non_local_replicas_fallback" structs:"non_local_replicas_fallback" mapstructure:"non_local_replicas_fallback"`
+	PemBundle                 string      `json:"pem_bundle" structs:"pem_bundle" mapstructure:"pem_bundle"`
+	PemJSON                   string      `json:"pem_json" structs:"pem_json" mapstructure:"pem_json"`
+	SkipVerification          bool        `json:"skip_verification" structs:"skip_verification" mapstructure:"skip_verification"`
+	BatchRevocationStatements bool        `json:"batch_revocation_statements" structs:"batch_revocation_statements" mapstructure:"batch_revocation_statements"`
 
 	connectTimeout  time.Duration
 	socketKeepAlive time.Duration
@@ -84,6 +89,14 @@ func (c *cassandraConnectionProducer) Initialize(ctx context.Context, req dbplug
 		return fmt.Errorf("password cannot be empty")
 	case len(c.PemJSON) > 0 && len(c.PemBundle) > 0:
 		return fmt.Errorf("cannot specify both pem_json and pem_bundle")
+	case c.LocalDatacenterOnly && len(c.LocalDatacenter) == 0:
+		return fmt.Errorf("local_datacenter must be set when local_datacenter_only is enabled")
+	case !c.TokenAwareRouting && (c.ShuffleReplicas || c.NonLocalReplicasFallback):
+		return fmt.Errorf("shuffle_replicas and non_local_replicas_fallback require token_aware_routing")
+	case c.NonLocalReplicasFallback && len(c.LocalDatacenter) == 0:
+		return fmt.Errorf("local_datacenter must be set when non_local_replicas_fallback is enabled")
+	case c.NonLocalReplicasFallback && c.LocalDatacenterOnly:
+		return fmt.Errorf("non_local_replicas_fallback cannot be used with local_datacenter_only")
 	}
 
 	var tlsMinVersion uint16 = tls.VersionTLS12
@@ -193,8 +206,12 @@ func (c *cassandraConnectionProducer) createSession(ctx context.Context) (*gocql
 	clusterConfig.SocketKeepalive = c.socketKeepAlive
 	clusterConfig.SslOpts = c.sslOpts
 
-	if c.LocalDatacenter != "" {
-		clusterConfig.PoolConfig.HostSelectionPolicy = gocql.DCAwareRoundRobinPolicy(c.LocalDatacenter)
+	clusterConfig.PoolConfig.HostSelectionPolicy = c.hostSelectionPolicy()
+	if c.LocalDatacenterOnly {
+		// Never connect to nodes outside of the local datacenter, so that
+		// queries cannot be routed across datacenters even when every
+		// local node is down.
+		clusterConfig.HostFilter = gocql.DataCentreHostFilter(c.LocalDatacenter)
 	}
 
 	session, err := clusterConfig.CreateSession()
@@ -232,6 +249,35 @@ func (c *cassandraConnectionProducer) createSession(ctx context.Context) (*gocql
 	return session, nil
 }
 
+// hostSelectionPolicy builds the gocql host selection policy from the
+// configured load balancing options. With no options set, gocql's default
+// round robin policy is used.
+func (c *cassandraConnectionProducer) hostSelectionPolicy() gocql.HostSelectionPolicy {
+	var policy gocql.HostSelectionPolicy
+	if c.LocalDatacenter != "" {
+		policy = gocql.DCAwareRoundRobinPolicy(c.LocalDatacenter)
+	}
+
+	if !c.TokenAwareRouting {
+		return policy
+	}
+
+	if policy == nil {
+		policy = gocql.RoundRobinHostPolicy()
+	}
+
+	switch {
+	case c.ShuffleReplicas && c.NonLocalReplicasFallback:
+		return gocql.TokenAwareHostPolicy(policy, gocql.ShuffleReplicas(), gocql.NonLocalReplicasFallback())
+	case c.ShuffleReplicas:
+		return gocql.TokenAwareHostPolicy(policy, gocql.ShuffleReplicas())
+	case c.NonLocalReplicasFallback:
+		return gocql.TokenAwareHostPolicy(policy, gocql.NonLocalReplicasFallback())
+	default:
+		return gocql.TokenAwareHostPolicy(policy)
+	}
+}
+
 func (c *cassandraConnectionProducer) secretValues() map[string]string {
 	return map[string]string{
 		c.Password:  "[password]",
@@ -228,3 +228,80 @@ func toJSON(t *testing.T, val interface{}) string {
 	require.NoError(t, err)
 	return string(b)
 }
+
+func TestInitialize_LoadBalancingOptions(t *testing.T) {
+	type testCase struct {
+		config    map[string]interface{}
+		expectErr bool
+	}
+
+	tests := map[string]testCase{
+		"dc aware with token aware routing": {
+			config: map[string]interface{}{
+				"local_datacenter":            "dc1",
+				"token_aware_routing":         true,
+				"shuffle_replicas":            true,
+				"non_local_replicas_fallback": true,
+			},
+		},
+		"local datacenter only": {
+			config: map[string]interface{}{
+				"local_datacenter":      "dc1",
+				"local_datacenter_only": true,
+			},
+		},
+		"local datacenter only without local datacenter": {
+			config: map[string]interface{}{
+				"local_datacenter_only": true,
+			},
+			expectErr: true,
+		},
+		"shuffle replicas without token aware routing": {
+			config: map[string]interface{}{
+				"shuffle_replicas": true,
+			},
+			expectErr: true,
+		},
+		"non local fallback without local datacenter": {
+			config: map[string]interface{}{
+				"token_aware_routing":         true,
+				"non_local_replicas_fallback": true,
+			},
+			expectErr: true,
+		},
+		"non local fallback with local datacenter only": {
+			config: map[string]interface{}{
+				"local_datacenter":            "dc1",
+				"local_datacenter_only":       true,
+				"token_aware_routing":         true,
+				"non_local_replicas_fallback": true,
+			},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := map[string]interface{}{
+				"hosts":    "localhost",
+				"username": "cassandra",
+				"password": "cassandra",
+			}
+			for k, v := range test.config {
+				config[k] = v
+			}
+
+			c := &cassandraConnectionProducer{}
+			err := c.Initialize(context.Background(), dbplugin.InitializeRequest{
+				Config:           config,
+				VerifyConnection: false,
+			})
+			if test.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, c.hostSelectionPolicy())
+		})
+	}
+}
@@ -3,6 +3,7 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -27,6 +28,23 @@ ALTER ROLE "{{name}}" VALID UNTIL '{{expiration}}';
 ALTER ROLE "{{username}}" WITH PASSWORD '{{password}}';
 `
 
+	defaultOwnedObjectsStatements = `
+REASSIGN OWNED BY "{{name}}" TO "{{reassign_owned_to}}";
+DROP OWNED BY "{{name}}";
+`
+
+	// ownedObjectsQuery finds whether a role owns objects or holds privileges
+	// in the current database, or owns shared objects, any of which prevents
+	// dropping the role.
+	ownedObjectsQuery = `
+SELECT EXISTS (
+	SELECT 1 FROM pg_catalog.pg_shdepend d
+	JOIN pg_catalog.pg_roles r ON d.refobjid = r.oid
+	WHERE r.rolname = $1
+	AND d.refclassid = 'pg_catalog.pg_authid'::regclass
+	AND d.dbid IN (0, (SELECT oid FROM pg_catalog.pg_database WHERE datname = current_database()))
+);`
+
 	expirationFormat = "2006-01-02 15:04:05-0700"
 
 	defaultUserNameTemplate = `{{ printf "v-%s-%s-%s-%s" (.DisplayName | truncate 8) (.RoleName | truncate 8) (random 20) (unix_time) | truncate 63 }}`
@@ -75,6 +93,14 @@ type PostgreSQL struct {
 	*connutil.SQLConnectionProducer
 
 	usernameProducer template.StringTemplate
+
+	// reassignOwnedTo is the role that objects owned by revoked users are
+	// reassigned to
+	reassignOwnedTo string
+
+	// ownedObjectsStatements are executed before revoking a user that owns
+	// objects or holds privileges
+	ownedObjectsStatements string
 }
 
 func (p *PostgreSQL) Initialize(ctx context.Context, req dbplugin.InitializeRequest) (dbplugin.InitializeResponse, error) {
@@ -102,6 +128,21 @@ func (p *PostgreSQL) Initialize(ctx context.Context, req dbplugin.InitializeRequ
 		return dbplugin.InitializeResponse{}, fmt.Errorf("invalid username template: %w", err)
 	}
 
+	p.reassignOwnedTo, err = strutil.GetString(req.Config, "reassign_owned_to")
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("failed to retrieve reassign_owned_to: %w", err)
+	}
+	p.ownedObjectsStatements, err = strutil.GetString(req.Config, "owned_objects_statements")
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("failed to retrieve owned_objects_statements: %w", err)
+	}
+	if p.ownedObjectsStatements == "" && p.reassignOwnedTo != "" {
+		p.ownedObjectsStatements = defaultOwnedObjectsStatements
+	}
+	if p.reassignOwnedTo == "" && strings.Contains(p.ownedObjectsStatements, "{{reassign_owned_to}}") {
+		return dbplugin.InitializeResponse{}, errors.New("owned_objects_statements reference reassign_owned_to, which is not set")
+	}
+
 	resp := dbplugin.InitializeResponse{
 		Config: newConf,
 	}
@@ -316,6 +357,10 @@ func (p *PostgreSQL) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequ
 	p.Lock()
 	defer p.Unlock()
 
+	if err := p.handleOwnedObjects(ctx, req.Username); err != nil {
+		return dbplugin.DeleteUserResponse{}, fmt.Errorf("failed to handle objects owned by user: %w", err)
+	}
+
 	if len(req.Statements.Commands) == 0 {
 		return dbplugin.DeleteUserResponse{}, p.defaultDeleteUser(ctx, req.Username)
 	}
@@ -357,6 +402,53 @@ func (p *PostgreSQL) customDeleteUser(ctx context.Context, username string, revo
 	return tx.Commit()
 }
 
+// handleOwnedObjects executes the owned objects statements if the user owns
+// objects or holds privileges that would prevent dropping it, such as tables
+// created by the user.
+func (p *PostgreSQL) handleOwnedObjects(ctx context.Context, username string) error {
+	if p.ownedObjectsStatements == "" {
+		return nil
+	}
+
+	db, err := p.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	var owns bool
+	if err := db.QueryRowContext(ctx, ownedObjectsQuery, username).Scan(&owns); err != nil {
+		return err
+	}
+	if !owns {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	m := map[string]string{
+		"name":              username,
+		"username":          username,
+		"reassign_owned_to": p.reassignOwnedTo,
+	}
+	for _, query := range strutil.ParseArbitraryStringSlice(p.ownedObjectsStatements, ";") {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			continue
+		}
+		if err := dbtxn.ExecuteTxQueryDirect(ctx, tx, m, query); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (p *PostgreSQL) defaultDeleteUser(ctx context.Context, username string) error {
 	db, err := p.getConnection(ctx)
 	if err != nil {
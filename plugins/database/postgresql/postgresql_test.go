@@ -635,6 +635,51 @@ func TestDeleteUser(t *testing.T) {
 	}
 }
 
+func TestDeleteUser_OwnedObjects(t *testing.T) {
+	db, cleanup := getPostgreSQL(t, map[string]interface{}{
+		"reassign_owned_to": "postgres",
+	})
+	defer cleanup()
+
+	password := "myreallysecurepassword"
+	createReq := dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "test",
+			RoleName:    "test",
+		},
+		Statements: dbplugin.Statements{
+			Commands: []string{createAdminUser},
+		},
+		Password:   password,
+		Expiration: time.Now().Add(time.Minute),
+	}
+	createResp := dbtesting.AssertNewUser(t, db, createReq)
+
+	// Create a table owned by the user, which prevents dropping it
+	connURL := strings.Replace(db.ConnectionURL, "postgres:secret", fmt.Sprintf("%s:%s", createResp.Username, password), 1)
+	userDB, err := sql.Open("pgx", connURL)
+	require.NoError(t, err)
+	defer userDB.Close()
+	_, err = userDB.Exec("CREATE TABLE owned_by_user (id integer);")
+	require.NoError(t, err)
+	userDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = db.DeleteUser(ctx, dbplugin.DeleteUserRequest{Username: createResp.Username})
+	require.NoError(t, err)
+
+	waitUntilCredsDoNotExist(2*time.Second)(t, db.ConnectionURL, createResp.Username, password)
+
+	adminDB, err := sql.Open("pgx", db.ConnectionURL)
+	require.NoError(t, err)
+	defer adminDB.Close()
+	var owner string
+	err = adminDB.QueryRow("SELECT tableowner FROM pg_catalog.pg_tables WHERE tablename = 'owned_by_user';").Scan(&owner)
+	require.NoError(t, err)
+	require.Equal(t, "postgres", owner)
+}
+
 type credsAssertion func(t testing.TB, connURL, username, password string)
 
 func assertCreds(assertions ...credsAssertion) credsAssertion {
@@ -59,6 +59,11 @@ func TestConversionsHaveAllFields(t *testing.T) {
 					"rollback_statement",
 				},
 			},
+			RevocationStatements: Statements{
+				Commands: []string{
+					"revocation_statement",
+				},
+			},
 			CredentialType: CredentialTypeRSAPrivateKey,
 			PublicKey:      []byte("-----BEGIN PUBLIC KEY-----"),
 			Password:       "password",
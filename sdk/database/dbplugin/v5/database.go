@@ -106,6 +106,11 @@ type NewUserRequest struct {
 	// if the new user creation process fails.
 	RollbackStatements Statements
 
+	// RevocationStatements is the ordered list of commands that will be sent
+	// to DeleteUser when the user is revoked. Plugins may validate them so
+	// that no user is created with statements that cannot revoke it.
+	RevocationStatements Statements
+
 	// CredentialType is the type of credential to use when creating a user.
 	// Respective fields for the credential type will contain the credential
 	// value that was generated by Vault.
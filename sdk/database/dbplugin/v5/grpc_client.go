@@ -125,6 +125,9 @@ func newUserReqToProto(req NewUserRequest) (*proto.NewUserRequest, error) {
 		RollbackStatements: &proto.Statements{
 			Commands: req.RollbackStatements.Commands,
 		},
+		RevocationStatements: &proto.Statements{
+			Commands: req.RevocationStatements.Commands,
+		},
 	}
 	return rpcReq, nil
 }
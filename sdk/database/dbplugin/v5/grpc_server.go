@@ -146,12 +146,13 @@ func (g *gRPCServer) NewUser(ctx context.Context, req *proto.NewUserRequest) (*p
 			DisplayName: req.GetUsernameConfig().GetDisplayName(),
 			RoleName:    req.GetUsernameConfig().GetRoleName(),
 		},
-		CredentialType:     CredentialType(req.GetCredentialType()),
-		Password:           req.GetPassword(),
-		PublicKey:          req.GetPublicKey(),
-		Expiration:         expiration,
-		Statements:         getStatementsFromProto(req.GetStatements()),
-		RollbackStatements: getStatementsFromProto(req.GetRollbackStatements()),
+		CredentialType:       CredentialType(req.GetCredentialType()),
+		Password:             req.GetPassword(),
+		PublicKey:            req.GetPublicKey(),
+		Expiration:           expiration,
+		Statements:           getStatementsFromProto(req.GetStatements()),
+		RollbackStatements:   getStatementsFromProto(req.GetRollbackStatements()),
+		RevocationStatements: getStatementsFromProto(req.GetRevocationStatements()),
 	}
 
 	dbResp, err := impl.NewUser(ctx, dbReq)
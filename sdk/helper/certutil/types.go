@@ -339,7 +339,7 @@ func (p *ParsedCertBundle) ToCertBundle() (*CertBundle, error) {
 				block.Type = string(ECBlock)
 			case RSAPrivateKey:
 				block.Type = string(PKCS1Block)
-			case Ed25519PrivateKey:
+			case Ed25519PrivateKey, ManagedPrivateKey:
 				block.Type = string(PKCS8Block)
 			}
 		}
@@ -801,6 +801,9 @@ type CreationParameters struct {
 
 	// The explicit SKID to use; especially useful for cross-signing.
 	SKID []byte
+
+	// The explicit serial number to use; a random one is generated when nil.
+	SerialNumber *big.Int
 }
 
 type CreationBundle struct {
@@ -10,3 +10,7 @@ const AgentPathMetrics = "/agent/v1/metrics"
 
 // AgentPathQuit is the path that the agent will use to trigger stopping it.
 const AgentPathQuit = "/agent/v1/quit"
+
+// AgentPathLoggers is the path that the agent will use to read and change
+// the log level of its subsystems.
+const AgentPathLoggers = "/agent/v1/loggers"
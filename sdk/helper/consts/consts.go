@@ -16,6 +16,11 @@ const (
 	// SSRF protection.
 	RequestHeaderName = "X-Vault-Request"
 
+	// TLSClientCertHeaderName is the conventional name of the header in which
+	// listeners pass verified client certificates on to backends. It is
+	// removed from every request, whatever the configuration of the listener.
+	TLSClientCertHeaderName = "X-Vault-Client-Cert"
+
 	// PerformanceReplicationALPN is the negotiated protocol used for
 	// performance replication.
 	PerformanceReplicationALPN = "replication_v1"
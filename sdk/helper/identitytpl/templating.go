@@ -213,6 +213,16 @@ func performTemplating(input string, p *PopulateStringInput) (string, error) {
 		case trimmed == "groups.ids":
 			return p.templateHandler(p.groupIDs)
 
+		case strings.HasPrefix(trimmed, "groups.names_with_prefix."):
+			prefix := strings.TrimPrefix(trimmed, "groups.names_with_prefix.")
+			names := []string{}
+			for _, name := range p.groupNames {
+				if strings.HasPrefix(name, prefix) {
+					names = append(names, name)
+				}
+			}
+			return p.templateHandler(names)
+
 		case strings.HasPrefix(trimmed, "aliases."):
 			split := strings.SplitN(strings.TrimPrefix(trimmed, "aliases."), ".", 2)
 			if len(split) != 2 {
@@ -276,6 +276,20 @@ func TestPopulate_Basic(t *testing.T) {
 			groupMemberships: []string{"foo", "bar"},
 			output:           `["foo","bar"]`,
 		},
+		{
+			mode:             JSONTemplating,
+			name:             "groups.names_with_prefix",
+			input:            "{{identity.entity.groups.names_with_prefix.app-}}",
+			groupMemberships: []string{"app-foo", "bar", "app-baz"},
+			output:           `["app-foo","app-baz"]`,
+		},
+		{
+			mode:             JSONTemplating,
+			name:             "groups.names_with_prefix no match",
+			input:            "{{identity.entity.groups.names_with_prefix.app-}}",
+			groupMemberships: []string{"foo", "bar"},
+			output:           `[]`,
+		},
 		{
 			mode:             JSONTemplating,
 			name:             "groups.ids",
@@ -0,0 +1,296 @@
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+)
+
+// HPKE (RFC 9180) in base mode, with the DHKEM(X25519, HKDF-SHA256) KEM and
+// the HKDF-SHA256 KDF, for KeyType_X25519 policies. Senders only need the
+// public key of the policy, so they can encrypt without reaching Vault.
+
+// HPKEAEAD is the identifier of the AEAD of an HPKE suite.
+type HPKEAEAD uint16
+
+const (
+	HPKEAEAD_AES128_GCM        HPKEAEAD = 0x0001
+	HPKEAEAD_AES256_GCM        HPKEAEAD = 0x0002
+	HPKEAEAD_ChaCha20_Poly1305 HPKEAEAD = 0x0003
+)
+
+const (
+	hpkeKEMX25519HKDFSHA256 = 0x0020
+	hpkeKDFHKDFSHA256       = 0x0001
+	hpkeModeBase            = 0x00
+
+	hpkeX25519Size = 32
+	hpkeNonceSize  = 12
+)
+
+var hpkeVersionLabel = []byte("HPKE-v1")
+
+// ParseHPKEAEAD returns the AEAD with the given name.
+func ParseHPKEAEAD(name string) (HPKEAEAD, error) {
+	switch name {
+	case "aes128-gcm":
+		return HPKEAEAD_AES128_GCM, nil
+	case "aes256-gcm", "":
+		return HPKEAEAD_AES256_GCM, nil
+	case "chacha20-poly1305":
+		return HPKEAEAD_ChaCha20_Poly1305, nil
+	}
+	return 0, fmt.Errorf("unsupported HPKE AEAD %q", name)
+}
+
+func (a HPKEAEAD) String() string {
+	switch a {
+	case HPKEAEAD_AES128_GCM:
+		return "aes128-gcm"
+	case HPKEAEAD_AES256_GCM:
+		return "aes256-gcm"
+	case HPKEAEAD_ChaCha20_Poly1305:
+		return "chacha20-poly1305"
+	}
+	return "[unknown]"
+}
+
+func (a HPKEAEAD) keySize() int {
+	if a == HPKEAEAD_AES128_GCM {
+		return 16
+	}
+	return 32
+}
+
+func (a HPKEAEAD) new(key []byte) (cipher.AEAD, error) {
+	switch a {
+	case HPKEAEAD_AES128_GCM, HPKEAEAD_AES256_GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case HPKEAEAD_ChaCha20_Poly1305:
+		return chacha20poly1305.New(key)
+	}
+	return nil, fmt.Errorf("unsupported HPKE AEAD %d", a)
+}
+
+// HPKEContext is an HPKE encryption context. Successive messages sealed with
+// a sender context must be opened in the same order with the receiver
+// context set up from the same encapsulated key, which makes contexts
+// suitable for streams of messages.
+type HPKEContext struct {
+	aead      cipher.AEAD
+	baseNonce []byte
+	seq       uint64
+}
+
+// Seal encrypts the next message of the context.
+func (c *HPKEContext) Seal(plaintext, associatedData []byte) ([]byte, error) {
+	nonce, err := c.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nil, nonce, plaintext, associatedData), nil
+}
+
+// Open decrypts the next message of the context.
+func (c *HPKEContext) Open(ciphertext, associatedData []byte) ([]byte, error) {
+	nonce, err := c.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, associatedData)
+	if err != nil {
+		return nil, errutil.UserError{Err: "message authentication failed"}
+	}
+	return plaintext, nil
+}
+
+func (c *HPKEContext) nextNonce() ([]byte, error) {
+	if c.seq == ^uint64(0) {
+		return nil, errors.New("HPKE message limit reached")
+	}
+
+	nonce := make([]byte, hpkeNonceSize)
+	binary.BigEndian.PutUint64(nonce[hpkeNonceSize-8:], c.seq)
+	for i := range nonce {
+		nonce[i] ^= c.baseNonce[i]
+	}
+	c.seq++
+	return nonce, nil
+}
+
+// HPKESetupSender returns the encapsulated key and the encryption context
+// sealing messages for the holder of the X25519 private key matching
+// publicKey.
+func HPKESetupSender(randReader io.Reader, publicKey []byte, aead HPKEAEAD, info []byte) ([]byte, *HPKEContext, error) {
+	ephemeral := make([]byte, hpkeX25519Size)
+	if _, err := io.ReadFull(randReader, ephemeral); err != nil {
+		return nil, nil, err
+	}
+	return hpkeSetupSender(ephemeral, publicKey, aead, info)
+}
+
+func hpkeSetupSender(ephemeral, publicKey []byte, aead HPKEAEAD, info []byte) ([]byte, *HPKEContext, error) {
+	if len(publicKey) != hpkeX25519Size {
+		return nil, nil, errutil.UserError{Err: "invalid X25519 public key"}
+	}
+
+	enc, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh, err := curve25519.X25519(ephemeral, publicKey)
+	if err != nil {
+		return nil, nil, errutil.UserError{Err: "invalid X25519 public key"}
+	}
+
+	sharedSecret, err := hpkeExtractAndExpand(dh, append(append([]byte{}, enc...), publicKey...))
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := hpkeKeySchedule(sharedSecret, aead, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ctx, nil
+}
+
+func hpkeSetupReceiver(privateKey, enc []byte, aead HPKEAEAD, info []byte) (*HPKEContext, error) {
+	if len(enc) != hpkeX25519Size {
+		return nil, errutil.UserError{Err: "invalid encapsulated key"}
+	}
+
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	dh, err := curve25519.X25519(privateKey, enc)
+	if err != nil {
+		return nil, errutil.UserError{Err: "invalid encapsulated key"}
+	}
+
+	sharedSecret, err := hpkeExtractAndExpand(dh, append(append([]byte{}, enc...), publicKey...))
+	if err != nil {
+		return nil, err
+	}
+	return hpkeKeySchedule(sharedSecret, aead, info)
+}
+
+// HPKEPublicKey returns the X25519 public key of the given version of the
+// policy; a version of 0 is the latest one.
+func (p *Policy) HPKEPublicKey(ver int) ([]byte, error) {
+	keyEntry, err := p.hpkeKeyEntry(ver)
+	if err != nil {
+		return nil, err
+	}
+	return curve25519.X25519(keyEntry.Key, curve25519.Basepoint)
+}
+
+// HPKESetupReceiver returns the decryption context of the messages sealed
+// for the given version of the policy with the encapsulated key enc; a
+// version of 0 is the latest one.
+func (p *Policy) HPKESetupReceiver(ver int, enc []byte, aead HPKEAEAD, info []byte) (*HPKEContext, error) {
+	if ver == 0 {
+		ver = p.LatestVersion
+	}
+	if p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion {
+		return nil, errutil.UserError{Err: ErrTooOld}
+	}
+
+	keyEntry, err := p.hpkeKeyEntry(ver)
+	if err != nil {
+		return nil, err
+	}
+	return hpkeSetupReceiver(keyEntry.Key, enc, aead, info)
+}
+
+func (p *Policy) hpkeKeyEntry(ver int) (KeyEntry, error) {
+	if !p.Type.HPKESupported() {
+		return KeyEntry{}, errutil.UserError{Err: fmt.Sprintf("HPKE not supported for key type %v", p.Type)}
+	}
+
+	if ver == 0 {
+		ver = p.LatestVersion
+	}
+	if ver < 0 || ver > p.LatestVersion {
+		return KeyEntry{}, errutil.UserError{Err: "invalid key version"}
+	}
+
+	keyEntry, err := p.safeGetKeyEntry(ver)
+	if err != nil {
+		return KeyEntry{}, err
+	}
+	return keyEntry, nil
+}
+
+func hpkeExtractAndExpand(dh, kemContext []byte) ([]byte, error) {
+	suiteID := []byte("KEM")
+	suiteID = binary.BigEndian.AppendUint16(suiteID, hpkeKEMX25519HKDFSHA256)
+
+	if subtle.ConstantTimeCompare(dh, make([]byte, len(dh))) == 1 {
+		return nil, errutil.UserError{Err: "invalid X25519 shared secret"}
+	}
+
+	prk := hpkeLabeledExtract(suiteID, nil, "eae_prk", dh)
+	return hpkeLabeledExpand(suiteID, prk, "shared_secret", kemContext, hpkeX25519Size)
+}
+
+func hpkeKeySchedule(sharedSecret []byte, aead HPKEAEAD, info []byte) (*HPKEContext, error) {
+	suiteID := []byte("HPKE")
+	suiteID = binary.BigEndian.AppendUint16(suiteID, hpkeKEMX25519HKDFSHA256)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, hpkeKDFHKDFSHA256)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, uint16(aead))
+
+	keyScheduleContext := []byte{hpkeModeBase}
+	keyScheduleContext = append(keyScheduleContext, hpkeLabeledExtract(suiteID, nil, "psk_id_hash", nil)...)
+	keyScheduleContext = append(keyScheduleContext, hpkeLabeledExtract(suiteID, nil, "info_hash", info)...)
+
+	secret := hpkeLabeledExtract(suiteID, sharedSecret, "secret", nil)
+	key, err := hpkeLabeledExpand(suiteID, secret, "key", keyScheduleContext, aead.keySize())
+	if err != nil {
+		return nil, err
+	}
+	baseNonce, err := hpkeLabeledExpand(suiteID, secret, "base_nonce", keyScheduleContext, hpkeNonceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	cipher, err := aead.new(key)
+	if err != nil {
+		return nil, err
+	}
+	return &HPKEContext{
+		aead:      cipher,
+		baseNonce: baseNonce,
+	}, nil
+}
+
+func hpkeLabeledExtract(suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := append(append(append(append([]byte{}, hpkeVersionLabel...), suiteID...), label...), ikm...)
+	return hkdf.Extract(sha256.New, labeledIKM, salt)
+}
+
+func hpkeLabeledExpand(suiteID, prk []byte, label string, info []byte, length int) ([]byte, error) {
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = append(append(append(append(labeledInfo, hpkeVersionLabel...), suiteID...), label...), info...)
+
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, labeledInfo), out); err != nil {
+		return nil, fmt.Errorf("error expanding HPKE %q: %w", label, err)
+	}
+	return out, nil
+}
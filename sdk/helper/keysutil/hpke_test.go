@@ -0,0 +1,137 @@
+package keysutil
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Test vector A.1.1 of RFC 9180: DHKEM(X25519, HKDF-SHA256), HKDF-SHA256,
+// AES-128-GCM in base mode
+func TestHPKE_RFC9180Vector(t *testing.T) {
+	info := mustDecodeHex(t, "4f6465206f6e2061204772656369616e2055726e")
+	skE := mustDecodeHex(t, "52c4a758a802cd8b936eceea314432798d5baf2d7e9235dc084ab1b9cfa2f736")
+	skR := mustDecodeHex(t, "4612c550263fc8ad58375df3f557aac531d26850903e55a9f23f21d8534e8ac8")
+	expectedEnc := mustDecodeHex(t, "37fda3567bdbd628e88668c3c8d7e97d1d1253b6d4ea6d44c150f741f1bf4431")
+	plaintext := mustDecodeHex(t, "4265617574792069732074727574682c20747275746820626561757479")
+	aad := mustDecodeHex(t, "436f756e742d30")
+	expectedCiphertext := mustDecodeHex(t, "f938558b5d72f1a23810b4be2ab4f84331acc02fc97babc53a52ae8218a355a96d8770ac83d07bea87e13c512a")
+
+	p := &Policy{
+		Type:          KeyType_X25519,
+		LatestVersion: 1,
+		Keys: keyEntryMap{
+			"1": KeyEntry{Key: skR},
+		},
+	}
+	pkR, err := p.HPKEPublicKey(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, sender, err := hpkeSetupSender(skE, pkR, HPKEAEAD_AES128_GCM, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expectedEnc, enc) {
+		t.Fatalf("expected enc %x, got %x", expectedEnc, enc)
+	}
+	ciphertext, err := sender.Seal(plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expectedCiphertext, ciphertext) {
+		t.Fatalf("expected ciphertext %x, got %x", expectedCiphertext, ciphertext)
+	}
+
+	receiver, err := p.HPKESetupReceiver(1, enc, HPKEAEAD_AES128_GCM, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := receiver.Open(ciphertext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, opened) {
+		t.Fatalf("expected plaintext %x, got %x", plaintext, opened)
+	}
+}
+
+func TestHPKE_Stream(t *testing.T) {
+	p := &Policy{
+		Type: KeyType_X25519,
+	}
+	if err := p.RotateInMemory(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	pk, err := p.HPKEPublicKey(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, aead := range []HPKEAEAD{HPKEAEAD_AES128_GCM, HPKEAEAD_AES256_GCM, HPKEAEAD_ChaCha20_Poly1305} {
+		enc, sender, err := HPKESetupSender(rand.Reader, pk, aead, []byte("info"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ciphertexts [][]byte
+		for _, chunk := range []string{"first", "second", "third"} {
+			ciphertext, err := sender.Seal([]byte(chunk), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ciphertexts = append(ciphertexts, ciphertext)
+		}
+
+		// Messages must be opened in order
+		receiver, err := p.HPKESetupReceiver(0, enc, aead, []byte("info"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := receiver.Open(ciphertexts[1], nil); err == nil {
+			t.Fatalf("%s: expected an error opening out of order", aead)
+		}
+
+		receiver, err = p.HPKESetupReceiver(0, enc, aead, []byte("info"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, chunk := range []string{"first", "second", "third"} {
+			opened, err := receiver.Open(ciphertexts[i], nil)
+			if err != nil {
+				t.Fatalf("%s: %v", aead, err)
+			}
+			if string(opened) != chunk {
+				t.Fatalf("%s: expected %q, got %q", aead, chunk, opened)
+			}
+		}
+
+		// A different info fails
+		receiver, err = p.HPKESetupReceiver(0, enc, aead, []byte("other"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := receiver.Open(ciphertexts[0], nil); err == nil {
+			t.Fatalf("%s: expected an error opening with a different info", aead)
+		}
+	}
+
+	// Old versions are rejected below the minimum decryption version
+	if err := p.RotateInMemory(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	p.MinDecryptionVersion = 2
+	if _, err := p.HPKESetupReceiver(1, make([]byte, 32), HPKEAEAD_AES256_GCM, nil); err == nil {
+		t.Fatal("expected an error with a version below the minimum decryption version")
+	}
+}
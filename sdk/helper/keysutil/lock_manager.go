@@ -59,6 +59,12 @@ type PolicyRequest struct {
 
 	// AllowImportedKeyRotation indicates whether an imported key may be rotated by Vault
 	AllowImportedKeyRotation bool
+
+	// The name of the managed key backing a KeyType_MANAGED_KEY policy
+	ManagedKeyName string
+
+	// The attestation verified for imported key material
+	ImportAttestation *ImportAttestation
 }
 
 type LockManager struct {
@@ -376,12 +382,22 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 				cleanup()
 				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
 			}
-		case KeyType_HMAC:
+		case KeyType_HMAC, KeyType_X25519:
 			if req.Derived || req.Convergent {
 				cleanup()
 				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
 			}
 
+		case KeyType_MANAGED_KEY:
+			if req.Derived || req.Convergent || req.Exportable {
+				cleanup()
+				return nil, false, fmt.Errorf("key derivation, convergent encryption and export not supported for keys of type %v", req.KeyType)
+			}
+			if req.ManagedKeyName == "" {
+				cleanup()
+				return nil, false, fmt.Errorf("a managed key name is required for keys of type %v", req.KeyType)
+			}
+
 		default:
 			cleanup()
 			return nil, false, fmt.Errorf("unsupported key type %v", req.KeyType)
@@ -396,6 +412,7 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 			AllowPlaintextBackup: req.AllowPlaintextBackup,
 			AutoRotatePeriod:     req.AutoRotatePeriod,
 			KeySize:              req.KeySize,
+			ManagedKeyName:       req.ManagedKeyName,
 		}
 
 		if req.Derived {
@@ -495,7 +512,7 @@ func (lm *LockManager) ImportPolicy(ctx context.Context, req PolicyRequest, key
 		}
 	}
 
-	err = p.Import(ctx, req.Storage, key, rand)
+	err = p.ImportWithAttestation(ctx, req.Storage, key, req.ImportAttestation, rand)
 	if err != nil {
 		return fmt.Errorf("error importing key: %s", err)
 	}
@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
 	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/hkdf"
 
@@ -64,6 +65,7 @@ const (
 	KeyType_RSA3072
 	KeyType_MANAGED_KEY
 	KeyType_HMAC
+	KeyType_X25519
 )
 
 const (
@@ -98,6 +100,12 @@ type SigningOptions struct {
 	Marshaling    MarshalingType
 	SaltLength    int
 	SigAlgorithm  string
+
+	// ManagedKeySigner and ManagedKeyPublicKey sign and verify on behalf of
+	// KeyType_MANAGED_KEY policies, whose key is held by a key management
+	// system.
+	ManagedKeySigner    crypto.Signer
+	ManagedKeyPublicKey crypto.PublicKey
 }
 
 type SigningResult struct {
@@ -129,7 +137,7 @@ func (kt KeyType) DecryptionSupported() bool {
 
 func (kt KeyType) SigningSupported() bool {
 	switch kt {
-	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096, KeyType_MANAGED_KEY:
 		return true
 	}
 	return false
@@ -137,7 +145,7 @@ func (kt KeyType) SigningSupported() bool {
 
 func (kt KeyType) HashSignatureInput() bool {
 	switch kt {
-	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096, KeyType_MANAGED_KEY:
 		return true
 	}
 	return false
@@ -151,6 +159,10 @@ func (kt KeyType) DerivationSupported() bool {
 	return false
 }
 
+func (kt KeyType) HPKESupported() bool {
+	return kt == KeyType_X25519
+}
+
 func (kt KeyType) AssociatedDataSupported() bool {
 	switch kt {
 	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
@@ -183,6 +195,10 @@ func (kt KeyType) String() string {
 		return "rsa-4096"
 	case KeyType_HMAC:
 		return "hmac"
+	case KeyType_X25519:
+		return "x25519"
+	case KeyType_MANAGED_KEY:
+		return "managed_key"
 	}
 
 	return "[unknown]"
@@ -220,6 +236,34 @@ type KeyEntry struct {
 	// This is deprecated (but still filled) in favor of the value above which
 	// is more precise
 	DeprecatedCreationTime int64 `json:"creation_time"`
+
+	// The attestation of the origin of imported key material, if one was
+	// verified when the key version was imported
+	ImportAttestation *ImportAttestation `json:"import_attestation,omitempty"`
+}
+
+// ImportAttestation is a signed statement of the origin of imported key
+// material, such as the one an HSM produces when it exports a key, along
+// with the certificates of its signer.
+type ImportAttestation struct {
+	// Origin is the origin of the key material claimed by the statement
+	Origin string `json:"origin"`
+
+	// Statement and Signature are the attestation statement and its
+	// signature, as they were provided on import
+	Statement []byte `json:"statement"`
+	Signature []byte `json:"signature"`
+
+	// Certificates is the PEM-encoded certificate chain of the signer of the
+	// statement, leaf first
+	Certificates string `json:"certificates"`
+
+	// SignerSubject is the subject of the certificate that signed the
+	// statement
+	SignerSubject string `json:"signer_subject"`
+
+	// VerifiedTime is when the attestation was verified
+	VerifiedTime time.Time `json:"verified_time"`
 }
 
 // deprecatedKeyEntryMap is used to allow JSON marshal/unmarshal
@@ -421,6 +465,40 @@ type Policy struct {
 	AllowImportedKeyRotation bool
 
 	ManagedKeyName string `json:"managed_key_name,omitempty"`
+
+	// UsageLog enables the hash-chained log of signing operations performed
+	// with the key. Each entry of the log is signed by the key itself.
+	UsageLog bool `json:"usage_log,omitempty"`
+
+	// SigningApprovalsRequired is the number of distinct callers that must
+	// request the same signature before it is produced. Zero or one lets
+	// every caller sign on its own.
+	SigningApprovalsRequired int `json:"signing_approvals_required,omitempty"`
+
+	// SigningApprovalWindow is how long approvals for a signature are kept
+	// waiting for the remaining approvers.
+	SigningApprovalWindow time.Duration `json:"signing_approval_window,omitempty"`
+
+	// SubkeyDerivationAllowed allows deriving subkeys from the key, which
+	// returns key material to the caller.
+	SubkeyDerivationAllowed bool `json:"subkey_derivation_allowed,omitempty"`
+
+	// MaxSubkeyLength is the maximum length in bytes of the subkeys derived
+	// from the key; zero uses DefaultMaxSubkeyLength.
+	MaxSubkeyLength int `json:"max_subkey_length,omitempty"`
+
+	// SubkeyWrapTTL, when set, forces the response wrapping of derived
+	// subkeys with at most this TTL.
+	SubkeyWrapTTL time.Duration `json:"subkey_wrap_ttl,omitempty"`
+
+	// VersionEncryptionPeriod is how long after its creation each version of
+	// the key can be used to encrypt and sign. Zero never ends it.
+	VersionEncryptionPeriod time.Duration `json:"version_encryption_period,omitempty"`
+
+	// VersionDecryptionPeriod is how long after its creation each version of
+	// the key can be used to decrypt and verify, after which the version is
+	// disabled. Zero never ends it.
+	VersionDecryptionPeriod time.Duration `json:"version_decryption_period,omitempty"`
 }
 
 func (p *Policy) Lock(exclusive bool) {
@@ -898,6 +976,10 @@ func (p *Policy) DecryptWithFactory(context, nonce []byte, value string, factori
 		return "", errutil.UserError{Err: ErrTooOld}
 	}
 
+	if err := p.checkVersionDecryptionPeriod(ver); err != nil {
+		return "", err
+	}
+
 	convergentVersion := p.convergentVersion(ver)
 	if convergentVersion == 1 && (nonce == nil || len(nonce) == 0) {
 		return "", errutil.UserError{Err: "invalid convergent nonce supplied"}
@@ -1030,6 +1112,10 @@ func (p *Policy) SignWithOptions(ver int, context, input []byte, options *Signin
 		return nil, errutil.UserError{Err: "requested version for signing is less than the minimum encryption key version"}
 	}
 
+	if err := p.checkVersionEncryptionPeriod(ver); err != nil {
+		return nil, err
+	}
+
 	var sig []byte
 	var pubKey []byte
 	var err error
@@ -1160,6 +1246,12 @@ func (p *Policy) SignWithOptions(ver int, context, input []byte, options *Signin
 			return nil, errutil.InternalError{Err: fmt.Sprintf("unsupported rsa signature algorithm %s", sigAlgorithm)}
 		}
 
+	case KeyType_MANAGED_KEY:
+		sig, err = p.signManagedKey(input, options)
+		if err != nil {
+			return nil, err
+		}
+
 	default:
 		return nil, fmt.Errorf("unsupported key type %v", p.Type)
 	}
@@ -1222,6 +1314,10 @@ func (p *Policy) VerifySignatureWithOptions(context, input []byte, sig string, o
 		return false, errutil.UserError{Err: ErrTooOld}
 	}
 
+	if err := p.checkVersionDecryptionPeriod(ver); err != nil {
+		return false, err
+	}
+
 	hashAlgorithm := options.HashAlgorithm
 	marshaling := options.Marshaling
 	saltLength := options.SaltLength
@@ -1333,16 +1429,120 @@ func (p *Policy) VerifySignatureWithOptions(context, input []byte, sig string, o
 
 		return err == nil, nil
 
+	case KeyType_MANAGED_KEY:
+		return p.verifyManagedKey(input, sigBytes, options)
+
 	default:
 		return false, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
 }
 
+// managedKeySignerOpts returns the options of a signature made with a
+// managed key. Key management systems only support PSS salts as long as
+// the hash.
+func managedKeySignerOpts(public crypto.PublicKey, options *SigningOptions) (crypto.SignerOpts, error) {
+	algo, ok := CryptoHashMap[options.HashAlgorithm]
+	if !ok || algo == 0 {
+		return nil, errutil.UserError{Err: "managed keys require a hash algorithm"}
+	}
+
+	if _, ok := public.(*rsa.PublicKey); !ok {
+		return algo, nil
+	}
+	switch options.SigAlgorithm {
+	case "", "pss":
+		if options.SaltLength != rsa.PSSSaltLengthAuto && options.SaltLength != rsa.PSSSaltLengthEqualsHash && options.SaltLength != algo.Size() {
+			return nil, errutil.UserError{Err: "managed keys only support PSS salt lengths equal to the hash length"}
+		}
+		return &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: algo}, nil
+	case "pkcs1v15":
+		return algo, nil
+	default:
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unsupported rsa signature algorithm %s", options.SigAlgorithm)}
+	}
+}
+
+func (p *Policy) signManagedKey(input []byte, options *SigningOptions) ([]byte, error) {
+	if options.ManagedKeySigner == nil {
+		return nil, errutil.InternalError{Err: "no signer provided for managed key"}
+	}
+	public := options.ManagedKeySigner.Public()
+	opts, err := managedKeySignerOpts(public, options)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := options.ManagedKeySigner.Sign(rand.Reader, input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ecdsaKey, ok := public.(*ecdsa.PublicKey)
+	if !ok || options.Marshaling != MarshalingTypeJWS {
+		return sig, nil
+	}
+
+	// Signers return ASN.1 encoded ECDSA signatures, JWS expects R and S
+	// padded to the size of the curve
+	var ecdsaSig ecdsaSignature
+	if _, err := asn1.Unmarshal(sig, &ecdsaSig); err != nil {
+		return nil, err
+	}
+	keyLen := (ecdsaKey.Curve.Params().BitSize + 7) / 8
+	jws := make([]byte, keyLen*2)
+	ecdsaSig.R.FillBytes(jws[:keyLen])
+	ecdsaSig.S.FillBytes(jws[keyLen:])
+	return jws, nil
+}
+
+func (p *Policy) verifyManagedKey(input, sigBytes []byte, options *SigningOptions) (bool, error) {
+	if options.ManagedKeyPublicKey == nil {
+		return false, errutil.InternalError{Err: "no public key provided for managed key"}
+	}
+	opts, err := managedKeySignerOpts(options.ManagedKeyPublicKey, options)
+	if err != nil {
+		return false, err
+	}
+
+	switch key := options.ManagedKeyPublicKey.(type) {
+	case *ecdsa.PublicKey:
+		if options.Marshaling == MarshalingTypeJWS {
+			// JWS signatures are R and S padded to the size of the curve
+			paramLen := (key.Curve.Params().BitSize + 7) / 8
+			if len(sigBytes) != paramLen*2 {
+				return false, errutil.UserError{Err: "supplied signature is invalid"}
+			}
+			r := new(big.Int).SetBytes(sigBytes[:paramLen])
+			s := new(big.Int).SetBytes(sigBytes[paramLen:])
+			return ecdsa.Verify(key, input, r, s), nil
+		}
+		return ecdsa.VerifyASN1(key, input, sigBytes), nil
+
+	case *rsa.PublicKey:
+		if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+			err = rsa.VerifyPSS(key, pssOpts.Hash, input, sigBytes, pssOpts)
+		} else {
+			err = rsa.VerifyPKCS1v15(key, opts.HashFunc(), input, sigBytes)
+		}
+		return err == nil, nil
+
+	default:
+		return false, errutil.InternalError{Err: fmt.Sprintf("unsupported managed key public key type %T", key)}
+	}
+}
+
 func (p *Policy) Import(ctx context.Context, storage logical.Storage, key []byte, randReader io.Reader) error {
+	return p.ImportWithAttestation(ctx, storage, key, nil, randReader)
+}
+
+// ImportWithAttestation imports key material as a new version of the policy,
+// recording the attestation verified for it, if any.
+func (p *Policy) ImportWithAttestation(ctx context.Context, storage logical.Storage, key []byte, attestation *ImportAttestation, randReader io.Reader) error {
 	now := time.Now()
 	entry := KeyEntry{
 		CreationTime:           now,
 		DeprecatedCreationTime: now.Unix(),
+		ImportAttestation:      attestation,
 	}
 
 	if p.Type != KeyType_HMAC {
@@ -1476,6 +1676,10 @@ func (p *Policy) Rotate(ctx context.Context, storage logical.Storage, randReader
 		return fmt.Errorf("imported key %s does not allow rotation within Vault", p.Name)
 	}
 
+	if p.Type == KeyType_MANAGED_KEY && p.LatestVersion > 0 {
+		return fmt.Errorf("managed key %s must be rotated within its key management system", p.Name)
+	}
+
 	if p.Keys != nil {
 		priorKeys = keyEntryMap{}
 		for k, v := range p.Keys {
@@ -1571,6 +1775,18 @@ func (p *Policy) RotateInMemory(randReader io.Reader) (retErr error) {
 		entry.Key = pri
 		entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(pub)
 
+	case KeyType_X25519:
+		pri, err := uuid.GenerateRandomBytesWithReader(curve25519.ScalarSize, randReader)
+		if err != nil {
+			return err
+		}
+		pub, err := curve25519.X25519(pri, curve25519.Basepoint)
+		if err != nil {
+			return err
+		}
+		entry.Key = pri
+		entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(pub)
+
 	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		bitSize := 2048
 		if p.Type == KeyType_RSA3072 {
@@ -1885,6 +2101,10 @@ func (p *Policy) EncryptWithFactory(ver int, context []byte, nonce []byte, value
 		return "", errutil.UserError{Err: "requested version for encryption is less than the minimum encryption key version"}
 	}
 
+	if err := p.checkVersionEncryptionPeriod(ver); err != nil {
+		return "", err
+	}
+
 	var ciphertext []byte
 
 	switch p.Type {
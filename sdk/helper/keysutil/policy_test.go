@@ -3,11 +3,14 @@ package keysutil
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	mathrand "math/rand"
@@ -1064,3 +1067,116 @@ func isUnsupportedGoHashType(hashType HashType, err error) bool {
 
 	return false
 }
+
+func Test_ManagedKey(t *testing.T) {
+	ctx := context.Background()
+	lm, _ := NewLockManager(true, 0)
+	storage := &logical.InmemStorage{}
+
+	_, _, err := lm.GetPolicy(ctx, PolicyRequest{
+		Upsert:  true,
+		Storage: storage,
+		KeyType: KeyType_MANAGED_KEY,
+		Name:    "missing-name",
+	}, rand.Reader)
+	if err == nil {
+		t.Fatal("expected an error without a managed key name")
+	}
+
+	p, _, err := lm.GetPolicy(ctx, PolicyRequest{
+		Upsert:         true,
+		Storage:        storage,
+		KeyType:        KeyType_MANAGED_KEY,
+		Name:           "test",
+		ManagedKeyName: "hsm-key",
+	}, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ManagedKeyName != "hsm-key" {
+		t.Fatalf("unexpected managed key name %q", p.ManagedKeyName)
+	}
+	if err := p.Rotate(ctx, storage, rand.Reader); err == nil {
+		t.Fatal("expected managed keys to refuse rotation")
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := []byte("managed key input")
+	for _, tc := range []struct {
+		name    string
+		signer  interface{ Public() crypto.PublicKey }
+		options SigningOptions
+	}{
+		{"rsa-pss", rsaKey, SigningOptions{HashAlgorithm: HashTypeSHA2256, Marshaling: MarshalingTypeASN1, SaltLength: rsa.PSSSaltLengthAuto, SigAlgorithm: "pss"}},
+		{"rsa-pkcs1v15", rsaKey, SigningOptions{HashAlgorithm: HashTypeSHA2384, Marshaling: MarshalingTypeASN1, SigAlgorithm: "pkcs1v15"}},
+		{"ecdsa-asn1", ecKey, SigningOptions{HashAlgorithm: HashTypeSHA2256, Marshaling: MarshalingTypeASN1}},
+		{"ecdsa-jws", ecKey, SigningOptions{HashAlgorithm: HashTypeSHA2256, Marshaling: MarshalingTypeJWS}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hash := CryptoHashMap[tc.options.HashAlgorithm].New()
+			hash.Write(input)
+			digest := hash.Sum(nil)
+
+			signOptions := tc.options
+			signOptions.ManagedKeySigner = tc.signer.(crypto.Signer)
+			sig, err := p.SignWithOptions(0, nil, digest, &signOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			verifyOptions := tc.options
+			verifyOptions.ManagedKeyPublicKey = tc.signer.Public()
+			ok, err := p.VerifySignatureWithOptions(nil, digest, sig.Signature, &verifyOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatal("signature did not verify")
+			}
+
+			digest[0] ^= 0xff
+			ok, err = p.VerifySignatureWithOptions(nil, digest, sig.Signature, &verifyOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				t.Fatal("signature verified over a different digest")
+			}
+		})
+	}
+
+	// JWS signatures of the wrong length are rejected rather than split
+	t.Run("ecdsa-jws-length", func(t *testing.T) {
+		options := SigningOptions{HashAlgorithm: HashTypeSHA2256, Marshaling: MarshalingTypeJWS}
+		digest := sha256.Sum256(input)
+
+		signOptions := options
+		signOptions.ManagedKeySigner = ecKey
+		sig, err := p.SignWithOptions(0, nil, digest[:], &signOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx := strings.LastIndex(sig.Signature, ":")
+		sigBytes, err := base64.RawURLEncoding.DecodeString(sig.Signature[idx+1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		verifyOptions := options
+		verifyOptions.ManagedKeyPublicKey = ecKey.Public()
+		for _, bad := range [][]byte{sigBytes[:len(sigBytes)-1], append(sigBytes, 0)} {
+			badSig := sig.Signature[:idx+1] + base64.RawURLEncoding.EncodeToString(bad)
+			if _, err := p.VerifySignatureWithOptions(nil, digest[:], badSig, &verifyOptions); err == nil {
+				t.Fatalf("expected an error verifying a %d byte signature", len(bad))
+			}
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package keysutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/kdf"
+)
+
+const (
+	// SubkeyAlgorithmHKDFSHA256 derives subkeys with HKDF (RFC 5869) using
+	// SHA-256.
+	SubkeyAlgorithmHKDFSHA256 = "hkdf-sha256"
+
+	// SubkeyAlgorithmKBKDFHMACSHA256 derives subkeys with the counter mode KDF
+	// of NIST SP 800-108 using HMAC-SHA256.
+	SubkeyAlgorithmKBKDFHMACSHA256 = "kbkdf-hmac-sha256"
+
+	// DefaultMaxSubkeyLength is the maximum length in bytes of the subkeys
+	// derived from a policy which doesn't set one.
+	DefaultMaxSubkeyLength = 32
+
+	// MaxSubkeyLength is the largest maximum length in bytes a policy can
+	// set for its subkeys.
+	MaxSubkeyLength = 64
+)
+
+// subkeyLabel domain-separates the key material of subkeys from the keys of
+// the policy itself, including its derived keys, so that no subkey can be
+// one of them.
+var subkeyLabel = []byte("vault transit subkey")
+
+// SubkeyDerivationSupported reports whether subkeys can be derived from keys
+// of the type.
+func (kt KeyType) SubkeyDerivationSupported() bool {
+	switch kt {
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_HMAC:
+		return true
+	}
+	return false
+}
+
+// MaxSubkeyLengthOrDefault returns the maximum length in bytes of the
+// subkeys derived from the policy.
+func (p *Policy) MaxSubkeyLengthOrDefault() int {
+	if p.MaxSubkeyLength > 0 {
+		return p.MaxSubkeyLength
+	}
+	return DefaultMaxSubkeyLength
+}
+
+// DeriveSubkey derives a subkey of length bytes from the given version of the
+// key with the given algorithm; a version of 0 is the latest one. The salt is
+// only used by HKDF. Subkeys are deterministic: the same parameters always
+// derive the same subkey.
+func (p *Policy) DeriveSubkey(ver int, algorithm string, salt, info []byte, length int) ([]byte, error) {
+	if !p.Type.SubkeyDerivationSupported() {
+		return nil, errutil.UserError{Err: fmt.Sprintf("subkey derivation not supported for key type %v", p.Type)}
+	}
+	if !p.SubkeyDerivationAllowed {
+		return nil, errutil.UserError{Err: "subkey derivation is not allowed by the key configuration"}
+	}
+	if length <= 0 || length > p.MaxSubkeyLengthOrDefault() {
+		return nil, errutil.UserError{Err: fmt.Sprintf("subkey length must be between 1 and %d bytes", p.MaxSubkeyLengthOrDefault())}
+	}
+
+	if ver == 0 {
+		ver = p.LatestVersion
+	}
+	if ver <= 0 || ver > p.LatestVersion {
+		return nil, errutil.UserError{Err: "invalid key version"}
+	}
+	if p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion {
+		return nil, errutil.UserError{Err: ErrTooOld}
+	}
+
+	keyEntry, err := p.safeGetKeyEntry(ver)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, keyEntry.Key)
+	mac.Write(subkeyLabel)
+	base := mac.Sum(nil)
+
+	switch algorithm {
+	case SubkeyAlgorithmHKDFSHA256:
+		subkey := make([]byte, length)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, base, salt, info), subkey); err != nil {
+			return nil, err
+		}
+		return subkey, nil
+
+	case SubkeyAlgorithmKBKDFHMACSHA256:
+		if len(salt) != 0 {
+			return nil, errutil.UserError{Err: fmt.Sprintf("salt is not supported by %s", algorithm)}
+		}
+		return kdf.CounterMode(kdf.HMACSHA256PRF, kdf.HMACSHA256PRFLen, base, info, uint32(length*8))
+
+	default:
+		return nil, errutil.UserError{Err: fmt.Sprintf("unsupported subkey derivation algorithm %q", algorithm)}
+	}
+}
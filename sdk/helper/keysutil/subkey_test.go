@@ -0,0 +1,78 @@
+package keysutil
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestPolicy_DeriveSubkey(t *testing.T) {
+	p := &Policy{
+		Type:    KeyType_AES256_GCM96,
+		Derived: true,
+		KDF:     Kdf_hkdf_sha256,
+	}
+	if err := p.RotateInMemory(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.DeriveSubkey(0, SubkeyAlgorithmHKDFSHA256, nil, []byte("info"), 32); err == nil {
+		t.Fatal("expected an error deriving a subkey without allowing it")
+	}
+	p.SubkeyDerivationAllowed = true
+
+	first, err := p.DeriveSubkey(0, SubkeyAlgorithmHKDFSHA256, []byte("salt"), []byte("info"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.DeriveSubkey(1, SubkeyAlgorithmHKDFSHA256, []byte("salt"), []byte("info"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("expected subkeys to be deterministic")
+	}
+
+	other, err := p.DeriveSubkey(0, SubkeyAlgorithmHKDFSHA256, []byte("salt"), []byte("other"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, other) {
+		t.Fatal("expected subkeys with different info to differ")
+	}
+
+	kbkdf, err := p.DeriveSubkey(0, SubkeyAlgorithmKBKDFHMACSHA256, nil, []byte("info"), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kbkdf) != 16 {
+		t.Fatalf("expected a subkey of 16 bytes, got %d", len(kbkdf))
+	}
+	if _, err := p.DeriveSubkey(0, SubkeyAlgorithmKBKDFHMACSHA256, []byte("salt"), []byte("info"), 16); err == nil {
+		t.Fatal("expected an error with a salt for kbkdf")
+	}
+
+	// Subkeys are not the derived keys of the policy
+	derived, err := p.DeriveKey([]byte("info"), nil, 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsalted, err := p.DeriveSubkey(0, SubkeyAlgorithmHKDFSHA256, nil, []byte("info"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(derived, unsalted) {
+		t.Fatal("expected subkeys to differ from derived keys")
+	}
+
+	if _, err := p.DeriveSubkey(0, SubkeyAlgorithmHKDFSHA256, nil, nil, 33); err == nil {
+		t.Fatal("expected an error above the default maximum length")
+	}
+	p.MaxSubkeyLength = 64
+	if _, err := p.DeriveSubkey(0, SubkeyAlgorithmHKDFSHA256, nil, nil, 64); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.DeriveSubkey(0, "sha1", nil, nil, 32); err == nil {
+		t.Fatal("expected an error with an unknown algorithm")
+	}
+}
@@ -0,0 +1,62 @@
+package keysutil
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+)
+
+// versionCreationTime returns when the given version of the policy was
+// created, or the zero time if the version does not exist.
+func (p *Policy) versionCreationTime(ver int) time.Time {
+	entry, ok := p.Keys[strconv.Itoa(ver)]
+	if !ok {
+		return time.Time{}
+	}
+	if entry.CreationTime.IsZero() && entry.DeprecatedCreationTime != 0 {
+		return time.Unix(entry.DeprecatedCreationTime, 0)
+	}
+	return entry.CreationTime
+}
+
+// VersionEncryptionExpiry returns when the given version of the policy stops
+// being usable to encrypt and sign, or the zero time if it never does.
+func (p *Policy) VersionEncryptionExpiry(ver int) time.Time {
+	created := p.versionCreationTime(ver)
+	if p.VersionEncryptionPeriod <= 0 || created.IsZero() {
+		return time.Time{}
+	}
+	return created.Add(p.VersionEncryptionPeriod)
+}
+
+// VersionDecryptionExpiry returns when the given version of the policy stops
+// being usable to decrypt and verify, or the zero time if it never does.
+func (p *Policy) VersionDecryptionExpiry(ver int) time.Time {
+	created := p.versionCreationTime(ver)
+	if p.VersionDecryptionPeriod <= 0 || created.IsZero() {
+		return time.Time{}
+	}
+	return created.Add(p.VersionDecryptionPeriod)
+}
+
+// checkVersionEncryptionPeriod returns an error if the encryption period of
+// the given version of the policy is over.
+func (p *Policy) checkVersionEncryptionPeriod(ver int) error {
+	expiry := p.VersionEncryptionExpiry(ver)
+	if !expiry.IsZero() && !time.Now().Before(expiry) {
+		return errutil.UserError{Err: fmt.Sprintf("key version %d can no longer be used to encrypt or sign since %s; rotate the key to use a new version", ver, expiry.Format(time.RFC3339))}
+	}
+	return nil
+}
+
+// checkVersionDecryptionPeriod returns an error if the decryption period of
+// the given version of the policy is over.
+func (p *Policy) checkVersionDecryptionPeriod(ver int) error {
+	expiry := p.VersionDecryptionExpiry(ver)
+	if !expiry.IsZero() && !time.Now().Before(expiry) {
+		return errutil.UserError{Err: fmt.Sprintf("key version %d is disabled since %s, when its decryption period ended", ver, expiry.Format(time.RFC3339))}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package keysutil
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicy_VersionPeriods(t *testing.T) {
+	p := &Policy{
+		Type:                    KeyType_AES256_GCM96,
+		VersionEncryptionPeriod: time.Hour,
+		VersionDecryptionPeriod: 2 * time.Hour,
+	}
+	if err := p.RotateInMemory(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := base64.StdEncoding.EncodeToString([]byte("secret"))
+	ciphertext, err := p.Encrypt(0, nil, nil, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setCreationTime := func(created time.Time) {
+		entry := p.Keys["1"]
+		entry.CreationTime = created
+		p.Keys["1"] = entry
+	}
+
+	// Past its encryption period, the version only decrypts
+	setCreationTime(time.Now().Add(-90 * time.Minute))
+	if _, err := p.Encrypt(0, nil, nil, plaintext); err == nil || !strings.Contains(err.Error(), "can no longer be used to encrypt") {
+		t.Fatalf("expected an error encrypting with an expired version, got: %v", err)
+	}
+	decrypted, err := p.Decrypt(nil, nil, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted != plaintext {
+		t.Fatal("unexpected plaintext")
+	}
+
+	// A new version encrypts again
+	if err := p.RotateInMemory(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Encrypt(0, nil, nil, plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Encrypt(1, nil, nil, plaintext); err == nil {
+		t.Fatal("expected an error encrypting with an expired version")
+	}
+
+	// Past its decryption period, the version is disabled
+	setCreationTime(time.Now().Add(-3 * time.Hour))
+	if _, err := p.Decrypt(nil, nil, ciphertext); err == nil || !strings.Contains(err.Error(), "is disabled") {
+		t.Fatalf("expected an error decrypting with a disabled version, got: %v", err)
+	}
+
+	if expiry := p.VersionDecryptionExpiry(2); !expiry.Equal(p.Keys["2"].CreationTime.Add(2 * time.Hour)) {
+		t.Fatalf("unexpected decryption expiry: %s", expiry)
+	}
+
+	// Without periods, versions never expire
+	p.VersionEncryptionPeriod, p.VersionDecryptionPeriod = 0, 0
+	if !p.VersionEncryptionExpiry(1).IsZero() || !p.VersionDecryptionExpiry(1).IsZero() {
+		t.Fatal("expected versions not to expire without periods")
+	}
+	if _, err := p.Decrypt(nil, nil, ciphertext); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPolicy_VersionPeriodsSigning(t *testing.T) {
+	p := &Policy{
+		Type:                    KeyType_ED25519,
+		VersionEncryptionPeriod: time.Hour,
+		VersionDecryptionPeriod: 2 * time.Hour,
+	}
+	if err := p.RotateInMemory(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	input := []byte("message")
+	sig, err := p.Sign(0, nil, input, HashTypeNone, "", MarshalingTypeASN1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := p.Keys["1"]
+	entry.CreationTime = time.Now().Add(-90 * time.Minute)
+	p.Keys["1"] = entry
+
+	if _, err := p.Sign(0, nil, input, HashTypeNone, "", MarshalingTypeASN1); err == nil {
+		t.Fatal("expected an error signing with an expired version")
+	}
+	valid, err := p.VerifySignature(nil, input, HashTypeNone, "", MarshalingTypeASN1, sig.Signature)
+	if err != nil || !valid {
+		t.Fatalf("expected the signature to verify: valid: %t, err: %v", valid, err)
+	}
+
+	entry.CreationTime = time.Now().Add(-3 * time.Hour)
+	p.Keys["1"] = entry
+	if _, err := p.VerifySignature(nil, input, HashTypeNone, "", MarshalingTypeASN1, sig.Signature); err == nil {
+		t.Fatal("expected an error verifying with a disabled version")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"hash"
+	"sync"
 
 	"github.com/hashicorp/errwrap"
 	uuid "github.com/hashicorp/go-uuid"
@@ -28,6 +29,10 @@ type Salt struct {
 	config    *Config
 	salt      string
 	generated bool
+
+	// hmacPool holds keyed HMAC hashes, so that hashing many values does not
+	// set up a new HMAC for each
+	hmacPool sync.Pool
 }
 
 type HashFunc func([]byte) []byte
@@ -141,9 +146,16 @@ func (s *Salt) SaltID(id string) string {
 // GetHMAC is used to apply a salt and hash function to data to make sure it is
 // not reversible, with an additional HMAC
 func (s *Salt) GetHMAC(data string) string {
-	hm := hmac.New(s.config.HMAC, []byte(s.salt))
+	hm, ok := s.hmacPool.Get().(hash.Hash)
+	if ok {
+		hm.Reset()
+	} else {
+		hm = hmac.New(s.config.HMAC, []byte(s.salt))
+	}
 	hm.Write([]byte(data))
-	return hex.EncodeToString(hm.Sum(nil))
+	sum := hex.EncodeToString(hm.Sum(nil))
+	s.hmacPool.Put(hm)
+	return sum
 }
 
 // GetIdentifiedHMAC is used to apply a salt and hash function to data to make
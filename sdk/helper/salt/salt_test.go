@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha1"
 	"crypto/sha256"
+	"sync"
 	"testing"
 
 	uuid "github.com/hashicorp/go-uuid"
@@ -86,3 +87,34 @@ func TestSaltID(t *testing.T) {
 		t.Fatalf("mismatch")
 	}
 }
+
+func TestSalt_GetHMAC(t *testing.T) {
+	salt := NewNonpersistentSalt()
+
+	// The pooled HMACs must not carry state between values
+	expected := map[string]string{}
+	for _, v := range []string{"foo", "bar", ""} {
+		expected[v] = HMACValue(salt.salt, v, sha256.New)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for v, hm := range expected {
+					if actual := salt.GetHMAC(v); actual != hm {
+						t.Errorf("bad HMAC of %q: expected %s, got %s", v, hm, actual)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if actual := salt.GetIdentifiedHMAC("foo"); actual != "hmac-sha256:"+expected["foo"] {
+		t.Fatalf("bad identified HMAC: %s", actual)
+	}
+}
@@ -3,6 +3,8 @@ package tokenutil
 import (
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
@@ -44,6 +46,20 @@ type TokenParams struct {
 
 	// The TTL to user for the token
 	TokenTTL time.Duration `json:"token_ttl" mapstructure:"token_ttl"`
+
+	// The countries that tokens generated using this role can be used from
+	TokenAllowedCountries []string `json:"token_allowed_countries,omitempty" mapstructure:"token_allowed_countries"`
+
+	// The countries that tokens generated using this role cannot be used from
+	TokenDeniedCountries []string `json:"token_denied_countries,omitempty" mapstructure:"token_denied_countries"`
+
+	// The autonomous systems that tokens generated using this role can be
+	// used from
+	TokenAllowedASNs []int `json:"token_allowed_asns,omitempty" mapstructure:"token_allowed_asns"`
+
+	// The autonomous systems that tokens generated using this role cannot be
+	// used from
+	TokenDeniedASNs []int `json:"token_denied_asns,omitempty" mapstructure:"token_denied_asns"`
 }
 
 // AddTokenFields adds fields to an existing role. It panics if it would
@@ -152,6 +168,42 @@ func TokenFields() map[string]*framework.FieldSchema {
 				Group: "Tokens",
 			},
 		},
+
+		"token_allowed_countries": {
+			Type:        framework.TypeCommaStringSlice,
+			Description: `Comma separated string or JSON list of ISO 3166-1 alpha-2 country codes. If set, the generated token can only be used from addresses located in these countries. Requires a GeoIP country database to be configured on the server.`,
+			DisplayAttrs: &framework.DisplayAttributes{
+				Name:  "Generated Token's Allowed Countries",
+				Group: "Tokens",
+			},
+		},
+
+		"token_denied_countries": {
+			Type:        framework.TypeCommaStringSlice,
+			Description: `Comma separated string or JSON list of ISO 3166-1 alpha-2 country codes. If set, the generated token cannot be used from addresses located in these countries. Requires a GeoIP country database to be configured on the server.`,
+			DisplayAttrs: &framework.DisplayAttributes{
+				Name:  "Generated Token's Denied Countries",
+				Group: "Tokens",
+			},
+		},
+
+		"token_allowed_asns": {
+			Type:        framework.TypeCommaIntSlice,
+			Description: `Comma separated string or JSON list of autonomous system numbers. If set, the generated token can only be used from addresses announced by these autonomous systems. Requires a GeoIP ASN database to be configured on the server.`,
+			DisplayAttrs: &framework.DisplayAttributes{
+				Name:  "Generated Token's Allowed ASNs",
+				Group: "Tokens",
+			},
+		},
+
+		"token_denied_asns": {
+			Type:        framework.TypeCommaIntSlice,
+			Description: `Comma separated string or JSON list of autonomous system numbers. If set, the generated token cannot be used from addresses announced by these autonomous systems. Requires a GeoIP ASN database to be configured on the server.`,
+			DisplayAttrs: &framework.DisplayAttributes{
+				Name:  "Generated Token's Denied ASNs",
+				Group: "Tokens",
+			},
+		},
 	}
 }
 
@@ -214,6 +266,38 @@ func (t *TokenParams) ParseTokenFields(req *logical.Request, d *framework.FieldD
 		return errors.New("'token_num_uses' cannot be negative")
 	}
 
+	if countriesRaw, ok := d.GetOk("token_allowed_countries"); ok {
+		countries, err := parseCountryCodes("token_allowed_countries", countriesRaw.([]string))
+		if err != nil {
+			return err
+		}
+		t.TokenAllowedCountries = countries
+	}
+
+	if countriesRaw, ok := d.GetOk("token_denied_countries"); ok {
+		countries, err := parseCountryCodes("token_denied_countries", countriesRaw.([]string))
+		if err != nil {
+			return err
+		}
+		t.TokenDeniedCountries = countries
+	}
+
+	if asnsRaw, ok := d.GetOk("token_allowed_asns"); ok {
+		asns, err := parseASNs("token_allowed_asns", asnsRaw.([]int))
+		if err != nil {
+			return err
+		}
+		t.TokenAllowedASNs = asns
+	}
+
+	if asnsRaw, ok := d.GetOk("token_denied_asns"); ok {
+		asns, err := parseASNs("token_denied_asns", asnsRaw.([]int))
+		if err != nil {
+			return err
+		}
+		t.TokenDeniedASNs = asns
+	}
+
 	if t.TokenType == logical.TokenTypeBatch || t.TokenType == logical.TokenTypeDefaultBatch {
 		if t.TokenPeriod != 0 {
 			return errors.New("'token_type' cannot be 'batch' or 'default_batch' when set to generate periodic tokens")
@@ -221,6 +305,9 @@ func (t *TokenParams) ParseTokenFields(req *logical.Request, d *framework.FieldD
 		if t.TokenNumUses != 0 {
 			return errors.New("'token_type' cannot be 'batch' or 'default_batch' when set to generate tokens with limited use count")
 		}
+		if !t.GeoConstraints().Empty() {
+			return errors.New("'token_type' cannot be 'batch' or 'default_batch' when set to generate tokens with country or ASN constraints")
+		}
 	}
 
 	if ttlRaw, ok := d.GetOk("token_ttl"); ok {
@@ -255,6 +342,19 @@ func (t *TokenParams) PopulateTokenData(m map[string]interface{}) {
 	if len(t.TokenBoundCIDRs) == 0 {
 		m["token_bound_cidrs"] = []string{}
 	}
+
+	if len(t.TokenAllowedCountries) > 0 {
+		m["token_allowed_countries"] = t.TokenAllowedCountries
+	}
+	if len(t.TokenDeniedCountries) > 0 {
+		m["token_denied_countries"] = t.TokenDeniedCountries
+	}
+	if len(t.TokenAllowedASNs) > 0 {
+		m["token_allowed_asns"] = t.TokenAllowedASNs
+	}
+	if len(t.TokenDeniedASNs) > 0 {
+		m["token_denied_asns"] = t.TokenDeniedASNs
+	}
 }
 
 // PopulateTokenAuth populates Auth with parameters
@@ -269,6 +369,45 @@ func (t *TokenParams) PopulateTokenAuth(auth *logical.Auth) {
 	auth.TokenType = t.TokenType
 	auth.TTL = t.TokenTTL
 	auth.NumUses = t.TokenNumUses
+	auth.GeoConstraints = t.GeoConstraints()
+}
+
+// GeoConstraints returns the country and ASN constraints of the generated
+// tokens, or nil if there are none.
+func (t *TokenParams) GeoConstraints() *logical.GeoConstraints {
+	g := &logical.GeoConstraints{
+		AllowedCountries: t.TokenAllowedCountries,
+		DeniedCountries:  t.TokenDeniedCountries,
+		AllowedASNs:      t.TokenAllowedASNs,
+		DeniedASNs:       t.TokenDeniedASNs,
+	}
+	if g.Empty() {
+		return nil
+	}
+	return g
+}
+
+func parseCountryCodes(param string, raw []string) ([]string, error) {
+	countries := make([]string, 0, len(raw))
+	for _, country := range raw {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 {
+			return nil, fmt.Errorf("invalid country code %q in '%s'", country, param)
+		}
+		countries = strutil.AppendIfMissing(countries, country)
+	}
+	return countries, nil
+}
+
+func parseASNs(param string, raw []int) ([]int, error) {
+	asns := make([]int, 0, len(raw))
+	for _, asn := range raw {
+		if asn <= 0 || int64(asn) > math.MaxUint32 {
+			return nil, fmt.Errorf("invalid autonomous system number %d in '%s'", asn, param)
+		}
+		asns = append(asns, asn)
+	}
+	return asns, nil
 }
 
 func DeprecationText(param string) string {
@@ -90,6 +90,10 @@ type Auth struct {
 	// The set of CIDRs that this token can be used with
 	BoundCIDRs []*sockaddr.SockAddrMarshaler `json:"bound_cidrs"`
 
+	// GeoConstraints restricts the countries and autonomous systems the
+	// token can be used from
+	GeoConstraints *GeoConstraints `json:"geo_constraints,omitempty"`
+
 	// CreationPath is a path that the backend can return to use in the lease.
 	// This is currently only supported for the token store where roles may
 	// change the perceived path of the lease, even though they don't change
@@ -13,6 +13,14 @@ type Connection struct {
 	// RemotePort is the network port that sent the request.
 	RemotePort int `json:"remote_port"`
 
+	// RemoteCountry is the country of the remote address, as resolved from
+	// the GeoIP country database configured on the server, if any.
+	RemoteCountry string `json:"remote_country,omitempty"`
+
+	// RemoteASN is the autonomous system number of the remote address, as
+	// resolved from the GeoIP ASN database configured on the server, if any.
+	RemoteASN int `json:"remote_asn,omitempty"`
+
 	// ConnState is the TLS connection state if applicable.
 	ConnState *tls.ConnectionState `sentinel:""`
 }
@@ -177,6 +177,11 @@ type Request struct {
 	// X-Vault-MFA header
 	MFACreds MFACreds `json:"mfa_creds" structs:"mfa_creds" mapstructure:"mfa_creds" sentinel:""`
 
+	// IdempotencyKey is supplied over the API as part of the
+	// X-Vault-Idempotency-Key header. Retried write requests with the same key
+	// return the response of the original request.
+	IdempotencyKey string `json:"idempotency_key" structs:"idempotency_key" mapstructure:"idempotency_key" sentinel:""`
+
 	// Cached token entry. This avoids another lookup in request handling when
 	// we've already looked it up at http handling time. Note that this token
 	// has not been "used", as in it will not properly take into account use
@@ -43,6 +43,18 @@ const (
 	// The value must be a string.
 	HTTPPragmaHeader = "http_raw_pragma"
 
+	// If set, HTTPETagHeader will set the ETag response header.
+	// The value must be a string.
+	HTTPETagHeader = "http_raw_etag"
+
+	// If set, HTTPExpiresHeader will set the Expires response header.
+	// The value must be a string.
+	HTTPExpiresHeader = "http_raw_expires"
+
+	// If set, HTTPLastModifiedHeader will set the Last-Modified response header.
+	// The value must be a string.
+	HTTPLastModifiedHeader = "http_raw_last_modified"
+
 	// If set, HTTPWWWAuthenticateHeader will set the WWW-Authenticate response header.
 	// The value must be a string.
 	HTTPWWWAuthenticateHeader = "http_www_authenticate"
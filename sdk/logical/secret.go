@@ -15,6 +15,11 @@ type Secret struct {
 	// This is generated by Vault core. Any set value will be ignored.
 	// For requests, this will always be blank.
 	LeaseID string `sentinel:""`
+
+	// Tags are searchable key/value pairs attached to the lease of the
+	// secret, such as the role or application it was issued for. They are
+	// only read when the lease is created, and can't be changed afterwards.
+	Tags map[string]string `json:"tags,omitempty" sentinel:""`
 }
 
 func (s *Secret) Validate() error {
@@ -22,6 +27,12 @@ func (s *Secret) Validate() error {
 		return fmt.Errorf("ttl duration must not be less than zero")
 	}
 
+	for key := range s.Tags {
+		if key == "" {
+			return fmt.Errorf("lease tag keys must not be empty")
+		}
+	}
+
 	return nil
 }
 
@@ -98,6 +98,15 @@ type ExtendedSystemView interface {
 	ForwardGenericRequest(context.Context, *Request) (*Response, error)
 }
 
+// PKIIssuersReader is implemented by the system views of builtin backends. It
+// allows them to read the certificates of the issuers of the PKI mounts of
+// their namespace, and nothing else of these mounts.
+type PKIIssuersReader interface {
+	// PKIIssuers returns the PEM encoded certificates of the issuers of the
+	// PKI mount at the given path.
+	PKIIssuers(ctx context.Context, mount string) ([]string, error)
+}
+
 type PasswordGenerator func() (password string, err error)
 
 type StaticSystemView struct {
@@ -163,6 +163,14 @@ type TokenEntry struct {
 	// The set of CIDRs that this token can be used with
 	BoundCIDRs []*sockaddr.SockAddrMarshaler `json:"bound_cidrs" sentinel:""`
 
+	// GeoConstraints restricts the countries and autonomous systems this
+	// token can be used from
+	GeoConstraints *GeoConstraints `json:"geo_constraints,omitempty" sentinel:""`
+
+	// BoundClientCertFingerprint is the hex encoded SHA-256 fingerprint of
+	// the TLS client certificate this token can only be used with
+	BoundClientCertFingerprint string `json:"bound_client_cert_fingerprint,omitempty" sentinel:""`
+
 	// NamespaceID is the identifier of the namespace to which this token is
 	// confined to. Do not return this value over the API when the token is
 	// being looked up.
@@ -173,6 +181,87 @@ type TokenEntry struct {
 	CubbyholeID string `json:"cubbyhole_id" mapstructure:"cubbyhole_id" structs:"cubbyhole_id" sentinel:""`
 }
 
+// GeoConstraints restricts the use of a token to clients whose address
+// resolves, using the GeoIP databases configured on the server, to an
+// allowed country and autonomous system.
+type GeoConstraints struct {
+	// AllowedCountries are the ISO 3166-1 alpha-2 codes of the countries the
+	// token can be used from. If empty, all countries not denied are allowed.
+	AllowedCountries []string `json:"allowed_countries,omitempty"`
+
+	// DeniedCountries are the ISO 3166-1 alpha-2 codes of the countries the
+	// token cannot be used from.
+	DeniedCountries []string `json:"denied_countries,omitempty"`
+
+	// AllowedASNs are the autonomous system numbers the token can be used
+	// from. If empty, all autonomous systems not denied are allowed.
+	AllowedASNs []int `json:"allowed_asns,omitempty"`
+
+	// DeniedASNs are the autonomous system numbers the token cannot be used
+	// from.
+	DeniedASNs []int `json:"denied_asns,omitempty"`
+}
+
+// Empty returns true if no constraint is set.
+func (g *GeoConstraints) Empty() bool {
+	return g == nil || (len(g.AllowedCountries) == 0 && len(g.DeniedCountries) == 0 &&
+		len(g.AllowedASNs) == 0 && len(g.DeniedASNs) == 0)
+}
+
+// HasCountryConstraints returns true if countries are allowed or denied.
+func (g *GeoConstraints) HasCountryConstraints() bool {
+	return g != nil && (len(g.AllowedCountries) > 0 || len(g.DeniedCountries) > 0)
+}
+
+// HasASNConstraints returns true if autonomous systems are allowed or denied.
+func (g *GeoConstraints) HasASNConstraints() bool {
+	return g != nil && (len(g.AllowedASNs) > 0 || len(g.DeniedASNs) > 0)
+}
+
+// Allows returns true if a client in the given country and autonomous system
+// satisfies the constraints. Unknown values are represented by an empty
+// country and an ASN of zero, and never satisfy an allow list.
+func (g *GeoConstraints) Allows(country string, asn int) bool {
+	if g == nil {
+		return true
+	}
+	for _, c := range g.DeniedCountries {
+		if strings.EqualFold(c, country) {
+			return false
+		}
+	}
+	for _, a := range g.DeniedASNs {
+		if a == asn {
+			return false
+		}
+	}
+	if len(g.AllowedCountries) > 0 {
+		var found bool
+		for _, c := range g.AllowedCountries {
+			if country != "" && strings.EqualFold(c, country) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(g.AllowedASNs) > 0 {
+		var found bool
+		for _, a := range g.AllowedASNs {
+			if asn != 0 && a == asn {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // CreateClientID returns the client ID, and a boolean which is false if the clientID
 // has an entity, and true otherwise
 func (te *TokenEntry) CreateClientID() (string, bool) {
@@ -101,3 +101,45 @@ func TestCreateClientID(t *testing.T) {
 		t.Fatalf("wrong ID: expected %s, found %s", expectedID, id)
 	}
 }
+
+func TestGeoConstraints_Allows(t *testing.T) {
+	var nilConstraints *GeoConstraints
+	if !nilConstraints.Allows("", 0) || !nilConstraints.Empty() {
+		t.Fatal("expected nil constraints to allow everything")
+	}
+
+	g := &GeoConstraints{
+		AllowedCountries: []string{"FR", "DE"},
+		DeniedASNs:       []int{64501},
+	}
+	cases := []struct {
+		country  string
+		asn      int
+		expected bool
+	}{
+		{"FR", 64500, true},
+		{"de", 0, true},
+		{"FR", 64501, false},
+		{"US", 64500, false},
+		{"", 64500, false},
+	}
+	for _, tc := range cases {
+		if actual := g.Allows(tc.country, tc.asn); actual != tc.expected {
+			t.Fatalf("%s/%d: expected %t, got %t", tc.country, tc.asn, tc.expected, actual)
+		}
+	}
+
+	g = &GeoConstraints{
+		DeniedCountries: []string{"US"},
+		AllowedASNs:     []int{64500},
+	}
+	if !g.Allows("", 64500) {
+		t.Fatal("expected unknown country to be allowed")
+	}
+	if g.Allows("FR", 0) {
+		t.Fatal("expected unknown ASN to be denied")
+	}
+	if g.Allows("US", 64500) {
+		t.Fatal("expected denied country to be denied")
+	}
+}
@@ -6,7 +6,6 @@ import (
 
 	metrics "github.com/armon/go-metrics"
 	log "github.com/hashicorp/go-hclog"
-	lru "github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/helper/pathmanager"
 )
@@ -31,6 +30,33 @@ var cacheExceptionsPaths = []string{
 	"core/raft/tls",
 }
 
+// DefaultCachePinnedPrefixes are the prefixes of the entries evicted only
+// once no other entry is left, unless others are configured: the mount
+// tables, and the identity store buckets, which are read on most requests.
+// A "+" segment matches any segment.
+var DefaultCachePinnedPrefixes = []string{
+	"core/mounts",
+	"core/local-mounts",
+	"core/auth",
+	"core/local-auth",
+	"logical/+/packer/",
+}
+
+// CacheConfig configures a Cache
+type CacheConfig struct {
+	// Size is the maximum number of cached entries, DefaultCacheSize if
+	// zero.
+	Size int
+
+	// SizeBytes is the maximum size of the cached entries. The size is not
+	// bounded if zero.
+	SizeBytes int64
+
+	// PinnedPrefixes are the prefixes of the entries evicted last,
+	// DefaultCachePinnedPrefixes if nil.
+	PinnedPrefixes []string
+}
+
 // CacheRefreshContext returns a context with an added value denoting if the
 // cache should attempt a refresh.
 func CacheRefreshContext(ctx context.Context, r bool) context.Context {
@@ -53,7 +79,7 @@ func cacheRefreshFromContext(ctx context.Context) bool {
 // by using a simple write-through cache.
 type Cache struct {
 	backend         Backend
-	lru             *lru.TwoQueueCache
+	lru             *shardedLRU
 	locks           []*locksutil.LockEntry
 	logger          log.Logger
 	enabled         *uint32
@@ -78,20 +104,33 @@ var (
 // NewCache returns a physical cache of the given size.
 // If no size is provided, the default size is used.
 func NewCache(b Backend, size int, logger log.Logger, metricSink metrics.MetricSink) *Cache {
-	if logger.IsDebug() {
-		logger.Debug("creating LRU cache", "size", size)
-	}
+	return NewCacheWithConfig(b, &CacheConfig{Size: size}, logger, metricSink)
+}
+
+// NewCacheWithConfig returns a physical cache bounded both in number of
+// entries and in size, so that large entries do not evict many small ones.
+func NewCacheWithConfig(b Backend, conf *CacheConfig, logger log.Logger, metricSink metrics.MetricSink) *Cache {
+	size := conf.Size
 	if size <= 0 {
 		size = DefaultCacheSize
 	}
+	sizeBytes := conf.SizeBytes
+	if sizeBytes < 0 {
+		sizeBytes = 0
+	}
+	pinned := conf.PinnedPrefixes
+	if pinned == nil {
+		pinned = DefaultCachePinnedPrefixes
+	}
+	if logger.IsDebug() {
+		logger.Debug("creating LRU cache", "size", size, "size_bytes", sizeBytes)
+	}
 
 	pm := pathmanager.New()
 	pm.AddPaths(cacheExceptionsPaths)
 
-	cache, _ := lru.New2Q(size)
 	c := &Cache{
 		backend: b,
-		lru:     cache,
 		locks:   locksutil.CreateLocks(),
 		logger:  logger,
 		// This fails safe.
@@ -99,12 +138,27 @@ func NewCache(b Backend, size int, logger log.Logger, metricSink metrics.MetricS
 		cacheExceptions: pm,
 		metricSink:      metricSink,
 	}
+	c.lru = newShardedLRU(size, sizeBytes, pinned, func(evicted int) {
+		c.metricSink.IncrCounter([]string{"cache", "evict"}, float32(evicted))
+	})
 	return c
 }
 
+// LRU returns the entries held by the cache.
+//
+// Deprecated: the cache is no longer a *lru.TwoQueueCache; the returned
+// CacheLRU only mirrors its methods for existing callers.
+func (c *Cache) LRU() *CacheLRU {
+	return &CacheLRU{lru: c.lru}
+}
+
 func NewTransactionalCache(b Backend, size int, logger log.Logger, metricSink metrics.MetricSink) *TransactionalCache {
+	return NewTransactionalCacheWithConfig(b, &CacheConfig{Size: size}, logger, metricSink)
+}
+
+func NewTransactionalCacheWithConfig(b Backend, conf *CacheConfig, logger log.Logger, metricSink metrics.MetricSink) *TransactionalCache {
 	c := &TransactionalCache{
-		Cache:         NewCache(b, size, logger, metricSink),
+		Cache:         NewCacheWithConfig(b, conf, logger, metricSink),
 		Transactional: b.(Transactional),
 	}
 	return c
@@ -139,6 +193,15 @@ func (c *Cache) Purge(ctx context.Context) {
 	c.lru.Purge()
 }
 
+// Invalidate drops key from the cache, when it was changed by another node
+func (c *Cache) Invalidate(key string) {
+	lock := locksutil.LockForKey(c.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	c.lru.Remove(key)
+}
+
 func (c *Cache) Put(ctx context.Context, entry *Entry) error {
 	if entry != nil && !c.ShouldCache(entry.Key) {
 		return c.backend.Put(ctx, entry)
@@ -167,12 +230,12 @@ func (c *Cache) Get(ctx context.Context, key string) (*Entry, error) {
 
 	// Check the LRU first
 	if !cacheRefreshFromContext(ctx) {
-		if raw, ok := c.lru.Get(key); ok {
-			if raw == nil {
+		if ent, ok := c.lru.Get(key); ok {
+			if ent == nil {
 				return nil, nil
 			}
 			c.metricSink.IncrCounter([]string{"cache", "hit"}, 1)
-			return raw.(*Entry), nil
+			return ent, nil
 		}
 	}
 
@@ -216,10 +279,6 @@ func (c *TransactionalCache) Locks() []*locksutil.LockEntry {
 	return c.locks
 }
 
-func (c *TransactionalCache) LRU() *lru.TwoQueueCache {
-	return c.lru
-}
-
 func (c *TransactionalCache) Transaction(ctx context.Context, txns []*TxnEntry) error {
 	// Bypass the locking below
 	if atomic.LoadUint32(c.enabled) == 0 {
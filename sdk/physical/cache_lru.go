@@ -0,0 +1,388 @@
+package physical
+
+import (
+	"container/list"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+)
+
+// cacheShardCount is the number of independently locked shards of the cache
+const cacheShardCount = 16
+
+// cacheEntryOverhead approximates the memory used by a cached entry besides
+// its key and value
+const cacheEntryOverhead = 64
+
+// cacheRecentRatio is the share of the entries of a shard kept for entries
+// read only once, as in the 2Q cache the sharded cache replaced, so that a
+// scan of many entries read once does not evict the frequently read ones.
+const cacheRecentRatio = 0.25
+
+// shardedLRU is an LRU cache split into shards, each bounded by a number of
+// entries and optionally by a number of bytes. As in a 2Q cache, entries
+// start in a recent segment and move to a frequent segment when read again;
+// the recent segment is evicted first while it holds more than its share of
+// the shard. Entries under pinned prefixes are only evicted once no other
+// entry of their shard is left.
+type shardedLRU struct {
+	shards [cacheShardCount]*lruShard
+	pinned []string
+
+	// onEvict is called with the number of entries evicted to make room
+	onEvict func(int)
+}
+
+type lruShard struct {
+	l          sync.Mutex
+	items      map[string]*list.Element
+	recent     *list.List
+	frequent   *list.List
+	pinned     *list.List
+	size       int64
+	maxItems   int
+	maxRecent  int
+	maxSize    int64
+	recentSize int64
+}
+
+type lruSegment int
+
+const (
+	segmentRecent lruSegment = iota
+	segmentFrequent
+	segmentPinned
+)
+
+type lruItem struct {
+	key     string
+	entry   *Entry
+	size    int64
+	segment lruSegment
+}
+
+// newShardedLRU returns a cache of at most maxItems entries, and of at most
+// maxSize bytes if maxSize is positive.
+func newShardedLRU(maxItems int, maxSize int64, pinned []string, onEvict func(int)) *shardedLRU {
+	c := &shardedLRU{
+		pinned:  pinned,
+		onEvict: onEvict,
+	}
+
+	shardItems := maxItems / cacheShardCount
+	if shardItems < 1 {
+		shardItems = 1
+	}
+	shardSize := int64(math.MaxInt64)
+	if maxSize > 0 {
+		shardSize = maxSize / cacheShardCount
+	}
+	for i := range c.shards {
+		c.shards[i] = &lruShard{
+			items:     make(map[string]*list.Element),
+			recent:    list.New(),
+			frequent:  list.New(),
+			pinned:    list.New(),
+			maxItems:  shardItems,
+			maxRecent: int(float64(shardItems) * cacheRecentRatio),
+			maxSize:   shardSize,
+		}
+	}
+	return c
+}
+
+func (c *shardedLRU) shard(key string) *lruShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()%cacheShardCount]
+}
+
+func (c *shardedLRU) isPinned(key string) bool {
+	for _, pattern := range c.pinned {
+		if matchCachePrefix(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCachePrefix reports whether key starts with pattern, where a "+"
+// segment of pattern matches any single segment of key.
+func matchCachePrefix(pattern, key string) bool {
+	if !strings.Contains(pattern, "+") {
+		return strings.HasPrefix(key, pattern)
+	}
+
+	patternParts := strings.Split(pattern, "/")
+	keyParts := strings.Split(key, "/")
+	if len(keyParts) < len(patternParts) {
+		return false
+	}
+	last := len(patternParts) - 1
+	for i, part := range patternParts {
+		switch {
+		case part == "+":
+		case i == last:
+			if !strings.HasPrefix(keyParts[i], part) {
+				return false
+			}
+		case part != keyParts[i]:
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the cached entry of key, which may be nil when the absence of
+// the entry is cached. Entries of the recent segment move to the frequent
+// one.
+func (c *shardedLRU) Get(key string) (*Entry, bool) {
+	s := c.shard(key)
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := elem.Value.(*lruItem)
+	switch item.segment {
+	case segmentRecent:
+		s.recent.Remove(elem)
+		s.recentSize -= item.size
+		item.segment = segmentFrequent
+		s.items[key] = s.frequent.PushFront(item)
+	default:
+		s.list(item.segment).MoveToFront(elem)
+	}
+	return item.entry, true
+}
+
+// Peek returns the cached entry of key without updating its recency.
+func (c *shardedLRU) Peek(key string) (*Entry, bool) {
+	s := c.shard(key)
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*lruItem).entry, true
+}
+
+// Add caches entry for key, evicting the least recently used entries of its
+// shard as needed. Entries too large for the shard are not cached.
+func (c *shardedLRU) Add(key string, entry *Entry) {
+	size := int64(len(key) + cacheEntryOverhead)
+	if entry != nil {
+		size += int64(len(entry.Value))
+	}
+
+	s := c.shard(key)
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	// Updated entries keep their segment, new ones start as recent
+	segment := segmentRecent
+	if elem, ok := s.items[key]; ok {
+		segment = elem.Value.(*lruItem).segment
+		s.remove(key)
+	}
+	if size > s.maxSize {
+		return
+	}
+	if c.isPinned(key) {
+		segment = segmentPinned
+	}
+
+	item := &lruItem{
+		key:     key,
+		entry:   entry,
+		size:    size,
+		segment: segment,
+	}
+	s.items[key] = s.list(segment).PushFront(item)
+	s.size += size
+	if segment == segmentRecent {
+		s.recentSize += size
+	}
+
+	evicted := 0
+	for len(s.items) > s.maxItems || s.size > s.maxSize {
+		s.remove(s.oldest().Value.(*lruItem).key)
+		evicted++
+	}
+	if evicted > 0 && c.onEvict != nil {
+		c.onEvict(evicted)
+	}
+}
+
+// Remove drops key from the cache
+func (c *shardedLRU) Remove(key string) {
+	s := c.shard(key)
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.remove(key)
+}
+
+// Purge drops all the entries of the cache
+func (c *shardedLRU) Purge() {
+	for _, s := range c.shards {
+		s.l.Lock()
+		s.items = make(map[string]*list.Element)
+		s.recent.Init()
+		s.frequent.Init()
+		s.pinned.Init()
+		s.size = 0
+		s.recentSize = 0
+		s.l.Unlock()
+	}
+}
+
+// Len returns the number of cached entries and their approximate size
+func (c *shardedLRU) Len() (int, int64) {
+	var items int
+	var size int64
+	for _, s := range c.shards {
+		s.l.Lock()
+		items += len(s.items)
+		size += s.size
+		s.l.Unlock()
+	}
+	return items, size
+}
+
+// Keys returns the cached keys of each shard, from the least recently used
+func (c *shardedLRU) Keys() []string {
+	var keys []string
+	for _, s := range c.shards {
+		s.l.Lock()
+		for _, l := range []*list.List{s.pinned, s.frequent, s.recent} {
+			for elem := l.Back(); elem != nil; elem = elem.Prev() {
+				keys = append(keys, elem.Value.(*lruItem).key)
+			}
+		}
+		s.l.Unlock()
+	}
+	return keys
+}
+
+func (s *lruShard) list(segment lruSegment) *list.List {
+	switch segment {
+	case segmentRecent:
+		return s.recent
+	case segmentFrequent:
+		return s.frequent
+	default:
+		return s.pinned
+	}
+}
+
+// oldest returns the next entry to evict: the least recently used recent
+// entry while the recent segment holds more than its share of the shard,
+// else the least recently used frequent one, and pinned entries last.
+func (s *lruShard) oldest() *list.Element {
+	recentShareExceeded := s.recent.Len() > s.maxRecent ||
+		(s.maxSize != math.MaxInt64 && float64(s.recentSize) > float64(s.maxSize)*cacheRecentRatio)
+	if s.recent.Len() > 0 && (recentShareExceeded || s.frequent.Len() == 0) {
+		return s.recent.Back()
+	}
+	if s.frequent.Len() > 0 {
+		return s.frequent.Back()
+	}
+	if s.recent.Len() > 0 {
+		return s.recent.Back()
+	}
+	return s.pinned.Back()
+}
+
+func (s *lruShard) remove(key string) {
+	elem, ok := s.items[key]
+	if !ok {
+		return
+	}
+	item := elem.Value.(*lruItem)
+	s.list(item.segment).Remove(elem)
+	delete(s.items, key)
+	s.size -= item.size
+	if item.segment == segmentRecent {
+		s.recentSize -= item.size
+	}
+}
+
+// CacheLRU gives access to the entries held by a Cache. Its methods mirror
+// the ones of the 2Q cache the Cache used before it was sharded.
+type CacheLRU struct {
+	lru *shardedLRU
+}
+
+// Get returns the cached *Entry of key, which is nil when the absence of
+// the entry is cached.
+func (c *CacheLRU) Get(key interface{}) (interface{}, bool) {
+	k, ok := key.(string)
+	if !ok {
+		return nil, false
+	}
+	return c.lru.Get(k)
+}
+
+// Peek returns the cached *Entry of key without updating its recency.
+func (c *CacheLRU) Peek(key interface{}) (interface{}, bool) {
+	k, ok := key.(string)
+	if !ok {
+		return nil, false
+	}
+	return c.lru.Peek(k)
+}
+
+// Contains returns whether key is cached, without updating its recency.
+func (c *CacheLRU) Contains(key interface{}) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Add caches value, which must be a string key and a *Entry or nil value.
+// Other keys and values are ignored.
+func (c *CacheLRU) Add(key, value interface{}) {
+	k, ok := key.(string)
+	if !ok {
+		return
+	}
+	switch v := value.(type) {
+	case *Entry:
+		c.lru.Add(k, v)
+	case nil:
+		c.lru.Add(k, nil)
+	}
+}
+
+// Remove drops key from the cache
+func (c *CacheLRU) Remove(key interface{}) {
+	if k, ok := key.(string); ok {
+		c.lru.Remove(k)
+	}
+}
+
+// Keys returns the cached keys
+func (c *CacheLRU) Keys() []interface{} {
+	keys := c.lru.Keys()
+	out := make([]interface{}, len(keys))
+	for i, key := range keys {
+		out[i] = key
+	}
+	return out
+}
+
+// Len returns the number of cached entries
+func (c *CacheLRU) Len() int {
+	items, _ := c.lru.Len()
+	return items
+}
+
+// Purge drops all the entries of the cache
+func (c *CacheLRU) Purge() {
+	c.lru.Purge()
+}
@@ -2,6 +2,7 @@ package inmem
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/armon/go-metrics"
@@ -328,3 +329,133 @@ func TestCache_Refresh(t *testing.T) {
 		t.Fatalf("expected value baz, got %s", string(r.Value))
 	}
 }
+
+func TestCache_SizeBytes(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each of the 16 shards holds up to 1KiB
+	cache := physical.NewCacheWithConfig(inm, &physical.CacheConfig{SizeBytes: 16 * 1024}, logger, &metrics.BlackholeSink{})
+	cache.SetEnabled(true)
+	ctx := context.Background()
+
+	pinned := []string{"core/mounts", "logical/1234/packer/buckets/7"}
+	for _, key := range pinned {
+		if err := cache.Put(ctx, &physical.Entry{Key: key, Value: []byte("pinned")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Entries larger than a shard are not cached
+	if err := cache.Put(ctx, &physical.Entry{Key: "large", Value: make([]byte, 2048)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fill the cache with many large values
+	for i := 0; i < 512; i++ {
+		if err := cache.Put(ctx, &physical.Entry{Key: fmt.Sprintf("kv/%d", i), Value: make([]byte, 512)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Delete from under
+	for _, key := range append(pinned, "large", "kv/0") {
+		if err := inm.Delete(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range pinned {
+		out, err := cache.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out == nil {
+			t.Fatalf("pinned key %q should have been kept in the cache", key)
+		}
+	}
+	for _, key := range []string{"large", "kv/0"} {
+		out, err := cache.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != nil {
+			t.Fatalf("key %q should not be cached", key)
+		}
+	}
+}
+
+func TestCache_ScanResistance(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each of the 16 shards holds up to 64 entries
+	cache := physical.NewCacheWithConfig(inm, &physical.CacheConfig{Size: 1024, PinnedPrefixes: []string{}}, logger, &metrics.BlackholeSink{})
+	cache.SetEnabled(true)
+	ctx := context.Background()
+
+	// Read the hot entries twice so that they are frequently used
+	var hot []string
+	for i := 0; i < 128; i++ {
+		key := fmt.Sprintf("hot/%d", i)
+		hot = append(hot, key)
+		if err := cache.Put(ctx, &physical.Entry{Key: key, Value: []byte("hot")}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cache.Get(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Scan many more entries than the cache holds
+	for i := 0; i < 4096; i++ {
+		if err := cache.Put(ctx, &physical.Entry{Key: fmt.Sprintf("scan/%d", i), Value: []byte("scan")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range hot {
+		if !cache.LRU().Contains(key) {
+			t.Fatalf("frequently read key %q should have been kept in the cache", key)
+		}
+	}
+	if n := cache.LRU().Len(); n > 1024 {
+		t.Fatalf("expected at most 1024 cached entries, got %d", n)
+	}
+}
+
+func TestCache_PinnedPrefixes(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := physical.NewCacheWithConfig(inm, &physical.CacheConfig{Size: 16, PinnedPrefixes: []string{"sys/+/config"}}, logger, &metrics.BlackholeSink{})
+	cache.SetEnabled(true)
+	ctx := context.Background()
+
+	for _, key := range []string{"sys/a/config", "core/mounts"} {
+		if err := cache.Put(ctx, &physical.Entry{Key: key, Value: []byte("value")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 256; i++ {
+		if err := cache.Put(ctx, &physical.Entry{Key: fmt.Sprintf("kv/%d", i), Value: []byte("value")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !cache.LRU().Contains("sys/a/config") {
+		t.Fatal("configured pinned key should have been kept in the cache")
+	}
+	if cache.LRU().Contains("core/mounts") {
+		t.Fatal("default pinned key should have been evicted once other prefixes are configured")
+	}
+}
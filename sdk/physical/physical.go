@@ -65,6 +65,15 @@ type ToggleablePurgemonster interface {
 	SetEnabled(bool)
 }
 
+// Invalidator is an optional interface for backends that are notified of the
+// changes made by other nodes, so that they can be dropped from the cache.
+type Invalidator interface {
+	// SetInvalidationHandler registers the function called with the key of
+	// each change. It is called with an empty key when changes may have been
+	// missed.
+	SetInvalidationHandler(func(key string))
+}
+
 // RedirectDetect is an optional interface that an HABackend
 // can implement. If they do, a redirect address can be automatically
 // detected.
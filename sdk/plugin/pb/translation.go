@@ -536,6 +536,7 @@ func LogicalAuthToProtoAuth(a *logical.Auth) (*Auth, error) {
 		Alias:            a.Alias,
 		GroupAliases:     a.GroupAliases,
 		BoundCIDRs:       boundCIDRs,
+		GeoConstraints:   LogicalGeoConstraintsToProtoGeoConstraints(a.GeoConstraints),
 		ExplicitMaxTTL:   int64(a.ExplicitMaxTTL),
 	}, nil
 }
@@ -584,10 +585,54 @@ func ProtoAuthToLogicalAuth(a *Auth) (*logical.Auth, error) {
 		Alias:            a.Alias,
 		GroupAliases:     a.GroupAliases,
 		BoundCIDRs:       boundCIDRs,
+		GeoConstraints:   ProtoGeoConstraintsToLogicalGeoConstraints(a.GeoConstraints),
 		ExplicitMaxTTL:   time.Duration(a.ExplicitMaxTTL),
 	}, nil
 }
 
+func LogicalGeoConstraintsToProtoGeoConstraints(g *logical.GeoConstraints) *GeoConstraints {
+	if g == nil {
+		return nil
+	}
+
+	allowedASNs := make([]int64, len(g.AllowedASNs))
+	for i, asn := range g.AllowedASNs {
+		allowedASNs[i] = int64(asn)
+	}
+	deniedASNs := make([]int64, len(g.DeniedASNs))
+	for i, asn := range g.DeniedASNs {
+		deniedASNs[i] = int64(asn)
+	}
+
+	return &GeoConstraints{
+		AllowedCountries: g.AllowedCountries,
+		DeniedCountries:  g.DeniedCountries,
+		AllowedAsns:      allowedASNs,
+		DeniedAsns:       deniedASNs,
+	}
+}
+
+func ProtoGeoConstraintsToLogicalGeoConstraints(g *GeoConstraints) *logical.GeoConstraints {
+	if g == nil {
+		return nil
+	}
+
+	var allowedASNs, deniedASNs []int
+	for _, asn := range g.AllowedAsns {
+		allowedASNs = append(allowedASNs, int(asn))
+	}
+	for _, asn := range g.DeniedAsns {
+		deniedASNs = append(deniedASNs, int(asn))
+	}
+
+	return &logical.GeoConstraints{
+		AllowedCountries: g.AllowedCountries,
+		DeniedCountries:  g.DeniedCountries,
+		AllowedASNs:      allowedASNs,
+		DeniedASNs:       deniedASNs,
+	}
+}
+
 func LogicalTokenEntryToProtoTokenEntry(t *logical.TokenEntry) *TokenEntry {
 	if t == nil {
 		return nil
@@ -620,6 +665,7 @@ func LogicalTokenEntryToProtoTokenEntry(t *logical.TokenEntry) *TokenEntry {
 		NamespaceID:        t.NamespaceID,
 		CubbyholeID:        t.CubbyholeID,
 		Type:               uint32(t.Type),
+		GeoConstraints:     LogicalGeoConstraintsToProtoGeoConstraints(t.GeoConstraints),
 	}
 }
 
@@ -660,6 +706,7 @@ func ProtoTokenEntryToLogicalTokenEntry(t *TokenEntry) (*logical.TokenEntry, err
 		NamespaceID:        t.NamespaceID,
 		CubbyholeID:        t.CubbyholeID,
 		Type:               logical.TokenType(t.Type),
+		GeoConstraints:     ProtoGeoConstraintsToLogicalGeoConstraints(t.GeoConstraints),
 	}, nil
 }
 
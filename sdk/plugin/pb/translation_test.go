@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/errutil"
 	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestTranslation_Errors(t *testing.T) {
@@ -281,6 +282,63 @@ func TestTranslation_Response(t *testing.T) {
 	}
 }
 
+func TestTranslation_GeoConstraints(t *testing.T) {
+	geo := &logical.GeoConstraints{
+		AllowedCountries: []string{"DE", "FR"},
+		DeniedCountries:  []string{"RU"},
+		AllowedASNs:      []int{3320, 4200000000},
+		DeniedASNs:       []int{64512},
+	}
+
+	auth := &logical.Auth{
+		InternalData:   map[string]interface{}{},
+		GeoConstraints: geo,
+	}
+	p, err := LogicalAuthToProtoAuth(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := proto.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Auth
+	if err := proto.Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	a, err := ProtoAuthToLogicalAuth(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(geo, a.GeoConstraints) {
+		t.Fatalf("auth geo constraints did not match: \n%#v, \n%#v", geo, a.GeoConstraints)
+	}
+
+	te, err := ProtoTokenEntryToLogicalTokenEntry(LogicalTokenEntryToProtoTokenEntry(&logical.TokenEntry{
+		ID:             "id",
+		GeoConstraints: geo,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(geo, te.GeoConstraints) {
+		t.Fatalf("token entry geo constraints did not match: \n%#v, \n%#v", geo, te.GeoConstraints)
+	}
+
+	// Auths without constraints are left without them
+	p, err = LogicalAuthToProtoAuth(&logical.Auth{InternalData: map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err = ProtoAuthToLogicalAuth(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.GeoConstraints != nil {
+		t.Fatalf("expected no geo constraints, got %#v", a.GeoConstraints)
+	}
+}
+
 // This is the contents of $GOROOT/src/crypto/tls/testdata/example-cert.pem
 // If it's good enough for testing the crypto/tls package it's good enough
 // for Vault.
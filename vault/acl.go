@@ -32,6 +32,10 @@ type ACL struct {
 
 	// Stores policies that are actually RGPs for later fetching
 	rgpPolicies []*Policy
+
+	// scope, if set, further restricts the operations allowed by the ACL to
+	// those it allows as well
+	scope *ACL
 }
 
 type PolicyCheckOpts struct {
@@ -328,8 +332,47 @@ func (a *ACL) Capabilities(ctx context.Context, path string) (pathCapabilities [
 	return
 }
 
+// setScope restricts the ACL to the operations that are allowed by the given
+// policy as well.
+func (a *ACL) setScope(ctx context.Context, policy *Policy) error {
+	scope, err := NewACL(ctx, []*Policy{policy})
+	if err != nil {
+		return err
+	}
+	a.scope = scope
+	return nil
+}
+
 // AllowOperation is used to check if the given operation is permitted.
-func (a *ACL) AllowOperation(ctx context.Context, req *logical.Request, capCheckOnly bool) (ret *ACLResults) {
+func (a *ACL) AllowOperation(ctx context.Context, req *logical.Request, capCheckOnly bool) *ACLResults {
+	ret := a.allowOperation(ctx, req, capCheckOnly)
+	if a.scope == nil {
+		return ret
+	}
+
+	// The operation must be allowed by both the ACL and its scope
+	scoped := a.scope.allowOperation(ctx, req, capCheckOnly)
+	switch {
+	case ret.IsRoot:
+		ret.CapabilitiesBitmap = scoped.CapabilitiesBitmap
+	case (ret.CapabilitiesBitmap|scoped.CapabilitiesBitmap)&DenyCapabilityInt > 0:
+		ret.CapabilitiesBitmap = DenyCapabilityInt
+	default:
+		ret.CapabilitiesBitmap &= scoped.CapabilitiesBitmap
+	}
+	ret.Allowed = ret.Allowed && scoped.Allowed
+	ret.RootPrivs = ret.RootPrivs && scoped.RootPrivs
+	ret.IsRoot = false
+	if len(scoped.MFAMethods) > 0 {
+		ret.MFAMethods = strutil.RemoveDuplicates(append(ret.MFAMethods, scoped.MFAMethods...), false)
+	}
+	if ret.ControlGroup == nil {
+		ret.ControlGroup = scoped.ControlGroup
+	}
+	return ret
+}
+
+func (a *ACL) allowOperation(ctx context.Context, req *logical.Request, capCheckOnly bool) (ret *ACLResults) {
 	ret = new(ACLResults)
 
 	// Fast-path root
@@ -68,6 +68,11 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 		return fmt.Errorf("backend path must be specified")
 	}
 
+	filter, err := auditFilter(entry)
+	if err != nil {
+		return err
+	}
+
 	// Update the audit table
 	c.auditLock.Lock()
 	defer c.auditLock.Unlock()
@@ -152,7 +157,7 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	c.audit = newTable
 
 	// Register the backend
-	c.auditBroker.Register(entry.Path, backend, view, entry.Local)
+	c.auditBroker.Register(entry.Path, backend, view, entry.Local, filter)
 	if c.logger.IsInfo() {
 		c.logger.Info("enabled audit backend", "path", entry.Path, "type", entry.Type)
 	}
@@ -374,6 +379,16 @@ func (c *Core) persistAudit(ctx context.Context, table *MountTable, localOnly bo
 	return nil
 }
 
+// auditFilter parses the filter option of the audit device. It returns nil
+// if the device has no filter.
+func auditFilter(entry *MountEntry) (*audit.Filter, error) {
+	expr := strings.TrimSpace(entry.Options["filter"])
+	if expr == "" {
+		return nil, nil
+	}
+	return audit.NewFilter(expr)
+}
+
 // setupAudit is invoked after we've loaded the audit able to
 // initialize the audit backends
 func (c *Core) setupAudits(ctx context.Context) error {
@@ -412,8 +427,15 @@ func (c *Core) setupAudits(ctx context.Context) error {
 			continue
 		}
 
+		// A filter which no longer parses logs everything rather than
+		// nothing, so no request goes unaudited
+		filter, err := auditFilter(entry)
+		if err != nil {
+			c.logger.Error("failed to parse audit filter, logging all entries", "path", entry.Path, "error", err)
+		}
+
 		// Mount the backend
-		broker.Register(entry.Path, backend, view, entry.Local)
+		broker.Register(entry.Path, backend, view, entry.Local, filter)
 
 		successCount++
 	}
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"sync"
 	"time"
@@ -9,14 +10,32 @@ import (
 	metrics "github.com/armon/go-metrics"
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/helper/salt"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// auditPreviousSaltLocation is the path in an audit device's view where the
+// salt replaced by the last rotation is kept until its overlap window ends.
+const auditPreviousSaltLocation = "salt-previous"
+
+// previousAuditSalt is a salt replaced by a rotation, which hashes are still
+// computed with until ExpiresAt so that audit logs written before the
+// rotation can be searched.
+type previousAuditSalt struct {
+	Salt      string    `json:"salt"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type backendEntry struct {
 	backend audit.Backend
 	view    *BarrierView
 	local   bool
+
+	// filter selects the entries the backend logs; all entries are logged
+	// when it is nil
+	filter *audit.Filter
 }
 
 // AuditBroker is used to provide a single ingest interface to auditable
@@ -36,14 +55,16 @@ func NewAuditBroker(log log.Logger) *AuditBroker {
 	return b
 }
 
-// Register is used to add new audit backend to the broker
-func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool) {
+// Register is used to add new audit backend to the broker. A nil filter
+// logs all entries.
+func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool, filter *audit.Filter) {
 	a.Lock()
 	defer a.Unlock()
 	a.backends[name] = backendEntry{
 		backend: b,
 		view:    v,
 		local:   local,
+		filter:  filter,
 	}
 }
 
@@ -62,6 +83,32 @@ func (a *AuditBroker) IsRegistered(name string) bool {
 	return ok
 }
 
+// LogsAll returns whether at least one registered audit backend has no
+// filter, and so logs all entries
+func (a *AuditBroker) LogsAll() bool {
+	a.RLock()
+	defer a.RUnlock()
+	for _, be := range a.backends {
+		if be.filter == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Logs returns whether at least one registered audit backend logs in, as its
+// filter selects it or it has no filter
+func (a *AuditBroker) Logs(ctx context.Context, in *logical.LogInput) bool {
+	a.RLock()
+	defer a.RUnlock()
+	for _, be := range a.backends {
+		if be.filter == nil || be.filter.Evaluate(ctx, in) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsLocal is used to check if a given audit backend is registered
 func (a *AuditBroker) IsLocal(name string) (bool, error) {
 	a.RLock()
@@ -85,6 +132,98 @@ func (a *AuditBroker) GetHash(ctx context.Context, name string, input string) (s
 	return be.backend.GetHash(ctx, input)
 }
 
+// GetPreviousHash returns a hash using the salt the given backend used before
+// its last rotation, and when that salt stops being used. It returns an empty
+// hash if the overlap window of the rotation has ended.
+func (a *AuditBroker) GetPreviousHash(ctx context.Context, name string, input string) (string, time.Time, error) {
+	a.RLock()
+	defer a.RUnlock()
+	be, ok := a.backends[name]
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("unknown audit backend %q", name)
+	}
+
+	raw, err := be.view.Get(ctx, auditPreviousSaltLocation)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to read previous salt: %w", err)
+	}
+	if raw == nil {
+		return "", time.Time{}, nil
+	}
+
+	var previous previousAuditSalt
+	if err := raw.DecodeJSON(&previous); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode previous salt: %w", err)
+	}
+	if time.Now().After(previous.ExpiresAt) {
+		return "", time.Time{}, nil
+	}
+
+	return salt.HMACIdentifiedValue(previous.Salt, input, "hmac-sha256", sha256.New), previous.ExpiresAt, nil
+}
+
+// RotateSalt replaces the salt of the given backend. Hashes with the replaced
+// salt can still be computed with GetPreviousHash for the overlap duration.
+func (a *AuditBroker) RotateSalt(ctx context.Context, name string, overlap time.Duration) error {
+	a.Lock()
+	defer a.Unlock()
+	be, ok := a.backends[name]
+	if !ok {
+		return fmt.Errorf("unknown audit backend %q", name)
+	}
+
+	current, err := be.view.Get(ctx, salt.DefaultLocation)
+	if err != nil {
+		return fmt.Errorf("failed to read salt: %w", err)
+	}
+
+	if current != nil && overlap > 0 {
+		entry, err := logical.StorageEntryJSON(auditPreviousSaltLocation, &previousAuditSalt{
+			Salt:      string(current.Value),
+			ExpiresAt: time.Now().Add(overlap),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode previous salt: %w", err)
+		}
+		if err := be.view.Put(ctx, entry); err != nil {
+			return fmt.Errorf("failed to persist previous salt: %w", err)
+		}
+	} else if err := be.view.Delete(ctx, auditPreviousSaltLocation); err != nil {
+		return fmt.Errorf("failed to delete previous salt: %w", err)
+	}
+
+	newSalt, err := uuid.GenerateUUID()
+	if err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	if err := be.view.Put(ctx, &logical.StorageEntry{
+		Key:   salt.DefaultLocation,
+		Value: []byte(newSalt),
+	}); err != nil {
+		return fmt.Errorf("failed to persist salt: %w", err)
+	}
+
+	// Drop the cached salt so that the new one is used from now on
+	be.backend.Invalidate(ctx)
+
+	return nil
+}
+
+// TestBackend writes the given test entry to the given backend, which is
+// configured with config, and returns how long the write took.
+func (a *AuditBroker) TestBackend(ctx context.Context, name string, in *logical.LogInput, config map[string]string) (time.Duration, error) {
+	a.RLock()
+	defer a.RUnlock()
+	be, ok := a.backends[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown audit backend %q", name)
+	}
+
+	start := time.Now()
+	err := be.backend.LogTestMessage(ctx, in, config)
+	return time.Since(start), err
+}
+
 // LogRequest is used to ensure all the audit backends have an opportunity to
 // log the given request and that *at least one* succeeds.
 func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, headersConfig *AuditedHeadersConfig) (ret error) {
@@ -129,9 +268,16 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	// Ensure at least one backend logs, among the ones whose filter selects
+	// the request
 	anyLogged := false
+	anySelected := false
 	for name, be := range a.backends {
+		if be.filter != nil && !be.filter.Evaluate(ctx, in) {
+			continue
+		}
+		anySelected = true
+
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
@@ -149,7 +295,7 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anySelected {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the request"))
 	}
 
@@ -194,9 +340,16 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	// Ensure at least one backend logs, among the ones whose filter selects
+	// the request
 	anyLogged := false
+	anySelected := false
 	for name, be := range a.backends {
+		if be.filter != nil && !be.filter.Evaluate(ctx, in) {
+			continue
+		}
+		anySelected = true
+
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
@@ -214,7 +367,7 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anySelected {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the response"))
 	}
 
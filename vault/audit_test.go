@@ -359,8 +359,8 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -445,8 +445,8 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		NumUses:     10,
@@ -544,6 +544,96 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	}
 }
 
+func TestAuditBroker_Filter(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+	logins := &NoopAudit{}
+	all := &NoopAudit{}
+	filter, err := audit.NewFilter(`path matches "^auth/[^/]+/login"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Register("logins", logins, nil, false, filter)
+	b.Register("all", all, nil, false, nil)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	ctx := namespace.RootContext(context.Background())
+
+	for _, path := range []string{"auth/userpass/login/bob", "sys/mounts"} {
+		logInput := &logical.LogInput{
+			Request: &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      path,
+			},
+		}
+		if err := b.LogRequest(ctx, logInput, headersConf); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := b.LogResponse(ctx, logInput, headersConf); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	if len(logins.Req) != 1 || logins.Req[0].Path != "auth/userpass/login/bob" || len(logins.RespReq) != 1 {
+		t.Fatalf("expected only the login to be logged by the filtered backend: %#v", logins.Req)
+	}
+	if len(all.Req) != 2 || len(all.RespReq) != 2 {
+		t.Fatalf("expected both requests to be logged by the unfiltered backend: %#v", all.Req)
+	}
+
+	// A failing backend which is filtered out doesn't fail the request, but
+	// one which is selected does when no other backend logs it
+	b.Deregister("all")
+	logins.ReqErr = fmt.Errorf("failed")
+	logInput := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "sys/mounts",
+		},
+	}
+	if err := b.LogRequest(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	logInput.Request.Path = "auth/userpass/login/bob"
+	if err := b.LogRequest(ctx, logInput, headersConf); !errwrap.Contains(err, "no audit backend succeeded in logging the request") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCore_EnableAudit_Filter(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+
+	me := &MountEntry{
+		Table: auditTableType,
+		Path:  "foo",
+		Type:  "noop",
+		Options: map[string]string{
+			"filter": `operation ==`,
+		},
+	}
+	if err := c.enableAudit(namespace.RootContext(nil), me, true); err == nil || !strings.Contains(err.Error(), "invalid filter expression") {
+		t.Fatalf("expected an invalid filter error, got: %v", err)
+	}
+	if c.auditBroker.IsRegistered("foo/") {
+		t.Fatal("audit backend with an invalid filter should not be registered")
+	}
+
+	me.Options["filter"] = `operation == "update"`
+	if err := c.enableAudit(namespace.RootContext(nil), me, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !c.auditBroker.IsRegistered("foo/") {
+		t.Fatalf("missing audit backend")
+	}
+}
+
 func TestAuditBroker_AuditHeaders(t *testing.T) {
 	logger := logging.NewVaultLogger(log.Trace)
 	b := NewAuditBroker(logger)
@@ -551,8 +641,8 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 	view := NewBarrierView(barrier, "headers/")
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
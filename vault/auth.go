@@ -914,6 +914,12 @@ func (c *Core) teardownCredentials(ctx context.Context) error {
 		}
 	}
 
+	// Background writes of the token store must be done before the barrier
+	// is sealed
+	if c.tokenStore != nil {
+		c.tokenStore.waitPendingWrites()
+	}
+
 	c.auth = nil
 	c.tokenStore = nil
 	return nil
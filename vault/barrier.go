@@ -181,6 +181,14 @@ type BarrierStorage interface {
 	List(ctx context.Context, prefix string) ([]string, error)
 }
 
+// BarrierBatchStorage is an optional interface for barriers which can
+// persist several entries in a single round trip to the physical backend.
+type BarrierBatchStorage interface {
+	// PutBatch is used to insert or update several entries, atomically when
+	// the physical backend supports transactions.
+	PutBatch(ctx context.Context, entries []*logical.StorageEntry) error
+}
+
 // BarrierEncryptor is the in memory only interface that does not actually
 // use the underlying barrier. It is used for lower level modules like the
 // Write-Ahead-Log and Merkle index to allow them to use the barrier.
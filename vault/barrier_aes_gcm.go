@@ -795,17 +795,59 @@ func (b *AESGCMBarrier) Put(ctx context.Context, entry *logical.StorageEntry) er
 	return b.putInternal(ctx, term, primary, entry)
 }
 
-func (b *AESGCMBarrier) putInternal(ctx context.Context, term uint32, primary cipher.AEAD, entry *logical.StorageEntry) error {
-	value, err := b.encryptTracked(entry.Key, term, primary, entry.Value)
+// PutBatch is used to insert or update several entries. The entries are
+// written in a single transaction if the physical backend is transactional,
+// and in order otherwise.
+func (b *AESGCMBarrier) PutBatch(ctx context.Context, entries []*logical.StorageEntry) error {
+	defer metrics.MeasureSince([]string{"barrier", "put_batch"}, time.Now())
+	b.l.RLock()
+	if b.sealed {
+		b.l.RUnlock()
+		return ErrBarrierSealed
+	}
+
+	term := b.keyring.ActiveTerm()
+	primary, err := b.aeadForTerm(term)
+	b.l.RUnlock()
 	if err != nil {
 		return err
 	}
-	pe := &physical.Entry{
-		Key:      entry.Key,
-		Value:    value,
-		SealWrap: entry.SealWrap,
+
+	return b.putInternal(ctx, term, primary, entries...)
+}
+
+// putInternal encrypts and writes the entries, in a single transaction when
+// there are several of them and the physical backend is transactional.
+func (b *AESGCMBarrier) putInternal(ctx context.Context, term uint32, primary cipher.AEAD, entries ...*logical.StorageEntry) error {
+	txnBackend, ok := b.backend.(physical.Transactional)
+	batch := ok && len(entries) > 1
+
+	var txns []*physical.TxnEntry
+	for _, entry := range entries {
+		value, err := b.encryptTracked(entry.Key, term, primary, entry.Value)
+		if err != nil {
+			return err
+		}
+		pe := &physical.Entry{
+			Key:      entry.Key,
+			Value:    value,
+			SealWrap: entry.SealWrap,
+		}
+		if !batch {
+			if err := b.backend.Put(ctx, pe); err != nil {
+				return err
+			}
+			continue
+		}
+		txns = append(txns, &physical.TxnEntry{
+			Operation: physical.PutOperation,
+			Entry:     pe,
+		})
 	}
-	return b.backend.Put(ctx, pe)
+	if !batch {
+		return nil
+	}
+	return txnBackend.Transaction(ctx, txns)
 }
 
 // Get is used to fetch an entry
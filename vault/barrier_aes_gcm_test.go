@@ -691,3 +691,58 @@ func TestBarrier_LegacyRotate(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestAESGCMBarrier_PutBatch(t *testing.T) {
+	for name, newBackend := range map[string]physical.Factory{
+		"non-transactional": inmem.NewInmem,
+		"transactional":     inmem.NewTransactionalInmem,
+	} {
+		t.Run(name, func(t *testing.T) {
+			inm, err := newBackend(nil, logger)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			b, err := NewAESGCMBarrier(inm)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			// Initialize and unseal
+			key, _ := b.GenerateKey(rand.Reader)
+			b.Initialize(context.Background(), key, nil, rand.Reader)
+			b.Unseal(context.Background(), key)
+
+			entries := []*logical.StorageEntry{
+				{Key: "test/one", Value: []byte("one")},
+				{Key: "test/two", Value: []byte("two")},
+			}
+			if err := b.PutBatch(context.Background(), entries); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			for _, entry := range entries {
+				out, err := b.Get(context.Background(), entry.Key)
+				if err != nil {
+					t.Fatalf("err: %v", err)
+				}
+				if out == nil || !bytes.Equal(out.Value, entry.Value) {
+					t.Fatalf("bad: %#v", out)
+				}
+
+				// The physical entries must be encrypted
+				pe, err := inm.Get(context.Background(), entry.Key)
+				if err != nil {
+					t.Fatalf("err: %v", err)
+				}
+				if pe == nil || bytes.Equal(pe.Value, entry.Value) {
+					t.Fatalf("bad: %#v", pe)
+				}
+			}
+
+			b.Seal()
+			if err := b.PutBatch(context.Background(), entries); err != ErrBarrierSealed {
+				t.Fatalf("expected sealed error, got: %v", err)
+			}
+		})
+	}
+}
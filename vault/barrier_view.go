@@ -76,6 +76,31 @@ func (v *BarrierView) Put(ctx context.Context, entry *logical.StorageEntry) erro
 	return v.storage.Put(ctx, entry)
 }
 
+// expandEntry returns a copy of the entry keyed by its full key in the
+// underlying barrier, so that it can be written along with the entries of
+// other views using PutBatch.
+func (v *BarrierView) expandEntry(entry *logical.StorageEntry) (*logical.StorageEntry, error) {
+	if entry == nil {
+		return nil, errors.New("cannot write nil entry")
+	}
+	if err := v.storage.SanityCheck(entry.Key); err != nil {
+		return nil, err
+	}
+
+	expandedKey := v.storage.ExpandKey(entry.Key)
+
+	roErr := v.getReadOnlyErr()
+	if roErr != nil {
+		if runICheck(v, expandedKey, roErr) {
+			return nil, roErr
+		}
+	}
+
+	expanded := *entry
+	expanded.Key = expandedKey
+	return &expanded, nil
+}
+
 // logical.Storage impl.
 func (v *BarrierView) Delete(ctx context.Context, key string) error {
 	expandedKey := v.storage.ExpandKey(key)
@@ -3,6 +3,7 @@ package vault
 import (
 	"context"
 	"sort"
+	"strings"
 
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -11,10 +12,44 @@ import (
 // Capabilities is used to fetch the capabilities of the given token on the
 // given path
 func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string, error) {
-	if path == "" {
-		return nil, &logical.StatusBadRequest{Err: "missing path"}
+	capabilities, err := c.CapabilitiesForPaths(ctx, token, []string{path})
+	if err != nil {
+		return nil, err
+	}
+	return capabilities[path], nil
+}
+
+// CapabilitiesForPaths is used to fetch the capabilities of the given token on
+// each of the given paths. The ACL of the token is only built once, so that
+// checking many paths costs little more than checking one.
+func (c *Core) CapabilitiesForPaths(ctx context.Context, token string, paths []string) (map[string][]string, error) {
+	for _, path := range paths {
+		if path == "" {
+			return nil, &logical.StatusBadRequest{Err: "missing path"}
+		}
+	}
+
+	acl, err := c.capabilitiesACL(ctx, token)
+	if err != nil {
+		return nil, err
 	}
 
+	ret := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		if acl == nil {
+			ret[path] = []string{DenyCapability}
+			continue
+		}
+		capabilities := acl.Capabilities(ctx, path)
+		sort.Strings(capabilities)
+		ret[path] = capabilities
+	}
+	return ret, nil
+}
+
+// capabilitiesACL builds the ACL of the given token. A nil ACL is returned
+// when the token has no policies, in which case everything is denied.
+func (c *Core) capabilitiesACL(ctx context.Context, token string) (*ACL, error) {
 	if token == "" {
 		return nil, &logical.StatusBadRequest{Err: "missing token"}
 	}
@@ -58,19 +93,25 @@ func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string,
 		policyCount += len(nsPolicies)
 	}
 
-	// Add capabilities of the inline policy if it's set
+	// Add capabilities of the inline policy if it's set, unless it scopes
+	// down the token
 	policies := make([]*Policy, 0)
+	var scopePolicy *Policy
 	if te.InlinePolicy != "" {
 		inlinePolicy, err := ParseACLPolicy(tokenNS, te.InlinePolicy)
 		if err != nil {
 			return nil, err
 		}
-		policies = append(policies, inlinePolicy)
-		policyCount++
+		if inlinePolicyIsScope(te) {
+			scopePolicy = inlinePolicy
+		} else {
+			policies = append(policies, inlinePolicy)
+			policyCount++
+		}
 	}
 
 	if policyCount == 0 {
-		return []string{DenyCapability}, nil
+		return nil, nil
 	}
 
 	// Construct the corresponding ACL object. ACL construction should be
@@ -80,8 +121,70 @@ func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string,
 	if err != nil {
 		return nil, err
 	}
+	if scopePolicy != nil {
+		if err := acl.setScope(tokenCtx, scopePolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	return acl, nil
+}
 
-	capabilities := acl.Capabilities(ctx, path)
-	sort.Strings(capabilities)
-	return capabilities, nil
+// mountsMatchingPath returns the paths of the secret and auth mounts of the
+// namespace of ctx that the given policy path applies to, as "+" segments and
+// a trailing "*" may make it match several mounts.
+func (c *Core) mountsMatchingPath(ctx context.Context, path string) ([]string, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var mountPaths []string
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		if entry.Namespace().Path == ns.Path {
+			mountPaths = append(mountPaths, entry.Path)
+		}
+	}
+	c.mountsLock.RUnlock()
+
+	c.authLock.RLock()
+	for _, entry := range c.auth.Entries {
+		if entry.Namespace().Path == ns.Path {
+			mountPaths = append(mountPaths, credentialRoutePrefix+entry.Path)
+		}
+	}
+	c.authLock.RUnlock()
+
+	matches := []string{}
+	for _, mountPath := range mountPaths {
+		if policyPathMatchesMount(path, mountPath) {
+			matches = append(matches, mountPath)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// policyPathMatchesMount reports whether some request path under mountPath
+// matches the policy path, where a "+" segment matches any single segment and
+// a trailing "*" matches any suffix.
+func policyPathMatchesMount(path, mountPath string) bool {
+	isPrefix := strings.HasSuffix(path, "*")
+	pathParts := strings.Split(strings.TrimSuffix(path, "*"), "/")
+	mountParts := strings.Split(strings.TrimSuffix(mountPath, "/"), "/")
+
+	for i, mountPart := range mountParts {
+		if i >= len(pathParts) {
+			return false
+		}
+		pathPart := pathParts[i]
+		if isPrefix && i == len(pathParts)-1 {
+			return strings.HasPrefix(mountPart, pathPart)
+		}
+		if pathPart != "+" && pathPart != mountPart {
+			return false
+		}
+	}
+	return true
 }
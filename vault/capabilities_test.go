@@ -226,3 +226,75 @@ func TestCapabilities(t *testing.T) {
 		t.Fatalf("bad: got\n%#v\nexpected\n%#v\n", actual, expected)
 	}
 }
+
+func TestCapabilitiesForPaths(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	policy, _ := ParseACLPolicy(namespace.RootNamespace, aclPolicy)
+	err := c.policyStore.SetPolicy(namespace.RootContext(nil), policy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ent := &logical.TokenEntry{
+		ID:       "capabilitiestoken",
+		Path:     "testpath",
+		Policies: []string{"dev"},
+		TTL:      time.Hour,
+	}
+	testMakeTokenDirectly(t, c.tokenStore, ent)
+
+	paths := make([]string, 0, 200)
+	for i := 0; i < 100; i++ {
+		paths = append(paths, fmt.Sprintf("foo/bar/%d", i), fmt.Sprintf("unknown/%d", i))
+	}
+	actual, err := c.CapabilitiesForPaths(namespace.RootContext(nil), "capabilitiestoken", paths)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual) != len(paths) {
+		t.Fatalf("expected %d paths, got %d", len(paths), len(actual))
+	}
+	for _, path := range paths {
+		expected, err := c.Capabilities(namespace.RootContext(nil), "capabilitiestoken", path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !reflect.DeepEqual(actual[path], expected) {
+			t.Fatalf("bad: %s: got\n%#v\nexpected\n%#v\n", path, actual[path], expected)
+		}
+	}
+
+	if _, err := c.CapabilitiesForPaths(namespace.RootContext(nil), "capabilitiestoken", []string{"foo", ""}); err == nil {
+		t.Fatal("expected an error for an empty path")
+	}
+}
+
+func TestCapabilities_policyPathMatchesMount(t *testing.T) {
+	tests := []struct {
+		path      string
+		mountPath string
+		expected  bool
+	}{
+		{"secret/foo", "secret/", true},
+		{"secret", "secret/", true},
+		{"secret-v2/foo", "secret/", false},
+		{"sec*", "secret/", true},
+		{"secret/fo*", "secret/", true},
+		{"*", "secret/", true},
+		{"+/foo", "secret/", true},
+		{"auth/+/login", "auth/userpass/", true},
+		{"auth/userpass/*", "auth/userpass/", true},
+		{"auth/*", "auth/userpass/", true},
+		{"auth", "auth/userpass/", false},
+		{"+/data/*", "auth/userpass/", false},
+		{"team/+/b/foo", "team/a/b/", true},
+		{"team/+/foo", "team/a/b/", false},
+		{"team/a/c", "team/a/b/", false},
+	}
+	for _, tt := range tests {
+		if actual := policyPathMatchesMount(tt.path, tt.mountPath); actual != tt.expected {
+			t.Errorf("%q on %q: expected %t, got %t", tt.path, tt.mountPath, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,472 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Kinds of objects managed by a configuration spec
+const (
+	configKindPolicy   = "policy"
+	configKindMount    = "mount"
+	configKindAuth     = "auth"
+	configKindResource = "resource"
+)
+
+// Actions planned for an object of a configuration spec
+const (
+	configActionCreate = "create"
+	configActionUpdate = "update"
+)
+
+// configSpec is the declarative configuration applied with sys/config/apply.
+// Resources are written to arbitrary paths, such as the roles of secrets
+// engines and auth methods, keyed by path.
+type configSpec struct {
+	Policies  map[string]string                 `mapstructure:"policies"`
+	Mounts    map[string]*namespaceBundleMount  `mapstructure:"mounts"`
+	Auth      map[string]*namespaceBundleMount  `mapstructure:"auth"`
+	Resources map[string]map[string]interface{} `mapstructure:"resources"`
+}
+
+// configChange is a change needed to reconcile an object with the spec
+type configChange struct {
+	Kind   string                      `json:"kind"`
+	Path   string                      `json:"path"`
+	Action string                      `json:"action"`
+	Diff   map[string]*configFieldDiff `json:"diff,omitempty"`
+
+	// apply makes the change
+	apply func(ctx context.Context) error
+}
+
+type configFieldDiff struct {
+	Current interface{} `json:"current"`
+	Desired interface{} `json:"desired"`
+}
+
+// configPlan holds the changes needed to reconcile the configuration with a
+// spec, in the order they are applied.
+type configPlan struct {
+	Changes []*configChange `json:"changes"`
+}
+
+// hash identifies the changes of the plan, so that a plan reviewed in a
+// pipeline can be applied only if nothing changed in the meantime.
+func (p *configPlan) hash() (string, error) {
+	encoded, err := jsonutil.EncodeJSON(p.Changes)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// planConfig compares spec with the configuration of the namespace of ctx.
+// Objects that are not part of the spec are left untouched. Resources are
+// read with the token of req.
+func (c *Core) planConfig(ctx context.Context, req *logical.Request, spec *configSpec) (*configPlan, error) {
+	plan := &configPlan{
+		Changes: []*configChange{},
+	}
+
+	for _, name := range sortedKeys(spec.Policies) {
+		change, err := c.planConfigPolicy(ctx, name, spec.Policies[name])
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", name, err)
+		}
+		if change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	for _, path := range sortedKeys(spec.Mounts) {
+		change, err := c.planConfigMount(ctx, mountTableType, path, spec.Mounts[path])
+		if err != nil {
+			return nil, fmt.Errorf("mount %q: %w", path, err)
+		}
+		if change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	for _, path := range sortedKeys(spec.Auth) {
+		change, err := c.planConfigMount(ctx, credentialTableType, path, spec.Auth[path])
+		if err != nil {
+			return nil, fmt.Errorf("auth method %q: %w", path, err)
+		}
+		if change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	// Resources are planned against the current state, so resources under
+	// mounts that are still to be created are always created
+	for _, path := range sortedKeys(spec.Resources) {
+		change, err := c.planConfigResource(ctx, req, path, spec.Resources[path])
+		if err != nil {
+			return nil, fmt.Errorf("resource %q: %w", path, err)
+		}
+		if change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	return plan, nil
+}
+
+// applyConfigPlan makes the changes of plan in order, and stops at the first
+// failure. It returns the changes that were made.
+func (c *Core) applyConfigPlan(ctx context.Context, plan *configPlan) ([]*configChange, error) {
+	applied := make([]*configChange, 0, len(plan.Changes))
+	for _, change := range plan.Changes {
+		if err := change.apply(ctx); err != nil {
+			return applied, fmt.Errorf("failed to %s %s %q: %w", change.Action, change.Kind, change.Path, err)
+		}
+		applied = append(applied, change)
+	}
+	return applied, nil
+}
+
+func (c *Core) planConfigPolicy(ctx context.Context, name, desired string) (*configChange, error) {
+	if strutil.StrListContains(immutablePolicies, name) {
+		return nil, fmt.Errorf("policy cannot be managed")
+	}
+
+	change := &configChange{
+		Kind:   configKindPolicy,
+		Path:   name,
+		Action: configActionCreate,
+		apply: func(ctx context.Context) error {
+			return c.routeNamespaceBundleRequest(ctx, logical.UpdateOperation, "sys/policies/acl/"+name, map[string]interface{}{
+				"policy": desired,
+			})
+		},
+	}
+
+	policy, err := c.policyStore.GetPolicy(ctx, name, PolicyTypeACL)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil {
+		if strings.TrimSpace(policy.Raw) == strings.TrimSpace(desired) {
+			return nil, nil
+		}
+		change.Action = configActionUpdate
+		change.Diff = map[string]*configFieldDiff{
+			"policy": {Current: policy.Raw, Desired: desired},
+		}
+	}
+	return change, nil
+}
+
+func (c *Core) planConfigMount(ctx context.Context, table, path string, desired *namespaceBundleMount) (*configChange, error) {
+	if desired == nil || desired.Type == "" {
+		return nil, fmt.Errorf("missing type")
+	}
+	path = sanitizePath(path)
+
+	kind := configKindMount
+	if table == credentialTableType {
+		kind = configKindAuth
+	}
+	change := &configChange{
+		Kind:   kind,
+		Path:   path,
+		Action: configActionCreate,
+		apply: func(ctx context.Context) error {
+			_, err := c.importNamespaceBundleMount(ctx, table, path, desired)
+			return err
+		},
+	}
+
+	var existing *MountEntry
+	var err error
+	if table == credentialTableType {
+		c.authLock.RLock()
+		existing, err = c.auth.find(ctx, path)
+		c.authLock.RUnlock()
+	} else {
+		c.mountsLock.RLock()
+		existing, err = c.mounts.find(ctx, path)
+		c.mountsLock.RUnlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return change, nil
+	}
+
+	if existing.Type != desired.Type {
+		return nil, fmt.Errorf("existing mount has type %q, not %q", existing.Type, desired.Type)
+	}
+	if existing.Local != desired.Local || existing.SealWrap != desired.SealWrap || existing.ExternalEntropyAccess != desired.ExternalEntropyAccess {
+		return nil, fmt.Errorf("local, seal_wrap and external_entropy_access cannot be changed on an existing mount")
+	}
+
+	current := namespaceBundleMountFromEntry(existing)
+	diff := map[string]*configFieldDiff{}
+	if current.Description != desired.Description {
+		diff["description"] = &configFieldDiff{Current: current.Description, Desired: desired.Description}
+	}
+	for key, value := range desired.Options {
+		if current.Options[key] != value {
+			diff["options."+key] = &configFieldDiff{Current: current.Options[key], Desired: value}
+		}
+	}
+	for key, value := range desired.Config {
+		if key == "force_no_cache" {
+			continue
+		}
+		if !configValuesEqual(current.Config[key], value) {
+			diff["config."+key] = &configFieldDiff{Current: current.Config[key], Desired: value}
+		}
+	}
+	if len(diff) == 0 {
+		return nil, nil
+	}
+
+	change.Action = configActionUpdate
+	change.Diff = diff
+	return change, nil
+}
+
+func (c *Core) planConfigResource(ctx context.Context, req *logical.Request, path string, desired map[string]interface{}) (*configChange, error) {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil, fmt.Errorf("missing path")
+	}
+	if strings.HasPrefix(path, "sys/") {
+		return nil, fmt.Errorf("sys paths cannot be managed as resources")
+	}
+
+	change := &configChange{
+		Kind:   configKindResource,
+		Path:   path,
+		Action: configActionCreate,
+		apply: func(ctx context.Context) error {
+			resp, err := c.handleConfigRequest(ctx, req, logical.UpdateOperation, path, desired)
+			if err != nil {
+				return err
+			}
+			return c.rejectConfigSecret(ctx, resp)
+		},
+	}
+
+	// The mount may only be created by the same plan
+	if c.router.MatchingMountEntry(ctx, path) == nil {
+		return change, nil
+	}
+
+	resp, err := c.handleConfigRequest(ctx, req, logical.ReadOperation, path, nil)
+	switch {
+	case errors.Is(err, logical.ErrUnsupportedPath), errors.Is(err, logical.ErrUnsupportedOperation):
+		return change, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := c.rejectConfigSecret(ctx, resp); err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return change, nil
+	}
+
+	// Fields that are not returned when reading, such as credentials, cannot
+	// be compared and are only written along with other changes
+	diff := map[string]*configFieldDiff{}
+	for key, value := range desired {
+		current, ok := resp.Data[key]
+		if !ok {
+			continue
+		}
+		if !configValuesEqual(current, value) {
+			diff[key] = &configFieldDiff{Current: current, Desired: value}
+		}
+	}
+	if len(diff) == 0 {
+		return nil, nil
+	}
+
+	change.Action = configActionUpdate
+	change.Diff = diff
+	return change, nil
+}
+
+// handleConfigRequest makes a request on behalf of the caller of req, so that
+// its policies, the audit devices and the lease of any secret apply as they
+// would to a request made by the caller. The state lock is already held by
+// the request of the caller.
+func (c *Core) handleConfigRequest(ctx context.Context, req *logical.Request, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+	resp, err := c.switchedLockHandleRequest(ctx, &logical.Request{
+		Operation:   op,
+		Path:        path,
+		Data:        data,
+		ClientToken: req.ClientToken,
+		Connection:  req.Connection,
+	}, false)
+	if resp != nil && resp.IsError() {
+		if err == nil || err == logical.ErrInvalidRequest {
+			err = resp.Error()
+		}
+	}
+	return resp, err
+}
+
+// rejectConfigSecret revokes the lease or token resp carries. Paths that
+// issue credentials, such as the creds endpoints of secrets engines, are not
+// configuration and cannot be managed as resources. Secrets without a lease,
+// which mounts that don't generate leases return along with a TTL, are not
+// credentials.
+func (c *Core) rejectConfigSecret(ctx context.Context, resp *logical.Response) error {
+	if resp == nil {
+		return nil
+	}
+
+	var err error
+	switch {
+	case resp.Secret != nil && resp.Secret.LeaseID != "":
+		err = c.expiration.Revoke(ctx, resp.Secret.LeaseID)
+	case resp.Auth != nil && resp.Auth.ClientToken != "":
+		err = c.tokenStore.revokeOrphan(ctx, resp.Auth.ClientToken)
+	default:
+		return nil
+	}
+	if err != nil {
+		c.logger.Error("failed to revoke credentials issued to a configuration resource", "error", err)
+	}
+	return fmt.Errorf("path issues credentials and cannot be managed as a resource")
+}
+
+// configValuesEqual compares a value read from Vault with the value given in
+// a spec. The spec may use any format accepted when writing, such as
+// durations for TTLs or comma-separated strings for lists.
+func configValuesEqual(current, desired interface{}) bool {
+	if isZeroConfigValue(current) && isZeroConfigValue(desired) {
+		return true
+	}
+
+	if currentList, ok := configStringList(current, false); ok {
+		desiredList, ok := configStringList(desired, true)
+		if !ok {
+			return false
+		}
+		currentList = append([]string(nil), currentList...)
+		desiredList = append([]string(nil), desiredList...)
+		sort.Strings(currentList)
+		sort.Strings(desiredList)
+		return reflect.DeepEqual(currentList, desiredList)
+	}
+
+	switch current.(type) {
+	case map[string]interface{}, map[string]string:
+		currentJSON, err := jsonutil.EncodeJSON(current)
+		if err != nil {
+			return false
+		}
+		desiredJSON, err := jsonutil.EncodeJSON(desired)
+		if err != nil {
+			return false
+		}
+		var currentMap, desiredMap interface{}
+		if json.Unmarshal(currentJSON, &currentMap) != nil || json.Unmarshal(desiredJSON, &desiredMap) != nil {
+			return false
+		}
+		return reflect.DeepEqual(currentMap, desiredMap)
+
+	case bool:
+		desiredBool, err := parseutil.ParseBool(desired)
+		return err == nil && desiredBool == current
+	}
+
+	if fmt.Sprint(current) == fmt.Sprint(desired) {
+		return true
+	}
+
+	// Numbers and durations, which are read back as a number of seconds
+	currentNumber, ok := configNumber(current)
+	if !ok {
+		return false
+	}
+	desiredNumber, ok := configNumber(desired)
+	return ok && currentNumber == desiredNumber
+}
+
+func isZeroConfigValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	case reflect.String:
+		return rv.Len() == 0
+	}
+	return false
+}
+
+// configStringList converts v to a list of strings. Strings are split on
+// commas when splitStrings is set.
+func configStringList(v interface{}, splitStrings bool) ([]string, bool) {
+	switch list := v.(type) {
+	case []string:
+		return list, true
+	case []interface{}:
+		result := make([]string, 0, len(list))
+		for _, elem := range list {
+			result = append(result, fmt.Sprint(elem))
+		}
+		return result, true
+	case string:
+		if splitStrings {
+			return strutil.ParseStringSlice(list, ","), true
+		}
+	}
+	return nil, false
+}
+
+// configNumber converts numbers, numeric strings and durations to a number of
+// seconds.
+func configNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		if f, err := strconv.ParseFloat(n, 64); err == nil {
+			return f, true
+		}
+		if n == "system" {
+			return 0, true
+		}
+		d, err := parseutil.ParseDurationSecond(n)
+		if err != nil {
+			return 0, false
+		}
+		return d.Seconds(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,280 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func testConfigApplySpec(policy, foo, maxLeaseTTL string) map[string]interface{} {
+	return map[string]interface{}{
+		"policies": map[string]interface{}{
+			"dev": policy,
+		},
+		"mounts": map[string]interface{}{
+			"kv-a": map[string]interface{}{
+				"type":        "kv",
+				"description": "team A secrets",
+				"config": map[string]interface{}{
+					"max_lease_ttl": maxLeaseTTL,
+				},
+			},
+		},
+		"auth": map[string]interface{}{
+			"corp/": map[string]interface{}{
+				"type": "noop",
+			},
+		},
+		"resources": map[string]interface{}{
+			"kv-a/app": map[string]interface{}{
+				"foo":   foo,
+				"count": 3,
+			},
+		},
+	}
+}
+
+// testConfigApplyRequest makes a request with token, as sys/config/apply
+// makes the requests of the plan with the token of its caller.
+func testConfigApplyRequest(c *Core, token string, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+	return c.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Operation:   op,
+		Path:        path,
+		Data:        data,
+		ClientToken: token,
+	})
+}
+
+func TestConfigApply_PlanApply(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	c, _, root := TestCoreUnsealed(t)
+	c.logicalBackends["kv"] = PassthroughBackendFactory
+
+	request := func(t *testing.T, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := testConfigApplyRequest(c, root, logical.UpdateOperation, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: bad: resp: %#v\nerr: %v", path, resp, err)
+		}
+		return resp
+	}
+
+	planActions := func(t *testing.T, spec map[string]interface{}) (map[string]string, string) {
+		t.Helper()
+		resp := request(t, "sys/config/apply/plan", map[string]interface{}{
+			"spec": spec,
+		})
+		actions := map[string]string{}
+		for _, change := range resp.Data["changes"].([]map[string]interface{}) {
+			actions[change["kind"].(string)+":"+change["path"].(string)] = change["action"].(string)
+		}
+		return actions, resp.Data["plan_hash"].(string)
+	}
+
+	spec := testConfigApplySpec(`path "kv-a/*" { capabilities = ["read"] }`, "bar", "2h")
+	actions, planHash := planActions(t, spec)
+	expected := map[string]string{
+		"policy:dev":        configActionCreate,
+		"mount:kv-a/":       configActionCreate,
+		"auth:corp/":        configActionCreate,
+		"resource:kv-a/app": configActionCreate,
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, actions)
+	}
+	for key, action := range expected {
+		if actions[key] != action {
+			t.Fatalf("expected %v, got %v", expected, actions)
+		}
+	}
+
+	resp := request(t, "sys/config/apply", map[string]interface{}{
+		"spec":      spec,
+		"plan_hash": planHash,
+	})
+	if applied := resp.Data["applied"].([]map[string]interface{}); len(applied) != 4 {
+		t.Fatalf("expected 4 applied changes, got %v", applied)
+	}
+	mount := c.router.MatchingMountEntry(ctx, "kv-a/")
+	if mount == nil || mount.Config.MaxLeaseTTL != 2*time.Hour {
+		t.Fatalf("bad mount: %#v", mount)
+	}
+	if c.router.MatchingMountEntry(ctx, "auth/corp/") == nil {
+		t.Fatal("auth method was not enabled")
+	}
+
+	// The configuration matches the spec, including when values are given
+	// in another format
+	actions, _ = planActions(t, testConfigApplySpec(`path "kv-a/*" { capabilities = ["read"] }`, "bar", "7200s"))
+	if len(actions) != 0 {
+		t.Fatalf("expected no changes, got %v", actions)
+	}
+
+	spec = testConfigApplySpec(`path "kv-a/*" { capabilities = ["read", "list"] }`, "baz", "1h")
+	actions, planHash = planActions(t, spec)
+	expected = map[string]string{
+		"policy:dev":        configActionUpdate,
+		"mount:kv-a/":       configActionUpdate,
+		"resource:kv-a/app": configActionUpdate,
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, actions)
+	}
+	for key, action := range expected {
+		if actions[key] != action {
+			t.Fatalf("expected %v, got %v", expected, actions)
+		}
+	}
+
+	// A stale plan is refused
+	resp, _ = testConfigApplyRequest(c, root, logical.UpdateOperation, "sys/config/apply", map[string]interface{}{
+		"spec":      spec,
+		"plan_hash": "stale",
+	})
+	if resp == nil || resp.Data["error"] == nil {
+		t.Fatalf("expected an error, got: %#v", resp)
+	}
+	if mount := c.router.MatchingMountEntry(ctx, "kv-a/"); mount.Config.MaxLeaseTTL != 2*time.Hour {
+		t.Fatal("stale plan was applied")
+	}
+
+	request(t, "sys/config/apply", map[string]interface{}{
+		"spec":      spec,
+		"plan_hash": planHash,
+	})
+	resp = testNamespaceBundleRequest(t, c, logical.ReadOperation, "kv-a/app", nil)
+	if resp.Data["foo"] != "baz" {
+		t.Fatalf("resource was not updated: %#v", resp.Data)
+	}
+	if actions, _ = planActions(t, spec); len(actions) != 0 {
+		t.Fatalf("expected no changes, got %v", actions)
+	}
+}
+
+func TestConfigApply_CallerPermissions(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	c.logicalBackends["kv"] = PassthroughBackendFactory
+
+	testNamespaceBundleRequest(t, c, logical.UpdateOperation, "sys/mounts/kv-a", map[string]interface{}{
+		"type": "kv",
+	})
+	testNamespaceBundleRequest(t, c, logical.UpdateOperation, "sys/policies/acl/apply", map[string]interface{}{
+		"policy": `path "sys/config/apply*" { capabilities = ["update", "sudo"] }`,
+	})
+	resp, err := testConfigApplyRequest(c, root, logical.UpdateOperation, "auth/token/create", map[string]interface{}{
+		"policies": []string{"apply"},
+	})
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	token := resp.Auth.ClientToken
+
+	// Resources are read and written with the token of the caller
+	for _, path := range []string{"sys/config/apply/plan", "sys/config/apply"} {
+		resp, err = testConfigApplyRequest(c, token, logical.UpdateOperation, path, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"resources": map[string]interface{}{
+					"kv-a/app": map[string]interface{}{"foo": "bar"},
+				},
+			},
+		})
+		if err == nil || resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "permission denied") {
+			t.Fatalf("%s: expected permission denied, got: resp: %#v\nerr: %v", path, resp, err)
+		}
+	}
+	if resp := testNamespaceBundleRequest(t, c, logical.ReadOperation, "kv-a/app", nil); resp != nil {
+		t.Fatalf("resource was written: %#v", resp.Data)
+	}
+}
+
+func TestConfigApply_RejectCredentials(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	// Reads of the leased kv secrets engine of the test core issue leases
+	testNamespaceBundleRequest(t, c, logical.UpdateOperation, "sys/mounts/leased", map[string]interface{}{
+		"type": "kv",
+	})
+	testNamespaceBundleRequest(t, c, logical.UpdateOperation, "leased/creds", map[string]interface{}{
+		"password": "hunter2",
+	})
+
+	resp, err := testConfigApplyRequest(c, root, logical.UpdateOperation, "sys/config/apply/plan", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"resources": map[string]interface{}{
+				"leased/creds": map[string]interface{}{"password": "other"},
+			},
+		},
+	})
+	if err == nil || resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "issues credentials") {
+		t.Fatalf("expected an error, got: resp: %#v\nerr: %v", resp, err)
+	}
+	if strings.Contains(fmt.Sprint(resp.Data), "hunter2") {
+		t.Fatalf("current value leaked: %#v", resp.Data)
+	}
+
+	leases, err := c.expiration.idView.List(namespace.RootContext(nil), "leased/creds/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 0 {
+		t.Fatalf("expected the lease of the plan to be revoked, got %v", leases)
+	}
+}
+
+func TestConfigApply_InvalidSpec(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	for _, spec := range []map[string]interface{}{
+		{"unknown": map[string]interface{}{}},
+		{"policies": map[string]interface{}{"root": `path "*" { capabilities = ["sudo"] }`}},
+		{"mounts": map[string]interface{}{"kv-a/": map[string]interface{}{}}},
+		{"resources": map[string]interface{}{"sys/mounts/kv-b": map[string]interface{}{"type": "kv"}}},
+	} {
+		resp, err := c.router.Route(namespace.RootContext(nil), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "sys/config/apply/plan",
+			Data: map[string]interface{}{
+				"spec": spec,
+			},
+		})
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%v: expected an error, got: %#v", spec, resp)
+		}
+	}
+}
+
+func TestConfigValuesEqual(t *testing.T) {
+	tests := []struct {
+		current  interface{}
+		desired  interface{}
+		expected bool
+	}{
+		{"foo", "foo", true},
+		{"foo", "bar", false},
+		{nil, "", true},
+		{[]string{}, nil, true},
+		{int64(3600), "1h", true},
+		{int64(3600), json.Number("3600"), true},
+		{int64(3600), 60, false},
+		{"7200s", "2h", true},
+		{"system", "system", true},
+		{"system", "1h", false},
+		{true, "true", true},
+		{false, true, false},
+		{[]string{"a", "b"}, "b,a", true},
+		{[]string{"a", "b"}, []interface{}{"a"}, false},
+		{[]interface{}{"a", "b"}, []string{"a", "b"}, true},
+		{map[string]interface{}{"a": "b"}, map[string]string{"a": "b"}, true},
+		{map[string]interface{}{"a": "b"}, map[string]string{"a": "c"}, false},
+	}
+	for _, tt := range tests {
+		if actual := configValuesEqual(tt.current, tt.desired); actual != tt.expected {
+			t.Errorf("%#v and %#v: expected %t, got %t", tt.current, tt.desired, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package vault
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/internalshared/configutil"
+)
+
+// ConfigReloadStatus is the outcome of the last reload of the server
+// configuration.
+type ConfigReloadStatus struct {
+	Time time.Time
+
+	// Changes are the settings that changed, whether or not they could be
+	// applied.
+	Changes []*server.ConfigChange
+
+	// Errors are the errors encountered during the reload, such as failures
+	// to parse the configuration or to reload listener certificates.
+	Errors []string
+}
+
+// configReloadStatus holds the outcome of the last configuration reload.
+type configReloadStatus struct {
+	l      sync.RWMutex
+	status *ConfigReloadStatus
+}
+
+// SetConfigReloadStatus records the outcome of a configuration reload.
+func (c *Core) SetConfigReloadStatus(status *ConfigReloadStatus) {
+	c.configReloadStatus.l.Lock()
+	defer c.configReloadStatus.l.Unlock()
+	c.configReloadStatus.status = status
+}
+
+// ConfigReloadStatus returns the outcome of the last configuration reload, or
+// nil if the configuration was not reloaded since the server started.
+func (c *Core) ConfigReloadStatus() *ConfigReloadStatus {
+	c.configReloadStatus.l.RLock()
+	defer c.configReloadStatus.l.RUnlock()
+	return c.configReloadStatus.status
+}
+
+// ListenerConfig returns the current configuration of the listener at
+// address, as of the last configuration reload, or nil if there is none.
+func (c *Core) ListenerConfig(address string) *configutil.Listener {
+	conf := c.GetCoreConfigInternal()
+	if conf == nil || conf.SharedConfig == nil {
+		return nil
+	}
+	for _, l := range conf.Listeners {
+		if l.Address == address {
+			return l
+		}
+	}
+	return nil
+}
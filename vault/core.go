@@ -37,8 +37,10 @@ import (
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/geoip"
 	"github.com/hashicorp/vault/helper/identity/mfa"
 	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/helper/monitor"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/osutil"
 	"github.com/hashicorp/vault/physical/raft"
@@ -340,6 +342,19 @@ type Core struct {
 	// against their migration ids
 	mountMigrationTracker *sync.Map
 
+	// mountTrashLock serializes the updates of the trash of disabled mounts
+	mountTrashLock sync.Mutex
+
+	// kvLocksLock serializes the updates of the locks of KV secrets
+	kvLocksLock sync.Mutex
+
+	// kvSchemasLock serializes the updates of the schemas of KV secrets
+	kvSchemasLock sync.Mutex
+
+	// unsealTOTPCodes tracks the TOTP codes used along with unseal key
+	// shares and the attempts made with each share
+	unsealTOTPCodes *cache.Cache
+
 	// auth is loaded after unseal since it is a protected
 	// configuration
 	auth *MountTable
@@ -393,6 +408,27 @@ type Core struct {
 	// activityLog is used to track active client count
 	activityLog *ActivityLog
 
+	// secretSync mirrors KV secrets into external secret stores
+	secretSync *secretSyncManager
+
+	// pprofCollector periodically captures profiles of this node
+	pprofCollector *pprofCollector
+
+	// configApplyLock serializes the applications of configuration specs, so
+	// that each one is planned against the result of the previous one
+	configApplyLock sync.Mutex
+
+	// managedKeyRegistry holds the keys of external key management systems
+	// that secrets engines can sign with
+	managedKeyRegistry *managedKeyRegistry
+
+	// leaseNotifier sends webhooks about leases close to expiry or failing
+	// to renew
+	leaseNotifier *leaseNotifier
+
+	// kvReplication replicates selected KV secrets to other clusters
+	kvReplication *kvReplicationManager
+
 	// metricsCh is used to stop the metrics streaming
 	metricsCh chan struct{}
 
@@ -403,6 +439,10 @@ type Core struct {
 	// inFlightReqMap is used to store info about in-flight requests
 	inFlightReqData *InFlightRequests
 
+	// requestTalkers keeps track of the top talkers by request count and
+	// latency
+	requestTalkers *requestTalkers
+
 	// mfaResponseAuthQueue is used to cache the auth response per request ID
 	mfaResponseAuthQueue     *LoginMFAPriorityQueue
 	mfaResponseAuthQueueLock sync.Mutex
@@ -419,6 +459,10 @@ type Core struct {
 	baseLogger log.Logger
 	logger     log.Logger
 
+	// logMultiplexer streams the logs to the subscribers of sys/monitor. It
+	// is nil when the logger is not an InterceptLogger.
+	logMultiplexer *monitor.Multiplexer
+
 	// log level provided by config, CLI flag, or env
 	logLevel string
 
@@ -519,6 +563,15 @@ type Core struct {
 	// pluginCatalog is used to manage plugin configurations
 	pluginCatalog *PluginCatalog
 
+	// idempotencyRecords holds the outcome of requests made with idempotency
+	// keys, keyed by mount, client and idempotency key
+	idempotencyRecords *cache.Cache
+
+	// geoIP resolves the country and autonomous system of client addresses
+	// for token constraints and audit logs; its databases are reopened on
+	// reload
+	geoIP *geoip.Resolver
+
 	// The userFailedLoginInfo map has user failed login information.
 	// It has user information (alias-name and mount accessor) as a key
 	// and login counter, last failed login time as value
@@ -534,6 +587,9 @@ type Core struct {
 	// Stores the sealunwrapper for downgrade needs
 	sealUnwrapper physical.Backend
 
+	// sealWrapper seal wraps the storage entries of critical paths
+	sealWrapper *sealWrapper
+
 	// unsealwithStoredKeysLock is a mutex that prevents multiple processes from
 	// unsealing with stored keys are the same time.
 	unsealWithStoredKeysLock sync.Mutex
@@ -586,6 +642,9 @@ type Core struct {
 	// rawConfig stores the config as-is from the provided server configuration.
 	rawConfig *atomic.Value
 
+	// configReloadStatus holds the outcome of the last reload of rawConfig
+	configReloadStatus configReloadStatus
+
 	coreNumber int
 
 	// secureRandomReader is the reader used for CSP operations
@@ -632,6 +691,14 @@ type Core struct {
 	// disableSSCTokens is used to disable server side consistent token creation/usage
 	disableSSCTokens bool
 
+	// tokenAccessorWriteBehind is used to persist the accessor index of new
+	// tokens in the background, after the tokens themselves
+	tokenAccessorWriteBehind bool
+
+	// lazyLeaseRestore is used to restore leases from the index of their
+	// expiry times, loading their full entries on demand
+	lazyLeaseRestore bool
+
 	// versionHistory is a map of vault versions to VaultVersion. The
 	// VaultVersion.TimestampInstalled when the version will denote when the version
 	// was first run. Note that because perf standbys should be upgraded first, and
@@ -706,6 +773,14 @@ type CoreConfig struct {
 	// Custom cache size for the LRU cache on the physical backend, or zero for default
 	CacheSize int
 
+	// Custom size in bytes for the LRU cache on the physical backend, or zero
+	// for default
+	CacheSizeBytes int64
+
+	// Custom prefixes of the entries evicted last from the LRU cache on the
+	// physical backend, or nil for default
+	CachePinnedPrefixes []string
+
 	// Set as the leader address for HA
 	RedirectAddr string
 
@@ -731,6 +806,11 @@ type CoreConfig struct {
 
 	PluginFilePermissions int
 
+	// GeoIPCountryDatabase and GeoIPASNDatabase are the paths of the MaxMind
+	// DB files used to resolve the country and autonomous system of clients
+	GeoIPCountryDatabase string
+	GeoIPASNDatabase     string
+
 	DisableSealWrap bool
 
 	RawConfig *server.Config
@@ -775,6 +855,16 @@ type CoreConfig struct {
 	// DisableSSCTokens is used to disable the use of server side consistent tokens
 	DisableSSCTokens bool
 
+	// TokenAccessorWriteBehind is used to persist the accessor index of new
+	// tokens in the background, trading the immediate availability of their
+	// accessors for fewer storage writes on the login path
+	TokenAccessorWriteBehind bool
+
+	// LazyLeaseRestore is used to maintain an index of the expiry times of
+	// leases, from which they are restored at unseal rather than from their
+	// full entries
+	LazyLeaseRestore bool
+
 	EffectiveSDKVersion string
 
 	RollbackPeriod time.Duration
@@ -928,7 +1018,10 @@ func CreateCore(conf *CoreConfig) (*Core, error) {
 		enableResponseHeaderHostname:   conf.EnableResponseHeaderHostname,
 		enableResponseHeaderRaftNodeID: conf.EnableResponseHeaderRaftNodeID,
 		mountMigrationTracker:          &sync.Map{},
+		unsealTOTPCodes:                cache.New(unsealTOTPAttemptsWindow, time.Minute),
 		disableSSCTokens:               conf.DisableSSCTokens,
+		tokenAccessorWriteBehind:       conf.TokenAccessorWriteBehind,
+		lazyLeaseRestore:               conf.LazyLeaseRestore,
 		effectiveSDKVersion:            effectiveSDKVersion,
 		userFailedLoginInfo:            make(map[FailedLoginUser]*FailedLoginInfo),
 	}
@@ -939,6 +1032,10 @@ func CreateCore(conf *CoreConfig) (*Core, error) {
 
 	c.allLoggers = append(c.allLoggers, c.logger)
 
+	if interceptLogger, ok := c.logger.(log.InterceptLogger); ok {
+		c.logMultiplexer = monitor.NewMultiplexer(interceptLogger)
+	}
+
 	c.router.logger = c.logger.Named("router")
 	c.allLoggers = append(c.allLoggers, c.router.logger)
 
@@ -946,6 +1043,7 @@ func CreateCore(conf *CoreConfig) (*Core, error) {
 		InFlightReqMap:   &sync.Map{},
 		InFlightReqCount: uberAtomic.NewUint64(0),
 	}
+	c.requestTalkers = newRequestTalkers()
 
 	c.SetConfig(conf.RawConfig)
 
@@ -1079,6 +1177,11 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		c.pluginFilePermissions = conf.PluginFilePermissions
 	}
 
+	c.geoIP, err = geoip.NewResolver(conf.GeoIPCountryDatabase, conf.GeoIPASNDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("core setup failed: %w", err)
+	}
+
 	createSecondaries(c, conf)
 
 	if conf.HAPhysical != nil && conf.HAPhysical.HAEnabled() {
@@ -2179,6 +2282,9 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 	if err := c.setupMounts(ctx); err != nil {
 		return err
 	}
+	if c.isPrimary() {
+		go c.mountTrashPurgeLoop(c.activeContext)
+	}
 	if err := enterpriseSetupAPILock(c, ctx); err != nil {
 		return err
 	}
@@ -2239,6 +2345,21 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 		if err := c.setupActivityLog(ctx, &wg); err != nil {
 			return err
 		}
+		if err := c.setupSecretSync(ctx); err != nil {
+			return err
+		}
+		if err := c.setupLeaseNotifications(ctx); err != nil {
+			return err
+		}
+		if err := c.setupKVReplication(ctx); err != nil {
+			return err
+		}
+		if err := c.setupRequestTalkers(ctx); err != nil {
+			return err
+		}
+		if err := c.setupPprofCollection(ctx); err != nil {
+			return err
+		}
 	} else {
 		c.auditBroker = NewAuditBroker(c.logger)
 	}
@@ -2345,6 +2466,8 @@ func (c *Core) postUnseal(ctx context.Context, ctxCancelFunc context.CancelFunc,
 		c.logger.Warn("disabling entities for local auth mounts through env var", "env", EnvVaultDisableLocalAuthMountEntities)
 	}
 	c.loginMFABackend.usedCodes = cache.New(0, 30*time.Second)
+	c.loginMFABackend.stepUpAssertions = cache.New(maxStepUpMaxAge, time.Minute)
+	c.idempotencyRecords = cache.New(maxIdempotencyKeyTTL, time.Minute)
 	if c.systemBackend != nil && c.systemBackend.mfaBackend != nil {
 		c.systemBackend.mfaBackend.usedCodes = cache.New(0, 30*time.Second)
 	}
@@ -2392,6 +2515,12 @@ func (c *Core) preSeal() error {
 		result = multierror.Append(result, fmt.Errorf("error stopping expiration: %w", err))
 	}
 	c.stopActivityLog()
+	c.stopSecretSync()
+	c.stopManagedKeyRegistry()
+	c.stopLeaseNotifications()
+	c.stopKVReplication()
+	c.stopRequestTalkers()
+	c.stopPprofCollection()
 
 	if err := c.teardownCredentials(context.Background()); err != nil {
 		result = multierror.Append(result, fmt.Errorf("error tearing down credentials: %w", err))
@@ -2960,6 +3089,21 @@ func (c *Core) ReloadCustomResponseHeaders() error {
 	return nil
 }
 
+// ReloadGeoIP opens the GeoIP databases of the current config again, so
+// that updated databases are used. The databases in use are kept on error.
+func (c *Core) ReloadGeoIP() error {
+	conf := c.rawConfig.Load()
+	if conf == nil {
+		return fmt.Errorf("failed to load core raw config")
+	}
+	config := conf.(*server.Config)
+
+	if err := c.geoIP.Reload(config.GeoIPCountryDatabase, config.GeoIPASNDatabase); err != nil {
+		return fmt.Errorf("failed to reload GeoIP databases: %w", err)
+	}
+	return nil
+}
+
 // SanitizedConfig returns a sanitized version of the current config.
 // See server.Config.Sanitized for specific values omitted.
 func (c *Core) SanitizedConfig() map[string]interface{} {
@@ -3166,6 +3310,10 @@ func (c *Core) loadLoginMFAConfigs(ctx context.Context) error {
 		}
 
 		eConfigs = append(eConfigs, loadedConfigs...)
+
+		if err := c.loginMFABackend.loadMFAStepUpEnforcements(ctx, ns); err != nil {
+			return fmt.Errorf("error loading MFA step-up enforcements, namespaceid %s, error: %w", ns.ID, err)
+		}
 	}
 
 	for _, conf := range eConfigs {
@@ -3238,6 +3386,11 @@ type InFlightReqData struct {
 	ReqPath          string    `json:"request_path"`
 	Method           string    `json:"request_method"`
 	ClientID         string    `json:"client_id"`
+
+	// TokenAccessor and EntityID identify the caller for the top talkers
+	// counters; they are not reported as part of the in-flight requests.
+	TokenAccessor string `json:"-"`
+	EntityID      string `json:"-"`
 }
 
 func (c *Core) StoreInFlightReqData(reqID string, data InFlightReqData) {
@@ -3254,6 +3407,10 @@ func (c *Core) FinalizeInFlightReqData(reqID string, statusCode int) {
 		c.LogCompletedRequests(reqID, statusCode)
 	}
 
+	if v, ok := c.inFlightReqData.InFlightReqMap.Load(reqID); ok {
+		c.requestTalkers.record(v.(InFlightReqData), time.Now())
+	}
+
 	c.inFlightReqData.InFlightReqMap.Delete(reqID)
 	c.inFlightReqData.InFlightReqCount.Dec()
 }
@@ -3273,8 +3430,8 @@ func (c *Core) LoadInFlightReqData() map[string]InFlightReqData {
 }
 
 // UpdateInFlightReqData updates the data for a specific reqID with
-// the clientID
-func (c *Core) UpdateInFlightReqData(reqID, clientID string) {
+// the clientID and the identity of the token used
+func (c *Core) UpdateInFlightReqData(reqID, clientID string, auth *logical.Auth) {
 	v, ok := c.inFlightReqData.InFlightReqMap.Load(reqID)
 	if !ok {
 		c.Logger().Trace("failed to retrieve request with ID", "request_id", reqID)
@@ -3284,6 +3441,10 @@ func (c *Core) UpdateInFlightReqData(reqID, clientID string) {
 	// there is only one writer to this map, so skip checking for errors
 	reqData := v.(InFlightReqData)
 	reqData.ClientID = clientID
+	if auth != nil {
+		reqData.TokenAccessor = auth.Accessor
+		reqData.EntityID = auth.EntityID
+	}
 	c.inFlightReqData.InFlightReqMap.Store(reqID, reqData)
 }
 
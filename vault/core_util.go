@@ -39,16 +39,43 @@ func coreInit(c *Core, conf *CoreConfig) error {
 	sealUnwrapperLogger := conf.Logger.Named("storage.sealunwrapper")
 	c.allLoggers = append(c.allLoggers, sealUnwrapperLogger)
 	c.sealUnwrapper = NewSealUnwrapper(phys, sealUnwrapperLogger)
+	c.sealWrapper = newSealWrapper(c, conf.DisableSealWrap)
+	switch u := c.sealUnwrapper.(type) {
+	case *sealUnwrapper:
+		u.sealWrapper = c.sealWrapper
+	case *transactionalSealUnwrapper:
+		u.sealWrapper = c.sealWrapper
+	}
 	// Wrap the physical backend in a cache layer if enabled
 	cacheLogger := c.baseLogger.Named("storage.cache")
 	c.allLoggers = append(c.allLoggers, cacheLogger)
+	cacheConf := &physical.CacheConfig{
+		Size:           conf.CacheSize,
+		SizeBytes:      conf.CacheSizeBytes,
+		PinnedPrefixes: conf.CachePinnedPrefixes,
+	}
+	var cache *physical.Cache
 	if txnOK {
-		c.physical = physical.NewTransactionalCache(c.sealUnwrapper, conf.CacheSize, cacheLogger, c.MetricSink().Sink)
+		txnCache := physical.NewTransactionalCacheWithConfig(c.sealUnwrapper, cacheConf, cacheLogger, c.MetricSink().Sink)
+		cache = txnCache.Cache
+		c.physical = txnCache
 	} else {
-		c.physical = physical.NewCache(c.sealUnwrapper, conf.CacheSize, cacheLogger, c.MetricSink().Sink)
+		cache = physical.NewCacheWithConfig(c.sealUnwrapper, cacheConf, cacheLogger, c.MetricSink().Sink)
+		c.physical = cache
 	}
 	c.physicalCache = c.physical.(physical.ToggleablePurgemonster)
 
+	// Drop the entries changed by other nodes from the cache
+	if invalidator, ok := phys.(physical.Invalidator); ok {
+		invalidator.SetInvalidationHandler(func(key string) {
+			if key == "" {
+				cache.Purge(context.Background())
+				return
+			}
+			cache.Invalidate(key)
+		})
+	}
+
 	// Wrap in encoding checks
 	if !conf.DisableKeyEncodingChecks {
 		c.physical = physical.NewStorageEncoding(c.physical)
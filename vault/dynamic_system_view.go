@@ -3,6 +3,8 @@ package vault
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/vault/helper/identity"
@@ -60,6 +62,70 @@ func (e extendedSystemViewImpl) ForwardGenericRequest(ctx context.Context, req *
 	return nil, logical.ErrReadOnly
 }
 
+// PKIIssuers returns the PEM encoded certificates of the issuers of the PKI
+// mount at mount, in the namespace of the backend. They are read through the
+// unauthenticated endpoints of the mount, with requests carrying no token.
+func (e extendedSystemViewImpl) PKIIssuers(ctx context.Context, mount string) ([]string, error) {
+	ctx = namespace.ContextWithNamespace(ctx, e.mountEntry.Namespace())
+	mount = strings.Trim(mount, "/") + "/"
+	entry := e.core.router.MatchingMountEntry(ctx, mount)
+	if entry == nil || entry.Type != "pki" || entry.Path != mount {
+		return nil, fmt.Errorf("no PKI secrets engine is mounted at %q", mount)
+	}
+
+	resp, err := e.core.router.Route(ctx, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      mount + "issuers/",
+	})
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("failed to list the issuers of %q: %w", mount, err)
+	case resp == nil:
+		return nil, nil
+	case resp.IsError():
+		return nil, fmt.Errorf("failed to list the issuers of %q: %w", mount, resp.Error())
+	}
+	ids, _ := resp.Data["keys"].([]string)
+
+	var certificates []string
+	for _, id := range ids {
+		resp, err := e.core.router.Route(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      mount + "issuer/" + id + "/json",
+		})
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("failed to read issuer %q of %q: %w", id, mount, err)
+		case resp == nil:
+			// The issuer was deleted after being listed
+			continue
+		case resp.IsError():
+			return nil, fmt.Errorf("failed to read issuer %q of %q: %w", id, mount, resp.Error())
+		}
+		if certificate, ok := resp.Data["certificate"].(string); ok {
+			certificates = append(certificates, certificate)
+		}
+	}
+	return certificates, nil
+}
+
+// entropySystemView is the system view of the mounts enabled with
+// external_entropy_access when entropy augmentation is configured. The
+// framework samples it for the randomness of key generation, see
+// framework.Backend.GetRandomReader.
+type entropySystemView struct {
+	extendedSystemViewImpl
+}
+
+// GetRandom returns randomness mixed with the external entropy source.
+func (e entropySystemView) GetRandom(bytes int) ([]byte, error) {
+	out := make([]byte, bytes)
+	if _, err := io.ReadFull(e.core.secureRandomReader, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SudoPrivilege returns true if given path has sudo privileges
 // for the given client token
 func (e extendedSystemViewImpl) SudoPrivilege(ctx context.Context, path string, token string) bool {
@@ -102,15 +168,20 @@ func (e extendedSystemViewImpl) SudoPrivilege(ctx context.Context, path string,
 
 	tokenCtx := namespace.ContextWithNamespace(ctx, tokenNS)
 
-	// Add the inline policy if it's set
+	// Add the inline policy if it's set, unless it scopes down the token
 	policies := make([]*Policy, 0)
+	var scopePolicy *Policy
 	if te.InlinePolicy != "" {
 		inlinePolicy, err := ParseACLPolicy(tokenNS, te.InlinePolicy)
 		if err != nil {
 			e.core.logger.Error("failed to parse the token's inline policy", "error", err)
 			return false
 		}
-		policies = append(policies, inlinePolicy)
+		if inlinePolicyIsScope(te) {
+			scopePolicy = inlinePolicy
+		} else {
+			policies = append(policies, inlinePolicy)
+		}
 	}
 
 	// Construct the corresponding ACL object. Derive and use a new context that
@@ -120,6 +191,12 @@ func (e extendedSystemViewImpl) SudoPrivilege(ctx context.Context, path string,
 		e.core.logger.Error("failed to retrieve ACL for token's policies", "token_policies", te.Policies, "error", err)
 		return false
 	}
+	if scopePolicy != nil {
+		if err := acl.setScope(tokenCtx, scopePolicy); err != nil {
+			e.core.logger.Error("failed to retrieve ACL scope for token's inline policy", "error", err)
+			return false
+		}
+	}
 
 	// The operation type isn't important here as this is run from a path the
 	// user has already been given access to; we only care about whether they
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"reflect"
@@ -11,6 +12,7 @@ import (
 
 	log "github.com/hashicorp/go-hclog"
 	ldapcred "github.com/hashicorp/vault/builtin/credential/ldap"
+	"github.com/hashicorp/vault/helper/entropy"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -292,6 +294,48 @@ func TestDynamicSystemView_GeneratePasswordFromPolicy_failed(t *testing.T) {
 	}
 }
 
+type testEntropySource struct {
+	calls int
+}
+
+func (s *testEntropySource) GetRandom(bytes int) ([]byte, error) {
+	s.calls++
+	out := make([]byte, bytes)
+	_, err := rand.Read(out)
+	return out, err
+}
+
+func TestDynamicSystemView_ExternalEntropy(t *testing.T) {
+	source := &testEntropySource{}
+	reader, err := entropy.NewAugmentedReader(source, entropy.FallbackFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := TestCore(t)
+	c.secureRandomReader = reader
+
+	if _, ok := c.mountEntrySysView(&MountEntry{}).(entropy.Source); ok {
+		t.Fatal("mounts without external_entropy_access should not have access to the external source")
+	}
+
+	sysView, ok := c.mountEntrySysView(&MountEntry{ExternalEntropyAccess: true}).(entropy.Source)
+	if !ok {
+		t.Fatal("expected the system view to provide entropy")
+	}
+	out, err := sysView.GetRandom(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 32 || source.calls != 1 {
+		t.Fatalf("unexpected output %v after %d calls to the source", out, source.calls)
+	}
+
+	c = TestCore(t)
+	if _, ok := c.mountEntrySysView(&MountEntry{ExternalEntropyAccess: true}).(entropy.Source); ok {
+		t.Fatal("external entropy should only be available with entropy augmentation")
+	}
+}
+
 type runes []rune
 
 func (r runes) Len() int           { return len(r) }
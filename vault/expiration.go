@@ -86,6 +86,10 @@ type pendingInfo struct {
 	cachedLeaseInfo  *leaseEntry
 	timer            *time.Timer
 	revokesAttempted uint8
+
+	// restoredFromIndex is whether the lease was restored from the lease
+	// expiry index, and its full entry was not loaded since
+	restoredFromIndex bool
 }
 
 // ExpirationManager is used by the Core to manage leases. Secrets
@@ -97,6 +101,7 @@ type ExpirationManager struct {
 	router     *Router
 	idView     *BarrierView
 	tokenView  *BarrierView
+	tagView    *BarrierView
 	tokenStore *TokenStore
 	logger     log.Logger
 
@@ -129,6 +134,11 @@ type ExpirationManager struct {
 
 	tidyLock *int32
 
+	// indexView holds the lease expiry index, maintained when leases are
+	// restored lazily, and leaseIndex its shards once read.
+	indexView  *BarrierView
+	leaseIndex []*leaseIndexShard
+
 	restoreMode        *int32
 	restoreModeLock    sync.RWMutex
 	restoreRequestLock sync.RWMutex
@@ -224,10 +234,16 @@ func (r *revocationJob) Execute() error {
 	}
 
 	r.m.coreStateLock.RLock()
-	err := r.m.Revoke(revokeCtx, r.leaseID)
-	r.m.coreStateLock.RUnlock()
+	defer r.m.coreStateLock.RUnlock()
 
-	return err
+	// Leases restored from the lease expiry index are only revoked once
+	// their full entry confirms they expired
+	due, err := r.m.hydrateIndexedLease(revokeCtx, r.leaseID)
+	if err != nil || !due {
+		return err
+	}
+
+	return r.m.Revoke(revokeCtx, r.leaseID)
 }
 
 func (r *revocationJob) OnFailure(err error) {
@@ -324,6 +340,9 @@ func NewExpirationManager(c *Core, view *BarrierView, e ExpireLeaseStrategy, log
 		router:      c.router,
 		idView:      view.SubView(leaseViewPrefix),
 		tokenView:   view.SubView(tokenViewPrefix),
+		tagView:     view.SubView(tagViewPrefix),
+		indexView:   view.SubView(leaseIndexPrefix),
+		leaseIndex:  newLeaseIndexShards(),
 		tokenStore:  c.tokenStore,
 		logger:      logger,
 		pending:     sync.Map{},
@@ -668,6 +687,22 @@ func (m *ExpirationManager) Restore(errorFunc func()) (retErr error) {
 		}
 	}()
 
+	// Once every lease is indexed, leases are restored from the lease
+	// expiry index alone, without listing or loading their entries
+	indexComplete := false
+	if m.core.lazyLeaseRestore {
+		var err error
+		indexComplete, err = m.leaseIndexComplete(m.quitContext)
+		if err != nil {
+			return err
+		}
+	} else if err := m.clearLeaseIndex(m.quitContext); err != nil {
+		return err
+	}
+	if indexComplete {
+		return m.restoreLazily()
+	}
+
 	// Accumulate existing leases
 	m.logger.Debug("collecting leases")
 	existing, leaseCount, err := m.collectLeases()
@@ -675,6 +710,10 @@ func (m *ExpirationManager) Restore(errorFunc func()) (retErr error) {
 		return err
 	}
 	m.logger.Debug("leases collected", "num_existing", leaseCount)
+	m.core.metricSink.SetGauge([]string{"expire", "restore", "leases"}, float32(leaseCount))
+	if m.core.lazyLeaseRestore {
+		m.logger.Info("loading and indexing leases for the lease expiry index", "num_leases", leaseCount)
+	}
 
 	// Make the channels used for the worker pool
 	type lease struct {
@@ -735,6 +774,7 @@ func (m *ExpirationManager) Restore(errorFunc func()) (retErr error) {
 				i++
 				if i%500 == 0 {
 					m.logger.Debug("leases loading", "progress", i)
+					m.core.metricSink.SetGauge([]string{"expire", "restore", "loaded"}, float32(i))
 				}
 
 				select {
@@ -780,6 +820,36 @@ LOOP:
 		return err
 	}
 
+	// Every lease is indexed now
+	if m.core.lazyLeaseRestore {
+		if err := m.markLeaseIndexComplete(m.quitContext); err != nil {
+			return err
+		}
+	}
+
+	m.finishRestore()
+	m.core.metricSink.SetGauge([]string{"expire", "restore", "loaded"}, float32(leaseCount))
+	m.logger.Info("lease restore complete")
+	return nil
+}
+
+// restoreLazily restores the leases from the lease expiry index, leaving
+// their full entries to be loaded when they expire.
+func (m *ExpirationManager) restoreLazily() error {
+	m.logger.Debug("restoring leases from the lease expiry index")
+	leaseCount, err := m.restoreFromIndex(m.quitContext)
+	if err != nil {
+		return err
+	}
+	m.core.metricSink.SetGauge([]string{"expire", "restore", "leases"}, float32(leaseCount))
+
+	m.finishRestore()
+	m.logger.Info("lease restore complete", "num_indexed", leaseCount)
+	return nil
+}
+
+// finishRestore turns off restore mode
+func (m *ExpirationManager) finishRestore() {
 	m.restoreModeLock.Lock()
 	atomic.StoreInt32(m.restoreMode, 0)
 	m.restoreLoaded.Range(func(k, v interface{}) bool {
@@ -788,9 +858,6 @@ LOOP:
 	})
 	m.restoreLocks = nil
 	m.restoreModeLock.Unlock()
-
-	m.logger.Info("lease restore complete")
-	return nil
 }
 
 // processRestore takes a lease and restores it in the expiration manager if it has
@@ -819,6 +886,13 @@ func (m *ExpirationManager) processRestore(ctx context.Context, leaseID string)
 		return err
 	}
 
+	// Index the lease, so that the next restore doesn't load it in full
+	if le != nil {
+		if err := m.indexLease(ctx, le); err != nil {
+			return err
+		}
+	}
+
 	// Update quotas with relevant lease information
 	if le != nil {
 		leaseInfo := &quotas.QuotaLeaseInformation{LeaseId: le.LeaseID, Role: le.LoginRole}
@@ -1024,6 +1098,10 @@ func (m *ExpirationManager) revokeCommon(ctx context.Context, leaseID string, fo
 				return err
 			}
 		}
+
+		if err := m.removeTagIndexes(ctx, le); err != nil {
+			return err
+		}
 	}
 
 	// Clear the expiration handler
@@ -1225,12 +1303,14 @@ func (m *ExpirationManager) Renew(ctx context.Context, leaseID string, increment
 	// Attempt to renew the entry
 	resp, err := m.renewEntry(ctx, le, increment)
 	if err != nil {
+		m.core.notifyLeaseRenewalFailed(leaseID, le.ExpireTime, err.Error())
 		return nil, err
 	}
 	if resp == nil {
 		return nil, nil
 	}
 	if resp.IsError() {
+		m.core.notifyLeaseRenewalFailed(leaseID, le.ExpireTime, resp.Error().Error())
 		return &logical.Response{
 			Data: resp.Data,
 		}, nil
@@ -1251,6 +1331,9 @@ func (m *ExpirationManager) Renew(ctx context.Context, leaseID string, increment
 	// Attach the LeaseID
 	resp.Secret.LeaseID = leaseID
 
+	// Tags are set at creation and kept across renewals, as they are indexed
+	resp.Secret.Tags = le.Secret.Tags
+
 	// Update the lease entry
 	le.Data = resp.Data
 	le.Secret = resp.Secret
@@ -1351,12 +1434,14 @@ func (m *ExpirationManager) RenewToken(ctx context.Context, req *logical.Request
 	// Attempt to renew the auth entry
 	resp, err := m.renewAuthEntry(ctx, req, le, increment)
 	if err != nil {
+		m.core.notifyLeaseRenewalFailed(le.LeaseID, le.ExpireTime, err.Error())
 		return nil, err
 	}
 	if resp == nil {
 		return nil, nil
 	}
 	if resp.IsError() {
+		m.core.notifyLeaseRenewalFailed(le.LeaseID, le.ExpireTime, resp.Error().Error())
 		return &logical.Response{
 			Data: resp.Data,
 		}, nil
@@ -1496,6 +1581,10 @@ func (m *ExpirationManager) Register(ctx context.Context, req *logical.Request,
 				retErr = multierror.Append(retErr, fmt.Errorf("an additional error was encountered removing lease indexes associated with the newly-generated secret: %w", err))
 			}
 
+			if err := m.removeTagIndexes(ctx, le); err != nil {
+				retErr = multierror.Append(retErr, fmt.Errorf("an additional error was encountered removing lease tag indexes associated with the newly-generated secret: %w", err))
+			}
+
 			m.deleteLockForLease(leaseID)
 		}
 	}()
@@ -1534,6 +1623,10 @@ func (m *ExpirationManager) Register(ctx context.Context, req *logical.Request,
 		}
 	}
 
+	if err := m.createTagIndexes(ctx, le); err != nil {
+		return "", err
+	}
+
 	// Setup revocation timer if there is a lease
 	m.updatePending(le)
 
@@ -2079,6 +2172,10 @@ func (m *ExpirationManager) persistEntry(ctx context.Context, le *leaseEntry) er
 		ent.SealWrap = true
 	}
 
+	if err := m.indexLease(ctx, le); err != nil {
+		return err
+	}
+
 	view := m.leaseView(le.namespace)
 	if err := view.Put(ctx, &ent); err != nil {
 		return fmt.Errorf("failed to persist lease entry: %w", err)
@@ -2092,7 +2189,7 @@ func (m *ExpirationManager) deleteEntry(ctx context.Context, le *leaseEntry) err
 	if err := view.Delete(ctx, le.LeaseID); err != nil {
 		return fmt.Errorf("failed to delete lease entry: %w", err)
 	}
-	return nil
+	return m.unindexLease(ctx, le.LeaseID)
 }
 
 // createIndexByToken creates a secondary index from the token to a lease entry
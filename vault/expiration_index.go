@@ -0,0 +1,348 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/quotas"
+)
+
+const (
+	// leaseIndexPrefix is the prefix used for the index of the expiry times
+	// of leases, from which leases are restored when lazy_lease_restore is
+	// enabled.
+	leaseIndexPrefix = "expiry-index/"
+
+	// leaseIndexShardPrefix is the prefix of the shards of the lease expiry
+	// index, under leaseIndexPrefix
+	leaseIndexShardPrefix = "shard/"
+
+	// leaseIndexCompleteKey is written under leaseIndexPrefix once every
+	// lease has been indexed, after which leases are restored from the index
+	// alone.
+	leaseIndexCompleteKey = "complete"
+
+	// leaseIndexShardCount is the number of shards of the lease expiry index
+	leaseIndexShardCount = 256
+)
+
+// leaseIndexShard is a shard of the lease expiry index. It holds the subset
+// of the lease entries kept in memory, by lease ID, for the leases whose ID
+// hashes to the shard. The shard is kept in memory once read and written as
+// a whole, so that updating it doesn't read it back from storage; concurrent
+// updates of a shard are written together.
+type leaseIndexShard struct {
+	key string
+
+	// l guards the fields below
+	l       sync.Mutex
+	loaded  bool
+	leases  map[string]*leaseEntry
+	version uint64
+
+	// flushLock is held while the shard is written, flushed is the version
+	// of the shard last written
+	flushLock sync.Mutex
+	flushed   uint64
+}
+
+func newLeaseIndexShards() []*leaseIndexShard {
+	shards := make([]*leaseIndexShard, leaseIndexShardCount)
+	for i := range shards {
+		shards[i] = &leaseIndexShard{
+			key: leaseIndexShardPrefix + hex.EncodeToString([]byte{byte(i)}),
+		}
+	}
+	return shards
+}
+
+// leaseIndexShardFor returns the shard of the lease expiry index of the lease
+func (m *ExpirationManager) leaseIndexShardFor(leaseID string) *leaseIndexShard {
+	sum := sha256.Sum256([]byte(leaseID))
+	return m.leaseIndex[sum[0]]
+}
+
+// leaseIndexInfo returns the subset of the lease entry recorded in the
+// lease expiry index, which is what is kept in memory for pending leases.
+func (m *ExpirationManager) leaseIndexInfo(le *leaseEntry) *leaseEntry {
+	ret := m.leaseTimesForExport(le)
+	if le.Auth != nil {
+		ret.Auth.Policies = le.Auth.Policies
+		ret.Path = le.Path
+	}
+	ret.RevokeErr = le.RevokeErr
+	ret.LoginRole = le.LoginRole
+	return ret
+}
+
+// loadLeaseIndexShard reads the shard from storage, unless it was already.
+// The lock of the shard must be held.
+func (m *ExpirationManager) loadLeaseIndexShard(ctx context.Context, s *leaseIndexShard) error {
+	if s.loaded {
+		return nil
+	}
+
+	out, err := m.indexView.Get(ctx, s.key)
+	if err != nil {
+		return fmt.Errorf("failed to read lease expiry index: %w", err)
+	}
+	leases := make(map[string]*leaseEntry)
+	if out != nil {
+		if err := json.Unmarshal(out.Value, &leases); err != nil {
+			return fmt.Errorf("failed to decode lease expiry index: %w", err)
+		}
+	}
+	s.leases = leases
+	s.loaded = true
+	return nil
+}
+
+// flushLeaseIndexShard writes the shard, unless a version at least as recent
+// as version was already written. Updates made while another write of the
+// shard is in progress are written together once it is done.
+func (m *ExpirationManager) flushLeaseIndexShard(ctx context.Context, s *leaseIndexShard, version uint64) error {
+	s.flushLock.Lock()
+	defer s.flushLock.Unlock()
+
+	if s.flushed >= version {
+		return nil
+	}
+
+	s.l.Lock()
+	current := s.version
+	empty := len(s.leases) == 0
+	entry, err := logical.StorageEntryJSON(s.key, s.leases)
+	s.l.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode lease expiry index: %w", err)
+	}
+
+	if empty {
+		err = m.indexView.Delete(ctx, s.key)
+	} else {
+		err = m.indexView.Put(ctx, entry)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update lease expiry index: %w", err)
+	}
+	s.flushed = current
+	return nil
+}
+
+// updateLeaseIndex records the lease in the lease expiry index, or removes
+// it if le is nil, if leases are restored lazily.
+func (m *ExpirationManager) updateLeaseIndex(ctx context.Context, leaseID string, le *leaseEntry) error {
+	if !m.core.lazyLeaseRestore {
+		return nil
+	}
+
+	s := m.leaseIndexShardFor(leaseID)
+	s.l.Lock()
+	if err := m.loadLeaseIndexShard(ctx, s); err != nil {
+		s.l.Unlock()
+		return err
+	}
+	if le == nil {
+		if _, ok := s.leases[leaseID]; !ok {
+			s.l.Unlock()
+			return nil
+		}
+		delete(s.leases, leaseID)
+	} else {
+		s.leases[leaseID] = m.leaseIndexInfo(le)
+	}
+	s.version++
+	version := s.version
+	s.l.Unlock()
+
+	return m.flushLeaseIndexShard(ctx, s, version)
+}
+
+// indexLease records the lease in the lease expiry index. It is called
+// before the lease entry is written, so that the index never misses a
+// stored lease; indexed leases which were not stored are dropped from the
+// index when they are hydrated.
+func (m *ExpirationManager) indexLease(ctx context.Context, le *leaseEntry) error {
+	return m.updateLeaseIndex(ctx, le.LeaseID, le)
+}
+
+// unindexLease removes the lease from the lease expiry index. It is called
+// after the lease entry is deleted.
+func (m *ExpirationManager) unindexLease(ctx context.Context, leaseID string) error {
+	return m.updateLeaseIndex(ctx, leaseID, nil)
+}
+
+// leaseIndexComplete returns whether every lease is recorded in the lease
+// expiry index.
+func (m *ExpirationManager) leaseIndexComplete(ctx context.Context) (bool, error) {
+	out, err := m.indexView.Get(ctx, leaseIndexCompleteKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read lease expiry index: %w", err)
+	}
+	return out != nil, nil
+}
+
+// markLeaseIndexComplete records that every lease is recorded in the lease
+// expiry index, once all the leases were restored and indexed in full.
+func (m *ExpirationManager) markLeaseIndexComplete(ctx context.Context) error {
+	entry := &logical.StorageEntry{
+		Key:   leaseIndexCompleteKey,
+		Value: []byte(time.Now().UTC().Format(time.RFC3339)),
+	}
+	if err := m.indexView.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to update lease expiry index: %w", err)
+	}
+	return nil
+}
+
+// clearLeaseIndex removes the lease expiry index, which goes stale once
+// lazy_lease_restore is disabled.
+func (m *ExpirationManager) clearLeaseIndex(ctx context.Context) error {
+	if err := logical.ClearView(ctx, m.indexView); err != nil {
+		return fmt.Errorf("failed to remove lease expiry index: %w", err)
+	}
+	return nil
+}
+
+// restoreFromIndex restores every lease from its subset recorded in the
+// lease expiry index, reading the shards of the index only, and returns the
+// number of leases restored. The index must be complete.
+func (m *ExpirationManager) restoreFromIndex(ctx context.Context) (int, error) {
+	restored := 0
+	for _, s := range m.leaseIndex {
+		select {
+		case <-m.quitCh:
+			return 0, context.Canceled
+		default:
+		}
+
+		n, err := m.restoreLeaseIndexShard(ctx, s)
+		if err != nil {
+			return 0, err
+		}
+		restored += n
+		m.core.metricSink.SetGauge([]string{"expire", "restore", "indexed"}, float32(restored))
+	}
+	return restored, nil
+}
+
+// restoreLeaseIndexShard restores the leases of a shard of the lease expiry
+// index and returns how many it restored. Leases of namespaces which no
+// longer exist are skipped.
+func (m *ExpirationManager) restoreLeaseIndexShard(ctx context.Context, s *leaseIndexShard) (int, error) {
+	s.l.Lock()
+	if err := m.loadLeaseIndexShard(ctx, s); err != nil {
+		s.l.Unlock()
+		return 0, err
+	}
+	leases := make([]*leaseEntry, 0, len(s.leases))
+	for leaseID, indexed := range s.leases {
+		le := *indexed
+		le.LeaseID = leaseID
+		leases = append(leases, &le)
+	}
+	s.l.Unlock()
+
+	restored := 0
+	for _, le := range leases {
+		ns := namespace.RootNamespace
+		if _, nsID := namespace.SplitIDFromString(le.LeaseID); nsID != "" {
+			var err error
+			ns, err = NamespaceByID(ctx, nsID, m.core)
+			if err != nil {
+				return 0, err
+			}
+			if ns == nil {
+				m.logger.Warn("skipping indexed lease of a missing namespace", "lease_id", le.LeaseID)
+				continue
+			}
+		}
+		le.namespace = ns
+
+		m.restoreIndexedLease(namespace.ContextWithNamespace(ctx, ns), le)
+		restored++
+	}
+	return restored, nil
+}
+
+// restoreIndexedLease restores a lease from its indexed subset, if it has
+// not already been seen, in the same way processRestore does from its full
+// entry.
+func (m *ExpirationManager) restoreIndexedLease(ctx context.Context, le *leaseEntry) {
+	m.restoreRequestLock.RLock()
+	defer m.restoreRequestLock.RUnlock()
+
+	if _, ok := m.restoreLoaded.Load(le.LeaseID); ok {
+		return
+	}
+
+	m.lockLease(le.LeaseID)
+	defer m.unlockLease(le.LeaseID)
+
+	if _, ok := m.restoreLoaded.Load(le.LeaseID); ok {
+		return
+	}
+	m.restoreLoaded.Store(le.LeaseID, struct{}{})
+
+	m.pendingLock.Lock()
+	m.updatePendingInternal(le)
+	if info, ok := m.pending.Load(le.LeaseID); ok {
+		pending := info.(pendingInfo)
+		pending.restoredFromIndex = true
+		m.pending.Store(le.LeaseID, pending)
+	}
+	m.pendingLock.Unlock()
+
+	leaseInfo := &quotas.QuotaLeaseInformation{LeaseId: le.LeaseID, Role: le.LoginRole}
+	if err := m.core.quotasHandleLeases(ctx, quotas.LeaseActionLoaded, []*quotas.QuotaLeaseInformation{leaseInfo}); err != nil {
+		m.logger.Error("failed to load lease into the quota sub-system", "LeaseID:", le.LeaseID, "LoginRole", le.LoginRole, "error", err)
+	}
+}
+
+// hydrateIndexedLease loads the full entry of a lease restored from the
+// lease expiry index before it is revoked on expiry, in case the index
+// recorded an earlier expiry time than the lease has. It returns whether the
+// lease is due for revocation; if not, its timer is reset to its expiry time.
+// Indexed leases without an entry are dropped.
+func (m *ExpirationManager) hydrateIndexedLease(ctx context.Context, leaseID string) (bool, error) {
+	info, ok := m.pending.Load(leaseID)
+	if !ok || !info.(pendingInfo).restoredFromIndex {
+		return true, nil
+	}
+
+	leaseLock := m.lockForLeaseID(leaseID)
+	leaseLock.Lock()
+	defer leaseLock.Unlock()
+
+	le, err := m.loadEntry(ctx, leaseID)
+	if err != nil {
+		return false, err
+	}
+	if le == nil {
+		m.pendingLock.Lock()
+		m.removeFromPending(ctx, leaseID, true)
+		m.pendingLock.Unlock()
+		return false, m.unindexLease(ctx, leaseID)
+	}
+
+	m.pendingLock.Lock()
+	defer m.pendingLock.Unlock()
+
+	due := !le.ExpireTime.IsZero() && !le.ExpireTime.After(time.Now())
+	if !due {
+		m.updatePendingInternal(le)
+	}
+	if info, ok := m.pending.Load(leaseID); ok {
+		pending := info.(pendingInfo)
+		pending.restoredFromIndex = false
+		m.pending.Store(leaseID, pending)
+	}
+	return due, nil
+}
@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestExpiration_LazyRestore(t *testing.T) {
+	c, _, _ := TestCoreUnsealedWithConfig(t, &CoreConfig{LazyLeaseRestore: true})
+	exp := c.expiration
+	ctx := namespace.RootContext(nil)
+	for exp.inRestoreMode() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	leaseIDs := []string{"prod/aws/foo/lease1", "prod/aws/foo/lease2"}
+	for _, leaseID := range leaseIDs {
+		le := &leaseEntry{
+			LeaseID: leaseID,
+			Path:    "prod/aws/foo",
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+			},
+			IssueTime:  time.Now(),
+			ExpireTime: time.Now().Add(time.Hour),
+			namespace:  namespace.RootNamespace,
+		}
+		if err := exp.persistEntry(ctx, le); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Persisted leases are written to their shard of the index, which is
+	// complete since the leases were restored at unseal
+	shard := readLeaseIndexShard(t, exp, leaseIDs[0])
+	indexed, ok := shard[leaseIDs[0]]
+	if !ok {
+		t.Fatalf("lease missing from the expiry index: %#v", shard)
+	}
+	if indexed.ExpireTime.IsZero() || indexed.Secret == nil || indexed.Secret.TTL != time.Hour {
+		t.Fatalf("bad: %#v", indexed)
+	}
+	complete, err := exp.leaseIndexComplete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !complete {
+		t.Fatal("expected the expiry index to be complete")
+	}
+
+	// Leases are restored from the index alone: a lease whose entry is gone
+	// is restored until it is hydrated
+	if err := exp.idView.Delete(ctx, leaseIDs[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := exp.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.setupExpiration(expireLeaseStrategyFairsharing); err != nil {
+		t.Fatal(err)
+	}
+	exp = c.expiration
+	for exp.inRestoreMode() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Leases are restored from the index without loading their entries
+	exp.pendingLock.RLock()
+	leaseCount := exp.leaseCount
+	exp.pendingLock.RUnlock()
+	if leaseCount != len(leaseIDs) {
+		t.Fatalf("expected %d leases, got %d", len(leaseIDs), leaseCount)
+	}
+	for _, leaseID := range leaseIDs {
+		info, ok := exp.pending.Load(leaseID)
+		if !ok || !info.(pendingInfo).restoredFromIndex {
+			t.Fatalf("lease %q not restored from the expiry index", leaseID)
+		}
+	}
+
+	// Leases which are not due yet are not revoked on hydration
+	due, err := exp.hydrateIndexedLease(ctx, leaseIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due {
+		t.Fatal("lease expiring in an hour is due for revocation")
+	}
+	info, ok := exp.pending.Load(leaseIDs[0])
+	if !ok || info.(pendingInfo).restoredFromIndex {
+		t.Fatal("lease not hydrated")
+	}
+
+	// Indexed leases without an entry are dropped on hydration
+	due, err = exp.hydrateIndexedLease(ctx, leaseIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due {
+		t.Fatal("lease without an entry is due for revocation")
+	}
+	if _, ok := exp.pending.Load(leaseIDs[1]); ok {
+		t.Fatal("lease without an entry still pending")
+	}
+	if _, ok := readLeaseIndexShard(t, exp, leaseIDs[1])[leaseIDs[1]]; ok {
+		t.Fatal("lease without an entry still in the expiry index")
+	}
+
+	// Deleted leases are removed from the index
+	le, err := exp.loadEntry(ctx, leaseIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.deleteEntry(ctx, le); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readLeaseIndexShard(t, exp, leaseIDs[0])[leaseIDs[0]]; ok {
+		t.Fatal("deleted lease still in the expiry index")
+	}
+}
+
+// readLeaseIndexShard reads the shard of the lease expiry index of the lease
+// from storage
+func readLeaseIndexShard(t *testing.T, exp *ExpirationManager, leaseID string) map[string]*leaseEntry {
+	t.Helper()
+
+	out, err := exp.indexView.Get(namespace.RootContext(nil), exp.leaseIndexShardFor(leaseID).key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shard := make(map[string]*leaseEntry)
+	if out != nil {
+		if err := json.Unmarshal(out.Value, &shard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return shard
+}
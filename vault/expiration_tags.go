@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// tagViewPrefix is the prefix used for the tag based lookup of leases.
+	tagViewPrefix = "tag/"
+)
+
+// leaseTagIndexKey returns the key under which the leases with the tag are
+// indexed. Tags are hashed as they can hold any character.
+func leaseTagIndexKey(key, value string) string {
+	sum := sha256.Sum256([]byte(key + "\x00" + value))
+	return hex.EncodeToString(sum[:])
+}
+
+func leaseTagIndexEntryKey(key, value, leaseID string) string {
+	sum := sha256.Sum256([]byte(leaseID))
+	return leaseTagIndexKey(key, value) + "/" + hex.EncodeToString(sum[:])
+}
+
+// createTagIndexes creates a secondary index from each tag of the secret of a
+// lease entry to the lease entry.
+func (m *ExpirationManager) createTagIndexes(ctx context.Context, le *leaseEntry) error {
+	if le.Secret == nil {
+		return nil
+	}
+
+	view := m.tagIndexView(le.namespace)
+	for key, value := range le.Secret.Tags {
+		ent := logical.StorageEntry{
+			Key:   leaseTagIndexEntryKey(key, value, le.LeaseID),
+			Value: []byte(le.LeaseID),
+		}
+		if err := view.Put(ctx, &ent); err != nil {
+			return fmt.Errorf("failed to persist lease tag index entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeTagIndexes removes the secondary indexes from the tags of the secret
+// of a lease entry to the lease entry.
+func (m *ExpirationManager) removeTagIndexes(ctx context.Context, le *leaseEntry) error {
+	if le.Secret == nil {
+		return nil
+	}
+
+	view := m.tagIndexView(le.namespace)
+	for key, value := range le.Secret.Tags {
+		if err := view.Delete(ctx, leaseTagIndexEntryKey(key, value, le.LeaseID)); err != nil {
+			return fmt.Errorf("failed to delete lease tag index entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// LeasesByTag returns the IDs of the leases of the namespace tagged with the
+// given key and value.
+func (m *ExpirationManager) LeasesByTag(ctx context.Context, key, value string) ([]string, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	view := m.tagIndexView(ns)
+	prefix := leaseTagIndexKey(key, value) + "/"
+	subKeys, err := view.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lease tag index: %w", err)
+	}
+
+	leaseIDs := make([]string, 0, len(subKeys))
+	for _, sub := range subKeys {
+		out, err := view.Get(ctx, prefix+sub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lease tag index entry: %w", err)
+		}
+		if out == nil {
+			continue
+		}
+		leaseIDs = append(leaseIDs, string(out.Value))
+	}
+	return leaseIDs, nil
+}
+
+// FetchLeaseTags returns the tags of a lease, or nil if the lease doesn't
+// exist.
+func (m *ExpirationManager) FetchLeaseTags(ctx context.Context, leaseID string) (map[string]string, error) {
+	le, err := m.loadEntryInternal(ctx, leaseID, true, false)
+	if err != nil {
+		return nil, err
+	}
+	if le == nil || le.Secret == nil {
+		return nil, nil
+	}
+	return le.Secret.Tags, nil
+}
@@ -0,0 +1,76 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestExpiration_TagIndex(t *testing.T) {
+	exp := mockExpiration(t)
+	ctx := namespace.RootContext(nil)
+
+	register := func(tags map[string]string) string {
+		t.Helper()
+		req := &logical.Request{
+			Operation:   logical.ReadOperation,
+			Path:        "prod/database/creds/billing",
+			ClientToken: "foobar",
+		}
+		req.SetTokenEntry(&logical.TokenEntry{ID: "foobar", NamespaceID: "root"})
+		resp := &logical.Response{
+			Secret: &logical.Secret{
+				LeaseOptions: logical.LeaseOptions{
+					TTL: time.Hour,
+				},
+				Tags: tags,
+			},
+		}
+		id, err := exp.Register(ctx, req, resp, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	billing := register(map[string]string{"app": "billing", "environment": "prod"})
+	register(map[string]string{"app": "search", "environment": "prod"})
+	register(nil)
+
+	leaseIDs, err := exp.LeasesByTag(ctx, "app", "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaseIDs) != 1 || leaseIDs[0] != billing {
+		t.Fatalf("bad: %v", leaseIDs)
+	}
+	leaseIDs, err = exp.LeasesByTag(ctx, "environment", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaseIDs) != 2 {
+		t.Fatalf("expected 2 leases, got %v", leaseIDs)
+	}
+
+	tags, err := exp.FetchLeaseTags(ctx, billing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags["app"] != "billing" || tags["environment"] != "prod" {
+		t.Fatalf("bad: %v", tags)
+	}
+
+	// Revoked leases are removed from the index
+	if err := exp.revokeCommon(ctx, billing, true, false); err != nil {
+		t.Fatal(err)
+	}
+	leaseIDs, err = exp.LeasesByTag(ctx, "app", "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaseIDs) != 0 {
+		t.Fatalf("revoked lease still indexed: %v", leaseIDs)
+	}
+}
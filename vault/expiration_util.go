@@ -17,6 +17,10 @@ func (m *ExpirationManager) tokenIndexView(*namespace.Namespace) *BarrierView {
 	return m.tokenView
 }
 
+func (m *ExpirationManager) tagIndexView(*namespace.Namespace) *BarrierView {
+	return m.tagView
+}
+
 func (m *ExpirationManager) collectLeases() (map[*namespace.Namespace][]string, int, error) {
 	leaseCount := 0
 	existing := make(map[*namespace.Namespace][]string)
@@ -261,7 +261,7 @@ func TestCore_EnableExternalPlugin_MultipleVersions(t *testing.T) {
 				t.Fatalf("missing mount, match: %q", match)
 			}
 
-			raw, _ := c.router.root.Get(match)
+			raw, _ := c.router.trees.Load().root.Get([]byte(match))
 			if raw.(*routeEntry).mountEntry.Version != tc.expectedVersion {
 				t.Errorf("Expected mount to be version %s but got %s", tc.expectedVersion, raw.(*routeEntry).mountEntry.Version)
 			}
@@ -495,7 +495,7 @@ func TestCore_EnableExternalPlugin_NoVersionsOkay(t *testing.T) {
 				t.Fatalf("missing mount, match: %q", match)
 			}
 
-			raw, _ := c.router.root.Get(match)
+			raw, _ := c.router.trees.Load().root.Get([]byte(match))
 			if raw.(*routeEntry).mountEntry.Version != "" {
 				t.Errorf("Expected mount to be empty version but got %s", raw.(*routeEntry).mountEntry.Version)
 			}
@@ -546,6 +546,80 @@ func TestRaft_SnapshotAPI(t *testing.T) {
 	}
 }
 
+func TestRaft_SnapshotAPI_PreviewAndSelectiveRestore(t *testing.T) {
+	t.Parallel()
+	cluster := raftCluster(t, nil)
+	defer cluster.Cleanup()
+
+	leaderClient := cluster.Cores[0].Client
+	require.NoError(t, leaderClient.Sys().Mount("other", &api.MountInput{Type: "kv"}))
+
+	for i := 0; i < 10; i++ {
+		_, err := leaderClient.Logical().Write(fmt.Sprintf("secret/%d", i), map[string]interface{}{
+			"test": "data",
+		})
+		require.NoError(t, err)
+	}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, leaderClient.Sys().RaftSnapshot(buf))
+	snap := buf.Bytes()
+
+	// Change secret/ and other/ after the snapshot.
+	for i := 10; i < 20; i++ {
+		_, err := leaderClient.Logical().Write(fmt.Sprintf("secret/%d", i), map[string]interface{}{
+			"test": "data",
+		})
+		require.NoError(t, err)
+	}
+	_, err := leaderClient.Logical().Write("secret/0", map[string]interface{}{
+		"test": "changed",
+	})
+	require.NoError(t, err)
+	_, err = leaderClient.Logical().Write("other/after", map[string]interface{}{
+		"test": "data",
+	})
+	require.NoError(t, err)
+	require.NoError(t, leaderClient.Sys().Mount("new", &api.MountInput{Type: "kv"}))
+
+	preview, err := leaderClient.Sys().RaftSnapshotPreview(bytes.NewReader(snap))
+	require.NoError(t, err)
+	removed := preview.Data["mounts"].(map[string]interface{})["removed"].([]interface{})
+	require.Len(t, removed, 1)
+	require.Equal(t, "new/", removed[0].(map[string]interface{})["path"])
+
+	resp, err := leaderClient.Sys().RaftSnapshotRestoreMounts(bytes.NewReader(snap), []string{"secret"}, true)
+	require.NoError(t, err)
+	result := resp.Data["mounts"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "1", fmt.Sprint(result["written"]))
+	require.Equal(t, "9", fmt.Sprint(result["unchanged"]))
+	require.Equal(t, "10", fmt.Sprint(result["deleted"]))
+
+	// Nothing changed on a dry run.
+	secret, err := leaderClient.Logical().List("secret/")
+	require.NoError(t, err)
+	require.Len(t, secret.Data["keys"], 20)
+
+	_, err = leaderClient.Sys().RaftSnapshotRestoreMounts(bytes.NewReader(snap), []string{"secret/"}, false)
+	require.NoError(t, err)
+
+	secret, err = leaderClient.Logical().List("secret/")
+	require.NoError(t, err)
+	require.Len(t, secret.Data["keys"], 10)
+	secret, err = leaderClient.Logical().Read("secret/0")
+	require.NoError(t, err)
+	require.Equal(t, "data", secret.Data["test"])
+
+	// Other mounts are untouched.
+	secret, err = leaderClient.Logical().Read("other/after")
+	require.NoError(t, err)
+	require.NotNil(t, secret)
+
+	// Mounts created after the snapshot cannot be restored.
+	_, err = leaderClient.Sys().RaftSnapshotRestoreMounts(bytes.NewReader(snap), []string{"new/"}, false)
+	require.Error(t, err)
+}
+
 func TestRaft_SnapshotAPI_MidstreamFailure(t *testing.T) {
 	// defer goleak.VerifyNone(t)
 	t.Parallel()
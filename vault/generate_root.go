@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/pgpkeys"
@@ -75,6 +76,9 @@ type GenerateRootConfig struct {
 	PGPFingerprint string
 	OTP            string
 	Strategy       GenerateRootStrategy
+
+	// TTL is the time read-only recovery tokens are valid for
+	TTL time.Duration
 }
 
 // GenerateRootResult holds the result of a root generation update
@@ -131,6 +135,10 @@ func (c *Core) GenerateRootConfiguration() (*GenerateRootConfig, error) {
 
 // GenerateRootInit is used to initialize the root generation settings
 func (c *Core) GenerateRootInit(otp, pgpKey string, strategy GenerateRootStrategy) error {
+	return c.generateRootInit(otp, pgpKey, strategy, 0)
+}
+
+func (c *Core) generateRootInit(otp, pgpKey string, strategy GenerateRootStrategy, ttl time.Duration) error {
 	var fingerprint string
 	switch {
 	case len(otp) > 0:
@@ -189,6 +197,7 @@ func (c *Core) GenerateRootInit(otp, pgpKey string, strategy GenerateRootStrateg
 		PGPKey:         pgpKey,
 		PGPFingerprint: fingerprint,
 		Strategy:       strategy,
+		TTL:            ttl,
 	}
 
 	if c.logger.IsInfo() {
@@ -197,6 +206,8 @@ func (c *Core) GenerateRootInit(otp, pgpKey string, strategy GenerateRootStrateg
 			c.logger.Info("root generation initialized", "nonce", c.generateRootConfig.Nonce)
 		case *generateRecoveryToken:
 			c.logger.Info("recovery operation token generation initialized", "nonce", c.generateRootConfig.Nonce)
+		case generateReadOnlyRecoveryToken:
+			c.logger.Info("read-only recovery token generation initialized", "nonce", c.generateRootConfig.Nonce, "ttl", ttl)
 		default:
 			c.logger.Info("dr operation token generation initialized", "nonce", c.generateRootConfig.Nonce)
 		}
@@ -349,6 +360,8 @@ func (c *Core) GenerateRootUpdate(ctx context.Context, key []byte, nonce string,
 		c.logger.Info("root generation finished", "nonce", c.generateRootConfig.Nonce)
 	case *generateRecoveryToken:
 		c.logger.Info("recovery operation token generation finished", "nonce", c.generateRootConfig.Nonce)
+	case generateReadOnlyRecoveryToken:
+		c.logger.Info("read-only recovery token generation finished", "nonce", c.generateRootConfig.Nonce)
 	default:
 		c.logger.Info("dr operation token generation finished", "nonce", c.generateRootConfig.Nonce)
 	}
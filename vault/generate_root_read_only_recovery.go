@@ -0,0 +1,248 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// readOnlyRecoveryPolicyName is the name of the policy of read-only
+	// recovery tokens
+	readOnlyRecoveryPolicyName = "recovery-read-only"
+
+	// readOnlyRecoveryPolicy allows reading and listing the paths of the
+	// system backend that do not require sudo, and the metadata of KV
+	// version 2 mounts. Other paths are denied as reading them may mint
+	// credentials.
+	readOnlyRecoveryPolicy = `
+path "sys/*" {
+	capabilities = ["read", "list"]
+}
+
+path "+/metadata/*" {
+	capabilities = ["read", "list"]
+}
+`
+
+	// readOnlyRecoveryTokenPath is the path of the registry of the read-only
+	// recovery token, which all the nodes read so that it can be used with
+	// any of them
+	readOnlyRecoveryTokenPath = "core/read-only-recovery-token"
+
+	// readOnlyRecoveryTokenEntryPath is the path of the token entries of
+	// read-only recovery tokens
+	readOnlyRecoveryTokenEntryPath = "sys/generate-recovery-token/update"
+
+	defaultReadOnlyRecoveryTokenTTL = time.Hour
+	maxReadOnlyRecoveryTokenTTL     = 24 * time.Hour
+)
+
+var (
+	// GenerateReadOnlyRecoveryTokenStrategy is the strategy used to generate
+	// a read-only recovery token
+	GenerateReadOnlyRecoveryTokenStrategy GenerateRootStrategy = generateReadOnlyRecoveryToken{}
+
+	errReadOnlyRecoveryTokenAudit = errors.New("read-only recovery tokens require an enabled audit device logging their requests")
+)
+
+// generateReadOnlyRecoveryToken implements the GenerateRootStrategy and is in
+// charge of creating read-only recovery tokens. These tokens are not stored in
+// the token store, and have no entity, so that they can be used when either
+// is corrupted.
+type generateReadOnlyRecoveryToken struct{}
+
+// readOnlyRecoveryTokenRegistration is the registry entry of the read-only
+// recovery token. Only the hash of the token is stored.
+type readOnlyRecoveryTokenRegistration struct {
+	TokenHash    string        `json:"token_hash"`
+	CreationTime int64         `json:"creation_time"`
+	TTL          time.Duration `json:"ttl"`
+}
+
+func (g generateReadOnlyRecoveryToken) authenticate(ctx context.Context, c *Core, combinedKey []byte) error {
+	return generateStandardRootToken{}.authenticate(ctx, c, combinedKey)
+}
+
+func (g generateReadOnlyRecoveryToken) generate(ctx context.Context, c *Core) (string, func(), error) {
+	if !c.auditBroker.LogsAll() {
+		return "", nil, errReadOnlyRecoveryTokenAudit
+	}
+
+	id, err := base62.Random(TokenLength)
+	if err != nil {
+		return "", nil, err
+	}
+	var token string
+	if c.DisableSSCTokens() {
+		token = consts.LegacyRecoveryTokenPrefix + id
+	} else {
+		token = consts.RecoveryTokenPrefix + id
+	}
+
+	// The generation configuration is locked by GenerateRootUpdate. The
+	// registration replaces the one of the previous token.
+	ttl := c.generateRootConfig.TTL
+	entry, err := logical.StorageEntryJSON(readOnlyRecoveryTokenPath, &readOnlyRecoveryTokenRegistration{
+		TokenHash:    hashReadOnlyRecoveryToken(token),
+		CreationTime: time.Now().Unix(),
+		TTL:          ttl,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return "", nil, fmt.Errorf("failed to register the read-only recovery token: %w", err)
+	}
+	c.logger.Warn("read-only recovery token generated", "ttl", ttl)
+
+	return token, func() { c.clearReadOnlyRecoveryToken(ctx, token) }, nil
+}
+
+// GenerateReadOnlyRecoveryTokenInit is used to initialize the generation of a
+// read-only recovery token valid for ttl, or for an hour if ttl is zero.
+func (c *Core) GenerateReadOnlyRecoveryTokenInit(otp, pgpKey string, ttl time.Duration) error {
+	switch {
+	case ttl == 0:
+		ttl = defaultReadOnlyRecoveryTokenTTL
+	case ttl < 0, ttl > maxReadOnlyRecoveryTokenTTL:
+		return fmt.Errorf("ttl must be positive and at most %s", maxReadOnlyRecoveryTokenTTL)
+	}
+	if c.auditBroker == nil || !c.auditBroker.LogsAll() {
+		return errReadOnlyRecoveryTokenAudit
+	}
+
+	return c.generateRootInit(otp, pgpKey, GenerateReadOnlyRecoveryTokenStrategy, ttl)
+}
+
+// isRecoveryToken returns whether token has the prefix of recovery tokens,
+// which tokens of the token store never have.
+func isRecoveryToken(token string) bool {
+	return strings.HasPrefix(token, consts.RecoveryTokenPrefix) ||
+		strings.HasPrefix(token, consts.LegacyRecoveryTokenPrefix)
+}
+
+// isReadOnlyRecoveryTokenEntry returns whether te is the entry of a read-only
+// recovery token.
+func isReadOnlyRecoveryTokenEntry(te *logical.TokenEntry) bool {
+	return te != nil && te.Path == readOnlyRecoveryTokenEntryPath && te.NamespaceID == namespace.RootNamespaceID
+}
+
+func hashReadOnlyRecoveryToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// readOnlyRecoveryTokenEntry returns the entry of the read-only recovery
+// token, or nil if token is not registered as the read-only recovery token or
+// has expired.
+func (c *Core) readOnlyRecoveryTokenEntry(ctx context.Context, token string) (*logical.TokenEntry, error) {
+	if !isRecoveryToken(token) {
+		return nil, nil
+	}
+
+	raw, err := c.barrier.Get(ctx, readOnlyRecoveryTokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the read-only recovery token registration: %w", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	var registration readOnlyRecoveryTokenRegistration
+	if err := raw.DecodeJSON(&registration); err != nil {
+		return nil, fmt.Errorf("failed to decode the read-only recovery token registration: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(registration.TokenHash), []byte(hashReadOnlyRecoveryToken(token))) != 1 {
+		return nil, nil
+	}
+	if time.Now().After(time.Unix(registration.CreationTime, 0).Add(registration.TTL)) {
+		return nil, nil
+	}
+
+	return &logical.TokenEntry{
+		ID:           token,
+		Path:         readOnlyRecoveryTokenEntryPath,
+		Policies:     []string{readOnlyRecoveryPolicyName},
+		DisplayName:  readOnlyRecoveryPolicyName,
+		Meta:         map[string]string{"recovery_token": "read-only"},
+		CreationTime: registration.CreationTime,
+		TTL:          registration.TTL,
+		NamespaceID:  namespace.RootNamespaceID,
+		Type:         logical.TokenTypeService,
+	}, nil
+}
+
+// clearReadOnlyRecoveryToken removes the registration of the read-only
+// recovery token if it is token.
+func (c *Core) clearReadOnlyRecoveryToken(ctx context.Context, token string) {
+	te, err := c.readOnlyRecoveryTokenEntry(ctx, token)
+	if err == nil && te != nil {
+		err = c.barrier.Delete(ctx, readOnlyRecoveryTokenPath)
+	}
+	if err != nil {
+		c.logger.Error("failed to clear the read-only recovery token", "error", err)
+	}
+}
+
+// readOnlyRecoveryACL returns the ACL of read-only recovery tokens, which
+// unlike other ACLs is not built from the policy store.
+func readOnlyRecoveryACL(ctx context.Context) (*ACL, error) {
+	policy, err := ParseACLPolicy(namespace.RootNamespace, readOnlyRecoveryPolicy)
+	if err != nil {
+		return nil, err
+	}
+	policy.Name = readOnlyRecoveryPolicyName
+
+	return NewACL(namespace.ContextWithNamespace(ctx, namespace.RootNamespace), []*Policy{policy})
+}
+
+// fetchReadOnlyRecoveryACLAndTokenEntry returns the ACL and token entry of
+// te, the entry of the read-only recovery token of req. The requests made
+// with these tokens must be audited, so they are denied when no audit device
+// logs them.
+func (c *Core) fetchReadOnlyRecoveryACLAndTokenEntry(ctx context.Context, req *logical.Request, te *logical.TokenEntry) (*ACL, *logical.TokenEntry, *identity.Entity, map[string][]string, error) {
+	in := &logical.LogInput{
+		Request: req,
+		Auth: &logical.Auth{
+			DisplayName: te.DisplayName,
+		},
+	}
+	if !c.auditBroker.Logs(ctx, in) {
+		c.logger.Warn("permission denied as no audit device logs the request of the read-only recovery token", "path", req.Path)
+		return nil, nil, nil, nil, fmt.Errorf("%w: %s", logical.ErrPermissionDenied, errReadOnlyRecoveryTokenAudit)
+	}
+
+	acl, err := readOnlyRecoveryACL(ctx)
+	if err != nil {
+		c.logger.Error("failed to construct read-only recovery ACL", "error", err)
+		return nil, nil, nil, nil, ErrInternalError
+	}
+	req.SetTokenEntry(te)
+
+	return acl, te, nil, nil, nil
+}
+
+// rejectReadOnlyRecoveryResponse revokes the secret of resp, returned to a
+// request of a read-only recovery token, before the response is rejected.
+// Read-only recovery tokens must not obtain credentials or tokens.
+func (c *Core) rejectReadOnlyRecoveryResponse(ctx context.Context, req *logical.Request, resp *logical.Response) error {
+	if resp.Secret != nil {
+		if _, err := c.router.Route(ctx, logical.RevokeRequest(req.Path, resp.Secret, resp.Data)); err != nil {
+			c.logger.Error("failed to revoke the secret returned to the read-only recovery token", "path", req.Path, "error", err)
+		}
+	}
+	c.logger.Warn("permission denied as the response to the read-only recovery token has credentials", "path", req.Path)
+	return fmt.Errorf("%w: read-only recovery tokens cannot obtain credentials", logical.ErrPermissionDenied)
+}
@@ -0,0 +1,247 @@
+package vault
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/base62"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/xor"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func testGenerateReadOnlyRecoveryToken(t *testing.T, c *Core, keys [][]byte, ttl time.Duration) string {
+	t.Helper()
+
+	otp, err := base62.Random(TokenPrefixLength + TokenLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GenerateReadOnlyRecoveryTokenInit(otp, "", ttl); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	conf, err := c.GenerateRootConfiguration()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var result *GenerateRootResult
+	for _, key := range keys {
+		result, err = c.GenerateRootUpdate(namespace.RootContext(nil), key, conf.Nonce, GenerateReadOnlyRecoveryTokenStrategy)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if result.EncodedToken != "" {
+			break
+		}
+	}
+
+	tokenBytes, err := base64.RawStdEncoding.DecodeString(result.EncodedToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenBytes, err = xor.XORBytes(tokenBytes, []byte(otp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(tokenBytes)
+}
+
+func TestCore_GenerateReadOnlyRecoveryToken(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	otp, err := base62.Random(TokenPrefixLength + TokenLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The requests made with the token must be audited
+	if err := c.GenerateReadOnlyRecoveryTokenInit(otp, "", 0); err == nil || !strings.Contains(err.Error(), "audit device") {
+		t.Fatalf("expected an error without audit devices, got: %v", err)
+	}
+	if err := c.enableAudit(ctx, &MountEntry{
+		Table: auditTableType,
+		Path:  "noop/",
+		Type:  "noop",
+	}, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The TTL is bounded
+	if err := c.GenerateReadOnlyRecoveryTokenInit(otp, "", 48*time.Hour); err == nil {
+		t.Fatal("expected an error with a ttl over the maximum")
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.ClientToken = root
+	req.Data["bar"] = "baz"
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	token := testGenerateReadOnlyRecoveryToken(t, c, keys, 0)
+	if !strings.HasPrefix(token, consts.RecoveryTokenPrefix) {
+		t.Fatalf("bad token: %q", token)
+	}
+
+	// The token is not in the token store, which fails to look it up
+	te, err := c.tokenStore.Lookup(ctx, token)
+	if err == nil && te != nil {
+		t.Fatalf("expected no token store entry, got: %#v", te)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = token
+	resp, err := c.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["secret/"] == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The token is read-only
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/foo")
+	req.ClientToken = token
+	req.Data["type"] = "kv"
+	if _, err := c.HandleRequest(ctx, req); err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// Paths outside of the allowed ones are denied, as reading them may mint
+	// credentials
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = token
+	if _, err := c.HandleRequest(ctx, req); err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// Paths requiring sudo are denied
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/audit")
+	req.ClientToken = token
+	if _, err := c.HandleRequest(ctx, req); err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// The token is registered in storage, without the token itself
+	raw, err := c.barrier.Get(ctx, readOnlyRecoveryTokenPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if raw == nil || strings.Contains(string(raw.Value), token) {
+		t.Fatalf("bad registration: %#v", raw)
+	}
+
+	// The token expires
+	var registration readOnlyRecoveryTokenRegistration
+	if err := raw.DecodeJSON(&registration); err != nil {
+		t.Fatal(err)
+	}
+	registration.CreationTime = time.Now().Add(-2 * time.Hour).Unix()
+	raw, err = logical.StorageEntryJSON(readOnlyRecoveryTokenPath, &registration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.barrier.Put(ctx, raw); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = token
+	if _, err := c.HandleRequest(ctx, req); err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+	if te, err := c.readOnlyRecoveryTokenEntry(ctx, token); err != nil || te != nil {
+		t.Fatalf("expected the expired token to be rejected, got: %#v, %v", te, err)
+	}
+}
+
+func TestCore_ReadOnlyRecoveryToken_Credentials(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	if err := c.enableAudit(ctx, &MountEntry{
+		Table: auditTableType,
+		Path:  "noop/",
+		Type:  "noop",
+	}, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The kv backend of the test core returns leases, which stand for
+	// credentials under a path the policy allows
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/leased")
+	req.ClientToken = root
+	req.Data["type"] = "kv"
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "leased/metadata/foo")
+	req.ClientToken = root
+	req.Data["bar"] = "baz"
+	req.Data["ttl"] = "1h"
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	token := testGenerateReadOnlyRecoveryToken(t, c, keys, 0)
+
+	req = logical.TestRequest(t, logical.ReadOperation, "leased/metadata/foo")
+	req.ClientToken = token
+	resp, err := c.HandleRequest(ctx, req)
+	if err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %#v, %v", resp, err)
+	}
+	if resp != nil && resp.Data["bar"] != nil {
+		t.Fatalf("expected no data, got: %#v", resp)
+	}
+}
+
+func TestCore_ReadOnlyRecoveryToken_AuditFilter(t *testing.T) {
+	c, keys, _ := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	// Generating the token requires a device logging all the requests
+	if err := c.enableAudit(ctx, &MountEntry{
+		Table:   auditTableType,
+		Path:    "filtered/",
+		Type:    "noop",
+		Options: map[string]string{"filter": `path matches "^sys/mounts"`},
+	}, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	otp, err := base62.Random(TokenPrefixLength + TokenLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GenerateReadOnlyRecoveryTokenInit(otp, "", 0); err == nil || !strings.Contains(err.Error(), "audit device") {
+		t.Fatalf("expected an error without unfiltered audit devices, got: %v", err)
+	}
+
+	if err := c.enableAudit(ctx, &MountEntry{
+		Table: auditTableType,
+		Path:  "noop/",
+		Type:  "noop",
+	}, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := testGenerateReadOnlyRecoveryToken(t, c, keys, 0)
+	if _, err := c.disableAudit(ctx, "noop/", true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Requests are only allowed if a device logs them
+	req := logical.TestRequest(t, logical.ReadOperation, "sys/mounts")
+	req.ClientToken = token
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/policy")
+	req.ClientToken = token
+	if _, err := c.HandleRequest(ctx, req); err == nil || !strings.Contains(err.Error(), "audit device") {
+		t.Fatalf("expected an error without audit devices logging the request, got: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package vault
+
+import (
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// populateGeoIP resolves the country and autonomous system of the remote
+// address of the request, so that they can be audit logged and checked
+// against token constraints.
+func (c *Core) populateGeoIP(req *logical.Request) {
+	if !c.geoIP.HasCountry() && !c.geoIP.HasASN() {
+		return
+	}
+	if req.Connection == nil || req.Connection.RemoteAddr == "" {
+		return
+	}
+	if req.Connection.RemoteCountry != "" || req.Connection.RemoteASN != 0 {
+		return
+	}
+
+	result, err := c.geoIP.Lookup(req.Connection.RemoteAddr)
+	if err != nil {
+		if c.logger.IsDebug() {
+			c.logger.Debug("failed to resolve remote address with GeoIP databases", "remote_addr", req.Connection.RemoteAddr, "error", err)
+		}
+		return
+	}
+	req.Connection.RemoteCountry = result.Country
+	req.Connection.RemoteASN = int(result.ASN)
+}
+
+// checkGeoConstraints returns whether the request satisfies the country and
+// ASN constraints. Constraints that can't be evaluated because no database
+// is configured are not satisfied.
+func (c *Core) checkGeoConstraints(constraints *logical.GeoConstraints, req *logical.Request) bool {
+	if constraints.Empty() {
+		return true
+	}
+	if req.Connection == nil {
+		return false
+	}
+	if constraints.HasCountryConstraints() && !c.geoIP.HasCountry() {
+		c.logger.Warn("denying token with country constraints since no GeoIP country database is configured")
+		return false
+	}
+	if constraints.HasASNConstraints() && !c.geoIP.HasASN() {
+		c.logger.Warn("denying token with ASN constraints since no GeoIP ASN database is configured")
+		return false
+	}
+
+	c.populateGeoIP(req)
+	return constraints.Allows(req.Connection.RemoteCountry, req.Connection.RemoteASN)
+}
@@ -0,0 +1,178 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/copystructure"
+)
+
+// maxIdempotencyKeyTTL bounds the retention of idempotent responses, which
+// are kept in memory.
+const maxIdempotencyKeyTTL = 24 * time.Hour
+
+var (
+	errIdempotencyKeyInProgress = errors.New("a request with the same idempotency key is in progress")
+	errIdempotencyKeyMismatch   = errors.New("idempotency key was already used for a different request")
+)
+
+// parseIdempotencyKeyTTL parses the idempotency_key_ttl mount option; an
+// empty value or zero disables idempotency keys on the mount.
+func parseIdempotencyKeyTTL(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	ttl, err := parseutil.ParseDurationSecond(raw)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse idempotency key TTL of %s: %w", raw, err)
+	}
+	if ttl < 0 {
+		return 0, errors.New("idempotency key TTL cannot be negative")
+	}
+	if ttl > maxIdempotencyKeyTTL {
+		return 0, fmt.Errorf("idempotency key TTL cannot be greater than %s", maxIdempotencyKeyTTL)
+	}
+	return ttl, nil
+}
+
+// idempotencyRecord is the outcome of a request made with an idempotency
+// key, or marks the request in progress until done is set.
+type idempotencyRecord struct {
+	fingerprint string
+	done        bool
+	resp        *logical.Response
+}
+
+// idempotentRequest identifies a request made with an idempotency key.
+type idempotentRequest struct {
+	key         string
+	ttl         time.Duration
+	fingerprint string
+}
+
+// newIdempotentRequest returns the idempotency state of the request, or nil
+// if the request isn't subject to idempotency.
+func newIdempotentRequest(entry *MountEntry, req *logical.Request, te *logical.TokenEntry) (*idempotentRequest, error) {
+	if req.IdempotencyKey == "" || entry == nil || te == nil {
+		return nil, nil
+	}
+	switch req.Operation {
+	case logical.CreateOperation, logical.UpdateOperation, logical.PatchOperation:
+	default:
+		return nil, nil
+	}
+	if entry.Config.IdempotencyKeyTTL <= 0 {
+		return nil, nil
+	}
+
+	fingerprint, err := idempotencyFingerprint(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys are scoped to the client so that clients can't replay the
+	// responses of each other
+	client := te.EntityID
+	if client == "" {
+		client = stepUpTokenKey(te.Accessor, req.ClientToken)
+	}
+	return &idempotentRequest{
+		key:         entry.Accessor + "/" + client + "/" + req.IdempotencyKey,
+		ttl:         entry.Config.IdempotencyKeyTTL,
+		fingerprint: fingerprint,
+	}, nil
+}
+
+// idempotencyFingerprint identifies the content of a request, so that keys
+// reused for different requests are detected.
+func idempotencyFingerprint(req *logical.Request) (string, error) {
+	data, err := json.Marshal(req.Data)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(req.Operation))
+	h.Write([]byte{0})
+	h.Write([]byte(req.Path))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// beginIdempotentRequest returns whether a previous request with the same
+// key succeeded, along with its response. Otherwise, the request is marked
+// in progress until finishIdempotentRequest is called.
+func (c *Core) beginIdempotentRequest(ir *idempotentRequest) (bool, *logical.Response, error) {
+	records := c.idempotencyRecords
+	if records == nil {
+		return false, nil, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := records.Add(ir.key, &idempotencyRecord{fingerprint: ir.fingerprint}, ir.ttl); err == nil {
+			return false, nil, nil
+		}
+
+		raw, ok := records.Get(ir.key)
+		if !ok {
+			// Expired in the meantime
+			continue
+		}
+		record := raw.(*idempotencyRecord)
+		switch {
+		case record.fingerprint != ir.fingerprint:
+			return false, nil, errIdempotencyKeyMismatch
+		case !record.done:
+			return false, nil, errIdempotencyKeyInProgress
+		case record.resp == nil:
+			return true, nil, nil
+		}
+
+		resp, err := copyIdempotentResponse(record.resp)
+		if err != nil {
+			return false, nil, err
+		}
+		resp.AddWarning("Response replayed from a previous request with the same idempotency key.")
+		return true, resp, nil
+	}
+	return false, nil, errIdempotencyKeyInProgress
+}
+
+// finishIdempotentRequest records the response of a successful request, or
+// releases the key of a failed one so that it can be retried.
+func (c *Core) finishIdempotentRequest(ir *idempotentRequest, resp *logical.Response, respErr error) {
+	records := c.idempotencyRecords
+	if records == nil {
+		return
+	}
+
+	if respErr != nil || resp.IsError() {
+		records.Delete(ir.key)
+		return
+	}
+
+	if resp != nil {
+		var err error
+		resp, err = copyIdempotentResponse(resp)
+		if err != nil {
+			c.logger.Warn("failed to record response for idempotency key", "error", err)
+			records.Delete(ir.key)
+			return
+		}
+	}
+	records.Set(ir.key, &idempotencyRecord{fingerprint: ir.fingerprint, done: true, resp: resp}, ir.ttl)
+}
+
+func copyIdempotentResponse(resp *logical.Response) (*logical.Response, error) {
+	raw, err := copystructure.Copy(resp)
+	if err != nil {
+		return nil, err
+	}
+	return raw.(*logical.Response), nil
+}
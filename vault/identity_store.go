@@ -107,9 +107,13 @@ func NewIdentityStore(ctx context.Context, core *Core, config *logical.BackendCo
 			LocalStorage: []string{
 				localAliasesBucketsPrefix,
 			},
+			SealWrapStorage: []string{
+				namedKeyConfigPath,
+			},
 		},
 		PeriodicFunc: func(ctx context.Context, req *logical.Request) error {
 			iStore.oidcPeriodicFunc(ctx)
+			iStore.dynamicGroupsPeriodicFunc(ctx)
 
 			return nil
 		},
@@ -138,6 +142,7 @@ func (i *IdentityStore) paths() []*framework.Path {
 		oidcPaths(i),
 		oidcProviderPaths(i),
 		mfaPaths(i),
+		mfaStepUpPaths(i),
 	)
 }
 
@@ -0,0 +1,278 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/identity/membership"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+)
+
+// membershipEntity returns the attributes of the entity which membership
+// expressions of dynamic groups are evaluated against.
+func (i *IdentityStore) membershipEntity(entity *identity.Entity) *membership.Entity {
+	candidate := &membership.Entity{
+		Name:     entity.Name,
+		Metadata: entity.Metadata,
+		Aliases:  make([]membership.Alias, 0, len(entity.Aliases)),
+	}
+
+	for _, alias := range entity.Aliases {
+		membershipAlias := membership.Alias{
+			Name:           alias.Name,
+			MountAccessor:  alias.MountAccessor,
+			Metadata:       alias.Metadata,
+			CustomMetadata: alias.CustomMetadata,
+		}
+		if mountValidationResp := i.router.ValidateMountByAccessor(alias.MountAccessor); mountValidationResp != nil {
+			membershipAlias.MountPath = mountValidationResp.MountPath
+			membershipAlias.MountType = mountValidationResp.MountType
+		}
+		candidate.Aliases = append(candidate.Aliases, membershipAlias)
+	}
+
+	return candidate
+}
+
+// dynamicGroupMemberEntityIDs returns the IDs of the entities of the namespace
+// which satisfy the membership expression.
+func (i *IdentityStore) dynamicGroupMemberEntityIDs(namespaceID string, expression *membership.Expression) ([]string, error) {
+	txn := i.db.Txn(false)
+
+	return i.dynamicGroupMemberEntityIDsInTxn(txn, namespaceID, expression)
+}
+
+func (i *IdentityStore) dynamicGroupMemberEntityIDsInTxn(txn *memdb.Txn, namespaceID string, expression *membership.Expression) ([]string, error) {
+	iter, err := txn.Get(entitiesTable, "namespace_id", namespaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch iterator for entities in memdb: %w", err)
+	}
+
+	var memberEntityIDs []string
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		entity := raw.(*identity.Entity)
+		if expression.Matches(i.membershipEntity(entity)) {
+			memberEntityIDs = append(memberEntityIDs, entity.ID)
+		}
+	}
+
+	return memberEntityIDs, nil
+}
+
+// memDBDynamicGroupsInTxn returns the dynamic groups of the namespace, or of
+// all the namespaces if namespaceID is empty.
+func (i *IdentityStore) memDBDynamicGroupsInTxn(txn *memdb.Txn, namespaceID string) ([]*identity.Group, error) {
+	var iter memdb.ResultIterator
+	var err error
+	if namespaceID == "" {
+		iter, err = txn.Get(groupsTable, "id")
+	} else {
+		iter, err = txn.Get(groupsTable, "namespace_id", namespaceID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch iterator for groups in memdb: %w", err)
+	}
+
+	var groups []*identity.Group
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		group := raw.(*identity.Group)
+		if group.Type == groupTypeDynamic {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
+
+// refreshDynamicGroupMembershipsByEntityID adds the entity to the dynamic
+// groups of its namespace whose membership expression it now satisfies, and
+// removes it from the ones whose expression it no longer satisfies.
+func (i *IdentityStore) refreshDynamicGroupMembershipsByEntityID(ctx context.Context, entityID string) error {
+	defer metrics.MeasureSince([]string{"identity", "refresh_dynamic_groups"}, time.Now())
+
+	if entityID == "" {
+		return fmt.Errorf("empty entity ID")
+	}
+
+	refreshFunc := func(dryRun bool) (bool, error) {
+		if !dryRun {
+			i.groupLock.Lock()
+			defer i.groupLock.Unlock()
+		}
+
+		txn := i.db.Txn(!dryRun)
+		defer txn.Abort()
+
+		entity, err := i.MemDBEntityByIDInTxn(txn, entityID, false)
+		if err != nil {
+			return false, err
+		}
+		if entity == nil {
+			return false, nil
+		}
+
+		groups, err := i.memDBDynamicGroupsInTxn(txn, entity.NamespaceID)
+		if err != nil {
+			return false, err
+		}
+
+		candidate := i.membershipEntity(entity)
+		for _, group := range groups {
+			expression, err := membership.Parse(group.MembershipExpression)
+			if err != nil {
+				i.logger.Warn("skipping dynamic group with an invalid membership expression", "group_id", group.ID, "error", err)
+				continue
+			}
+
+			isMember := strutil.StrListContains(group.MemberEntityIDs, entityID)
+			if expression.Matches(candidate) == isMember {
+				continue
+			}
+
+			// We need to update a group, if we are in a dry run we should
+			// report back that a change needs to take place.
+			if dryRun {
+				return true, nil
+			}
+
+			group, err = group.Clone()
+			if err != nil {
+				return false, err
+			}
+
+			if isMember {
+				i.logger.Debug("removing member entity ID from dynamic group", "member_entity_id", entityID, "group_id", group.ID)
+				group.MemberEntityIDs = strutil.StrListDelete(group.MemberEntityIDs, entityID)
+			} else {
+				i.logger.Debug("adding member entity ID to dynamic group", "member_entity_id", entityID, "group_id", group.ID)
+				group.MemberEntityIDs = append(group.MemberEntityIDs, entityID)
+			}
+
+			err = i.UpsertGroupInTxn(ctx, txn, group, true)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		txn.Commit()
+		return false, nil
+	}
+
+	// dryRun
+	needsUpdate, err := refreshFunc(true)
+	if err != nil || !needsUpdate {
+		return err
+	}
+
+	_, err = refreshFunc(false)
+	return err
+}
+
+// markDynamicGroupRefreshed records when the members of a dynamic group were
+// last computed.
+func (i *IdentityStore) markDynamicGroupRefreshed(groupID string, refreshedAt time.Time) {
+	i.dynamicGroupRefreshesLock.Lock()
+	defer i.dynamicGroupRefreshesLock.Unlock()
+
+	if i.dynamicGroupRefreshes == nil {
+		i.dynamicGroupRefreshes = make(map[string]time.Time)
+	}
+	i.dynamicGroupRefreshes[groupID] = refreshedAt
+}
+
+// dynamicGroupRefreshDue returns whether the refresh interval of the dynamic
+// group has elapsed since its members were last computed.
+func (i *IdentityStore) dynamicGroupRefreshDue(group *identity.Group, now time.Time) bool {
+	i.dynamicGroupRefreshesLock.Lock()
+	defer i.dynamicGroupRefreshesLock.Unlock()
+
+	lastRefresh, ok := i.dynamicGroupRefreshes[group.ID]
+	if !ok {
+		return true
+	}
+
+	interval := time.Duration(group.MembershipRefreshInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultDynamicGroupRefreshInterval
+	}
+	return now.Sub(lastRefresh) >= interval
+}
+
+// dynamicGroupsPeriodicFunc recomputes the members of the dynamic groups
+// whose refresh interval has elapsed.
+func (i *IdentityStore) dynamicGroupsPeriodicFunc(ctx context.Context) {
+	// Group updates write to storage, so only run this on the primary cluster.
+	if i.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return
+	}
+
+	now := time.Now()
+
+	groups, err := i.memDBDynamicGroupsInTxn(i.db.Txn(false), "")
+	if err != nil {
+		i.logger.Error("failed to list dynamic groups", "error", err)
+		return
+	}
+
+	for _, group := range groups {
+		if !i.dynamicGroupRefreshDue(group, now) {
+			continue
+		}
+
+		if err := i.refreshDynamicGroupMembers(ctx, group.ID); err != nil {
+			i.logger.Error("failed to refresh dynamic group members", "group_id", group.ID, "error", err)
+			continue
+		}
+
+		i.markDynamicGroupRefreshed(group.ID, now)
+	}
+}
+
+// refreshDynamicGroupMembers recomputes the members of a dynamic group from
+// its membership expression.
+func (i *IdentityStore) refreshDynamicGroupMembers(ctx context.Context, groupID string) error {
+	defer metrics.MeasureSince([]string{"identity", "refresh_dynamic_group_members"}, time.Now())
+
+	i.groupLock.Lock()
+	defer i.groupLock.Unlock()
+
+	txn := i.db.Txn(true)
+	defer txn.Abort()
+
+	group, err := i.MemDBGroupByIDInTxn(txn, groupID, true)
+	if err != nil {
+		return err
+	}
+	if group == nil || group.Type != groupTypeDynamic {
+		return nil
+	}
+
+	expression, err := membership.Parse(group.MembershipExpression)
+	if err != nil {
+		return fmt.Errorf("invalid membership expression: %w", err)
+	}
+
+	memberEntityIDs, err := i.dynamicGroupMemberEntityIDsInTxn(txn, group.NamespaceID, expression)
+	if err != nil {
+		return err
+	}
+
+	if strutil.EquivalentSlices(group.MemberEntityIDs, memberEntityIDs) {
+		return nil
+	}
+
+	i.logger.Debug("refreshing dynamic group members", "group_id", group.ID, "member_entity_ids", memberEntityIDs)
+
+	group.MemberEntityIDs = memberEntityIDs
+	if err := i.UpsertGroupInTxn(ctx, txn, group, true); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
@@ -0,0 +1,168 @@
+package vault
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestIdentityStore_DynamicGroups(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	is, ghAccessor, _ := testIdentityStoreWithGithubAuth(ctx, t)
+
+	createEntity := func(name, team string) string {
+		t.Helper()
+		resp, err := is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "entity",
+			Data: map[string]interface{}{
+				"name":     name,
+				"metadata": []string{"team=" + team},
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp.Data["id"].(string)
+	}
+
+	platformID := createEntity("alice", "platform")
+	createEntity("bob", "security")
+	githubID := createEntity("carol", "security")
+
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity-alias",
+		Data: map[string]interface{}{
+			"name":           "carol",
+			"mount_accessor": ghAccessor,
+			"canonical_id":   githubID,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "group",
+		Data: map[string]interface{}{
+			"name":                  "dynamic",
+			"type":                  "dynamic",
+			"membership_expression": `entity.metadata.team == "platform" or alias.mount_type == "github"`,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	groupID := resp.Data["id"].(string)
+
+	readMembers := func() []string {
+		t.Helper()
+		resp, err := is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "group/id/" + groupID,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		if resp.Data["membership_refresh_interval"].(int64) != int64(time.Hour.Seconds()) {
+			t.Fatalf("bad: membership refresh interval: %v", resp.Data["membership_refresh_interval"])
+		}
+		members := resp.Data["member_entity_ids"].([]string)
+		sort.Strings(members)
+		return members
+	}
+
+	expected := []string{platformID, githubID}
+	sort.Strings(expected)
+	if diff := deep.Equal(readMembers(), expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// Moving an entity to another team changes its membership on the next
+	// login.
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity/id/" + platformID,
+		Data: map[string]interface{}{
+			"metadata": []string{"team=security"},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if err := is.refreshDynamicGroupMembershipsByEntityID(ctx, platformID); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(readMembers(), []string{githubID}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// The periodic refresh picks up changes of entities which did not log in.
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity/id/" + platformID,
+		Data: map[string]interface{}{
+			"metadata": []string{"team=platform"},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	is.dynamicGroupsPeriodicFunc(ctx)
+	if diff := deep.Equal(readMembers(), []string{githubID}); diff != nil {
+		t.Fatalf("members refreshed before the refresh interval elapsed: %v", diff)
+	}
+	is.markDynamicGroupRefreshed(groupID, time.Now().Add(-2*time.Hour))
+	is.dynamicGroupsPeriodicFunc(ctx)
+	if diff := deep.Equal(readMembers(), expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestIdentityStore_DynamicGroups_Validation(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	is, _, _ := testIdentityStoreWithGithubAuth(ctx, t)
+
+	tests := map[string]map[string]interface{}{
+		"missing expression": {
+			"type": "dynamic",
+		},
+		"invalid expression": {
+			"type":                  "dynamic",
+			"membership_expression": `entity.metadata.team = "platform"`,
+		},
+		"expression on internal group": {
+			"membership_expression": `entity.metadata.team == "platform"`,
+		},
+		"refresh interval on internal group": {
+			"membership_refresh_interval": "10m",
+		},
+		"manual members": {
+			"type":                  "dynamic",
+			"membership_expression": `entity.metadata.team == "platform"`,
+			"member_entity_ids":     []string{"abc"},
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := is.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "group",
+				Data:      data,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp == nil || !resp.IsError() {
+				t.Fatalf("expected an error response, got: %#v", resp)
+			}
+		})
+	}
+}
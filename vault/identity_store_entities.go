@@ -107,6 +107,7 @@ func entityPaths(i *IdentityStore) []*framework.Path {
 		},
 		{
 			Pattern: "entity/name/?$",
+			Fields:  identityListFields(),
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.ListOperation: i.pathEntityNameList(),
 			},
@@ -116,6 +117,7 @@ func entityPaths(i *IdentityStore) []*framework.Path {
 		},
 		{
 			Pattern: "entity/id/?$",
+			Fields:  identityListFields(),
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.ListOperation: i.pathEntityIDList(),
 			},
@@ -659,19 +661,24 @@ func (i *IdentityStore) pathEntityNameList() framework.OperationFunc {
 	}
 }
 
-// handlePathEntityListCommon lists the IDs or names of all the valid entities
-// in the identity store
+// handlePathEntityListCommon lists the IDs or names of the valid entities in
+// the identity store, paginated and filtered by the list options
 func (i *IdentityStore) handlePathEntityListCommon(ctx context.Context, req *logical.Request, d *framework.FieldData, byID bool) (*logical.Response, error) {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	opts, err := i.parseIdentityListOptions(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	ws := memdb.NewWatchSet()
 
 	txn := i.db.Txn(false)
 
-	iter, err := txn.Get(entitiesTable, "namespace_id", ns.ID)
+	iter, err := opts.iterator(txn, entitiesTable, ns, byID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch iterator for entities in memdb: %w", err)
 	}
@@ -699,10 +706,23 @@ func (i *IdentityStore) handlePathEntityListCommon(ctx context.Context, req *log
 		}
 
 		raw := iter.Next()
-		if raw == nil {
+		if raw == nil || opts.full(keys) {
 			break
 		}
 		entity := raw.(*identity.Entity)
+
+		mountAccessors := make([]string, 0, len(entity.Aliases))
+		for _, alias := range entity.Aliases {
+			mountAccessors = append(mountAccessors, alias.MountAccessor)
+		}
+		match, done := opts.match(ns, byID, entity.NamespaceID, entity.ID, entity.Name, entity.Metadata, mountAccessors)
+		if done {
+			break
+		}
+		if !match {
+			continue
+		}
+
 		if byID {
 			keys = append(keys, entity.ID)
 		} else {
@@ -639,6 +639,114 @@ func TestIdentityStore_ListEntities(t *testing.T) {
 	}
 }
 
+func TestIdentityStore_ListEntities_Paginated(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	is, ghAccessor, _ := testIdentityStoreWithGithubAuth(ctx, t)
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		data := map[string]interface{}{
+			"name": fmt.Sprintf("user-%02d", i),
+		}
+		if i%2 == 0 {
+			data["metadata"] = map[string]interface{}{"team": "dev"}
+		}
+		resp, err := is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "entity",
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		ids = append(ids, resp.Data["id"].(string))
+
+		if i < 3 {
+			resp, err = is.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "entity-alias",
+				Data: map[string]interface{}{
+					"name":           fmt.Sprintf("alias-%02d", i),
+					"mount_accessor": ghAccessor,
+					"canonical_id":   resp.Data["id"],
+				},
+			})
+			if err != nil || (resp != nil && resp.IsError()) {
+				t.Fatalf("err:%v resp:%#v", err, resp)
+			}
+		}
+	}
+	resp, err := is.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "entity",
+		Data: map[string]interface{}{
+			"name": "admin",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	ids = append(ids, resp.Data["id"].(string))
+	sort.Strings(ids)
+
+	list := func(path string, data map[string]interface{}) []string {
+		t.Helper()
+		resp, err := is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		keys, _ := resp.Data["keys"].([]string)
+		return keys
+	}
+
+	// Pages of IDs are listed in order
+	var paged []string
+	after := ""
+	for {
+		keys := list("entity/id", map[string]interface{}{"after": after, "limit": 4})
+		if len(keys) == 0 {
+			break
+		}
+		if len(keys) > 4 {
+			t.Fatalf("page exceeds the limit: %#v", keys)
+		}
+		paged = append(paged, keys...)
+		after = keys[len(keys)-1]
+	}
+	if !reflect.DeepEqual(ids, paged) {
+		t.Fatalf("bad: paged entity IDs; expected: %#v\n actual: %#v\n", ids, paged)
+	}
+
+	// Names are filtered by prefix and listed in order
+	keys := list("entity/name", map[string]interface{}{"name_prefix": "user-", "after": "user-05", "limit": 3})
+	expected := []string{"user-06", "user-07", "user-08"}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Fatalf("bad: listed entity names; expected: %#v\n actual: %#v\n", expected, keys)
+	}
+
+	keys = list("entity/name", map[string]interface{}{"metadata_key": "team"})
+	expected = []string{"user-00", "user-02", "user-04", "user-06", "user-08"}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Fatalf("bad: listed entity names; expected: %#v\n actual: %#v\n", expected, keys)
+	}
+
+	keys = list("entity/name", map[string]interface{}{"mount_accessor": ghAccessor})
+	expected = []string{"user-00", "user-01", "user-02"}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Fatalf("bad: listed entity names; expected: %#v\n actual: %#v\n", expected, keys)
+	}
+
+	// Filters apply to IDs as well
+	keys = list("entity/id", map[string]interface{}{"name_prefix": "admin"})
+	if len(keys) != 1 {
+		t.Fatalf("bad: listed entity IDs: %#v", keys)
+	}
+}
+
 func TestIdentityStore_LoadingEntities(t *testing.T) {
 	var resp *logical.Response
 	// Add github credential factory to core config
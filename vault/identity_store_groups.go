@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/identity/membership"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -16,6 +18,11 @@ import (
 const (
 	groupTypeInternal = "internal"
 	groupTypeExternal = "external"
+	groupTypeDynamic  = "dynamic"
+
+	// defaultDynamicGroupRefreshInterval is how often the members of a dynamic
+	// group are recomputed when no refresh interval is given.
+	defaultDynamicGroupRefreshInterval = time.Hour
 )
 
 func groupPathFields() map[string]*framework.FieldSchema {
@@ -26,7 +33,7 @@ func groupPathFields() map[string]*framework.FieldSchema {
 		},
 		"type": {
 			Type:        framework.TypeString,
-			Description: "Type of the group, 'internal' or 'external'. Defaults to 'internal'",
+			Description: "Type of the group, 'internal', 'external' or 'dynamic'. Defaults to 'internal'",
 		},
 		"name": {
 			Type:        framework.TypeString,
@@ -52,6 +59,14 @@ vault <command> <path> metadata=key1=value1 metadata=key2=value2
 			Type:        framework.TypeCommaStringSlice,
 			Description: "Entity IDs to be assigned as group members.",
 		},
+		"membership_expression": {
+			Type:        framework.TypeString,
+			Description: "Expression over entity and alias attributes which selects the members of a dynamic group.",
+		},
+		"membership_refresh_interval": {
+			Type:        framework.TypeDurationSecond,
+			Description: "How often the members of a dynamic group are recomputed. Defaults to 1h.",
+		},
 	}
 }
 
@@ -81,6 +96,7 @@ func groupPaths(i *IdentityStore) []*framework.Path {
 		},
 		{
 			Pattern: "group/id/?$",
+			Fields:  identityListFields(),
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.ListOperation: i.pathGroupIDList(),
 			},
@@ -102,6 +118,7 @@ func groupPaths(i *IdentityStore) []*framework.Path {
 		},
 		{
 			Pattern: "group/name/?$",
+			Fields:  identityListFields(),
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.ListOperation: i.pathGroupNameList(),
 			},
@@ -203,10 +220,41 @@ func (i *IdentityStore) handleGroupUpdateCommon(ctx context.Context, req *logica
 		group.Type = groupTypeInternal
 	}
 
-	if group.Type != groupTypeInternal && group.Type != groupTypeExternal {
+	switch group.Type {
+	case groupTypeInternal, groupTypeExternal, groupTypeDynamic:
+	default:
 		return logical.ErrorResponse(fmt.Sprintf("invalid group type %q", group.Type)), nil
 	}
 
+	membershipExpressionRaw, ok := d.GetOk("membership_expression")
+	if ok {
+		if group.Type != groupTypeDynamic {
+			return logical.ErrorResponse("membership expression can only be set for dynamic groups"), nil
+		}
+		group.MembershipExpression = membershipExpressionRaw.(string)
+	}
+
+	membershipRefreshIntervalRaw, ok := d.GetOk("membership_refresh_interval")
+	switch {
+	case ok && group.Type != groupTypeDynamic:
+		return logical.ErrorResponse("membership refresh interval can only be set for dynamic groups"), nil
+	case ok && membershipRefreshIntervalRaw.(int) <= 0:
+		return logical.ErrorResponse("membership refresh interval must be positive"), nil
+	case ok:
+		group.MembershipRefreshInterval = int64(membershipRefreshIntervalRaw.(int))
+	case group.Type == groupTypeDynamic && group.MembershipRefreshInterval == 0:
+		group.MembershipRefreshInterval = int64(defaultDynamicGroupRefreshInterval.Seconds())
+	}
+
+	var membershipExpression *membership.Expression
+	if group.Type == groupTypeDynamic {
+		var err error
+		membershipExpression, err = membership.Parse(group.MembershipExpression)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid membership expression: %v", err)), nil
+		}
+	}
+
 	// Get the name
 	groupName := d.Get("name").(string)
 	if groupName != "" {
@@ -238,8 +286,8 @@ func (i *IdentityStore) handleGroupUpdateCommon(ctx context.Context, req *logica
 
 	memberEntityIDsRaw, ok := d.GetOk("member_entity_ids")
 	if ok {
-		if group.Type == groupTypeExternal {
-			return logical.ErrorResponse("member entities can't be set manually for external groups"), nil
+		if group.Type != groupTypeInternal {
+			return logical.ErrorResponse(fmt.Sprintf("member entities can't be set manually for %s groups", group.Type)), nil
 		}
 		group.MemberEntityIDs = memberEntityIDsRaw.([]string)
 	}
@@ -253,6 +301,17 @@ func (i *IdentityStore) handleGroupUpdateCommon(ctx context.Context, req *logica
 		memberGroupIDs = memberGroupIDsRaw.([]string)
 	}
 
+	if membershipExpression != nil {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		group.MemberEntityIDs, err = i.dynamicGroupMemberEntityIDs(ns.ID, membershipExpression)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = i.sanitizeAndUpsertGroup(ctx, group, nil, memberGroupIDs)
 	if err != nil {
 		if errStr := err.Error(); strings.HasPrefix(errStr, errCycleDetectedPrefix) {
@@ -262,6 +321,10 @@ func (i *IdentityStore) handleGroupUpdateCommon(ctx context.Context, req *logica
 		return nil, err
 	}
 
+	if membershipExpression != nil {
+		i.markDynamicGroupRefreshed(group.ID, time.Now())
+	}
+
 	if !newGroup {
 		return nil, nil
 	}
@@ -339,6 +402,11 @@ func (i *IdentityStore) handleGroupReadCommon(ctx context.Context, group *identi
 	respData["type"] = group.Type
 	respData["namespace_id"] = group.NamespaceID
 
+	if group.Type == groupTypeDynamic {
+		respData["membership_expression"] = group.MembershipExpression
+		respData["membership_refresh_interval"] = group.MembershipRefreshInterval
+	}
+
 	aliasMap := map[string]interface{}{}
 	if group.Alias != nil {
 		aliasMap["id"] = group.Alias.ID
@@ -460,26 +528,31 @@ func (i *IdentityStore) handleGroupDeleteCommon(ctx context.Context, key string,
 // pathGroupIDList lists the IDs of all the groups in the identity store
 func (i *IdentityStore) pathGroupIDList() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-		return i.handleGroupListCommon(ctx, true)
+		return i.handleGroupListCommon(ctx, d, true)
 	}
 }
 
 // pathGroupNameList lists the names of all the groups in the identity store
 func (i *IdentityStore) pathGroupNameList() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-		return i.handleGroupListCommon(ctx, false)
+		return i.handleGroupListCommon(ctx, d, false)
 	}
 }
 
-func (i *IdentityStore) handleGroupListCommon(ctx context.Context, byID bool) (*logical.Response, error) {
+func (i *IdentityStore) handleGroupListCommon(ctx context.Context, d *framework.FieldData, byID bool) (*logical.Response, error) {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	opts, err := i.parseIdentityListOptions(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	txn := i.db.Txn(false)
 
-	iter, err := txn.Get(groupsTable, "namespace_id", ns.ID)
+	iter, err := opts.iterator(txn, groupsTable, ns, byID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to lookup groups using namespace ID: %w", err)
 	}
@@ -493,9 +566,21 @@ func (i *IdentityStore) handleGroupListCommon(ctx context.Context, byID bool) (*
 	}
 	mountAccessorMap := map[string]mountInfo{}
 
-	for entry := iter.Next(); entry != nil; entry = iter.Next() {
+	for entry := iter.Next(); entry != nil && !opts.full(keys); entry = iter.Next() {
 		group := entry.(*identity.Group)
 
+		var mountAccessors []string
+		if group.Alias != nil {
+			mountAccessors = []string{group.Alias.MountAccessor}
+		}
+		match, done := opts.match(ns, byID, group.NamespaceID, group.ID, group.Name, group.Metadata, mountAccessors)
+		if done {
+			break
+		}
+		if !match {
+			continue
+		}
+
 		if byID {
 			keys = append(keys, group.ID)
 		} else {
@@ -0,0 +1,139 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+)
+
+// identityListFields returns the fields paginating and filtering the LIST
+// endpoints of entities and groups.
+func identityListFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"after": {
+			Type:        framework.TypeString,
+			Description: "Optional key to list after, such as the last key of the previous page. When set, keys are listed in order.",
+		},
+		"limit": {
+			Type:        framework.TypeInt,
+			Description: "Optional maximum number of keys to list. When set, keys are listed in order.",
+		},
+		"name_prefix": {
+			Type:        framework.TypeString,
+			Description: "Optional prefix of the names to list.",
+		},
+		"mount_accessor": {
+			Type:        framework.TypeString,
+			Description: "Optional accessor of a mount; only the keys having an alias on this mount are listed.",
+		},
+		"metadata_key": {
+			Type:        framework.TypeString,
+			Description: "Optional metadata key; only the keys having this metadata key are listed.",
+		},
+	}
+}
+
+// identityListOptions are the options paginating and filtering the listing
+// of entities or groups.
+type identityListOptions struct {
+	after         string
+	limit         int
+	namePrefix    string
+	mountAccessor string
+	metadataKey   string
+
+	// lowerCaseNames is whether names are indexed lowercased
+	lowerCaseNames bool
+}
+
+func (i *IdentityStore) parseIdentityListOptions(d *framework.FieldData) (*identityListOptions, error) {
+	opts := &identityListOptions{
+		after:          d.Get("after").(string),
+		limit:          d.Get("limit").(int),
+		namePrefix:     d.Get("name_prefix").(string),
+		mountAccessor:  d.Get("mount_accessor").(string),
+		metadataKey:    d.Get("metadata_key").(string),
+		lowerCaseNames: !i.disableLowerCasedNames,
+	}
+	if opts.limit < 0 {
+		return nil, errors.New("limit must not be negative")
+	}
+	return opts, nil
+}
+
+// paginated returns whether any option is set. Otherwise, everything is
+// listed in the order of the namespace index, as it always was.
+func (o *identityListOptions) paginated() bool {
+	return o.after != "" || o.limit > 0 || o.namePrefix != "" || o.mountAccessor != "" || o.metadataKey != ""
+}
+
+func (o *identityListOptions) normalizeName(name string) string {
+	if o.lowerCaseNames {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// iterator returns an iterator over the table, in the order of the IDs or
+// names of its entries, starting at the first entry of the namespace which
+// can be listed.
+func (o *identityListOptions) iterator(txn *memdb.Txn, table string, ns *namespace.Namespace, byID bool) (memdb.ResultIterator, error) {
+	switch {
+	case byID && o.after != "":
+		return txn.LowerBound(table, "id", o.after)
+	case byID || !o.paginated():
+		// The namespace index orders the entries of a namespace by ID
+		return txn.Get(table, "namespace_id", ns.ID)
+	default:
+		start := o.namePrefix
+		if o.normalizeName(o.after) > o.normalizeName(start) {
+			start = o.after
+		}
+		return txn.LowerBound(table, "name", ns.ID, start)
+	}
+}
+
+// match returns whether the entry of the iterator is listed, and whether the
+// iterator is past the entries which can be listed.
+func (o *identityListOptions) match(ns *namespace.Namespace, byID bool, namespaceID, id, name string, metadata map[string]string, mountAccessors []string) (bool, bool) {
+	if !o.paginated() {
+		return true, false
+	}
+
+	if !byID {
+		// Iterating the name index of the namespace from the name prefix
+		if namespaceID != ns.ID || !strings.HasPrefix(o.normalizeName(name), o.normalizeName(o.namePrefix)) {
+			return false, true
+		}
+		if o.after != "" && o.normalizeName(name) <= o.normalizeName(o.after) {
+			return false, false
+		}
+	} else {
+		// Iterating the ID index of every namespace
+		if namespaceID != ns.ID || (o.after != "" && id <= o.after) {
+			return false, false
+		}
+		if !strings.HasPrefix(o.normalizeName(name), o.normalizeName(o.namePrefix)) {
+			return false, false
+		}
+	}
+
+	if o.metadataKey != "" {
+		if _, ok := metadata[o.metadataKey]; !ok {
+			return false, false
+		}
+	}
+	if o.mountAccessor != "" && !strutil.StrListContains(mountAccessors, o.mountAccessor) {
+		return false, false
+	}
+	return true, false
+}
+
+// full returns whether the listing reached its limit.
+func (o *identityListOptions) full(keys []string) bool {
+	return o.limit > 0 && len(keys) >= o.limit
+}
@@ -63,6 +63,10 @@ type role struct {
 	Key      string        `json:"key"`
 	Template string        `json:"template"`
 	ClientID string        `json:"client_id"`
+
+	// ClaimTypes maps dot-separated claim paths to the type their templated
+	// value is converted to
+	ClaimTypes map[string]string `json:"claim_types,omitempty"`
 }
 
 // idToken contains the required OIDC fields.
@@ -81,6 +85,10 @@ type idToken struct {
 	AuthTime        int64  `json:"auth_time"` // AuthTime given in OIDC authentication requests
 	AccessTokenHash string `json:"at_hash"`   // Access token hash value
 	CodeHash        string `json:"c_hash"`    // Authorization code hash value
+
+	// claimTypes is used to convert the templated claims to their
+	// configured type
+	claimTypes map[string]string
 }
 
 // discovery contains a subset of the required elements of OIDC discovery needed
@@ -270,6 +278,12 @@ func oidcPaths(i *IdentityStore) []*framework.Path {
 					Type:        framework.TypeString,
 					Description: "Optional client_id",
 				},
+				"claim_types": {
+					Type: framework.TypeKVPairs,
+					Description: `Types to convert templated claims to, keyed by claim. Nested claims
+are addressed with dot-separated paths. Valid types are string, int, float,
+bool, string_array and json.`,
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.pathOIDCCreateUpdateRole,
@@ -867,6 +881,8 @@ func (i *IdentityStore) pathOIDCGenerateToken(ctx context.Context, req *logical.
 		Audience:  role.ClientID,
 		Expiry:    now.Add(expiry).Unix(),
 		IssuedAt:  now.Unix(),
+
+		claimTypes: role.ClaimTypes,
 	}
 
 	e, err := i.MemDBEntityByID(req.EntityID, true)
@@ -982,6 +998,7 @@ func (tok *idToken) generatePayload(logger hclog.Logger, templates ...string) ([
 		logger.Error("failed to populate templates for ID token generation", "error", err)
 		return nil, err
 	}
+	coerceClaimTypes(logger, output, tok.claimTypes)
 
 	payload, err := json.Marshal(output)
 	if err != nil {
@@ -1153,6 +1170,13 @@ func (i *IdentityStore) pathOIDCCreateUpdateRole(ctx context.Context, req *logic
 		}
 	}
 
+	if claimTypes, ok := d.GetOk("claim_types"); ok {
+		role.ClaimTypes = claimTypes.(map[string]string)
+	}
+	if err := validateClaimTypes(role.ClaimTypes); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
 	if ttl, ok := d.GetOk("ttl"); ok {
 		role.TokenTTL = time.Duration(ttl.(int)) * time.Second
 	} else if req.Operation == logical.CreateOperation {
@@ -1217,12 +1241,18 @@ func (i *IdentityStore) pathOIDCReadRole(ctx context.Context, req *logical.Reque
 		return nil, nil
 	}
 
+	claimTypes := role.ClaimTypes
+	if claimTypes == nil {
+		claimTypes = map[string]string{}
+	}
+
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"client_id": role.ClientID,
-			"key":       role.Key,
-			"template":  role.Template,
-			"ttl":       int64(role.TokenTTL.Seconds()),
+			"client_id":   role.ClientID,
+			"key":         role.Key,
+			"template":    role.Template,
+			"ttl":         int64(role.TokenTTL.Seconds()),
+			"claim_types": claimTypes,
 		},
 	}, nil
 }
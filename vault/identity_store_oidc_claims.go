@@ -0,0 +1,174 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+)
+
+// Claim types a templated claim can be coerced to. Template values populated
+// from entity or group metadata are always strings, so they need to be
+// converted to produce claims of other types.
+const (
+	claimTypeString      = "string"
+	claimTypeInt         = "int"
+	claimTypeFloat       = "float"
+	claimTypeBool        = "bool"
+	claimTypeStringArray = "string_array"
+	claimTypeJSON        = "json"
+)
+
+var validClaimTypes = []string{
+	claimTypeString,
+	claimTypeInt,
+	claimTypeFloat,
+	claimTypeBool,
+	claimTypeStringArray,
+	claimTypeJSON,
+}
+
+// validateClaimTypes checks that each claim path of claimTypes is mapped to a
+// known type, and that it does not target one of the reserved claims.
+func validateClaimTypes(claimTypes map[string]string) error {
+	for path, claimType := range claimTypes {
+		if path == "" {
+			return fmt.Errorf("claim path cannot be empty")
+		}
+		for _, part := range strings.Split(path, ".") {
+			if part == "" {
+				return fmt.Errorf("invalid claim path %q", path)
+			}
+		}
+		if top := strings.SplitN(path, ".", 2)[0]; strutil.StrListContains(reservedClaims, top) {
+			return fmt.Errorf("claim %q not allowed. Restricted keys: %s", path, strings.Join(reservedClaims, ", "))
+		}
+		if !strutil.StrListContains(validClaimTypes, claimType) {
+			return fmt.Errorf("invalid type %q for claim %q. Valid types: %s", claimType, path, strings.Join(validClaimTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// coerceClaimTypes converts the claims of output found at the dot-separated
+// paths of claimTypes to their configured type. Claims that are missing or
+// that cannot be converted are logged and left untouched, so that they do not
+// block the generation of the token.
+func coerceClaimTypes(logger hclog.Logger, output map[string]interface{}, claimTypes map[string]string) {
+	// Coerce parents first, so that a claim expanded from JSON can have its
+	// nested claims coerced as well
+	paths := make([]string, 0, len(claimTypes))
+	for path := range claimTypes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		parts := strings.Split(path, ".")
+		parent := output
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := parent[part].(map[string]interface{})
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = next
+		}
+		if parent == nil {
+			continue
+		}
+
+		last := parts[len(parts)-1]
+		value, ok := parent[last]
+		if !ok {
+			continue
+		}
+		coerced, err := coerceClaim(value, claimTypes[path])
+		if err != nil {
+			logger.Warn("failed to coerce OIDC claim", "claim", path, "type", claimTypes[path], "error", err)
+			continue
+		}
+		parent[last] = coerced
+	}
+}
+
+func coerceClaim(value interface{}, claimType string) (interface{}, error) {
+	switch claimType {
+	case claimTypeString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []interface{}, map[string]interface{}:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			return string(encoded), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+
+	case claimTypeInt:
+		switch v := value.(type) {
+		case float64:
+			if v != float64(int64(v)) {
+				return nil, fmt.Errorf("%v is not an integer", v)
+			}
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+
+	case claimTypeFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+
+	case claimTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(strings.TrimSpace(v))
+		}
+
+	case claimTypeStringArray:
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return []string{}, nil
+			}
+			return strutil.TrimStrings(strings.Split(v, ",")), nil
+		case []interface{}:
+			result := make([]string, 0, len(v))
+			for _, elem := range v {
+				s, ok := elem.(string)
+				if !ok {
+					return nil, fmt.Errorf("array element %v is not a string", elem)
+				}
+				result = append(result, s)
+			}
+			return result, nil
+		}
+
+	case claimTypeJSON:
+		s, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		var result interface{}
+		if err := json.Unmarshal([]byte(s), &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %T to %s", value, claimType)
+}
@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestOIDC_coerceClaimTypes(t *testing.T) {
+	output := map[string]interface{}{
+		"level":     "3",
+		"ratio":     "0.5",
+		"admin":     "true",
+		"teams":     "a, b,c",
+		"groups":    []interface{}{"g1", "g2"},
+		"profile":   `{"team": "red", "rank": "7"}`,
+		"invalid":   "abc",
+		"id":        float64(12),
+		"untouched": "5",
+	}
+	claimTypes := map[string]string{
+		"level":        claimTypeInt,
+		"ratio":        claimTypeFloat,
+		"admin":        claimTypeBool,
+		"teams":        claimTypeStringArray,
+		"groups":       claimTypeStringArray,
+		"profile":      claimTypeJSON,
+		"profile.rank": claimTypeInt,
+		"invalid":      claimTypeInt,
+		"id":           claimTypeString,
+		"missing":      claimTypeInt,
+		"missing.sub":  claimTypeInt,
+	}
+
+	coerceClaimTypes(hclog.NewNullLogger(), output, claimTypes)
+
+	expected := map[string]interface{}{
+		"level":  int64(3),
+		"ratio":  0.5,
+		"admin":  true,
+		"teams":  []string{"a", "b", "c"},
+		"groups": []string{"g1", "g2"},
+		"profile": map[string]interface{}{
+			"team": "red",
+			"rank": int64(7),
+		},
+		"invalid":   "abc",
+		"id":        "12",
+		"untouched": "5",
+	}
+	if diff := deep.Equal(expected, output); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestOIDC_validateClaimTypes(t *testing.T) {
+	tests := map[string]struct {
+		claimTypes map[string]string
+		wantErr    bool
+	}{
+		"valid":         {map[string]string{"a.b": "int", "c": "json"}, false},
+		"unknown type":  {map[string]string{"a": "integer"}, true},
+		"reserved":      {map[string]string{"sub": "string"}, true},
+		"reserved path": {map[string]string{"iss.x": "string"}, true},
+		"empty segment": {map[string]string{"a.": "int"}, true},
+		"empty":         {map[string]string{"": "int"}, true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateClaimTypes(tt.claimTypes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %t, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -113,10 +113,11 @@ func TestOIDC_Path_OIDCRole_UpdateNoKey(t *testing.T) {
 	})
 	expectSuccess(t, resp, err)
 	expected := map[string]interface{}{
-		"key":       "test-key",
-		"ttl":       int64(120),
-		"template":  "",
-		"client_id": resp.Data["client_id"],
+		"key":         "test-key",
+		"ttl":         int64(120),
+		"template":    "",
+		"client_id":   resp.Data["client_id"],
+		"claim_types": map[string]string{},
 	}
 	if diff := deep.Equal(expected, resp.Data); diff != nil {
 		t.Fatal(diff)
@@ -167,10 +168,11 @@ func TestOIDC_Path_OIDCRole_UpdateEmptyKey(t *testing.T) {
 	})
 	expectSuccess(t, resp, err)
 	expected := map[string]interface{}{
-		"key":       "test-key",
-		"ttl":       int64(86400),
-		"template":  "",
-		"client_id": resp.Data["client_id"],
+		"key":         "test-key",
+		"ttl":         int64(86400),
+		"template":    "",
+		"client_id":   resp.Data["client_id"],
+		"claim_types": map[string]string{},
 	}
 	if diff := deep.Equal(expected, resp.Data); diff != nil {
 		t.Fatal(diff)
@@ -209,10 +211,11 @@ func TestOIDC_Path_OIDCRoleRole(t *testing.T) {
 	})
 	expectSuccess(t, resp, err)
 	expected := map[string]interface{}{
-		"key":       "test-key",
-		"ttl":       int64(86400),
-		"template":  "",
-		"client_id": resp.Data["client_id"],
+		"key":         "test-key",
+		"ttl":         int64(86400),
+		"template":    "",
+		"client_id":   resp.Data["client_id"],
+		"claim_types": map[string]string{},
 	}
 	if diff := deep.Equal(expected, resp.Data); diff != nil {
 		t.Fatal(diff)
@@ -239,10 +242,11 @@ func TestOIDC_Path_OIDCRoleRole(t *testing.T) {
 	})
 	expectSuccess(t, resp, err)
 	expected = map[string]interface{}{
-		"key":       "test-key",
-		"ttl":       int64(7200),
-		"template":  "{\"some-key\":\"some-value\"}",
-		"client_id": "my_custom_id",
+		"key":         "test-key",
+		"ttl":         int64(7200),
+		"template":    "{\"some-key\":\"some-value\"}",
+		"client_id":   "my_custom_id",
+		"claim_types": map[string]string{},
 	}
 	if diff := deep.Equal(expected, resp.Data); diff != nil {
 		t.Fatal(diff)
@@ -272,6 +276,59 @@ func TestOIDC_Path_OIDCRoleRole(t *testing.T) {
 	}
 }
 
+// TestOIDC_Path_OIDCRole_ClaimTypes tests the validation of claim types
+func TestOIDC_Path_OIDCRole_ClaimTypes(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	storage := &logical.InmemStorage{}
+
+	// Create a test key "test-key"
+	c.identityStore.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/key/test-key",
+		Operation: logical.CreateOperation,
+		Storage:   storage,
+	})
+
+	for _, claimTypes := range []map[string]interface{}{
+		{"level": "integer"},
+		{"exp": "string"},
+		{"nested..level": "int"},
+	} {
+		resp, err := c.identityStore.HandleRequest(ctx, &logical.Request{
+			Path:      "oidc/role/test-role1",
+			Operation: logical.CreateOperation,
+			Data: map[string]interface{}{
+				"key":         "test-key",
+				"claim_types": claimTypes,
+			},
+			Storage: storage,
+		})
+		expectError(t, resp, err)
+	}
+
+	resp, err := c.identityStore.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/role/test-role1",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"key":         "test-key",
+			"template":    `{"org": {"level": {{identity.entity.metadata.level}} } }`,
+			"claim_types": map[string]interface{}{"org.level": "int"},
+		},
+		Storage: storage,
+	})
+	expectSuccess(t, resp, err)
+
+	resp, err = c.identityStore.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/role/test-role1",
+		Operation: logical.ReadOperation,
+		Storage:   storage,
+	})
+	expectSuccess(t, resp, err)
+	if diff := deep.Equal(map[string]string{"org.level": "int"}, resp.Data["claim_types"]); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
 // TestOIDC_Path_OIDCRole_InvalidTokenTTL tests the TokenTTL validation
 func TestOIDC_Path_OIDCRole_InvalidTokenTTL(t *testing.T) {
 	c, _, _ := TestCoreUnsealed(t)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"regexp"
 	"sync"
+	"time"
 
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-memdb"
@@ -60,6 +61,11 @@ type IdentityStore struct {
 	// groupLock is used to protect modifications to group entries
 	groupLock sync.RWMutex
 
+	// dynamicGroupRefreshes records when the members of each dynamic group
+	// were last computed, keyed by group ID.
+	dynamicGroupRefreshes     map[string]time.Time
+	dynamicGroupRefreshesLock sync.Mutex
+
 	// oidcCache stores common response data as well as when the periodic func needs
 	// to run. This is conservatively managed, and most writes to the OIDC endpoints
 	// will invalidate the cache.
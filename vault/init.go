@@ -36,6 +36,10 @@ type InitResult struct {
 	SecretShares   [][]byte
 	RecoveryShares [][]byte
 	RootToken      string
+
+	// SecretSharesTOTP are the TOTP keys of the secret shares, in the same
+	// order, when they are TOTP protected.
+	SecretSharesTOTP [][]byte
 }
 
 var (
@@ -121,11 +125,14 @@ func (c *Core) InitializedLocally(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
-func (c *Core) generateShares(sc *SealConfig) ([]byte, [][]byte, error) {
+// generateShares generates a root key and splits it according to sc. It
+// returns the root key, its shares, and the unseal keys to hand out, which
+// are the shares encrypted with the PGP keys of sc if it has any.
+func (c *Core) generateShares(sc *SealConfig) ([]byte, [][]byte, [][]byte, error) {
 	// Generate a root key
 	rootKey, err := c.barrier.GenerateKey(c.secureRandomReader)
 	if err != nil {
-		return nil, nil, fmt.Errorf("key generation failed: %w", err)
+		return nil, nil, nil, fmt.Errorf("key generation failed: %w", err)
 	}
 
 	// Return the root key if only a single key part is used
@@ -136,10 +143,11 @@ func (c *Core) generateShares(sc *SealConfig) ([]byte, [][]byte, error) {
 		// Split the root key using the Shamir algorithm
 		shares, err := shamir.Split(rootKey, sc.SecretShares, sc.SecretThreshold)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to generate barrier shares: %w", err)
+			return nil, nil, nil, fmt.Errorf("failed to generate barrier shares: %w", err)
 		}
 		unsealKeys = shares
 	}
+	shares := unsealKeys
 
 	// If we have PGP keys, perform the encryption
 	if len(sc.PGPKeys) > 0 {
@@ -149,12 +157,12 @@ func (c *Core) generateShares(sc *SealConfig) ([]byte, [][]byte, error) {
 		}
 		_, encryptedShares, err := pgpkeys.EncryptShares(hexEncodedShares, sc.PGPKeys)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		unsealKeys = encryptedShares
 	}
 
-	return rootKey, unsealKeys, nil
+	return rootKey, shares, unsealKeys, nil
 }
 
 // Initialize is used to initialize the Vault with the given
@@ -202,6 +210,13 @@ func (c *Core) Initialize(ctx context.Context, initParams *InitParams) (*InitRes
 		if len(recoveryConfig.PGPKeys) > 0 && len(recoveryConfig.PGPKeys) != recoveryConfig.SecretShares {
 			return nil, fmt.Errorf("incorrect number of PGP keys for recovery")
 		}
+		if recoveryConfig.TOTPProtected {
+			return nil, fmt.Errorf("TOTP protected shares not supported for recovery keys")
+		}
+	}
+
+	if barrierConfig.TOTPProtected && c.seal.BarrierType() != wrapping.WrapperTypeShamir {
+		return nil, fmt.Errorf("TOTP protected shares only supported with Shamir seals")
 	}
 
 	if c.seal.RecoveryKeySupported() {
@@ -268,17 +283,17 @@ func (c *Core) Initialize(ctx context.Context, initParams *InitParams) (*InitRes
 		defer initPTCleanup()
 	}
 
-	barrierKey, barrierKeyShares, err := c.generateShares(barrierConfig)
+	barrierKey, barrierShares, barrierKeyShares, err := c.generateShares(barrierConfig)
 	if err != nil {
 		c.logger.Error("error generating shares", "error", err)
 		return nil, err
 	}
 
 	var sealKey []byte
-	var sealKeyShares [][]byte
+	var sealShares, sealKeyShares [][]byte
 
 	if barrierConfig.StoredShares == 1 && c.seal.BarrierType() == wrapping.WrapperTypeShamir {
-		sealKey, sealKeyShares, err = c.generateShares(barrierConfig)
+		sealKey, sealShares, sealKeyShares, err = c.generateShares(barrierConfig)
 		if err != nil {
 			c.logger.Error("error generating shares", "error", err)
 			return nil, err
@@ -322,6 +337,7 @@ func (c *Core) Initialize(ctx context.Context, initParams *InitParams) (*InitRes
 
 	// If we are storing shares, pop them out of the returned results and push
 	// them through the seal
+	var totpShares [][]byte
 	switch c.seal.StoredKeysSupported() {
 	case seal.StoredKeysSupportedShamirRoot:
 		keysToStore := [][]byte{barrierKey}
@@ -334,6 +350,7 @@ func (c *Core) Initialize(ctx context.Context, initParams *InitParams) (*InitRes
 			return nil, fmt.Errorf("failed to store keys: %w", err)
 		}
 		results.SecretShares = sealKeyShares
+		totpShares = sealShares
 	case seal.StoredKeysSupportedGeneric:
 		keysToStore := [][]byte{barrierKey}
 		if err := c.seal.SetStoredKeys(ctx, keysToStore); err != nil {
@@ -344,6 +361,21 @@ func (c *Core) Initialize(ctx context.Context, initParams *InitParams) (*InitRes
 		// We don't support initializing an old-style Shamir seal anymore, so
 		// this case is only reachable by tests.
 		results.SecretShares = barrierKeyShares
+		totpShares = barrierShares
+	}
+
+	// Bind the shares handed out to the TOTP secrets of their holders
+	if barrierConfig.TOTPProtected {
+		bindings, totpKeys, err := c.newUnsealTOTPBindings(ctx, totpShares, barrierConfig.PGPKeys)
+		if err != nil {
+			c.logger.Error("failed to generate TOTP secrets", "error", err)
+			return nil, err
+		}
+		if err := c.persistUnsealTOTPBindings(ctx, bindings); err != nil {
+			c.logger.Error("failed to store TOTP secrets", "error", err)
+			return nil, err
+		}
+		results.SecretSharesTOTP = totpKeys
 	}
 
 	// Perform initial setup
@@ -374,7 +406,7 @@ func (c *Core) Initialize(ctx context.Context, initParams *InitParams) (*InitRes
 		}
 
 		if recoveryConfig.SecretShares > 0 {
-			recoveryKey, recoveryUnsealKeys, err := c.generateShares(recoveryConfig)
+			recoveryKey, _, recoveryUnsealKeys, err := c.generateShares(recoveryConfig)
 			if err != nil {
 				c.logger.Error("failed to generate recovery shares", "error", err)
 				return nil, err
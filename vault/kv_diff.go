@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// kvDiffSide is a version of a KV version 2 secret compared by a diff.
+type kvDiffSide struct {
+	mount      *MountEntry
+	secretPath string
+
+	// version is the version of the secret requested, where 0 is the latest
+	// version, and resolvedVersion the version that was read. data is nil
+	// if the version does not exist, was deleted or was destroyed.
+	version         int
+	resolvedVersion int64
+	data            map[string]interface{}
+}
+
+// dataPath returns the path the version is read from, relative to its
+// namespace.
+func (s *kvDiffSide) dataPath() string {
+	return s.mount.Path + "data/" + s.secretPath
+}
+
+// kvDiff holds the keys of the data of a secret version that changed in
+// another version.
+type kvDiff struct {
+	from, to *kvDiffSide
+
+	added    []string
+	removed  []string
+	modified []string
+}
+
+// resolveKVDiffSide returns the version of the KV version 2 secret at p,
+// relative to the namespace of ctx.
+func (c *Core) resolveKVDiffSide(ctx context.Context, p string, version int) (*kvDiffSide, error) {
+	if version < 0 {
+		return nil, fmt.Errorf("invalid version %d", version)
+	}
+
+	p = strings.Trim(p, "/")
+	entry := c.router.MatchingMountEntry(ctx, p)
+	if entry == nil {
+		return nil, fmt.Errorf("no secrets engine is mounted at %q", p)
+	}
+	if !isKVv2Mount(entry) {
+		return nil, fmt.Errorf("%q is not a KV version 2 secrets engine", entry.Path)
+	}
+
+	secretPath := strings.TrimPrefix(p, entry.Path)
+	if secretPath == "" {
+		return nil, fmt.Errorf("missing secret path within %q", entry.Path)
+	}
+	return &kvDiffSide{
+		mount:      entry,
+		secretPath: secretPath,
+		version:    version,
+	}, nil
+}
+
+// checkKVDiffAccess ensures token can read every version compared by a diff,
+// since they are read on its behalf through the router.
+func (c *Core) checkKVDiffAccess(ctx context.Context, token string, sides ...*kvDiffSide) error {
+	paths := make([]string, 0, len(sides))
+	for _, side := range sides {
+		paths = append(paths, side.dataPath())
+	}
+
+	capabilities, err := c.CapabilitiesForPaths(ctx, token, paths)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if !strutil.StrListContains(capabilities[p], ReadCapability) && !strutil.StrListContains(capabilities[p], RootCapability) {
+			return fmt.Errorf("%w: reading %q is not allowed", logical.ErrPermissionDenied, p)
+		}
+	}
+	return nil
+}
+
+// readKVDiffSide reads the data of the version of the secret.
+func (c *Core) readKVDiffSide(ctx context.Context, side *kvDiffSide) error {
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      side.dataPath(),
+	}
+	if side.version > 0 {
+		req.Data = map[string]interface{}{
+			"version": side.version,
+		}
+	}
+
+	resp, err := c.router.Route(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil
+	}
+	if resp.IsError() {
+		return resp.Error()
+	}
+
+	if metadata, ok := resp.Data["metadata"].(map[string]interface{}); ok {
+		if side.resolvedVersion, err = parseutil.ParseInt(metadata["version"]); err != nil {
+			return fmt.Errorf("failed to parse secret version: %w", err)
+		}
+	}
+	side.data, _ = resp.Data["data"].(map[string]interface{})
+	return nil
+}
+
+// diffKVSecrets compares the data of two versions of KV version 2 secrets,
+// which may be versions of the same secret or of secrets in different
+// mounts. Versions that do not exist compare as empty data.
+func (c *Core) diffKVSecrets(ctx context.Context, token string, from, to *kvDiffSide) (*kvDiff, error) {
+	if err := c.checkKVDiffAccess(ctx, token, from, to); err != nil {
+		return nil, err
+	}
+	for _, side := range []*kvDiffSide{from, to} {
+		if err := c.readKVDiffSide(ctx, side); err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", side.dataPath(), err)
+		}
+	}
+
+	diff := &kvDiff{
+		from: from,
+		to:   to,
+	}
+	for key, fromValue := range from.data {
+		toValue, ok := to.data[key]
+		switch {
+		case !ok:
+			diff.removed = append(diff.removed, key)
+		case !reflect.DeepEqual(fromValue, toValue):
+			diff.modified = append(diff.modified, key)
+		}
+	}
+	for key := range to.data {
+		if _, ok := from.data[key]; !ok {
+			diff.added = append(diff.added, key)
+		}
+	}
+	sort.Strings(diff.added)
+	sort.Strings(diff.removed)
+	sort.Strings(diff.modified)
+
+	return diff, nil
+}
@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCore_KVDiff(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	testMountKVv2(t, c, "kv2/", "kv2-other/")
+
+	handle := func(token string, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := logical.TestRequest(t, op, path)
+		req.ClientToken = token
+		req.Data = data
+		return c.HandleRequest(ctx, req)
+	}
+	write := func(path string, version int, data map[string]interface{}) {
+		t.Helper()
+		if _, err := handle(root, logical.UpdateOperation, path, map[string]interface{}{
+			"data":     data,
+			"metadata": map[string]interface{}{"version": version},
+		}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	write("kv2/data/app", 3, map[string]interface{}{
+		"user":     "app",
+		"password": "old",
+		"host":     "db.example.com",
+	})
+	write("kv2-other/data/app", 7, map[string]interface{}{
+		"user":     "app",
+		"password": "new",
+		"port":     "5432",
+	})
+
+	// Only KV version 2 secrets can be compared
+	if _, err := handle(root, logical.UpdateOperation, "sys/kv-diff", map[string]interface{}{
+		"from_path": "secret/app",
+	}); err == nil {
+		t.Fatal("expected an error comparing a secret of a KV version 1 mount")
+	}
+
+	resp, err := handle(root, logical.UpdateOperation, "sys/kv-diff", map[string]interface{}{
+		"from_path": "kv2/app",
+		"to_path":   "kv2-other/app",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[string]interface{}{
+		"from":     map[string]interface{}{"path": "kv2/app", "version": int64(3), "exists": true},
+		"to":       map[string]interface{}{"path": "kv2-other/app", "version": int64(7), "exists": true},
+		"added":    []string{"port"},
+		"removed":  []string{"host"},
+		"modified": []string{"password"},
+		"changes": map[string]interface{}{
+			"port":     map[string]interface{}{"to": "5432"},
+			"host":     map[string]interface{}{"from": "db.example.com"},
+			"password": map[string]interface{}{"from": "old", "to": "new"},
+		},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: got\n%#v\nexpected\n%#v", resp.Data, expected)
+	}
+
+	// Values can be masked, and missing secrets compare as empty data
+	resp, err = handle(root, logical.UpdateOperation, "sys/kv-diff", map[string]interface{}{
+		"from_path":   "kv2/missing",
+		"to_path":     "kv2/app",
+		"mask_values": true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := resp.Data["changes"]; ok {
+		t.Fatalf("expected masked values: %#v", resp.Data)
+	}
+	if !reflect.DeepEqual(resp.Data["added"], []string{"host", "password", "user"}) || resp.Data["from"].(map[string]interface{})["exists"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The caller must be allowed to read both secrets
+	policy, err := ParseACLPolicy(namespace.RootNamespace, `
+path "sys/kv-diff" {
+	capabilities = ["update"]
+}
+path "kv2/data/app" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy.Name = "kv-diff"
+	if err := c.policyStore.SetPolicy(ctx, policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testMakeTokenDirectly(t, c.tokenStore, &logical.TokenEntry{
+		ID:       "kvdifftoken",
+		Path:     "auth/token/create",
+		Policies: []string{"kv-diff"},
+		TTL:      time.Hour,
+	})
+
+	if _, err := handle("kvdifftoken", logical.UpdateOperation, "sys/kv-diff", map[string]interface{}{
+		"from_path": "kv2/app",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = handle("kvdifftoken", logical.UpdateOperation, "sys/kv-diff", map[string]interface{}{
+		"from_path": "kv2/app",
+		"to_path":   "kv2-other/app",
+	})
+	if err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+}
@@ -0,0 +1,375 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// coreKVLocksPath is the prefix the locks of KV version 2 secrets are
+	// stored under, keyed by the UUID of their mount and their path within
+	// the mount.
+	coreKVLocksPath = "core/kv-locks/"
+
+	// kvLockDefaultUnlockWindow is how long a locked secret can be deleted
+	// for after it is unlocked, when the lock does not set it.
+	kvLockDefaultUnlockWindow = 15 * time.Minute
+
+	// kvDefaultMaxVersions is the number of versions KV version 2 keeps
+	// when neither the metadata of a secret nor the mount set it.
+	kvDefaultMaxVersions = 10
+)
+
+var errKVSecretLocked = errors.New("secret is locked against deletion")
+
+// kvLock protects a KV version 2 secret from deletion. Deleting versions of
+// the secret, destroying them, deleting its metadata or writing metadata which
+// deletes versions fails unless the secret was unlocked within the unlock
+// window.
+type kvLock struct {
+	Path         string        `json:"path"`
+	UnlockWindow time.Duration `json:"unlock_window"`
+	CreationTime time.Time     `json:"creation_time"`
+
+	// UnlockedUntil is when the last unlock of the secret expires, and
+	// UnlockedBy the entity or display name of whoever unlocked it.
+	UnlockedUntil time.Time `json:"unlocked_until"`
+	UnlockedBy    string    `json:"unlocked_by"`
+}
+
+// unlocked returns whether deletions of the locked secret are allowed at now.
+func (l *kvLock) unlocked(now time.Time) bool {
+	return now.Before(l.UnlockedUntil)
+}
+
+// kvLockTarget is a KV version 2 secret a lock applies to.
+type kvLockTarget struct {
+	mount      *MountEntry
+	secretPath string
+}
+
+func (t *kvLockTarget) storagePath() string {
+	return coreKVLocksPath + t.mount.UUID + "/" + t.secretPath
+}
+
+func (t *kvLockTarget) String() string {
+	return t.mount.Path + t.secretPath
+}
+
+// isKVv2Mount returns whether entry is a KV version 2 secrets engine.
+func isKVv2Mount(entry *MountEntry) bool {
+	return entry != nil && entry.Type == "kv" && entry.Options["version"] == "2"
+}
+
+// resolveKVLockTarget returns the KV version 2 secret at p, relative to the
+// namespace of ctx.
+func (c *Core) resolveKVLockTarget(ctx context.Context, p string) (*kvLockTarget, error) {
+	p = strings.Trim(p, "/")
+	// Match the mount itself too, given without its trailing slash
+	entry := c.router.MatchingMountEntry(ctx, p+"/")
+	if entry == nil {
+		return nil, fmt.Errorf("no secrets engine is mounted at %q", p)
+	}
+	if !isKVv2Mount(entry) {
+		return nil, fmt.Errorf("%q is not a KV version 2 secrets engine", entry.Path)
+	}
+
+	secretPath := strings.TrimSuffix(strings.TrimPrefix(p+"/", entry.Path), "/")
+	if secretPath == "" {
+		return nil, fmt.Errorf("missing secret path within %q", entry.Path)
+	}
+	return &kvLockTarget{
+		mount:      entry,
+		secretPath: secretPath,
+	}, nil
+}
+
+// kvLockedRequestPath returns the path of the secret whose versions or
+// metadata req may delete, relative to the mount of entry, if entry is a KV
+// version 2 secrets engine. It also returns whether req writes the metadata
+// of the secret, which only deletes versions depending on its data.
+func kvLockedRequestPath(entry *MountEntry, req *logical.Request) (string, bool, bool) {
+	if !isKVv2Mount(entry) {
+		return "", false, false
+	}
+
+	p := strings.TrimPrefix(req.Path, entry.Path)
+	var prefixes []string
+	switch req.Operation {
+	case logical.DeleteOperation:
+		prefixes = []string{"data/", "metadata/"}
+	case logical.UpdateOperation, logical.CreateOperation, logical.PatchOperation:
+		prefixes = []string{"delete/", "destroy/", "metadata/"}
+	default:
+		return "", false, false
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(p, prefix) {
+			secretPath := strings.Trim(strings.TrimPrefix(p, prefix), "/")
+			metadataWrite := prefix == "metadata/" && req.Operation != logical.DeleteOperation
+			return secretPath, metadataWrite, secretPath != ""
+		}
+	}
+	return "", false, false
+}
+
+// kvMetadataWriteDeletesVersions returns whether writing data to the
+// metadata of the secret at secretPath of entry deletes versions of the
+// secret: either by lowering the number of versions kept, or by setting
+// delete_version_after. The current metadata and configuration of the mount
+// are read through the router to tell whether the number is lowered.
+func (c *Core) kvMetadataWriteDeletesVersions(ctx context.Context, entry *MountEntry, secretPath string, data map[string]interface{}) (bool, error) {
+	if raw, ok := data["delete_version_after"]; ok {
+		deleteVersionAfter, err := parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return false, fmt.Errorf("invalid delete_version_after: %w", err)
+		}
+		if deleteVersionAfter != 0 {
+			return true, nil
+		}
+	}
+
+	raw, ok := data["max_versions"]
+	if !ok {
+		return false, nil
+	}
+	maxVersions, err := parseutil.SafeParseInt(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid max_versions: %w", err)
+	}
+
+	current, err := c.readKVMaxVersions(ctx, entry.Path+"metadata/"+secretPath)
+	if err != nil {
+		return false, err
+	}
+	mountMaxVersions, err := c.readKVMaxVersions(ctx, entry.Path+"config")
+	if err != nil {
+		return false, err
+	}
+	effective := func(maxVersions int) int {
+		switch {
+		case maxVersions > 0:
+			return maxVersions
+		case mountMaxVersions > 0:
+			return mountMaxVersions
+		default:
+			return kvDefaultMaxVersions
+		}
+	}
+	return effective(maxVersions) < effective(current), nil
+}
+
+// readKVMaxVersions returns the max_versions of the KV version 2 metadata or
+// configuration at p, or 0 if it is not set.
+func (c *Core) readKVMaxVersions(ctx context.Context, p string) (int, error) {
+	resp, err := c.router.Route(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      p,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %q: %w", p, err)
+	}
+	if resp == nil || resp.Data["max_versions"] == nil {
+		return 0, nil
+	}
+	maxVersions, err := parseutil.SafeParseInt(resp.Data["max_versions"])
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_versions at %q: %w", p, err)
+	}
+	return maxVersions, nil
+}
+
+// checkKVLock fails requests deleting, destroying or removing the metadata
+// of a locked KV version 2 secret which is not currently unlocked, as well as
+// metadata writes deleting versions of the secret.
+func (c *Core) checkKVLock(ctx context.Context, entry *MountEntry, req *logical.Request) error {
+	secretPath, metadataWrite, ok := kvLockedRequestPath(entry, req)
+	if !ok {
+		return nil
+	}
+
+	target := &kvLockTarget{
+		mount:      entry,
+		secretPath: secretPath,
+	}
+	lock, err := c.getKVLock(ctx, target)
+	if err != nil {
+		return err
+	}
+	if lock == nil || lock.unlocked(time.Now()) {
+		return nil
+	}
+	if metadataWrite {
+		deletes, err := c.kvMetadataWriteDeletesVersions(ctx, entry, secretPath, req.Data)
+		if err != nil {
+			return err
+		}
+		if !deletes {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unlock %q through sys/kv-unlock first", errKVSecretLocked, target.String())
+}
+
+func (c *Core) getKVLock(ctx context.Context, target *kvLockTarget) (*kvLock, error) {
+	raw, err := c.barrier.Get(ctx, target.storagePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KV lock: %w", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var lock kvLock
+	if err := raw.DecodeJSON(&lock); err != nil {
+		return nil, fmt.Errorf("failed to decode KV lock: %w", err)
+	}
+	// The mount may have moved since the lock was created
+	lock.Path = target.String()
+	return &lock, nil
+}
+
+func (c *Core) persistKVLock(ctx context.Context, target *kvLockTarget, lock *kvLock) error {
+	entry, err := logical.StorageEntryJSON(target.storagePath(), lock)
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to persist KV lock: %w", err)
+	}
+	return nil
+}
+
+// readKVLock returns the lock of the secret at p, or nil if it is not
+// locked.
+func (c *Core) readKVLock(ctx context.Context, p string) (*kvLock, error) {
+	target, err := c.resolveKVLockTarget(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return c.getKVLock(ctx, target)
+}
+
+// setKVLock locks the secret at p, or updates its unlock window if it is
+// already locked. Either way, the secret is locked again if it was
+// unlocked.
+func (c *Core) setKVLock(ctx context.Context, p string, unlockWindow time.Duration) (*kvLock, error) {
+	target, err := c.resolveKVLockTarget(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	c.kvLocksLock.Lock()
+	defer c.kvLocksLock.Unlock()
+
+	lock, err := c.getKVLock(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		lock = &kvLock{
+			Path:         target.String(),
+			CreationTime: time.Now().UTC(),
+		}
+	}
+	lock.UnlockWindow = unlockWindow
+	lock.UnlockedUntil = time.Time{}
+	lock.UnlockedBy = ""
+
+	if err := c.persistKVLock(ctx, target, lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// unlockKVLock allows deleting the locked secret at p for the unlock window
+// of its lock.
+func (c *Core) unlockKVLock(ctx context.Context, p, unlockedBy string) (*kvLock, error) {
+	target, err := c.resolveKVLockTarget(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	c.kvLocksLock.Lock()
+	defer c.kvLocksLock.Unlock()
+
+	lock, err := c.getKVLock(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return nil, logical.CodedError(404, fmt.Sprintf("secret %q is not locked", target.String()))
+	}
+
+	lock.UnlockedUntil = time.Now().UTC().Add(lock.UnlockWindow)
+	lock.UnlockedBy = unlockedBy
+	if err := c.persistKVLock(ctx, target, lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// removeKVLock removes the lock of the secret at p. As removing the lock
+// allows deletions as well, the secret must be unlocked first.
+func (c *Core) removeKVLock(ctx context.Context, p string) error {
+	target, err := c.resolveKVLockTarget(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	c.kvLocksLock.Lock()
+	defer c.kvLocksLock.Unlock()
+
+	lock, err := c.getKVLock(ctx, target)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return nil
+	}
+	if !lock.unlocked(time.Now()) {
+		return fmt.Errorf("secret %q must be unlocked before its lock is removed", target.String())
+	}
+
+	if err := c.barrier.Delete(ctx, target.storagePath()); err != nil {
+		return fmt.Errorf("failed to delete KV lock: %w", err)
+	}
+	return nil
+}
+
+// listKVLocks returns the paths of the locked secrets of the KV version 2
+// mounts of the namespace of ctx.
+func (c *Core) listKVLocks(ctx context.Context) ([]string, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	view := NewBarrierView(c.barrier, coreKVLocksPath)
+	keys, err := logical.CollectKeys(ctx, view)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list KV locks: %w", err)
+	}
+
+	var paths []string
+	for _, key := range keys {
+		mountUUID, secretPath, ok := strings.Cut(key, "/")
+		if !ok {
+			continue
+		}
+		entry := c.router.MatchingMountByUUID(mountUUID)
+		if entry == nil || entry.NamespaceID != ns.ID {
+			continue
+		}
+		paths = append(paths, entry.Path+secretPath)
+	}
+	return paths, nil
+}
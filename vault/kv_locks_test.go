@@ -0,0 +1,143 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCore_KVLocks(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	testMountKVv2(t, c, "kv2/")
+
+	handle := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := logical.TestRequest(t, op, path)
+		req.ClientToken = root
+		req.Data = data
+		return c.HandleRequest(ctx, req)
+	}
+
+	// Only KV version 2 secrets can be locked
+	if _, err := handle(logical.UpdateOperation, "sys/kv-locks/secret/crown", nil); err == nil {
+		t.Fatal("expected an error locking a secret of a KV version 1 mount")
+	}
+	if resp, err := handle(logical.UpdateOperation, "sys/kv-locks/kv2", nil); err == nil || !strings.Contains(resp.Error().Error(), "missing secret path") {
+		t.Fatalf("expected an error locking a mount, got: %#v, %v", resp, err)
+	}
+
+	if _, err := handle(logical.UpdateOperation, "kv2/metadata/crown", map[string]interface{}{"max_versions": 5}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := handle(logical.UpdateOperation, "sys/kv-locks/kv2/crown", map[string]interface{}{
+		"unlock_window": "1h",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["path"] != "kv2/crown" || resp.Data["unlock_window"] != int64(3600) || resp.Data["unlocked"] != false {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err = handle(logical.ListOperation, "sys/kv-locks", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "kv2/crown" {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	// Every way of deleting the locked secret fails, while other secrets
+	// and other operations are unaffected
+	locked := []struct {
+		op   logical.Operation
+		path string
+	}{
+		{logical.DeleteOperation, "kv2/data/crown"},
+		{logical.UpdateOperation, "kv2/delete/crown"},
+		{logical.UpdateOperation, "kv2/destroy/crown"},
+		{logical.DeleteOperation, "kv2/metadata/crown"},
+	}
+	for _, l := range locked {
+		resp, err := handle(l.op, l.path, map[string]interface{}{"versions": "1"})
+		if err == nil || resp == nil || !strings.Contains(resp.Error().Error(), "locked against deletion") {
+			t.Fatalf("expected %s on %q to fail: resp: %#v, err: %v", l.op, l.path, resp, err)
+		}
+	}
+	if _, err := handle(logical.UpdateOperation, "kv2/data/crown", map[string]interface{}{"data": "value"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Metadata writes are locked when they delete versions, by lowering the
+	// number of versions kept or by setting delete_version_after
+	for _, data := range []map[string]interface{}{
+		{"max_versions": 3},
+		{"delete_version_after": "1h"},
+	} {
+		resp, err := handle(logical.UpdateOperation, "kv2/metadata/crown", data)
+		if err == nil || resp == nil || !strings.Contains(resp.Error().Error(), "locked against deletion") {
+			t.Fatalf("expected writing %v to the metadata to fail: resp: %#v, err: %v", data, resp, err)
+		}
+	}
+	if _, err := handle(logical.UpdateOperation, "kv2/metadata/crown", map[string]interface{}{
+		"max_versions":         8,
+		"delete_version_after": "0s",
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp, err := handle(logical.UpdateOperation, "kv2/metadata/crown", map[string]interface{}{"max_versions": 5}); err == nil {
+		t.Fatalf("expected lowering max_versions to fail: %#v", resp)
+	}
+	if _, err := handle(logical.DeleteOperation, "kv2/data/other", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Locks cannot be removed while the secret is locked
+	if _, err := handle(logical.DeleteOperation, "sys/kv-locks/kv2/crown", nil); err == nil {
+		t.Fatal("expected an error removing the lock of a locked secret")
+	}
+
+	resp, err = handle(logical.UpdateOperation, "sys/kv-unlock/kv2/crown", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["unlocked"] != true || resp.Data["unlocked_by"] != "root" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	for _, l := range locked {
+		if _, err := handle(l.op, l.path, map[string]interface{}{"versions": "1"}); err != nil {
+			t.Fatalf("err: %s on %q: %v", l.op, l.path, err)
+		}
+	}
+
+	// Writing the lock again locks the secret immediately
+	if _, err := handle(logical.UpdateOperation, "sys/kv-locks/kv2/crown", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lock, err := c.readKVLock(ctx, "kv2/crown")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if lock.unlocked(time.Now()) || lock.UnlockWindow != kvLockDefaultUnlockWindow {
+		t.Fatalf("bad lock: %#v", lock)
+	}
+	if _, err := handle(logical.DeleteOperation, "kv2/data/crown", nil); err == nil {
+		t.Fatal("expected an error deleting a locked secret")
+	}
+
+	if _, err := handle(logical.UpdateOperation, "sys/kv-unlock/kv2/crown", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := handle(logical.DeleteOperation, "sys/kv-locks/kv2/crown", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := handle(logical.UpdateOperation, "sys/kv-unlock/kv2/crown", nil); err == nil {
+		t.Fatal("expected an error unlocking a secret which is not locked")
+	}
+}
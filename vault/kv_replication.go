@@ -0,0 +1,659 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/tlsutil"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/secretsync"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// kvReplicationSubPath is the sub-path used for the KV replication
+	// configuration. It holds client certificates and is seal wrapped.
+	kvReplicationSubPath = "kv-replication/"
+
+	kvReplicationTargetPrefix = "targets/"
+	kvReplicationStatePrefix  = "state/"
+
+	kvReplicationDefaultAuthMount      = "cert"
+	kvReplicationDefaultInterval       = 30 * time.Second
+	kvReplicationDefaultResyncInterval = time.Hour
+)
+
+// kvReplicationTick is how often targets are checked for a pending
+// replication pass. It is a variable so tests can shorten it.
+var kvReplicationTick = 5 * time.Second
+
+var errKVReplicationUnavailable = errors.New("kv replication is not available on this node")
+
+// kvReplicationTargetEntry is the stored configuration of a cluster that
+// selected KV v2 paths are replicated to.
+type kvReplicationTargetEntry struct {
+	Name          string `json:"name"`
+	Address       string `json:"address"`
+	CACert        string `json:"ca_cert"`
+	ClientCert    string `json:"client_cert"`
+	ClientKey     string `json:"client_key"`
+	TLSServerName string `json:"tls_server_name"`
+	AuthMount     string `json:"auth_mount"`
+	AuthRole      string `json:"auth_role"`
+
+	// Paths are the replicated mount paths and prefixes. A prefix ending
+	// with a slash, or an empty one, replicates a whole tree; otherwise a
+	// single secret is replicated.
+	Paths []*kvReplicationPath `json:"paths"`
+
+	Interval       time.Duration `json:"interval"`
+	ResyncInterval time.Duration `json:"resync_interval"`
+}
+
+// kvReplicationPath is a replicated KV v2 mount and prefix. Secrets are
+// written to the same mount and path on the target cluster.
+type kvReplicationPath struct {
+	Mount  string `json:"mount"`
+	Prefix string `json:"prefix"`
+}
+
+func (p *kvReplicationPath) String() string {
+	return p.Mount + p.Prefix
+}
+
+// kvReplicationState tracks what has been replicated to a target. It is
+// stored apart from the configuration as it changes on every pass.
+type kvReplicationState struct {
+	// Versions holds the last replicated version of every secret, keyed by
+	// mount and secret path.
+	Versions map[string]int64 `json:"versions"`
+
+	LastRun      time.Time `json:"last_run"`
+	LastSuccess  time.Time `json:"last_success"`
+	LastFullSync time.Time `json:"last_full_sync"`
+	LastError    string    `json:"last_error"`
+
+	// Written and Deleted count the secrets changed by the last pass.
+	Written int `json:"written"`
+	Deleted int `json:"deleted"`
+}
+
+// kvReplicationSource provides the secrets being replicated.
+type kvReplicationSource interface {
+	splitPath(ctx context.Context, p string) (string, string, error)
+	listSecrets(ctx context.Context, mount, prefix string) ([]string, error)
+	readSecret(ctx context.Context, mount, secretPath string) (map[string]interface{}, int64, error)
+}
+
+// kvReplicationTarget writes secrets to the receiving cluster.
+type kvReplicationTarget interface {
+	// readSecret returns the data of the latest version of a secret, or
+	// nil if it does not exist or was deleted.
+	readSecret(ctx context.Context, mount, secretPath string) (map[string]interface{}, error)
+	writeSecret(ctx context.Context, mount, secretPath string, data map[string]interface{}) error
+
+	// deleteSecret soft deletes the latest version of a secret;
+	// destroySecret removes the secret along with all of its versions.
+	deleteSecret(ctx context.Context, mount, secretPath string) error
+	destroySecret(ctx context.Context, mount, secretPath string) error
+}
+
+// kvReplicationManager streams selected KV v2 secrets one way to other
+// clusters. Replication is eventually consistent: each target is brought up
+// to date on its own interval, and the source always wins over changes made
+// on the target.
+type kvReplicationManager struct {
+	core   *Core
+	logger log.Logger
+	view   *BarrierView
+
+	source    kvReplicationSource
+	newTarget func(entry *kvReplicationTargetEntry) (kvReplicationTarget, error)
+
+	// l serializes configuration changes and replication passes.
+	l       sync.Mutex
+	targets map[string]kvReplicationTarget
+
+	cancel context.CancelFunc
+	doneCh chan struct{}
+}
+
+func newKVReplicationManager(c *Core, logger log.Logger, view *BarrierView) *kvReplicationManager {
+	m := &kvReplicationManager{
+		core:    c,
+		logger:  logger,
+		view:    view,
+		source:  &kvSource{core: c},
+		targets: make(map[string]kvReplicationTarget),
+	}
+	m.newTarget = m.newRemoteTarget
+	return m
+}
+
+func (c *Core) setupKVReplication(ctx context.Context) error {
+	logger := c.baseLogger.Named("kv-replication")
+	c.AddLogger(logger)
+
+	c.kvReplication = newKVReplicationManager(c, logger, c.systemBarrierView.SubView(kvReplicationSubPath))
+
+	// Secrets are only pushed from the active node of the primary cluster.
+	if c.perfStandby || c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+
+	c.kvReplication.start(ctx)
+	return nil
+}
+
+func (c *Core) stopKVReplication() {
+	// preSeal may run before setupKVReplication got a chance to complete.
+	if c.kvReplication != nil {
+		c.kvReplication.stop()
+	}
+
+	c.kvReplication = nil
+}
+
+func (m *kvReplicationManager) start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(namespace.RootContext(ctx))
+	m.doneCh = make(chan struct{})
+
+	go func() {
+		defer close(m.doneCh)
+
+		ticker := time.NewTicker(kvReplicationTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.replicateDue(ctx)
+			}
+		}
+	}()
+}
+
+func (m *kvReplicationManager) stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	<-m.doneCh
+}
+
+// replicateDue runs a replication pass for every target whose interval has
+// elapsed since its last pass.
+func (m *kvReplicationManager) replicateDue(ctx context.Context) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	names, err := m.view.List(ctx, kvReplicationTargetPrefix)
+	if err != nil {
+		m.logger.Error("failed to list targets", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return
+		}
+
+		entry, err := m.getTarget(ctx, name)
+		if err != nil || entry == nil {
+			if err != nil {
+				m.logger.Error("failed to read target", "name", name, "error", err)
+			}
+			continue
+		}
+		state, err := m.getState(ctx, name)
+		if err != nil {
+			m.logger.Error("failed to read target state", "name", name, "error", err)
+			continue
+		}
+
+		if now.Sub(state.LastRun) < entry.Interval {
+			continue
+		}
+		full := now.Sub(state.LastFullSync) >= entry.ResyncInterval
+		if err := m.replicateLocked(ctx, entry, state, full); err != nil {
+			m.logger.Error("failed to replicate to target", "name", name, "error", err)
+		}
+	}
+}
+
+func (m *kvReplicationManager) listTargets(ctx context.Context) ([]string, error) {
+	names, err := m.view.List(ctx, kvReplicationTargetPrefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *kvReplicationManager) getTarget(ctx context.Context, name string) (*kvReplicationTargetEntry, error) {
+	raw, err := m.view.Get(ctx, kvReplicationTargetPrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry kvReplicationTargetEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (m *kvReplicationManager) getState(ctx context.Context, name string) (*kvReplicationState, error) {
+	state := &kvReplicationState{}
+	raw, err := m.view.Get(ctx, kvReplicationStatePrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if raw != nil {
+		if err := raw.DecodeJSON(state); err != nil {
+			return nil, err
+		}
+	}
+	if state.Versions == nil {
+		state.Versions = make(map[string]int64)
+	}
+	return state, nil
+}
+
+func (m *kvReplicationManager) putState(ctx context.Context, name string, state *kvReplicationState) error {
+	raw, err := logical.StorageEntryJSON(kvReplicationStatePrefix+name, state)
+	if err != nil {
+		return err
+	}
+	return m.view.Put(ctx, raw)
+}
+
+// target returns the cached client of a target, creating it if needed.
+func (m *kvReplicationManager) target(entry *kvReplicationTargetEntry) (kvReplicationTarget, error) {
+	if target, ok := m.targets[entry.Name]; ok {
+		return target, nil
+	}
+
+	target, err := m.newTarget(entry)
+	if err != nil {
+		return nil, err
+	}
+	m.targets[entry.Name] = target
+	return target, nil
+}
+
+// writeTarget creates or updates a target. The update function is applied to
+// the stored entry, or to a new entry if the target does not exist yet.
+func (m *kvReplicationManager) writeTarget(ctx context.Context, name string, update func(entry *kvReplicationTargetEntry) error) (*kvReplicationTargetEntry, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	entry, err := m.getTarget(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		entry = &kvReplicationTargetEntry{
+			Name:           name,
+			AuthMount:      kvReplicationDefaultAuthMount,
+			Interval:       kvReplicationDefaultInterval,
+			ResyncInterval: kvReplicationDefaultResyncInterval,
+		}
+	}
+	if err := update(entry); err != nil {
+		return nil, err
+	}
+
+	if entry.Address == "" {
+		return nil, fmt.Errorf("%w: address is required", secretsync.ErrInvalidConfig)
+	}
+	if len(entry.Paths) == 0 {
+		return nil, fmt.Errorf("%w: at least one path is required", secretsync.ErrInvalidConfig)
+	}
+
+	// Building the client validates the connection settings.
+	target, err := m.newTarget(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := logical.StorageEntryJSON(kvReplicationTargetPrefix+name, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.view.Put(ctx, raw); err != nil {
+		return nil, err
+	}
+	m.targets[name] = target
+	return entry, nil
+}
+
+// deleteTarget stops replicating to a target. Secrets already replicated are
+// left in place on the target cluster.
+func (m *kvReplicationManager) deleteTarget(ctx context.Context, name string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if err := m.view.Delete(ctx, kvReplicationTargetPrefix+name); err != nil {
+		return err
+	}
+	if err := m.view.Delete(ctx, kvReplicationStatePrefix+name); err != nil {
+		return err
+	}
+	delete(m.targets, name)
+	return nil
+}
+
+// replicate runs a replication pass against a target immediately. A full
+// pass compares every secret with the target instead of relying on the
+// recorded versions, undoing any change made on the target.
+func (m *kvReplicationManager) replicate(ctx context.Context, name string, full bool) (*kvReplicationState, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	entry, err := m.getTarget(ctx, name)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	state, err := m.getState(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, m.replicateLocked(ctx, entry, state, full)
+}
+
+func (m *kvReplicationManager) replicateLocked(ctx context.Context, entry *kvReplicationTargetEntry, state *kvReplicationState, full bool) error {
+	defer metrics.MeasureSince([]string{"kv-replication", "pass"}, time.Now())
+
+	state.LastRun = time.Now().UTC()
+	state.Written, state.Deleted = 0, 0
+
+	err := m.replicatePass(ctx, entry, state, full)
+	if err != nil {
+		metrics.IncrCounter([]string{"kv-replication", "error"}, 1)
+		state.LastError = err.Error()
+	} else {
+		state.LastError = ""
+		state.LastSuccess = state.LastRun
+		if full {
+			state.LastFullSync = state.LastRun
+		}
+	}
+
+	// The state is saved even on failure so that secrets replicated before
+	// the error are not written again.
+	if putErr := m.putState(ctx, entry.Name, state); putErr != nil {
+		return putErr
+	}
+	return err
+}
+
+func (m *kvReplicationManager) replicatePass(ctx context.Context, entry *kvReplicationTargetEntry, state *kvReplicationState, full bool) error {
+	target, err := m.target(entry)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range entry.Paths {
+		var secrets []string
+		if p.Prefix == "" || strings.HasSuffix(p.Prefix, "/") {
+			secrets, err = m.source.listSecrets(ctx, p.Mount, p.Prefix)
+			if err != nil {
+				return fmt.Errorf("failed to list %q: %w", p.String(), err)
+			}
+		} else {
+			secrets = []string{p.Prefix}
+		}
+
+		for _, secretPath := range secrets {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			key := p.Mount + secretPath
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if err := m.replicateSecret(ctx, target, state, p.Mount, secretPath, full); err != nil {
+				return fmt.Errorf("failed to replicate %q: %w", key, err)
+			}
+		}
+	}
+
+	// Secrets that are gone from the source, or no longer in scope, are
+	// removed from the target.
+	for key := range state.Versions {
+		if seen[key] {
+			continue
+		}
+		mount, secretPath, err := m.source.splitPath(ctx, key)
+		if err != nil {
+			// The mount itself is gone; there is nothing left to compare.
+			m.logger.Warn("dropping replicated secret of missing mount", "target", entry.Name, "path", key, "error", err)
+			delete(state.Versions, key)
+			continue
+		}
+		if err := target.destroySecret(ctx, mount, secretPath); err != nil {
+			return fmt.Errorf("failed to remove %q: %w", key, err)
+		}
+		delete(state.Versions, key)
+		state.Deleted++
+	}
+
+	return nil
+}
+
+// replicateSecret brings a single secret up to date on the target.
+func (m *kvReplicationManager) replicateSecret(ctx context.Context, target kvReplicationTarget, state *kvReplicationState, mount, secretPath string, full bool) error {
+	key := mount + secretPath
+	data, version, err := m.source.readSecret(ctx, mount, secretPath)
+	if err != nil {
+		return err
+	}
+
+	// The latest version was deleted or destroyed on the source; a version
+	// of 0 records that it was deleted on the target too.
+	if data == nil {
+		if replicated, ok := state.Versions[key]; ok && replicated == 0 && !full {
+			return nil
+		}
+		if err := target.deleteSecret(ctx, mount, secretPath); err != nil {
+			return err
+		}
+		state.Versions[key] = 0
+		state.Deleted++
+		return nil
+	}
+
+	if replicated, ok := state.Versions[key]; ok && replicated == version {
+		if !full {
+			return nil
+		}
+
+		remote, err := target.readSecret(ctx, mount, secretPath)
+		if err != nil {
+			return err
+		}
+		if kvReplicationDataEqual(remote, data) {
+			return nil
+		}
+		m.logger.Warn("replicated secret was modified on the target, overwriting", "path", key)
+	}
+
+	if err := target.writeSecret(ctx, mount, secretPath, data); err != nil {
+		return err
+	}
+	state.Versions[key] = version
+	state.Written++
+	return nil
+}
+
+// kvReplicationDataEqual compares secret data as it would be serialized,
+// so that values decoded from JSON compare equal to the originals.
+func kvReplicationDataEqual(a, b map[string]interface{}) bool {
+	normalize := func(data map[string]interface{}) interface{} {
+		buf, err := json.Marshal(data)
+		if err != nil {
+			return nil
+		}
+		var out interface{}
+		if err := json.Unmarshal(buf, &out); err != nil {
+			return nil
+		}
+		return out
+	}
+
+	na, nb := normalize(a), normalize(b)
+	return na != nil && reflect.DeepEqual(na, nb)
+}
+
+// remoteKVTarget replicates to another Vault cluster over its API, using a
+// client certificate both for mutual TLS and to log in.
+type remoteKVTarget struct {
+	client    *api.Client
+	authMount string
+	authRole  string
+
+	tokenExpiry time.Time
+}
+
+func (m *kvReplicationManager) newRemoteTarget(entry *kvReplicationTargetEntry) (kvReplicationTarget, error) {
+	if entry.ClientCert == "" || entry.ClientKey == "" {
+		return nil, fmt.Errorf("%w: client_cert and client_key are required", secretsync.ErrInvalidConfig)
+	}
+
+	tlsConfig, err := tlsutil.ClientTLSConfig([]byte(entry.CACert), []byte(entry.ClientCert), []byte(entry.ClientKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create TLS config: %v", secretsync.ErrInvalidConfig, err)
+	}
+	tlsConfig.ServerName = entry.TLSServerName
+
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.TLSClientConfig = tlsConfig
+	if err := http2.ConfigureTransport(transport); err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	config := api.DefaultConfig()
+	if config.Error != nil {
+		return nil, fmt.Errorf("failed to create api client: %w", config.Error)
+	}
+	config.Address = entry.Address
+	config.HttpClient = &http.Client{
+		Transport: transport,
+	}
+	config.MaxRetries = 0
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api client: %w", err)
+	}
+	// Replicated paths are relative to the root namespace of the target.
+	client.ClearNamespace()
+	client.ClearToken()
+
+	return &remoteKVTarget{
+		client:    client,
+		authMount: entry.AuthMount,
+		authRole:  entry.AuthRole,
+	}, nil
+}
+
+// login authenticates with the client certificate if the current token is
+// missing or about to expire.
+func (t *remoteKVTarget) login(ctx context.Context) error {
+	if t.client.Token() != "" && time.Now().Before(t.tokenExpiry) {
+		return nil
+	}
+
+	data := map[string]interface{}{}
+	if t.authRole != "" {
+		data["name"] = t.authRole
+	}
+	secret, err := t.client.Logical().WriteWithContext(ctx, "auth/"+strings.Trim(t.authMount, "/")+"/login", data)
+	if err != nil {
+		return fmt.Errorf("failed to log in to target: %w", err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return errors.New("failed to log in to target: no token returned")
+	}
+
+	t.client.SetToken(secret.Auth.ClientToken)
+	ttl := time.Duration(secret.Auth.LeaseDuration) * time.Second
+	if ttl == 0 {
+		// Root-like tokens never expire, but log in again once in a while
+		// to pick up policy changes.
+		ttl = time.Hour
+	}
+	t.tokenExpiry = time.Now().Add(ttl * 3 / 4)
+	return nil
+}
+
+func (t *remoteKVTarget) readSecret(ctx context.Context, mount, secretPath string) (map[string]interface{}, error) {
+	if err := t.login(ctx); err != nil {
+		return nil, err
+	}
+
+	secret, err := t.client.Logical().ReadWithContext(ctx, mount+"data/"+secretPath)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+	data, _ := secret.Data["data"].(map[string]interface{})
+	return data, nil
+}
+
+func (t *remoteKVTarget) writeSecret(ctx context.Context, mount, secretPath string, data map[string]interface{}) error {
+	if err := t.login(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.client.Logical().WriteWithContext(ctx, mount+"data/"+secretPath, map[string]interface{}{
+		"data": data,
+	})
+	return err
+}
+
+func (t *remoteKVTarget) deleteSecret(ctx context.Context, mount, secretPath string) error {
+	if err := t.login(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.client.Logical().DeleteWithContext(ctx, mount+"data/"+secretPath)
+	return err
+}
+
+func (t *remoteKVTarget) destroySecret(ctx context.Context, mount, secretPath string) error {
+	if err := t.login(ctx); err != nil {
+		return err
+	}
+
+	_, err := t.client.Logical().DeleteWithContext(ctx, mount+"metadata/"+secretPath)
+	return err
+}
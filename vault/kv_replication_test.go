@@ -0,0 +1,177 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/secretsync"
+)
+
+// testReplicationSource serves secrets of a single "secret/" mount from
+// memory.
+type testReplicationSource struct {
+	secrets  map[string]map[string]interface{}
+	versions map[string]int64
+}
+
+func (s *testReplicationSource) splitPath(_ context.Context, p string) (string, string, error) {
+	if !strings.HasPrefix(p, "secret/") {
+		return "", "", fmt.Errorf("%w: no secrets engine is mounted at %q", secretsync.ErrInvalidConfig, p)
+	}
+	return "secret/", strings.TrimPrefix(p, "secret/"), nil
+}
+
+func (s *testReplicationSource) listSecrets(_ context.Context, _, prefix string) ([]string, error) {
+	var secrets []string
+	for p := range s.versions {
+		if strings.HasPrefix(p, prefix) {
+			secrets = append(secrets, p)
+		}
+	}
+	sort.Strings(secrets)
+	return secrets, nil
+}
+
+func (s *testReplicationSource) readSecret(_ context.Context, _, secretPath string) (map[string]interface{}, int64, error) {
+	return s.secrets[secretPath], s.versions[secretPath], nil
+}
+
+func (s *testReplicationSource) write(secretPath string, data map[string]interface{}) {
+	s.secrets[secretPath] = data
+	s.versions[secretPath]++
+}
+
+// testReplicationTarget is an in-memory target cluster.
+type testReplicationTarget struct {
+	secrets map[string]map[string]interface{}
+	writes  int
+}
+
+func (t *testReplicationTarget) readSecret(_ context.Context, mount, secretPath string) (map[string]interface{}, error) {
+	return t.secrets[mount+secretPath], nil
+}
+
+func (t *testReplicationTarget) writeSecret(_ context.Context, mount, secretPath string, data map[string]interface{}) error {
+	t.secrets[mount+secretPath] = data
+	t.writes++
+	return nil
+}
+
+func (t *testReplicationTarget) deleteSecret(_ context.Context, mount, secretPath string) error {
+	delete(t.secrets, mount+secretPath)
+	return nil
+}
+
+func (t *testReplicationTarget) destroySecret(_ context.Context, mount, secretPath string) error {
+	delete(t.secrets, mount+secretPath)
+	return nil
+}
+
+func TestKVReplication(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	ctx := namespace.RootContext(nil)
+
+	source := &testReplicationSource{
+		secrets:  make(map[string]map[string]interface{}),
+		versions: make(map[string]int64),
+	}
+	target := &testReplicationTarget{secrets: make(map[string]map[string]interface{})}
+	c.kvReplication.source = source
+	c.kvReplication.newTarget = func(*kvReplicationTargetEntry) (kvReplicationTarget, error) {
+		return target, nil
+	}
+
+	source.write("app/db", map[string]interface{}{"password": "one"})
+	source.write("app/api", map[string]interface{}{"key": "abc"})
+	source.write("other/x", map[string]interface{}{"foo": "bar"})
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "kv-replication/targets/dr")
+	req.Data["address"] = "https://dr.example.com:8200"
+	req.Data["client_key"] = "secret key"
+	req.Data["paths"] = "secret/app/"
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if _, ok := resp.Data["client_key"]; ok {
+		t.Fatal("client_key was returned")
+	}
+
+	sync := func(full bool) map[string]interface{} {
+		t.Helper()
+		req := logical.TestRequest(t, logical.UpdateOperation, "kv-replication/targets/dr/sync")
+		req.Data["full"] = full
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp.IsError() || len(resp.Warnings) > 0 {
+			t.Fatalf("err: %v resp: %#v", err, resp)
+		}
+		return resp.Data
+	}
+
+	status := sync(false)
+	if status["last_written"] != 2 {
+		t.Fatalf("expected 2 secrets written, got %#v", status)
+	}
+	if target.secrets["secret/app/db"]["password"] != "one" {
+		t.Fatalf("secret not replicated: %#v", target.secrets)
+	}
+	if _, ok := target.secrets["secret/other/x"]; ok {
+		t.Fatal("secret outside of the replicated paths was replicated")
+	}
+
+	// Unchanged secrets are not written again.
+	if status := sync(false); status["last_written"] != 0 {
+		t.Fatalf("expected no writes, got %#v", status)
+	}
+
+	// Changes on the target survive incremental passes but are overwritten
+	// by a full resync.
+	source.write("app/db", map[string]interface{}{"password": "two"})
+	target.secrets["secret/app/api"] = map[string]interface{}{"key": "tampered"}
+	sync(false)
+	if target.secrets["secret/app/db"]["password"] != "two" {
+		t.Fatalf("new version not replicated: %#v", target.secrets["secret/app/db"])
+	}
+	if target.secrets["secret/app/api"]["key"] != "tampered" {
+		t.Fatal("incremental pass compared secrets with the target")
+	}
+	if status := sync(true); status["last_written"] != 1 {
+		t.Fatalf("expected 1 write, got %#v", status)
+	}
+	if target.secrets["secret/app/api"]["key"] != "abc" {
+		t.Fatalf("source did not win: %#v", target.secrets["secret/app/api"])
+	}
+
+	// Deletions are replicated.
+	delete(source.secrets, "app/api")
+	delete(source.versions, "app/api")
+	status = sync(false)
+	if status["last_deleted"] != 1 || status["replicated_secrets"] != 1 {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+	if _, ok := target.secrets["secret/app/api"]; ok {
+		t.Fatal("deleted secret still on target")
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "kv-replication/targets")
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "dr" {
+		t.Fatalf("unexpected targets: %v", keys)
+	}
+
+	// Paths must belong to a KV version 2 mount.
+	req = logical.TestRequest(t, logical.UpdateOperation, "kv-replication/targets/dr")
+	req.Data["paths"] = "cubbyhole/"
+	resp, err = b.HandleRequest(ctx, req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got err: %v resp: %#v", err, resp)
+	}
+}
@@ -0,0 +1,377 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// coreKVSchemasPath is the prefix the JSON schemas of KV version 2 mounts are
+// stored under, with a single entry per mount keyed by its UUID.
+const coreKVSchemasPath = "core/kv-schemas/"
+
+// kvSchemaSet holds the JSON schemas of a KV version 2 mount, keyed by the
+// path they apply to within the mount. An empty path is the default of the
+// mount, a path ending with a slash applies to the secrets under it, and
+// any other path to a single secret. The API refers to the paths ending with
+// a slash by a trailing "*" instead, as paths ending with a slash can't be
+// written to.
+type kvSchemaSet struct {
+	Schemas map[string]string `json:"schemas"`
+}
+
+// match returns the path and schema applying to secretPath: the schema of
+// the secret itself, or else the one of its closest parent path.
+func (s *kvSchemaSet) match(secretPath string) (string, string, bool) {
+	if schema, ok := s.Schemas[secretPath]; ok {
+		return secretPath, schema, true
+	}
+
+	best := ""
+	found := false
+	for p := range s.Schemas {
+		if p != "" && !strings.HasSuffix(p, "/") {
+			continue
+		}
+		if strings.HasPrefix(secretPath, p) && (!found || len(p) > len(best)) {
+			best = p
+			found = true
+		}
+	}
+	if !found {
+		return "", "", false
+	}
+	return best, s.Schemas[best], true
+}
+
+// kvSchemaAPIPath returns the path of the API of the schema set on p within
+// the mount at mountPath, ending with "*" if p applies to the secrets under
+// it.
+func kvSchemaAPIPath(mountPath, p string) string {
+	if p == "" || strings.HasSuffix(p, "/") {
+		return mountPath + p + "*"
+	}
+	return mountPath + p
+}
+
+// kvSchemaValidationError is a violation of a schema by a secret.
+type kvSchemaValidationError struct {
+	Field       string `json:"field"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// kvSchemaError is returned when a KV secret being written does not match
+// the schema of its path.
+type kvSchemaError struct {
+	SchemaPath string
+	Errors     []kvSchemaValidationError
+}
+
+func (e *kvSchemaError) Error() string {
+	descriptions := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		descriptions = append(descriptions, err.Field+": "+err.Description)
+	}
+	return fmt.Sprintf("secret does not match the schema of %q: %s", e.SchemaPath, strings.Join(descriptions, "; "))
+}
+
+// response returns the error response of the write, carrying the violations
+// in its data so that clients can process them.
+func (e *kvSchemaError) response() *logical.Response {
+	resp := logical.ErrorResponse(e.Error())
+	resp.Data["data"] = map[string]interface{}{
+		"schema_path":       e.SchemaPath,
+		"validation_errors": e.Errors,
+	}
+	return resp
+}
+
+// compileKVSchema parses a JSON schema.
+func compileKVSchema(schema string) (*gojsonschema.Schema, error) {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return compiled, nil
+}
+
+// kvSchemaTarget is the KV version 2 mount and path within it a schema
+// applies to.
+type kvSchemaTarget struct {
+	mount *MountEntry
+	path  string
+}
+
+// resolveKVSchemaTarget returns the KV version 2 mount and path within it of
+// p, relative to the namespace of ctx. A trailing "*" stands for the secrets
+// under the path before it.
+func (c *Core) resolveKVSchemaTarget(ctx context.Context, p string) (*kvSchemaTarget, error) {
+	p = strings.TrimPrefix(p, "/")
+	if strings.HasSuffix(p, "/*") {
+		p = strings.TrimSuffix(p, "*")
+	}
+	entry := c.router.MatchingMountEntry(ctx, p)
+	if entry == nil && !strings.HasSuffix(p, "/") {
+		// Allow the mount to be given without its trailing slash
+		p += "/"
+		entry = c.router.MatchingMountEntry(ctx, p)
+		if entry != nil && entry.Path != p {
+			entry = nil
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("no secrets engine is mounted at %q", p)
+	}
+	if !isKVv2Mount(entry) {
+		return nil, fmt.Errorf("%q is not a KV version 2 secrets engine", entry.Path)
+	}
+
+	return &kvSchemaTarget{
+		mount: entry,
+		path:  strings.TrimPrefix(p, entry.Path),
+	}, nil
+}
+
+func (c *Core) getKVSchemaSet(ctx context.Context, mount *MountEntry) (*kvSchemaSet, error) {
+	raw, err := c.barrier.Get(ctx, coreKVSchemasPath+mount.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KV schemas: %w", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var set kvSchemaSet
+	if err := raw.DecodeJSON(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode KV schemas: %w", err)
+	}
+	return &set, nil
+}
+
+func (c *Core) persistKVSchemaSet(ctx context.Context, mount *MountEntry, set *kvSchemaSet) error {
+	key := coreKVSchemasPath + mount.UUID
+	if len(set.Schemas) == 0 {
+		if err := c.barrier.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to delete KV schemas: %w", err)
+		}
+		return nil
+	}
+
+	entry, err := logical.StorageEntryJSON(key, set)
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to persist KV schemas: %w", err)
+	}
+	return nil
+}
+
+// readKVSchema returns the schema set on p, or an empty string if there is
+// none.
+func (c *Core) readKVSchema(ctx context.Context, p string) (string, error) {
+	target, err := c.resolveKVSchemaTarget(ctx, p)
+	if err != nil {
+		return "", err
+	}
+
+	set, err := c.getKVSchemaSet(ctx, target.mount)
+	if err != nil || set == nil {
+		return "", err
+	}
+	return set.Schemas[target.path], nil
+}
+
+// setKVSchema sets the schema the secrets written at p are validated
+// against.
+func (c *Core) setKVSchema(ctx context.Context, p, schema string) error {
+	target, err := c.resolveKVSchemaTarget(ctx, p)
+	if err != nil {
+		return err
+	}
+	if _, err := compileKVSchema(schema); err != nil {
+		return err
+	}
+
+	c.kvSchemasLock.Lock()
+	defer c.kvSchemasLock.Unlock()
+
+	set, err := c.getKVSchemaSet(ctx, target.mount)
+	if err != nil {
+		return err
+	}
+	if set == nil {
+		set = &kvSchemaSet{}
+	}
+	if set.Schemas == nil {
+		set.Schemas = make(map[string]string)
+	}
+	set.Schemas[target.path] = schema
+	return c.persistKVSchemaSet(ctx, target.mount, set)
+}
+
+// removeKVSchema removes the schema set on p.
+func (c *Core) removeKVSchema(ctx context.Context, p string) error {
+	target, err := c.resolveKVSchemaTarget(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	c.kvSchemasLock.Lock()
+	defer c.kvSchemasLock.Unlock()
+
+	set, err := c.getKVSchemaSet(ctx, target.mount)
+	if err != nil || set == nil {
+		return err
+	}
+	if _, ok := set.Schemas[target.path]; !ok {
+		return nil
+	}
+	delete(set.Schemas, target.path)
+	return c.persistKVSchemaSet(ctx, target.mount, set)
+}
+
+// listKVSchemas returns the paths with a schema of the KV version 2 mounts
+// of the namespace of ctx.
+func (c *Core) listKVSchemas(ctx context.Context) ([]string, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := c.barrier.List(ctx, coreKVSchemasPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list KV schemas: %w", err)
+	}
+
+	var paths []string
+	for _, mountUUID := range keys {
+		entry := c.router.MatchingMountByUUID(mountUUID)
+		if entry == nil || entry.NamespaceID != ns.ID {
+			continue
+		}
+		set, err := c.getKVSchemaSet(ctx, entry)
+		if err != nil {
+			return nil, err
+		}
+		if set == nil {
+			continue
+		}
+		for p := range set.Schemas {
+			paths = append(paths, kvSchemaAPIPath(entry.Path, p))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// checkKVSchema validates the data of the secrets written to KV version 2
+// mounts against the schema of their path, returning a *kvSchemaError if it
+// does not match. Patches are validated by the secret they result in.
+func (c *Core) checkKVSchema(ctx context.Context, entry *MountEntry, req *logical.Request) error {
+	if !isKVv2Mount(entry) {
+		return nil
+	}
+	switch req.Operation {
+	case logical.CreateOperation, logical.UpdateOperation, logical.PatchOperation:
+	default:
+		return nil
+	}
+
+	p := strings.TrimPrefix(req.Path, entry.Path)
+	if !strings.HasPrefix(p, "data/") {
+		return nil
+	}
+	secretPath := strings.TrimPrefix(p, "data/")
+	data, ok := req.Data["data"].(map[string]interface{})
+	if secretPath == "" || !ok {
+		// Left for the secrets engine to reject
+		return nil
+	}
+
+	set, err := c.getKVSchemaSet(ctx, entry)
+	if err != nil || set == nil {
+		return err
+	}
+	schemaPath, schema, ok := set.match(secretPath)
+	if !ok {
+		return nil
+	}
+
+	if req.Operation == logical.PatchOperation {
+		data, err = c.patchedKVSecret(ctx, entry, secretPath, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	compiled, err := compileKVSchema(schema)
+	if err != nil {
+		return err
+	}
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(data))
+	if err != nil {
+		return fmt.Errorf("failed to validate secret: %w", err)
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	schemaErr := &kvSchemaError{
+		SchemaPath: kvSchemaAPIPath(entry.Path, schemaPath),
+	}
+	for _, resultErr := range result.Errors() {
+		schemaErr.Errors = append(schemaErr.Errors, kvSchemaValidationError{
+			Field:       resultErr.Field(),
+			Type:        resultErr.Type(),
+			Description: resultErr.Description(),
+		})
+	}
+	return schemaErr
+}
+
+// patchedKVSecret returns the data of the latest version of a secret once
+// patch is merged into it.
+func (c *Core) patchedKVSecret(ctx context.Context, entry *MountEntry, secretPath string, patch map[string]interface{}) (map[string]interface{}, error) {
+	resp, err := c.router.Route(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      entry.Path + "data/" + secretPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret to patch: %w", err)
+	}
+
+	current := map[string]interface{}{}
+	if resp != nil && resp.Data != nil {
+		if data, ok := resp.Data["data"].(map[string]interface{}); ok {
+			current = data
+		}
+	}
+
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	patchedJSON, err := jsonpatch.MergePatch(currentJSON, patchJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch secret: %w", err)
+	}
+
+	var patched map[string]interface{}
+	if err := json.Unmarshal(patchedJSON, &patched); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
@@ -0,0 +1,129 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const testKVSchemaCredentials = `{
+  "type": "object",
+  "required": ["username", "password"],
+  "properties": {
+    "username": {"type": "string"},
+    "password": {"type": "string", "minLength": 12}
+  }
+}`
+
+func TestCore_KVSchemas(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	testMountKVv2(t, c, "kv2/")
+
+	handle := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := logical.TestRequest(t, op, path)
+		req.ClientToken = root
+		req.Data = data
+		return c.HandleRequest(ctx, req)
+	}
+
+	if _, err := handle(logical.UpdateOperation, "sys/kv-schemas/kv2/db/*", map[string]interface{}{
+		"schema": `{"type": "object"`,
+	}); err == nil {
+		t.Fatal("expected an error setting an invalid schema")
+	}
+	if _, err := handle(logical.UpdateOperation, "sys/kv-schemas/secret", map[string]interface{}{
+		"schema": `{"type": "object"}`,
+	}); err == nil {
+		t.Fatal("expected an error setting the schema of a KV version 1 mount")
+	}
+
+	// The mount requires objects with string values, while secrets under db/
+	// must be credentials, except for db/legacy which has no constraint
+	schemas := map[string]string{
+		"kv2":           `{"type": "object", "additionalProperties": {"type": "string"}}`,
+		"kv2/db/*":      testKVSchemaCredentials,
+		"kv2/db/legacy": `{}`,
+	}
+	for p, schema := range schemas {
+		if _, err := handle(logical.UpdateOperation, "sys/kv-schemas/"+p, map[string]interface{}{
+			"schema": schema,
+		}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	resp, err := handle(logical.ListOperation, "sys/kv-schemas", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 3 || keys[0] != "kv2/*" || keys[1] != "kv2/db/*" || keys[2] != "kv2/db/legacy" {
+		t.Fatalf("bad keys: %v", keys)
+	}
+
+	resp, err = handle(logical.ReadOperation, "sys/kv-schemas/kv2/db/*", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["schema"] != testKVSchemaCredentials {
+		t.Fatalf("bad schema: %v", resp.Data["schema"])
+	}
+
+	write := func(op logical.Operation, secretPath string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return handle(op, "kv2/data/"+secretPath, map[string]interface{}{
+			"data": data,
+		})
+	}
+
+	if _, err := write(logical.UpdateOperation, "app", map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp, err = write(logical.UpdateOperation, "app", map[string]interface{}{"key": 1})
+	if err == nil {
+		t.Fatal("expected an error writing a secret not matching the schema of the mount")
+	}
+	violations := resp.Data["data"].(map[string]interface{})
+	if violations["schema_path"] != "kv2/*" {
+		t.Fatalf("bad schema path: %v", violations["schema_path"])
+	}
+
+	resp, err = write(logical.UpdateOperation, "db/main", map[string]interface{}{"username": "app"})
+	if err == nil {
+		t.Fatal("expected an error writing a secret not matching the schema of its parent")
+	}
+	violations = resp.Data["data"].(map[string]interface{})
+	errs := violations["validation_errors"].([]kvSchemaValidationError)
+	if violations["schema_path"] != "kv2/db/*" || len(errs) != 1 || errs[0].Type != "required" || !strings.Contains(errs[0].Description, "password") {
+		t.Fatalf("bad violations: %#v", violations)
+	}
+
+	if _, err := write(logical.UpdateOperation, "db/main", map[string]interface{}{"username": "app", "password": "correct horse battery"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := write(logical.UpdateOperation, "db/legacy", map[string]interface{}{"dsn": "postgres://"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Patches are validated by the secret they result in
+	resp, err = write(logical.PatchOperation, "db/main", map[string]interface{}{"password": "short"})
+	if err == nil || !strings.Contains(resp.Error().Error(), "password") {
+		t.Fatalf("expected an error patching a secret into one not matching its schema: resp: %#v, err: %v", resp, err)
+	}
+	resp, err = write(logical.PatchOperation, "db/main", map[string]interface{}{"username": nil})
+	if err == nil || !strings.Contains(resp.Error().Error(), "username") {
+		t.Fatalf("expected an error removing a required field: resp: %#v, err: %v", resp, err)
+	}
+
+	if _, err := handle(logical.DeleteOperation, "sys/kv-schemas/kv2/db/*", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := write(logical.UpdateOperation, "db/other", map[string]interface{}{"username": "app"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
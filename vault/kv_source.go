@@ -0,0 +1,113 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/secretsync"
+)
+
+// kvSource reads secrets from KV version 2 mounts through the router, for
+// subsystems pushing secrets out of Vault.
+type kvSource struct {
+	core *Core
+}
+
+// validateMount ensures mount is a KV version 2 secrets engine.
+func (s *kvSource) validateMount(ctx context.Context, mount string) error {
+	me := s.core.router.MatchingMountEntry(namespace.RootContext(ctx), mount)
+	if me == nil || me.Path != mount {
+		return fmt.Errorf("%w: no secrets engine is mounted at %q", secretsync.ErrInvalidConfig, mount)
+	}
+	if me.Type != "kv" || me.Options["version"] != "2" {
+		return fmt.Errorf("%w: %q is not a KV version 2 secrets engine", secretsync.ErrInvalidConfig, mount)
+	}
+	return nil
+}
+
+// readSecret returns the data and version of the latest version of a KV v2
+// secret. The data is nil if the secret does not exist or its latest
+// version was deleted.
+func (s *kvSource) readSecret(ctx context.Context, mount, secretPath string) (map[string]interface{}, int64, error) {
+	if err := s.validateMount(ctx, mount); err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := s.core.router.Route(namespace.RootContext(ctx), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      mount + "data/" + secretPath,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, 0, nil
+	}
+	if resp.IsError() {
+		return nil, 0, resp.Error()
+	}
+
+	data, _ := resp.Data["data"].(map[string]interface{})
+	if data == nil {
+		return nil, 0, nil
+	}
+
+	metadata, _ := resp.Data["metadata"].(map[string]interface{})
+	version, err := parseutil.ParseInt(metadata["version"])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse secret version: %w", err)
+	}
+
+	return data, version, nil
+}
+
+// listSecrets returns the paths of every secret under prefix, relative to
+// the mount, recursing into sub-directories.
+func (s *kvSource) listSecrets(ctx context.Context, mount, prefix string) ([]string, error) {
+	resp, err := s.core.router.Route(namespace.RootContext(ctx), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      mount + "metadata/" + prefix,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, nil
+	}
+	if resp.IsError() {
+		return nil, resp.Error()
+	}
+
+	keys, _ := resp.Data["keys"].([]string)
+	var secrets []string
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			children, err := s.listSecrets(ctx, mount, prefix+key)
+			if err != nil {
+				return nil, err
+			}
+			secrets = append(secrets, children...)
+			continue
+		}
+		secrets = append(secrets, prefix+key)
+	}
+
+	return secrets, nil
+}
+
+// splitPath splits a path into the KV version 2 mount it belongs to and the
+// remainder of the path within the mount.
+func (s *kvSource) splitPath(ctx context.Context, p string) (string, string, error) {
+	mount := s.core.router.MatchingMount(namespace.RootContext(ctx), p)
+	if mount == "" {
+		return "", "", fmt.Errorf("%w: no secrets engine is mounted at %q", secretsync.ErrInvalidConfig, p)
+	}
+	if err := s.validateMount(ctx, mount); err != nil {
+		return "", "", err
+	}
+	return mount, strings.TrimPrefix(p, mount), nil
+}
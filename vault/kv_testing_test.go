@@ -0,0 +1,26 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+)
+
+// testMountKVv2 mounts KV version 2 mounts at the given paths of the test
+// core. The test core serves kv mounts with the passthrough backend, which
+// accepts the paths of KV version 2 and returns the data written to them as
+// is, so the metadata of the versions is the one written by the tests.
+func testMountKVv2(t *testing.T, c *Core, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		me := &MountEntry{
+			Table:   mountTableType,
+			Path:    path,
+			Type:    "kv",
+			Options: map[string]string{"version": "2"},
+		}
+		if err := c.mount(namespace.RootContext(nil), me); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+}
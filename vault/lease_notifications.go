@@ -0,0 +1,427 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// leaseNotificationsSubPath is the sub-path used for the lease
+	// notification rules.
+	leaseNotificationsSubPath = "lease-notifications/"
+
+	// leaseNotificationQueueSize bounds the number of notifications waiting
+	// to be delivered; notifications are dropped once it is reached.
+	leaseNotificationQueueSize = 1024
+
+	leaseNotificationTimeout = 10 * time.Second
+
+	// leaseNotificationSignatureHeader carries the HMAC-SHA256 of the body,
+	// keyed with the secret of the rule, when the rule has one.
+	leaseNotificationSignatureHeader = "X-Vault-Signature"
+
+	LeaseNotificationEventExpiring      = "lease-expiring"
+	LeaseNotificationEventRenewalFailed = "lease-renewal-failed"
+)
+
+// leaseNotificationCheckInterval is how often the remaining TTL of the leases
+// is compared with the thresholds of the rules. It is a variable so tests can
+// shorten it.
+var leaseNotificationCheckInterval = 1 * time.Minute
+
+var errLeaseNotificationsUnavailable = errors.New("lease notifications are not available on this node")
+
+// leaseNotificationEntry is a stored notification rule. Leases are matched
+// by the prefix of their ID, which starts with the path of the mount and of
+// the role they were issued from.
+type leaseNotificationEntry struct {
+	Name       string          `json:"name"`
+	URL        string          `json:"url"`
+	Secret     string          `json:"secret"`
+	PathPrefix string          `json:"path_prefix"`
+	Thresholds []time.Duration `json:"thresholds"`
+
+	NotifyRenewalFailure bool `json:"notify_renewal_failure"`
+}
+
+func (e *leaseNotificationEntry) matches(leaseID string) bool {
+	return strings.HasPrefix(leaseID, e.PathPrefix)
+}
+
+// LeaseNotificationEvent is the body of the requests sent to the URL of a
+// notification rule.
+type LeaseNotificationEvent struct {
+	Type         string    `json:"type"`
+	Notification string    `json:"notification"`
+	LeaseID      string    `json:"lease_id"`
+	ExpireTime   time.Time `json:"expire_time"`
+	TTL          int64     `json:"ttl"`
+	Threshold    int64     `json:"threshold,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+type leaseNotification struct {
+	entry *leaseNotificationEntry
+	event *LeaseNotificationEvent
+}
+
+// leaseNotifier sends webhooks when leases are about to expire or fail to
+// renew.
+type leaseNotifier struct {
+	core   *Core
+	logger log.Logger
+	view   *BarrierView
+	client *http.Client
+
+	// l protects entries, the cached copy of the stored rules.
+	l       sync.RWMutex
+	entries map[string]*leaseNotificationEntry
+
+	queue     chan *leaseNotification
+	lastCheck time.Time
+	cancel    context.CancelFunc
+	doneCh    chan struct{}
+}
+
+func newLeaseNotifier(c *Core, logger log.Logger, view *BarrierView) *leaseNotifier {
+	client := cleanhttp.DefaultClient()
+	client.Timeout = leaseNotificationTimeout
+
+	return &leaseNotifier{
+		core:    c,
+		logger:  logger,
+		view:    view,
+		client:  client,
+		entries: make(map[string]*leaseNotificationEntry),
+		queue:   make(chan *leaseNotification, leaseNotificationQueueSize),
+	}
+}
+
+func (c *Core) setupLeaseNotifications(ctx context.Context) error {
+	logger := c.baseLogger.Named("lease-notifications")
+	c.AddLogger(logger)
+
+	n := newLeaseNotifier(c, logger, c.systemBarrierView.SubView(leaseNotificationsSubPath))
+	if err := n.reload(ctx); err != nil {
+		return err
+	}
+	c.leaseNotifier = n
+
+	if c.perfStandby {
+		return nil
+	}
+
+	n.start(ctx)
+	return nil
+}
+
+func (c *Core) stopLeaseNotifications() {
+	// preSeal may run before setupLeaseNotifications got a chance to
+	// complete.
+	if c.leaseNotifier != nil {
+		c.leaseNotifier.stop()
+	}
+
+	c.leaseNotifier = nil
+}
+
+// notifyLeaseRenewalFailed sends a notification to the rules matching the
+// lease which asked for renewal failures.
+func (c *Core) notifyLeaseRenewalFailed(leaseID string, expireTime time.Time, renewErr string) {
+	if c.leaseNotifier == nil {
+		return
+	}
+	c.leaseNotifier.renewalFailed(leaseID, expireTime, renewErr, time.Now())
+}
+
+func (n *leaseNotifier) start(ctx context.Context) {
+	ctx, n.cancel = context.WithCancel(ctx)
+	n.doneCh = make(chan struct{})
+	n.lastCheck = time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case notification := <-n.queue:
+				n.deliver(ctx, notification)
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(leaseNotificationCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := n.reload(ctx); err != nil {
+					n.logger.Error("failed to load notification rules", "error", err)
+				}
+				n.checkExpiring(now)
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(n.doneCh)
+	}()
+}
+
+func (n *leaseNotifier) stop() {
+	if n.cancel == nil {
+		return
+	}
+	n.cancel()
+	<-n.doneCh
+}
+
+// reload refreshes the cached rules from storage, picking up the changes
+// replicated from another cluster.
+func (n *leaseNotifier) reload(ctx context.Context) error {
+	names, err := n.view.List(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]*leaseNotificationEntry, len(names))
+	for _, name := range names {
+		entry, err := n.get(ctx, name)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			entries[name] = entry
+		}
+	}
+
+	n.l.Lock()
+	n.entries = entries
+	n.l.Unlock()
+	return nil
+}
+
+func (n *leaseNotifier) list() []*leaseNotificationEntry {
+	n.l.RLock()
+	defer n.l.RUnlock()
+
+	entries := make([]*leaseNotificationEntry, 0, len(n.entries))
+	for _, entry := range n.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+func (n *leaseNotifier) get(ctx context.Context, name string) (*leaseNotificationEntry, error) {
+	raw, err := n.view.Get(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lease notification %q: %w", name, err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry leaseNotificationEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode lease notification %q: %w", name, err)
+	}
+	return &entry, nil
+}
+
+func (n *leaseNotifier) put(ctx context.Context, entry *leaseNotificationEntry) error {
+	raw, err := logical.StorageEntryJSON(entry.Name, entry)
+	if err != nil {
+		return err
+	}
+	if err := n.view.Put(ctx, raw); err != nil {
+		return fmt.Errorf("failed to store lease notification %q: %w", entry.Name, err)
+	}
+
+	n.l.Lock()
+	n.entries[entry.Name] = entry
+	n.l.Unlock()
+	return nil
+}
+
+func (n *leaseNotifier) delete(ctx context.Context, name string) error {
+	if err := n.view.Delete(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete lease notification %q: %w", name, err)
+	}
+
+	n.l.Lock()
+	delete(n.entries, name)
+	n.l.Unlock()
+	return nil
+}
+
+// checkExpiring notifies the rules whose thresholds were crossed by the
+// remaining TTL of a lease since the previous check.
+func (n *leaseNotifier) checkExpiring(now time.Time) {
+	elapsed := now.Sub(n.lastCheck)
+	n.lastCheck = now
+
+	var entries []*leaseNotificationEntry
+	for _, entry := range n.list() {
+		if len(entry.Thresholds) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	expiration := n.core.expiration
+	if expiration == nil {
+		return
+	}
+
+	err := expiration.walkLeases(func(leaseID string, expireTime time.Time) bool {
+		if expireTime.IsZero() {
+			return true
+		}
+		remaining := expireTime.Sub(now)
+		if remaining <= 0 {
+			return true
+		}
+
+		for _, entry := range entries {
+			if !entry.matches(leaseID) {
+				continue
+			}
+			for _, threshold := range entry.Thresholds {
+				if remaining > threshold || remaining+elapsed <= threshold {
+					continue
+				}
+				n.enqueue(entry, &LeaseNotificationEvent{
+					Type:         LeaseNotificationEventExpiring,
+					Notification: entry.Name,
+					LeaseID:      leaseID,
+					ExpireTime:   expireTime,
+					TTL:          int64(remaining.Seconds()),
+					Threshold:    int64(threshold.Seconds()),
+					Timestamp:    now,
+				})
+			}
+		}
+		return true
+	})
+	if err != nil && !errors.Is(err, ErrInRestoreMode) {
+		n.logger.Error("failed to walk leases", "error", err)
+	}
+}
+
+func (n *leaseNotifier) renewalFailed(leaseID string, expireTime time.Time, renewErr string, now time.Time) {
+	for _, entry := range n.list() {
+		if !entry.NotifyRenewalFailure || !entry.matches(leaseID) {
+			continue
+		}
+
+		var ttl int64
+		if !expireTime.IsZero() && expireTime.After(now) {
+			ttl = int64(expireTime.Sub(now).Seconds())
+		}
+		n.enqueue(entry, &LeaseNotificationEvent{
+			Type:         LeaseNotificationEventRenewalFailed,
+			Notification: entry.Name,
+			LeaseID:      leaseID,
+			ExpireTime:   expireTime,
+			TTL:          ttl,
+			Error:        renewErr,
+			Timestamp:    now,
+		})
+	}
+}
+
+func (n *leaseNotifier) enqueue(entry *leaseNotificationEntry, event *LeaseNotificationEvent) {
+	// Nothing delivers the notifications on performance standbys.
+	if n.cancel == nil {
+		return
+	}
+
+	select {
+	case n.queue <- &leaseNotification{entry: entry, event: event}:
+	default:
+		metrics.IncrCounter([]string{"expire", "notifications", "dropped"}, 1)
+		n.logger.Warn("dropping lease notification, too many notifications pending", "notification", entry.Name, "type", event.Type, "lease_id", event.LeaseID)
+	}
+}
+
+func (n *leaseNotifier) deliver(ctx context.Context, notification *leaseNotification) {
+	if err := n.send(ctx, notification.entry, notification.event); err != nil {
+		metrics.IncrCounter([]string{"expire", "notifications", "failed"}, 1)
+		n.logger.Error("failed to send lease notification", "notification", notification.entry.Name, "type", notification.event.Type, "lease_id", notification.event.LeaseID, "error", err)
+		return
+	}
+	metrics.IncrCounter([]string{"expire", "notifications", "sent"}, 1)
+}
+
+func (n *leaseNotifier) send(ctx context.Context, entry *leaseNotificationEntry, event *LeaseNotificationEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, entry.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if entry.Secret != "" {
+		req.Header.Set(leaseNotificationSignatureHeader, "sha256="+leaseNotificationSignature(entry.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func leaseNotificationSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// leaseNotificationsWritable returns whether the rules can be changed on this
+// node; performance secondaries use the rules of the primary.
+func (c *Core) leaseNotificationsWritable() bool {
+	return !c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary)
+}
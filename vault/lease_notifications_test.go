@@ -0,0 +1,151 @@
+package vault
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestLeaseNotifications(t *testing.T) {
+	events := make(chan *LeaseNotificationEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if sig := r.Header.Get(leaseNotificationSignatureHeader); sig != "sha256="+leaseNotificationSignature("hunter2", body) {
+			t.Errorf("bad signature: %q", sig)
+		}
+		var event LeaseNotificationEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Error(err)
+			return
+		}
+		events <- &event
+	}))
+	defer srv.Close()
+
+	c, b, _ := testCoreSystemBackend(t)
+	ctx := namespace.RootContext(nil)
+
+	for c.expiration.inRestoreMode() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	req := logical.TestRequest(t, logical.CreateOperation, "leases/notifications/invalid")
+	req.Data["url"] = srv.URL
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error without thresholds nor notify_renewal_failure, got: %#v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.CreateOperation, "leases/notifications/aws")
+	req.Data["url"] = srv.URL
+	req.Data["secret"] = "hunter2"
+	req.Data["path_prefix"] = "prod/aws/"
+	req.Data["thresholds"] = "1h"
+	req.Data["notify_renewal_failure"] = true
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["has_secret"] != true || resp.Data["thresholds"].([]int64)[0] != 3600 {
+		t.Fatalf("unexpected response: %#v", resp.Data)
+	}
+
+	noop := &NoopBackend{}
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := NewBarrierView(c.barrier, "logical/")
+	err = c.router.Mount(noop, "prod/aws/", &MountEntry{Path: "prod/aws/", Type: "noop", UUID: meUUID, Accessor: "noop-accessor", namespace: namespace.RootNamespace}, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaseReq := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "prod/aws/creds",
+		ClientToken: "foobar",
+	}
+	leaseReq.SetTokenEntry(&logical.TokenEntry{ID: "foobar", NamespaceID: "root"})
+	leaseID, err := c.expiration.Register(ctx, leaseReq, &logical.Response{
+		Secret: &logical.Secret{
+			LeaseOptions: logical.LeaseOptions{
+				TTL:       2 * time.Hour,
+				Renewable: true,
+			},
+		},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNoEvent := func() {
+		t.Helper()
+		select {
+		case event := <-events:
+			t.Fatalf("unexpected notification: %#v", event)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	expectEvent := func(typ string) *LeaseNotificationEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			if event.Type != typ || event.LeaseID != leaseID || event.Notification != "aws" {
+				t.Fatalf("unexpected notification: %#v", event)
+			}
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for a %s notification", typ)
+		}
+		return nil
+	}
+
+	n := c.leaseNotifier
+	now := time.Now()
+	n.lastCheck = now
+
+	n.checkExpiring(now.Add(30 * time.Minute))
+	expectNoEvent()
+
+	n.checkExpiring(now.Add(90 * time.Minute))
+	if event := expectEvent(LeaseNotificationEventExpiring); event.Threshold != 3600 {
+		t.Fatalf("unexpected threshold: %d", event.Threshold)
+	}
+
+	// The threshold was already crossed.
+	n.checkExpiring(now.Add(91 * time.Minute))
+	expectNoEvent()
+
+	noop.Response = logical.ErrorResponse("backend unavailable")
+	if _, err := c.expiration.Renew(ctx, leaseID, 0); err == nil {
+		t.Fatal("expected the renewal to fail")
+	}
+	if event := expectEvent(LeaseNotificationEventRenewalFailed); !strings.Contains(event.Error, "backend unavailable") {
+		t.Fatalf("unexpected error: %q", event.Error)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "leases/notifications/aws")
+	if _, err := b.HandleRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.expiration.Renew(ctx, leaseID, 0); err == nil {
+		t.Fatal("expected the renewal to fail")
+	}
+	expectNoEvent()
+}
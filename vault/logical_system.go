@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"io"
 	"net/http"
 	"path"
 	"path/filepath"
@@ -21,6 +22,9 @@ import (
 	"unicode"
 
 	"github.com/hashicorp/vault/helper/versions"
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
 	"golang.org/x/crypto/sha3"
 
 	"github.com/hashicorp/errwrap"
@@ -93,6 +97,8 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"remount",
 				"audit",
 				"audit/*",
+				"audit-hash-rotate/*",
+				"audit-test",
 				"raw",
 				"raw/*",
 				"replication/primary/secondary-token",
@@ -111,9 +117,13 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"leases/revoke-prefix/*",
 				"leases/revoke-force/*",
 				"leases/lookup/*",
+				"leases/tagged/",
 				"storage/raft/snapshot-auto/config/*",
 				"leases",
 				"internal/inspect/*",
+				"namespace-bundle/*",
+				"config/apply",
+				"config/apply/*",
 			},
 
 			Unauthenticated: []string{
@@ -161,6 +171,8 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 
 			SealWrapStorage: []string{
 				managedKeyRegistrySubPath,
+				secretSyncSubPath,
+				kvReplicationSubPath,
 			},
 		},
 	}
@@ -183,6 +195,9 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	b.Backend.Paths = append(b.Backend.Paths, b.capabilitiesPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.internalPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.pprofPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.pprofCollectionPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.namespaceBundlePaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.configApplyPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.remountPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.metricsPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.monitorPath())
@@ -191,6 +206,15 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	b.Backend.Paths = append(b.Backend.Paths, b.quotasPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.rootActivityPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.loginMFAPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.secretSyncPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.managedKeyPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.leaseNotificationPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.kvReplicationPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.kvLockPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.kvSchemaPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.kvDiffPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.talkersPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.wrappingTokensPaths()...)
 
 	if core.rawEnabled {
 		b.Backend.Paths = append(b.Backend.Paths, b.rawPaths()...)
@@ -246,6 +270,40 @@ func (b *SystemBackend) handleConfigStateSanitized(ctx context.Context, req *log
 	return resp, nil
 }
 
+// handleConfigReloadStatus returns the outcome of the last reload of the
+// server configuration.
+func (b *SystemBackend) handleConfigReloadStatus(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	status := b.Core.ConfigReloadStatus()
+	if status == nil {
+		return nil, nil
+	}
+
+	changes := make([]map[string]interface{}, 0, len(status.Changes))
+	for _, change := range status.Changes {
+		c := map[string]interface{}{
+			"setting":    change.Setting,
+			"reloadable": change.Reloadable,
+			"applied":    change.Applied,
+		}
+		if change.Error != "" {
+			c["error"] = change.Error
+		}
+		changes = append(changes, c)
+	}
+	reloadErrors := status.Errors
+	if reloadErrors == nil {
+		reloadErrors = []string{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"reload_time": status.Time.Format(time.RFC3339Nano),
+			"changes":     changes,
+			"errors":      reloadErrors,
+		},
+	}, nil
+}
+
 // handleConfigReload handles reloading specific pieces of the configuration.
 func (b *SystemBackend) handleConfigReload(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	subsystem := data.Get("subsystem").(string)
@@ -779,6 +837,10 @@ func (b *SystemBackend) handleCapabilitiesAccessor(ctx context.Context, req *log
 	return b.handleCapabilities(ctx, req, d)
 }
 
+// maxCapabilitiesPaths is the maximum number of paths whose capabilities can
+// be queried in a single request
+const maxCapabilitiesPaths = 1000
+
 // handleCapabilities returns the ACL capabilities of the token for a given path
 func (b *SystemBackend) handleCapabilities(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	var token string
@@ -807,18 +869,32 @@ func (b *SystemBackend) handleCapabilities(ctx context.Context, req *logical.Req
 	if len(paths) == 0 {
 		return logical.ErrorResponse("paths must be supplied"), nil
 	}
+	if len(paths) > maxCapabilitiesPaths {
+		return logical.ErrorResponse("at most %d paths can be supplied", maxCapabilitiesPaths), nil
+	}
 
-	for _, path := range paths {
-		pathCap, err := b.Core.Capabilities(ctx, token, path)
-		if err != nil {
-			if !strings.HasSuffix(req.Path, "capabilities-self") && errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
-				return nil, &logical.StatusBadRequest{Err: "invalid token"}
-			}
-			return nil, err
+	capabilities, err := b.Core.CapabilitiesForPaths(ctx, token, paths)
+	if err != nil {
+		if !strings.HasSuffix(req.Path, "capabilities-self") && errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			return nil, &logical.StatusBadRequest{Err: "invalid token"}
 		}
+		return nil, err
+	}
+	for path, pathCap := range capabilities {
 		ret.Data[path] = pathCap
 	}
 
+	if d.Get("expand_mounts").(bool) {
+		mounts := make(map[string][]string, len(paths))
+		for _, path := range paths {
+			mounts[path], err = b.Core.mountsMatchingPath(ctx, path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ret.Data["expanded_mounts"] = mounts
+	}
+
 	// This is only here for backwards compatibility
 	if len(paths) == 1 {
 		ret.Data["capabilities"] = ret.Data[paths[0]]
@@ -942,6 +1018,18 @@ func (b *SystemBackend) mountInfo(ctx context.Context, entry *MountEntry) map[st
 	if rawVal, ok := entry.synthesizedConfigCache.Load("allowed_managed_keys"); ok {
 		entryConfig["allowed_managed_keys"] = rawVal.([]string)
 	}
+	if entry.Config.IdempotencyKeyTTL > 0 {
+		entryConfig["idempotency_key_ttl"] = int64(entry.Config.IdempotencyKeyTTL.Seconds())
+	}
+	if entry.Config.MaxRequestSize > 0 {
+		entryConfig["max_request_size"] = entry.Config.MaxRequestSize
+	}
+	if entry.Config.MaxResponseSize > 0 {
+		entryConfig["max_response_size"] = entry.Config.MaxResponseSize
+	}
+	if entry.Config.RequestTimeout > 0 {
+		entryConfig["request_timeout"] = int64(entry.Config.RequestTimeout.Seconds())
+	}
 	if entry.Table == credentialTableType {
 		entryConfig["token_type"] = entry.Config.TokenType.String()
 	}
@@ -1160,6 +1248,24 @@ func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, d
 		config.AllowedManagedKeys = apiConfig.AllowedManagedKeys
 	}
 
+	config.IdempotencyKeyTTL, err = parseIdempotencyKeyTTL(apiConfig.IdempotencyKeyTTL)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if err := validateMountSizeLimit("max_request_size", apiConfig.MaxRequestSize); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err := validateMountSizeLimit("max_response_size", apiConfig.MaxResponseSize); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config.MaxRequestSize = apiConfig.MaxRequestSize
+	config.MaxResponseSize = apiConfig.MaxResponseSize
+	config.RequestTimeout, err = parseMountRequestTimeout(apiConfig.RequestTimeout)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// Create the mount entry
 	me := &MountEntry{
 		Table:                 mountTableType,
@@ -1278,6 +1384,11 @@ func (b *SystemBackend) handleUnmount(ctx context.Context, req *logical.Request,
 		return nil, err
 	}
 
+	retention := time.Duration(data.Get("retention").(int)) * time.Second
+	if retention < 0 {
+		return logical.ErrorResponse("retention cannot be negative"), logical.ErrInvalidRequest
+	}
+
 	repState := b.Core.ReplicationState()
 	entry := b.Core.router.MatchingMountEntry(ctx, path)
 
@@ -1288,6 +1399,12 @@ func (b *SystemBackend) handleUnmount(ctx context.Context, req *logical.Request,
 		return nil, logical.ErrReadOnly
 	}
 
+	// The trash is replicated from the primary, it cannot hold local mounts
+	// of performance secondaries
+	if retention > 0 && repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("retention is not supported for local mounts of performance secondaries"), logical.ErrInvalidRequest
+	}
+
 	// We return success when the mount does not exist to not expose if the
 	// mount existed or not
 	match := b.Core.router.MatchingMount(ctx, path)
@@ -1302,7 +1419,7 @@ func (b *SystemBackend) handleUnmount(ctx context.Context, req *logical.Request,
 	}
 
 	// Attempt unmount
-	if err := b.Core.unmount(ctx, path); err != nil {
+	if err := b.Core.unmountWithRetention(ctx, path, retention); err != nil {
 		b.Backend.Logger().Error("unmount failed", "path", path, "error", err)
 		return handleError(err)
 	}
@@ -1322,6 +1439,95 @@ func (b *SystemBackend) handleUnmount(ctx context.Context, req *logical.Request,
 	return nil, nil
 }
 
+// handleMountTrashList lists the mounts disabled with a retention period
+func (b *SystemBackend) handleMountTrashList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	trash, err := b.Core.listMountTrash(ctx)
+	if err != nil {
+		return handleError(err)
+	}
+
+	keys := make([]string, 0, len(trash))
+	keyInfo := make(map[string]interface{}, len(trash))
+	for _, t := range trash {
+		keys = append(keys, t.Entry.UUID)
+		keyInfo[t.Entry.UUID] = trashedMountResponseData(t)
+	}
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+// handleMountTrashRead returns a mount of the trash
+func (b *SystemBackend) handleMountTrashRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	id := data.Get("id").(string)
+
+	trash, err := b.Core.listMountTrash(ctx)
+	if err != nil {
+		return handleError(err)
+	}
+	for _, t := range trash {
+		if t.Entry.UUID == id {
+			return &logical.Response{
+				Data: trashedMountResponseData(t),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// handleMountTrashPurge removes a mount from the trash along with its data
+func (b *SystemBackend) handleMountTrashPurge(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if b.Core.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil, logical.ErrReadOnly
+	}
+
+	if err := b.Core.purgeMountFromTrash(ctx, data.Get("id").(string)); err != nil {
+		b.Backend.Logger().Error("mount trash purge failed", "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleMountTrashRestore enables a mount of the trash again, with its data
+func (b *SystemBackend) handleMountTrashRestore(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if b.Core.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil, logical.ErrReadOnly
+	}
+
+	id := data.Get("id").(string)
+	path := data.Get("path").(string)
+	if path != "" {
+		path = sanitizePath(path)
+		if err := validateMountPath(path); err != nil {
+			return handleError(err)
+		}
+	}
+
+	entry, err := b.Core.restoreMountFromTrash(ctx, id, path)
+	if err != nil {
+		b.Backend.Logger().Error("mount trash restore failed", "id", id, "error", err)
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"path":     entry.Path,
+			"type":     entry.Type,
+			"accessor": entry.Accessor,
+		},
+	}, nil
+}
+
+func trashedMountResponseData(t *trashedMount) map[string]interface{} {
+	return map[string]interface{}{
+		"path":          t.Entry.Path,
+		"type":          t.Entry.Type,
+		"description":   t.Entry.Description,
+		"accessor":      t.Entry.Accessor,
+		"local":         t.Entry.Local,
+		"deletion_time": t.DeletionTime.Format(time.RFC3339),
+		"purge_time":    t.PurgeTime.Format(time.RFC3339),
+	}
+}
+
 func validateMountPath(p string) error {
 	hasSuffix := strings.HasSuffix(p, "/")
 	s := path.Clean(p)
@@ -1614,6 +1820,22 @@ func (b *SystemBackend) handleTuneReadCommon(ctx context.Context, path string) (
 		resp.Data["allowed_managed_keys"] = rawVal.([]string)
 	}
 
+	if mountEntry.Config.IdempotencyKeyTTL > 0 {
+		resp.Data["idempotency_key_ttl"] = int64(mountEntry.Config.IdempotencyKeyTTL.Seconds())
+	}
+
+	if mountEntry.Config.MaxRequestSize > 0 {
+		resp.Data["max_request_size"] = mountEntry.Config.MaxRequestSize
+	}
+
+	if mountEntry.Config.MaxResponseSize > 0 {
+		resp.Data["max_response_size"] = mountEntry.Config.MaxResponseSize
+	}
+
+	if mountEntry.Config.RequestTimeout > 0 {
+		resp.Data["request_timeout"] = int64(mountEntry.Config.RequestTimeout.Seconds())
+	}
+
 	if mountEntry.Config.UserLockoutConfig != nil {
 		resp.Data["user_lockout_counter_reset_duration"] = int64(mountEntry.Config.UserLockoutConfig.LockoutCounterReset.Seconds())
 		resp.Data["user_lockout_threshold"] = mountEntry.Config.UserLockoutConfig.LockoutThreshold
@@ -2101,6 +2323,86 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	if rawVal, ok := data.GetOk("idempotency_key_ttl"); ok {
+		idempotencyKeyTTL, err := parseIdempotencyKeyTTL(rawVal.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		oldVal := mountEntry.Config.IdempotencyKeyTTL
+		mountEntry.Config.IdempotencyKeyTTL = idempotencyKeyTTL
+
+		// Update the mount table
+		switch {
+		case strings.HasPrefix(path, "auth/"):
+			err = b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local)
+		default:
+			err = b.Core.persistMounts(ctx, b.Core.mounts, &mountEntry.Local)
+		}
+		if err != nil {
+			mountEntry.Config.IdempotencyKeyTTL = oldVal
+			return handleError(err)
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of idempotency_key_ttl successful", "path", path)
+		}
+	}
+
+	maxRequestSizeRaw, maxRequestSizeOk := data.GetOk("max_request_size")
+	maxResponseSizeRaw, maxResponseSizeOk := data.GetOk("max_response_size")
+	requestTimeoutRaw, requestTimeoutOk := data.GetOk("request_timeout")
+	if maxRequestSizeOk || maxResponseSizeOk || requestTimeoutOk {
+		maxRequestSize := mountEntry.Config.MaxRequestSize
+		if maxRequestSizeOk {
+			maxRequestSize = int64(maxRequestSizeRaw.(int))
+			if err := validateMountSizeLimit("max_request_size", maxRequestSize); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+		maxResponseSize := mountEntry.Config.MaxResponseSize
+		if maxResponseSizeOk {
+			maxResponseSize = int64(maxResponseSizeRaw.(int))
+			if err := validateMountSizeLimit("max_response_size", maxResponseSize); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+		requestTimeout := mountEntry.Config.RequestTimeout
+		if requestTimeoutOk {
+			var err error
+			requestTimeout, err = parseMountRequestTimeout(requestTimeoutRaw.(string))
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
+		oldMaxRequestSize := mountEntry.Config.MaxRequestSize
+		oldMaxResponseSize := mountEntry.Config.MaxResponseSize
+		oldRequestTimeout := mountEntry.Config.RequestTimeout
+		mountEntry.Config.MaxRequestSize = maxRequestSize
+		mountEntry.Config.MaxResponseSize = maxResponseSize
+		mountEntry.Config.RequestTimeout = requestTimeout
+
+		// Update the mount table
+		var err error
+		switch {
+		case strings.HasPrefix(path, "auth/"):
+			err = b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local)
+		default:
+			err = b.Core.persistMounts(ctx, b.Core.mounts, &mountEntry.Local)
+		}
+		if err != nil {
+			mountEntry.Config.MaxRequestSize = oldMaxRequestSize
+			mountEntry.Config.MaxResponseSize = oldMaxResponseSize
+			mountEntry.Config.RequestTimeout = oldRequestTimeout
+			return handleError(err)
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of request limits successful", "path", path)
+		}
+	}
+
 	var err error
 	var resp *logical.Response
 	var options map[string]string
@@ -2225,6 +2527,15 @@ func (b *SystemBackend) handleLeaseLookup(ctx context.Context, req *logical.Requ
 		resp.Data["expire_time"] = leaseTimes.ExpireTime
 		resp.Data["ttl"] = leaseTimes.ttl()
 	}
+
+	tags, err := b.Core.expiration.FetchLeaseTags(ctx, leaseID)
+	if err != nil {
+		b.Backend.Logger().Error("error retrieving lease tags", "lease_id", leaseID, "error", err)
+		return handleError(err)
+	}
+	if len(tags) > 0 {
+		resp.Data["tags"] = tags
+	}
 	return resp, nil
 }
 
@@ -2247,6 +2558,58 @@ func (b *SystemBackend) handleLeaseLookupList(ctx context.Context, req *logical.
 	return logical.ListResponse(keys), nil
 }
 
+// handleLeaseTaggedList lists the leases tagged with a key and value, along
+// with their metadata.
+func (b *SystemBackend) handleLeaseTaggedList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("key").(string)
+	if key == "" {
+		return logical.ErrorResponse("key must be specified"), logical.ErrInvalidRequest
+	}
+	value := data.Get("value").(string)
+
+	leaseIDs, err := b.Core.expiration.LeasesByTag(ctx, key, value)
+	if err != nil {
+		b.Backend.Logger().Error("error listing leases by tag", "key", key, "error", err)
+		return handleErrorNoReadOnlyForward(err)
+	}
+
+	keys := make([]string, 0, len(leaseIDs))
+	keyInfo := make(map[string]interface{}, len(leaseIDs))
+	for _, leaseID := range leaseIDs {
+		leaseTimes, err := b.Core.expiration.FetchLeaseTimes(ctx, leaseID)
+		if err != nil {
+			return handleError(err)
+		}
+		if leaseTimes == nil {
+			// The lease was revoked since it was listed
+			continue
+		}
+		tags, err := b.Core.expiration.FetchLeaseTags(ctx, leaseID)
+		if err != nil {
+			return handleError(err)
+		}
+
+		info := map[string]interface{}{
+			"issue_time":  leaseTimes.IssueTime,
+			"expire_time": nil,
+			"ttl":         int64(0),
+			"tags":        tags,
+		}
+		renewable, _ := leaseTimes.renewable()
+		info["renewable"] = renewable
+		if !leaseTimes.ExpireTime.IsZero() {
+			info["expire_time"] = leaseTimes.ExpireTime
+			info["ttl"] = leaseTimes.ttl()
+		}
+
+		keys = append(keys, leaseID)
+		keyInfo[leaseID] = info
+	}
+	sort.Strings(keys)
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
 // handleRenew is used to renew a lease with a given LeaseID
 func (b *SystemBackend) handleRenew(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	// Get all the options
@@ -2589,6 +2952,24 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 		config.AllowedManagedKeys = apiConfig.AllowedManagedKeys
 	}
 
+	config.IdempotencyKeyTTL, err = parseIdempotencyKeyTTL(apiConfig.IdempotencyKeyTTL)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if err := validateMountSizeLimit("max_request_size", apiConfig.MaxRequestSize); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err := validateMountSizeLimit("max_response_size", apiConfig.MaxResponseSize); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	config.MaxRequestSize = apiConfig.MaxRequestSize
+	config.MaxResponseSize = apiConfig.MaxResponseSize
+	config.RequestTimeout, err = parseMountRequestTimeout(apiConfig.RequestTimeout)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// Create the mount entry
 	me := &MountEntry{
 		Table:                 credentialTableType,
@@ -2895,6 +3276,10 @@ func getPasswordPolicyKey(policyName string) string {
 const (
 	minPasswordLength = 4
 	maxPasswordLength = 100
+
+	maxPassphraseWords = 20
+
+	maxPasswordGenerateCount = 100
 )
 
 // handlePoliciesPasswordList returns the list of password policies
@@ -2931,9 +3316,17 @@ func (*SystemBackend) handlePoliciesPasswordSet(ctx context.Context, req *logica
 		return nil, logical.CodedError(http.StatusBadRequest, fmt.Sprintf("invalid password policy: %s", err))
 	}
 
-	if policy.Length > maxPasswordLength || policy.Length < minPasswordLength {
-		return nil, logical.CodedError(http.StatusBadRequest,
-			fmt.Sprintf("passwords must be between %d and %d characters", minPasswordLength, maxPasswordLength))
+	switch policy.Mode {
+	case random.ModePassphrase:
+		if policy.Words > maxPassphraseWords {
+			return nil, logical.CodedError(http.StatusBadRequest,
+				fmt.Sprintf("passphrases must have at most %d words", maxPassphraseWords))
+		}
+	default:
+		if policy.Length > maxPasswordLength || policy.Length < minPasswordLength {
+			return nil, logical.CodedError(http.StatusBadRequest,
+				fmt.Sprintf("passwords must be between %d and %d characters", minPasswordLength, maxPasswordLength))
+		}
 	}
 
 	// Generate some passwords to ensure that we're confident that the policy isn't impossible
@@ -3036,41 +3429,94 @@ func (*SystemBackend) handlePoliciesPasswordDelete(ctx context.Context, req *log
 	return nil, nil
 }
 
-// handlePoliciesPasswordGenerate generates a password from the specified password policy
+// handlePoliciesPasswordGenerate generates one or more passwords from the specified password policy
 func (*SystemBackend) handlePoliciesPasswordGenerate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	policyName := data.Get("name").(string)
-	if policyName == "" {
-		return nil, logical.CodedError(http.StatusBadRequest, "missing policy name")
+	count := data.Get("count").(int)
+	if count < 1 || count > maxPasswordGenerateCount {
+		return nil, logical.CodedError(http.StatusBadRequest,
+			fmt.Sprintf("count must be between 1 and %d", maxPasswordGenerateCount))
 	}
 
-	cfg, err := retrievePasswordPolicy(ctx, req.Storage, policyName)
+	policy, err := parseStoredPasswordPolicy(ctx, req.Storage, data.Get("name").(string))
 	if err != nil {
-		return nil, logical.CodedError(http.StatusInternalServerError, "failed to retrieve password policy")
+		return nil, err
 	}
-	if cfg == nil {
-		return nil, logical.CodedError(http.StatusNotFound, "policy does not exist")
+
+	passwords := make([]string, 0, count)
+	for len(passwords) < count {
+		password, err := policy.Generate(ctx, nil)
+		if err != nil {
+			return nil, logical.CodedError(http.StatusInternalServerError,
+				fmt.Sprintf("failed to generate password from policy: %s", err))
+		}
+		passwords = append(passwords, password)
 	}
 
-	policy, err := random.ParsePolicy(cfg.HCLPolicy)
-	if err != nil {
-		return nil, logical.CodedError(http.StatusInternalServerError,
-			"stored password policy configuration failed to parse")
+	if count == 1 {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"password": passwords[0],
+			},
+		}, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"passwords": passwords,
+		},
+	}
+	return resp, nil
+}
+
+// handlePoliciesPasswordTest validates a candidate password against the specified password policy
+func (*SystemBackend) handlePoliciesPasswordTest(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	password := data.Get("password").(string)
+	if password == "" {
+		return nil, logical.CodedError(http.StatusBadRequest, "missing password")
 	}
 
-	password, err := policy.Generate(ctx, nil)
+	policy, err := parseStoredPasswordPolicy(ctx, req.Storage, data.Get("name").(string))
 	if err != nil {
-		return nil, logical.CodedError(http.StatusInternalServerError,
-			fmt.Sprintf("failed to generate password from policy: %s", err))
+		return nil, err
+	}
+
+	failures := policy.Validate(password)
+	if failures == nil {
+		failures = []string{}
 	}
 
 	resp := &logical.Response{
 		Data: map[string]interface{}{
-			"password": password,
+			"valid":    len(failures) == 0,
+			"failures": failures,
 		},
 	}
 	return resp, nil
 }
 
+// parseStoredPasswordPolicy retrieves and parses a password policy, returning coded errors
+// suitable for the password policy handlers
+func parseStoredPasswordPolicy(ctx context.Context, storage logical.Storage, policyName string) (*random.StringGenerator, error) {
+	if policyName == "" {
+		return nil, logical.CodedError(http.StatusBadRequest, "missing policy name")
+	}
+
+	cfg, err := retrievePasswordPolicy(ctx, storage, policyName)
+	if err != nil {
+		return nil, logical.CodedError(http.StatusInternalServerError, "failed to retrieve password policy")
+	}
+	if cfg == nil {
+		return nil, logical.CodedError(http.StatusNotFound, "policy does not exist")
+	}
+
+	policy, err := random.ParsePolicy(cfg.HCLPolicy)
+	if err != nil {
+		return nil, logical.CodedError(http.StatusInternalServerError,
+			"stored password policy configuration failed to parse")
+	}
+	return &policy, nil
+}
+
 // handleAuditTable handles the "audit" endpoint to provide the audit table
 func (b *SystemBackend) handleAuditTable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	b.Core.auditLock.RLock()
@@ -3092,25 +3538,148 @@ func (b *SystemBackend) handleAuditTable(ctx context.Context, req *logical.Reque
 	return resp, nil
 }
 
+// maxAuditHashBatchSize is the maximum number of inputs hashed by a single
+// audit-hash request.
+const maxAuditHashBatchSize = 1000
+
 // handleAuditHash is used to fetch the hash of the given input data with the
-// specified audit backend's salt
+// specified audit backend's salt. While the overlap window of a salt rotation
+// is open, the hash with the previous salt is returned as well.
 func (b *SystemBackend) handleAuditHash(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	path := data.Get("path").(string)
 	input := data.Get("input").(string)
-	if input == "" {
+	inputs := data.Get("inputs").([]string)
+	switch {
+	case input == "" && len(inputs) == 0:
 		return logical.ErrorResponse("the \"input\" parameter is empty"), nil
+	case input != "" && len(inputs) > 0:
+		return logical.ErrorResponse("only one of \"input\" or \"inputs\" can be specified"), nil
+	case len(inputs) > maxAuditHashBatchSize:
+		return logical.ErrorResponse(fmt.Sprintf("at most %d inputs can be hashed at once", maxAuditHashBatchSize)), nil
 	}
 
 	path = sanitizePath(path)
 
-	hash, err := b.Core.auditBroker.GetHash(ctx, path, input)
+	batch := len(inputs) > 0
+	if !batch {
+		inputs = []string{input}
+	}
+
+	hashes := make([]string, 0, len(inputs))
+	previousHashes := make([]string, 0, len(inputs))
+	var previousExpiration time.Time
+	for _, input := range inputs {
+		hash, err := b.Core.auditBroker.GetHash(ctx, path, input)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		hashes = append(hashes, hash)
+
+		previousHash, expiration, err := b.Core.auditBroker.GetPreviousHash(ctx, path, input)
+		if err != nil {
+			return nil, err
+		}
+		if previousHash != "" {
+			previousHashes = append(previousHashes, previousHash)
+			previousExpiration = expiration
+		}
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{},
+	}
+	if batch {
+		resp.Data["hashes"] = hashes
+	} else {
+		resp.Data["hash"] = hashes[0]
+	}
+	if len(previousHashes) == len(hashes) {
+		if batch {
+			resp.Data["previous_hashes"] = previousHashes
+		} else {
+			resp.Data["previous_hash"] = previousHashes[0]
+		}
+		resp.Data["previous_hash_expiration"] = previousExpiration.Format(time.RFC3339)
+	}
+
+	return resp, nil
+}
+
+// handleAuditHashRotate is used to replace the salt of the specified audit
+// backend. Hashes with the previous salt keep being returned by
+// handleAuditHash for the overlap duration.
+func (b *SystemBackend) handleAuditHashRotate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := sanitizePath(data.Get("path").(string))
+	overlap := time.Duration(data.Get("overlap").(int)) * time.Second
+	if overlap < 0 {
+		return logical.ErrorResponse("overlap cannot be negative"), nil
+	}
+
+	local, err := b.Core.auditBroker.IsLocal(path)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), nil
 	}
+	// Rotating the salt of a replicated audit device writes to replicated
+	// storage, so it must happen on the primary cluster
+	if !local && b.Core.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil, logical.ErrReadOnly
+	}
+
+	if err := b.Core.auditBroker.RotateSalt(ctx, path, overlap); err != nil {
+		b.Backend.Logger().Error("audit salt rotation failed", "path", path, "error", err)
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// handleAuditTest writes a test entry to each enabled audit backend, or to
+// the given one, and reports the latency and outcome of each write.
+func (b *SystemBackend) handleAuditTest(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := data.Get("path").(string)
+	if path != "" {
+		path = sanitizePath(path)
+	}
+
+	b.Core.auditLock.RLock()
+	var entries []*MountEntry
+	for _, entry := range b.Core.audit.Entries {
+		if path == "" || entry.Path == path {
+			entries = append(entries, entry)
+		}
+	}
+	b.Core.auditLock.RUnlock()
+
+	if path != "" && len(entries) == 0 {
+		return logical.ErrorResponse("no audit backend enabled at %q", path), nil
+	}
+
+	healthy := true
+	devices := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		probe, err := b.Core.generateAuditTestProbe()
+		if err != nil {
+			return nil, err
+		}
+
+		latency, err := b.Core.auditBroker.TestBackend(ctx, entry.Path, probe, entry.Options)
+		result := map[string]interface{}{
+			"type":       entry.Type,
+			"latency_ms": latency.Milliseconds(),
+			"success":    err == nil,
+		}
+		if err != nil {
+			b.Backend.Logger().Warn("audit backend test failed", "path", entry.Path, "error", err)
+			result["error"] = err.Error()
+			healthy = false
+		}
+		devices[entry.Path] = result
+	}
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"hash": hash,
+			"healthy": healthy,
+			"devices": devices,
 		},
 	}, nil
 }
@@ -3636,23 +4205,26 @@ func (b *SystemBackend) handleMonitor(ctx context.Context, req *logical.Request,
 		}
 	}
 
+	if b.Core.logMultiplexer == nil {
+		return logical.ErrorResponse("log streaming not supported"), nil
+	}
+
 	isJson := b.Core.LogFormat() == "json" || lf == "json"
-	logger := b.Core.Logger().(log.InterceptLogger)
 
-	mon, err := monitor.NewMonitor(512, logger, &log.LoggerOptions{
+	// Each request is a subscriber of the log multiplexer of the core, so
+	// that concurrent monitors share a single sink
+	sub, err := b.Core.logMultiplexer.Subscribe(monitor.SubscriberOptions{
 		Level:      logLevel,
+		Loggers:    data.Get("logger_name").([]string),
 		JSONFormat: isJson,
+		Framed:     data.Get("framed").(bool),
 	})
 	if err != nil {
 		return nil, err
 	}
+	defer sub.Stop()
 
-	logCh := mon.Start()
-	defer mon.Stop()
-
-	if logCh == nil {
-		return nil, fmt.Errorf("error trying to start a monitor that's already been started")
-	}
+	logCh := sub.Logs()
 
 	w.WriteHeader(http.StatusOK)
 
@@ -3941,17 +4513,49 @@ func (b *SystemBackend) handleWrappingRewrap(ctx context.Context, req *logical.R
 	}, nil
 }
 
+// hashKeyHeader carries the base64-encoded key of a keyed hash when the input
+// is streamed as the request body.
+const hashKeyHeader = "X-Vault-Hash-Key"
+
 func (b *SystemBackend) pathHashWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	inputB64 := d.Get("input").(string)
 	format := d.Get("format").(string)
+	keyB64 := d.Get("key").(string)
 	algorithm := d.Get("urlalgorithm").(string)
 	if algorithm == "" {
 		algorithm = d.Get("algorithm").(string)
 	}
 
-	input, err := base64.StdEncoding.DecodeString(inputB64)
-	if err != nil {
-		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+	// Streamed input is the raw request body, and the other parameters are
+	// read from the query string and headers.
+	streaming := req.HTTPRequest != nil && req.HTTPRequest.Body != nil
+	if streaming {
+		query := req.HTTPRequest.URL.Query()
+		if v := query.Get("format"); v != "" {
+			format = v
+		}
+		if v := query.Get("algorithm"); v != "" && d.Get("urlalgorithm").(string) == "" {
+			algorithm = v
+		}
+		keyB64 = req.HTTPRequest.Header.Get(hashKeyHeader)
+	}
+
+	var input []byte
+	if !streaming {
+		var err error
+		input, err = base64.StdEncoding.DecodeString(inputB64)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+		}
+	}
+
+	var key []byte
+	if keyB64 != "" {
+		var err error
+		key, err = base64.StdEncoding.DecodeString(keyB64)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("unable to decode key as base64: %s", err)), logical.ErrInvalidRequest
+		}
 	}
 
 	switch format {
@@ -3962,6 +4566,7 @@ func (b *SystemBackend) pathHashWrite(ctx context.Context, req *logical.Request,
 	}
 
 	var hf hash.Hash
+	var err error
 	switch algorithm {
 	case "sha2-224":
 		hf = sha256.New224()
@@ -3979,10 +4584,35 @@ func (b *SystemBackend) pathHashWrite(ctx context.Context, req *logical.Request,
 		hf = sha3.New384()
 	case "sha3-512":
 		hf = sha3.New512()
+	case "blake2b-256":
+		hf, err = blake2b.New256(key)
+	case "blake2b-512":
+		hf, err = blake2b.New512(key)
+	case "blake2s-256":
+		hf, err = blake2s.New256(key)
+	case "blake3":
+		if key == nil {
+			hf = blake3.New()
+		} else {
+			hf, err = blake3.NewKeyed(key)
+		}
 	default:
 		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 	}
-	hf.Write(input)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid key for %s: %s", algorithm, err)), logical.ErrInvalidRequest
+	}
+	if key != nil && !strings.HasPrefix(algorithm, "blake") {
+		return logical.ErrorResponse("key is only supported by the BLAKE2 and BLAKE3 algorithms"), logical.ErrInvalidRequest
+	}
+
+	if streaming {
+		if _, err := io.Copy(hf, req.HTTPRequest.Body); err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+	} else {
+		hf.Write(input)
+	}
 	retBytes := hf.Sum(nil)
 
 	var retStr string
@@ -4012,6 +4642,11 @@ func hasMountAccess(ctx context.Context, acl *ACL, path string) bool {
 		return false
 	}
 
+	// A scoped ACL only has access to the mounts its scope has access to
+	if acl.scope != nil && !hasMountAccess(ctx, acl.scope, path) {
+		return false
+	}
+
 	// If a policy is giving us direct access to the mount path then we can do
 	// a fast return.
 	capabilities := acl.Capabilities(ctx, ns.TrimmedPath(path))
@@ -4315,6 +4950,12 @@ func (b *SystemBackend) pathInternalUIResultantACL(ctx context.Context, req *log
 		},
 	}
 
+	// The rules of a scoped ACL are bounded by the rules of its scope, which
+	// are the ones returned
+	if acl.scope != nil {
+		acl = acl.scope
+	}
+
 	if acl.root {
 		resp.Data["root"] = true
 		return resp, nil
@@ -4542,6 +5183,7 @@ type SealStatusResponse struct {
 	ClusterName       string   `json:"cluster_name,omitempty"`
 	ClusterID         string   `json:"cluster_id,omitempty"`
 	RecoverySeal      bool     `json:"recovery_seal"`
+	TOTPProtected     bool     `json:"totp_protected,omitempty"`
 	StorageType       string   `json:"storage_type,omitempty"`
 	HCPLinkStatus     string   `json:"hcp_link_status,omitempty"`
 	HCPLinkResourceID string   `json:"hcp_link_resource_ID,omitempty"`
@@ -4617,21 +5259,22 @@ func (core *Core) GetSealStatus(ctx context.Context) (*SealStatusResponse, error
 	progress, nonce := core.SecretProgress()
 
 	s := &SealStatusResponse{
-		Type:         sealConfig.Type,
-		Initialized:  initialized,
-		Sealed:       sealed,
-		T:            sealConfig.SecretThreshold,
-		N:            sealConfig.SecretShares,
-		Progress:     progress,
-		Nonce:        nonce,
-		Version:      version.GetVersion().VersionNumber(),
-		BuildDate:    version.BuildDate,
-		Migration:    core.IsInSealMigrationMode() && !core.IsSealMigrated(),
-		ClusterName:  clusterName,
-		ClusterID:    clusterID,
-		RecoverySeal: core.SealAccess().RecoveryKeySupported(),
-		StorageType:  core.StorageType(),
-		Warnings:     core.getStatusWarnings(),
+		Type:          sealConfig.Type,
+		Initialized:   initialized,
+		Sealed:        sealed,
+		T:             sealConfig.SecretThreshold,
+		N:             sealConfig.SecretShares,
+		Progress:      progress,
+		Nonce:         nonce,
+		Version:       version.GetVersion().VersionNumber(),
+		BuildDate:     version.BuildDate,
+		Migration:     core.IsInSealMigrationMode() && !core.IsSealMigrated(),
+		ClusterName:   clusterName,
+		ClusterID:     clusterID,
+		RecoverySeal:  core.SealAccess().RecoveryKeySupported(),
+		TOTPProtected: sealConfig.TOTPProtected,
+		StorageType:   core.StorageType(),
+		Warnings:      core.getStatusWarnings(),
 	}
 
 	if resourceIDonHCP != "" {
@@ -4978,6 +5621,16 @@ The path responds to the following HTTP methods.
         Sets the license for the server
 	`,
 	},
+	"config/reload-status": {
+		"Returns the settings that changed on the last reload of the server configuration.",
+		`
+Lists the settings of the server configuration that changed when it was last
+reloaded with SIGHUP, whether they are applied on reload or only on the next
+restart, and whether they were applied. Settings of listeners are prefixed
+with their address. Nothing is returned if the configuration was not reloaded
+since the server started.
+		`,
+	},
 	"config/cors": {
 		"Configures or returns the current configuration of CORS settings.",
 		`
@@ -5115,6 +5768,49 @@ west coast.
 		"",
 	},
 
+	"mount_retention": {
+		`When disabling a mount, how long to keep its data in the trash, from
+which the mount can be restored. The data is removed immediately if unset.`,
+		"",
+	},
+
+	"mount_trash": {
+		"List the mounts disabled with a retention period.",
+		`
+Mounts disabled with a retention period keep their data until the period has
+passed. Until then, they are listed here by ID and can be restored, with their
+data, at their original path or at a new one.
+		`,
+	},
+
+	"mount_trash_id": {
+		`The ID of the disabled mount, as listed in the trash.`,
+		"",
+	},
+
+	"mount_trash_entry": {
+		"Read or purge a mount of the trash.",
+		`
+Reading returns the original path, type and accessor of the mount, along with
+when it was disabled and when its data will be removed. Deleting removes its
+data immediately.
+		`,
+	},
+
+	"mount_trash_restore": {
+		"Enable a mount of the trash again, with its data.",
+		`
+The mount is enabled with its original configuration and accessor, at the
+given path or at its original path. Leases revoked when it was disabled are
+not restored.
+		`,
+	},
+
+	"mount_trash_restore_path": {
+		`The path to enable the mount at. Defaults to its original path.`,
+		"",
+	},
+
 	"mount_type": {
 		`The type of the backend. Example: "passthrough"`,
 		"",
@@ -5172,6 +5868,22 @@ in the plugin catalog.`,
 		`The options to pass into the backend. Should be a json object with string keys and values.`,
 	},
 
+	"tune_idempotency_key_ttl": {
+		`How long the responses of write requests made with an X-Vault-Idempotency-Key header are retained, so that retried requests with the same key return the original response. Zero disables idempotency keys on the mount.`,
+	},
+
+	"tune_max_request_size": {
+		`The maximum size in bytes of the requests made to the mount, overriding the max_request_size of the listener. Zero keeps the limit of the listener.`,
+	},
+
+	"tune_max_response_size": {
+		`The maximum size in bytes of the responses to the reads and lists of the mount; larger responses are replaced with an error. Zero leaves responses unlimited.`,
+	},
+
+	"tune_request_timeout": {
+		`The maximum duration of the requests made to the mount, overriding the max_request_duration of the listener. Zero keeps the duration of the listener.`,
+	},
+
 	"tune_user_lockout_config": {
 		`The user lockout configuration to pass into the backend. Should be a json object with string keys and values.`,
 	},
@@ -5391,7 +6103,30 @@ or delete a policy.
 
 	"audit-hash": {
 		"The hash of the given string via the given audit backend",
-		"",
+		`
+Hashes the "input" string, or each of the "inputs" strings, with the salt of
+the given audit backend. While the overlap window of a salt rotation is open,
+the hashes with the previous salt are returned as well.
+		`,
+	},
+
+	"audit-hash-rotate": {
+		"Rotate the salt the given audit backend hashes values with.",
+		`
+Replaces the salt of the given audit backend. For the "overlap" duration, the
+audit-hash endpoint also returns hashes with the previous salt, so that audit
+logs written before the rotation can still be searched.
+		`,
+	},
+
+	"audit-test": {
+		"Write a test entry to the enabled audit backends.",
+		`
+Writes a test entry to each enabled audit backend, or only to the given one,
+and reports for each backend whether the write succeeded and how long it
+took. This checks that audit backends are healthy before a failing backend
+blocks requests.
+		`,
 	},
 
 	"audit-table": {
@@ -5631,6 +6366,13 @@ This path responds to the following HTTP methods.
 		`The path to list leases under. Example: "aws/creds/deploy"`,
 		"",
 	},
+	"leases-tagged": {
+		"List leases by tag.",
+		`
+This path lists the leases tagged with the given key and value by the secrets
+engine that issued them, along with their metadata. Requires sudo capability.
+		`,
+	},
 	"plugin-reload": {
 		"Reload mounts that use a particular backend plugin.",
 		`Reload mounts that use a particular backend plugin. Either the plugin name
@@ -5648,7 +6390,13 @@ This path responds to the following HTTP methods.
 	},
 	"hash": {
 		"Generate a hash sum for input data",
-		"Generates a hash sum of the given algorithm against the given input data.",
+		`Generates a hash sum of the given algorithm against the given input data.
+
+The BLAKE2 and BLAKE3 algorithms optionally take a key, making the sum usable
+as a message authentication code. Large inputs can be streamed as the raw
+request body by sending it with the "application/octet-stream" content type;
+the algorithm and format are then read from the URL, and the key from the
+X-Vault-Hash-Key header.`,
 	},
 	"random": {
 		"Generate random bytes",
@@ -5712,6 +6460,77 @@ This path responds to the following HTTP methods.
 		"Count of active tokens in this Vault cluster.",
 		"Count of active tokens in this Vault cluster.",
 	},
+	"internal-counters-talkers": {
+		"Top tokens, entities and client addresses by request count or latency on this node.",
+		`
+Reports the callers that sent the most requests to this node, or whose
+requests took the most time, over a rolling window of up to 15 minutes.
+Tokens are identified by their accessor. The counters are kept in memory and
+are not shared between nodes.
+		`,
+	},
+	"internal-counters-talkers-config": {
+		"Configure automatic snapshots of the top talkers.",
+		`
+When the number of in-flight requests reaches snapshot_threshold, the top
+talkers are stored so that they can be inspected after the incident. At most
+one snapshot is stored per snapshot_cooldown, and the 20 most recent
+snapshots are kept.
+		`,
+	},
+	"internal-counters-talkers-snapshots": {
+		"Read the snapshots of the top talkers stored on overload.",
+		"",
+	},
+	"pprof-collection-config": {
+		"Configure the periodic collection of profiles.",
+		`
+When enabled, the active node captures the configured profiles every interval,
+keeps the last ones of each type in storage and optionally sends them to a
+pprof-compatible endpoint, so that they are available after an incident.
+		`,
+	},
+	"pprof-collection-profiles": {
+		"List, read and delete the collected profiles.",
+		`
+Profiles are identified by their type and the time they were captured. Their
+content, in the pprof format, is downloaded from the raw endpoint.
+		`,
+	},
+	"namespace-bundle-export": {
+		"Export the configuration of the namespace as a bundle.",
+		`
+The bundle holds the secrets engines, auth methods, ACL policies and identity
+groups of the namespace, but no secrets, entities or backend configuration. It
+can be imported into the same namespace of another cluster.
+		`,
+	},
+	"namespace-bundle-import": {
+		"Apply a bundle to the namespace.",
+		`
+Missing secrets engines, auth methods, ACL policies and identity groups are
+created and existing ones are updated to match the bundle. Objects that are
+not part of the bundle are left untouched, so that importing a bundle again
+changes nothing.
+		`,
+	},
+	"config-apply-plan": {
+		"Compute the changes needed to reconcile the configuration with a spec.",
+		`
+The spec declares ACL policies, secrets engines, auth methods and resources,
+which are the data written to other paths such as roles. The response lists
+the objects to create or update with the fields that differ, along with a hash
+identifying the plan.
+		`,
+	},
+	"config-apply": {
+		"Reconcile the configuration with a spec.",
+		`
+The changes of the plan of the spec are applied in order: policies, secrets
+engines, auth methods, then resources. When plan_hash is given, nothing is
+applied unless the changes still match the reviewed plan.
+		`,
+	},
 	"internal-counters-entities": {
 		"Count of active entities in this Vault cluster.",
 		"Count of active entities in this Vault cluster.",
@@ -5763,4 +6582,169 @@ This path responds to the following HTTP methods.
         Returns a list historical version changes sorted by installation time in ascending order.
 		`,
 	},
+	"secret-sync-destinations": {
+		"List the destinations secrets are synced to.",
+		"List the destinations secrets are synced to, as <type>/<name>.",
+	},
+	"secret-sync-destination": {
+		"Configure a destination secrets are synced to.",
+		`
+Destinations are external secret stores that KV version 2 secrets are
+mirrored into, for consumers that cannot call Vault. Supported types are
+aws-sm (AWS Secrets Manager), gcp-sm (Google Cloud Secret Manager), azure-kv
+(Azure Key Vault) and kubernetes (Kubernetes Secrets).
+
+Deleting a destination deletes the secrets written to it, unless its
+deletion_policy is "retain".
+		`,
+	},
+	"secret-sync-associations": {
+		"Manage the secrets synced to a destination.",
+		`
+Associating a secret with a destination writes it to the destination and
+keeps it up to date as new versions are written. Removing the association,
+or deleting the secret in Vault, deletes it from the destination unless the
+destination's deletion_policy is "retain".
+		`,
+	},
+	"secret-sync-reconcile": {
+		"Sync the secrets of a destination immediately.",
+		`
+Secrets are reconciled periodically in the background. This endpoint
+reconciles every secret associated with the destination immediately,
+including checking whether they were modified outside of Vault.
+		`,
+	},
+	"managed-keys": {
+		"List the managed keys of a type.",
+		`
+Managed keys are keys held by an HSM or a cloud key management service that
+secrets engines such as PKI and transit can sign with. The private key never
+leaves the key management system.
+		`,
+	},
+	"managed-key": {
+		"Configure a managed key.",
+		`
+A managed key references a key in an external key management system along
+with the credentials to use it. Usages restrict the operations secrets engines
+may perform with the key, and mounts may only use keys listed in their
+allowed_managed_keys unless any_mount is set on the key.
+		`,
+	},
+	"managed-key-test-sign": {
+		"Check that a managed key can sign.",
+		`
+Signs a random digest with the key and verifies the signature against its
+public key. The result is recorded and returned when reading the key.
+		`,
+	},
+	"lease-notifications": {
+		"List the lease notification rules.",
+		"",
+	},
+	"lease-notification": {
+		"Configure webhooks sent about leases close to expiry or failing to renew.",
+		`
+A notification rule applies to the leases whose ID starts with its
+path_prefix, typically the path of a mount or of a role. A lease-expiring
+notification is posted to the URL of the rule when the remaining TTL of a
+matching lease crosses one of its thresholds, and a lease-renewal-failed
+notification when a matching lease fails to renew, if notify_renewal_failure
+is set. Notifications are sent from the active node on a best-effort basis
+and are not retried.
+		`,
+	},
+	"kv-replication-targets": {
+		"List the KV replication targets.",
+		"",
+	},
+	"kv-replication-target": {
+		"Configure a cluster that KV secrets are replicated to.",
+		`
+Secrets under the configured KV version 2 paths are replicated one way to
+the same paths on the target cluster, over mutual TLS. The client
+certificate is also used to log in to the target through its cert auth
+method. Replication is eventually consistent: the target is brought up to
+date on every interval, and changes made on the target are overwritten by
+the periodic full resync.
+		`,
+	},
+	"kv-replication-status": {
+		"Read the replication status of a target.",
+		"",
+	},
+	"kv-replication-sync": {
+		"Replicate to a target immediately.",
+		`
+Runs a replication pass against the target without waiting for its
+interval. With full set, every secret is compared with its copy on the
+target and changes made on the target are overwritten.
+		`,
+	},
+	"kv-locks": {
+		"List the KV secrets locked against deletion.",
+		"",
+	},
+	"kv-lock": {
+		"Lock a KV secret against deletion.",
+		`
+Deleting versions of a locked KV version 2 secret, destroying them or
+deleting its metadata fails unless the secret was unlocked through
+sys/kv-unlock within the unlock window of the lock. Writing the lock again
+locks the secret immediately. A lock can only be removed while the secret
+is unlocked.
+		`,
+	},
+	"kv-unlock": {
+		"Unlock a locked KV secret for deletion.",
+		`
+Allows deleting the secret for the unlock window of its lock. Unlocking is
+a separate path from managing locks so that it can be granted to other
+policies.
+		`,
+	},
+	"kv-schemas": {
+		"List the paths with a KV schema.",
+		"",
+	},
+	"kv-schema": {
+		"Manage the JSON schema of a KV path.",
+		`
+The data of the secrets written to KV version 2 mounts is validated against
+the JSON schema of their path: the schema of the secret itself if it has
+one, or else the schema of its closest parent path ending with "/*", or of
+the mount. Writes not matching the schema fail, with the violations
+returned in the data of the error response. Patches are validated by the
+secret they result in.
+		`,
+	},
+	"kv-diff": {
+		"Compare two versions of KV secrets.",
+		`
+Returns the keys of the data of a KV version 2 secret version that were
+added, removed or modified in another version, of the same secret or of a
+secret in another mount, along with their values unless mask_values is set.
+Unchanged keys are omitted. Versions that do not exist, were deleted or were
+destroyed compare as empty data. The caller must be allowed to read both
+versions.
+		`,
+	},
+	"wrapping-tokens": {
+		"List the outstanding response-wrapping tokens created by the caller.",
+		`
+Returns the accessors of the response-wrapping tokens created by the entity
+of the calling token, or by the token itself if it has no entity, which
+have not been unwrapped, revoked or expired yet, along with their creation
+path and remaining TTL.
+		`,
+	},
+	"wrapping-tokens-revoke": {
+		"Revoke an outstanding response-wrapping token created by the caller.",
+		`
+Revokes the response-wrapping token with the given accessor, so that it can
+no longer be unwrapped. Only tokens listed in sys/wrapping/tokens can be
+revoked through this path.
+		`,
+	},
 }
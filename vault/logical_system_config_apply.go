@@ -0,0 +1,154 @@
+package vault
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+// configApplyPaths returns the paths used to plan and apply a declarative
+// configuration spec.
+func (b *SystemBackend) configApplyPaths() []*framework.Path {
+	specField := &framework.FieldSchema{
+		Type:        framework.TypeMap,
+		Description: "Declarative spec of the policies, mounts, auth methods and resources to reconcile.",
+		Required:    true,
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "config/apply/plan$",
+			Fields: map[string]*framework.FieldSchema{
+				"spec": specField,
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleConfigApplyPlan,
+					Summary:  "Compute the changes needed to reconcile the configuration with a spec.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["config-apply-plan"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["config-apply-plan"][1]),
+		},
+		{
+			Pattern: "config/apply$",
+			Fields: map[string]*framework.FieldSchema{
+				"spec": specField,
+				"plan_hash": {
+					Type: framework.TypeString,
+					Description: `Hash of a reviewed plan. When set, nothing is applied unless the
+changes still match this plan.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleConfigApply,
+					Summary:  "Reconcile the configuration with a spec.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["config-apply"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["config-apply"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) configApplyPlan(ctx context.Context, req *logical.Request, d *framework.FieldData) (*configPlan, string, *logical.Response, error) {
+	specMap := d.Get("spec").(map[string]interface{})
+	if len(specMap) == 0 {
+		return nil, "", logical.ErrorResponse("spec must be supplied"), logical.ErrInvalidRequest
+	}
+
+	var spec configSpec
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		Result:           &spec,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if err := decoder.Decode(specMap); err != nil {
+		return nil, "", logical.ErrorResponse("invalid spec: %s", err), logical.ErrInvalidRequest
+	}
+
+	plan, err := b.Core.planConfig(ctx, req, &spec)
+	if err != nil {
+		return nil, "", logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	planHash, err := plan.hash()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return plan, planHash, nil, nil
+}
+
+func configChangesResponseData(changes []*configChange) []map[string]interface{} {
+	data := make([]map[string]interface{}, 0, len(changes))
+	for _, change := range changes {
+		changeData := map[string]interface{}{
+			"kind":   change.Kind,
+			"path":   change.Path,
+			"action": change.Action,
+		}
+		if len(change.Diff) > 0 {
+			diff := make(map[string]interface{}, len(change.Diff))
+			for field, fieldDiff := range change.Diff {
+				diff[field] = map[string]interface{}{
+					"current": fieldDiff.Current,
+					"desired": fieldDiff.Desired,
+				}
+			}
+			changeData["diff"] = diff
+		}
+		data = append(data, changeData)
+	}
+	return data
+}
+
+func (b *SystemBackend) handleConfigApplyPlan(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	plan, planHash, resp, err := b.configApplyPlan(ctx, req, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"changes":   configChangesResponseData(plan.Changes),
+			"plan_hash": planHash,
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleConfigApply(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.configApplyLock.Lock()
+	defer b.Core.configApplyLock.Unlock()
+
+	plan, planHash, resp, err := b.configApplyPlan(ctx, req, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if expected := d.Get("plan_hash").(string); expected != "" && expected != planHash {
+		resp := logical.ErrorResponse("the configuration changed since the plan was computed, plan it again")
+		resp.Data["changes"] = configChangesResponseData(plan.Changes)
+		resp.Data["plan_hash"] = planHash
+		return resp, logical.ErrInvalidRequest
+	}
+
+	applied, err := b.Core.applyConfigPlan(ctx, plan)
+	if err != nil {
+		resp := logical.ErrorResponse(err.Error())
+		resp.Data["applied"] = configChangesResponseData(applied)
+		return resp, logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"applied":   configChangesResponseData(applied),
+			"plan_hash": planHash,
+		},
+	}, nil
+}
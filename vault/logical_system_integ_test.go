@@ -97,6 +97,11 @@ func TestSystemBackend_InternalUIResultantACL(t *testing.T) {
 					"update",
 				},
 			},
+			"sys/mfa/step-up": map[string]interface{}{
+				"capabilities": []interface{}{
+					"update",
+				},
+			},
 			"sys/renew": map[string]interface{}{
 				"capabilities": []interface{}{
 					"update",
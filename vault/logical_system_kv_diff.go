@@ -0,0 +1,123 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// kvDiffPath returns the path used to compare versions of KV secrets.
+func (b *SystemBackend) kvDiffPath() *framework.Path {
+	return &framework.Path{
+		Pattern: "kv-diff$",
+		Fields: map[string]*framework.FieldSchema{
+			"from_path": {
+				Type:        framework.TypeString,
+				Description: `Path of the KV version 2 secret to compare from, including its mount, such as "secret/app/db".`,
+				Required:    true,
+			},
+			"from_version": {
+				Type:        framework.TypeInt,
+				Description: "Version of the secret to compare from. Defaults to the latest version.",
+			},
+			"to_path": {
+				Type:        framework.TypeString,
+				Description: "Path of the KV version 2 secret to compare to, including its mount. Defaults to from_path.",
+			},
+			"to_version": {
+				Type:        framework.TypeInt,
+				Description: "Version of the secret to compare to. Defaults to the latest version.",
+			},
+			"mask_values": {
+				Type:        framework.TypeBool,
+				Description: "If set, only the changed keys are returned, without their values.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.handleKVDiff,
+				Summary:  "Compare two versions of KV secrets.",
+			},
+		},
+		HelpSynopsis:    strings.TrimSpace(sysHelp["kv-diff"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["kv-diff"][1]),
+	}
+}
+
+func (b *SystemBackend) handleKVDiff(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	fromPath := data.Get("from_path").(string)
+	if fromPath == "" {
+		return logical.ErrorResponse("from_path is required"), logical.ErrInvalidRequest
+	}
+	toPath := data.Get("to_path").(string)
+	if toPath == "" {
+		toPath = fromPath
+	}
+
+	from, err := b.Core.resolveKVDiffSide(ctx, fromPath, data.Get("from_version").(int))
+	if err != nil {
+		return handleError(err)
+	}
+	to, err := b.Core.resolveKVDiffSide(ctx, toPath, data.Get("to_version").(int))
+	if err != nil {
+		return handleError(err)
+	}
+
+	diff, err := b.Core.diffKVSecrets(ctx, req.ClientToken, from, to)
+	if err != nil {
+		if errors.Is(err, logical.ErrPermissionDenied) {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: kvDiffResponseData(diff, data.Get("mask_values").(bool)),
+	}, nil
+}
+
+func kvDiffResponseData(diff *kvDiff, maskValues bool) map[string]interface{} {
+	side := func(s *kvDiffSide) map[string]interface{} {
+		return map[string]interface{}{
+			"path":    s.mount.Path + s.secretPath,
+			"version": s.resolvedVersion,
+			"exists":  s.data != nil,
+		}
+	}
+	keys := func(k []string) []string {
+		if k == nil {
+			return []string{}
+		}
+		return k
+	}
+
+	data := map[string]interface{}{
+		"from":     side(diff.from),
+		"to":       side(diff.to),
+		"added":    keys(diff.added),
+		"removed":  keys(diff.removed),
+		"modified": keys(diff.modified),
+	}
+	if maskValues {
+		return data
+	}
+
+	changes := make(map[string]interface{})
+	for _, key := range diff.added {
+		changes[key] = map[string]interface{}{"to": diff.to.data[key]}
+	}
+	for _, key := range diff.removed {
+		changes[key] = map[string]interface{}{"from": diff.from.data[key]}
+	}
+	for _, key := range diff.modified {
+		changes[key] = map[string]interface{}{
+			"from": diff.from.data[key],
+			"to":   diff.to.data[key],
+		}
+	}
+	data["changes"] = changes
+	return data
+}
@@ -0,0 +1,150 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// kvLockPaths returns the paths used to lock KV secrets against deletion
+// and to unlock them.
+func (b *SystemBackend) kvLockPaths() []*framework.Path {
+	pathField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Path of the KV version 2 secret, including its mount, such as "secret/app/db".`,
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "kv-locks/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleKVLocksList,
+					Summary:  "List the locked KV secrets.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-locks"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-locks"][1]),
+		},
+		{
+			Pattern: "kv-locks/(?P<path>.+)",
+			Fields: map[string]*framework.FieldSchema{
+				"path": pathField,
+				"unlock_window": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long the secret can be deleted for after it is unlocked.",
+					Default:     int(kvLockDefaultUnlockWindow.Seconds()),
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleKVLockRead,
+					Summary:  "Read the lock of a KV secret.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleKVLockWrite,
+					Summary:  "Lock a KV secret against deletion.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleKVLockDelete,
+					Summary:  "Remove the lock of an unlocked KV secret.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-lock"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-lock"][1]),
+		},
+		{
+			Pattern: "kv-unlock/(?P<path>.+)",
+			Fields: map[string]*framework.FieldSchema{
+				"path": pathField,
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleKVUnlock,
+					Summary:  "Allow deleting a locked KV secret for the unlock window of its lock.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-unlock"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-unlock"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleKVLocksList(ctx context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	paths, err := b.Core.listKVLocks(ctx)
+	if err != nil {
+		return handleError(err)
+	}
+	return logical.ListResponse(paths), nil
+}
+
+func (b *SystemBackend) handleKVLockRead(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	lock, err := b.Core.readKVLock(ctx, data.Get("path").(string))
+	if err != nil {
+		return handleError(err)
+	}
+	if lock == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: kvLockResponseData(lock),
+	}, nil
+}
+
+func (b *SystemBackend) handleKVLockWrite(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	unlockWindow := time.Duration(data.Get("unlock_window").(int)) * time.Second
+	if unlockWindow <= 0 {
+		return logical.ErrorResponse("unlock_window must be positive"), logical.ErrInvalidRequest
+	}
+
+	lock, err := b.Core.setKVLock(ctx, data.Get("path").(string), unlockWindow)
+	if err != nil {
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: kvLockResponseData(lock),
+	}, nil
+}
+
+func (b *SystemBackend) handleKVLockDelete(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.Core.removeKVLock(ctx, data.Get("path").(string)); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+func (b *SystemBackend) handleKVUnlock(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	unlockedBy := req.EntityID
+	if unlockedBy == "" {
+		unlockedBy = req.DisplayName
+	}
+
+	lock, err := b.Core.unlockKVLock(ctx, data.Get("path").(string), unlockedBy)
+	if err != nil {
+		return handleError(err)
+	}
+	b.Backend.Logger().Info("unlocked KV secret for deletion", "path", lock.Path, "unlocked_by", unlockedBy, "unlocked_until", lock.UnlockedUntil)
+
+	return &logical.Response{
+		Data: kvLockResponseData(lock),
+	}, nil
+}
+
+func kvLockResponseData(lock *kvLock) map[string]interface{} {
+	data := map[string]interface{}{
+		"path":          lock.Path,
+		"unlock_window": int64(lock.UnlockWindow.Seconds()),
+		"creation_time": lock.CreationTime.Format(time.RFC3339Nano),
+		"unlocked":      lock.unlocked(time.Now()),
+	}
+	if !lock.UnlockedUntil.IsZero() {
+		data["unlocked_until"] = lock.UnlockedUntil.Format(time.RFC3339Nano)
+		data["unlocked_by"] = lock.UnlockedBy
+	}
+	return data
+}
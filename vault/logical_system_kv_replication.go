@@ -0,0 +1,348 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/secretsync"
+)
+
+// kvReplicationPaths returns the paths used to manage KV replication targets.
+func (b *SystemBackend) kvReplicationPaths() []*framework.Path {
+	nameField := &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: "Name of the target.",
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "kv-replication/targets/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleKVReplicationTargetsList,
+					Summary:  "List the KV replication targets.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-replication-targets"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-replication-targets"][1]),
+		},
+		{
+			Pattern: "kv-replication/targets/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+				"address": {
+					Type:        framework.TypeString,
+					Description: "API address of the target cluster.",
+				},
+				"ca_cert": {
+					Type:        framework.TypeString,
+					Description: "PEM encoded CA certificate used to verify the target cluster.",
+				},
+				"client_cert": {
+					Type:        framework.TypeString,
+					Description: "PEM encoded client certificate presented to the target cluster.",
+				},
+				"client_key": {
+					Type:        framework.TypeString,
+					Description: "PEM encoded private key of the client certificate.",
+				},
+				"tls_server_name": {
+					Type:        framework.TypeString,
+					Description: "Name used to verify the certificate of the target cluster.",
+				},
+				"auth_mount": {
+					Type:        framework.TypeString,
+					Description: "Path of the cert auth method to log in with on the target cluster.",
+					Default:     kvReplicationDefaultAuthMount,
+				},
+				"auth_role": {
+					Type:        framework.TypeString,
+					Description: "Name of the cert auth role to log in with on the target cluster.",
+				},
+				"paths": {
+					Type: framework.TypeCommaStringSlice,
+					Description: `KV version 2 paths to replicate, such as "secret/" or
+"secret/app/". Paths not ending with a slash replicate a single secret.`,
+				},
+				"interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How often changes are replicated to the target.",
+					Default:     int(kvReplicationDefaultInterval.Seconds()),
+				},
+				"resync_interval": {
+					Type: framework.TypeDurationSecond,
+					Description: `How often every secret is compared with its copy on the
+target, overwriting changes made on the target.`,
+					Default: int(kvReplicationDefaultResyncInterval.Seconds()),
+				},
+			},
+			ExistenceCheck: b.handleKVReplicationTargetExistenceCheck,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleKVReplicationTargetRead,
+					Summary:  "Read a KV replication target.",
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.handleKVReplicationTargetWrite,
+					Summary:  "Create a KV replication target.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleKVReplicationTargetWrite,
+					Summary:  "Update a KV replication target.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleKVReplicationTargetDelete,
+					Summary:  "Stop replicating to a target.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-replication-target"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-replication-target"][1]),
+		},
+		{
+			Pattern: "kv-replication/targets/" + framework.GenericNameRegex("name") + "/status$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleKVReplicationStatus,
+					Summary:  "Read the replication status of a target.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-replication-status"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-replication-status"][1]),
+		},
+		{
+			Pattern: "kv-replication/targets/" + framework.GenericNameRegex("name") + "/sync$",
+			Fields: map[string]*framework.FieldSchema{
+				"name": nameField,
+				"full": {
+					Type:        framework.TypeBool,
+					Description: "Compare every secret with the target instead of only replicating new versions.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleKVReplicationSync,
+					Summary:  "Replicate to a target immediately.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-replication-sync"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-replication-sync"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) kvReplication() (*kvReplicationManager, error) {
+	if b.Core.kvReplication == nil {
+		return nil, errKVReplicationUnavailable
+	}
+	return b.Core.kvReplication, nil
+}
+
+func (b *SystemBackend) handleKVReplicationTargetsList(ctx context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	m, err := b.kvReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := m.listTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+func (b *SystemBackend) handleKVReplicationTargetExistenceCheck(ctx context.Context, _ *logical.Request, data *framework.FieldData) (bool, error) {
+	m, err := b.kvReplication()
+	if err != nil {
+		return false, err
+	}
+
+	entry, err := m.getTarget(ctx, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+func (b *SystemBackend) handleKVReplicationTargetRead(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.kvReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := m.getTarget(ctx, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: kvReplicationTargetResponseData(entry),
+	}, nil
+}
+
+func kvReplicationTargetResponseData(entry *kvReplicationTargetEntry) map[string]interface{} {
+	paths := make([]string, 0, len(entry.Paths))
+	for _, p := range entry.Paths {
+		paths = append(paths, p.String())
+	}
+
+	return map[string]interface{}{
+		"name":            entry.Name,
+		"address":         entry.Address,
+		"ca_cert":         entry.CACert,
+		"client_cert":     entry.ClientCert,
+		"tls_server_name": entry.TLSServerName,
+		"auth_mount":      entry.AuthMount,
+		"auth_role":       entry.AuthRole,
+		"paths":           paths,
+		"interval":        int64(entry.Interval.Seconds()),
+		"resync_interval": int64(entry.ResyncInterval.Seconds()),
+	}
+}
+
+func (b *SystemBackend) handleKVReplicationTargetWrite(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.kvReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := m.writeTarget(ctx, data.Get("name").(string), func(entry *kvReplicationTargetEntry) error {
+		for field, value := range map[string]*string{
+			"address":         &entry.Address,
+			"ca_cert":         &entry.CACert,
+			"client_cert":     &entry.ClientCert,
+			"client_key":      &entry.ClientKey,
+			"tls_server_name": &entry.TLSServerName,
+			"auth_role":       &entry.AuthRole,
+		} {
+			if raw, ok := data.GetOk(field); ok {
+				*value = raw.(string)
+			}
+		}
+		if raw, ok := data.GetOk("auth_mount"); ok {
+			entry.AuthMount = strings.Trim(raw.(string), "/")
+		}
+
+		if raw, ok := data.GetOk("paths"); ok {
+			entry.Paths = nil
+			for _, p := range raw.([]string) {
+				p = strings.TrimPrefix(p, "/")
+				if !strings.Contains(p, "/") {
+					p += "/"
+				}
+				mount, prefix, err := m.source.splitPath(ctx, p)
+				if err != nil {
+					return err
+				}
+				entry.Paths = append(entry.Paths, &kvReplicationPath{Mount: mount, Prefix: prefix})
+			}
+		}
+
+		if raw, ok := data.GetOk("interval"); ok {
+			entry.Interval = time.Duration(raw.(int)) * time.Second
+		}
+		if raw, ok := data.GetOk("resync_interval"); ok {
+			entry.ResyncInterval = time.Duration(raw.(int)) * time.Second
+		}
+		if entry.Interval <= 0 || entry.ResyncInterval < entry.Interval {
+			return fmt.Errorf("%w: interval must be positive and no greater than resync_interval", secretsync.ErrInvalidConfig)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return secretSyncErrorResponse(err)
+	}
+
+	return &logical.Response{
+		Data: kvReplicationTargetResponseData(entry),
+	}, nil
+}
+
+func (b *SystemBackend) handleKVReplicationTargetDelete(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.kvReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, m.deleteTarget(ctx, data.Get("name").(string))
+}
+
+func (b *SystemBackend) handleKVReplicationStatus(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.kvReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	name := data.Get("name").(string)
+	entry, err := m.getTarget(ctx, name)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	state, err := m.getState(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: kvReplicationStatusResponseData(state),
+	}, nil
+}
+
+func (b *SystemBackend) handleKVReplicationSync(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.kvReplication()
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := m.replicate(ctx, data.Get("name").(string), data.Get("full").(bool))
+	if state == nil {
+		return secretSyncErrorResponse(err)
+	}
+
+	resp := &logical.Response{
+		Data: kvReplicationStatusResponseData(state),
+	}
+	if err != nil {
+		resp.AddWarning("Replication failed and will be retried: " + err.Error())
+	}
+	return resp, nil
+}
+
+func kvReplicationStatusResponseData(state *kvReplicationState) map[string]interface{} {
+	var replicated, deleted int
+	for _, version := range state.Versions {
+		if version == 0 {
+			deleted++
+			continue
+		}
+		replicated++
+	}
+
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	return map[string]interface{}{
+		"replicated_secrets": replicated,
+		"deleted_secrets":    deleted,
+		"last_run":           formatTime(state.LastRun),
+		"last_success":       formatTime(state.LastSuccess),
+		"last_full_sync":     formatTime(state.LastFullSync),
+		"last_error":         state.LastError,
+		"last_written":       state.Written,
+		"last_deleted":       state.Deleted,
+	}
+}
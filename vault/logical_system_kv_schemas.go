@@ -0,0 +1,101 @@
+package vault
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// kvSchemaPaths returns the paths used to manage the JSON schemas KV secrets
+// are validated against.
+func (b *SystemBackend) kvSchemaPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "kv-schemas/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleKVSchemasList,
+					Summary:  "List the paths with a KV schema.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-schemas"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-schemas"][1]),
+		},
+		{
+			Pattern: "kv-schemas/(?P<path>.+)",
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type: framework.TypeString,
+					Description: `Path the schema applies to, including the KV version 2 mount. A mount
+applies to all of its secrets, a path ending with "/*" to the secrets under
+it and any other path to a single secret.`,
+				},
+				"schema": {
+					Type:        framework.TypeString,
+					Description: "JSON schema the data of the secrets written is validated against.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleKVSchemaRead,
+					Summary:  "Read the KV schema of a path.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleKVSchemaWrite,
+					Summary:  "Set the KV schema of a path.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleKVSchemaDelete,
+					Summary:  "Remove the KV schema of a path.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["kv-schema"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["kv-schema"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleKVSchemasList(ctx context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	paths, err := b.Core.listKVSchemas(ctx)
+	if err != nil {
+		return handleError(err)
+	}
+	return logical.ListResponse(paths), nil
+}
+
+func (b *SystemBackend) handleKVSchemaRead(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	schema, err := b.Core.readKVSchema(ctx, data.Get("path").(string))
+	if err != nil {
+		return handleError(err)
+	}
+	if schema == "" {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"schema": schema,
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleKVSchemaWrite(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	schema := data.Get("schema").(string)
+	if schema == "" {
+		return logical.ErrorResponse("missing schema"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.setKVSchema(ctx, data.Get("path").(string), schema); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+func (b *SystemBackend) handleKVSchemaDelete(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := b.Core.removeKVSchema(ctx, data.Get("path").(string)); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,249 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// leaseNotificationPaths returns the paths used to manage the webhooks sent
+// about leases close to expiry or failing to renew.
+func (b *SystemBackend) leaseNotificationPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "leases/notifications/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleLeaseNotificationsList,
+					Summary:  "List the lease notification rules.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["lease-notifications"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["lease-notifications"][1]),
+		},
+		{
+			Pattern: "leases/notifications/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the notification rule.",
+				},
+				"url": {
+					Type:        framework.TypeString,
+					Description: "HTTP or HTTPS URL the notifications are posted to.",
+				},
+				"secret": {
+					Type: framework.TypeString,
+					Description: `Key used to sign the notifications. When set, the
+X-Vault-Signature header holds the HMAC-SHA256 of the body as
+"sha256=<hex>".`,
+				},
+				"path_prefix": {
+					Type: framework.TypeString,
+					Description: `Prefix of the lease IDs the rule applies to, such as the
+path of a mount or of a role, e.g. "database/creds/readonly/". Defaults to
+all leases.`,
+				},
+				"thresholds": {
+					Type: framework.TypeCommaStringSlice,
+					Description: `Remaining TTLs, e.g. "24h,1h", at which a lease-expiring
+notification is sent for each matching lease.`,
+				},
+				"notify_renewal_failure": {
+					Type:        framework.TypeBool,
+					Description: "Send a lease-renewal-failed notification when a matching lease fails to renew.",
+				},
+			},
+			ExistenceCheck: b.handleLeaseNotificationExistenceCheck,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleLeaseNotificationRead,
+					Summary:  "Read a lease notification rule.",
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.handleLeaseNotificationWrite,
+					Summary:  "Create a lease notification rule.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleLeaseNotificationWrite,
+					Summary:  "Update a lease notification rule.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleLeaseNotificationDelete,
+					Summary:  "Delete a lease notification rule.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["lease-notification"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["lease-notification"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) leaseNotifier() (*leaseNotifier, error) {
+	if b.Core.leaseNotifier == nil {
+		return nil, errLeaseNotificationsUnavailable
+	}
+	return b.Core.leaseNotifier, nil
+}
+
+func (b *SystemBackend) handleLeaseNotificationsList(_ context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	n, err := b.leaseNotifier()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := n.list()
+	keys := make([]string, 0, len(entries))
+	keyInfo := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Name)
+		keyInfo[entry.Name] = map[string]interface{}{
+			"url":         entry.URL,
+			"path_prefix": entry.PathPrefix,
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (b *SystemBackend) handleLeaseNotificationExistenceCheck(ctx context.Context, _ *logical.Request, data *framework.FieldData) (bool, error) {
+	n, err := b.leaseNotifier()
+	if err != nil {
+		return false, err
+	}
+
+	entry, err := n.get(ctx, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+func (b *SystemBackend) handleLeaseNotificationRead(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	n, err := b.leaseNotifier()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := n.get(ctx, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: leaseNotificationResponseData(entry),
+	}, nil
+}
+
+func leaseNotificationResponseData(entry *leaseNotificationEntry) map[string]interface{} {
+	thresholds := make([]int64, 0, len(entry.Thresholds))
+	for _, threshold := range entry.Thresholds {
+		thresholds = append(thresholds, int64(threshold.Seconds()))
+	}
+
+	return map[string]interface{}{
+		"name":                   entry.Name,
+		"url":                    entry.URL,
+		"has_secret":             entry.Secret != "",
+		"path_prefix":            entry.PathPrefix,
+		"thresholds":             thresholds,
+		"notify_renewal_failure": entry.NotifyRenewalFailure,
+	}
+}
+
+func (b *SystemBackend) handleLeaseNotificationWrite(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if !b.Core.leaseNotificationsWritable() {
+		return nil, logical.ErrReadOnly
+	}
+
+	n, err := b.leaseNotifier()
+	if err != nil {
+		return nil, err
+	}
+
+	name := data.Get("name").(string)
+	entry, err := n.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		entry = &leaseNotificationEntry{Name: name}
+	}
+
+	if urlRaw, ok := data.GetOk("url"); ok {
+		entry.URL = urlRaw.(string)
+	}
+	if entry.URL == "" {
+		return logical.ErrorResponse("url is required"), nil
+	}
+	parsed, err := url.Parse(entry.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return logical.ErrorResponse("url must be an absolute http or https URL"), nil
+	}
+
+	if secretRaw, ok := data.GetOk("secret"); ok {
+		entry.Secret = secretRaw.(string)
+	}
+	if prefixRaw, ok := data.GetOk("path_prefix"); ok {
+		entry.PathPrefix = prefixRaw.(string)
+	}
+	if notifyRaw, ok := data.GetOk("notify_renewal_failure"); ok {
+		entry.NotifyRenewalFailure = notifyRaw.(bool)
+	}
+
+	if thresholdsRaw, ok := data.GetOk("thresholds"); ok {
+		thresholds := make([]time.Duration, 0, len(thresholdsRaw.([]string)))
+		for _, raw := range thresholdsRaw.([]string) {
+			threshold, err := parseutil.ParseDurationSecond(raw)
+			if err != nil {
+				return logical.ErrorResponse("invalid threshold %q: %v", raw, err), nil
+			}
+			if threshold <= 0 {
+				return logical.ErrorResponse("thresholds must be positive"), nil
+			}
+			thresholds = append(thresholds, threshold)
+		}
+		sort.Slice(thresholds, func(i, j int) bool {
+			return thresholds[i] > thresholds[j]
+		})
+		entry.Thresholds = thresholds
+	}
+
+	if len(entry.Thresholds) == 0 && !entry.NotifyRenewalFailure {
+		return logical.ErrorResponse("at least one of thresholds or notify_renewal_failure must be set"), nil
+	}
+
+	if err := n.put(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to save lease notification: %w", err)
+	}
+
+	return &logical.Response{
+		Data: leaseNotificationResponseData(entry),
+	}, nil
+}
+
+func (b *SystemBackend) handleLeaseNotificationDelete(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if !b.Core.leaseNotificationsWritable() {
+		return nil, logical.ErrReadOnly
+	}
+
+	n, err := b.leaseNotifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.delete(ctx, data.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,331 @@
+package vault
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/managedkeys"
+)
+
+var managedKeyTestHashes = map[string]crypto.Hash{
+	"sha1":     crypto.SHA1,
+	"sha2-224": crypto.SHA224,
+	"sha2-256": crypto.SHA256,
+	"sha2-384": crypto.SHA384,
+	"sha2-512": crypto.SHA512,
+	"sha3-224": crypto.SHA3_224,
+	"sha3-256": crypto.SHA3_256,
+	"sha3-384": crypto.SHA3_384,
+	"sha3-512": crypto.SHA3_512,
+}
+
+// managedKeyConfigFields returns the fields of the parameters of every
+// managed key type.
+func managedKeyConfigFields() map[string]*framework.FieldSchema {
+	types := make(map[string][]string)
+	for typ, def := range managedkeys.BuiltinDefinitions() {
+		for _, field := range def.ConfigFields {
+			types[field] = append(types[field], typ)
+		}
+	}
+
+	fields := make(map[string]*framework.FieldSchema, len(types))
+	for field, fieldTypes := range types {
+		sort.Strings(fieldTypes)
+		fields[field] = &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Parameter of " + strings.Join(fieldTypes, " and ") + " managed keys.",
+		}
+	}
+	return fields
+}
+
+// managedKeyPaths returns the paths used to manage the keys of external key
+// management systems that secrets engines can sign with.
+func (b *SystemBackend) managedKeyPaths() []*framework.Path {
+	keyFields := map[string]*framework.FieldSchema{
+		"type": {
+			Type:        framework.TypeString,
+			Description: "Type of the key management system: pkcs11, awskms or azurekeyvault.",
+		},
+		"name": {
+			Type:        framework.TypeString,
+			Description: "Name of the managed key.",
+		},
+	}
+
+	writeFields := managedKeyConfigFields()
+	writeFields["type"] = keyFields["type"]
+	writeFields["name"] = keyFields["name"]
+	writeFields["usages"] = &framework.FieldSchema{
+		Type: framework.TypeCommaStringSlice,
+		Description: `Operations secrets engines may perform with the key: "sign",
+"verify" or both. Defaults to both.`,
+	}
+	writeFields["any_mount"] = &framework.FieldSchema{
+		Type: framework.TypeBool,
+		Description: `Allow every mount to use the key. Otherwise the key must be
+listed in the allowed_managed_keys of the mount.`,
+	}
+	writeFields["allow_generate_key"] = &framework.FieldSchema{
+		Type: framework.TypeBool,
+		Description: `Generate the key in the key management system if it cannot
+be found there.`,
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "managed-keys/" + framework.GenericNameRegex("type") + "/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"type": keyFields["type"],
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeysList,
+					Summary:  "List the managed keys of a type.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["managed-keys"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["managed-keys"][1]),
+		},
+		{
+			Pattern:        "managed-keys/" + framework.GenericNameRegex("type") + "/" + framework.GenericNameRegex("name"),
+			Fields:         writeFields,
+			ExistenceCheck: b.handleManagedKeyExistenceCheck,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeyRead,
+					Summary:  "Read a managed key.",
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeyWrite,
+					Summary:  "Create a managed key.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeyWrite,
+					Summary:  "Update a managed key.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeyDelete,
+					Summary:  "Delete a managed key.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["managed-key"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["managed-key"][1]),
+		},
+		{
+			Pattern: "managed-keys/" + framework.GenericNameRegex("type") + "/" + framework.GenericNameRegex("name") + "/test/sign$",
+			Fields: map[string]*framework.FieldSchema{
+				"type": keyFields["type"],
+				"name": keyFields["name"],
+				"hash_algorithm": {
+					Type:    framework.TypeString,
+					Default: "sha2-256",
+					Description: `Hash algorithm of the test signature: sha1, sha2-224,
+sha2-256, sha2-384, sha2-512, sha3-224, sha3-256, sha3-384 or sha3-512.`,
+				},
+				"use_pss": {
+					Type:        framework.TypeBool,
+					Description: `Use PSS for the test signature of RSA keys.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleManagedKeyTestSign,
+					Summary:  "Check that a managed key can sign.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["managed-key-test-sign"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["managed-key-test-sign"][1]),
+		},
+	}
+}
+
+// managedKeyErrorResponse converts a registry error into a response, turning
+// configuration problems into user errors.
+func managedKeyErrorResponse(err error) (*logical.Response, error) {
+	if errors.Is(err, managedkeys.ErrInvalidConfig) {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, err
+}
+
+func (b *SystemBackend) managedKeyRegistry() (*managedKeyRegistry, error) {
+	if b.Core.managedKeyRegistry == nil {
+		return nil, errManagedKeyRegistryUnavailable
+	}
+	return b.Core.managedKeyRegistry, nil
+}
+
+func (b *SystemBackend) handleManagedKeysList(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	r, err := b.managedKeyRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := r.list(ctx, data.Get("type").(string))
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(names), nil
+}
+
+func (b *SystemBackend) handleManagedKeyExistenceCheck(ctx context.Context, _ *logical.Request, data *framework.FieldData) (bool, error) {
+	r, err := b.managedKeyRegistry()
+	if err != nil {
+		return false, err
+	}
+
+	entry, err := r.get(ctx, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+func (b *SystemBackend) handleManagedKeyRead(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	r, err := b.managedKeyRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := r.get(ctx, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.Type != data.Get("type").(string) {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: r.keyResponseData(entry),
+	}, nil
+}
+
+func (r *managedKeyRegistry) keyResponseData(entry *managedKeyEntry) map[string]interface{} {
+	data := map[string]interface{}{
+		"type":               entry.Type,
+		"name":               entry.Name,
+		"UUID":               entry.UUID,
+		"usages":             entry.Usages,
+		"any_mount":          entry.AnyMount,
+		"allow_generate_key": entry.AllowGenerateKey,
+	}
+
+	def := r.definitions[entry.Type]
+	for _, field := range def.ConfigFields {
+		data[field] = entry.Config[field]
+	}
+	for _, field := range def.SensitiveConfig {
+		if entry.Config[field] != "" {
+			data[field] = "redacted"
+		}
+	}
+
+	if !entry.LastHealthCheck.IsZero() {
+		data["last_health_check"] = entry.LastHealthCheck.Format(time.RFC3339)
+		data["healthy"] = entry.LastHealthError == ""
+	}
+	if entry.LastHealthError != "" {
+		data["last_health_error"] = entry.LastHealthError
+	}
+	return data
+}
+
+func (b *SystemBackend) handleManagedKeyWrite(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	r, err := b.managedKeyRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	typ := data.Get("type").(string)
+	def, ok := r.definitions[typ]
+	if !ok {
+		return logical.ErrorResponse("unknown managed key type %q", typ), logical.ErrInvalidRequest
+	}
+
+	entry, err := r.write(ctx, typ, data.Get("name").(string), func(entry *managedKeyEntry) error {
+		for _, field := range def.ConfigFields {
+			raw, ok, err := data.GetOkErr(field)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if value := raw.(string); value != "" {
+				entry.Config[field] = value
+			} else {
+				delete(entry.Config, field)
+			}
+		}
+
+		if usagesRaw, ok := data.GetOk("usages"); ok {
+			usages, err := parseManagedKeyUsages(usagesRaw.([]string))
+			if err != nil {
+				return err
+			}
+			entry.Usages = usages
+		}
+
+		if anyMountRaw, ok := data.GetOk("any_mount"); ok {
+			entry.AnyMount = anyMountRaw.(bool)
+		}
+		if allowGenerateRaw, ok := data.GetOk("allow_generate_key"); ok {
+			entry.AllowGenerateKey = allowGenerateRaw.(bool)
+		}
+		return nil
+	})
+	if err != nil {
+		return managedKeyErrorResponse(err)
+	}
+
+	return &logical.Response{
+		Data: r.keyResponseData(entry),
+	}, nil
+}
+
+func (b *SystemBackend) handleManagedKeyDelete(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	r, err := b.managedKeyRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.delete(ctx, data.Get("type").(string), data.Get("name").(string)); err != nil {
+		return managedKeyErrorResponse(err)
+	}
+	return nil, nil
+}
+
+func (b *SystemBackend) handleManagedKeyTestSign(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	r, err := b.managedKeyRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, ok := managedKeyTestHashes[data.Get("hash_algorithm").(string)]
+	if !ok {
+		return logical.ErrorResponse("unsupported hash_algorithm %q", data.Get("hash_algorithm").(string)), nil
+	}
+
+	entry, err := r.test(ctx, data.Get("type").(string), data.Get("name").(string), hash, data.Get("use_pss").(bool))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	if entry.LastHealthError != "" {
+		return logical.ErrorResponse("managed key test failed: %s", entry.LastHealthError), nil
+	}
+
+	return &logical.Response{
+		Data: r.keyResponseData(entry),
+	}, nil
+}
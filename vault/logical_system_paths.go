@@ -59,6 +59,19 @@ func (b *SystemBackend) configPaths() []*framework.Path {
 			},
 		},
 
+		{
+			Pattern: "config/reload-status$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleConfigReloadStatus,
+					Summary:  "Return the settings that changed on the last reload of the server configuration.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["config/reload-status"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["config/reload-status"][1]),
+		},
+
 		{
 			Pattern: "config/reload/(?P<subsystem>.+)",
 			Fields: map[string]*framework.FieldSchema{
@@ -562,6 +575,11 @@ func (b *SystemBackend) auditPaths() []*framework.Path {
 				"input": {
 					Type: framework.TypeString,
 				},
+
+				"inputs": {
+					Type:        framework.TypeStringSlice,
+					Description: "List of strings to hash in a single request, instead of input.",
+				},
 			},
 
 			Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -572,6 +590,51 @@ func (b *SystemBackend) auditPaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["audit-hash"][1]),
 		},
 
+		{
+			Pattern: "audit-hash-rotate/(?P<path>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["audit_path"][0]),
+				},
+
+				"overlap": {
+					Type:        framework.TypeDurationSecond,
+					Default:     86400,
+					Description: "How long hashes with the previous salt keep being returned. Defaults to 24h.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleAuditHashRotate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["audit-hash-rotate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["audit-hash-rotate"][1]),
+		},
+
+		{
+			Pattern: "audit-test$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Optional path of the audit backend to test. All enabled audit backends are tested if unset.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleAuditTest,
+					Summary:  "Write a test entry to the enabled audit devices.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["audit-test"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["audit-test"][1]),
+		},
+
 		{
 			Pattern: "audit$",
 
@@ -878,10 +941,23 @@ func (b *SystemBackend) toolsPaths() []*framework.Path {
 			* sha2-256
 			* sha2-384
 			* sha2-512
+			* sha3-224
+			* sha3-256
+			* sha3-384
+			* sha3-512
+			* blake2b-256
+			* blake2b-512
+			* blake2s-256
+			* blake3
 
 			Defaults to "sha2-256".`,
 				},
 
+				"key": {
+					Type:        framework.TypeString,
+					Description: "The base64-encoded key of the BLAKE2 and BLAKE3 algorithms. When set, the sum is a keyed hash.",
+				},
+
 				"urlalgorithm": {
 					Type:        framework.TypeString,
 					Description: `Algorithm to use (POST URL parameter)`,
@@ -1102,6 +1178,10 @@ func (b *SystemBackend) capabilitiesPaths() []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: "Paths on which capabilities are being queried.",
 				},
+				"expand_mounts": {
+					Type:        framework.TypeBool,
+					Description: "Whether to also return the mounts each path applies to.",
+				},
 			},
 
 			Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -1129,6 +1209,10 @@ func (b *SystemBackend) capabilitiesPaths() []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: "Paths on which capabilities are being queried.",
 				},
+				"expand_mounts": {
+					Type:        framework.TypeBool,
+					Description: "Whether to also return the mounts each path applies to.",
+				},
 			},
 
 			Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -1156,6 +1240,10 @@ func (b *SystemBackend) capabilitiesPaths() []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: "Paths on which capabilities are being queried.",
 				},
+				"expand_mounts": {
+					Type:        framework.TypeBool,
+					Description: "Whether to also return the mounts each path applies to.",
+				},
 			},
 
 			Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -1212,6 +1300,33 @@ func (b *SystemBackend) leasePaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["leases"][1]),
 		},
 
+		{
+			Pattern: "leases/tagged/?$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"key": {
+					Type:        framework.TypeString,
+					Description: "The key of the tag to list leases by.",
+					Query:       true,
+				},
+				"value": {
+					Type:        framework.TypeString,
+					Description: "The value of the tag to list leases by.",
+					Query:       true,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleLeaseTaggedList,
+					Summary:  "Returns the leases tagged with a key and value, with their metadata.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["leases-tagged"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["leases-tagged"][1]),
+		},
+
 		{
 			Pattern: "(leases/)?renew" + framework.OptionalParamRegex("url_lease_id"),
 
@@ -1465,6 +1580,16 @@ func (b *SystemBackend) monitorPath() *framework.Path {
 				Query:       true,
 				Default:     "standard",
 			},
+			"logger_name": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Names of the loggers to stream the logs of, along with the logs of their sub-loggers, such as \"core\" or \"storage.raft\". The logs of all loggers are streamed if not specified.",
+				Query:       true,
+			},
+			"framed": {
+				Type:        framework.TypeBool,
+				Description: "If true, each log is wrapped in a JSON object holding its sequence number and the number of logs dropped before it.",
+				Query:       true,
+			},
 		},
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation: b.handleMonitor,
@@ -1563,6 +1688,22 @@ func (b *SystemBackend) authPaths() []*framework.Path {
 					Type:        framework.TypeMap,
 					Description: strings.TrimSpace(sysHelp["tune_user_lockout_config"][0]),
 				},
+				"idempotency_key_ttl": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["tune_idempotency_key_ttl"][0]),
+				},
+				"max_request_size": {
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["tune_max_request_size"][0]),
+				},
+				"max_response_size": {
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["tune_max_response_size"][0]),
+				},
+				"request_timeout": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["tune_request_timeout"][0]),
+				},
 				"plugin_version": {
 					Type:        framework.TypeString,
 					Description: strings.TrimSpace(sysHelp["plugin-catalog_version"][0]),
@@ -1768,6 +1909,11 @@ func (b *SystemBackend) policyPaths() []*framework.Path {
 					Type:        framework.TypeString,
 					Description: "The name of the password policy.",
 				},
+				"count": {
+					Type:        framework.TypeInt,
+					Description: "The number of passwords to generate.",
+					Default:     1,
+				},
 			},
 
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -1777,8 +1923,35 @@ func (b *SystemBackend) policyPaths() []*framework.Path {
 				},
 			},
 
-			HelpSynopsis:    "Generate a password from an existing password policy.",
-			HelpDescription: "Generate a password from an existing password policy.",
+			HelpSynopsis: "Generate a password from an existing password policy.",
+			HelpDescription: "Generate one or more passwords from an existing password policy. " +
+				"When count is greater than 1, the passwords are returned as a list.",
+		},
+
+		{
+			Pattern: "policies/password/(?P<name>.+)/test$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "The name of the password policy.",
+				},
+				"password": {
+					Type:        framework.TypeString,
+					Description: "The candidate password to validate.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handlePoliciesPasswordTest,
+					Summary:  "Validate a password against an existing password policy.",
+				},
+			},
+
+			HelpSynopsis: "Validate a password against an existing password policy.",
+			HelpDescription: "Validate a candidate password against the length and rules of an existing " +
+				"password policy and report the requirements it does not meet.",
 		},
 
 		{
@@ -1894,6 +2067,70 @@ func (b *SystemBackend) wrappingPaths() []*framework.Path {
 
 func (b *SystemBackend) mountPaths() []*framework.Path {
 	return []*framework.Path{
+		{
+			Pattern: "mounts/trash/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleMountTrashList,
+					Summary:  "List the mounts disabled with a retention period.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mount_trash"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mount_trash"][1]),
+		},
+
+		{
+			Pattern: "mounts/trash/(?P<id>[^/]+)/restore$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mount_trash_id"][0]),
+				},
+				"path": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mount_trash_restore_path"][0]),
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleMountTrashRestore,
+					Summary:  "Enable a disabled mount again, with its data.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mount_trash_restore"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mount_trash_restore"][1]),
+		},
+
+		{
+			Pattern: "mounts/trash/(?P<id>[^/]+)$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["mount_trash_id"][0]),
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleMountTrashRead,
+					Summary:  "Read a mount of the trash.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleMountTrashPurge,
+					Summary:  "Remove a mount of the trash along with its data.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["mount_trash_entry"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["mount_trash_entry"][1]),
+		},
+
 		{
 			Pattern: "mounts/(?P<path>.+?)/tune$",
 
@@ -1954,6 +2191,22 @@ func (b *SystemBackend) mountPaths() []*framework.Path {
 					Type:        framework.TypeMap,
 					Description: strings.TrimSpace(sysHelp["tune_user_lockout_config"][0]),
 				},
+				"idempotency_key_ttl": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["tune_idempotency_key_ttl"][0]),
+				},
+				"max_request_size": {
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["tune_max_request_size"][0]),
+				},
+				"max_response_size": {
+					Type:        framework.TypeInt,
+					Description: strings.TrimSpace(sysHelp["tune_max_response_size"][0]),
+				},
+				"request_timeout": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["tune_request_timeout"][0]),
+				},
 			},
 
 			Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -2012,6 +2265,10 @@ func (b *SystemBackend) mountPaths() []*framework.Path {
 					Type:        framework.TypeString,
 					Description: strings.TrimSpace(sysHelp["plugin-catalog_version"][0]),
 				},
+				"retention": {
+					Type:        framework.TypeDurationSecond,
+					Description: strings.TrimSpace(sysHelp["mount_retention"][0]),
+				},
 			},
 
 			Operations: map[logical.Operation]framework.OperationHandler{
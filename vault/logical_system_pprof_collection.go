@@ -0,0 +1,250 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+var errPprofCollectionUnavailable = errors.New("profile collection is not available on this node")
+
+// pprofCollectionPaths returns the paths used to configure the periodic
+// collection of profiles and to read the collected profiles.
+func (b *SystemBackend) pprofCollectionPaths() []*framework.Path {
+	idField := map[string]*framework.FieldSchema{
+		"id": {
+			Type:        framework.TypeString,
+			Description: "Identifier of the profile.",
+		},
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "pprof-collection/config$",
+			Fields: map[string]*framework.FieldSchema{
+				"enabled": {
+					Type:        framework.TypeBool,
+					Description: "Whether profiles are collected.",
+				},
+				"interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Time between two collections.",
+				},
+				"cpu_duration": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Length of the CPU profiles. Must be less than interval.",
+				},
+				"profiles": {
+					Type: framework.TypeCommaStringSlice,
+					Description: `Profiles to collect: cpu, heap, allocs, goroutine, block, mutex or
+threadcreate.`,
+				},
+				"retain": {
+					Type: framework.TypeInt,
+					Description: `Number of profiles of each type kept in storage. 0 disables storage,
+in which case profiles are only sent to endpoint_url.`,
+				},
+				"endpoint_url": {
+					Type: framework.TypeString,
+					Description: `URL profiles are sent to with a POST request, in the pprof format.
+Leave empty to only store profiles.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handlePprofCollectionConfigRead,
+					Summary:  "Read the configuration of the profile collection.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handlePprofCollectionConfigWrite,
+					Summary:  "Configure the profile collection.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["pprof-collection-config"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["pprof-collection-config"][1]),
+		},
+		{
+			Pattern: "pprof-collection/profiles/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handlePprofCollectionProfilesList,
+					Summary:  "List the collected profiles.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["pprof-collection-profiles"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["pprof-collection-profiles"][1]),
+		},
+		{
+			Pattern: "pprof-collection/profiles/" + framework.GenericNameRegex("id") + "/raw$",
+			Fields:  idField,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handlePprofCollectionProfileRaw,
+					Summary:  "Download a collected profile in the pprof format.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["pprof-collection-profiles"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["pprof-collection-profiles"][1]),
+		},
+		{
+			Pattern: "pprof-collection/profiles/" + framework.GenericNameRegex("id") + "$",
+			Fields:  idField,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handlePprofCollectionProfileRead,
+					Summary:  "Read the details of a collected profile.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handlePprofCollectionProfileDelete,
+					Summary:  "Delete a collected profile.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["pprof-collection-profiles"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["pprof-collection-profiles"][1]),
+		},
+	}
+}
+
+func pprofCollectionConfigResponseData(config pprofCollectionConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"enabled":      config.Enabled,
+		"interval":     int64(config.Interval.Seconds()),
+		"cpu_duration": int64(config.CPUDuration.Seconds()),
+		"profiles":     config.Profiles,
+		"retain":       config.Retain,
+		"endpoint_url": config.EndpointURL,
+	}
+}
+
+func (b *SystemBackend) handlePprofCollectionConfigRead(_ context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	p := b.Core.pprofCollector
+	if p == nil {
+		return nil, errPprofCollectionUnavailable
+	}
+
+	return &logical.Response{
+		Data: pprofCollectionConfigResponseData(p.getConfig()),
+	}, nil
+}
+
+func (b *SystemBackend) handlePprofCollectionConfigWrite(ctx context.Context, _ *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	p := b.Core.pprofCollector
+	if p == nil {
+		return nil, errPprofCollectionUnavailable
+	}
+	config := p.getConfig()
+
+	if raw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = raw.(bool)
+	}
+	if raw, ok := d.GetOk("interval"); ok {
+		config.Interval = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := d.GetOk("cpu_duration"); ok {
+		config.CPUDuration = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := d.GetOk("profiles"); ok {
+		config.Profiles = raw.([]string)
+	}
+	if raw, ok := d.GetOk("retain"); ok {
+		config.Retain = raw.(int)
+	}
+	if raw, ok := d.GetOk("endpoint_url"); ok {
+		config.EndpointURL = raw.(string)
+	}
+
+	if err := config.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err := p.setConfig(ctx, config); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: pprofCollectionConfigResponseData(config),
+	}, nil
+}
+
+func (b *SystemBackend) handlePprofCollectionProfilesList(ctx context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	p := b.Core.pprofCollector
+	if p == nil {
+		return nil, errPprofCollectionUnavailable
+	}
+
+	ids, err := p.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		profile, err := p.get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if profile != nil {
+			keyInfo[id] = pprofCollectionProfileResponseData(profile)
+		}
+	}
+	return logical.ListResponseWithInfo(ids, keyInfo), nil
+}
+
+func pprofCollectionProfileResponseData(profile *collectedProfile) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         profile.ID,
+		"type":       profile.Type,
+		"node":       profile.Node,
+		"start_time": profile.StartTime.Format(time.RFC3339),
+		"end_time":   profile.EndTime.Format(time.RFC3339),
+		"size":       len(profile.Data),
+	}
+}
+
+func (b *SystemBackend) handlePprofCollectionProfileRead(ctx context.Context, _ *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	p := b.Core.pprofCollector
+	if p == nil {
+		return nil, errPprofCollectionUnavailable
+	}
+
+	profile, err := p.get(ctx, d.Get("id").(string))
+	if err != nil || profile == nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: pprofCollectionProfileResponseData(profile),
+	}, nil
+}
+
+func (b *SystemBackend) handlePprofCollectionProfileRaw(ctx context.Context, _ *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	p := b.Core.pprofCollector
+	if p == nil {
+		return nil, errPprofCollectionUnavailable
+	}
+
+	profile, err := p.get(ctx, d.Get("id").(string))
+	if err != nil || profile == nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  200,
+			logical.HTTPRawBody:     profile.Data,
+			logical.HTTPContentType: "application/octet-stream",
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handlePprofCollectionProfileDelete(ctx context.Context, _ *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	p := b.Core.pprofCollector
+	if p == nil {
+		return nil, errPprofCollectionUnavailable
+	}
+
+	if err := p.delete(ctx, d.Get("id").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
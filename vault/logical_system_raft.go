@@ -6,12 +6,15 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/go-uuid"
+	raftlib "github.com/hashicorp/raft"
 	"github.com/hashicorp/vault/helper/constants"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/physical/raft"
@@ -149,6 +152,65 @@ func (b *SystemBackend) raftStoragePaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-force"][0]),
 			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-force"][1]),
 		},
+		{
+			Pattern: "storage/raft/snapshot-preview",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotPreview(),
+					Summary:  "Reports the differences between the provided snapshot and the current state of vault, without restoring it.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-preview"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-preview"][1]),
+		},
+		{
+			Pattern: "storage/raft/snapshot-selective",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotSelective(),
+					Summary:  "Restores the data of the given mounts from the provided snapshot.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-selective"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-selective"][1]),
+		},
+		{
+			Pattern: "storage/raft/usage",
+			Fields: map[string]*framework.FieldSchema{
+				"depth": {
+					Type:        framework.TypeInt,
+					Default:     2,
+					Description: "Number of path segments to aggregate storage usage by. Must be at least 2.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftUsage(),
+					Summary:  "Returns the storage used by the local raft database, broken down by prefix and category.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-usage"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-usage"][1]),
+		},
+		{
+			Pattern: "storage/raft/compact",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftCompactStatus(),
+					Summary:  "Returns the progress of the current or last compaction of the local raft database.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftCompact(),
+					Summary:  "Starts compacting the local raft database.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-compact"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-compact"][1]),
+		},
 		{
 			Pattern: "storage/raft/autopilot/state",
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -554,32 +616,14 @@ func (b *SystemBackend) handleStorageRaftSnapshotWrite(force bool) framework.Ope
 		if !ok {
 			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
 		}
-		if req.HTTPRequest == nil || req.HTTPRequest.Body == nil {
-			return nil, errors.New("no reader for request")
-		}
-
-		access := b.Core.seal.GetAccess()
-		if force {
-			access = nil
-		}
 
 		// We want to buffer the http request reader into a temp file here so we
 		// don't have to hold the full snapshot in memory. We also want to do
 		// the restore in two parts so we can restore the snapshot while the
 		// stateLock is write locked.
-		snapFile, cleanup, metadata, err := raftStorage.WriteSnapshotToTemp(req.HTTPRequest.Body, access)
-		switch {
-		case err == nil:
-		case strings.Contains(err.Error(), "failed to open the sealed hashes"):
-			switch b.Core.seal.BarrierType() {
-			case wrapping.WrapperTypeShamir:
-				return logical.ErrorResponse("could not verify hash file, possibly the snapshot is using a different set of unseal keys; use the snapshot-force API to bypass this check"), logical.ErrInvalidRequest
-			default:
-				return logical.ErrorResponse("could not verify hash file, possibly the snapshot is using a different autoseal key; use the snapshot-force API to bypass this check"), logical.ErrInvalidRequest
-			}
-		case err != nil:
-			b.Core.logger.Error("raft snapshot restore: failed to write snapshot", "error", err)
-			return nil, err
+		snapFile, cleanup, metadata, resp, err := b.writeRaftSnapshotToTemp(raftStorage, req, force)
+		if resp != nil || err != nil {
+			return resp, err
 		}
 
 		// We want to do this in a go routine so we can upgrade the lock and
@@ -663,6 +707,284 @@ func (b *SystemBackend) handleStorageRaftSnapshotWrite(force bool) framework.Ope
 	}
 }
 
+// writeRaftSnapshotToTemp writes the snapshot uploaded in the request to a
+// temporary file. Unless force is set, the snapshot must have been taken with
+// the same unseal or autoseal keys.
+func (b *SystemBackend) writeRaftSnapshotToTemp(raftStorage *raft.RaftBackend, req *logical.Request, force bool) (*os.File, func(), raftlib.SnapshotMeta, *logical.Response, error) {
+	var metadata raftlib.SnapshotMeta
+	if req.HTTPRequest == nil || req.HTTPRequest.Body == nil {
+		return nil, nil, metadata, nil, errors.New("no reader for request")
+	}
+
+	access := b.Core.seal.GetAccess()
+	if force {
+		access = nil
+	}
+
+	snapFile, cleanup, metadata, err := raftStorage.WriteSnapshotToTemp(req.HTTPRequest.Body, access)
+	switch {
+	case err == nil:
+	case strings.Contains(err.Error(), "failed to open the sealed hashes"):
+		switch b.Core.seal.BarrierType() {
+		case wrapping.WrapperTypeShamir:
+			return nil, nil, metadata, logical.ErrorResponse("could not verify hash file, possibly the snapshot is using a different set of unseal keys; use the snapshot-force API to bypass this check"), logical.ErrInvalidRequest
+		default:
+			return nil, nil, metadata, logical.ErrorResponse("could not verify hash file, possibly the snapshot is using a different autoseal key; use the snapshot-force API to bypass this check"), logical.ErrInvalidRequest
+		}
+	case err != nil:
+		b.Core.logger.Error("raft snapshot restore: failed to write snapshot", "error", err)
+		return nil, nil, metadata, nil, err
+	}
+
+	return snapFile, cleanup, metadata, nil, nil
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotPreview() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		raftStorage, ok := b.Core.underlyingPhysical.(*raft.RaftBackend)
+		if !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		snapFile, cleanup, metadata, resp, err := b.writeRaftSnapshotToTemp(raftStorage, req, false)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+		defer cleanup()
+
+		preview, err := b.Core.previewRaftSnapshot(ctx, raftStorage, snapFile)
+		if err != nil {
+			if errors.Is(err, logical.ErrInvalidRequest) {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			return nil, err
+		}
+		preview["index"] = metadata.Index
+		preview["term"] = metadata.Term
+
+		return &logical.Response{
+			Data: preview,
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotSelective() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		raftStorage, ok := b.Core.underlyingPhysical.(*raft.RaftBackend)
+		if !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+		if req.HTTPRequest == nil || req.HTTPRequest.URL == nil {
+			return nil, errors.New("no reader for request")
+		}
+
+		// The body holds the snapshot, so the options are passed in the query.
+		query := req.HTTPRequest.URL.Query()
+		mounts := query["mount"]
+		if len(mounts) == 0 {
+			return logical.ErrorResponse("at least one mount must be provided"), logical.ErrInvalidRequest
+		}
+		var dryRun bool
+		if raw := query.Get("dry_run"); raw != "" {
+			var err error
+			if dryRun, err = parseutil.ParseBool(raw); err != nil {
+				return logical.ErrorResponse("invalid value for dry_run: %v", err), logical.ErrInvalidRequest
+			}
+		}
+
+		snapFile, cleanup, _, resp, err := b.writeRaftSnapshotToTemp(raftStorage, req, false)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+		defer cleanup()
+
+		results, err := b.Core.restoreRaftSnapshotMounts(ctx, snapFile, mounts, dryRun)
+		if err != nil {
+			if errors.Is(err, logical.ErrInvalidRequest) {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			return nil, err
+		}
+		if !dryRun {
+			b.logger.Info("restored mounts from raft snapshot", "mounts", mounts)
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"dry_run": dryRun,
+				"mounts":  results,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftUsage() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		raftBackend := b.Core.getRaftBackend()
+		if raftBackend == nil {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		// Categories are derived from the first two path segments.
+		depth := d.Get("depth").(int)
+		if depth < 2 {
+			return logical.ErrorResponse("depth must be at least 2"), logical.ErrInvalidRequest
+		}
+
+		usage, err := raftBackend.StorageUsage(ctx, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		mountPaths := b.Core.storageUsageMountPaths()
+		categories := make(map[string]map[string]int64)
+		prefixes := make([]map[string]interface{}, 0, len(usage.Prefixes))
+		for _, p := range usage.Prefixes {
+			category, mountPath := storageUsageCategory(p.Prefix, mountPaths)
+			if categories[category] == nil {
+				categories[category] = map[string]int64{"keys": 0, "bytes": 0}
+			}
+			categories[category]["keys"] += p.Keys
+			categories[category]["bytes"] += p.Bytes
+
+			entry := map[string]interface{}{
+				"prefix":   p.Prefix,
+				"keys":     p.Keys,
+				"bytes":    p.Bytes,
+				"category": category,
+			}
+			if mountPath != "" {
+				entry["mount"] = mountPath
+			}
+			prefixes = append(prefixes, entry)
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"db_file_size": usage.DBFileSize,
+				"db_data_size": usage.DBDataSize,
+				"free_bytes":   usage.FreeBytes,
+				"free_pages":   usage.FreePages,
+				"total_keys":   usage.TotalKeys,
+				"total_bytes":  usage.TotalBytes,
+				"categories":   categories,
+				"prefixes":     prefixes,
+			},
+		}, nil
+	}
+}
+
+// storageUsageMountPaths maps the storage prefix of every mount to the path
+// it is mounted at.
+func (c *Core) storageUsageMountPaths() map[string]*MountEntry {
+	mounts := make(map[string]*MountEntry)
+
+	c.mountsLock.RLock()
+	if c.mounts != nil {
+		for _, entry := range c.mounts.Entries {
+			mounts[backendBarrierPrefix+entry.UUID+"/"] = entry
+		}
+	}
+	c.mountsLock.RUnlock()
+
+	c.authLock.RLock()
+	if c.auth != nil {
+		for _, entry := range c.auth.Entries {
+			mounts[credentialBarrierPrefix+entry.UUID+"/"] = entry
+		}
+	}
+	c.authLock.RUnlock()
+
+	return mounts
+}
+
+// storageUsageCategory returns the category of a storage prefix and, if the
+// prefix belongs to a mount, the path of the mount.
+func storageUsageCategory(prefix string, mounts map[string]*MountEntry) (string, string) {
+	switch {
+	case strings.HasPrefix(prefix, systemBarrierPrefix+expirationSubPath):
+		return "leases", ""
+	case strings.HasPrefix(prefix, systemBarrierPrefix+tokenSubPath):
+		return "tokens", ""
+	case strings.HasPrefix(prefix, systemBarrierPrefix):
+		return "system", ""
+	case strings.HasPrefix(prefix, "core/"):
+		return "core", ""
+	case strings.HasPrefix(prefix, auditBarrierPrefix):
+		return "audit", ""
+	case strings.HasPrefix(prefix, backendBarrierPrefix), strings.HasPrefix(prefix, credentialBarrierPrefix):
+		for mountPrefix, entry := range mounts {
+			if !strings.HasPrefix(prefix, mountPrefix) {
+				continue
+			}
+			mountPath := entry.Path
+			switch {
+			case entry.Type == identityMountType:
+				return "identity", mountPath
+			case entry.Table == credentialTableType:
+				return "auth", credentialRoutePrefix + mountPath
+			default:
+				return "secrets", mountPath
+			}
+		}
+		return "unknown_mounts", ""
+	default:
+		return "other", ""
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftCompactStatus() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		raftBackend := b.Core.getRaftBackend()
+		if raftBackend == nil {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		return compactionStatusResponse(raftBackend.CompactionStatus()), nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftCompact() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		raftBackend := b.Core.getRaftBackend()
+		if raftBackend == nil {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		if err := raftBackend.StartCompaction(); err != nil {
+			if errors.Is(err, raft.ErrCompactionInProgress) {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			return nil, err
+		}
+
+		b.logger.Info("raft database compaction started")
+		return compactionStatusResponse(raftBackend.CompactionStatus()), nil
+	}
+}
+
+func compactionStatusResponse(status raft.CompactionStatus) *logical.Response {
+	data := map[string]interface{}{
+		"state":       status.State,
+		"total_keys":  status.TotalKeys,
+		"keys_copied": status.KeysCopied,
+		"size_before": status.SizeBefore,
+		"size_after":  status.SizeAfter,
+	}
+	if !status.StartTime.IsZero() {
+		data["start_time"] = status.StartTime.Format(time.RFC3339)
+	}
+	if !status.EndTime.IsZero() {
+		data["end_time"] = status.EndTime.Format(time.RFC3339)
+	}
+	if status.Error != "" {
+		data["error"] = status.Error
+	}
+	return &logical.Response{
+		Data: data,
+	}
+}
+
 var sysRaftHelp = map[string][2]string{
 	"raft-bootstrap-challenge": {
 		"Creates a challenge for the new peer to be joined to the raft cluster.",
@@ -689,6 +1011,25 @@ var sysRaftHelp = map[string][2]string{
 		"Force restore a raft cluster snapshot",
 		"",
 	},
+	"raft-snapshot-preview": {
+		"Previews the restore of a raft cluster snapshot.",
+		`
+Reports the differences between the uploaded snapshot and the current state
+of vault without restoring it: the secrets engines and auth methods that
+restoring the snapshot would add or remove, and the number of keys under each
+storage prefix.
+		`,
+	},
+	"raft-snapshot-selective": {
+		"Restores some mounts of a raft cluster snapshot.",
+		`
+Restores the data of the given secrets engines and auth methods from the
+uploaded snapshot, leaving the rest of vault untouched. The mounts are passed
+in the "mount" query parameter, which can be repeated, and must still exist
+in the cluster. With the "dry_run" query parameter set, the number of entries
+that would be written and deleted are reported without changing anything.
+		`,
+	},
 	"raft-autopilot-state": {
 		"Returns the state of the raft cluster under integrated storage as seen by autopilot.",
 		"",
@@ -697,4 +1038,25 @@ var sysRaftHelp = map[string][2]string{
 		"Returns autopilot configuration.",
 		"",
 	},
+	"raft-usage": {
+		"Returns the storage used by the local raft database.",
+		`
+Walks the local raft database and reports the number of keys and bytes stored
+under each prefix, along with totals per category (secrets engines, auth
+methods, identity, leases, tokens, audit, core and system). Prefixes under
+which a mount stores its data are annotated with the mount path. The file
+size and the space held by free pages, which compaction reclaims, are also
+reported.
+		`,
+	},
+	"raft-compact": {
+		"Compacts the local raft database.",
+		`
+Rewrites the local raft database into a new file without its free pages and
+swaps it in place, shrinking the file on disk. Compaction runs in the
+background; reading this endpoint reports its progress. The database is
+locked while it is compacted, so the node cannot serve requests or apply raft
+logs until compaction completes.
+		`,
+	},
 }
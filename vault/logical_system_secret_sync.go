@@ -0,0 +1,403 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/secretsync"
+)
+
+// secretSyncPaths returns the paths used to manage secrets sync
+// destinations and the secrets associated with them.
+func (b *SystemBackend) secretSyncPaths() []*framework.Path {
+	destinationFields := map[string]*framework.FieldSchema{
+		"type": {
+			Type:        framework.TypeString,
+			Description: "Type of the destination: aws-sm, gcp-sm, azure-kv or kubernetes.",
+		},
+		"name": {
+			Type:        framework.TypeString,
+			Description: "Name of the destination.",
+		},
+	}
+
+	associationFields := map[string]*framework.FieldSchema{
+		"type": destinationFields["type"],
+		"name": destinationFields["name"],
+		"mount": {
+			Type:        framework.TypeString,
+			Description: "Path of the KV version 2 secrets engine holding the secret.",
+		},
+		"secret_name": {
+			Type:        framework.TypeString,
+			Description: "Path of the secret within the mount.",
+		},
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: "sync/destinations/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleSecretSyncDestinationsList,
+					Summary:  "List the secrets sync destinations.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["secret-sync-destinations"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["secret-sync-destinations"][1]),
+		},
+		{
+			Pattern: "sync/destinations/" + framework.GenericNameRegex("type") + "/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"type": destinationFields["type"],
+				"name": destinationFields["name"],
+				"config": {
+					Type: framework.TypeKVPairs,
+					Description: `Connection settings and credentials of the destination. Keys
+omitted on update keep their current value; set a key to an empty string to
+remove it.`,
+				},
+				"deletion_policy": {
+					Type: framework.TypeString,
+					Description: `What happens to a destination secret when its source secret
+is deleted or stops being synced: "delete" (default) or "retain".`,
+				},
+				"drift_policy": {
+					Type: framework.TypeString,
+					Description: `What happens when a destination secret is modified outside of
+Vault: "overwrite" (default) restores it, "report" only flags it.`,
+				},
+			},
+			ExistenceCheck: b.handleSecretSyncDestinationExistenceCheck,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleSecretSyncDestinationRead,
+					Summary:  "Read a secrets sync destination and the status of its secrets.",
+				},
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.handleSecretSyncDestinationWrite,
+					Summary:  "Create a secrets sync destination.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleSecretSyncDestinationWrite,
+					Summary:  "Update a secrets sync destination.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleSecretSyncDestinationDelete,
+					Summary:  "Delete a secrets sync destination.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["secret-sync-destination"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["secret-sync-destination"][1]),
+		},
+		{
+			Pattern: "sync/destinations/" + framework.GenericNameRegex("type") + "/" + framework.GenericNameRegex("name") + "/associations/set$",
+			Fields: map[string]*framework.FieldSchema{
+				"type":        associationFields["type"],
+				"name":        associationFields["name"],
+				"mount":       associationFields["mount"],
+				"secret_name": associationFields["secret_name"],
+				"remote_name": {
+					Type: framework.TypeString,
+					Description: `Name of the secret in the destination. Defaults to
+vault/<mount>/<secret_name>, adjusted to the characters the destination
+accepts.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleSecretSyncAssociationSet,
+					Summary:  "Start syncing a secret to a destination.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["secret-sync-associations"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["secret-sync-associations"][1]),
+		},
+		{
+			Pattern: "sync/destinations/" + framework.GenericNameRegex("type") + "/" + framework.GenericNameRegex("name") + "/associations/remove$",
+			Fields:  associationFields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleSecretSyncAssociationRemove,
+					Summary:  "Stop syncing a secret to a destination.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["secret-sync-associations"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["secret-sync-associations"][1]),
+		},
+		{
+			Pattern: "sync/destinations/" + framework.GenericNameRegex("type") + "/" + framework.GenericNameRegex("name") + "/reconcile$",
+			Fields:  destinationFields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleSecretSyncReconcile,
+					Summary:  "Sync every secret associated with a destination now.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["secret-sync-reconcile"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["secret-sync-reconcile"][1]),
+		},
+	}
+}
+
+// secretSyncErrorResponse converts a manager error into a response, turning
+// configuration problems into user errors.
+func secretSyncErrorResponse(err error) (*logical.Response, error) {
+	if errors.Is(err, secretsync.ErrInvalidConfig) {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, err
+}
+
+func (b *SystemBackend) secretSync() (*secretSyncManager, error) {
+	if b.Core.secretSync == nil {
+		return nil, errSecretSyncUnavailable
+	}
+	return b.Core.secretSync, nil
+}
+
+func (b *SystemBackend) handleSecretSyncDestinationsList(ctx context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	m, err := b.secretSync()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := m.listDestinations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(entries))
+	keyInfo := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		key := entry.Type + "/" + entry.Name
+		keys = append(keys, key)
+		keyInfo[key] = map[string]interface{}{
+			"type":         entry.Type,
+			"name":         entry.Name,
+			"associations": len(entry.Associations),
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (b *SystemBackend) handleSecretSyncDestinationExistenceCheck(ctx context.Context, _ *logical.Request, data *framework.FieldData) (bool, error) {
+	m, err := b.secretSync()
+	if err != nil {
+		return false, err
+	}
+
+	entry, err := m.getDestination(ctx, data.Get("type").(string), data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+func (b *SystemBackend) handleSecretSyncDestinationRead(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.secretSync()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := m.getDestination(ctx, data.Get("type").(string), data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: m.destinationResponseData(entry),
+	}, nil
+}
+
+func (m *secretSyncManager) destinationResponseData(entry *secretSyncDestinationEntry) map[string]interface{} {
+	sensitive := make(map[string]bool)
+	for _, k := range m.definitions[entry.Type].SensitiveConfig {
+		sensitive[k] = true
+	}
+	config := make(map[string]string, len(entry.Config))
+	for k, v := range entry.Config {
+		if sensitive[k] {
+			v = "<redacted>"
+		}
+		config[k] = v
+	}
+
+	associations := make(map[string]interface{}, len(entry.Associations))
+	for _, key := range entry.sortedAssociationKeys() {
+		assoc := entry.Associations[key]
+		status := map[string]interface{}{
+			"mount":          assoc.Mount,
+			"secret_name":    assoc.SecretPath,
+			"remote_name":    assoc.RemoteName,
+			"synced_version": assoc.SyncedVersion,
+			"drifted":        assoc.Drifted,
+			"last_error":     assoc.LastError,
+		}
+		if !assoc.LastSyncTime.IsZero() {
+			status["last_sync_time"] = assoc.LastSyncTime.Format(time.RFC3339)
+		}
+		if !assoc.LastDriftTime.IsZero() {
+			status["last_drift_time"] = assoc.LastDriftTime.Format(time.RFC3339)
+		}
+		associations[key] = status
+	}
+
+	return map[string]interface{}{
+		"type":            entry.Type,
+		"name":            entry.Name,
+		"config":          config,
+		"deletion_policy": entry.DeletionPolicy,
+		"drift_policy":    entry.DriftPolicy,
+		"associations":    associations,
+	}
+}
+
+func (b *SystemBackend) handleSecretSyncDestinationWrite(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.secretSync()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := m.writeDestination(ctx, data.Get("type").(string), data.Get("name").(string), func(entry *secretSyncDestinationEntry) error {
+		if configRaw, ok := data.GetOk("config"); ok {
+			for k, v := range configRaw.(map[string]string) {
+				if v == "" {
+					delete(entry.Config, k)
+					continue
+				}
+				entry.Config[k] = v
+			}
+		}
+
+		if policyRaw, ok := data.GetOk("deletion_policy"); ok {
+			switch policy := policyRaw.(string); policy {
+			case secretSyncDeletionPolicyDelete, secretSyncDeletionPolicyRetain:
+				entry.DeletionPolicy = policy
+			default:
+				return fmt.Errorf("%w: deletion_policy must be %q or %q", secretsync.ErrInvalidConfig,
+					secretSyncDeletionPolicyDelete, secretSyncDeletionPolicyRetain)
+			}
+		}
+
+		if policyRaw, ok := data.GetOk("drift_policy"); ok {
+			switch policy := policyRaw.(string); policy {
+			case secretSyncDriftPolicyOverwrite, secretSyncDriftPolicyReport:
+				entry.DriftPolicy = policy
+			default:
+				return fmt.Errorf("%w: drift_policy must be %q or %q", secretsync.ErrInvalidConfig,
+					secretSyncDriftPolicyOverwrite, secretSyncDriftPolicyReport)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return secretSyncErrorResponse(err)
+	}
+
+	return &logical.Response{
+		Data: m.destinationResponseData(entry),
+	}, nil
+}
+
+func (b *SystemBackend) handleSecretSyncDestinationDelete(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.secretSync()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.deleteDestination(ctx, data.Get("type").(string), data.Get("name").(string)); err != nil {
+		return secretSyncErrorResponse(err)
+	}
+	return nil, nil
+}
+
+func (b *SystemBackend) handleSecretSyncAssociationSet(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.secretSync()
+	if err != nil {
+		return nil, err
+	}
+
+	mount, secretPath, resp := secretSyncAssociationParams(data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	assoc, err := m.setAssociation(ctx, data.Get("type").(string), data.Get("name").(string), mount, secretPath, data.Get("remote_name").(string))
+	if err != nil {
+		return secretSyncErrorResponse(err)
+	}
+
+	resp = &logical.Response{
+		Data: map[string]interface{}{
+			"mount":          assoc.Mount,
+			"secret_name":    assoc.SecretPath,
+			"remote_name":    assoc.RemoteName,
+			"synced_version": assoc.SyncedVersion,
+		},
+	}
+	if assoc.LastError != "" {
+		resp.AddWarning("Initial sync failed and will be retried: " + assoc.LastError)
+	}
+	return resp, nil
+}
+
+func (b *SystemBackend) handleSecretSyncAssociationRemove(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.secretSync()
+	if err != nil {
+		return nil, err
+	}
+
+	mount, secretPath, resp := secretSyncAssociationParams(data)
+	if resp != nil {
+		return resp, nil
+	}
+
+	if err := m.removeAssociation(ctx, data.Get("type").(string), data.Get("name").(string), mount, secretPath); err != nil {
+		return secretSyncErrorResponse(err)
+	}
+	return nil, nil
+}
+
+func (b *SystemBackend) handleSecretSyncReconcile(ctx context.Context, _ *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	m, err := b.secretSync()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := m.reconcileDestination(ctx, data.Get("type").(string), data.Get("name").(string))
+	if err != nil {
+		return secretSyncErrorResponse(err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: m.destinationResponseData(entry),
+	}, nil
+}
+
+// secretSyncAssociationParams returns the normalized mount and secret path
+// of an association request.
+func secretSyncAssociationParams(data *framework.FieldData) (string, string, *logical.Response) {
+	mount := strings.Trim(data.Get("mount").(string), "/")
+	secretPath := strings.Trim(data.Get("secret_name").(string), "/")
+	if mount == "" {
+		return "", "", logical.ErrorResponse("missing mount")
+	}
+	if secretPath == "" {
+		return "", "", logical.ErrorResponse("missing secret_name")
+	}
+	return mount + "/", secretPath, nil
+}
@@ -0,0 +1,227 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// talkersPaths returns the paths reporting the top talkers of this node.
+func (b *SystemBackend) talkersPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "internal/counters/talkers$",
+			Fields: map[string]*framework.FieldSchema{
+				"window": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Length of the window to report on, up to 15 minutes.",
+					Default:     int(talkersDefaultWindow.Seconds()),
+				},
+				"limit": {
+					Type:        framework.TypeInt,
+					Description: "Number of talkers of each kind to return.",
+					Default:     talkersDefaultLimit,
+				},
+				"sort_by": {
+					Type:        framework.TypeString,
+					Description: `Order of the talkers: "requests" (default) or "latency".`,
+					Default:     TalkersSortByRequests,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathInternalCountersTalkers,
+					Summary:  "Backwards compatibility is not guaranteed for this API",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters-talkers"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["internal-counters-talkers"][1]),
+		},
+		{
+			Pattern: "internal/counters/talkers/config$",
+			Fields: map[string]*framework.FieldSchema{
+				"snapshot_threshold": {
+					Type:        framework.TypeInt,
+					Description: "Number of in-flight requests from which a snapshot of the top talkers is stored. 0 disables snapshots.",
+				},
+				"snapshot_cooldown": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Minimum time between two snapshots.",
+					Default:     int(talkersDefaultCooldown.Seconds()),
+				},
+				"snapshot_window": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Length of the window covered by snapshots, up to 15 minutes.",
+					Default:     int(talkersDefaultWindow.Seconds()),
+				},
+				"snapshot_limit": {
+					Type:        framework.TypeInt,
+					Description: "Number of talkers of each kind stored in snapshots.",
+					Default:     talkersDefaultLimit,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathInternalCountersTalkersConfigRead,
+					Summary:  "Backwards compatibility is not guaranteed for this API",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathInternalCountersTalkersConfigWrite,
+					Summary:  "Backwards compatibility is not guaranteed for this API",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters-talkers-config"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["internal-counters-talkers-config"][1]),
+		},
+		{
+			Pattern: "internal/counters/talkers/snapshots/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathInternalCountersTalkersSnapshotsList,
+					Summary:  "Backwards compatibility is not guaranteed for this API",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters-talkers-snapshots"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["internal-counters-talkers-snapshots"][1]),
+		},
+		{
+			Pattern: "internal/counters/talkers/snapshots/" + framework.GenericNameRegex("id"),
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: "Identifier of the snapshot.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathInternalCountersTalkersSnapshotRead,
+					Summary:  "Backwards compatibility is not guaranteed for this API",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters-talkers-snapshots"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["internal-counters-talkers-snapshots"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) pathInternalCountersTalkers(_ context.Context, _ *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	window := time.Duration(d.Get("window").(int)) * time.Second
+	if window <= 0 || window > talkersMaxWindow {
+		return logical.ErrorResponse("window must be between 1s and %s", talkersMaxWindow), logical.ErrInvalidRequest
+	}
+	limit := d.Get("limit").(int)
+	if limit <= 0 {
+		return logical.ErrorResponse("limit must be positive"), logical.ErrInvalidRequest
+	}
+	sortBy := d.Get("sort_by").(string)
+	if sortBy != TalkersSortByRequests && sortBy != TalkersSortByLatency {
+		return logical.ErrorResponse("sort_by must be %q or %q", TalkersSortByRequests, TalkersSortByLatency), logical.ErrInvalidRequest
+	}
+
+	report := b.Core.requestTalkers.top(window, limit, sortBy, time.Now())
+	report.InFlight = b.Core.inFlightReqData.InFlightReqCount.Load()
+
+	return &logical.Response{
+		Data: talkersReportResponseData(report),
+	}, nil
+}
+
+func talkersReportResponseData(report *TalkersReport) map[string]interface{} {
+	return map[string]interface{}{
+		"start_time":         report.StartTime.Format(time.RFC3339),
+		"end_time":           report.EndTime.Format(time.RFC3339),
+		"sort_by":            report.SortBy,
+		"in_flight_requests": report.InFlight,
+		"tokens":             report.Tokens,
+		"entities":           report.Entities,
+		"client_addresses":   report.ClientAddresses,
+	}
+}
+
+func (b *SystemBackend) pathInternalCountersTalkersConfigRead(_ context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	config, ok := b.Core.requestTalkers.getConfig()
+	if !ok {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: talkersConfigResponseData(config),
+	}, nil
+}
+
+func talkersConfigResponseData(config talkersConfig) map[string]interface{} {
+	if config.SnapshotCooldown == 0 {
+		config.SnapshotCooldown = talkersDefaultCooldown
+	}
+	if config.SnapshotWindow == 0 {
+		config.SnapshotWindow = talkersDefaultWindow
+	}
+	if config.SnapshotLimit == 0 {
+		config.SnapshotLimit = talkersDefaultLimit
+	}
+
+	return map[string]interface{}{
+		"snapshot_threshold": config.SnapshotThreshold,
+		"snapshot_cooldown":  int64(config.SnapshotCooldown.Seconds()),
+		"snapshot_window":    int64(config.SnapshotWindow.Seconds()),
+		"snapshot_limit":     config.SnapshotLimit,
+	}
+}
+
+func (b *SystemBackend) pathInternalCountersTalkersConfigWrite(ctx context.Context, _ *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, _ := b.Core.requestTalkers.getConfig()
+
+	if raw, ok := d.GetOk("snapshot_threshold"); ok {
+		config.SnapshotThreshold = raw.(int)
+	}
+	if raw, ok := d.GetOk("snapshot_cooldown"); ok {
+		config.SnapshotCooldown = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := d.GetOk("snapshot_window"); ok {
+		config.SnapshotWindow = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := d.GetOk("snapshot_limit"); ok {
+		config.SnapshotLimit = raw.(int)
+	}
+
+	switch {
+	case config.SnapshotThreshold < 0:
+		return logical.ErrorResponse("snapshot_threshold must not be negative"), logical.ErrInvalidRequest
+	case config.SnapshotCooldown < 0:
+		return logical.ErrorResponse("snapshot_cooldown must not be negative"), logical.ErrInvalidRequest
+	case config.SnapshotWindow < 0 || config.SnapshotWindow > talkersMaxWindow:
+		return logical.ErrorResponse("snapshot_window must be at most %s", talkersMaxWindow), logical.ErrInvalidRequest
+	case config.SnapshotLimit < 0:
+		return logical.ErrorResponse("snapshot_limit must not be negative"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.requestTalkers.setConfig(ctx, config); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: talkersConfigResponseData(config),
+	}, nil
+}
+
+func (b *SystemBackend) pathInternalCountersTalkersSnapshotsList(ctx context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	ids, err := b.Core.requestTalkers.listSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(ids), nil
+}
+
+func (b *SystemBackend) pathInternalCountersTalkersSnapshotRead(ctx context.Context, _ *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	report, err := b.Core.requestTalkers.getSnapshot(ctx, d.Get("id").(string))
+	if err != nil || report == nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: talkersReportResponseData(report),
+	}, nil
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -18,9 +19,11 @@ import (
 	"github.com/fatih/structs"
 	"github.com/go-test/deep"
 	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
 	semver "github.com/hashicorp/go-version"
 	"github.com/hashicorp/vault/audit"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/command/server"
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/namespace"
@@ -43,6 +46,8 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"remount",
 		"audit",
 		"audit/*",
+		"audit-hash-rotate/*",
+		"audit-test",
 		"raw",
 		"raw/*",
 		"replication/primary/secondary-token",
@@ -61,9 +66,13 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"leases/revoke-prefix/*",
 		"leases/revoke-force/*",
 		"leases/lookup/*",
+		"leases/tagged/",
 		"storage/raft/snapshot-auto/config/*",
 		"leases",
 		"internal/inspect/*",
+		"namespace-bundle/*",
+		"config/apply",
+		"config/apply/*",
 	}
 
 	b := testSystemBackend(t)
@@ -598,6 +607,77 @@ func TestSystemBackend_PathCapabilities(t *testing.T) {
 	nonRootCheckFunc(t, resp)
 }
 
+func TestSystemBackend_PathCapabilities_ExpandMounts(t *testing.T) {
+	_, b, rootToken := testCoreSystemBackend(t)
+
+	for _, path := range []string{"kv-a/", "kv-b/", "other/"} {
+		req := logical.TestRequest(t, logical.UpdateOperation, "mounts/"+path)
+		req.Data["type"] = "kv"
+		resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+		}
+	}
+
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		ClientToken: rootToken,
+		Path:        "capabilities-self",
+		Operation:   logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"paths":         []string{"kv-*", "+/data/foo", "other/foo", "missing/foo"},
+			"expand_mounts": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	expandedMounts := resp.Data["expanded_mounts"].(map[string][]string)
+
+	// "+" matches the path of every secret mount, but no auth mount
+	anyMount := expandedMounts["+/data/foo"]
+	for _, path := range []string{"kv-a/", "kv-b/", "other/", "sys/"} {
+		if !strutil.StrListContains(anyMount, path) {
+			t.Fatalf("expected %q in %v", path, anyMount)
+		}
+	}
+	if strutil.StrListContains(anyMount, "auth/token/") {
+		t.Fatalf("unexpected auth mount in %v", anyMount)
+	}
+	delete(expandedMounts, "+/data/foo")
+
+	expected := map[string][]string{
+		"kv-*":        {"kv-a/", "kv-b/"},
+		"other/foo":   {"other/"},
+		"missing/foo": {},
+	}
+	if diff := deep.Equal(expected, expandedMounts); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal([]string{"root"}, resp.Data["kv-*"]); diff != nil {
+		t.Fatal(diff)
+	}
+
+	paths := make([]string, maxCapabilitiesPaths+1)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("secret/%d", i)
+	}
+	resp, err = b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		ClientToken: rootToken,
+		Path:        "capabilities-self",
+		Operation:   logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"paths": paths,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response, got: %#v", resp)
+	}
+}
+
 func TestSystemBackend_Capabilities_BC(t *testing.T) {
 	testCapabilities(t, "capabilities")
 	testCapabilities(t, "capabilities-self")
@@ -2240,6 +2320,133 @@ func TestSystemBackend_auditHash(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_auditHashRotate(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "audit/foo")
+	req.Data["type"] = "noop"
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	hash := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		req := logical.TestRequest(t, logical.UpdateOperation, "audit-hash/foo")
+		req.Data = data
+		resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp
+	}
+	rotate := func(overlap string) {
+		t.Helper()
+		req := logical.TestRequest(t, logical.UpdateOperation, "audit-hash-rotate/foo")
+		req.Data["overlap"] = overlap
+		resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil || resp != nil {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+	}
+
+	resp = hash(map[string]interface{}{"inputs": []string{"bar", "baz"}})
+	original := resp.Data["hashes"].([]string)
+	if len(original) != 2 || original[0] == original[1] {
+		t.Fatalf("bad hashes: %v", original)
+	}
+	if _, ok := resp.Data["previous_hashes"]; ok {
+		t.Fatalf("previous hashes returned before a rotation: %#v", resp.Data)
+	}
+
+	rotate("1h")
+
+	resp = hash(map[string]interface{}{"input": "bar"})
+	if resp.Data["hash"] == original[0] {
+		t.Fatal("hash did not change after the rotation")
+	}
+	if resp.Data["previous_hash"] != original[0] {
+		t.Fatalf("expected previous hash %q, got %#v", original[0], resp.Data)
+	}
+	if resp.Data["previous_hash_expiration"] == nil {
+		t.Fatalf("missing previous hash expiration: %#v", resp.Data)
+	}
+
+	rotate("0")
+
+	resp = hash(map[string]interface{}{"inputs": []string{"bar", "baz"}})
+	if _, ok := resp.Data["previous_hashes"]; ok {
+		t.Fatalf("previous hashes returned without an overlap window: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "audit-hash/foo")
+	req.Data["input"] = "bar"
+	req.Data["inputs"] = []string{"baz"}
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error response, got resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestSystemBackend_auditTest(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+
+	for _, path := range []string{"audit/foo", "audit/bar"} {
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data["type"] = "noop"
+		resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil || resp != nil {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "audit-test")
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if !resp.Data["healthy"].(bool) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	devices := resp.Data["devices"].(map[string]interface{})
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices, got: %#v", devices)
+	}
+	foo := devices["foo/"].(map[string]interface{})
+	if foo["type"] != "noop" || !foo["success"].(bool) {
+		t.Fatalf("bad: %#v", foo)
+	}
+
+	// A single device can be tested
+	req = logical.TestRequest(t, logical.UpdateOperation, "audit-test")
+	req.Data["path"] = "bar"
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	devices = resp.Data["devices"].(map[string]interface{})
+	if _, ok := devices["bar/"]; !ok || len(devices) != 1 {
+		t.Fatalf("bad: %#v", devices)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "audit-test")
+	req.Data["path"] = "baz"
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an unknown device: resp: %#v, err: %v", resp, err)
+	}
+}
+
 func TestSystemBackend_enableAudit_invalid(t *testing.T) {
 	b := testSystemBackend(t)
 	req := logical.TestRequest(t, logical.UpdateOperation, "audit/foo")
@@ -3227,6 +3434,30 @@ func TestSystemBackend_ToolsHash(t *testing.T) {
 	req.Data["algorithm"] = "sha3-512"
 	doRequest(req, false, "f7cac5ad830422a5408b36a60a60620687be180765a3e2895bc3bdbd857c9e08246c83064d4e3612f0cb927f3ead208413ab98624bf7b0617af0f03f62080976")
 
+	// Test BLAKE2 and BLAKE3, unkeyed and keyed
+	req.Data["algorithm"] = "blake2b-256"
+	doRequest(req, false, "9ad992086a8d14adf5d8516c38785029661251102a64ebcff25310731fe1857d")
+
+	req.Data["algorithm"] = "blake3"
+	doRequest(req, false, "6589539add86d80ad66e9a12104dc414cdeaf3f2e20486ca63cba42324af4856")
+
+	req.Data["key"] = "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="
+	doRequest(req, false, "22c74b8f5354e90a8e149faf3891a08ebfe8df3dfae6730c5d4a578d223ffeb4")
+
+	req.Data["algorithm"] = "blake2b-256"
+	doRequest(req, false, "7acd8ff9fdfbe4ec90d700398c84d5b2b44055456e28b68e7a0dfa4f0d1435e1")
+
+	// Keys are only supported by BLAKE2 and BLAKE3
+	req.Data["algorithm"] = "sha2-256"
+	doRequest(req, true, "")
+	delete(req.Data, "key")
+
+	// Test streamed input
+	streamReq := logical.TestRequest(t, logical.UpdateOperation, "tools/hash/blake3")
+	streamReq.HTTPRequest = httptest.NewRequest(http.MethodPost, "/v1/sys/tools/hash/blake3?format=hex", strings.NewReader("the quick brown fox"))
+	streamReq.HTTPRequest.Header.Set("X-Vault-Hash-Key", "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=")
+	doRequest(streamReq, false, "22c74b8f5354e90a8e149faf3891a08ebfe8df3dfae6730c5d4a578d223ffeb4")
+
 	// Test bad input/format/algorithm
 	req.Data["format"] = "base92"
 	doRequest(req, true, "")
@@ -4433,6 +4664,107 @@ func TestHandlePoliciesPasswordGenerate(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("count", func(t *testing.T) {
+		storage := makeStorage(t, storageEntry(t, "testpolicy",
+			"mode = \"passphrase\"\n"+
+				"words = 4\n"+
+				"separator = \" \"\n"))
+
+		req := &logical.Request{
+			Storage: storage,
+		}
+
+		b := &SystemBackend{}
+
+		inputData := passwordPoliciesFieldData(map[string]interface{}{
+			"name":  "testpolicy",
+			"count": 5,
+		})
+		actualResp, err := b.handlePoliciesPasswordGenerate(context.Background(), req, inputData)
+		if err != nil {
+			t.Fatalf("no error expected, got: %s", err)
+		}
+		passwords := actualResp.Data["passwords"].([]string)
+		if len(passwords) != 5 {
+			t.Fatalf("expected 5 passwords, got %v", passwords)
+		}
+		for _, password := range passwords {
+			if words := strings.Split(password, " "); len(words) != 4 {
+				t.Fatalf("expected a passphrase of 4 words, got %q", password)
+			}
+		}
+
+		inputData = passwordPoliciesFieldData(map[string]interface{}{
+			"name":  "testpolicy",
+			"count": maxPasswordGenerateCount + 1,
+		})
+		_, err = b.handlePoliciesPasswordGenerate(context.Background(), req, inputData)
+		if err == nil {
+			t.Fatalf("err expected, got nil")
+		}
+	})
+}
+
+func TestHandlePoliciesPasswordTest(t *testing.T) {
+	storage := makeStorage(t, storageEntry(t, "testpolicy",
+		"length = 8\n"+
+			"rule \"charset\" {\n"+
+			"	charset=\"abcdefghij\"\n"+
+			"}\n"+
+			"rule \"charset\" {\n"+
+			"	charset=\"0123456789\"\n"+
+			"	min-chars=2\n"+
+			"}"))
+
+	type testCase struct {
+		password         string
+		expectedValid    bool
+		expectedFailures int
+	}
+
+	tests := map[string]testCase{
+		"valid": {
+			password:         "abcdef012",
+			expectedValid:    true,
+			expectedFailures: 0,
+		},
+		"too short": {
+			password:         "abc12",
+			expectedValid:    false,
+			expectedFailures: 1,
+		},
+		"missing digits and bad character": {
+			password:         "abcdefghiz",
+			expectedValid:    false,
+			expectedFailures: 2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := &logical.Request{
+				Storage: storage,
+			}
+
+			b := &SystemBackend{}
+
+			inputData := passwordPoliciesFieldData(map[string]interface{}{
+				"name":     "testpolicy",
+				"password": test.password,
+			})
+			actualResp, err := b.handlePoliciesPasswordTest(context.Background(), req, inputData)
+			if err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+			if actualResp.Data["valid"] != test.expectedValid {
+				t.Fatalf("expected valid to be %t, got %#v", test.expectedValid, actualResp.Data)
+			}
+			if failures := actualResp.Data["failures"].([]string); len(failures) != test.expectedFailures {
+				t.Fatalf("expected %d failures, got %v", test.expectedFailures, failures)
+			}
+		})
+	}
 }
 
 func assertTrue(t *testing.T, pass bool, f string, vals ...interface{}) {
@@ -4470,6 +4802,15 @@ func passwordPoliciesFieldData(raw map[string]interface{}) *framework.FieldData
 				Type:        framework.TypeString,
 				Description: "The password policy",
 			},
+			"count": {
+				Type:        framework.TypeInt,
+				Description: "The number of passwords to generate.",
+				Default:     1,
+			},
+			"password": {
+				Type:        framework.TypeString,
+				Description: "The candidate password to validate.",
+			},
 		},
 	}
 }
@@ -5113,3 +5454,43 @@ func TestSortVersionedPlugins(t *testing.T) {
 		})
 	}
 }
+
+func TestSystemBackend_ConfigReloadStatus(t *testing.T) {
+	core, b, _ := testCoreSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.ReadOperation, "config/reload-status")
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("expected no response before a reload, got: %#v", resp)
+	}
+
+	reloadTime := time.Now()
+	core.SetConfigReloadStatus(&ConfigReloadStatus{
+		Time: reloadTime,
+		Changes: []*server.ConfigChange{
+			{Setting: "log_level", Reloadable: true, Applied: true},
+			{Setting: "telemetry.statsd_address", Reloadable: true, Error: "dial failed"},
+			{Setting: "log_format"},
+		},
+	})
+
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp := map[string]interface{}{
+		"reload_time": reloadTime.Format(time.RFC3339Nano),
+		"changes": []map[string]interface{}{
+			{"setting": "log_level", "reloadable": true, "applied": true},
+			{"setting": "telemetry.statsd_address", "reloadable": true, "applied": false, "error": "dial failed"},
+			{"setting": "log_format", "reloadable": false, "applied": false},
+		},
+		"errors": []string{},
+	}
+	if diff := deep.Equal(resp.Data, exp); len(diff) > 0 {
+		t.Fatalf("bad: diff: %#v", diff)
+	}
+}
@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// wrappingTokensPaths returns the paths used to list the outstanding
+// response-wrapping tokens created by the caller and to revoke them.
+func (b *SystemBackend) wrappingTokensPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "wrapping/tokens/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleWrappingTokensList,
+					Summary:  "List the outstanding response-wrapping tokens created by the caller.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["wrapping-tokens"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["wrapping-tokens"][1]),
+		},
+		{
+			Pattern: "wrapping/tokens/revoke$",
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the response-wrapping token to revoke.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleWrappingTokensRevoke,
+					Summary:  "Revoke an outstanding response-wrapping token created by the caller.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["wrapping-tokens-revoke"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["wrapping-tokens-revoke"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleWrappingTokensList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	entries, err := b.Core.listWrappingTokens(ctx, req)
+	if err != nil {
+		return handleError(err)
+	}
+
+	now := time.Now()
+	accessors := make([]string, 0, len(entries))
+	keyInfo := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		accessors = append(accessors, entry.Accessor)
+		keyInfo[entry.Accessor] = map[string]interface{}{
+			"creation_path": entry.CreationPath,
+			"creation_time": entry.CreationTime.Format(time.RFC3339Nano),
+			"creation_ttl":  int64(entry.CreationTTL.Seconds()),
+			"ttl":           int64(entry.CreationTime.Add(entry.CreationTTL).Sub(now).Seconds()),
+		}
+	}
+	return logical.ListResponseWithInfo(accessors, keyInfo), nil
+}
+
+func (b *SystemBackend) handleWrappingTokensRevoke(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	accessor := data.Get("accessor").(string)
+	if accessor == "" {
+		return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+	}
+
+	err := b.Core.revokeWrappingToken(ctx, req, accessor)
+	if errors.Is(err, errWrappingTokenNotFound) {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
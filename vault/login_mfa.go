@@ -89,6 +89,23 @@ func (b *SystemBackend) loginMFAPaths() []*framework.Path {
 				},
 			},
 		},
+		{
+			Pattern: "mfa/step-up",
+			Fields: map[string]*framework.FieldSchema{
+				"mfa_payload": {
+					Type:        framework.TypeMap,
+					Description: "A map from MFA method ID to a slice of passcodes or an empty slice if the method does not use passcodes",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback:                  b.Core.loginMFABackend.handleMFAStepUp,
+					Summary:                   "Validates the given MFA methods for the calling token, satisfying the step-up enforcements using them",
+					ForwardPerformanceStandby: true,
+				},
+			},
+		},
 	}
 }
 
@@ -108,6 +125,14 @@ type MFABackend struct {
 
 type LoginMFABackend struct {
 	*MFABackend
+
+	// stepUpEnforcements holds the step-up enforcements of all namespaces,
+	// keyed by namespace ID and name, and is guarded by mfaLock.
+	stepUpEnforcements map[string]*mfaStepUpEnforcement
+
+	// stepUpAssertions records when tokens last validated each MFA method
+	// for step-up enforcements.
+	stepUpAssertions *cache.Cache
 }
 
 func loginMFASchemaFuncs() []func() *memdb.TableSchema {
@@ -119,7 +144,10 @@ func loginMFASchemaFuncs() []func() *memdb.TableSchema {
 
 func NewLoginMFABackend(core *Core, logger hclog.Logger) *LoginMFABackend {
 	b := NewMFABackend(core, logger, memDBLoginMFAConfigsTable, loginMFASchemaFuncs())
-	return &LoginMFABackend{b}
+	return &LoginMFABackend{
+		MFABackend:         b,
+		stepUpEnforcements: make(map[string]*mfaStepUpEnforcement),
+	}
 }
 
 func NewMFABackend(core *Core, logger hclog.Logger, prefix string, schemaFuncs []func() *memdb.TableSchema) *MFABackend {
@@ -164,6 +192,10 @@ func (b *LoginMFABackend) ResetLoginMFAMemDB() error {
 
 	b.db = db
 
+	b.mfaLock.Lock()
+	b.stepUpEnforcements = make(map[string]*mfaStepUpEnforcement)
+	b.mfaLock.Unlock()
+
 	return nil
 }
 
@@ -732,6 +764,7 @@ func (c *Core) teardownLoginMFA() error {
 		c.mfaResponseAuthQueueLock.Unlock()
 
 		c.loginMFABackend.usedCodes = nil
+		c.loginMFABackend.stepUpAssertions = nil
 
 		if err := c.loginMFABackend.ResetLoginMFAMemDB(); err != nil {
 			return err
@@ -2612,6 +2645,12 @@ func (b *LoginMFABackend) deleteMFAConfigByMethodID(ctx context.Context, configI
 		}
 	}
 
+	for _, e := range b.stepUpEnforcements {
+		if strutil.StrListContains(e.MFAMethodIDs, configID) {
+			return fmt.Errorf("methodID is still used by the step-up enforcement %q", e.Name)
+		}
+	}
+
 	// Delete the config from storage
 	entryIndex := prefix + configID
 	err = b.Core.systemBarrierView.Delete(ctx, entryIndex)
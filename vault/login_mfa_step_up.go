@@ -0,0 +1,605 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// mfaStepUpEnforcementPrefix is the storage prefix for persisting step-up
+	// MFA enforcements
+	mfaStepUpEnforcementPrefix = "login-mfa/step-up-enforcement/"
+
+	defaultStepUpMaxAge = 15 * time.Minute
+
+	// maxStepUpMaxAge bounds the max_age of enforcements, and is how long
+	// MFA assertions are remembered.
+	maxStepUpMaxAge = 24 * time.Hour
+)
+
+// mfaStepUpEnforcement requires tokens to have validated one of the MFA
+// methods within the last MaxAge to access the paths matching Paths, even
+// though they are already authenticated.
+type mfaStepUpEnforcement struct {
+	Name              string        `json:"name"`
+	NamespaceID       string        `json:"namespace_id"`
+	MFAMethodIDs      []string      `json:"mfa_method_ids"`
+	Paths             []string      `json:"paths"`
+	MaxAge            time.Duration `json:"max_age"`
+	IdentityGroupIDs  []string      `json:"identity_group_ids"`
+	IdentityEntityIDs []string      `json:"identity_entity_ids"`
+}
+
+func (e *mfaStepUpEnforcement) toResponseData() map[string]interface{} {
+	return map[string]interface{}{
+		"name":                e.Name,
+		"namespace_id":        e.NamespaceID,
+		"mfa_method_ids":      e.MFAMethodIDs,
+		"paths":               e.Paths,
+		"max_age":             int64(e.MaxAge.Seconds()),
+		"identity_group_ids":  e.IdentityGroupIDs,
+		"identity_entity_ids": e.IdentityEntityIDs,
+	}
+}
+
+// matchesPath returns whether the namespace-relative request path is
+// protected by the enforcement. Paths use the same globbing as ACL policies:
+// a "+" segment matches any single segment and a trailing "*" matches any
+// suffix.
+func (e *mfaStepUpEnforcement) matchesPath(reqPath string) bool {
+	for _, pattern := range e.Paths {
+		if stepUpPathMatch(pattern, reqPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func stepUpPathMatch(pattern, reqPath string) bool {
+	glob := strings.HasSuffix(pattern, "*")
+	pattern = strings.TrimSuffix(pattern, "*")
+
+	patternSegments := strings.Split(pattern, "/")
+	pathSegments := strings.Split(reqPath, "/")
+	if len(pathSegments) < len(patternSegments) || (!glob && len(pathSegments) != len(patternSegments)) {
+		return false
+	}
+
+	for i, segment := range patternSegments {
+		if glob && i == len(patternSegments)-1 {
+			return strings.HasPrefix(strings.Join(pathSegments[i:], "/"), segment)
+		}
+		if segment != "+" && segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// stepUpEnforcementKey indexes the enforcements across namespaces.
+func stepUpEnforcementKey(namespaceID, name string) string {
+	return namespaceID + "/" + name
+}
+
+// stepUpTokenKey identifies the token MFA assertions are bound to; batch
+// tokens don't have an accessor, so a hash of the token is used instead.
+func stepUpTokenKey(accessor, token string) string {
+	if accessor != "" {
+		return accessor
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "token-" + hex.EncodeToString(sum[:])
+}
+
+func mfaStepUpPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "mfa/step-up-enforcement/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name for this step-up enforcement configuration",
+					Required:    true,
+				},
+				"mfa_method_ids": {
+					Type:        framework.TypeStringSlice,
+					Description: "Array of Method IDs, any of which satisfies the enforcement",
+					Required:    true,
+				},
+				"paths": {
+					Type:        framework.TypeStringSlice,
+					Description: `Array of request paths requiring a fresh MFA assertion. A "+" segment matches any single segment, and a trailing "*" matches any suffix`,
+					Required:    true,
+				},
+				"max_age": {
+					Type:        framework.TypeDurationSecond,
+					Description: "How long a successful MFA validation satisfies the enforcement. Defaults to 15 minutes",
+				},
+				"identity_group_ids": {
+					Type:        framework.TypeStringSlice,
+					Description: "Array of identity group IDs the enforcement is restricted to",
+				},
+				"identity_entity_ids": {
+					Type:        framework.TypeStringSlice,
+					Description: "Array of identity entity IDs the enforcement is restricted to",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: i.handleMFAStepUpEnforcementRead,
+					Summary:  "Read the current step-up enforcement",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: i.handleMFAStepUpEnforcementUpdate,
+					Summary:  "Create or update a step-up enforcement",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: i.handleMFAStepUpEnforcementDelete,
+					Summary:  "Delete a step-up enforcement",
+				},
+			},
+		},
+		{
+			Pattern: "mfa/step-up-enforcement/?$",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: i.handleMFAStepUpEnforcementList,
+					Summary:  "List step-up enforcements",
+				},
+			},
+		},
+	}
+}
+
+func (i *IdentityStore) handleMFAStepUpEnforcementList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b := i.mfaBackend
+	b.mfaLock.RLock()
+	defer b.mfaLock.RUnlock()
+
+	var keys []string
+	keyInfo := make(map[string]interface{})
+	for _, e := range b.stepUpEnforcements {
+		if e.NamespaceID != ns.ID {
+			continue
+		}
+		keys = append(keys, e.Name)
+		keyInfo[e.Name] = e.toResponseData()
+	}
+	sort.Strings(keys)
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (i *IdentityStore) handleMFAStepUpEnforcementRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b := i.mfaBackend
+	b.mfaLock.RLock()
+	defer b.mfaLock.RUnlock()
+
+	e, ok := b.stepUpEnforcements[stepUpEnforcementKey(ns.ID, d.Get("name").(string))]
+	if !ok {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: e.toResponseData(),
+	}, nil
+}
+
+func (i *IdentityStore) handleMFAStepUpEnforcementUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := d.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing enforcement name"), nil
+	}
+
+	b := i.mfaBackend
+	b.mfaLock.Lock()
+	defer b.mfaLock.Unlock()
+
+	e := &mfaStepUpEnforcement{
+		Name:        name,
+		NamespaceID: ns.ID,
+		MaxAge:      defaultStepUpMaxAge,
+	}
+	if existing, ok := b.stepUpEnforcements[stepUpEnforcementKey(ns.ID, name)]; ok {
+		copied := *existing
+		e = &copied
+	}
+
+	if mfaMethodIDs, ok := d.GetOk("mfa_method_ids"); ok {
+		for _, mmid := range mfaMethodIDs.([]string) {
+			config, err := b.mfaConfigReadByMethodID(mmid)
+			if err != nil {
+				return nil, err
+			}
+			if config == nil {
+				return logical.ErrorResponse("one of the provided method ids doesn't exist"), nil
+			}
+
+			mfaNs, err := i.namespacer.NamespaceByID(ctx, config["namespace_id"].(string))
+			if err != nil {
+				return logical.ErrorResponse("failed to retrieve config namespace"), nil
+			}
+			if ns.ID != mfaNs.ID && !ns.HasParent(mfaNs) {
+				return logical.ErrorResponse("one of the provided method ids is in an incompatible namespace and can't be used"), nil
+			}
+		}
+		e.MFAMethodIDs = mfaMethodIDs.([]string)
+	}
+	if len(e.MFAMethodIDs) == 0 {
+		return logical.ErrorResponse("missing method ids"), nil
+	}
+
+	if paths, ok := d.GetOk("paths"); ok {
+		e.Paths = nil
+		for _, p := range paths.([]string) {
+			p = strings.TrimPrefix(strings.TrimSpace(p), "/")
+			if p == "" {
+				return logical.ErrorResponse("paths can't be empty"), nil
+			}
+			e.Paths = append(e.Paths, p)
+		}
+	}
+	if len(e.Paths) == 0 {
+		return logical.ErrorResponse("missing paths"), nil
+	}
+
+	if maxAge, ok := d.GetOk("max_age"); ok {
+		e.MaxAge = time.Duration(maxAge.(int)) * time.Second
+	}
+	if e.MaxAge <= 0 || e.MaxAge > maxStepUpMaxAge {
+		return logical.ErrorResponse(fmt.Sprintf("max_age must be positive and at most %s", maxStepUpMaxAge)), nil
+	}
+
+	if identityGroupIDs, ok := d.GetOk("identity_group_ids"); ok {
+		for _, groupID := range identityGroupIDs.([]string) {
+			group, err := i.MemDBGroupByID(groupID, true)
+			if err != nil {
+				return nil, err
+			}
+			if group == nil {
+				return logical.ErrorResponse("one of the provided group ids doesn't exist"), nil
+			}
+		}
+		e.IdentityGroupIDs = identityGroupIDs.([]string)
+	}
+
+	if identityEntityIDs, ok := d.GetOk("identity_entity_ids"); ok {
+		for _, entityID := range identityEntityIDs.([]string) {
+			entity, err := i.MemDBEntityByID(entityID, true)
+			if err != nil {
+				return nil, err
+			}
+			if entity == nil {
+				return logical.ErrorResponse("one of the provided entity ids doesn't exist"), nil
+			}
+		}
+		e.IdentityEntityIDs = identityEntityIDs.([]string)
+	}
+
+	barrierView, err := b.Core.barrierViewForNamespace(ns.ID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := logical.StorageEntryJSON(mfaStepUpEnforcementPrefix+name, e)
+	if err != nil {
+		return nil, err
+	}
+	if err := barrierView.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	b.stepUpEnforcements[stepUpEnforcementKey(ns.ID, name)] = e
+	return nil, nil
+}
+
+func (i *IdentityStore) handleMFAStepUpEnforcementDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := d.Get("name").(string)
+
+	b := i.mfaBackend
+	b.mfaLock.Lock()
+	defer b.mfaLock.Unlock()
+
+	barrierView, err := b.Core.barrierViewForNamespace(ns.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := barrierView.Delete(ctx, mfaStepUpEnforcementPrefix+name); err != nil {
+		return nil, err
+	}
+
+	delete(b.stepUpEnforcements, stepUpEnforcementKey(ns.ID, name))
+	return nil, nil
+}
+
+// loadMFAStepUpEnforcements loads the step-up MFA enforcements of the
+// namespace
+func (b *LoginMFABackend) loadMFAStepUpEnforcements(ctx context.Context, ns *namespace.Namespace) error {
+	barrierView, err := b.Core.barrierViewForNamespace(ns.ID)
+	if err != nil {
+		return fmt.Errorf("error getting namespace view, namespaceid %s, error %w", ns.ID, err)
+	}
+	existing, err := barrierView.List(ctx, mfaStepUpEnforcementPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list MFA step-up enforcements for namespace %s: %w", ns.Path, err)
+	}
+
+	b.mfaLock.Lock()
+	defer b.mfaLock.Unlock()
+
+	for _, key := range existing {
+		entry, err := barrierView.Get(ctx, mfaStepUpEnforcementPrefix+key)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+
+		var e mfaStepUpEnforcement
+		if err := jsonutil.DecodeJSON(entry.Value, &e); err != nil {
+			return fmt.Errorf("failed to decode MFA step-up enforcement %q: %w", key, err)
+		}
+		b.stepUpEnforcements[stepUpEnforcementKey(e.NamespaceID, e.Name)] = &e
+	}
+
+	return nil
+}
+
+// stepUpEnforcementsForRequest returns the enforcements of the namespace of
+// the request, or of its ancestors, which protect the request path.
+func (b *LoginMFABackend) stepUpEnforcementsForRequest(ctx context.Context, ns *namespace.Namespace, reqPath string) ([]*mfaStepUpEnforcement, error) {
+	b.mfaLock.RLock()
+	var candidates []*mfaStepUpEnforcement
+	for _, e := range b.stepUpEnforcements {
+		if e.matchesPath(reqPath) {
+			candidates = append(candidates, e)
+		}
+	}
+	b.mfaLock.RUnlock()
+
+	var matched []*mfaStepUpEnforcement
+	for _, e := range candidates {
+		eNS, err := b.Core.NamespaceByID(ctx, e.NamespaceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the namespace of MFA step-up enforcement %q: %w", e.Name, err)
+		}
+		if eNS == nil || (eNS.ID != ns.ID && !ns.HasParent(eNS)) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Name < matched[j].Name
+	})
+	return matched, nil
+}
+
+// stepUpAppliesToEntity returns whether the enforcement, which may be restricted to
+// entities and groups, applies to the entity.
+func (c *Core) stepUpAppliesToEntity(e *mfaStepUpEnforcement, entity *identity.Entity) (bool, error) {
+	if len(e.IdentityEntityIDs) == 0 && len(e.IdentityGroupIDs) == 0 {
+		return true, nil
+	}
+	if strutil.StrListContains(e.IdentityEntityIDs, entity.ID) {
+		return true, nil
+	}
+
+	directGroups, inheritedGroups, err := c.identityStore.groupsByEntityID(entity.ID)
+	if err != nil {
+		return false, fmt.Errorf("error on retrieving groups by entityID in MFA: %w", err)
+	}
+	for _, g := range append(directGroups, inheritedGroups...) {
+		if strutil.StrListContains(e.IdentityGroupIDs, g.ID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// stepUpSatisfied returns whether the token validated one of the methods of
+// the enforcement recently enough.
+func (b *LoginMFABackend) stepUpSatisfied(tokenKey string, e *mfaStepUpEnforcement, now time.Time) bool {
+	assertions := b.stepUpAssertions
+	if assertions == nil {
+		return false
+	}
+	for _, methodID := range e.MFAMethodIDs {
+		raw, ok := assertions.Get(tokenKey + "/" + methodID)
+		if !ok {
+			continue
+		}
+		if now.Sub(raw.(time.Time)) <= e.MaxAge {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *LoginMFABackend) recordStepUpAssertion(tokenKey, methodID string, now time.Time) {
+	if assertions := b.stepUpAssertions; assertions != nil {
+		assertions.Set(tokenKey+"/"+methodID, now, maxStepUpMaxAge)
+	}
+}
+
+// validateStepUpMFA validates the MFA credentials against the methods of the
+// enforcements, and records an assertion for each validated method.
+func (c *Core) validateStepUpMFA(ctx context.Context, tokenKey string, enforcements []*mfaStepUpEnforcement, entity *identity.Entity, remoteAddr string, mfaCreds logical.MFACreds) ([]string, error) {
+	var retErr error
+	var validated []string
+	now := time.Now()
+
+	for _, e := range enforcements {
+		if c.loginMFABackend.stepUpSatisfied(tokenKey, e, now) {
+			continue
+		}
+
+		satisfied := false
+		for _, methodID := range e.MFAMethodIDs {
+			creds, ok := mfaCreds[methodID]
+			if !ok {
+				continue
+			}
+			if strutil.StrListContains(validated, methodID) {
+				satisfied = true
+				break
+			}
+			if err := c.validateLoginMFAInternal(ctx, methodID, entity, remoteAddr, creds); err != nil {
+				retErr = multierror.Append(retErr, err)
+				continue
+			}
+			c.loginMFABackend.recordStepUpAssertion(tokenKey, methodID, now)
+			validated = append(validated, methodID)
+			satisfied = true
+			break
+		}
+		if !satisfied {
+			return validated, multierror.Append(retErr, fmt.Errorf("failed to satisfy step-up enforcement %q with any of the MFA methods %v", e.Name, e.MFAMethodIDs))
+		}
+	}
+
+	return validated, nil
+}
+
+// checkStepUpMFA enforces the step-up MFA enforcements protecting the request
+// path. The MFA credentials can be supplied with the request, in the
+// X-Vault-MFA header, or beforehand on the sys/mfa/step-up endpoint. Like
+// Sentinel policies, step-up enforcements don't apply to root tokens.
+func (c *Core) checkStepUpMFA(ctx context.Context, req *logical.Request, te *logical.TokenEntry, entity *identity.Entity) error {
+	if c.loginMFABackend == nil || te == nil {
+		return nil
+	}
+	switch req.Path {
+	case "sys/mfa/step-up", "sys/mfa/validate":
+		return nil
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	enforcements, err := c.loginMFABackend.stepUpEnforcementsForRequest(ctx, ns, req.Path)
+	if err != nil {
+		return err
+	}
+	if len(enforcements) == 0 || strutil.StrListContains(te.Policies, "root") {
+		return nil
+	}
+	if entity == nil {
+		return multierror.Append(errors.New("step-up MFA requires a token with an entity"), logical.ErrPermissionDenied)
+	}
+
+	tokenKey := stepUpTokenKey(req.ClientTokenAccessor, req.ClientToken)
+	now := time.Now()
+	var unsatisfied []*mfaStepUpEnforcement
+	for _, e := range enforcements {
+		applies, err := c.stepUpAppliesToEntity(e, entity)
+		if err != nil {
+			return err
+		}
+		if applies && !c.loginMFABackend.stepUpSatisfied(tokenKey, e, now) {
+			unsatisfied = append(unsatisfied, e)
+		}
+	}
+	if len(unsatisfied) == 0 {
+		return nil
+	}
+
+	// Assertions are recorded on the active node
+	if c.perfStandby {
+		return logical.ErrPerfStandbyPleaseForward
+	}
+
+	if len(req.MFACreds) > 0 {
+		if _, err := c.validateStepUpMFA(ctx, tokenKey, unsatisfied, entity, req.Connection.RemoteAddr, req.MFACreds); err != nil {
+			return multierror.Append(err, logical.ErrPermissionDenied)
+		}
+		return nil
+	}
+
+	var methodIDs []string
+	for _, e := range unsatisfied {
+		for _, methodID := range e.MFAMethodIDs {
+			methodIDs = strutil.AppendIfMissing(methodIDs, methodID)
+		}
+	}
+	return multierror.Append(fmt.Errorf("step-up MFA required: validate one of the MFA methods %v with the X-Vault-MFA header or on sys/mfa/step-up", methodIDs), logical.ErrPermissionDenied)
+}
+
+func (b *LoginMFABackend) handleMFAStepUp(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if req.ClientToken == "" || req.EntityID == "" {
+		return logical.ErrorResponse("step-up MFA requires a token with an entity"), logical.ErrInvalidRequest
+	}
+
+	var mfaCreds logical.MFACreds
+	if err := mapstructure.Decode(d.Get("mfa_payload"), &mfaCreds); err != nil || len(mfaCreds) == 0 {
+		return logical.ErrorResponse("invalid mfa payload"), nil
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entity, _, err := b.Core.fetchEntityAndDerivedPolicies(ctx, ns, req.EntityID, true)
+	if err != nil || entity == nil {
+		return nil, fmt.Errorf("MFA validation failed. entity not found: %v", err)
+	}
+
+	// Validate each method of the payload, so that the token can step up
+	// ahead of the requests needing it.
+	var enforcements []*mfaStepUpEnforcement
+	for methodID := range mfaCreds {
+		enforcements = append(enforcements, &mfaStepUpEnforcement{
+			Name:         methodID,
+			MFAMethodIDs: []string{methodID},
+		})
+	}
+
+	tokenKey := stepUpTokenKey(req.ClientTokenAccessor, req.ClientToken)
+	validated, err := b.Core.validateStepUpMFA(ctx, tokenKey, enforcements, entity, req.Connection.RemoteAddr, mfaCreds)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("MFA validation failed: %s", err.Error())), logical.ErrPermissionDenied
+	}
+
+	sort.Strings(validated)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"validated_method_ids": validated,
+		},
+	}, nil
+}
@@ -0,0 +1,58 @@
+package vault
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStepUpPathMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"pki/root/+", "pki/root/generate", true},
+		{"pki/root/+", "pki/root/generate/internal", false},
+		{"pki/root/+", "pki/root", false},
+		{"pki/root/*", "pki/root/generate/internal", true},
+		{"pki/root*", "pki/root-ca/issue", true},
+		{"sys/policies/+/+", "sys/policies/acl/admin", true},
+		{"sys/policies/+/+", "sys/policies/acl", false},
+		{"+/config", "secret/config", true},
+		{"secret/data", "secret/data", true},
+		{"secret/data", "secret/data/foo", false},
+	}
+
+	for _, tc := range cases {
+		if got := stepUpPathMatch(tc.pattern, tc.path); got != tc.match {
+			t.Errorf("stepUpPathMatch(%q, %q) = %v, expected %v", tc.pattern, tc.path, got, tc.match)
+		}
+	}
+}
+
+func TestLoginMFABackend_StepUpSatisfied(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	b := c.loginMFABackend
+
+	e := &mfaStepUpEnforcement{
+		Name:         "pki-root",
+		MFAMethodIDs: []string{"method-a", "method-b"},
+		MaxAge:       5 * time.Minute,
+	}
+
+	now := time.Now()
+	if b.stepUpSatisfied("accessor", e, now) {
+		t.Fatal("expected enforcement not to be satisfied without an assertion")
+	}
+
+	b.recordStepUpAssertion("accessor", "method-b", now.Add(-time.Minute))
+	if !b.stepUpSatisfied("accessor", e, now) {
+		t.Fatal("expected a recent assertion to satisfy the enforcement")
+	}
+	if b.stepUpSatisfied("other-accessor", e, now) {
+		t.Fatal("expected assertions to be bound to the token")
+	}
+	if b.stepUpSatisfied("accessor", e, now.Add(5*time.Minute)) {
+		t.Fatal("expected a stale assertion not to satisfy the enforcement")
+	}
+}
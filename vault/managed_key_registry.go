@@ -2,16 +2,608 @@
 
 package vault
 
-// managedKeyRegistrySubPath is the storage prefix used by the registry.
-// We need to define the constant even though managed keys is a Vault Enterprise
-// feature in order to set up seal wrapping in the SystemBackend.
-const managedKeyRegistrySubPath = "managed-key-registry/"
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/managedkeys"
+)
+
+const (
+	// managedKeyRegistrySubPath is the storage prefix used by the registry.
+	// It holds the credentials of the key management systems and is seal
+	// wrapped.
+	managedKeyRegistrySubPath = "managed-key-registry/"
+
+	managedKeyPrefix     = "keys/"
+	managedKeyUUIDPrefix = "uuids/"
+
+	managedKeyUsageSign   = "sign"
+	managedKeyUsageVerify = "verify"
+)
+
+var (
+	errManagedKeyRegistryUnavailable = errors.New("managed key registry is not available on this node")
+	errManagedKeyNotFound            = errors.New("managed key not found")
+)
+
+// managedKeyUsages maps the usages that can be set on a managed key to the
+// usages requested by secrets engines. Keys are backed by asymmetric
+// signing keys, so only signing and verification can be allowed.
+var managedKeyUsages = map[string]logical.KeyUsage{
+	managedKeyUsageSign:   logical.KeyUsageSign,
+	managedKeyUsageVerify: logical.KeyUsageVerify,
+}
+
+// managedKeyEntry is the stored configuration of a managed key.
+type managedKeyEntry struct {
+	Type   string            `json:"type"`
+	Name   string            `json:"name"`
+	UUID   string            `json:"uuid"`
+	Config map[string]string `json:"config"`
+	Usages []string          `json:"usages"`
+
+	// AnyMount allows every mount to use the key. Otherwise the key must be
+	// listed in the allowed_managed_keys of the mount.
+	AnyMount bool `json:"any_mount"`
+
+	// AllowGenerateKey lets the registry create the key in its key
+	// management system if it cannot be found there.
+	AllowGenerateKey bool `json:"allow_generate_key"`
+
+	// LastHealthCheck and LastHealthError record the result of the last
+	// test of the key.
+	LastHealthCheck time.Time `json:"last_health_check,omitempty"`
+	LastHealthError string    `json:"last_health_error,omitempty"`
+}
+
+func (e *managedKeyEntry) allows(usage string) bool {
+	return strutil.StrListContains(e.Usages, usage)
+}
+
+// managedKeyRegistry stores managed keys and instantiates them on behalf of
+// secrets engines.
+type managedKeyRegistry struct {
+	core   *Core
+	logger log.Logger
+	view   *BarrierView
+
+	definitions map[string]managedkeys.Definition
+
+	// l serializes configuration changes and protects keys, the
+	// instantiated keys by UUID.
+	l    sync.Mutex
+	keys map[string]managedkeys.Key
+}
+
+func newManagedKeyRegistry(c *Core, logger log.Logger, view *BarrierView) *managedKeyRegistry {
+	return &managedKeyRegistry{
+		core:        c,
+		logger:      logger,
+		view:        view,
+		definitions: managedkeys.BuiltinDefinitions(),
+		keys:        make(map[string]managedkeys.Key),
+	}
+}
 
 func (c *Core) setupManagedKeyRegistry() error {
-	// Nothing to do, the registry is only used by enterprise features
+	logger := c.baseLogger.Named("managed-keys")
+	c.AddLogger(logger)
+
+	c.managedKeyRegistry = newManagedKeyRegistry(c, logger, c.systemBarrierView.SubView(managedKeyRegistrySubPath))
 	return nil
 }
 
+func (c *Core) stopManagedKeyRegistry() {
+	// preSeal may run before setupManagedKeyRegistry got a chance to
+	// complete.
+	if c.managedKeyRegistry != nil {
+		c.managedKeyRegistry.closeKeys()
+	}
+
+	c.managedKeyRegistry = nil
+}
+
+// ReloadManagedKeyRegistryConfig closes the instantiated keys so that they
+// are created again using the kms_library stanzas of the new configuration.
 func (c *Core) ReloadManagedKeyRegistryConfig() {
-	// Nothing to do, the registry is only used by enterprise features
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	if c.managedKeyRegistry != nil {
+		c.managedKeyRegistry.closeKeys()
+	}
+}
+
+func (r *managedKeyRegistry) closeKeys() {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	for id, key := range r.keys {
+		if err := key.Close(); err != nil {
+			r.logger.Warn("failed to close managed key", "uuid", id, "error", err)
+		}
+		delete(r.keys, id)
+	}
+}
+
+// libraries returns the kms_library stanzas of the server configuration,
+// keyed by name.
+func (r *managedKeyRegistry) libraries() map[string]string {
+	libraries := make(map[string]string)
+	conf, ok := r.core.rawConfig.Load().(*server.Config)
+	if !ok || conf == nil {
+		return libraries
+	}
+	for _, lib := range conf.KMSLibraries {
+		libraries[lib.Name] = lib.Library
+	}
+	return libraries
+}
+
+func (r *managedKeyRegistry) list(ctx context.Context, typ string) ([]string, error) {
+	names, err := r.view.List(ctx, managedKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, name := range names {
+		entry, err := r.get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil && entry.Type == typ {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (r *managedKeyRegistry) get(ctx context.Context, name string) (*managedKeyEntry, error) {
+	raw, err := r.view.Get(ctx, managedKeyPrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry managedKeyEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, err
+	}
+	if entry.Config == nil {
+		entry.Config = make(map[string]string)
+	}
+	return &entry, nil
+}
+
+func (r *managedKeyRegistry) getByUUID(ctx context.Context, id string) (*managedKeyEntry, error) {
+	raw, err := r.view.Get(ctx, managedKeyUUIDPrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	return r.get(ctx, string(raw.Value))
+}
+
+func (r *managedKeyRegistry) put(ctx context.Context, entry *managedKeyEntry) error {
+	raw, err := logical.StorageEntryJSON(managedKeyPrefix+entry.Name, entry)
+	if err != nil {
+		return err
+	}
+	return r.view.Put(ctx, raw)
+}
+
+// write creates or updates a managed key. The update function is applied
+// to the stored entry, or to a new entry if the key does not exist yet.
+// Keys are instantiated before being stored so that configuration problems
+// are reported right away.
+func (r *managedKeyRegistry) write(ctx context.Context, typ, name string, update func(*managedKeyEntry) error) (*managedKeyEntry, error) {
+	def, ok := r.definitions[typ]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown managed key type %q", managedkeys.ErrInvalidConfig, typ)
+	}
+
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	entry, err := r.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	created := entry == nil
+	switch {
+	case created:
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		entry = &managedKeyEntry{
+			Type:   typ,
+			Name:   name,
+			UUID:   id,
+			Config: make(map[string]string),
+			Usages: []string{managedKeyUsageSign, managedKeyUsageVerify},
+		}
+	case entry.Type != typ:
+		return nil, fmt.Errorf("%w: a managed key named %q already exists with type %q", managedkeys.ErrInvalidConfig, name, entry.Type)
+	}
+
+	if err := update(entry); err != nil {
+		return nil, err
+	}
+
+	key, err := def.Factory(ctx, entry.Config, r.libraries(), r.logger.With("type", typ, "name", name))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.put(ctx, entry); err != nil {
+		key.Close()
+		return nil, err
+	}
+	if created {
+		if err := r.view.Put(ctx, &logical.StorageEntry{
+			Key:   managedKeyUUIDPrefix + entry.UUID,
+			Value: []byte(entry.Name),
+		}); err != nil {
+			key.Close()
+			return nil, err
+		}
+	}
+
+	// Drop the instantiated key so the new configuration takes effect
+	key.Close()
+	if old, ok := r.keys[entry.UUID]; ok {
+		old.Close()
+		delete(r.keys, entry.UUID)
+	}
+	return entry, nil
+}
+
+// delete removes a managed key. Deleting a key that does not exist is not
+// an error; deleting a key that mounts are allowed to use is.
+func (r *managedKeyRegistry) delete(ctx context.Context, typ, name string) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	entry, err := r.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return nil
+	}
+	if entry.Type != typ {
+		return fmt.Errorf("%w: managed key %q has type %q", managedkeys.ErrInvalidConfig, name, entry.Type)
+	}
+	if mounts := r.mountsAllowing(entry); len(mounts) > 0 {
+		return fmt.Errorf("%w: managed key %q is in the allowed_managed_keys of mounts %s", managedkeys.ErrInvalidConfig, name, strings.Join(mounts, ", "))
+	}
+
+	if err := r.view.Delete(ctx, managedKeyUUIDPrefix+entry.UUID); err != nil {
+		return err
+	}
+	if err := r.view.Delete(ctx, managedKeyPrefix+name); err != nil {
+		return err
+	}
+
+	if key, ok := r.keys[entry.UUID]; ok {
+		key.Close()
+		delete(r.keys, entry.UUID)
+	}
+	return nil
+}
+
+// mountsAllowing returns the paths of the mounts listing the key in their
+// allowed_managed_keys.
+func (r *managedKeyRegistry) mountsAllowing(entry *managedKeyEntry) []string {
+	var paths []string
+	collect := func(table *MountTable) {
+		if table == nil {
+			return
+		}
+		for _, mountEntry := range table.Entries {
+			for _, allowed := range mountEntry.Config.AllowedManagedKeys {
+				if allowed == entry.Name || allowed == entry.UUID {
+					paths = append(paths, mountEntry.Path)
+					break
+				}
+			}
+		}
+	}
+
+	r.core.mountsLock.RLock()
+	collect(r.core.mounts)
+	r.core.mountsLock.RUnlock()
+
+	r.core.authLock.RLock()
+	collect(r.core.auth)
+	r.core.authLock.RUnlock()
+
+	return paths
+}
+
+// key returns the instantiated key of an entry, creating it if needed. A
+// key missing from its key management system is generated if the entry
+// allows it.
+func (r *managedKeyRegistry) key(ctx context.Context, entry *managedKeyEntry) (managedkeys.Key, error) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	if key, ok := r.keys[entry.UUID]; ok {
+		return key, nil
+	}
+
+	def, ok := r.definitions[entry.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown managed key type %q", entry.Type)
+	}
+	logger := r.logger.With("type", entry.Type, "name", entry.Name)
+	key, err := def.Factory(ctx, entry.Config, r.libraries(), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.AllowGenerateKey {
+		if _, err := key.PublicKey(ctx); errors.Is(err, managedkeys.ErrKeyNotFound) {
+			logger.Info("generating managed key")
+			if err := key.GenerateKey(ctx); err != nil {
+				key.Close()
+				return nil, fmt.Errorf("failed to generate key: %w", err)
+			}
+		}
+	}
+
+	r.keys[entry.UUID] = key
+	return key, nil
+}
+
+// test checks that the key can sign and that its signatures verify against
+// its public key. The result is recorded on the entry.
+func (r *managedKeyRegistry) test(ctx context.Context, typ, name string, hash crypto.Hash, usePSS bool) (*managedKeyEntry, error) {
+	entry, err := r.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.Type != typ {
+		return nil, nil
+	}
+
+	testErr := r.testKey(ctx, entry, hash, usePSS)
+
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	// Reload the entry as it may have changed while the test was running
+	current, err := r.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.UUID != entry.UUID {
+		return nil, nil
+	}
+	current.LastHealthCheck = time.Now().UTC()
+	current.LastHealthError = ""
+	if testErr != nil {
+		current.LastHealthError = testErr.Error()
+	}
+	if err := r.put(ctx, current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+func (r *managedKeyRegistry) testKey(ctx context.Context, entry *managedKeyEntry, hash crypto.Hash, usePSS bool) error {
+	key, err := r.key(ctx, entry)
+	if err != nil {
+		return err
+	}
+	public, err := key.PublicKey(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get public key: %w", err)
+	}
+
+	digest := make([]byte, hash.Size())
+	if _, err := io.ReadFull(r.core.secureRandomReader, digest); err != nil {
+		return err
+	}
+	var opts crypto.SignerOpts = hash
+	if _, ok := public.(*rsa.PublicKey); ok && usePSS {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	}
+	sig, err := key.Sign(ctx, digest, opts)
+	if err != nil {
+		return fmt.Errorf("failed to sign: %w", err)
+	}
+	valid, err := managedkeys.Verify(public, digest, sig, opts)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("signature does not verify against the public key")
+	}
+	return nil
+}
+
+// mountKey returns the entry of a managed key that the mount is allowed to
+// use, looking it up by name or UUID.
+func (r *managedKeyRegistry) mountKey(ctx context.Context, mountEntry *MountEntry, ref string, byUUID bool) (*managedKeyEntry, error) {
+	var entry *managedKeyEntry
+	var err error
+	if byUUID {
+		entry, err = r.getByUUID(ctx, ref)
+	} else {
+		entry, err = r.get(ctx, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %q", errManagedKeyNotFound, ref)
+	}
+
+	if entry.AnyMount {
+		return entry, nil
+	}
+	if mountEntry != nil {
+		for _, allowed := range mountEntry.Config.AllowedManagedKeys {
+			if allowed == entry.Name || allowed == entry.UUID {
+				return entry, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("managed key %q is not allowed on this mount; add it to the allowed_managed_keys of the mount or set any_mount on the key", entry.Name)
+}
+
+// withManagedKey instantiates a managed key the mount is allowed to use and
+// passes it to f.
+func (d dynamicSystemView) withManagedKey(ctx context.Context, ref string, byUUID bool, f func(context.Context, *managedSigningKey) error) error {
+	r := d.core.managedKeyRegistry
+	if r == nil {
+		return errManagedKeyRegistryUnavailable
+	}
+
+	entry, err := r.mountKey(ctx, d.mountEntry, ref, byUUID)
+	if err != nil {
+		return err
+	}
+	key, err := r.key(ctx, entry)
+	if err != nil {
+		return err
+	}
+	return f(ctx, &managedSigningKey{entry: entry, key: key})
+}
+
+func (d dynamicSystemView) WithManagedKeyByName(ctx context.Context, keyName, _ string, f logical.ManagedKeyConsumer) error {
+	return d.withManagedKey(ctx, keyName, false, func(ctx context.Context, key *managedSigningKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (d dynamicSystemView) WithManagedKeyByUUID(ctx context.Context, keyUuid, _ string, f logical.ManagedKeyConsumer) error {
+	return d.withManagedKey(ctx, keyUuid, true, func(ctx context.Context, key *managedSigningKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (d dynamicSystemView) WithManagedSigningKeyByName(ctx context.Context, keyName, _ string, f logical.ManagedSigningKeyConsumer) error {
+	return d.withManagedKey(ctx, keyName, false, func(ctx context.Context, key *managedSigningKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (d dynamicSystemView) WithManagedSigningKeyByUUID(ctx context.Context, keyUuid, _ string, f logical.ManagedSigningKeyConsumer) error {
+	return d.withManagedKey(ctx, keyUuid, true, func(ctx context.Context, key *managedSigningKey) error {
+		return f(ctx, key)
+	})
+}
+
+func (d dynamicSystemView) WithManagedEncryptingKeyByName(context.Context, string, string, logical.ManagedEncryptingKeyConsumer) error {
+	return errors.New("managed keys can only be used for signing")
+}
+
+func (d dynamicSystemView) WithManagedEncryptingKeyByUUID(context.Context, string, string, logical.ManagedEncryptingKeyConsumer) error {
+	return errors.New("managed keys can only be used for signing")
+}
+
+// managedSigningKey is the view of a managed key handed to secrets
+// engines. Operations are refused unless allowed by the usages of the key.
+type managedSigningKey struct {
+	entry *managedKeyEntry
+	key   managedkeys.Key
+}
+
+var _ logical.ManagedSigningKey = (*managedSigningKey)(nil)
+
+func (k *managedSigningKey) Name() string {
+	return k.entry.Name
+}
+
+func (k *managedSigningKey) UUID() string {
+	return k.entry.UUID
+}
+
+func (k *managedSigningKey) Present(ctx context.Context) (bool, error) {
+	if _, err := k.key.PublicKey(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (k *managedSigningKey) AllowsAll(usages []logical.KeyUsage) bool {
+	for _, usage := range usages {
+		allowed := false
+		for _, name := range k.entry.Usages {
+			if managedKeyUsages[name] == usage {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func (k *managedSigningKey) GetPublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	return k.key.PublicKey(ctx)
+}
+
+func (k *managedSigningKey) Sign(ctx context.Context, value []byte, _ io.Reader, opts crypto.SignerOpts) ([]byte, error) {
+	if !k.entry.allows(managedKeyUsageSign) {
+		return nil, fmt.Errorf("managed key %q does not allow signing", k.entry.Name)
+	}
+	return k.key.Sign(ctx, value, opts)
+}
+
+func (k *managedSigningKey) Verify(ctx context.Context, signature, value []byte, opts crypto.SignerOpts) (bool, error) {
+	if !k.entry.allows(managedKeyUsageVerify) {
+		return false, fmt.Errorf("managed key %q does not allow verification", k.entry.Name)
+	}
+	public, err := k.key.PublicKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	return managedkeys.Verify(public, value, signature, opts)
+}
+
+func (k *managedSigningKey) GetSigner(ctx context.Context) (crypto.Signer, error) {
+	if !k.entry.allows(managedKeyUsageSign) {
+		return nil, fmt.Errorf("managed key %q does not allow signing", k.entry.Name)
+	}
+	return managedkeys.NewSigner(ctx, k.key)
+}
+
+// parseManagedKeyUsages validates the usages of a managed key.
+func parseManagedKeyUsages(usages []string) ([]string, error) {
+	result := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		usage = strings.ToLower(strings.TrimSpace(usage))
+		if _, ok := managedKeyUsages[usage]; !ok {
+			return nil, fmt.Errorf("%w: unsupported usage %q; managed keys support %q and %q", managedkeys.ErrInvalidConfig,
+				usage, managedKeyUsageSign, managedKeyUsageVerify)
+		}
+		result = append(result, usage)
+	}
+	return strutil.RemoveDuplicates(result, false), nil
 }
@@ -0,0 +1,215 @@
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/managedkeys"
+)
+
+// testManagedKey is a software key standing in for a key management system.
+// Generated keys are kept in keys, indexed by their label.
+type testManagedKey struct {
+	keys  map[string]*ecdsa.PrivateKey
+	label string
+}
+
+func (k *testManagedKey) PublicKey(context.Context) (crypto.PublicKey, error) {
+	key, ok := k.keys[k.label]
+	if !ok {
+		return nil, fmt.Errorf("%w: no key labeled %q", managedkeys.ErrKeyNotFound, k.label)
+	}
+	return key.Public(), nil
+}
+
+func (k *testManagedKey) Sign(ctx context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if _, err := k.PublicKey(ctx); err != nil {
+		return nil, err
+	}
+	return k.keys[k.label].Sign(rand.Reader, digest, opts)
+}
+
+func (k *testManagedKey) GenerateKey(context.Context) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	k.keys[k.label] = key
+	return nil
+}
+
+func (k *testManagedKey) Close() error {
+	return nil
+}
+
+func testManagedKeyRegistry(t *testing.T) (*Core, logical.Backend) {
+	t.Helper()
+	c, b, _ := testCoreSystemBackend(t)
+
+	keys := make(map[string]*ecdsa.PrivateKey)
+	c.managedKeyRegistry.definitions["test"] = managedkeys.Definition{
+		Factory: func(_ context.Context, config map[string]string, _ map[string]string, _ log.Logger) (managedkeys.Key, error) {
+			if config["key_label"] == "" {
+				return nil, fmt.Errorf("%w: key_label is required", managedkeys.ErrInvalidConfig)
+			}
+			return &testManagedKey{keys: keys, label: config["key_label"]}, nil
+		},
+		ConfigFields:    []string{"key_label", "pin"},
+		SensitiveConfig: []string{"pin"},
+	}
+	return c, b
+}
+
+func TestManagedKeyRegistry_Lifecycle(t *testing.T) {
+	c, b := testManagedKeyRegistry(t)
+	ctx := namespace.RootContext(nil)
+
+	// Invalid configuration is rejected before anything is stored
+	req := logical.TestRequest(t, logical.UpdateOperation, "managed-keys/test/signer")
+	req.Data["pin"] = "1234"
+	resp, err := b.HandleRequest(ctx, req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected error, got resp: %#v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "managed-keys/test/signer")
+	req.Data["key_label"] = "vault-signer"
+	req.Data["pin"] = "1234"
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["pin"] != "redacted" || resp.Data["key_label"] != "vault-signer" {
+		t.Fatalf("bad response: %#v", resp.Data)
+	}
+	uuid := resp.Data["UUID"].(string)
+	if uuid == "" {
+		t.Fatal("no uuid assigned")
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "managed-keys/test")
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "signer" {
+		t.Fatalf("bad keys: %#v", keys)
+	}
+
+	// The key does not exist and may not be generated
+	req = logical.TestRequest(t, logical.UpdateOperation, "managed-keys/test/signer/test/sign")
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected failed test, got err: %v resp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "managed-keys/test/signer")
+	req.Data["allow_generate_key"] = true
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "managed-keys/test/signer/test/sign")
+	req.Data["hash_algorithm"] = "sha2-384"
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["healthy"] != true || resp.Data["key_label"] != "vault-signer" {
+		t.Fatalf("bad response: %#v", resp.Data)
+	}
+
+	// Mounts may only use keys they are allowed to
+	digest := sha256.Sum256([]byte("input"))
+	sign := func(mountEntry *MountEntry, ref string, byUUID bool) ([]byte, error) {
+		sysView := dynamicSystemView{core: c, mountEntry: mountEntry}
+		var sig []byte
+		consumer := func(ctx context.Context, key logical.ManagedSigningKey) (err error) {
+			sig, err = key.Sign(ctx, digest[:], rand.Reader, crypto.SHA256)
+			return err
+		}
+		var err error
+		if byUUID {
+			err = sysView.WithManagedSigningKeyByUUID(ctx, ref, "", consumer)
+		} else {
+			err = sysView.WithManagedSigningKeyByName(ctx, ref, "", consumer)
+		}
+		return sig, err
+	}
+
+	if _, err := sign(&MountEntry{}, "signer", false); err == nil {
+		t.Fatal("expected signing from a mount without the key allowed to fail")
+	}
+	allowed := &MountEntry{Config: MountConfig{AllowedManagedKeys: []string{"signer"}}}
+	sig, err := sign(allowed, "signer", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sign(allowed, uuid, true); err != nil {
+		t.Fatal(err)
+	}
+
+	sysView := dynamicSystemView{core: c, mountEntry: allowed}
+	err = sysView.WithManagedSigningKeyByName(ctx, "signer", "", func(ctx context.Context, key logical.ManagedSigningKey) error {
+		valid, err := key.Verify(ctx, sig, digest[:], crypto.SHA256)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("signature did not verify")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Usages are enforced
+	req = logical.TestRequest(t, logical.UpdateOperation, "managed-keys/test/signer")
+	req.Data["usages"] = "verify"
+	req.Data["any_mount"] = true
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if _, err := sign(&MountEntry{}, "signer", false); err == nil {
+		t.Fatal("expected signing with a verify-only key to fail")
+	}
+
+	// Keys cannot change type
+	req = logical.TestRequest(t, logical.UpdateOperation, "managed-keys/awskms/signer")
+	resp, err = b.HandleRequest(ctx, req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected error, got resp: %#v", resp)
+	}
+
+	// Keys allowed on a mount cannot be deleted
+	cubbyhole := c.router.MatchingMountEntry(ctx, "cubbyhole/")
+	cubbyhole.Config.AllowedManagedKeys = []string{uuid}
+	req = logical.TestRequest(t, logical.DeleteOperation, "managed-keys/test/signer")
+	resp, err = b.HandleRequest(ctx, req)
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected error, got resp: %#v", resp)
+	}
+	cubbyhole.Config.AllowedManagedKeys = nil
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "managed-keys/test/signer")
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if _, err := sign(allowed, uuid, true); err == nil {
+		t.Fatal("expected deleted key to be unavailable")
+	}
+}
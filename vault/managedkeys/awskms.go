@@ -0,0 +1,194 @@
+package managedkeys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/awsutil"
+)
+
+var awsKMSHashNames = map[crypto.Hash]string{
+	crypto.SHA256: "SHA_256",
+	crypto.SHA384: "SHA_384",
+	crypto.SHA512: "SHA_512",
+}
+
+var awsKMSKeySpecs = map[string]string{
+	"rsa-2048":   kms.KeySpecRsa2048,
+	"rsa-3072":   kms.KeySpecRsa3072,
+	"rsa-4096":   kms.KeySpecRsa4096,
+	"ecdsa-p256": kms.KeySpecEccNistP256,
+	"ecdsa-p384": kms.KeySpecEccNistP384,
+	"ecdsa-p521": kms.KeySpecEccNistP521,
+}
+
+type awsKMSKey struct {
+	client  *kms.KMS
+	kmsKey  string
+	keyType string
+	keyBits string
+	curve   string
+
+	l      sync.Mutex
+	public crypto.PublicKey
+}
+
+var _ Key = (*awsKMSKey)(nil)
+
+// NewAWSKMS returns a key held by AWS KMS. Credentials not present in
+// config are taken from the environment, following the AWS default
+// credential chain.
+func NewAWSKMS(_ context.Context, config map[string]string, _ map[string]string, logger log.Logger) (Key, error) {
+	if err := requireConfig(config, "kms_key", "key_type"); err != nil {
+		return nil, err
+	}
+
+	region, err := awsutil.GetRegion(config["region"])
+	if err != nil {
+		region = awsutil.DefaultRegion
+	}
+	credsConfig := &awsutil.CredentialsConfig{
+		AccessKey: config["access_key"],
+		SecretKey: config["secret_key"],
+		Region:    region,
+		Logger:    logger,
+	}
+	creds, err := credsConfig.GenerateCredentialChain()
+	if err != nil {
+		return nil, err
+	}
+
+	awsConfig := aws.NewConfig().
+		WithCredentials(creds).
+		WithRegion(region)
+	if endpoint := config["endpoint"]; endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &awsKMSKey{
+		client:  kms.New(sess),
+		kmsKey:  config["kms_key"],
+		keyType: strings.ToLower(config["key_type"]),
+		keyBits: config["key_bits"],
+		curve:   strings.ToLower(config["curve"]),
+	}, nil
+}
+
+func (k *awsKMSKey) PublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	k.l.Lock()
+	defer k.l.Unlock()
+
+	if k.public != nil {
+		return k.public, nil
+	}
+
+	out, err := k.client.GetPublicKeyWithContext(ctx, &kms.GetPublicKeyInput{
+		KeyId: aws.String(k.kmsKey),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == kms.ErrCodeNotFoundException {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, awsErr.Message())
+		}
+		return nil, err
+	}
+	if aws.StringValue(out.KeyUsage) != kms.KeyUsageTypeSignVerify {
+		return nil, fmt.Errorf("%w: KMS key %q is not a signing key", ErrInvalidConfig, k.kmsKey)
+	}
+
+	k.public, err = x509.ParsePKIXPublicKey(out.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key of KMS key: %w", err)
+	}
+	return k.public, nil
+}
+
+func (k *awsKMSKey) Sign(ctx context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	public, err := k.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := hashName(opts.HashFunc(), awsKMSHashNames)
+	if err != nil {
+		return nil, err
+	}
+	var algorithm string
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if _, ok := opts.(*rsa.PSSOptions); ok {
+			algorithm = "RSASSA_PSS_" + hash
+		} else {
+			algorithm = "RSASSA_PKCS1_V1_5_" + hash
+		}
+	case *ecdsa.PublicKey:
+		algorithm = "ECDSA_" + hash
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+
+	out, err := k.client.SignWithContext(ctx, &kms.SignInput{
+		KeyId:            aws.String(k.kmsKey),
+		Message:          digest,
+		MessageType:      aws.String(kms.MessageTypeDigest),
+		SigningAlgorithm: aws.String(algorithm),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Signature, nil
+}
+
+// GenerateKey creates a signing key and points the kms_key alias at it.
+func (k *awsKMSKey) GenerateKey(ctx context.Context) error {
+	if !strings.HasPrefix(k.kmsKey, "alias/") {
+		return fmt.Errorf("%w: kms_key must be an alias to generate a key", ErrInvalidConfig)
+	}
+
+	var spec string
+	switch k.keyType {
+	case "rsa":
+		spec = awsKMSKeySpecs["rsa-"+k.keyBits]
+	case "ecdsa":
+		spec = awsKMSKeySpecs["ecdsa-"+k.curve]
+	}
+	if spec == "" {
+		return fmt.Errorf("%w: unsupported key_type %q with key_bits %q and curve %q", ErrInvalidConfig, k.keyType, k.keyBits, k.curve)
+	}
+
+	out, err := k.client.CreateKeyWithContext(ctx, &kms.CreateKeyInput{
+		Description: aws.String("Managed by Vault"),
+		KeySpec:     aws.String(spec),
+		KeyUsage:    aws.String(kms.KeyUsageTypeSignVerify),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = k.client.CreateAliasWithContext(ctx, &kms.CreateAliasInput{
+		AliasName:   aws.String(k.kmsKey),
+		TargetKeyId: out.KeyMetadata.KeyId,
+	})
+	return err
+}
+
+func (k *awsKMSKey) Close() error {
+	return nil
+}
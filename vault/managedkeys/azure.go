@@ -0,0 +1,329 @@
+package managedkeys
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+)
+
+const azureKeyVaultAPIVersion = "7.3"
+
+var azureHashSizes = map[crypto.Hash]string{
+	crypto.SHA256: "256",
+	crypto.SHA384: "384",
+	crypto.SHA512: "512",
+}
+
+type azureKey struct {
+	client   *http.Client
+	vaultURI string
+	token    *adal.ServicePrincipalToken
+
+	keyName    string
+	keyVersion string
+	keyType    string
+	keyBits    int
+	curve      string
+
+	l      sync.Mutex
+	kid    string
+	public crypto.PublicKey
+}
+
+var _ Key = (*azureKey)(nil)
+
+// NewAzureKeyVault returns a key held by Azure Key Vault. Without a client
+// secret, the managed identity of the host is used.
+func NewAzureKeyVault(_ context.Context, config map[string]string, _ map[string]string, _ log.Logger) (Key, error) {
+	if err := requireConfig(config, "tenant_id", "vault_name", "key_name", "key_type"); err != nil {
+		return nil, err
+	}
+
+	k := &azureKey{
+		client:     cleanhttp.DefaultPooledClient(),
+		keyName:    config["key_name"],
+		keyVersion: config["key_version"],
+		keyType:    strings.ToUpper(config["key_type"]),
+		curve:      config["curve"],
+	}
+	switch k.keyType {
+	case "RSA", "RSA-HSM":
+		k.keyBits = 2048
+		if raw := config["key_bits"]; raw != "" {
+			var err error
+			k.keyBits, err = strconv.Atoi(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: key_bits must be an integer", ErrInvalidConfig)
+			}
+		}
+	case "EC", "EC-HSM":
+		if k.curve == "" {
+			k.curve = "P-256"
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported key_type %q", ErrInvalidConfig, config["key_type"])
+	}
+
+	environment := azure.PublicCloud
+	if name := config["environment"]; name != "" {
+		var err error
+		environment, err = azure.EnvironmentFromName(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+		}
+	}
+	dnsSuffix := config["resource"]
+	if dnsSuffix == "" {
+		dnsSuffix = environment.KeyVaultDNSSuffix
+	}
+	k.vaultURI = "https://" + config["vault_name"] + "." + dnsSuffix
+	resource := "https://" + dnsSuffix
+
+	if config["client_secret"] != "" {
+		if err := requireConfig(config, "client_id"); err != nil {
+			return nil, err
+		}
+		oauthConfig, err := adal.NewOAuthConfig(environment.ActiveDirectoryEndpoint, config["tenant_id"])
+		if err != nil {
+			return nil, err
+		}
+		k.token, err = adal.NewServicePrincipalToken(*oauthConfig, config["client_id"], config["client_secret"], resource)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		msiEndpoint, err := adal.GetMSIVMEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		if clientID := config["client_id"]; clientID != "" {
+			k.token, err = adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(msiEndpoint, resource, clientID)
+		} else {
+			k.token, err = adal.NewServicePrincipalTokenFromMSI(msiEndpoint, resource)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// azureJSONWebKey is the subset of the JSON web key returned by Key Vault
+// needed to build the public key.
+type azureJSONWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+func (k *azureKey) do(ctx context.Context, method, u string, body, out interface{}) error {
+	if err := k.token.EnsureFreshWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to obtain access token: %w", err)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u+"?api-version="+azureKeyVaultAPIVersion, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+k.token.OAuthToken())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		err := fmt.Errorf("key vault returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		if resp.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("%w: %s", ErrKeyNotFound, err)
+		}
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (k *azureKey) keyURL() string {
+	u := k.vaultURI + "/keys/" + url.PathEscape(k.keyName)
+	if k.keyVersion != "" {
+		u += "/" + url.PathEscape(k.keyVersion)
+	}
+	return u
+}
+
+func (k *azureKey) PublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	k.l.Lock()
+	defer k.l.Unlock()
+
+	if k.public != nil {
+		return k.public, nil
+	}
+
+	var bundle struct {
+		Key azureJSONWebKey `json:"key"`
+	}
+	if err := k.do(ctx, http.MethodGet, k.keyURL(), nil, &bundle); err != nil {
+		return nil, err
+	}
+
+	public, err := bundle.Key.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	k.kid, k.public = bundle.Key.Kid, public
+	return public, nil
+}
+
+func (k *azureKey) Sign(ctx context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	public, err := k.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := hashName(opts.HashFunc(), azureHashSizes)
+	if err != nil {
+		return nil, err
+	}
+	var algorithm string
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if _, ok := opts.(*rsa.PSSOptions); ok {
+			algorithm = "PS" + size
+		} else {
+			algorithm = "RS" + size
+		}
+	case *ecdsa.PublicKey:
+		algorithm = "ES" + size
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+
+	var result struct {
+		Value string `json:"value"`
+	}
+	err = k.do(ctx, http.MethodPost, k.kid+"/sign", map[string]string{
+		"alg":   algorithm,
+		"value": base64.RawURLEncoding.EncodeToString(digest),
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(result.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if _, ok := public.(*ecdsa.PublicKey); ok {
+		// Key Vault returns ECDSA signatures as the concatenation of R and S
+		half := len(sig) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			R: new(big.Int).SetBytes(sig[:half]),
+			S: new(big.Int).SetBytes(sig[half:]),
+		})
+	}
+	return sig, nil
+}
+
+func (k *azureKey) GenerateKey(ctx context.Context) error {
+	body := map[string]interface{}{
+		"kty":     k.keyType,
+		"key_ops": []string{"sign", "verify"},
+	}
+	if strings.HasPrefix(k.keyType, "EC") {
+		body["crv"] = k.curve
+	} else {
+		body["key_size"] = k.keyBits
+	}
+
+	var bundle struct {
+		Key azureJSONWebKey `json:"key"`
+	}
+	return k.do(ctx, http.MethodPost, k.vaultURI+"/keys/"+url.PathEscape(k.keyName)+"/create", body, &bundle)
+}
+
+func (k *azureKey) Close() error {
+	return nil
+}
+
+func (j *azureJSONWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key parameter: %w", err)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch strings.TrimSuffix(j.Kty, "-HSM") {
+	case "RSA":
+		n, err := decode(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+}
@@ -0,0 +1,159 @@
+// Package managedkeys contains the key management systems that managed keys
+// can be backed by. The private key material of a managed key never leaves
+// its key management system; Vault only holds a reference to it.
+package managedkeys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+const (
+	TypePKCS11        = "pkcs11"
+	TypeAWSKMS        = "awskms"
+	TypeAzureKeyVault = "azurekeyvault"
+)
+
+// ErrInvalidConfig is returned by factories when a key is misconfigured.
+// The wrapping error describes the problem.
+var ErrInvalidConfig = errors.New("invalid managed key configuration")
+
+// ErrKeyNotFound is returned by keys whose key management system does not
+// hold the configured key.
+var ErrKeyNotFound = errors.New("managed key not found in key management system")
+
+// Key is an asymmetric signing key held by a key management system.
+type Key interface {
+	// PublicKey returns the public key of the key.
+	PublicKey(ctx context.Context) (crypto.PublicKey, error)
+
+	// Sign signs a digest computed with opts.HashFunc(). RSA keys use PSS
+	// when opts is a *rsa.PSSOptions and PKCS #1 v1.5 otherwise; ECDSA
+	// signatures are ASN.1 encoded.
+	Sign(ctx context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error)
+
+	// GenerateKey creates the key in the key management system, under the
+	// label, name or alias it is configured with.
+	GenerateKey(ctx context.Context) error
+
+	// Close releases the resources held by the key.
+	Close() error
+}
+
+// Factory creates a key from its stored configuration. Libraries maps the
+// names of the kms_library stanzas of the server configuration to the
+// path of their shared library.
+type Factory func(ctx context.Context, config map[string]string, libraries map[string]string, logger log.Logger) (Key, error)
+
+// Definition describes a managed key type.
+type Definition struct {
+	Factory Factory
+
+	// ConfigFields lists the parameters of the type.
+	ConfigFields []string
+
+	// SensitiveConfig lists the parameters that hold credentials and must
+	// never be returned when reading the key.
+	SensitiveConfig []string
+}
+
+// BuiltinDefinitions returns the managed key types available by default,
+// keyed by type name.
+func BuiltinDefinitions() map[string]Definition {
+	return map[string]Definition{
+		TypePKCS11: {
+			Factory:         NewPKCS11,
+			ConfigFields:    []string{"library", "slot", "token_label", "pin", "key_label", "key_id", "mechanism", "curve", "key_bits"},
+			SensitiveConfig: []string{"pin"},
+		},
+		TypeAWSKMS: {
+			Factory:         NewAWSKMS,
+			ConfigFields:    []string{"access_key", "secret_key", "region", "endpoint", "kms_key", "key_type", "key_bits", "curve"},
+			SensitiveConfig: []string{"secret_key"},
+		},
+		TypeAzureKeyVault: {
+			Factory:         NewAzureKeyVault,
+			ConfigFields:    []string{"tenant_id", "client_id", "client_secret", "environment", "vault_name", "key_name", "key_version", "resource", "key_type", "key_bits", "curve"},
+			SensitiveConfig: []string{"client_secret"},
+		},
+	}
+}
+
+// Signer adapts a key to crypto.Signer for use with the standard library.
+// The public key is fetched once, when the signer is created.
+type Signer struct {
+	ctx    context.Context
+	key    Key
+	public crypto.PublicKey
+}
+
+var _ crypto.Signer = (*Signer)(nil)
+
+// NewSigner returns a signer using the key. The context is used for every
+// signing operation.
+func NewSigner(ctx context.Context, key Key) (*Signer, error) {
+	public, err := key.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{ctx: ctx, key: key, public: public}, nil
+}
+
+func (s *Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign signs the digest; the random source is ignored since key management
+// systems use their own.
+func (s *Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(s.ctx, digest, opts)
+}
+
+// Verify checks a signature made by Sign against the public key.
+func Verify(public crypto.PublicKey, digest, signature []byte, opts crypto.SignerOpts) (bool, error) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		var err error
+		if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+			err = rsa.VerifyPSS(public, pssOpts.Hash, digest, signature, pssOpts)
+		} else {
+			err = rsa.VerifyPKCS1v15(public, opts.HashFunc(), digest, signature)
+		}
+		return err == nil, nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(public, digest, signature), nil
+	default:
+		return false, fmt.Errorf("unsupported public key type %T", public)
+	}
+}
+
+// hashName returns the name of the hash functions supported by key
+// management systems, using the spelling of the given mapping.
+func hashName(h crypto.Hash, names map[crypto.Hash]string) (string, error) {
+	name, ok := names[h]
+	if !ok {
+		return "", fmt.Errorf("unsupported hash function %v", h)
+	}
+	return name, nil
+}
+
+func requireConfig(config map[string]string, keys ...string) error {
+	var missing []string
+	for _, k := range keys {
+		if config[k] == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrInvalidConfig, strings.Join(missing, ", "))
+	}
+	return nil
+}
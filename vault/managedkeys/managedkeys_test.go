@@ -0,0 +1,63 @@
+package managedkeys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("input"))
+
+	for name, tc := range map[string]struct {
+		signer crypto.Signer
+		opts   crypto.SignerOpts
+	}{
+		"rsa-pss":      {rsaKey, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}},
+		"rsa-pkcs1v15": {rsaKey, crypto.SHA256},
+		"ecdsa":        {ecKey, crypto.SHA256},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sig, err := tc.signer.Sign(rand.Reader, digest[:], tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			valid, err := Verify(tc.signer.Public(), digest[:], sig, tc.opts)
+			if err != nil || !valid {
+				t.Fatalf("signature did not verify: %v", err)
+			}
+
+			sig[len(sig)-1] ^= 0xff
+			valid, err = Verify(tc.signer.Public(), digest[:], sig, tc.opts)
+			if err != nil || valid {
+				t.Fatalf("tampered signature verified: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireConfig(t *testing.T) {
+	for name, factory := range map[string]Factory{
+		TypePKCS11:        NewPKCS11,
+		TypeAWSKMS:        NewAWSKMS,
+		TypeAzureKeyVault: NewAzureKeyVault,
+	} {
+		if _, err := factory(context.Background(), map[string]string{}, nil, nil); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("%s: expected invalid config error, got %v", name, err)
+		}
+	}
+}
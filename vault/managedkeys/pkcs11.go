@@ -0,0 +1,471 @@
+//go:build cgo
+
+package managedkeys
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/miekg/pkcs11"
+)
+
+// pkcs11Modules holds the libraries loaded by this process. A library must
+// only be initialized once, so keys sharing a library share its module.
+var pkcs11Modules = struct {
+	sync.Mutex
+	modules map[string]*pkcs11Module
+}{modules: make(map[string]*pkcs11Module)}
+
+type pkcs11Module struct {
+	ctx  *pkcs11.Ctx
+	path string
+	refs int
+}
+
+func openPKCS11Module(path string) (*pkcs11Module, error) {
+	pkcs11Modules.Lock()
+	defer pkcs11Modules.Unlock()
+
+	if m, ok := pkcs11Modules.modules[path]; ok {
+		m.refs++
+		return m, nil
+	}
+
+	ctx := pkcs11.New(path)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 library %q", path)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to initialize PKCS#11 library %q: %w", path, err)
+	}
+
+	m := &pkcs11Module{ctx: ctx, path: path, refs: 1}
+	pkcs11Modules.modules[path] = m
+	return m, nil
+}
+
+func (m *pkcs11Module) close() {
+	pkcs11Modules.Lock()
+	defer pkcs11Modules.Unlock()
+
+	m.refs--
+	if m.refs > 0 {
+		return
+	}
+	delete(pkcs11Modules.modules, m.path)
+	m.ctx.Finalize()
+	m.ctx.Destroy()
+}
+
+var (
+	oidNamedCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidNamedCurveP384 = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidNamedCurveP521 = asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+)
+
+// pkcs1DigestInfoPrefixes are the DER prefixes of the DigestInfo structure
+// that CKM_RSA_PKCS expects the digest to be wrapped in.
+var pkcs1DigestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+var pkcs11PSSParams = map[crypto.Hash]struct{ hash, mgf uint }{
+	crypto.SHA256: {pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256},
+	crypto.SHA384: {pkcs11.CKM_SHA384, pkcs11.CKG_MGF1_SHA384},
+	crypto.SHA512: {pkcs11.CKM_SHA512, pkcs11.CKG_MGF1_SHA512},
+}
+
+type pkcs11Key struct {
+	module *pkcs11Module
+	slot   uint
+	pin    string
+
+	keyLabel  string
+	keyID     []byte
+	mechanism uint
+	keyBits   int
+	curve     string
+
+	l      sync.Mutex
+	public crypto.PublicKey
+}
+
+var _ Key = (*pkcs11Key)(nil)
+
+// NewPKCS11 returns a key held by a PKCS#11 token. The library must be
+// declared in a kms_library stanza of the server configuration.
+func NewPKCS11(_ context.Context, config map[string]string, libraries map[string]string, _ log.Logger) (Key, error) {
+	if err := requireConfig(config, "library", "pin", "mechanism"); err != nil {
+		return nil, err
+	}
+	if config["key_label"] == "" && config["key_id"] == "" {
+		return nil, fmt.Errorf("%w: one of key_label or key_id is required", ErrInvalidConfig)
+	}
+	path, ok := libraries[config["library"]]
+	if !ok {
+		return nil, fmt.Errorf("%w: no kms_library named %q is configured on this node", ErrInvalidConfig, config["library"])
+	}
+
+	k := &pkcs11Key{
+		pin:      config["pin"],
+		keyLabel: config["key_label"],
+		curve:    strings.ToUpper(strings.ReplaceAll(config["curve"], "-", "")),
+	}
+	mechanism, err := strconv.ParseUint(config["mechanism"], 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: mechanism must be a hexadecimal number such as 0x1041", ErrInvalidConfig)
+	}
+	switch k.mechanism = uint(mechanism); k.mechanism {
+	case pkcs11.CKM_ECDSA, pkcs11.CKM_RSA_PKCS_PSS, pkcs11.CKM_RSA_PKCS:
+	case pkcs11.CKM_RSA_PKCS_OAEP:
+		return nil, fmt.Errorf("%w: managed keys only support signing mechanisms", ErrInvalidConfig)
+	default:
+		return nil, fmt.Errorf("%w: unsupported mechanism %s", ErrInvalidConfig, config["mechanism"])
+	}
+	if raw := config["key_id"]; raw != "" {
+		k.keyID, err = hex.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key_id must be hex encoded", ErrInvalidConfig)
+		}
+	}
+	if raw := config["key_bits"]; raw != "" {
+		k.keyBits, err = strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key_bits must be an integer", ErrInvalidConfig)
+		}
+	}
+
+	k.module, err = openPKCS11Module(path)
+	if err != nil {
+		return nil, err
+	}
+
+	k.slot, err = k.findSlot(config["slot"], config["token_label"])
+	if err != nil {
+		k.module.close()
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *pkcs11Key) findSlot(slot, tokenLabel string) (uint, error) {
+	if slot != "" {
+		id, err := strconv.ParseUint(slot, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: slot must be an integer", ErrInvalidConfig)
+		}
+		return uint(id), nil
+	}
+	if tokenLabel == "" {
+		return 0, fmt.Errorf("%w: one of slot or token_label is required", ErrInvalidConfig)
+	}
+
+	slots, err := k.module.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range slots {
+		info, err := k.module.ctx.GetTokenInfo(id)
+		if err != nil {
+			return 0, err
+		}
+		if info.Label == tokenLabel {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("no token labeled %q found", tokenLabel)
+}
+
+// session opens a logged in session on the token. Sessions are opened per
+// operation, so concurrent operations never share one.
+func (k *pkcs11Key) session() (pkcs11.SessionHandle, error) {
+	session, err := k.module.ctx.OpenSession(k.slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return 0, err
+	}
+	// Logins are shared by every session of the application
+	err = k.module.ctx.Login(session, pkcs11.CKU_USER, k.pin)
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+		k.module.ctx.CloseSession(session)
+		return 0, fmt.Errorf("failed to log into token: %w", err)
+	}
+	return session, nil
+}
+
+func (k *pkcs11Key) findObject(session pkcs11.SessionHandle, class uint) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+	}
+	if k.keyLabel != "" {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, k.keyLabel))
+	}
+	if len(k.keyID) > 0 {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_ID, k.keyID))
+	}
+
+	if err := k.module.ctx.FindObjectsInit(session, template); err != nil {
+		return 0, err
+	}
+	objects, _, err := k.module.ctx.FindObjects(session, 2)
+	k.module.ctx.FindObjectsFinal(session)
+	if err != nil {
+		return 0, err
+	}
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("%w: no key on the token matches key_label and key_id", ErrKeyNotFound)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, errors.New("more than one key on the token matches key_label and key_id")
+	}
+}
+
+func (k *pkcs11Key) PublicKey(_ context.Context) (crypto.PublicKey, error) {
+	k.l.Lock()
+	defer k.l.Unlock()
+
+	if k.public != nil {
+		return k.public, nil
+	}
+
+	session, err := k.session()
+	if err != nil {
+		return nil, err
+	}
+	defer k.module.ctx.CloseSession(session)
+
+	object, err := k.findObject(session, pkcs11.CKO_PUBLIC_KEY)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := k.module.ctx.GetAttributeValue(session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// CK_ULONG attributes are encoded in the byte order of the host, so
+	// they are compared to attributes encoded the same way
+	keyType := attrs[0].Value
+	switch {
+	case bytes.Equal(keyType, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_RSA).Value):
+		attrs, err = k.module.ctx.GetAttributeValue(session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+		k.public = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		}
+	case bytes.Equal(keyType, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC).Value):
+		attrs, err = k.module.ctx.GetAttributeValue(session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+		k.public, err = pkcs11ECPublicKey(attrs[0].Value, attrs[1].Value)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#11 key type %x", keyType)
+	}
+	return k.public, nil
+}
+
+func (k *pkcs11Key) Sign(ctx context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	public, err := k.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pssOpts, pss := opts.(*rsa.PSSOptions)
+	_, isEC := public.(*ecdsa.PublicKey)
+	switch {
+	case (k.mechanism == pkcs11.CKM_ECDSA) != isEC:
+		return nil, errors.New("the type of the key on the token does not match mechanism")
+	case k.mechanism == pkcs11.CKM_RSA_PKCS_PSS && !pss:
+		return nil, errors.New("key is configured for PSS signatures")
+	case k.mechanism == pkcs11.CKM_RSA_PKCS && pss:
+		return nil, errors.New("key is configured for PKCS #1 v1.5 signatures")
+	}
+
+	var mechanism *pkcs11.Mechanism
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if pss {
+			params, ok := pkcs11PSSParams[opts.HashFunc()]
+			if !ok {
+				return nil, fmt.Errorf("unsupported hash function %v", opts.HashFunc())
+			}
+			saltLength := pssOpts.SaltLength
+			if saltLength <= 0 {
+				saltLength = opts.HashFunc().Size()
+			}
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, pkcs11.NewPSSParams(params.hash, params.mgf, uint(saltLength)))
+		} else {
+			prefix, ok := pkcs1DigestInfoPrefixes[opts.HashFunc()]
+			if !ok {
+				return nil, fmt.Errorf("unsupported hash function %v", opts.HashFunc())
+			}
+			digest = append(append([]byte{}, prefix...), digest...)
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+		}
+	case *ecdsa.PublicKey:
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)
+	}
+
+	session, err := k.session()
+	if err != nil {
+		return nil, err
+	}
+	defer k.module.ctx.CloseSession(session)
+
+	object, err := k.findObject(session, pkcs11.CKO_PRIVATE_KEY)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.module.ctx.SignInit(session, []*pkcs11.Mechanism{mechanism}, object); err != nil {
+		return nil, err
+	}
+	sig, err := k.module.ctx.Sign(session, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := public.(*ecdsa.PublicKey); ok {
+		// PKCS#11 returns ECDSA signatures as the concatenation of R and S
+		half := len(sig) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			R: new(big.Int).SetBytes(sig[:half]),
+			S: new(big.Int).SetBytes(sig[half:]),
+		})
+	}
+	return sig, nil
+}
+
+func (k *pkcs11Key) GenerateKey(_ context.Context) error {
+	if k.keyLabel == "" {
+		return fmt.Errorf("%w: key_label is required to generate a key", ErrInvalidConfig)
+	}
+
+	session, err := k.session()
+	if err != nil {
+		return err
+	}
+	defer k.module.ctx.CloseSession(session)
+
+	if _, err := k.findObject(session, pkcs11.CKO_PRIVATE_KEY); err == nil {
+		return fmt.Errorf("a key labeled %q already exists on the token", k.keyLabel)
+	}
+
+	publicTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, k.keyLabel),
+	}
+	privateTemplate := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, k.keyLabel),
+	}
+	if len(k.keyID) > 0 {
+		publicTemplate = append(publicTemplate, pkcs11.NewAttribute(pkcs11.CKA_ID, k.keyID))
+		privateTemplate = append(privateTemplate, pkcs11.NewAttribute(pkcs11.CKA_ID, k.keyID))
+	}
+
+	var mechanism *pkcs11.Mechanism
+	switch k.mechanism {
+	case pkcs11.CKM_RSA_PKCS_PSS, pkcs11.CKM_RSA_PKCS:
+		switch k.keyBits {
+		case 2048, 3072, 4096:
+		default:
+			return fmt.Errorf("%w: key_bits must be 2048, 3072 or 4096", ErrInvalidConfig)
+		}
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_KEY_PAIR_GEN, nil)
+		publicTemplate = append(publicTemplate,
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, k.keyBits),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, []byte{1, 0, 1}))
+	case pkcs11.CKM_ECDSA:
+		var oid asn1.ObjectIdentifier
+		switch k.curve {
+		case "P256":
+			oid = oidNamedCurveP256
+		case "P384":
+			oid = oidNamedCurveP384
+		case "P521":
+			oid = oidNamedCurveP521
+		default:
+			return fmt.Errorf("%w: curve must be P256, P384 or P521", ErrInvalidConfig)
+		}
+		params, err := asn1.Marshal(oid)
+		if err != nil {
+			return err
+		}
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)
+		publicTemplate = append(publicTemplate, pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params))
+	}
+
+	_, _, err = k.module.ctx.GenerateKeyPair(session, []*pkcs11.Mechanism{mechanism}, publicTemplate, privateTemplate)
+	return err
+}
+
+func (k *pkcs11Key) Close() error {
+	k.module.close()
+	return nil
+}
+
+func pkcs11ECPublicKey(params, point []byte) (*ecdsa.PublicKey, error) {
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(params, &oid); err != nil {
+		return nil, fmt.Errorf("failed to parse EC parameters: %w", err)
+	}
+	var curve elliptic.Curve
+	switch {
+	case oid.Equal(oidNamedCurveP256):
+		curve = elliptic.P256()
+	case oid.Equal(oidNamedCurveP384):
+		curve = elliptic.P384()
+	case oid.Equal(oidNamedCurveP521):
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %v", oid)
+	}
+
+	// The point is wrapped in an octet string by compliant tokens
+	var raw []byte
+	if rest, err := asn1.Unmarshal(point, &raw); err != nil || len(rest) > 0 {
+		raw = point
+	}
+	x, y := elliptic.Unmarshal(curve, raw)
+	if x == nil {
+		return nil, errors.New("failed to parse EC point")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
@@ -0,0 +1,16 @@
+//go:build !cgo
+
+package managedkeys
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+// NewPKCS11 is unavailable without cgo since PKCS#11 libraries are native
+// shared libraries.
+func NewPKCS11(_ context.Context, _ map[string]string, _ map[string]string, _ log.Logger) (Key, error) {
+	return nil, fmt.Errorf("%w: PKCS#11 managed keys require a Vault binary built with cgo", ErrInvalidConfig)
+}
@@ -352,6 +352,10 @@ type MountConfig struct {
 	TokenType                 logical.TokenType     `json:"token_type,omitempty" structs:"token_type" mapstructure:"token_type"`
 	AllowedManagedKeys        []string              `json:"allowed_managed_keys,omitempty" mapstructure:"allowed_managed_keys"`
 	UserLockoutConfig         *UserLockoutConfig    `json:"user_lockout_config,omitempty" mapstructure:"user_lockout_config"`
+	IdempotencyKeyTTL         time.Duration         `json:"idempotency_key_ttl,omitempty" mapstructure:"idempotency_key_ttl"`
+	MaxRequestSize            int64                 `json:"max_request_size,omitempty" mapstructure:"max_request_size"`
+	MaxResponseSize           int64                 `json:"max_response_size,omitempty" mapstructure:"max_response_size"`
+	RequestTimeout            time.Duration         `json:"request_timeout,omitempty" mapstructure:"request_timeout"`
 
 	// PluginName is the name of the plugin registered in the catalog.
 	//
@@ -387,6 +391,10 @@ type APIMountConfig struct {
 	AllowedManagedKeys        []string              `json:"allowed_managed_keys,omitempty" mapstructure:"allowed_managed_keys"`
 	UserLockoutConfig         *UserLockoutConfig    `json:"user_lockout_config,omitempty" mapstructure:"user_lockout_config"`
 	PluginVersion             string                `json:"plugin_version,omitempty" mapstructure:"plugin_version"`
+	IdempotencyKeyTTL         string                `json:"idempotency_key_ttl,omitempty" mapstructure:"idempotency_key_ttl"`
+	MaxRequestSize            int64                 `json:"max_request_size,omitempty" mapstructure:"max_request_size"`
+	MaxResponseSize           int64                 `json:"max_response_size,omitempty" mapstructure:"max_response_size"`
+	RequestTimeout            string                `json:"request_timeout,omitempty" mapstructure:"request_timeout"`
 
 	// PluginName is the name of the plugin registered in the catalog.
 	//
@@ -533,6 +541,11 @@ func (c *Core) mount(ctx context.Context, entry *MountEntry) error {
 		}
 	}
 
+	// The trash of disabled mounts is served under sys/mounts/trash/
+	if strings.HasPrefix(entry.Path, mountTrashPathPrefix) {
+		return logical.CodedError(400, fmt.Sprintf("cannot mount %q, the path is reserved", entry.Path))
+	}
+
 	// Do not allow more than one instance of a singleton mount
 	for _, p := range singletonMounts {
 		if entry.Type == p {
@@ -765,6 +778,13 @@ func (c *Core) builtinTypeFromMountEntry(ctx context.Context, entry *MountEntry)
 // Unmount is used to unmount a path. The boolean indicates whether the mount
 // was found.
 func (c *Core) unmount(ctx context.Context, path string) error {
+	return c.unmountWithRetention(ctx, path, 0)
+}
+
+// unmountWithRetention unmounts a path. When retention is set, the storage of
+// the mount is kept and the mount is moved to the trash, from which it can be
+// restored until retention has passed.
+func (c *Core) unmountWithRetention(ctx context.Context, path string, retention time.Duration) error {
 	// Ensure we end the path in a slash
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
@@ -778,7 +798,7 @@ func (c *Core) unmount(ctx context.Context, path string) error {
 	}
 
 	// Unmount mount internally
-	if err := c.unmountInternal(ctx, path, MountTableUpdateStorage); err != nil {
+	if err := c.unmountInternalWithRetention(ctx, path, MountTableUpdateStorage, retention); err != nil {
 		return err
 	}
 
@@ -791,6 +811,10 @@ func (c *Core) unmount(ctx context.Context, path string) error {
 }
 
 func (c *Core) unmountInternal(ctx context.Context, path string, updateStorage bool) error {
+	return c.unmountInternalWithRetention(ctx, path, updateStorage, 0)
+}
+
+func (c *Core) unmountInternalWithRetention(ctx context.Context, path string, updateStorage bool, retention time.Duration) error {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
 		return err
@@ -810,6 +834,14 @@ func (c *Core) unmountInternal(ctx context.Context, path string, updateStorage b
 	backend := c.router.MatchingBackend(ctx, path)
 	entry := c.router.MatchingMountEntry(ctx, path)
 
+	// Keep a copy of the entry as it was before tainting, to restore it
+	var trashed *MountEntry
+	if retention > 0 {
+		if trashed, err = entry.Clone(); err != nil {
+			return err
+		}
+	}
+
 	// Mark the entry as tainted
 	if err := c.taintMountEntry(ctx, ns.ID, path, updateStorage, true); err != nil {
 		c.logger.Error("failed to taint mount entry for path being unmounted", "error", err, "path", path)
@@ -845,6 +877,12 @@ func (c *Core) unmountInternal(ctx context.Context, path string, updateStorage b
 	switch {
 	case !updateStorage:
 		// Don't attempt to clear data, replication will handle this
+	case trashed != nil:
+		// Keep the data, it is cleared once the mount is purged from the trash
+		if err := c.addMountToTrash(ctx, trashed, retention); err != nil {
+			c.logger.Error("failed to move mount being unmounted to the trash", "error", err, "path", path)
+			return err
+		}
 	case c.IsDRSecondary():
 		// If we are a dr secondary we want to clear the view, but the provided
 		// view is marked as read only. We use the barrier here to get around
@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+)
+
+// MountRequestLimits are the limits tuned on a mount for the requests made
+// to it, overriding the ones of the listener. A zero value keeps the limit
+// of the listener.
+type MountRequestLimits struct {
+	MaxRequestSize  int64
+	MaxResponseSize int64
+	RequestTimeout  time.Duration
+}
+
+// MountRequestLimits returns the limits tuned on the mount handling the
+// given request path, or nil if the mount doesn't override any.
+func (c *Core) MountRequestLimits(ctx context.Context, reqPath string) *MountRequestLimits {
+	entry := c.router.MatchingMountEntry(ctx, reqPath)
+	if entry == nil {
+		return nil
+	}
+	if entry.Config.MaxRequestSize == 0 && entry.Config.MaxResponseSize == 0 && entry.Config.RequestTimeout == 0 {
+		return nil
+	}
+	return &MountRequestLimits{
+		MaxRequestSize:  entry.Config.MaxRequestSize,
+		MaxResponseSize: entry.Config.MaxResponseSize,
+		RequestTimeout:  entry.Config.RequestTimeout,
+	}
+}
+
+// validateMountSizeLimit validates the max_request_size and max_response_size
+// mount options; zero keeps the limit of the listener.
+func validateMountSizeLimit(name string, size int64) error {
+	if size < 0 {
+		return fmt.Errorf("%s cannot be negative", name)
+	}
+	return nil
+}
+
+// parseMountRequestTimeout parses the request_timeout mount option; an empty
+// value or zero keeps the maximum request duration of the listener.
+func parseMountRequestTimeout(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	timeout, err := parseutil.ParseDurationSecond(raw)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse request timeout of %s: %w", raw, err)
+	}
+	if timeout < 0 {
+		return 0, errors.New("request timeout cannot be negative")
+	}
+	return timeout, nil
+}
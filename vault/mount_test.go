@@ -204,7 +204,7 @@ func TestCore_Mount_secrets_builtin_RunningVersion(t *testing.T) {
 		t.Fatalf("missing mount")
 	}
 
-	raw, _ := c.router.root.Get(match)
+	raw, _ := c.router.trees.Load().root.Get([]byte(match))
 	// we override the running version of builtins
 	if !strings.Contains(raw.(*routeEntry).mountEntry.RunningVersion, "builtin") {
 		t.Errorf("Expected mount to have builtin version but got %s", raw.(*routeEntry).mountEntry.RunningVersion)
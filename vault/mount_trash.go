@@ -0,0 +1,246 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// coreMountTrashPath is used to store the mounts disabled with a
+	// retention period, along with the time their data is to be removed.
+	coreMountTrashPath = "core/mounts-trash"
+
+	// mountTrashPathPrefix is the mount path prefix reserved for the trash
+	// endpoints under sys/mounts/
+	mountTrashPathPrefix = "trash/"
+
+	// mountTrashPurgeInterval is how often the trash is checked for mounts
+	// whose retention period has passed
+	mountTrashPurgeInterval = 5 * time.Minute
+)
+
+// trashedMount is a mount that was disabled with a retention period. Its
+// storage is kept under the view path of Entry until PurgeTime.
+type trashedMount struct {
+	Entry        *MountEntry `json:"entry"`
+	DeletionTime time.Time   `json:"deletion_time"`
+	PurgeTime    time.Time   `json:"purge_time"`
+}
+
+// loadMountTrash returns the trashed mounts. The trash lock must be held.
+func (c *Core) loadMountTrash(ctx context.Context) ([]*trashedMount, error) {
+	raw, err := c.barrier.Get(ctx, coreMountTrashPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mount trash: %w", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var trash []*trashedMount
+	if err := raw.DecodeJSON(&trash); err != nil {
+		return nil, fmt.Errorf("failed to decode mount trash: %w", err)
+	}
+	return trash, nil
+}
+
+// persistMountTrash stores the trashed mounts. The trash lock must be held.
+func (c *Core) persistMountTrash(ctx context.Context, trash []*trashedMount) error {
+	if len(trash) == 0 {
+		return c.barrier.Delete(ctx, coreMountTrashPath)
+	}
+
+	entry, err := logical.StorageEntryJSON(coreMountTrashPath, trash)
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to persist mount trash: %w", err)
+	}
+	return nil
+}
+
+// addMountToTrash records that the storage of entry is kept for retention
+// after it is unmounted.
+func (c *Core) addMountToTrash(ctx context.Context, entry *MountEntry, retention time.Duration) error {
+	c.mountTrashLock.Lock()
+	defer c.mountTrashLock.Unlock()
+
+	trash, err := c.loadMountTrash(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	trash = append(trash, &trashedMount{
+		Entry:        entry,
+		DeletionTime: now,
+		PurgeTime:    now.Add(retention),
+	})
+	return c.persistMountTrash(ctx, trash)
+}
+
+// listMountTrash returns the trashed mounts of the namespace of ctx
+func (c *Core) listMountTrash(ctx context.Context) ([]*trashedMount, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mountTrashLock.Lock()
+	defer c.mountTrashLock.Unlock()
+
+	trash, err := c.loadMountTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*trashedMount
+	for _, t := range trash {
+		if t.Entry.NamespaceID == ns.ID {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// restoreMountFromTrash mounts the trashed mount with the given UUID again,
+// with its original storage, at path or at its original path if path is
+// empty.
+func (c *Core) restoreMountFromTrash(ctx context.Context, id, path string) (*MountEntry, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mountTrashLock.Lock()
+	defer c.mountTrashLock.Unlock()
+
+	trash, err := c.loadMountTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := -1
+	for i, t := range trash {
+		if t.Entry.UUID == id && t.Entry.NamespaceID == ns.ID {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return nil, logical.CodedError(404, fmt.Sprintf("no mount with id %q in the trash", id))
+	}
+
+	entry, err := trash[idx].Entry.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		entry.Path = path
+	}
+	if err := c.mount(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	trash = append(trash[:idx], trash[idx+1:]...)
+	if err := c.persistMountTrash(ctx, trash); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// purgeMountFromTrash removes the trashed mount with the given UUID, along
+// with its storage.
+func (c *Core) purgeMountFromTrash(ctx context.Context, id string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mountTrashLock.Lock()
+	defer c.mountTrashLock.Unlock()
+
+	trash, err := c.loadMountTrash(ctx)
+	if err != nil {
+		return err
+	}
+
+	remaining := trash[:0]
+	for _, t := range trash {
+		if t.Entry.UUID == id && t.Entry.NamespaceID == ns.ID {
+			if err := c.clearTrashedMountStorage(ctx, t); err != nil {
+				return err
+			}
+			continue
+		}
+		remaining = append(remaining, t)
+	}
+	return c.persistMountTrash(ctx, remaining)
+}
+
+// purgeExpiredMountTrash removes the trashed mounts whose retention period
+// has passed, along with their storage.
+func (c *Core) purgeExpiredMountTrash(ctx context.Context) error {
+	c.mountTrashLock.Lock()
+	defer c.mountTrashLock.Unlock()
+
+	trash, err := c.loadMountTrash(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	remaining := trash[:0]
+	purged := 0
+	for _, t := range trash {
+		if now.Before(t.PurgeTime) {
+			remaining = append(remaining, t)
+			continue
+		}
+		if err := c.clearTrashedMountStorage(ctx, t); err != nil {
+			// Keep the entry so the purge is retried
+			c.logger.Error("failed to purge mount from the trash", "path", t.Entry.Path, "namespace_id", t.Entry.NamespaceID, "error", err)
+			remaining = append(remaining, t)
+			continue
+		}
+		purged++
+	}
+	if purged == 0 {
+		return nil
+	}
+	return c.persistMountTrash(ctx, remaining)
+}
+
+func (c *Core) clearTrashedMountStorage(ctx context.Context, t *trashedMount) error {
+	view := NewBarrierView(c.barrier, t.Entry.ViewPath())
+	logger := c.logger.Named("secrets.deletion").With("namespace", t.Entry.NamespaceID, "path", t.Entry.Path)
+	if err := logical.ClearViewWithLogging(ctx, view, logger); err != nil {
+		return fmt.Errorf("failed to clear storage of trashed mount %q: %w", t.Entry.Path, err)
+	}
+	if c.logger.IsInfo() {
+		c.logger.Info("purged mount from the trash", "path", t.Entry.Path, "namespace_id", t.Entry.NamespaceID)
+	}
+	return nil
+}
+
+// mountTrashPurgeLoop periodically purges the expired mounts of the trash,
+// until ctx is done.
+func (c *Core) mountTrashPurgeLoop(ctx context.Context) {
+	t := time.NewTicker(mountTrashPurgeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := c.purgeExpiredMountTrash(ctx); err != nil {
+				c.logger.Error("error purging the mount trash", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCore_MountTrash(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	c.logicalBackends["noop"] = func(context.Context, *logical.BackendConfig) (logical.Backend, error) {
+		return &NoopBackend{}, nil
+	}
+	ctx := namespace.RootContext(nil)
+
+	if err := c.mount(ctx, &MountEntry{Table: mountTableType, Path: "trash/", Type: "noop"}); err == nil {
+		t.Fatal("expected the trash path to be reserved")
+	}
+
+	me := &MountEntry{
+		Table: mountTableType,
+		Path:  "test/",
+		Type:  "noop",
+	}
+	if err := c.mount(ctx, me); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	view := c.router.MatchingStorageByAPIPath(ctx, "test/")
+	if err := view.Put(context.Background(), &logical.StorageEntry{Key: "keep", Value: []byte("test")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := logical.TestRequest(t, logical.DeleteOperation, "sys/mounts/test")
+	req.ClientToken = root
+	req.Data["retention"] = "1h"
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if match := c.router.MatchingMount(ctx, "test/foo"); match != "" {
+		t.Fatal("backend present")
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "sys/mounts/trash")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 1 || keys[0] != me.UUID {
+		t.Fatalf("bad keys: %v", keys)
+	}
+	info := resp.Data["key_info"].(map[string]interface{})[me.UUID].(map[string]interface{})
+	if info["path"] != "test/" || info["accessor"] != me.Accessor {
+		t.Fatalf("bad key info: %#v", info)
+	}
+
+	// Restore at a new path, the data is still there
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/trash/"+me.UUID+"/restore")
+	req.ClientToken = root
+	req.Data["path"] = "restored"
+	resp, err = c.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["path"] != "restored/" || resp.Data["accessor"] != me.Accessor {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	view = c.router.MatchingStorageByAPIPath(ctx, "restored/")
+	if entry, err := view.Get(context.Background(), "keep"); err != nil || entry == nil {
+		t.Fatalf("expected the data to be restored, got %v, %v", entry, err)
+	}
+	trash, err := c.listMountTrash(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(trash) != 0 {
+		t.Fatalf("expected an empty trash, got %d entries", len(trash))
+	}
+
+	// Expired mounts are purged with their data
+	if err := c.unmountWithRetention(ctx, "restored/", time.Nanosecond); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.purgeExpiredMountTrash(ctx); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	trash, err = c.listMountTrash(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(trash) != 0 {
+		t.Fatalf("expected an empty trash, got %d entries", len(trash))
+	}
+	out, err := logical.CollectKeys(context.Background(), NewBarrierView(c.barrier, me.ViewPath()))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("expected the data to be removed, got %v", out)
+	}
+}
@@ -6,12 +6,19 @@ import (
 	"context"
 	"path"
 
+	"github.com/hashicorp/vault/helper/entropy"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
-func addPathCheckers(*Core, *MountEntry, logical.Backend, string)             {}
-func removePathCheckers(*Core, *MountEntry, string)                           {}
+func addPathCheckers(c *Core, entry *MountEntry, backend logical.Backend, viewPath string) {
+	c.sealWrapper.addMountPaths(entry, backend, viewPath)
+}
+
+func removePathCheckers(c *Core, entry *MountEntry, viewPath string) {
+	c.sealWrapper.removeMountPaths(viewPath)
+}
+
 func addAuditPathChecker(*Core, *MountEntry, *BarrierView, string)            {}
 func removeAuditPathChecker(*Core, *MountEntry)                               {}
 func addFilterablePath(*Core, string)                                         {}
@@ -47,11 +54,22 @@ func verifyNamespace(*Core, *namespace.Namespace, *MountEntry) error { return ni
 // mount-specific entries; because this should be called when setting
 // up a mountEntry, it doesn't check to ensure that me is not nil
 func (c *Core) mountEntrySysView(entry *MountEntry) extendedSystemView {
-	return extendedSystemViewImpl{
+	sysView := extendedSystemViewImpl{
 		dynamicSystemView{
 			core:        c,
 			mountEntry:  entry,
 			perfStandby: c.perfStandby,
 		},
 	}
+	if entry.ExternalEntropyAccess && c.entropyAugmentationEnabled() {
+		return entropySystemView{sysView}
+	}
+	return sysView
+}
+
+// entropyAugmentationEnabled returns whether the secure random reader mixes
+// in an external entropy source.
+func (c *Core) entropyAugmentationEnabled() bool {
+	_, ok := c.secureRandomReader.(*entropy.AugmentedReader)
+	return ok
 }
@@ -0,0 +1,499 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// namespaceBundleVersion is the version of the format of namespace bundles
+const namespaceBundleVersion = 1
+
+// namespaceBundle is the declarative definition of the configuration of a
+// namespace. It holds no secrets and no entities, so that it can be applied
+// to another cluster to promote configuration between environments.
+type namespaceBundle struct {
+	Version  int                              `json:"version" mapstructure:"version"`
+	Mounts   map[string]*namespaceBundleMount `json:"mounts" mapstructure:"mounts"`
+	Auth     map[string]*namespaceBundleMount `json:"auth" mapstructure:"auth"`
+	Policies map[string]string                `json:"policies" mapstructure:"policies"`
+	Groups   map[string]*namespaceBundleGroup `json:"groups" mapstructure:"groups"`
+}
+
+// namespaceBundleMount describes a secrets engine or an auth method. Config
+// holds the tunable parameters, in the format of the mount endpoints.
+type namespaceBundleMount struct {
+	Type                  string                 `json:"type" mapstructure:"type"`
+	Description           string                 `json:"description,omitempty" mapstructure:"description"`
+	Local                 bool                   `json:"local,omitempty" mapstructure:"local"`
+	SealWrap              bool                   `json:"seal_wrap,omitempty" mapstructure:"seal_wrap"`
+	ExternalEntropyAccess bool                   `json:"external_entropy_access,omitempty" mapstructure:"external_entropy_access"`
+	Options               map[string]string      `json:"options,omitempty" mapstructure:"options"`
+	Config                map[string]interface{} `json:"config,omitempty" mapstructure:"config"`
+}
+
+// namespaceBundleGroup describes an identity group. Members are referenced by
+// group name, as entities and IDs differ from one cluster to another.
+type namespaceBundleGroup struct {
+	Type                      string                     `json:"type" mapstructure:"type"`
+	Policies                  []string                   `json:"policies,omitempty" mapstructure:"policies"`
+	Metadata                  map[string]string          `json:"metadata,omitempty" mapstructure:"metadata"`
+	MemberGroupNames          []string                   `json:"member_group_names,omitempty" mapstructure:"member_group_names"`
+	MembershipExpression      string                     `json:"membership_expression,omitempty" mapstructure:"membership_expression"`
+	MembershipRefreshInterval int64                      `json:"membership_refresh_interval,omitempty" mapstructure:"membership_refresh_interval"`
+	Alias                     *namespaceBundleGroupAlias `json:"alias,omitempty" mapstructure:"alias"`
+}
+
+// namespaceBundleGroupAlias is the alias of an external group, referencing
+// its auth method by path.
+type namespaceBundleGroupAlias struct {
+	Name      string `json:"name" mapstructure:"name"`
+	MountPath string `json:"mount_path" mapstructure:"mount_path"`
+}
+
+// exportNamespaceBundle builds the bundle of the namespace of ctx
+func (c *Core) exportNamespaceBundle(ctx context.Context) (*namespaceBundle, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &namespaceBundle{
+		Version:  namespaceBundleVersion,
+		Mounts:   map[string]*namespaceBundleMount{},
+		Auth:     map[string]*namespaceBundleMount{},
+		Policies: map[string]string{},
+		Groups:   map[string]*namespaceBundleGroup{},
+	}
+
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		if entry.Namespace().Path != ns.Path || strutil.StrListContains(singletonMounts, entry.Type) {
+			continue
+		}
+		bundle.Mounts[entry.Path] = namespaceBundleMountFromEntry(entry)
+	}
+	c.mountsLock.RUnlock()
+
+	c.authLock.RLock()
+	for _, entry := range c.auth.Entries {
+		if entry.Namespace().Path != ns.Path || strutil.StrListContains(singletonMounts, entry.Type) {
+			continue
+		}
+		bundle.Auth[entry.Path] = namespaceBundleMountFromEntry(entry)
+	}
+	c.authLock.RUnlock()
+
+	policyNames, err := c.policyStore.ListPolicies(ctx, PolicyTypeACL)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range policyNames {
+		if strutil.StrListContains(immutablePolicies, name) {
+			continue
+		}
+		policy, err := c.policyStore.GetPolicy(ctx, name, PolicyTypeACL)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			bundle.Policies[name] = policy.Raw
+		}
+	}
+
+	groups, err := c.identityStore.namespaceGroups(ns)
+	if err != nil {
+		return nil, err
+	}
+	groupNames := make(map[string]string, len(groups))
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+	for _, group := range groups {
+		bundleGroup := &namespaceBundleGroup{
+			Type:                      group.Type,
+			Policies:                  group.Policies,
+			Metadata:                  group.Metadata,
+			MembershipExpression:      group.MembershipExpression,
+			MembershipRefreshInterval: group.MembershipRefreshInterval,
+		}
+		if group.Alias != nil {
+			mountEntry := c.router.MatchingMountByAccessor(group.Alias.MountAccessor)
+			if mountEntry == nil {
+				return nil, fmt.Errorf("failed to find the auth method of the alias of group %q", group.Name)
+			}
+			bundleGroup.Alias = &namespaceBundleGroupAlias{
+				Name:      group.Alias.Name,
+				MountPath: mountEntry.Path,
+			}
+		}
+		bundle.Groups[group.Name] = bundleGroup
+	}
+	for _, group := range groups {
+		for _, parentID := range group.ParentGroupIDs {
+			parentName, ok := groupNames[parentID]
+			if !ok {
+				continue
+			}
+			parent := bundle.Groups[parentName]
+			parent.MemberGroupNames = append(parent.MemberGroupNames, group.Name)
+		}
+	}
+	for _, group := range bundle.Groups {
+		sort.Strings(group.MemberGroupNames)
+	}
+
+	return bundle, nil
+}
+
+func namespaceBundleMountFromEntry(entry *MountEntry) *namespaceBundleMount {
+	config := map[string]interface{}{
+		"default_lease_ttl": namespaceBundleTTL(entry.Config.DefaultLeaseTTL.Seconds()),
+		"max_lease_ttl":     namespaceBundleTTL(entry.Config.MaxLeaseTTL.Seconds()),
+	}
+	if entry.Config.ForceNoCache {
+		config["force_no_cache"] = true
+	}
+	if entry.Config.ListingVisibility != "" {
+		config["listing_visibility"] = string(entry.Config.ListingVisibility)
+	}
+	if len(entry.Config.AuditNonHMACRequestKeys) > 0 {
+		config["audit_non_hmac_request_keys"] = entry.Config.AuditNonHMACRequestKeys
+	}
+	if len(entry.Config.AuditNonHMACResponseKeys) > 0 {
+		config["audit_non_hmac_response_keys"] = entry.Config.AuditNonHMACResponseKeys
+	}
+	if len(entry.Config.PassthroughRequestHeaders) > 0 {
+		config["passthrough_request_headers"] = entry.Config.PassthroughRequestHeaders
+	}
+	if len(entry.Config.AllowedResponseHeaders) > 0 {
+		config["allowed_response_headers"] = entry.Config.AllowedResponseHeaders
+	}
+	if entry.Table == credentialTableType && entry.Config.TokenType != logical.TokenTypeDefault {
+		config["token_type"] = entry.Config.TokenType.String()
+	}
+	if entry.Version != "" {
+		config["plugin_version"] = entry.Version
+	}
+
+	return &namespaceBundleMount{
+		Type:                  entry.Type,
+		Description:           entry.Description,
+		Local:                 entry.Local,
+		SealWrap:              entry.SealWrap,
+		ExternalEntropyAccess: entry.ExternalEntropyAccess,
+		Options:               entry.Options,
+		Config:                config,
+	}
+}
+
+// namespaceBundleTTL formats a TTL of the mount configuration, where 0 means
+// that the system value is used.
+func namespaceBundleTTL(seconds float64) string {
+	if seconds == 0 {
+		return "system"
+	}
+	return strconv.FormatInt(int64(seconds), 10) + "s"
+}
+
+// namespaceGroups returns the groups of the given namespace, sorted by name
+func (i *IdentityStore) namespaceGroups(ns *namespace.Namespace) ([]*identity.Group, error) {
+	txn := i.db.Txn(false)
+	iter, err := txn.Get(groupsTable, "namespace_id", ns.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup groups using namespace ID: %w", err)
+	}
+
+	var groups []*identity.Group
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		groups = append(groups, raw.(*identity.Group))
+	}
+	sort.Slice(groups, func(a, b int) bool {
+		return groups[a].Name < groups[b].Name
+	})
+	return groups, nil
+}
+
+// namespaceBundleImportResult records what importing a bundle did to each
+// object, keyed by kind and then by path or name.
+type namespaceBundleImportResult map[string]map[string]string
+
+func (r namespaceBundleImportResult) record(kind, name, action string) {
+	if r[kind] == nil {
+		r[kind] = map[string]string{}
+	}
+	r[kind][name] = action
+}
+
+// importNamespaceBundle applies bundle to the namespace of ctx. Objects that
+// are missing are created and the others are updated to match the bundle;
+// objects that are not part of the bundle are left untouched, so that
+// importing the same bundle several times is harmless. Changes are applied
+// through the regular endpoints, so that they are validated the same way.
+func (c *Core) importNamespaceBundle(ctx context.Context, bundle *namespaceBundle) (namespaceBundleImportResult, error) {
+	if bundle.Version != namespaceBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+
+	result := namespaceBundleImportResult{}
+
+	// Policies go first, as mounts and groups may reference them by name
+	for _, name := range sortedKeys(bundle.Policies) {
+		if strutil.StrListContains(immutablePolicies, name) {
+			return nil, fmt.Errorf("policy %q cannot be imported", name)
+		}
+		if err := c.routeNamespaceBundleRequest(ctx, logical.UpdateOperation, "sys/policies/acl/"+name, map[string]interface{}{
+			"policy": bundle.Policies[name],
+		}); err != nil {
+			return nil, fmt.Errorf("failed to import policy %q: %w", name, err)
+		}
+		result.record("policies", name, "updated")
+	}
+
+	for _, path := range sortedKeys(bundle.Mounts) {
+		action, err := c.importNamespaceBundleMount(ctx, mountTableType, path, bundle.Mounts[path])
+		if err != nil {
+			return nil, fmt.Errorf("failed to import mount %q: %w", path, err)
+		}
+		result.record("mounts", path, action)
+	}
+
+	for _, path := range sortedKeys(bundle.Auth) {
+		action, err := c.importNamespaceBundleMount(ctx, credentialTableType, path, bundle.Auth[path])
+		if err != nil {
+			return nil, fmt.Errorf("failed to import auth method %q: %w", path, err)
+		}
+		result.record("auth", path, action)
+	}
+
+	groupNames := sortedKeys(bundle.Groups)
+	for _, name := range groupNames {
+		action, err := c.importNamespaceBundleGroup(ctx, name, bundle.Groups[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to import group %q: %w", name, err)
+		}
+		result.record("groups", name, action)
+	}
+
+	// Member groups are set once all the groups exist
+	for _, name := range groupNames {
+		bundleGroup := bundle.Groups[name]
+		if bundleGroup.Type == groupTypeExternal {
+			continue
+		}
+		memberGroupIDs := make([]string, 0, len(bundleGroup.MemberGroupNames))
+		for _, memberName := range bundleGroup.MemberGroupNames {
+			member, err := c.identityStore.MemDBGroupByName(ctx, memberName, false)
+			if err != nil {
+				return nil, err
+			}
+			if member == nil {
+				return nil, fmt.Errorf("member group %q of group %q not found", memberName, name)
+			}
+			memberGroupIDs = append(memberGroupIDs, member.ID)
+		}
+		if err := c.routeNamespaceBundleRequest(ctx, logical.UpdateOperation, "identity/group/name/"+name, map[string]interface{}{
+			"member_group_ids": memberGroupIDs,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to set the member groups of group %q: %w", name, err)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Core) importNamespaceBundleMount(ctx context.Context, table, path string, mount *namespaceBundleMount) (string, error) {
+	if mount == nil || mount.Type == "" {
+		return "", fmt.Errorf("missing type")
+	}
+	if strutil.StrListContains(singletonMounts, mount.Type) {
+		return "", fmt.Errorf("%q mounts cannot be imported", mount.Type)
+	}
+	path = sanitizePath(path)
+
+	var existing *MountEntry
+	var err error
+	if table == credentialTableType {
+		c.authLock.RLock()
+		existing, err = c.auth.find(ctx, path)
+		c.authLock.RUnlock()
+	} else {
+		c.mountsLock.RLock()
+		existing, err = c.mounts.find(ctx, path)
+		c.mountsLock.RUnlock()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	prefix := "sys/mounts/"
+	if table == credentialTableType {
+		prefix = "sys/auth/"
+	}
+
+	if existing == nil {
+		data := map[string]interface{}{
+			"type":                    mount.Type,
+			"description":             mount.Description,
+			"local":                   mount.Local,
+			"seal_wrap":               mount.SealWrap,
+			"external_entropy_access": mount.ExternalEntropyAccess,
+		}
+		if len(mount.Options) > 0 {
+			data["options"] = mount.Options
+		}
+		if len(mount.Config) > 0 {
+			data["config"] = mount.Config
+		}
+		if err := c.routeNamespaceBundleRequest(ctx, logical.UpdateOperation, prefix+path, data); err != nil {
+			return "", err
+		}
+		return "created", nil
+	}
+
+	if existing.Type != mount.Type {
+		return "", fmt.Errorf("existing mount has type %q, not %q", existing.Type, mount.Type)
+	}
+	if existing.Local != mount.Local || existing.SealWrap != mount.SealWrap || existing.ExternalEntropyAccess != mount.ExternalEntropyAccess {
+		return "", fmt.Errorf("local, seal_wrap and external_entropy_access cannot be changed on an existing mount")
+	}
+
+	data := map[string]interface{}{
+		"description": mount.Description,
+	}
+	for key, value := range mount.Config {
+		// force_no_cache can only be set when mounting
+		if key != "force_no_cache" {
+			data[key] = value
+		}
+	}
+	if len(mount.Options) > 0 {
+		data["options"] = mount.Options
+	}
+	if err := c.routeNamespaceBundleRequest(ctx, logical.UpdateOperation, "sys/mounts/"+existing.APIPathNoNamespace()+"tune", data); err != nil {
+		return "", err
+	}
+	return "updated", nil
+}
+
+func (c *Core) importNamespaceBundleGroup(ctx context.Context, name string, bundleGroup *namespaceBundleGroup) (string, error) {
+	if bundleGroup == nil {
+		return "", fmt.Errorf("missing group definition")
+	}
+
+	if bundleGroup.Type == "" {
+		bundleGroup.Type = groupTypeInternal
+	}
+
+	existing, err := c.identityStore.MemDBGroupByName(ctx, name, false)
+	if err != nil {
+		return "", err
+	}
+	action := "created"
+	if existing != nil {
+		action = "updated"
+		if existing.Type != bundleGroup.Type {
+			return "", fmt.Errorf("existing group has type %q, not %q", existing.Type, bundleGroup.Type)
+		}
+	}
+
+	policies := bundleGroup.Policies
+	if policies == nil {
+		policies = []string{}
+	}
+	metadata := bundleGroup.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	data := map[string]interface{}{
+		"type":     bundleGroup.Type,
+		"policies": policies,
+		"metadata": metadata,
+	}
+	if bundleGroup.Type == groupTypeDynamic {
+		data["membership_expression"] = bundleGroup.MembershipExpression
+		if bundleGroup.MembershipRefreshInterval > 0 {
+			data["membership_refresh_interval"] = bundleGroup.MembershipRefreshInterval
+		}
+	}
+	if err := c.routeNamespaceBundleRequest(ctx, logical.UpdateOperation, "identity/group/name/"+name, data); err != nil {
+		return "", err
+	}
+
+	if bundleGroup.Alias == nil {
+		return action, nil
+	}
+	if bundleGroup.Type != groupTypeExternal {
+		return "", fmt.Errorf("only external groups can have an alias")
+	}
+
+	c.authLock.RLock()
+	mountEntry, err := c.auth.find(ctx, sanitizePath(strings.TrimPrefix(bundleGroup.Alias.MountPath, credentialRoutePrefix)))
+	c.authLock.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	if mountEntry == nil {
+		return "", fmt.Errorf("auth method %q of the group alias not found", bundleGroup.Alias.MountPath)
+	}
+
+	group, err := c.identityStore.MemDBGroupByName(ctx, name, false)
+	if err != nil {
+		return "", err
+	}
+	if group == nil {
+		return "", fmt.Errorf("group not found after being written")
+	}
+
+	aliasPath := "identity/group-alias"
+	if group.Alias != nil {
+		if group.Alias.Name == bundleGroup.Alias.Name && group.Alias.MountAccessor == mountEntry.Accessor {
+			return action, nil
+		}
+		aliasPath = "identity/group-alias/id/" + group.Alias.ID
+	}
+	if err := c.routeNamespaceBundleRequest(ctx, logical.UpdateOperation, aliasPath, map[string]interface{}{
+		"name":           bundleGroup.Alias.Name,
+		"mount_accessor": mountEntry.Accessor,
+		"canonical_id":   group.ID,
+	}); err != nil {
+		return "", fmt.Errorf("failed to write the group alias: %w", err)
+	}
+	return action, nil
+}
+
+// routeNamespaceBundleRequest routes an internal request used to apply a
+// bundle, and turns error responses into errors.
+func (c *Core) routeNamespaceBundleRequest(ctx context.Context, op logical.Operation, path string, data map[string]interface{}) error {
+	resp, err := c.router.Route(ctx, &logical.Request{
+		Operation: op,
+		Path:      path,
+		Data:      data,
+	})
+	if err != nil {
+		if resp != nil && resp.IsError() {
+			return resp.Error()
+		}
+		return err
+	}
+	if resp != nil && resp.IsError() {
+		return resp.Error()
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
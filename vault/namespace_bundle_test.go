@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func testNamespaceBundleRequest(t *testing.T, c *Core, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := c.router.Route(namespace.RootContext(nil), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("%s %s: bad: resp: %#v\nerr: %v", op, path, resp, err)
+	}
+	return resp
+}
+
+func TestNamespaceBundle_ExportImport(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	source, _, _ := TestCoreUnsealed(t)
+
+	testNamespaceBundleRequest(t, source, logical.UpdateOperation, "sys/mounts/kv-a", map[string]interface{}{
+		"type":        "kv",
+		"description": "team A secrets",
+		"config": map[string]interface{}{
+			"max_lease_ttl": "2h",
+		},
+	})
+	testNamespaceBundleRequest(t, source, logical.UpdateOperation, "sys/auth/corp", map[string]interface{}{
+		"type": "noop",
+	})
+	testNamespaceBundleRequest(t, source, logical.UpdateOperation, "sys/policies/acl/dev", map[string]interface{}{
+		"policy": `path "kv-a/*" { capabilities = ["read"] }`,
+	})
+	testNamespaceBundleRequest(t, source, logical.UpdateOperation, "identity/group/name/child", map[string]interface{}{
+		"policies": []string{"dev"},
+	})
+	child, err := source.identityStore.MemDBGroupByName(ctx, "child", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testNamespaceBundleRequest(t, source, logical.UpdateOperation, "identity/group/name/parent", map[string]interface{}{
+		"metadata":         map[string]string{"team": "a"},
+		"member_group_ids": []string{child.ID},
+	})
+	resp := testNamespaceBundleRequest(t, source, logical.UpdateOperation, "identity/group/name/ext", map[string]interface{}{
+		"type": "external",
+	})
+	testNamespaceBundleRequest(t, source, logical.UpdateOperation, "identity/group-alias", map[string]interface{}{
+		"name":           "engineers",
+		"mount_accessor": source.router.MatchingMountEntry(ctx, "auth/corp/").Accessor,
+		"canonical_id":   resp.Data["id"],
+	})
+
+	resp = testNamespaceBundleRequest(t, source, logical.ReadOperation, "sys/namespace-bundle/export", nil)
+
+	// Round trip the bundle through JSON as it would over the API
+	encoded, err := jsonutil.EncodeJSON(resp.Data["bundle"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle map[string]interface{}
+	if err := jsonutil.DecodeJSON(encoded, &bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	target, _, _ := TestCoreUnsealed(t)
+	resp = testNamespaceBundleRequest(t, target, logical.UpdateOperation, "sys/namespace-bundle/import", map[string]interface{}{
+		"bundle": bundle,
+	})
+	expected := map[string]interface{}{
+		"mounts":   map[string]string{"kv-a/": "created", "secret/": "updated"},
+		"auth":     map[string]string{"corp/": "created"},
+		"policies": map[string]string{"default": "updated", "dev": "updated"},
+		"groups":   map[string]string{"child": "created", "ext": "created", "parent": "created"},
+	}
+	if diff := deep.Equal(expected, resp.Data); diff != nil {
+		t.Fatal(diff)
+	}
+
+	mount := target.router.MatchingMountEntry(ctx, "kv-a/")
+	if mount == nil || mount.Description != "team A secrets" || mount.Config.MaxLeaseTTL != 2*time.Hour {
+		t.Fatalf("bad mount: %#v", mount)
+	}
+	authMount := target.router.MatchingMountEntry(ctx, "auth/corp/")
+	if authMount == nil || authMount.Type != "noop" {
+		t.Fatalf("bad auth method: %#v", authMount)
+	}
+	policy, err := target.policyStore.GetPolicy(ctx, "dev", PolicyTypeACL)
+	if err != nil || policy == nil {
+		t.Fatalf("missing policy: %v", err)
+	}
+
+	parent, err := target.identityStore.MemDBGroupByName(ctx, "parent", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetChild, err := target.identityStore.MemDBGroupByName(ctx, "child", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Metadata["team"] != "a" || len(targetChild.ParentGroupIDs) != 1 || targetChild.ParentGroupIDs[0] != parent.ID {
+		t.Fatalf("bad groups: %#v, %#v", parent, targetChild)
+	}
+	ext, err := target.identityStore.MemDBGroupByName(ctx, "ext", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext.Alias == nil || ext.Alias.Name != "engineers" || ext.Alias.MountAccessor != authMount.Accessor {
+		t.Fatalf("bad external group alias: %#v", ext.Alias)
+	}
+
+	// Importing again only updates the objects
+	resp = testNamespaceBundleRequest(t, target, logical.UpdateOperation, "sys/namespace-bundle/import", map[string]interface{}{
+		"bundle": bundle,
+	})
+	expected = map[string]interface{}{
+		"mounts":   map[string]string{"kv-a/": "updated", "secret/": "updated"},
+		"auth":     map[string]string{"corp/": "updated"},
+		"policies": map[string]string{"default": "updated", "dev": "updated"},
+		"groups":   map[string]string{"child": "updated", "ext": "updated", "parent": "updated"},
+	}
+	if diff := deep.Equal(expected, resp.Data); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// The exports of both clusters now match
+	resp = testNamespaceBundleRequest(t, target, logical.ReadOperation, "sys/namespace-bundle/export", nil)
+	encoded, err = jsonutil.EncodeJSON(resp.Data["bundle"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var targetBundle map[string]interface{}
+	if err := jsonutil.DecodeJSON(encoded, &targetBundle); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(bundle, targetBundle); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestNamespaceBundle_ImportConflict(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	testNamespaceBundleRequest(t, c, logical.UpdateOperation, "sys/mounts/kv-a", map[string]interface{}{
+		"type": "kv",
+	})
+
+	resp, err := c.router.Route(namespace.RootContext(nil), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sys/namespace-bundle/import",
+		Data: map[string]interface{}{
+			"bundle": map[string]interface{}{
+				"version": 1,
+				"mounts": map[string]interface{}{
+					"kv-a/": map[string]interface{}{"type": "noop"},
+				},
+			},
+		},
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got: %#v", resp)
+	}
+
+	resp, err = c.router.Route(namespace.RootContext(nil), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "sys/namespace-bundle/import",
+		Data: map[string]interface{}{
+			"bundle": map[string]interface{}{
+				"version": 2,
+			},
+		},
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an unknown version, got: %#v", resp)
+	}
+}
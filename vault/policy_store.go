@@ -151,6 +151,11 @@ path "sys/control-group/request" {
     capabilities = ["update"]
 }
 
+# Allow a token to validate MFA methods to satisfy step-up MFA enforcements
+path "sys/mfa/step-up" {
+    capabilities = ["update"]
+}
+
 # Allow a token to make requests to the Authorization Endpoint for OIDC providers.
 path "identity/oidc/provider/+/authorize" {
 	capabilities = ["read", "update"]
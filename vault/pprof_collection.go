@@ -0,0 +1,429 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	pprofCollectionSubPath       = "pprof-collection/"
+	pprofCollectionConfigPath    = "config"
+	pprofCollectionProfilePrefix = "profiles/"
+
+	pprofCollectionDefaultInterval    = time.Hour
+	pprofCollectionDefaultCPUDuration = 30 * time.Second
+	pprofCollectionDefaultRetain      = 24
+	pprofCollectionMaxRetain          = 500
+
+	// pprofCollectionMaxProfileSize bounds the size of stored profiles, so
+	// that they fit in a storage entry
+	pprofCollectionMaxProfileSize = 512 * 1024
+
+	pprofCollectionShipTimeout = 30 * time.Second
+
+	pprofProfileCPU = "cpu"
+)
+
+// pprofCollectionProfileTypes are the profiles that can be collected, besides
+// the CPU profile.
+var pprofCollectionProfileTypes = []string{"heap", "allocs", "goroutine", "block", "mutex", "threadcreate"}
+
+// pprofCollectionConfig controls the periodic collection of profiles.
+type pprofCollectionConfig struct {
+	Enabled     bool          `json:"enabled"`
+	Interval    time.Duration `json:"interval"`
+	CPUDuration time.Duration `json:"cpu_duration"`
+	Profiles    []string      `json:"profiles"`
+
+	// Retain is the number of profiles of each type kept in storage; 0
+	// disables storage, in which case profiles are only shipped.
+	Retain int `json:"retain"`
+
+	// EndpointURL is where profiles are sent, if set.
+	EndpointURL string `json:"endpoint_url"`
+}
+
+func defaultPprofCollectionConfig() pprofCollectionConfig {
+	return pprofCollectionConfig{
+		Interval:    pprofCollectionDefaultInterval,
+		CPUDuration: pprofCollectionDefaultCPUDuration,
+		Profiles:    []string{pprofProfileCPU, "heap"},
+		Retain:      pprofCollectionDefaultRetain,
+	}
+}
+
+func (c *pprofCollectionConfig) validate() error {
+	switch {
+	case c.Interval <= 0:
+		return fmt.Errorf("interval must be positive")
+	case c.CPUDuration <= 0 || c.CPUDuration >= c.Interval:
+		return fmt.Errorf("cpu_duration must be positive and less than interval")
+	case c.Retain < 0 || c.Retain > pprofCollectionMaxRetain:
+		return fmt.Errorf("retain must be between 0 and %d", pprofCollectionMaxRetain)
+	case c.Enabled && c.Retain == 0 && c.EndpointURL == "":
+		return fmt.Errorf("retain or endpoint_url must be set to collect profiles")
+	case len(c.Profiles) == 0:
+		return fmt.Errorf("at least one profile must be collected")
+	}
+	for _, p := range c.Profiles {
+		if p != pprofProfileCPU && !strutil.StrListContains(pprofCollectionProfileTypes, p) {
+			return fmt.Errorf("unknown profile %q", p)
+		}
+	}
+	if c.EndpointURL != "" {
+		u, err := url.Parse(c.EndpointURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint_url must be an http or https URL")
+		}
+	}
+	return nil
+}
+
+// collectedProfile is a profile captured by the collector, in the gzipped
+// protobuf format of pprof.
+type collectedProfile struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Node      string    `json:"node"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Data      []byte    `json:"data"`
+}
+
+// pprofCollector periodically captures profiles of this node, so that they
+// are available after an incident.
+type pprofCollector struct {
+	logger     log.Logger
+	view       *BarrierView
+	node       string
+	httpClient *http.Client
+
+	// configLock serializes configuration changes, which restart the
+	// collection loop.
+	configLock sync.Mutex
+	config     pprofCollectionConfig
+
+	// l serializes the changes to the stored profiles.
+	l sync.Mutex
+
+	// parentCtx is the context the collection loop is bound to.
+	parentCtx context.Context
+	cancel    context.CancelFunc
+	doneCh    chan struct{}
+}
+
+func (c *Core) setupPprofCollection(ctx context.Context) error {
+	logger := c.baseLogger.Named("pprof-collection")
+	c.AddLogger(logger)
+
+	node, _ := os.Hostname()
+	p := &pprofCollector{
+		logger:     logger,
+		view:       c.systemBarrierView.SubView(pprofCollectionSubPath),
+		node:       node,
+		httpClient: cleanhttp.DefaultClient(),
+		config:     defaultPprofCollectionConfig(),
+		parentCtx:  namespace.RootContext(ctx),
+	}
+	p.httpClient.Timeout = pprofCollectionShipTimeout
+
+	raw, err := p.view.Get(ctx, pprofCollectionConfigPath)
+	if err != nil {
+		return err
+	}
+	if raw != nil {
+		if err := raw.DecodeJSON(&p.config); err != nil {
+			return err
+		}
+	}
+	c.pprofCollector = p
+
+	// Profiles are only collected by the active node, which stores them.
+	if c.perfStandby {
+		return nil
+	}
+
+	p.configLock.Lock()
+	p.startLocked()
+	p.configLock.Unlock()
+	return nil
+}
+
+func (c *Core) stopPprofCollection() {
+	// preSeal may run before setupPprofCollection got a chance to complete.
+	if c.pprofCollector != nil {
+		c.pprofCollector.configLock.Lock()
+		c.pprofCollector.stopLocked()
+		c.pprofCollector.configLock.Unlock()
+	}
+
+	c.pprofCollector = nil
+}
+
+func (p *pprofCollector) startLocked() {
+	if !p.config.Enabled || p.parentCtx == nil {
+		return
+	}
+
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(p.parentCtx)
+	p.doneCh = make(chan struct{})
+	config := p.config
+
+	go func() {
+		defer close(p.doneCh)
+
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.collect(ctx, config)
+			}
+		}
+	}()
+}
+
+func (p *pprofCollector) stopLocked() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.doneCh
+	p.cancel = nil
+}
+
+func (p *pprofCollector) getConfig() pprofCollectionConfig {
+	p.configLock.Lock()
+	defer p.configLock.Unlock()
+	return p.config
+}
+
+// setConfig stores config and restarts the collection with it.
+func (p *pprofCollector) setConfig(ctx context.Context, config pprofCollectionConfig) error {
+	entry, err := logical.StorageEntryJSON(pprofCollectionConfigPath, config)
+	if err != nil {
+		return err
+	}
+
+	p.configLock.Lock()
+	defer p.configLock.Unlock()
+
+	if err := p.view.Put(ctx, entry); err != nil {
+		return err
+	}
+	p.stopLocked()
+	p.config = config
+	p.startLocked()
+	return nil
+}
+
+// collect captures, stores and ships each of the configured profiles.
+func (p *pprofCollector) collect(ctx context.Context, config pprofCollectionConfig) {
+	for _, typ := range config.Profiles {
+		profile, err := p.capture(ctx, typ, config.CPUDuration)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.logger.Error("failed to capture profile", "type", typ, "error", err)
+			}
+			continue
+		}
+
+		if config.EndpointURL != "" {
+			if err := p.ship(ctx, config.EndpointURL, profile); err != nil {
+				p.logger.Error("failed to ship profile", "type", typ, "error", err)
+			}
+		}
+		if config.Retain > 0 {
+			if err := p.store(ctx, profile, config.Retain); err != nil {
+				p.logger.Error("failed to store profile", "type", typ, "error", err)
+			}
+		}
+	}
+}
+
+func (p *pprofCollector) capture(ctx context.Context, typ string, cpuDuration time.Duration) (*collectedProfile, error) {
+	profile := &collectedProfile{
+		Type:      typ,
+		Node:      p.node,
+		StartTime: time.Now().UTC(),
+	}
+
+	var buf bytes.Buffer
+	if typ == pprofProfileCPU {
+		// This fails if a CPU profile is already being taken, e.g. through
+		// sys/pprof/profile
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return nil, err
+		}
+		timer := time.NewTimer(cpuDuration)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+		pprof.StopCPUProfile()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		lookup := pprof.Lookup(typ)
+		if lookup == nil {
+			return nil, fmt.Errorf("unknown profile %q", typ)
+		}
+		if err := lookup.WriteTo(&buf, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	profile.EndTime = time.Now().UTC()
+	profile.ID = pprofProfileID(profile.EndTime, typ)
+	profile.Data = buf.Bytes()
+	return profile, nil
+}
+
+// pprofProfileID returns an identifier that sorts profiles of a type by time
+func pprofProfileID(t time.Time, typ string) string {
+	return typ + "-" + strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// store stores profile, and removes the oldest profiles of its type so that at
+// most retain are kept.
+func (p *pprofCollector) store(ctx context.Context, profile *collectedProfile, retain int) error {
+	if len(profile.Data) > pprofCollectionMaxProfileSize {
+		return fmt.Errorf("profile of %d bytes exceeds the maximum of %d bytes", len(profile.Data), pprofCollectionMaxProfileSize)
+	}
+
+	entry, err := logical.StorageEntryJSON(pprofCollectionProfilePrefix+profile.ID, profile)
+	if err != nil {
+		return err
+	}
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if err := p.view.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	ids, err := p.listLocked(ctx)
+	if err != nil {
+		return err
+	}
+	var sameType []string
+	for _, id := range ids {
+		if strings.HasPrefix(id, profile.Type+"-") {
+			sameType = append(sameType, id)
+		}
+	}
+	for len(sameType) > retain {
+		if err := p.view.Delete(ctx, pprofCollectionProfilePrefix+sameType[0]); err != nil {
+			return err
+		}
+		sameType = sameType[1:]
+	}
+	return nil
+}
+
+// ship sends profile to the endpoint in the body of a POST request, with its
+// metadata in the query parameters.
+func (p *pprofCollector) ship(ctx context.Context, endpoint string, profile *collectedProfile) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("name", "vault."+profile.Type)
+	q.Set("type", profile.Type)
+	q.Set("node", profile.Node)
+	q.Set("from", strconv.FormatInt(profile.StartTime.Unix(), 10))
+	q.Set("until", strconv.FormatInt(profile.EndTime.Unix(), 10))
+	q.Set("format", "pprof")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(profile.Data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// list returns the identifiers of the stored profiles, oldest first for each
+// type.
+func (p *pprofCollector) list(ctx context.Context) ([]string, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.listLocked(ctx)
+}
+
+func (p *pprofCollector) listLocked(ctx context.Context) ([]string, error) {
+	ids, err := p.view.List(ctx, pprofCollectionProfilePrefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ti, ni := splitPprofProfileID(ids[i])
+		tj, nj := splitPprofProfileID(ids[j])
+		if ti != tj {
+			return ti < tj
+		}
+		return ni < nj
+	})
+	return ids, nil
+}
+
+func splitPprofProfileID(id string) (string, int64) {
+	idx := strings.LastIndexByte(id, '-')
+	if idx == -1 {
+		return id, 0
+	}
+	n, _ := strconv.ParseInt(id[idx+1:], 10, 64)
+	return id[:idx], n
+}
+
+func (p *pprofCollector) get(ctx context.Context, id string) (*collectedProfile, error) {
+	raw, err := p.view.Get(ctx, pprofCollectionProfilePrefix+id)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	profile := new(collectedProfile)
+	if err := raw.DecodeJSON(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (p *pprofCollector) delete(ctx context.Context, id string) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.view.Delete(ctx, pprofCollectionProfilePrefix+id)
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPprofCollection(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	ctx := namespace.RootContext(nil)
+
+	var l sync.Mutex
+	shipped := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		defer l.Unlock()
+		if r.Method != http.MethodPost || r.URL.Query().Get("node") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		shipped[r.URL.Query().Get("type")]++
+	}))
+	defer srv.Close()
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "pprof-collection/config")
+	req.Data["profiles"] = "heap,nope"
+	resp, err := b.HandleRequest(ctx, req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected an unknown profile to be rejected, got %v, %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "pprof-collection/config")
+	req.Data["profiles"] = "heap,goroutine"
+	req.Data["retain"] = 2
+	req.Data["endpoint_url"] = srv.URL
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	// Collect without waiting for the interval
+	config := c.pprofCollector.getConfig()
+	for i := 0; i < 3; i++ {
+		c.pprofCollector.collect(context.Background(), config)
+	}
+
+	l.Lock()
+	if shipped["heap"] != 3 || shipped["goroutine"] != 3 {
+		t.Fatalf("unexpected shipped profiles: %v", shipped)
+	}
+	l.Unlock()
+
+	req = logical.TestRequest(t, logical.ListOperation, "pprof-collection/profiles")
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 4 {
+		t.Fatalf("expected 2 profiles of each type to be retained, got %v", keys)
+	}
+	info := resp.Data["key_info"].(map[string]interface{})[keys[0]].(map[string]interface{})
+	if info["type"] != "goroutine" || info["size"].(int) == 0 {
+		t.Fatalf("unexpected profile info: %#v", info)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "pprof-collection/profiles/"+keys[0]+"/raw")
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	// Profiles are gzipped protobufs
+	if data := resp.Data[logical.HTTPRawBody].([]byte); !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		t.Fatalf("unexpected profile content: %q", data)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "pprof-collection/profiles/"+keys[0])
+	if _, err := b.HandleRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "pprof-collection/profiles/"+keys[0])
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp != nil {
+		t.Fatalf("expected the profile to be deleted, got %v, %#v", err, resp)
+	}
+}
@@ -0,0 +1,397 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// raftSnapshotMountRestore tracks the restore of the data of a mount from a
+// snapshot.
+type raftSnapshotMountRestore struct {
+	entry  *MountEntry
+	prefix string
+
+	// snapshotKeys holds the keys of the mount found in the snapshot, so that
+	// the other keys of the mount can be deleted.
+	snapshotKeys map[string]struct{}
+	written      int
+	unchanged    int
+	deleted      []string
+}
+
+// raftSnapshotRestoreFor returns the restore of the mount holding key, if any.
+func raftSnapshotRestoreFor(restores []*raftSnapshotMountRestore, key string) *raftSnapshotMountRestore {
+	for _, r := range restores {
+		if strings.HasPrefix(key, r.prefix) {
+			return r
+		}
+	}
+	return nil
+}
+
+// raftSnapshotMounts returns the secrets engines and auth methods of the
+// snapshot data written by WriteSnapshotToTemp, keyed by the storage prefix
+// of their data. The mount tables are decrypted with the current keyring.
+func (c *Core) raftSnapshotMounts(ctx context.Context, snap io.Reader) (map[string]*MountEntry, error) {
+	mounts := make(map[string]*MountEntry)
+	err := raft.ForEachSnapshotEntry(ctx, snap, func(key string, value []byte) error {
+		var barrierPrefix string
+		switch key {
+		case coreMountConfigPath, coreLocalMountConfigPath:
+			barrierPrefix = backendBarrierPrefix
+		case coreAuthConfigPath, coreLocalAuthConfigPath:
+			barrierPrefix = credentialBarrierPrefix
+		default:
+			return nil
+		}
+
+		raw, _, err := c.decryptRaftSnapshotEntry(ctx, key, value)
+		if err != nil {
+			return fmt.Errorf("%w: failed to decrypt the mount table of the snapshot, possibly it was taken with different keys: %v", logical.ErrInvalidRequest, err)
+		}
+		table := new(MountTable)
+		if err := jsonutil.DecodeJSON(raw, table); err != nil {
+			return fmt.Errorf("failed to decode the mount table of the snapshot: %w", err)
+		}
+		for _, entry := range table.Entries {
+			mounts[barrierPrefix+entry.UUID+"/"] = entry
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mounts, nil
+}
+
+// decryptRaftSnapshotEntry decrypts a value of the snapshot data, which is
+// stored as written to the physical backend: seal wrapped entries are
+// unwrapped with the seal before being decrypted by the barrier. It returns
+// whether the entry was seal wrapped.
+func (c *Core) decryptRaftSnapshotEntry(ctx context.Context, key string, value []byte) ([]byte, bool, error) {
+	value, sealWrapped, err := c.sealWrapper.unwrapValue(ctx, key, value)
+	if err != nil {
+		return nil, false, err
+	}
+	plain, err := c.barrier.Decrypt(ctx, key, value)
+	if err != nil {
+		return nil, false, err
+	}
+	return plain, sealWrapped, nil
+}
+
+// raftSnapshotMountPath returns the path of a mount as used in requests.
+func raftSnapshotMountPath(entry *MountEntry) string {
+	if entry.Table == credentialTableType {
+		return credentialRoutePrefix + entry.Path
+	}
+	return entry.Path
+}
+
+func raftSnapshotMountInfo(entry *MountEntry) map[string]interface{} {
+	info := map[string]interface{}{
+		"path":     raftSnapshotMountPath(entry),
+		"type":     entry.Type,
+		"accessor": entry.Accessor,
+	}
+	if entry.NamespaceID != "" && entry.NamespaceID != namespace.RootNamespaceID {
+		info["namespace_id"] = entry.NamespaceID
+	}
+	return info
+}
+
+func sortRaftSnapshotMountInfos(infos []map[string]interface{}) {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i]["path"].(string) < infos[j]["path"].(string)
+	})
+}
+
+// previewRaftSnapshot compares the snapshot data written by
+// WriteSnapshotToTemp with the current state of the cluster: the mounts that
+// restoring it would add, remove or move, and the number of keys under each
+// storage prefix.
+func (c *Core) previewRaftSnapshot(ctx context.Context, raftStorage *raft.RaftBackend, snapFile *os.File) (map[string]interface{}, error) {
+	snapMounts, err := c.raftSnapshotMounts(ctx, snapFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := snapFile.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	snapUsage, err := raft.SnapshotUsage(ctx, snapFile, 2)
+	if err != nil {
+		return nil, err
+	}
+	curUsage, err := raftStorage.StorageUsage(ctx, 2)
+	if err != nil {
+		return nil, err
+	}
+	curMounts := c.storageUsageMountPaths()
+
+	added := make([]map[string]interface{}, 0)
+	removed := make([]map[string]interface{}, 0)
+	moved := make([]map[string]interface{}, 0)
+	for prefix, entry := range snapMounts {
+		cur, ok := curMounts[prefix]
+		switch {
+		case !ok:
+			added = append(added, raftSnapshotMountInfo(entry))
+		case raftSnapshotMountPath(cur) != raftSnapshotMountPath(entry):
+			info := raftSnapshotMountInfo(entry)
+			info["current_path"] = raftSnapshotMountPath(cur)
+			moved = append(moved, info)
+		}
+	}
+	for prefix, entry := range curMounts {
+		if _, ok := snapMounts[prefix]; !ok {
+			removed = append(removed, raftSnapshotMountInfo(entry))
+		}
+	}
+	sortRaftSnapshotMountInfos(added)
+	sortRaftSnapshotMountInfos(removed)
+	sortRaftSnapshotMountInfos(moved)
+
+	// Name the prefixes of mounts that only exist in the snapshot, too.
+	allMounts := make(map[string]*MountEntry, len(curMounts)+len(snapMounts))
+	for prefix, entry := range snapMounts {
+		allMounts[prefix] = entry
+	}
+	for prefix, entry := range curMounts {
+		allMounts[prefix] = entry
+	}
+
+	prefixes := make(map[string]map[string]interface{})
+	prefixEntry := func(prefix string) map[string]interface{} {
+		if p, ok := prefixes[prefix]; ok {
+			return p
+		}
+		category, mountPath := storageUsageCategory(prefix, allMounts)
+		p := map[string]interface{}{
+			"prefix":         prefix,
+			"category":       category,
+			"current_keys":   int64(0),
+			"current_bytes":  int64(0),
+			"snapshot_keys":  int64(0),
+			"snapshot_bytes": int64(0),
+		}
+		if mountPath != "" {
+			p["mount"] = mountPath
+		}
+		prefixes[prefix] = p
+		return p
+	}
+	for _, u := range curUsage.Prefixes {
+		p := prefixEntry(u.Prefix)
+		p["current_keys"] = u.Keys
+		p["current_bytes"] = u.Bytes
+	}
+	for _, u := range snapUsage.Prefixes {
+		p := prefixEntry(u.Prefix)
+		p["snapshot_keys"] = u.Keys
+		p["snapshot_bytes"] = u.Bytes
+	}
+
+	prefixList := make([]map[string]interface{}, 0, len(prefixes))
+	for _, p := range prefixes {
+		prefixList = append(prefixList, p)
+	}
+	sort.Slice(prefixList, func(i, j int) bool {
+		return prefixList[i]["prefix"].(string) < prefixList[j]["prefix"].(string)
+	})
+
+	return map[string]interface{}{
+		"total_keys": map[string]int64{
+			"current":  curUsage.TotalKeys,
+			"snapshot": snapUsage.TotalKeys,
+		},
+		"total_bytes": map[string]int64{
+			"current":  curUsage.TotalBytes,
+			"snapshot": snapUsage.TotalBytes,
+		},
+		"mounts": map[string]interface{}{
+			"added":   added,
+			"removed": removed,
+			"moved":   moved,
+		},
+		"prefixes": prefixList,
+	}, nil
+}
+
+// restoreRaftSnapshotMounts restores the data of the given mounts of the root
+// namespace from the snapshot data written by WriteSnapshotToTemp. The mounts
+// must still exist. Entries are written through the barrier, and entries
+// missing from the snapshot are deleted. With dryRun set, nothing is changed
+// and only the counts are reported.
+func (c *Core) restoreRaftSnapshotMounts(ctx context.Context, snapFile *os.File, paths []string, dryRun bool) ([]map[string]interface{}, error) {
+	snapMounts, err := c.raftSnapshotMounts(ctx, snapFile)
+	if err != nil {
+		return nil, err
+	}
+	curMounts := c.storageUsageMountPaths()
+
+	var restores []*raftSnapshotMountRestore
+	for _, p := range paths {
+		p = strings.TrimPrefix(p, "/")
+		if !strings.HasSuffix(p, "/") {
+			p += "/"
+		}
+
+		var prefix string
+		for snapPrefix, entry := range snapMounts {
+			if entry.NamespaceID != "" && entry.NamespaceID != namespace.RootNamespaceID {
+				continue
+			}
+			if raftSnapshotMountPath(entry) == p {
+				prefix = snapPrefix
+				break
+			}
+		}
+		if prefix == "" {
+			return nil, fmt.Errorf("%w: no mount at %q in the snapshot", logical.ErrInvalidRequest, p)
+		}
+
+		entry, ok := curMounts[prefix]
+		if !ok {
+			return nil, fmt.Errorf("%w: the mount at %q in the snapshot no longer exists, only the data of existing mounts can be restored", logical.ErrInvalidRequest, p)
+		}
+		if strutil.StrListContains(singletonMounts, entry.Type) {
+			return nil, fmt.Errorf("%w: the %q mount cannot be restored selectively", logical.ErrInvalidRequest, p)
+		}
+
+		if raftSnapshotRestoreFor(restores, prefix) == nil {
+			restores = append(restores, &raftSnapshotMountRestore{
+				entry:        entry,
+				prefix:       prefix,
+				snapshotKeys: make(map[string]struct{}),
+			})
+		}
+	}
+
+	// Every entry is decrypted before anything is changed, so that a restore
+	// is not interrupted half way by entries encrypted with unknown keys.
+	if _, err := snapFile.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	err = raft.ForEachSnapshotEntry(ctx, snapFile, func(key string, value []byte) error {
+		r := raftSnapshotRestoreFor(restores, key)
+		if r == nil {
+			return nil
+		}
+
+		plain, _, err := c.decryptRaftSnapshotEntry(ctx, key, value)
+		if err != nil {
+			return fmt.Errorf("%w: failed to decrypt %q from the snapshot: %v", logical.ErrInvalidRequest, key, err)
+		}
+		r.snapshotKeys[key] = struct{}{}
+
+		current, err := c.barrier.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if current != nil && bytes.Equal(current.Value, plain) {
+			r.unchanged++
+		} else {
+			r.written++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range restores {
+		keys, err := logical.CollectKeys(ctx, NewBarrierView(c.barrier, r.prefix))
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if _, ok := r.snapshotKeys[r.prefix+key]; !ok {
+				r.deleted = append(r.deleted, r.prefix+key)
+			}
+		}
+	}
+
+	if !dryRun {
+		if err := c.applyRaftSnapshotMountRestores(ctx, snapFile, restores); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]map[string]interface{}, 0, len(restores))
+	for _, r := range restores {
+		results = append(results, map[string]interface{}{
+			"path":      raftSnapshotMountPath(r.entry),
+			"type":      r.entry.Type,
+			"written":   r.written,
+			"unchanged": r.unchanged,
+			"deleted":   len(r.deleted),
+		})
+	}
+	return results, nil
+}
+
+// applyRaftSnapshotMountRestores writes the entries of the mounts being
+// restored, deletes the entries missing from the snapshot and reloads the
+// mounts so that they drop any cached state.
+func (c *Core) applyRaftSnapshotMountRestores(ctx context.Context, snapFile *os.File, restores []*raftSnapshotMountRestore) error {
+	if _, err := snapFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	err := raft.ForEachSnapshotEntry(ctx, snapFile, func(key string, value []byte) error {
+		if raftSnapshotRestoreFor(restores, key) == nil {
+			return nil
+		}
+
+		plain, sealWrapped, err := c.decryptRaftSnapshotEntry(ctx, key, value)
+		if err != nil {
+			return err
+		}
+		current, err := c.barrier.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if current != nil && bytes.Equal(current.Value, plain) {
+			return nil
+		}
+		return c.barrier.Put(ctx, &logical.StorageEntry{
+			Key:      key,
+			Value:    plain,
+			SealWrap: sealWrapped,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore snapshot entries: %w", err)
+	}
+
+	for _, r := range restores {
+		for _, key := range r.deleted {
+			if err := c.barrier.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to delete %q: %w", key, err)
+			}
+		}
+	}
+
+	c.mountsLock.RLock()
+	defer c.mountsLock.RUnlock()
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+
+	for _, r := range restores {
+		if err := c.reloadBackendCommon(ctx, r.entry, r.entry.Table == credentialTableType); err != nil {
+			return fmt.Errorf("failed to reload %q after restoring it: %w", raftSnapshotMountPath(r.entry), err)
+		}
+	}
+	return nil
+}
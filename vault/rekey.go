@@ -42,6 +42,10 @@ type RekeyResult struct {
 	RecoveryKey          bool
 	VerificationRequired bool
 	VerificationNonce    string
+
+	// TOTPKeys are the TOTP keys of the new shares, in the same order, when
+	// they are TOTP protected.
+	TOTPKeys [][]byte
 }
 
 type RekeyVerifyResult struct {
@@ -192,6 +196,9 @@ func (c *Core) BarrierRekeyInit(config *SealConfig) logical.HTTPCodedError {
 		if config.Backup {
 			return logical.CodedError(http.StatusBadRequest, "key backup not supported when using stored keys")
 		}
+		if config.TOTPProtected {
+			return logical.CodedError(http.StatusBadRequest, "TOTP protected shares not supported when using stored keys")
+		}
 	}
 
 	if c.seal.RecoveryKeySupported() {
@@ -246,6 +253,9 @@ func (c *Core) RecoveryRekeyInit(config *SealConfig) logical.HTTPCodedError {
 	if config.StoredShares > 0 {
 		return logical.CodedError(http.StatusBadRequest, "stored shares not supported by recovery key")
 	}
+	if config.TOTPProtected {
+		return logical.CodedError(http.StatusBadRequest, "TOTP protected shares not supported by recovery key")
+	}
 
 	// Check if the seal configuration is valid
 	if err := config.Validate(); err != nil {
@@ -451,6 +461,17 @@ func (c *Core) BarrierRekeyUpdate(ctx context.Context, key []byte, nonce string)
 		}
 	}
 
+	// Bind the new shares to the TOTP secrets of their holders, stored once
+	// the rekey completes.
+	c.barrierRekeyConfig.totpBindings = nil
+	if c.barrierRekeyConfig.TOTPProtected {
+		c.barrierRekeyConfig.totpBindings, results.TOTPKeys, err = c.newUnsealTOTPBindings(ctx, results.SecretShares, c.barrierRekeyConfig.PGPKeys)
+		if err != nil {
+			c.logger.Error("failed to generate TOTP secrets", "error", err)
+			return nil, logical.CodedError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
 	// If PGP keys are passed in, encrypt shares with corresponding PGP keys.
 	if len(c.barrierRekeyConfig.PGPKeys) > 0 {
 		hexEncodedShares := make([][]byte, len(results.SecretShares))
@@ -572,6 +593,13 @@ func (c *Core) performBarrierRekey(ctx context.Context, newSealKey []byte) logic
 		return logical.CodedError(http.StatusInternalServerError, fmt.Errorf("failed to save rekey seal configuration: %w", err).Error())
 	}
 
+	// Replace the TOTP secrets of the previous shares, if any
+	if err := c.persistUnsealTOTPBindings(ctx, c.barrierRekeyConfig.totpBindings); err != nil {
+		c.logger.Error("error saving unseal TOTP secrets", "error", err)
+		return logical.CodedError(http.StatusInternalServerError, err.Error())
+	}
+	c.barrierRekeyConfig.totpBindings = nil
+
 	// Write to the canary path, which will force a synchronous truing during
 	// replication
 	if err := c.barrier.Put(ctx, &logical.StorageEntry{
@@ -59,6 +59,11 @@ type HandlerProperties struct {
 	DisablePrintableCheck bool
 	RecoveryMode          bool
 	RecoveryToken         *uberAtomic.String
+
+	// ClientCertHeaders are the client certificate headers of all listeners,
+	// removed from every request so that they can't be forged on listeners
+	// that don't set them.
+	ClientCertHeaders []string
 }
 
 // fetchEntityAndDerivedPolicies returns the entity object for the given entity
@@ -137,16 +142,25 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 		return nil, nil, nil, nil, logical.ErrPermissionDenied
 	}
 
+	// Read-only recovery tokens are never in the token store, so that they
+	// can be used when it is unavailable
+	te, err := c.readOnlyRecoveryTokenEntry(ctx, req.ClientToken)
+	if err != nil {
+		c.logger.Error("failed to lookup read-only recovery token", "error", err)
+		return nil, nil, nil, nil, ErrInternalError
+	}
+	if te != nil {
+		return c.fetchReadOnlyRecoveryACLAndTokenEntry(ctx, req, te)
+	}
+
 	if c.tokenStore == nil {
 		c.logger.Error("token store is unavailable")
 		return nil, nil, nil, nil, ErrInternalError
 	}
 
 	// Resolve the token policy
-	var te *logical.TokenEntry
 	switch req.TokenEntry() {
 	case nil:
-		var err error
 		te, err = c.tokenStore.Lookup(ctx, req.ClientToken)
 		if err != nil {
 			c.logger.Error("failed to lookup acl token", "error", err)
@@ -184,6 +198,18 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 		}
 	}
 
+	// Country and ASN constraints bind all tokens except non-expiring root
+	// tokens, like CIDRs
+	if te.TTL != 0 && !c.checkGeoConstraints(te.GeoConstraints, req) {
+		return nil, nil, nil, nil, logical.ErrPermissionDenied
+	}
+
+	// Tokens bound to a TLS client certificate can only be used over
+	// connections presenting it
+	if te.BoundClientCertFingerprint != "" && !tokenClientCertMatches(te, req) {
+		return nil, nil, nil, nil, logical.ErrPermissionDenied
+	}
+
 	policyNames := make(map[string][]string)
 	// Add tokens policies
 	policyNames[te.NamespaceID] = append(policyNames[te.NamespaceID], te.Policies...)
@@ -225,14 +251,19 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 		tokenCtx = namespace.ContextWithNamespace(ctx, tokenNS)
 	}
 
-	// Add the inline policy if it's set
+	// Add the inline policy if it's set, unless it scopes down the token
 	policies := make([]*Policy, 0)
+	var scopePolicy *Policy
 	if te.InlinePolicy != "" {
 		inlinePolicy, err := ParseACLPolicy(tokenNS, te.InlinePolicy)
 		if err != nil {
 			return nil, nil, nil, nil, ErrInternalError
 		}
-		policies = append(policies, inlinePolicy)
+		if inlinePolicyIsScope(te) {
+			scopePolicy = inlinePolicy
+		} else {
+			policies = append(policies, inlinePolicy)
+		}
 	}
 
 	// Construct the corresponding ACL object. ACL construction should be
@@ -246,6 +277,12 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 		c.logger.Error("failed to construct ACL", "error", err)
 		return nil, nil, nil, nil, ErrInternalError
 	}
+	if scopePolicy != nil {
+		if err := acl.setScope(tokenCtx, scopePolicy); err != nil {
+			c.logger.Error("failed to construct ACL scope", "error", err)
+			return nil, nil, nil, nil, ErrInternalError
+		}
+	}
 
 	return acl, te, entity, identityPolicies, nil
 }
@@ -421,6 +458,22 @@ func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool
 		auth.PolicyResults.GrantingPolicies = append(auth.PolicyResults.GrantingPolicies, authResults.SentinelResults.GrantingPolicies...)
 	}
 
+	// Require a fresh MFA assertion on paths protected by step-up
+	// enforcements
+	if !unauth {
+		if err := c.checkStepUpMFA(ctx, req, te, entity); err != nil {
+			if err == logical.ErrPerfStandbyPleaseForward && len(req.ClientToken) != 0 {
+				switch req.ClientTokenSource {
+				case logical.ClientTokenFromVaultHeader:
+					req.Headers[consts.AuthHeaderName] = []string{req.ClientToken}
+				case logical.ClientTokenFromAuthzHeader:
+					req.Headers["Authorization"] = append(req.Headers["Authorization"], fmt.Sprintf("Bearer %s", req.ClientToken))
+				}
+			}
+			return auth, te, err
+		}
+	}
+
 	// If it is an authenticated ( i.e with vault token ) request, increment client count
 	if !unauth && c.activityLog != nil {
 		c.activityLog.HandleTokenUsage(ctx, te, clientID, isTWE)
@@ -497,6 +550,9 @@ func (c *Core) handleCancelableRequest(ctx context.Context, req *logical.Request
 	// as it is depended on by some functionality (e.g. quotas)
 	req.MountPoint = c.router.MatchingMount(ctx, req.Path)
 
+	// Resolve the country and ASN of the client for audit logs
+	c.populateGeoIP(req)
+
 	// Decrement the wait group when our request is done
 	if waitGroup != nil {
 		defer waitGroup.Done()
@@ -858,7 +914,7 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	// Updating in-flight request data with client/entity ID
 	inFlightReqID, ok := ctx.Value(logical.CtxKeyInFlightRequestID{}).(string)
 	if ok && req.ClientID != "" {
-		c.UpdateInFlightReqData(inFlightReqID, req.ClientID)
+		c.UpdateInFlightReqData(inFlightReqID, req.ClientID, auth)
 	}
 
 	// We run this logic first because we want to decrement the use count even
@@ -972,6 +1028,51 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		return nil, nil, multierror.Append(retErr, err)
 	}
 
+	// Refuse deleting locked KV secrets unless they were unlocked recently
+	if err := c.checkKVLock(ctx, entry, req); err != nil {
+		if errors.Is(err, errKVSecretLocked) {
+			retErr = multierror.Append(retErr, logical.ErrInvalidRequest)
+			return logical.ErrorResponse(err.Error()), auth, retErr
+		}
+		c.logger.Error("failed to check KV lock", "path", req.Path, "error", err)
+		retErr = multierror.Append(retErr, ErrInternalError)
+		return nil, auth, retErr
+	}
+
+	// Validate the KV secrets written against the schema of their path
+	if err := c.checkKVSchema(ctx, entry, req); err != nil {
+		var schemaErr *kvSchemaError
+		if errors.As(err, &schemaErr) {
+			retErr = multierror.Append(retErr, logical.ErrInvalidRequest)
+			return schemaErr.response(), auth, retErr
+		}
+		c.logger.Error("failed to check KV schema", "path", req.Path, "error", err)
+		retErr = multierror.Append(retErr, ErrInternalError)
+		return nil, auth, retErr
+	}
+
+	// Return the outcome of a previous request with the same idempotency key
+	// rather than performing the request again
+	idempotentReq, err := newIdempotentRequest(entry, req, te)
+	if err != nil {
+		c.logger.Error("failed to fingerprint request for idempotency key", "path", req.Path, "error", err)
+		retErr = multierror.Append(retErr, ErrInternalError)
+		return nil, auth, retErr
+	}
+	if idempotentReq != nil {
+		replayed, replayedResp, err := c.beginIdempotentRequest(idempotentReq)
+		if err != nil {
+			retErr = multierror.Append(retErr, logical.ErrInvalidRequest)
+			return logical.ErrorResponse(err.Error()), auth, retErr
+		}
+		if replayed {
+			return replayedResp, auth, nil
+		}
+		defer func() {
+			c.finishIdempotentRequest(idempotentReq, retResp, retErr)
+		}()
+	}
+
 	leaseGenerated := false
 	loginRole := c.DetermineRoleFromLoginRequest(req.MountPoint, req.Data, ctx)
 	quotaResp, quotaErr := c.applyLeaseCountQuota(ctx, &quotas.Request{
@@ -1006,6 +1107,10 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 	// Route the request
 	resp, routeErr := c.doRouting(ctx, req)
+	if resp != nil && (resp.Secret != nil || resp.Auth != nil) && isReadOnlyRecoveryTokenEntry(te) {
+		retErr = multierror.Append(retErr, c.rejectReadOnlyRecoveryResponse(ctx, req, resp))
+		return nil, auth, retErr
+	}
 	if resp != nil {
 
 		// If wrapping is used, use the shortest between the request and response
@@ -1268,7 +1373,7 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 	// Updating in-flight request data with client/entity ID
 	inFlightReqID, ok := ctx.Value(logical.CtxKeyInFlightRequestID{}).(string)
 	if ok && req.ClientID != "" {
-		c.UpdateInFlightReqData(inFlightReqID, req.ClientID)
+		c.UpdateInFlightReqData(inFlightReqID, req.ClientID, auth)
 	}
 
 	if ctErr != nil {
@@ -1391,6 +1496,13 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 			return
 		}
 
+		// Deny the login if the client can't use the token it would be
+		// issued because of its country or autonomous system
+		if !c.checkGeoConstraints(resp.Auth.GeoConstraints, req) {
+			retErr = multierror.Append(retErr, logical.ErrPermissionDenied)
+			return
+		}
+
 		// The request successfully authenticated itself. Run the quota checks
 		// before creating lease.
 		quotaResp, quotaErr := c.applyLeaseCountQuota(ctx, &quotas.Request{
@@ -1490,6 +1602,10 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 				return nil, nil, err
 			}
 			auth.GroupAliases = validAliases
+
+			if err := c.identityStore.refreshDynamicGroupMembershipsByEntityID(ctx, auth.EntityID); err != nil {
+				return nil, nil, err
+			}
 		}
 
 	CREATE_TOKEN:
@@ -1668,6 +1784,10 @@ func (c *Core) LoginCreateToken(ctx context.Context, ns *namespace.Namespace, re
 		}
 	}
 
+	if auth.TokenType == logical.TokenTypeBatch && !auth.GeoConstraints.Empty() {
+		return false, logical.ErrorResponse("batch tokens cannot be issued with country or ASN constraints"), logical.ErrInvalidRequest
+	}
+
 	var registerFunc RegisterAuthFunc
 	var funcGetErr error
 	// Batch tokens should not be forwarded to perf standby
@@ -1771,6 +1891,7 @@ func (c *Core) RegisterAuth(ctx context.Context, tokenTTL time.Duration, path st
 		NumUses:        auth.NumUses,
 		EntityID:       auth.EntityID,
 		BoundCIDRs:     auth.BoundCIDRs,
+		GeoConstraints: auth.GeoConstraints,
 		Policies:       auth.TokenPolicies,
 		NamespaceID:    ns.ID,
 		ExplicitMaxTTL: auth.ExplicitMaxTTL,
@@ -1862,7 +1983,20 @@ func (c *Core) PopulateTokenEntry(ctx context.Context, req *logical.Request) err
 		}
 	}
 	req.ClientToken = token
-	te, err := c.LookupToken(ctx, token)
+	te, err := c.readOnlyRecoveryTokenEntry(ctx, token)
+	if err != nil {
+		return fmt.Errorf("error performing token check: %w", err)
+	}
+	if te != nil {
+		req.SetTokenEntry(te)
+		return nil
+	}
+	// Tokens of the token store can't contain dots, so other recovery
+	// tokens, such as expired ones, are invalid
+	if isRecoveryToken(token) {
+		return logical.ErrPermissionDenied
+	}
+	te, err = c.LookupToken(ctx, token)
 	if err != nil {
 		// If we have two dots but the second char is a dot it's a vault
 		// token of the form s.SOMETHING.nsid, not a JWT
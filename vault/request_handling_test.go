@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -470,3 +471,87 @@ func TestRequestHandling_SecretLeaseMetric(t *testing.T) {
 		},
 	)
 }
+
+func TestRequestHandling_IdempotencyKey(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+
+	var calls int
+	n := &NoopBackend{
+		RequestHandler: func(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+			calls++
+			return &logical.Response{
+				Data: map[string]interface{}{
+					"call": calls,
+				},
+			}, nil
+		},
+	}
+	core.logicalBackends["noop"] = func(context.Context, *logical.BackendConfig) (logical.Backend, error) {
+		return n, nil
+	}
+
+	meUUID, _ := uuid.GenerateUUID()
+	err := core.mount(namespace.RootContext(nil), &MountEntry{
+		Table: mountTableType,
+		UUID:  meUUID,
+		Path:  "idempotent",
+		Type:  "noop",
+		Config: MountConfig{
+			IdempotencyKeyTTL: time.Minute,
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	write := func(key string, data map[string]interface{}) (*logical.Response, error) {
+		return core.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Path:           "idempotent/issue",
+			ClientToken:    root,
+			Operation:      logical.UpdateOperation,
+			Data:           data,
+			IdempotencyKey: key,
+		})
+	}
+
+	resp, err := write("key1", map[string]interface{}{"name": "foo"})
+	if err != nil || resp == nil || resp.Data["call"] != 1 {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Retries with the same key return the original response
+	resp, err = write("key1", map[string]interface{}{"name": "foo"})
+	if err != nil || resp == nil || resp.Data["call"] != 1 {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("expected a replay warning, got: %v", resp.Warnings)
+	}
+	if calls != 1 {
+		t.Fatalf("expected the backend to be called once, got %d", calls)
+	}
+
+	// The same key can't be used for a different request
+	resp, err = write("key1", map[string]interface{}{"name": "bar"})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error, got resp: %#v", resp)
+	}
+
+	resp, err = write("key2", map[string]interface{}{"name": "foo"})
+	if err != nil || resp == nil || resp.Data["call"] != 2 {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = write("", map[string]interface{}{"name": "foo"})
+	if err != nil || resp == nil || resp.Data["call"] != 3 {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Keys are ignored on mounts without a retention window
+	me := core.router.MatchingMountEntry(namespace.RootContext(nil), "idempotent/")
+	me.Config.IdempotencyKeyTTL = 0
+	resp, err = write("key1", map[string]interface{}{"name": "foo"})
+	if err != nil || resp == nil || resp.Data["call"] != 4 {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+}
@@ -0,0 +1,406 @@
+package vault
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// talkersBucketWidth is the granularity of the rolling window of top
+	// talkers, and talkersMaxWindow its length.
+	talkersBucketWidth = time.Minute
+	talkersMaxWindow   = 15 * time.Minute
+
+	// talkersMaxKeysPerBucket bounds the memory used by each bucket; once
+	// reached, new keys are accounted for under talkersOtherKey.
+	talkersMaxKeysPerBucket = 10000
+	talkersOtherKey         = "_other"
+
+	talkersSubPath         = countersSubPath + "talkers/"
+	talkersConfigPath      = "config"
+	talkersSnapshotPrefix  = "snapshots/"
+	talkersMaxSnapshots    = 20
+	talkersDefaultWindow   = 5 * time.Minute
+	talkersDefaultLimit    = 10
+	talkersDefaultCooldown = 5 * time.Minute
+
+	TalkersSortByRequests = "requests"
+	TalkersSortByLatency  = "latency"
+)
+
+// talkersCheckInterval is how often the number of in-flight requests is
+// compared with the snapshot threshold. It is a variable so tests can
+// shorten it.
+var talkersCheckInterval = time.Second
+
+// TalkerStats is the load generated by a single token, entity or client
+// address.
+type TalkerStats struct {
+	Key       string        `json:"key" mapstructure:"key"`
+	Requests  int64         `json:"requests" mapstructure:"requests"`
+	Latency   time.Duration `json:"-" mapstructure:"-"`
+	LatencyMs int64         `json:"latency_ms" mapstructure:"latency_ms"`
+}
+
+// TalkersReport lists the top talkers over a window of time.
+type TalkersReport struct {
+	StartTime       time.Time      `json:"start_time" mapstructure:"start_time"`
+	EndTime         time.Time      `json:"end_time" mapstructure:"end_time"`
+	SortBy          string         `json:"sort_by" mapstructure:"sort_by"`
+	InFlight        uint64         `json:"in_flight_requests" mapstructure:"in_flight_requests"`
+	Tokens          []*TalkerStats `json:"tokens" mapstructure:"tokens"`
+	Entities        []*TalkerStats `json:"entities" mapstructure:"entities"`
+	ClientAddresses []*TalkerStats `json:"client_addresses" mapstructure:"client_addresses"`
+}
+
+// talkersConfig controls the automatic snapshots of the top talkers.
+type talkersConfig struct {
+	// SnapshotThreshold is the number of in-flight requests from which a
+	// snapshot is taken; 0 disables snapshots.
+	SnapshotThreshold int           `json:"snapshot_threshold"`
+	SnapshotCooldown  time.Duration `json:"snapshot_cooldown"`
+	SnapshotWindow    time.Duration `json:"snapshot_window"`
+	SnapshotLimit     int           `json:"snapshot_limit"`
+}
+
+type talkersBucket struct {
+	start     time.Time
+	tokens    map[string]*TalkerStats
+	entities  map[string]*TalkerStats
+	addresses map[string]*TalkerStats
+}
+
+func newTalkersBucket(start time.Time) *talkersBucket {
+	return &talkersBucket{
+		start:     start,
+		tokens:    make(map[string]*TalkerStats),
+		entities:  make(map[string]*TalkerStats),
+		addresses: make(map[string]*TalkerStats),
+	}
+}
+
+func addTalker(m map[string]*TalkerStats, key string, latency time.Duration) {
+	if key == "" {
+		return
+	}
+	s, ok := m[key]
+	if !ok {
+		if len(m) >= talkersMaxKeysPerBucket {
+			key = talkersOtherKey
+			s, ok = m[key]
+		}
+		if !ok {
+			s = &TalkerStats{Key: key}
+			m[key] = s
+		}
+	}
+	s.Requests++
+	s.Latency += latency
+}
+
+// requestTalkers keeps a rolling window of the requests handled by this
+// node, aggregated by token accessor, entity and client address.
+type requestTalkers struct {
+	l       sync.Mutex
+	buckets []*talkersBucket
+
+	// The fields below are only set while the node is unsealed, and are
+	// used to store snapshots.
+	snapshotLock sync.Mutex
+	config       talkersConfig
+	view         *BarrierView
+	lastSnapshot time.Time
+	logger       log.Logger
+	cancel       context.CancelFunc
+	doneCh       chan struct{}
+}
+
+func newRequestTalkers() *requestTalkers {
+	return &requestTalkers{
+		buckets: make([]*talkersBucket, int(talkersMaxWindow/talkersBucketWidth)),
+	}
+}
+
+// record accounts for a completed request.
+func (t *requestTalkers) record(data InFlightReqData, now time.Time) {
+	latency := now.Sub(data.StartTime)
+	addr := data.ClientRemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	start := now.Truncate(talkersBucketWidth)
+	idx := int(start.Unix()/int64(talkersBucketWidth.Seconds())) % len(t.buckets)
+
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	b := t.buckets[idx]
+	if b == nil || !b.start.Equal(start) {
+		b = newTalkersBucket(start)
+		t.buckets[idx] = b
+	}
+	addTalker(b.tokens, data.TokenAccessor, latency)
+	addTalker(b.entities, data.EntityID, latency)
+	addTalker(b.addresses, addr, latency)
+}
+
+// top returns the limit top talkers of each kind over the given window.
+func (t *requestTalkers) top(window time.Duration, limit int, sortBy string, now time.Time) *TalkersReport {
+	if window > talkersMaxWindow {
+		window = talkersMaxWindow
+	}
+	report := &TalkersReport{
+		StartTime: now.Add(-window).Truncate(talkersBucketWidth),
+		EndTime:   now,
+		SortBy:    sortBy,
+	}
+
+	tokens := make(map[string]*TalkerStats)
+	entities := make(map[string]*TalkerStats)
+	addresses := make(map[string]*TalkerStats)
+	merge := func(dst, src map[string]*TalkerStats) {
+		for k, s := range src {
+			d, ok := dst[k]
+			if !ok {
+				d = &TalkerStats{Key: k}
+				dst[k] = d
+			}
+			d.Requests += s.Requests
+			d.Latency += s.Latency
+		}
+	}
+
+	t.l.Lock()
+	for _, b := range t.buckets {
+		if b == nil || b.start.Before(report.StartTime) || b.start.After(now) {
+			continue
+		}
+		merge(tokens, b.tokens)
+		merge(entities, b.entities)
+		merge(addresses, b.addresses)
+	}
+	t.l.Unlock()
+
+	report.Tokens = topTalkers(tokens, limit, sortBy)
+	report.Entities = topTalkers(entities, limit, sortBy)
+	report.ClientAddresses = topTalkers(addresses, limit, sortBy)
+	return report
+}
+
+func topTalkers(m map[string]*TalkerStats, limit int, sortBy string) []*TalkerStats {
+	talkers := make([]*TalkerStats, 0, len(m))
+	for _, s := range m {
+		s.LatencyMs = s.Latency.Milliseconds()
+		talkers = append(talkers, s)
+	}
+
+	sort.Slice(talkers, func(i, j int) bool {
+		a, b := talkers[i], talkers[j]
+		if sortBy == TalkersSortByLatency && a.Latency != b.Latency {
+			return a.Latency > b.Latency
+		}
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Key < b.Key
+	})
+
+	if limit > 0 && len(talkers) > limit {
+		talkers = talkers[:limit]
+	}
+	return talkers
+}
+
+func (c *Core) setupRequestTalkers(ctx context.Context) error {
+	t := c.requestTalkers
+	view := c.systemBarrierView.SubView(talkersSubPath)
+
+	logger := c.baseLogger.Named("talkers")
+	c.AddLogger(logger)
+
+	config := talkersConfig{}
+	raw, err := view.Get(ctx, talkersConfigPath)
+	if err != nil {
+		return err
+	}
+	if raw != nil {
+		if err := raw.DecodeJSON(&config); err != nil {
+			return err
+		}
+	}
+
+	t.snapshotLock.Lock()
+	t.view = view
+	t.config = config
+	t.logger = logger
+	t.snapshotLock.Unlock()
+
+	// Snapshots are only stored by the active node, which handles the
+	// bulk of the requests.
+	if c.perfStandby {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(namespace.RootContext(ctx))
+	t.cancel = cancel
+	t.doneCh = make(chan struct{})
+	go func() {
+		defer close(t.doneCh)
+
+		ticker := time.NewTicker(talkersCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.checkOverload(ctx, c.inFlightReqData.InFlightReqCount.Load())
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *Core) stopRequestTalkers() {
+	t := c.requestTalkers
+	if t.cancel != nil {
+		t.cancel()
+		<-t.doneCh
+		t.cancel = nil
+	}
+
+	t.snapshotLock.Lock()
+	t.view = nil
+	t.snapshotLock.Unlock()
+}
+
+// checkOverload stores a snapshot of the top talkers if the number of
+// in-flight requests reached the configured threshold.
+func (t *requestTalkers) checkOverload(ctx context.Context, inFlight uint64) {
+	t.snapshotLock.Lock()
+	defer t.snapshotLock.Unlock()
+
+	config := t.config
+	if config.SnapshotThreshold <= 0 || inFlight < uint64(config.SnapshotThreshold) || t.view == nil {
+		return
+	}
+
+	now := time.Now()
+	cooldown := config.SnapshotCooldown
+	if cooldown == 0 {
+		cooldown = talkersDefaultCooldown
+	}
+	if now.Sub(t.lastSnapshot) < cooldown {
+		return
+	}
+	t.lastSnapshot = now
+
+	window, limit := config.SnapshotWindow, config.SnapshotLimit
+	if window == 0 {
+		window = talkersDefaultWindow
+	}
+	if limit == 0 {
+		limit = talkersDefaultLimit
+	}
+	report := t.top(window, limit, TalkersSortByRequests, now)
+	report.InFlight = inFlight
+
+	t.logger.Warn("in-flight requests over threshold, storing top talkers snapshot", "in_flight", inFlight, "threshold", config.SnapshotThreshold)
+	if err := t.storeSnapshotLocked(ctx, report); err != nil {
+		t.logger.Error("failed to store top talkers snapshot", "error", err)
+	}
+}
+
+func (t *requestTalkers) storeSnapshotLocked(ctx context.Context, report *TalkersReport) error {
+	id := strconv.FormatInt(report.EndTime.UnixNano(), 10)
+	entry, err := logical.StorageEntryJSON(talkersSnapshotPrefix+id, report)
+	if err != nil {
+		return err
+	}
+	if err := t.view.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	// Identifiers are timestamps of equal length, so they sort in order.
+	ids, err := t.view.List(ctx, talkersSnapshotPrefix)
+	if err != nil {
+		return err
+	}
+	sort.Strings(ids)
+	for len(ids) > talkersMaxSnapshots {
+		if err := t.view.Delete(ctx, talkersSnapshotPrefix+ids[0]); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+func (t *requestTalkers) getConfig() (talkersConfig, bool) {
+	t.snapshotLock.Lock()
+	defer t.snapshotLock.Unlock()
+	return t.config, t.view != nil
+}
+
+func (t *requestTalkers) setConfig(ctx context.Context, config talkersConfig) error {
+	t.snapshotLock.Lock()
+	defer t.snapshotLock.Unlock()
+
+	if t.view == nil {
+		return consts.ErrSealed
+	}
+	entry, err := logical.StorageEntryJSON(talkersConfigPath, config)
+	if err != nil {
+		return err
+	}
+	if err := t.view.Put(ctx, entry); err != nil {
+		return err
+	}
+	t.config = config
+	return nil
+}
+
+func (t *requestTalkers) listSnapshots(ctx context.Context) ([]string, error) {
+	t.snapshotLock.Lock()
+	defer t.snapshotLock.Unlock()
+
+	if t.view == nil {
+		return nil, consts.ErrSealed
+	}
+	ids, err := t.view.List(ctx, talkersSnapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (t *requestTalkers) getSnapshot(ctx context.Context, id string) (*TalkersReport, error) {
+	t.snapshotLock.Lock()
+	defer t.snapshotLock.Unlock()
+
+	if t.view == nil {
+		return nil, consts.ErrSealed
+	}
+	raw, err := t.view.Get(ctx, talkersSnapshotPrefix+id)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	var report TalkersReport
+	if err := raw.DecodeJSON(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
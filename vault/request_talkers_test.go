@@ -0,0 +1,103 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestRequestTalkers_Top(t *testing.T) {
+	talkers := newRequestTalkers()
+	now := time.Now()
+
+	record := func(accessor, addr string, latency time.Duration, at time.Time) {
+		talkers.record(InFlightReqData{
+			StartTime:        at.Add(-latency),
+			ClientRemoteAddr: addr,
+			TokenAccessor:    accessor,
+			EntityID:         "entity-" + accessor,
+		}, at)
+	}
+
+	for i := 0; i < 5; i++ {
+		record("busy", "10.0.0.1:1234", time.Millisecond, now)
+	}
+	record("slow", "10.0.0.2:1234", time.Second, now)
+	// Outside of the window.
+	record("old", "10.0.0.3:1234", time.Millisecond, now.Add(-10*time.Minute))
+
+	report := talkers.top(5*time.Minute, 10, TalkersSortByRequests, now)
+	if len(report.Tokens) != 2 || report.Tokens[0].Key != "busy" || report.Tokens[0].Requests != 5 {
+		t.Fatalf("unexpected tokens: %#v", report.Tokens)
+	}
+	if report.Entities[0].Key != "entity-busy" {
+		t.Fatalf("unexpected entities: %#v", report.Entities)
+	}
+	if report.ClientAddresses[0].Key != "10.0.0.1" {
+		t.Fatalf("client address port not stripped: %#v", report.ClientAddresses)
+	}
+
+	report = talkers.top(5*time.Minute, 1, TalkersSortByLatency, now)
+	if len(report.Tokens) != 1 || report.Tokens[0].Key != "slow" || report.Tokens[0].LatencyMs != 1000 {
+		t.Fatalf("unexpected tokens: %#v", report.Tokens)
+	}
+
+	report = talkers.top(15*time.Minute, 10, TalkersSortByRequests, now)
+	if len(report.Tokens) != 3 {
+		t.Fatalf("expected 3 tokens over the whole window, got %#v", report.Tokens)
+	}
+}
+
+func TestRequestTalkers_Snapshot(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	ctx := namespace.RootContext(nil)
+
+	c.requestTalkers.record(InFlightReqData{
+		StartTime:        time.Now(),
+		ClientRemoteAddr: "10.0.0.1:1234",
+		TokenAccessor:    "accessor",
+	}, time.Now())
+
+	req := logical.TestRequest(t, logical.ReadOperation, "internal/counters/talkers")
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if tokens := resp.Data["tokens"].([]*TalkerStats); len(tokens) != 1 || tokens[0].Key != "accessor" {
+		t.Fatalf("unexpected tokens: %#v", tokens)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "internal/counters/talkers/config")
+	req.Data["snapshot_threshold"] = 2
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	// Below the threshold, then above it twice within the cooldown.
+	c.requestTalkers.checkOverload(context.Background(), 1)
+	c.requestTalkers.checkOverload(context.Background(), 2)
+	c.requestTalkers.checkOverload(context.Background(), 3)
+
+	req = logical.TestRequest(t, logical.ListOperation, "internal/counters/talkers/snapshots")
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 1 {
+		t.Fatalf("expected a single snapshot, got %v", keys)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "internal/counters/talkers/snapshots/"+keys[0])
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["in_flight_requests"] != uint64(2) {
+		t.Fatalf("unexpected snapshot: %#v", resp.Data)
+	}
+}
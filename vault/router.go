@@ -12,6 +12,7 @@ import (
 	"github.com/armon/go-metrics"
 	"github.com/armon/go-radix"
 	"github.com/hashicorp/go-hclog"
+	iradix "github.com/hashicorp/go-immutable-radix"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -26,36 +27,94 @@ var deniedPassthroughRequestHeaders = []string{
 // matches when '+' is next to a non-slash char
 var wcAdjacentNonSlashRegEx = regexp.MustCompile(`\+[^/]|[^/]\+`).MatchString
 
-// Router is used to do prefix based routing of a request to a logical backend
+// Router is used to do prefix based routing of a request to a logical backend.
+// Lookups are lock-free: the routing trees are immutable, and changes to the
+// mount table atomically swap in updated copies of them.
 type Router struct {
-	l                  sync.RWMutex
-	root               *radix.Tree
-	mountUUIDCache     *radix.Tree
-	mountAccessorCache *radix.Tree
+	// l serializes the changes to the routing trees
+	l                  sync.Mutex
+	trees              atomic.Pointer[routerTrees]
 	tokenStoreSaltFunc func(context.Context) (*salt.Salt, error)
+	logger             hclog.Logger
+}
+
+// routerTrees is a snapshot of the routing trees of the router, which must
+// not be modified once stored in the router.
+type routerTrees struct {
+	root               *iradix.Tree
+	mountUUIDCache     *iradix.Tree
+	mountAccessorCache *iradix.Tree
 	// storagePrefix maps the prefix used for storage (ala the BarrierView)
 	// to the backend. This is used to map a key back into the backend that owns it.
 	// For example, logical/uuid1/foobar -> secrets/ (kv backend) + foobar
-	storagePrefix *radix.Tree
-	logger        hclog.Logger
+	storagePrefix *iradix.Tree
+}
+
+func newRouterTrees() *routerTrees {
+	return &routerTrees{
+		root:               iradix.New(),
+		mountUUIDCache:     iradix.New(),
+		mountAccessorCache: iradix.New(),
+		storagePrefix:      iradix.New(),
+	}
+}
+
+// routerTxn holds the changes to the routing trees until they are committed
+type routerTxn struct {
+	root               *iradix.Txn
+	mountUUIDCache     *iradix.Txn
+	mountAccessorCache *iradix.Txn
+	storagePrefix      *iradix.Txn
+}
+
+func (t *routerTrees) txn() *routerTxn {
+	return &routerTxn{
+		root:               t.root.Txn(),
+		mountUUIDCache:     t.mountUUIDCache.Txn(),
+		mountAccessorCache: t.mountAccessorCache.Txn(),
+		storagePrefix:      t.storagePrefix.Txn(),
+	}
+}
+
+func (t *routerTxn) commit() *routerTrees {
+	return &routerTrees{
+		root:               t.root.Commit(),
+		mountUUIDCache:     t.mountUUIDCache.Commit(),
+		mountAccessorCache: t.mountAccessorCache.Commit(),
+		storagePrefix:      t.storagePrefix.Commit(),
+	}
+}
+
+// longestPrefix returns the longest key of tree that prefixes path, and its
+// value
+func longestPrefix(tree *iradix.Tree, path string) (string, interface{}, bool) {
+	k, v, ok := tree.Root().LongestPrefix([]byte(path))
+	return string(k), v, ok
 }
 
 // NewRouter returns a new router
 func NewRouter() *Router {
 	r := &Router{
-		root:               radix.New(),
-		storagePrefix:      radix.New(),
-		mountUUIDCache:     radix.New(),
-		mountAccessorCache: radix.New(),
 		// this will get replaced in production with a real logger but it's useful to have a default in place for tests
 		logger: hclog.NewNullLogger(),
 	}
+	r.trees.Store(newRouterTrees())
 	return r
 }
 
+// routeEntryByPath returns the route entry mounted at exactly path, which
+// includes the namespace path
+func (r *Router) routeEntryByPath(path string) (*routeEntry, bool) {
+	raw, ok := r.trees.Load().root.Get([]byte(path))
+	if !ok {
+		return nil, false
+	}
+	return raw.(*routeEntry), true
+}
+
 // routeEntry is used to represent a mount point in the router
 type routeEntry struct {
-	tainted       bool
+	tainted       atomic.Bool
 	backend       logical.Backend
 	mountEntry    *MountEntry
 	storageView   logical.Storage
@@ -88,33 +147,30 @@ type ValidateMountResponse struct {
 func (r *Router) reset() {
 	r.l.Lock()
 	defer r.l.Unlock()
-	r.root = radix.New()
-	r.storagePrefix = radix.New()
-	r.mountUUIDCache = radix.New()
-	r.mountAccessorCache = radix.New()
+	r.trees.Store(newRouterTrees())
 }
 
 func (r *Router) GetRecords(tag string) ([]map[string]interface{}, error) {
-	r.l.RLock()
-	defer r.l.RUnlock()
+	trees := r.trees.Load()
 	var data []map[string]interface{}
-	var tree *radix.Tree
+	var tree *iradix.Tree
 	switch tag {
 	case "root":
-		tree = r.root
+		tree = trees.root
 	case "uuid":
-		tree = r.mountUUIDCache
+		tree = trees.mountUUIDCache
 	case "accessor":
-		tree = r.mountAccessorCache
+		tree = trees.mountAccessorCache
 	case "storage":
-		tree = r.storagePrefix
+		tree = trees.storagePrefix
 	default:
 		return nil, logical.ErrUnsupportedPath
 	}
-	for _, v := range tree.ToMap() {
+	tree.Root().Walk(func(k []byte, v interface{}) bool {
 		info := v.(Deserializable).Deserialize()
 		data = append(data, info)
-	}
+		return false
+	})
 	return data, nil
 }
 
@@ -122,7 +178,7 @@ func (entry *routeEntry) Deserialize() map[string]interface{} {
 	entry.l.RLock()
 	defer entry.l.RUnlock()
 	ret := map[string]interface{}{
-		"tainted":        entry.tainted,
+		"tainted":        entry.tainted.Load(),
 		"storage_prefix": entry.storagePrefix,
 	}
 	for k, v := range entry.mountEntry.Deserialize() {
@@ -171,7 +227,8 @@ func (r *Router) Mount(backend logical.Backend, prefix string, mountEntry *Mount
 	prefix = mountEntry.Namespace().Path + prefix
 
 	// Check if this is a nested mount
-	if existing, _, ok := r.root.LongestPrefix(prefix); ok && existing != "" {
+	trees := r.trees.Load()
+	if existing, _, ok := longestPrefix(trees.root, prefix); ok && existing != "" {
 		return fmt.Errorf("cannot mount under existing mount %q", existing)
 	}
 
@@ -186,12 +243,12 @@ func (r *Router) Mount(backend logical.Backend, prefix string, mountEntry *Mount
 
 	// Create a mount entry
 	re := &routeEntry{
-		tainted:       mountEntry.Tainted,
 		backend:       backend,
 		mountEntry:    mountEntry,
 		storagePrefix: storageView.Prefix(),
 		storageView:   storageView,
 	}
+	re.tainted.Store(mountEntry.Tainted)
 	re.rootPaths.Store(pathsToRadix(paths.Root))
 	loginPathsEntry, err := parseUnauthenticatedPaths(paths.Unauthenticated)
 	if err != nil {
@@ -210,10 +267,12 @@ func (r *Router) Mount(backend logical.Backend, prefix string, mountEntry *Mount
 		return fmt.Errorf("missing mount accessor; mount_path: %q, mount_type: %q", re.mountEntry.Path, re.mountEntry.Type)
 	}
 
-	r.root.Insert(prefix, re)
-	r.storagePrefix.Insert(re.storagePrefix, re)
-	r.mountUUIDCache.Insert(re.mountEntry.UUID, re.mountEntry)
-	r.mountAccessorCache.Insert(re.mountEntry.Accessor, re.mountEntry)
+	txn := trees.txn()
+	txn.root.Insert([]byte(prefix), re)
+	txn.storagePrefix.Insert([]byte(re.storagePrefix), re)
+	txn.mountUUIDCache.Insert([]byte(re.mountEntry.UUID), re.mountEntry)
+	txn.mountAccessorCache.Insert([]byte(re.mountEntry.Accessor), re.mountEntry)
+	r.trees.Store(txn.commit())
 
 	return nil
 }
@@ -230,22 +289,23 @@ func (r *Router) Unmount(ctx context.Context, prefix string) error {
 	defer r.l.Unlock()
 
 	// Fast-path out if the backend doesn't exist
-	raw, ok := r.root.Get(prefix)
+	re, ok := r.routeEntryByPath(prefix)
 	if !ok {
 		return nil
 	}
 
 	// Call backend's Cleanup routine
-	re := raw.(*routeEntry)
 	if re.backend != nil {
 		re.backend.Cleanup(ctx)
 	}
 
 	// Purge from the radix trees
-	r.root.Delete(prefix)
-	r.storagePrefix.Delete(re.storagePrefix)
-	r.mountUUIDCache.Delete(re.mountEntry.UUID)
-	r.mountAccessorCache.Delete(re.mountEntry.Accessor)
+	txn := r.trees.Load().txn()
+	txn.root.Delete([]byte(prefix))
+	txn.storagePrefix.Delete([]byte(re.storagePrefix))
+	txn.mountUUIDCache.Delete([]byte(re.mountEntry.UUID))
+	txn.mountAccessorCache.Delete([]byte(re.mountEntry.Accessor))
+	r.trees.Store(txn.commit())
 
 	return nil
 }
@@ -263,14 +323,16 @@ func (r *Router) Remount(ctx context.Context, src, dst string) error {
 	defer r.l.Unlock()
 
 	// Check for existing mount
-	raw, ok := r.root.Get(src)
+	re, ok := r.routeEntryByPath(src)
 	if !ok {
 		return fmt.Errorf("no mount at %q", src)
 	}
 
 	// Update the mount point
-	r.root.Delete(src)
-	r.root.Insert(dst, raw)
+	txn := r.trees.Load().txn()
+	txn.root.Delete([]byte(src))
+	txn.root.Insert([]byte(dst), re)
+	r.trees.Store(txn.commit())
 	return nil
 }
 
@@ -283,11 +345,9 @@ func (r *Router) Taint(ctx context.Context, path string) error {
 	}
 	path = ns.Path + path
 
-	r.l.Lock()
-	defer r.l.Unlock()
-	_, raw, ok := r.root.LongestPrefix(path)
+	_, raw, ok := longestPrefix(r.trees.Load().root, path)
 	if ok {
-		raw.(*routeEntry).tainted = true
+		raw.(*routeEntry).tainted.Store(true)
 	}
 	return nil
 }
@@ -300,11 +360,9 @@ func (r *Router) Untaint(ctx context.Context, path string) error {
 	}
 	path = ns.Path + path
 
-	r.l.Lock()
-	defer r.l.Unlock()
-	_, raw, ok := r.root.LongestPrefix(path)
+	_, raw, ok := longestPrefix(r.trees.Load().root, path)
 	if ok {
-		raw.(*routeEntry).tainted = false
+		raw.(*routeEntry).tainted.Store(false)
 	}
 	return nil
 }
@@ -314,15 +372,10 @@ func (r *Router) MatchingMountByUUID(mountID string) *MountEntry {
 		return nil
 	}
 
-	r.l.RLock()
-
-	_, raw, ok := r.mountUUIDCache.LongestPrefix(mountID)
+	_, raw, ok := longestPrefix(r.trees.Load().mountUUIDCache, mountID)
 	if !ok {
-		r.l.RUnlock()
 		return nil
 	}
-
-	r.l.RUnlock()
 	return raw.(*MountEntry)
 }
 
@@ -332,34 +385,26 @@ func (r *Router) MatchingMountByAccessor(mountAccessor string) *MountEntry {
 		return nil
 	}
 
-	r.l.RLock()
-
-	_, raw, ok := r.mountAccessorCache.LongestPrefix(mountAccessor)
+	_, raw, ok := longestPrefix(r.trees.Load().mountAccessorCache, mountAccessor)
 	if !ok {
-		r.l.RUnlock()
 		return nil
 	}
-
-	r.l.RUnlock()
 	return raw.(*MountEntry)
 }
 
 // MatchingMount returns the mount prefix that would be used for a path
 func (r *Router) MatchingMount(ctx context.Context, path string) string {
-	r.l.RLock()
-	mount := r.matchingMountInternal(ctx, path)
-	r.l.RUnlock()
-	return mount
+	return r.matchingMountInternal(ctx, r.trees.Load(), path)
 }
 
-func (r *Router) matchingMountInternal(ctx context.Context, path string) string {
+func (r *Router) matchingMountInternal(ctx context.Context, trees *routerTrees, path string) string {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
 		return ""
 	}
 	path = ns.Path + path
 
-	mount, _, ok := r.root.LongestPrefix(path)
+	mount, _, ok := longestPrefix(trees.root, path)
 	if !ok {
 		return ""
 	}
@@ -367,7 +412,7 @@ func (r *Router) matchingMountInternal(ctx context.Context, path string) string
 }
 
 // matchingPrefixInternal returns a mount prefix that a path may be a part of
-func (r *Router) matchingPrefixInternal(ctx context.Context, path string) string {
+func (r *Router) matchingPrefixInternal(ctx context.Context, trees *routerTrees, path string) string {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
 		return ""
@@ -375,25 +420,24 @@ func (r *Router) matchingPrefixInternal(ctx context.Context, path string) string
 	path = ns.Path + path
 
 	var existing string
-	fn := func(existingPath string, v interface{}) bool {
-		if strings.HasPrefix(existingPath, path) {
-			existing = existingPath
+	fn := func(existingPath []byte, v interface{}) bool {
+		if strings.HasPrefix(string(existingPath), path) {
+			existing = string(existingPath)
 			return true
 		}
 		return false
 	}
-	r.root.WalkPrefix(path, fn)
+	trees.root.Root().WalkPrefix([]byte(path), fn)
 	return existing
 }
 
 // MountConflict determines if there are potential path conflicts
 func (r *Router) MountConflict(ctx context.Context, path string) string {
-	r.l.RLock()
-	defer r.l.RUnlock()
-	if exactMatch := r.matchingMountInternal(ctx, path); exactMatch != "" {
+	trees := r.trees.Load()
+	if exactMatch := r.matchingMountInternal(ctx, trees, path); exactMatch != "" {
 		return exactMatch
 	}
-	if prefixMatch := r.matchingPrefixInternal(ctx, path); prefixMatch != "" {
+	if prefixMatch := r.matchingPrefixInternal(ctx, trees, path); prefixMatch != "" {
 		return prefixMatch
 	}
 	return ""
@@ -418,13 +462,12 @@ func (r *Router) matchingStorage(ctx context.Context, path string, apiPath bool)
 
 	var raw interface{}
 	var ok bool
-	r.l.RLock()
+	trees := r.trees.Load()
 	if apiPath {
-		_, raw, ok = r.root.LongestPrefix(path)
+		_, raw, ok = longestPrefix(trees.root, path)
 	} else {
-		_, raw, ok = r.storagePrefix.LongestPrefix(path)
+		_, raw, ok = longestPrefix(trees.storagePrefix, path)
 	}
-	r.l.RUnlock()
 	if !ok {
 		return nil
 	}
@@ -439,9 +482,7 @@ func (r *Router) MatchingMountEntry(ctx context.Context, path string) *MountEntr
 	}
 	path = ns.Path + path
 
-	r.l.RLock()
-	_, raw, ok := r.root.LongestPrefix(path)
-	r.l.RUnlock()
+	_, raw, ok := longestPrefix(r.trees.Load().root, path)
 	if !ok {
 		return nil
 	}
@@ -456,9 +497,7 @@ func (r *Router) MatchingBackend(ctx context.Context, path string) logical.Backe
 	}
 	path = ns.Path + path
 
-	r.l.RLock()
-	_, raw, ok := r.root.LongestPrefix(path)
-	r.l.RUnlock()
+	_, raw, ok := longestPrefix(r.trees.Load().root, path)
 	if !ok {
 		return nil
 	}
@@ -473,9 +512,7 @@ func (r *Router) MatchingSystemView(ctx context.Context, path string) logical.Sy
 	}
 	path = ns.Path + path
 
-	r.l.RLock()
-	_, raw, ok := r.root.LongestPrefix(path)
-	r.l.RUnlock()
+	_, raw, ok := longestPrefix(r.trees.Load().root, path)
 	if !ok || raw.(*routeEntry).backend == nil {
 		return nil
 	}
@@ -521,13 +558,12 @@ func (r *Router) MatchingAPIPrefixByStoragePath(ctx context.Context, path string
 func (r *Router) matchingMountEntryByPath(ctx context.Context, path string, apiPath bool) (*MountEntry, string, bool) {
 	var raw interface{}
 	var ok bool
-	r.l.RLock()
+	trees := r.trees.Load()
 	if apiPath {
-		_, raw, ok = r.root.LongestPrefix(path)
+		_, raw, ok = longestPrefix(trees.root, path)
 	} else {
-		_, raw, ok = r.storagePrefix.LongestPrefix(path)
+		_, raw, ok = longestPrefix(trees.storagePrefix, path)
 	}
-	r.l.RUnlock()
 	if !ok {
 		return nil, "", false
 	}
@@ -558,16 +594,15 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 	}
 
 	// Find the mount point
-	r.l.RLock()
+	trees := r.trees.Load()
 	adjustedPath := req.Path
-	mount, raw, ok := r.root.LongestPrefix(ns.Path + adjustedPath)
+	mount, raw, ok := longestPrefix(trees.root, ns.Path+adjustedPath)
 	if !ok && !strings.HasSuffix(adjustedPath, "/") {
 		// Re-check for a backend by appending a slash. This lets "foo" mean
 		// "foo/" at the root level which is almost always what we want.
 		adjustedPath += "/"
-		mount, raw, ok = r.root.LongestPrefix(ns.Path + adjustedPath)
+		mount, raw, ok = longestPrefix(trees.root, ns.Path+adjustedPath)
 	}
-	r.l.RUnlock()
 	if !ok {
 		return logical.ErrorResponse(fmt.Sprintf("no handler for route %q. route entry not found.", req.Path)), false, false, logical.ErrUnsupportedPath
 	}
@@ -597,7 +632,7 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 
 	// If the path is tainted, we reject any operation except for
 	// Rollback and Revoke
-	if re.tainted {
+	if re.tainted.Load() {
 		switch req.Operation {
 		case logical.RevokeOperation, logical.RollbackOperation:
 		default:
@@ -830,9 +865,7 @@ func (r *Router) RootPath(ctx context.Context, path string) bool {
 
 	adjustedPath := ns.Path + path
 
-	r.l.RLock()
-	mount, raw, ok := r.root.LongestPrefix(adjustedPath)
-	r.l.RUnlock()
+	mount, raw, ok := longestPrefix(r.trees.Load().root, adjustedPath)
 	if !ok {
 		return false
 	}
@@ -871,9 +904,7 @@ func (r *Router) LoginPath(ctx context.Context, path string) bool {
 
 	adjustedPath := ns.Path + path
 
-	r.l.RLock()
-	mount, raw, ok := r.root.LongestPrefix(adjustedPath)
-	r.l.RUnlock()
+	mount, raw, ok := longestPrefix(r.trees.Load().root, adjustedPath)
 	if !ok {
 		return false
 	}
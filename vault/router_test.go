@@ -1,8 +1,10 @@
 package vault
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/go-uuid"
@@ -628,3 +630,134 @@ func TestParseUnauthenticatedPaths_Error(t *testing.T) {
 		}
 	}
 }
+
+func mountBenchmarkRouter(tb testing.TB, r *Router, count int) {
+	_, barrier, _ := mockBarrier(tb)
+	for i := 0; i < count; i++ {
+		path := fmt.Sprintf("mount-%d/", i)
+		meUUID, err := uuid.GenerateUUID()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		mountEntry := &MountEntry{
+			Path:        path,
+			UUID:        meUUID,
+			Accessor:    "accessor-" + meUUID,
+			NamespaceID: namespace.RootNamespaceID,
+			namespace:   namespace.RootNamespace,
+		}
+		view := NewBarrierView(barrier, "logical/"+meUUID+"/")
+		if err := r.Mount(&NoopBackend{}, path, mountEntry, view); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestRouter_ConcurrentMount(t *testing.T) {
+	r := NewRouter()
+	mountBenchmarkRouter(t, r, 10)
+	ctx := namespace.RootContext(nil)
+
+	stopCh := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stopCh:
+					return
+				default:
+				}
+				if path := r.MatchingMount(ctx, "mount-3/foo"); path != "mount-3/" {
+					t.Errorf("bad: %s", path)
+					return
+				}
+				r.MatchingMountEntry(ctx, "other/foo")
+			}
+		}()
+	}
+
+	_, barrier, _ := mockBarrier(t)
+	for i := 0; i < 100; i++ {
+		meUUID, err := uuid.GenerateUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mountEntry := &MountEntry{
+			Path:        "other/",
+			UUID:        meUUID,
+			Accessor:    "other",
+			NamespaceID: namespace.RootNamespaceID,
+			namespace:   namespace.RootNamespace,
+		}
+		if err := r.Mount(&NoopBackend{}, "other/", mountEntry, NewBarrierView(barrier, "logical/other/")); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Taint(ctx, "other/"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Unmount(ctx, "other/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stopCh)
+	wg.Wait()
+
+	if path := r.MatchingMount(ctx, "other/foo"); path != "" {
+		t.Fatalf("bad: %s", path)
+	}
+}
+
+func BenchmarkRouter_MatchingMount(b *testing.B) {
+	r := NewRouter()
+	mountBenchmarkRouter(b, r, 100)
+	ctx := namespace.RootContext(nil)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.MatchingMount(ctx, "mount-42/foo/bar")
+		}
+	})
+}
+
+func BenchmarkRouter_MatchingMountWhileMounting(b *testing.B) {
+	r := NewRouter()
+	mountBenchmarkRouter(b, r, 100)
+	ctx := namespace.RootContext(nil)
+
+	_, barrier, _ := mockBarrier(b)
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		for {
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+			mountEntry := &MountEntry{
+				Path:        "churn/",
+				UUID:        "churn",
+				Accessor:    "churn",
+				NamespaceID: namespace.RootNamespaceID,
+				namespace:   namespace.RootNamespace,
+			}
+			r.Mount(&NoopBackend{}, "churn/", mountEntry, NewBarrierView(barrier, "logical/churn/"))
+			r.Unmount(ctx, "churn/")
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.MatchingMount(ctx, "mount-42/foo/bar")
+		}
+	})
+	b.StopTimer()
+	close(stopCh)
+	<-doneCh
+}
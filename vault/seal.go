@@ -315,6 +315,10 @@ type SealConfig struct {
 	// How many keys to store, for seals that support storage.  Always 0 or 1.
 	StoredShares int `json:"stored_shares" mapstructure:"stored_shares"`
 
+	// TOTPProtected indicates that each key share is bound to a TOTP secret
+	// of its holder, and must be submitted along with a current code.
+	TOTPProtected bool `json:"totp_protected,omitempty" mapstructure:"totp_protected"`
+
 	// Stores the progress of the rekey operation (key shares)
 	RekeyProgress [][]byte `json:"-"`
 
@@ -333,6 +337,10 @@ type SealConfig struct {
 
 	// Stores the progress of the verification operation (key shares)
 	VerificationProgress [][]byte `json:"-"`
+
+	// totpBindings are the TOTP secrets bound to the new key shares of a
+	// rekey, stored once the rekey completes.
+	totpBindings []*unsealTOTPBinding
 }
 
 // Validate is used to sanity check the seal configuration
@@ -384,8 +392,10 @@ func (s *SealConfig) Clone() *SealConfig {
 		Nonce:                s.Nonce,
 		Backup:               s.Backup,
 		StoredShares:         s.StoredShares,
+		TOTPProtected:        s.TOTPProtected,
 		VerificationRequired: s.VerificationRequired,
 		VerificationNonce:    s.VerificationNonce,
+		totpBindings:         s.totpBindings,
 	}
 	if len(s.PGPKeys) > 0 {
 		ret.PGPKeys = make([]string, len(s.PGPKeys))
@@ -3,8 +3,8 @@
 package vault
 
 import (
+	"bytes"
 	"context"
-	"fmt"
 	"sync/atomic"
 
 	proto "github.com/golang/protobuf/proto"
@@ -43,6 +43,10 @@ type sealUnwrapper struct {
 	logger       log.Logger
 	locks        []*locksutil.LockEntry
 	allowUnwraps *uint32
+
+	// sealWrapper seal wraps the entries written to critical paths, and
+	// unwraps them on read. It is nil if seal wrapping isn't set up.
+	sealWrapper *sealWrapper
 }
 
 // transactionalSealUnwrapper is a seal unwrapper that wraps a physical that is transactional
@@ -56,6 +60,11 @@ func (d *sealUnwrapper) Put(ctx context.Context, entry *physical.Entry) error {
 		return nil
 	}
 
+	entry, err := d.sealWrapper.wrap(ctx, entry)
+	if err != nil {
+		return err
+	}
+
 	locksutil.LockForKey(d.locks, entry.Key).Lock()
 	defer locksutil.LockForKey(d.locks, entry.Key).Unlock()
 
@@ -85,9 +94,9 @@ func (d *sealUnwrapper) Get(ctx context.Context, key string) (*physical.Entry, e
 	if !performUnwrap {
 		return entry, nil
 	}
-	// It's actually encrypted and we can't read it
+	// It's actually encrypted, decrypt it with the seal
 	if se.Wrapped {
-		return nil, fmt.Errorf("cannot decode sealwrapped storage entry %q", entry.Key)
+		return d.unwrapSealWrapped(ctx, entry, se)
 	}
 	if atomic.LoadUint32(d.allowUnwraps) != 1 {
 		return &physical.Entry{
@@ -125,7 +134,11 @@ func (d *sealUnwrapper) Get(ctx context.Context, key string) (*physical.Entry, e
 		return entry, nil
 	}
 	if se.Wrapped {
-		return nil, fmt.Errorf("cannot decode sealwrapped storage entry %q", entry.Key)
+		value, _, err := d.sealWrapper.unwrap(ctx, entry.Key, se)
+		if err != nil {
+			return nil, err
+		}
+		return &physical.Entry{Key: entry.Key, Value: value, SealWrap: true}, nil
 	}
 
 	entry = &physical.Entry{
@@ -139,6 +152,43 @@ func (d *sealUnwrapper) Get(ctx context.Context, key string) (*physical.Entry, e
 	return entry, d.underlying.Put(ctx, entry)
 }
 
+// unwrapSealWrapped returns the unwrapped value of a seal wrapped entry. If it
+// was wrapped by a seal other than the current one, or seal wrapping is
+// disabled, the entry is persisted again on the active node so that it is
+// wrapped according to the current configuration.
+func (d *sealUnwrapper) unwrapSealWrapped(ctx context.Context, entry *physical.Entry, se *wrapping.BlobInfo) (*physical.Entry, error) {
+	value, rewrap, err := d.sealWrapper.unwrap(ctx, entry.Key, se)
+	if err != nil {
+		return nil, err
+	}
+	unwrapped := &physical.Entry{
+		Key:      entry.Key,
+		Value:    value,
+		SealWrap: true,
+	}
+	if !rewrap || atomic.LoadUint32(d.allowUnwraps) != 1 {
+		return unwrapped, nil
+	}
+
+	locksutil.LockForKey(d.locks, entry.Key).Lock()
+	defer locksutil.LockForKey(d.locks, entry.Key).Unlock()
+
+	// Only rewrap the entry if it wasn't modified in the meantime
+	current, err := d.underlying.Get(ctx, entry.Key)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || !bytes.Equal(current.Value, entry.Value) {
+		return unwrapped, nil
+	}
+
+	rewrapped, err := d.sealWrapper.wrap(ctx, unwrapped)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapped, d.underlying.Put(ctx, rewrapped)
+}
+
 func (d *sealUnwrapper) Delete(ctx context.Context, key string) error {
 	locksutil.LockForKey(d.locks, key).Lock()
 	defer locksutil.LockForKey(d.locks, key).Unlock()
@@ -151,6 +201,23 @@ func (d *sealUnwrapper) List(ctx context.Context, prefix string) ([]string, erro
 }
 
 func (d *transactionalSealUnwrapper) Transaction(ctx context.Context, txns []*physical.TxnEntry) error {
+	// Seal wrap the entries written to critical paths
+	wrappedTxns := make([]*physical.TxnEntry, 0, len(txns))
+	for _, curr := range txns {
+		if curr.Operation == physical.PutOperation {
+			entry, err := d.sealWrapper.wrap(ctx, curr.Entry)
+			if err != nil {
+				return err
+			}
+			curr = &physical.TxnEntry{
+				Operation: curr.Operation,
+				Entry:     entry,
+			}
+		}
+		wrappedTxns = append(wrappedTxns, curr)
+	}
+	txns = wrappedTxns
+
 	// Collect keys that need to be locked
 	var keys []string
 	for _, curr := range txns {
@@ -0,0 +1,196 @@
+//go:build !enterprise
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/armon/go-radix"
+	proto "github.com/golang/protobuf/proto"
+	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/physical"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
+)
+
+// coreSealWrapPaths are the storage entries outside of mounts which are
+// always seal wrapped when seal wrapping is available.
+var coreSealWrapPaths = []string{
+	keyringPath,
+	rootKeyPath,
+}
+
+// sealWrapper seal wraps storage entries: on top of the barrier encryption,
+// their values are encrypted with the seal, e.g. the auto-unseal KMS. Entries
+// are seal wrapped when they are flagged as such, or when their key falls
+// under one of the registered paths: the core paths and the SealWrapStorage
+// paths of the mounts enabled with seal_wrap.
+type sealWrapper struct {
+	core *Core
+
+	// disabled restricts seal wrapping to the root key
+	disabled bool
+
+	pathsLock sync.RWMutex
+	paths     *radix.Tree
+}
+
+func newSealWrapper(core *Core, disabled bool) *sealWrapper {
+	w := &sealWrapper{
+		core:     core,
+		disabled: disabled,
+		paths:    radix.New(),
+	}
+	for _, path := range coreSealWrapPaths {
+		w.paths.Insert(path, true)
+	}
+	return w
+}
+
+// addMountPaths registers the SealWrapStorage paths of the backend mounted at
+// viewPath. The paths are only registered if the mount is seal wrapped; the
+// identity store, which can't be remounted, always is.
+func (w *sealWrapper) addMountPaths(entry *MountEntry, backend logical.Backend, viewPath string) {
+	if w == nil || backend == nil {
+		return
+	}
+	if !entry.SealWrap && entry.Type != identityMountType {
+		return
+	}
+
+	specialPaths := backend.SpecialPaths()
+	if specialPaths == nil {
+		return
+	}
+
+	w.pathsLock.Lock()
+	defer w.pathsLock.Unlock()
+	for _, path := range specialPaths.SealWrapStorage {
+		w.paths.Insert(viewPath+strings.TrimSuffix(path, "*"), true)
+	}
+}
+
+// removeMountPaths unregisters the paths of the mount at viewPath.
+func (w *sealWrapper) removeMountPaths(viewPath string) {
+	if w == nil {
+		return
+	}
+
+	w.pathsLock.Lock()
+	defer w.pathsLock.Unlock()
+	w.paths.DeletePrefix(viewPath)
+}
+
+// access returns the seal access entries are wrapped with, or nil if the
+// seal can't wrap entries.
+func (w *sealWrapper) access() *vaultseal.Access {
+	seal := w.core.seal
+	if seal == nil || !seal.SealWrapable() {
+		return nil
+	}
+	return seal.GetAccess()
+}
+
+// shouldWrap returns whether the entry has to be seal wrapped.
+func (w *sealWrapper) shouldWrap(entry *physical.Entry) bool {
+	if w.disabled {
+		return entry.Key == rootKeyPath
+	}
+	if entry.SealWrap {
+		return true
+	}
+
+	w.pathsLock.RLock()
+	defer w.pathsLock.RUnlock()
+	_, _, ok := w.paths.LongestPrefix(entry.Key)
+	return ok
+}
+
+// wrap returns the entry to persist for the given entry, whose value is
+// encrypted with the seal if it has to be seal wrapped. The key of the entry
+// is used as additional data, so that wrapped values can't be moved to
+// another key.
+func (w *sealWrapper) wrap(ctx context.Context, entry *physical.Entry) (*physical.Entry, error) {
+	if w == nil || !w.shouldWrap(entry) {
+		return entry, nil
+	}
+	access := w.access()
+	if access == nil {
+		return entry, nil
+	}
+
+	blobInfo, err := access.Encrypt(ctx, entry.Value, []byte(entry.Key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal wrap storage entry %q: %w", entry.Key, err)
+	}
+	blobInfo.Wrapped = true
+
+	value, err := proto.Marshal(blobInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode seal wrapped storage entry %q: %w", entry.Key, err)
+	}
+
+	return &physical.Entry{
+		Key:      entry.Key,
+		Value:    append(value, 's'),
+		SealWrap: true,
+	}, nil
+}
+
+// unwrap decrypts the value of a seal wrapped entry. While a seal migration
+// is configured, entries wrapped by the seal being migrated from can be
+// decrypted as well; rewrap is then true to report that the entry should be
+// persisted again to be wrapped with the current seal. It is true as well when
+// seal wrapping was disabled since the entry was written.
+func (w *sealWrapper) unwrap(ctx context.Context, key string, blobInfo *wrapping.BlobInfo) (value []byte, rewrap bool, err error) {
+	if w == nil {
+		return nil, false, fmt.Errorf("cannot decode sealwrapped storage entry %q", key)
+	}
+
+	var accesses []*vaultseal.Access
+	if access := w.access(); access != nil {
+		accesses = append(accesses, access)
+	}
+	if migrationInfo := w.core.migrationInfo; migrationInfo != nil && migrationInfo.seal != nil && migrationInfo.seal.SealWrapable() {
+		accesses = append(accesses, migrationInfo.seal.GetAccess())
+	}
+	if len(accesses) == 0 {
+		return nil, false, fmt.Errorf("cannot decode sealwrapped storage entry %q: seal does not support seal wrapping", key)
+	}
+
+	for i, access := range accesses {
+		value, err = access.Decrypt(ctx, blobInfo, []byte(key))
+		if err == nil {
+			return value, i > 0 || (w.disabled && key != rootKeyPath), nil
+		}
+	}
+
+	return nil, false, fmt.Errorf("failed to unwrap sealwrapped storage entry %q: %w", key, err)
+}
+
+// unwrapValue returns the barrier encrypted value of a value read from the
+// physical backend without going through the seal unwrapper, such as a value
+// of a raft snapshot, and whether it was seal wrapped.
+func (w *sealWrapper) unwrapValue(ctx context.Context, key string, value []byte) ([]byte, bool, error) {
+	eLen := len(value)
+	if eLen == 0 || value[eLen-1] != 's' {
+		return value, false, nil
+	}
+	se := &wrapping.BlobInfo{}
+	if err := proto.Unmarshal(value[:eLen-1], se); err != nil {
+		// The canary is not a guarantee
+		return value, false, nil
+	}
+	if !se.Wrapped {
+		return se.Ciphertext, false, nil
+	}
+
+	unwrapped, _, err := w.unwrap(ctx, key, se)
+	if err != nil {
+		return nil, false, err
+	}
+	return unwrapped, true, nil
+}
@@ -0,0 +1,184 @@
+//go:build !enterprise
+
+package vault
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	proto "github.com/golang/protobuf/proto"
+	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/plugin/pb"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+func testCoreUnsealedSealWrapping(t *testing.T) (*Core, string) {
+	t.Helper()
+	c, _, _, root := TestCoreUnsealedWithConfigSealOpts(t,
+		&SealConfig{StoredShares: 1, SecretShares: 1, SecretThreshold: 1},
+		&SealConfig{StoredShares: 1, SecretShares: 1, SecretThreshold: 1},
+		&seal.TestSealOpts{StoredKeys: seal.StoredKeysSupportedGeneric})
+	return c, root
+}
+
+// testIsSealWrapped returns whether the entry stored at key is seal wrapped.
+func testIsSealWrapped(t *testing.T, c *Core, key string) bool {
+	t.Helper()
+	entry, err := c.underlyingPhysical.Get(namespace.RootContext(nil), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Fatalf("missing storage entry %q", key)
+	}
+	eLen := len(entry.Value)
+	if eLen == 0 || entry.Value[eLen-1] != 's' {
+		return false
+	}
+	se := &wrapping.BlobInfo{}
+	if err := proto.Unmarshal(entry.Value[:eLen-1], se); err != nil {
+		return false
+	}
+	return se.Wrapped
+}
+
+func TestSealWrapper(t *testing.T) {
+	c, root := testCoreUnsealedSealWrapping(t)
+	ctx := namespace.RootContext(nil)
+
+	isWrapped := func(key string) bool {
+		t.Helper()
+		return testIsSealWrapped(t, c, key)
+	}
+
+	for _, key := range []string{keyringPath, rootKeyPath} {
+		if !isWrapped(key) {
+			t.Fatalf("expected %q to be seal wrapped", key)
+		}
+	}
+
+	for _, sealWrap := range []bool{true, false} {
+		path := fmt.Sprintf("kv-%t/", sealWrap)
+		me := &MountEntry{
+			Table:    mountTableType,
+			Path:     path,
+			Type:     "kv",
+			SealWrap: sealWrap,
+		}
+		if err := c.mount(ctx, me); err != nil {
+			t.Fatal(err)
+		}
+
+		req := logical.TestRequest(t, logical.UpdateOperation, path+"foo")
+		req.Data["bar"] = "baz"
+		req.ClientToken = root
+		resp, err := c.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+
+		if wrapped := isWrapped(me.ViewPath() + "foo"); wrapped != sealWrap {
+			t.Fatalf("expected seal wrapped to be %t for mount %q, got %t", sealWrap, path, wrapped)
+		}
+
+		// Bypass the cache to read the entry back from storage
+		c.physicalCache.Purge(ctx)
+
+		req = logical.TestRequest(t, logical.ReadOperation, path+"foo")
+		req.ClientToken = root
+		resp, err = c.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		if resp.Data["bar"] != "baz" {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+	}
+}
+
+func TestSealWrapper_RaftSnapshotMountRestore(t *testing.T) {
+	c, root := testCoreUnsealedSealWrapping(t)
+	ctx := namespace.RootContext(nil)
+
+	me := &MountEntry{
+		Table:    mountTableType,
+		Path:     "kv-wrapped/",
+		Type:     "kv",
+		SealWrap: true,
+	}
+	if err := c.mount(ctx, me); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, value string) {
+		t.Helper()
+		req := logical.TestRequest(t, logical.UpdateOperation, path)
+		req.Data["bar"] = value
+		req.ClientToken = root
+		resp, err := c.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+	}
+	write("kv-wrapped/foo", "snapshot")
+	if !testIsSealWrapped(t, c, me.ViewPath()+"foo") {
+		t.Fatal("expected the entry to be seal wrapped")
+	}
+
+	// Snapshots hold the entries as stored by the physical backend
+	snapFile, err := os.CreateTemp(t.TempDir(), "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapFile.Close()
+	w := raft.NewDelimitedWriter(snapFile)
+	keys := []string{coreMountConfigPath}
+	dataKeys, err := c.underlyingPhysical.List(ctx, me.ViewPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range dataKeys {
+		keys = append(keys, me.ViewPath()+key)
+	}
+	for _, key := range keys {
+		entry, err := c.underlyingPhysical.Get(ctx, key)
+		if err != nil || entry == nil {
+			t.Fatalf("failed to read %q: %#v, %v", key, entry, err)
+		}
+		if err := w.WriteMsg(&pb.StorageEntry{Key: entry.Key, Value: entry.Value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := snapFile.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	write("kv-wrapped/foo", "changed")
+	write("kv-wrapped/extra", "changed")
+
+	results, err := c.restoreRaftSnapshotMounts(ctx, snapFile, []string{"kv-wrapped"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0]["written"] != 1 || results[0]["deleted"] != 1 {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+
+	if !testIsSealWrapped(t, c, me.ViewPath()+"foo") {
+		t.Fatal("expected the restored entry to be seal wrapped")
+	}
+	c.physicalCache.Purge(ctx)
+	req := logical.TestRequest(t, logical.ReadOperation, "kv-wrapped/foo")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if resp.Data["bar"] != "snapshot" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,547 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/secretsync"
+)
+
+const (
+	// secretSyncSubPath is the sub-path used for the secrets sync
+	// configuration. It holds destination credentials and is seal wrapped.
+	secretSyncSubPath = "secret-sync/"
+
+	secretSyncDestinationPrefix = "destinations/"
+
+	secretSyncDeletionPolicyDelete = "delete"
+	secretSyncDeletionPolicyRetain = "retain"
+
+	secretSyncDriftPolicyOverwrite = "overwrite"
+	secretSyncDriftPolicyReport    = "report"
+)
+
+// secretSyncInterval is how often every association is reconciled with its
+// destination. It is a variable so tests can shorten it.
+var secretSyncInterval = 1 * time.Minute
+
+var errSecretSyncUnavailable = errors.New("secrets sync is not available on this node")
+
+// secretSyncDestinationEntry is the stored configuration of a destination
+// along with the secrets associated with it.
+type secretSyncDestinationEntry struct {
+	Type           string            `json:"type"`
+	Name           string            `json:"name"`
+	Config         map[string]string `json:"config"`
+	DeletionPolicy string            `json:"deletion_policy"`
+	DriftPolicy    string            `json:"drift_policy"`
+
+	// Associations are keyed by mount path and secret path.
+	Associations map[string]*secretSyncAssociation `json:"associations"`
+}
+
+// secretSyncAssociation tracks the state of a KV v2 secret mirrored into a
+// destination.
+type secretSyncAssociation struct {
+	Mount      string `json:"mount"`
+	SecretPath string `json:"secret_path"`
+	RemoteName string `json:"remote_name"`
+
+	// SyncedVersion is the version of the secret last written to the
+	// destination, or zero if nothing has been written.
+	SyncedVersion int64     `json:"synced_version"`
+	LastSyncTime  time.Time `json:"last_sync_time"`
+	LastError     string    `json:"last_error,omitempty"`
+
+	// Drifted is set when the destination was found to have been modified
+	// outside of Vault and the drift policy does not allow overwriting it.
+	Drifted       bool      `json:"drifted"`
+	LastDriftTime time.Time `json:"last_drift_time,omitempty"`
+}
+
+func secretSyncAssociationKey(mount, secretPath string) string {
+	return mount + secretPath
+}
+
+func (e *secretSyncDestinationEntry) storageKey() string {
+	return secretSyncDestinationPrefix + e.Type + "/" + e.Name
+}
+
+// secretSyncManager mirrors KV v2 secrets into external secret stores and
+// keeps them in sync.
+type secretSyncManager struct {
+	core   *Core
+	logger log.Logger
+	view   *BarrierView
+
+	definitions map[string]secretsync.Definition
+	source      secretSyncSource
+
+	// l serializes configuration changes and reconciliation.
+	l       sync.Mutex
+	clients map[string]secretsync.Destination
+
+	cancel context.CancelFunc
+	doneCh chan struct{}
+}
+
+func newSecretSyncManager(c *Core, logger log.Logger, view *BarrierView) *secretSyncManager {
+	return &secretSyncManager{
+		core:        c,
+		logger:      logger,
+		view:        view,
+		definitions: secretsync.BuiltinDefinitions(),
+		source:      &kvSource{core: c},
+		clients:     make(map[string]secretsync.Destination),
+	}
+}
+
+func (c *Core) setupSecretSync(ctx context.Context) error {
+	logger := c.baseLogger.Named("secret-sync")
+	c.AddLogger(logger)
+
+	c.secretSync = newSecretSyncManager(c, logger, c.systemBarrierView.SubView(secretSyncSubPath))
+
+	// Secrets are only pushed from the active node of the primary cluster;
+	// performance secondaries hold their own copy of the configuration.
+	if c.perfStandby || c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+		return nil
+	}
+
+	c.secretSync.start(ctx)
+	return nil
+}
+
+func (c *Core) stopSecretSync() {
+	// preSeal may run before setupSecretSync got a chance to complete.
+	if c.secretSync != nil {
+		c.secretSync.stop()
+	}
+
+	c.secretSync = nil
+}
+
+func (m *secretSyncManager) start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(namespace.RootContext(ctx))
+	m.doneCh = make(chan struct{})
+
+	go func() {
+		defer close(m.doneCh)
+
+		ticker := time.NewTicker(secretSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.reconcileAll(ctx)
+			}
+		}
+	}()
+}
+
+func (m *secretSyncManager) stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	<-m.doneCh
+}
+
+func (m *secretSyncManager) reconcileAll(ctx context.Context) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	entries, err := m.listDestinations(ctx)
+	if err != nil {
+		m.logger.Error("failed to list destinations", "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := m.reconcileDestinationLocked(ctx, entry); err != nil {
+			m.logger.Error("failed to reconcile destination", "type", entry.Type, "name", entry.Name, "error", err)
+		}
+	}
+}
+
+func (m *secretSyncManager) listDestinations(ctx context.Context) ([]*secretSyncDestinationEntry, error) {
+	types, err := m.view.List(ctx, secretSyncDestinationPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*secretSyncDestinationEntry
+	for _, typ := range types {
+		typ = strings.TrimSuffix(typ, "/")
+		names, err := m.view.List(ctx, secretSyncDestinationPrefix+typ+"/")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			entry, err := m.getDestination(ctx, typ, name)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+func (m *secretSyncManager) getDestination(ctx context.Context, typ, name string) (*secretSyncDestinationEntry, error) {
+	raw, err := m.view.Get(ctx, secretSyncDestinationPrefix+typ+"/"+name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry secretSyncDestinationEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, err
+	}
+	if entry.Associations == nil {
+		entry.Associations = make(map[string]*secretSyncAssociation)
+	}
+	return &entry, nil
+}
+
+func (m *secretSyncManager) putDestination(ctx context.Context, entry *secretSyncDestinationEntry) error {
+	raw, err := logical.StorageEntryJSON(entry.storageKey(), entry)
+	if err != nil {
+		return err
+	}
+	return m.view.Put(ctx, raw)
+}
+
+// client returns the cached client of a destination, creating it if needed.
+func (m *secretSyncManager) client(ctx context.Context, entry *secretSyncDestinationEntry) (secretsync.Destination, error) {
+	key := entry.storageKey()
+	if client, ok := m.clients[key]; ok {
+		return client, nil
+	}
+
+	def, ok := m.definitions[entry.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown destination type %q", entry.Type)
+	}
+	client, err := def.Factory(ctx, entry.Config, m.logger.With("type", entry.Type, "name", entry.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	m.clients[key] = client
+	return client, nil
+}
+
+// writeDestination creates or updates a destination. The update function
+// is applied to the stored entry, or to a new entry if the destination does
+// not exist yet; associations are preserved.
+func (m *secretSyncManager) writeDestination(ctx context.Context, typ, name string, update func(entry *secretSyncDestinationEntry) error) (*secretSyncDestinationEntry, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	def, ok := m.definitions[typ]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown destination type %q", secretsync.ErrInvalidConfig, typ)
+	}
+
+	entry, err := m.getDestination(ctx, typ, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		entry = &secretSyncDestinationEntry{
+			Type:           typ,
+			Name:           name,
+			Config:         make(map[string]string),
+			DeletionPolicy: secretSyncDeletionPolicyDelete,
+			DriftPolicy:    secretSyncDriftPolicyOverwrite,
+			Associations:   make(map[string]*secretSyncAssociation),
+		}
+	}
+	if err := update(entry); err != nil {
+		return nil, err
+	}
+
+	// Building the client validates the configuration.
+	client, err := def.Factory(ctx, entry.Config, m.logger.With("type", entry.Type, "name", entry.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.putDestination(ctx, entry); err != nil {
+		return nil, err
+	}
+	m.clients[entry.storageKey()] = client
+	return entry, nil
+}
+
+// deleteDestination removes a destination. Secrets already written to it
+// are deleted first if its deletion policy says so.
+func (m *secretSyncManager) deleteDestination(ctx context.Context, typ, name string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	entry, err := m.getDestination(ctx, typ, name)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	if entry.DeletionPolicy == secretSyncDeletionPolicyDelete && len(entry.Associations) > 0 {
+		client, err := m.client(ctx, entry)
+		if err != nil {
+			return err
+		}
+		for key, assoc := range entry.Associations {
+			if err := client.Delete(ctx, assoc.RemoteName); err != nil {
+				return fmt.Errorf("failed to delete %q from destination: %w", assoc.RemoteName, err)
+			}
+			delete(entry.Associations, key)
+		}
+	}
+
+	if err := m.view.Delete(ctx, entry.storageKey()); err != nil {
+		return err
+	}
+	delete(m.clients, entry.storageKey())
+	return nil
+}
+
+// setAssociation associates a secret with a destination and syncs it
+// immediately.
+func (m *secretSyncManager) setAssociation(ctx context.Context, typ, name, mount, secretPath, remoteName string) (*secretSyncAssociation, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	entry, err := m.getDestination(ctx, typ, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: destination %s/%s does not exist", secretsync.ErrInvalidConfig, typ, name)
+	}
+
+	if err := m.source.validateMount(ctx, mount); err != nil {
+		return nil, err
+	}
+
+	client, err := m.client(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	if remoteName == "" {
+		remoteName = path.Join("vault", mount, secretPath)
+	}
+	remoteName = client.NormalizeName(remoteName)
+
+	key := secretSyncAssociationKey(mount, secretPath)
+	assoc, ok := entry.Associations[key]
+	if !ok {
+		assoc = &secretSyncAssociation{
+			Mount:      mount,
+			SecretPath: secretPath,
+		}
+		entry.Associations[key] = assoc
+	}
+	if assoc.RemoteName != "" && assoc.RemoteName != remoteName {
+		// The secret moves to a new name; the old one is cleaned up
+		// according to the deletion policy.
+		if entry.DeletionPolicy == secretSyncDeletionPolicyDelete {
+			if err := client.Delete(ctx, assoc.RemoteName); err != nil {
+				return nil, err
+			}
+		}
+		assoc.SyncedVersion = 0
+	}
+	assoc.RemoteName = remoteName
+
+	m.syncAssociation(ctx, entry, client, assoc)
+	if err := m.putDestination(ctx, entry); err != nil {
+		return nil, err
+	}
+	return assoc, nil
+}
+
+// removeAssociation stops syncing a secret, deleting it from the
+// destination if the deletion policy says so.
+func (m *secretSyncManager) removeAssociation(ctx context.Context, typ, name, mount, secretPath string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	entry, err := m.getDestination(ctx, typ, name)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	key := secretSyncAssociationKey(mount, secretPath)
+	assoc, ok := entry.Associations[key]
+	if !ok {
+		return nil
+	}
+
+	if entry.DeletionPolicy == secretSyncDeletionPolicyDelete {
+		client, err := m.client(ctx, entry)
+		if err != nil {
+			return err
+		}
+		if err := client.Delete(ctx, assoc.RemoteName); err != nil {
+			return err
+		}
+	}
+
+	delete(entry.Associations, key)
+	return m.putDestination(ctx, entry)
+}
+
+// reconcileDestination syncs every secret associated with a destination.
+func (m *secretSyncManager) reconcileDestination(ctx context.Context, typ, name string) (*secretSyncDestinationEntry, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	entry, err := m.getDestination(ctx, typ, name)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	return entry, m.reconcileDestinationLocked(ctx, entry)
+}
+
+func (m *secretSyncManager) reconcileDestinationLocked(ctx context.Context, entry *secretSyncDestinationEntry) error {
+	if len(entry.Associations) == 0 {
+		return nil
+	}
+
+	client, err := m.client(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	for _, assoc := range entry.Associations {
+		m.syncAssociation(ctx, entry, client, assoc)
+	}
+
+	return m.putDestination(ctx, entry)
+}
+
+// syncAssociation brings a single destination secret up to date. Failures
+// are recorded on the association rather than returned, so that one broken
+// secret does not hold up the others.
+func (m *secretSyncManager) syncAssociation(ctx context.Context, entry *secretSyncDestinationEntry, client secretsync.Destination, assoc *secretSyncAssociation) {
+	defer metrics.MeasureSince([]string{"secret-sync", "sync"}, time.Now())
+
+	err := m.syncAssociationErr(ctx, entry, client, assoc)
+	if err != nil {
+		m.logger.Warn("failed to sync secret", "type", entry.Type, "name", entry.Name,
+			"mount", assoc.Mount, "path", assoc.SecretPath, "error", err)
+		metrics.IncrCounterWithLabels([]string{"secret-sync", "error"}, 1, []metrics.Label{
+			{Name: "type", Value: entry.Type},
+		})
+		assoc.LastError = err.Error()
+		return
+	}
+
+	assoc.LastError = ""
+	assoc.LastSyncTime = time.Now().UTC()
+}
+
+func (m *secretSyncManager) syncAssociationErr(ctx context.Context, entry *secretSyncDestinationEntry, client secretsync.Destination, assoc *secretSyncAssociation) error {
+	data, version, err := m.source.readSecret(ctx, assoc.Mount, assoc.SecretPath)
+	if err != nil {
+		return err
+	}
+
+	// The source secret was deleted or destroyed.
+	if data == nil {
+		if assoc.SyncedVersion == 0 {
+			return nil
+		}
+		if entry.DeletionPolicy == secretSyncDeletionPolicyDelete {
+			if err := client.Delete(ctx, assoc.RemoteName); err != nil {
+				return err
+			}
+		}
+		assoc.SyncedVersion = 0
+		assoc.Drifted = false
+		return nil
+	}
+
+	flat, err := secretsync.Flatten(data)
+	if err != nil {
+		return err
+	}
+
+	if version != assoc.SyncedVersion {
+		if err := client.Put(ctx, assoc.RemoteName, flat); err != nil {
+			return err
+		}
+		assoc.SyncedVersion = version
+		assoc.Drifted = false
+		return nil
+	}
+
+	// Nothing changed in Vault; check whether the destination was modified
+	// behind our back.
+	remote, err := client.Get(ctx, assoc.RemoteName)
+	if err != nil {
+		return err
+	}
+	if secretsync.Equal(remote, flat) {
+		assoc.Drifted = false
+		return nil
+	}
+
+	m.logger.Warn("destination secret was modified outside of vault", "type", entry.Type, "name", entry.Name, "remote_name", assoc.RemoteName)
+	metrics.IncrCounterWithLabels([]string{"secret-sync", "drift"}, 1, []metrics.Label{
+		{Name: "type", Value: entry.Type},
+	})
+	assoc.LastDriftTime = time.Now().UTC()
+
+	if entry.DriftPolicy == secretSyncDriftPolicyReport {
+		assoc.Drifted = true
+		return nil
+	}
+
+	if err := client.Put(ctx, assoc.RemoteName, flat); err != nil {
+		return err
+	}
+	assoc.Drifted = false
+	return nil
+}
+
+// secretSyncSource provides the secrets being synced.
+type secretSyncSource interface {
+	validateMount(ctx context.Context, mount string) error
+	readSecret(ctx context.Context, mount, secretPath string) (map[string]interface{}, int64, error)
+}
+
+// sortedAssociationKeys returns the association keys of entry in order.
+func (e *secretSyncDestinationEntry) sortedAssociationKeys() []string {
+	keys := make([]string, 0, len(e.Associations))
+	for k := range e.Associations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,203 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/secretsync"
+)
+
+// testSyncDestination is an in-memory destination.
+type testSyncDestination struct {
+	l       sync.Mutex
+	secrets map[string]map[string]string
+}
+
+func (d *testSyncDestination) Put(_ context.Context, name string, data map[string]string) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+	d.secrets[name] = data
+	return nil
+}
+
+func (d *testSyncDestination) Get(_ context.Context, name string) (map[string]string, error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+	return d.secrets[name], nil
+}
+
+func (d *testSyncDestination) Delete(_ context.Context, name string) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+	delete(d.secrets, name)
+	return nil
+}
+
+func (d *testSyncDestination) NormalizeName(name string) string {
+	return name
+}
+
+// testSyncSource serves secrets from memory, keyed by mount and path.
+type testSyncSource struct {
+	secrets  map[string]map[string]interface{}
+	versions map[string]int64
+}
+
+func (s *testSyncSource) validateMount(_ context.Context, mount string) error {
+	if mount != "secret/" {
+		return fmt.Errorf("%w: %q is not a KV version 2 secrets engine", secretsync.ErrInvalidConfig, mount)
+	}
+	return nil
+}
+
+func (s *testSyncSource) readSecret(_ context.Context, mount, secretPath string) (map[string]interface{}, int64, error) {
+	key := mount + secretPath
+	return s.secrets[key], s.versions[key], nil
+}
+
+func (s *testSyncSource) write(secretPath string, data map[string]interface{}) {
+	s.secrets["secret/"+secretPath] = data
+	s.versions["secret/"+secretPath]++
+}
+
+func testSecretSync(t *testing.T) (*Core, logical.Backend, *testSyncDestination, *testSyncSource) {
+	t.Helper()
+	c, b, _ := testCoreSystemBackend(t)
+
+	dest := &testSyncDestination{secrets: make(map[string]map[string]string)}
+	source := &testSyncSource{
+		secrets:  make(map[string]map[string]interface{}),
+		versions: make(map[string]int64),
+	}
+
+	c.secretSync.definitions["test"] = secretsync.Definition{
+		Factory: func(context.Context, map[string]string, log.Logger) (secretsync.Destination, error) {
+			return dest, nil
+		},
+		SensitiveConfig: []string{"password"},
+	}
+	c.secretSync.source = source
+
+	return c, b, dest, source
+}
+
+func TestSecretSync_Lifecycle(t *testing.T) {
+	c, b, dest, source := testSecretSync(t)
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sync/destinations/test/primary")
+	req.Data["config"] = map[string]interface{}{"user": "vault", "password": "hunter2"}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["config"].(map[string]string)["password"] != "<redacted>" {
+		t.Fatalf("sensitive config was returned: %#v", resp.Data["config"])
+	}
+
+	source.write("app/db", map[string]interface{}{"password": "one", "port": 5432})
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sync/destinations/test/primary/associations/set")
+	req.Data["mount"] = "secret"
+	req.Data["secret_name"] = "app/db"
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	remoteName := resp.Data["remote_name"].(string)
+	if remoteName != "vault/secret/app/db" {
+		t.Fatalf("bad remote name: %q", remoteName)
+	}
+	if got := dest.secrets[remoteName]; got["password"] != "one" || got["port"] != "5432" {
+		t.Fatalf("secret not synced: %#v", got)
+	}
+
+	// A new version is picked up on the next reconciliation.
+	source.write("app/db", map[string]interface{}{"password": "two"})
+	c.secretSync.reconcileAll(ctx)
+	if got := dest.secrets[remoteName]; got["password"] != "two" {
+		t.Fatalf("new version not synced: %#v", got)
+	}
+
+	// Drift is overwritten by default.
+	dest.secrets[remoteName] = map[string]string{"password": "tampered"}
+	c.secretSync.reconcileAll(ctx)
+	if got := dest.secrets[remoteName]; got["password"] != "two" {
+		t.Fatalf("drift not corrected: %#v", got)
+	}
+
+	// Deleting the source secret deletes the destination secret.
+	delete(source.secrets, "secret/app/db")
+	c.secretSync.reconcileAll(ctx)
+	if _, ok := dest.secrets[remoteName]; ok {
+		t.Fatal("destination secret not deleted with source")
+	}
+
+	// Associations with other engines are rejected.
+	req = logical.TestRequest(t, logical.UpdateOperation, "sync/destinations/test/primary/associations/set")
+	req.Data["mount"] = "cubbyhole"
+	req.Data["secret_name"] = "foo"
+	resp, err = b.HandleRequest(ctx, req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got err: %v resp: %#v", err, resp)
+	}
+}
+
+func TestSecretSync_RetainAndReport(t *testing.T) {
+	c, b, dest, source := testSecretSync(t)
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sync/destinations/test/primary")
+	req.Data["deletion_policy"] = "retain"
+	req.Data["drift_policy"] = "report"
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	source.write("app/api", map[string]interface{}{"key": "abc"})
+	assoc, err := c.secretSync.setAssociation(ctx, "test", "primary", "secret/", "app/api", "api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assoc.LastError != "" {
+		t.Fatalf("sync failed: %s", assoc.LastError)
+	}
+
+	dest.secrets["api-key"] = map[string]string{"key": "tampered"}
+	entry, err := c.secretSync.reconcileDestination(ctx, "test", "primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Associations["secret/app/api"].Drifted {
+		t.Fatal("drift not reported")
+	}
+	if dest.secrets["api-key"]["key"] != "tampered" {
+		t.Fatal("drift was overwritten despite report policy")
+	}
+
+	if err := c.secretSync.removeAssociation(ctx, "test", "primary", "secret/", "app/api"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dest.secrets["api-key"]; !ok {
+		t.Fatal("destination secret deleted despite retain policy")
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "sync/destinations/test/primary")
+	if _, err := b.HandleRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	req = logical.TestRequest(t, logical.ListOperation, "sync/destinations")
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := resp.Data["keys"].([]string); len(keys) != 0 {
+		t.Fatalf("expected no destinations, got %v", keys)
+	}
+}
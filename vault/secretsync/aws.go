@@ -0,0 +1,120 @@
+package secretsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/awsutil"
+)
+
+type awsSecretsManager struct {
+	client *secretsmanager.SecretsManager
+}
+
+var _ Destination = (*awsSecretsManager)(nil)
+
+// NewAWSSecretsManager returns a destination writing to AWS Secrets
+// Manager. Credentials not present in config are taken from the
+// environment, following the AWS default credential chain.
+func NewAWSSecretsManager(_ context.Context, config map[string]string, logger log.Logger) (Destination, error) {
+	if err := requireConfig(config, "region"); err != nil {
+		return nil, err
+	}
+
+	credsConfig := &awsutil.CredentialsConfig{
+		AccessKey:    config["access_key_id"],
+		SecretKey:    config["secret_access_key"],
+		SessionToken: config["session_token"],
+		Region:       config["region"],
+		Logger:       logger,
+	}
+	creds, err := credsConfig.GenerateCredentialChain()
+	if err != nil {
+		return nil, err
+	}
+
+	awsConfig := aws.NewConfig().
+		WithCredentials(creds).
+		WithRegion(config["region"])
+	if endpoint := config["endpoint"]; endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &awsSecretsManager{
+		client: secretsmanager.New(sess),
+	}, nil
+}
+
+func (d *awsSecretsManager) Put(ctx context.Context, name string, data map[string]string) error {
+	value, err := Encode(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(string(value)),
+	})
+	if !isAWSNotFound(err) {
+		return err
+	}
+
+	_, err = d.client.CreateSecretWithContext(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		Description:  aws.String("Managed by Vault secrets sync"),
+		SecretString: aws.String(string(value)),
+	})
+	return err
+}
+
+func (d *awsSecretsManager) Get(ctx context.Context, name string) (map[string]string, error) {
+	out, err := d.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if isAWSNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode([]byte(aws.StringValue(out.SecretString)))
+}
+
+func (d *awsSecretsManager) Delete(ctx context.Context, name string) error {
+	_, err := d.client.DeleteSecretWithContext(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(name),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	})
+	if isAWSNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// NormalizeName keeps the characters Secrets Manager allows in names.
+func (d *awsSecretsManager) NormalizeName(name string) string {
+	return replaceInvalid(name, func(r rune) bool {
+		switch r {
+		case '/', '_', '+', '=', '.', '@', '-':
+			return true
+		}
+		return isAlphanumeric(r)
+	}, '_')
+}
+
+func isAWSNotFound(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == secretsmanager.ErrCodeResourceNotFoundException
+}
@@ -0,0 +1,181 @@
+package secretsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+)
+
+const azureKeyVaultAPIVersion = "7.3"
+
+type azureKeyVault struct {
+	client   *http.Client
+	vaultURI string
+	token    *adal.ServicePrincipalToken
+}
+
+var _ Destination = (*azureKeyVault)(nil)
+
+// NewAzureKeyVault returns a destination writing to an Azure Key Vault.
+// Without a client secret, the managed identity of the host is used.
+func NewAzureKeyVault(_ context.Context, config map[string]string, _ log.Logger) (Destination, error) {
+	if err := requireConfig(config, "key_vault_uri"); err != nil {
+		return nil, err
+	}
+	vaultURI, err := url.Parse(config["key_vault_uri"])
+	if err != nil || vaultURI.Scheme != "https" || vaultURI.Host == "" {
+		return nil, fmt.Errorf("%w: key_vault_uri must be an https URL", ErrInvalidConfig)
+	}
+
+	environment := azure.PublicCloud
+	if name := config["cloud"]; name != "" {
+		environment, err = azure.EnvironmentFromName(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+		}
+	}
+	resource := strings.TrimSuffix(environment.ResourceIdentifiers.KeyVault, "/")
+
+	var token *adal.ServicePrincipalToken
+	if config["client_secret"] != "" {
+		if err := requireConfig(config, "tenant_id", "client_id"); err != nil {
+			return nil, err
+		}
+		oauthConfig, err := adal.NewOAuthConfig(environment.ActiveDirectoryEndpoint, config["tenant_id"])
+		if err != nil {
+			return nil, err
+		}
+		token, err = adal.NewServicePrincipalToken(*oauthConfig, config["client_id"], config["client_secret"], resource)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		msiEndpoint, err := adal.GetMSIVMEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		token, err = adal.NewServicePrincipalTokenFromMSI(msiEndpoint, resource)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &azureKeyVault{
+		client:   cleanhttp.DefaultPooledClient(),
+		vaultURI: strings.TrimSuffix(vaultURI.String(), "/"),
+		token:    token,
+	}, nil
+}
+
+type azureSecretBundle struct {
+	Value       string            `json:"value"`
+	ContentType string            `json:"contentType,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+func (d *azureKeyVault) do(ctx context.Context, method, name string, body interface{}) (*http.Response, error) {
+	if err := d.token.EnsureFreshWithContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to obtain access token: %w", err)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	u := fmt.Sprintf("%s/secrets/%s?api-version=%s", d.vaultURI, url.PathEscape(name), azureKeyVaultAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.token.OAuthToken())
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return d.client.Do(req)
+}
+
+func (d *azureKeyVault) Put(ctx context.Context, name string, data map[string]string) error {
+	value, err := Encode(data)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.do(ctx, http.MethodPut, name, &azureSecretBundle{
+		Value:       string(value),
+		ContentType: "application/json",
+		Tags:        map[string]string{"managed-by": "vault"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return azureResponseError(resp)
+}
+
+func (d *azureKeyVault) Get(ctx context.Context, name string) (map[string]string, error) {
+	resp, err := d.do(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := azureResponseError(resp); err != nil {
+		return nil, err
+	}
+
+	var bundle azureSecretBundle
+	if err := json.NewDecoder(resp.Body).Decode(&bundle); err != nil {
+		return nil, err
+	}
+	return Decode([]byte(bundle.Value))
+}
+
+// Delete removes the secret. On vaults with soft-delete enabled, the
+// secret is retained as deleted until purged or its retention expires.
+func (d *azureKeyVault) Delete(ctx context.Context, name string) error {
+	resp, err := d.do(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return azureResponseError(resp)
+}
+
+// NormalizeName keeps the characters Key Vault allows in secret names.
+func (d *azureKeyVault) NormalizeName(name string) string {
+	return replaceInvalid(name, func(r rune) bool {
+		return r == '-' || isAlphanumeric(r)
+	}, '-')
+}
+
+func azureResponseError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("key vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,109 @@
+package secretsync
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	log "github.com/hashicorp/go-hclog"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+)
+
+type gcpSecretManager struct {
+	service *secretmanager.Service
+	project string
+}
+
+var _ Destination = (*gcpSecretManager)(nil)
+
+// NewGCPSecretManager returns a destination writing to Google Cloud Secret
+// Manager. Without explicit credentials, Application Default Credentials
+// are used.
+func NewGCPSecretManager(ctx context.Context, config map[string]string, _ log.Logger) (Destination, error) {
+	if err := requireConfig(config, "project_id"); err != nil {
+		return nil, err
+	}
+
+	var opts []option.ClientOption
+	if creds := config["credentials"]; creds != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(creds)))
+	}
+
+	service, err := secretmanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secret manager client: %w", err)
+	}
+
+	return &gcpSecretManager{
+		service: service,
+		project: config["project_id"],
+	}, nil
+}
+
+func (d *gcpSecretManager) secretName(name string) string {
+	return fmt.Sprintf("projects/%s/secrets/%s", d.project, name)
+}
+
+func (d *gcpSecretManager) Put(ctx context.Context, name string, data map[string]string) error {
+	value, err := Encode(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.service.Projects.Secrets.Create("projects/"+d.project, &secretmanager.Secret{
+		Labels: map[string]string{"managed-by": "vault"},
+		Replication: &secretmanager.Replication{
+			Automatic: &secretmanager.Automatic{},
+		},
+	}).SecretId(name).Context(ctx).Do()
+	if err != nil && !isGoogleAPIStatus(err, http.StatusConflict) {
+		return err
+	}
+
+	_, err = d.service.Projects.Secrets.AddVersion(d.secretName(name), &secretmanager.AddSecretVersionRequest{
+		Payload: &secretmanager.SecretPayload{
+			Data: base64.StdEncoding.EncodeToString(value),
+		},
+	}).Context(ctx).Do()
+	return err
+}
+
+func (d *gcpSecretManager) Get(ctx context.Context, name string) (map[string]string, error) {
+	resp, err := d.service.Projects.Secrets.Versions.Access(d.secretName(name) + "/versions/latest").Context(ctx).Do()
+	if isGoogleAPIStatus(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(value)
+}
+
+func (d *gcpSecretManager) Delete(ctx context.Context, name string) error {
+	_, err := d.service.Projects.Secrets.Delete(d.secretName(name)).Context(ctx).Do()
+	if isGoogleAPIStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+// NormalizeName keeps the characters Secret Manager allows in secret IDs.
+func (d *gcpSecretManager) NormalizeName(name string) string {
+	return replaceInvalid(name, func(r rune) bool {
+		return r == '_' || r == '-' || isAlphanumeric(r)
+	}, '_')
+}
+
+func isGoogleAPIStatus(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
@@ -0,0 +1,218 @@
+package secretsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+)
+
+const (
+	kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+	kubernetesManagedByLabel    = "app.kubernetes.io/managed-by"
+)
+
+type kubernetes struct {
+	client    *http.Client
+	host      string
+	token     string
+	namespace string
+}
+
+var _ Destination = (*kubernetes)(nil)
+
+// NewKubernetes returns a destination writing Kubernetes Secrets. Settings
+// not present in config default to the service account of the pod Vault
+// runs in.
+func NewKubernetes(_ context.Context, config map[string]string, _ log.Logger) (Destination, error) {
+	host := config["host"]
+	if host == "" {
+		h, p := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if h == "" || p == "" {
+			return nil, fmt.Errorf("%w: host is required when not running in kubernetes", ErrInvalidConfig)
+		}
+		host = "https://" + net.JoinHostPort(h, p)
+	}
+
+	token := config["token"]
+	if token == "" {
+		raw, err := os.ReadFile(kubernetesServiceAccountDir + "token")
+		if err != nil {
+			return nil, fmt.Errorf("%w: token is required when not running in kubernetes", ErrInvalidConfig)
+		}
+		token = strings.TrimSpace(string(raw))
+	}
+
+	namespace := config["namespace"]
+	if namespace == "" {
+		raw, err := os.ReadFile(kubernetesServiceAccountDir + "namespace")
+		if err != nil {
+			return nil, fmt.Errorf("%w: namespace is required when not running in kubernetes", ErrInvalidConfig)
+		}
+		namespace = strings.TrimSpace(string(raw))
+	}
+
+	caPEM := []byte(config["ca_cert"])
+	if len(caPEM) == 0 {
+		// Fall back to the system roots if the pod's CA is unavailable.
+		caPEM, _ = os.ReadFile(kubernetesServiceAccountDir + "ca.crt")
+	}
+
+	client := cleanhttp.DefaultPooledClient()
+	if len(caPEM) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("%w: ca_cert contains no valid certificates", ErrInvalidConfig)
+		}
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &kubernetes{
+		client:    client,
+		host:      strings.TrimSuffix(host, "/"),
+		token:     token,
+		namespace: namespace,
+	}, nil
+}
+
+type kubernetesSecret struct {
+	APIVersion string                   `json:"apiVersion"`
+	Kind       string                   `json:"kind"`
+	Metadata   kubernetesSecretMetadata `json:"metadata"`
+	Type       string                   `json:"type,omitempty"`
+	Data       map[string][]byte        `json:"data"`
+}
+
+type kubernetesSecretMetadata struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func (d *kubernetes) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	u := fmt.Sprintf("%s/api/v1/namespaces/%s/secrets%s", d.host, d.namespace, path)
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return d.client.Do(req)
+}
+
+func (d *kubernetes) Put(ctx context.Context, name string, data map[string]string) error {
+	secret := &kubernetesSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: kubernetesSecretMetadata{
+			Name:   name,
+			Labels: map[string]string{kubernetesManagedByLabel: "vault"},
+		},
+		Type: "Opaque",
+		Data: make(map[string][]byte, len(data)),
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+
+	resp, err := d.do(ctx, http.MethodPut, "/"+name, secret)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		defer resp.Body.Close()
+		return kubernetesResponseError(resp)
+	}
+	resp.Body.Close()
+
+	resp, err = d.do(ctx, http.MethodPost, "", secret)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return kubernetesResponseError(resp)
+}
+
+func (d *kubernetes) Get(ctx context.Context, name string) (map[string]string, error) {
+	resp, err := d.do(ctx, http.MethodGet, "/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := kubernetesResponseError(resp); err != nil {
+		return nil, err
+	}
+
+	var secret kubernetesSecret
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return data, nil
+}
+
+func (d *kubernetes) Delete(ctx context.Context, name string) error {
+	resp, err := d.do(ctx, http.MethodDelete, "/"+name, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return kubernetesResponseError(resp)
+}
+
+// NormalizeName converts name into a valid DNS subdomain, as required for
+// Secret names.
+func (d *kubernetes) NormalizeName(name string) string {
+	name = replaceInvalid(strings.ToLower(name), func(r rune) bool {
+		return r == '-' || r == '.' || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+	}, '-')
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return strings.Trim(name, "-.")
+}
+
+func kubernetesResponseError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("kubernetes API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
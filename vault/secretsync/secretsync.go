@@ -0,0 +1,159 @@
+// Package secretsync contains the clients used to mirror Vault secrets into
+// external secret stores.
+package secretsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+const (
+	TypeAWSSecretsManager = "aws-sm"
+	TypeGCPSecretManager  = "gcp-sm"
+	TypeAzureKeyVault     = "azure-kv"
+	TypeKubernetes        = "kubernetes"
+)
+
+// ErrInvalidConfig is returned by factories when a destination is
+// misconfigured. The wrapping error describes the problem.
+var ErrInvalidConfig = errors.New("invalid destination configuration")
+
+// Destination is an external secret store secrets are pushed to.
+//
+// Secrets are key/value pairs; stores holding a single opaque value per
+// secret receive the pairs encoded as a JSON object.
+type Destination interface {
+	// Put creates or replaces the named secret.
+	Put(ctx context.Context, name string, data map[string]string) error
+
+	// Get returns the current content of the named secret, or nil if it
+	// does not exist.
+	Get(ctx context.Context, name string) (map[string]string, error)
+
+	// Delete removes the named secret. Deleting a secret that does not
+	// exist is not an error.
+	Delete(ctx context.Context, name string) error
+
+	// NormalizeName maps a name onto the character set the store accepts
+	// for secret names.
+	NormalizeName(name string) string
+}
+
+// Factory creates a destination from its stored configuration.
+type Factory func(ctx context.Context, config map[string]string, logger log.Logger) (Destination, error)
+
+// Definition describes a destination type.
+type Definition struct {
+	Factory Factory
+
+	// SensitiveConfig lists the configuration keys that hold credentials
+	// and must never be returned when reading the destination.
+	SensitiveConfig []string
+}
+
+// BuiltinDefinitions returns the destination types available by default,
+// keyed by type name.
+func BuiltinDefinitions() map[string]Definition {
+	return map[string]Definition{
+		TypeAWSSecretsManager: {
+			Factory:         NewAWSSecretsManager,
+			SensitiveConfig: []string{"secret_access_key", "session_token"},
+		},
+		TypeGCPSecretManager: {
+			Factory:         NewGCPSecretManager,
+			SensitiveConfig: []string{"credentials"},
+		},
+		TypeAzureKeyVault: {
+			Factory:         NewAzureKeyVault,
+			SensitiveConfig: []string{"client_secret"},
+		},
+		TypeKubernetes: {
+			Factory:         NewKubernetes,
+			SensitiveConfig: []string{"token"},
+		},
+	}
+}
+
+// Encode returns the canonical JSON encoding of data, used for stores
+// holding a single value per secret. Keys are sorted, so equal data always
+// encodes identically.
+func Encode(data map[string]string) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// Decode parses a value written by Encode.
+func Decode(raw []byte) (map[string]string, error) {
+	var data map[string]string
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("secret was not written by vault: %w", err)
+	}
+	return data, nil
+}
+
+// Flatten converts KV secret data into string pairs. String values are kept
+// as is; other values are JSON encoded.
+func Flatten(data map[string]interface{}) (map[string]string, error) {
+	flat := make(map[string]string, len(data))
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			flat[k] = s
+			continue
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value of %q: %w", k, err)
+		}
+		flat[k] = string(encoded)
+	}
+	return flat, nil
+}
+
+// Equal reports whether two secrets hold the same pairs.
+func Equal(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// requireConfig returns an error naming every key in keys missing from
+// config.
+func requireConfig(config map[string]string, keys ...string) error {
+	var missing []string
+	for _, k := range keys {
+		if config[k] == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("%w: missing %s", ErrInvalidConfig, strings.Join(missing, ", "))
+}
+
+// replaceInvalid replaces every rune of name not accepted by valid with
+// replacement.
+func replaceInvalid(name string, valid func(r rune) bool, replacement rune) string {
+	return strings.Map(func(r rune) rune {
+		if valid(r) {
+			return r
+		}
+		return replacement
+	}, name)
+}
+
+func isAlphanumeric(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
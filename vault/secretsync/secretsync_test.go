@@ -0,0 +1,68 @@
+package secretsync
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	flat, err := Flatten(map[string]interface{}{
+		"password": "hunter2",
+		"port":     5432,
+		"hosts":    []interface{}{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"password": "hunter2",
+		"port":     "5432",
+		"hosts":    `["a","b"]`,
+	}
+	if !Equal(flat, expected) {
+		t.Fatalf("expected %v, got %v", expected, flat)
+	}
+
+	encoded, err := Encode(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(decoded, flat) {
+		t.Fatalf("round trip mismatch: %v", decoded)
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	cases := []struct {
+		dest     Destination
+		expected string
+	}{
+		{&awsSecretsManager{}, "vault/secret/app/db_prod"},
+		{&gcpSecretManager{}, "vault_secret_app_db_prod"},
+		{&azureKeyVault{}, "vault-secret-app-db-prod"},
+		{&kubernetes{}, "vault-secret-app-db-prod"},
+	}
+
+	for _, tc := range cases {
+		if got := tc.dest.NormalizeName("vault/secret/app/db prod"); got != tc.expected {
+			t.Fatalf("%T: expected %q, got %q", tc.dest, tc.expected, got)
+		}
+	}
+}
+
+func TestRequireConfig(t *testing.T) {
+	if _, err := NewAWSSecretsManager(context.Background(), map[string]string{}, nil); err == nil {
+		t.Fatal("expected error for missing region")
+	}
+	if _, err := NewGCPSecretManager(context.Background(), map[string]string{}, nil); err == nil {
+		t.Fatal("expected error for missing project_id")
+	}
+	if _, err := NewAzureKeyVault(context.Background(), map[string]string{"key_vault_uri": "http://example.com"}, nil); err == nil {
+		t.Fatal("expected error for non-https key_vault_uri")
+	}
+}
@@ -204,6 +204,8 @@ func TestCoreWithSealAndUINoCleanup(t testing.T, opts *CoreConfig) *Core {
 	conf.RawConfig = opts.RawConfig
 	conf.EnableResponseHeaderHostname = opts.EnableResponseHeaderHostname
 	conf.DisableSSCTokens = opts.DisableSSCTokens
+	conf.TokenAccessorWriteBehind = opts.TokenAccessorWriteBehind
+	conf.LazyLeaseRestore = opts.LazyLeaseRestore
 	conf.PluginDirectory = opts.PluginDirectory
 
 	if opts.Logger != nil {
@@ -1572,6 +1574,8 @@ func NewTestCluster(t testing.T, base *CoreConfig, opts *TestClusterOptions) *Te
 		coreConfig.DefaultLeaseTTL = base.DefaultLeaseTTL
 		coreConfig.MaxLeaseTTL = base.MaxLeaseTTL
 		coreConfig.CacheSize = base.CacheSize
+		coreConfig.CacheSizeBytes = base.CacheSizeBytes
+		coreConfig.CachePinnedPrefixes = base.CachePinnedPrefixes
 		coreConfig.PluginDirectory = base.PluginDirectory
 		coreConfig.Seal = base.Seal
 		coreConfig.UnwrapSeal = base.UnwrapSeal
@@ -1644,6 +1648,8 @@ func NewTestCluster(t testing.T, base *CoreConfig, opts *TestClusterOptions) *Te
 		coreConfig.ActivityLogConfig = base.ActivityLogConfig
 		coreConfig.EnableResponseHeaderHostname = base.EnableResponseHeaderHostname
 		coreConfig.EnableResponseHeaderRaftNodeID = base.EnableResponseHeaderRaftNodeID
+		coreConfig.TokenAccessorWriteBehind = base.TokenAccessorWriteBehind
+		coreConfig.LazyLeaseRestore = base.LazyLeaseRestore
 
 		coreConfig.RollbackPeriod = base.RollbackPeriod
 
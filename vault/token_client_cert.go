@@ -0,0 +1,30 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// clientCertFingerprint returns the hex encoded SHA-256 fingerprint of the
+// TLS client certificate presented with the request, or an empty string if
+// there is none.
+func clientCertFingerprint(req *logical.Request) string {
+	if req.Connection == nil || req.Connection.ConnState == nil || len(req.Connection.ConnState.PeerCertificates) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(req.Connection.ConnState.PeerCertificates[0].Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenClientCertMatches returns whether the request presents the TLS client
+// certificate the token is bound to.
+func tokenClientCertMatches(te *logical.TokenEntry, req *logical.Request) bool {
+	fingerprint := clientCertFingerprint(req)
+	if fingerprint == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(fingerprint), []byte(te.BoundClientCertFingerprint)) == 1
+}
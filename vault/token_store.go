@@ -92,6 +92,10 @@ const (
 	// IgnoreForBilling used for HCP Link batch tokens and inserted into the InternalMeta
 	// Tokens created for the purpose of HCP Link should bypass counting for billing purposes
 	IgnoreForBilling = "ignore_for_billing"
+
+	// inlinePolicyScopeMeta is inserted into the InternalMeta of tokens whose
+	// inline policy restricts their policies instead of adding to them
+	inlinePolicyScopeMeta = "inline_policy_scope"
 )
 
 var (
@@ -218,6 +222,10 @@ func (ts *TokenStore) paths() []*framework.Path {
 					Type:        framework.TypeStringSlice,
 					Description: "List of policies for the token",
 				},
+				"inline_policy": {
+					Type:        framework.TypeString,
+					Description: "ACL policy, in HCL or JSON, that further restricts the operations allowed by the policies of a batch token",
+				},
 				"format": {
 					Type:        framework.TypeString,
 					Query:       true,
@@ -293,6 +301,10 @@ func (ts *TokenStore) paths() []*framework.Path {
 					Type:        framework.TypeStringSlice,
 					Description: "List of policies for the token",
 				},
+				"inline_policy": {
+					Type:        framework.TypeString,
+					Description: "ACL policy, in HCL or JSON, that further restricts the operations allowed by the policies of a batch token",
+				},
 				"format": {
 					Type:        framework.TypeString,
 					Query:       true,
@@ -364,6 +376,10 @@ func (ts *TokenStore) paths() []*framework.Path {
 					Type:        framework.TypeStringSlice,
 					Description: "List of policies for the token",
 				},
+				"inline_policy": {
+					Type:        framework.TypeString,
+					Description: "ACL policy, in HCL or JSON, that further restricts the operations allowed by the policies of a batch token",
+				},
 				"format": {
 					Type:        framework.TypeString,
 					Query:       true,
@@ -647,6 +663,11 @@ func (ts *TokenStore) paths() []*framework.Path {
 				Type:        framework.TypeCommaStringSlice,
 				Description: "String or JSON list of allowed entity aliases. If set, specifies the entity aliases which are allowed to be used during token generation. This field supports globbing.",
 			},
+
+			"bind_client_cert": {
+				Type:        framework.TypeBool,
+				Description: tokenBindClientCertHelp,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -659,7 +680,7 @@ func (ts *TokenStore) paths() []*framework.Path {
 		ExistenceCheck: ts.tokenStoreRoleExistenceCheck,
 	}
 
-	tokenutil.AddTokenFieldsWithAllowList(rolesPath.Fields, []string{"token_bound_cidrs", "token_explicit_max_ttl", "token_period", "token_type", "token_no_default_policy", "token_num_uses"})
+	tokenutil.AddTokenFieldsWithAllowList(rolesPath.Fields, []string{"token_bound_cidrs", "token_explicit_max_ttl", "token_period", "token_type", "token_no_default_policy", "token_num_uses", "token_allowed_countries", "token_denied_countries", "token_allowed_asns", "token_denied_asns"})
 	p = append(p, rolesPath)
 
 	return p
@@ -727,6 +748,15 @@ type TokenStore struct {
 	// failed. Revocation needs to handle these states accordingly.
 	tokensPendingDeletion *sync.Map
 
+	// pendingWrites stores the entries being persisted in the background, as
+	// *pendingWrite by key, so that they can be read until they are persisted
+	// and canceled when their token is revoked first.
+	pendingWrites *sync.Map
+
+	// pendingWritesWG tracks the background writes, which must be done
+	// before the barrier is sealed.
+	pendingWritesWG sync.WaitGroup
+
 	cubbyholeDestroyer func(context.Context, *TokenStore, *logical.TokenEntry) error
 
 	logger log.Logger
@@ -767,6 +797,7 @@ func NewTokenStore(ctx context.Context, logger log.Logger, core *Core, config *l
 		logger:                logger,
 		tokenLocks:            locksutil.CreateLocks(),
 		tokensPendingDeletion: &sync.Map{},
+		pendingWrites:         &sync.Map{},
 		saltLock:              sync.RWMutex{},
 		tidyLock:              new(uint32),
 		quitContext:           core.activeContext,
@@ -887,6 +918,10 @@ type tsRoleEntry struct {
 
 	// The set of allowed entity aliases used during token creation
 	AllowedEntityAliases []string `json:"allowed_entity_aliases" mapstructure:"allowed_entity_aliases" structs:"allowed_entity_aliases"`
+
+	// If set, tokens created using this role are bound to the TLS client
+	// certificate of the connection they are created over
+	BindClientCert bool `json:"bind_client_cert" mapstructure:"bind_client_cert" structs:"bind_client_cert"`
 }
 
 type accessorEntry struct {
@@ -984,9 +1019,138 @@ func (ts *TokenStore) tokenStoreAccessorList(ctx context.Context, req *logical.R
 	return resp, nil
 }
 
+// tokenStoreWrite is a storage write of the token store.
+type tokenStoreWrite struct {
+	view  *BarrierView
+	entry *logical.StorageEntry
+
+	// behind is whether the entry is persisted in the background, once the
+	// other writes are.
+	behind bool
+}
+
+// tokenStoreWrites collects the storage writes of a token, so that they are
+// persisted in a single round trip to storage when the barrier supports it.
+type tokenStoreWrites struct {
+	writes []*tokenStoreWrite
+}
+
+func (w *tokenStoreWrites) add(view *BarrierView, entry *logical.StorageEntry) {
+	w.writes = append(w.writes, &tokenStoreWrite{view: view, entry: entry})
+}
+
+func (w *tokenStoreWrites) addBehind(view *BarrierView, entry *logical.StorageEntry) {
+	w.writes = append(w.writes, &tokenStoreWrite{view: view, entry: entry, behind: true})
+}
+
+// commitWrites persists the writes, in a single transaction if the barrier
+// and the physical backend support it, and in order otherwise. The writes
+// persisted in the background are started once the others are persisted.
+func (ts *TokenStore) commitWrites(ctx context.Context, w *tokenStoreWrites) error {
+	var direct, behind []*tokenStoreWrite
+	for _, write := range w.writes {
+		if write.behind {
+			behind = append(behind, write)
+		} else {
+			direct = append(direct, write)
+		}
+	}
+
+	batcher, ok := ts.core.barrier.(BarrierBatchStorage)
+	switch {
+	case len(direct) > 1 && ok:
+		entries := make([]*logical.StorageEntry, 0, len(direct))
+		for _, write := range direct {
+			entry, err := write.view.expandEntry(write.entry)
+			if err != nil {
+				return fmt.Errorf("failed to persist entry: %w", err)
+			}
+			entries = append(entries, entry)
+		}
+		if err := batcher.PutBatch(ctx, entries); err != nil {
+			return fmt.Errorf("failed to persist entry: %w", err)
+		}
+	default:
+		for _, write := range direct {
+			if err := write.view.Put(ctx, write.entry); err != nil {
+				return fmt.Errorf("failed to persist entry: %w", err)
+			}
+		}
+	}
+
+	for _, write := range behind {
+		ts.putBehind(write)
+	}
+	return nil
+}
+
+// pendingWrite is an entry being persisted in the background.
+type pendingWrite struct {
+	// l is held while the entry is persisted
+	l        sync.Mutex
+	value    []byte
+	canceled bool
+}
+
+// putBehind persists the entry in the background. Until it is persisted,
+// its value is kept in pendingWrites for lookups.
+func (ts *TokenStore) putBehind(write *tokenStoreWrite) {
+	key := write.view.storage.ExpandKey(write.entry.Key)
+	pending := &pendingWrite{value: write.entry.Value}
+	ts.pendingWrites.Store(key, pending)
+
+	ts.pendingWritesWG.Add(1)
+	go func() {
+		defer ts.pendingWritesWG.Done()
+
+		pending.l.Lock()
+		defer pending.l.Unlock()
+		defer ts.pendingWrites.Delete(key)
+
+		if pending.canceled {
+			return
+		}
+		if err := write.view.Put(ts.quitContext, write.entry); err != nil {
+			ts.logger.Error("failed to persist entry in the background", "key", key, "error", err)
+		}
+	}()
+}
+
+// cancelPendingWrite cancels the background write of the entry of the view
+// with the given key, or waits for it if it already started, so that the
+// entry can be deleted without being written back afterwards.
+func (ts *TokenStore) cancelPendingWrite(view *BarrierView, key string) {
+	value, ok := ts.pendingWrites.Load(view.storage.ExpandKey(key))
+	if !ok {
+		return
+	}
+	pending := value.(*pendingWrite)
+	pending.l.Lock()
+	pending.canceled = true
+	pending.l.Unlock()
+	ts.pendingWrites.Delete(view.storage.ExpandKey(key))
+}
+
+// waitPendingWrites waits for the writes started in the background.
+func (ts *TokenStore) waitPendingWrites() {
+	ts.pendingWritesWG.Wait()
+}
+
+// pendingEntry returns the entry of the view being persisted in the
+// background with the given key, if any.
+func (ts *TokenStore) pendingEntry(view *BarrierView, key string) *logical.StorageEntry {
+	value, ok := ts.pendingWrites.Load(view.storage.ExpandKey(key))
+	if !ok {
+		return nil
+	}
+	pending := value.(*pendingWrite)
+	return &logical.StorageEntry{Key: key, Value: pending.value}
+}
+
 // createAccessor is used to create an identifier for the token ID.
-// A storage index, mapping the accessor to the token ID is also created.
-func (ts *TokenStore) createAccessor(ctx context.Context, entry *logical.TokenEntry) error {
+// A storage index, mapping the accessor to the token ID is also added to the
+// writes of the token.
+func (ts *TokenStore) createAccessor(ctx context.Context, entry *logical.TokenEntry, writes *tokenStoreWrites) error {
 	defer metrics.MeasureSince([]string{"token", "createAccessor"}, time.Now())
 
 	var err error
@@ -1027,8 +1191,10 @@ func (ts *TokenStore) createAccessor(ctx context.Context, entry *logical.TokenEn
 	}
 
 	le := &logical.StorageEntry{Key: saltID, Value: aEntryBytes}
-	if err := ts.accessorView(tokenNS).Put(ctx, le); err != nil {
-		return fmt.Errorf("failed to persist accessor index entry: %w", err)
+	if ts.core.tokenAccessorWriteBehind {
+		writes.addBehind(ts.accessorView(tokenNS), le)
+	} else {
+		writes.add(ts.accessorView(tokenNS), le)
 	}
 	return nil
 }
@@ -1124,12 +1290,18 @@ func (ts *TokenStore) create(ctx context.Context, entry *logical.TokenEntry) err
 			}
 		}
 
-		err = ts.createAccessor(ctx, entry)
+		writes := &tokenStoreWrites{}
+		err = ts.createAccessor(ctx, entry, writes)
 		if err != nil {
 			return err
 		}
 
-		err = ts.storeCommon(ctx, entry, true)
+		err = ts.storeCommon(ctx, entry, true, writes)
+		if err != nil {
+			return err
+		}
+
+		err = ts.commitWrites(ctx, writes)
 		if err != nil {
 			return err
 		}
@@ -1295,12 +1467,16 @@ func (ts *TokenStore) CalculateSignedTokenHMAC(marshalledToken []byte) ([]byte,
 // secondary index.
 func (ts *TokenStore) store(ctx context.Context, entry *logical.TokenEntry) error {
 	defer metrics.MeasureSince([]string{"token", "store"}, time.Now())
-	return ts.storeCommon(ctx, entry, false)
+	writes := &tokenStoreWrites{}
+	if err := ts.storeCommon(ctx, entry, false, writes); err != nil {
+		return err
+	}
+	return ts.commitWrites(ctx, writes)
 }
 
-// storeCommon handles the actual storage of an entry, possibly generating
-// secondary indexes
-func (ts *TokenStore) storeCommon(ctx context.Context, entry *logical.TokenEntry, writeSecondary bool) error {
+// storeCommon adds the storage of an entry to the writes of the token,
+// possibly generating secondary indexes
+func (ts *TokenStore) storeCommon(ctx context.Context, entry *logical.TokenEntry, writeSecondary bool, writes *tokenStoreWrites) error {
 	tokenNS, err := NamespaceByID(ctx, entry.NamespaceID, ts.core)
 	if err != nil {
 		return err
@@ -1323,9 +1499,10 @@ func (ts *TokenStore) storeCommon(ctx context.Context, entry *logical.TokenEntry
 
 	if writeSecondary {
 		// Write the secondary index if necessary. This is done before the
-		// primary index because we'd rather have a dangling pointer with
-		// a missing primary instead of missing the parent index and potentially
-		// escaping the revocation chain.
+		// primary index, unless both are written in a single transaction,
+		// because we'd rather have a dangling pointer with a missing primary
+		// instead of missing the parent index and potentially escaping the
+		// revocation chain.
 		if entry.Parent != "" {
 			// Ensure the parent exists
 			parent, err := ts.Lookup(ctx, entry.Parent)
@@ -1357,10 +1534,7 @@ func (ts *TokenStore) storeCommon(ctx context.Context, entry *logical.TokenEntry
 				path = fmt.Sprintf("%s.%s", path, tokenNS.ID)
 			}
 
-			le := &logical.StorageEntry{Key: path}
-			if err := ts.parentView(parentNS).Put(ctx, le); err != nil {
-				return fmt.Errorf("failed to persist entry: %w", err)
-			}
+			writes.add(ts.parentView(parentNS), &logical.StorageEntry{Key: path})
 		}
 	}
 
@@ -1369,9 +1543,7 @@ func (ts *TokenStore) storeCommon(ctx context.Context, entry *logical.TokenEntry
 	if len(entry.Policies) == 1 && entry.Policies[0] == "root" {
 		le.SealWrap = true
 	}
-	if err := ts.idView(tokenNS).Put(ctx, le); err != nil {
-		return fmt.Errorf("failed to persist entry: %w", err)
-	}
+	writes.add(ts.idView(tokenNS), le)
 	return nil
 }
 
@@ -1878,6 +2050,9 @@ func (ts *TokenStore) revokeInternal(ctx context.Context, saltedID string, skipO
 			return err
 		}
 
+		// The accessor index may still be being persisted in the background
+		ts.cancelPendingWrite(ts.accessorView(tokenNS), accessorSaltedID)
+
 		if err = ts.accessorView(tokenNS).Delete(ctx, accessorSaltedID); err != nil {
 			return fmt.Errorf("failed to delete entry: %w", err)
 		}
@@ -2140,6 +2315,10 @@ func (ts *TokenStore) lookupByAccessor(ctx context.Context, id string, salted, t
 	if err != nil {
 		return nil, fmt.Errorf("failed to read index using accessor: %w", err)
 	}
+	if entry == nil {
+		// The accessor index of a new token may still be being persisted
+		entry = ts.pendingEntry(ts.accessorView(ns), lookupID)
+	}
 	if entry == nil {
 		return nil, nil
 	}
@@ -2435,6 +2614,16 @@ func (ts *TokenStore) handleTidy(ctx context.Context, req *logical.Request, data
 			ts.logger.Info("number of deleted accessors which had invalid tokens", "count", deletedCountAccessorInvalidToken)
 			ts.logger.Info("number of deleted cubbyhole keys that were invalid", "count", deletedCountInvalidCubbyholeKey)
 
+			// The index of outstanding wrapping tokens is shared by all
+			// namespaces, so only tidy it from the root one
+			if ns.ID == namespace.RootNamespaceID {
+				deletedCountWrappingIndex, err := ts.core.tidyWrappingIndex(quitCtx)
+				if err != nil {
+					tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to tidy the wrapping token index: %w", err))
+				}
+				ts.logger.Info("number of deleted wrapping token index entries", "count", deletedCountWrappingIndex)
+			}
+
 			return tidyErrors.ErrorOrNil()
 		}
 
@@ -2607,6 +2796,13 @@ func (ts *TokenStore) handleCreate(ctx context.Context, req *logical.Request, d
 	return ts.handleCreateCommon(ctx, req, d, false, nil)
 }
 
+// inlinePolicyIsScope returns whether the inline policy of the token scopes
+// down its policies, as set on batch tokens created with an inline_policy,
+// rather than adding to them as the inline policies of other tokens do.
+func inlinePolicyIsScope(te *logical.TokenEntry) bool {
+	return te.InlinePolicy != "" && te.InternalMeta[inlinePolicyScopeMeta] == "true"
+}
+
 // handleCreateCommon handles the auth/token/create path for creation of new tokens
 func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Request, d *framework.FieldData, orphan bool, role *tsRoleEntry) (*logical.Response, error) {
 	// Read the parent policy
@@ -2647,6 +2843,7 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		Period          string
 		Type            string `mapstructure:"type"`
 		EntityAlias     string `mapstructure:"entity_alias"`
+		InlinePolicy    string `mapstructure:"inline_policy"`
 	}
 	if err := mapstructure.WeakDecode(req.Data, &data); err != nil {
 		return logical.ErrorResponse(fmt.Sprintf(
@@ -3018,6 +3215,15 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 			te.BoundCIDRs = role.TokenBoundCIDRs
 		}
 
+		te.GeoConstraints = role.GeoConstraints()
+
+		if role.BindClientCert {
+			te.BoundClientCertFingerprint = clientCertFingerprint(req)
+			if te.BoundClientCertFingerprint == "" {
+				return logical.ErrorResponse("a TLS client certificate is required to create tokens with this role"), logical.ErrInvalidRequest
+			}
+		}
+
 	case data.NoParent:
 		// Only allow an orphan token if the client has sudo policy
 		if !isSudo {
@@ -3051,6 +3257,8 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		// circumstances.
 		if role == nil {
 			te.BoundCIDRs = parent.BoundCIDRs
+			te.GeoConstraints = parent.GeoConstraints
+			te.BoundClientCertFingerprint = parent.BoundClientCertFingerprint
 		}
 	}
 
@@ -3165,6 +3373,34 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		}
 		renewable = false
 		te.BoundCIDRs = nil
+		te.GeoConstraints = nil
+	}
+
+	// Batch tokens are encoded without country and ASN constraints, so
+	// they can't carry them
+	if te.Type == logical.TokenTypeBatch && !te.GeoConstraints.Empty() {
+		return logical.ErrorResponse("batch tokens cannot be created with country or ASN constraints"), logical.ErrInvalidRequest
+	}
+
+	// Likewise for TLS client certificate bindings
+	if te.Type == logical.TokenTypeBatch && te.BoundClientCertFingerprint != "" {
+		return logical.ErrorResponse("batch tokens cannot be bound to a TLS client certificate"), logical.ErrInvalidRequest
+	}
+
+	// An inline policy scopes down the policies of the token, so that it
+	// can't grant more than the token would otherwise be allowed
+	if data.InlinePolicy != "" {
+		if te.Type != logical.TokenTypeBatch {
+			return logical.ErrorResponse("inline policies can only be set on batch tokens"), logical.ErrInvalidRequest
+		}
+		if _, err := ParseACLPolicy(ns, data.InlinePolicy); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to parse inline policy: %v", err)), logical.ErrInvalidRequest
+		}
+		te.InlinePolicy = data.InlinePolicy
+		if te.InternalMeta == nil {
+			te.InternalMeta = make(map[string]string)
+		}
+		te.InternalMeta[inlinePolicyScopeMeta] = "true"
 	}
 
 	if te.ID != "" {
@@ -3389,6 +3625,18 @@ func (ts *TokenStore) handleLookup(ctx context.Context, req *logical.Request, da
 		resp.Data["bound_cidrs"] = out.BoundCIDRs
 	}
 
+	if !out.GeoConstraints.Empty() {
+		resp.Data["geo_constraints"] = out.GeoConstraints
+	}
+
+	if out.BoundClientCertFingerprint != "" {
+		resp.Data["bound_client_cert_fingerprint"] = out.BoundClientCertFingerprint
+	}
+
+	if inlinePolicyIsScope(out) {
+		resp.Data["inline_policy"] = out.InlinePolicy
+	}
+
 	tokenNS, err := NamespaceByID(ctx, out.NamespaceID, ts.core)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -3615,6 +3863,21 @@ func (ts *TokenStore) tokenStoreRoleRead(ctx context.Context, req *logical.Reque
 	if role.TokenNumUses > 0 {
 		resp.Data["token_num_uses"] = role.TokenNumUses
 	}
+	if role.BindClientCert {
+		resp.Data["bind_client_cert"] = true
+	}
+	if len(role.TokenAllowedCountries) > 0 {
+		resp.Data["token_allowed_countries"] = role.TokenAllowedCountries
+	}
+	if len(role.TokenDeniedCountries) > 0 {
+		resp.Data["token_denied_countries"] = role.TokenDeniedCountries
+	}
+	if len(role.TokenAllowedASNs) > 0 {
+		resp.Data["token_allowed_asns"] = role.TokenAllowedASNs
+	}
+	if len(role.TokenDeniedASNs) > 0 {
+		resp.Data["token_denied_asns"] = role.TokenDeniedASNs
+	}
 
 	return resp, nil
 }
@@ -3839,6 +4102,11 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(ctx context.Context, req *logic
 		entry.TokenNumUses = tokenNumUses.(int)
 	}
 
+	bindClientCertRaw, ok := data.GetOk("bind_client_cert")
+	if ok {
+		entry.BindClientCert = bindClientCertRaw.(bool)
+	}
+
 	// Run validity checks on token type
 	if entry.TokenType == logical.TokenTypeBatch {
 		if !entry.Orphan {
@@ -3853,6 +4121,12 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(ctx context.Context, req *logic
 		if entry.ExplicitMaxTTL != 0 || entry.TokenExplicitMaxTTL != 0 {
 			return logical.ErrorResponse("'token_type' cannot be 'batch' when role is set to generate tokens with an explicit max TTL"), nil
 		}
+		if !entry.GeoConstraints().Empty() {
+			return logical.ErrorResponse("'token_type' cannot be 'batch' when role is set to generate tokens with country or ASN constraints"), nil
+		}
+		if entry.BindClientCert {
+			return logical.ErrorResponse("'token_type' cannot be 'batch' when role is set to bind tokens to the TLS client certificate"), nil
+		}
 	}
 
 	allowedEntityAliasesRaw, ok := data.GetOk("allowed_entity_aliases")
@@ -4230,6 +4504,9 @@ the current maximum TTL values of the role
 and the mount are not checked for changes,
 and any updates to these values will have
 no effect on the token being renewed.`
+	tokenBindClientCertHelp = `If set, tokens created via this role are bound
+to the SHA-256 fingerprint of the TLS client certificate used to create them,
+and can only be used over connections presenting that certificate.`
 	tokenRenewableHelp = `Tokens created via this role will be
 renewable or not according to this value.
 Defaults to "true".`
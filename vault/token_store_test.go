@@ -2,6 +2,8 @@ package vault
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"path"
@@ -711,6 +713,85 @@ func TestTokenStore_AccessorIndex(t *testing.T) {
 	}
 }
 
+func TestTokenStore_AccessorIndex_WriteBehind(t *testing.T) {
+	c, _, _ := TestCoreUnsealedWithConfig(t, &CoreConfig{TokenAccessorWriteBehind: true})
+	ts := c.tokenStore
+
+	ent := &logical.TokenEntry{
+		Path:        "test",
+		Policies:    []string{"dev", "ops"},
+		TTL:         time.Hour,
+		NamespaceID: namespace.RootNamespaceID,
+	}
+	testMakeTokenDirectly(t, ts, ent)
+
+	// The accessor resolves whether or not its index is persisted yet
+	aEntry, err := ts.lookupByAccessor(namespace.RootContext(nil), ent.Accessor, false, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if aEntry == nil || aEntry.TokenID != ent.ID {
+		t.Fatalf("bad: %#v", aEntry)
+	}
+
+	saltID, err := ts.SaltID(namespace.RootContext(nil), ent.Accessor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entry, err := ts.accessorView(namespace.RootNamespace).Get(namespace.RootContext(nil), saltID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("accessor index was not persisted in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTokenStore_AccessorIndex_WriteBehindRevoke(t *testing.T) {
+	c, _, _ := TestCoreUnsealedWithConfig(t, &CoreConfig{TokenAccessorWriteBehind: true})
+	ts := c.tokenStore
+	ctx := namespace.RootContext(nil)
+
+	for i := 0; i < 20; i++ {
+		ent := &logical.TokenEntry{
+			Path:        "test",
+			Policies:    []string{"dev"},
+			TTL:         time.Hour,
+			NamespaceID: namespace.RootNamespaceID,
+		}
+		testMakeTokenDirectly(t, ts, ent)
+
+		// Revoking the token before its accessor index is persisted must
+		// not leave the index behind
+		if err := ts.revokeOrphan(ctx, ent.ID); err != nil {
+			t.Fatal(err)
+		}
+		ts.waitPendingWrites()
+
+		saltID, err := ts.SaltID(ctx, ent.Accessor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := ts.accessorView(namespace.RootNamespace).Get(ctx, saltID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			t.Fatalf("accessor index of revoked token %d was persisted", i)
+		}
+		if aEntry, err := ts.lookupByAccessor(ctx, ent.Accessor, false, false); err != nil || aEntry != nil {
+			t.Fatalf("accessor of revoked token %d resolves: %#v, %v", i, aEntry, err)
+		}
+	}
+}
+
 func TestTokenStore_HandleRequest_LookupAccessor(t *testing.T) {
 	c, _, root := TestCoreUnsealed(t)
 	ts := c.tokenStore
@@ -6162,3 +6243,248 @@ func TestTokenStore_Collectors(t *testing.T) {
 	// Need to set up router for this to work, TODO
 	// ts.gaugeCollectorByMethod( ctx )
 }
+
+func TestTokenStore_RoleGeoConstraints(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ts := c.tokenStore
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "roles/geo")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"allowed_policies":        "default",
+		"token_allowed_countries": "fr,de",
+		"token_denied_asns":       "64501",
+	}
+	resp, err := ts.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "roles/geo")
+	req.ClientToken = root
+	resp, err = ts.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+	if diff := deep.Equal(resp.Data["token_allowed_countries"], []string{"FR", "DE"}); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(resp.Data["token_denied_asns"], []int{64501}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// Batch tokens can't carry the constraints
+	req = logical.TestRequest(t, logical.UpdateOperation, "roles/geo-batch")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"orphan":                  true,
+		"renewable":               false,
+		"token_type":              "batch",
+		"token_allowed_countries": "FR",
+	}
+	resp, err = ts.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got err: %v\nresp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "create/geo")
+	req.ClientToken = root
+	resp = testMakeTokenViaRequest(t, ts, req)
+	geoToken := resp.Auth.ClientToken
+
+	te, err := ts.Lookup(namespace.RootContext(nil), resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &logical.GeoConstraints{
+		AllowedCountries: []string{"FR", "DE"},
+		DeniedASNs:       []int{64501},
+	}
+	if diff := deep.Equal(te.GeoConstraints, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// Without a GeoIP database the constraints can't be satisfied
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = geoToken
+	req.Connection = &logical.Connection{RemoteAddr: "192.0.2.1"}
+	_, err = c.HandleRequest(namespace.RootContext(nil), req)
+	if err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// Child tokens inherit the constraints of their parent
+	req = logical.TestRequest(t, logical.UpdateOperation, "create")
+	req.ClientToken = geoToken
+	resp = testMakeTokenViaRequest(t, ts, req)
+	te, err = ts.Lookup(namespace.RootContext(nil), resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(te.GeoConstraints, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestTokenStore_RoleBindClientCert(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ts := c.tokenStore
+
+	connection := func(raw string) *logical.Connection {
+		return &logical.Connection{
+			RemoteAddr: "127.0.0.1",
+			ConnState: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Raw: []byte(raw)}},
+			},
+		}
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "roles/mtls")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"allowed_policies": "default",
+		"bind_client_cert": true,
+	}
+	resp, err := ts.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+
+	// Batch tokens can't be bound
+	req = logical.TestRequest(t, logical.UpdateOperation, "roles/mtls-batch")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"orphan":           true,
+		"renewable":        false,
+		"token_type":       "batch",
+		"bind_client_cert": true,
+	}
+	resp, err = ts.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got err: %v\nresp: %#v", err, resp)
+	}
+
+	// A client certificate is required to create tokens
+	req = logical.TestRequest(t, logical.UpdateOperation, "create/mtls")
+	req.ClientToken = root
+	resp, err = ts.HandleRequest(namespace.RootContext(nil), req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got err: %v\nresp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "create/mtls")
+	req.ClientToken = root
+	req.Connection = connection("cert-a")
+	resp = testMakeTokenViaRequest(t, ts, req)
+	boundToken := resp.Auth.ClientToken
+
+	te, err := ts.Lookup(namespace.RootContext(nil), boundToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if te.BoundClientCertFingerprint != clientCertFingerprint(req) {
+		t.Fatalf("bad: fingerprint: %q", te.BoundClientCertFingerprint)
+	}
+
+	lookupSelf := func(conn *logical.Connection) error {
+		req := logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+		req.ClientToken = boundToken
+		req.Connection = conn
+		_, err := c.HandleRequest(namespace.RootContext(nil), req)
+		return err
+	}
+	for _, conn := range []*logical.Connection{{RemoteAddr: "127.0.0.1"}, connection("cert-b")} {
+		if err := lookupSelf(conn); err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("expected permission denied, got: %v", err)
+		}
+	}
+	if err := lookupSelf(connection("cert-a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Child tokens inherit the binding of their parent
+	req = logical.TestRequest(t, logical.UpdateOperation, "create")
+	req.ClientToken = boundToken
+	resp = testMakeTokenViaRequest(t, ts, req)
+	child, err := ts.Lookup(namespace.RootContext(nil), resp.Auth.ClientToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.BoundClientCertFingerprint != te.BoundClientCertFingerprint {
+		t.Fatalf("bad: child fingerprint: %q", child.BoundClientCertFingerprint)
+	}
+}
+
+func TestTokenStore_BatchInlinePolicy(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ts := c.tokenStore
+
+	policy, _ := ParseACLPolicy(namespace.RootNamespace, `
+path "secret/*" {
+	capabilities = ["create", "read", "update"]
+}`)
+	policy.Name = "kv"
+	if err := c.policyStore.SetPolicy(namespace.RootContext(nil), policy); err != nil {
+		t.Fatal(err)
+	}
+
+	inlinePolicy := `
+path "secret/worker" {
+	capabilities = ["read"]
+}
+
+path "sys/mounts" {
+	capabilities = ["read", "sudo"]
+}`
+
+	// Only batch tokens can be scoped down
+	req := logical.TestRequest(t, logical.UpdateOperation, "create")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"policies":      "kv",
+		"inline_policy": inlinePolicy,
+	}
+	resp, err := ts.HandleRequest(namespace.RootContext(nil), req)
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got err: %v\nresp: %#v", err, resp)
+	}
+
+	// Malformed inline policies are rejected before the token is created
+	req.Data["type"] = "batch"
+	req.Data["inline_policy"] = `path "secret/*" { capabilities = `
+	resp, err = ts.HandleRequest(namespace.RootContext(nil), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() || resp.Auth != nil {
+		t.Fatalf("expected a parse error, got err: %v\nresp: %#v", err, resp)
+	}
+	if !strings.Contains(resp.Error().Error(), "failed to parse inline policy") {
+		t.Fatalf("unexpected error: %v", resp.Error())
+	}
+
+	req.Data["inline_policy"] = inlinePolicy
+	resp = testMakeTokenViaRequest(t, ts, req)
+	token := resp.Auth.ClientToken
+
+	for path, expected := range map[string][]string{
+		"secret/worker": {"read"},
+		"secret/other":  {"deny"},
+		"sys/mounts":    {"deny"},
+	} {
+		actual, err := c.Capabilities(namespace.RootContext(nil), token, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(actual, expected); diff != nil {
+			t.Fatalf("path %q: %v", path, diff)
+		}
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "lookup-self")
+	req.ClientToken = token
+	resp, err = ts.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+	if resp.Data["inline_policy"] != inlinePolicy {
+		t.Fatalf("bad inline policy: %#v", resp.Data["inline_policy"])
+	}
+}
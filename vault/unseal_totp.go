@@ -0,0 +1,274 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	wrapping "github.com/hashicorp/go-kms-wrapping/v2"
+	aeadwrapper "github.com/hashicorp/go-kms-wrapping/wrappers/aead/v2"
+	"github.com/hashicorp/vault/helper/pgpkeys"
+	"github.com/hashicorp/vault/sdk/physical"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// coreUnsealTOTPPath is the path used to store the TOTP secrets bound to
+	// the unseal key shares. This is outside of the barrier, as the secrets
+	// are needed while Vault is sealed, so each secret is encrypted with its
+	// share instead.
+	coreUnsealTOTPPath = "core/unseal-totp"
+
+	// unsealTOTPKeyInfo is the HKDF info deriving the key encrypting the
+	// TOTP secret of a share from the share
+	unsealTOTPKeyInfo = "vault-unseal-totp"
+
+	unsealTOTPIssuer = "Vault"
+	unsealTOTPPeriod = 30
+	unsealTOTPSkew   = 1
+
+	// unsealTOTPMaxAttempts is the number of codes that can be submitted
+	// with a share within unsealTOTPAttemptsWindow.
+	unsealTOTPMaxAttempts    = 5
+	unsealTOTPAttemptsWindow = 5 * time.Minute
+)
+
+// unsealTOTPBinding binds an unseal key share, identified by its hash, to
+// the TOTP secret of its holder. The secret is encrypted the way the Shamir
+// seal encrypts with the combined key, with a key derived from the share, so
+// that it cannot be read from storage without the share.
+type unsealTOTPBinding struct {
+	ShareHash       string `json:"share_hash"`
+	EncryptedSecret []byte `json:"encrypted_secret"`
+}
+
+// unsealTOTPHash identifies an unseal key share without revealing it.
+func unsealTOTPHash(share []byte) string {
+	sum := sha256.Sum256(share)
+	return hex.EncodeToString(sum[:])
+}
+
+// unsealTOTPWrapper returns the wrapper encrypting the TOTP secret of share.
+func unsealTOTPWrapper(share []byte) (*aeadwrapper.ShamirWrapper, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, share, nil, []byte(unsealTOTPKeyInfo)), key); err != nil {
+		return nil, err
+	}
+	wrapper := aeadwrapper.NewShamirWrapper()
+	if err := wrapper.SetAesGcmKeyBytes(key); err != nil {
+		return nil, err
+	}
+	return wrapper, nil
+}
+
+// encryptSecret sets the encrypted secret of the binding of share.
+func (b *unsealTOTPBinding) encryptSecret(ctx context.Context, share []byte, secret string) error {
+	wrapper, err := unsealTOTPWrapper(share)
+	if err != nil {
+		return err
+	}
+	blobInfo, err := wrapper.Encrypt(ctx, []byte(secret))
+	if err != nil {
+		return err
+	}
+	b.EncryptedSecret, err = proto.Marshal(blobInfo)
+	return err
+}
+
+// decryptSecret returns the secret of the binding of share.
+func (b *unsealTOTPBinding) decryptSecret(ctx context.Context, share []byte) (string, error) {
+	wrapper, err := unsealTOTPWrapper(share)
+	if err != nil {
+		return "", err
+	}
+	var blobInfo wrapping.BlobInfo
+	if err := proto.Unmarshal(b.EncryptedSecret, &blobInfo); err != nil {
+		return "", err
+	}
+	secret, err := wrapper.Decrypt(ctx, &blobInfo)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// newUnsealTOTPBindings generates a TOTP secret for each of the given
+// unseal key shares. The returned keys are the otpauth URLs of the secrets,
+// in the order of the shares, to hand out along with them. When PGP keys
+// are given, the URLs are encrypted with the same keys as the shares.
+func (c *Core) newUnsealTOTPBindings(ctx context.Context, shares [][]byte, pgpKeys []string) ([]*unsealTOTPBinding, [][]byte, error) {
+	bindings := make([]*unsealTOTPBinding, 0, len(shares))
+	keys := make([][]byte, 0, len(shares))
+	for i, share := range shares {
+		key, err := totplib.Generate(totplib.GenerateOpts{
+			Issuer:      unsealTOTPIssuer,
+			AccountName: fmt.Sprintf("unseal-key-%d", i+1),
+			Period:      unsealTOTPPeriod,
+			Digits:      otplib.DigitsSix,
+			Algorithm:   otplib.AlgorithmSHA1,
+			Rand:        c.secureRandomReader,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+		}
+
+		binding := &unsealTOTPBinding{
+			ShareHash: unsealTOTPHash(share),
+		}
+		if err := binding.encryptSecret(ctx, share, key.Secret()); err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt TOTP secret: %w", err)
+		}
+		bindings = append(bindings, binding)
+		keys = append(keys, []byte(key.String()))
+	}
+
+	if len(pgpKeys) > 0 {
+		_, encryptedKeys, err := pgpkeys.EncryptShares(keys, pgpKeys)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt TOTP secrets: %w", err)
+		}
+		for i := range encryptedKeys {
+			encryptedKeys[i] = []byte(base64.StdEncoding.EncodeToString(encryptedKeys[i]))
+		}
+		keys = encryptedKeys
+	}
+
+	return bindings, keys, nil
+}
+
+// persistUnsealTOTPBindings stores the bindings of the unseal key shares,
+// removing them if there are none.
+func (c *Core) persistUnsealTOTPBindings(ctx context.Context, bindings []*unsealTOTPBinding) error {
+	c.unsealTOTPCodes.Flush()
+
+	if len(bindings) == 0 {
+		if err := c.physical.Delete(ctx, coreUnsealTOTPPath); err != nil {
+			return fmt.Errorf("failed to delete unseal TOTP secrets: %w", err)
+		}
+		return nil
+	}
+
+	buf, err := json.Marshal(bindings)
+	if err != nil {
+		return fmt.Errorf("failed to encode unseal TOTP secrets: %w", err)
+	}
+	if err := c.physical.Put(ctx, &physical.Entry{
+		Key:   coreUnsealTOTPPath,
+		Value: buf,
+	}); err != nil {
+		return fmt.Errorf("failed to store unseal TOTP secrets: %w", err)
+	}
+	return nil
+}
+
+func (c *Core) unsealTOTPBinding(ctx context.Context, share []byte) (*unsealTOTPBinding, error) {
+	entry, err := c.physical.Get(ctx, coreUnsealTOTPPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read unseal TOTP secrets: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var bindings []*unsealTOTPBinding
+	if err := json.Unmarshal(entry.Value, &bindings); err != nil {
+		return nil, fmt.Errorf("failed to decode unseal TOTP secrets: %w", err)
+	}
+
+	hash := unsealTOTPHash(share)
+	for _, binding := range bindings {
+		if subtle.ConstantTimeCompare([]byte(binding.ShareHash), []byte(hash)) == 1 {
+			return binding, nil
+		}
+	}
+	return nil, nil
+}
+
+// unsealTOTPSeal returns the Shamir seal whose key shares are submitted, if
+// any.
+func (c *Core) unsealTOTPSeal() Seal {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	if c.migrationInfo != nil && c.migrationInfo.seal != nil && c.migrationInfo.seal.BarrierType() == wrapping.WrapperTypeShamir {
+		return c.migrationInfo.seal
+	}
+	if c.seal.BarrierType() == wrapping.WrapperTypeShamir {
+		return c.seal
+	}
+	return nil
+}
+
+// CheckUnsealKeyTOTP verifies the TOTP code submitted along with an unseal
+// key share, when the shares are TOTP protected. It must be called before
+// the share is used to unseal, rekey or generate a root token. Codes can
+// only be used once, and the number of attempts per share is limited.
+func (c *Core) CheckUnsealKeyTOTP(ctx context.Context, share []byte, code string) error {
+	sealToUse := c.unsealTOTPSeal()
+	if sealToUse == nil {
+		return nil
+	}
+	config, err := sealToUse.BarrierConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config == nil || !config.TOTPProtected {
+		return nil
+	}
+
+	if code == "" {
+		return &ErrInvalidKey{"a TOTP code is required along with the key"}
+	}
+
+	binding, err := c.unsealTOTPBinding(ctx, share)
+	if err != nil {
+		return err
+	}
+	if binding == nil {
+		return &ErrInvalidKey{"key is not a TOTP protected unseal key"}
+	}
+
+	attemptsKey := "attempts_" + binding.ShareHash
+	if _, err := c.unsealTOTPCodes.IncrementUint32(attemptsKey, 1); err != nil {
+		c.unsealTOTPCodes.Set(attemptsKey, uint32(1), unsealTOTPAttemptsWindow)
+	}
+	if attempts, ok := c.unsealTOTPCodes.Get(attemptsKey); ok && attempts.(uint32) > unsealTOTPMaxAttempts {
+		return &ErrInvalidKey{fmt.Sprintf("maximum TOTP attempts exceeded for this key, try again in %v", unsealTOTPAttemptsWindow)}
+	}
+
+	usedKey := "used_" + binding.ShareHash + "_" + code
+	if _, ok := c.unsealTOTPCodes.Get(usedKey); ok {
+		return &ErrInvalidKey{"TOTP code already used"}
+	}
+
+	secret, err := binding.decryptSecret(ctx, share)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	valid, err := totplib.ValidateCustom(code, secret, time.Now(), totplib.ValidateOpts{
+		Period:    unsealTOTPPeriod,
+		Skew:      unsealTOTPSkew,
+		Digits:    otplib.DigitsSix,
+		Algorithm: otplib.AlgorithmSHA1,
+	})
+	if err != nil && err != otplib.ErrValidateInputInvalidLength {
+		return fmt.Errorf("failed to validate TOTP code: %w", err)
+	}
+	if !valid {
+		return &ErrInvalidKey{"invalid TOTP code"}
+	}
+
+	// Codes stay valid for the skew on either side of their period
+	c.unsealTOTPCodes.Set(usedKey, nil, time.Duration(2+unsealTOTPSkew)*unsealTOTPPeriod*time.Second)
+	c.unsealTOTPCodes.Delete(attemptsKey)
+	return nil
+}
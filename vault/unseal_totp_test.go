@@ -0,0 +1,113 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+func TestCore_UnsealKeyTOTP(t *testing.T) {
+	c, _ := testCore_NewTestCore(t, nil)
+	ctx := context.Background()
+
+	res, err := c.Initialize(ctx, &InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    3,
+			SecretThreshold: 2,
+			TOTPProtected:   true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(res.SecretSharesTOTP) != len(res.SecretShares) {
+		t.Fatalf("expected a TOTP key per share, got %d for %d shares", len(res.SecretSharesTOTP), len(res.SecretShares))
+	}
+
+	// The secrets are not stored in the clear
+	entry, err := c.physical.Get(ctx, coreUnsealTOTPPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry == nil {
+		t.Fatal("expected the TOTP secrets to be stored")
+	}
+	for _, url := range res.SecretSharesTOTP {
+		key, err := otplib.NewKeyFromURL(string(url))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if bytes.Contains(entry.Value, []byte(key.Secret())) {
+			t.Fatal("expected the stored TOTP secrets to be encrypted")
+		}
+	}
+
+	code := func(i int) string {
+		t.Helper()
+		key, err := otplib.NewKeyFromURL(string(res.SecretSharesTOTP[i]))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		code, err := totplib.GenerateCodeCustom(key.Secret(), time.Now(), totplib.ValidateOpts{
+			Period:    unsealTOTPPeriod,
+			Digits:    otplib.DigitsSix,
+			Algorithm: otplib.AlgorithmSHA1,
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return code
+	}
+	expectInvalidKey := func(err error) {
+		t.Helper()
+		var invalidKey *ErrInvalidKey
+		if !errors.As(err, &invalidKey) {
+			t.Fatalf("expected an invalid key error, got: %v", err)
+		}
+	}
+
+	// Codes are required, and only valid for the share they are bound to
+	expectInvalidKey(c.CheckUnsealKeyTOTP(ctx, res.SecretShares[0], ""))
+	expectInvalidKey(c.CheckUnsealKeyTOTP(ctx, res.SecretShares[0], code(1)))
+	expectInvalidKey(c.CheckUnsealKeyTOTP(ctx, []byte("not a share"), code(0)))
+
+	if err := c.CheckUnsealKeyTOTP(ctx, res.SecretShares[0], code(0)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := c.Unseal(res.SecretShares[0]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Codes cannot be replayed
+	expectInvalidKey(c.CheckUnsealKeyTOTP(ctx, res.SecretShares[0], code(0)))
+
+	// Shares are locked out after too many attempts
+	for i := 0; i < unsealTOTPMaxAttempts; i++ {
+		expectInvalidKey(c.CheckUnsealKeyTOTP(ctx, res.SecretShares[2], "000000"))
+	}
+	expectInvalidKey(c.CheckUnsealKeyTOTP(ctx, res.SecretShares[2], code(2)))
+
+	if err := c.CheckUnsealKeyTOTP(ctx, res.SecretShares[1], code(1)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	unsealed, err := c.Unseal(res.SecretShares[1])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !unsealed {
+		t.Fatal("expected the core to be unsealed")
+	}
+
+	status, err := c.GetSealStatus(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !status.TOTPProtected {
+		t.Fatalf("expected the seal status to report TOTP protected shares: %#v", status)
+	}
+}
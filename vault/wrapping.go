@@ -332,6 +332,12 @@ DONELISTHANDLING:
 		return nil, ErrInternalError
 	}
 
+	// The token is usable at this point, so failing to index it only keeps
+	// it out of sys/wrapping/tokens
+	if err := c.indexWrappingToken(ctx, req, &te, resp.WrapInfo.CreationPath); err != nil {
+		c.logger.Warn("failed to index wrapping token", "request_path", req.Path, "error", err)
+	}
+
 	return nil, nil
 }
 
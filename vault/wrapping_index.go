@@ -0,0 +1,237 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// coreWrappingIndexPath is the prefix the outstanding response-wrapping
+// tokens are indexed under, keyed by their creator and their accessor.
+const coreWrappingIndexPath = "core/wrapping/index/"
+
+var errWrappingTokenNotFound = errors.New("no outstanding wrapping token created by the caller with this accessor")
+
+// wrappingIndexEntry records the creation of a response-wrapping token so
+// that its creator can find and revoke it before it is unwrapped.
+type wrappingIndexEntry struct {
+	Accessor     string        `json:"accessor"`
+	CreationPath string        `json:"creation_path"`
+	CreationTime time.Time     `json:"creation_time"`
+	CreationTTL  time.Duration `json:"creation_ttl"`
+	NamespaceID  string        `json:"namespace_id"`
+}
+
+// expired returns whether the wrapping token has expired at now.
+func (e *wrappingIndexEntry) expired(now time.Time) bool {
+	return !now.Before(e.CreationTime.Add(e.CreationTTL))
+}
+
+// wrappingIndexCreator returns the key the wrapping tokens created by req
+// are indexed under: the entity of the client if it has one, else the
+// accessor of its token. Unauthenticated requests have no key.
+func wrappingIndexCreator(req *logical.Request) string {
+	switch {
+	case req.EntityID != "":
+		return "entity/" + req.EntityID
+	case req.ClientTokenAccessor != "":
+		return "token/" + req.ClientTokenAccessor
+	default:
+		return ""
+	}
+}
+
+func wrappingIndexPrefix(creator string) string {
+	return coreWrappingIndexPath + creator + "/"
+}
+
+// indexWrappingToken records the wrapping token te, created for req, under
+// the creator of req.
+func (c *Core) indexWrappingToken(ctx context.Context, req *logical.Request, te *logical.TokenEntry, creationPath string) error {
+	creator := wrappingIndexCreator(req)
+	if creator == "" {
+		return nil
+	}
+
+	entry, err := logical.StorageEntryJSON(wrappingIndexPrefix(creator)+te.Accessor, &wrappingIndexEntry{
+		Accessor:     te.Accessor,
+		CreationPath: creationPath,
+		CreationTime: time.Unix(te.CreationTime, 0).UTC(),
+		CreationTTL:  te.TTL,
+		NamespaceID:  te.NamespaceID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to persist wrapping token index entry: %w", err)
+	}
+	return nil
+}
+
+func (c *Core) getWrappingIndexEntry(ctx context.Context, key string) (*wrappingIndexEntry, error) {
+	raw, err := c.barrier.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wrapping token index entry: %w", err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entry wrappingIndexEntry
+	if err := raw.DecodeJSON(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode wrapping token index entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// lookupIndexedWrappingToken returns the wrapping token of the index entry,
+// or nil if it was unwrapped, revoked or has expired.
+func (c *Core) lookupIndexedWrappingToken(ctx context.Context, entry *wrappingIndexEntry) (*logical.TokenEntry, error) {
+	if entry.expired(time.Now()) {
+		return nil, nil
+	}
+
+	ns, err := NamespaceByID(ctx, entry.NamespaceID, c)
+	if err != nil {
+		return nil, err
+	}
+	if ns == nil {
+		return nil, nil
+	}
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+
+	aEntry, err := c.tokenStore.lookupByAccessor(nsCtx, entry.Accessor, false, false)
+	if err != nil {
+		return nil, err
+	}
+	if aEntry == nil {
+		return nil, nil
+	}
+	return c.tokenStore.Lookup(nsCtx, aEntry.TokenID)
+}
+
+// listWrappingTokens returns the outstanding wrapping tokens created by the
+// client of req. The index entries of the ones which are gone are skipped,
+// and left for tidyWrappingIndex to delete on the active node, so that the
+// list can be served by performance standbys.
+func (c *Core) listWrappingTokens(ctx context.Context, req *logical.Request) ([]*wrappingIndexEntry, error) {
+	creator := wrappingIndexCreator(req)
+	if creator == "" {
+		return nil, nil
+	}
+
+	prefix := wrappingIndexPrefix(creator)
+	keys, err := c.barrier.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wrapping token index entries: %w", err)
+	}
+
+	var entries []*wrappingIndexEntry
+	for _, key := range keys {
+		entry, err := c.getWrappingIndexEntry(ctx, prefix+key)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		te, err := c.lookupIndexedWrappingToken(ctx, entry)
+		if err != nil {
+			return nil, err
+		}
+		if te == nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// revokeWrappingToken revokes the outstanding wrapping token with the given
+// accessor, which must have been created by the client of req.
+func (c *Core) revokeWrappingToken(ctx context.Context, req *logical.Request, accessor string) error {
+	creator := wrappingIndexCreator(req)
+	if creator == "" || accessor == "" || strings.Contains(accessor, "/") {
+		return errWrappingTokenNotFound
+	}
+
+	key := wrappingIndexPrefix(creator) + accessor
+	entry, err := c.getWrappingIndexEntry(ctx, key)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return errWrappingTokenNotFound
+	}
+
+	te, err := c.lookupIndexedWrappingToken(ctx, entry)
+	if err != nil {
+		return err
+	}
+	if te != nil {
+		tokenNS, err := NamespaceByID(ctx, te.NamespaceID, c)
+		if err != nil {
+			return err
+		}
+		if tokenNS == nil {
+			return namespace.ErrNoNamespace
+		}
+
+		revokeCtx := namespace.ContextWithNamespace(c.tokenStore.quitContext, tokenNS)
+		leaseID, err := c.expiration.CreateOrFetchRevocationLeaseByToken(revokeCtx, te)
+		if err != nil {
+			return err
+		}
+		if err := c.expiration.Revoke(revokeCtx, leaseID); err != nil {
+			return err
+		}
+	}
+
+	if err := c.barrier.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete wrapping token index entry: %w", err)
+	}
+	if te == nil {
+		return errWrappingTokenNotFound
+	}
+	return nil
+}
+
+// tidyWrappingIndex drops the index entries of the wrapping tokens which
+// were unwrapped, revoked or have expired.
+func (c *Core) tidyWrappingIndex(ctx context.Context) (int, error) {
+	keys, err := logical.CollectKeys(ctx, NewBarrierView(c.barrier, coreWrappingIndexPath))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list wrapping token index entries: %w", err)
+	}
+
+	var deleted int
+	for _, key := range keys {
+		entry, err := c.getWrappingIndexEntry(ctx, coreWrappingIndexPath+key)
+		if err != nil {
+			return deleted, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		te, err := c.lookupIndexedWrappingToken(ctx, entry)
+		if err != nil {
+			return deleted, err
+		}
+		if te != nil {
+			continue
+		}
+		if err := c.barrier.Delete(ctx, coreWrappingIndexPath+key); err != nil {
+			return deleted, fmt.Errorf("failed to delete wrapping token index entry: %w", err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
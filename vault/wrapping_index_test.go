@@ -0,0 +1,148 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCore_WrappingTokensIndex(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	handle := func(token string, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := logical.TestRequest(t, op, path)
+		req.ClientToken = token
+		req.Data = data
+		if path == "sys/wrapping/wrap" {
+			req.WrapInfo = &logical.RequestWrapInfo{
+				TTL: time.Hour,
+			}
+		}
+		return c.HandleRequest(ctx, req)
+	}
+	listKeys := func(token string) map[string]interface{} {
+		t.Helper()
+		resp, err := handle(token, logical.ListOperation, "sys/wrapping/tokens", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp == nil || resp.Data["key_info"] == nil {
+			return nil
+		}
+		return resp.Data["key_info"].(map[string]interface{})
+	}
+
+	var accessors []string
+	var tokens []string
+	for i := 0; i < 2; i++ {
+		resp, err := handle(root, logical.UpdateOperation, "sys/wrapping/wrap", map[string]interface{}{"foo": "bar"})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp == nil || resp.WrapInfo == nil {
+			t.Fatalf("bad: %#v", resp)
+		}
+		accessors = append(accessors, resp.WrapInfo.Accessor)
+		tokens = append(tokens, resp.WrapInfo.Token)
+	}
+
+	keyInfo := listKeys(root)
+	if len(keyInfo) != 2 {
+		t.Fatalf("bad key info: %#v", keyInfo)
+	}
+	info := keyInfo[accessors[0]].(map[string]interface{})
+	if info["creation_path"] != "sys/wrapping/wrap" || info["creation_ttl"] != int64(3600) {
+		t.Fatalf("bad info: %#v", info)
+	}
+	if ttl := info["ttl"].(int64); ttl <= 0 || ttl > 3600 {
+		t.Fatalf("bad ttl: %d", ttl)
+	}
+
+	// Other tokens neither see nor revoke the wrapping tokens of the root
+	// token, even with a policy letting them manage their own
+	if _, err := handle(root, logical.UpdateOperation, "sys/policy/wrapping-tokens", map[string]interface{}{
+		"policy": `
+path "sys/wrapping/tokens" {
+    capabilities = ["list"]
+}
+path "sys/wrapping/tokens/revoke" {
+    capabilities = ["update"]
+}`,
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp, err := handle(root, logical.UpdateOperation, "auth/token/create", map[string]interface{}{
+		"policies": "wrapping-tokens",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	other := resp.Auth.ClientToken
+	if keyInfo := listKeys(other); len(keyInfo) != 0 {
+		t.Fatalf("bad key info: %#v", keyInfo)
+	}
+	if _, err := handle(other, logical.UpdateOperation, "sys/wrapping/tokens/revoke", map[string]interface{}{
+		"accessor": accessors[0],
+	}); err == nil {
+		t.Fatal("expected an error revoking a wrapping token of another token")
+	}
+
+	if _, err := handle(root, logical.UpdateOperation, "sys/wrapping/tokens/revoke", map[string]interface{}{
+		"accessor": accessors[0],
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := handle(root, logical.UpdateOperation, "sys/wrapping/unwrap", map[string]interface{}{
+		"token": tokens[0],
+	}); err == nil {
+		t.Fatal("expected an error unwrapping a revoked wrapping token")
+	}
+	if _, err := handle(root, logical.UpdateOperation, "sys/wrapping/tokens/revoke", map[string]interface{}{
+		"accessor": accessors[0],
+	}); err == nil {
+		t.Fatal("expected an error revoking a wrapping token twice")
+	}
+
+	// Unwrapped tokens are dropped from the list
+	if _, err := handle(root, logical.UpdateOperation, "sys/wrapping/unwrap", map[string]interface{}{
+		"token": tokens[1],
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keyInfo := listKeys(root); len(keyInfo) != 0 {
+		t.Fatalf("bad key info: %#v", keyInfo)
+	}
+
+	// Listing leaves the index untouched, tidying deletes the entries of
+	// the tokens which are gone
+	rootEntry, err := c.tokenStore.Lookup(ctx, root)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	prefix := wrappingIndexPrefix("token/" + rootEntry.Accessor)
+	keys, err := c.barrier.List(ctx, prefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("expected the index entry of the unwrapped token to be kept, got %v", keys)
+	}
+	deleted, err := c.tidyWrappingIndex(ctx)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 index entry to be deleted, got %d", deleted)
+	}
+	keys, err = c.barrier.List(ctx, prefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("expected the index entries to be deleted, got %v", keys)
+	}
+}